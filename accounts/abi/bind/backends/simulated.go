@@ -46,6 +46,7 @@ var _ bind.ContractBackend = (*SimulatedBackend)(nil)
 
 var errBlockNumberUnsupported = errors.New("SimulatedBackend cannot access blocks other than the latest block")
 var errGasEstimationFailed = errors.New("gas required exceeds allowance or always failing transaction")
+var errUnknownSnapshot = errors.New("unknown snapshot")
 
 // SimulatedBackend implements bind.ContractBackend, simulating a blockchain in
 // the background. Its main purpose is to allow easily testing contract bindings.
@@ -56,6 +57,8 @@ type SimulatedBackend struct {
 	mu           sync.Mutex
 	pendingBlock *types.Block   // Currently pending block that will be imported on request
 	pendingState *state.StateDB // Currently pending state that will be the active on on request
+	pendingShift int64          // Seconds the time of the pending block is shifted by
+	coinbase     common.Address // Beneficiary of the rewards of the simulated blocks
 
 	events *filters.EventSystem // Event system for filtering log events live
 
@@ -65,10 +68,19 @@ type SimulatedBackend struct {
 // NewSimulatedBackend creates a new binding backend using a simulated blockchain
 // for testing purposes.
 func NewSimulatedBackend(alloc core.GenesisAlloc) *SimulatedBackend {
+	return NewSimulatedBackendWithConfig(alloc, params.AllAquahashProtocolChanges)
+}
+
+// NewSimulatedBackendWithConfig creates a new binding backend simulating a chain
+// with the given configuration, such as params.MainnetChainConfig, so contracts
+// can be tested against the chain ID, forks and header versions they will be
+// deployed on. Blocks are sealed instantly, without proof-of-work.
+func NewSimulatedBackendWithConfig(alloc core.GenesisAlloc, config *params.ChainConfig) *SimulatedBackend {
 	database, _ := aquadb.NewMemDatabase()
-	genesis := core.Genesis{Config: params.AllAquahashProtocolChanges, Alloc: alloc}
+	genesis := core.Genesis{Config: config, Alloc: alloc}
 	genesis.MustCommit(database)
-	blockchain, _ := core.NewBlockChain(database, nil, genesis.Config, aquahash.NewFaker(), vm.Config{})
+	// Keep every state around, so the chain can be reverted to any snapshot
+	blockchain, _ := core.NewBlockChain(database, &core.CacheConfig{Disabled: true}, genesis.Config, aquahash.NewFaker(), vm.Config{})
 
 	backend := &SimulatedBackend{
 		database:   database,
@@ -100,8 +112,59 @@ func (b *SimulatedBackend) Rollback() {
 	b.rollback()
 }
 
+// Snapshot returns an identifier of the current chain head, which the chain can
+// later be reverted to with RevertToSnapshot. Pending transactions are not part
+// of a snapshot.
+func (b *SimulatedBackend) Snapshot() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.blockchain.CurrentBlock().NumberU64()
+}
+
+// RevertToSnapshot rewinds the chain to the head it had when the snapshot was
+// taken, dropping all blocks committed since along with any pending transactions.
+func (b *SimulatedBackend) RevertToSnapshot(snapshot uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if snapshot > b.blockchain.CurrentBlock().NumberU64() {
+		return errUnknownSnapshot
+	}
+	if err := b.blockchain.SetHead(snapshot); err != nil {
+		return err
+	}
+	b.rollback()
+	return nil
+}
+
+// SetCoinbase sets the beneficiary of the rewards of the pending and all later
+// blocks, the zero address by default.
+func (b *SimulatedBackend) SetCoinbase(coinbase common.Address) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.coinbase = coinbase
+	b.generate(b.pendingBlock.Transactions())
+}
+
 func (b *SimulatedBackend) rollback() {
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), aquahash.NewFaker(), b.database, 1, func(int, *core.BlockGen) {})
+	b.pendingShift = 0
+	b.generate(nil)
+}
+
+// generate rebuilds the pending block on top of the chain head from the given
+// transactions, keeping its coinbase and time shift.
+func (b *SimulatedBackend) generate(txs types.Transactions) {
+	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), aquahash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
+		block.SetCoinbase(b.coinbase)
+		if b.pendingShift != 0 {
+			block.OffsetTime(b.pendingShift)
+		}
+		for _, tx := range txs {
+			block.AddTx(tx)
+		}
+	})
 	statedb, _ := b.blockchain.State()
 
 	b.pendingBlock = blocks[0]
@@ -157,6 +220,21 @@ func (b *SimulatedBackend) StorageAt(ctx context.Context, contract common.Addres
 	return val[:], nil
 }
 
+// HeaderByNumber returns the header of the given committed block, or of the
+// latest one if number is nil.
+func (b *SimulatedBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if number == nil {
+		return b.blockchain.CurrentBlock().Header(), nil
+	}
+	if header := b.blockchain.GetHeaderByNumber(number.Uint64()); header != nil {
+		return header, nil
+	}
+	return nil, aquachain.NotFound
+}
+
 // TransactionReceipt returns the receipt of a transaction.
 func (b *SimulatedBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
 	receipt, _, _, _ := core.GetReceipt(b.database, txHash)
@@ -296,7 +374,7 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	sender, err := types.Sender(types.HomesteadSigner{}, tx)
+	sender, err := types.Sender(types.MakeSigner(b.config, b.pendingBlock.Number()), tx)
 	if err != nil {
 		panic(fmt.Errorf("invalid transaction: %v", err))
 	}
@@ -304,17 +382,7 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 	if tx.Nonce() != nonce {
 		panic(fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce))
 	}
-
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), aquahash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTx(tx)
-		}
-		block.AddTx(tx)
-	})
-	statedb, _ := b.blockchain.State()
-
-	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
+	b.generate(append(b.pendingBlock.Transactions(), tx))
 	return nil
 }
 
@@ -380,21 +448,14 @@ func (b *SimulatedBackend) SubscribeFilterLogs(ctx context.Context, query aquach
 	}), nil
 }
 
-// AdjustTime adds a time shift to the simulated clock.
+// AdjustTime adds a time shift to the simulated clock. The shift applies to the
+// pending block, surviving the transactions sent until it is committed.
 func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), aquahash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTx(tx)
-		}
-		block.OffsetTime(int64(adjustment.Seconds()))
-	})
-	statedb, _ := b.blockchain.State()
-
-	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
 
+	b.pendingShift += int64(adjustment.Seconds())
+	b.generate(b.pendingBlock.Transactions())
 	return nil
 }
 
@@ -408,6 +469,8 @@ func (m callmsg) Nonce() uint64        { return 0 }
 func (m callmsg) CheckNonce() bool     { return false }
 func (m callmsg) To() *common.Address  { return m.CallMsg.To }
 func (m callmsg) GasPrice() *big.Int   { return m.CallMsg.GasPrice }
+func (m callmsg) GasFeeCap() *big.Int  { return m.CallMsg.GasPrice }
+func (m callmsg) GasTipCap() *big.Int  { return m.CallMsg.GasPrice }
 func (m callmsg) Gas() uint64          { return m.CallMsg.Gas }
 func (m callmsg) Value() *big.Int      { return m.CallMsg.Value }
 func (m callmsg) Data() []byte         { return m.CallMsg.Data }
@@ -450,6 +513,21 @@ func (fb *filterBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*ty
 	return logs, nil
 }
 
+func (fb *filterBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	var header *types.Header
+	if block, ok := blockNrOrHash.Number(); ok {
+		header, _ = fb.HeaderByNumber(ctx, block)
+	} else {
+		hash, _ := blockNrOrHash.Hash()
+		header = fb.bc.GetHeaderByHash(hash)
+	}
+	if header == nil {
+		return nil, nil, errors.New("header not found")
+	}
+	statedb, err := fb.bc.StateAt(header.Root)
+	return statedb, header, err
+}
+
 func (fb *filterBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Subscription {
 	return event.NewSubscription(func(quit <-chan struct{}) error {
 		<-quit
@@ -462,6 +540,10 @@ func (fb *filterBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Su
 func (fb *filterBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return fb.bc.SubscribeRemovedLogsEvent(ch)
 }
+
+func (fb *filterBackend) SubscribeReorgEvent(ch chan<- core.ReorgEvent) event.Subscription {
+	return fb.bc.SubscribeReorgEvent(ch)
+}
 func (fb *filterBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return fb.bc.SubscribeLogsEvent(ch)
 }
@@ -0,0 +1,133 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/accounts/abi/bind/backends"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+var testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+
+// Tests that the simulator accepts transactions replay protected with the chain
+// ID of its config, and that the chain can be reverted to a snapshot.
+func TestSimulatedSnapshot(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		from   = crypto.PubkeyToAddress(testKey.PublicKey)
+		to     = common.HexToAddress("0x0000000000000000000000000000000000000aaa")
+		config = *params.TestChainConfig
+	)
+	config.ChainId = params.MainnetChainConfig.ChainId
+	sim := backends.NewSimulatedBackendWithConfig(core.GenesisAlloc{from: {Balance: big.NewInt(params.Aqua)}}, &config)
+
+	transfer := func(nonce uint64) {
+		tx := types.NewTransaction(nonce, to, big.NewInt(1), params.TxGas, big.NewInt(1), nil)
+		tx, _ = types.SignTx(tx, types.NewEIP155Signer(config.ChainId), testKey)
+		if err := sim.SendTransaction(ctx, tx); err != nil {
+			t.Fatalf("failed to send transaction %d: %v", nonce, err)
+		}
+		sim.Commit()
+	}
+	transfer(0)
+	snapshot := sim.Snapshot()
+	if snapshot != 1 {
+		t.Fatalf("snapshot mismatch: have %d, want 1", snapshot)
+	}
+	transfer(1)
+	transfer(2)
+
+	if balance, _ := sim.BalanceAt(ctx, to, nil); balance.Int64() != 3 {
+		t.Fatalf("balance mismatch: have %v, want 3", balance)
+	}
+	if err := sim.RevertToSnapshot(snapshot); err != nil {
+		t.Fatalf("failed to revert: %v", err)
+	}
+	if balance, _ := sim.BalanceAt(ctx, to, nil); balance.Int64() != 1 {
+		t.Fatalf("reverted balance mismatch: have %v, want 1", balance)
+	}
+	if nonce, _ := sim.PendingNonceAt(ctx, from); nonce != 1 {
+		t.Fatalf("reverted nonce mismatch: have %d, want 1", nonce)
+	}
+	if err := sim.RevertToSnapshot(snapshot + 1); err == nil {
+		t.Fatalf("reverted to future snapshot")
+	}
+	// The reverted chain must be extendable again
+	transfer(1)
+	if balance, _ := sim.BalanceAt(ctx, to, nil); balance.Int64() != 2 {
+		t.Fatalf("balance mismatch after revert: have %v, want 2", balance)
+	}
+}
+
+// Tests that adjusting the simulated clock shifts the time of the next block.
+func TestSimulatedAdjustTime(t *testing.T) {
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{crypto.PubkeyToAddress(testKey.PublicKey): {Balance: big.NewInt(params.Aqua)}})
+
+	sim.Commit()
+	prev, _ := sim.HeaderByNumber(context.Background(), nil)
+
+	if err := sim.AdjustTime(time.Hour); err != nil {
+		t.Fatalf("failed to adjust time: %v", err)
+	}
+	// Transactions sent after the adjustment must not undo it
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{}, new(big.Int), params.TxGas, new(big.Int), nil), types.HomesteadSigner{}, testKey)
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	sim.Commit()
+	head, _ := sim.HeaderByNumber(context.Background(), nil)
+
+	if diff := head.Time.Int64() - prev.Time.Int64(); diff < 3600 {
+		t.Fatalf("time not adjusted: have %ds between blocks, want at least 3600s", diff)
+	}
+	if head.Version != params.AllAquahashProtocolChanges.GetBlockVersion(head.Number) {
+		t.Fatalf("header version mismatch: have %d, want %d", head.Version, params.AllAquahashProtocolChanges.GetBlockVersion(head.Number))
+	}
+}
+
+// Tests that the rewards of the simulated blocks go to the set coinbase.
+func TestSimulatedCoinbase(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		coinbase = common.HexToAddress("0x0000000000000000000000000000000000000bbb")
+	)
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{})
+
+	sim.Commit()
+	sim.SetCoinbase(coinbase)
+	sim.Commit()
+	sim.Commit()
+
+	head, _ := sim.HeaderByNumber(ctx, nil)
+	if head.Coinbase != coinbase {
+		t.Fatalf("coinbase mismatch: have %x, want %x", head.Coinbase, coinbase)
+	}
+	want := new(big.Int).Mul(aquahash.BlockReward, big.NewInt(2))
+	if balance, _ := sim.BalanceAt(ctx, coinbase, nil); balance.Cmp(want) != 0 {
+		t.Fatalf("reward mismatch: have %v, want %v", balance, want)
+	}
+}
@@ -75,9 +75,11 @@ type Wallet interface {
 	// to the wallet's tracked account list.
 	Derive(path DerivationPath, pin bool) (Account, error)
 
-	// SelfDerive sets a base account derivation path from which the wallet attempts
-	// to discover non zero accounts and automatically add them to list of tracked
-	// accounts.
+	// SelfDerive sets the base account derivation paths from which the wallet
+	// attempts to discover non zero accounts and automatically add them to list
+	// of tracked accounts. The last base path is the preferred one, its next
+	// empty account is tracked too, the preceding ones are fallbacks searched
+	// for used accounts only.
 	//
 	// Note, self derivaton will increment the last component of the specified path
 	// opposed to decending into a child path to allow discovering accounts starting
@@ -85,7 +87,7 @@ type Wallet interface {
 	//
 	// You can disable automatic account discovery by calling SelfDerive with a nil
 	// chain state reader.
-	SelfDerive(base DerivationPath, chain aquachain.ChainStateReader)
+	SelfDerive(bases []DerivationPath, chain aquachain.ChainStateReader)
 
 	// SignHash requests the wallet to sign the given hash.
 	//
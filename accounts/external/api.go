@@ -0,0 +1,93 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"context"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+// SignerAPI is the interface external signers serve in the "account" RPC
+// namespace. Signers are expected to ask their operator (or a rule engine) to
+// approve every signing request, and to return an error if it's rejected.
+type SignerAPI interface {
+	// Version returns the version of the signer API implemented by the signer.
+	Version(ctx context.Context) (string, error)
+
+	// List returns the addresses of the accounts the signer manages.
+	List(ctx context.Context) ([]common.Address, error)
+
+	// SignTransaction signs the given transaction with the key of args.From.
+	SignTransaction(ctx context.Context, args SendTxArgs) (*SignTxResult, error)
+
+	// SignHash signs the given 32 byte hash with the key of addr, returning the
+	// signature in the [R || S || V] format with V being 0 or 1.
+	SignHash(ctx context.Context, addr common.Address, hash hexutil.Bytes) (hexutil.Bytes, error)
+}
+
+// SendTxArgs is the transaction to be signed by an external signer.
+type SendTxArgs struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      hexutil.Uint64  `json:"gas"`
+	GasPrice hexutil.Big     `json:"gasPrice"`
+	Value    hexutil.Big     `json:"value"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	Data     hexutil.Bytes   `json:"data"`
+	ChainID  *hexutil.Big    `json:"chainId,omitempty"` // Omitted for replayable, pre EIP-155 signatures
+}
+
+// SignTxResult is the signed transaction returned by an external signer.
+type SignTxResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+// rpcSigner is a SignerAPI served by a remote signer over RPC.
+type rpcSigner struct {
+	client *rpc.Client
+}
+
+func (s *rpcSigner) Version(ctx context.Context) (string, error) {
+	var version string
+	err := s.client.CallContext(ctx, &version, "account_version")
+	return version, err
+}
+
+func (s *rpcSigner) List(ctx context.Context) ([]common.Address, error) {
+	var addrs []common.Address
+	err := s.client.CallContext(ctx, &addrs, "account_list")
+	return addrs, err
+}
+
+func (s *rpcSigner) SignTransaction(ctx context.Context, args SendTxArgs) (*SignTxResult, error) {
+	var res SignTxResult
+	if err := s.client.CallContext(ctx, &res, "account_signTransaction", args); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *rpcSigner) SignHash(ctx context.Context, addr common.Address, hash hexutil.Bytes) (hexutil.Bytes, error) {
+	var sig hexutil.Bytes
+	err := s.client.CallContext(ctx, &sig, "account_signHash", addr, hash)
+	return sig, err
+}
@@ -0,0 +1,227 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+// pollInterval is the time between two checks of a pending signing request.
+const pollInterval = time.Second
+
+// Signing request states reported by asynchronous signers.
+const (
+	StatePending  = "pending"
+	StateSigned   = "signed"
+	StateRejected = "rejected"
+)
+
+// errRequestRejected is returned if an asynchronous signer rejects a request
+// without telling why.
+var errRequestRejected = errors.New("signing request rejected")
+
+// AsyncSignerAPI is the interface of external signers completing signatures
+// asynchronously, such as threshold (MPC) signing services collecting the
+// shares of several custody parties. It's served in the "account" namespace:
+//
+//	account_version()                        string
+//	account_list()                           []address
+//	account_submitTransaction(SendTxArgs)    id
+//	account_submitHash(address, hash)        id
+//	account_requestStatus(id)                SignStatus
+//	account_cancelRequest(id)
+//
+// Requests are submitted, then polled for until signed, rejected or timed out.
+type AsyncSignerAPI interface {
+	// Version returns the version of the signer API implemented by the signer.
+	Version(ctx context.Context) (string, error)
+
+	// List returns the addresses of the accounts the signer manages.
+	List(ctx context.Context) ([]common.Address, error)
+
+	// SubmitTransaction requests the signing of a transaction with the key of
+	// args.From, returning the identifier of the request.
+	SubmitTransaction(ctx context.Context, args SendTxArgs) (string, error)
+
+	// SubmitHash requests the signing of a 32 byte hash with the key of addr,
+	// returning the identifier of the request.
+	SubmitHash(ctx context.Context, addr common.Address, hash hexutil.Bytes) (string, error)
+
+	// RequestStatus returns the state of a signing request, along with the
+	// signature or signed transaction once it's signed.
+	RequestStatus(ctx context.Context, id string) (*SignStatus, error)
+
+	// CancelRequest withdraws a pending signing request.
+	CancelRequest(ctx context.Context, id string) error
+}
+
+// SignStatus is the state of a signing request of an asynchronous signer.
+type SignStatus struct {
+	State     string        `json:"state"`               // StatePending, StateSigned or StateRejected
+	Signature hexutil.Bytes `json:"signature,omitempty"` // Signature of a signed hash, in the [R || S || V] format
+	Raw       hexutil.Bytes `json:"raw,omitempty"`       // RLP encoding of a signed transaction
+	Error     string        `json:"error,omitempty"`     // Reason of a rejection
+}
+
+// NewAsyncBackend connects to the asynchronous external signer at the given IPC
+// path or HTTP URL, allowing it timeout to complete signing requests (the
+// DefaultRequestTimeout if zero).
+func NewAsyncBackend(endpoint string, timeout time.Duration) (*Backend, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	api := NewAsyncSigner(&rpcAsyncSigner{client}, pollInterval)
+	signer := NewSigner(api, accounts.URL{Scheme: ExternalScheme, Path: endpoint})
+	signer.SetTimeout(timeout)
+	if _, err := signer.Status(); err != nil {
+		log.Warn("External signer unreachable", "endpoint", endpoint, "err", err)
+	}
+	return &Backend{signers: []accounts.Wallet{signer}}, nil
+}
+
+// asyncSigner is a SignerAPI on top of an asynchronous signer, waiting for the
+// completion of the signing requests.
+type asyncSigner struct {
+	api  AsyncSignerAPI
+	poll time.Duration
+}
+
+// NewAsyncSigner wraps an asynchronous signer into a SignerAPI, checking the
+// submitted requests at the given interval until completed.
+func NewAsyncSigner(api AsyncSignerAPI, poll time.Duration) SignerAPI {
+	return &asyncSigner{api: api, poll: poll}
+}
+
+func (s *asyncSigner) Version(ctx context.Context) (string, error) {
+	return s.api.Version(ctx)
+}
+
+func (s *asyncSigner) List(ctx context.Context) ([]common.Address, error) {
+	return s.api.List(ctx)
+}
+
+func (s *asyncSigner) SignTransaction(ctx context.Context, args SendTxArgs) (*SignTxResult, error) {
+	id, err := s.api.SubmitTransaction(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	status, err := s.wait(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &SignTxResult{Raw: status.Raw}, nil
+}
+
+func (s *asyncSigner) SignHash(ctx context.Context, addr common.Address, hash hexutil.Bytes) (hexutil.Bytes, error) {
+	id, err := s.api.SubmitHash(ctx, addr, hash)
+	if err != nil {
+		return nil, err
+	}
+	status, err := s.wait(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return status.Signature, nil
+}
+
+// wait polls a signing request until it's completed. If the context expires
+// first, the request is cancelled so that it can't be signed unnoticed later.
+func (s *asyncSigner) wait(ctx context.Context, id string) (*SignStatus, error) {
+	ticker := time.NewTicker(s.poll)
+	defer ticker.Stop()
+
+	for {
+		status, err := s.api.RequestStatus(ctx, id)
+		if err == nil {
+			switch status.State {
+			case StateSigned:
+				return status, nil
+			case StateRejected:
+				if status.Error != "" {
+					return nil, fmt.Errorf("signing request rejected: %s", status.Error)
+				}
+				return nil, errRequestRejected
+			case StatePending:
+			default:
+				return nil, fmt.Errorf("unknown signing request state %q", status.State)
+			}
+		} else if ctx.Err() == nil {
+			log.Debug("Failed to check signing request", "id", id, "err", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			cctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := s.api.CancelRequest(cctx, id); err != nil {
+				log.Warn("Failed to cancel timed out signing request", "id", id, "err", err)
+			}
+			return nil, fmt.Errorf("signing request %s not completed: %v", id, ctx.Err())
+		}
+	}
+}
+
+// rpcAsyncSigner is an AsyncSignerAPI served by a remote signer over RPC.
+type rpcAsyncSigner struct {
+	client *rpc.Client
+}
+
+func (s *rpcAsyncSigner) Version(ctx context.Context) (string, error) {
+	var version string
+	err := s.client.CallContext(ctx, &version, "account_version")
+	return version, err
+}
+
+func (s *rpcAsyncSigner) List(ctx context.Context) ([]common.Address, error) {
+	var addrs []common.Address
+	err := s.client.CallContext(ctx, &addrs, "account_list")
+	return addrs, err
+}
+
+func (s *rpcAsyncSigner) SubmitTransaction(ctx context.Context, args SendTxArgs) (string, error) {
+	var id string
+	err := s.client.CallContext(ctx, &id, "account_submitTransaction", args)
+	return id, err
+}
+
+func (s *rpcAsyncSigner) SubmitHash(ctx context.Context, addr common.Address, hash hexutil.Bytes) (string, error) {
+	var id string
+	err := s.client.CallContext(ctx, &id, "account_submitHash", addr, hash)
+	return id, err
+}
+
+func (s *rpcAsyncSigner) RequestStatus(ctx context.Context, id string) (*SignStatus, error) {
+	var status SignStatus
+	if err := s.client.CallContext(ctx, &status, "account_requestStatus", id); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (s *rpcAsyncSigner) CancelRequest(ctx context.Context, id string) error {
+	return s.client.CallContext(ctx, nil, "account_cancelRequest", id)
+}
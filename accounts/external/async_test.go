@@ -0,0 +1,177 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+// FakeAsyncSigner is a minimal asynchronous signer, completing the requests
+// once polled a number of times, as if waiting for the shares of the parties.
+type FakeAsyncSigner struct {
+	signer *FakeSigner
+	polls  int  // Number of status checks before a request completes
+	stall  bool // Whether requests never complete
+
+	requests  map[string]*fakeRequest
+	cancelled []string
+	lock      sync.Mutex
+}
+
+type fakeRequest struct {
+	polls  int
+	status *SignStatus
+}
+
+func (s *FakeAsyncSigner) Version(ctx context.Context) (string, error) { return "1.0.0", nil }
+
+func (s *FakeAsyncSigner) List(ctx context.Context) ([]common.Address, error) {
+	return s.signer.List(ctx)
+}
+
+func (s *FakeAsyncSigner) SubmitTransaction(ctx context.Context, args SendTxArgs) (string, error) {
+	status := &SignStatus{State: StateSigned}
+	if res, err := s.signer.SignTransaction(ctx, args); err != nil {
+		status = &SignStatus{State: StateRejected, Error: err.Error()}
+	} else {
+		status.Raw = res.Raw
+	}
+	return s.submit(status), nil
+}
+
+func (s *FakeAsyncSigner) SubmitHash(ctx context.Context, addr common.Address, hash hexutil.Bytes) (string, error) {
+	sig, err := s.signer.SignHash(ctx, addr, hash)
+	if err != nil {
+		return "", err
+	}
+	return s.submit(&SignStatus{State: StateSigned, Signature: sig}), nil
+}
+
+func (s *FakeAsyncSigner) submit(status *SignStatus) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	id := fmt.Sprintf("req-%d", len(s.requests))
+	s.requests[id] = &fakeRequest{status: status}
+	return id
+}
+
+func (s *FakeAsyncSigner) RequestStatus(ctx context.Context, id string) (*SignStatus, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	req, ok := s.requests[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown request %s", id)
+	}
+	if req.polls++; s.stall || req.polls <= s.polls {
+		return &SignStatus{State: StatePending}, nil
+	}
+	return req.status, nil
+}
+
+func (s *FakeAsyncSigner) CancelRequest(ctx context.Context, id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cancelled = append(s.cancelled, id)
+	return nil
+}
+
+// newTestAsyncAPI serves a test asynchronous signer over an in-process RPC
+// connection.
+func newTestAsyncAPI(t *testing.T, signer *FakeAsyncSigner) SignerAPI {
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", signer); err != nil {
+		t.Fatal(err)
+	}
+	return NewAsyncSigner(&rpcAsyncSigner{rpc.DialInProc(server)}, time.Millisecond)
+}
+
+func TestAsyncSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := &FakeAsyncSigner{
+		signer:   &FakeSigner{key: key},
+		polls:    3,
+		requests: make(map[string]*fakeRequest),
+	}
+	wallet := NewSigner(newTestAsyncAPI(t, signer), accounts.URL{Scheme: ExternalScheme, Path: "inproc"})
+	account := accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+
+	// Sign a hash and a transaction, both completed after a few checks
+	hash := crypto.Keccak256([]byte("aquachain"))
+	sig, err := wallet.SignHash(account, hash)
+	if err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+	if pub, err := crypto.SigToPub(hash, sig); err != nil || crypto.PubkeyToAddress(*pub) != account.Address {
+		t.Fatalf("hash signer mismatch: have %v (%v), want %x", pub, err, account.Address)
+	}
+	chainID := big.NewInt(61717561)
+	tx := types.NewTransaction(3, common.Address{0xaa}, big.NewInt(1), 21000, big.NewInt(1), nil)
+
+	signed, err := wallet.SignTx(account, tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if from, _ := types.Sender(types.NewEIP155Signer(chainID), signed); from != account.Address {
+		t.Fatalf("transaction signer mismatch: have %x, want %x", from, account.Address)
+	}
+	for id, req := range signer.requests {
+		if req.polls != signer.polls+1 {
+			t.Errorf("request %s: checks mismatch: have %d, want %d", id, req.polls, signer.polls+1)
+		}
+	}
+	// Rejections are reported with their reason
+	signer.signer.reject = true
+	if _, err := wallet.SignTx(account, tx, chainID); err == nil || err.Error() != "signing request rejected: request denied" {
+		t.Fatalf("rejection error mismatch: have %v", err)
+	}
+}
+
+// Tests that requests not completed in time are cancelled.
+func TestAsyncSignerTimeout(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := &FakeAsyncSigner{
+		signer:   &FakeSigner{key: key},
+		stall:    true,
+		requests: make(map[string]*fakeRequest),
+	}
+	api := newTestAsyncAPI(t, signer)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := api.SignHash(ctx, crypto.PubkeyToAddress(key.PublicKey), crypto.Keccak256(nil)); err == nil {
+		t.Fatalf("stalled request completed")
+	}
+	if len(signer.cancelled) != 1 || signer.cancelled[0] != "req-0" {
+		t.Fatalf("cancelled requests mismatch: have %v, want [req-0]", signer.cancelled)
+	}
+}
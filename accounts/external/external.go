@@ -0,0 +1,277 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package external implements an account backend delegating all signing to an
+// external signer process, so that keys never enter the node process.
+//
+// The signer is reached over IPC or HTTP and serves the SignerAPI in the
+// "account" namespace, or over gRPC (grpc://host:port endpoints) as the Signer
+// service of signer.proto:
+//
+//	account_version()                    string
+//	account_list()                       []address
+//	account_signTransaction(SendTxArgs)  {raw, tx}
+//	account_signHash(address, hash)      signature
+//
+// Approval of the individual requests is up to the signer. Signers completing
+// the signatures asynchronously, such as threshold (MPC) signing services, serve
+// the AsyncSignerAPI instead.
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain"
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/rlp"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+// ExternalScheme is the protocol scheme prefixing account and wallet URLs.
+const ExternalScheme = "extapi"
+
+// DefaultRequestTimeout is the default time allowed for the signer to answer a
+// signing request. Signing requests may wait for manual approval, so this is
+// deliberately generous.
+const DefaultRequestTimeout = 5 * time.Minute
+
+// errSignerMismatch is returned if the signer returns a transaction different
+// from the one requested, or signed by a different account.
+var errSignerMismatch = errors.New("external signer returned a mismatching transaction")
+
+// Backend is an accounts.Backend for a single external signer.
+type Backend struct {
+	signers []accounts.Wallet
+}
+
+// NewBackend connects to the external signer at the given IPC path, HTTP URL
+// or gRPC endpoint, allowing it timeout to answer signing requests (the
+// DefaultRequestTimeout if zero).
+func NewBackend(endpoint string, timeout time.Duration) (*Backend, error) {
+	var api SignerAPI
+	if strings.HasPrefix(endpoint, GRPCScheme) {
+		grpc, err := DialGRPC(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		api = grpc
+	} else {
+		client, err := rpc.Dial(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		api = &rpcSigner{client}
+	}
+	signer := NewSigner(api, accounts.URL{Scheme: ExternalScheme, Path: endpoint})
+	signer.SetTimeout(timeout)
+	if _, err := signer.Status(); err != nil {
+		log.Warn("External signer unreachable", "endpoint", endpoint, "err", err)
+	}
+	return &Backend{signers: []accounts.Wallet{signer}}, nil
+}
+
+// Wallets implements accounts.Backend, returning the external signer.
+func (b *Backend) Wallets() []accounts.Wallet {
+	return b.signers
+}
+
+// Subscribe implements accounts.Backend. The wallet list of an external backend
+// never changes, so no events are ever sent.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// Signer is an accounts.Wallet forwarding all requests to an external signer.
+type Signer struct {
+	api     SignerAPI
+	url     accounts.URL
+	timeout time.Duration // Time allowed for the signer to answer signing requests
+
+	cache []accounts.Account // Accounts last listed by the signer
+	lock  sync.Mutex
+}
+
+// NewSigner creates a wallet on top of the given signer API.
+func NewSigner(api SignerAPI, url accounts.URL) *Signer {
+	return &Signer{api: api, url: url, timeout: DefaultRequestTimeout}
+}
+
+// SetTimeout sets the time allowed for the signer to answer signing requests,
+// resetting it to the DefaultRequestTimeout if zero.
+func (s *Signer) SetTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultRequestTimeout
+	}
+	s.timeout = timeout
+}
+
+// URL implements accounts.Wallet, returning the endpoint of the signer.
+func (s *Signer) URL() accounts.URL {
+	return s.url
+}
+
+// Status implements accounts.Wallet, returning the version of the signer.
+func (s *Signer) Status() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	version, err := s.api.Version(ctx)
+	if err != nil {
+		return "Failed", err
+	}
+	return fmt.Sprintf("ok [version=%v]", version), nil
+}
+
+// Open implements accounts.Wallet. The signer needs no opening, the connection
+// is established on demand.
+func (s *Signer) Open(passphrase string) error {
+	return nil
+}
+
+// Close implements accounts.Wallet.
+func (s *Signer) Close() error {
+	return nil
+}
+
+// Accounts implements accounts.Wallet, retrieving the accounts of the signer.
+// If the signer is unreachable, the last known accounts are returned.
+func (s *Signer) Accounts() []accounts.Account {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	addrs, err := s.api.List(ctx)
+	if err != nil {
+		log.Warn("Failed to list external signer accounts", "url", s.url, "err", err)
+		return s.cache
+	}
+	s.cache = make([]accounts.Account, len(addrs))
+	for i, addr := range addrs {
+		s.cache[i] = accounts.Account{Address: addr, URL: s.url}
+	}
+	return s.cache
+}
+
+// Contains implements accounts.Wallet, returning whether the signer manages the
+// given account.
+func (s *Signer) Contains(account accounts.Account) bool {
+	if account.URL != (accounts.URL{}) && account.URL != s.url {
+		return false
+	}
+	for _, acc := range s.Accounts() {
+		if acc.Address == account.Address {
+			return true
+		}
+	}
+	return false
+}
+
+// Derive implements accounts.Wallet, but is not supported by external signers.
+func (s *Signer) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop for external signers.
+func (s *Signer) SelfDerive(bases []accounts.DerivationPath, chain aquachain.ChainStateReader) {}
+
+// SignHash implements accounts.Wallet, requesting the signer to sign the hash.
+func (s *Signer) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	sig, err := s.api.SignHash(ctx, account.Address, hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("invalid signature length %d from external signer", len(sig))
+	}
+	return sig, nil
+}
+
+// SignTx implements accounts.Wallet, requesting the signer to sign the
+// transaction. The returned transaction is checked to be the requested one,
+// signed by the requested account.
+func (s *Signer) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	// The signing request only carries the fields of legacy transactions
+	if tx.Type() != types.LegacyTxType {
+		return nil, types.ErrTxTypeNotSupported
+	}
+	args := SendTxArgs{
+		From:     account.Address,
+		To:       tx.To(),
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: hexutil.Big(*tx.GasPrice()),
+		Value:    hexutil.Big(*tx.Value()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Data:     tx.Data(),
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if chainID != nil {
+		args.ChainID = (*hexutil.Big)(chainID)
+		signer = types.NewFeeMarketSigner(chainID)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	res, err := s.api.SignTransaction(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	signed := res.Tx
+	if len(res.Raw) > 0 {
+		signed = new(types.Transaction)
+		if err := rlp.DecodeBytes(res.Raw, signed); err != nil {
+			return nil, err
+		}
+	}
+	// The signing hash covers all fields but the signature, so matching it
+	// ensures the signer didn't alter the transaction
+	if signed == nil || signer.Hash(signed) != signer.Hash(tx) {
+		return nil, errSignerMismatch
+	}
+	if from, err := types.Sender(signer, signed); err != nil || from != account.Address {
+		return nil, errSignerMismatch
+	}
+	return signed, nil
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, but passphrases are not
+// supported by external signers, which handle authentication themselves.
+func (s *Signer) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, but passphrases are not
+// supported by external signers, which handle authentication themselves.
+func (s *Signer) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, accounts.ErrNotSupported
+}
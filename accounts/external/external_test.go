@@ -0,0 +1,204 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/rlp"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+// FakeSigner is a minimal external signer holding a single key. If tamper is
+// set, it bumps the nonce of the transactions it signs. If stall is set, it
+// waits for the request to be abandoned before signing a hash.
+type FakeSigner struct {
+	key    *ecdsa.PrivateKey
+	tamper bool
+	reject bool
+	stall  bool
+}
+
+func (s *FakeSigner) Version(ctx context.Context) (string, error) { return "1.0.0", nil }
+
+func (s *FakeSigner) List(ctx context.Context) ([]common.Address, error) {
+	return []common.Address{crypto.PubkeyToAddress(s.key.PublicKey)}, nil
+}
+
+func (s *FakeSigner) SignTransaction(ctx context.Context, args SendTxArgs) (*SignTxResult, error) {
+	if s.reject {
+		return nil, errors.New("request denied")
+	}
+	nonce := uint64(args.Nonce)
+	if s.tamper {
+		nonce++
+	}
+	tx := types.NewTransaction(nonce, *args.To, (*big.Int)(&args.Value), uint64(args.Gas), (*big.Int)(&args.GasPrice), args.Data)
+	signed, err := types.SignTx(tx, types.NewEIP155Signer((*big.Int)(args.ChainID)), s.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		return nil, err
+	}
+	return &SignTxResult{Raw: raw, Tx: signed}, nil
+}
+
+func (s *FakeSigner) SignHash(ctx context.Context, addr common.Address, hash hexutil.Bytes) (hexutil.Bytes, error) {
+	if s.stall {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return crypto.Sign(hash, s.key)
+}
+
+// newTestWallet serves a test signer over an in-process RPC connection.
+func newTestWallet(t *testing.T, signer *FakeSigner) *Signer {
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", signer); err != nil {
+		t.Fatal(err)
+	}
+	return NewSigner(&rpcSigner{rpc.DialInProc(server)}, accounts.URL{Scheme: ExternalScheme, Path: "inproc"})
+}
+
+func TestExternalSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := &FakeSigner{key: key}
+	wallet := newTestWallet(t, signer)
+
+	account := accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+	if accs := wallet.Accounts(); len(accs) != 1 || accs[0].Address != account.Address {
+		t.Fatalf("account mismatch: have %v, want %x", accs, account.Address)
+	}
+	if !wallet.Contains(account) {
+		t.Fatalf("wallet doesn't contain %x", account.Address)
+	}
+	if wallet.Contains(accounts.Account{Address: common.Address{1}}) {
+		t.Fatalf("wallet contains unknown account")
+	}
+	// Sign a hash and check the recovered signer
+	hash := crypto.Keccak256([]byte("aquachain"))
+	sig, err := wallet.SignHash(account, hash)
+	if err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil || crypto.PubkeyToAddress(*pub) != account.Address {
+		t.Fatalf("hash signer mismatch: have %v (%v), want %x", pub, err, account.Address)
+	}
+	// Sign a transaction, then ensure tampering and rejections are caught
+	chainID := big.NewInt(61717561)
+	tx := types.NewTransaction(3, common.Address{0xaa}, big.NewInt(1), 21000, big.NewInt(1), nil)
+
+	signed, err := wallet.SignTx(account, tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if from, _ := types.Sender(types.NewEIP155Signer(chainID), signed); from != account.Address {
+		t.Fatalf("transaction signer mismatch: have %x, want %x", from, account.Address)
+	}
+	signer.tamper = true
+	if _, err := wallet.SignTx(account, tx, chainID); err != errSignerMismatch {
+		t.Fatalf("tampered transaction error mismatch: have %v, want %v", err, errSignerMismatch)
+	}
+	signer.tamper, signer.reject = false, true
+	if _, err := wallet.SignTx(account, tx, chainID); err == nil {
+		t.Fatalf("rejected transaction returned no error")
+	}
+	if _, err := wallet.SignTxWithPassphrase(account, "", tx, chainID); err != accounts.ErrNotSupported {
+		t.Fatalf("passphrase signing error mismatch: have %v, want %v", err, accounts.ErrNotSupported)
+	}
+}
+
+func TestGRPCSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := &FakeSigner{key: key}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewGRPCServer(signer)
+	go server.Serve(listener)
+	defer server.Close()
+
+	backend, err := NewBackend(GRPCScheme+listener.Addr().String(), 0)
+	if err != nil {
+		t.Fatalf("failed to dial gRPC signer: %v", err)
+	}
+	wallet := backend.Wallets()[0]
+	if status, err := wallet.Status(); err != nil || status != "ok [version=1.0.0]" {
+		t.Fatalf("status mismatch: have %q (%v), want %q", status, err, "ok [version=1.0.0]")
+	}
+	account := accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+	if accs := wallet.Accounts(); len(accs) != 1 || accs[0].Address != account.Address {
+		t.Fatalf("account mismatch: have %v, want %x", accs, account.Address)
+	}
+	hash := crypto.Keccak256([]byte("aquachain"))
+	sig, err := wallet.SignHash(account, hash)
+	if err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+	if pub, err := crypto.SigToPub(hash, sig); err != nil || crypto.PubkeyToAddress(*pub) != account.Address {
+		t.Fatalf("hash signer mismatch: have %v (%v), want %x", pub, err, account.Address)
+	}
+	chainID := big.NewInt(61717561)
+	tx := types.NewTransaction(3, common.Address{0xaa}, big.NewInt(1), 21000, big.NewInt(1), []byte{0x01})
+	signed, err := wallet.SignTx(account, tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if from, _ := types.Sender(types.NewEIP155Signer(chainID), signed); from != account.Address {
+		t.Fatalf("transaction signer mismatch: have %x, want %x", from, account.Address)
+	}
+	signer.tamper = true
+	if _, err := wallet.SignTx(account, tx, chainID); err != errSignerMismatch {
+		t.Fatalf("tampered transaction error mismatch: have %v, want %v", err, errSignerMismatch)
+	}
+	signer.tamper, signer.reject = false, true
+	if _, err := wallet.SignTx(account, tx, chainID); err == nil || !strings.Contains(err.Error(), "request denied") {
+		t.Fatalf("rejection error mismatch: have %v, want %q", err, "request denied")
+	}
+}
+
+func TestSignerTimeout(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	wallet := newTestWallet(t, &FakeSigner{key: key, stall: true})
+	wallet.SetTimeout(50 * time.Millisecond)
+
+	account := accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+	start := time.Now()
+	if _, err := wallet.SignHash(account, crypto.Keccak256(nil)); err == nil {
+		t.Fatalf("stalled signer returned no error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("signing timeout mismatch: have %v, want %v", elapsed, 50*time.Millisecond)
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/rlp"
+	"github.com/golang/protobuf/proto"
+)
+
+// GRPCScheme prefixes the endpoints of signers serving the Signer service of
+// signer.proto over gRPC, instead of the "account" RPC namespace.
+const GRPCScheme = "grpc://"
+
+// grpcService is the path prefix of the methods of the Signer service.
+const grpcService = "/aquachain.signer.v1.Signer/"
+
+// gRPC status codes used by the signer service.
+const (
+	grpcOK              = 0
+	grpcUnknown         = 2
+	grpcInvalidArgument = 3
+	grpcUnimplemented   = 12
+)
+
+// maxGRPCMessage is the largest message accepted from either side of the wire.
+const maxGRPCMessage = 4 * 1024 * 1024
+
+var errGRPCFrame = errors.New("invalid gRPC message frame")
+
+// The messages of signer.proto, encoded by the proto package.
+
+type grpcVersionRequest struct{}
+
+func (m *grpcVersionRequest) Reset()         { *m = grpcVersionRequest{} }
+func (m *grpcVersionRequest) String() string { return proto.CompactTextString(m) }
+func (*grpcVersionRequest) ProtoMessage()    {}
+
+type grpcVersionReply struct {
+	Version string `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+func (m *grpcVersionReply) Reset()         { *m = grpcVersionReply{} }
+func (m *grpcVersionReply) String() string { return proto.CompactTextString(m) }
+func (*grpcVersionReply) ProtoMessage()    {}
+
+type grpcListRequest struct{}
+
+func (m *grpcListRequest) Reset()         { *m = grpcListRequest{} }
+func (m *grpcListRequest) String() string { return proto.CompactTextString(m) }
+func (*grpcListRequest) ProtoMessage()    {}
+
+type grpcListReply struct {
+	Addresses [][]byte `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`
+}
+
+func (m *grpcListReply) Reset()         { *m = grpcListReply{} }
+func (m *grpcListReply) String() string { return proto.CompactTextString(m) }
+func (*grpcListReply) ProtoMessage()    {}
+
+type grpcSignTransactionRequest struct {
+	From     []byte `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To       []byte `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Gas      uint64 `protobuf:"varint,3,opt,name=gas,proto3" json:"gas,omitempty"`
+	GasPrice []byte `protobuf:"bytes,4,opt,name=gas_price,json=gasPrice,proto3" json:"gas_price,omitempty"`
+	Value    []byte `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
+	Nonce    uint64 `protobuf:"varint,6,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Data     []byte `protobuf:"bytes,7,opt,name=data,proto3" json:"data,omitempty"`
+	ChainId  []byte `protobuf:"bytes,8,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+}
+
+func (m *grpcSignTransactionRequest) Reset()         { *m = grpcSignTransactionRequest{} }
+func (m *grpcSignTransactionRequest) String() string { return proto.CompactTextString(m) }
+func (*grpcSignTransactionRequest) ProtoMessage()    {}
+
+type grpcSignTransactionReply struct {
+	Raw []byte `protobuf:"bytes,1,opt,name=raw,proto3" json:"raw,omitempty"`
+}
+
+func (m *grpcSignTransactionReply) Reset()         { *m = grpcSignTransactionReply{} }
+func (m *grpcSignTransactionReply) String() string { return proto.CompactTextString(m) }
+func (*grpcSignTransactionReply) ProtoMessage()    {}
+
+type grpcSignHashRequest struct {
+	Address []byte `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Hash    []byte `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (m *grpcSignHashRequest) Reset()         { *m = grpcSignHashRequest{} }
+func (m *grpcSignHashRequest) String() string { return proto.CompactTextString(m) }
+func (*grpcSignHashRequest) ProtoMessage()    {}
+
+type grpcSignHashReply struct {
+	Signature []byte `protobuf:"bytes,1,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *grpcSignHashReply) Reset()         { *m = grpcSignHashReply{} }
+func (m *grpcSignHashReply) String() string { return proto.CompactTextString(m) }
+func (*grpcSignHashReply) ProtoMessage()    {}
+
+// encodeGRPCFrame prefixes the encoding of msg with the uncompressed flag and
+// its length, as messages are framed on a gRPC stream.
+func encodeGRPCFrame(msg proto.Message) ([]byte, error) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	copy(frame[5:], data)
+	return frame, nil
+}
+
+// decodeGRPCFrame decodes the single, uncompressed message framed in data.
+func decodeGRPCFrame(data []byte, msg proto.Message) error {
+	if len(data) < 5 || data[0] != 0 {
+		return errGRPCFrame
+	}
+	if size := binary.BigEndian.Uint32(data[1:]); uint64(size) != uint64(len(data)-5) {
+		return errGRPCFrame
+	}
+	return proto.Unmarshal(data[5:], msg)
+}
+
+// grpcSigner is a SignerAPI served by a remote signer over gRPC.
+type grpcSigner struct {
+	client *http.Client
+	url    string
+}
+
+// DialGRPC returns a SignerAPI calling the Signer service at the given
+// endpoint, either "grpc://host:port" or "host:port". Calls are carried over
+// plaintext HTTP/1.1, the HTTP/2 implementation isn't vendored.
+func DialGRPC(endpoint string) (SignerAPI, error) {
+	host := strings.TrimPrefix(endpoint, GRPCScheme)
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		return nil, fmt.Errorf("invalid gRPC signer endpoint %q: %v", endpoint, err)
+	}
+	return &grpcSigner{
+		client: new(http.Client),
+		url:    "http://" + host + grpcService,
+	}, nil
+}
+
+// invoke calls a method of the signer service, decoding its answer into reply.
+func (s *grpcSigner) invoke(ctx context.Context, method string, args, reply proto.Message) error {
+	frame, err := encodeGRPCFrame(args)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.url+method, bytes.NewReader(frame))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/grpc+proto")
+	req.Header.Set("TE", "trailers")
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set("Grpc-Timeout", strconv.FormatInt(int64(time.Until(deadline)/time.Millisecond)+1, 10)+"m")
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("gRPC signer returned HTTP status %s", res.Status)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxGRPCMessage+5))
+	if err != nil {
+		return err
+	}
+	// Errors are reported in the trailers, or in the headers if no message
+	// was sent at all
+	status, message := res.Trailer.Get("Grpc-Status"), res.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = res.Header.Get("Grpc-Status"), res.Header.Get("Grpc-Message")
+	}
+	if status != strconv.Itoa(grpcOK) {
+		if msg, err := url.PathUnescape(message); err == nil {
+			message = msg
+		}
+		return fmt.Errorf("gRPC signer error (code %s): %s", status, message)
+	}
+	return decodeGRPCFrame(body, reply)
+}
+
+func (s *grpcSigner) Version(ctx context.Context) (string, error) {
+	var reply grpcVersionReply
+	err := s.invoke(ctx, "Version", new(grpcVersionRequest), &reply)
+	return reply.Version, err
+}
+
+func (s *grpcSigner) List(ctx context.Context) ([]common.Address, error) {
+	var reply grpcListReply
+	if err := s.invoke(ctx, "List", new(grpcListRequest), &reply); err != nil {
+		return nil, err
+	}
+	addrs := make([]common.Address, len(reply.Addresses))
+	for i, addr := range reply.Addresses {
+		if len(addr) != common.AddressLength {
+			return nil, fmt.Errorf("invalid address length %d from external signer", len(addr))
+		}
+		addrs[i] = common.BytesToAddress(addr)
+	}
+	return addrs, nil
+}
+
+func (s *grpcSigner) SignTransaction(ctx context.Context, args SendTxArgs) (*SignTxResult, error) {
+	req := &grpcSignTransactionRequest{
+		From:     args.From.Bytes(),
+		Gas:      uint64(args.Gas),
+		GasPrice: args.GasPrice.ToInt().Bytes(),
+		Value:    args.Value.ToInt().Bytes(),
+		Nonce:    uint64(args.Nonce),
+		Data:     args.Data,
+	}
+	if args.To != nil {
+		req.To = args.To.Bytes()
+	}
+	if args.ChainID != nil {
+		req.ChainId = args.ChainID.ToInt().Bytes()
+	}
+	var reply grpcSignTransactionReply
+	if err := s.invoke(ctx, "SignTransaction", req, &reply); err != nil {
+		return nil, err
+	}
+	return &SignTxResult{Raw: reply.Raw}, nil
+}
+
+func (s *grpcSigner) SignHash(ctx context.Context, addr common.Address, hash hexutil.Bytes) (hexutil.Bytes, error) {
+	var reply grpcSignHashReply
+	if err := s.invoke(ctx, "SignHash", &grpcSignHashRequest{Address: addr.Bytes(), Hash: hash}, &reply); err != nil {
+		return nil, err
+	}
+	return reply.Signature, nil
+}
+
+// grpcServer serves a SignerAPI as the Signer service of signer.proto.
+type grpcServer struct {
+	api SignerAPI
+}
+
+// NewGRPCHandler returns an http.Handler serving api as the gRPC Signer
+// service. It is the reference server for signers written in Go.
+func NewGRPCHandler(api SignerAPI) http.Handler {
+	return &grpcServer{api: api}
+}
+
+// NewGRPCServer returns an HTTP server serving api as the gRPC Signer service.
+func NewGRPCServer(api SignerAPI) *http.Server {
+	return &http.Server{Handler: NewGRPCHandler(api)}
+}
+
+// ServeHTTP implements http.Handler, answering a single gRPC call.
+func (s *grpcServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc+proto")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	reply, code, err := s.call(r)
+	if err == nil {
+		var frame []byte
+		if frame, err = encodeGRPCFrame(reply); err == nil {
+			w.WriteHeader(http.StatusOK)
+			w.Write(frame)
+		} else {
+			code = grpcUnknown
+		}
+	}
+	if err != nil {
+		w.Header().Set("Grpc-Message", url.PathEscape(err.Error()))
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+}
+
+// call decodes the request, and forwards it to the signer.
+func (s *grpcServer) call(r *http.Request) (proto.Message, int, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxGRPCMessage+5))
+	if err != nil {
+		return nil, grpcInvalidArgument, err
+	}
+	ctx := r.Context()
+
+	switch method := strings.TrimPrefix(r.URL.Path, grpcService); method {
+	case "Version":
+		if err := decodeGRPCFrame(body, new(grpcVersionRequest)); err != nil {
+			return nil, grpcInvalidArgument, err
+		}
+		version, err := s.api.Version(ctx)
+		if err != nil {
+			return nil, grpcUnknown, err
+		}
+		return &grpcVersionReply{Version: version}, grpcOK, nil
+
+	case "List":
+		if err := decodeGRPCFrame(body, new(grpcListRequest)); err != nil {
+			return nil, grpcInvalidArgument, err
+		}
+		addrs, err := s.api.List(ctx)
+		if err != nil {
+			return nil, grpcUnknown, err
+		}
+		reply := &grpcListReply{Addresses: make([][]byte, len(addrs))}
+		for i, addr := range addrs {
+			reply.Addresses[i] = addr.Bytes()
+		}
+		return reply, grpcOK, nil
+
+	case "SignTransaction":
+		req := new(grpcSignTransactionRequest)
+		if err := decodeGRPCFrame(body, req); err != nil {
+			return nil, grpcInvalidArgument, err
+		}
+		if len(req.From) != common.AddressLength || (len(req.To) != 0 && len(req.To) != common.AddressLength) {
+			return nil, grpcInvalidArgument, errors.New("invalid address length")
+		}
+		args := SendTxArgs{
+			From:     common.BytesToAddress(req.From),
+			Gas:      hexutil.Uint64(req.Gas),
+			GasPrice: hexutil.Big(*new(big.Int).SetBytes(req.GasPrice)),
+			Value:    hexutil.Big(*new(big.Int).SetBytes(req.Value)),
+			Nonce:    hexutil.Uint64(req.Nonce),
+			Data:     req.Data,
+		}
+		if len(req.To) > 0 {
+			to := common.BytesToAddress(req.To)
+			args.To = &to
+		}
+		if len(req.ChainId) > 0 {
+			args.ChainID = (*hexutil.Big)(new(big.Int).SetBytes(req.ChainId))
+		}
+		res, err := s.api.SignTransaction(ctx, args)
+		if err != nil {
+			return nil, grpcUnknown, err
+		}
+		raw := res.Raw
+		if len(raw) == 0 && res.Tx != nil {
+			if raw, err = rlp.EncodeToBytes(res.Tx); err != nil {
+				return nil, grpcUnknown, err
+			}
+		}
+		return &grpcSignTransactionReply{Raw: raw}, grpcOK, nil
+
+	case "SignHash":
+		req := new(grpcSignHashRequest)
+		if err := decodeGRPCFrame(body, req); err != nil {
+			return nil, grpcInvalidArgument, err
+		}
+		if len(req.Address) != common.AddressLength {
+			return nil, grpcInvalidArgument, errors.New("invalid address length")
+		}
+		sig, err := s.api.SignHash(ctx, common.BytesToAddress(req.Address), req.Hash)
+		if err != nil {
+			return nil, grpcUnknown, err
+		}
+		return &grpcSignHashReply{Signature: sig}, grpcOK, nil
+
+	default:
+		return nil, grpcUnimplemented, fmt.Errorf("unknown method %q", method)
+	}
+}
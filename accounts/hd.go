@@ -39,6 +39,12 @@ var DefaultBaseDerivationPath = DerivationPath{0x80000000 + 44, 0x80000000 + 60,
 // at m/44'/60'/0'/1, etc.
 var DefaultLedgerBaseDerivationPath = DerivationPath{0x80000000 + 44, 0x80000000 + 60, 0x80000000 + 0, 0}
 
+// AquaBaseDerivationPath is the base path of the coin type registered for
+// AquaChain in SLIP-44 (61717561'). As such, the first account will be at
+// m/44'/61717561'/0'/0/0, the second at m/44'/61717561'/0'/0/1, etc. Accounts
+// derived from it don't collide with Ethereum accounts using the same seed.
+var AquaBaseDerivationPath = DerivationPath{0x80000000 + 44, 0x80000000 + 61717561, 0x80000000 + 0, 0, 0}
+
 // DerivationPath represents the computer friendly version of a hierarchical
 // deterministic wallet account derivaion path.
 //
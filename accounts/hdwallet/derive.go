@@ -0,0 +1,98 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/common/math"
+	"github.com/aquanetwork/aquachain/crypto"
+)
+
+// errInvalidChild is returned in the astronomically unlikely case of a path
+// component yielding an invalid key, in which case BIP-32 says to skip it.
+var errInvalidChild = errors.New("derived key is invalid, use the next index")
+
+// masterKeySalt is the HMAC key deriving the BIP-32 master key from a seed.
+var masterKeySalt = []byte("Bitcoin seed")
+
+// DeriveKey derives the private key at the given BIP-32 path from a seed.
+func DeriveKey(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	mac := hmac.New(sha512.New, masterKeySalt)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key, chainCode := sum[:32], sum[32:]
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	for _, index := range path {
+		var err error
+		if key, chainCode, err = deriveChild(key, chainCode, index); err != nil {
+			return nil, err
+		}
+	}
+	return crypto.ToECDSA(key)
+}
+
+// deriveChild derives the child private key and chain code with the given index,
+// hardened if the index is at least 0x80000000.
+func deriveChild(key, chainCode []byte, index uint32) ([]byte, []byte, error) {
+	var data []byte
+	if index >= 0x80000000 {
+		data = append([]byte{0}, key...)
+	} else {
+		priv, err := crypto.ToECDSA(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = crypto.CompressPubkey(&priv.PublicKey)
+	}
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[len(data)-4:], index)
+
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	if err := checkKey(sum[:32]); err != nil {
+		return nil, nil, err
+	}
+	n := crypto.S256().Params().N
+	child := new(big.Int).SetBytes(sum[:32])
+	child.Add(child, new(big.Int).SetBytes(key))
+	child.Mod(child, n)
+	if child.Sign() == 0 {
+		return nil, nil, errInvalidChild
+	}
+	return math.PaddedBigBytes(child, 32), sum[32:], nil
+}
+
+// checkKey ensures the key material is a valid secp256k1 private key.
+func checkKey(key []byte) error {
+	k := new(big.Int).SetBytes(key)
+	if k.Sign() == 0 || k.Cmp(crypto.S256().Params().N) >= 0 {
+		return errInvalidChild
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/crypto"
+)
+
+func TestWordlist(t *testing.T) {
+	if len(englishWords) != 2048 {
+		t.Fatalf("wordlist length mismatch: have %d, want 2048", len(englishWords))
+	}
+	hash := sha256.Sum256([]byte(strings.Join(englishWords, "\n") + "\n"))
+	if have := hex.EncodeToString(hash[:]); have != "2f5eed53a4727b4bf8880d8f3f199efc90e58503646d9ff8eff3a2ed3b24dbda" {
+		t.Fatalf("wordlist hash mismatch: have %s", have)
+	}
+}
+
+// Tests mnemonic encoding and seed derivation against the BIP-39 reference vectors.
+func TestMnemonic(t *testing.T) {
+	tests := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			"00000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+			"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+			"dd48c104698c30cfe2b6142103248622fb7bb0ff692eebb00089b32d22484e1613912f0a5b694407be899ffd31ed3992c456cdf60f5d4564b8ba3f05a69890ad",
+		},
+	}
+	for i, tt := range tests {
+		entropy := common.FromHex(tt.entropy)
+		mnemonic, err := EntropyToMnemonic(entropy)
+		if err != nil {
+			t.Fatalf("test %d: failed to encode entropy: %v", i, err)
+		}
+		if mnemonic != tt.mnemonic {
+			t.Errorf("test %d: mnemonic mismatch: have %q, want %q", i, mnemonic, tt.mnemonic)
+		}
+		decoded, err := MnemonicToEntropy(tt.mnemonic)
+		if err != nil || !bytes.Equal(decoded, entropy) {
+			t.Errorf("test %d: entropy mismatch: have %x (%v), want %x", i, decoded, err, entropy)
+		}
+		seed, err := NewSeed(tt.mnemonic, "TREZOR")
+		if err != nil {
+			t.Fatalf("test %d: failed to derive seed: %v", i, err)
+		}
+		if have := hex.EncodeToString(seed); have != tt.seed {
+			t.Errorf("test %d: seed mismatch: have %s, want %s", i, have, tt.seed)
+		}
+	}
+	// Ensure invalid phrases are rejected
+	for i, mnemonic := range []string{
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", // bad checksum
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon aqua",    // unknown word
+		"abandon abandon abandon about", // too short
+	} {
+		if ValidateMnemonic(mnemonic) {
+			t.Errorf("invalid mnemonic %d accepted", i)
+		}
+	}
+	// Ensure generated phrases are valid
+	mnemonic, err := NewMnemonic(DefaultEntropyBits)
+	if err != nil {
+		t.Fatalf("failed to generate mnemonic: %v", err)
+	}
+	if words := len(strings.Fields(mnemonic)); words != 24 || !ValidateMnemonic(mnemonic) {
+		t.Fatalf("invalid generated mnemonic (%d words): %q", words, mnemonic)
+	}
+}
+
+// Tests key derivation against BIP-32 test vector 1 and a well known wallet.
+func TestDeriveKey(t *testing.T) {
+	seed := common.FromHex("000102030405060708090a0b0c0d0e0f")
+	tests := []struct {
+		path string
+		key  string
+	}{
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{"m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+	for i, tt := range tests {
+		path, err := accounts.ParseDerivationPath(tt.path)
+		if err != nil {
+			t.Fatalf("test %d: invalid path %s: %v", i, tt.path, err)
+		}
+		key, err := DeriveKey(seed, path)
+		if err != nil {
+			t.Fatalf("test %d: failed to derive key: %v", i, err)
+		}
+		if have := hex.EncodeToString(crypto.FromECDSA(key)); have != tt.key {
+			t.Errorf("test %d: key mismatch at %s: have %s, want %s", i, tt.path, have, tt.key)
+		}
+	}
+	seed, _ = NewSeed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "")
+	key, err := DeriveKey(seed, accounts.DefaultBaseDerivationPath)
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+	if addr := crypto.PubkeyToAddress(key.PublicKey); addr != common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94") {
+		t.Fatalf("address mismatch: have %x", addr)
+	}
+}
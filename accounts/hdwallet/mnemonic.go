@@ -0,0 +1,134 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package hdwallet implements BIP-39 mnemonic phrases and BIP-32 key derivation,
+// allowing accounts to be backed up and restored from a single seed phrase.
+package hdwallet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/aquanetwork/aquachain/common/math"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// DefaultEntropyBits is the entropy of generated mnemonics, yielding 24 words.
+const DefaultEntropyBits = 256
+
+var (
+	// ErrInvalidMnemonic is returned if a phrase contains unknown words, has an
+	// invalid length or fails its checksum.
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+	// ErrInvalidEntropy is returned if a mnemonic is requested with an entropy
+	// size not permitted by BIP-39.
+	ErrInvalidEntropy = errors.New("entropy must be 128-256 bits, a multiple of 32")
+)
+
+// wordIndex maps each word of the wordlist to its position.
+var wordIndex = make(map[string]int, len(englishWords))
+
+func init() {
+	for i, word := range englishWords {
+		wordIndex[word] = i
+	}
+}
+
+// NewMnemonic generates a random mnemonic phrase with the given bits of entropy.
+func NewMnemonic(bits int) (string, error) {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", ErrInvalidEntropy
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+	return EntropyToMnemonic(entropy)
+}
+
+// EntropyToMnemonic encodes the entropy into a mnemonic phrase, appending the
+// BIP-39 checksum.
+func EntropyToMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", ErrInvalidEntropy
+	}
+	// Append the first bits/32 bits of the entropy hash as checksum
+	checksum := sha256.Sum256(entropy)
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, uint(bits/32))
+	data.Or(data, big.NewInt(int64(checksum[0]>>uint(8-bits/32))))
+
+	// Split the data into 11 bit word indexes, most significant first
+	words := make([]string, (bits+bits/32)/11)
+	mask := big.NewInt(2047)
+	for i := len(words) - 1; i >= 0; i-- {
+		words[i] = englishWords[new(big.Int).And(data, mask).Int64()]
+		data.Rsh(data, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy decodes a mnemonic phrase, verifying its checksum.
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, ErrInvalidMnemonic
+	}
+	data := new(big.Int)
+	for _, word := range words {
+		index, ok := wordIndex[strings.ToLower(word)]
+		if !ok {
+			return nil, fmt.Errorf("%v: unknown word %q", ErrInvalidMnemonic, word)
+		}
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+	checksumBits := uint(len(words) / 3)
+	checksum := new(big.Int).And(data, big.NewInt(1<<checksumBits-1)).Int64()
+	data.Rsh(data, checksumBits)
+
+	entropy := math.PaddedBigBytes(data, (len(words)*11-int(checksumBits))/8)
+
+	hash := sha256.Sum256(entropy)
+	if int64(hash[0]>>(8-checksumBits)) != checksum {
+		return nil, fmt.Errorf("%v: checksum mismatch", ErrInvalidMnemonic)
+	}
+	return entropy, nil
+}
+
+// ValidateMnemonic returns whether the phrase is a valid BIP-39 mnemonic.
+func ValidateMnemonic(mnemonic string) bool {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err == nil
+}
+
+// NewSeed validates the mnemonic and derives the 64 byte BIP-39 seed from it,
+// protected by an optional passphrase. The passphrase is used verbatim, without
+// Unicode normalization.
+func NewSeed(mnemonic, passphrase string) ([]byte, error) {
+	if _, err := MnemonicToEntropy(mnemonic); err != nil {
+		return nil, err
+	}
+	normalized := strings.ToLower(strings.Join(strings.Fields(mnemonic), " "))
+	return pbkdf2.Key([]byte(normalized), []byte("mnemonic"+passphrase), 2048, 64, sha512.New), nil
+}
@@ -34,6 +34,7 @@ import (
 	"time"
 
 	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/accounts/hdwallet"
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/crypto"
@@ -279,7 +280,7 @@ func (ks *KeyStore) SignTx(a accounts.Account, tx *types.Transaction, chainID *b
 	}
 	// Depending on the presence of the chain ID, sign with EIP155 or homestead
 	if chainID != nil {
-		return types.SignTx(tx, types.NewEIP155Signer(chainID), unlockedKey.PrivateKey)
+		return types.SignTx(tx, types.NewFeeMarketSigner(chainID), unlockedKey.PrivateKey)
 	}
 	return types.SignTx(tx, types.HomesteadSigner{}, unlockedKey.PrivateKey)
 }
@@ -307,7 +308,7 @@ func (ks *KeyStore) SignTxWithPassphrase(a accounts.Account, passphrase string,
 
 	// Depending on the presence of the chain ID, sign with EIP155 or homestead
 	if chainID != nil {
-		return types.SignTx(tx, types.NewEIP155Signer(chainID), key.PrivateKey)
+		return types.SignTx(tx, types.NewFeeMarketSigner(chainID), key.PrivateKey)
 	}
 	return types.SignTx(tx, types.HomesteadSigner{}, key.PrivateKey)
 }
@@ -453,6 +454,22 @@ func (ks *KeyStore) ImportECDSA(priv *ecdsa.PrivateKey, passphrase string) (acco
 	return ks.importKey(key, passphrase)
 }
 
+// ImportMnemonic derives the key at the given path from a BIP-39 mnemonic and
+// its optional seed passphrase, storing it into the key directory encrypted with
+// the passphrase.
+func (ks *KeyStore) ImportMnemonic(mnemonic, seedPassphrase string, path accounts.DerivationPath, passphrase string) (accounts.Account, error) {
+	seed, err := hdwallet.NewSeed(mnemonic, seedPassphrase)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	priv, err := hdwallet.DeriveKey(seed, path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	defer zeroKey(priv)
+	return ks.ImportECDSA(priv, passphrase)
+}
+
 func (ks *KeyStore) importKey(key *Key, passphrase string) (accounts.Account, error) {
 	a := accounts.Account{Address: key.Address, URL: accounts.URL{Scheme: KeyStoreScheme, Path: ks.storage.JoinPath(keyFileName(key.Address))}}
 	if err := ks.storage.StoreKey(a.URL.Path, key, passphrase); err != nil {
@@ -35,6 +35,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/math"
@@ -68,6 +70,31 @@ const (
 	scryptDKLen = 32
 )
 
+// kdfPresets are the named scrypt parameter presets of new keyfiles.
+var kdfPresets = map[string][2]int{
+	"light":    {LightScryptN, LightScryptP},
+	"standard": {StandardScryptN, StandardScryptP},
+}
+
+// KDFPresets returns the names of the scrypt parameter presets.
+func KDFPresets() []string {
+	names := make([]string, 0, len(kdfPresets))
+	for name := range kdfPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ScryptParams returns the N and P scrypt parameters of a named preset.
+func ScryptParams(kdf string) (scryptN, scryptP int, err error) {
+	params, ok := kdfPresets[kdf]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown kdf %q, want one of: %s", kdf, strings.Join(KDFPresets(), ", "))
+	}
+	return params[0], params[1], nil
+}
+
 type keyStorePassphrase struct {
 	keysDirPath string
 	scryptN     int
@@ -58,3 +58,16 @@ func TestKeyEncryptDecrypt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the scrypt parameter presets are resolved by name.
+func TestScryptParams(t *testing.T) {
+	if n, p, err := ScryptParams("light"); err != nil || n != LightScryptN || p != LightScryptP {
+		t.Errorf("light params mismatch: have %d, %d (%v), want %d, %d", n, p, err, LightScryptN, LightScryptP)
+	}
+	if n, p, err := ScryptParams("standard"); err != nil || n != StandardScryptN || p != StandardScryptP {
+		t.Errorf("standard params mismatch: have %d, %d (%v), want %d, %d", n, p, err, StandardScryptN, StandardScryptP)
+	}
+	if _, _, err := ScryptParams("heavy"); err == nil {
+		t.Errorf("unknown preset resolved")
+	}
+}
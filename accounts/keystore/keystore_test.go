@@ -85,6 +85,29 @@ func TestSign(t *testing.T) {
 	}
 }
 
+func TestImportMnemonic(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	acc, err := ks.ImportMnemonic(mnemonic, "", accounts.DefaultBaseDerivationPath, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94"); acc.Address != want {
+		t.Fatalf("address mismatch: have %x, want %x", acc.Address, want)
+	}
+	if err := ks.Unlock(acc, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.ImportMnemonic(mnemonic, "", accounts.DefaultBaseDerivationPath, "foo"); err == nil {
+		t.Fatal("imported the same account twice")
+	}
+	if _, err := ks.ImportMnemonic("abandon abandon abandon", "", accounts.DefaultBaseDerivationPath, "foo"); err == nil {
+		t.Fatal("imported an invalid mnemonic")
+	}
+}
+
 func TestSignWithPassphrase(t *testing.T) {
 	dir, ks := tmpKeyStore(t, true)
 	defer os.RemoveAll(dir)
@@ -0,0 +1,187 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aquanetwork/aquachain/accounts"
+)
+
+// keyField is the field of a secret holding the hex encoded private key.
+const keyField = "key"
+
+// SecretStore is a remote store of private keys, indexed by name.
+type SecretStore interface {
+	// List returns the names of the secrets in the store.
+	List(ctx context.Context) ([]string, error)
+
+	// Get returns the private key of the named secret, or
+	// accounts.ErrUnknownAccount if there's none.
+	Get(ctx context.Context, name string) ([]byte, error)
+
+	// Put stores a private key as the named secret.
+	Put(ctx context.Context, name string, key []byte) error
+}
+
+// VaultStore is a SecretStore backed by a HashiCorp Vault KV version 2 secrets
+// engine, reached over its HTTP API.
+type VaultStore struct {
+	addr   string // Address of the vault server, scheme and host
+	mount  string // Mount point of the KV secrets engine
+	path   string // Path of the secrets within the engine
+	token  string
+	client *http.Client
+}
+
+// NewVaultStore creates a store of the secrets under the given URL, of the form
+// http(s)://host:port/<mount>/<path>. If token is empty, it's taken from the
+// VAULT_TOKEN environment variable.
+func NewVaultStore(rawurl, token string) (*VaultStore, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid vault URL %q, http(s) required", rawurl)
+	}
+	parts := strings.SplitN(strings.Trim(u.Path, "/"), "/", 2)
+	if parts[0] == "" {
+		return nil, fmt.Errorf("invalid vault URL %q, secrets engine mount missing", rawurl)
+	}
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if token == "" {
+		return nil, errors.New("vault token missing, set VAULT_TOKEN")
+	}
+	store := &VaultStore{
+		addr:   u.Scheme + "://" + u.Host,
+		mount:  parts[0],
+		token:  token,
+		client: new(http.Client),
+	}
+	if len(parts) > 1 {
+		store.path = parts[1]
+	}
+	return store, nil
+}
+
+// Location returns the host and path of the secrets, for display.
+func (s *VaultStore) Location() string {
+	return strings.TrimPrefix(strings.TrimPrefix(s.addr, "http://"), "https://") + "/" + strings.Trim(s.mount+"/"+s.path, "/")
+}
+
+// List implements SecretStore.
+func (s *VaultStore) List(ctx context.Context) ([]string, error) {
+	var res struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	err := s.do(ctx, "LIST", s.endpoint("metadata", ""), nil, &res)
+	if err == accounts.ErrUnknownAccount {
+		return nil, nil // Nothing stored yet
+	}
+	return res.Data.Keys, err
+}
+
+// Get implements SecretStore.
+func (s *VaultStore) Get(ctx context.Context, name string) ([]byte, error) {
+	var res struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := s.do(ctx, "GET", s.endpoint("data", name), nil, &res); err != nil {
+		return nil, err
+	}
+	encoded, ok := res.Data.Data[keyField]
+	if !ok {
+		return nil, fmt.Errorf("secret %q has no %q field", name, keyField)
+	}
+	return hex.DecodeString(strings.TrimPrefix(encoded, "0x"))
+}
+
+// Put implements SecretStore.
+func (s *VaultStore) Put(ctx context.Context, name string, key []byte) error {
+	body := map[string]interface{}{
+		"data": map[string]string{keyField: hex.EncodeToString(key)},
+	}
+	return s.do(ctx, "POST", s.endpoint("data", name), body, nil)
+}
+
+// endpoint returns the API URL of a secret (or of the secrets if name is empty)
+// within the given section of the KV engine.
+func (s *VaultStore) endpoint(section, name string) string {
+	path := strings.Trim(s.path+"/"+name, "/")
+	return s.addr + "/v1/" + s.mount + "/" + section + "/" + path
+}
+
+// do sends a request to the vault API, decoding the response into result if set.
+func (s *VaultStore) do(ctx context.Context, method, endpoint string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		blob, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(blob)
+	}
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("X-Vault-Token", s.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return accounts.ErrUnknownAccount
+	case resp.StatusCode >= 300:
+		var res struct {
+			Errors []string `json:"errors"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&res); err == nil && len(res.Errors) > 0 {
+			return fmt.Errorf("vault: %s", strings.Join(res.Errors, ", "))
+		}
+		return fmt.Errorf("vault: %s", resp.Status)
+	case result == nil || resp.StatusCode == http.StatusNoContent:
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,292 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package vault implements an account backend keeping the private keys in a
+// remote secret store, such as a HashiCorp Vault KV secrets engine, so that they
+// are never written to the local disk.
+//
+// Every key is a secret named after the hex address of its account, holding the
+// hex encoded private key in its "key" field. Keys are fetched for every signing
+// request and wiped from memory right after.
+//
+// The wallet refuses to sign until it's explicitly opened, and closes itself
+// again once its unlock timeout expires.
+package vault
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain"
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/log"
+)
+
+// VaultScheme is the protocol scheme prefixing account and wallet URLs.
+const VaultScheme = "vault"
+
+// requestTimeout is the time allowed for the secret store to answer a request.
+const requestTimeout = 10 * time.Second
+
+// DefaultUnlockTimeout is the time an opened wallet signs for before closing.
+const DefaultUnlockTimeout = 5 * time.Minute
+
+// Backend is an accounts.Backend for a single remote secret store.
+type Backend struct {
+	wallets []accounts.Wallet
+}
+
+// NewBackend creates a backend on top of the Vault KV version 2 secrets engine
+// at the given URL, of the form http(s)://host:port/<mount>/<path>. If token is
+// empty, it's taken from the VAULT_TOKEN environment variable. Once opened, the
+// wallet signs for the unlock timeout, DefaultUnlockTimeout if zero.
+func NewBackend(rawurl, token string, unlock time.Duration) (*Backend, error) {
+	store, err := NewVaultStore(rawurl, token)
+	if err != nil {
+		return nil, err
+	}
+	wallet := NewWallet(store, accounts.URL{Scheme: VaultScheme, Path: store.Location()}, unlock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if _, err := store.List(ctx); err != nil {
+		log.Warn("Remote key vault unreachable", "url", wallet.url, "err", err)
+	}
+	return &Backend{wallets: []accounts.Wallet{wallet}}, nil
+}
+
+// Wallets implements accounts.Backend, returning the remote vault.
+func (b *Backend) Wallets() []accounts.Wallet {
+	return b.wallets
+}
+
+// Subscribe implements accounts.Backend. The wallet list of a vault backend
+// never changes, so no events are ever sent.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// Wallet is an accounts.Wallet signing with the keys of a remote secret store.
+type Wallet struct {
+	store SecretStore
+	url   accounts.URL
+
+	cache  []accounts.Account // Accounts last listed by the store
+	unlock time.Duration      // Time the wallet signs for once opened
+	expiry time.Time          // Time the wallet closes at, zero if closed
+	lock   sync.Mutex
+}
+
+// NewWallet creates a closed wallet on top of the given secret store, signing
+// for the unlock timeout once opened.
+func NewWallet(store SecretStore, url accounts.URL, unlock time.Duration) *Wallet {
+	if unlock == 0 {
+		unlock = DefaultUnlockTimeout
+	}
+	return &Wallet{store: store, url: url, unlock: unlock}
+}
+
+// URL implements accounts.Wallet, returning the location of the secret store.
+func (w *Wallet) URL() accounts.URL {
+	return w.url
+}
+
+// Status implements accounts.Wallet, returning whether the wallet is open and
+// the store reachable.
+func (w *Wallet) Status() (string, error) {
+	if !w.opened() {
+		return "Closed", accounts.ErrWalletClosed
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	names, err := w.store.List(ctx)
+	if err != nil {
+		return "Failed", err
+	}
+	return fmt.Sprintf("ok [secrets=%d]", len(names)), nil
+}
+
+// Open implements accounts.Wallet, allowing the wallet to sign until its unlock
+// timeout expires. The passphrase is unused, access to the store is granted by
+// its token. Reopening an open wallet extends the timeout.
+func (w *Wallet) Open(passphrase string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	if _, err := w.store.List(ctx); err != nil {
+		return err
+	}
+	w.lock.Lock()
+	w.expiry = time.Now().Add(w.unlock)
+	w.lock.Unlock()
+
+	log.Info("Opened remote key vault", "url", w.url, "timeout", w.unlock)
+	return nil
+}
+
+// Close implements accounts.Wallet, refusing any further signing requests until
+// the wallet is opened again.
+func (w *Wallet) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.expiry = time.Time{}
+	return nil
+}
+
+// opened reports whether the wallet is open and its unlock timeout not expired.
+func (w *Wallet) opened() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return time.Now().Before(w.expiry)
+}
+
+// Accounts implements accounts.Wallet, listing the keys of the store. Secrets
+// not named after an address are skipped. If the store is unreachable, the
+// last known accounts are returned.
+func (w *Wallet) Accounts() []accounts.Account {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	names, err := w.store.List(ctx)
+	if err != nil {
+		log.Warn("Failed to list remote vault accounts", "url", w.url, "err", err)
+		return w.cache
+	}
+	w.cache = w.cache[:0]
+	for _, name := range names {
+		if !common.IsHexAddress(name) {
+			continue
+		}
+		addr := common.HexToAddress(name)
+		w.cache = append(w.cache, accounts.Account{
+			Address: addr,
+			URL:     accounts.URL{Scheme: VaultScheme, Path: w.url.Path + "/" + addr.Hex()},
+		})
+	}
+	return w.cache
+}
+
+// Contains implements accounts.Wallet, returning whether the store holds the
+// key of the given account.
+func (w *Wallet) Contains(account accounts.Account) bool {
+	for _, acc := range w.Accounts() {
+		if acc.Address == account.Address && (account.URL == (accounts.URL{}) || account.URL == acc.URL) {
+			return true
+		}
+	}
+	return false
+}
+
+// Derive implements accounts.Wallet, but is not supported by remote vaults.
+func (w *Wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop for remote vaults.
+func (w *Wallet) SelfDerive(bases []accounts.DerivationPath, chain aquachain.ChainStateReader) {}
+
+// SignHash implements accounts.Wallet, signing the hash with the key of the
+// account fetched from the store if the wallet is open.
+func (w *Wallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	if !w.opened() {
+		return nil, accounts.ErrWalletClosed
+	}
+	key, err := w.fetchKey(account.Address)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return crypto.Sign(hash, key)
+}
+
+// SignTx implements accounts.Wallet, signing the transaction with the key of
+// the account fetched from the store if the wallet is open.
+func (w *Wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if !w.opened() {
+		return nil, accounts.ErrWalletClosed
+	}
+	key, err := w.fetchKey(account.Address)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+
+	if chainID != nil {
+		return types.SignTx(tx, types.NewFeeMarketSigner(chainID), key)
+	}
+	return types.SignTx(tx, types.HomesteadSigner{}, key)
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, but passphrases are not
+// supported by remote vaults, access is granted by the store's token.
+func (w *Wallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, but passphrases are not
+// supported by remote vaults, access is granted by the store's token.
+func (w *Wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// fetchKey retrieves the private key of an account from the store, checking it
+// matches the address it's filed under.
+func (w *Wallet) fetchKey(addr common.Address) (*ecdsa.PrivateKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	secret, err := w.store.Get(ctx, addr.Hex())
+	if err != nil {
+		return nil, err
+	}
+	key, err := crypto.ToECDSA(secret)
+	for i := range secret {
+		secret[i] = 0
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid key of account %x: %v", addr, err)
+	}
+	if crypto.PubkeyToAddress(key.PublicKey) != addr {
+		zeroKey(key)
+		return nil, fmt.Errorf("key of account %x belongs to a different account", addr)
+	}
+	return key, nil
+}
+
+// zeroKey zeroes a private key in memory.
+func zeroKey(k *ecdsa.PrivateKey) {
+	b := k.D.Bits()
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+)
+
+const testToken = "s.testtoken"
+
+// fakeVault is a minimal HashiCorp Vault KV version 2 secrets engine mounted at
+// "secret".
+type fakeVault struct {
+	secrets map[string]map[string]string
+	lock    sync.Mutex
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if r.Header.Get("X-Vault-Token") != testToken {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string][]string{"errors": {"permission denied"}})
+		return
+	}
+	switch {
+	case r.Method == "LIST" && strings.HasPrefix(r.URL.Path, "/v1/secret/metadata/"):
+		prefix := strings.TrimPrefix(r.URL.Path, "/v1/secret/metadata/") + "/"
+		var keys []string
+		for name := range v.secrets {
+			if strings.HasPrefix(name, prefix) {
+				keys = append(keys, strings.TrimPrefix(name, prefix))
+			}
+		}
+		if len(keys) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string][]string{"keys": keys}})
+
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/v1/secret/data/"):
+		secret, ok := v.secrets[strings.TrimPrefix(r.URL.Path, "/v1/secret/data/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": secret}})
+
+	case r.Method == "POST" && strings.HasPrefix(r.URL.Path, "/v1/secret/data/"):
+		var req struct {
+			Data map[string]string `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		v.secrets[strings.TrimPrefix(r.URL.Path, "/v1/secret/data/")] = req.Data
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// newTestWallet starts a fake vault, returning a wallet on top of it.
+func newTestWallet(t *testing.T) (*Wallet, *fakeVault, func()) {
+	vault := &fakeVault{secrets: make(map[string]map[string]string)}
+	server := httptest.NewServer(vault)
+
+	backend, err := NewBackend(server.URL+"/secret/aquachain", testToken, 0)
+	if err != nil {
+		server.Close()
+		t.Fatalf("failed to create backend: %v", err)
+	}
+	return backend.Wallets()[0].(*Wallet), vault, server.Close
+}
+
+// Tests that the accounts are listed from the secret names, and that the keys
+// sign for them.
+func TestVaultSigning(t *testing.T) {
+	wallet, vault, stop := newTestWallet(t)
+	defer stop()
+
+	if accs := wallet.Accounts(); len(accs) != 0 {
+		t.Fatalf("accounts listed in empty vault: %v", accs)
+	}
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	if err := wallet.store.Put(context.Background(), addr.Hex(), crypto.FromECDSA(key)); err != nil {
+		t.Fatalf("failed to store key: %v", err)
+	}
+	vault.secrets["aquachain/notes"] = map[string]string{"text": "not a key"}
+
+	accs := wallet.Accounts()
+	if len(accs) != 1 || accs[0].Address != addr {
+		t.Fatalf("accounts mismatch: have %v, want [%x]", accs, addr)
+	}
+	if !wallet.Contains(accounts.Account{Address: addr}) {
+		t.Fatalf("stored account not contained")
+	}
+	// Sign a hash and a transaction with the stored key
+	hash := crypto.Keccak256([]byte("hello"))
+	if err := wallet.Open(""); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	sig, err := wallet.SignHash(accs[0], hash)
+	if err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+	if pub, err := crypto.SigToPub(hash, sig); err != nil || crypto.PubkeyToAddress(*pub) != addr {
+		t.Fatalf("hash signed by wrong key: %v", err)
+	}
+	tx := types.NewTransaction(1, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil)
+	signed, err := wallet.SignTx(accs[0], tx, big.NewInt(61717561))
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if from, err := types.Sender(types.NewEIP155Signer(big.NewInt(61717561)), signed); err != nil || from != addr {
+		t.Fatalf("transaction signed by wrong key: have %x, want %x (%v)", from, addr, err)
+	}
+	// Unknown and misfiled keys are refused
+	other, _ := crypto.GenerateKey()
+	if _, err := wallet.SignHash(accounts.Account{Address: crypto.PubkeyToAddress(other.PublicKey)}, hash); err != accounts.ErrUnknownAccount {
+		t.Fatalf("unknown account error mismatch: have %v, want %v", err, accounts.ErrUnknownAccount)
+	}
+	misfiled := common.Address{0xff}
+	wallet.store.Put(context.Background(), misfiled.Hex(), crypto.FromECDSA(other))
+	if _, err := wallet.SignHash(accounts.Account{Address: misfiled}, hash); err == nil {
+		t.Fatalf("signed with misfiled key")
+	}
+}
+
+// Tests that requests with an invalid token are refused.
+func TestVaultToken(t *testing.T) {
+	server := httptest.NewServer(&fakeVault{secrets: make(map[string]map[string]string)})
+	defer server.Close()
+
+	store, err := NewVaultStore(server.URL+"/secret/aquachain", "s.wrong")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	if _, err := store.List(context.Background()); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("error mismatch: have %v, want permission denied", err)
+	}
+	if _, err := NewVaultStore(server.URL, testToken); err == nil {
+		t.Fatalf("store created without secrets engine mount")
+	}
+}
+
+// Tests that the wallet only signs while open, and closes itself once its
+// unlock timeout expires.
+func TestVaultUnlock(t *testing.T) {
+	wallet, _, stop := newTestWallet(t)
+	defer stop()
+
+	key, _ := crypto.GenerateKey()
+	account := accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+	if err := wallet.store.Put(context.Background(), account.Address.Hex(), crypto.FromECDSA(key)); err != nil {
+		t.Fatalf("failed to store key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("hello"))
+	tx := types.NewTransaction(1, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil)
+
+	check := func(want error) {
+		t.Helper()
+		if _, err := wallet.Status(); err != want {
+			t.Errorf("status error mismatch: have %v, want %v", err, want)
+		}
+		if _, err := wallet.SignHash(account, hash); err != want {
+			t.Errorf("hash signing error mismatch: have %v, want %v", err, want)
+		}
+		if _, err := wallet.SignTx(account, tx, big.NewInt(61717561)); err != want {
+			t.Errorf("transaction signing error mismatch: have %v, want %v", err, want)
+		}
+	}
+	check(accounts.ErrWalletClosed)
+
+	if err := wallet.Open(""); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	check(nil)
+
+	wallet.Close()
+	check(accounts.ErrWalletClosed)
+
+	wallet.unlock = time.Millisecond
+	if err := wallet.Open(""); err != nil {
+		t.Fatalf("failed to reopen wallet: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	check(accounts.ErrWalletClosed)
+}
@@ -0,0 +1,104 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// errResearchOnMainnet is returned when the research options altering the
+// announcement of mined blocks are enabled on the main network.
+var errResearchOnMainnet = errors.New("block announcement delay and withholding are research options, refused on the main network")
+
+// checkAnnouncePolicy refuses the research options altering the announcement of
+// mined blocks on the main network.
+func checkAnnouncePolicy(config *Config, chainConfig *params.ChainConfig) error {
+	if config.AnnounceDelay == 0 && !config.WithholdBlocks {
+		return nil
+	}
+	mainnet := params.MainnetChainConfig.ChainId
+	if config.NetworkId == mainnet.Uint64() || (chainConfig.ChainId != nil && chainConfig.ChainId.Cmp(mainnet) == 0) {
+		return errResearchOnMainnet
+	}
+	return nil
+}
+
+// minedAnnouncer delays or withholds the broadcast of the locally mined blocks,
+// for studying block propagation and selfish mining with the real codebase.
+//
+// Withheld blocks are all released as soon as the network announces a block at
+// the height of the oldest of them, racing it with the private chain.
+type minedAnnouncer struct {
+	delay     time.Duration
+	withhold  bool
+	broadcast func(*types.Block) // Propagates and announces a block to the peers
+
+	withheld []*types.Block // Mined blocks not yet broadcast, oldest first
+	lock     sync.Mutex
+}
+
+// newMinedAnnouncer creates an announcer of mined blocks, nil if neither delaying
+// nor withholding them.
+func newMinedAnnouncer(delay time.Duration, withhold bool, broadcast func(*types.Block)) *minedAnnouncer {
+	if delay == 0 && !withhold {
+		return nil
+	}
+	log.Warn("Altering the announcement of mined blocks, for research only", "delay", common.PrettyDuration(delay), "withhold", withhold)
+	return &minedAnnouncer{
+		delay:     delay,
+		withhold:  withhold,
+		broadcast: broadcast,
+	}
+}
+
+// mined schedules the broadcast of a locally mined block.
+func (a *minedAnnouncer) mined(block *types.Block) {
+	if a.withhold {
+		a.lock.Lock()
+		a.withheld = append(a.withheld, block)
+		a.lock.Unlock()
+
+		log.Info("Withholding mined block", "number", block.Number(), "hash", block.Hash())
+		return
+	}
+	time.AfterFunc(a.delay, func() { a.broadcast(block) })
+}
+
+// announced notes a block announced by the network, releasing the withheld
+// blocks if the network caught up with the oldest of them.
+func (a *minedAnnouncer) announced(number uint64) {
+	a.lock.Lock()
+	if len(a.withheld) == 0 || number < a.withheld[0].NumberU64() {
+		a.lock.Unlock()
+		return
+	}
+	released := a.withheld
+	a.withheld = nil
+	a.lock.Unlock()
+
+	log.Info("Releasing withheld blocks", "count", len(released), "from", released[0].Number(), "competing", number)
+	for _, block := range released {
+		a.broadcast(block)
+	}
+}
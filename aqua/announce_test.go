@@ -0,0 +1,88 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that the research options are refused on the main network.
+func TestAnnouncePolicyMainnet(t *testing.T) {
+	tests := []struct {
+		config  Config
+		chainId *big.Int
+		err     error
+	}{
+		{Config{NetworkId: 1337}, params.MainnetChainConfig.ChainId, nil},
+		{Config{NetworkId: 1337, WithholdBlocks: true}, big.NewInt(1337), nil},
+		{Config{NetworkId: 1337, AnnounceDelay: time.Second}, params.MainnetChainConfig.ChainId, errResearchOnMainnet},
+		{Config{NetworkId: DefaultConfig.NetworkId, WithholdBlocks: true}, big.NewInt(1337), errResearchOnMainnet},
+	}
+	for i, tt := range tests {
+		if err := checkAnnouncePolicy(&tt.config, &params.ChainConfig{ChainId: tt.chainId}); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that mined blocks are broadcast after the delay.
+func TestAnnounceDelay(t *testing.T) {
+	if newMinedAnnouncer(0, false, nil) != nil {
+		t.Fatalf("announcer created without delay nor withholding")
+	}
+	sent := make(chan time.Time, 1)
+	announcer := newMinedAnnouncer(100*time.Millisecond, false, func(*types.Block) { sent <- time.Now() })
+
+	start := time.Now()
+	announcer.mined(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)}))
+	select {
+	case at := <-sent:
+		if at.Sub(start) < 100*time.Millisecond {
+			t.Fatalf("block broadcast too early: after %v", at.Sub(start))
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("block not broadcast")
+	}
+}
+
+// Tests that withheld blocks are all released, in order, once the network
+// catches up with the oldest of them.
+func TestAnnounceWithhold(t *testing.T) {
+	var sent []uint64
+	announcer := newMinedAnnouncer(0, true, func(block *types.Block) { sent = append(sent, block.NumberU64()) })
+
+	for number := int64(5); number <= 7; number++ {
+		announcer.mined(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number)}))
+	}
+	announcer.announced(4)
+	if len(sent) != 0 {
+		t.Fatalf("blocks released before the network caught up: %v", sent)
+	}
+	announcer.announced(5)
+	if len(sent) != 3 || sent[0] != 5 || sent[1] != 6 || sent[2] != 7 {
+		t.Fatalf("released blocks mismatch: have %v, want [5 6 7]", sent)
+	}
+	announcer.announced(8)
+	if len(sent) != 3 {
+		t.Fatalf("blocks released twice: %v", sent)
+	}
+}
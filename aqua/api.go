@@ -19,12 +19,15 @@ package aqua
 import (
 	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"os"
 	"strings"
 
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/aqua/scheduler"
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/hexutil"
 	"github.com/aquanetwork/aquachain/core"
@@ -74,6 +77,7 @@ type PublicMinerAPI struct {
 // NewPublicMinerAPI create a new PublicMinerAPI instance.
 func NewPublicMinerAPI(e *AquaChain) *PublicMinerAPI {
 	agent := miner.NewRemoteAgent(e.BlockChain(), e.Engine())
+	agent.SetStaleGrace(e.config.StaleGrace)
 	e.Miner().Register(agent)
 
 	return &PublicMinerAPI{e, agent}
@@ -85,9 +89,14 @@ func (api *PublicMinerAPI) Mining() bool {
 }
 
 // SubmitWork can be used by external miner to submit their POW solution. It returns an indication if the work was
-// accepted. Note, this is not an indication if the provided work was valid!
-func (api *PublicMinerAPI) SubmitWork(nonce types.BlockNonce, solution, digest common.Hash) bool {
-	return api.agent.SubmitWork(nonce, digest, solution)
+// accepted. Note, this is not an indication if the provided work was valid! The optional id, the same one passed to
+// SubmitHashrate, attributes the submission in the share statistics.
+func (api *PublicMinerAPI) SubmitWork(nonce types.BlockNonce, solution, digest common.Hash, id *common.Hash) bool {
+	var source common.Hash
+	if id != nil {
+		source = *id
+	}
+	return api.agent.SubmitWork(nonce, digest, solution, source)
 }
 
 // GetWork returns a work package for external miner. The work package consists of 3 strings
@@ -187,10 +196,76 @@ func (api *PrivateMinerAPI) SetGasPrice(gasPrice hexutil.Big) bool {
 	return true
 }
 
-// SetAquabase sets the aquabase of the miner
-func (api *PrivateMinerAPI) SetAquabase(aquabase common.Address) bool {
+// SetThrottle sets the fraction of the time the mining threads hash, resting
+// for the remainder and yielding to block verification.
+func (api *PrivateMinerAPI) SetThrottle(throttle float64) (bool, error) {
+	if throttle <= 0 || throttle > 1 {
+		return false, fmt.Errorf("throttle must be in (0, 1], have %v", throttle)
+	}
+	type throttled interface {
+		SetThrottle(throttle float64)
+	}
+	th, ok := api.e.engine.(throttled)
+	if !ok {
+		return false, errors.New("consensus engine can't be throttled")
+	}
+	log.Info("Updated mining throttle", "throttle", throttle)
+	th.SetThrottle(throttle)
+	return true, nil
+}
+
+// SetAquabase sets the aquabase of the miner, refusing the zero address and known
+// burn addresses unless the node was started with --miner.force-aquabase.
+func (api *PrivateMinerAPI) SetAquabase(aquabase common.Address) (bool, error) {
+	if err := CheckAquabase(aquabase, api.e.config.ForceAquabase); err != nil {
+		return false, err
+	}
 	api.e.SetAquabase(aquabase)
-	return true
+	return true, nil
+}
+
+// SetAquabases sets the addresses the miner rotates the block rewards through,
+// by block number, overriding the aquabase. An empty list stops the rotation.
+// The zero address and known burn addresses are refused unless the node was
+// started with --miner.force-aquabase.
+func (api *PrivateMinerAPI) SetAquabases(aquabases []common.Address) (bool, error) {
+	for _, aquabase := range aquabases {
+		if err := CheckAquabase(aquabase, api.e.config.ForceAquabase); err != nil {
+			return false, fmt.Errorf("%s: %v", aquabase.Hex(), err)
+		}
+	}
+	api.e.Miner().SetAquabases(aquabases)
+	return true, nil
+}
+
+// SetUncles sets the uncle inclusion mode of the miner ("always", "never" or
+// "profitable").
+func (api *PrivateMinerAPI) SetUncles(mode string) (bool, error) {
+	policy := api.e.Miner().UnclePolicy()
+	policy.Mode = mode
+	if err := api.e.Miner().SetUnclePolicy(policy); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UncleStats returns the counts of the valid uncles included in and left out of
+// the blocks mined since the node started.
+func (api *PrivateMinerAPI) UncleStats() miner.UncleStats {
+	return api.e.Miner().UncleStats()
+}
+
+// Stats returns the mining progress: the hashrates, the difficulty of the block
+// being mined and the expected time to seal it, along with the counts of the
+// sealed blocks and the last one.
+func (api *PrivateMinerAPI) Stats() miner.Stats {
+	return api.e.Miner().Stats()
+}
+
+// ShareStats returns the outcomes of the solutions submitted by remote miners,
+// accepted, stale, duplicate or invalid, in total and by miner identifier.
+func (api *PrivateMinerAPI) ShareStats() miner.ShareStats {
+	return api.e.Miner().ShareStats()
 }
 
 // GetHashrate returns the current hashrate of the miner.
@@ -198,6 +273,35 @@ func (api *PrivateMinerAPI) GetHashrate() uint64 {
 	return uint64(api.e.miner.HashRate())
 }
 
+// PrivateTxPoolAPI offers the transaction pool spam defense controls, exposed
+// over the private txpool endpoint.
+type PrivateTxPoolAPI struct {
+	pool *core.TxPool
+}
+
+// NewPrivateTxPoolAPI creates a new API definition for the private txpool
+// methods of the AquaChain service.
+func NewPrivateTxPoolAPI(pool *core.TxPool) *PrivateTxPoolAPI {
+	return &PrivateTxPoolAPI{pool: pool}
+}
+
+// Defense reports the state of the spam defense: whether it's active, the
+// escalation of the minimum gas price and the banned peers.
+func (api *PrivateTxPoolAPI) Defense() core.DefenseStatus {
+	return api.pool.DefenseStatus()
+}
+
+// Unban lifts the ban of a peer flooding the pool with malformed transactions.
+func (api *PrivateTxPoolAPI) Unban(peer string) bool {
+	return api.pool.Unban(peer)
+}
+
+// ResetDefense deactivates the spam defense and lifts all bans.
+func (api *PrivateTxPoolAPI) ResetDefense() bool {
+	api.pool.ResetDefense()
+	return true
+}
+
 // PrivateAdminAPI is the collection of AquaChain full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -357,6 +461,125 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// maxCoinbaseAudit is the maximum number of recent blocks admin_auditCoinbases
+// inspects in one call.
+const maxCoinbaseAudit = 100000
+
+// CoinbaseAudit summarises the blocks credited to a single coinbase.
+type CoinbaseAudit struct {
+	Coinbase   common.Address `json:"coinbase"`
+	Blocks     uint64         `json:"blocks"`     // Number of audited blocks mined to the coinbase
+	FirstBlock uint64         `json:"firstBlock"` // Oldest audited block mined to the coinbase
+	LastBlock  uint64         `json:"lastBlock"`  // Newest audited block mined to the coinbase
+	Local      bool           `json:"local"`      // Whether the key of the coinbase is held locally
+	Burn       bool           `json:"burn"`       // Whether the coinbase is a known burn address
+}
+
+// AuditCoinbases tallies the coinbases of the most recent canonical blocks (1024
+// by default), flagging the ones held by a local account and known burn addresses,
+// so miners can verify their rewards reach the intended address.
+func (api *PrivateAdminAPI) AuditCoinbases(count *uint64) ([]CoinbaseAudit, error) {
+	blocks := uint64(1024)
+	if count != nil {
+		blocks = *count
+	}
+	if blocks > maxCoinbaseAudit {
+		return nil, fmt.Errorf("too many blocks requested, max %d", maxCoinbaseAudit)
+	}
+	chain := api.aqua.BlockChain()
+	head := chain.CurrentHeader().Number.Uint64()
+
+	var (
+		audits []CoinbaseAudit
+		index  = make(map[common.Address]int)
+	)
+	for number := head; number+blocks > head && number > 0; number-- {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			break
+		}
+		i, ok := index[header.Coinbase]
+		if !ok {
+			i = len(audits)
+			index[header.Coinbase] = i
+			audits = append(audits, CoinbaseAudit{
+				Coinbase:  header.Coinbase,
+				LastBlock: number,
+				Local:     api.hasAccount(header.Coinbase),
+				Burn:      header.Coinbase == (common.Address{}) || IsBurnAddress(header.Coinbase),
+			})
+		}
+		audits[i].Blocks++
+		audits[i].FirstBlock = number
+	}
+	return audits, nil
+}
+
+// hasAccount reports whether any local wallet holds the address.
+func (api *PrivateAdminAPI) hasAccount(addr common.Address) bool {
+	wallet, err := api.aqua.AccountManager().Find(accounts.Account{Address: addr})
+	return wallet != nil && err == nil
+}
+
+// PruningStats reports how the node persists and garbage collects state tries.
+type PruningStats struct {
+	Mode           string `json:"mode"`           // Garbage collection mode, "full" or "archive"
+	Commits        uint64 `json:"commits"`        // State tries flushed to disk since startup
+	FlushedNodes   uint64 `json:"flushedNodes"`   // Trie nodes flushed to disk since startup
+	FlushedSize    uint64 `json:"flushedSize"`    // Bytes of trie nodes flushed to disk since startup
+	CollectedNodes uint64 `json:"collectedNodes"` // Trie nodes pruned from memory since startup
+	CollectedSize  uint64 `json:"collectedSize"`  // Bytes of trie nodes pruned from memory since startup
+	CollectionTime string `json:"collectionTime"` // Time spent pruning since startup
+	LiveNodes      int    `json:"liveNodes"`      // Trie nodes currently held in memory
+	LiveSize       uint64 `json:"liveSize"`       // Bytes of trie data currently held in memory
+}
+
+// PruningStats retrieves the state garbage collection statistics of the node.
+func (api *PrivateAdminAPI) PruningStats() PruningStats {
+	stats := api.aqua.BlockChain().StateCache().TrieDB().Stats()
+
+	mode := "full"
+	if api.aqua.config.NoPruning {
+		mode = "archive"
+	}
+	return PruningStats{
+		Mode:           mode,
+		Commits:        stats.Commits,
+		FlushedNodes:   stats.FlushedNodes,
+		FlushedSize:    uint64(stats.FlushedSize),
+		CollectedNodes: stats.CollectedNodes,
+		CollectedSize:  uint64(stats.CollectedSize),
+		CollectionTime: stats.CollectionTime.String(),
+		LiveNodes:      stats.LiveNodes,
+		LiveSize:       uint64(stats.LiveSize),
+	}
+}
+
+// ScheduledTasks retrieves the state of the periodic maintenance tasks.
+func (api *PrivateAdminAPI) ScheduledTasks() []scheduler.Status {
+	if api.aqua.maintenance == nil {
+		return []scheduler.Status{}
+	}
+	return api.aqua.maintenance.Status()
+}
+
+// ForkStatus reports whether the connected peers agree on the upcoming fork of
+// the local chain, from the fork identifiers they announced.
+func (api *PrivateAdminAPI) ForkStatus() *ForkStatus {
+	return api.aqua.protocolManager.forkStatus()
+}
+
+// RunTask runs a periodic maintenance task immediately, unless it is running.
+func (api *PrivateAdminAPI) RunTask(name string) (bool, error) {
+	if api.aqua.maintenance == nil {
+		return false, fmt.Errorf("%v %q", scheduler.ErrUnknownTask, name)
+	}
+	if err := api.aqua.maintenance.Trigger(name); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // PublicDebugAPI is the collection of AquaChain full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -413,8 +636,8 @@ func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hex
 	return db.Get(hash.Bytes())
 }
 
-// GetBadBLocks returns a list of the last 'bad blocks' that the client has seen on the network
-// and returns them as a JSON list of block-hashes
+// GetBadBlocks returns a list of the last 'bad blocks' that the client has seen on the network,
+// with their full RLP encoding and the reason their import failed
 func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]core.BadBlockArgs, error) {
 	return api.aqua.BlockChain().BadBlocks()
 }
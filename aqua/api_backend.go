@@ -18,6 +18,7 @@ package aqua
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 
 	"github.com/aquanetwork/aquachain/accounts"
@@ -32,6 +33,8 @@ import (
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/core/vm"
 	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/internal/aquaapi"
+	"github.com/aquanetwork/aquachain/log"
 	"github.com/aquanetwork/aquachain/params"
 	"github.com/aquanetwork/aquachain/rpc"
 )
@@ -53,9 +56,36 @@ func (b *AquaApiBackend) CurrentBlock() *types.Block {
 	return b.aqua.blockchain.CurrentBlock()
 }
 
-func (b *AquaApiBackend) SetHead(number uint64) {
+func (b *AquaApiBackend) UnprotectedAllowed() bool {
+	return b.aqua.config.RPCAllowUnprotectedTxs
+}
+
+func (b *AquaApiBackend) TxWebhook() string {
+	return b.aqua.config.RPCTxWebhook
+}
+
+func (b *AquaApiBackend) RPCGasCap() uint64 {
+	return b.aqua.config.RPCGasCap
+}
+
+func (b *AquaApiBackend) RPCCallGasCap() uint64 {
+	return b.aqua.config.RPCCallGasCap
+}
+
+func (b *AquaApiBackend) SetHead(number uint64) error {
 	b.aqua.protocolManager.downloader.Cancel()
-	b.aqua.blockchain.SetHead(number)
+	txs, err := b.aqua.blockchain.Rewind(number)
+	if err != nil {
+		return err
+	}
+	dropped := 0
+	for _, err := range b.aqua.txPool.Reinject(b.aqua.blockchain.CurrentBlock().Header(), txs) {
+		if err != nil {
+			dropped++
+		}
+	}
+	log.Info("Reinjected rewound transactions", "transactions", len(txs), "dropped", dropped)
+	return nil
 }
 
 func (b *AquaApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
@@ -66,11 +96,15 @@ func (b *AquaApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNu
 	}
 	// Otherwise resolve and return the block
 	if blockNr == rpc.LatestBlockNumber {
-		return b.aqua.blockchain.CurrentBlock().Header(), nil
+		return b.aqua.protocolManager.currentHead(), nil
 	}
 	return b.aqua.blockchain.GetHeaderByNumber(uint64(blockNr)), nil
 }
 
+func (b *AquaApiBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.aqua.blockchain.GetHeaderByHash(hash), nil
+}
+
 func (b *AquaApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
 	// Pending block is only known by the miner
 	if blockNr == rpc.PendingBlockNumber {
@@ -92,11 +126,78 @@ func (b *AquaApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc
 	}
 	// Otherwise resolve the block number and return its state
 	header, err := b.HeaderByNumber(ctx, blockNr)
-	if header == nil || err != nil {
+	if err != nil {
 		return nil, nil, err
 	}
+	if header == nil {
+		return nil, nil, aquaapi.ErrHeaderNotFound
+	}
+	return b.stateAt(header)
+}
+
+func (b *AquaApiBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		return b.StateAndHeaderByNumber(ctx, blockNr)
+	}
+	hash, _ := blockNrOrHash.Hash()
+	header := b.aqua.blockchain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, nil, aquaapi.ErrHeaderNotFound
+	}
+	if blockNrOrHash.RequireCanonical && core.GetCanonicalHash(b.aqua.chainDb, header.Number.Uint64()) != hash {
+		return nil, nil, aquaapi.ErrNonCanonicalHash
+	}
+	return b.stateAt(header)
+}
+
+// AccountAt looks the balance and nonce of an account after a past canonical
+// block up in the account history index, returning nil if the index isn't
+// maintained or doesn't cover the block. The head and pending states are left
+// to be read from the state.
+func (b *AquaApiBackend) AccountAt(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*core.AccountState, error) {
+	if !b.aqua.config.AccountHistory {
+		return nil, nil
+	}
+	var number uint64
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		if blockNr < 0 {
+			return nil, nil
+		}
+		number = uint64(blockNr)
+	} else {
+		hash, _ := blockNrOrHash.Hash()
+		header := b.aqua.blockchain.GetHeaderByHash(hash)
+		if header == nil || core.GetCanonicalHash(b.aqua.chainDb, header.Number.Uint64()) != hash {
+			return nil, nil
+		}
+		number = header.Number.Uint64()
+	}
+	if number >= b.aqua.blockchain.CurrentBlock().NumberU64() {
+		return nil, nil
+	}
+	account, err := b.aqua.blockchain.AccountAt(address, number)
+	if err == core.ErrAccountNotIndexed {
+		return nil, nil
+	}
+	return account, err
+}
+
+// stateAt returns the state of the given block. A state already garbage collected
+// is regenerated by re-executing up to the configured number of blocks, failing
+// with a descriptive error if that isn't enough.
+func (b *AquaApiBackend) stateAt(header *types.Header) (*state.StateDB, *types.Header, error) {
 	stateDb, err := b.aqua.BlockChain().StateAt(header.Root)
-	return stateDb, header, err
+	if err == nil {
+		return stateDb, header, nil
+	}
+	if reexec := b.aqua.config.RPCReexec; reexec > 0 {
+		if block := b.aqua.blockchain.GetBlock(header.Hash(), header.Number.Uint64()); block != nil {
+			if stateDb, err = b.aqua.regen.stateAt(block, reexec); err == nil {
+				return stateDb, header, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("missing state of block #%d [%x], pruned or not yet synced (historical state requires --gcmode=archive or a higher --rpc.reexec): %v", header.Number, header.Hash().Bytes()[:4], err)
 }
 
 func (b *AquaApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
@@ -128,13 +229,17 @@ func (b *AquaApiBackend) GetEVM(ctx context.Context, msg core.Message, state *st
 	vmError := func() error { return nil }
 
 	context := core.NewEVMContext(msg, header, b.aqua.BlockChain(), nil)
-	return vm.NewEVM(context, state, b.aqua.chainConfig, vmCfg), vmError, nil
+	return vm.NewEVM(context, state, b.aqua.chainConfig, b.aqua.config.RPCVMConfig(vmCfg)), vmError, nil
 }
 
 func (b *AquaApiBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return b.aqua.BlockChain().SubscribeRemovedLogsEvent(ch)
 }
 
+func (b *AquaApiBackend) SubscribeReorgEvent(ch chan<- core.ReorgEvent) event.Subscription {
+	return b.aqua.BlockChain().SubscribeReorgEvent(ch)
+}
+
 func (b *AquaApiBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.aqua.BlockChain().SubscribeChainEvent(ch)
 }
@@ -199,6 +304,10 @@ func (b *AquaApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *AquaApiBackend) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	return b.gpo.FeeHistory(ctx, blocks, lastBlock, percentiles)
+}
+
 func (b *AquaApiBackend) ChainDb() aquadb.Database {
 	return b.aqua.ChainDb()
 }
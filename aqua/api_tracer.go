@@ -29,6 +29,7 @@ import (
 	"github.com/aquanetwork/aquachain/aqua/tracers"
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/common/math"
 	"github.com/aquanetwork/aquachain/core"
 	"github.com/aquanetwork/aquachain/core/state"
 	"github.com/aquanetwork/aquachain/core/types"
@@ -49,6 +50,10 @@ const (
 	// and reexecute to produce missing historical state necessary to run a specific
 	// trace.
 	defaultTraceReexec = uint64(128)
+
+	// standardTraceFormat is the struct logger output format following EIP-3155,
+	// as opposed to the default geth compatible structLogs.
+	standardTraceFormat = "eip3155"
 )
 
 // TraceConfig holds extra parameters to trace functions.
@@ -57,6 +62,7 @@ type TraceConfig struct {
 	Tracer  *string
 	Timeout *string
 	Reexec  *uint64
+	Format  *string // Struct logger output format, "eip3155" or empty for the default
 }
 
 // txTraceResult is the result of a single transaction trace.
@@ -468,66 +474,7 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 // If no state is locally available for the given block, a number of blocks are
 // attempted to be reexecuted to generate the desired state.
 func (api *PrivateDebugAPI) computeStateDB(block *types.Block, reexec uint64) (*state.StateDB, error) {
-	// If we have the state fully available, use that
-	statedb, err := api.aqua.blockchain.StateAt(block.Root())
-	if err == nil {
-		return statedb, nil
-	}
-	// Otherwise try to reexec blocks until we find a state or reach our limit
-	origin := block.NumberU64()
-	database := state.NewDatabase(api.aqua.ChainDb())
-
-	for i := uint64(0); i < reexec; i++ {
-		block = api.aqua.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
-		if block == nil {
-			break
-		}
-		if statedb, err = state.New(block.Root(), database); err == nil {
-			break
-		}
-	}
-	if err != nil {
-		switch err.(type) {
-		case *trie.MissingNodeError:
-			return nil, errors.New("required historical state unavailable")
-		default:
-			return nil, err
-		}
-	}
-	// State was available at historical point, regenerate
-	var (
-		start  = time.Now()
-		logged time.Time
-		proot  common.Hash
-	)
-	for block.NumberU64() < origin {
-		// Print progress logs if long enough time elapsed
-		if time.Since(logged) > 8*time.Second {
-			log.Info("Regenerating historical state", "block", block.NumberU64()+1, "target", origin, "elapsed", time.Since(start))
-			logged = time.Now()
-		}
-		// Retrieve the next block to regenerate and process it
-		if block = api.aqua.blockchain.GetBlockByNumber(block.NumberU64() + 1); block == nil {
-			return nil, fmt.Errorf("block #%d not found", block.NumberU64()+1)
-		}
-		_, _, _, err := api.aqua.blockchain.Processor().Process(block, statedb, vm.Config{})
-		if err != nil {
-			return nil, err
-		}
-		// Finalize the state so any modifications are written to the trie
-		root, err := statedb.Commit(true)
-		if err != nil {
-			return nil, err
-		}
-		if err := statedb.Reset(root); err != nil {
-			return nil, err
-		}
-		database.TrieDB().Reference(root, common.Hash{})
-		database.TrieDB().Dereference(proot, common.Hash{})
-		proot = root
-	}
-	log.Info("Historical state regenerated", "block", block.NumberU64(), "elapsed", time.Since(start), "size", database.TrieDB().Size())
-	return statedb, nil
+	return api.aqua.regen.stateAt(block, reexec)
 }
 
 // TraceTransaction returns the structured logs created during the execution of EVM
@@ -550,6 +497,43 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	return api.traceTx(ctx, msg, vmctx, statedb, config)
 }
 
+// TraceCall lets you trace a given aqua_call. It executes the call on top of the
+// state of the requested block and returns the output of the configured tracer.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args aquaapi.CallArgs, number rpc.BlockNumber, config *TraceConfig) (interface{}, error) {
+	// Fetch the block and the state that we want to execute on top of
+	var (
+		block   *types.Block
+		statedb *state.StateDB
+		err     error
+	)
+	switch number {
+	case rpc.PendingBlockNumber:
+		block, statedb = api.aqua.miner.Pending()
+	case rpc.LatestBlockNumber:
+		block = api.aqua.blockchain.CurrentBlock()
+	default:
+		block = api.aqua.blockchain.GetBlockByNumber(uint64(number))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	if statedb == nil {
+		reexec := defaultTraceReexec
+		if config != nil && config.Reexec != nil {
+			reexec = *config.Reexec
+		}
+		if statedb, err = api.computeStateDB(block, reexec); err != nil {
+			return nil, err
+		}
+	}
+	// Execute the call the same way aqua_call does, with an unlimited balance
+	msg := args.ToMessage()
+	statedb.SetBalance(msg.From(), math.MaxBig256)
+	vmctx := core.NewEVMContext(msg, block.Header(), api.aqua.blockchain, nil)
+
+	return api.traceTx(ctx, msg, vmctx, statedb, config)
+}
+
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
@@ -559,7 +543,17 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 		tracer vm.Tracer
 		err    error
 	)
+	var format string
+	if config != nil && config.Format != nil {
+		format = *config.Format
+	}
 	switch {
+	case format != "" && format != standardTraceFormat:
+		return nil, fmt.Errorf("unknown trace format %q", format)
+
+	case format != "" && config.Tracer != nil:
+		return nil, errors.New("trace format only applies to the struct logger")
+
 	case config != nil && config.Tracer != nil:
 		// Define a meaningful timeout of a single transaction trace
 		timeout := defaultTraceTimeout
@@ -580,14 +574,20 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 		}()
 		defer cancel()
 
-	case config == nil:
-		tracer = vm.NewStructLogger(nil)
-
 	default:
-		tracer = vm.NewStructLogger(config.LogConfig)
+		var logConfig vm.LogConfig
+		if config != nil && config.LogConfig != nil {
+			logConfig = *config.LogConfig
+		}
+		// Cap the number of struct logs to the node's trace limit
+		if limit := api.aqua.config.RPCTraceLimit; limit > 0 && (logConfig.Limit == 0 || logConfig.Limit > limit) {
+			logConfig.Limit = limit
+		}
+		tracer = vm.NewStructLogger(&logConfig)
 	}
 	// Run the transaction with tracing enabled.
-	vmenv := vm.NewEVM(vmctx, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
+	vmConfig := api.aqua.config.RPCVMConfig(vm.Config{Debug: true, Tracer: tracer})
+	vmenv := vm.NewEVM(vmctx, statedb, api.config, vmConfig)
 
 	ret, gas, failed, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
 	if err != nil {
@@ -596,6 +596,14 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	// Depending on the tracer type, format and return the output
 	switch tracer := tracer.(type) {
 	case *vm.StructLogger:
+		if format == standardTraceFormat {
+			return &aquaapi.StandardTraceResult{
+				Steps:   aquaapi.FormatStandardLogs(tracer.StructLogs()),
+				Output:  ret,
+				GasUsed: hexutil.Uint64(gas),
+				Pass:    !failed,
+			}, nil
+		}
 		return &aquaapi.ExecutionResult{
 			Gas:         gas,
 			Failed:      failed,
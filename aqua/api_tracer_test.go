@@ -0,0 +1,122 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/internal/aquaapi"
+	"github.com/aquanetwork/aquachain/miner"
+	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+// Tests that calls are traced on top of the state selected by block number, and
+// that reverting calls are traced as failed.
+func TestTraceCall(t *testing.T) {
+	var (
+		payee    = common.Address{0x01}
+		reporter = common.Address{0x0a} // Returns the balance of the payee
+		reverter = common.Address{0x0b} // Reverts unconditionally
+
+		db, _    = aquadb.NewMemDatabase()
+		genDb, _ = aquadb.NewMemDatabase()
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				testBank: {Balance: big.NewInt(1000000000)},
+				reporter: {Balance: new(big.Int), Code: append(append([]byte{byte(vm.PUSH20)}, payee.Bytes()...),
+					byte(vm.BALANCE), byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN))},
+				reverter: {Balance: new(big.Int), Code: []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT)}},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	gspec.MustCommit(genDb)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, aquahash.NewFaker(), genDb, 2, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), payee, big.NewInt(1000), params.TxGas, nil, nil), signer, testBankKey)
+		block.AddTx(tx)
+	})
+	blockchain, _ := core.NewBlockChain(db, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	config, poolConfig := DefaultConfig, core.DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	poolConfig.Snapshot = ""
+
+	backend := &AquaChain{
+		config:      &config,
+		chainDb:     db,
+		chainConfig: gspec.Config,
+		eventMux:    new(event.TypeMux),
+		engine:      aquahash.NewFaker(),
+		blockchain:  blockchain,
+		txPool:      core.NewTxPool(poolConfig, gspec.Config, blockchain),
+		regen:       newStateRegenerator(blockchain, state.NewDatabase(db)),
+	}
+	defer backend.txPool.Stop()
+	backend.miner = miner.New(backend, gspec.Config, backend.eventMux, backend.engine)
+	defer backend.miner.Stop()
+
+	api := NewPrivateDebugAPI(gspec.Config, backend)
+	tests := []struct {
+		to     common.Address
+		number rpc.BlockNumber
+		want   string // Hex encoded return value
+		failed bool
+	}{
+		{reporter, 1, fmt.Sprintf("%064x", 1000), false},
+		{reporter, rpc.LatestBlockNumber, fmt.Sprintf("%064x", 2000), false},
+		{reporter, rpc.PendingBlockNumber, fmt.Sprintf("%064x", 2000), false},
+		{reporter, 0, fmt.Sprintf("%064x", 0), false},
+		{reverter, rpc.LatestBlockNumber, "", true},
+	}
+	for i, tt := range tests {
+		to := tt.to
+		res, err := api.TraceCall(context.Background(), aquaapi.CallArgs{From: testBank, To: &to}, tt.number, nil)
+		if err != nil {
+			t.Fatalf("test %d: failed to trace call: %v", i, err)
+		}
+		result := res.(*aquaapi.ExecutionResult)
+		if result.Failed != tt.failed {
+			t.Errorf("test %d: failure mismatch: have %v, want %v", i, result.Failed, tt.failed)
+		}
+		if result.ReturnValue != tt.want {
+			t.Errorf("test %d: return value mismatch: have %s, want %s", i, result.ReturnValue, tt.want)
+		}
+		if len(result.StructLogs) == 0 {
+			t.Errorf("test %d: no struct logs traced", i)
+		}
+	}
+	if _, err := api.TraceCall(context.Background(), aquaapi.CallArgs{From: testBank, To: &reporter}, 3, nil); err == nil {
+		t.Errorf("call traced on an unknown block")
+	}
+}
@@ -24,11 +24,18 @@ import (
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/accounts/keystore"
+	"github.com/aquanetwork/aquachain/aqua/contractmeta"
 	"github.com/aquanetwork/aquachain/aqua/downloader"
 	"github.com/aquanetwork/aquachain/aqua/filters"
 	"github.com/aquanetwork/aquachain/aqua/gasprice"
+	"github.com/aquanetwork/aquachain/aqua/minerpeers"
+	"github.com/aquanetwork/aquachain/aqua/scheduler"
+	"github.com/aquanetwork/aquachain/aqua/supply"
+	"github.com/aquanetwork/aquachain/aqua/txconflict"
 	"github.com/aquanetwork/aquachain/aquadb"
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/hexutil"
@@ -37,6 +44,7 @@ import (
 	"github.com/aquanetwork/aquachain/consensus/clique"
 	"github.com/aquanetwork/aquachain/core"
 	"github.com/aquanetwork/aquachain/core/bloombits"
+	"github.com/aquanetwork/aquachain/core/state"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/core/vm"
 	"github.com/aquanetwork/aquachain/event"
@@ -46,6 +54,7 @@ import (
 	"github.com/aquanetwork/aquachain/node"
 	"github.com/aquanetwork/aquachain/p2p"
 	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/permission"
 	"github.com/aquanetwork/aquachain/rlp"
 	"github.com/aquanetwork/aquachain/rpc"
 )
@@ -63,14 +72,15 @@ type AquaChain struct {
 	chainConfig *params.ChainConfig
 
 	// Channel for shutting down the service
-	shutdownChan  chan bool    // Channel for shutting down the aquachain
-	stopDbUpgrade func() error // stop chain db sequential key upgrade
+	shutdownChan chan bool // Channel for shutting down the aquachain
 
 	// Handlers
 	txPool          *core.TxPool
 	blockchain      *core.BlockChain
+	regen           *stateRegenerator // Regenerates pruned historical states
 	protocolManager *ProtocolManager
 	lesServer       LesServer
+	minerPeers      *minerpeers.Manager // Direct connections to announced miners, if enabled
 
 	// DB interfaces
 	chainDb aquadb.Database // Block chain database
@@ -84,7 +94,16 @@ type AquaChain struct {
 
 	ApiBackend *AquaApiBackend
 
-	miner    *miner.Miner
+	contractMeta *contractmeta.Store  // Verified contract metadata store
+	txConflicts  *txconflict.Detector // Conflicting transaction detector
+	supply       *supply.Tracker      // Coin supply index
+	metaQuit     chan struct{}        // Channel to stop the registry sync loop
+
+	miner     *miner.Miner
+	forkGuard *miner.ForkGuard // Pauses mining on suspected minority forks, if enabled
+
+	maintenance *scheduler.Scheduler // Runs the periodic maintenance tasks, if configured
+
 	gasPrice *big.Int
 	aquabase common.Address
 
@@ -112,13 +131,24 @@ func New(ctx *node.ServiceContext, config *Config) (*AquaChain, error) {
 	if err != nil {
 		return nil, err
 	}
-	stopDbUpgrade := upgradeDeduplicateData(chainDb)
+	if err := core.MigrateDatabase(chainDb); err != nil {
+		return nil, err
+	}
 	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlock(chainDb, config.Genesis)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
+	if err := checkAnnouncePolicy(config, chainConfig); err != nil {
+		return nil, err
+	}
+
+	config.Aquahash.Checkpoints = params.TrustedCheckpoints(genesisHash).Merge(core.GetTrustedCheckpoints(chainDb)).Merge(config.Aquahash.Checkpoints)
+	if latest := config.Aquahash.Checkpoints.Latest(); latest != nil {
+		log.Info("Loaded trusted checkpoints", "count", len(config.Aquahash.Checkpoints), "latest", latest.Number, "hash", latest.Hash)
+	}
+
 	aqua := &AquaChain{
 		config:         config,
 		chainDb:        chainDb,
@@ -127,7 +157,6 @@ func New(ctx *node.ServiceContext, config *Config) (*AquaChain, error) {
 		accountManager: ctx.AccountManager,
 		engine:         CreateConsensusEngine(ctx, &config.Aquahash, chainConfig, chainDb),
 		shutdownChan:   make(chan bool),
-		stopDbUpgrade:  stopDbUpgrade,
 		networkId:      config.NetworkId,
 		gasPrice:       config.GasPrice,
 		aquabase:       config.Aquabase,
@@ -146,12 +175,22 @@ func New(ctx *node.ServiceContext, config *Config) (*AquaChain, error) {
 	//}
 	var (
 		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout, TxLookupLimit: config.TxLookupLimit, Snapshot: config.Snapshot, NoPrefetch: config.NoPrefetch, AccountHistory: config.AccountHistory}
 	)
 	aqua.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, aqua.chainConfig, aqua.engine, vmConfig)
 	if err != nil {
 		return nil, err
 	}
+	if config.TieBreak != "" {
+		if _, err := core.ParseTieBreak(string(config.TieBreak)); err != nil {
+			return nil, err
+		}
+		aqua.blockchain.SetTieBreak(config.TieBreak, aqua.isLocalBlock)
+	}
+	if config.ForensicsDir != "" {
+		aqua.blockchain.SetForensicsDir(ctx.ResolvePath(config.ForensicsDir))
+	}
+	aqua.regen = newStateRegenerator(aqua.blockchain, state.NewDatabase(chainDb))
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -163,13 +202,59 @@ func New(ctx *node.ServiceContext, config *Config) (*AquaChain, error) {
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.Snapshot != "" {
+		config.TxPool.Snapshot = ctx.ResolvePath(config.TxPool.Snapshot)
+	}
+	if !config.TxPool.NoLocals {
+		// Exempt the transactions of our own accounts from price eviction
+		config.TxPool.Locals = append(config.TxPool.Locals, keystoreAccounts(ctx.AccountManager)...)
+	}
 	aqua.txPool = core.NewTxPool(config.TxPool, aqua.chainConfig, aqua.blockchain)
 
+	// Restrict peers and transaction senders on permissioned networks
+	var checker *permission.Checker
+	if chainConfig.Permission != nil {
+		if checker, err = permission.New(chainConfig, aqua.blockchain, ctx.ResolvePath(permission.AllowlistFile)); err != nil {
+			return nil, err
+		}
+		if checker.RestrictsAccounts() {
+			aqua.txPool.SetSenderFilter(checker.AccountAllowed)
+			aqua.blockchain.SetSenderFilter(checker.AccountPermitted)
+		}
+		if checker.RestrictsNodes() && config.LightServ > 0 {
+			log.Warn("Light server not supported on node permissioned networks, disabling")
+			config.LightServ = 0
+		}
+		log.Info("Enabled network permissioning", "nodes", checker.RestrictsNodes(), "accounts", checker.RestrictsAccounts())
+	}
 	if aqua.protocolManager, err = NewProtocolManager(aqua.chainConfig, config.SyncMode, config.NetworkId, aqua.eventMux, aqua.txPool, aqua.engine, aqua.blockchain, chainDb); err != nil {
 		return nil, err
 	}
+	if checker != nil && checker.RestrictsNodes() {
+		aqua.protocolManager.permission = checker
+	}
+	aqua.protocolManager.announcer = newMinedAnnouncer(config.AnnounceDelay, config.WithholdBlocks, aqua.protocolManager.broadcastMined)
+	aqua.protocolManager.scores = newPeerScorer(ctx.ResolvePath(peerBansFile))
+	if latest := config.Aquahash.Checkpoints.Latest(); latest != nil {
+		aqua.protocolManager.downloader.SetCheckpoint(latest.Number)
+	}
 	aqua.miner = miner.New(aqua, aqua.chainConfig, aqua.EventMux(), aqua.engine)
 	aqua.miner.SetExtra(makeExtraData(config.ExtraData))
+	if err := aqua.miner.SetUnclePolicy(config.Uncles); err != nil {
+		return nil, err
+	}
+	if len(config.Aquabases) > 0 {
+		aqua.miner.SetAquabases(config.Aquabases)
+	}
+	if config.MinerPeers {
+		aqua.minerPeers = minerpeers.New(aqua.blockchain, aqua.Aquabase, aqua.IsMining, aqua.signHash)
+	}
+	if config.ForkGuard {
+		aqua.forkGuard = miner.NewForkGuard(aqua.miner, aqua.blockchain, aqua.protocolManager.peers.HeadHeaders, aqua.eventMux)
+	}
+	if aqua.maintenance, err = newMaintenance(aqua, config.Maintenance); err != nil {
+		return nil, err
+	}
 
 	aqua.ApiBackend = &AquaApiBackend{aqua, nil}
 	gpoParams := config.GPO
@@ -178,9 +263,41 @@ func New(ctx *node.ServiceContext, config *Config) (*AquaChain, error) {
 	}
 	aqua.ApiBackend.gpo = gasprice.NewOracle(aqua.ApiBackend, gpoParams)
 
+	aqua.contractMeta = contractmeta.NewStore(chainDb, aqua.contractCodeHash)
+	aqua.txConflicts = txconflict.New(aqua.chainConfig, aqua.blockchain, aqua.txPool)
+	aqua.supply = supply.New(chainDb, aqua.blockchain)
+	aqua.metaQuit = make(chan struct{})
+
 	return aqua, nil
 }
 
+// contractCodeHash returns the hash of the code deployed at addr in the current
+// state, used to verify registered contract metadata.
+func (s *AquaChain) contractCodeHash(addr common.Address) (common.Hash, error) {
+	statedb, err := s.blockchain.State()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return statedb.GetCodeHash(addr), nil
+}
+
+// keystoreAccounts returns the addresses of the accounts in the local keystore.
+// Remote wallets aren't queried, their accounts need listing as txpool locals.
+func keystoreAccounts(am *accounts.Manager) []common.Address {
+	if am == nil {
+		return nil
+	}
+	var addrs []common.Address
+	for _, backend := range am.Backends(keystore.KeyStoreType) {
+		for _, wallet := range backend.Wallets() {
+			for _, account := range wallet.Accounts() {
+				addrs = append(addrs, account.Address)
+			}
+		}
+	}
+	return addrs
+}
+
 func makeExtraData(extra []byte) []byte {
 	if len(extra) == 0 {
 		// create default extradata
@@ -207,6 +324,7 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (aquadb.Dat
 	if db, ok := db.(*aquadb.LDBDatabase); ok {
 		db.Meter("aqua/db/chaindata/")
 	}
+	core.SetStorageCompression(config.StorageCompression)
 	return db, nil
 }
 
@@ -235,6 +353,9 @@ func CreateConsensusEngine(ctx *node.ServiceContext, config *aquahash.Config, ch
 			DatasetDir:     config.DatasetDir,
 			DatasetsInMem:  config.DatasetsInMem,
 			DatasetsOnDisk: config.DatasetsOnDisk,
+			Checkpoints:    config.Checkpoints,
+			CachesAhead:    config.CachesAhead,
+			DatasetsAhead:  config.DatasetsAhead,
 		})
 		engine.SetThreads(-1) // Disable CPU mining
 		return engine
@@ -244,7 +365,7 @@ func CreateConsensusEngine(ctx *node.ServiceContext, config *aquahash.Config, ch
 // APIs returns the collection of RPC services the aquachain package offers.
 // NOTE, some of these services probably need to be moved to somewhere else.
 func (s *AquaChain) APIs() []rpc.API {
-	apis := aquaapi.GetAPIs(s.ApiBackend)
+	apis := aquaapi.GetAPIs(s.ApiBackend, !s.config.NoPersonal)
 
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
@@ -270,6 +391,10 @@ func (s *AquaChain) APIs() []rpc.API {
 			Namespace: "miner",
 			Version:   "1.0",
 			Service:   NewPrivateMinerAPI(s),
+		}, {
+			Namespace: "txpool",
+			Version:   "1.0",
+			Service:   NewPrivateTxPoolAPI(s.txPool),
 			Public:    false,
 		}, {
 			Namespace: "aqua",
@@ -294,6 +419,25 @@ func (s *AquaChain) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   s.netRPCService,
 			Public:    true,
+		}, {
+			Namespace: "aqua",
+			Version:   "1.0",
+			Service:   contractmeta.NewPublicContractMetaAPI(s.contractMeta),
+			Public:    true,
+		}, {
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   contractmeta.NewPrivateContractMetaAPI(s.contractMeta),
+		}, {
+			Namespace: "aqua",
+			Version:   "1.0",
+			Service:   txconflict.NewPublicConflictAPI(s.txConflicts),
+			Public:    true,
+		}, {
+			Namespace: "aqua",
+			Version:   "1.0",
+			Service:   supply.NewPublicSupplyAPI(s.supply),
+			Public:    true,
 		},
 	}...)
 }
@@ -302,6 +446,14 @@ func (s *AquaChain) ResetWithGenesisBlock(gb *types.Block) {
 	s.blockchain.ResetWithGenesisBlock(gb)
 }
 
+// isLocalBlock reports whether the block was mined to the local aquabase.
+func (s *AquaChain) isLocalBlock(block *types.Block) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.aquabase != (common.Address{}) && block.Coinbase() == s.aquabase
+}
+
 func (s *AquaChain) Aquabase() (eb common.Address, err error) {
 	s.lock.RLock()
 	aquabase := s.aquabase
@@ -335,11 +487,18 @@ func (self *AquaChain) SetAquabase(aquabase common.Address) {
 }
 
 func (s *AquaChain) StartMining(local bool) error {
+	if s.config.SyncMode == downloader.HeaderSync {
+		return errors.New("can't mine in header sync mode, no state is available")
+	}
 	eb, err := s.Aquabase()
 	if err != nil {
 		log.Error("Cannot start mining without aquabase", "err", err)
 		return fmt.Errorf("aquabase missing: %v", err)
 	}
+	if err := CheckAquabase(eb, s.config.ForceAquabase); err != nil {
+		log.Error("Cannot start mining to invalid aquabase", "aquabase", eb, "err", err)
+		return err
+	}
 	if clique, ok := s.engine.(*clique.Clique); ok {
 		wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
 		if wallet == nil || err != nil {
@@ -359,6 +518,16 @@ func (s *AquaChain) StartMining(local bool) error {
 	return nil
 }
 
+// signHash signs the hash with the key of the account, if available and unlocked
+// in one of the local wallets.
+func (s *AquaChain) signHash(account accounts.Account, hash []byte) ([]byte, error) {
+	wallet, err := s.accountManager.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.SignHash(account, hash)
+}
+
 func (s *AquaChain) StopMining()         { s.miner.Stop() }
 func (s *AquaChain) IsMining() bool      { return s.miner.Mining() }
 func (s *AquaChain) Miner() *miner.Miner { return s.miner }
@@ -368,6 +537,7 @@ func (s *AquaChain) BlockChain() *core.BlockChain       { return s.blockchain }
 func (s *AquaChain) TxPool() *core.TxPool               { return s.txPool }
 func (s *AquaChain) EventMux() *event.TypeMux           { return s.eventMux }
 func (s *AquaChain) Engine() consensus.Engine           { return s.engine }
+func (s *AquaChain) Config() *Config                    { return s.config }
 func (s *AquaChain) ChainDb() aquadb.Database           { return s.chainDb }
 func (s *AquaChain) IsListening() bool                  { return true } // Always listening
 func (s *AquaChain) AquaVersion() int                   { return int(s.protocolManager.SubProtocols[0].Version) }
@@ -377,10 +547,14 @@ func (s *AquaChain) Downloader() *downloader.Downloader { return s.protocolManag
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *AquaChain) Protocols() []p2p.Protocol {
+	protos := s.protocolManager.SubProtocols
+	if s.minerPeers != nil {
+		protos = append(protos, s.minerPeers.Protocols()...)
+	}
 	if s.lesServer == nil {
-		return s.protocolManager.SubProtocols
+		return protos
 	}
-	return append(s.protocolManager.SubProtocols, s.lesServer.Protocols()...)
+	return append(protos, s.lesServer.Protocols()...)
 }
 
 // Start implements node.Service, starting all internal goroutines needed by the
@@ -405,21 +579,47 @@ func (s *AquaChain) Start(srvr *p2p.Server) error {
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
+	if s.minerPeers != nil {
+		s.minerPeers.Start(srvr)
+	}
+	if s.forkGuard != nil {
+		s.forkGuard.Start()
+	}
+	if s.maintenance != nil {
+		s.maintenance.Start()
+	}
+	s.txConflicts.Start()
+	if err := s.supply.Start(); err != nil {
+		return err
+	}
+	// Keep the contract metadata store in sync with the community registry
+	if s.config.ContractRegistry != "" {
+		go s.contractMeta.SyncLoop(s.config.ContractRegistry, time.Hour, s.metaQuit)
+	}
 	return nil
 }
 
 // Stop implements node.Service, terminating all internal goroutines used by the
 // AquaChain protocol.
 func (s *AquaChain) Stop() error {
-	if s.stopDbUpgrade != nil {
-		s.stopDbUpgrade()
+	close(s.metaQuit)
+	if s.maintenance != nil {
+		s.maintenance.Stop()
 	}
+	s.supply.Stop()
 	s.bloomIndexer.Close()
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
 		s.lesServer.Stop()
 	}
+	if s.minerPeers != nil {
+		s.minerPeers.Stop()
+	}
+	if s.forkGuard != nil {
+		s.forkGuard.Stop()
+	}
+	s.txConflicts.Stop()
 	s.txPool.Stop()
 	s.miner.Stop()
 	s.eventMux.Stop()
@@ -0,0 +1,348 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package backup implements a devp2p subprotocol through which a node streams
+// its entire chain database to a fresh node, allowing replicas to be bootstrapped
+// without copying database files around.
+//
+// The receiver drives the transfer: it asks for the entries following the last
+// key it committed, and acknowledges every checksummed chunk by asking for the
+// next one. The receiver records its progress in the database along with each
+// chunk, so an interrupted transfer resumes where it stopped.
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/p2p"
+	"github.com/aquanetwork/aquachain/rlp"
+)
+
+const (
+	// ProtocolName is the capability name of the backup streaming protocol.
+	ProtocolName = "aquabackup"
+
+	// ProtocolVersion is the version of the backup streaming protocol.
+	ProtocolVersion = 1
+
+	requestMsg = 0x00 // Request for the entries following a key, acknowledging the previous chunk
+	chunkMsg   = 0x01 // Checksummed batch of database entries
+	doneMsg    = 0x02 // End of the database, confirmed by the receiver
+
+	chunkSize  = 512 * 1024       // Amount of key and value data to send in a chunk
+	maxMsgSize = 16 * 1024 * 1024 // Maximum size of a chunk message
+
+	reportInterval = 8 * time.Second // Time between progress logs
+)
+
+// progressKey is where the receiver tracks the progress of an unfinished
+// transfer in its database.
+var progressKey = []byte("BackupStreamProgress")
+
+var (
+	errNotIterable   = errors.New("database can't be iterated")
+	errNotEmpty      = errors.New("database is not empty and has no unfinished backup")
+	errIncomplete    = errors.New("database is an unfinished backup")
+	errMsgTooLarge   = errors.New("message too large")
+	errBadChecksum   = errors.New("chunk checksum mismatch")
+	errUnordered     = errors.New("chunk entries out of order")
+	errUnexpectedAck = errors.New("acknowledged chunk doesn't match the one sent")
+)
+
+// Protocol returns the backup streaming protocol, handing negotiated peers to
+// run. Both ends of a transfer run it, with run calling Send or Receive.
+func Protocol(run func(p *p2p.Peer, rw p2p.MsgReadWriter) error) p2p.Protocol {
+	return p2p.Protocol{
+		Name:    ProtocolName,
+		Version: ProtocolVersion,
+		Length:  3,
+		Run:     run,
+	}
+}
+
+// Stats summarises a finished transfer.
+type Stats struct {
+	Entries uint64             // Entries transferred in this session
+	Resumed uint64             // Entries transferred by earlier sessions
+	Size    common.StorageSize // Amount of key and value data transferred in this session
+}
+
+type request struct {
+	After []byte // Last key committed by the receiver, empty to start over
+}
+
+type entry struct {
+	Key, Value []byte
+}
+
+type chunk struct {
+	Entries  []entry
+	Checksum common.Hash
+}
+
+type done struct {
+	Entries uint64 // Total number of entries in the database
+}
+
+// progress is the state of an unfinished transfer kept by the receiver.
+type progress struct {
+	Last    []byte
+	Entries uint64
+}
+
+// checksum computes the hash a chunk of entries is sent with.
+func checksum(entries []entry) (common.Hash, error) {
+	blob, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(blob), nil
+}
+
+// readProgress retrieves the progress of an unfinished transfer into db, or nil
+// if there's none.
+func readProgress(db aquadb.Database) (*progress, error) {
+	blob, err := db.Get(progressKey)
+	if len(blob) == 0 || err != nil {
+		return nil, nil
+	}
+	prog := new(progress)
+	if err := rlp.DecodeBytes(blob, prog); err != nil {
+		return nil, fmt.Errorf("invalid backup progress: %v", err)
+	}
+	return prog, nil
+}
+
+// readMsg reads the next message from rw, ensuring it has the expected code.
+func readMsg(rw p2p.MsgReadWriter, code uint64, val interface{}) error {
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	defer msg.Discard()
+
+	if msg.Size > maxMsgSize {
+		return errMsgTooLarge
+	}
+	if msg.Code != code {
+		return fmt.Errorf("unexpected message code %d, want %d", msg.Code, code)
+	}
+	if err := msg.Decode(val); err != nil {
+		return fmt.Errorf("invalid message %d: %v", code, err)
+	}
+	return nil
+}
+
+// Send streams the entries of db to the receiver at the other end of rw,
+// starting after the last key the receiver committed. The database must not be
+// modified during the transfer.
+func Send(db aquadb.Database, rw p2p.MsgReadWriter) (*Stats, error) {
+	iteratee, ok := db.(aquadb.Iteratee)
+	if !ok {
+		return nil, errNotIterable
+	}
+	if prog, err := readProgress(db); err != nil {
+		return nil, err
+	} else if prog != nil {
+		return nil, errIncomplete
+	}
+	var req request
+	if err := readMsg(rw, requestMsg, &req); err != nil {
+		return nil, err
+	}
+	if len(req.After) > 0 {
+		log.Info("Resuming backup stream", "after", common.Bytes2Hex(req.After))
+	}
+	var (
+		stats    = new(Stats)
+		total    uint64
+		pending  []entry
+		size     int
+		start    = time.Now()
+		reported = time.Now()
+	)
+	// flush sends the pending entries and waits for the receiver to commit them
+	flush := func() error {
+		sum, err := checksum(pending)
+		if err != nil {
+			return err
+		}
+		if err := p2p.Send(rw, chunkMsg, &chunk{Entries: pending, Checksum: sum}); err != nil {
+			return err
+		}
+		var ack request
+		if err := readMsg(rw, requestMsg, &ack); err != nil {
+			return err
+		}
+		if !bytes.Equal(ack.After, pending[len(pending)-1].Key) {
+			return errUnexpectedAck
+		}
+		stats.Entries += uint64(len(pending))
+		stats.Size += common.StorageSize(size)
+		pending, size = nil, 0
+
+		if time.Since(reported) >= reportInterval {
+			log.Info("Streaming backup", "entries", stats.Entries, "size", stats.Size, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+		return nil
+	}
+	err := iteratee.Iterate(func(key, value []byte) error {
+		total++
+		// Skip everything the receiver already has
+		if len(req.After) > 0 && bytes.Compare(key, req.After) <= 0 {
+			stats.Resumed++
+			return nil
+		}
+		pending = append(pending, entry{common.CopyBytes(key), common.CopyBytes(value)})
+		size += len(key) + len(value)
+		if size >= chunkSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil && len(pending) > 0 {
+		err = flush()
+	}
+	if err != nil {
+		return stats, err
+	}
+	if err := p2p.Send(rw, doneMsg, &done{Entries: total}); err != nil {
+		return stats, err
+	}
+	var ack done
+	if err := readMsg(rw, doneMsg, &ack); err != nil {
+		return stats, err
+	}
+	if ack.Entries != total {
+		return stats, fmt.Errorf("receiver committed %d entries, sent %d", ack.Entries, total)
+	}
+	return stats, nil
+}
+
+// Receive writes the entries streamed by the sender at the other end of rw into
+// db, which must be empty or hold an unfinished transfer to resume.
+func Receive(db aquadb.Database, rw p2p.MsgReadWriter) (*Stats, error) {
+	prog, err := readProgress(db)
+	if err != nil {
+		return nil, err
+	}
+	if prog == nil {
+		iteratee, ok := db.(aquadb.Iteratee)
+		if !ok {
+			return nil, errNotIterable
+		}
+		if err := iteratee.Iterate(func(key, value []byte) error { return errNotEmpty }); err != nil {
+			return nil, err
+		}
+		prog = new(progress)
+	} else {
+		log.Info("Resuming backup stream", "after", common.Bytes2Hex(prog.Last), "entries", prog.Entries)
+	}
+	if err := p2p.Send(rw, requestMsg, &request{After: prog.Last}); err != nil {
+		return nil, err
+	}
+	var (
+		stats    = &Stats{Resumed: prog.Entries}
+		batch    = db.NewBatch()
+		start    = time.Now()
+		reported = time.Now()
+	)
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return stats, err
+		}
+		if msg.Size > maxMsgSize {
+			msg.Discard()
+			return stats, errMsgTooLarge
+		}
+		switch msg.Code {
+		case chunkMsg:
+			var c chunk
+			if err := msg.Decode(&c); err != nil {
+				return stats, fmt.Errorf("invalid chunk: %v", err)
+			}
+			if len(c.Entries) == 0 {
+				return stats, errors.New("empty chunk")
+			}
+			if sum, err := checksum(c.Entries); err != nil {
+				return stats, err
+			} else if sum != c.Checksum {
+				return stats, errBadChecksum
+			}
+			// Write the chunk and the progress atomically, so a crash can't leave
+			// the recorded progress ahead of the data
+			batch.Reset()
+			last := prog.Last
+			for _, e := range c.Entries {
+				if len(last) > 0 && bytes.Compare(e.Key, last) <= 0 {
+					return stats, errUnordered
+				}
+				if err := batch.Put(e.Key, e.Value); err != nil {
+					return stats, err
+				}
+				last = e.Key
+				stats.Size += common.StorageSize(len(e.Key) + len(e.Value))
+			}
+			next := &progress{Last: last, Entries: prog.Entries + uint64(len(c.Entries))}
+			blob, err := rlp.EncodeToBytes(next)
+			if err != nil {
+				return stats, err
+			}
+			if err := batch.Put(progressKey, blob); err != nil {
+				return stats, err
+			}
+			if err := batch.Write(); err != nil {
+				return stats, err
+			}
+			prog = next
+			stats.Entries += uint64(len(c.Entries))
+
+			if err := p2p.Send(rw, requestMsg, &request{After: prog.Last}); err != nil {
+				return stats, err
+			}
+			if time.Since(reported) >= reportInterval {
+				log.Info("Receiving backup", "entries", prog.Entries, "size", stats.Size, "elapsed", common.PrettyDuration(time.Since(start)))
+				reported = time.Now()
+			}
+
+		case doneMsg:
+			var d done
+			if err := msg.Decode(&d); err != nil {
+				return stats, fmt.Errorf("invalid completion: %v", err)
+			}
+			if d.Entries != prog.Entries {
+				return stats, fmt.Errorf("sender has %d entries, received %d", d.Entries, prog.Entries)
+			}
+			if err := db.Delete(progressKey); err != nil {
+				return stats, err
+			}
+			return stats, p2p.Send(rw, doneMsg, &done{Entries: prog.Entries})
+
+		default:
+			msg.Discard()
+			return stats, fmt.Errorf("unexpected message code %d", msg.Code)
+		}
+	}
+}
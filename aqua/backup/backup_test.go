@@ -0,0 +1,159 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/p2p"
+)
+
+// newTestDatabase creates a database holding enough data for several chunks.
+func newTestDatabase(t *testing.T) *aquadb.MemDatabase {
+	db, _ := aquadb.NewMemDatabase()
+	for i := 0; i < 2000; i++ {
+		if err := db.Put([]byte(fmt.Sprintf("key-%05d", i)), bytes.Repeat([]byte{byte(i)}, 1024)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// failingWriter fails every write after the given number of chunks was sent.
+type failingWriter struct {
+	p2p.MsgReadWriter
+	chunks int
+}
+
+func (w *failingWriter) WriteMsg(msg p2p.Msg) error {
+	if msg.Code == chunkMsg {
+		if w.chunks == 0 {
+			return errors.New("connection lost")
+		}
+		w.chunks--
+	}
+	return w.MsgReadWriter.WriteMsg(msg)
+}
+
+// transfer runs a sender and a receiver against each other.
+func transfer(src, dst aquadb.Database, sender func(p2p.MsgReadWriter) p2p.MsgReadWriter) (*Stats, error, error) {
+	a, b := p2p.MsgPipe()
+	result := make(chan error, 1)
+	go func() {
+		_, err := Send(src, sender(a))
+		a.Close()
+		result <- err
+	}()
+	stats, err := Receive(dst, b)
+	b.Close()
+	return stats, <-result, err
+}
+
+func identity(rw p2p.MsgReadWriter) p2p.MsgReadWriter { return rw }
+
+func checkCopy(t *testing.T, src, dst *aquadb.MemDatabase) {
+	if has, _ := dst.Has(progressKey); has {
+		t.Error("progress left in the finished backup")
+	}
+	if src.Len() != dst.Len() {
+		t.Fatalf("entry count mismatch: have %d, want %d", dst.Len(), src.Len())
+	}
+	for _, key := range src.Keys() {
+		want, _ := src.Get(key)
+		have, err := dst.Get(key)
+		if err != nil || !bytes.Equal(have, want) {
+			t.Fatalf("entry %q mismatch: have %x, want %x", key, have, want)
+		}
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	src := newTestDatabase(t)
+	dst, _ := aquadb.NewMemDatabase()
+
+	stats, sendErr, recvErr := transfer(src, dst, identity)
+	if sendErr != nil || recvErr != nil {
+		t.Fatalf("transfer failed: send %v, receive %v", sendErr, recvErr)
+	}
+	if stats.Entries != uint64(src.Len()) || stats.Resumed != 0 {
+		t.Errorf("stats mismatch: have %d entries %d resumed, want %d entries", stats.Entries, stats.Resumed, src.Len())
+	}
+	checkCopy(t, src, dst)
+}
+
+func TestTransferResume(t *testing.T) {
+	src := newTestDatabase(t)
+	dst, _ := aquadb.NewMemDatabase()
+
+	_, sendErr, recvErr := transfer(src, dst, func(rw p2p.MsgReadWriter) p2p.MsgReadWriter {
+		return &failingWriter{MsgReadWriter: rw, chunks: 2}
+	})
+	if sendErr == nil || recvErr == nil {
+		t.Fatalf("interrupted transfer succeeded: send %v, receive %v", sendErr, recvErr)
+	}
+	prog, err := readProgress(dst)
+	if err != nil || prog == nil || prog.Entries == 0 {
+		t.Fatalf("no progress recorded: %v, %v", prog, err)
+	}
+	// The sender refuses to stream an unfinished backup
+	if _, err := Send(dst, nil); err != errIncomplete {
+		t.Errorf("sending unfinished backup: have %v, want %v", err, errIncomplete)
+	}
+	stats, sendErr, recvErr := transfer(src, dst, identity)
+	if sendErr != nil || recvErr != nil {
+		t.Fatalf("resumed transfer failed: send %v, receive %v", sendErr, recvErr)
+	}
+	if stats.Resumed != prog.Entries || stats.Resumed+stats.Entries != uint64(src.Len()) {
+		t.Errorf("stats mismatch: have %d entries %d resumed, want %d resumed of %d", stats.Entries, stats.Resumed, prog.Entries, src.Len())
+	}
+	checkCopy(t, src, dst)
+}
+
+func TestReceiveNotEmpty(t *testing.T) {
+	src := newTestDatabase(t)
+	dst, _ := aquadb.NewMemDatabase()
+	dst.Put([]byte("LastBlock"), common.Hash{}.Bytes())
+
+	if _, _, err := transfer(src, dst, identity); err != errNotEmpty {
+		t.Fatalf("error mismatch: have %v, want %v", err, errNotEmpty)
+	}
+}
+
+func TestReceiveBadChecksum(t *testing.T) {
+	dst, _ := aquadb.NewMemDatabase()
+	a, b := p2p.MsgPipe()
+	defer a.Close()
+
+	go func() {
+		var req request
+		if err := readMsg(a, requestMsg, &req); err != nil {
+			return
+		}
+		p2p.Send(a, chunkMsg, &chunk{Entries: []entry{{[]byte("key"), []byte("value")}}})
+	}()
+	if _, err := Receive(dst, b); err != errBadChecksum {
+		t.Fatalf("error mismatch: have %v, want %v", err, errBadChecksum)
+	}
+	if dst.Len() != 0 {
+		t.Errorf("corrupt chunk written: %d entries", dst.Len())
+	}
+}
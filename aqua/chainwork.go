@@ -0,0 +1,177 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/consensus"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+const (
+	// maxChainWorkRange is the maximum number of blocks aqua_getChainWork sums
+	// up in one call.
+	maxChainWorkRange = 100000
+
+	// blockTimeSample is the number of most recent blocks the network hashrate
+	// is estimated over, of all header versions.
+	blockTimeSample = 128
+)
+
+// EraWork is the work of the blocks of a range sealed with one header version.
+// The difficulties of different hash algorithms aren't comparable, so the work
+// of each is reported apart.
+type EraWork struct {
+	Version    hexutil.Uint64 `json:"version"`
+	Algorithm  string         `json:"algorithm"`
+	Blocks     hexutil.Uint64 `json:"blocks"`
+	FirstBlock hexutil.Uint64 `json:"firstBlock"`
+	LastBlock  hexutil.Uint64 `json:"lastBlock"`
+	Work       *hexutil.Big   `json:"work"` // Sum of the block difficulties, in expected hashes
+}
+
+// ReorgCost estimates the work to replace the most recent blocks of the chain
+// at the current difficulty, for setting confirmation requirements.
+type ReorgCost struct {
+	Blocks     hexutil.Uint64 `json:"blocks"`
+	Algorithm  string         `json:"algorithm"`  // Hash algorithm of the head block
+	Difficulty *hexutil.Big   `json:"difficulty"` // Difficulty of the head block
+	Work       *hexutil.Big   `json:"work"`       // Expected hashes to mine the blocks
+	BlockTime  hexutil.Uint64 `json:"blockTime"`  // Average seconds between the recent blocks
+	Hashrate   *hexutil.Big   `json:"hashrate"`   // Estimated network hashrate, in hashes per second
+}
+
+// ChainWork is the work of a range of canonical blocks.
+type ChainWork struct {
+	From  hexutil.Uint64 `json:"from"`
+	To    hexutil.Uint64 `json:"to"`
+	Eras  []EraWork      `json:"eras"`
+	Reorg ReorgCost      `json:"reorg"`
+}
+
+// GetChainWork reports the work of the canonical blocks in a range, per header
+// version, along with an estimate of the work to reorg a number of most recent
+// blocks (as many as in the range by default) at the current difficulty.
+func (api *PublicAquaChainAPI) GetChainWork(from, to rpc.BlockNumber, depth *hexutil.Uint64) (*ChainWork, error) {
+	chain := api.e.BlockChain()
+	head := chain.CurrentHeader().Number.Uint64()
+
+	resolve := func(number rpc.BlockNumber) uint64 {
+		if number < 0 || uint64(number) > head {
+			return head // Pending and latest, the chain doesn't go further anyway
+		}
+		return uint64(number)
+	}
+	start, end := resolve(from), resolve(to)
+	if start > end {
+		return nil, errors.New("invalid block range")
+	}
+	if end-start >= maxChainWorkRange {
+		return nil, fmt.Errorf("too many blocks requested, max %d", maxChainWorkRange)
+	}
+	blocks := end - start + 1
+	if depth != nil {
+		blocks = uint64(*depth)
+	}
+	return chainWork(chain, start, end, blocks)
+}
+
+// chainWork sums up the work of the canonical blocks in the range [from, to],
+// and estimates the work to reorg the given number of most recent blocks.
+func chainWork(chain consensus.ChainReader, from, to, depth uint64) (*ChainWork, error) {
+	var (
+		config = chain.Config()
+		res    = &ChainWork{From: hexutil.Uint64(from), To: hexutil.Uint64(to)}
+		index  = make(map[types.HeaderVersion]int)
+	)
+	for number := from; number <= to; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		version := types.BlockVersion(config, header)
+		i, ok := index[version]
+		if !ok {
+			i = len(res.Eras)
+			index[version] = i
+			res.Eras = append(res.Eras, EraWork{
+				Version:    hexutil.Uint64(version),
+				Algorithm:  algorithmName(version),
+				FirstBlock: hexutil.Uint64(number),
+				Work:       (*hexutil.Big)(new(big.Int)),
+			})
+		}
+		era := &res.Eras[i]
+		era.Blocks++
+		era.LastBlock = hexutil.Uint64(number)
+		(*big.Int)(era.Work).Add((*big.Int)(era.Work), header.Difficulty)
+	}
+	// Estimate the hashrate of the head's algorithm from the recent blocks
+	head := chain.CurrentHeader()
+	version := types.BlockVersion(config, head)
+
+	blockTime := params.DurationLimit.Uint64()
+	if first, last, blocks := recentBlockTimes(chain, head, version); blocks > 1 && last > first {
+		blockTime = (last - first) / (blocks - 1)
+	}
+	if blockTime == 0 {
+		blockTime = 1
+	}
+	res.Reorg = ReorgCost{
+		Blocks:     hexutil.Uint64(depth),
+		Algorithm:  algorithmName(version),
+		Difficulty: (*hexutil.Big)(new(big.Int).Set(head.Difficulty)),
+		Work:       (*hexutil.Big)(new(big.Int).Mul(head.Difficulty, new(big.Int).SetUint64(depth))),
+		BlockTime:  hexutil.Uint64(blockTime),
+		Hashrate:   (*hexutil.Big)(new(big.Int).Div(head.Difficulty, new(big.Int).SetUint64(blockTime))),
+	}
+	return res, nil
+}
+
+// recentBlockTimes returns the timestamps of the oldest and newest of the most
+// recent blocks sealed with the given header version, and their number.
+func recentBlockTimes(chain consensus.ChainReader, head *types.Header, version types.HeaderVersion) (first, last, blocks uint64) {
+	config := chain.Config()
+	for i, header := 0, head; header != nil && i < blockTimeSample; i++ {
+		if types.BlockVersion(config, header) == version {
+			if blocks == 0 {
+				last = header.Time.Uint64()
+			}
+			first = header.Time.Uint64()
+			blocks++
+		}
+		if header.Number.Sign() == 0 {
+			break
+		}
+		header = chain.GetHeaderByNumber(header.Number.Uint64() - 1)
+	}
+	return first, last, blocks
+}
+
+// algorithmName returns the name of the hash algorithm of a header version.
+func algorithmName(version types.HeaderVersion) string {
+	if codec, err := types.LookupHeaderCodec(version); err == nil {
+		return codec.Name
+	}
+	return "unknown"
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+func TestChainWork(t *testing.T) {
+	var (
+		db, _   = aquadb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, aquahash.NewFaker(), db, 20, nil)
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	work, err := chainWork(chain, 2, 20, 6)
+	if err != nil {
+		t.Fatalf("failed to sum up chain work: %v", err)
+	}
+	// The test chain switches from keccak256 to argon2id at HF5
+	want := []struct {
+		version     types.HeaderVersion
+		first, last uint64
+	}{
+		{types.H_KECCAK256, 2, 4},
+		{types.H_ARGON2ID, 5, 20},
+	}
+	if len(work.Eras) != len(want) {
+		t.Fatalf("era count mismatch: have %d, want %d", len(work.Eras), len(want))
+	}
+	for i, era := range work.Eras {
+		if types.HeaderVersion(era.Version) != want[i].version || uint64(era.FirstBlock) != want[i].first || uint64(era.LastBlock) != want[i].last {
+			t.Errorf("era %d: have version %d blocks %d-%d, want version %d blocks %d-%d", i, era.Version, era.FirstBlock, era.LastBlock, want[i].version, want[i].first, want[i].last)
+		}
+		if uint64(era.Blocks) != want[i].last-want[i].first+1 {
+			t.Errorf("era %d: block count mismatch: have %d", i, era.Blocks)
+		}
+		sum := new(big.Int)
+		for n := want[i].first; n <= want[i].last; n++ {
+			sum.Add(sum, chain.GetHeaderByNumber(n).Difficulty)
+		}
+		if (*big.Int)(era.Work).Cmp(sum) != 0 {
+			t.Errorf("era %d: work mismatch: have %v, want %v", i, era.Work, sum)
+		}
+	}
+	head := chain.CurrentHeader()
+	if work.Reorg.Algorithm != "argon2id" {
+		t.Errorf("reorg algorithm mismatch: have %s, want argon2id", work.Reorg.Algorithm)
+	}
+	if want := new(big.Int).Mul(head.Difficulty, big.NewInt(6)); (*big.Int)(work.Reorg.Work).Cmp(want) != 0 {
+		t.Errorf("reorg work mismatch: have %v, want %v", work.Reorg.Work, want)
+	}
+	blockTime := (head.Time.Uint64() - chain.GetHeaderByNumber(5).Time.Uint64()) / 15
+	if uint64(work.Reorg.BlockTime) != blockTime {
+		t.Errorf("block time mismatch: have %d, want %d", work.Reorg.BlockTime, blockTime)
+	}
+	if want := new(big.Int).Div(head.Difficulty, new(big.Int).SetUint64(blockTime)); (*big.Int)(work.Reorg.Hashrate).Cmp(want) != 0 {
+		t.Errorf("hashrate mismatch: have %v, want %v", work.Reorg.Hashrate, want)
+	}
+}
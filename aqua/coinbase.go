@@ -0,0 +1,84 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aquanetwork/aquachain/common"
+)
+
+var (
+	// ErrZeroAquabase is returned when mining to the zero address is requested.
+	ErrZeroAquabase = errors.New("refusing to mine to the zero address")
+
+	// ErrBurnAquabase is returned when mining to a well known burn address is
+	// requested.
+	ErrBurnAquabase = errors.New("refusing to mine to a known burn address")
+
+	// ErrAquabaseChecksum is returned when a mixed case address fails its
+	// checksum, which is most likely caused by a typo.
+	ErrAquabaseChecksum = errors.New("invalid address checksum")
+)
+
+// burnAddresses are well known addresses nobody holds the key of, rewards sent
+// to them are lost forever.
+var burnAddresses = map[common.Address]bool{
+	common.HexToAddress("0x0000000000000000000000000000000000000001"): true,
+	common.HexToAddress("0x000000000000000000000000000000000000dEaD"): true,
+	common.HexToAddress("0xdead000000000000000000000000000000000000"): true,
+	common.HexToAddress("0xffffffffffffffffffffffffffffffffffffffff"): true,
+}
+
+// IsBurnAddress reports whether the address is a well known burn address.
+func IsBurnAddress(addr common.Address) bool {
+	return burnAddresses[addr]
+}
+
+// CheckAquabase validates an address for receiving mining rewards, refusing the
+// zero address and known burn addresses unless forced.
+func CheckAquabase(addr common.Address, force bool) error {
+	if force {
+		return nil
+	}
+	switch {
+	case addr == (common.Address{}):
+		return ErrZeroAquabase
+	case IsBurnAddress(addr):
+		return fmt.Errorf("%v: %s", ErrBurnAquabase, addr.Hex())
+	}
+	return nil
+}
+
+// ParseAquabase parses a hex address, verifying its checksum if it is given in
+// mixed case.
+func ParseAquabase(s string) (common.Address, error) {
+	if !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("invalid address %q", s)
+	}
+	addr := common.HexToAddress(s)
+
+	hex := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if hex != strings.ToLower(hex) && hex != strings.ToUpper(hex) {
+		if want := addr.Hex(); want[2:] != hex {
+			return common.Address{}, fmt.Errorf("%v: have %s, want %s", ErrAquabaseChecksum, s, want)
+		}
+	}
+	return addr, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+)
+
+func TestParseAquabase(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{"0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8", true},  // lower case, no checksum
+		{"0x7EF5A6135F1FD6A02593EEDC869C6D41D934AEF8", true},  // upper case, no checksum
+		{"0x7EF5A6135f1FD6a02593eEdC869c6D41D934aef8", true},  // valid checksum
+		{"0x7EF5A6135f1FD6a02593eEdC869c6D41D934aeF8", false}, // invalid checksum
+		{"0x7ef5a6135f1fd6a02593eedc869c6d41d934ae", false},   // too short
+	}
+	for i, tt := range tests {
+		addr, err := ParseAquabase(tt.input)
+		if (err == nil) != tt.ok {
+			t.Errorf("test %d: error mismatch: have %v, want ok %v", i, err, tt.ok)
+		}
+		if err == nil && addr != common.HexToAddress(tt.input) {
+			t.Errorf("test %d: address mismatch: have %x", i, addr)
+		}
+	}
+}
+
+func TestCheckAquabase(t *testing.T) {
+	var (
+		zero = common.Address{}
+		burn = common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+		good = common.HexToAddress("0x7EF5A6135f1FD6a02593eEdC869c6D41D934aef8")
+	)
+	if err := CheckAquabase(zero, false); err != ErrZeroAquabase {
+		t.Errorf("zero address: have %v, want %v", err, ErrZeroAquabase)
+	}
+	if err := CheckAquabase(burn, false); err == nil {
+		t.Errorf("burn address accepted")
+	}
+	if err := CheckAquabase(good, false); err != nil {
+		t.Errorf("valid address rejected: %v", err)
+	}
+	for _, addr := range []common.Address{zero, burn} {
+		if err := CheckAquabase(addr, true); err != nil {
+			t.Errorf("forced address %x rejected: %v", addr, err)
+		}
+	}
+}
@@ -26,10 +26,13 @@ import (
 
 	"github.com/aquanetwork/aquachain/aqua/downloader"
 	"github.com/aquanetwork/aquachain/aqua/gasprice"
+	"github.com/aquanetwork/aquachain/aqua/scheduler"
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/hexutil"
 	"github.com/aquanetwork/aquachain/consensus/aquahash"
 	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/miner"
 )
 
 // DefaultConfig contains default settings for use on the AquaChain main net.
@@ -48,12 +51,19 @@ var DefaultConfig = Config{
 	TrieCache:     256,
 	TrieTimeout:   5 * time.Minute,
 	GasPrice:      big.NewInt(100000000), // 0.1 gwei
+	TieBreak:      core.TieBreakRandom,
+	Uncles:        miner.DefaultUnclePolicy,
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
 		Blocks:     20,
 		Percentile: 60,
 	},
+	RPCMemoryLimit: 32 * 1024 * 1024,
+	RPCGasCap:      50000000,
+	RPCTraceLimit:  250000,
+	RPCReexec:      128,
+	ForensicsDir:   "forensics",
 }
 
 func init() {
@@ -82,6 +92,22 @@ type Config struct {
 	SyncMode  downloader.SyncMode
 	NoPruning bool
 
+	// Number of recent blocks to keep transaction lookup entries for, zero
+	// keeping them for the whole chain
+	TxLookupLimit uint64 `toml:",omitempty"`
+
+	// Whether to maintain a flat snapshot of the state, serving the account and
+	// storage reads without walking the tries
+	Snapshot bool `toml:",omitempty"`
+
+	// Whether to skip executing the next block of an import on a throwaway state
+	// while processing the current one, pre-caching the state it touches
+	NoPrefetch bool `toml:",omitempty"`
+
+	// Whether to index the balance and nonce changes of the accounts, serving
+	// their historical values without the state of the blocks
+	AccountHistory bool `toml:",omitempty"`
+
 	// Light client options
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
@@ -90,14 +116,33 @@ type Config struct {
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
+
+	// Compresses the block bodies and receipts written to the database with
+	// snappy, those already stored are read alike either way
+	StorageCompression bool `toml:",omitempty"`
 	TrieCache          int
 	TrieTimeout        time.Duration
 
 	// Mining-related options
-	Aquabase     common.Address `toml:",omitempty"`
-	MinerThreads int            `toml:",omitempty"`
-	ExtraData    []byte         `toml:",omitempty"`
-	GasPrice     *big.Int
+	Mining        bool             `toml:",omitempty"` // Start mining when the node starts
+	Aquabase      common.Address   `toml:",omitempty"`
+	Aquabases     []common.Address `toml:",omitempty"` // Addresses the block rewards rotate through by block number, overriding Aquabase
+	MinerThreads  int              `toml:",omitempty"`
+	ExtraData     []byte           `toml:",omitempty"`
+	MinerPeers    bool             `toml:",omitempty"` // Keep direct connections to announced miners
+	ForkGuard     bool             `toml:",omitempty"` // Pause mining while on a suspected minority fork
+	StaleGrace    bool             `toml:",omitempty"` // Accept remote solutions for the work handed out before the head changed
+	ForceAquabase bool             `toml:",omitempty"` // Allow mining to the zero address and known burn addresses
+	TieBreak      core.TieBreak    `toml:",omitempty"` // Rule choosing between competing blocks of equal total difficulty
+	GasPrice      *big.Int
+
+	// Research options altering the announcement of mined blocks, refused on
+	// the main network
+	AnnounceDelay  time.Duration `toml:",omitempty"` // Delay before broadcasting mined blocks
+	WithholdBlocks bool          `toml:",omitempty"` // Withhold mined blocks until the network catches up (selfish mining)
+
+	// Uncle inclusion policy of the miner
+	Uncles miner.UnclePolicy
 
 	// Aquahash options
 	Aquahash aquahash.Config
@@ -111,10 +156,56 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Directory of the forensic bundles dumped for the blocks whose state root
+	// mismatches, resolved within the data directory. Empty disables them.
+	ForensicsDir string `toml:",omitempty"`
+
+	// Limits on EVM executions serving RPC calls and traces, zero disables a
+	// limit. These never affect block processing.
+	RPCCallDepth   int    `toml:",omitempty"` // Maximum call depth, below the protocol limit
+	RPCMemoryLimit uint64 `toml:",omitempty"` // Maximum memory per call frame in bytes
+	RPCTraceLimit  int    `toml:",omitempty"` // Maximum number of struct logs per trace
+
+	// Maximum gas of aqua_estimateGas, zero bounds estimates by the block gas
+	// limit only
+	RPCGasCap uint64 `toml:",omitempty"`
+
+	// Maximum gas of aqua_call, zero (the default) leaves calls unmetered and
+	// bounded by a timeout only
+	RPCCallGasCap uint64 `toml:",omitempty"`
+
+	// Maximum number of blocks re-executed to regenerate a pruned historical
+	// state queried over RPC, zero disables regeneration
+	RPCReexec uint64
+
+	// Accepts transactions without EIP-155 replay protection over RPC once the
+	// chain enforces it, letting them be replayed on other chains
+	RPCAllowUnprotectedTxs bool `toml:",omitempty"`
+
+	// Webhook notified, with an HTTP POST, of the status changes of the
+	// transactions submitted over RPC with watch set
+	RPCTxWebhook string `toml:",omitempty"`
+
+	// Disables the personal API, leaving sessions as the only way to sign via RPC
+	NoPersonal bool `toml:",omitempty"`
+
+	// Contract metadata registry to periodically import verified contracts from
+	ContractRegistry string `toml:",omitempty"`
+
+	// Periodic maintenance tasks run within the node
+	Maintenance []scheduler.Schedule `toml:",omitempty"`
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 }
 
+// RPCVMConfig applies the RPC execution limits to an EVM configuration.
+func (c *Config) RPCVMConfig(cfg vm.Config) vm.Config {
+	cfg.MaxCallDepth = c.RPCCallDepth
+	cfg.MaxMemory = c.RPCMemoryLimit
+	return cfg
+}
+
 type configMarshaling struct {
 	ExtraData hexutil.Bytes
 }
@@ -0,0 +1,70 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package contractmeta
+
+import (
+	"context"
+
+	"github.com/aquanetwork/aquachain/common"
+)
+
+// PublicContractMetaAPI exposes verified contract metadata to anyone.
+type PublicContractMetaAPI struct {
+	store *Store
+}
+
+// NewPublicContractMetaAPI creates a new public contract metadata API.
+func NewPublicContractMetaAPI(store *Store) *PublicContractMetaAPI {
+	return &PublicContractMetaAPI{store}
+}
+
+// GetContractMetadata returns the verified metadata of the contract at the
+// given address, or nil if no metadata has been registered.
+func (api *PublicContractMetaAPI) GetContractMetadata(addr common.Address) (*Metadata, error) {
+	return api.store.Get(addr)
+}
+
+// PrivateContractMetaAPI allows node operators to manage the metadata store.
+type PrivateContractMetaAPI struct {
+	store *Store
+}
+
+// NewPrivateContractMetaAPI creates a new private contract metadata API.
+func NewPrivateContractMetaAPI(store *Store) *PrivateContractMetaAPI {
+	return &PrivateContractMetaAPI{store}
+}
+
+// RegisterContractMetadata verifies and stores the metadata of a deployed contract.
+func (api *PrivateContractMetaAPI) RegisterContractMetadata(meta Metadata) (bool, error) {
+	if err := api.store.Register(&meta); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveContractMetadata drops the metadata registered for an address.
+func (api *PrivateContractMetaAPI) RemoveContractMetadata(addr common.Address) (bool, error) {
+	if err := api.store.Delete(addr); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SyncContractMetadata imports all verifiable entries from a registry URL.
+func (api *PrivateContractMetaAPI) SyncContractMetadata(ctx context.Context, url string) (SyncResult, error) {
+	return api.store.Sync(ctx, url)
+}
@@ -0,0 +1,173 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package contractmeta implements a local store for verified contract metadata
+// (source hash, compiler settings and ABI) that explorers can query without
+// relying on central infrastructure.
+package contractmeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/log"
+)
+
+var metadataPrefix = []byte("contract-meta-") // metadataPrefix + address -> json encoded metadata
+
+var (
+	errNoCode           = errors.New("no contract code at address")
+	errCodeHashMismatch = errors.New("code hash does not match deployed contract")
+	errMissingABI       = errors.New("missing contract ABI")
+	errRegistered       = errors.New("different metadata already registered for contract")
+)
+
+// Metadata describes the verified source of a deployed contract.
+type Metadata struct {
+	Address         common.Address  `json:"address"`
+	CodeHash        common.Hash     `json:"codeHash"`        // Hash of the deployed runtime bytecode
+	SourceHash      common.Hash     `json:"sourceHash"`      // Keccak256 of the verified source
+	Name            string          `json:"name,omitempty"`  // Contract name within the source unit
+	Compiler        string          `json:"compiler"`        // Compiler identifier (e.g. "solc")
+	CompilerVersion string          `json:"compilerVersion"` // Full compiler version string
+	Optimizer       bool            `json:"optimizer"`       // Whether the optimizer was enabled
+	OptimizerRuns   uint64          `json:"optimizerRuns,omitempty"`
+	ABI             json.RawMessage `json:"abi"`
+	Source          string          `json:"source,omitempty"` // Optional full source, checked against SourceHash
+}
+
+// CodeHashFunc returns the hash of the code currently deployed at an address,
+// or the empty hash if no code exists.
+type CodeHashFunc func(addr common.Address) (common.Hash, error)
+
+// Store is a database backed registry of contract metadata.
+type Store struct {
+	db       aquadb.Database
+	codeHash CodeHashFunc
+	lock     sync.Mutex // Serializes lookups and registrations
+}
+
+// NewStore creates a metadata store on top of the given database. The code hash
+// function is used to check registrations against the deployed contracts.
+func NewStore(db aquadb.Database, codeHash CodeHashFunc) *Store {
+	return &Store{db: db, codeHash: codeHash}
+}
+
+func metadataKey(addr common.Address) []byte {
+	return append(append([]byte{}, metadataPrefix...), addr.Bytes()...)
+}
+
+// Get retrieves the metadata registered for an address, nil if none is known.
+func (s *Store) Get(addr common.Address) (*Metadata, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	data, err := s.get(addr)
+	if data == nil || err != nil {
+		return nil, err
+	}
+	meta := new(Metadata)
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// get retrieves the encoded metadata registered for an address, nil if none is
+// known. The caller must hold the lock.
+func (s *Store) get(addr common.Address) ([]byte, error) {
+	key := metadataKey(addr)
+	if has, err := s.db.Has(key); !has || err != nil {
+		return nil, err
+	}
+	return s.db.Get(key)
+}
+
+// Register validates the metadata against the deployed contract and stores it.
+// Registering different metadata for an already registered contract fails, the
+// previous entry has to be deleted first.
+func (s *Store) Register(meta *Metadata) error {
+	if err := s.verify(meta); err != nil {
+		return err
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	prev, err := s.get(meta.Address)
+	if err != nil {
+		return err
+	}
+	if prev != nil {
+		if bytes.Equal(prev, data) {
+			return nil
+		}
+		return errRegistered
+	}
+	if err := s.db.Put(metadataKey(meta.Address), data); err != nil {
+		return err
+	}
+	log.Debug("Registered contract metadata", "address", meta.Address, "name", meta.Name, "compiler", meta.CompilerVersion)
+	return nil
+}
+
+// Delete drops the metadata registered for an address.
+func (s *Store) Delete(addr common.Address) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.db.Delete(metadataKey(addr))
+}
+
+// verify checks that the metadata refers to the contract actually deployed at
+// its address and that the optional source matches the declared hash. The source
+// is not compiled, so nothing ties the source and ABI to the deployed bytecode.
+func (s *Store) verify(meta *Metadata) error {
+	if len(meta.ABI) == 0 {
+		return errMissingABI
+	}
+	if !json.Valid(meta.ABI) {
+		return fmt.Errorf("invalid contract ABI")
+	}
+	if meta.Source != "" {
+		if hash := crypto.Keccak256Hash([]byte(meta.Source)); hash != meta.SourceHash {
+			return fmt.Errorf("source hash mismatch: have %x, want %x", hash, meta.SourceHash)
+		}
+	}
+	if s.codeHash == nil {
+		return nil
+	}
+	have, err := s.codeHash(meta.Address)
+	if err != nil {
+		return err
+	}
+	if have == (common.Hash{}) || have == crypto.Keccak256Hash(nil) {
+		return errNoCode
+	}
+	if have != meta.CodeHash {
+		return errCodeHashMismatch
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package contractmeta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/crypto"
+)
+
+var (
+	deployed   = common.HexToAddress("0x0000000000000000000000000000000000001000")
+	undeployed = common.HexToAddress("0x0000000000000000000000000000000000002000")
+	codeHash   = crypto.Keccak256Hash([]byte{0x60, 0x00})
+)
+
+func newTestStore() *Store {
+	db, _ := aquadb.NewMemDatabase()
+	return NewStore(db, func(addr common.Address) (common.Hash, error) {
+		if addr == deployed {
+			return codeHash, nil
+		}
+		return common.Hash{}, nil
+	})
+}
+
+func testMetadata(addr common.Address, hash common.Hash) *Metadata {
+	source := "contract Test {}"
+	return &Metadata{
+		Address:         addr,
+		CodeHash:        hash,
+		SourceHash:      crypto.Keccak256Hash([]byte(source)),
+		Name:            "Test",
+		Compiler:        "solc",
+		CompilerVersion: "0.4.24+commit.e67f0147",
+		ABI:             json.RawMessage(`[]`),
+		Source:          source,
+	}
+}
+
+func TestRegister(t *testing.T) {
+	store := newTestStore()
+
+	if err := store.Register(testMetadata(deployed, codeHash)); err != nil {
+		t.Fatalf("failed to register metadata: %v", err)
+	}
+	meta, err := store.Get(deployed)
+	if err != nil {
+		t.Fatalf("failed to retrieve metadata: %v", err)
+	}
+	if meta == nil || meta.CodeHash != codeHash || meta.Name != "Test" {
+		t.Fatalf("metadata mismatch: have %+v", meta)
+	}
+	if err := store.Delete(deployed); err != nil {
+		t.Fatalf("failed to delete metadata: %v", err)
+	}
+	if meta, _ := store.Get(deployed); meta != nil {
+		t.Fatalf("metadata still present after delete: %+v", meta)
+	}
+}
+
+func TestRegisterRejects(t *testing.T) {
+	store := newTestStore()
+
+	tests := []struct {
+		meta *Metadata
+		err  error
+	}{
+		{testMetadata(undeployed, codeHash), errNoCode},
+		{testMetadata(deployed, common.Hash{1}), errCodeHashMismatch},
+		{&Metadata{Address: deployed, CodeHash: codeHash}, errMissingABI},
+	}
+	for i, tt := range tests {
+		if err := store.Register(tt.meta); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	bad := testMetadata(deployed, codeHash)
+	bad.Source = "contract Other {}"
+	if err := store.Register(bad); err == nil {
+		t.Errorf("registered metadata with mismatching source")
+	}
+}
+
+// Tests that registered metadata can't be replaced by different metadata, only
+// registered again unchanged.
+func TestRegisterOverwrite(t *testing.T) {
+	store := newTestStore()
+
+	meta := testMetadata(deployed, codeHash)
+	if err := store.Register(meta); err != nil {
+		t.Fatalf("failed to register metadata: %v", err)
+	}
+	if err := store.Register(testMetadata(deployed, codeHash)); err != nil {
+		t.Fatalf("failed to register identical metadata: %v", err)
+	}
+	other := testMetadata(deployed, codeHash)
+	other.ABI = json.RawMessage(`[{"type":"fallback"}]`)
+	if err := store.Register(other); err != errRegistered {
+		t.Fatalf("error mismatch: have %v, want %v", err, errRegistered)
+	}
+	if have, _ := store.Get(deployed); string(have.ABI) != string(meta.ABI) {
+		t.Fatalf("ABI mismatch: have %s, want %s", have.ABI, meta.ABI)
+	}
+	store.Delete(deployed)
+	if err := store.Register(other); err != nil {
+		t.Fatalf("failed to register metadata after delete: %v", err)
+	}
+}
+
+// failingDB is a database failing all lookups.
+type failingDB struct {
+	*aquadb.MemDatabase
+}
+
+var errFailingDB = errors.New("database failure")
+
+func (db failingDB) Has(key []byte) (bool, error) { return false, errFailingDB }
+
+// Tests that database failures are reported instead of missing metadata.
+func TestGetError(t *testing.T) {
+	db, _ := aquadb.NewMemDatabase()
+	store := NewStore(failingDB{db}, nil)
+
+	if meta, err := store.Get(deployed); err != errFailingDB {
+		t.Fatalf("error mismatch: have %v (%v), want %v", err, meta, errFailingDB)
+	}
+	if err := store.Register(testMetadata(deployed, codeHash)); err != errFailingDB {
+		t.Fatalf("error mismatch: have %v, want %v", err, errFailingDB)
+	}
+}
+
+func TestSync(t *testing.T) {
+	store := newTestStore()
+
+	entries := []*Metadata{testMetadata(deployed, codeHash), testMetadata(undeployed, codeHash)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(entries)
+	}))
+	defer server.Close()
+
+	result, err := store.Sync(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("failed to sync registry: %v", err)
+	}
+	if result.Imported != 1 || result.Rejected != 1 {
+		t.Fatalf("sync result mismatch: have %+v, want 1 imported, 1 rejected", result)
+	}
+	if meta, _ := store.Get(deployed); meta == nil {
+		t.Fatalf("synced metadata missing")
+	}
+	if meta, _ := store.Get(undeployed); meta != nil {
+		t.Fatalf("unverifiable metadata imported: %+v", meta)
+	}
+}
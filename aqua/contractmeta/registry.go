@@ -0,0 +1,97 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package contractmeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aquanetwork/aquachain/log"
+)
+
+const (
+	// maxRegistrySize is the maximum number of bytes accepted from a registry.
+	maxRegistrySize = 64 * 1024 * 1024
+
+	// registryTimeout is the maximum time allowed for a single registry fetch.
+	registryTimeout = time.Minute
+)
+
+// SyncResult summarizes a registry synchronisation.
+type SyncResult struct {
+	Imported int `json:"imported"` // Entries that were verified and stored
+	Rejected int `json:"rejected"` // Entries that failed verification
+}
+
+// Sync downloads a JSON array of metadata entries from a community registry and
+// registers every entry whose code hash matches the contract deployed at its
+// address. Entries of undeployed contracts, or for contracts with different
+// metadata already registered, are skipped. The sources and ABIs are not checked
+// against the bytecode, they are only as trustworthy as the registry.
+func (s *Store) Sync(ctx context.Context, url string) (SyncResult, error) {
+	var result SyncResult
+
+	ctx, cancel := context.WithTimeout(ctx, registryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return result, err
+	}
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return result, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("registry returned %s", res.Status)
+	}
+	var entries []*Metadata
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxRegistrySize)).Decode(&entries); err != nil {
+		return result, fmt.Errorf("invalid registry content: %v", err)
+	}
+	for _, meta := range entries {
+		if err := s.Register(meta); err != nil {
+			log.Debug("Rejected registry contract metadata", "address", meta.Address, "err", err)
+			result.Rejected++
+			continue
+		}
+		result.Imported++
+	}
+	log.Info("Synchronised contract metadata registry", "url", url, "imported", result.Imported, "rejected", result.Rejected)
+	return result, nil
+}
+
+// SyncLoop periodically synchronises the store with the given registry until
+// the quit channel is closed.
+func (s *Store) SyncLoop(url string, interval time.Duration, quit chan struct{}) {
+	for {
+		if _, err := s.Sync(context.Background(), url); err != nil {
+			log.Warn("Contract metadata registry sync failed", "url", url, "err", err)
+		}
+		select {
+		case <-time.After(interval):
+		case <-quit:
+			return
+		}
+	}
+}
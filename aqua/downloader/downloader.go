@@ -106,12 +106,18 @@ type Downloader struct {
 	// Statistics
 	syncStatsChainOrigin uint64 // Origin block number where syncing started at
 	syncStatsChainHeight uint64 // Highest block number known when syncing started
+	syncStatsHeaders     uint64 // Highest header processed by the current sync
+	syncStatsContent     uint64 // Highest block whose content the current sync downloaded
+	syncStatsStart       time.Time
+	syncStatsStartBlock  uint64 // Current block when the current sync started
 	syncStatsState       stateSyncStats
 	syncStatsLock        sync.RWMutex // Lock protecting the sync stats fields
 
 	lightchain LightChain
 	blockchain BlockChain
 
+	checkpoint uint64 // Latest trusted checkpoint, no reorgs are allowed below it
+
 	// Callbacks
 	dropPeer peerDropFn // Drops a peer for misbehaving
 
@@ -233,6 +239,13 @@ func New(mode SyncMode, stateDb aquadb.Database, mux *event.TypeMux, chain Block
 	return dl
 }
 
+// SetCheckpoint sets the latest trusted checkpoint. Once the local chain has
+// reached it, peers whose chain forks off below it are rejected immediately.
+// It must be called before synchronisation starts.
+func (d *Downloader) SetCheckpoint(number uint64) {
+	d.checkpoint = number
+}
+
 // Progress retrieves the synchronisation boundaries, specifically the origin
 // block where synchronisation started at (may have failed/suspended); the block
 // or header sync is currently at; and the latest known block which the sync targets.
@@ -245,18 +258,9 @@ func (d *Downloader) Progress() aquachain.SyncProgress {
 	d.syncStatsLock.RLock()
 	defer d.syncStatsLock.RUnlock()
 
-	current := uint64(0)
-	switch d.mode {
-	case FullSync:
-		current = d.blockchain.CurrentBlock().NumberU64()
-	case FastSync:
-		current = d.blockchain.CurrentFastBlock().NumberU64()
-	case LightSync:
-		current = d.lightchain.CurrentHeader().Number.Uint64()
-	}
 	return aquachain.SyncProgress{
 		StartingBlock: d.syncStatsChainOrigin,
-		CurrentBlock:  current,
+		CurrentBlock:  d.currentBlock(),
 		HighestBlock:  d.syncStatsChainHeight,
 		PulledStates:  d.syncStatsState.processed,
 		KnownStates:   d.syncStatsState.processed + d.syncStatsState.pending,
@@ -336,6 +340,16 @@ func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode
 	return err
 }
 
+// IsInvalidChainError reports whether a synchronisation error is caused by the
+// chain the peer served, rather than by the peer being slow or unavailable.
+func IsInvalidChainError(err error) bool {
+	switch err {
+	case errBadPeer, errEmptyHeaderSet, errInvalidAncestor, errInvalidChain:
+		return true
+	}
+	return false
+}
+
 // synchronise will select the peer and use it for synchronising. If an empty string is given
 // it will use the best peer possible and synchronize if its TD is higher than our own. If any of the
 // checks fail an error will be returned. This method is synchronous
@@ -436,6 +450,8 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 		d.syncStatsChainOrigin = origin
 	}
 	d.syncStatsChainHeight = height
+	d.syncStatsHeaders, d.syncStatsContent = origin, origin
+	d.syncStatsStart, d.syncStatsStartBlock = time.Now(), d.currentBlock()
 	d.syncStatsLock.Unlock()
 
 	// Ensure our origin point is below any fast sync pivot point
@@ -596,6 +612,10 @@ func (d *Downloader) findAncestor(p *peerConnection, height uint64) (uint64, err
 	if ceil >= MaxForkAncestry {
 		floor = int64(ceil - MaxForkAncestry)
 	}
+	// Chains forking off before a locally reached checkpoint are never valid
+	if ceil >= d.checkpoint && int64(d.checkpoint)-1 > floor {
+		floor = int64(d.checkpoint) - 1
+	}
 	p.log.Debug("Looking for common ancestor", "local", ceil, "remote", height)
 
 	// Request the topmost blocks to short circuit binary ancestor lookup
@@ -1154,7 +1174,9 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 // keeps processing and scheduling them into the header chain and downloader's
 // queue until the stream ends or a failure occurs.
 func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) error {
-	// Keep a count of uncertain headers to roll back
+	// Keep a count of uncertain headers to roll back. They're rolled back on
+	// shutdowns too, as their seals weren't all verified: the next sync resumes
+	// from the last certain header.
 	rollback := []*types.Header{}
 	defer func() {
 		if len(rollback) > 0 {
@@ -1211,7 +1233,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				// L: Sync begins, and finds common ancestor at 11
 				// L: Request new headers up from 11 (R's TD was higher, it must have something)
 				// R: Nothing to give
-				if d.mode != LightSync {
+				if !d.mode.headersOnly() {
 					head := d.blockchain.CurrentBlock()
 					if !gotHeaders && td.Cmp(d.blockchain.GetTd(head.SetVersion(d.blockchain.RetrieveHeaderVersion(head.Number())), head.NumberU64())) > 0 {
 						return errStallingPeer
@@ -1224,7 +1246,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				// This check cannot be executed "as is" for full imports, since blocks may still be
 				// queued for processing when the header download completes. However, as long as the
 				// peer gave us something useful, we're already happy/progressed (above check).
-				if d.mode == FastSync || d.mode == LightSync {
+				if d.mode == FastSync || d.mode.headersOnly() {
 					head := d.lightchain.CurrentHeader()
 					if td.Cmp(d.lightchain.GetTd(head.Hash(), head.Number.Uint64())) > 0 {
 						return errStallingPeer
@@ -1252,7 +1274,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				chunk := headers[:limit]
 
 				// In case of header only syncing, validate the chunk immediately
-				if d.mode == FastSync || d.mode == LightSync {
+				if d.mode == FastSync || d.mode.headersOnly() {
 					// Collect the yet unknown headers to mark them as uncertain
 					unknown := make([]*types.Header, 0, len(headers))
 					for _, header := range chunk {
@@ -1261,9 +1283,10 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 							unknown = append(unknown, header)
 						}
 					}
-					// If we're importing pure headers, verify based on their recentness
+					// If we're importing pure headers, verify based on their recentness,
+					// unless header syncing where every single seal is checked
 					frequency := fsHeaderCheckFrequency
-					if chunk[len(chunk)-1].Number.Uint64()+uint64(fsHeaderForceVerify) > pivot {
+					if d.mode == HeaderSync || chunk[len(chunk)-1].Number.Uint64()+uint64(fsHeaderForceVerify) > pivot {
 						frequency = 1
 					}
 					if n, err := d.lightchain.InsertHeaderChain(chunk, frequency); err != nil {
@@ -1306,6 +1329,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 			if d.syncStatsChainHeight < origin {
 				d.syncStatsChainHeight = origin - 1
 			}
+			d.syncStatsHeaders = origin - 1
 			d.syncStatsLock.Unlock()
 
 			// Signal the content downloaders of the availablility of new tasks
@@ -1326,6 +1350,7 @@ func (d *Downloader) processFullSyncContent() error {
 		if len(results) == 0 {
 			return nil
 		}
+		d.contentDownloaded(results)
 		if d.chainInsertHook != nil {
 			d.chainInsertHook(results)
 		}
@@ -1402,6 +1427,7 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 			default:
 			}
 		}
+		d.contentDownloaded(results)
 		if d.chainInsertHook != nil {
 			d.chainInsertHook(results)
 		}
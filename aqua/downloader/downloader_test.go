@@ -630,7 +630,7 @@ func assertOwnForkedChain(t *testing.T, tester *downloadTester, common int, leng
 	switch tester.downloader.mode {
 	case FullSync:
 		receipts = 1
-	case LightSync:
+	case LightSync, HeaderSync:
 		blocks, receipts = 1, 1
 	}
 	if hs := len(tester.ownHeaders); hs != headers {
@@ -669,6 +669,10 @@ func TestCanonicalSynchronisation64Full(t *testing.T)  { testCanonicalSynchronis
 func TestCanonicalSynchronisation64Fast(t *testing.T)  { testCanonicalSynchronisation(t, 64, FastSync) }
 func TestCanonicalSynchronisation64Light(t *testing.T) { testCanonicalSynchronisation(t, 64, LightSync) }
 
+func TestCanonicalSynchronisation64Headers(t *testing.T) {
+	testCanonicalSynchronisation(t, 64, HeaderSync)
+}
+
 func testCanonicalSynchronisation(t *testing.T, protocol int, mode SyncMode) {
 	t.Parallel()
 
@@ -782,6 +786,8 @@ func TestForkedSync64Full(t *testing.T)  { testForkedSync(t, 64, FullSync) }
 func TestForkedSync64Fast(t *testing.T)  { testForkedSync(t, 64, FastSync) }
 func TestForkedSync64Light(t *testing.T) { testForkedSync(t, 64, LightSync) }
 
+func TestForkedSync64Headers(t *testing.T) { testForkedSync(t, 64, HeaderSync) }
+
 func testForkedSync(t *testing.T, protocol int, mode SyncMode) {
 	t.Parallel()
 
@@ -1267,6 +1273,62 @@ func testInvalidHeaderRollback(t *testing.T, protocol int, mode SyncMode) {
 	}
 }
 
+// terminatingChain is a chain terminating its downloader once a given number of
+// headers was inserted, simulating a shutdown in the middle of a sync.
+type terminatingChain struct {
+	*downloadTester
+	limit   uint64 // Header number to terminate the downloader at
+	highest uint64 // Highest header inserted into the chain
+	once    sync.Once
+}
+
+func (c *terminatingChain) InsertHeaderChain(headers []*types.Header, checkFreq int) (int, error) {
+	n, err := c.downloadTester.InsertHeaderChain(headers, checkFreq)
+	if head := c.downloadTester.CurrentHeader().Number.Uint64(); head > c.highest {
+		c.highest = head
+	}
+	if c.highest >= c.limit {
+		c.once.Do(c.downloadTester.downloader.Terminate)
+	}
+	return n, err
+}
+
+// Tests that the uncertain headers of a synchronisation interrupted by a shutdown
+// are rolled back, and that the next synchronisation resumes from the remaining
+// ones after a restart.
+func TestTerminateRollsBackHeaders64Fast(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	targetBlocks := 3*fsHeaderSafetyNet + 256 + fsMinFullBlocks
+	hashes, headers, blocks, receipts := tester.makeChain(targetBlocks, 0, tester.genesis, nil, false)
+
+	chain := &terminatingChain{downloadTester: tester, limit: uint64(2 * fsHeaderSafetyNet)}
+	tester.downloader = New(FullSync, tester.stateDb, new(event.TypeMux), chain, nil, tester.dropPeer)
+	tester.newPeer("peer", 64, hashes, headers, blocks, receipts)
+
+	if err := tester.sync("peer", nil, FastSync); err == nil {
+		t.Fatalf("terminated synchronisation succeeded")
+	}
+	head := tester.CurrentHeader().Number.Uint64()
+	if want := chain.highest - uint64(fsHeaderSafetyNet); head != want {
+		t.Fatalf("head header mismatch after shutdown: have %d, want %d", head, want)
+	}
+	if stages := tester.downloader.Stages(); stages.Headers.Current < head || stages.Receipts == nil {
+		t.Fatalf("header stage mismatch: have %+v, want at least %d", stages, head)
+	}
+	// Restart the downloader and ensure the sync resumes to completion
+	tester.downloader = New(FullSync, tester.stateDb, new(event.TypeMux), tester, nil, tester.dropPeer)
+	tester.newPeer("peer", 64, hashes, headers, blocks, receipts)
+
+	if err := tester.sync("peer", nil, FastSync); err != nil {
+		t.Fatalf("failed to resume synchronisation: %v", err)
+	}
+	assertOwnChain(t, tester, targetBlocks+1)
+}
+
 // Tests that a peer advertising an high TD doesn't get to stall the downloader
 // afterwards by not sending any useful hashes.
 func TestHighTDStarvationAttack65Full(t *testing.T) { testHighTDStarvationAttack(t, 65, FullSync) }
@@ -1415,6 +1477,15 @@ func testSyncProgress(t *testing.T, protocol int, mode SyncMode) {
 	if progress := tester.downloader.Progress(); progress.StartingBlock != uint64(targetBlocks/2+1) || progress.CurrentBlock != uint64(targetBlocks) || progress.HighestBlock != uint64(targetBlocks) {
 		t.Fatalf("Final progress mismatch: have %v/%v/%v, want %v/%v/%v", progress.StartingBlock, progress.CurrentBlock, progress.HighestBlock, targetBlocks/2+1, targetBlocks, targetBlocks)
 	}
+	stages := tester.downloader.Stages()
+	for name, stage := range map[string]StageProgress{"headers": stages.Headers, "bodies": stages.Bodies, "blocks": stages.Blocks} {
+		if stage.Current != uint64(targetBlocks) || stage.Highest != uint64(targetBlocks) {
+			t.Errorf("Final %s stage mismatch: have %d/%d, want %d/%d", name, stage.Current, stage.Highest, targetBlocks, targetBlocks)
+		}
+	}
+	if (stages.Receipts != nil) != (mode == FastSync) {
+		t.Errorf("Final receipts stage mismatch: have %v, want only in fast sync", stages.Receipts)
+	}
 }
 
 // Tests that synchronisation progress (origin block number and highest block
@@ -22,13 +22,19 @@ import "fmt"
 type SyncMode int
 
 const (
-	FullSync  SyncMode = iota // Synchronise the entire blockchain history from full blocks
-	FastSync                  // Quickly download the headers, full sync only at the chain head
-	LightSync                 // Download only the headers and terminate afterwards
+	FullSync   SyncMode = iota // Synchronise the entire blockchain history from full blocks
+	FastSync                   // Quickly download the headers, full sync only at the chain head
+	LightSync                  // Download only the headers and terminate afterwards
+	HeaderSync                 // Download and fully verify only the headers, never any state
 )
 
 func (mode SyncMode) IsValid() bool {
-	return mode >= FullSync && mode <= LightSync
+	return mode >= FullSync && mode <= HeaderSync
+}
+
+// headersOnly reports whether the mode syncs headers without any block content.
+func (mode SyncMode) headersOnly() bool {
+	return mode == LightSync || mode == HeaderSync
 }
 
 // String implements the stringer interface.
@@ -40,6 +46,8 @@ func (mode SyncMode) String() string {
 		return "fast"
 	case LightSync:
 		return "light"
+	case HeaderSync:
+		return "headers"
 	default:
 		return "unknown"
 	}
@@ -53,6 +61,8 @@ func (mode SyncMode) MarshalText() ([]byte, error) {
 		return []byte("fast"), nil
 	case LightSync:
 		return []byte("light"), nil
+	case HeaderSync:
+		return []byte("headers"), nil
 	default:
 		return nil, fmt.Errorf("unknown sync mode %d", mode)
 	}
@@ -66,8 +76,10 @@ func (mode *SyncMode) UnmarshalText(text []byte) error {
 		*mode = FastSync
 	case "light":
 		*mode = LightSync
+	case "headers":
+		*mode = HeaderSync
 	default:
-		return fmt.Errorf(`unknown sync mode %q, want "full", "fast" or "light"`, text)
+		return fmt.Errorf(`unknown sync mode %q, want "full", "fast", "light" or "headers"`, text)
 	}
 	return nil
 }
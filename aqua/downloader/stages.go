@@ -0,0 +1,108 @@
+// Copyright 2015 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"time"
+)
+
+// StageProgress is the progress of a stage of the synchronisation.
+type StageProgress struct {
+	Current uint64 // Highest block the stage completed, without gaps
+	Highest uint64 // Highest block the stage has to reach
+	Pending uint64 // Blocks queued for the stage
+}
+
+// SyncStages details the progress of the current synchronisation, stage by
+// stage. Headers are downloaded first, then the bodies, and the receipts in
+// fast sync, before the blocks are imported; fast sync also downloads the state
+// of its pivot block.
+type SyncStages struct {
+	Headers  StageProgress
+	Bodies   StageProgress
+	Receipts *StageProgress // Nil unless fast syncing
+	Blocks   StageProgress  // Blocks imported, fast or full
+
+	PulledStates uint64 // Number of state trie entries already downloaded
+	KnownStates  uint64 // Total number of state trie entries known about
+
+	// Remaining is the time left to import the blocks, estimated from the
+	// import rate since the synchronisation started, 0 if unknown
+	Remaining time.Duration
+}
+
+// Stages returns the progress of the current synchronisation stage by stage.
+// Light and header syncs only download headers, so all their stages follow it.
+func (d *Downloader) Stages() SyncStages {
+	d.syncStatsLock.RLock()
+	defer d.syncStatsLock.RUnlock()
+
+	highest, current := d.syncStatsChainHeight, d.currentBlock()
+	stages := SyncStages{
+		Headers:      StageProgress{Current: d.syncStatsHeaders, Highest: highest},
+		Bodies:       StageProgress{Current: d.syncStatsContent, Highest: highest, Pending: uint64(d.queue.PendingBlocks())},
+		Blocks:       StageProgress{Current: current, Highest: highest},
+		PulledStates: d.syncStatsState.processed,
+		KnownStates:  d.syncStatsState.processed + d.syncStatsState.pending,
+	}
+	switch d.mode {
+	case FastSync:
+		stages.Receipts = &StageProgress{Current: d.syncStatsContent, Highest: highest, Pending: uint64(d.queue.PendingReceipts())}
+	case LightSync, HeaderSync:
+		stages.Headers.Current = current
+		stages.Bodies = stages.Headers
+	}
+	if current > stages.Headers.Current {
+		stages.Headers.Current = current
+	}
+	if current > stages.Bodies.Current {
+		stages.Bodies.Current = current
+	}
+	// Estimate the remaining time once some blocks were imported
+	if elapsed := time.Since(d.syncStatsStart); !d.syncStatsStart.IsZero() && current > d.syncStatsStartBlock && highest > current {
+		rate := float64(current-d.syncStatsStartBlock) / elapsed.Seconds()
+		stages.Remaining = time.Duration(float64(highest-current) / rate * float64(time.Second)).Round(time.Second)
+	}
+	return stages
+}
+
+// currentBlock returns the block the synchronisation reached, in the mode it
+// runs in.
+func (d *Downloader) currentBlock() uint64 {
+	switch d.mode {
+	case FullSync:
+		return d.blockchain.CurrentBlock().NumberU64()
+	case FastSync:
+		return d.blockchain.CurrentFastBlock().NumberU64()
+	case LightSync, HeaderSync:
+		return d.lightchain.CurrentHeader().Number.Uint64()
+	}
+	return 0
+}
+
+// contentDownloaded records the progress of the content download once the
+// results are ready for import.
+func (d *Downloader) contentDownloaded(results []*fetchResult) {
+	if len(results) == 0 {
+		return
+	}
+	d.syncStatsLock.Lock()
+	if last := results[len(results)-1].Header.Number.Uint64(); last > d.syncStatsContent {
+		d.syncStatsContent = last
+	}
+	d.syncStatsLock.Unlock()
+}
@@ -35,6 +35,7 @@ var (
 
 	headerFetchMeter = metrics.NewRegisteredMeter("aqua/fetcher/fetch/headers", nil)
 	bodyFetchMeter   = metrics.NewRegisteredMeter("aqua/fetcher/fetch/bodies", nil)
+	txFetchMeter     = metrics.NewRegisteredMeter("aqua/fetcher/fetch/txs", nil)
 
 	headerFilterInMeter  = metrics.NewRegisteredMeter("aqua/fetcher/filter/headers/in", nil)
 	headerFilterOutMeter = metrics.NewRegisteredMeter("aqua/fetcher/filter/headers/out", nil)
@@ -0,0 +1,243 @@
+// Copyright 2015 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/log"
+)
+
+const (
+	txFetchTimeout = 5 * time.Second // Maximum allotted time to return requested transactions
+	maxTxFetching  = 4096            // Maximum number of announced transactions tracked at once
+	maxTxRetrieval = 256             // Maximum number of transactions requested at once
+)
+
+// txRequesterFn is a callback type for sending a transaction retrieval request.
+type txRequesterFn func([]common.Hash) error
+
+// txAnnounce is the hash notification of the availability of new transactions.
+type txAnnounce struct {
+	origin  string        // Identifier of the peer announcing the transactions
+	hashes  []common.Hash // Hashes of the transactions announced
+	fetchTx txRequesterFn // Fetcher function to retrieve the announced transactions
+}
+
+// txDelivery is the reply of a peer to a transaction retrieval request.
+type txDelivery struct {
+	origin string        // Identifier of the peer delivering the transactions, for tracing
+	hashes []common.Hash // Hashes of the transactions delivered
+}
+
+// txFetch is an announced transaction being retrieved.
+type txFetch struct {
+	origin     string                   // Peer the transaction was requested from
+	deadline   time.Time                // Time the request times out
+	alternates map[string]txRequesterFn // Other peers announcing the transaction, not yet requested
+}
+
+// TxFetcher retrieves the transactions announced by hash, requesting each from
+// a single announcer at a time and moving on to the next one if the request
+// isn't answered with it in time.
+type TxFetcher struct {
+	notify  chan *txAnnounce
+	deliver chan *txDelivery
+	drop    chan string
+	quit    chan struct{}
+
+	fetching map[common.Hash]*txFetch // Announced transactions, currently fetching
+	timeout  time.Duration            // Time allowance of the requests
+
+	// Callbacks
+	hasTx func(common.Hash) bool                              // Reports whether a transaction is already known
+	addTx func(peer string, txs []*types.Transaction) []error // Injects retrieved transactions into the pool
+
+	// Testing hooks
+	fetchingHook func(string, []common.Hash) // Method to call upon requesting transactions from a peer
+}
+
+// NewTxFetcher creates a transaction fetcher to retrieve transactions based on
+// hash announcements.
+func NewTxFetcher(hasTx func(common.Hash) bool, addTx func(string, []*types.Transaction) []error) *TxFetcher {
+	return &TxFetcher{
+		notify:   make(chan *txAnnounce),
+		deliver:  make(chan *txDelivery),
+		drop:     make(chan string),
+		quit:     make(chan struct{}),
+		fetching: make(map[common.Hash]*txFetch),
+		timeout:  txFetchTimeout,
+		hasTx:    hasTx,
+		addTx:    addTx,
+	}
+}
+
+// Start boots up the transaction fetcher.
+func (f *TxFetcher) Start() {
+	go f.loop()
+}
+
+// Stop terminates the transaction fetcher, abandoning the pending retrievals.
+func (f *TxFetcher) Stop() {
+	close(f.quit)
+}
+
+// Notify announces the fetcher of the availability of transactions at a peer.
+func (f *TxFetcher) Notify(peer string, hashes []common.Hash, fetchTx txRequesterFn) error {
+	select {
+	case f.notify <- &txAnnounce{origin: peer, hashes: hashes, fetchTx: fetchTx}:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Enqueue delivers the transactions a peer replied with, injecting them into the
+// pool.
+func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction) error {
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	f.addTx(peer, txs)
+
+	select {
+	case f.deliver <- &txDelivery{origin: peer, hashes: hashes}:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Drop forgets a disconnected peer, retrieving the transactions requested from
+// it from the other announcers.
+func (f *TxFetcher) Drop(peer string) error {
+	select {
+	case f.drop <- peer:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// loop is the main fetcher loop, tracking the retrievals and moving the ones
+// failing on to other announcers.
+func (f *TxFetcher) loop() {
+	ticker := time.NewTicker(f.timeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.quit:
+			return
+
+		case ann := <-f.notify:
+			var request []common.Hash
+			for _, hash := range ann.hashes {
+				if fetch, ok := f.fetching[hash]; ok {
+					if fetch.origin != ann.origin {
+						fetch.alternates[ann.origin] = ann.fetchTx
+					}
+					continue
+				}
+				if len(f.fetching) >= maxTxFetching || f.hasTx(hash) {
+					continue
+				}
+				f.fetching[hash] = &txFetch{origin: ann.origin, deadline: time.Now().Add(f.timeout), alternates: make(map[string]txRequesterFn)}
+				request = append(request, hash)
+			}
+			f.request(ann.origin, request, ann.fetchTx)
+
+		case delivery := <-f.deliver:
+			// Replies may be cut short by size limits, so the transactions left
+			// out are only moved on to other announcers once timing out
+			for _, hash := range delivery.hashes {
+				delete(f.fetching, hash)
+			}
+			log.Trace("Delivered announced transactions", "peer", delivery.origin, "count", len(delivery.hashes))
+
+		case peer := <-f.drop:
+			for _, fetch := range f.fetching {
+				delete(fetch.alternates, peer)
+			}
+			f.reschedule(func(fetch *txFetch) bool { return fetch.origin == peer })
+
+		case now := <-ticker.C:
+			f.reschedule(func(fetch *txFetch) bool { return now.After(fetch.deadline) })
+		}
+	}
+}
+
+// reschedule requests the transactions whose retrieval failed from another of
+// their announcers, forgetting the ones nobody else announced.
+func (f *TxFetcher) reschedule(failed func(*txFetch) bool) {
+	var (
+		requests = make(map[string][]common.Hash)
+		fetchers = make(map[string]txRequesterFn)
+	)
+	for hash, fetch := range f.fetching {
+		if !failed(fetch) {
+			continue
+		}
+		if f.hasTx(hash) {
+			delete(f.fetching, hash)
+			continue
+		}
+		var next string
+		for peer, fetchTx := range fetch.alternates {
+			next, fetchers[peer] = peer, fetchTx
+			break
+		}
+		if next == "" {
+			delete(f.fetching, hash)
+			continue
+		}
+		delete(fetch.alternates, next)
+		fetch.origin, fetch.deadline = next, time.Now().Add(f.timeout)
+		requests[next] = append(requests[next], hash)
+	}
+	for peer, hashes := range requests {
+		f.request(peer, hashes, fetchers[peer])
+	}
+}
+
+// request sends the retrieval requests of the given transactions to a peer, in
+// batches of the allowed size.
+func (f *TxFetcher) request(peer string, hashes []common.Hash, fetchTx txRequesterFn) {
+	if len(hashes) == 0 {
+		return
+	}
+	if f.fetchingHook != nil {
+		f.fetchingHook(peer, hashes)
+	}
+	txFetchMeter.Mark(int64(len(hashes)))
+	go func() {
+		for len(hashes) > 0 {
+			batch := hashes
+			if len(batch) > maxTxRetrieval {
+				batch = batch[:maxTxRetrieval]
+			}
+			if err := fetchTx(batch); err != nil {
+				log.Debug("Transaction retrieval failed", "peer", peer, "err", err)
+				return
+			}
+			hashes = hashes[len(batch):]
+		}
+	}()
+}
@@ -0,0 +1,138 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+)
+
+// txFetcherTester is a test simulator for the transaction fetcher, tracking the
+// requests sent to the peers.
+type txFetcherTester struct {
+	fetcher *TxFetcher
+
+	requests chan string // Peers the transactions were requested from
+	lock     sync.Mutex
+	pool     map[common.Hash]*types.Transaction
+}
+
+func newTxFetcherTester(timeout time.Duration) *txFetcherTester {
+	tester := &txFetcherTester{
+		requests: make(chan string, 16),
+		pool:     make(map[common.Hash]*types.Transaction),
+	}
+	tester.fetcher = NewTxFetcher(tester.hasTx, tester.addTx)
+	tester.fetcher.timeout = timeout
+	tester.fetcher.fetchingHook = func(peer string, hashes []common.Hash) { tester.requests <- peer }
+	return tester
+}
+
+func (f *txFetcherTester) hasTx(hash common.Hash) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.pool[hash] != nil
+}
+
+func (f *txFetcherTester) addTx(peer string, txs []*types.Transaction) []error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, tx := range txs {
+		f.pool[tx.Hash()] = tx
+	}
+	return make([]error, len(txs))
+}
+
+func noopTxRequester([]common.Hash) error { return nil }
+
+// nextTxRequest waits for the transactions to be requested from a peer.
+func nextTxRequest(t *testing.T, requests chan string) string {
+	select {
+	case peer := <-requests:
+		return peer
+	case <-time.After(time.Second):
+		t.Fatalf("transactions not requested")
+	}
+	return ""
+}
+
+// waitTxRequest waits for the transactions to be requested from the given peer.
+func waitTxRequest(t *testing.T, requests chan string, want string) {
+	if peer := nextTxRequest(t, requests); peer != want {
+		t.Fatalf("requested peer mismatch: have %s, want %s", peer, want)
+	}
+}
+
+// Tests that announced transactions are requested once, and forgotten after
+// their delivery.
+func TestTxFetcherDelivery(t *testing.T) {
+	tester := newTxFetcherTester(time.Minute)
+	tester.fetcher.Start()
+	defer tester.fetcher.Stop()
+
+	tx := types.NewTransaction(0, common.Address{}, nil, 0, nil, nil)
+	tester.fetcher.Notify("A", []common.Hash{tx.Hash()}, noopTxRequester)
+	waitTxRequest(t, tester.requests, "A")
+
+	tester.fetcher.Notify("B", []common.Hash{tx.Hash()}, noopTxRequester)
+	tester.fetcher.Enqueue("A", []*types.Transaction{tx})
+	if !tester.hasTx(tx.Hash()) {
+		t.Fatalf("delivered transaction not added")
+	}
+	tester.fetcher.Notify("C", []common.Hash{tx.Hash()}, noopTxRequester)
+	select {
+	case peer := <-tester.requests:
+		t.Fatalf("known transaction requested from %s", peer)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// Tests that transactions not delivered in time, or whose announcer dropped,
+// are requested from the other announcers.
+func TestTxFetcherFailover(t *testing.T) {
+	tester := newTxFetcherTester(100 * time.Millisecond)
+	tester.fetcher.Start()
+	defer tester.fetcher.Stop()
+
+	tx := types.NewTransaction(0, common.Address{}, nil, 0, nil, nil)
+	tester.fetcher.Notify("A", []common.Hash{tx.Hash()}, noopTxRequester)
+	waitTxRequest(t, tester.requests, "A")
+	tester.fetcher.Notify("B", []common.Hash{tx.Hash()}, noopTxRequester)
+	tester.fetcher.Notify("C", []common.Hash{tx.Hash()}, noopTxRequester)
+
+	// Time out the first request, dropping the second announcer
+	first := nextTxRequest(t, tester.requests)
+	if first == "A" {
+		t.Fatalf("transaction requested again from the timed out peer")
+	}
+	tester.fetcher.Drop(first)
+
+	second := nextTxRequest(t, tester.requests)
+	if second == "A" || second == first {
+		t.Fatalf("requested peer mismatch: have %s, want the last announcer", second)
+	}
+	tester.fetcher.Enqueue(second, []*types.Transaction{tx})
+	if !tester.hasTx(tx.Hash()) {
+		t.Fatalf("delivered transaction not added")
+	}
+}
@@ -29,8 +29,11 @@ import (
 	"github.com/aquanetwork/aquachain/aquadb"
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/state"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/log"
 	"github.com/aquanetwork/aquachain/rpc"
 )
 
@@ -38,6 +41,10 @@ var (
 	deadline = 5 * time.Minute // consider a filter inactive if it has not been polled for within deadline
 )
 
+// maxStateChangeSlots is the number of storage slots a single stateChanges
+// subscription may watch, bounding the state reads done per imported block.
+const maxStateChangeSlots = 256
+
 // filter is a helper struct that holds meta information over the filter type
 // and associated subscription in the event system.
 type filter struct {
@@ -135,7 +142,8 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
 // enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+// If fullTx is true, the full transactions are sent instead of their hashes.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -144,13 +152,22 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	rpcSub := notifier.CreateSubscription()
 
 	go func() {
-		txHashes := make(chan common.Hash)
-		pendingTxSub := api.events.SubscribePendingTxEvents(txHashes)
-
+		var (
+			txHashes     = make(chan common.Hash)
+			txs          = make(chan *types.Transaction)
+			pendingTxSub *Subscription
+		)
+		if fullTx != nil && *fullTx {
+			pendingTxSub = api.events.SubscribePendingTxs(txs)
+		} else {
+			pendingTxSub = api.events.SubscribePendingTxEvents(txHashes)
+		}
 		for {
 			select {
 			case h := <-txHashes:
 				notifier.Notify(rpcSub.ID, h)
+			case tx := <-txs:
+				notifier.Notify(rpcSub.ID, tx)
 			case <-rpcSub.Err():
 				pendingTxSub.Unsubscribe()
 				return
@@ -267,6 +284,205 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 	return rpcSub, nil
 }
 
+// BlockRef identifies a block.
+type BlockRef struct {
+	Number hexutil.Uint64 `json:"number"`
+	Hash   common.Hash    `json:"hash"`
+}
+
+// Reorg is the notification of a reorgs subscription. The dropped and added
+// blocks are ordered from the oldest, the first ones being children of the
+// common ancestor. The dropped transactions are the ones of the dropped blocks
+// no longer in the canonical chain, whose effects are reverted.
+type Reorg struct {
+	CommonAncestor      BlockRef      `json:"commonAncestor"`
+	Depth               int           `json:"depth"`
+	Dropped             []BlockRef    `json:"dropped"`
+	Added               []BlockRef    `json:"added"`
+	DroppedTransactions []common.Hash `json:"droppedTransactions"`
+}
+
+// newReorg converts a reorg event to its notification.
+func newReorg(ev *core.ReorgEvent) *Reorg {
+	refs := func(blocks types.Blocks) []BlockRef {
+		res := make([]BlockRef, len(blocks))
+		for i, block := range blocks {
+			res[len(blocks)-1-i] = BlockRef{Number: hexutil.Uint64(block.NumberU64()), Hash: block.Hash()}
+		}
+		return res
+	}
+	reorg := &Reorg{
+		CommonAncestor:      BlockRef{Number: hexutil.Uint64(ev.Common.NumberU64()), Hash: ev.Common.Hash()},
+		Depth:               len(ev.Dropped),
+		Dropped:             refs(ev.Dropped),
+		Added:               refs(ev.Added),
+		DroppedTransactions: make([]common.Hash, len(ev.DroppedTxs)),
+	}
+	for i, tx := range ev.DroppedTxs {
+		reorg.DroppedTransactions[i] = tx.Hash()
+	}
+	return reorg
+}
+
+// Reorgs sends a notification each time the chain reorganises, so that the
+// effects of the transactions in the dropped blocks can be reverted right away.
+func (api *PublicFilterAPI) Reorgs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		reorgs := make(chan *core.ReorgEvent)
+		reorgsSub := api.events.SubscribeReorgs(reorgs)
+
+		for {
+			select {
+			case ev := <-reorgs:
+				notifier.Notify(rpcSub.ID, newReorg(ev))
+			case <-rpcSub.Err():
+				reorgsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				reorgsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// StateChangeCriteria selects the account, and the storage slots of it, watched
+// by a stateChanges subscription.
+type StateChangeCriteria struct {
+	Address common.Address `json:"address"`
+	Slots   []common.Hash  `json:"slots"`
+}
+
+// StateChange is the notification of a stateChanges subscription, holding the
+// watched fields of the account which changed since the previous head. Fields
+// that didn't change are omitted.
+type StateChange struct {
+	BlockHash   common.Hash                 `json:"blockHash"`
+	BlockNumber *hexutil.Big                `json:"blockNumber"`
+	Address     common.Address              `json:"address"`
+	Balance     *hexutil.Big                `json:"balance,omitempty"`
+	Nonce       *hexutil.Uint64             `json:"nonce,omitempty"`
+	Storage     map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// watchedState is the value of the watched fields of an account at some block.
+type watchedState struct {
+	balance *big.Int
+	nonce   uint64
+	storage map[common.Hash]common.Hash
+}
+
+// readWatchedState retrieves the fields of the account selected by crit.
+func readWatchedState(statedb *state.StateDB, crit StateChangeCriteria) (*watchedState, error) {
+	ws := &watchedState{
+		balance: statedb.GetBalance(crit.Address),
+		nonce:   statedb.GetNonce(crit.Address),
+		storage: make(map[common.Hash]common.Hash, len(crit.Slots)),
+	}
+	for _, slot := range crit.Slots {
+		ws.storage[slot] = statedb.GetState(crit.Address, slot)
+	}
+	// Light clients retrieve the state on demand, surface any failure
+	if err := statedb.Error(); err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+// diff returns the fields of next which differ from ws, or nil if none does.
+func (ws *watchedState) diff(next *watchedState) *StateChange {
+	var (
+		change  StateChange
+		changed bool
+	)
+	if ws.balance.Cmp(next.balance) != 0 {
+		change.Balance, changed = (*hexutil.Big)(next.balance), true
+	}
+	if ws.nonce != next.nonce {
+		nonce := hexutil.Uint64(next.nonce)
+		change.Nonce, changed = &nonce, true
+	}
+	for slot, value := range next.storage {
+		if ws.storage[slot] != value {
+			if change.Storage == nil {
+				change.Storage = make(map[common.Hash]common.Hash)
+			}
+			change.Storage[slot], changed = value, true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return &change
+}
+
+// StateChanges creates a subscription that fires each time a new head changes the
+// balance, nonce or one of the given storage slots of the watched account. On a
+// reorg the changes are reported against the previous head, so reverted values
+// are sent again.
+func (api *PublicFilterAPI) StateChanges(ctx context.Context, crit StateChangeCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if len(crit.Slots) > maxStateChangeSlots {
+		return nil, fmt.Errorf("too many storage slots: %d > %d", len(crit.Slots), maxStateChangeSlots)
+	}
+	// Take the current values as the base of the first notification
+	statedb, _, err := api.backend.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	if err != nil {
+		return nil, err
+	}
+	last, err := readWatchedState(statedb, crit)
+	if err != nil {
+		return nil, err
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		headers := make(chan *types.Header)
+		headersSub := api.events.SubscribeNewHeads(headers)
+		defer headersSub.Unsubscribe()
+
+		for {
+			select {
+			case h := <-headers:
+				hash := h.Hash()
+				statedb, _, err := api.backend.StateAndHeaderByNumberOrHash(context.Background(), rpc.BlockNumberOrHashWithHash(hash, false))
+				if err != nil {
+					log.Debug("Failed to retrieve state for subscription", "number", h.Number, "hash", hash, "err", err)
+					continue
+				}
+				current, err := readWatchedState(statedb, crit)
+				if err != nil {
+					log.Debug("Failed to read state for subscription", "number", h.Number, "hash", hash, "err", err)
+					continue
+				}
+				if change := last.diff(current); change != nil {
+					change.BlockHash, change.BlockNumber, change.Address = hash, (*hexutil.Big)(h.Number), crit.Address
+					notifier.Notify(rpcSub.ID, change)
+				}
+				last = current
+			case <-rpcSub.Err(): // client send an unsubscribe request
+				return
+			case <-notifier.Closed(): // connection dropped
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // FilterCriteria represents a request to create a new filter.
 //
 // TODO(karalabe): Kill this in favor of aquachain.FilterQuery.
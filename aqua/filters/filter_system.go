@@ -51,6 +51,8 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// ReorgsSubscription queries for the reorganisations of the chain
+	ReorgsSubscription
 	// LastSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -66,6 +68,8 @@ const (
 	logsChanSize = 10
 	// chainEvChanSize is the size of channel listening to ChainEvent.
 	chainEvChanSize = 10
+	// reorgChanSize is the size of channel listening to ReorgEvent.
+	reorgChanSize = 10
 )
 
 var (
@@ -79,7 +83,9 @@ type subscription struct {
 	logsCrit  aquachain.FilterQuery
 	logs      chan []*types.Log
 	hashes    chan common.Hash
+	txs       chan *types.Transaction // full pending transactions, hashes are sent if nil
 	headers   chan *types.Header
+	reorgs    chan *core.ReorgEvent
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
 }
@@ -142,7 +148,9 @@ func (sub *Subscription) Unsubscribe() {
 				break uninstallLoop
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
+			case <-sub.f.txs:
 			case <-sub.f.headers:
+			case <-sub.f.reorgs:
 			}
 		}
 
@@ -282,6 +290,40 @@ func (es *EventSystem) SubscribePendingTxEvents(hashes chan common.Hash) *Subscr
 	return es.subscribe(sub)
 }
 
+// SubscribePendingTxs creates a subscription that writes transactions for
+// transactions that enter the transaction pool.
+func (es *EventSystem) SubscribePendingTxs(txs chan *types.Transaction) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       PendingTransactionsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan common.Hash),
+		txs:       txs,
+		headers:   make(chan *types.Header),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeReorgs creates a subscription that writes the reorganisations of the
+// chain.
+func (es *EventSystem) SubscribeReorgs(reorgs chan *core.ReorgEvent) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       ReorgsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan common.Hash),
+		headers:   make(chan *types.Header),
+		reorgs:    reorgs,
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
 type filterIndex map[Type]map[rpc.ID]*subscription
 
 // broadcast event to filters that match criteria.
@@ -318,7 +360,15 @@ func (es *EventSystem) broadcast(filters filterIndex, ev interface{}) {
 		}
 	case core.TxPreEvent:
 		for _, f := range filters[PendingTransactionsSubscription] {
-			f.hashes <- e.Tx.Hash()
+			if f.txs != nil {
+				f.txs <- e.Tx
+			} else {
+				f.hashes <- e.Tx.Hash()
+			}
+		}
+	case core.ReorgEvent:
+		for _, f := range filters[ReorgsSubscription] {
+			f.reorgs <- &e
 		}
 	case core.ChainEvent:
 		for _, f := range filters[BlocksSubscription] {
@@ -429,6 +479,9 @@ func (es *EventSystem) eventLoop() {
 		// Subscribe ChainEvent
 		chainEvCh  = make(chan core.ChainEvent, chainEvChanSize)
 		chainEvSub = es.backend.SubscribeChainEvent(chainEvCh)
+		// Subscribe ReorgEvent
+		reorgCh  = make(chan core.ReorgEvent, reorgChanSize)
+		reorgSub = es.backend.SubscribeReorgEvent(reorgCh)
 	)
 
 	// Unsubscribe all events
@@ -437,6 +490,7 @@ func (es *EventSystem) eventLoop() {
 	defer rmLogsSub.Unsubscribe()
 	defer logsSub.Unsubscribe()
 	defer chainEvSub.Unsubscribe()
+	defer reorgSub.Unsubscribe()
 
 	for i := UnknownSubscription; i < LastIndexSubscription; i++ {
 		index[i] = make(map[rpc.ID]*subscription)
@@ -459,6 +513,8 @@ func (es *EventSystem) eventLoop() {
 			es.broadcast(index, ev)
 		case ev := <-chainEvCh:
 			es.broadcast(index, ev)
+		case ev := <-reorgCh:
+			es.broadcast(index, ev)
 
 		case f := <-es.install:
 			if f.typ == MinedAndPendingLogsSubscription {
@@ -488,6 +544,8 @@ func (es *EventSystem) eventLoop() {
 			return
 		case <-chainEvSub.Err():
 			return
+		case <-reorgSub.Err():
+			return
 		}
 	}
 }
@@ -31,6 +31,7 @@ import (
 	"github.com/aquanetwork/aquachain/consensus/aquahash"
 	"github.com/aquanetwork/aquachain/core"
 	"github.com/aquanetwork/aquachain/core/bloombits"
+	"github.com/aquanetwork/aquachain/core/state"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/event"
 	"github.com/aquanetwork/aquachain/params"
@@ -45,6 +46,7 @@ type testBackend struct {
 	rmLogsFeed *event.Feed
 	logsFeed   *event.Feed
 	chainFeed  *event.Feed
+	reorgFeed  *event.Feed
 }
 
 func (b *testBackend) ChainDb() aquadb.Database {
@@ -91,6 +93,21 @@ func (b *testBackend) GetLogs(ctx context.Context, blockHash common.Hash) ([][]*
 	return logs, nil
 }
 
+func (b *testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	var header *types.Header
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		header, _ = b.HeaderByNumber(ctx, blockNr)
+	} else {
+		hash, _ := blockNrOrHash.Hash()
+		header = core.GetHeaderNoVersion(b.db, hash, core.GetBlockNumber(b.db, hash))
+	}
+	if header == nil {
+		return nil, nil, fmt.Errorf("header not found")
+	}
+	statedb, err := state.New(header.Root, state.NewDatabase(b.db))
+	return statedb, header, err
+}
+
 func (b *testBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Subscription {
 	return b.txFeed.Subscribe(ch)
 }
@@ -107,6 +124,10 @@ func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subsc
 	return b.chainFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeReorgEvent(ch chan<- core.ReorgEvent) event.Subscription {
+	return b.reorgFeed.Subscribe(ch)
+}
+
 func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
@@ -153,7 +174,7 @@ func TestBlockSubscription(t *testing.T) {
 		rmLogsFeed  = new(event.Feed)
 		logsFeed    = new(event.Feed)
 		chainFeed   = new(event.Feed)
-		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api         = NewPublicFilterAPI(backend, false)
 		genesis     = new(core.Genesis).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, aquahash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {})
@@ -199,6 +220,58 @@ func TestBlockSubscription(t *testing.T) {
 	<-sub1.Err()
 }
 
+// TestReorgSubscription tests that reorgs are notified, with the blocks ordered
+// from the common ancestor.
+func TestReorgSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db, _     = aquadb.NewMemDatabase()
+		reorgFeed = new(event.Feed)
+		backend   = &testBackend{new(event.TypeMux), db, 0, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed), reorgFeed}
+		api       = NewPublicFilterAPI(backend, false)
+		genesis   = new(core.Genesis).MustCommit(db)
+		chain, _  = core.GenerateChain(params.TestChainConfig, genesis, aquahash.NewFaker(), db, 3, func(i int, gen *core.BlockGen) {})
+		fork, _   = core.GenerateChain(params.TestChainConfig, chain[0], aquahash.NewFaker(), db, 3, func(i int, gen *core.BlockGen) {
+			gen.SetExtra([]byte("fork"))
+		})
+		tx = types.NewTransaction(0, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil)
+	)
+	reorgs := make(chan *core.ReorgEvent)
+	sub := api.events.SubscribeReorgs(reorgs)
+	defer sub.Unsubscribe()
+
+	time.Sleep(100 * time.Millisecond)
+	reorgFeed.Send(core.ReorgEvent{
+		Common:     chain[0],
+		Dropped:    types.Blocks{chain[2], chain[1]},
+		Added:      types.Blocks{fork[2], fork[1], fork[0]},
+		DroppedTxs: types.Transactions{tx},
+	})
+	select {
+	case ev := <-reorgs:
+		reorg := newReorg(ev)
+		if reorg.CommonAncestor.Hash != chain[0].Hash() || reorg.Depth != 2 {
+			t.Errorf("reorg mismatch: common ancestor %x, depth %d", reorg.CommonAncestor.Hash, reorg.Depth)
+		}
+		for i, ref := range reorg.Dropped {
+			if ref.Hash != chain[i+1].Hash() || uint64(ref.Number) != chain[i+1].NumberU64() {
+				t.Errorf("dropped block %d mismatch: have #%d %x, want #%d %x", i, ref.Number, ref.Hash, chain[i+1].NumberU64(), chain[i+1].Hash())
+			}
+		}
+		for i, ref := range reorg.Added {
+			if ref.Hash != fork[i].Hash() {
+				t.Errorf("added block %d mismatch: have %x, want %x", i, ref.Hash, fork[i].Hash())
+			}
+		}
+		if len(reorg.DroppedTransactions) != 1 || reorg.DroppedTransactions[0] != tx.Hash() {
+			t.Errorf("dropped transactions mismatch: have %x, want [%x]", reorg.DroppedTransactions, tx.Hash())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("reorg not notified")
+	}
+}
+
 // TestPendingTxFilter tests whether pending tx filters retrieve all pending transactions that are posted to the event mux.
 func TestPendingTxFilter(t *testing.T) {
 	t.Parallel()
@@ -210,7 +283,7 @@ func TestPendingTxFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		transactions = []*types.Transaction{
@@ -263,6 +336,63 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestPendingTxSubscription tests whether full pending transaction subscriptions
+// and hash subscriptions both receive the transactions posted to the event mux.
+func TestPendingTxSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux        = new(event.TypeMux)
+		db, _      = aquadb.NewMemDatabase()
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
+		api        = NewPublicFilterAPI(backend, false)
+
+		transactions = []*types.Transaction{
+			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil),
+			types.NewTransaction(1, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil),
+			types.NewTransaction(2, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil),
+		}
+	)
+
+	txs := make(chan *types.Transaction)
+	sub0 := api.events.SubscribePendingTxs(txs)
+	hashes := make(chan common.Hash)
+	sub1 := api.events.SubscribePendingTxEvents(hashes)
+
+	go func() { // simulate client
+		i0, i1 := 0, 0
+		for i0 != len(transactions) || i1 != len(transactions) {
+			select {
+			case tx := <-txs:
+				if tx.Hash() != transactions[i0].Hash() {
+					t.Errorf("sub0 received invalid transaction on index %d, want %x, got %x", i0, transactions[i0].Hash(), tx.Hash())
+				}
+				i0++
+			case hash := <-hashes:
+				if hash != transactions[i1].Hash() {
+					t.Errorf("sub1 received invalid hash on index %d, want %x, got %x", i1, transactions[i1].Hash(), hash)
+				}
+				i1++
+			}
+		}
+
+		sub0.Unsubscribe()
+		sub1.Unsubscribe()
+	}()
+
+	time.Sleep(1 * time.Second)
+	for _, tx := range transactions {
+		txFeed.Send(core.TxPreEvent{Tx: tx})
+	}
+
+	<-sub0.Err()
+	<-sub1.Err()
+}
+
 // TestLogFilterCreation test whether a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
@@ -273,7 +403,7 @@ func TestLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		testCases = []struct {
@@ -322,7 +452,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 	)
 
@@ -352,7 +482,7 @@ func TestLogFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
@@ -471,7 +601,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
@@ -577,3 +707,46 @@ func TestPendingLogsSubscription(t *testing.T) {
 		}
 	}
 }
+
+// Tests that only the watched account fields which changed are reported.
+func TestStateChangeDiff(t *testing.T) {
+	var (
+		db, _   = aquadb.NewMemDatabase()
+		addr    = common.HexToAddress("0x0000000000000000000000000000000000000aaa")
+		slot1   = common.HexToHash("0x01")
+		slot2   = common.HexToHash("0x02")
+		crit    = StateChangeCriteria{Address: addr, Slots: []common.Hash{slot1, slot2}}
+		stdb, _ = state.New(common.Hash{}, state.NewDatabase(db))
+	)
+	stdb.SetBalance(addr, big.NewInt(100))
+	stdb.SetState(addr, slot1, common.HexToHash("0xaa"))
+
+	prev, err := readWatchedState(stdb, crit)
+	if err != nil {
+		t.Fatalf("failed to read state: %v", err)
+	}
+	if change := prev.diff(prev); change != nil {
+		t.Fatalf("unchanged state reported: %+v", change)
+	}
+	stdb.SetState(addr, slot2, common.HexToHash("0xbb"))
+	stdb.SetNonce(addr, 1)
+
+	next, err := readWatchedState(stdb, crit)
+	if err != nil {
+		t.Fatalf("failed to read state: %v", err)
+	}
+	change := prev.diff(next)
+	if change == nil {
+		t.Fatalf("changed state not reported")
+	}
+	if change.Balance != nil {
+		t.Errorf("unchanged balance reported: %v", change.Balance)
+	}
+	if change.Nonce == nil || *change.Nonce != 1 {
+		t.Errorf("nonce mismatch: have %v, want 1", change.Nonce)
+	}
+	want := map[common.Hash]common.Hash{slot2: common.HexToHash("0xbb")}
+	if !reflect.DeepEqual(change.Storage, want) {
+		t.Errorf("storage mismatch: have %v, want %v", change.Storage, want)
+	}
+}
@@ -56,7 +56,7 @@ func BenchmarkFilters(b *testing.B) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		key1, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr1      = crypto.PubkeyToAddress(key1.PublicKey)
 		addr2      = common.BytesToAddress([]byte("jeff"))
@@ -121,7 +121,7 @@ func TestFilters(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		key1, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr       = crypto.PubkeyToAddress(key1.PublicKey)
 
@@ -241,3 +241,57 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+// versionedBackend is a testBackend for a chain with its own header versions.
+type versionedBackend struct {
+	*testBackend
+	config *params.ChainConfig
+}
+
+func (b *versionedBackend) GetHeaderVersion(h *big.Int) params.HeaderVersion {
+	return b.config.GetBlockVersion(h)
+}
+
+// Tests that matched blocks are hashed with the header versions of the chain
+// being filtered, not of the testnet.
+func TestFiltersHeaderVersion(t *testing.T) {
+	config := *params.TestChainConfig
+	config.HF = params.AquachainHF
+
+	var (
+		db, _   = aquadb.NewMemDatabase()
+		backend = &versionedBackend{&testBackend{new(event.TypeMux), db, 0, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)}, &config}
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		topic   = common.BytesToHash([]byte("topic"))
+	)
+	// Log past the testnet version switch, still before the one of the chain
+	number := params.TestnetHF[5].Uint64() + 3
+	if config.GetBlockVersion(new(big.Int).SetUint64(number)) == params.TestChainConfig.GetBlockVersion(new(big.Int).SetUint64(number)) {
+		t.Fatalf("header versions of block %d don't differ", number)
+	}
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(&config, genesis, aquahash.NewFaker(), db, int(number)+1, func(i int, gen *core.BlockGen) {
+		if uint64(i)+1 == number {
+			receipt := types.NewReceipt(nil, false, 0)
+			receipt.Logs = []*types.Log{{Address: addr, Topics: []common.Hash{topic}}}
+			gen.AddUncheckedReceipt(receipt)
+		}
+	})
+	for i, block := range chain {
+		core.WriteBlock(db, block)
+		core.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		core.WriteHeadBlockHash(db, block.Hash())
+		core.WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	logs, err := New(backend, 0, -1, []common.Address{addr}, [][]common.Hash{{topic}}).Logs(context.Background())
+	if err != nil {
+		t.Fatalf("failed to filter logs: %v", err)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("log count mismatch: have %d, want %d", len(logs), 1)
+	}
+	if logs[0].Topics[0] != topic {
+		t.Errorf("log topic mismatch: have %x, want %x", logs[0].Topics[0], topic)
+	}
+}
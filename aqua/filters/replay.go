@@ -0,0 +1,282 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+// Types of the events sent by a replayEvents subscription, also used to select
+// the events to replay.
+const (
+	ReplayBlock = "block" // A block became part of the canonical chain
+	ReplayLog   = "log"   // A log was emitted, or removed by a reorg
+	ReplayReorg = "reorg" // Replayed blocks were dropped from the canonical chain
+)
+
+// ReplayEvent is the notification of a replayEvents subscription. Block events
+// carry the header of the block, log events the log in the same format as the
+// logs subscription, and reorg events the header of the last replayed block
+// still in the canonical chain.
+type ReplayEvent struct {
+	Type   string        `json:"type"`
+	Header *types.Header `json:"header,omitempty"`
+	Log    *types.Log    `json:"log,omitempty"`
+}
+
+// replayer walks the canonical chain, sending the events of every block it
+// passes in order.
+type replayer struct {
+	backend Backend
+	from    uint64          // First block to replay
+	types   map[string]bool // Types of events to send
+	send    func(*ReplayEvent) error
+
+	last *types.Header // Last replayed block, nil if none yet
+}
+
+// canonical reports whether the header is part of the canonical chain.
+func (r *replayer) canonical(header *types.Header) (bool, error) {
+	canon, err := r.backend.HeaderByNumber(context.Background(), rpc.BlockNumber(header.Number.Int64()))
+	if err != nil {
+		return false, err
+	}
+	return canon != nil && canon.Hash() == header.Hash(), nil
+}
+
+// advance replays the canonical blocks following the last replayed one, up to
+// the given number or the head of the chain, whichever is lower.
+func (r *replayer) advance(to uint64) error {
+	if r.last != nil {
+		if ok, err := r.canonical(r.last); err != nil {
+			return err
+		} else if !ok {
+			if err := r.rewind(); err != nil {
+				return err
+			}
+		}
+	}
+	for {
+		next := r.from
+		if r.last != nil {
+			next = r.last.Number.Uint64() + 1
+		}
+		if next > to {
+			return nil
+		}
+		header, err := r.backend.HeaderByNumber(context.Background(), rpc.BlockNumber(next))
+		if err != nil {
+			return err
+		}
+		if header == nil {
+			return nil // Reached the head of the chain
+		}
+		if r.last != nil && header.ParentHash != r.last.Hash() {
+			// The chain was reorganised since the last block was replayed
+			if err := r.rewind(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := r.block(header); err != nil {
+			return err
+		}
+		r.last = header
+	}
+}
+
+// block sends the events of a block joining the canonical chain.
+func (r *replayer) block(header *types.Header) error {
+	if r.types[ReplayBlock] {
+		if err := r.send(&ReplayEvent{Type: ReplayBlock, Header: header}); err != nil {
+			return err
+		}
+	}
+	return r.logs(header, false)
+}
+
+// logs sends the logs of a block, marked as removed if the block was dropped
+// from the canonical chain.
+func (r *replayer) logs(header *types.Header, removed bool) error {
+	if !r.types[ReplayLog] || header.Bloom == (types.Bloom{}) {
+		return nil
+	}
+	receipts, err := r.backend.GetLogs(context.Background(), header.Hash())
+	if err != nil {
+		return err
+	}
+	for _, logs := range receipts {
+		for _, l := range logs {
+			logcopy := *l
+			logcopy.Removed = removed
+			if err := r.send(&ReplayEvent{Type: ReplayLog, Log: &logcopy}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rewind rolls the replay back to the most recent replayed block which is still
+// canonical, sending the removal of the logs of the dropped blocks.
+func (r *replayer) rewind() error {
+	var dropped []*types.Header
+	for r.last != nil {
+		ok, err := r.canonical(r.last)
+		if err != nil {
+			return err
+		}
+		if ok {
+			break
+		}
+		dropped = append(dropped, r.last)
+		if r.last.Number.Uint64() <= r.from {
+			r.last = nil
+			break
+		}
+		parent := core.GetHeaderNoVersion(r.backend.ChainDb(), r.last.ParentHash, r.last.Number.Uint64()-1)
+		if parent == nil {
+			return fmt.Errorf("missing ancestor #%d [%x]", r.last.Number.Uint64()-1, r.last.ParentHash)
+		}
+		parent.Version = r.backend.GetHeaderVersion(parent.Number)
+		r.last = parent
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+	if r.types[ReplayReorg] {
+		if err := r.send(&ReplayEvent{Type: ReplayReorg, Header: r.last}); err != nil {
+			return err
+		}
+	}
+	for _, header := range dropped {
+		if err := r.logs(header, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReplayEvents creates a subscription that sends the block, log and reorg events
+// of the canonical chain from fromBlock to toBlock in order, in the same format
+// as if they were happening live. If toBlock is "latest", the subscription keeps
+// following the chain once the replay caught up, without missing any event in
+// between. The event types to send are selected by eventTypes, all if empty.
+func (api *PublicFilterAPI) ReplayEvents(ctx context.Context, fromBlock, toBlock rpc.BlockNumber, eventTypes []string) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if fromBlock < 0 {
+		return nil, errors.New("replay must start at a block number")
+	}
+	if toBlock == rpc.PendingBlockNumber {
+		return nil, errors.New("pending block can't be replayed")
+	}
+	follow := toBlock == rpc.LatestBlockNumber
+	if !follow && toBlock < fromBlock {
+		return nil, fmt.Errorf("invalid range: %d > %d", fromBlock, toBlock)
+	}
+	selected := make(map[string]bool)
+	for _, typ := range eventTypes {
+		switch typ {
+		case ReplayBlock, ReplayLog, ReplayReorg:
+			selected[typ] = true
+		default:
+			return nil, fmt.Errorf("unknown event type %q", typ)
+		}
+	}
+	if len(selected) == 0 {
+		selected = map[string]bool{ReplayBlock: true, ReplayLog: true, ReplayReorg: true}
+	}
+	var (
+		rpcSub = notifier.CreateSubscription()
+		quit   = make(chan struct{})
+	)
+	r := &replayer{
+		backend: api.backend,
+		from:    uint64(fromBlock),
+		types:   selected,
+		send: func(event *ReplayEvent) error {
+			select {
+			case <-quit:
+				return errReplayStopped
+			default:
+			}
+			return notifier.Notify(rpcSub.ID, event)
+		},
+	}
+	go func() {
+		select {
+		case <-rpcSub.Err():
+		case <-notifier.Closed():
+		}
+		close(quit)
+	}()
+
+	go func() {
+		// Notifications are dropped until the client knows the subscription
+		select {
+		case <-rpcSub.Activated():
+		case <-quit:
+			return
+		}
+		to := uint64(toBlock)
+		if follow {
+			to = ^uint64(0)
+		}
+		if err := r.advance(to); err != nil {
+			if err != errReplayStopped {
+				log.Debug("Event replay failed", "err", err)
+			}
+			return
+		}
+		if !follow {
+			return
+		}
+		// Caught up with the chain, continue with the new heads. Blocks imported
+		// before subscribing are picked up by the first advance.
+		headers := make(chan *types.Header)
+		headersSub := api.events.SubscribeNewHeads(headers)
+		defer headersSub.Unsubscribe()
+
+		for {
+			if err := r.advance(to); err != nil {
+				if err != errReplayStopped {
+					log.Debug("Event replay failed", "err", err)
+				}
+				return
+			}
+			select {
+			case <-headers:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+var errReplayStopped = errors.New("replay stopped")
@@ -0,0 +1,139 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// writeCanonical writes blocks and their receipts as the canonical chain.
+func writeCanonical(t *testing.T, db aquadb.Database, chain types.Blocks, receipts []types.Receipts) {
+	for i, block := range chain {
+		core.WriteBlock(db, block)
+		if err := core.WriteCanonicalHash(db, block.Hash(), block.NumberU64()); err != nil {
+			t.Fatal(err)
+		}
+		if err := core.WriteHeadBlockHash(db, block.Hash()); err != nil {
+			t.Fatal(err)
+		}
+		if err := core.WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// describe summarises replayed events as "block:N", "log:N" or "removed:N" and
+// "reorg:N", N being the block number of the event.
+func describe(events []*ReplayEvent) string {
+	var s []string
+	for _, event := range events {
+		switch event.Type {
+		case ReplayLog:
+			if event.Log.Removed {
+				s = append(s, fmt.Sprintf("removed:%d", event.Log.BlockNumber))
+			} else {
+				s = append(s, fmt.Sprintf("log:%d", event.Log.BlockNumber))
+			}
+		default:
+			s = append(s, fmt.Sprintf("%s:%d", event.Type, event.Header.Number))
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+func TestReplayEvents(t *testing.T) {
+	var (
+		db, _   = aquadb.NewMemDatabase()
+		backend = &testBackend{new(event.TypeMux), db, 0, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)}
+		addr    = common.BytesToAddress([]byte("replay"))
+		genesis = core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	)
+	withLogs := func(numbers ...int) func(int, *core.BlockGen) {
+		return func(i int, gen *core.BlockGen) {
+			for _, n := range numbers {
+				if gen.Number().Int64() == int64(n) {
+					receipt := makeReceipt(addr)
+					receipt.Logs[0].BlockNumber = uint64(n)
+					gen.AddUncheckedReceipt(receipt)
+				}
+			}
+		}
+	}
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, aquahash.NewFaker(), db, 6, withLogs(2, 4))
+	writeCanonical(t, db, chain, receipts)
+
+	var events []*ReplayEvent
+	r := &replayer{
+		backend: backend,
+		from:    1,
+		types:   map[string]bool{ReplayBlock: true, ReplayLog: true, ReplayReorg: true},
+		send: func(event *ReplayEvent) error {
+			events = append(events, event)
+			return nil
+		},
+	}
+	if err := r.advance(4); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := describe(events), "block:1 block:2 log:2 block:3 block:4 log:4"; have != want {
+		t.Fatalf("replay to #4 mismatch:\nhave %s\nwant %s", have, want)
+	}
+
+	// Replace blocks 4 and up, the replay must roll back to block 3
+	fork, forkReceipts := core.GenerateChain(params.TestChainConfig, chain[2], aquahash.NewFaker(), db, 4, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.BytesToAddress([]byte("fork")))
+		withLogs(5)(i, gen)
+	})
+	writeCanonical(t, db, fork, forkReceipts)
+
+	events = nil
+	if err := r.advance(^uint64(0)); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := describe(events), "reorg:3 removed:4 block:4 block:5 log:5 block:6 block:7"; have != want {
+		t.Fatalf("replay after reorg mismatch:\nhave %s\nwant %s", have, want)
+	}
+	if r.last.Hash() != fork[len(fork)-1].Hash() {
+		t.Errorf("replay stopped at %x, want head %x", r.last.Hash(), fork[len(fork)-1].Hash())
+	}
+
+	// Selecting block events only leaves out logs and reorgs
+	events = nil
+	r = &replayer{
+		backend: backend,
+		from:    3,
+		types:   map[string]bool{ReplayBlock: true},
+		send:    r.send,
+	}
+	if err := r.advance(5); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := describe(events), "block:3 block:4 block:5"; have != want {
+		t.Fatalf("block replay mismatch:\nhave %s\nwant %s", have, want)
+	}
+}
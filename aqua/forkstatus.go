@@ -0,0 +1,108 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"time"
+
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core/forkid"
+	"github.com/aquanetwork/aquachain/log"
+)
+
+const (
+	forkCheckInterval = 10 * time.Minute // Interval between two checks of the peers' fork readiness
+	forkWarnBlocks    = 5040             // Blocks ahead of a fork to warn about unready peers (about two weeks)
+)
+
+// PeerForkStatus is the fork identifier announced by a peer and its readiness
+// for the next local fork.
+type PeerForkStatus struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Version   int              `json:"version"`
+	ForkHash  hexutil.Bytes    `json:"forkHash,omitempty"`
+	ForkNext  uint64           `json:"forkNext"`
+	Readiness forkid.Readiness `json:"readiness"`
+}
+
+// ForkStatus is the readiness of the connected peers for the next scheduled
+// fork of the local chain.
+type ForkStatus struct {
+	Head       uint64                   `json:"head"`
+	ForkHash   hexutil.Bytes            `json:"forkHash"`
+	NextFork   uint64                   `json:"nextFork"`   // 0 if no fork is scheduled
+	BlocksLeft uint64                   `json:"blocksLeft"` // Blocks before the next fork
+	Peers      []PeerForkStatus         `json:"peers"`
+	Readiness  map[forkid.Readiness]int `json:"readiness"` // Number of peers per readiness
+	Warning    bool                     `json:"warning"`   // Whether most peers are unready for a close fork
+}
+
+// forkStatus checks the fork identifiers announced by the peers against the
+// next fork of the local chain.
+func (pm *ProtocolManager) forkStatus() *ForkStatus {
+	var (
+		genesis = pm.blockchain.Genesis().Hash()
+		head    = pm.blockchain.CurrentHeader().Number.Uint64()
+		id      = forkid.NewID(pm.chainconfig, genesis, head)
+	)
+	status := &ForkStatus{
+		Head:      head,
+		ForkHash:  id.Hash[:],
+		NextFork:  id.Next,
+		Peers:     []PeerForkStatus{},
+		Readiness: make(map[forkid.Readiness]int),
+	}
+	if id.Next > 0 {
+		status.BlocksLeft = id.Next - head
+	}
+	for _, p := range pm.peers.Peers() {
+		peer := PeerForkStatus{ID: p.id, Name: p.Name(), Version: p.version, Readiness: forkid.Unknown}
+		if p.forkID != nil {
+			peer.ForkHash, peer.ForkNext = p.forkID.Hash[:], p.forkID.Next
+			peer.Readiness = forkid.CheckReadiness(pm.chainconfig, genesis, head, *p.forkID)
+		}
+		status.Peers = append(status.Peers, peer)
+		status.Readiness[peer.Readiness]++
+	}
+	// Warn if most of the peers telling their schedule won't follow a close fork
+	var (
+		ready   = status.Readiness[forkid.Ready]
+		unready = status.Readiness[forkid.Unupgraded] + status.Readiness[forkid.Mismatch]
+	)
+	status.Warning = id.Next > 0 && status.BlocksLeft <= forkWarnBlocks && unready > ready
+	return status
+}
+
+// forkMonitor periodically checks whether the peers are ready for the next
+// fork, warning the operator if most of them aren't once it comes close.
+func (pm *ProtocolManager) forkMonitor() {
+	ticker := time.NewTicker(forkCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if status := pm.forkStatus(); status.Warning {
+				log.Warn("Most peers are not ready for the upcoming fork", "block", status.NextFork, "left", status.BlocksLeft,
+					"ready", status.Readiness[forkid.Ready], "unupgraded", status.Readiness[forkid.Unupgraded], "mismatch", status.Readiness[forkid.Mismatch])
+			}
+		case <-pm.quitSync:
+			return
+		}
+	}
+}
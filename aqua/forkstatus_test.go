@@ -0,0 +1,73 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aqua/downloader"
+	"github.com/aquanetwork/aquachain/core/forkid"
+	"github.com/aquanetwork/aquachain/p2p"
+)
+
+// Tests that the peers are classified by the fork identifiers they announced,
+// and that the operator is warned if most of them are unready for the next fork.
+func TestForkStatus(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	var (
+		genesis = pm.blockchain.Genesis().Hash()
+		head    = pm.blockchain.CurrentHeader()
+		td      = pm.blockchain.GetTd(head.Hash(), head.Number.Uint64())
+		id      = forkid.NewID(pm.chainconfig, genesis, 0)
+	)
+	// Connect an old peer, a ready one and two unaware of the next fork
+	connect := func(version int, forkID *forkid.ID) {
+		p, _ := newTestPeer("peer", version, pm, false)
+		msg, err := p.app.ReadMsg()
+		if err != nil {
+			t.Fatalf("status recv: %v", err)
+		}
+		msg.Discard()
+		go p2p.Send(p.app, StatusMsg, &statusData{uint32(version), DefaultConfig.NetworkId, td, head.Hash(), genesis, forkID})
+	}
+	connect(63, nil)
+	connect(aqua66, &id)
+	connect(aqua66, &forkid.ID{Hash: id.Hash})
+	connect(aqua66, &forkid.ID{Hash: id.Hash})
+
+	for start := time.Now(); pm.peers.Len() < 4; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("peers not registered: have %d, want 4", pm.peers.Len())
+		}
+	}
+	status := pm.forkStatus()
+	if status.NextFork != id.Next || status.BlocksLeft != id.Next {
+		t.Errorf("next fork mismatch: have %d in %d blocks, want %d", status.NextFork, status.BlocksLeft, id.Next)
+	}
+	want := map[forkid.Readiness]int{forkid.Unknown: 1, forkid.Ready: 1, forkid.Unupgraded: 2}
+	for readiness, count := range want {
+		if status.Readiness[readiness] != count {
+			t.Errorf("%s peers mismatch: have %d, want %d", readiness, status.Readiness[readiness], count)
+		}
+	}
+	if !status.Warning {
+		t.Errorf("no warning with most peers unupgraded")
+	}
+}
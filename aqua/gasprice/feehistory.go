@@ -0,0 +1,137 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+// maxFeeHistory is the maximum number of blocks a fee history can span.
+const maxFeeHistory = 1024
+
+var (
+	errInvalidPercentile = errors.New("invalid reward percentile")
+	errRequestBeyondHead = errors.New("request beyond head block")
+)
+
+// FeeHistory returns the gas used ratio of up to blocks blocks ending with
+// lastBlock, along with the gas prices paid at the given percentiles of the gas
+// used in each of them. Percentiles must be ascending numbers in [0, 100].
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	for i, p := range percentiles {
+		if p < 0 || p > 100 || (i > 0 && p < percentiles[i-1]) {
+			return nil, nil, nil, fmt.Errorf("%v: %f", errInvalidPercentile, p)
+		}
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	if blocks < 1 {
+		return new(big.Int), nil, nil, nil
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	last := head.Number.Uint64()
+	if lastBlock >= 0 {
+		if uint64(lastBlock) > last {
+			return nil, nil, nil, fmt.Errorf("%v: requested %d, head %d", errRequestBeyondHead, lastBlock, last)
+		}
+		last = uint64(lastBlock)
+	}
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	oldest := last + 1 - uint64(blocks)
+
+	var (
+		rewards = make([][]*big.Int, blocks)
+		ratios  = make([]float64, blocks)
+	)
+	for i := 0; i < blocks; i++ {
+		block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(oldest+uint64(i)))
+		if block == nil {
+			if err == nil {
+				err = fmt.Errorf("block #%d not found", oldest+uint64(i))
+			}
+			return nil, nil, nil, err
+		}
+		if block.GasLimit() > 0 {
+			ratios[i] = float64(block.GasUsed()) / float64(block.GasLimit())
+		}
+		if len(percentiles) == 0 {
+			continue
+		}
+		var receipts types.Receipts
+		if len(block.Transactions()) > 0 {
+			if receipts, err = gpo.backend.GetReceipts(ctx, block.Hash()); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		rewards[i] = blockRewards(block, receipts, percentiles)
+	}
+	if len(percentiles) == 0 {
+		rewards = nil
+	}
+	return new(big.Int).SetUint64(oldest), rewards, ratios, nil
+}
+
+// txGasAndPrice is the gas used and gas price of a single transaction.
+type txGasAndPrice struct {
+	gasUsed uint64
+	price   *big.Int
+}
+
+// blockRewards returns the gas prices paid at the given percentiles of the gas
+// used by the transactions of a block, ordered by gas price.
+func blockRewards(block *types.Block, receipts types.Receipts, percentiles []float64) []*big.Int {
+	reward := make([]*big.Int, len(percentiles))
+	txs := block.Transactions()
+	if len(txs) == 0 || len(receipts) != len(txs) {
+		for i := range reward {
+			reward[i] = new(big.Int)
+		}
+		return reward
+	}
+	sorted := make([]txGasAndPrice, len(txs))
+	for i, tx := range txs {
+		sorted[i] = txGasAndPrice{gasUsed: receipts[i].GasUsed, price: tx.GasPrice()}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].price.Cmp(sorted[j].price) < 0 })
+
+	var (
+		txIndex    int
+		sumGasUsed = sorted[0].gasUsed
+	)
+	for i, p := range percentiles {
+		threshold := uint64(float64(block.GasUsed()) * p / 100)
+		for sumGasUsed < threshold && txIndex < len(sorted)-1 {
+			txIndex++
+			sumGasUsed += sorted[txIndex].gasUsed
+		}
+		reward[i] = sorted[txIndex].price
+	}
+	return reward
+}
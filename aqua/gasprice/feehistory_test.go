@@ -0,0 +1,131 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/internal/aquaapi"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+// testBackend serves a chain of blocks, each containing transactions priced
+// 1, 2 and 3 wei using 10000, 20000 and 30000 gas respectively.
+type testBackend struct {
+	aquaapi.Backend
+	blocks []*types.Block
+}
+
+func newTestBackend(n int) *testBackend {
+	b := new(testBackend)
+	for i := 0; i < n; i++ {
+		header := &types.Header{
+			Number:   big.NewInt(int64(i)),
+			GasLimit: 120000,
+			GasUsed:  60000,
+			Version:  types.H_KECCAK256,
+		}
+		var txs []*types.Transaction
+		for j := 3; j > 0; j-- {
+			txs = append(txs, types.NewTransaction(uint64(j), common.Address{}, new(big.Int), 21000, big.NewInt(int64(j)), nil))
+		}
+		b.blocks = append(b.blocks, types.NewBlockWithHeader(header).WithBody(txs, nil))
+	}
+	return b
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	block, err := b.BlockByNumber(ctx, number)
+	if block == nil {
+		return nil, err
+	}
+	return block.Header(), nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber {
+		number = rpc.BlockNumber(len(b.blocks) - 1)
+	}
+	if int(number) >= len(b.blocks) {
+		return nil, nil
+	}
+	return b.blocks[number], nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return types.Receipts{{GasUsed: 30000}, {GasUsed: 20000}, {GasUsed: 10000}}, nil
+}
+
+func TestFeeHistory(t *testing.T) {
+	oracle := NewOracle(newTestBackend(10), Config{Blocks: 20, Percentile: 60})
+
+	tests := []struct {
+		blocks      int
+		last        rpc.BlockNumber
+		percentiles []float64
+		oldest      uint64
+		count       int
+		reward      []int64
+		fail        bool
+	}{
+		{blocks: 3, last: rpc.LatestBlockNumber, percentiles: []float64{0, 10, 20, 50, 100}, oldest: 7, count: 3, reward: []int64{1, 1, 2, 2, 3}},
+		{blocks: 20, last: 4, oldest: 0, count: 5},
+		{blocks: 2, last: 10, fail: true},
+		{blocks: 2, last: 5, percentiles: []float64{50, 10}, fail: true},
+		{blocks: 2, last: 5, percentiles: []float64{101}, fail: true},
+	}
+	for i, tt := range tests {
+		oldest, reward, ratios, err := oracle.FeeHistory(context.Background(), tt.blocks, tt.last, tt.percentiles)
+		if (err != nil) != tt.fail {
+			t.Errorf("test %d: error mismatch: have %v, want failure %v", i, err, tt.fail)
+			continue
+		}
+		if tt.fail {
+			continue
+		}
+		if oldest.Uint64() != tt.oldest {
+			t.Errorf("test %d: oldest block mismatch: have %v, want %d", i, oldest, tt.oldest)
+		}
+		if len(ratios) != tt.count {
+			t.Errorf("test %d: ratio count mismatch: have %d, want %d", i, len(ratios), tt.count)
+		}
+		for _, ratio := range ratios {
+			if ratio != 0.5 {
+				t.Errorf("test %d: gas used ratio mismatch: have %f, want 0.5", i, ratio)
+			}
+		}
+		if tt.percentiles == nil {
+			if reward != nil {
+				t.Errorf("test %d: rewards returned without percentiles", i)
+			}
+			continue
+		}
+		if len(reward) != tt.count {
+			t.Errorf("test %d: reward count mismatch: have %d, want %d", i, len(reward), tt.count)
+			continue
+		}
+		for j, want := range tt.reward {
+			if have := reward[0][j]; have.Int64() != want {
+				t.Errorf("test %d: reward %d mismatch: have %v, want %d", i, j, have, want)
+			}
+		}
+	}
+}
@@ -4,13 +4,16 @@ package aqua
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/aquanetwork/aquachain/aqua/downloader"
 	"github.com/aquanetwork/aquachain/aqua/gasprice"
+	"github.com/aquanetwork/aquachain/aqua/scheduler"
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/hexutil"
 	"github.com/aquanetwork/aquachain/consensus/aquahash"
 	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/miner"
 )
 
 var _ = (*configMarshaling)(nil)
@@ -20,38 +23,98 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
-		LightServ               int  `toml:",omitempty"`
-		LightPeers              int  `toml:",omitempty"`
-		SkipBcVersionCheck      bool `toml:"-"`
-		DatabaseHandles         int  `toml:"-"`
+		NoPruning               bool
+		TxLookupLimit           uint64 `toml:",omitempty"`
+		Snapshot                bool   `toml:",omitempty"`
+		NoPrefetch              bool   `toml:",omitempty"`
+		AccountHistory          bool   `toml:",omitempty"`
+		LightServ               int    `toml:",omitempty"`
+		LightPeers              int    `toml:",omitempty"`
+		SkipBcVersionCheck      bool   `toml:"-"`
+		DatabaseHandles         int    `toml:"-"`
 		DatabaseCache           int
-		Aquabase                common.Address `toml:",omitempty"`
-		MinerThreads            int            `toml:",omitempty"`
-		ExtraData               hexutil.Bytes  `toml:",omitempty"`
+		StorageCompression      bool `toml:",omitempty"`
+		TrieCache               int
+		TrieTimeout             time.Duration
+		Aquabase                common.Address   `toml:",omitempty"`
+		Aquabases               []common.Address `toml:",omitempty"`
+		Mining                  bool             `toml:",omitempty"`
+		MinerThreads            int              `toml:",omitempty"`
+		ExtraData               hexutil.Bytes    `toml:",omitempty"`
+		MinerPeers              bool             `toml:",omitempty"`
+		ForkGuard               bool             `toml:",omitempty"`
+		StaleGrace              bool             `toml:",omitempty"`
+		ForceAquabase           bool             `toml:",omitempty"`
+		TieBreak                core.TieBreak    `toml:",omitempty"`
 		GasPrice                *big.Int
+		AnnounceDelay           time.Duration `toml:",omitempty"`
+		WithholdBlocks          bool          `toml:",omitempty"`
+		Uncles                  miner.UnclePolicy
 		Aquahash                aquahash.Config
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
-		DocRoot                 string `toml:"-"`
+		ForensicsDir            string `toml:",omitempty"`
+		RPCCallDepth            int    `toml:",omitempty"`
+		RPCMemoryLimit          uint64 `toml:",omitempty"`
+		RPCTraceLimit           int    `toml:",omitempty"`
+		RPCGasCap               uint64 `toml:",omitempty"`
+		RPCCallGasCap           uint64 `toml:",omitempty"`
+		RPCReexec               uint64
+		RPCAllowUnprotectedTxs  bool                 `toml:",omitempty"`
+		RPCTxWebhook            string               `toml:",omitempty"`
+		NoPersonal              bool                 `toml:",omitempty"`
+		ContractRegistry        string               `toml:",omitempty"`
+		Maintenance             []scheduler.Schedule `toml:",omitempty"`
+		DocRoot                 string               `toml:"-"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
+	enc.NoPruning = c.NoPruning
+	enc.TxLookupLimit = c.TxLookupLimit
+	enc.Snapshot = c.Snapshot
+	enc.NoPrefetch = c.NoPrefetch
+	enc.AccountHistory = c.AccountHistory
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.StorageCompression = c.StorageCompression
+	enc.TrieCache = c.TrieCache
+	enc.TrieTimeout = c.TrieTimeout
 	enc.Aquabase = c.Aquabase
+	enc.Aquabases = c.Aquabases
+	enc.Mining = c.Mining
 	enc.MinerThreads = c.MinerThreads
 	enc.ExtraData = c.ExtraData
+	enc.MinerPeers = c.MinerPeers
+	enc.ForkGuard = c.ForkGuard
+	enc.StaleGrace = c.StaleGrace
+	enc.ForceAquabase = c.ForceAquabase
+	enc.TieBreak = c.TieBreak
 	enc.GasPrice = c.GasPrice
+	enc.AnnounceDelay = c.AnnounceDelay
+	enc.WithholdBlocks = c.WithholdBlocks
+	enc.Uncles = c.Uncles
 	enc.Aquahash = c.Aquahash
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.ForensicsDir = c.ForensicsDir
+	enc.RPCCallDepth = c.RPCCallDepth
+	enc.RPCMemoryLimit = c.RPCMemoryLimit
+	enc.RPCTraceLimit = c.RPCTraceLimit
+	enc.RPCGasCap = c.RPCGasCap
+	enc.RPCCallGasCap = c.RPCCallGasCap
+	enc.RPCReexec = c.RPCReexec
+	enc.RPCAllowUnprotectedTxs = c.RPCAllowUnprotectedTxs
+	enc.RPCTxWebhook = c.RPCTxWebhook
+	enc.NoPersonal = c.NoPersonal
+	enc.ContractRegistry = c.ContractRegistry
+	enc.Maintenance = c.Maintenance
 	enc.DocRoot = c.DocRoot
 	return &enc, nil
 }
@@ -61,20 +124,50 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
-		LightServ               *int  `toml:",omitempty"`
-		LightPeers              *int  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool `toml:"-"`
-		DatabaseHandles         *int  `toml:"-"`
+		NoPruning               *bool
+		TxLookupLimit           *uint64 `toml:",omitempty"`
+		Snapshot                *bool   `toml:",omitempty"`
+		NoPrefetch              *bool   `toml:",omitempty"`
+		AccountHistory          *bool   `toml:",omitempty"`
+		LightServ               *int    `toml:",omitempty"`
+		LightPeers              *int    `toml:",omitempty"`
+		SkipBcVersionCheck      *bool   `toml:"-"`
+		DatabaseHandles         *int    `toml:"-"`
 		DatabaseCache           *int
-		Aquabase                *common.Address `toml:",omitempty"`
-		MinerThreads            *int            `toml:",omitempty"`
-		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
+		StorageCompression      *bool `toml:",omitempty"`
+		TrieCache               *int
+		TrieTimeout             *time.Duration
+		Aquabase                *common.Address  `toml:",omitempty"`
+		Aquabases               []common.Address `toml:",omitempty"`
+		Mining                  *bool            `toml:",omitempty"`
+		MinerThreads            *int             `toml:",omitempty"`
+		ExtraData               *hexutil.Bytes   `toml:",omitempty"`
+		MinerPeers              *bool            `toml:",omitempty"`
+		ForkGuard               *bool            `toml:",omitempty"`
+		StaleGrace              *bool            `toml:",omitempty"`
+		ForceAquabase           *bool            `toml:",omitempty"`
+		TieBreak                *core.TieBreak   `toml:",omitempty"`
 		GasPrice                *big.Int
+		AnnounceDelay           *time.Duration `toml:",omitempty"`
+		WithholdBlocks          *bool          `toml:",omitempty"`
+		Uncles                  *miner.UnclePolicy
 		Aquahash                *aquahash.Config
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
-		DocRoot                 *string `toml:"-"`
+		ForensicsDir            *string `toml:",omitempty"`
+		RPCCallDepth            *int    `toml:",omitempty"`
+		RPCMemoryLimit          *uint64 `toml:",omitempty"`
+		RPCTraceLimit           *int    `toml:",omitempty"`
+		RPCGasCap               *uint64 `toml:",omitempty"`
+		RPCCallGasCap           *uint64 `toml:",omitempty"`
+		RPCReexec               *uint64
+		RPCAllowUnprotectedTxs  *bool                `toml:",omitempty"`
+		RPCTxWebhook            *string              `toml:",omitempty"`
+		NoPersonal              *bool                `toml:",omitempty"`
+		ContractRegistry        *string              `toml:",omitempty"`
+		Maintenance             []scheduler.Schedule `toml:",omitempty"`
+		DocRoot                 *string              `toml:"-"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -89,6 +182,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.SyncMode != nil {
 		c.SyncMode = *dec.SyncMode
 	}
+	if dec.NoPruning != nil {
+		c.NoPruning = *dec.NoPruning
+	}
+	if dec.TxLookupLimit != nil {
+		c.TxLookupLimit = *dec.TxLookupLimit
+	}
+	if dec.Snapshot != nil {
+		c.Snapshot = *dec.Snapshot
+	}
+	if dec.NoPrefetch != nil {
+		c.NoPrefetch = *dec.NoPrefetch
+	}
+	if dec.AccountHistory != nil {
+		c.AccountHistory = *dec.AccountHistory
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
@@ -104,18 +212,57 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.StorageCompression != nil {
+		c.StorageCompression = *dec.StorageCompression
+	}
+	if dec.TrieCache != nil {
+		c.TrieCache = *dec.TrieCache
+	}
+	if dec.TrieTimeout != nil {
+		c.TrieTimeout = *dec.TrieTimeout
+	}
 	if dec.Aquabase != nil {
 		c.Aquabase = *dec.Aquabase
 	}
+	if dec.Aquabases != nil {
+		c.Aquabases = dec.Aquabases
+	}
+	if dec.Mining != nil {
+		c.Mining = *dec.Mining
+	}
 	if dec.MinerThreads != nil {
 		c.MinerThreads = *dec.MinerThreads
 	}
 	if dec.ExtraData != nil {
 		c.ExtraData = *dec.ExtraData
 	}
+	if dec.MinerPeers != nil {
+		c.MinerPeers = *dec.MinerPeers
+	}
+	if dec.ForkGuard != nil {
+		c.ForkGuard = *dec.ForkGuard
+	}
+	if dec.StaleGrace != nil {
+		c.StaleGrace = *dec.StaleGrace
+	}
+	if dec.ForceAquabase != nil {
+		c.ForceAquabase = *dec.ForceAquabase
+	}
+	if dec.TieBreak != nil {
+		c.TieBreak = *dec.TieBreak
+	}
 	if dec.GasPrice != nil {
 		c.GasPrice = dec.GasPrice
 	}
+	if dec.AnnounceDelay != nil {
+		c.AnnounceDelay = *dec.AnnounceDelay
+	}
+	if dec.WithholdBlocks != nil {
+		c.WithholdBlocks = *dec.WithholdBlocks
+	}
+	if dec.Uncles != nil {
+		c.Uncles = *dec.Uncles
+	}
 	if dec.Aquahash != nil {
 		c.Aquahash = *dec.Aquahash
 	}
@@ -128,6 +275,42 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.ForensicsDir != nil {
+		c.ForensicsDir = *dec.ForensicsDir
+	}
+	if dec.RPCCallDepth != nil {
+		c.RPCCallDepth = *dec.RPCCallDepth
+	}
+	if dec.RPCMemoryLimit != nil {
+		c.RPCMemoryLimit = *dec.RPCMemoryLimit
+	}
+	if dec.RPCTraceLimit != nil {
+		c.RPCTraceLimit = *dec.RPCTraceLimit
+	}
+	if dec.RPCGasCap != nil {
+		c.RPCGasCap = *dec.RPCGasCap
+	}
+	if dec.RPCCallGasCap != nil {
+		c.RPCCallGasCap = *dec.RPCCallGasCap
+	}
+	if dec.RPCReexec != nil {
+		c.RPCReexec = *dec.RPCReexec
+	}
+	if dec.RPCAllowUnprotectedTxs != nil {
+		c.RPCAllowUnprotectedTxs = *dec.RPCAllowUnprotectedTxs
+	}
+	if dec.RPCTxWebhook != nil {
+		c.RPCTxWebhook = *dec.RPCTxWebhook
+	}
+	if dec.NoPersonal != nil {
+		c.NoPersonal = *dec.NoPersonal
+	}
+	if dec.ContractRegistry != nil {
+		c.ContractRegistry = *dec.ContractRegistry
+	}
+	if dec.Maintenance != nil {
+		c.Maintenance = dec.Maintenance
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
@@ -32,22 +32,30 @@ import (
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/consensus"
 	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/forkid"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/event"
 	"github.com/aquanetwork/aquachain/log"
 	"github.com/aquanetwork/aquachain/p2p"
 	"github.com/aquanetwork/aquachain/p2p/discover"
 	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/permission"
 	"github.com/aquanetwork/aquachain/rlp"
+	"github.com/hashicorp/golang-lru"
 )
 
 const (
 	softResponseLimit = 2 * 1024 * 1024 // Target maximum size of returned blocks, headers or node data.
 	estHeaderRlpSize  = 500             // Approximate size of an RLP encoded block header
+	maxPooledTxsServe = 256             // Maximum number of pooled transactions served per request
 
 	// txChanSize is the size of channel listening to TxPreEvent.
 	// The number is referenced from the size of tx pool.
 	txChanSize = 4096
+
+	// propagatedBlocks is the number of recently propagated blocks served to the
+	// peers fetching them after a cut-through announcement, before their import.
+	propagatedBlocks = 16
 )
 
 var (
@@ -58,6 +66,13 @@ var (
 // not compatible (low protocol version restrictions and high requirements).
 var errIncompatibleConfig = errors.New("incompatible configuration")
 
+// errNodeNotPermitted is returned if a peer is not allowed to join a permissioned
+// network.
+var errNodeNotPermitted = errors.New("node not permitted")
+
+// errPeerBanned is returned if a peer is banned for misbehaving.
+var errPeerBanned = errors.New("peer banned")
+
 func errResp(code errCode, format string, v ...interface{}) error {
 	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
 }
@@ -65,17 +80,24 @@ func errResp(code errCode, format string, v ...interface{}) error {
 type ProtocolManager struct {
 	networkId uint64
 
-	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
-	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
+	fastSync   uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
+	acceptTxs  uint32 // Flag whether we're considered synchronised (enables transaction processing)
+	headerSync bool   // Flag whether only headers are tracked, without any block content or state
 
 	txpool      txPool
 	blockchain  *core.BlockChain
 	chainconfig *params.ChainConfig
 	maxPeers    int
+	permission  *permission.Checker // Node allowlist of permissioned networks, nil if unrestricted
 
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
+	txFetcher  *fetcher.TxFetcher
+	propagated *lru.Cache // Blocks recently propagated, served before their import
 	peers      *peerSet
+	scores     *peerScorer
+	forkFilter forkid.Filter   // Fork identifier filter of the peers announcing one
+	announcer  *minedAnnouncer // Delays or withholds mined blocks for research, nil if disabled
 
 	SubProtocols []p2p.Protocol
 
@@ -106,11 +128,15 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		blockchain:  blockchain,
 		chainconfig: config,
 		peers:       newPeerSet(),
+		scores:      newPeerScorer(""),
 		newPeerCh:   make(chan *peer),
 		noMorePeers: make(chan struct{}),
 		txsyncCh:    make(chan *txsync),
 		quitSync:    make(chan struct{}),
 	}
+	manager.forkFilter = forkid.NewFilter(config, blockchain.Genesis().Hash(), func() uint64 {
+		return blockchain.CurrentHeader().Number.Uint64()
+	})
 	// Figure out whether to allow fast sync or not
 	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
 		log.Warn("Blockchain not empty, fast sync disabled")
@@ -119,6 +145,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 	if mode == downloader.FastSync {
 		manager.fastSync = uint32(1)
 	}
+	manager.headerSync = mode == downloader.HeaderSync
 	// Initiate a sub-protocol for every implemented version we can handle
 	manager.SubProtocols = make([]p2p.Protocol, 0, len(ProtocolVersions))
 	for i, version := range ProtocolVersions {
@@ -148,7 +175,9 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 			},
 			PeerInfo: func(id discover.NodeID) interface{} {
 				if p := manager.peers.Peer(fmt.Sprintf("%x", id[:8])); p != nil {
-					return p.Info()
+					info := p.Info()
+					info.Score = manager.scores.score(p.id)
+					return info
 				}
 				return nil
 			},
@@ -165,7 +194,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		return engine.VerifyHeader(blockchain, header, true)
 	}
 	heighter := func() uint64 {
-		return blockchain.CurrentBlock().NumberU64()
+		return manager.currentHead().Number.Uint64()
 	}
 	inserter := func(blocks types.Blocks) (int, error) {
 		// If fast sync is running, deny importing weird blocks
@@ -173,14 +202,38 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 			log.Warn("Discarded bad propagated block", "number", blocks[0].Number(), "hash", blocks[0].Hash())
 			return 0, nil
 		}
+		// Header only nodes keep the headers of propagated blocks, nothing else
+		if manager.headerSync {
+			headers := make([]*types.Header, len(blocks))
+			for i, block := range blocks {
+				headers[i] = block.Header()
+			}
+			return manager.blockchain.InsertHeaderChain(headers, 1)
+		}
 		atomic.StoreUint32(&manager.acceptTxs, 1) // Mark initial sync done on any fetcher import
 		return manager.blockchain.InsertChain(blocks)
 	}
-	manager.fetcher = fetcher.New(config.GetBlockVersion, blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removePeer)
+	manager.fetcher = fetcher.New(config.GetBlockVersion, blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, func(id string) {
+		manager.misbehave(id, penaltyInvalid, "invalid block")
+		manager.removePeer(id)
+	})
+	manager.txFetcher = fetcher.NewTxFetcher(func(hash common.Hash) bool {
+		return txpool.Get(hash) != nil
+	}, txpool.AddRemotesFrom)
+	manager.propagated, _ = lru.New(propagatedBlocks)
 
 	return manager, nil
 }
 
+// currentHead retrieves the head of the locally tracked chain, which is the
+// current header for header only nodes and the current block otherwise.
+func (pm *ProtocolManager) currentHead() *types.Header {
+	if pm.headerSync {
+		return pm.blockchain.CurrentHeader()
+	}
+	return pm.blockchain.CurrentBlock().Header()
+}
+
 func (pm *ProtocolManager) removePeer(id string) {
 	// Short circuit if the peer was already removed
 	peer := pm.peers.Peer(id)
@@ -191,6 +244,7 @@ func (pm *ProtocolManager) removePeer(id string) {
 
 	// Unregister the peer from the downloader and AquaChain peer set
 	pm.downloader.UnregisterPeer(id)
+	pm.txFetcher.Drop(id)
 	if err := pm.peers.Unregister(id); err != nil {
 		log.Error("Peer removal failed", "peer", id, "err", err)
 	}
@@ -200,6 +254,16 @@ func (pm *ProtocolManager) removePeer(id string) {
 	}
 }
 
+// misbehave penalizes a peer for misbehaving, disconnecting it if it got banned,
+// and reports whether it did.
+func (pm *ProtocolManager) misbehave(id string, penalty float64, cause string) bool {
+	if !pm.scores.penalize(id, penalty, cause) {
+		return false
+	}
+	pm.removePeer(id)
+	return true
+}
+
 func (pm *ProtocolManager) Start(maxPeers int) {
 	pm.maxPeers = maxPeers
 
@@ -215,6 +279,7 @@ func (pm *ProtocolManager) Start(maxPeers int) {
 	// start sync handlers
 	go pm.syncer()
 	go pm.txsyncLoop()
+	go pm.forkMonitor()
 }
 
 func (pm *ProtocolManager) Stop() {
@@ -252,6 +317,16 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	if pm.peers.Len() >= pm.maxPeers {
 		return p2p.DiscTooManyPeers
 	}
+	if pm.permission != nil && !pm.permission.NodeAllowed(p.ID()) {
+		p.Log().Debug("Rejected unpermitted AquaChain peer", "name", p.Name())
+		return errNodeNotPermitted
+	}
+	if pm.scores.connect(p.id, p.ID(), p.Peer.Trusted()) {
+		p.Log().Debug("Rejected banned AquaChain peer", "name", p.Name())
+		return errPeerBanned
+	}
+	defer pm.scores.disconnect(p.id)
+
 	p.Log().Debug("AquaChain peer connected", "name", p.Name())
 
 	// Execute the AquaChain handshake
@@ -262,7 +337,8 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		number  = head.Number.Uint64()
 		td      = pm.blockchain.GetTd(hash, number)
 	)
-	if err := p.Handshake(pm.networkId, td, hash, genesis.Hash()); err != nil {
+	forkID := forkid.NewID(pm.chainconfig, genesis.Hash(), number)
+	if err := p.Handshake(pm.networkId, td, hash, genesis.Hash(), forkID, pm.forkFilter); err != nil {
 		p.Log().Debug("AquaChain handshake failed", "err", err)
 		return err
 	}
@@ -302,10 +378,26 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		return err
 	}
 	if msg.Size > ProtocolMaxMsgSize {
+		pm.misbehave(p.id, penaltyOversized, "oversized message")
 		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
 	}
 	defer msg.Discard()
 
+	// Throttle the requests of the peer, penalizing floods
+	switch msg.Code {
+	case GetBlockHeadersMsg, GetBlockBodiesMsg, GetNodeDataMsg, GetReceiptsMsg, GetPooledTransactionsMsg:
+		if wait := pm.scores.throttle(p.id); wait > 0 {
+			if pm.misbehave(p.id, penaltyThrottled, "request flood") {
+				return errPeerBanned
+			}
+			select {
+			case <-time.After(wait):
+			case <-pm.quitSync:
+				return p2p.DiscQuitting
+			}
+		}
+	}
+
 	// Handle the message depending on its contents
 	switch {
 	case msg.Code == StatusMsg:
@@ -332,6 +424,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			var origin *types.Header
 			if hashMode {
 				origin = pm.blockchain.GetHeaderByHash(query.Origin.Hash)
+				if origin == nil && len(headers) == 0 {
+					if block := pm.propagatedBlock(query.Origin.Hash); block != nil {
+						origin = block.Header()
+					}
+				}
 			} else {
 				origin = pm.blockchain.GetHeaderByNumber(query.Origin.Number)
 			}
@@ -397,6 +494,16 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&headers); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		if !p.Answered(BlockHeadersMsg) {
+			pm.misbehave(p.id, penaltyUseless, "unrequested headers")
+		}
+		// Keep the head headers requested to verify the advertised chains
+		if len(headers) == 1 {
+			header := types.CopyHeader(headers[0])
+			if types.VerifyHeaderVersion(pm.chainconfig, header) == nil {
+				p.DeliverHeadHeader(header)
+			}
+		}
 		// Filter out any explicitly requested headers, deliver the rest to the downloader
 		filter := len(headers) == 1
 		if filter {
@@ -429,7 +536,13 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				return errResp(ErrDecode, "msg %v: %v", msg, err)
 			}
 			// Retrieve the requested block body, stopping if enough was found
-			if data := pm.blockchain.GetBodyRLP(hash); len(data) != 0 {
+			data := pm.blockchain.GetBodyRLP(hash)
+			if len(data) == 0 {
+				if block := pm.propagatedBlock(hash); block != nil {
+					data, _ = rlp.EncodeToBytes(&blockBody{Transactions: block.Transactions(), Uncles: block.Uncles()})
+				}
+			}
+			if len(data) != 0 {
 				bodies = append(bodies, data)
 				bytes += len(data)
 			}
@@ -442,6 +555,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&request); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		if !p.Answered(BlockBodiesMsg) {
+			pm.misbehave(p.id, penaltyUseless, "unrequested bodies")
+		}
 		// Deliver them all to the downloader for queuing
 		trasactions := make([][]*types.Transaction, len(request))
 		uncles := make([][]*types.Header, len(request))
@@ -495,6 +611,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&data); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		if !p.Answered(NodeDataMsg) {
+			pm.misbehave(p.id, penaltyUseless, "unrequested node data")
+		}
 		// Deliver all to the downloader
 		if err := pm.downloader.DeliverNodeData(p.id, data); err != nil {
 			log.Debug("Failed to deliver node state data", "err", err)
@@ -542,6 +661,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&receipts); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		if !p.Answered(ReceiptsMsg) {
+			pm.misbehave(p.id, penaltyUseless, "unrequested receipts")
+		}
 		// Deliver all to the downloader
 		if err := pm.downloader.DeliverReceipts(p.id, receipts); err != nil {
 			log.Debug("Failed to deliver receipts", "err", err)
@@ -556,13 +678,26 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		for _, block := range announces {
 			p.MarkBlock(block.Hash)
 		}
-		// Schedule all the unknown hashes for retrieval
-		unknown := make(newBlockHashesData, 0, len(announces))
+		// Schedule all the unknown hashes for retrieval, penalizing stale ones
+		var (
+			unknown = make(newBlockHashesData, 0, len(announces))
+			stale   bool
+		)
 		for _, block := range announces {
+			if pm.announcer != nil {
+				pm.announcer.announced(block.Number)
+			}
+			if pm.staleAnnounce(block.Number) {
+				stale = true
+				continue
+			}
 			if !pm.blockchain.HasBlock(block.Hash, block.Number) {
 				unknown = append(unknown, block)
 			}
 		}
+		if stale && pm.misbehave(p.id, penaltyStale, "stale announcement") {
+			return errPeerBanned
+		}
 		for _, block := range unknown {
 			pm.fetcher.Notify(p.id, block.Hash, block.Number, time.Now(), p.RequestOneHeader, p.RequestBodies)
 		}
@@ -575,6 +710,15 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 		request.Block.ReceivedAt = msg.ReceivedAt
 		request.Block.ReceivedFrom = p
+		if pm.announcer != nil {
+			pm.announcer.announced(request.Block.NumberU64())
+		}
+		if pm.staleAnnounce(request.Block.NumberU64()) {
+			if pm.misbehave(p.id, penaltyStale, "stale block") {
+				return errPeerBanned
+			}
+			break
+		}
 
 		// Mark the peer as owning the block and schedule it for import
 		p.MarkBlock(request.Block.SetVersion(pm.chainconfig.GetBlockVersion(request.Block.Number())))
@@ -593,8 +737,8 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			// Schedule a sync if above ours. Note, this will not fire a sync for a gap of
 			// a singe block (as the true TD is below the propagated block), however this
 			// scenario should easily be covered by the fetcher.
-			currentBlock := pm.blockchain.CurrentBlock()
-			if trueTD.Cmp(pm.blockchain.GetTd(currentBlock.Hash(), currentBlock.NumberU64())) > 0 {
+			head := pm.currentHead()
+			if trueTD.Cmp(pm.blockchain.GetTd(head.Hash(), head.Number.Uint64())) > 0 {
 				go pm.synchronise(p)
 			}
 		}
@@ -616,7 +760,64 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 			p.MarkTransaction(tx.Hash())
 		}
-		pm.txpool.AddRemotes(txs)
+		pm.txpool.AddRemotesFrom(p.id, txs)
+
+	case p.version >= aqua67 && msg.Code == NewPooledTransactionHashesMsg:
+		// Transactions announced, retrieve the unknown ones once synchronised
+		if atomic.LoadUint32(&pm.acceptTxs) == 0 {
+			break
+		}
+		var hashes []common.Hash
+		if err := msg.Decode(&hashes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		for _, hash := range hashes {
+			p.MarkTransaction(hash)
+		}
+		pm.txFetcher.Notify(p.id, hashes, p.RequestTxs)
+
+	case p.version >= aqua67 && msg.Code == GetPooledTransactionsMsg:
+		// Decode the retrieval message
+		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
+		if _, err := msgStream.List(); err != nil {
+			return err
+		}
+		// Gather transactions until the fetch or network limits is reached
+		var (
+			hash  common.Hash
+			bytes common.StorageSize
+			txs   types.Transactions
+		)
+		for bytes < softResponseLimit && len(txs) < maxPooledTxsServe {
+			if err := msgStream.Decode(&hash); err == rlp.EOL {
+				break
+			} else if err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			// Retrieve the requested transaction, skipping any no longer pooled
+			if tx := pm.txpool.Get(hash); tx != nil {
+				txs = append(txs, tx)
+				bytes += tx.Size()
+			}
+		}
+		return p.SendPooledTransactions(txs)
+
+	case p.version >= aqua67 && msg.Code == PooledTransactionsMsg:
+		// Requested transactions arrived, deliver them to the pool if synchronised
+		if atomic.LoadUint32(&pm.acceptTxs) == 0 {
+			break
+		}
+		var txs []*types.Transaction
+		if err := msg.Decode(&txs); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		for i, tx := range txs {
+			if tx == nil {
+				return errResp(ErrDecode, "transaction %d is nil", i)
+			}
+			p.MarkTransaction(tx.Hash())
+		}
+		pm.txFetcher.Enqueue(p.id, txs)
 
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
@@ -624,6 +825,12 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	return nil
 }
 
+// staleAnnounce reports whether an announced block number is too far below our
+// head to be of any use.
+func (pm *ProtocolManager) staleAnnounce(number uint64) bool {
+	return number+staleAnnounceDist < pm.currentHead().Number.Uint64()
+}
+
 // BroadcastBlock will either propagate a block to a subset of it's peers, or
 // will only announce it's availability (depending what's requested).
 func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
@@ -645,7 +852,18 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 		for _, peer := range transfer {
 			peer.SendNewBlock(block, td)
 		}
-		log.Trace("Propagated block", "hash", hash, "recipients", len(transfer), "duration", common.PrettyDuration(time.Since(block.ReceivedAt)))
+		// Cut through to the rest of the peers speaking aqua/67, announcing the
+		// block without waiting for its import. They may fetch it meanwhile.
+		pm.propagated.Add(hash, block)
+
+		var announced int
+		for _, peer := range peers[len(transfer):] {
+			if peer.version >= aqua67 {
+				peer.SendNewBlockHashes([]common.Hash{hash}, []uint64{block.NumberU64()})
+				announced++
+			}
+		}
+		log.Trace("Propagated block", "hash", hash, "recipients", len(transfer), "announced", announced, "duration", common.PrettyDuration(time.Since(block.ReceivedAt)))
 		return
 	}
 	// Otherwise if the block is indeed in out own chain, announce it
@@ -658,15 +876,35 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 }
 
 // BroadcastTx will propagate a transaction to all peers which are not known to
-// already have the given transaction.
+// already have the given transaction. The transaction is sent in full to a
+// square root of them, and announced by hash to the rest speaking aqua/67, who
+// retrieve it if they don't know it yet. Older peers always get it in full.
 func (pm *ProtocolManager) BroadcastTx(hash common.Hash, tx *types.Transaction) {
-	// Broadcast transaction to a batch of peers not knowing about it
-	peers := pm.peers.PeersWithoutTx(hash)
-	//FIXME include this again: peers = peers[:int(math.Sqrt(float64(len(peers))))]
-	for _, peer := range peers {
-		peer.SendTransactions(types.Transactions{tx})
+	var (
+		peers     = pm.peers.PeersWithoutTx(hash)
+		direct    = int(math.Sqrt(float64(len(peers))))
+		sent      int
+		announced int
+	)
+	for i, peer := range peers {
+		if i < direct || peer.version < aqua67 {
+			peer.SendTransactions(types.Transactions{tx})
+			sent++
+		} else {
+			peer.SendPooledTransactionHashes([]common.Hash{hash})
+			announced++
+		}
 	}
-	log.Trace("Broadcast transaction", "hash", hash, "recipients", len(peers))
+	log.Trace("Broadcast transaction", "hash", hash, "recipients", sent, "announced", announced)
+}
+
+// propagatedBlock retrieves a block recently propagated, which might not have
+// been imported yet.
+func (pm *ProtocolManager) propagatedBlock(hash common.Hash) *types.Block {
+	if block, ok := pm.propagated.Get(hash); ok {
+		return block.(*types.Block)
+	}
+	return nil
 }
 
 // Mined broadcast loop
@@ -675,12 +913,21 @@ func (self *ProtocolManager) minedBroadcastLoop() {
 	for obj := range self.minedBlockSub.Chan() {
 		switch ev := obj.Data.(type) {
 		case core.NewMinedBlockEvent:
-			self.BroadcastBlock(ev.Block, true)  // First propagate block to peers
-			self.BroadcastBlock(ev.Block, false) // Only then announce to the rest
+			if self.announcer != nil {
+				self.announcer.mined(ev.Block)
+				continue
+			}
+			self.broadcastMined(ev.Block)
 		}
 	}
 }
 
+// broadcastMined propagates a locally mined block to the peers.
+func (self *ProtocolManager) broadcastMined(block *types.Block) {
+	self.BroadcastBlock(block, true)  // First propagate block to peers
+	self.BroadcastBlock(block, false) // Only then announce to the rest
+}
+
 func (self *ProtocolManager) txBroadcastLoop() {
 	for {
 		select {
@@ -706,12 +953,12 @@ type NodeInfo struct {
 
 // NodeInfo retrieves some protocol metadata about the running host node.
 func (self *ProtocolManager) NodeInfo() *NodeInfo {
-	currentBlock := self.blockchain.CurrentBlock()
+	head := self.currentHead()
 	return &NodeInfo{
 		Network:    self.networkId,
-		Difficulty: self.blockchain.GetTd(currentBlock.Hash(), currentBlock.NumberU64()),
+		Difficulty: self.blockchain.GetTd(head.Hash(), head.Number.Uint64()),
 		Genesis:    self.blockchain.Genesis().Hash(),
 		Config:     self.blockchain.Config(),
-		Head:       currentBlock.Hash(),
+		Head:       head.Hash(),
 	}
 }
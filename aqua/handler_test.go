@@ -521,3 +521,78 @@ func testDAOChallenge(t *testing.T, localForked, remoteForked bool, timeout bool
 		}
 	}
 }
+
+// Tests that the head headers of the peers are fetched on demand for the fork
+// guard to verify, and that answering such a request isn't penalised.
+func TestPeerHeadHeaders(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 4, nil, nil)
+	peer, _ := newTestPeer("peer", aqua64, pm, true)
+	defer peer.close()
+
+	for start := time.Now(); pm.peers.Len() == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("peer not registered")
+		}
+	}
+	head := pm.blockchain.CurrentHeader()
+	if heads := pm.peers.HeadHeaders(); len(heads) != 0 {
+		t.Fatalf("unfetched head headers returned: %v", heads)
+	}
+	if err := p2p.ExpectMsg(peer.app, GetBlockHeadersMsg, &getBlockHeadersData{Origin: hashOrNumber{Hash: head.Hash()}, Amount: 1}); err != nil {
+		t.Fatalf("head header not requested: %v", err)
+	}
+	if err := p2p.Send(peer.app, BlockHeadersMsg, []*types.Header{head}); err != nil {
+		t.Fatalf("failed to send head header: %v", err)
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if heads := pm.peers.HeadHeaders(); len(heads) == 1 {
+			if heads[0].Header.Hash() != head.Hash() {
+				t.Fatalf("head header mismatch: have %x, want %x", heads[0].Header.Hash(), head.Hash())
+			}
+			break
+		}
+		if time.Since(start) > time.Second {
+			t.Fatalf("head header not delivered")
+		}
+	}
+	if score := pm.scores.score(peer.peer.id); score != 0 {
+		t.Errorf("peer penalised for its head header: score %v", score)
+	}
+}
+
+// Tests that responses are only penalised if nothing was requested from the
+// peer, not if they come late or the sync they were meant for is over.
+func TestUnsolicitedResponses(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 4, nil, nil)
+	now := time.Now()
+	pm.scores.now = func() time.Time { return now }
+
+	peer, _ := newTestPeer("peer", aqua64, pm, true)
+	defer peer.close()
+
+	for start := time.Now(); pm.peers.Len() == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("peer not registered")
+		}
+	}
+	// Answer a request no sync is waiting for anymore
+	go pm.peers.Peer(peer.peer.id).RequestReceipts([]common.Hash{{0x01}})
+	if err := p2p.ExpectMsg(peer.app, GetReceiptsMsg, []common.Hash{{0x01}}); err != nil {
+		t.Fatalf("receipts not requested: %v", err)
+	}
+	if err := p2p.Send(peer.app, ReceiptsMsg, [][]*types.Receipt{}); err != nil {
+		t.Fatalf("failed to send receipts: %v", err)
+	}
+	// Send the same response again, unrequested this time
+	if err := p2p.Send(peer.app, ReceiptsMsg, [][]*types.Receipt{}); err != nil {
+		t.Fatalf("failed to send receipts: %v", err)
+	}
+	for start := time.Now(); pm.scores.score(peer.peer.id) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("unrequested receipts not penalised")
+		}
+	}
+	if score := pm.scores.score(peer.peer.id); score != penaltyUseless {
+		t.Fatalf("score mismatch: have %v, want %v", score, penaltyUseless)
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/consensus/aquahash"
 	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/forkid"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/core/vm"
 	"github.com/aquanetwork/aquachain/crypto"
@@ -108,6 +109,24 @@ func (p *testTxPool) AddRemotes(txs []*types.Transaction) []error {
 	return make([]error, len(txs))
 }
 
+// AddRemotesFrom appends a batch of transactions relayed by a peer to the pool.
+func (p *testTxPool) AddRemotesFrom(peer string, txs []*types.Transaction) []error {
+	return p.AddRemotes(txs)
+}
+
+// Get returns the transaction of the given hash, if known to the pool
+func (p *testTxPool) Get(hash common.Hash) *types.Transaction {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	for _, tx := range p.pool {
+		if tx.Hash() == hash {
+			return tx
+		}
+	}
+	return nil
+}
+
 // Pending returns all the transactions known to the pool
 func (p *testTxPool) Pending() (map[common.Address]types.Transactions, error) {
 	p.lock.RLock()
@@ -170,15 +189,16 @@ func newTestPeer(name string, version int, pm *ProtocolManager, shake bool) (*te
 			genesis = pm.blockchain.Genesis()
 			head    = pm.blockchain.CurrentHeader()
 			td      = pm.blockchain.GetTd(head.Hash(), head.Number.Uint64())
+			forkID  = forkid.NewID(pm.chainconfig, genesis.Hash(), head.Number.Uint64())
 		)
-		tp.handshake(nil, td, head.Hash(), genesis.Hash())
+		tp.handshake(nil, td, head.Hash(), genesis.Hash(), forkID)
 	}
 	return tp, errc
 }
 
 // handshake simulates a trivial handshake that expects the same state from the
 // remote side as we are simulating locally.
-func (p *testPeer) handshake(t *testing.T, td *big.Int, head common.Hash, genesis common.Hash) {
+func (p *testPeer) handshake(t *testing.T, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID) {
 	msg := &statusData{
 		ProtocolVersion: uint32(p.version),
 		NetworkId:       DefaultConfig.NetworkId,
@@ -186,6 +206,9 @@ func (p *testPeer) handshake(t *testing.T, td *big.Int, head common.Hash, genesi
 		CurrentBlock:    head,
 		GenesisBlock:    genesis,
 	}
+	if p.version >= aqua66 {
+		msg.ForkID = &forkID
+	}
 	if err := p2p.ExpectMsg(p.app, StatusMsg, msg); err != nil {
 		t.Fatalf("status recv: %v", err)
 	}
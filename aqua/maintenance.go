@@ -0,0 +1,132 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aqua/scheduler"
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/metrics"
+)
+
+// newMaintenance creates the scheduler running the configured maintenance tasks,
+// or nil if none are configured. The supported tasks are:
+//
+//	compact:  compacts the chain database
+//	snapshot: dumps the head state into a new file within Path
+//	backup:   exports the canonical chain into a new file within Path
+//	metrics:  appends the current metrics to the file at Path
+func newMaintenance(aqua *AquaChain, schedules []scheduler.Schedule) (*scheduler.Scheduler, error) {
+	if len(schedules) == 0 {
+		return nil, nil
+	}
+	funcs := make(map[string]scheduler.Func)
+	for _, schedule := range schedules {
+		path := schedule.Path
+		switch schedule.Task {
+		case "compact":
+			funcs[schedule.Task] = aqua.compactDatabase
+		case "snapshot", "backup", "metrics":
+			if path == "" {
+				return nil, fmt.Errorf("maintenance task %q requires a path", schedule.Task)
+			}
+		}
+		switch schedule.Task {
+		case "snapshot":
+			funcs[schedule.Task] = func(quit <-chan struct{}) error { return aqua.snapshotState(path) }
+		case "backup":
+			funcs[schedule.Task] = func(quit <-chan struct{}) error { return aqua.backupChain(path) }
+		case "metrics":
+			funcs[schedule.Task] = func(quit <-chan struct{}) error { return flushMetrics(path) }
+		}
+	}
+	return scheduler.New(schedules, funcs)
+}
+
+// compactDatabase compacts the whole key range of the chain database.
+func (s *AquaChain) compactDatabase(quit <-chan struct{}) error {
+	db, ok := s.chainDb.(aquadb.Compacter)
+	if !ok {
+		return fmt.Errorf("compaction unsupported by %T", s.chainDb)
+	}
+	return db.Compact()
+}
+
+// snapshotState dumps the state of the current head into a timestamped file
+// within the given directory.
+func (s *AquaChain) snapshotState(dir string) error {
+	head := s.blockchain.CurrentBlock()
+	statedb, err := s.blockchain.StateAt(head.Root())
+	if err != nil {
+		return err
+	}
+	return writeMaintenanceFile(dir, fmt.Sprintf("state-%d", head.NumberU64()), func(w io.Writer) error {
+		return statedb.IterativeDump(w, nil)
+	})
+}
+
+// backupChain exports the canonical chain up to the current head into a
+// timestamped file within the given directory.
+func (s *AquaChain) backupChain(dir string) error {
+	head := s.blockchain.CurrentBlock().NumberU64()
+	return writeMaintenanceFile(dir, fmt.Sprintf("chain-%d", head), func(w io.Writer) error {
+		return s.blockchain.ExportN(w, 0, head)
+	})
+}
+
+// writeMaintenanceFile writes gzipped content into a new timestamped file within
+// the directory. The file is written under a temporary name first, so partially
+// written files are never mistaken for complete ones.
+func writeMaintenanceFile(dir, prefix string, write func(w io.Writer) error) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	name := filepath.Join(dir, fmt.Sprintf("%s-%s.gz", prefix, time.Now().UTC().Format("20060102T150405Z")))
+	fh, err := os.OpenFile(name+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(fh)
+	if err = write(gz); err == nil {
+		err = gz.Close()
+	}
+	if cerr := fh.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name + ".tmp")
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
+
+// flushMetrics appends a snapshot of all registered metrics to the file.
+func flushMetrics(path string) error {
+	fh, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(fh, "# %s\n", time.Now().UTC().Format(time.RFC3339))
+	metrics.WriteOnce(metrics.DefaultRegistry, fh)
+	return fh.Close()
+}
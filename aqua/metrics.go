@@ -26,6 +26,10 @@ var (
 	propTxnInTrafficMeter     = metrics.NewRegisteredMeter("aqua/prop/txns/in/traffic", nil)
 	propTxnOutPacketsMeter    = metrics.NewRegisteredMeter("aqua/prop/txns/out/packets", nil)
 	propTxnOutTrafficMeter    = metrics.NewRegisteredMeter("aqua/prop/txns/out/traffic", nil)
+	propTxHashInPacketsMeter  = metrics.NewRegisteredMeter("aqua/prop/txhashes/in/packets", nil)
+	propTxHashInTrafficMeter  = metrics.NewRegisteredMeter("aqua/prop/txhashes/in/traffic", nil)
+	propTxHashOutPacketsMeter = metrics.NewRegisteredMeter("aqua/prop/txhashes/out/packets", nil)
+	propTxHashOutTrafficMeter = metrics.NewRegisteredMeter("aqua/prop/txhashes/out/traffic", nil)
 	propHashInPacketsMeter    = metrics.NewRegisteredMeter("aqua/prop/hashes/in/packets", nil)
 	propHashInTrafficMeter    = metrics.NewRegisteredMeter("aqua/prop/hashes/in/traffic", nil)
 	propHashOutPacketsMeter   = metrics.NewRegisteredMeter("aqua/prop/hashes/out/packets", nil)
@@ -50,6 +54,10 @@ var (
 	reqReceiptInTrafficMeter  = metrics.NewRegisteredMeter("aqua/req/receipts/in/traffic", nil)
 	reqReceiptOutPacketsMeter = metrics.NewRegisteredMeter("aqua/req/receipts/out/packets", nil)
 	reqReceiptOutTrafficMeter = metrics.NewRegisteredMeter("aqua/req/receipts/out/traffic", nil)
+	reqTxInPacketsMeter       = metrics.NewRegisteredMeter("aqua/req/txns/in/packets", nil)
+	reqTxInTrafficMeter       = metrics.NewRegisteredMeter("aqua/req/txns/in/traffic", nil)
+	reqTxOutPacketsMeter      = metrics.NewRegisteredMeter("aqua/req/txns/out/packets", nil)
+	reqTxOutTrafficMeter      = metrics.NewRegisteredMeter("aqua/req/txns/out/traffic", nil)
 	miscInPacketsMeter        = metrics.NewRegisteredMeter("aqua/misc/in/packets", nil)
 	miscInTrafficMeter        = metrics.NewRegisteredMeter("aqua/misc/in/traffic", nil)
 	miscOutPacketsMeter       = metrics.NewRegisteredMeter("aqua/misc/out/packets", nil)
@@ -103,6 +111,11 @@ func (rw *meteredMsgReadWriter) ReadMsg() (p2p.Msg, error) {
 		packets, traffic = propBlockInPacketsMeter, propBlockInTrafficMeter
 	case msg.Code == TxMsg:
 		packets, traffic = propTxnInPacketsMeter, propTxnInTrafficMeter
+
+	case rw.version >= aqua67 && msg.Code == NewPooledTransactionHashesMsg:
+		packets, traffic = propTxHashInPacketsMeter, propTxHashInTrafficMeter
+	case rw.version >= aqua67 && msg.Code == PooledTransactionsMsg:
+		packets, traffic = reqTxInPacketsMeter, reqTxInTrafficMeter
 	}
 	packets.Mark(1)
 	traffic.Mark(int64(msg.Size))
@@ -130,6 +143,11 @@ func (rw *meteredMsgReadWriter) WriteMsg(msg p2p.Msg) error {
 		packets, traffic = propBlockOutPacketsMeter, propBlockOutTrafficMeter
 	case msg.Code == TxMsg:
 		packets, traffic = propTxnOutPacketsMeter, propTxnOutTrafficMeter
+
+	case rw.version >= aqua67 && msg.Code == NewPooledTransactionHashesMsg:
+		packets, traffic = propTxHashOutPacketsMeter, propTxHashOutTrafficMeter
+	case rw.version >= aqua67 && msg.Code == PooledTransactionsMsg:
+		packets, traffic = reqTxOutPacketsMeter, reqTxOutTrafficMeter
 	}
 	packets.Mark(1)
 	traffic.Mark(int64(msg.Size))
@@ -0,0 +1,421 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package minerpeers implements an opt-in protocol extension through which mining
+// nodes announce themselves, allowing other miners to keep direct connections to
+// them and cut block propagation latency. Announcements are signed with the key
+// of the coinbase, so only miners holding it can announce themselves.
+package minerpeers
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/p2p"
+	"github.com/aquanetwork/aquachain/p2p/discover"
+	"github.com/aquanetwork/aquachain/rlp"
+)
+
+const (
+	// ProtocolName is the capability name of the miner announcement extension.
+	ProtocolName = "aquaminer"
+
+	// ProtocolVersion is the version of the miner announcement extension.
+	ProtocolVersion = 2
+
+	announceMsg = 0x00 // Announcement of a mining node
+	maxMsgSize  = 1024 // Maximum size of an announcement message
+
+	recentBlocks     = 256              // Number of recent blocks a coinbase must have mined in to be active
+	announceInterval = 5 * time.Minute  // Time between re-announcements of the local miner
+	announceLifetime = 20 * time.Minute // Time after which an unrefreshed announcement expires
+	maxMiners        = 16               // Maximum number of miners to keep direct connections to
+	maxCoinbaseNodes = 2                // Maximum number of nodes kept per coinbase
+)
+
+var (
+	errMsgTooLarge   = errors.New("message too large")
+	errStale         = errors.New("stale announcement")
+	errInactive      = errors.New("coinbase not active in recent blocks")
+	errTooManyMiners = errors.New("too many known miners")
+	errNotStarted    = errors.New("miner peering not started")
+	errNotSelf       = errors.New("announcement not made by the announced node")
+	errBadSignature  = errors.New("announcement not signed by the coinbase")
+	errTooManyNodes  = errors.New("too many nodes announced for coinbase")
+)
+
+// Announcement advertises a mining node along with the coinbase it mines to.
+type Announcement struct {
+	Coinbase common.Address
+	Node     string // Enode URL of the mining node
+	Time     uint64 // Unix time the announcement was made at
+	Sig      []byte // Signature of the coinbase over the fields above
+}
+
+// sigHash returns the hash signed by the coinbase of the announcement.
+func (ann *Announcement) sigHash() []byte {
+	enc, _ := rlp.EncodeToBytes([]interface{}{ann.Coinbase, ann.Node, ann.Time})
+	return crypto.Keccak256(enc)
+}
+
+// SignerFn is a signer callback function to request a hash to be signed by a
+// backing account.
+type SignerFn func(accounts.Account, []byte) ([]byte, error)
+
+// ChainReader retrieves the recent headers used to check coinbase activity.
+type ChainReader interface {
+	CurrentHeader() *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+}
+
+// Server is the part of the p2p server used to maintain connections to miners.
+type Server interface {
+	Self() *discover.Node
+	AddPeer(node *discover.Node)
+	RemovePeer(node *discover.Node)
+}
+
+// miner is an announced mining node we keep a direct connection to.
+type miner struct {
+	node *discover.Node
+	ann  *Announcement
+}
+
+// Manager tracks the miners announced on the network, keeps direct connections
+// to the ones which recently mined a block and announces the local node while
+// it is mining.
+type Manager struct {
+	chain    ChainReader
+	coinbase func() (common.Address, error) // Coinbase of the local miner
+	mining   func() bool                    // Whether the local node is mining
+	sign     SignerFn                       // Signer of the local announcements
+	server   Server
+
+	peers  map[discover.NodeID]p2p.MsgReadWriter // Peers supporting the extension
+	miners map[discover.NodeID]*miner            // Active miners we keep connections to
+
+	activeHead common.Hash             // Chain head the active coinbases were gathered at
+	active     map[common.Address]bool // Coinbases of the recent blocks
+
+	lock sync.Mutex
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a miner peering manager on top of the given chain. The local node
+// is only announced if sign can sign with the key of its coinbase.
+func New(chain ChainReader, coinbase func() (common.Address, error), mining func() bool, sign SignerFn) *Manager {
+	return &Manager{
+		chain:    chain,
+		coinbase: coinbase,
+		mining:   mining,
+		sign:     sign,
+		peers:    make(map[discover.NodeID]p2p.MsgReadWriter),
+		miners:   make(map[discover.NodeID]*miner),
+		quit:     make(chan struct{}),
+	}
+}
+
+// Protocols returns the protocol extension to run alongside the aqua protocol.
+func (m *Manager) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{{
+		Name:    ProtocolName,
+		Version: ProtocolVersion,
+		Length:  1,
+		Run:     m.handle,
+	}}
+}
+
+// Start starts announcing the local miner and expiring stale miners.
+func (m *Manager) Start(server Server) {
+	m.lock.Lock()
+	m.server = server
+	m.lock.Unlock()
+
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Stop terminates the announcement loop.
+func (m *Manager) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// Miners returns the announcements of the miners currently connected to.
+func (m *Manager) Miners() []*Announcement {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	anns := make([]*Announcement, 0, len(m.miners))
+	for _, miner := range m.miners {
+		anns = append(anns, miner.ann)
+	}
+	return anns
+}
+
+// handle is the callback invoked to manage the life cycle of a peer supporting
+// the extension.
+func (m *Manager) handle(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	id := p.ID()
+
+	m.lock.Lock()
+	m.peers[id] = rw
+	m.lock.Unlock()
+
+	defer func() {
+		m.lock.Lock()
+		delete(m.peers, id)
+		m.lock.Unlock()
+	}()
+	// Let the new peer know about us if we're mining
+	if ann := m.localAnnouncement(); ann != nil {
+		go send([]p2p.MsgReadWriter{rw}, ann)
+	}
+
+	var remoteIP net.IP
+	if addr, ok := p.RemoteAddr().(*net.TCPAddr); ok {
+		remoteIP = addr.IP
+	}
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		if msg.Size > maxMsgSize {
+			msg.Discard()
+			return errMsgTooLarge
+		}
+		if msg.Code != announceMsg {
+			msg.Discard()
+			return fmt.Errorf("invalid message code %d", msg.Code)
+		}
+		var ann Announcement
+		if err := msg.Decode(&ann); err != nil {
+			return fmt.Errorf("invalid announcement: %v", err)
+		}
+		if err := m.process(&ann, id, remoteIP); err != nil {
+			p.Log().Trace("Rejected miner announcement", "coinbase", ann.Coinbase, "err", err)
+		}
+	}
+}
+
+// process validates an announcement received from a peer and starts maintaining
+// a connection to the announced miner.
+//
+// Only the announcements a miner makes about itself are accepted, binding them
+// to the node ID authenticated by the p2p handshake, and they must be signed by
+// the coinbase. Every peer holds at most one entry, and every coinbase at most
+// maxCoinbaseNodes. Relayed announcements are never forwarded.
+func (m *Manager) process(ann *Announcement, from discover.NodeID, remoteIP net.IP) error {
+	now := time.Now()
+	announced := time.Unix(int64(ann.Time), 0)
+	if now.Sub(announced) > announceLifetime || announced.Sub(now) > time.Minute {
+		return errStale
+	}
+	node, err := discover.ParseNode(ann.Node)
+	if err != nil {
+		return err
+	}
+	if node.ID != from {
+		return errNotSelf
+	}
+	pubkey, err := crypto.SigToPub(ann.sigHash(), ann.Sig)
+	if err != nil || crypto.PubkeyToAddress(*pubkey) != ann.Coinbase {
+		return errBadSignature
+	}
+	// Miners behind an unconfigured NAT don't know their own IP, use the one
+	// they're connecting from
+	if node.IP.IsUnspecified() && remoteIP != nil {
+		node = discover.NewNode(node.ID, remoteIP, node.UDP, node.TCP)
+	}
+	if !m.isActive(ann.Coinbase) {
+		return errInactive
+	}
+	m.lock.Lock()
+	if m.server == nil {
+		m.lock.Unlock()
+		return errNotStarted
+	}
+	if node.ID == m.server.Self().ID {
+		m.lock.Unlock()
+		return nil
+	}
+	known, ok := m.miners[node.ID]
+	if ok && known.ann.Time >= ann.Time {
+		m.lock.Unlock()
+		return nil
+	}
+	if !ok && len(m.miners) >= maxMiners {
+		m.lock.Unlock()
+		return errTooManyMiners
+	}
+	if !ok || known.ann.Coinbase != ann.Coinbase {
+		nodes := 0
+		for _, miner := range m.miners {
+			if miner.ann.Coinbase == ann.Coinbase {
+				nodes++
+			}
+		}
+		if nodes >= maxCoinbaseNodes {
+			m.lock.Unlock()
+			return errTooManyNodes
+		}
+	}
+	m.miners[node.ID] = &miner{node: node, ann: ann}
+	if !ok {
+		log.Info("Maintaining connection to announced miner", "coinbase", ann.Coinbase, "node", node)
+		m.server.AddPeer(node)
+	}
+	m.lock.Unlock()
+
+	return nil
+}
+
+// isActive reports whether the coinbase mined any of the recent blocks.
+func (m *Manager) isActive(coinbase common.Address) bool {
+	head := m.chain.CurrentHeader()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if hash := head.Hash(); hash != m.activeHead {
+		m.active = make(map[common.Address]bool)
+		number := head.Number.Uint64()
+		for i := uint64(0); i < recentBlocks && i <= number; i++ {
+			if header := m.chain.GetHeaderByNumber(number - i); header != nil {
+				m.active[header.Coinbase] = true
+			}
+		}
+		m.activeHead = hash
+	}
+	return m.active[coinbase]
+}
+
+// loop periodically announces the local miner and drops stale miners.
+func (m *Manager) loop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(announceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.expire()
+			if ann := m.localAnnouncement(); ann != nil {
+				m.lock.Lock()
+				peers := make([]p2p.MsgReadWriter, 0, len(m.peers))
+				for _, rw := range m.peers {
+					peers = append(peers, rw)
+				}
+				m.lock.Unlock()
+
+				send(peers, ann)
+			}
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// expire drops the miners which weren't reannounced in time or didn't mine any
+// recent block, releasing their connections.
+func (m *Manager) expire() {
+	var stale []*miner
+
+	m.lock.Lock()
+	for id, miner := range m.miners {
+		if time.Since(time.Unix(int64(miner.ann.Time), 0)) > announceLifetime {
+			stale = append(stale, miner)
+			delete(m.miners, id)
+		}
+	}
+	m.lock.Unlock()
+
+	for id, miner := range m.snapshot() {
+		if !m.isActive(miner.ann.Coinbase) {
+			m.lock.Lock()
+			delete(m.miners, id)
+			m.lock.Unlock()
+			stale = append(stale, miner)
+		}
+	}
+	for _, miner := range stale {
+		log.Info("Dropping connection to stale miner", "coinbase", miner.ann.Coinbase, "node", miner.node)
+		m.server.RemovePeer(miner.node)
+	}
+}
+
+// snapshot returns a copy of the known miners.
+func (m *Manager) snapshot() map[discover.NodeID]*miner {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	miners := make(map[discover.NodeID]*miner, len(m.miners))
+	for id, miner := range m.miners {
+		miners[id] = miner
+	}
+	return miners
+}
+
+// localAnnouncement creates a signed announcement of the local node if it's
+// mining.
+func (m *Manager) localAnnouncement() *Announcement {
+	if !m.mining() {
+		return nil
+	}
+	coinbase, err := m.coinbase()
+	if err != nil {
+		return nil
+	}
+	m.lock.Lock()
+	if m.server == nil {
+		m.lock.Unlock()
+		return nil
+	}
+	ann := &Announcement{
+		Coinbase: coinbase,
+		Node:     m.server.Self().String(),
+		Time:     uint64(time.Now().Unix()),
+	}
+	m.lock.Unlock()
+
+	if ann.Sig, err = m.sign(accounts.Account{Address: coinbase}, ann.sigHash()); err != nil {
+		log.Warn("Failed to sign miner announcement", "coinbase", coinbase, "err", err)
+		return nil
+	}
+	return ann
+}
+
+// send delivers the announcements to each of the peers.
+func send(peers []p2p.MsgReadWriter, anns ...*Announcement) {
+	for _, rw := range peers {
+		for _, ann := range anns {
+			if err := p2p.Send(rw, announceMsg, ann); err != nil {
+				break
+			}
+		}
+	}
+}
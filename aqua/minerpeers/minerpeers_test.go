@@ -0,0 +1,257 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package minerpeers
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/p2p"
+	"github.com/aquanetwork/aquachain/p2p/discover"
+)
+
+var (
+	activeKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	inactiveKey, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+	activeMiner    = crypto.PubkeyToAddress(activeKey.PublicKey)
+	inactiveMiner  = crypto.PubkeyToAddress(inactiveKey.PublicKey)
+)
+
+// announce creates an announcement of the node signed by the key.
+func announce(key *ecdsa.PrivateKey, node *discover.Node, time uint64) *Announcement {
+	ann := &Announcement{Coinbase: crypto.PubkeyToAddress(key.PublicKey), Node: node.String(), Time: time}
+	ann.Sig, _ = crypto.Sign(ann.sigHash(), key)
+	return ann
+}
+
+// testChain is a chain of headers all mined by activeMiner.
+type testChain struct {
+	headers []*types.Header
+}
+
+func newTestChain(n int) *testChain {
+	chain := new(testChain)
+	for i := 0; i < n; i++ {
+		chain.headers = append(chain.headers, &types.Header{
+			Number:   big.NewInt(int64(i)),
+			Coinbase: activeMiner,
+			Version:  types.H_KECCAK256,
+		})
+	}
+	return chain
+}
+
+func (c *testChain) CurrentHeader() *types.Header { return c.headers[len(c.headers)-1] }
+
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(c.headers)) {
+		return c.headers[number]
+	}
+	return nil
+}
+
+// testServer records the peers the manager asks to connect to.
+type testServer struct {
+	self  *discover.Node
+	added map[discover.NodeID]bool
+}
+
+func newTestServer() *testServer {
+	return &testServer{
+		self:  testNode(0),
+		added: make(map[discover.NodeID]bool),
+	}
+}
+
+func (s *testServer) Self() *discover.Node               { return s.self }
+func (s *testServer) AddPeer(node *discover.Node)        { s.added[node.ID] = true }
+func (s *testServer) RemovePeer(node *discover.Node)     { delete(s.added, node.ID) }
+func (s *testServer) connected(node *discover.Node) bool { return s.added[node.ID] }
+
+func testNode(i byte) *discover.Node {
+	var id discover.NodeID
+	id[0] = i + 1
+	return discover.NewNode(id, net.IP{127, 0, 0, 1}, 30303, 30303)
+}
+
+func newTestManager() (*Manager, *testServer) {
+	server := newTestServer()
+	manager := New(newTestChain(16), func() (common.Address, error) { return activeMiner, nil }, func() bool { return false }, nil)
+	manager.server = server
+	return manager, server
+}
+
+func TestProcessAnnouncement(t *testing.T) {
+	manager, server := newTestManager()
+	now := uint64(time.Now().Unix())
+
+	// Announcement of the active coinbase signed by another key
+	forged := announce(inactiveKey, testNode(1), now)
+	forged.Coinbase = activeMiner
+
+	tests := []struct {
+		ann *Announcement
+		err error
+	}{
+		{announce(inactiveKey, testNode(1), now), errInactive},
+		{announce(activeKey, testNode(1), now-uint64(announceLifetime/time.Second)-1), errStale},
+		{announce(activeKey, testNode(2), now), errNotSelf},
+		{&Announcement{Coinbase: activeMiner, Node: testNode(1).String(), Time: now}, errBadSignature},
+		{forged, errBadSignature},
+		{announce(activeKey, testNode(1), now), nil},
+	}
+	for i, tt := range tests {
+		if err := manager.process(tt.ann, testNode(1).ID, nil); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	if !server.connected(testNode(1)) {
+		t.Fatalf("announced miner not connected")
+	}
+	if server.connected(testNode(2)) {
+		t.Fatalf("miner announced by another node connected")
+	}
+	if miners := manager.Miners(); len(miners) != 1 || miners[0].Coinbase != activeMiner {
+		t.Fatalf("known miners mismatch: have %v", miners)
+	}
+}
+
+func TestMaxMiners(t *testing.T) {
+	manager, _ := newTestManager()
+	now := uint64(time.Now().Unix())
+
+	// Every miner mines to its own coinbase, all of them active
+	chain := newTestChain(0)
+	keys := make([]*ecdsa.PrivateKey, maxMiners+1)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		chain.headers = append(chain.headers, &types.Header{
+			Number:   big.NewInt(int64(i)),
+			Coinbase: crypto.PubkeyToAddress(keys[i].PublicKey),
+			Version:  types.H_KECCAK256,
+		})
+	}
+	manager.chain = chain
+
+	for i := 0; i < maxMiners; i++ {
+		node := testNode(byte(i + 1))
+		if err := manager.process(announce(keys[i], node, now), node.ID, nil); err != nil {
+			t.Fatalf("miner %d: failed to process announcement: %v", i, err)
+		}
+	}
+	node := testNode(maxMiners + 1)
+	if err := manager.process(announce(keys[maxMiners], node, now), node.ID, nil); err != errTooManyMiners {
+		t.Fatalf("error mismatch: have %v, want %v", err, errTooManyMiners)
+	}
+}
+
+// Tests that a single coinbase can't take over the miner table by announcing
+// many nodes.
+func TestMaxCoinbaseNodes(t *testing.T) {
+	manager, server := newTestManager()
+	now := uint64(time.Now().Unix())
+
+	for i := 0; i < maxCoinbaseNodes; i++ {
+		node := testNode(byte(i + 1))
+		if err := manager.process(announce(activeKey, node, now), node.ID, nil); err != nil {
+			t.Fatalf("node %d: failed to process announcement: %v", i, err)
+		}
+	}
+	node := testNode(maxCoinbaseNodes + 1)
+	if err := manager.process(announce(activeKey, node, now), node.ID, nil); err != errTooManyNodes {
+		t.Fatalf("error mismatch: have %v, want %v", err, errTooManyNodes)
+	}
+	if server.connected(node) {
+		t.Fatalf("node over the coinbase limit connected")
+	}
+	// Refreshing the announcement of a known node is still possible
+	if err := manager.process(announce(activeKey, testNode(1), now+1), testNode(1).ID, nil); err != nil {
+		t.Fatalf("failed to refresh announcement: %v", err)
+	}
+}
+
+// Tests that the local announcement is signed by the coinbase, and withheld if
+// it can't be signed.
+func TestLocalAnnouncement(t *testing.T) {
+	manager, server := newTestManager()
+	manager.mining = func() bool { return true }
+	manager.sign = func(account accounts.Account, hash []byte) ([]byte, error) {
+		if account.Address != activeMiner {
+			return nil, accounts.ErrUnknownAccount
+		}
+		return crypto.Sign(hash, activeKey)
+	}
+	ann := manager.localAnnouncement()
+	if ann == nil {
+		t.Fatalf("no local announcement")
+	}
+	if ann.Node != server.self.String() {
+		t.Fatalf("announced node mismatch: have %s, want %s", ann.Node, server.self)
+	}
+	pubkey, err := crypto.SigToPub(ann.sigHash(), ann.Sig)
+	if err != nil || crypto.PubkeyToAddress(*pubkey) != activeMiner {
+		t.Fatalf("local announcement not signed by coinbase: %v", err)
+	}
+	manager.coinbase = func() (common.Address, error) { return inactiveMiner, nil }
+	if ann := manager.localAnnouncement(); ann != nil {
+		t.Fatalf("unsigned local announcement made: %+v", ann)
+	}
+}
+
+// Tests that announcements are never relayed to other peers, as they can't be
+// authenticated once they leave the announcing node.
+func TestNoRelayAnnouncement(t *testing.T) {
+	manager, _ := newTestManager()
+
+	local, remote := p2p.MsgPipe()
+	defer local.Close()
+	manager.peers[testNode(3).ID] = local
+
+	ann := announce(activeKey, testNode(1), uint64(time.Now().Unix()))
+	if err := manager.process(ann, testNode(1).ID, nil); err != nil {
+		t.Fatalf("failed to process announcement: %v", err)
+	}
+	local.Close()
+	if msg, err := remote.ReadMsg(); err == nil {
+		t.Fatalf("announcement relayed: %v", msg)
+	}
+}
+
+func TestExpireMiners(t *testing.T) {
+	manager, server := newTestManager()
+
+	ann := announce(activeKey, testNode(1), uint64(time.Now().Unix()))
+	if err := manager.process(ann, testNode(1).ID, nil); err != nil {
+		t.Fatalf("failed to process announcement: %v", err)
+	}
+	ann.Time -= uint64(announceLifetime/time.Second) + 1
+	manager.expire()
+
+	if server.connected(testNode(1)) {
+		t.Fatalf("stale miner still connected")
+	}
+	if miners := manager.Miners(); len(miners) != 0 {
+		t.Fatalf("stale miner still known: %v", miners)
+	}
+}
@@ -24,7 +24,9 @@ import (
 	"time"
 
 	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/forkid"
 	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/miner"
 	"github.com/aquanetwork/aquachain/p2p"
 	"github.com/aquanetwork/aquachain/rlp"
 	"gopkg.in/fatih/set.v0"
@@ -48,6 +50,7 @@ type PeerInfo struct {
 	Version    int      `json:"version"`    // AquaChain protocol version negotiated
 	Difficulty *big.Int `json:"difficulty"` // Total difficulty of the peer's blockchain
 	Head       string   `json:"head"`       // SHA3 hash of the peer's best owned block
+	Score      float64  `json:"score"`      // Misbehaviour score, banned at 100
 }
 
 type peer struct {
@@ -59,9 +62,14 @@ type peer struct {
 	version  int         // Protocol version negotiated
 	forkDrop *time.Timer // Timed connection dropper if forks aren't validated in time
 
-	head common.Hash
-	td   *big.Int
-	lock sync.RWMutex
+	head   common.Hash
+	td     *big.Int
+	header *types.Header // Header of the head, once fetched to verify the advertised chain
+	query  common.Hash   // Head hash whose header was requested, zero if none pending
+	forkID *forkid.ID    // Fork identifier announced in the handshake, nil before aqua/66
+	lock   sync.RWMutex
+
+	pending map[uint64]int // Requests sent to the peer and not answered yet, by response code
 
 	knownTxs    *set.Set // Set of transaction hashes known to be known by this peer
 	knownBlocks *set.Set // Set of block hashes known to be known by this peer
@@ -77,6 +85,7 @@ func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 		id:          fmt.Sprintf("%x", id[:8]),
 		knownTxs:    set.New(),
 		knownBlocks: set.New(),
+		pending:     make(map[uint64]int),
 	}
 }
 
@@ -110,6 +119,68 @@ func (p *peer) SetHead(hash common.Hash, td *big.Int) {
 	p.td.Set(td)
 }
 
+// HeadHeader retrieves the header of the current head of the peer, along with
+// its total difficulty, or nil if it wasn't fetched yet.
+func (p *peer) HeadHeader() (*types.Header, *big.Int) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.header == nil || p.header.Hash() != p.head {
+		return nil, nil
+	}
+	return p.header, new(big.Int).Set(p.td)
+}
+
+// RequestHeadHeader requests the header of the current head of the peer, unless
+// it was already fetched or requested.
+func (p *peer) RequestHeadHeader() error {
+	p.lock.Lock()
+	if (p.header != nil && p.header.Hash() == p.head) || p.query == p.head {
+		p.lock.Unlock()
+		return nil
+	}
+	p.query = p.head
+	p.lock.Unlock()
+
+	return p.RequestHeadersByHash(p.query, 1, 0, false)
+}
+
+// DeliverHeadHeader stores the header answering a RequestHeadHeader, reporting
+// whether it was one.
+func (p *peer) DeliverHeadHeader(header *types.Header) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.query == (common.Hash{}) || header.Hash() != p.query {
+		return false
+	}
+	p.header, p.query = header, common.Hash{}
+	return true
+}
+
+// request records a request sent to the peer, to be answered by a message of
+// the given code.
+func (p *peer) request(code uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.pending[code]++
+}
+
+// Answered consumes a pending request answered by a message of the given code,
+// reporting whether there was one. Late answers to requests the downloader gave
+// up on still count as solicited, only answers to no request at all don't.
+func (p *peer) Answered(code uint64) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.pending[code] == 0 {
+		return false
+	}
+	p.pending[code]--
+	return true
+}
+
 // MarkBlock marks a block as known for the peer, ensuring that the block will
 // never be propagated to this particular peer.
 func (p *peer) MarkBlock(hash common.Hash) {
@@ -139,6 +210,37 @@ func (p *peer) SendTransactions(txs types.Transactions) error {
 	return p2p.Send(p.rw, TxMsg, txs)
 }
 
+// SendPooledTransactionHashes announces the availability of a number of
+// transactions through a hash notification, leaving it up to the peer to
+// retrieve the ones it doesn't know about.
+func (p *peer) SendPooledTransactionHashes(hashes []common.Hash) error {
+	for _, hash := range hashes {
+		p.knownTxs.Add(hash)
+	}
+	return p2p.Send(p.rw, NewPooledTransactionHashesMsg, hashes)
+}
+
+// SendPooledTransactions sends the transactions requested by the peer.
+func (p *peer) SendPooledTransactions(txs types.Transactions) error {
+	for _, tx := range txs {
+		p.knownTxs.Add(tx.Hash())
+	}
+	return p2p.Send(p.rw, PooledTransactionsMsg, txs)
+}
+
+// syncTransactions sends a pack of the pending transactions to a newly connected
+// peer, only announcing them by hash if it speaks aqua/67.
+func (p *peer) syncTransactions(txs types.Transactions) error {
+	if p.version < aqua67 {
+		return p.SendTransactions(txs)
+	}
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	return p.SendPooledTransactionHashes(hashes)
+}
+
 // SendNewBlockHashes announces the availability of a number of blocks through
 // a hash notification.
 func (p *peer) SendNewBlockHashes(hashes []common.Hash, numbers []uint64) error {
@@ -170,9 +272,11 @@ func (p *peer) SendBlockBodies(bodies []*blockBody) error {
 }
 
 // SendBlockBodiesRLP sends a batch of block contents to the remote peer from
-// an already RLP encoded format.
+// an already RLP encoded format. The bodies are streamed in place, not copied
+// into the message.
 func (p *peer) SendBlockBodiesRLP(bodies []rlp.RawValue) error {
-	return p2p.Send(p.rw, BlockBodiesMsg, bodies)
+	size, r := rlp.RawListReader(bodies)
+	return p.rw.WriteMsg(p2p.Msg{Code: BlockBodiesMsg, Size: uint32(size), Payload: r})
 }
 
 // SendNodeDataRLP sends a batch of arbitrary internal data, corresponding to the
@@ -191,6 +295,7 @@ func (p *peer) SendReceiptsRLP(receipts []rlp.RawValue) error {
 // single header. It is used solely by the fetcher.
 func (p *peer) RequestOneHeader(hash common.Hash) error {
 	p.Log().Debug("Fetching single header", "hash", hash)
+	p.request(BlockHeadersMsg)
 	return p2p.Send(p.rw, GetBlockHeadersMsg, &getBlockHeadersData{Origin: hashOrNumber{Hash: hash}, Amount: uint64(1), Skip: uint64(0), Reverse: false})
 }
 
@@ -198,6 +303,7 @@ func (p *peer) RequestOneHeader(hash common.Hash) error {
 // specified header query, based on the hash of an origin block.
 func (p *peer) RequestHeadersByHash(origin common.Hash, amount int, skip int, reverse bool) error {
 	p.Log().Debug("Fetching batch of headers", "count", amount, "fromhash", origin, "skip", skip, "reverse", reverse)
+	p.request(BlockHeadersMsg)
 	return p2p.Send(p.rw, GetBlockHeadersMsg, &getBlockHeadersData{Origin: hashOrNumber{Hash: origin}, Amount: uint64(amount), Skip: uint64(skip), Reverse: reverse})
 }
 
@@ -205,6 +311,7 @@ func (p *peer) RequestHeadersByHash(origin common.Hash, amount int, skip int, re
 // specified header query, based on the number of an origin block.
 func (p *peer) RequestHeadersByNumber(origin uint64, amount int, skip int, reverse bool) error {
 	p.Log().Debug("Fetching batch of headers", "count", amount, "fromnum", origin, "skip", skip, "reverse", reverse)
+	p.request(BlockHeadersMsg)
 	return p2p.Send(p.rw, GetBlockHeadersMsg, &getBlockHeadersData{Origin: hashOrNumber{Number: origin}, Amount: uint64(amount), Skip: uint64(skip), Reverse: reverse})
 }
 
@@ -212,6 +319,7 @@ func (p *peer) RequestHeadersByNumber(origin uint64, amount int, skip int, rever
 // specified.
 func (p *peer) RequestBodies(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of block bodies", "count", len(hashes))
+	p.request(BlockBodiesMsg)
 	return p2p.Send(p.rw, GetBlockBodiesMsg, hashes)
 }
 
@@ -219,33 +327,45 @@ func (p *peer) RequestBodies(hashes []common.Hash) error {
 // data, corresponding to the specified hashes.
 func (p *peer) RequestNodeData(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of state data", "count", len(hashes))
+	p.request(NodeDataMsg)
 	return p2p.Send(p.rw, GetNodeDataMsg, hashes)
 }
 
 // RequestReceipts fetches a batch of transaction receipts from a remote node.
 func (p *peer) RequestReceipts(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of receipts", "count", len(hashes))
+	p.request(ReceiptsMsg)
 	return p2p.Send(p.rw, GetReceiptsMsg, hashes)
 }
 
+// RequestTxs fetches a batch of transactions announced by the peer.
+func (p *peer) RequestTxs(hashes []common.Hash) error {
+	p.Log().Trace("Fetching batch of transactions", "count", len(hashes))
+	return p2p.Send(p.rw, GetPooledTransactionsMsg, hashes)
+}
+
 // Handshake executes the aqua protocol handshake, negotiating version number,
 // network IDs, difficulties, head and genesis blocks.
-func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash) error {
+func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID, forkFilter forkid.Filter) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
 	var status statusData // safe to read after two values have been received from errc
 
 	go func() {
-		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
+		status := &statusData{
 			ProtocolVersion: uint32(p.version),
 			NetworkId:       network,
 			TD:              td,
 			CurrentBlock:    head,
 			GenesisBlock:    genesis,
-		})
+		}
+		if p.version >= aqua66 {
+			status.ForkID = &forkID
+		}
+		errc <- p2p.Send(p.rw, StatusMsg, status)
 	}()
 	go func() {
-		errc <- p.readStatus(network, &status, genesis)
+		errc <- p.readStatus(network, &status, genesis, forkFilter)
 	}()
 	timeout := time.NewTimer(handshakeTimeout)
 	defer timeout.Stop()
@@ -259,11 +379,11 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 			return p2p.DiscReadTimeout
 		}
 	}
-	p.td, p.head = status.TD, status.CurrentBlock
+	p.td, p.head, p.forkID = status.TD, status.CurrentBlock, status.ForkID
 	return nil
 }
 
-func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash) (err error) {
+func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash, forkFilter forkid.Filter) (err error) {
 	msg, err := p.rw.ReadMsg()
 	if err != nil {
 		return err
@@ -287,6 +407,16 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 	if int(status.ProtocolVersion) != p.version {
 		return errResp(ErrProtocolVersionMismatch, "%d (!= %d)", status.ProtocolVersion, p.version)
 	}
+	if p.version >= aqua66 {
+		if status.ForkID == nil {
+			return errResp(ErrForkIDRejected, "missing")
+		}
+		if err := forkFilter(*status.ForkID); err != nil {
+			return errResp(ErrForkIDRejected, "%v", err)
+		}
+	} else {
+		status.ForkID = nil
+	}
 	return nil
 }
 
@@ -404,6 +534,40 @@ func (ps *peerSet) BestPeer() *peer {
 	return bestPeer
 }
 
+// HeadHeaders retrieves the head headers and total difficulties of the known
+// peers, requesting the headers not fetched yet in the background. Peers whose
+// head header isn't known are left out.
+func (ps *peerSet) HeadHeaders() []miner.PeerHead {
+	peers := ps.Peers()
+
+	heads := make([]miner.PeerHead, 0, len(peers))
+	for _, p := range peers {
+		header, td := p.HeadHeader()
+		if header == nil {
+			go func(p *peer) {
+				if err := p.RequestHeadHeader(); err != nil {
+					p.Log().Debug("Failed to request head header", "err", err)
+				}
+			}(p)
+			continue
+		}
+		heads = append(heads, miner.PeerHead{Header: header, Td: td})
+	}
+	return heads
+}
+
+// Peers retrieves all the known peers.
+func (ps *peerSet) Peers() []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		list = append(list, p)
+	}
+	return list
+}
+
 // Close disconnects all peers.
 // No new peers can be registered after Close has returned.
 func (ps *peerSet) Close() {
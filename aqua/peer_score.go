@@ -0,0 +1,243 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/p2p/discover"
+)
+
+// peerBansFile is the file in the data directory the peer bans are persisted to.
+const peerBansFile = "peerbans.json"
+
+const (
+	scoreHalfLife = 10 * time.Minute // Time for misbehaviour scores to decay by half
+	banThreshold  = 100              // Score at which a peer is banned
+
+	banDuration    = time.Hour          // Duration of the first ban of a peer
+	maxBanDuration = 7 * 24 * time.Hour // Limit of the doubling ban durations of repeat offenders
+
+	requestRate  = 50  // Requests per second a peer may sustain before being throttled
+	requestBurst = 200 // Requests a peer may send in a burst before being throttled
+
+	staleAnnounceDist = 16 // Blocks below our head announcements are considered stale from
+)
+
+// Penalties of the peer misbehaviours.
+const (
+	penaltyOversized   = banThreshold // Message above the protocol size limit
+	penaltyInvalid     = banThreshold // Propagated block or header failing verification
+	penaltySyncFailure = 25           // Invalid chain served during a sync, timeouts aren't scored
+	penaltyUseless     = 5            // Response to no request sent to the peer
+	penaltyStale       = 2            // Announcement of a block far below our head
+	penaltyThrottled   = 1            // Request above the sustainable rate
+)
+
+// peerScore is the misbehaviour score and request allowance of a peer.
+type peerScore struct {
+	node      discover.NodeID
+	trusted   bool // Trusted peers are never banned
+	connected bool
+
+	score   float64
+	updated time.Time // Time the score was last decayed
+
+	tokens float64   // Requests allowed before throttling
+	filled time.Time // Time the tokens were last refilled
+}
+
+// peerBan is a ban of a peer in the persisted ban list.
+type peerBan struct {
+	Until     time.Time `json:"until"`
+	Offences  int       `json:"offences"`
+	LastCause string    `json:"cause"`
+}
+
+// peerScorer tracks the misbehaviour of the peers, throttles their requests and
+// bans those whose score reaches the ban threshold. Repeat offenders are banned
+// for increasingly long, until they behave for long enough to be forgotten.
+type peerScorer struct {
+	path string // File the bans are persisted to, empty if not persisted
+	now  func() time.Time
+
+	lock  sync.Mutex
+	peers map[string]*peerScore        // Scores of the peers, by peer id
+	bans  map[discover.NodeID]*peerBan // Current and recent bans, by node id
+}
+
+// newPeerScorer creates a peer scorer, loading the bans persisted to the given
+// file if any.
+func newPeerScorer(path string) *peerScorer {
+	s := &peerScorer{
+		path:  path,
+		now:   time.Now,
+		peers: make(map[string]*peerScore),
+		bans:  make(map[discover.NodeID]*peerBan),
+	}
+	if path == "" {
+		return s
+	}
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("Failed to load peer bans", "path", path, "err", err)
+		}
+		return s
+	}
+	if err := json.Unmarshal(blob, &s.bans); err != nil {
+		log.Warn("Failed to decode peer bans", "path", path, "err", err)
+	}
+	return s
+}
+
+// connect starts tracking the score of a peer, reporting whether it's banned.
+func (s *peerScorer) connect(id string, node discover.NodeID, trusted bool) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	if ban := s.bans[node]; ban != nil && now.Before(ban.Until) && !trusted {
+		return true
+	}
+	// Drop the scores of disconnected peers decayed enough to be irrelevant,
+	// keeping the rest to carry over reconnects
+	for pid, peer := range s.peers {
+		if !peer.connected && peer.decay(now) < 1 {
+			delete(s.peers, pid)
+		}
+	}
+	if peer := s.peers[id]; peer != nil {
+		peer.trusted, peer.connected = trusted, true
+		return false
+	}
+	s.peers[id] = &peerScore{node: node, trusted: trusted, connected: true, updated: now, tokens: requestBurst, filled: now}
+	return false
+}
+
+// disconnect marks a peer disconnected, its score is kept until decayed.
+func (s *peerScorer) disconnect(id string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if peer := s.peers[id]; peer != nil {
+		peer.connected = false
+	}
+}
+
+// decay applies the decay of the score since its last update.
+func (p *peerScore) decay(now time.Time) float64 {
+	p.score *= math.Pow(0.5, float64(now.Sub(p.updated))/float64(scoreHalfLife))
+	p.updated = now
+	return p.score
+}
+
+// score returns the current misbehaviour score of a peer.
+func (s *peerScorer) score(id string) float64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if peer := s.peers[id]; peer != nil {
+		return peer.decay(s.now())
+	}
+	return 0
+}
+
+// penalize adds a misbehaviour penalty to the score of a peer, reporting whether
+// the peer got banned by it.
+func (s *peerScorer) penalize(id string, penalty float64, cause string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	peer := s.peers[id]
+	if peer == nil {
+		return false
+	}
+	now := s.now()
+	if peer.decay(now)+penalty < banThreshold || peer.trusted {
+		peer.score += penalty
+		return false
+	}
+	peer.score = 0
+
+	// Ban the peer, doubling the duration of each repeated ban. Offences are
+	// forgotten once a peer behaved for the longest ban duration.
+	ban := s.bans[peer.node]
+	if ban == nil || now.Sub(ban.Until) > maxBanDuration {
+		ban = new(peerBan)
+		s.bans[peer.node] = ban
+	}
+	ban.Offences++
+	ban.LastCause = cause
+
+	duration := maxBanDuration
+	if ban.Offences < 16 && banDuration<<uint(ban.Offences-1) < maxBanDuration {
+		duration = banDuration << uint(ban.Offences-1)
+	}
+	ban.Until = now.Add(duration)
+	log.Info("Banned misbehaving peer", "peer", id, "cause", cause, "offences", ban.Offences, "duration", duration)
+
+	s.save(now)
+	return true
+}
+
+// throttle takes a request from the allowance of a peer, returning the time the
+// peer has to wait for it if the allowance is exhausted.
+func (s *peerScorer) throttle(id string) time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	peer := s.peers[id]
+	if peer == nil {
+		return 0
+	}
+	now := s.now()
+	peer.tokens = math.Min(requestBurst, peer.tokens+now.Sub(peer.filled).Seconds()*requestRate)
+	peer.filled = now
+
+	peer.tokens--
+	if peer.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-peer.tokens / requestRate * float64(time.Second))
+}
+
+// save persists the bans, dropping the ones forgotten already. It's called with
+// the lock held.
+func (s *peerScorer) save(now time.Time) {
+	for node, ban := range s.bans {
+		if now.Sub(ban.Until) > maxBanDuration {
+			delete(s.bans, node)
+		}
+	}
+	if s.path == "" {
+		return
+	}
+	blob, err := json.MarshalIndent(s.bans, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(s.path, blob, 0644)
+	}
+	if err != nil {
+		log.Warn("Failed to persist peer bans", "path", s.path, "err", err)
+	}
+}
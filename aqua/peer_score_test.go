@@ -0,0 +1,155 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aqua/downloader"
+	"github.com/aquanetwork/aquachain/p2p"
+	"github.com/aquanetwork/aquachain/p2p/discover"
+)
+
+// newTestScorer creates a peer scorer running on a manually advanced clock.
+func newTestScorer(path string) (*peerScorer, *time.Time) {
+	now := time.Unix(1500000000, 0)
+	scorer := newPeerScorer(path)
+	scorer.now = func() time.Time { return now }
+	return scorer, &now
+}
+
+func TestPeerScoreDecay(t *testing.T) {
+	scorer, now := newTestScorer("")
+	scorer.connect("peer", discover.NodeID{1}, false)
+
+	for i := 0; i < 3; i++ {
+		if scorer.penalize("peer", penaltySyncFailure, "test") {
+			t.Fatalf("peer banned below the threshold")
+		}
+	}
+	if score := scorer.score("peer"); score != 3*penaltySyncFailure {
+		t.Fatalf("score mismatch: have %v, want %v", score, 3*penaltySyncFailure)
+	}
+	*now = now.Add(scoreHalfLife)
+	if score := scorer.score("peer"); score != 1.5*penaltySyncFailure {
+		t.Fatalf("decayed score mismatch: have %v, want %v", score, 1.5*penaltySyncFailure)
+	}
+	// Decayed scores of disconnected peers are forgotten
+	scorer.disconnect("peer")
+	*now = now.Add(10 * scoreHalfLife)
+	scorer.connect("other", discover.NodeID{2}, false)
+	if _, ok := scorer.peers["peer"]; ok {
+		t.Fatalf("decayed score not forgotten")
+	}
+}
+
+func TestPeerBans(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peer-bans-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, peerBansFile)
+
+	scorer, now := newTestScorer(path)
+	node := discover.NodeID{1}
+	scorer.connect("peer", node, false)
+
+	if !scorer.penalize("peer", penaltyOversized, "test") {
+		t.Fatalf("peer not banned at the threshold")
+	}
+	if !scorer.connect("peer", node, false) {
+		t.Fatalf("banned peer accepted")
+	}
+	if scorer.connect("peer", node, true) {
+		t.Fatalf("banned trusted peer rejected")
+	}
+	if scorer.penalize("peer", penaltyOversized, "test") {
+		t.Fatalf("trusted peer banned")
+	}
+	// Bans are persisted, repeated ones lasting twice as long
+	*now = now.Add(banDuration)
+	scorer, _ = newTestScorer(path)
+	scorer.now = func() time.Time { return *now }
+	if scorer.connect("peer", node, false) {
+		t.Fatalf("peer still banned after the ban duration")
+	}
+	scorer.penalize("peer", penaltyOversized, "test")
+	if ban := scorer.bans[node]; ban.Offences != 2 || ban.Until.Sub(*now) != 2*banDuration {
+		t.Fatalf("repeated ban mismatch: offences %d, duration %v", ban.Offences, ban.Until.Sub(*now))
+	}
+	// Offences are forgotten after behaving long enough
+	*now = now.Add(2*banDuration + maxBanDuration + time.Second)
+	scorer.connect("peer", node, false)
+	scorer.penalize("peer", penaltyOversized, "test")
+	if ban := scorer.bans[node]; ban.Offences != 1 || ban.Until.Sub(*now) != banDuration {
+		t.Fatalf("ban after forgiveness mismatch: offences %d, duration %v", ban.Offences, ban.Until.Sub(*now))
+	}
+}
+
+func TestPeerThrottle(t *testing.T) {
+	scorer, now := newTestScorer("")
+	scorer.connect("peer", discover.NodeID{1}, false)
+
+	for i := 0; i < requestBurst; i++ {
+		if wait := scorer.throttle("peer"); wait != 0 {
+			t.Fatalf("request %d throttled within the burst: %v", i, wait)
+		}
+	}
+	if wait := scorer.throttle("peer"); wait != time.Second/requestRate {
+		t.Fatalf("throttle mismatch: have %v, want %v", wait, time.Second/requestRate)
+	}
+	// The allowance refills at the sustainable rate
+	*now = now.Add(time.Second)
+	for i := 0; i < requestRate-1; i++ {
+		if wait := scorer.throttle("peer"); wait != 0 {
+			t.Fatalf("request %d throttled after refilling: %v", i, wait)
+		}
+	}
+	if wait := scorer.throttle("peer"); wait == 0 {
+		t.Fatalf("request not throttled after the refilled allowance")
+	}
+}
+
+// Tests that peers sending oversized messages are disconnected and banned.
+func TestOversizedMessageBan(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	peer, errc := newTestPeer("peer", aqua64, pm, true)
+	defer peer.close()
+
+	size := ProtocolMaxMsgSize + 1
+	go peer.app.WriteMsg(p2p.Msg{Code: TxMsg, Size: uint32(size), Payload: bytes.NewReader(make([]byte, size))})
+
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Fatalf("peer not disconnected")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("peer not disconnected in time")
+	}
+	if !pm.scores.connect(peer.peer.id, peer.peer.ID(), false) {
+		t.Fatalf("peer not banned")
+	}
+}
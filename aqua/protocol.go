@@ -23,6 +23,7 @@ import (
 
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/forkid"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/event"
 	"github.com/aquanetwork/aquachain/rlp"
@@ -32,6 +33,8 @@ import (
 const (
 	aqua64 = 64
 	aqua65 = 65
+	aqua66 = 66
+	aqua67 = 67
 	//eth62  = 62
 	//aqua64  = 63
 )
@@ -40,10 +43,10 @@ const (
 var ProtocolName = "aqua"
 
 // Supported versions of the aqua protocol (first is primary).
-var ProtocolVersions = []uint{aqua64, aqua65}
+var ProtocolVersions = []uint{aqua64, aqua65, aqua66, aqua67}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{17, 17}
+var ProtocolLengths = []uint64{17, 17, 17, 17}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -59,6 +62,11 @@ const (
 	BlockBodiesMsg     = 0x06
 	NewBlockMsg        = 0x07
 
+	// Protocol messages belonging to aqua/67
+	NewPooledTransactionHashesMsg = 0x08
+	GetPooledTransactionsMsg      = 0x09
+	PooledTransactionsMsg         = 0x0a
+
 	// Protocol messages belonging to aqua/63
 	GetNodeDataMsg = 0x0d
 	NodeDataMsg    = 0x0e
@@ -78,6 +86,7 @@ const (
 	ErrNoStatusMsg
 	ErrExtraStatusMsg
 	ErrSuspendedPeer
+	ErrForkIDRejected
 )
 
 func (e errCode) String() string {
@@ -95,12 +104,20 @@ var errorToString = map[int]string{
 	ErrNoStatusMsg:             "No status message",
 	ErrExtraStatusMsg:          "Extra status message",
 	ErrSuspendedPeer:           "Suspended peer",
+	ErrForkIDRejected:          "Fork ID rejected",
 }
 
 type txPool interface {
 	// AddRemotes should add the given transactions to the pool.
 	AddRemotes([]*types.Transaction) []error
 
+	// AddRemotesFrom should add the given transactions relayed by a peer to the
+	// pool.
+	AddRemotesFrom(peer string, txs []*types.Transaction) []error
+
+	// Get should return the transaction of the given hash, if in the pool.
+	Get(hash common.Hash) *types.Transaction
+
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending() (map[common.Address]types.Transactions, error)
@@ -117,6 +134,7 @@ type statusData struct {
 	TD              *big.Int
 	CurrentBlock    common.Hash
 	GenesisBlock    common.Hash
+	ForkID          *forkid.ID `rlp:"optional"` // Fork identifier, announced from aqua/66 on
 }
 
 // newBlockHashesData is the network packet for the block announcements.
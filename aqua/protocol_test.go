@@ -24,6 +24,7 @@ import (
 
 	"github.com/aquanetwork/aquachain/aqua/downloader"
 	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/forkid"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/crypto"
 	"github.com/aquanetwork/aquachain/p2p"
@@ -39,6 +40,7 @@ var testAccount, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6
 // Tests that handshake failures are detected and reported correctly.
 func TestStatusMsgErrors62(t *testing.T) { testStatusMsgErrors(t, 62) }
 func TestStatusMsgErrors63(t *testing.T) { testStatusMsgErrors(t, 63) }
+func TestStatusMsgErrors66(t *testing.T) { testStatusMsgErrors(t, 66) }
 
 func testStatusMsgErrors(t *testing.T, protocol int) {
 	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
@@ -59,18 +61,34 @@ func testStatusMsgErrors(t *testing.T, protocol int) {
 			wantError: errResp(ErrNoStatusMsg, "first msg has code 2 (!= 0)"),
 		},
 		{
-			code: StatusMsg, data: statusData{10, DefaultConfig.NetworkId, td, head.Hash(), genesis.Hash()},
+			code: StatusMsg, data: statusData{10, DefaultConfig.NetworkId, td, head.Hash(), genesis.Hash(), nil},
 			wantError: errResp(ErrProtocolVersionMismatch, "10 (!= %d)", protocol),
 		},
 		{
-			code: StatusMsg, data: statusData{uint32(protocol), 999, td, head.Hash(), genesis.Hash()},
+			code: StatusMsg, data: statusData{uint32(protocol), 999, td, head.Hash(), genesis.Hash(), nil},
 			wantError: errResp(ErrNetworkIdMismatch, "999 (!= 61717561)"),
 		},
 		{
-			code: StatusMsg, data: statusData{uint32(protocol), DefaultConfig.NetworkId, td, head.Hash(), common.Hash{3}},
+			code: StatusMsg, data: statusData{uint32(protocol), DefaultConfig.NetworkId, td, head.Hash(), common.Hash{3}, nil},
 			wantError: errResp(ErrGenesisBlockMismatch, "0300000000000000 (!= %x)", genesis.Hash().Bytes()[:8]),
 		},
 	}
+	if protocol >= aqua66 {
+		tests = append(tests, []struct {
+			code      uint64
+			data      interface{}
+			wantError error
+		}{
+			{
+				code: StatusMsg, data: statusData{uint32(protocol), DefaultConfig.NetworkId, td, head.Hash(), genesis.Hash(), nil},
+				wantError: errResp(ErrForkIDRejected, "missing"),
+			},
+			{
+				code: StatusMsg, data: statusData{uint32(protocol), DefaultConfig.NetworkId, td, head.Hash(), genesis.Hash(), &forkid.ID{Hash: [4]byte{1, 2, 3, 4}}},
+				wantError: errResp(ErrForkIDRejected, "%v", forkid.ErrLocalIncompatibleOrStale),
+			},
+		}...)
+	}
 
 	for i, test := range tests {
 		p, errc := newTestPeer("peer", protocol, pm, false)
@@ -123,6 +141,7 @@ func testRecvTransactions(t *testing.T, protocol int) {
 // This test checks that pending transactions are sent.
 func TestSendTransactions62(t *testing.T) { testSendTransactions(t, 62) }
 func TestSendTransactions63(t *testing.T) { testSendTransactions(t, 63) }
+func TestSendTransactions67(t *testing.T) { testSendTransactions(t, 67) }
 
 func testSendTransactions(t *testing.T, protocol int) {
 	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
@@ -146,18 +165,31 @@ func testSendTransactions(t *testing.T, protocol int) {
 			seen[tx.Hash()] = false
 		}
 		for n := 0; n < len(alltxs) && !t.Failed(); {
-			var txs []*types.Transaction
+			// Peers speaking aqua/67 only get the hashes announced
+			var hashes []common.Hash
 			msg, err := p.app.ReadMsg()
 			if err != nil {
 				t.Errorf("%v: read error: %v", p.Peer, err)
-			} else if msg.Code != TxMsg {
-				t.Errorf("%v: got code %d, want TxMsg", p.Peer, msg.Code)
-			}
-			if err := msg.Decode(&txs); err != nil {
-				t.Errorf("%v: %v", p.Peer, err)
+			} else if protocol >= aqua67 {
+				if msg.Code != NewPooledTransactionHashesMsg {
+					t.Errorf("%v: got code %d, want NewPooledTransactionHashesMsg", p.Peer, msg.Code)
+				}
+				if err := msg.Decode(&hashes); err != nil {
+					t.Errorf("%v: %v", p.Peer, err)
+				}
+			} else {
+				if msg.Code != TxMsg {
+					t.Errorf("%v: got code %d, want TxMsg", p.Peer, msg.Code)
+				}
+				var txs []*types.Transaction
+				if err := msg.Decode(&txs); err != nil {
+					t.Errorf("%v: %v", p.Peer, err)
+				}
+				for _, tx := range txs {
+					hashes = append(hashes, tx.Hash())
+				}
 			}
-			for _, tx := range txs {
-				hash := tx.Hash()
+			for _, hash := range hashes {
 				seentx, want := seen[hash]
 				if seentx {
 					t.Errorf("%v: got tx more than once: %x", p.Peer, hash)
@@ -178,6 +210,61 @@ func testSendTransactions(t *testing.T, protocol int) {
 	wg.Wait()
 }
 
+// Tests that transactions announced by hash are retrieved from the announcer and
+// added to the local pool.
+func TestRecvAnnouncedTransactions67(t *testing.T) {
+	txAdded := make(chan []*types.Transaction)
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, txAdded)
+	pm.acceptTxs = 1 // mark synced to accept transactions
+	p, _ := newTestPeer("peer", aqua67, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	if err := p2p.Send(p.app, NewPooledTransactionHashesMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("announce error: %v", err)
+	}
+	if err := p2p.ExpectMsg(p.app, GetPooledTransactionsMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("retrieval request mismatch: %v", err)
+	}
+	if err := p2p.Send(p.app, PooledTransactionsMsg, []*types.Transaction{tx}); err != nil {
+		t.Fatalf("delivery error: %v", err)
+	}
+	select {
+	case added := <-txAdded:
+		if len(added) != 1 || added[0].Hash() != tx.Hash() {
+			t.Errorf("added transactions mismatch: have %d, want %x", len(added), tx.Hash())
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("announced transaction not added within 2 seconds")
+	}
+}
+
+// Tests that pooled transactions are served by hash, skipping unknown ones.
+func TestGetPooledTransactions67(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	txs := []*types.Transaction{newTestTransaction(testAccount, 0, 0), newTestTransaction(testAccount, 1, 0)}
+	pm.txpool.AddRemotes(txs)
+
+	p, _ := newTestPeer("peer", aqua67, pm, true)
+	defer p.close()
+
+	// Drain the announcement of the pending transactions
+	if msg, err := p.app.ReadMsg(); err != nil || msg.Code != NewPooledTransactionHashesMsg {
+		t.Fatalf("pending announcement mismatch: code %d, err %v", msg.Code, err)
+	} else {
+		msg.Discard()
+	}
+	if err := p2p.Send(p.app, GetPooledTransactionsMsg, []common.Hash{txs[1].Hash(), {0x01}, txs[0].Hash()}); err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	if err := p2p.ExpectMsg(p.app, PooledTransactionsMsg, []*types.Transaction{txs[1], txs[0]}); err != nil {
+		t.Fatalf("pooled transactions mismatch: %v", err)
+	}
+}
+
 // Tests that the custom union field encoder and decoder works correctly.
 func TestGetBlockHeadersDataEncodeDecode(t *testing.T) {
 	// Create a "random" hash for testing
@@ -0,0 +1,220 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package scheduler runs periodic maintenance tasks within the node, so they
+// can't race with it the way externally scheduled jobs do.
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/log"
+)
+
+// minInterval is the shortest interval a task may be scheduled with.
+const minInterval = time.Minute
+
+var (
+	// ErrUnknownTask is returned if a task is requested which isn't scheduled.
+	ErrUnknownTask = errors.New("unknown task")
+
+	// ErrTaskRunning is returned if a task is triggered while it is still running.
+	ErrTaskRunning = errors.New("task already running")
+
+	errStopped = errors.New("scheduler stopped")
+)
+
+// Schedule configures when a task runs.
+type Schedule struct {
+	Task     string        // Name of the task to run
+	Interval time.Duration // Time between two runs of the task
+	Jitter   time.Duration // Maximum random delay added to every run
+	Path     string        `toml:",omitempty"` // Output location of tasks writing files
+}
+
+// Func is the work of a task, which should return early once quit is closed.
+type Func func(quit <-chan struct{}) error
+
+// Status reports the state of a scheduled task.
+type Status struct {
+	Task         string    `json:"task"`
+	Interval     string    `json:"interval"`
+	Running      bool      `json:"running"`
+	Runs         uint64    `json:"runs"`         // Completed runs of the task
+	Failures     uint64    `json:"failures"`     // Runs that returned an error
+	Skipped      uint64    `json:"skipped"`      // Runs skipped as the previous one was still active
+	LastRun      time.Time `json:"lastRun"`      // Start of the last completed run
+	LastDuration string    `json:"lastDuration"` // Duration of the last completed run
+	LastError    string    `json:"lastError"`    // Error of the last completed run, if any
+	NextRun      time.Time `json:"nextRun"`      // Scheduled start of the next run
+}
+
+// task is a scheduled task along with its run statistics.
+type task struct {
+	schedule Schedule
+	run      Func
+	status   Status
+}
+
+// Scheduler runs tasks periodically, each at most once at a time.
+type Scheduler struct {
+	tasks map[string]*task
+
+	lock sync.Mutex
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a scheduler running the given schedules with the task functions
+// they refer to.
+func New(schedules []Schedule, funcs map[string]Func) (*Scheduler, error) {
+	s := &Scheduler{
+		tasks: make(map[string]*task),
+		quit:  make(chan struct{}),
+	}
+	for _, schedule := range schedules {
+		run, ok := funcs[schedule.Task]
+		if !ok {
+			return nil, fmt.Errorf("%v %q", ErrUnknownTask, schedule.Task)
+		}
+		if _, ok := s.tasks[schedule.Task]; ok {
+			return nil, fmt.Errorf("task %q scheduled twice", schedule.Task)
+		}
+		if schedule.Interval < minInterval {
+			return nil, fmt.Errorf("task %q: interval %v below minimum of %v", schedule.Task, schedule.Interval, minInterval)
+		}
+		if schedule.Jitter < 0 {
+			return nil, fmt.Errorf("task %q: negative jitter", schedule.Task)
+		}
+		s.tasks[schedule.Task] = &task{
+			schedule: schedule,
+			run:      run,
+			status:   Status{Task: schedule.Task, Interval: schedule.Interval.String()},
+		}
+	}
+	return s, nil
+}
+
+// Start launches the periodic execution of all tasks.
+func (s *Scheduler) Start() {
+	for _, t := range s.tasks {
+		s.wg.Add(1)
+		go s.loop(t)
+	}
+}
+
+// Stop terminates the scheduling, waiting for the running tasks to return.
+func (s *Scheduler) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// loop runs a task every interval, delayed by a random jitter.
+func (s *Scheduler) loop(t *task) {
+	defer s.wg.Done()
+
+	for {
+		delay := t.schedule.Interval
+		if t.schedule.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(t.schedule.Jitter)))
+		}
+		s.lock.Lock()
+		t.status.NextRun = time.Now().Add(delay)
+		s.lock.Unlock()
+
+		select {
+		case <-time.After(delay):
+			if err := s.trigger(t); err == ErrTaskRunning {
+				s.lock.Lock()
+				t.status.Skipped++
+				s.lock.Unlock()
+				log.Warn("Skipping scheduled task, previous run still active", "task", t.schedule.Task)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// Trigger runs a task immediately in the background, unless it is running.
+func (s *Scheduler) Trigger(name string) error {
+	t, ok := s.tasks[name]
+	if !ok {
+		return fmt.Errorf("%v %q", ErrUnknownTask, name)
+	}
+	return s.trigger(t)
+}
+
+// trigger starts a run of the task if it isn't already running.
+func (s *Scheduler) trigger(t *task) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	select {
+	case <-s.quit:
+		return errStopped
+	default:
+	}
+	if t.status.Running {
+		return ErrTaskRunning
+	}
+	t.status.Running = true
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		log.Info("Running scheduled task", "task", t.schedule.Task)
+		start := time.Now()
+		err := t.run(s.quit)
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		t.status.Running = false
+		t.status.Runs++
+		t.status.LastRun = start
+		t.status.LastDuration = common.PrettyDuration(time.Since(start)).String()
+		t.status.LastError = ""
+		if err != nil {
+			t.status.Failures++
+			t.status.LastError = err.Error()
+			log.Error("Scheduled task failed", "task", t.schedule.Task, "elapsed", common.PrettyDuration(time.Since(start)), "err", err)
+		} else {
+			log.Info("Scheduled task done", "task", t.schedule.Task, "elapsed", common.PrettyDuration(time.Since(start)))
+		}
+	}()
+	return nil
+}
+
+// Status returns the state of all scheduled tasks, ordered by name.
+func (s *Scheduler) Status() []Status {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	list := make([]Status, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		list = append(list, t.status)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Task < list[j].Task })
+	return list
+}
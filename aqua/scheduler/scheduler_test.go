@@ -0,0 +1,99 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// Tests that invalid schedules are rejected.
+func TestNewValidation(t *testing.T) {
+	funcs := map[string]Func{"noop": func(<-chan struct{}) error { return nil }}
+
+	tests := []struct {
+		schedules []Schedule
+		ok        bool
+	}{
+		{[]Schedule{{Task: "noop", Interval: time.Hour}}, true},
+		{[]Schedule{{Task: "noop", Interval: time.Hour, Jitter: time.Minute}}, true},
+		{[]Schedule{{Task: "missing", Interval: time.Hour}}, false},
+		{[]Schedule{{Task: "noop", Interval: time.Second}}, false},
+		{[]Schedule{{Task: "noop", Interval: time.Hour, Jitter: -time.Minute}}, false},
+		{[]Schedule{{Task: "noop", Interval: time.Hour}, {Task: "noop", Interval: 2 * time.Hour}}, false},
+	}
+	for i, tt := range tests {
+		if _, err := New(tt.schedules, funcs); (err == nil) != tt.ok {
+			t.Errorf("test %d: error mismatch: have %v, want ok %v", i, err, tt.ok)
+		}
+	}
+}
+
+// Tests that a task is never run concurrently with itself, and that the results
+// of its runs are tracked.
+func TestTriggerOverlap(t *testing.T) {
+	var (
+		release = make(chan struct{})
+		fail    = errors.New("failed")
+		calls   int
+	)
+	funcs := map[string]Func{
+		"slow": func(<-chan struct{}) error {
+			calls++
+			<-release
+			if calls == 2 {
+				return fail
+			}
+			return nil
+		},
+	}
+	s, err := New([]Schedule{{Task: "slow", Interval: time.Hour}}, funcs)
+	if err != nil {
+		t.Fatalf("failed to create scheduler: %v", err)
+	}
+	defer s.Stop()
+
+	if err := s.Trigger("missing"); err == nil {
+		t.Fatalf("unknown task triggered")
+	}
+	for run := 1; run <= 2; run++ {
+		if err := s.Trigger("slow"); err != nil {
+			t.Fatalf("run %d: failed to trigger: %v", run, err)
+		}
+		if err := s.Trigger("slow"); err != ErrTaskRunning {
+			t.Fatalf("run %d: overlapping trigger error mismatch: have %v, want %v", run, err, ErrTaskRunning)
+		}
+		release <- struct{}{}
+		waitIdle(t, s)
+	}
+	status := s.Status()[0]
+	if status.Runs != 2 || status.Failures != 1 || status.LastError != fail.Error() {
+		t.Errorf("status mismatch: have runs %d, failures %d, error %q, want 2, 1, %q", status.Runs, status.Failures, status.LastError, fail)
+	}
+}
+
+// waitIdle waits until no task of the scheduler is running.
+func waitIdle(t *testing.T, s *Scheduler) {
+	for i := 0; i < 100; i++ {
+		if !s.Status()[0].Running {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("task still running")
+}
@@ -0,0 +1,521 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package simnet runs networks of in-process aquachain nodes for testing, with
+// the nodes connected in configurable topologies over in-memory pipes, to check
+// how they mine, relay transactions and converge on forks and reorgs.
+package simnet
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aqua"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/consensus/misc"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/node"
+	"github.com/aquanetwork/aquachain/p2p/discover"
+	"github.com/aquanetwork/aquachain/p2p/simulations"
+	"github.com/aquanetwork/aquachain/p2p/simulations/adapters"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// pollInterval is how often the network checks its nodes while waiting on them.
+const pollInterval = 20 * time.Millisecond
+
+var (
+	errNotRunning = errors.New("node not running")
+	errTimeout    = errors.New("timed out")
+)
+
+// Config is the configuration of a test network.
+type Config struct {
+	Nodes    int           // Number of nodes to run
+	Accounts int           // Number of accounts funded in the genesis block
+	Genesis  *core.Genesis // Genesis block of the nodes, DefaultGenesis if nil
+	PowMode  aquahash.Mode // Proof-of-work mode of the nodes, ModeFake for generated blocks
+	Topology Topology      // How the nodes are initially connected, none if nil
+	Balance  *big.Int      // Genesis balance of each account
+	Aqua     *aqua.Config  // Base service configuration of the nodes, aqua.DefaultConfig if nil
+	Timeout  time.Duration // How long to wait on the nodes to connect and converge
+}
+
+// DefaultGenesis returns a genesis block with the hard forks of the test chain
+// configuration, all active from the first blocks on.
+func DefaultGenesis() *core.Genesis {
+	config := *params.TestChainConfig
+	return &core.Genesis{
+		Config:     &config,
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: params.MinimumDifficultyHF5,
+		Alloc:      make(core.GenesisAlloc),
+	}
+}
+
+// Network is a running test network of aquachain nodes.
+type Network struct {
+	config   Config
+	genesis  *core.Genesis
+	net      *simulations.Network
+	ids      []discover.NodeID
+	keys     []*ecdsa.PrivateKey // Node keys, also used as the coinbases of the nodes
+	accounts []*ecdsa.PrivateKey // Accounts funded in the genesis block
+
+	services map[discover.NodeID]*aqua.AquaChain
+	lock     sync.RWMutex
+	quit     chan struct{}
+}
+
+// New starts a network of nodes running the aqua service as configured, and
+// waits for them to connect as the topology describes.
+func New(config Config) (*Network, error) {
+	if config.Genesis == nil {
+		config.Genesis = DefaultGenesis()
+	}
+	if config.Balance == nil {
+		config.Balance = new(big.Int).Mul(big.NewInt(1000000), big.NewInt(params.Aqua))
+	}
+	if config.Timeout == 0 {
+		// Nodes with few peers only sync on the forced cycle, every ten seconds
+		config.Timeout = 30 * time.Second
+	}
+	n := &Network{
+		config:   config,
+		services: make(map[discover.NodeID]*aqua.AquaChain),
+		quit:     make(chan struct{}),
+	}
+	// Fund the accounts on a copy of the genesis, shared by all the nodes
+	genesis := *config.Genesis
+	genesis.Alloc = make(core.GenesisAlloc)
+	for addr, account := range config.Genesis.Alloc {
+		genesis.Alloc[addr] = account
+	}
+	for i := 0; i < config.Accounts; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		n.accounts = append(n.accounts, key)
+		genesis.Alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{Balance: config.Balance}
+	}
+	n.genesis = &genesis
+
+	adapter := adapters.NewSimAdapter(map[string]adapters.ServiceFunc{"aqua": n.newService})
+	n.net = simulations.NewNetwork(adapter, &simulations.NetworkConfig{DefaultService: "aqua"})
+
+	for i := 0; i < config.Nodes; i++ {
+		conf := adapters.RandomNodeConfig()
+		if _, err := n.net.NewNodeWithConfig(conf); err != nil {
+			n.Shutdown()
+			return nil, err
+		}
+		n.ids = append(n.ids, conf.ID)
+		n.keys = append(n.keys, conf.PrivateKey)
+	}
+	if err := n.net.StartAll(); err != nil {
+		n.Shutdown()
+		return nil, err
+	}
+	if config.Topology != nil {
+		for _, edge := range config.Topology(config.Nodes) {
+			if err := n.Connect(edge[0], edge[1]); err != nil {
+				n.Shutdown()
+				return nil, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// newService creates the aqua service of a node, with its own in-memory
+// database and the node key as coinbase.
+func (n *Network) newService(ctx *adapters.ServiceContext) (node.Service, error) {
+	config := aqua.DefaultConfig
+	if n.config.Aqua != nil {
+		config = *n.config.Aqua
+	}
+	config.Genesis = n.genesis
+	config.NetworkId = n.genesis.Config.ChainId.Uint64()
+	config.Aquahash.PowMode = n.config.PowMode
+	config.Aquabase = crypto.PubkeyToAddress(ctx.Config.PrivateKey.PublicKey)
+
+	service, err := aqua.New(ctx.NodeContext, &config)
+	if err != nil {
+		return nil, err
+	}
+	n.lock.Lock()
+	n.services[ctx.Config.ID] = service
+	n.lock.Unlock()
+	return service, nil
+}
+
+// Shutdown stops all the nodes of the network.
+func (n *Network) Shutdown() {
+	select {
+	case <-n.quit:
+	default:
+		close(n.quit)
+	}
+	n.net.Shutdown()
+}
+
+// Len returns the number of nodes in the network.
+func (n *Network) Len() int {
+	return len(n.ids)
+}
+
+// Node returns the aqua service of the node at index, nil if it isn't running.
+func (n *Network) Node(index int) *aqua.AquaChain {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	return n.services[n.ids[index]]
+}
+
+// ID returns the node ID of the node at index.
+func (n *Network) ID(index int) discover.NodeID {
+	return n.ids[index]
+}
+
+// Coinbase returns the address the node at index mines to.
+func (n *Network) Coinbase(index int) common.Address {
+	return crypto.PubkeyToAddress(n.keys[index].PublicKey)
+}
+
+// Genesis returns the genesis block of the network, with the funded accounts.
+func (n *Network) Genesis() *core.Genesis {
+	return n.genesis
+}
+
+// Account returns the key of a genesis funded account.
+func (n *Network) Account(index int) *ecdsa.PrivateKey {
+	return n.accounts[index]
+}
+
+// Head returns the current block of the node at index.
+func (n *Network) Head(index int) *types.Block {
+	return n.Node(index).BlockChain().CurrentBlock()
+}
+
+// Connect connects two nodes and waits until they run the aqua protocol with
+// each other.
+func (n *Network) Connect(one, other int) error {
+	// A dropped connection may not be redialed right away, retry until it is
+	var (
+		deadline = time.Now().Add(n.config.Timeout)
+		err      error
+	)
+	for err = n.net.Connect(n.ids[one], n.ids[other]); err != nil; err = n.net.Connect(n.ids[one], n.ids[other]) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("failed to connect node %d to %d: %v", one, other, err)
+		}
+		time.Sleep(pollInterval)
+	}
+	return n.waitPeers(one, other, true)
+}
+
+// Disconnect disconnects two nodes and waits until they dropped each other.
+func (n *Network) Disconnect(one, other int) error {
+	if err := n.net.Disconnect(n.ids[one], n.ids[other]); err != nil {
+		return err
+	}
+	return n.waitPeers(one, other, false)
+}
+
+// Partition disconnects every node of the group from the nodes outside of it.
+func (n *Network) Partition(group ...int) error {
+	inside := make(map[int]bool)
+	for _, index := range group {
+		inside[index] = true
+	}
+	for one := range n.ids {
+		for other := one + 1; other < len(n.ids); other++ {
+			if inside[one] == inside[other] || !n.isPeer(one, other) {
+				continue
+			}
+			if err := n.Disconnect(one, other); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// waitPeers waits until two nodes are aqua peers of each other, or aren't.
+func (n *Network) waitPeers(one, other int, connected bool) error {
+	deadline := time.Now().Add(n.config.Timeout)
+	for {
+		if n.isPeer(one, other) == connected && n.isPeer(other, one) == connected {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("nodes %d and %d peering (want %v): %v", one, other, connected, errTimeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// isPeer reports whether the node at index completed the aqua handshake with
+// the node at peer.
+func (n *Network) isPeer(index, peer int) bool {
+	server := n.net.GetNode(n.ids[index]).Node.(*adapters.SimNode).Server()
+	if server == nil {
+		return false
+	}
+	for _, info := range server.PeersInfo() {
+		if info.ID != n.ids[peer].String() {
+			continue
+		}
+		// The protocol metadata is a placeholder string until the handshake is done
+		_, handshaking := info.Protocols["aqua"].(string)
+		return info.Protocols["aqua"] != nil && !handshaking
+	}
+	return false
+}
+
+// SendTransaction adds a signed transaction to the pool of the node at index,
+// to be relayed to its peers.
+func (n *Network) SendTransaction(index int, tx *types.Transaction) error {
+	service := n.Node(index)
+	if service == nil {
+		return errNotRunning
+	}
+	return service.TxPool().AddLocal(tx)
+}
+
+// Transfer sends amount from a genesis funded account to the given address,
+// through the node at index, with the next nonce of the account in its pool.
+func (n *Network) Transfer(index, account int, to common.Address, amount *big.Int) (*types.Transaction, error) {
+	service := n.Node(index)
+	if service == nil {
+		return nil, errNotRunning
+	}
+	var (
+		key    = n.accounts[account]
+		nonce  = service.TxPool().State().GetNonce(crypto.PubkeyToAddress(key.PublicKey))
+		signer = types.NewEIP155Signer(n.genesis.Config.ChainId)
+	)
+	tx, err := types.SignTx(types.NewTransaction(nonce, to, amount, params.TxGas, service.TxPool().GasPrice(), nil), signer, key)
+	if err != nil {
+		return nil, err
+	}
+	return tx, service.TxPool().AddLocal(tx)
+}
+
+// Mine forges count blocks on top of the current block of the node at index,
+// interval seconds apart, with the executable transactions of its pool. The
+// blocks are imported and broadcast by the node as if it mined them, each one
+// reaching the nodes connected to the miner before the next is forged. The seals
+// are made by the consensus engine of the node, which is only practical with
+// fake proof-of-work; nodes with real proof-of-work mine with StartMining.
+func (n *Network) Mine(index, count int, interval uint64) ([]*types.Block, error) {
+	service := n.Node(index)
+	if service == nil {
+		return nil, errNotRunning
+	}
+	blocks := make([]*types.Block, 0, count)
+	for i := 0; i < count; i++ {
+		block, err := n.forge(service, n.Coinbase(index), interval)
+		if err != nil {
+			return blocks, err
+		}
+		if _, err := service.BlockChain().InsertChain(types.Blocks{block}); err != nil {
+			return blocks, err
+		}
+		service.EventMux().Post(core.NewMinedBlockEvent{Block: block})
+		blocks = append(blocks, block)
+
+		if err := n.waitBlock(index, block); err != nil {
+			return blocks, err
+		}
+	}
+	return blocks, nil
+}
+
+// waitBlock waits until all the nodes reachable from the node at index have the
+// block, whether they made it their current block or not.
+func (n *Network) waitBlock(index int, block *types.Block) error {
+	deadline := time.Now().Add(n.config.Timeout)
+	for _, peer := range n.reachable(index) {
+		for !n.Node(peer).BlockChain().HasBlock(block.Hash(), block.NumberU64()) {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("block #%d [%x…] reaching node %d: %v", block.NumberU64(), block.Hash().Bytes()[:4], peer, errTimeout)
+			}
+			time.Sleep(pollInterval)
+		}
+	}
+	return nil
+}
+
+// reachable returns the indexes of the nodes connected to the node at index,
+// directly or through other nodes.
+func (n *Network) reachable(index int) []int {
+	var (
+		seen  = map[int]bool{index: true}
+		queue = []int{index}
+		nodes []int
+	)
+	for len(queue) > 0 {
+		one := queue[0]
+		queue = queue[1:]
+		for other := range n.ids {
+			if !seen[other] && n.isPeer(one, other) {
+				seen[other] = true
+				queue = append(queue, other)
+				nodes = append(nodes, other)
+			}
+		}
+	}
+	return nodes
+}
+
+// forge assembles and seals a block on top of the current block of the service,
+// the way its miner would.
+func (n *Network) forge(service *aqua.AquaChain, coinbase common.Address, interval uint64) (*types.Block, error) {
+	var (
+		chain  = service.BlockChain()
+		engine = service.Engine()
+		config = chain.Config()
+		parent = chain.CurrentBlock()
+		num    = new(big.Int).Add(parent.Number(), common.Big1)
+	)
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     num,
+		GasLimit:   core.CalcGasLimit(parent),
+		Time:       new(big.Int).Add(parent.Time(), new(big.Int).SetUint64(interval)),
+		Coinbase:   coinbase,
+		Version:    config.GetBlockVersion(num),
+	}
+	if config.IsFeeMarket(num) {
+		header.BaseFee = misc.CalcBaseFee(config, parent.Header())
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		return nil, err
+	}
+	statedb, err := chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+	if hf4 := config.GetHF(4); hf4 != nil && hf4.Cmp(num) == 0 {
+		misc.ApplyHardFork4(statedb)
+	}
+	if hf5 := config.GetHF(5); hf5 != nil && hf5.Cmp(num) == 0 {
+		misc.ApplyHardFork5(statedb)
+	}
+	pending, err := service.TxPool().Pending()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		txs      = types.NewTransactionsByPriceAndNonce(types.MakeSigner(config, num), pending)
+		included []*types.Transaction
+		receipts []*types.Receipt
+	)
+	for tx := txs.Peek(); tx != nil && gp.Gas() >= params.TxGas; tx = txs.Peek() {
+		statedb.Prepare(tx.Hash(), common.Hash{}, len(included))
+
+		snap := statedb.Snapshot()
+		receipt, _, err := core.ApplyTransaction(config, chain, &coinbase, gp, statedb, header, tx, &header.GasUsed, vm.Config{})
+		if err != nil {
+			// Skip the rest of the sender's transactions, they can't execute either
+			statedb.RevertToSnapshot(snap)
+			txs.Pop()
+			continue
+		}
+		included = append(included, tx)
+		receipts = append(receipts, receipt)
+		txs.Shift()
+	}
+	block, err := engine.Finalize(chain, header, statedb, included, nil, receipts)
+	if err != nil {
+		return nil, err
+	}
+	return engine.Seal(chain, block, n.quit)
+}
+
+// StartMining starts the miner of the node at index, mining to its coinbase.
+func (n *Network) StartMining(index int) error {
+	service := n.Node(index)
+	if service == nil {
+		return errNotRunning
+	}
+	return service.StartMining(true)
+}
+
+// StopMining stops the miner of the node at index.
+func (n *Network) StopMining(index int) {
+	if service := n.Node(index); service != nil {
+		service.StopMining()
+	}
+}
+
+// WaitHead waits until the current block of the node at index is at least the
+// given number.
+func (n *Network) WaitHead(index int, number uint64) (*types.Block, error) {
+	deadline := time.Now().Add(n.config.Timeout)
+	for {
+		if head := n.Head(index); head.NumberU64() >= number {
+			return head, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("node %d head #%d, want #%d: %v", index, n.Head(index).NumberU64(), number, errTimeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Converge waits until the nodes, all of the network if none are given, agree
+// on the current block, and returns it. Note nodes only hear of a heavier chain
+// their peers reorged to from the next block propagated on it.
+func (n *Network) Converge(nodes ...int) (*types.Block, error) {
+	if len(nodes) == 0 {
+		for i := range n.ids {
+			nodes = append(nodes, i)
+		}
+	}
+	deadline := time.Now().Add(n.config.Timeout)
+	for {
+		head, agreed := n.Head(nodes[0]), true
+		for _, index := range nodes[1:] {
+			if n.Head(index).Hash() != head.Hash() {
+				agreed = false
+				break
+			}
+		}
+		if agreed {
+			return head, nil
+		}
+		if time.Now().After(deadline) {
+			heads := make([]string, len(nodes))
+			for i, index := range nodes {
+				head := n.Head(index)
+				heads[i] = fmt.Sprintf("%d: #%d [%x…]", index, head.NumberU64(), head.Hash().Bytes()[:4])
+			}
+			return nil, fmt.Errorf("heads %v didn't converge: %v", heads, errTimeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
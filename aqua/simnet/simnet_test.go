@@ -0,0 +1,132 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package simnet
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that blocks and transactions mined on one end of a line of nodes reach
+// the other end.
+func TestPropagation(t *testing.T) {
+	net, err := New(Config{Nodes: 3, Accounts: 1, PowMode: aquahash.ModeFake, Topology: Line})
+	if err != nil {
+		t.Fatalf("failed to start network: %v", err)
+	}
+	defer net.Shutdown()
+
+	if _, err := net.Mine(0, 5, params.DurationLimit.Uint64()); err != nil {
+		t.Fatalf("failed to mine: %v", err)
+	}
+	head, err := net.Converge()
+	if err != nil {
+		t.Fatalf("failed to converge: %v", err)
+	}
+	if head.NumberU64() != 5 {
+		t.Fatalf("head number mismatch: have %d, want %d", head.NumberU64(), 5)
+	}
+	to, amount := common.Address{0x01}, big.NewInt(1000)
+	tx, err := net.Transfer(0, 0, to, amount)
+	if err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	blocks, err := net.Mine(0, 1, params.DurationLimit.Uint64())
+	if err != nil {
+		t.Fatalf("failed to mine: %v", err)
+	}
+	if txs := blocks[0].Transactions(); len(txs) != 1 || txs[0].Hash() != tx.Hash() {
+		t.Fatalf("mined transactions mismatch: have %v, want [%x]", txs, tx.Hash())
+	}
+	if _, err := net.Converge(); err != nil {
+		t.Fatalf("failed to converge: %v", err)
+	}
+	statedb, err := net.Node(2).BlockChain().State()
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	if balance := statedb.GetBalance(to); balance.Cmp(amount) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", balance, amount)
+	}
+}
+
+// Tests that partitioned nodes mining across a hard fork boundary reorg onto
+// the heavier side once reconnected.
+func TestPartitionReorg(t *testing.T) {
+	genesis := DefaultGenesis()
+	genesis.Config.HF = params.ForkMap{0: big.NewInt(0), 1: big.NewInt(0), 2: big.NewInt(0), 3: big.NewInt(0), 4: big.NewInt(4), 5: big.NewInt(6)}
+
+	net, err := New(Config{Nodes: 4, Genesis: genesis, PowMode: aquahash.ModeFake, Topology: Ring})
+	if err != nil {
+		t.Fatalf("failed to start network: %v", err)
+	}
+	defer net.Shutdown()
+
+	if _, err := net.Mine(0, 3, params.DurationLimit.Uint64()); err != nil {
+		t.Fatalf("failed to mine: %v", err)
+	}
+	if _, err := net.Converge(); err != nil {
+		t.Fatalf("failed to converge: %v", err)
+	}
+	// Split the ring in two, both sides mining past the difficulty reset of HF5,
+	// the second half a longer fork of faster blocks
+	if err := net.Partition(0, 1); err != nil {
+		t.Fatalf("failed to partition: %v", err)
+	}
+	if _, err := net.Mine(0, 4, params.DurationLimit.Uint64()); err != nil {
+		t.Fatalf("failed to mine: %v", err)
+	}
+	heavy, err := net.Mine(2, 5, params.DurationLimit.Uint64()/4)
+	if err != nil {
+		t.Fatalf("failed to mine: %v", err)
+	}
+	if _, err := net.Converge(0, 1); err != nil {
+		t.Fatalf("failed to converge light side: %v", err)
+	}
+	if _, err := net.Converge(2, 3); err != nil {
+		t.Fatalf("failed to converge heavy side: %v", err)
+	}
+	if net.Head(0).Hash() == net.Head(2).Hash() {
+		t.Fatalf("partitioned sides didn't fork")
+	}
+	// Heal the partition, the bridging node reorging onto the heavy side, and
+	// check everyone else does once its next block spreads the heavier total
+	// difficulty
+	if err := net.Connect(1, 2); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if _, err := net.Converge(1, 2, 3); err != nil {
+		t.Fatalf("failed to converge bridge: %v", err)
+	}
+	next, err := net.Mine(2, 1, params.DurationLimit.Uint64())
+	if err != nil {
+		t.Fatalf("failed to mine: %v", err)
+	}
+	heavy = append(heavy, next...)
+
+	head, err := net.Converge()
+	if err != nil {
+		t.Fatalf("failed to converge: %v", err)
+	}
+	if want := heavy[len(heavy)-1].Hash(); head.Hash() != want {
+		t.Errorf("head mismatch: have %x, want %x", head.Hash(), want)
+	}
+}
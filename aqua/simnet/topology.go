@@ -0,0 +1,59 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package simnet
+
+// Topology returns the pairs of node indexes to connect in a network of the
+// given number of nodes.
+type Topology func(nodes int) [][2]int
+
+// Line connects every node to the next one.
+func Line(nodes int) [][2]int {
+	var edges [][2]int
+	for i := 0; i+1 < nodes; i++ {
+		edges = append(edges, [2]int{i, i + 1})
+	}
+	return edges
+}
+
+// Ring connects every node to the next one, and the last one to the first.
+func Ring(nodes int) [][2]int {
+	edges := Line(nodes)
+	if nodes > 2 {
+		edges = append(edges, [2]int{nodes - 1, 0})
+	}
+	return edges
+}
+
+// Star connects every node to the first one.
+func Star(nodes int) [][2]int {
+	var edges [][2]int
+	for i := 1; i < nodes; i++ {
+		edges = append(edges, [2]int{0, i})
+	}
+	return edges
+}
+
+// Full connects every node to every other one.
+func Full(nodes int) [][2]int {
+	var edges [][2]int
+	for i := 0; i < nodes; i++ {
+		for j := i + 1; j < nodes; j++ {
+			edges = append(edges, [2]int{i, j})
+		}
+	}
+	return edges
+}
@@ -0,0 +1,135 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/log"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// regenCacheSize is the number of regenerated states kept in memory, so repeated
+// queries of the same historical block don't re-execute it again.
+const regenCacheSize = 16
+
+// stateRegenerator regenerates the pruned state of historical blocks by
+// re-executing them on top of the nearest ancestor state still available.
+type stateRegenerator struct {
+	chain *core.BlockChain
+
+	lock     sync.Mutex     // Serialises regenerations, bounding their cost
+	database state.Database // Memory database holding the regenerated states
+	cache    *lru.Cache     // Block hash -> root of the regenerated states kept
+}
+
+func newStateRegenerator(chain *core.BlockChain, db state.Database) *stateRegenerator {
+	r := &stateRegenerator{chain: chain, database: db}
+	r.cache, _ = lru.NewWithEvict(regenCacheSize, func(key, value interface{}) {
+		r.database.TrieDB().Dereference(value.(common.Hash), common.Hash{})
+	})
+	return r
+}
+
+// stateAt returns the state of the given block. If it isn't available anymore,
+// up to reexec blocks are re-executed on top of the nearest ancestor state to
+// regenerate it.
+func (r *stateRegenerator) stateAt(block *types.Block, reexec uint64) (*state.StateDB, error) {
+	if statedb, err := r.chain.StateAt(block.Root()); err == nil {
+		return statedb, nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// Return the state if it was regenerated already, refreshing it in the cache
+	if root, ok := r.cache.Get(block.Hash()); ok {
+		if statedb, err := state.New(root.(common.Hash), r.database); err == nil {
+			return statedb, nil
+		}
+	}
+	// Find the nearest state, which may be a regenerated one
+	var (
+		blocks []*types.Block // Blocks to re-execute, newest first
+		base   = block
+	)
+	statedb, err := state.New(base.Root(), r.database)
+	for err != nil {
+		if uint64(len(blocks)) >= reexec || base.NumberU64() == 0 {
+			return nil, fmt.Errorf("no state available within %d blocks of block #%d", reexec, block.NumberU64())
+		}
+		blocks = append(blocks, base)
+		if base = r.chain.GetBlock(base.ParentHash(), base.NumberU64()-1); base == nil {
+			return nil, fmt.Errorf("missing ancestor #%d of block #%d", blocks[len(blocks)-1].NumberU64()-1, block.NumberU64())
+		}
+		statedb, err = state.New(base.Root(), r.database)
+	}
+	if len(blocks) == 0 {
+		return statedb, nil
+	}
+	// Re-execute the blocks up to the requested one. Intermediate states are
+	// dropped as soon as the next one is generated, the base state is never
+	// dereferenced as it may be a cached one.
+	var (
+		start  = time.Now()
+		logged = start
+		trieDB = r.database.TrieDB()
+		proot  common.Hash
+	)
+	release := func() {
+		if proot != (common.Hash{}) {
+			trieDB.Dereference(proot, common.Hash{})
+		}
+	}
+	for i := len(blocks) - 1; i >= 0; i-- {
+		current := blocks[i]
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Regenerating historical state", "block", current.NumberU64(), "target", block.NumberU64(), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		if _, _, _, err := r.chain.Processor().Process(current, statedb, vm.Config{}); err != nil {
+			release()
+			return nil, fmt.Errorf("re-executing block #%d failed: %v", current.NumberU64(), err)
+		}
+		root, err := statedb.Commit(r.chain.Config().IsEIP158(current.Number()))
+		if err != nil {
+			release()
+			return nil, err
+		}
+		if root != current.Root() {
+			release()
+			return nil, fmt.Errorf("regenerated state of block #%d mismatch: have %x, want %x", current.NumberU64(), root, current.Root())
+		}
+		if err := statedb.Reset(root); err != nil {
+			release()
+			return nil, err
+		}
+		trieDB.Reference(root, common.Hash{})
+		release()
+		proot = root
+	}
+	// Keep the reference of the requested state for the cache to release
+	r.cache.Add(block.Hash(), proot)
+	log.Debug("Regenerated historical state", "block", block.NumberU64(), "reexec", len(blocks), "elapsed", common.PrettyDuration(time.Since(start)), "size", trieDB.Size())
+	return statedb, nil
+}
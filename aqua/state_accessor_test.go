@@ -0,0 +1,104 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// newPrunedChain creates a chain of the given length which only retains the
+// state of the genesis and the last two blocks, as left by a pruning node.
+func newPrunedChain(t *testing.T, blocks int) (*core.BlockChain, aquadb.Database, []*types.Block) {
+	var (
+		db, _    = aquadb.NewMemDatabase()
+		genDb, _ = aquadb.NewMemDatabase()
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	gspec.MustCommit(genDb)
+	chain, _ := core.GenerateChain(gspec.Config, genesis, aquahash.NewFaker(), genDb, blocks, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), common.Address{byte(i)}, big.NewInt(1000), params.TxGas, nil, nil), signer, testBankKey)
+		block.AddTx(tx)
+	})
+	blockchain, _ := core.NewBlockChain(db, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatal(err)
+	}
+	// Stopping the chain only flushes the most recent states
+	blockchain.Stop()
+	blockchain, err := core.NewBlockChain(db, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blockchain, db, chain
+}
+
+func TestStateRegeneration(t *testing.T) {
+	blockchain, db, chain := newPrunedChain(t, 10)
+	defer blockchain.Stop()
+	regen := newStateRegenerator(blockchain, state.NewDatabase(db))
+
+	block := chain[4] // #5, five blocks after the genesis state
+	if _, err := blockchain.StateAt(block.Root()); err == nil {
+		t.Fatal("state of block #5 not pruned")
+	}
+	if _, err := regen.stateAt(block, 4); err == nil {
+		t.Fatal("state regenerated beyond the re-execution limit")
+	}
+	statedb, err := regen.stateAt(block, 5)
+	if err != nil {
+		t.Fatalf("failed to regenerate state: %v", err)
+	}
+	if root := statedb.IntermediateRoot(true); root != block.Root() {
+		t.Fatalf("regenerated root mismatch: have %x, want %x", root, block.Root())
+	}
+	if balance := statedb.GetBalance(common.Address{4}); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("regenerated balance mismatch: have %v, want 1000", balance)
+	}
+	// The regenerated state is cached, and serves as a base for the next blocks
+	if regen.cache.Len() != 1 {
+		t.Errorf("cached states mismatch: have %d, want 1", regen.cache.Len())
+	}
+	if _, err := regen.stateAt(block, 0); err != nil {
+		t.Errorf("cached state not found: %v", err)
+	}
+	statedb, err = regen.stateAt(chain[6], 2)
+	if err != nil {
+		t.Fatalf("failed to regenerate state from a cached one: %v", err)
+	}
+	if root := statedb.IntermediateRoot(true); root != chain[6].Root() {
+		t.Fatalf("regenerated root mismatch: have %x, want %x", root, chain[6].Root())
+	}
+	// Available states are returned as is
+	if _, err := regen.stateAt(chain[9], 0); err != nil {
+		t.Errorf("head state not found: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package supply
+
+import (
+	"fmt"
+
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+// PublicSupplyAPI exposes the coin supply of the chain to anyone.
+type PublicSupplyAPI struct {
+	tracker *Tracker
+}
+
+// NewPublicSupplyAPI creates a new public coin supply API.
+func NewPublicSupplyAPI(tracker *Tracker) *PublicSupplyAPI {
+	return &PublicSupplyAPI{tracker}
+}
+
+// header retrieves the canonical header of the given number, the head one for
+// the latest and pending blocks.
+func (api *PublicSupplyAPI) header(number rpc.BlockNumber) (*types.Header, error) {
+	if number < 0 {
+		return api.tracker.chain.CurrentBlock().Header(), nil
+	}
+	header := api.tracker.chain.GetHeaderByNumber(uint64(number))
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	return header, nil
+}
+
+// TotalSupply returns the coins in existence at the given block, the latest one
+// if omitted, along with the genesis allocation, the rewards issued and the
+// fees burnt making it up.
+func (api *PublicSupplyAPI) TotalSupply(number *rpc.BlockNumber) (*Supply, error) {
+	latest := rpc.LatestBlockNumber
+	if number == nil {
+		number = &latest
+	}
+	header, err := api.header(*number)
+	if err != nil {
+		return nil, err
+	}
+	return api.tracker.Supply(header)
+}
+
+// SupplyDelta returns the rewards issued and the fees burnt by the blocks from
+// first up to last, both included.
+func (api *PublicSupplyAPI) SupplyDelta(first, last rpc.BlockNumber) (*Delta, error) {
+	from, err := api.header(first)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.header(last)
+	if err != nil {
+		return nil, err
+	}
+	if from.Number.Cmp(to.Number) > 0 {
+		return nil, fmt.Errorf("first block #%d after last block #%d", from.Number, to.Number)
+	}
+	return api.tracker.Delta(from, to)
+}
@@ -0,0 +1,395 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package supply tracks the coin supply of the chain: the genesis allocation,
+// the rewards issued to the miners of blocks and uncles, and the base fees
+// burnt since. Issuance follows the reward schedule of the chain config, see
+// params.RewardConfig.
+package supply
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/rlp"
+	"github.com/aquanetwork/aquachain/trie"
+)
+
+const chainHeadChanSize = 16 // Size of the channel listening to the chain heads
+
+var (
+	recordPrefix = []byte("supply-")        // recordPrefix + block hash -> supply record up to the block
+	genesisKey   = []byte("SupplyGenesis")  // genesisKey -> coins allocated in the genesis block (big endian)
+	progressKey  = []byte("SupplyProgress") // progressKey -> newest indexed canonical block (uint64 big endian)
+
+	errNotIndexed = errors.New("supply not indexed yet")
+)
+
+// record is the supply issued and burnt from the genesis up to a block.
+type record struct {
+	Rewards      *big.Int // Rewards of the block miners, uncle inclusion included
+	UncleRewards *big.Int // Rewards of the uncle miners
+	Burnt        *big.Int // Base fees burnt
+}
+
+// blockChain is the chain the tracker indexes.
+type blockChain interface {
+	Config() *params.ChainConfig
+	CurrentBlock() *types.Block
+	Genesis() *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetHeaderByNumber(number uint64) *types.Header
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Tracker indexes the supply of every block imported into the chain. Records
+// are kept by block hash, so the ones of reorged blocks don't get in the way.
+type Tracker struct {
+	db      aquadb.Database
+	chain   blockChain
+	rewards func(header *types.Header, uncles []*types.Header) (*big.Int, []*big.Int) // Nil without issuance
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a supply tracker of the chain, storing its index in db.
+func New(db aquadb.Database, chain blockChain) *Tracker {
+	t := &Tracker{
+		db:    db,
+		chain: chain,
+		quit:  make(chan struct{}),
+	}
+	if config := chain.Config(); config.Clique == nil {
+		t.rewards = func(header *types.Header, uncles []*types.Header) (*big.Int, []*big.Int) {
+			return aquahash.Rewards(config, header, uncles)
+		}
+	}
+	return t
+}
+
+// Start indexes the genesis block, then catches up with the chain in the
+// background and follows its head.
+func (t *Tracker) Start() error {
+	if err := t.indexGenesis(); err != nil {
+		return err
+	}
+	t.wg.Add(1)
+	go t.loop()
+	return nil
+}
+
+// Stop stops the indexing, which resumes from where it stopped on next start.
+func (t *Tracker) Stop() {
+	close(t.quit)
+	t.wg.Wait()
+}
+
+// indexGenesis records the coins allocated in the genesis block, if not done yet.
+func (t *Tracker) indexGenesis() error {
+	genesis := t.chain.Genesis()
+	if t.record(genesis.Hash()) != nil {
+		return nil
+	}
+	tr, err := state.NewDatabase(t.db).OpenTrie(genesis.Root())
+	if err != nil {
+		return err
+	}
+	allocated := new(big.Int)
+	it := trie.NewIterator(tr.NodeIterator(nil))
+	for it.Next() {
+		var account state.Account
+		if err := rlp.DecodeBytes(it.Value, &account); err != nil {
+			return err
+		}
+		allocated.Add(allocated, account.Balance)
+	}
+	if it.Err != nil {
+		return it.Err
+	}
+	if err := t.db.Put(genesisKey, allocated.Bytes()); err != nil {
+		return err
+	}
+	return t.writeRecord(t.db, genesis.Hash(), &record{new(big.Int), new(big.Int), new(big.Int)})
+}
+
+func (t *Tracker) loop() {
+	defer t.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	sub := t.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	var (
+		done    = make(chan struct{})
+		running bool
+		pending bool // New head while indexing the previous one
+	)
+	index := func() {
+		running = true
+		go func() {
+			t.index(t.chain.CurrentBlock().NumberU64())
+			done <- struct{}{}
+		}()
+	}
+	index()
+	for {
+		select {
+		case <-heads:
+			if running {
+				pending = true
+			} else {
+				index()
+			}
+		case <-done:
+			running = false
+			if pending {
+				pending = false
+				index()
+			}
+		case <-sub.Err():
+			if running {
+				<-done
+			}
+			return
+		case <-t.quit:
+			if running {
+				<-done
+			}
+			return
+		}
+	}
+}
+
+// index records the supply of the canonical blocks up to head, starting after
+// the newest one already indexed, until done or stopped.
+func (t *Tracker) index(head uint64) {
+	// Find the newest indexed canonical block, the last one indexed may have been
+	// reorged out of the chain since
+	number := t.progress()
+	if number > head {
+		number = head
+	}
+	var (
+		parent *record
+		hash   common.Hash
+	)
+	for {
+		header := t.chain.GetHeaderByNumber(number)
+		if header == nil {
+			return
+		}
+		hash = header.Hash()
+		if parent = t.record(hash); parent != nil {
+			break
+		}
+		if number == 0 {
+			return
+		}
+		number--
+	}
+	var (
+		batch  = t.db.NewBatch()
+		start  = time.Now()
+		logged = start
+		from   = number
+	)
+	flush := func() bool {
+		if err := writeProgress(batch, number); err == nil {
+			if err = batch.Write(); err == nil {
+				batch.Reset()
+				return true
+			}
+		}
+		log.Error("Failed to index supply", "number", number)
+		return false
+	}
+	for number < head {
+		select {
+		case <-t.quit:
+			flush()
+			return
+		default:
+		}
+		block := t.chain.GetBlockByNumber(number + 1)
+		if block == nil || block.ParentHash() != hash {
+			break // Reorged meanwhile, the next head will resume
+		}
+		rec := t.next(parent, block)
+		if err := t.writeRecord(batch, block.Hash(), rec); err != nil {
+			log.Error("Failed to encode supply record", "number", block.NumberU64(), "err", err)
+			return
+		}
+		number, hash, parent = block.NumberU64(), block.Hash(), rec
+
+		if batch.ValueSize() >= aquadb.IdealBatchSize && !flush() {
+			return
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Indexing coin supply", "number", number, "head", head, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if flush() && number-from > 1 {
+		log.Debug("Indexed coin supply", "from", from+1, "to", number, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+}
+
+// next returns the supply record of block, following the one of its parent.
+func (t *Tracker) next(parent *record, block *types.Block) *record {
+	rec := &record{
+		Rewards:      new(big.Int).Set(parent.Rewards),
+		UncleRewards: new(big.Int).Set(parent.UncleRewards),
+		Burnt:        new(big.Int).Set(parent.Burnt),
+	}
+	if t.rewards != nil {
+		if reward, uncleRewards := t.rewards(block.Header(), block.Uncles()); reward != nil {
+			rec.Rewards.Add(rec.Rewards, reward)
+			for _, r := range uncleRewards {
+				rec.UncleRewards.Add(rec.UncleRewards, r)
+			}
+		}
+	}
+	if baseFee := block.BaseFee(); baseFee != nil {
+		rec.Burnt.Add(rec.Burnt, new(big.Int).Mul(baseFee, new(big.Int).SetUint64(block.GasUsed())))
+	}
+	return rec
+}
+
+// record retrieves the supply record of a block, nil if not indexed.
+func (t *Tracker) record(hash common.Hash) *record {
+	data, _ := t.db.Get(append(recordPrefix, hash.Bytes()...))
+	if len(data) == 0 {
+		return nil
+	}
+	rec := new(record)
+	if err := rlp.DecodeBytes(data, rec); err != nil {
+		log.Error("Invalid supply record", "hash", hash, "err", err)
+		return nil
+	}
+	return rec
+}
+
+func (t *Tracker) writeRecord(db aquadb.Putter, hash common.Hash, rec *record) error {
+	data, err := rlp.EncodeToBytes(rec)
+	if err != nil {
+		return err
+	}
+	return db.Put(append(recordPrefix, hash.Bytes()...), data)
+}
+
+// progress returns the number of the newest canonical block indexed so far.
+func (t *Tracker) progress() uint64 {
+	enc, _ := t.db.Get(progressKey)
+	if len(enc) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(enc)
+}
+
+func writeProgress(db aquadb.Putter, number uint64) error {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], number)
+	return db.Put(progressKey, enc[:])
+}
+
+// Supply is the coin supply of the chain at a block.
+type Supply struct {
+	Number       hexutil.Uint64 `json:"blockNumber"`
+	Hash         common.Hash    `json:"blockHash"`
+	Genesis      *hexutil.Big   `json:"genesis"`      // Coins allocated in the genesis block
+	Rewards      *hexutil.Big   `json:"rewards"`      // Coins issued to the block miners
+	UncleRewards *hexutil.Big   `json:"uncleRewards"` // Coins issued to the uncle miners
+	Burnt        *hexutil.Big   `json:"burnt"`        // Base fees burnt
+	Total        *hexutil.Big   `json:"totalSupply"`  // Coins in existence
+}
+
+// Supply returns the coin supply of the chain at the given block.
+func (t *Tracker) Supply(header *types.Header) (*Supply, error) {
+	rec := t.record(header.Hash())
+	if rec == nil {
+		return nil, fmt.Errorf("%v at block #%d", errNotIndexed, header.Number)
+	}
+	data, _ := t.db.Get(genesisKey)
+	genesis := new(big.Int).SetBytes(data)
+
+	total := new(big.Int).Add(genesis, rec.Rewards)
+	total.Add(total, rec.UncleRewards)
+	total.Sub(total, rec.Burnt)
+
+	return &Supply{
+		Number:       hexutil.Uint64(header.Number.Uint64()),
+		Hash:         header.Hash(),
+		Genesis:      (*hexutil.Big)(genesis),
+		Rewards:      (*hexutil.Big)(rec.Rewards),
+		UncleRewards: (*hexutil.Big)(rec.UncleRewards),
+		Burnt:        (*hexutil.Big)(rec.Burnt),
+		Total:        (*hexutil.Big)(total),
+	}, nil
+}
+
+// Delta is the change of the coin supply over a range of blocks.
+type Delta struct {
+	From         hexutil.Uint64 `json:"fromBlock"`
+	To           hexutil.Uint64 `json:"toBlock"`
+	Rewards      *hexutil.Big   `json:"rewards"`
+	UncleRewards *hexutil.Big   `json:"uncleRewards"`
+	Burnt        *hexutil.Big   `json:"burnt"`
+	Delta        *hexutil.Big   `json:"delta"` // Rewards issued minus fees burnt
+}
+
+// Delta returns the change of the coin supply brought by the blocks from first
+// up to last, both included.
+func (t *Tracker) Delta(first, last *types.Header) (*Delta, error) {
+	before := first.ParentHash
+	if first.Number.Sign() == 0 {
+		before = first.Hash() // Nothing issued nor burnt in the genesis block
+	}
+	prev, rec := t.record(before), t.record(last.Hash())
+	if prev == nil || rec == nil {
+		return nil, fmt.Errorf("%v at block #%d", errNotIndexed, last.Number)
+	}
+	var (
+		rewards      = new(big.Int).Sub(rec.Rewards, prev.Rewards)
+		uncleRewards = new(big.Int).Sub(rec.UncleRewards, prev.UncleRewards)
+		burnt        = new(big.Int).Sub(rec.Burnt, prev.Burnt)
+	)
+	delta := new(big.Int).Add(rewards, uncleRewards)
+	delta.Sub(delta, burnt)
+
+	return &Delta{
+		From:         hexutil.Uint64(first.Number.Uint64()),
+		To:           hexutil.Uint64(last.Number.Uint64()),
+		Rewards:      (*hexutil.Big)(rewards),
+		UncleRewards: (*hexutil.Big)(uncleRewards),
+		Burnt:        (*hexutil.Big)(burnt),
+		Delta:        (*hexutil.Big)(delta),
+	}, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package supply
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/rlp"
+	"github.com/aquanetwork/aquachain/rpc"
+	"github.com/aquanetwork/aquachain/trie"
+)
+
+// Tests that the indexed supply accounts for every coin in the state, through
+// block and uncle rewards and burnt base fees.
+func TestSupply(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		db, _  = aquadb.NewMemDatabase()
+		config = *params.TestChainConfig
+		funds  = big.NewInt(params.Aqua)
+		gspec  = &core.Genesis{Config: &config, Alloc: core.GenesisAlloc{addr: {Balance: funds}}}
+		signer = types.NewFeeMarketSigner(config.ChainId)
+	)
+	config.FeeMarket = &params.FeeMarketConfig{Block: big.NewInt(2)}
+	genesis := gspec.MustCommit(db)
+
+	blocks, _ := core.GenerateChain(&config, genesis, aquahash.NewFaker(), db, 6, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{byte(i + 1)})
+		tx, _ := types.SignTx(types.NewDynamicFeeTransaction(config.ChainId, gen.TxNonce(addr), &common.Address{0xff}, big.NewInt(1000), params.TxGas, big.NewInt(1), new(big.Int).SetUint64(2*params.InitialBaseFee), nil), signer, key)
+		switch {
+		case i == 0:
+			tx, _ = types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{0xff}, big.NewInt(1000), params.TxGas, big.NewInt(1), nil), signer, key)
+		case i == 3:
+			uncle := gen.PrevBlock(1).Header()
+			uncle.Extra = []byte("uncle")
+			gen.AddUncle(uncle)
+		}
+		gen.AddTx(tx)
+	})
+	chain, _ := core.NewBlockChain(db, nil, &config, aquahash.NewFaker(), vm.Config{})
+	defer chain.Stop()
+
+	if i, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", i, err)
+	}
+	tracker := New(db, chain)
+	if err := tracker.Start(); err != nil {
+		t.Fatalf("failed to start tracker: %v", err)
+	}
+	defer tracker.Stop()
+
+	for deadline := time.Now().Add(5 * time.Second); tracker.progress() != uint64(len(blocks)); {
+		if time.Now().After(deadline) {
+			t.Fatalf("supply not indexed: progress %d, head %d", tracker.progress(), len(blocks))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	api := NewPublicSupplyAPI(tracker)
+	supply, err := api.TotalSupply(nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve supply: %v", err)
+	}
+	// Every coin in the state must be accounted for
+	tr, _ := state.NewDatabase(db).OpenTrie(chain.CurrentBlock().Root())
+	balances := new(big.Int)
+	for it := trie.NewIterator(tr.NodeIterator(nil)); it.Next(); {
+		var account state.Account
+		rlp.DecodeBytes(it.Value, &account)
+		balances.Add(balances, account.Balance)
+	}
+	if supply.Total.ToInt().Cmp(balances) != 0 {
+		t.Errorf("total supply mismatch: have %v, want %v", supply.Total, balances)
+	}
+	if supply.Genesis.ToInt().Cmp(funds) != 0 {
+		t.Errorf("genesis supply mismatch: have %v, want %v", supply.Genesis, funds)
+	}
+	if supply.UncleRewards.ToInt().Sign() == 0 || supply.Burnt.ToInt().Sign() == 0 {
+		t.Errorf("uncle rewards or burnt fees missing: %v, %v", supply.UncleRewards, supply.Burnt)
+	}
+	// The deltas of consecutive ranges add up to the total
+	first, err := api.SupplyDelta(0, 3)
+	if err != nil {
+		t.Fatalf("failed to retrieve first delta: %v", err)
+	}
+	second, err := api.SupplyDelta(4, rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to retrieve second delta: %v", err)
+	}
+	if have, want := new(big.Int).Add(first.Delta.ToInt(), second.Delta.ToInt()), new(big.Int).Sub(balances, funds); have.Cmp(want) != 0 {
+		t.Errorf("supply delta mismatch: have %v, want %v", have, want)
+	}
+	burnt := new(big.Int)
+	for _, block := range blocks[1:3] {
+		burnt.Add(burnt, new(big.Int).Mul(block.BaseFee(), new(big.Int).SetUint64(block.GasUsed())))
+	}
+	if first.Burnt.ToInt().Cmp(burnt) != 0 {
+		t.Errorf("first burnt fees mismatch: have %v, want %v", first.Burnt, burnt)
+	}
+	if _, err := api.SupplyDelta(3, 2); err == nil {
+		t.Errorf("reversed range accepted")
+	}
+}
@@ -53,3 +53,29 @@ func TestFastSyncDisabling(t *testing.T) {
 		t.Fatalf("fast sync not disabled after successful synchronisation")
 	}
 }
+
+// Tests that header sync only imports headers, leaving the block chain and the
+// transaction processing untouched.
+func TestHeaderSync(t *testing.T) {
+	pmHeaders, _ := newTestProtocolManagerMust(t, downloader.HeaderSync, 0, nil, nil)
+	pmFull, _ := newTestProtocolManagerMust(t, downloader.FullSync, 1024, nil, nil)
+
+	// Sync up the two peers
+	io1, io2 := p2p.MsgPipe()
+
+	go pmFull.handle(pmFull.newPeer(64, p2p.NewPeer(discover.NodeID{}, "headers", nil), io2))
+	go pmHeaders.handle(pmHeaders.newPeer(64, p2p.NewPeer(discover.NodeID{}, "full", nil), io1))
+
+	time.Sleep(250 * time.Millisecond)
+	pmHeaders.synchronise(pmHeaders.peers.BestPeer())
+
+	if head := pmHeaders.blockchain.CurrentHeader(); head.Hash() != pmFull.blockchain.CurrentBlock().Hash() {
+		t.Fatalf("header chain head mismatch: have #%d, want #%d", head.Number, pmFull.blockchain.CurrentBlock().Number())
+	}
+	if head := pmHeaders.blockchain.CurrentBlock(); head.NumberU64() != 0 {
+		t.Fatalf("blocks imported in header sync mode: head #%d", head.NumberU64())
+	}
+	if atomic.LoadUint32(&pmHeaders.acceptTxs) == 1 {
+		t.Fatalf("transactions accepted in header sync mode")
+	}
+}
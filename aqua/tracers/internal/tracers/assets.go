@@ -92,7 +92,7 @@ func _4byte_tracerJs() (*asset, error) {
 	return a, nil
 }
 
-var _call_tracerJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd4\x59\xdf\x6f\x1b\x37\xf2\x7f\x96\xfe\x8a\x49\x1e\x6a\x09\x51\x24\x27\xe9\xb7\x5f\xc0\xae\x7a\xd0\x39\x4a\x6a\xc0\x8d\x03\x5b\x69\x10\x04\x79\xa0\x76\x67\x25\xd6\x5c\x72\x4b\x72\x2d\xef\xa5\xfe\xdf\x0f\x33\xe4\xae\x56\x3f\xec\xe8\x7a\xb8\x43\xef\x45\xd0\x2e\x67\x86\xc3\x99\xcf\xfc\xe2\x8e\x46\x70\x66\x8a\xca\xca\xc5\xd2\xc3\xcb\xe3\x17\xff\x0f\xb3\x25\xc2\xc2\x3c\x47\xbf\x44\x8b\x65\x0e\x93\xd2\x2f\x8d\x75\xdd\xd1\x08\x66\x4b\xe9\x20\x93\x0a\x41\x3a\x28\x84\xf5\x60\x32\xf0\x5b\xf4\x4a\xce\xad\xb0\xd5\xb0\x3b\x1a\x05\x9e\xbd\xcb\x24\x21\xb3\x88\xe0\x4c\xe6\x57\xc2\xe2\x09\x54\xa6\x84\x44\x68\xb0\x98\x4a\xe7\xad\x9c\x97\x1e\x41\x7a\x10\x3a\x1d\x19\x0b\xb9\x49\x65\x56\x91\x48\xe9\xa1\xd4\x29\x5a\xde\xda\xa3\xcd\x5d\xad\xc7\xdb\x77\x1f\xe0\x02\x9d\x43\x0b\x6f\x51\xa3\x15\x0a\xde\x97\x73\x25\x13\xb8\x90\x09\x6a\x87\x20\x1c\x14\xf4\xc6\x2d\x31\x85\x39\x8b\x23\xc6\x37\xa4\xca\x75\x54\x05\xde\x98\x52\xa7\xc2\x4b\xa3\x07\x80\x92\x34\x87\x5b\xb4\x4e\x1a\x0d\xaf\xea\xad\xa2\xc0\x01\x18\x4b\x42\x7a\xc2\xd3\x01\x2c\x98\x82\xf8\xfa\x20\x74\x05\x4a\xf8\x35\xeb\x01\x06\x59\x9f\x3b\x05\xa9\x79\x9b\xa5\x29\x10\xfc\x52\x78\x3a\xf5\x4a\x2a\x05\x73\x84\xd2\x61\x56\xaa\x01\x49\x9b\x97\x1e\x3e\x9e\xcf\x7e\xbe\xfc\x30\x83\xc9\xbb\x4f\xf0\x71\x72\x75\x35\x79\x37\xfb\x74\x0a\x2b\xe9\x97\xa6\xf4\x80\xb7\x18\x44\xc9\xbc\x50\x12\x53\x58\x09\x6b\x85\xf6\x15\x98\x8c\x24\xfc\x32\xbd\x3a\xfb\x79\xf2\x6e\x36\xf9\xfb\xf9\xc5\xf9\xec\x13\x18\x0b\x6f\xce\x67\xef\xa6\xd7\xd7\xf0\xe6\xf2\x0a\x26\xf0\x7e\x72\x35\x3b\x3f\xfb\x70\x31\xb9\x82\xf7\x1f\xae\xde\x5f\x5e\x4f\x87\x70\x8d\xa4\x15\x12\xff\xb7\x6d\x9e\xb1\xf7\x2c\x42\x8a\x5e\x48\xe5\x6a\x4b\x7c\x32\x25\xb8\xa5\x29\x55\x0a\x4b\x71\x8b\x60\x31\x41\x79\x8b\x29\x08\x48\x4c\x51\x1d\xec\x54\x92\x25\x94\xd1\x0b\x3e\xf3\x83\x80\x84\xf3\x0c\xb4\xf1\x03\x70\x88\xf0\xe3\xd2\xfb\xe2\x64\x34\x5a\xad\x56\xc3\x85\x2e\x87\xc6\x2e\x46\x2a\x88\x73\xa3\x9f\x86\x5d\x92\x99\x08\xa5\x66\x56\x24\x68\xc9\x39\x02\xb2\x92\xcc\xaf\xcc\x4a\x83\xb7\x42\x3b\x91\x90\xab\xe9\x7f\xc2\x60\x14\x1e\xf0\x8e\x9e\xbc\x23\xd0\x82\xc5\xc2\x58\xfa\xaf\x54\x8d\x33\xa9\x3d\x5a\x2d\x14\xcb\x76\x90\x8b\x14\x61\x5e\x81\x68\x0b\x1c\xb4\x0f\x43\x30\x0a\xee\x06\xa9\x33\x63\x73\x86\xe5\xb0\xfb\xb5\xdb\x89\x1a\x3a\x2f\x92\x1b\x52\x90\xe4\x27\xa5\xb5\xa8\x3d\x99\xb2\xb4\x4e\xde\x22\x93\x40\xa0\x89\xf6\x9c\xfe\xfa\x0b\xe0\x1d\x26\x65\x90\xd4\x69\x84\x9c\xc0\xe7\xaf\xf7\x5f\x06\x5d\x16\x9d\xa2\x4b\x50\xa7\x98\xf2\xf9\x6e\x1c\xac\x96\x6c\x51\x58\xe1\xd1\x2d\xc2\x6f\xa5\xf3\x2d\x9a\xcc\x9a\x1c\x84\x06\x53\x12\xe2\xdb\xd6\x91\xda\x1b\x16\x28\xe8\xbf\x46\xcb\x1a\x0d\xbb\x9d\x86\xf9\x04\x32\xa1\x1c\xc6\x7d\x9d\xc7\x82\x4e\x23\xf5\xad\xb9\x21\xc9\xc6\x12\x84\x6d\x05\xa6\x48\x4c\x1a\x83\x81\xce\xd1\x1c\x03\xdd\xb0\xdb\x21\xbe\x13\xc8\x4a\xcd\xdb\xf6\x94\x59\x0c\x20\x9d\xf7\xe1\x6b\xb7\x43\x62\xcf\x44\xe1\x4b\x8b\x6c\x4f\xb4\xd6\x58\x07\x32\xcf\x31\x95\xc2\xa3\xaa\xba\x9d\xce\xad\xb0\x61\x01\xc6\xa0\xcc\x62\xb8\x40\x3f\xa5\xc7\x5e\xff\xb4\xdb\xe9\xc8\x0c\x7a\x61\xf5\xc9\x78\xcc\xd9\x27\x93\x1a\xd3\x20\xbe\xe3\x97\xd2\x0d\x33\x51\x2a\xdf\xec\x4b\x4c\x1d\x8b\xbe\xb4\x9a\xfe\xde\x07\x2d\x3e\x22\x18\xad\x2a\x48\x28\xcb\x88\x39\x85\xa7\xab\x9c\xc7\x3c\x1e\xce\x0d\x20\x13\x8e\x4c\x28\x33\x58\x21\x14\x16\x9f\x27\x4b\x24\xdf\xe9\x04\xa3\x96\xae\x72\xec\xd4\x31\xd0\x6e\x43\x53\x0c\xbd\x79\x57\xe6\x73\xb4\xbd\x3e\x7c\x07\xc7\x77\xd9\x71\x1f\xc6\x63\xfe\x53\xeb\x1e\x79\xa2\xbe\x24\xc5\x14\xf1\xa0\xcc\x7f\xed\xad\xd4\x8b\x70\xd6\xa8\xeb\x79\x06\x02\x34\xae\x20\x31\x9a\x41\x4d\x5e\x99\xa3\xd4\x0b\x48\x2c\x0a\x8f\xe9\x00\x44\x9a\x82\x37\x01\x79\x0d\xce\x36\xb7\x84\xef\xbe\xe3\xbd\xc6\x70\x74\x76\x35\x9d\xcc\xa6\x47\x2d\x25\xa4\xbe\xcc\xb2\xa8\x07\xf3\x0e\x0b\xc4\x9b\xde\x8b\xfe\xf0\x56\xa8\x12\x2f\xb3\xa0\x51\xa4\x9d\xea\x14\xc6\x91\xe7\xd9\x36\xcf\xcb\x0d\x1e\x62\x1a\x8d\x60\xe2\x1c\xe6\x73\x85\xbb\xb1\x17\x83\x93\xe3\xd4\x79\x4a\x4e\x04\xb4\xc4\xe4\x85\x42\x02\x50\xbd\x6b\xb4\x34\x6b\xdc\xf1\x55\x81\x27\x00\x00\xa6\x18\xf0\x0b\x82\x3d\xbf\xf0\xe6\x67\xbc\x63\x77\xd4\xd6\x22\x00\x4d\xd2\xd4\xa2\x73\xbd\x7e\x3f\x90\x4b\x5d\x94\xfe\x64\x83\x3c\xc7\xdc\xd8\x6a\xe8\x28\xf7\xf4\xf8\x68\x83\x70\xd2\x9a\x67\x21\xdc\xb9\x26\x9e\x08\xca\xb7\xc2\xf5\xd6\x4b\x67\xc6\xf9\x93\x7a\x89\x1e\xea\x35\xb6\x05\xb1\x1d\x1d\xdf\x1d\xed\x5a\xeb\xb8\xbf\x76\xfa\x8b\x1f\xfa\xc4\x72\x7f\xda\x40\xb9\xc9\x08\xc3\xa2\x74\xcb\x1e\x23\x67\xbd\xba\x8e\xfa\x31\x78\x5b\xe2\x5e\xa4\x33\x7a\x76\x91\xe3\x50\x65\x94\x36\xbc\x2d\x13\x46\xd0\x42\x70\x52\xe1\xa0\x16\x94\x64\x5d\x39\x67\x9b\x7b\x63\x1e\x04\xd2\xf5\xf4\xe2\xcd\xeb\xe9\xf5\xec\xea\xc3\xd9\xac\x0d\x27\x85\x99\x27\xa5\x36\xcf\xa0\x50\x2f\xfc\x92\xf5\x27\x71\x9b\xab\x9f\x89\xe7\xf9\x8b\x2f\xe1\x0d\x8c\xf7\x44\x77\xe7\x71\x0e\xf8\xfc\x85\x65\xdf\xef\x9a\x6f\x93\x34\x18\xf3\x6b\x00\x91\x29\xee\xdb\x39\x62\x4f\xd8\xe5\xe8\x97\x26\xe5\x3c\x98\x88\x90\x4a\x6b\x2b\xa6\x46\xe3\xc1\xc1\xd7\xab\xa3\x6f\x72\x71\x71\x04\x7f\xfc\x01\xad\xe7\xb3\xcb\xd7\xd3\xf6\xbb\xd7\xd3\x8b\xe9\xdb\xc9\x6c\xba\x4d\x7b\x3d\x9b\xcc\xce\xcf\xf8\x6d\x3f\x5a\x65\x34\x82\xeb\x1b\x59\x70\x42\xe5\x34\x65\xf2\x82\x3b\xc3\x46\x5f\x37\x00\xbf\x34\xd4\x73\xd9\x58\x2f\x32\xa1\x93\x3a\x8f\xbb\xda\x69\xde\x90\xcb\x4c\x1d\x2b\xbb\xa9\xa0\x0d\xd4\x7e\xe3\x46\xe9\xde\x5b\x8c\x9b\xa6\x3d\x6f\x6a\xbd\xd6\x06\x0d\x1e\xe1\x5c\xc7\x49\xa6\x77\xf8\x21\xe1\x6f\x70\x0c\x27\xf0\x22\x66\x92\x47\x52\xd5\x4b\x78\x46\xe2\xff\x44\xc2\x7a\xb5\x87\xf3\xaf\x99\xb6\xbc\x61\xe2\x9a\xdc\x9b\xff\x7e\x3a\x33\xa5\xbf\xcc\xb2\x13\xd8\x36\xe2\xf7\x3b\x46\x6c\xe8\x2f\x50\xef\xd2\xff\xdf\x0e\xfd\x3a\xf5\x11\xaa\x4c\x01\x4f\x76\x20\x12\x12\xcf\x93\xad\x38\x88\xc6\xe5\x6e\x86\xa5\xc1\xf8\x81\x64\xfb\x72\x13\xc3\x0f\x65\x8b\x7f\x2b\xd9\xee\xed\xca\xa8\xf7\xda\xec\xbb\x06\x60\xd1\x5b\x89\xb7\x34\x59\x1d\x39\x16\x49\xfd\xa9\x59\x09\x9d\xe0\x10\x3e\x62\x90\xa8\x11\x39\xb9\xc4\x7e\x96\xda\x11\x6e\xf1\xa8\x27\x8d\x93\x09\x43\x4c\x70\xdb\x69\x11\x72\x51\xd1\x64\x92\x95\xfa\xa6\x82\x85\x70\x90\x56\x5a\xe4\x32\x71\x41\x1e\xf7\xb2\x16\x17\xc2\xb2\x58\x8b\xbf\x97\xe8\x68\xcc\x21\x20\x8b\xc4\x97\x42\xa9\x0a\x16\x92\x66\x15\xe2\xee\xbd\x7c\x75\x7c\x0c\xce\xcb\x02\x75\x3a\x80\x1f\x5e\x8d\x7e\xf8\x1e\x6c\xa9\xb0\x3f\xec\xb6\xd2\x78\x73\xd4\xe8\x0d\x5a\x88\xe8\x79\x8d\x85\x5f\xf6\xfa\xf0\xd3\x03\xf5\xe0\x81\xe4\xbe\x97\x16\x9e\xc3\x8b\x2f\x43\xd2\x6b\xbc\x81\xdb\xe0\x49\x40\xe5\x30\x4a\xa3\xf9\xee\xf2\xf5\x65\xef\x46\x58\xa1\xc4\x1c\xfb\x27\x3c\xef\xb1\xad\x56\x22\x36\xfc\xe4\x14\x28\x94\x90\x1a\x44\x92\x98\x52\x7b\x32\x7c\xdd\xbb\xab\x8a\xf2\xfb\x91\xaf\xe5\xf1\x68\x24\x92\x04\x9d\xab\xd3\x3d\x7b\x8d\xd4\x11\x39\x71\x83\xd4\x4e\xa6\xd8\xf2\x0a\x65\x07\xc3\xa9\x39\x52\xd0\xe4\x58\x0b\xcc\x8d\xa3\x4d\xe6\x08\x2b\x4b\x73\x86\x93\x3a\xe1\x41\x3b\x45\xb2\xb6\x03\xa3\x41\x80\x32\x3c\xdd\x73\x8c\x83\xb0\x0b\x37\x0c\xf9\x9e\xb6\xa5\x9c\xa3\xcd\x6a\xb8\x09\xe4\x36\x54\xb9\xa3\xdf\x6a\x07\x34\xe0\x9d\x74\x9e\x1b\x48\xd2\x52\x3a\x08\x48\x96\x7a\x31\x80\xc2\x14\x9c\xa7\x0f\xec\x25\xaf\xa6\xbf\x4e\xaf\x9a\xe2\x7f\xb8\x13\xeb\x16\xff\x69\x33\x01\x81\xa5\xf1\xc2\x63\xfa\x74\x4f\xcf\xbe\x07\x50\xe3\x07\x00\x45\xf2\xd7\xb5\xf1\x7d\xeb\x38\x4a\x38\xbf\x76\xcc\x02\xc3\xf8\xd2\x56\xc0\x95\xca\xbb\xad\xdc\xbd\x9d\x1c\x4c\x51\x57\x08\x52\x8a\xd3\x0e\x25\xf6\x3d\x9d\x75\x34\xb8\x6f\x03\x4f\x40\xa0\x69\x25\x00\x5e\xaf\x3b\x34\x11\x72\x3e\x6b\x68\x4a\x4f\x4e\xa7\x2a\xbd\x4e\x71\x0b\xe1\x3e\x38\xf6\x6d\x4c\x72\x73\xb9\x38\xd7\xbe\x57\x2f\x9e\x6b\x78\x0e\xf5\x03\xa5\x6e\x78\xbe\x11\x2b\x7b\x72\x60\x27\x45\x85\x1e\x61\x2d\xe2\x14\xb6\x5e\x91\xa0\x70\x68\x36\x8d\x45\xbf\x5b\x82\x8f\xa3\x34\x32\xcb\x13\x8b\x7e\x88\xbf\x97\x42\xb9\xde\x71\xd3\x12\x84\x13\x78\xc3\x45\x6c\xdc\x94\xb1\xba\xce\x11\xcf\x46\x93\x11\x05\x06\xb6\x68\x8d\x9a\x2d\x9d\x87\xda\x94\xe2\xa3\x12\xa2\x88\x98\x1c\x1a\x8f\x45\xf8\xed\xeb\x32\x3b\x6d\x02\x78\xda\x94\xfd\x4c\x48\x55\x5a\x7c\x7a\x0a\x7b\x92\x8b\x2b\x6d\x26\x12\xf6\xa5\x43\xe0\x11\xd4\x81\x33\x39\x2e\xcd\x2a\x28\xb0\x2f\x45\xed\x82\xa3\xc1\xc1\x56\x91\xe0\xbb\x14\xe1\xa0\x74\x62\x81\x2d\x70\x34\x06\xaf\x1d\xb5\x77\x2e\xfe\xd3\xd0\x79\xd6\x3c\x7e\x03\x45\x61\x97\x6f\x42\xe3\x31\x6c\xec\xf5\xf2\x4e\x2f\x53\x13\x71\x47\xd3\x7a\xa8\x55\x0d\x0d\x47\x83\x9c\x7f\xc5\xef\xff\x19\xc7\x07\xcf\xc7\xdf\x43\x03\x6d\x9b\x36\x9c\x71\x93\x38\x9c\x74\xdd\xc4\x7c\x1b\x05\xcd\xea\x43\x00\x78\xa8\x3f\x22\xa8\xea\xdf\x30\xf1\x6b\xb8\x72\x4b\x43\x4f\x85\xc5\x5b\x69\x4a\xaa\x56\xf8\xbf\x34\xff\x35\xfd\xdd\x7d\xb7\x73\x1f\xef\xbc\xd8\x7d\xed\x4b\xaf\xd5\x32\xde\xd9\x86\xd6\xa8\x55\x2b\x0c\x17\xd2\x78\x15\x96\x85\xdb\xd4\x0e\xf3\x3f\x72\xf9\x15\xe3\xdd\x9b\x82\x6a\x7f\x2c\x45\xca\xa2\x48\xab\xa6\xfa\x0d\x42\xd7\x01\x4b\xa1\xd3\x38\x79\x88\x34\x95\x24\x8f\xb1\x48\x1a\x8a\x85\x90\xba\xbb\xd7\x8c\xdf\x2c\xb9\xfb\x90\xb1\xd3\xc8\xb6\xab\x66\x9c\x18\x69\xbc\x63\x8d\xbb\x07\x54\xc7\xad\x58\xda\xbe\xc7\x8b\x57\x81\x46\xbb\x32\xe7\xb6\x17\xc4\xad\x90\x4a\xd0\xa8\xc5\xed\x94\x4e\x21\x51\x28\x74\xb8\xbd\xc7\xcc\x9b\x5b\xb4\xae\x7b\x00\xc8\xff\x0c\xc6\xb7\x92\x63\xfd\x18\xcd\x71\x78\xcc\x1e\x1a\xb1\xe1\xf8\x6f\x94\xf0\x3e\xc2\xab\x65\xde\x10\x59\xd2\xf3\x87\x1d\xd4\xbe\x7b\x58\x48\x71\x83\x44\x34\x3f\xc1\x71\xab\x09\xff\xab\x04\xd9\x2e\xc4\x2e\x9a\x66\x2c\x1e\xde\x1b\x33\x00\x85\x82\x47\xa2\xfa\xb3\x4b\xdd\x7c\x3e\x36\xa1\xd5\xd1\x1b\xda\xb7\x9d\xf0\xe5\x4b\xac\x25\xd6\xd7\x1d\xa1\x8f\x9f\x23\x6a\x90\x1e\xad\xa0\xe1\x87\xd0\x15\xbf\x14\x90\x96\x8e\xc5\xb1\x5f\x24\x05\x5d\x14\x1c\xaf\xed\xa9\x3e\x4b\xbd\x18\x76\x3b\xe1\x7d\x2b\xde\x13\x7f\xb7\x8e\xf7\x50\x0c\x99\x33\x5e\x00\x34\xf3\x7f\xe2\xef\xb8\x67\xe4\x19\x79\xeb\x12\x80\xd6\xe8\x55\x18\xa0\xb7\x46\x7e\x66\x8c\x63\xff\xf6\xcd\x22\xad\xf1\xbb\x0d\x80\x33\xe9\x42\xb8\x20\x66\x2b\x24\xfc\xdd\x6e\x44\xd4\x0c\x14\x0c\x27\xfb\x19\x68\x69\x0f\xd3\xd6\x35\x04\x11\xf3\xab\xb0\x1a\x0a\xfb\x49\x7b\x35\xbc\x8a\x07\x95\x79\xcb\x36\x32\x67\xdb\xdc\x9f\xee\x4f\x72\xc7\x35\x1e\xf7\x27\x33\xb2\x79\x03\xd8\x07\x58\xdb\x83\xc5\x2e\xc9\x63\xa9\x92\xa5\xd7\x99\xed\x01\x56\x96\xde\x6a\x3d\xfc\xdd\xe1\x22\x1b\xe2\xb6\x8a\x1b\x34\xfb\x84\xc4\x3c\x13\xe9\x82\x65\x6b\x01\x01\xd5\x41\x57\x46\xb4\xfc\x07\x46\x89\xed\xf8\xa9\x97\xc0\x62\xf8\xb0\xc0\x0d\x29\x85\x8f\x99\x73\xf1\x2f\x1d\xcd\x8c\xeb\xb8\x48\xd1\x49\x8b\x29\x64\x12\x55\x0a\x26\x45\xcb\x13\xe9\x6f\xce\xe8\xf0\x09\x09\xad\x24\x89\xe1\x53\x59\xf8\x6a\xcd\x1f\xf0\xb4\x4c\xd0\x57\x90\xa1\xe0\x6f\x41\xde\x40\x21\x9c\x83\x1c\x05\xcd\xa0\x59\xa9\x54\x05\xc6\xa6\x48\xc2\x9b\xa1\x8c\x42\xd2\x40\xe9\xd0\x3a\x58\x2d\x4d\x2c\x93\xdc\xa5\x15\xd4\x74\x4a\x3f\x88\xf7\x2e\xd2\x15\x4a\x54\x20\x3d\x95\xe4\x78\xa8\x76\x94\x36\x1f\x60\xf8\x2b\x8e\xa1\xaa\xbb\x1b\xa2\xf5\x5c\xb7\x19\xa3\xfc\x9a\x9e\x36\xa3\x33\xce\x35\x9b\x71\xb9\xbe\x91\xda\x0c\xc2\xba\x6c\x6c\x46\x5a\xbb\x08\x6d\x86\x13\xaf\xf0\xd3\x66\x20\xb5\xfa\x65\x5e\x60\x70\x34\x0c\xfc\xb4\x15\x5a\xac\x65\x8c\xad\xf0\xb9\xb1\x21\xe7\xa7\x41\x04\x0c\x79\xb1\x47\xc6\xb9\xc1\x8a\x32\x71\xb0\x51\xab\xac\x84\x17\x9f\x6f\xb0\xfa\xb2\xbf\x8a\x44\x38\xb6\xe8\x9a\xb2\x51\x43\x3a\xac\x3d\x12\xc8\x8d\x16\x72\x7c\x7c\x0a\xf2\xc7\x36\x43\x5d\xf9\x40\x3e\x7b\x56\xef\xd9\x5e\xff\x2c\xbf\xd4\xd1\xd9\x20\x7e\x6b\xbd\xbf\xa1\x51\x8c\x91\x40\x43\x41\xd1\xbd\xef\xfe\x33\x00\x00\xff\xff\xb5\x25\x8b\x4d\x94\x21\x00\x00")
+var _call_tracerJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xd4\x59\x5f\x6f\x1b\xb7\xb2\x7f\x96\x3e\xc5\x24\x0f\xb5\x84\x28\x92\x92\xf4\xf6\x02\x72\xd5\x0b\x5d\x47\x49\x0d\xb8\x71\x60\x2b\x0d\x82\x20\x0f\xd4\xee\xac\xc4\x9a\x22\xb7\x24\xd7\xf2\x9e\xd4\xdf\xfd\x60\x86\xdc\xd5\xea\x8f\x1d\xb5\x07\xe7\xa0\xc7\x0f\x86\x76\x39\x33\x1c\xce\xfc\xe6\x1f\x77\x30\x80\x33\x93\x97\x56\x2e\x96\x1e\x5e\x0e\x5f\xfc\x2f\xcc\x96\x08\xe2\xf7\x42\x24\x4b\x21\x35\x4c\x0a\xbf\x34\xd6\xb5\x07\x03\x98\x2d\xa5\x83\x4c\x2a\x04\xe9\x20\x17\xd6\x83\xc9\xc0\x6f\x51\x2b\x39\xb7\xc2\x96\xfd\xf6\x60\x10\x38\x0e\x2c\x12\x77\x66\x11\xc1\x99\xcc\xaf\x85\xc5\x11\x94\xa6\x80\x44\x68\xb0\x98\x4a\xe7\xad\x9c\x17\x1e\x41\x7a\x10\x3a\x1d\x18\x0b\x2b\x93\xca\xac\x24\x81\xd2\x43\xa1\x53\xb4\xbc\xad\x47\xbb\x72\x95\x0e\x6f\xdf\x7d\x80\x0b\x74\x0e\x2d\xbc\x45\x8d\x56\x28\x78\x5f\xcc\x95\x4c\xe0\x42\x26\xa8\x1d\x82\x70\x90\xd3\x1b\xb7\xc4\x14\xe6\x2c\x8e\x18\xdf\x90\x2a\xd7\x51\x15\x78\x63\x0a\x9d\x0a\x2f\x8d\xee\x01\x4a\xbf\x44\x0b\xb7\x68\x9d\x34\x1a\x5e\x55\x5b\x45\x81\x3d\x30\x96\x84\x74\x84\xa7\x03\x58\x30\x39\xf1\x75\x41\xe8\x12\x94\xf0\x1b\xd6\x6f\x9a\x63\x73\xea\x14\xa4\xe6\x4d\x96\x26\x47\xf0\x4b\xe1\xe9\xcc\x6b\xa9\x14\xcc\x11\x0a\x87\x59\xa1\x7a\x24\x6b\x5e\x78\xf8\x78\x3e\xfb\xf9\xf2\xc3\x0c\x26\xef\x3e\xc1\xc7\xc9\xd5\xd5\xe4\xdd\xec\xd3\x29\xac\xa5\x5f\x9a\xc2\x03\xde\x62\x10\x25\x57\xb9\x92\x98\xc2\x5a\x58\x2b\xb4\x2f\xc1\x64\x24\xe1\x97\xe9\xd5\xd9\xcf\x93\x77\xb3\xc9\xff\x9f\x5f\x9c\xcf\x3e\x81\xb1\xf0\xe6\x7c\xf6\x6e\x7a\x7d\x0d\x6f\x2e\xaf\x60\x02\xef\x27\x57\xb3\xf3\xb3\x0f\x17\x93\x2b\x78\xff\xe1\xea\xfd\xe5\xf5\xb4\x0f\xd7\x48\x5a\x21\xf1\x7f\xdb\xe2\x19\xfb\xce\x22\xa4\xe8\x85\x54\xae\xb2\xc3\x27\x53\x80\x5b\x9a\x42\xa5\xb0\x14\xb7\x08\x16\x13\x94\xb7\x98\x82\x80\xc4\xe4\xe5\xd1\x2e\x25\x59\x42\x19\xbd\xe0\x33\x3f\x00\x45\x38\xcf\x40\x1b\xdf\x03\x87\x08\x3f\x2e\xbd\xcf\x47\x83\xc1\x7a\xbd\xee\x2f\x74\xd1\x37\x76\x31\x50\x41\x98\x1b\xfc\xd4\x6f\x93\xc4\x44\x28\x35\xb3\x22\x41\x4b\xae\x11\x90\x15\x64\x7c\x65\xd6\x1a\xbc\x15\xda\x89\x84\xdc\x0c\x3e\x90\xb0\x8b\xf0\x8e\x9e\xbc\x23\xc0\x82\xc5\xdc\x58\xfa\xad\x54\x85\x31\xa9\x3d\x5a\x2d\x14\xcb\x76\xb0\x12\x29\xc2\xbc\x04\xd1\x14\xd8\x6b\x1e\x85\x20\x14\x9c\x0d\x52\x67\xc6\xae\x18\x92\xfd\xf6\xd7\x76\x2b\x6a\xe8\xbc\x48\x6e\x48\x41\x92\x9f\x14\xd6\xa2\xf6\x64\xc8\xc2\x3a\x79\x8b\x4c\x02\x81\x26\x5a\x73\xfa\xeb\x2f\x80\x77\x98\x14\x41\x52\xab\x16\x32\x82\xcf\x5f\xef\xbf\xf4\xda\x2c\x3a\x45\x97\xa0\x4e\x31\xe5\xf3\xdd\x38\x58\x2f\x91\xa3\x60\x8d\x27\xb7\x08\xbf\x15\xce\x37\x68\x32\x6b\x56\x20\x34\x98\x82\xd0\xde\xb4\x8e\xd4\xde\xb0\x40\x41\xbf\x35\x5a\xd6\xa8\xdf\x6e\xd5\xcc\x23\xc8\x84\x72\x18\xf7\x75\x1e\x73\x3a\x8d\xd4\xb7\xe6\x86\x24\x1b\x4b\x00\xb6\x25\x98\x3c\x31\x69\x0c\x05\x3a\x47\x7d\x0c\x74\xfd\x76\x8b\xf8\x46\x90\x15\x9a\xb7\xed\x28\xb3\xe8\x41\x3a\xef\xc2\xd7\x76\x8b\xc4\x9e\x89\xdc\x17\x16\xd9\x9e\x68\xad\xb1\x0e\xe4\x6a\x85\xa9\x14\x1e\x55\xd9\x6e\xb5\x6e\x85\x0d\x0b\x30\x06\x65\x16\xfd\x05\xfa\x29\x3d\x76\xba\xa7\xed\x56\x4b\x66\xd0\x09\xab\x4f\xc6\x63\xce\x3c\x99\xd4\x98\x06\xf1\x2d\xbf\x94\xae\x9f\x89\x42\xf9\x7a\x5f\x62\x6a\x59\xf4\x85\xd5\xf4\xf3\x3e\x68\xf1\x11\xc1\x68\x55\x42\x22\x48\x95\x39\x05\xa7\x2b\x9d\xc7\x55\x3c\x9c\xeb\x41\x26\x1c\x99\x50\x66\xb0\x46\xc8\x2d\x3e\x4f\x96\x48\xbe\xd3\x09\x46\x2d\x5d\xe9\xd8\xa9\x63\xa0\xdd\xfa\x26\xef\x7b\xf3\xae\x58\xcd\xd1\x76\xba\xf0\x1d\x0c\xef\xb2\x61\x17\xc6\x63\xfe\x51\xe9\x1e\x79\xa2\xbe\x24\xc5\xe4\xf1\xa0\xcc\x7f\xed\xad\xd4\x8b\x4e\xb7\xa1\xeb\x79\x06\x02\x34\xae\x21\x31\x9a\x41\x4d\x5e\x99\xa3\xd4\x0b\x48\x2c\x0a\x8f\x69\x0f\x44\x9a\x82\x37\x01\x79\x35\xce\xb6\xb7\x84\xef\xbe\xe3\xbd\xc6\x70\x72\x76\x35\x9d\xcc\xa6\x27\x0d\x25\xa4\xbe\xcc\xb2\xa8\x07\xf3\xf6\x73\xc4\x9b\xce\x8b\x6e\xff\x56\xa8\x02\x2f\xb3\xa0\x51\xa4\x9d\xea\x14\xc6\x91\xe7\xd9\x2e\xcf\xcb\x2d\x1e\x62\x1a\x0c\x60\xe2\x1c\xae\xe6\x0a\xf7\x63\x2f\x06\x27\xc7\xa9\xf3\x94\x9a\x08\x68\x89\x59\xe5\x0a\x09\x40\xd5\xae\xd1\xd2\xac\x71\xcb\x97\x39\x8e\x00\x00\x4c\xde\xe3\x17\x04\x7b\x7e\xe1\xcd\xcf\x78\xc7\xee\xa8\xac\x45\x00\x9a\xa4\xa9\x45\xe7\x3a\xdd\x6e\x20\x97\x3a\x2f\xfc\x68\x8b\x7c\x85\x2b\x63\xcb\xbe\xa3\xdc\xd3\xe1\xa3\xf5\xc2\x49\x2b\x9e\x85\x70\xe7\x9a\x78\x22\x28\xdf\x0a\xd7\xd9\x2c\x9d\x19\xe7\x47\xd5\x12\x3d\x54\x6b\x6c\x0b\x62\x3b\x19\xde\x9d\xec\x5b\x6b\xd8\xdd\x38\xfd\xc5\x0f\x5d\x62\xb9\x3f\xad\xa1\x5c\x67\x84\x7e\x5e\xb8\x65\x87\x1e\xbb\x9b\xd5\x4d\xd4\x8f\xc1\xdb\x02\x0f\x22\x9d\xd1\xb3\x8f\x1c\x87\x2a\xa3\xb4\xe1\x6d\x91\x30\x82\x16\x82\x93\x0a\x07\xb5\x70\x20\xc0\x15\x73\xb6\xb9\x37\xe6\x41\x20\x5d\x4f\x2f\xde\xbc\x9e\x5e\xcf\xae\x3e\x9c\xcd\x9a\x70\x52\x98\x79\x52\x6a\xfb\x0c\x0a\xf5\xc2\x2f\x59\x7f\x12\xb7\xbd\xfa\x99\x78\x9e\xbf\xf8\x12\xde\xc0\xf8\x40\x74\xb7\x1e\xe7\x80\xcf\x5f\x58\xf6\x7d\xfb\x1b\xa4\xc1\x98\x5f\x03\x88\x4c\x7e\xdf\xcc\x11\x07\xc2\x6e\x85\x7e\x69\x52\xce\x83\x89\x08\xa9\xb4\xb2\x62\x6a\x34\x1e\x1d\x7c\x9d\x2a\xfa\x26\x17\x17\x27\xf0\xc7\x1f\xd0\x78\x3e\xbb\x7c\x3d\x6d\xbe\x7b\x3d\xbd\x98\xbe\x9d\xcc\xa6\xbb\xb4\xd7\xb3\xc9\xec\xfc\x8c\xdf\x76\xa3\x55\x06\x03\xb8\xbe\x91\x39\x27\x54\x4e\x53\x66\x95\x73\x47\x58\xeb\xeb\x7a\xe0\x97\xc6\x21\x08\x1b\xeb\x45\x26\x74\x52\xe5\x71\x57\x39\xcd\x1b\x72\x99\xa9\x62\x65\x3f\x15\x34\x81\xda\xad\xdd\x28\xdd\x7b\x8b\x71\xd3\xb4\xe3\x4d\xa5\xd7\xc6\xa0\xad\xfb\x6a\x0b\xc3\x49\xa6\x73\xfc\x21\xe1\xff\x60\x08\x23\x78\x11\x33\xc9\x23\xa9\xea\x25\x3c\x23\xf1\x7f\x21\x61\xbd\x3a\xc0\xf9\xf7\x4c\x5b\xde\x30\x71\x45\xee\xcd\x7f\x3e\x9d\x99\xc2\x5f\x66\xd9\x08\x76\x8d\xf8\xfd\x9e\x11\x6b\xfa\x0b\xd4\xfb\xf4\xff\xb3\x47\xbf\x49\x7d\x84\x2a\x93\xc3\x93\x3d\x88\x84\xc4\xf3\x64\x27\x0e\xa2\x71\xb9\x9b\x61\x69\x30\x7e\x20\xd9\xbe\xdc\xc6\xf0\x43\xd9\xe2\x5f\x4a\xb6\x07\xbb\x32\xea\xbd\xb6\xfb\xae\x1e\x58\xf4\x56\xe2\x2d\x82\xf4\x27\x8e\x45\x82\x50\xca\xac\x85\x4e\xb0\x0f\x1f\x31\x48\xd4\x88\x9c\x5c\x62\x3f\x0b\x32\x0b\x2d\x1e\xf5\xa4\x71\x2e\x21\x71\x20\xb8\xed\xb4\x08\x2b\x51\xd2\x5c\x92\x15\xfa\xa6\x84\x85\x70\x90\x96\x5a\xac\x64\xe2\x82\x3c\xe2\x03\x8b\x0b\x61\x59\xac\xc5\xdf\x0b\x74\x1e\x53\x06\xb2\x48\x7c\x21\x94\x2a\x61\x21\x69\x52\x21\xee\xce\xcb\x57\xc3\x21\x38\x2f\x73\xd4\x69\x0f\x7e\x78\x35\xf8\xe1\x7b\xb0\x85\xc2\x6e\xbf\xdd\x48\xe3\xf5\x51\xa3\x37\x68\x21\xa2\xe7\x35\xe6\x7e\xd9\xe9\xc2\x4f\x0f\xd4\x83\x07\x92\xfb\x41\x5a\x78\x0e\x2f\xbe\xf4\x49\xaf\xf1\x16\x6e\x83\x27\x01\x95\xc3\x28\x8d\x66\xbb\xcb\xd7\x97\x9d\x1b\x61\x85\x12\x73\xec\x8e\x78\xd6\x63\x5b\xad\x45\x6c\xf8\xc9\x29\x90\x2b\x21\x35\x88\x24\x31\x85\xf6\x64\xf8\xaa\x77\x57\x25\xe5\xf7\x13\x5f\xc9\xe3\xc1\x48\x24\x09\x3a\x57\xa5\x7b\xf6\x1a\xa9\x23\x56\xc4\x0d\x52\x3b\x99\x62\xc3\x2b\x94\x1d\x0c\xa7\xe6\x48\x41\x73\x63\x25\x70\x65\x1c\x6d\x32\x47\x58\x5b\x9a\x33\x9c\xd4\x09\x0f\xd9\x29\x92\xb5\x1d\x18\x0d\x02\x94\xe1\xa9\x9e\x63\x1c\x84\x5d\xb8\x7e\xc8\xf7\xb4\x2d\xe5\x1c\x6d\xd6\xfd\x6d\x20\x37\xa1\xca\x1d\xfd\x4e\x3b\xa0\x01\xef\xa4\xf3\xdc\x40\x92\x96\xd2\x41\x40\xb2\xd4\x8b\x1e\xe4\x26\xe7\x3c\x7d\x64\x2f\x79\x35\xfd\x75\x7a\x55\x17\xff\xe3\x9d\x58\xb5\xf8\x4f\xeb\x09\x08\x2c\x8d\x17\x1e\xd3\xa7\x07\x7a\xf6\x03\x80\x1a\x8f\xe1\x41\xf9\x9b\xda\xf8\xbe\x71\x1c\x25\x9c\xdf\x38\x66\x81\x61\x7c\x69\x2a\xe0\x0a\xe5\xdd\x4e\xee\xde\x4d\x0e\x26\xaf\x2a\x04\x29\x45\x0b\x7d\x4a\xec\x07\x3a\xeb\x68\x70\xdf\x04\x9e\x80\x40\xd3\x48\x00\xbc\x5e\x75\x68\x22\xe4\x7c\xd6\xd0\x14\x9e\x9c\x4e\x55\x7a\x93\xe2\x16\xc2\x7d\x70\x98\x6e\x92\xdc\x5c\x2e\xce\xb5\xef\x54\x8b\xe7\x1a\x9e\x43\xf5\x40\xa9\x1b\x9e\x6f\xc5\xca\x81\x1c\xd8\x4a\x51\xa1\x47\xd8\x88\x38\x85\x9d\x57\x24\x28\x1c\x9a\x4d\x63\xd1\xef\x97\xe0\x61\x94\x46\x66\x79\x62\xd1\xf7\xf1\xf7\x42\x28\xd7\x19\xd6\x2d\x41\x38\x81\x37\x40\x7f\xe3\xba\x8c\x55\x75\x8e\x78\xb6\x9a\x8c\xee\x69\x83\x2d\x5a\xa3\x62\x4b\xe7\xa1\x36\xa5\xf8\xa8\x84\x28\x22\x26\x87\xda\x63\x11\x7e\x87\xba\xcc\x56\x93\x00\x9e\xd6\x65\x3f\x13\x52\x15\x16\x9f\x9e\xc2\x81\xe4\xe2\x0a\x9b\x89\x84\x7d\xe9\x10\x78\x04\x75\xe0\xcc\x0a\x97\x66\x1d\x14\x38\x94\xa2\xf6\xc1\x51\xe3\x60\xa7\x48\x10\x19\x45\x7c\xe1\xc4\x02\x1b\xe0\xa8\x0d\x5e\x39\x0a\x9e\x3c\x7c\xa6\x3f\x0f\x9d\x67\xf5\xe3\x37\x50\xd4\x6e\x1d\x05\x8d\xc7\xb0\x71\xd0\xcb\x7b\xbd\x4c\x45\xc4\x1d\x4d\xe3\xa1\x52\x35\x34\x1c\x35\x72\xfe\x8c\xdf\xff\x3d\x8e\x0f\x9e\x8f\xff\x8f\x0d\xb4\x5d\xda\x70\xc6\x6d\xe2\x70\xd2\x4d\x13\xf3\x6d\x14\xd4\xab\x0f\x01\xe0\xa1\xfe\x88\xa0\xaa\x7f\xc3\xc4\x6f\xe0\xca\x2d\x0d\x3d\xe5\x16\x6f\xa5\x29\xa8\x5a\xe1\x7f\xd3\xfc\x57\xf7\x77\xf7\xed\xd6\x7d\xbc\xf3\x62\xf7\x35\x2f\xbd\xd6\xcb\x78\x63\x1b\x5a\xa3\x46\xad\x30\x5c\x48\xe3\x55\x58\x16\xee\x52\x5b\xcc\xff\xc8\xe5\x57\x8c\x77\x6f\x72\xaa\xfd\xb1\x14\x29\x8b\x22\x2d\xeb\xea\xd7\x0b\x5d\x07\x2c\x85\x4e\xe3\xe4\x21\xd2\x54\x92\x3c\xa1\xa2\x86\x62\x21\xa4\x6e\x1f\x34\xe3\x37\x4b\xee\x21\x64\xec\x35\xb2\xcd\xaa\x19\x27\x46\x1a\xef\x58\xe3\xf6\x11\xd5\x71\x27\x96\x76\xef\xf1\xe2\x55\xa0\xd1\xae\x58\x71\xdb\x0b\xe2\x56\x48\x25\x68\xd4\xe2\x76\x4a\xa7\x90\x28\x14\x3a\xdc\xdc\x63\xe6\x0d\x5d\xdc\xb7\x8f\x00\xf9\x5f\xc1\xf8\x4e\x72\xac\x1e\xa3\x39\x8e\x8f\xd9\x63\x23\x36\x1c\xff\x8d\x12\xde\x47\x78\x35\xcc\x1b\x22\x4b\x7a\xfe\xa0\x83\xda\xb7\x8f\x0b\x29\x6e\x90\x88\xe6\x27\x18\x36\x9a\xf0\xbf\x4b\x90\xed\x43\xec\xa2\x6e\xc6\xe2\xe1\xbd\x31\x3d\x50\x28\x78\x24\xaa\x3e\xba\x54\xcd\xe7\x63\x13\x5a\x15\xbd\xa1\x7d\xdb\x0b\x5f\xda\x82\x44\xc5\xeb\x8e\xd0\xc7\xcf\x11\x35\x48\x8f\x56\x78\x4c\x81\xd0\x15\xbf\x14\x90\x96\x8e\xc5\xb1\x5f\x24\x05\x5d\x14\x1c\xaf\xed\xa9\x3e\x4b\xbd\xe8\xb7\x5b\xe1\x7d\x23\xde\x13\x7f\xb7\x89\xf7\x50\x0c\x99\x33\x5e\x00\xd4\xf3\x7f\xe2\xef\xb8\x67\xe4\x19\x79\xe7\x12\x80\xd6\xe8\x55\x18\xa0\x77\x46\x7e\x66\x8c\x63\xff\xee\xcd\x22\xad\xf1\xbb\x2d\x80\x33\xe9\x42\xb8\x20\x66\x27\x24\xfc\xdd\x7e\x44\x54\x0c\x14\x0c\xa3\xc3\x0c\xb4\x74\x80\x69\xe7\x1a\x82\x88\xf9\x55\x58\x0d\x85\x7d\xd4\x5c\x0d\xaf\x78\xf9\xfe\xf4\x70\x3a\x1b\x56\xc8\x3b\x9c\xb6\xc8\xba\x35\x34\x1f\x60\x6d\x8e\x10\xfb\x24\x8f\x25\x45\x96\x5e\xe5\xb0\x07\x58\x4f\xdb\xdb\x4d\x86\xbf\x3b\x5e\x64\x4d\xdc\x54\x71\x8b\xe6\x90\x90\x98\x51\x22\x5d\xb0\x61\x25\x20\xe0\x37\xe8\xca\xd8\x95\xff\xc0\x28\xb1\x19\x29\xd5\x12\x58\x0c\x9f\x10\xb8\xf5\x14\x4a\x81\x99\x73\x99\x2f\x1c\x4d\x87\x9b\x08\x48\xd1\x49\x4b\x1f\x81\x24\xaa\x14\x4c\x8a\x96\x67\xcf\xdf\x9c\xd1\xe1\x63\x11\x5a\x49\x12\xc3\x47\xb1\xf0\x5d\x9a\x3f\xd5\x69\x99\xa0\x2f\x21\x43\xc1\x5f\x7d\xbc\x81\x5c\x38\x07\x2b\x14\x34\x6d\xd2\x87\xbc\x12\x8c\x4d\x91\x84\xd7\xe3\x17\x05\x9f\x81\xc2\xa1\xa5\xaf\x5d\x26\x16\x44\xee\xc7\x72\x8b\x1e\xa4\xef\xc5\x1b\x16\xe9\x72\x25\x4a\x90\x9e\x8a\x6f\x3c\x54\x33\x1e\xeb\x4f\x2d\xfc\xbd\xc6\x50\x7d\xdd\x0f\xc6\x6a\x82\xdb\x8e\x46\x7e\x4d\x4f\xdb\x71\x18\xa8\xcd\x76\x04\x6e\xee\x9e\xb6\xc3\xad\x2a\x10\xdb\x31\xd5\x2c\x37\xdb\x81\xc3\x2b\xfc\xb4\x1d\x32\x8d\xce\x98\x17\x18\x1c\x35\x03\x3f\xf5\xaa\x42\xe6\xea\xf7\xfc\xd4\x8b\xc8\x20\x77\x75\xc8\x0a\x37\x58\x82\xd4\xd1\x18\x8d\x4a\x11\x5e\x7c\xbe\xc1\xf2\xcb\xe1\xc2\x10\x71\xd7\xa0\xab\x2b\x41\x85\xdd\xb0\xf6\x48\xc4\xd6\x5a\xc8\xf1\xf0\x14\xe4\x8f\x4d\x86\xaa\x98\x81\x7c\xf6\xac\xda\xb3\xb9\xfe\x59\x7e\xa9\xc2\xb0\x86\xf6\xce\x7a\x77\x4b\xa3\x18\x0c\x81\x86\xd0\xdf\xbe\x6f\xff\x73\x00\x75\x13\x8d\xf3\x5d\x21\x00\x00")
 
 func call_tracerJsBytes() ([]byte, error) {
 	return bindataRead(
@@ -172,7 +172,7 @@ func opcount_tracerJs() (*asset, error) {
 	return a, nil
 }
 
-var _prestate_tracerJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x57\xdd\x6f\x1b\xb9\x11\x7f\xde\xfd\x2b\xa6\x7e\x91\x84\x53\x56\xce\x15\xb8\x02\x72\x5d\x60\xa3\x28\x89\x00\x9d\x6d\x48\x4a\x5d\xf7\x70\x0f\x5c\x72\x76\xc5\x13\x45\x2e\x48\xae\x3e\x10\xf8\x7f\x2f\x86\xfb\x21\xcb\x67\x27\x6e\xeb\x27\x2f\x39\xfc\xcd\xf7\x6f\x46\xa3\x11\x4c\x4c\x79\xb4\xb2\x58\x7b\xf8\xf9\xf2\xfd\xdf\x60\xb5\x46\x28\xcc\x3b\xf4\x6b\xb4\x58\x6d\x21\xad\xfc\xda\x58\x17\x8f\x46\xb0\x5a\x4b\x07\xb9\x54\x08\xd2\x41\xc9\xac\x07\x93\x83\x7f\x26\xaf\x64\x66\x99\x3d\x26\xf1\x68\x54\xbf\x79\xf1\x9a\x10\x72\x8b\x08\xce\xe4\x7e\xcf\x2c\x8e\xe1\x68\x2a\xe0\x4c\x83\x45\x21\x9d\xb7\x32\xab\x3c\x82\xf4\xc0\xb4\x18\x19\x0b\x5b\x23\x64\x7e\x24\x48\xe9\xa1\xd2\x02\x6d\x50\xed\xd1\x6e\x5d\x6b\xc7\xe7\x9b\xaf\x30\x47\xe7\xd0\xc2\x67\xd4\x68\x99\x82\xbb\x2a\x53\x92\xc3\x5c\x72\xd4\x0e\x81\x39\x28\xe9\xc4\xad\x51\x40\x16\xe0\xe8\xe1\x27\x32\x65\xd9\x98\x02\x9f\x4c\xa5\x05\xf3\xd2\xe8\x21\xa0\x24\xcb\x61\x87\xd6\x49\xa3\xe1\xaf\xad\xaa\x06\x70\x08\xc6\x12\x48\x9f\x79\x72\xc0\x82\x29\xe9\xdd\x00\x98\x3e\x82\x62\xfe\xf4\xf4\x0d\x01\x39\xf9\x2d\x40\xea\xa0\x66\x6d\x4a\x04\xbf\x66\x9e\xbc\xde\x4b\xa5\x20\x43\xa8\x1c\xe6\x95\x1a\x12\x5a\x56\x79\xb8\x9f\xad\xbe\xdc\x7e\x5d\x41\x7a\xf3\x00\xf7\xe9\x62\x91\xde\xac\x1e\xae\x60\x2f\xfd\xda\x54\x1e\x70\x87\x35\x94\xdc\x96\x4a\xa2\x80\x3d\xb3\x96\x69\x7f\x04\x93\x13\xc2\xaf\xd3\xc5\xe4\x4b\x7a\xb3\x4a\x3f\xcc\xe6\xb3\xd5\x03\x18\x0b\x9f\x66\xab\x9b\xe9\x72\x09\x9f\x6e\x17\x90\xc2\x5d\xba\x58\xcd\x26\x5f\xe7\xe9\x02\xee\xbe\x2e\xee\x6e\x97\xd3\x04\x96\x48\x56\x21\xbd\xff\x71\xcc\xf3\x90\x3d\x8b\x20\xd0\x33\xa9\x5c\x1b\x89\x07\x53\x81\x5b\x9b\x4a\x09\x58\xb3\x1d\x82\x45\x8e\x72\x87\x02\x18\x70\x53\x1e\xdf\x9c\x54\xc2\x62\xca\xe8\x22\xf8\xfc\x6a\x41\xc2\x2c\x07\x6d\xfc\x10\x1c\x22\xfc\x7d\xed\x7d\x39\x1e\x8d\xf6\xfb\x7d\x52\xe8\x2a\x31\xb6\x18\xa9\x1a\xce\x8d\xfe\x91\xc4\x84\x59\x5a\x74\x9e\x79\x5c\x59\xc6\xd1\x82\xa9\x7c\x59\x79\x07\xae\xca\x73\xc9\x25\x6a\x0f\x52\xe7\xc6\x6e\x43\xa5\x80\x37\xc0\x2d\x32\x8f\xc0\x40\x19\xce\x14\xe0\x01\x79\x15\xee\xea\x48\x87\x72\xb5\x4c\x3b\xc6\xc3\x69\x6e\xcd\x96\x7c\xad\x9c\xa7\x7f\x9c\xc3\x6d\xa6\x50\x40\x81\x1a\x9d\x74\x90\x29\xc3\x37\x49\xfc\x2d\x8e\x9e\x18\x43\x75\x12\x3c\x6c\x84\x42\x6d\xec\xb1\x67\x11\xb2\x4a\x2a\x21\x75\x91\xc4\x51\x2b\x3d\x06\x5d\x29\x35\x8c\x03\x84\x32\x66\x53\x95\x29\xe7\xa6\x0a\xb6\xff\x81\xdc\xd7\x60\xae\x44\x2e\x73\x2a\x0e\xd6\xdd\x7a\x13\xae\x3a\xbd\x26\x23\xf9\x24\x8e\xce\x60\xc6\x90\x57\x3a\xb8\xd3\x67\x42\xd8\x21\x88\x6c\xf0\x2d\x8e\xa2\x1d\xb3\x84\x05\xd7\xe0\xcd\x17\x3c\x84\xcb\xc1\x55\x1c\x45\x32\x87\xbe\x5f\x4b\x97\xb4\xc0\xbf\x31\xce\x7f\x87\xeb\xeb\xeb\xd0\xd4\xb9\xd4\x28\x06\x40\x10\xd1\x4b\x62\xf5\x4d\x94\x31\xc5\x34\xc7\x31\xf4\x2e\x0f\x3d\xf8\x09\x44\x96\x14\xe8\x3f\xd4\xa7\xb5\xb2\xc4\x9b\xa5\xb7\x52\x17\xfd\xf7\xbf\x0c\x86\xe1\x95\x36\xe1\x0d\x34\xe2\x37\xa6\x13\xae\xef\xb9\x11\xe1\xba\xb1\xb9\x96\x9a\x18\xd1\x08\x35\x52\xce\x1b\xcb\x0a\x1c\xc3\xb7\x47\xfa\x7e\x24\xaf\x1e\xe3\xe8\xf1\x2c\xca\xcb\x5a\xe8\x95\x28\x37\x10\x80\xda\xdb\xae\xce\x0b\x49\x9d\xfa\x34\x01\x01\xef\x7b\x49\x58\xb6\xa6\x3c\x4b\xc2\x06\x8f\x3f\xce\x04\x5d\x48\x71\xe8\x2e\x36\x78\x1c\x5c\xc5\xaf\xa6\x28\x69\x8c\xfe\x4d\x8a\xc3\xcb\xf9\x22\xc0\x1d\x53\x1d\x60\x1d\xbf\x25\x21\x9c\xec\x1a\x04\xdd\x41\x07\xc9\xfe\xe5\x1a\x2e\x2e\x0f\x97\xff\xe7\xdf\x45\x63\xc1\x0b\x25\xf3\xcc\xec\x37\x98\xf6\x78\x9e\x4f\x8b\xae\x52\x9e\xda\x4e\xea\x9d\xd9\x10\x81\xae\x29\x4f\x4a\x85\xd4\x98\x92\xaa\xc6\xd5\x0c\x96\x21\x6a\x90\x1e\x2d\x23\x0a\x37\x3b\xb4\x34\xbd\xc0\xa2\xaf\xac\x76\x5d\x3a\x73\xa9\x99\x6a\x81\x9b\xec\x7b\xcb\x78\xdd\xbb\xf5\xf9\x93\x9c\x72\x7f\x08\xd9\x0c\x3e\x8e\x46\x90\x7a\x20\x3f\xa1\x34\x52\xfb\x21\xec\x11\x34\xa2\x20\x02\x12\x28\x2a\xee\x03\x5e\x6f\xc7\x54\x85\xbd\x9a\x64\x88\xaa\xc3\x53\x53\xd1\x44\x7a\x42\x42\xc3\x60\xe0\xd6\xec\xc2\xa8\xcd\x18\xdf\x40\xd3\xf8\xc6\xca\x42\xea\xb8\x89\xe9\x59\xd3\x93\x45\x09\x01\x07\xb3\x42\xcd\x50\xee\xe9\xe4\x43\xc8\x7f\x26\x8b\x99\xf6\xcf\x8a\xa8\x8e\x7c\xfb\x74\xf0\x7b\xd2\x34\x71\xe2\x88\x78\xfb\x3f\x0f\x86\xf0\xfe\x97\xae\x32\xbd\x21\x28\xf8\x31\x98\x37\xaf\x43\xc5\xcf\x2b\xe2\xe5\x67\x41\x0d\x31\xc9\x4f\x41\x6b\xe2\xaa\x8c\xd2\x51\xfb\x19\xe2\x78\xce\x26\x57\xdf\xc1\x3d\xf7\xad\xc5\x6d\x42\x93\x30\x21\x5e\x07\xad\x53\xf4\x11\xb9\xc5\x2d\x4d\x17\xca\x02\x67\x4a\xa1\xed\x39\x08\xdc\x35\x6c\xca\x29\xe4\x0b\xb7\xa5\x3f\xb6\x33\xc7\x33\x5b\xa0\x77\x3f\x36\x2c\xe0\xbc\x7b\xd7\x52\x71\x08\xc5\xb1\x44\xb8\xbe\x86\xde\x64\x31\x4d\x57\xd3\x5e\xd3\x4c\xa3\x11\xdc\x63\xd8\xc8\x32\x25\x33\xa1\x8e\x20\x50\xa1\xc7\xda\x2e\xa3\x43\x88\x3a\x6a\x1a\xd2\x6a\x45\x4b\x0f\x1e\xa4\xf3\x52\x17\x50\x33\xd6\x9e\xe6\x7b\x03\x17\x7a\x84\xb3\xca\x51\xb5\x3e\x1b\x86\xde\xd0\x66\x63\x91\xf8\x8d\xe6\x50\x68\x37\xa6\x64\xb7\x09\xe5\xd2\x3a\x0f\xa5\x62\x1c\x13\xc2\xeb\x8c\x79\x3d\xbf\x0d\x33\x93\xea\x45\x68\xc1\x00\x74\x1a\xb4\x4c\xd1\xa0\x26\xf5\x0e\xfa\x2d\xc6\x20\x8e\x22\xdb\x4a\x3f\xc1\xbe\x3a\x51\x82\xf3\x58\x3e\x25\x04\x5a\x70\x70\x87\x44\xe5\x81\x0d\xea\xa1\x4c\xba\xfe\xf9\x6b\xb3\x05\xa0\x4b\xe2\x88\xde\x3d\xe9\x6b\x65\x8a\xf3\xbe\x16\x75\x58\x78\x65\x2d\xe5\xbf\x1b\x05\x39\xf5\xf8\x1f\x95\xf3\x14\x53\x4b\xe1\x69\xd8\xe2\x25\xb2\x0e\xd4\x4c\x53\x7f\xf0\xe7\x21\x4a\xf3\x33\xcc\x2b\x52\xd7\x4c\xcb\x7a\xab\x2c\x8d\x47\xed\x25\x53\xea\x48\x79\xd8\x5b\x5a\xa7\x68\x81\x1a\x82\x93\x24\x15\x18\x27\x88\x4a\xcd\x55\x25\xea\x32\x08\x75\xdc\xe0\xb9\x60\xf3\xf9\x1e\xb6\x45\xe7\x58\x81\x09\x55\x52\x2e\x0f\xcd\x26\xab\xa1\x57\x93\x5c\x7f\xd0\x4b\x3a\x23\xcf\x29\x46\x99\x22\x69\x8b\x8c\xb8\x3a\x15\xc2\xa2\x73\xfd\x41\xc3\x39\x5d\x66\xef\xd7\xa8\x29\xf8\xa0\x71\x0f\xdd\x8a\xc4\x38\xa7\x95\x51\x0c\x81\x09\x41\xd4\xf6\x6c\x9d\x89\xa3\xc8\xed\xa5\xe7\x6b\x08\x9a\x4c\x79\xea\xc5\x41\x53\xff\x9c\x39\x84\x8b\xe9\xbf\x56\x93\xdb\x8f\xd3\xc9\xed\xdd\xc3\xc5\x18\xce\xce\x96\xb3\x7f\x4f\xbb\xb3\x0f\xe9\x3c\xbd\x99\x4c\x2f\xc6\xa7\x39\x74\xee\x90\x37\xad\x0b\xa4\xd0\x79\xc6\x37\x49\x89\xb8\xe9\x5f\x9e\xf3\xc0\xc9\xc1\x28\xca\x2c\xb2\xcd\xd5\xc9\x98\xba\x41\x1b\x1d\x2d\xe5\xc2\x35\xbc\x1a\xac\xab\xd7\xad\x99\x34\xf2\xfd\x96\xc8\x4f\x2b\x51\xa0\x8a\xef\xda\x91\xce\xe7\x9d\xe7\xf4\x41\xe1\xe8\x0e\x3e\x4e\xe7\xd3\xcf\xe9\x6a\x7a\x26\xb5\x5c\xa5\xab\xd9\xa4\x3e\xfa\xaf\x43\xf4\xfe\xcd\x21\xea\x2d\x97\xab\xdb\xc5\xb4\x37\x6e\xbe\xe6\xb7\xe9\xc7\xde\x9f\x14\x36\x7b\xd3\xf7\x8a\xcc\x9b\x7b\x63\xc5\xff\x92\xab\x27\xbb\x43\xce\x5e\x5a\x1d\x02\x09\x71\x5f\x3d\xfb\x89\x00\x4c\xb7\xfc\x91\xd7\x3f\x93\xa2\xf0\xfe\x45\xc6\x78\x8c\x1f\xe3\xff\x04\x00\x00\xff\xff\xb5\x44\x89\xaf\xbc\x0f\x00\x00")
+var _prestate_tracerJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xa4\x57\xdd\x6e\x1b\xbb\x11\xbe\xde\x7d\x8a\x69\x6e\x24\x21\x3a\x2b\xe7\x14\x38\x05\xec\xa3\x02\x8a\xa2\x24\x46\x7d\x6c\x43\x52\xea\xba\x41\x2e\xb8\xcb\x59\x2d\x63\x8a\xdc\x92\xb3\x92\xd5\xc0\xef\x5e\x0c\xf7\x47\x96\x23\xc7\x69\xeb\x2b\x2f\x39\xfc\xe6\xff\x9b\xd1\x68\x04\x53\x5b\xee\x9c\x5a\x15\x04\xbf\x9e\xbc\xf9\x0b\x2c\x0b\x04\xf1\xaf\x4a\x64\x85\x50\x06\x26\x15\x15\xd6\xf9\x78\x34\x82\x65\xa1\x3c\xe4\x4a\x23\x28\x0f\xa5\x70\x04\x36\x07\x3a\x90\xd6\x2a\x75\xc2\xed\x92\x78\x34\xaa\x5f\x1c\xb9\xe4\xd7\xb9\x43\x04\x6f\x73\xda\x0a\x87\xa7\xb0\xb3\x15\x64\xc2\x80\x43\xa9\x3c\x39\x95\x56\x84\xa0\x08\x84\x91\x23\xeb\x60\x6d\xa5\xca\x77\x0c\xa8\x08\x2a\x23\xd1\x05\xb5\x84\x6e\xed\x5b\x1b\x3e\x5c\x7e\x82\x0b\xf4\x1e\x1d\x7c\x40\x83\x4e\x68\xb8\xae\x52\xad\x32\xb8\x50\x19\x1a\x8f\x20\x3c\x94\x7c\xe2\x0b\x94\x90\x06\x38\x7e\xf8\x9e\x4d\x59\x34\xa6\xc0\x7b\x5b\x19\x29\x48\x59\x33\x04\x54\x54\xa0\x83\x0d\x3a\xaf\xac\x81\x3f\xb7\xaa\x1a\xc0\x21\x58\xc7\x20\x7d\x41\xec\x80\x03\x5b\xf2\xbb\x01\x08\xb3\x03\x2d\x68\xff\xf4\xc5\x70\xec\xbd\x96\xa0\x4c\x50\x52\xd8\x12\x81\x0a\x41\xec\xf3\x56\x69\x0d\x29\x42\xe5\x31\xaf\xf4\x90\xb1\xd2\x8a\xe0\xe6\x7c\xf9\xf1\xea\xd3\x12\x26\x97\xb7\x70\x33\x99\xcf\x27\x97\xcb\xdb\x33\xd8\x2a\x2a\x6c\x45\x80\x1b\xac\xa1\xd4\xba\xd4\x0a\x25\x6c\x85\x73\xc2\xd0\x0e\x6c\xce\x08\x7f\xcc\xe6\xd3\x8f\x93\xcb\xe5\xe4\xed\xf9\xc5\xf9\xf2\x16\xac\x83\xf7\xe7\xcb\xcb\xd9\x62\x01\xef\xaf\xe6\x30\x81\xeb\xc9\x7c\x79\x3e\xfd\x74\x31\x99\xc3\xf5\xa7\xf9\xf5\xd5\x62\x96\xc0\x02\xd9\x2a\xe4\xf7\x2f\x47\x3c\x0f\xb9\x73\x08\x12\x49\x28\xed\xdb\x38\xdc\xda\x0a\x7c\x61\x2b\x2d\xa1\x10\x1b\x04\x87\x19\xaa\x0d\x4a\x10\x90\xd9\x72\xf7\xd3\x29\x65\x2c\xa1\xad\x59\x05\x9f\x9f\x29\x45\x38\xcf\xc1\x58\x1a\x82\x47\x84\xdf\x0b\xa2\xf2\x74\x34\xda\x6e\xb7\xc9\xca\x54\x89\x75\xab\x91\xae\xc1\xfc\xe8\xaf\x49\xcc\x88\xa5\x43\x4f\x82\x70\xe9\x44\x86\x0e\x6c\x45\x65\x45\x1e\x7c\x95\xe7\x2a\x53\x68\x08\x94\xc9\xad\x5b\x87\x2a\x01\xb2\x90\x39\x14\x84\x20\x40\xdb\x4c\x68\xc0\x7b\xcc\xaa\x70\x57\xc7\x39\x94\xaa\x13\xc6\x8b\x2c\x9c\xe6\xce\xae\xd9\xd3\xca\x13\xff\xe3\x3d\xae\x53\x8d\x12\x56\x68\xd0\x2b\x0f\xa9\xb6\xd9\x5d\x12\x7f\x8b\xa3\x47\xc6\x70\x95\x30\x50\x2b\x14\x2a\x63\x8b\x3d\x87\x90\x56\x4a\x4b\x65\x56\x49\x1c\xb5\xd2\xa7\x60\x2a\xad\x87\x71\x80\xd0\xd6\xde\x55\xe5\x24\xcb\x6c\x15\x6c\xff\x8a\x19\xd5\x60\xbe\xc4\x4c\xe5\x5c\x1a\xa2\xbb\x25\x1b\xae\x3a\xbd\x36\x65\xf9\x24\x8e\x0e\x60\x4e\x21\xaf\x4c\x70\xa7\x2f\xa4\x74\x43\x90\xe9\xe0\x5b\x1c\x45\x1b\xe1\x18\x0b\xc6\x40\xf6\x23\xde\x87\xcb\xc1\x59\x1c\x45\x2a\x87\x3e\x15\xca\x27\x2d\xf0\x67\x91\x65\x5f\x60\x3c\x1e\x87\x86\xce\x95\x41\x39\x00\x86\x88\x8e\x89\xd5\x37\x51\x2a\xb4\x30\x19\x9e\x42\xef\xe4\xbe\x07\xaf\x41\xa6\xc9\x0a\xe9\x6d\x7d\x5a\x2b\x4b\xc8\x2e\xc8\x29\xb3\xea\xbf\xf9\x6d\x30\x0c\xaf\x8c\x0d\x6f\xa0\x11\xbf\xb4\x9d\x70\x7d\x9f\x59\x19\xae\x1b\x9b\x6b\xa9\xa9\x95\x8d\x50\x23\xe5\xc9\x3a\xb1\xc2\x53\xf8\xf6\xc0\xdf\x0f\xec\xd5\x43\x1c\x3d\x1c\x44\x79\x51\x0b\x3d\x13\xe5\x06\x02\xd0\x90\xeb\xaa\x7c\xa5\xb8\x4f\x1f\x27\x20\xe0\xfd\x28\x09\x8b\xd6\x94\x27\x49\xb8\xc3\xdd\xcb\x99\xe0\x0b\x25\xef\xbb\x8b\x3b\xdc\x0d\xce\xe2\x67\x53\x94\x34\x46\x7f\x56\xf2\xfe\x78\xbe\x18\x70\x23\x34\x8c\x0f\xe2\xb7\x60\x84\xbd\x5d\x83\xa0\x3b\xe8\x60\xd9\x3f\x8d\xe1\xd5\xc9\xfd\xc9\xff\xf9\xf7\xaa\xb1\x20\x7a\xd1\xec\x9f\x30\xed\xe1\x30\x9f\x0e\x7d\xa5\x89\xdb\x4e\x99\x8d\xbd\x63\xfa\x2c\x38\x4f\x5a\x87\xd4\xd8\x92\xab\xc6\xd7\xfc\x95\x22\x1a\x50\x84\x4e\x10\x4a\xb0\x1b\x74\x3c\xb9\xc0\x21\x55\xce\xf8\x2e\x9d\xb9\x32\x42\xb7\xc0\x4d\xf6\xc9\x89\xac\xee\xdd\xfa\xfc\x51\x4e\x33\xba\x0f\xd9\x0c\x3e\x8e\x46\x30\x21\x60\x3f\xa1\xb4\xca\xd0\x10\xb6\x08\x06\x51\x02\x59\x90\x28\xab\x8c\x02\x5e\x6f\x23\x74\x85\xbd\x9a\x64\x98\xa8\xc3\x53\x5b\x11\xba\xc7\x24\x34\x0c\x06\xae\xed\x26\x8c\xd9\x54\x64\x77\xd0\x34\xbe\x75\x6a\xa5\x4c\xdc\xc4\xf4\xa0\xe9\xd9\xa2\x84\x81\x83\x59\xa1\x66\x38\xf7\x7c\xf2\x36\xe4\x3f\x55\xab\x73\x43\x4f\x8a\xa8\x8e\x7c\xfb\x74\xf0\x25\x69\x9a\x38\xf1\x4c\xbc\xfd\x5f\x07\x43\x78\xf3\x5b\x57\x99\x64\x19\x0a\x5e\x06\x23\xfb\x3c\x54\x1c\x45\x3f\xf3\x2c\xa8\x61\x26\x79\x1d\xb4\x26\xbe\x4a\x39\x1d\xb5\x9f\x21\x8e\x87\x6c\x72\xf6\x03\xdc\x43\xdf\x5a\xdc\x26\x34\x89\x90\xf2\x79\xd0\x3a\x45\xef\x30\x73\xb8\x46\x53\xa7\x31\x13\x5a\xa3\xeb\x79\x08\xdc\x35\x6c\xca\x29\xe4\x0b\xd7\x25\xed\xda\x99\x43\xc2\xad\x90\xfc\xcb\x86\x05\x9c\x5f\x7e\x69\xa9\x98\x6f\x68\x57\x22\x8c\xc7\xd0\x9b\xce\x67\x93\xe5\xac\xd7\x34\xd3\x68\x04\x37\x18\xb6\xb1\x54\xab\x54\xea\x1d\x48\xd4\x48\x58\xdb\x65\x4d\x08\x51\x47\x4d\x43\x10\x3e\x2c\x3c\x78\xaf\x3c\x29\xb3\x82\x70\x0c\x5b\x9e\xee\x0d\x5c\xe8\x91\x4c\x54\x1e\x65\x5b\xf3\xdd\x30\x24\x0b\x29\x82\x43\xe6\x37\x94\x0c\xa6\xcc\x46\x68\xd5\xed\x41\xb9\x72\x9e\xa0\xd4\x22\xc3\x84\xf1\x3a\x63\x9e\xcf\x6f\xc3\xcc\xac\x7a\x1e\x5a\x30\x00\xed\x07\xad\xd0\x3c\xa8\x59\xbd\x87\x7e\x8b\x31\x60\x85\x02\x24\xf2\x56\xa9\x0c\x3b\x93\x81\x75\x12\x5d\x53\x99\xad\x20\x8c\x6b\xe5\xde\x3a\xfa\x1b\xee\xfc\x61\x85\x86\x22\xe1\xbd\xa7\xdf\x12\xb0\x32\xb0\xd7\x11\x22\x7c\x94\xa8\xbe\x83\x3d\x2a\x35\x68\x7d\xab\xb9\xa5\x43\x3e\xdb\x13\x57\x0b\x00\x0e\xeb\x22\xe1\xfc\x34\x13\xa4\xde\x93\x14\x79\xe6\x3d\x1f\x3c\xf6\x19\x1a\xde\x1e\x6a\x5f\x43\x3a\xa9\xc0\x1d\x08\x87\x01\xce\xf2\x0e\xbc\x55\x1e\xc1\xa3\x53\x42\xab\x7f\xef\x77\xd4\xf0\x24\xfc\xd7\x24\x9d\x6b\x21\xcb\x78\x65\x93\x49\x1c\xb5\x96\x3c\xe2\x34\x9b\x7e\xad\x83\xc0\xd1\xe1\x7b\x94\x3c\xdc\x1f\xda\xf6\x0f\x66\x8d\xe1\xaa\x9e\x77\xfc\x15\x9e\x84\xa8\xf4\x0f\x63\xab\xc6\x27\x67\xa0\x7e\x67\x99\x44\xa3\x59\x51\x71\x06\xea\xf5\xeb\x26\xc6\x35\xf6\x67\xbe\xfd\xac\xbe\xf0\x04\xb0\xe9\xd7\xee\xf3\x49\x14\x6b\xe1\xc7\x31\x24\x2c\x1f\x53\x3f\x2b\xc5\x0d\xba\x5d\xc3\xfb\xf5\xfa\xc5\x8e\xff\xfd\x8f\x66\xdf\x43\xcf\x1e\x13\x96\x8f\xbc\xd5\x76\x75\xc8\xe0\xb2\x6e\x80\xac\x72\x8e\x3b\xbd\x1b\xfa\x39\xb3\xf9\xd7\xca\x13\x07\x32\x04\xa5\x99\x0b\xc7\xc6\x72\x18\xc2\xbc\xdf\x0d\xbe\x5f\x97\xba\x60\xb2\xba\x66\x2f\xaa\x7f\x3d\x94\x96\xd0\x90\x12\x5a\xef\xb8\xe3\xb6\x8e\xd7\xe6\x02\x1d\x0e\xc1\x2b\x96\x62\x9c\x5a\x54\x99\x4c\x57\xb2\x6e\xf8\xc0\x58\x0d\x9e\x0f\x36\x1f\xee\xdb\x6b\xf4\x5e\xac\x30\x61\xce\xc8\xd5\x7d\xf3\x8b\xc5\x40\xaf\x1e\x67\xfd\x41\x2f\x89\xa3\xa3\xc3\x44\xdb\x55\xd2\xd2\x09\x4f\xe5\x89\x94\x0e\xbd\xef\x0f\x9a\xe9\xd2\xf5\xf0\x4d\x81\x86\x83\x0f\x06\xb7\xd0\x2d\xc3\x6d\x9d\x0d\x41\x48\x09\x8a\xe0\xc9\xe2\x1a\x47\x91\xdf\x2a\xca\x0a\x08\x9a\x6c\xb9\x67\xdd\x41\x53\x23\x99\xf0\x08\xaf\x66\xff\x58\x4e\xaf\xde\xcd\xa6\x57\xd7\xb7\xaf\x4e\xe1\xe0\x6c\x71\xfe\xcf\x59\x77\xf6\x76\x72\x31\xb9\x9c\xce\x5e\x9d\xc6\xd1\x71\x87\xc8\xb6\x2e\xb0\x42\x4f\x22\xbb\x4b\x4a\xc4\xbb\xfe\xc9\x21\xe3\xef\x1d\x8c\xa2\xd4\xa1\xb8\x3b\xdb\x1b\x53\x53\x71\xa3\xa3\x1d\xae\x30\x86\x67\x83\x75\xf6\xbc\x35\xd3\x46\xbe\xdf\x8e\xec\xfd\xf2\xcb\x27\x3f\xb6\x63\x72\x71\xd1\x79\xce\x1f\x1c\x8e\xee\xe0\xdd\xec\x62\xf6\x61\xb2\x9c\x1d\x48\x2d\x96\x93\xe5\xf9\xb4\x3e\xfa\xaf\x43\xf4\xe6\xa7\x43\xd4\x5b\x2c\x96\x57\xf3\x59\xef\xb4\xf9\xba\xb8\x9a\xbc\xeb\x7d\xa7\xb0\xd9\x90\x7f\x54\x64\x64\x6f\xac\x93\xff\x4b\xae\x1e\x6d\x89\xb9\x38\xb6\x24\x86\x71\x93\x51\xf5\xe4\xc7\x60\xe0\xe1\x9a\x3f\xf2\xfa\xe7\x70\x14\xde\x1f\x65\x8c\x87\xf8\x21\xfe\xcf\x00\x9f\x1b\x51\xf5\x9c\x11\x00\x00")
 
 func prestate_tracerJsBytes() ([]byte, error) {
 	return bindataRead(
@@ -67,8 +67,23 @@
 			// have caused the transaction to be rejected as invalid in the first place.
 			delete this.prestate[toHex(ctx.to)];
 		}
-		// Return the assembled allocations (prestate)
-		return this.prestate;
+		// Return the assembled allocations (prestate) in a deterministic order
+		var prestate = this.sortKeys(this.prestate);
+		for (var acc in prestate) {
+			prestate[acc].storage = this.sortKeys(prestate[acc].storage);
+		}
+		return prestate;
+	},
+
+	// sortKeys recreates an object with its keys in ascending order, as they are
+	// otherwise serialized in the order the state was accessed.
+	sortKeys: function(obj) {
+		var sorted = {};
+		var keys = Object.keys(obj).sort();
+		for (var i=0; i<keys.length; i++) {
+			sorted[keys[i]] = obj[keys[i]];
+		}
+		return sorted;
 	},
 
 	// step is invoked for every opcode that the VM executes.
@@ -0,0 +1,70 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package txconflict
+
+import (
+	"context"
+
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+// PublicConflictAPI exposes the detected conflicting transactions to anyone.
+type PublicConflictAPI struct {
+	detector *Detector
+}
+
+// NewPublicConflictAPI creates a new public conflicting transaction API.
+func NewPublicConflictAPI(detector *Detector) *PublicConflictAPI {
+	return &PublicConflictAPI{detector}
+}
+
+// RecentConflicts returns the most recently detected conflicting transactions,
+// oldest first.
+func (api *PublicConflictAPI) RecentConflicts() []*Conflict {
+	return api.detector.Recent()
+}
+
+// ConflictingTransactions creates a subscription that is triggered each time a
+// transaction conflicting with a previously seen one, having the same sender
+// and nonce, enters the transaction pool or is mined.
+func (api *PublicConflictAPI) ConflictingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		conflicts := make(chan *Conflict, 16)
+		sub := api.detector.SubscribeConflicts(conflicts)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case conflict := <-conflicts:
+				notifier.Notify(rpcSub.ID, conflict)
+			case <-sub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
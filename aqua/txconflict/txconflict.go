@@ -0,0 +1,205 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package txconflict detects conflicting transactions, different transactions
+// of the same sender and nonce, seen in the transaction pool and in blocks. At
+// most one of them can ever be included in the chain, so a conflict seen after
+// a transaction was accepted with few or no confirmations is a double spend
+// attempt for whoever accepted it.
+package txconflict
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/metrics"
+	"github.com/aquanetwork/aquachain/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	seenLimit   = 65536 // Number of sender and nonce pairs remembered
+	recentLimit = 64    // Number of recent conflicts kept for polling
+
+	txChanSize    = 4096 // Size of the channel listening to the transaction pool
+	chainChanSize = 16   // Size of the channel listening to imported blocks
+)
+
+var (
+	conflictMeter      = metrics.NewRegisteredMeter("txconflict/conflicts", nil)
+	minedConflictMeter = metrics.NewRegisteredMeter("txconflict/mined", nil)
+)
+
+// Conflict is a pair of different transactions of the same sender and nonce.
+type Conflict struct {
+	Sender   common.Address     `json:"sender"`
+	Nonce    hexutil.Uint64     `json:"nonce"`
+	Previous *types.Transaction `json:"previous"`        // Transaction seen first
+	Conflict *types.Transaction `json:"conflict"`        // Transaction seen later
+	Mined    bool               `json:"mined"`           // Whether the later transaction was mined, replacing the first
+	Block    *common.Hash       `json:"block,omitempty"` // Block the later transaction was mined in
+	Time     time.Time          `json:"time"`
+}
+
+// txPool is the transaction pool the detector watches.
+type txPool interface {
+	SubscribeTxPreEvent(chan<- core.TxPreEvent) event.Subscription
+}
+
+// blockChain is the chain the detector watches.
+type blockChain interface {
+	CurrentBlock() *types.Block
+	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
+}
+
+// seenKey identifies the nonce of a sender.
+type seenKey struct {
+	sender common.Address
+	nonce  uint64
+}
+
+// Detector watches the transactions entering the pool and the blocks imported
+// into the chain for conflicting transactions.
+type Detector struct {
+	config *params.ChainConfig
+	pool   txPool
+	chain  blockChain
+	seen   *lru.Cache // Transactions last seen by sender and nonce
+
+	feed  event.Feed
+	scope event.SubscriptionScope
+
+	lock   sync.RWMutex
+	recent []*Conflict // Most recent conflicts, oldest first
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a conflicting transaction detector.
+func New(config *params.ChainConfig, chain blockChain, pool txPool) *Detector {
+	seen, _ := lru.New(seenLimit)
+	return &Detector{
+		config: config,
+		pool:   pool,
+		chain:  chain,
+		seen:   seen,
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start starts watching the transaction pool and the chain.
+func (d *Detector) Start() {
+	d.wg.Add(1)
+	go d.loop()
+}
+
+// Stop stops the detector, ending all the conflict subscriptions.
+func (d *Detector) Stop() {
+	close(d.quit)
+	d.wg.Wait()
+	d.scope.Close()
+}
+
+// SubscribeConflicts subscribes to the conflicts detected from now on.
+func (d *Detector) SubscribeConflicts(ch chan<- *Conflict) event.Subscription {
+	return d.scope.Track(d.feed.Subscribe(ch))
+}
+
+// Recent returns the most recently detected conflicts, oldest first.
+func (d *Detector) Recent() []*Conflict {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return append([]*Conflict(nil), d.recent...)
+}
+
+func (d *Detector) loop() {
+	defer d.wg.Done()
+
+	txCh := make(chan core.TxPreEvent, txChanSize)
+	txSub := d.pool.SubscribeTxPreEvent(txCh)
+	defer txSub.Unsubscribe()
+
+	chainCh := make(chan core.ChainEvent, chainChanSize)
+	chainSub := d.chain.SubscribeChainEvent(chainCh)
+	defer chainSub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-txCh:
+			signer := types.MakeSigner(d.config, d.chain.CurrentBlock().Number())
+			d.observe(signer, ev.Tx, common.Hash{})
+
+		case ev := <-chainCh:
+			signer := types.MakeSigner(d.config, ev.Block.Number())
+			for _, tx := range ev.Block.Transactions() {
+				d.observe(signer, tx, ev.Block.Hash())
+			}
+
+		case <-txSub.Err():
+			return
+		case <-chainSub.Err():
+			return
+		case <-d.quit:
+			return
+		}
+	}
+}
+
+// observe records a transaction seen in the pool or in a block, reporting a
+// conflict if a different one was seen with the same sender and nonce.
+func (d *Detector) observe(signer types.Signer, tx *types.Transaction, block common.Hash) {
+	sender, err := types.Sender(signer, tx)
+	if err != nil {
+		return
+	}
+	key := seenKey{sender, tx.Nonce()}
+	if prev, ok := d.seen.Get(key); ok {
+		prev := prev.(*types.Transaction)
+		if prev.Hash() == tx.Hash() {
+			return
+		}
+		d.report(&Conflict{Sender: sender, Nonce: hexutil.Uint64(tx.Nonce()), Previous: prev, Conflict: tx, Time: time.Now()}, block)
+	}
+	d.seen.Add(key, tx)
+}
+
+// report records and announces a detected conflict.
+func (d *Detector) report(conflict *Conflict, block common.Hash) {
+	conflictMeter.Mark(1)
+	if block != (common.Hash{}) {
+		conflict.Mined, conflict.Block = true, &block
+		minedConflictMeter.Mark(1)
+		log.Warn("Conflicting transaction mined", "sender", conflict.Sender, "nonce", conflict.Nonce, "previous", conflict.Previous.Hash(), "mined", conflict.Conflict.Hash(), "block", block)
+	} else {
+		log.Info("Conflicting transaction pooled", "sender", conflict.Sender, "nonce", conflict.Nonce, "previous", conflict.Previous.Hash(), "pooled", conflict.Conflict.Hash())
+	}
+	d.lock.Lock()
+	d.recent = append(d.recent, conflict)
+	if len(d.recent) > recentLimit {
+		d.recent = append([]*Conflict(nil), d.recent[len(d.recent)-recentLimit:]...)
+	}
+	d.lock.Unlock()
+
+	d.feed.Send(conflict)
+}
@@ -0,0 +1,114 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package txconflict
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// testBackend is a transaction pool and chain posting events on request.
+type testBackend struct {
+	txFeed    event.Feed
+	chainFeed event.Feed
+	head      *types.Block
+}
+
+func (b *testBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Subscription {
+	return b.txFeed.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	return b.chainFeed.Subscribe(ch)
+}
+
+func (b *testBackend) CurrentBlock() *types.Block { return b.head }
+
+func (b *testBackend) mine(txs ...*types.Transaction) common.Hash {
+	header := &types.Header{Number: new(big.Int).Add(b.head.Number(), common.Big1), ParentHash: b.head.Hash()}
+	b.head = types.NewBlock(header, txs, nil, nil)
+	b.chainFeed.Send(core.ChainEvent{Block: b.head, Hash: b.head.Hash()})
+	return b.head.Hash()
+}
+
+func TestConflictDetection(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		backend = &testBackend{head: types.NewBlock(&types.Header{Number: big.NewInt(0)}, nil, nil, nil)}
+		signer  = types.HomesteadSigner{}
+	)
+	transfer := func(nonce uint64, to byte) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{to}, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, key)
+		return tx
+	}
+	detector := New(params.TestChainConfig, backend, backend)
+	conflicts := make(chan *Conflict, 4)
+	detector.SubscribeConflicts(conflicts)
+	detector.Start()
+	defer detector.Stop()
+
+	expect := func(previous, conflict *types.Transaction, block common.Hash) {
+		t.Helper()
+		select {
+		case c := <-conflicts:
+			if c.Sender != sender || uint64(c.Nonce) != conflict.Nonce() {
+				t.Fatalf("conflict mismatch: sender %x, nonce %d", c.Sender, c.Nonce)
+			}
+			if c.Previous.Hash() != previous.Hash() || c.Conflict.Hash() != conflict.Hash() {
+				t.Fatalf("conflicting transactions mismatch: have %x and %x, want %x and %x", c.Previous.Hash(), c.Conflict.Hash(), previous.Hash(), conflict.Hash())
+			}
+			if c.Mined != (block != common.Hash{}) || (c.Mined && *c.Block != block) {
+				t.Fatalf("conflict block mismatch: mined %v, block %v, want %x", c.Mined, c.Block, block)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("conflict not detected")
+		}
+	}
+	// A pooled transaction mined as is doesn't conflict
+	a0 := transfer(0, 1)
+	backend.txFeed.Send(core.TxPreEvent{Tx: a0})
+	backend.mine(a0)
+
+	// A pooled transaction replaced in the pool, then by a mined one
+	a1, b1, c1 := transfer(1, 1), transfer(1, 2), transfer(1, 3)
+	backend.txFeed.Send(core.TxPreEvent{Tx: a1})
+	backend.txFeed.Send(core.TxPreEvent{Tx: b1})
+	expect(a1, b1, common.Hash{})
+
+	block := backend.mine(c1)
+	expect(b1, c1, block)
+
+	// A mined transaction rebroadcast to the pool doesn't conflict
+	backend.txFeed.Send(core.TxPreEvent{Tx: c1})
+	select {
+	case c := <-conflicts:
+		t.Fatalf("unexpected conflict: %x and %x", c.Previous.Hash(), c.Conflict.Hash())
+	case <-time.After(50 * time.Millisecond):
+	}
+	if recent := detector.Recent(); len(recent) != 2 {
+		t.Fatalf("recent conflicts mismatch: have %d, want 2", len(recent))
+	}
+}
@@ -28,7 +28,6 @@ import (
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/hexutil"
 	"github.com/aquanetwork/aquachain/core/types"
-	"github.com/aquanetwork/aquachain/rlp"
 	"github.com/aquanetwork/aquachain/rpc"
 )
 
@@ -39,7 +38,13 @@ type Client struct {
 
 // Dial connects a client to the given URL.
 func Dial(rawurl string) (*Client, error) {
-	c, err := rpc.Dial(rawurl)
+	return DialContext(context.Background(), rawurl)
+}
+
+// DialContext connects a client to the given URL, an HTTP, WebSocket or IPC
+// endpoint, aborting the connection attempt when the context is canceled.
+func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	c, err := rpc.DialContext(ctx, rawurl)
 	if err != nil {
 		return nil, err
 	}
@@ -51,8 +56,31 @@ func NewClient(c *rpc.Client) *Client {
 	return &Client{c}
 }
 
+// Close closes the underlying RPC connection.
+func (ec *Client) Close() {
+	ec.c.Close()
+}
+
 // Blockchain Access
 
+// ChainID retrieves the chain ID used for transaction replay protection.
+func (ec *Client) ChainID(ctx context.Context) (*big.Int, error) {
+	var result hexutil.Big
+	if err := ec.c.CallContext(ctx, &result, "aqua_chainId"); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&result), nil
+}
+
+// BlockNumber returns the number of the most recent block.
+func (ec *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	var result hexutil.Big
+	if err := ec.c.CallContext(ctx, &result, "aqua_blockNumber"); err != nil {
+		return 0, err
+	}
+	return (*big.Int)(&result).Uint64(), nil
+}
+
 // BlockByHash returns the given full block.
 //
 // Note that loading full blocks requires two requests. Use HeaderByHash
@@ -253,6 +281,17 @@ func (ec *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*
 	return r, err
 }
 
+// BlockReceipts returns the receipts of all the transactions of the block selected
+// by number or hash, in the order the transactions appear in the block.
+func (ec *Client) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	var r []*types.Receipt
+	err := ec.c.CallContext(ctx, &r, "aqua_getBlockReceipts", blockNrOrHash)
+	if err == nil && r == nil {
+		return nil, aquachain.NotFound
+	}
+	return r, err
+}
+
 func toBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
@@ -346,6 +385,38 @@ func (ec *Client) NonceAt(ctx context.Context, account common.Address, blockNumb
 	return uint64(result), err
 }
 
+// BalanceAtHash returns the wei balance of the given account in the state of the
+// block with the given hash, which doesn't need to be canonical.
+func (ec *Client) BalanceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (*big.Int, error) {
+	var result hexutil.Big
+	err := ec.c.CallContext(ctx, &result, "aqua_getBalance", account, rpc.BlockNumberOrHashWithHash(blockHash, false))
+	return (*big.Int)(&result), err
+}
+
+// StorageAtHash returns the value of key in the contract storage of the given
+// account in the state of the block with the given hash.
+func (ec *Client) StorageAtHash(ctx context.Context, account common.Address, key common.Hash, blockHash common.Hash) ([]byte, error) {
+	var result hexutil.Bytes
+	err := ec.c.CallContext(ctx, &result, "aqua_getStorageAt", account, key, rpc.BlockNumberOrHashWithHash(blockHash, false))
+	return result, err
+}
+
+// CodeAtHash returns the contract code of the given account in the state of the
+// block with the given hash.
+func (ec *Client) CodeAtHash(ctx context.Context, account common.Address, blockHash common.Hash) ([]byte, error) {
+	var result hexutil.Bytes
+	err := ec.c.CallContext(ctx, &result, "aqua_getCode", account, rpc.BlockNumberOrHashWithHash(blockHash, false))
+	return result, err
+}
+
+// NonceAtHash returns the account nonce of the given account in the state of the
+// block with the given hash.
+func (ec *Client) NonceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (uint64, error) {
+	var result hexutil.Uint64
+	err := ec.c.CallContext(ctx, &result, "aqua_getTransactionCount", account, rpc.BlockNumberOrHashWithHash(blockHash, false))
+	return uint64(result), err
+}
+
 // Filters
 
 // FilterLogs executes a filter query.
@@ -411,7 +482,11 @@ func (ec *Client) PendingTransactionCount(ctx context.Context) (uint, error) {
 	return uint(num), err
 }
 
-// TODO: SubscribePendingTransactions (needs server side)
+// SubscribePendingTransactions subscribes to notifications about transactions
+// entering the transaction pool of the node.
+func (ec *Client) SubscribePendingTransactions(ctx context.Context, ch chan<- *types.Transaction) (aquachain.Subscription, error) {
+	return ec.c.AquaSubscribe(ctx, ch, "newPendingTransactions", true)
+}
 
 // Contract Calling
 
@@ -430,6 +505,17 @@ func (ec *Client) CallContract(ctx context.Context, msg aquachain.CallMsg, block
 	return hex, nil
 }
 
+// CallContractAtHash executes a message call transaction on the state of the
+// block with the given hash, which doesn't need to be canonical.
+func (ec *Client) CallContractAtHash(ctx context.Context, msg aquachain.CallMsg, blockHash common.Hash) ([]byte, error) {
+	var hex hexutil.Bytes
+	err := ec.c.CallContext(ctx, &hex, "aqua_call", toCallArg(msg), rpc.BlockNumberOrHashWithHash(blockHash, false))
+	if err != nil {
+		return nil, err
+	}
+	return hex, nil
+}
+
 // PendingCallContract executes a message call transaction using the EVM.
 // The state seen by the contract call is the pending state.
 func (ec *Client) PendingCallContract(ctx context.Context, msg aquachain.CallMsg) ([]byte, error) {
@@ -469,7 +555,7 @@ func (ec *Client) EstimateGas(ctx context.Context, msg aquachain.CallMsg) (uint6
 // If the transaction was a contract creation use the TransactionReceipt method to get the
 // contract address after the transaction has been mined.
 func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return err
 	}
@@ -16,7 +16,15 @@
 
 package aquaclient
 
-import "github.com/aquanetwork/aquachain"
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/rpc"
+)
 
 // Verify that Client implements the aquachain interfaces.
 var (
@@ -29,6 +37,30 @@ var (
 	_ = aquachain.GasPricer(&Client{})
 	_ = aquachain.LogFilterer(&Client{})
 	_ = aquachain.PendingStateReader(&Client{})
-	// _ = aquachain.PendingStateEventer(&Client{})
+	_ = aquachain.PendingStateEventer(&Client{})
 	_ = aquachain.PendingContractCaller(&Client{})
 )
+
+// TestChainService serves the chain methods the client is tested against, encoded
+// like those of the node.
+type TestChainService struct{}
+
+func (TestChainService) ChainId() *hexutil.Big { return (*hexutil.Big)(big.NewInt(61717561)) }
+func (TestChainService) BlockNumber() *big.Int { return big.NewInt(42) }
+
+// Tests that the chain ID and head number are decoded from the node's replies.
+func TestChainIDBlockNumber(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("aqua", TestChainService{}); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	client := NewClient(rpc.DialInProc(server))
+	defer client.Close()
+
+	if id, err := client.ChainID(context.Background()); err != nil || id.Int64() != 61717561 {
+		t.Errorf("chain ID mismatch: have %v (%v), want %d", id, err, 61717561)
+	}
+	if number, err := client.BlockNumber(context.Background()); err != nil || number != 42 {
+		t.Errorf("block number mismatch: have %d (%v), want %d", number, err, 42)
+	}
+}
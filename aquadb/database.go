@@ -29,6 +29,7 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/filter"
 	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 var OpenFileLimit = 64
@@ -147,6 +148,38 @@ func (db *LDBDatabase) NewIterator() iterator.Iterator {
 	return db.db.NewIterator(nil, nil)
 }
 
+// Iterate implements Iteratee, calling fn with every entry of the database.
+func (db *LDBDatabase) Iterate(fn func(key, value []byte) error) error {
+	it := db.db.NewIterator(nil, nil)
+	defer it.Release()
+
+	for it.Next() {
+		if err := fn(it.Key(), it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+// IteratePrefix implements Iteratee, calling fn with every entry of the database
+// whose key starts with prefix.
+func (db *LDBDatabase) IteratePrefix(prefix []byte, fn func(key, value []byte) error) error {
+	it := db.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer it.Release()
+
+	for it.Next() {
+		if err := fn(it.Key(), it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+// Compact implements Compacter, compacting the entire database.
+func (db *LDBDatabase) Compact() error {
+	return db.db.CompactRange(util.Range{})
+}
+
 func (db *LDBDatabase) Close() {
 	// Stop the metrics collection to avoid internal database races
 	db.quitLock.Lock()
@@ -0,0 +1,84 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquadb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// LevelDBEngine is the goleveldb backed storage engine.
+	LevelDBEngine = "leveldb"
+
+	// DefaultEngine is the engine used for new databases if none is requested.
+	DefaultEngine = LevelDBEngine
+)
+
+// Opener opens the database stored in the directory file, creating it if it
+// doesn't exist yet, using cache megabytes of memory and handles file handles.
+type Opener func(file string, cache int, handles int) (Database, error)
+
+// engines are the storage engines compiled into the binary.
+var engines = map[string]Opener{
+	LevelDBEngine: func(file string, cache int, handles int) (Database, error) {
+		return NewLDBDatabase(file, cache, handles)
+	},
+}
+
+// Engines returns the names of the storage engines compiled into the binary.
+func Engines() []string {
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DetectEngine returns the storage engine of the database in the directory, or
+// an empty string if there is no database.
+func DetectEngine(file string) string {
+	if _, err := os.Stat(filepath.Join(file, "CURRENT")); err == nil {
+		return LevelDBEngine
+	}
+	return ""
+}
+
+// Open opens the database in the directory with the given storage engine. An
+// existing database is opened with the engine it was created with if engine is
+// empty, and refused if created with a different one. New databases are created
+// with the DefaultEngine unless requested otherwise.
+func Open(engine string, file string, cache int, handles int) (Database, error) {
+	existing := DetectEngine(file)
+	switch {
+	case engine == "" && existing != "":
+		engine = existing
+	case engine == "":
+		engine = DefaultEngine
+	case existing != "" && existing != engine:
+		return nil, fmt.Errorf("database %s uses the %s engine, not %s", file, existing, engine)
+	}
+	open, ok := engines[engine]
+	if !ok {
+		return nil, fmt.Errorf("database engine %q not available, supported: %s", engine, strings.Join(Engines(), ", "))
+	}
+	return open(file, cache, handles)
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquadb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+)
+
+// Tests that existing databases are opened with the engine they were created
+// with, and refused if another one is requested.
+func TestOpenEngine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aquadb-engine-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ldbdir := filepath.Join(dir, "leveldb")
+	if engine := aquadb.DetectEngine(ldbdir); engine != "" {
+		t.Fatalf("missing database detected as %q", engine)
+	}
+	db, err := aquadb.Open("", ldbdir, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create default database: %v", err)
+	}
+	db.Close()
+
+	if engine := aquadb.DetectEngine(ldbdir); engine != aquadb.LevelDBEngine {
+		t.Fatalf("engine mismatch: have %q, want %q", engine, aquadb.LevelDBEngine)
+	}
+	if _, err := aquadb.Open("nosuchengine", ldbdir, 0, 0); err == nil {
+		t.Fatalf("leveldb database opened with another engine")
+	}
+	if _, err := aquadb.Open("nosuchengine", filepath.Join(dir, "other"), 0, 0); err == nil {
+		t.Fatalf("unknown engine opened")
+	}
+}
+
+func TestLDB_Iterate(t *testing.T) {
+	db, remove := newTestLDB()
+	defer remove()
+	testIterate(db, t)
+}
+
+func TestMemoryDB_Iterate(t *testing.T) {
+	db, _ := aquadb.NewMemDatabase()
+	testIterate(db, t)
+}
+
+func testIterate(db aquadb.Database, t *testing.T) {
+	for _, v := range []string{"c", "a", "b"} {
+		db.Put([]byte(v), []byte(v+v))
+	}
+	var keys string
+	err := db.(aquadb.Iteratee).Iterate(func(key, value []byte) error {
+		if string(value) != string(key)+string(key) {
+			t.Errorf("value mismatch for %q: have %q", key, value)
+		}
+		keys += string(key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	if keys != "abc" {
+		t.Errorf("iteration order mismatch: have %q, want %q", keys, "abc")
+	}
+	db.Put([]byte("ba"), []byte("baba"))
+	keys = ""
+	err = db.(aquadb.Iteratee).IteratePrefix([]byte("b"), func(key, value []byte) error {
+		keys += string(key) + ","
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("prefix iteration failed: %v", err)
+	}
+	if keys != "b,ba," {
+		t.Errorf("prefix iteration mismatch: have %q, want %q", keys, "b,ba,")
+	}
+}
@@ -35,6 +35,25 @@ type Database interface {
 	NewBatch() Batch
 }
 
+// Iteratee is implemented by databases able to iterate over all their entries.
+type Iteratee interface {
+	// Iterate calls fn with every key-value pair of the database in ascending key
+	// order, stopping at the first error. The slices are only valid during the
+	// call.
+	Iterate(fn func(key, value []byte) error) error
+
+	// IteratePrefix is like Iterate, only calling fn with the entries whose key
+	// starts with prefix.
+	IteratePrefix(prefix []byte, fn func(key, value []byte) error) error
+}
+
+// Compacter is implemented by databases able to compact their storage.
+type Compacter interface {
+	// Compact compacts the entire key range, releasing the disk space of deleted
+	// and overwritten entries.
+	Compact() error
+}
+
 // Batch is a write-only database that commits changes to its host database
 // when Write is called. Batch cannot be used concurrently.
 type Batch interface {
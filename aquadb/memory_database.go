@@ -17,7 +17,10 @@
 package aquadb
 
 import (
+	"bytes"
 	"errors"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/aquanetwork/aquachain/common"
@@ -80,6 +83,33 @@ func (db *MemDatabase) Keys() [][]byte {
 	return keys
 }
 
+// Iterate implements Iteratee, calling fn with a snapshot of the entries of the
+// database, so fn may modify it.
+func (db *MemDatabase) Iterate(fn func(key, value []byte) error) error {
+	return db.IteratePrefix(nil, fn)
+}
+
+// IteratePrefix implements Iteratee, calling fn with a snapshot of the entries
+// of the database whose key starts with prefix, so fn may modify it.
+func (db *MemDatabase) IteratePrefix(prefix []byte, fn func(key, value []byte) error) error {
+	db.lock.RLock()
+	entries := make([]kv, 0, len(db.db))
+	for key, value := range db.db {
+		if strings.HasPrefix(key, string(prefix)) {
+			entries = append(entries, kv{[]byte(key), value})
+		}
+	}
+	db.lock.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].k, entries[j].k) < 0 })
+	for _, entry := range entries {
+		if err := fn(entry.k, entry.v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (db *MemDatabase) Delete(key []byte) error {
 	db.lock.Lock()
 	defer db.lock.Unlock()
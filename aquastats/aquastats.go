@@ -84,11 +84,9 @@ type Service struct {
 
 // New returns a monitoring service ready for stats reporting.
 func New(url string, ethServ *aqua.AquaChain, lesServ *les.LightAquaChain) (*Service, error) {
-	// Parse the netstats connection url
-	re := regexp.MustCompile("([^:@]*)(:([^@]*))?@(.+)")
-	parts := re.FindStringSubmatch(url)
-	if len(parts) != 5 {
-		return nil, fmt.Errorf("invalid netstats url: \"%s\", should be nodename:secret@host:port", url)
+	node, pass, host, err := parseURL(url)
+	if err != nil {
+		return nil, err
 	}
 	// Assemble and return the stats service
 	var engine consensus.Engine
@@ -101,14 +99,25 @@ func New(url string, ethServ *aqua.AquaChain, lesServ *les.LightAquaChain) (*Ser
 		aqua:   ethServ,
 		les:    lesServ,
 		engine: engine,
-		node:   parts[1],
-		pass:   parts[3],
-		host:   parts[4],
+		node:   node,
+		pass:   pass,
+		host:   host,
 		pongCh: make(chan struct{}),
 		histCh: make(chan []uint64, 1),
 	}, nil
 }
 
+// parseURL splits a netstats connection url into the name of the node, the
+// secret of the monitoring server and its address.
+func parseURL(url string) (node, pass, host string, err error) {
+	re := regexp.MustCompile("([^:@]*)(:([^@]*))?@(.+)")
+	parts := re.FindStringSubmatch(url)
+	if len(parts) != 5 {
+		return "", "", "", fmt.Errorf("invalid netstats url: \"%s\", should be nodename:secret@host[:port]", url)
+	}
+	return parts[1], parts[3], parts[4], nil
+}
+
 // Protocols implements node.Service, returning the P2P network protocols used
 // by the stats service (nil as it doesn't use the devp2p overlay network).
 func (s *Service) Protocols() []p2p.Protocol { return nil }
@@ -426,6 +435,9 @@ func (s *Service) report(conn *websocket.Conn) error {
 	if err := s.reportStats(conn); err != nil {
 		return err
 	}
+	if err := s.reportPeers(conn); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -634,25 +646,27 @@ func (s *Service) reportHistory(conn *websocket.Conn, list []uint64) error {
 // pendStats is the information to report about pending transactions.
 type pendStats struct {
 	Pending int `json:"pending"`
+	Queued  int `json:"queued"` // Always zero on light nodes, which don't queue
 }
 
-// reportPending retrieves the current number of pending transactions and reports
-// it to the stats server.
+// reportPending retrieves the current number of pending and queued transactions
+// and reports it to the stats server.
 func (s *Service) reportPending(conn *websocket.Conn) error {
-	// Retrieve the pending count from the local blockchain
-	var pending int
+	// Retrieve the pool counts from the local blockchain
+	var pending, queued int
 	if s.aqua != nil {
-		pending, _ = s.aqua.TxPool().Stats()
+		pending, queued = s.aqua.TxPool().Stats()
 	} else {
 		pending = s.les.TxPool().Stats()
 	}
 	// Assemble the transaction stats and send it to the server
-	log.Trace("Sending pending transactions to aquastats", "count", pending)
+	log.Trace("Sending pending transactions to aquastats", "pending", pending, "queued", queued)
 
 	stats := map[string]interface{}{
 		"id": s.node,
 		"stats": &pendStats{
 			Pending: pending,
+			Queued:  queued,
 		},
 	}
 	report := map[string][]interface{}{
@@ -672,7 +686,7 @@ type nodeStats struct {
 	Uptime   int  `json:"uptime"`
 }
 
-// reportPending retrieves various stats about the node at the networking and
+// reportStats retrieves various stats about the node at the networking and
 // mining layer and reports it to the stats server.
 func (s *Service) reportStats(conn *websocket.Conn) error {
 	// Gather the syncing and mining infos from the local miner instance
@@ -687,13 +701,13 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 		hashrate = int(s.aqua.Miner().HashRate())
 
 		sync := s.aqua.Downloader().Progress()
-		syncing = s.aqua.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestBlock
+		syncing = s.aqua.BlockChain().CurrentHeader().Number.Uint64() < sync.HighestBlock
 
 		price, _ := s.aqua.ApiBackend.SuggestPrice(context.Background())
 		gasprice = int(price.Uint64())
 	} else {
 		sync := s.les.Downloader().Progress()
-		syncing = s.les.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestBlock
+		syncing = s.les.BlockChain().CurrentHeader().Number.Uint64() < sync.HighestBlock
 	}
 	// Assemble the node stats and send it to the server
 	log.Trace("Sending node details to aquastats")
@@ -715,3 +729,56 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 	}
 	return websocket.JSON.Send(conn, report)
 }
+
+// peerStats is the information to report about individual peers. Addresses are
+// left out, the dashboards being public.
+type peerStats struct {
+	Id         string   `json:"id"`         // Abbreviated node identifier
+	Name       string   `json:"name"`       // Client type, version and OS
+	Inbound    bool     `json:"inbound"`    // Whether the peer dialed in
+	Protocol   string   `json:"protocol"`   // Negotiated chain protocol, empty if none yet
+	Head       string   `json:"head"`       // Hash of the best block of the peer, if known
+	Difficulty *big.Int `json:"difficulty"` // Total difficulty of the peer's chain, if known
+}
+
+// assemblePeerStats abbreviates the metadata of the connected peers into the
+// stats to report.
+func assemblePeerStats(infos []*p2p.PeerInfo) []*peerStats {
+	peers := make([]*peerStats, 0, len(infos))
+	for _, info := range infos {
+		stats := &peerStats{
+			Id:      info.ID,
+			Name:    info.Name,
+			Inbound: info.Network.Inbound,
+		}
+		if len(stats.Id) > 16 {
+			stats.Id = stats.Id[:16]
+		}
+		for _, name := range []string{"aqua", "les"} {
+			if proto, ok := info.Protocols[name].(*aqua.PeerInfo); ok {
+				stats.Protocol = fmt.Sprintf("%s/%d", name, proto.Version)
+				stats.Head, stats.Difficulty = proto.Head, proto.Difficulty
+				break
+			}
+		}
+		peers = append(peers, stats)
+	}
+	return peers
+}
+
+// reportPeers retrieves the connected peers and reports them to the stats server.
+func (s *Service) reportPeers(conn *websocket.Conn) error {
+	peers := assemblePeerStats(s.server.PeersInfo())
+
+	// Assemble the peer stats and send it to the server
+	log.Trace("Sending peer details to aquastats", "count", len(peers))
+
+	stats := map[string]interface{}{
+		"id":    s.node,
+		"peers": peers,
+	}
+	report := map[string][]interface{}{
+		"emit": {"peers", stats},
+	}
+	return websocket.JSON.Send(conn, report)
+}
@@ -0,0 +1,82 @@
+// Copyright 2016 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquastats
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aqua"
+	"github.com/aquanetwork/aquachain/p2p"
+)
+
+// Tests that netstats urls are split into node name, secret and host, with or
+// without a port.
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url              string
+		node, pass, host string
+		fail             bool
+	}{
+		{url: "mynode:s3cret@stats.aquacha.in", node: "mynode", pass: "s3cret", host: "stats.aquacha.in"},
+		{url: "mynode:s3cret@stats.aquacha.in:3000", node: "mynode", pass: "s3cret", host: "stats.aquacha.in:3000"},
+		{url: "mynode@ws://localhost:3000", node: "mynode", host: "ws://localhost:3000"},
+		{url: "stats.aquacha.in", fail: true},
+	}
+	for i, tt := range tests {
+		node, pass, host, err := parseURL(tt.url)
+		if (err != nil) != tt.fail {
+			t.Errorf("test %d: error mismatch: have %v, want failure %v", i, err, tt.fail)
+			continue
+		}
+		if node != tt.node || pass != tt.pass || host != tt.host {
+			t.Errorf("test %d: parts mismatch: have %q/%q/%q, want %q/%q/%q", i, node, pass, host, tt.node, tt.pass, tt.host)
+		}
+	}
+}
+
+// Tests that peer stats leave out the addresses, and pick up the chain protocol
+// of full and light peers.
+func TestPeerStats(t *testing.T) {
+	full := &p2p.PeerInfo{
+		ID:        "0123456789abcdef0123456789abcdef",
+		Name:      "AquaChain/v1.7",
+		Protocols: map[string]interface{}{"aqua": &aqua.PeerInfo{Version: 63, Head: "abcd", Difficulty: big.NewInt(100)}},
+	}
+	full.Network.RemoteAddress = "10.0.0.1:21303"
+	full.Network.Inbound = true
+
+	light := &p2p.PeerInfo{
+		ID:        "fedcba",
+		Protocols: map[string]interface{}{"les": &aqua.PeerInfo{Version: 2, Head: "ef01", Difficulty: big.NewInt(50)}},
+	}
+	handshaking := &p2p.PeerInfo{ID: "00", Protocols: map[string]interface{}{"aqua": "handshake"}}
+
+	peers := assemblePeerStats([]*p2p.PeerInfo{full, light, handshaking})
+	if len(peers) != 3 {
+		t.Fatalf("peer count mismatch: have %d, want 3", len(peers))
+	}
+	if p := peers[0]; p.Id != "0123456789abcdef" || !p.Inbound || p.Protocol != "aqua/63" || p.Head != "abcd" || p.Difficulty.Int64() != 100 {
+		t.Errorf("full peer stats mismatch: %+v", p)
+	}
+	if p := peers[1]; p.Protocol != "les/2" || p.Head != "ef01" {
+		t.Errorf("light peer stats mismatch: %+v", p)
+	}
+	if p := peers[2]; p.Protocol != "" || p.Difficulty != nil {
+		t.Errorf("handshaking peer stats mismatch: %+v", p)
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright 2016 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package aquastream implements the chain event streaming service, pushing new
+// blocks, reorgs and matching logs to an external sink.
+package aquastream
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/p2p"
+	"github.com/aquanetwork/aquachain/rlp"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	maxBatchBlocks = 64              // Maximum number of blocks delivered to the sink at once
+	minRetryDelay  = time.Second     // Delay before retrying a failed delivery
+	maxRetryDelay  = 2 * time.Minute // Maximum delay between the retries of a failed delivery
+)
+
+// The types of the streamed events.
+const (
+	EventBlock = "block" // A block was added to the canonical chain
+	EventReorg = "reorg" // Blocks left the canonical chain, rolling it back to a block
+)
+
+// cursorPrefix + keccak256(sink) -> last block delivered to the sink
+var cursorPrefix = []byte("aquastream-cursor-")
+
+var errStopped = errors.New("stream stopped")
+
+// Config are the settings of the streaming service.
+type Config struct {
+	// Sink is the URL events are pushed to: an http(s) webhook receiving them as
+	// JSON arrays, or kafka://host:port/topic for a Kafka REST proxy.
+	Sink string
+
+	// Addresses and Topics filter the logs included in block events the way
+	// aqua_getLogs does: logs of any of the addresses, with any of the topics
+	// of every position. Empty filters match everything.
+	Addresses []common.Address
+	Topics    [][]common.Hash
+
+	// From is the first block streamed when no delivery to the sink was made
+	// yet, the head of the chain if zero. Later the stream resumes after the last
+	// block delivered.
+	From uint64
+}
+
+// Event is a chain event pushed to the sink. Block events carry the logs of the
+// block passing the filters. Reorg events carry the hashes of the blocks that
+// left the canonical chain, newest first, and name the block it was rolled back
+// to; the block events of the new chain follow.
+type Event struct {
+	Type         string         `json:"type"`
+	Number       hexutil.Uint64 `json:"number"`
+	Hash         common.Hash    `json:"hash"`
+	ParentHash   common.Hash    `json:"parentHash,omitempty"`
+	Time         *hexutil.Big   `json:"timestamp,omitempty"`
+	Transactions int            `json:"transactions,omitempty"`
+	Logs         []*types.Log   `json:"logs,omitempty"`
+	Dropped      []common.Hash  `json:"dropped,omitempty"`
+}
+
+// cursor is the last block delivered to the sink.
+type cursor struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// Service streams the chain events to a sink, delivering each at least once:
+// the stream resumes after the last block the sink accepted, so events delivered
+// just before an interruption may be repeated.
+type Service struct {
+	config Config
+	chain  *core.BlockChain
+	db     aquadb.Database
+	sink   Sink
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// New creates a streaming service pushing the events of the chain to the sink of
+// the configuration.
+func New(config Config, chain *core.BlockChain, db aquadb.Database) (*Service, error) {
+	sink, err := NewSink(config.Sink)
+	if err != nil {
+		return nil, err
+	}
+	return newService(config, chain, db, sink), nil
+}
+
+func newService(config Config, chain *core.BlockChain, db aquadb.Database, sink Sink) *Service {
+	return &Service{
+		config: config,
+		chain:  chain,
+		db:     db,
+		sink:   sink,
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Protocols implements node.Service, returning the P2P network protocols used
+// by the streaming service (nil as it doesn't use the devp2p overlay network).
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the RPC API endpoints provided by the
+// streaming service (nil as it doesn't provide any user callable APIs).
+func (s *Service) APIs() []rpc.API { return nil }
+
+// Start implements node.Service, starting up the streaming of the chain events.
+func (s *Service) Start(server *p2p.Server) error {
+	go s.loop()
+
+	log.Info("Chain event stream started", "sink", s.config.Sink)
+	return nil
+}
+
+// Stop implements node.Service, terminating the streaming of the chain events.
+func (s *Service) Stop() error {
+	close(s.quit)
+	<-s.done
+
+	log.Info("Chain event stream stopped")
+	return nil
+}
+
+// loop streams the chain events up to the head of the chain whenever it changes.
+func (s *Service) loop() {
+	defer close(s.done)
+
+	heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	sub := s.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		if err := s.stream(); err == errStopped {
+			return
+		}
+		select {
+		case <-heads:
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// stream delivers the events from the last delivered block up to the head of
+// the chain.
+func (s *Service) stream() error {
+	last, ok := s.cursor()
+	if !ok {
+		head := s.chain.CurrentBlock()
+		last = cursor{Number: head.NumberU64(), Hash: head.Hash()}
+		if s.config.From > 0 && s.config.From <= head.NumberU64() {
+			parent := s.chain.GetHeaderByNumber(s.config.From - 1)
+			last = cursor{Number: parent.Number.Uint64(), Hash: parent.Hash()}
+		}
+		log.Info("Starting chain event stream", "number", last.Number+1)
+		if err := s.writeCursor(last); err != nil {
+			return err
+		}
+	}
+	// Roll the stream back if the last delivered block left the canonical chain
+	if reorg := s.reorg(last); reorg != nil {
+		if err := s.deliver([]*Event{reorg}); err != nil {
+			return err
+		}
+		last = cursor{Number: uint64(reorg.Number), Hash: reorg.Hash}
+		if err := s.writeCursor(last); err != nil {
+			return err
+		}
+	}
+	for {
+		head := s.chain.CurrentBlock().NumberU64()
+		if last.Number >= head {
+			return nil
+		}
+		var events []*Event
+		for number := last.Number + 1; number <= head && len(events) < maxBatchBlocks; number++ {
+			block := s.chain.GetBlockByNumber(number)
+			if block == nil || (number == last.Number+1 && block.ParentHash() != last.Hash) {
+				break // Reorged meanwhile, picked up on the next head event
+			}
+			events = append(events, s.blockEvent(block))
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		if err := s.deliver(events); err != nil {
+			return err
+		}
+		end := events[len(events)-1]
+		last = cursor{Number: uint64(end.Number), Hash: end.Hash}
+		if err := s.writeCursor(last); err != nil {
+			return err
+		}
+	}
+}
+
+// reorg returns the reorg event rolling the stream back from the last delivered
+// block to its newest canonical ancestor, nil if it's still canonical.
+func (s *Service) reorg(last cursor) *Event {
+	canonical := func(number uint64, hash common.Hash) bool {
+		header := s.chain.GetHeaderByNumber(number)
+		return header != nil && header.Hash() == hash
+	}
+	if canonical(last.Number, last.Hash) {
+		return nil
+	}
+	var (
+		dropped = []common.Hash{last.Hash}
+		header  = s.chain.GetHeader(last.Hash, last.Number)
+		number  uint64
+	)
+	if last.Number > 0 {
+		number = last.Number - 1
+	}
+	for header != nil && number > 0 && !canonical(number, header.ParentHash) {
+		dropped = append(dropped, header.ParentHash)
+		header = s.chain.GetHeader(header.ParentHash, number)
+		number--
+	}
+	// If the dropped blocks can't be followed any further, roll back below the
+	// ones known, and no further than the head
+	if head := s.chain.CurrentBlock().NumberU64(); number > head {
+		number = head
+	}
+	ancestor := s.chain.GetHeaderByNumber(number)
+	log.Info("Chain event stream reorged", "number", number, "hash", ancestor.Hash(), "dropped", len(dropped))
+	return &Event{Type: EventReorg, Number: hexutil.Uint64(number), Hash: ancestor.Hash(), Dropped: dropped}
+}
+
+// blockEvent assembles the event of a canonical block, with its logs passing the
+// filters.
+func (s *Service) blockEvent(block *types.Block) *Event {
+	ev := &Event{
+		Type:         EventBlock,
+		Number:       hexutil.Uint64(block.NumberU64()),
+		Hash:         block.Hash(),
+		ParentHash:   block.ParentHash(),
+		Time:         (*hexutil.Big)(block.Time()),
+		Transactions: len(block.Transactions()),
+	}
+	for _, receipt := range core.GetBlockReceipts(s.db, block.Hash(), block.NumberU64()) {
+		for _, log := range receipt.Logs {
+			if matchLog(log, s.config.Addresses, s.config.Topics) {
+				ev.Logs = append(ev.Logs, log)
+			}
+		}
+	}
+	return ev
+}
+
+// matchLog returns whether a log is of any of the addresses and has any of the
+// topics of every position, empty filters matching everything.
+func matchLog(log *types.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		var found bool
+		for _, addr := range addresses {
+			if log.Address == addr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(topics) > len(log.Topics) {
+		return false
+	}
+	for i, alternatives := range topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+		var found bool
+		for _, topic := range alternatives {
+			if log.Topics[i] == topic {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// deliver pushes events to the sink, retrying with increasing delays until it
+// accepts them or the service stops.
+func (s *Service) deliver(events []*Event) error {
+	delay := minRetryDelay
+	for {
+		err := s.sink.Send(events)
+		if err == nil {
+			streamEventMeter.Mark(int64(len(events)))
+			return nil
+		}
+		streamFailureMeter.Mark(1)
+		log.Warn("Failed to deliver chain events", "first", events[0].Number, "count", len(events), "retry", delay, "err", err)
+
+		select {
+		case <-time.After(delay):
+		case <-s.quit:
+			return errStopped
+		}
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+func (s *Service) cursorKey() []byte {
+	return append(append([]byte{}, cursorPrefix...), crypto.Keccak256([]byte(s.config.Sink))...)
+}
+
+// cursor retrieves the last block delivered to the sink, returning false if the
+// stream didn't start yet.
+func (s *Service) cursor() (cursor, bool) {
+	var last cursor
+	data, _ := s.db.Get(s.cursorKey())
+	if len(data) == 0 {
+		return last, false
+	}
+	if err := rlp.DecodeBytes(data, &last); err != nil {
+		log.Error("Invalid chain event stream cursor", "err", err)
+		return last, false
+	}
+	return last, true
+}
+
+// writeCursor stores the last block delivered to the sink.
+func (s *Service) writeCursor(last cursor) error {
+	data, err := rlp.EncodeToBytes(last)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(s.cursorKey(), data)
+}
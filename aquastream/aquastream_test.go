@@ -0,0 +1,222 @@
+// Copyright 2016 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquastream
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// testSink records the delivered events, refusing the given number of batches
+// first.
+type testSink struct {
+	events chan *Event
+	fails  int32
+}
+
+func newTestSink(fails int32) *testSink {
+	return &testSink{events: make(chan *Event, 256), fails: fails}
+}
+
+func (s *testSink) Send(events []*Event) error {
+	if atomic.AddInt32(&s.fails, -1) >= 0 {
+		return errors.New("sink unavailable")
+	}
+	for _, ev := range events {
+		s.events <- ev
+	}
+	return nil
+}
+
+// expect waits for the next event delivered to the sink, checking it's of the
+// given type and block.
+func (s *testSink) expect(t *testing.T, typ string, number uint64, hash common.Hash) *Event {
+	t.Helper()
+	select {
+	case ev := <-s.events:
+		if ev.Type != typ || uint64(ev.Number) != number || ev.Hash != hash {
+			t.Fatalf("event mismatch: have %s #%d [%x], want %s #%d [%x]", ev.Type, ev.Number, ev.Hash[:4], typ, number, hash[:4])
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s event #%d not delivered", typ, number)
+	}
+	return nil
+}
+
+// Tests that blocks and their matching logs are streamed once the sink accepts
+// them, that reorgs roll the stream back, and that it resumes after the last
+// delivered block.
+func TestStream(t *testing.T) {
+	var (
+		db, _   = aquadb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+		addr    = common.Address{0xaa}
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, aquahash.NewFaker(), db, 5, nil)
+	fork, _ := core.GenerateChain(gspec.Config, blocks[2], aquahash.NewFaker(), db, 5, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{0xc0})
+	})
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Give the third block a matching log and another
+	receipts := types.Receipts{{Logs: []*types.Log{
+		{Address: addr, BlockNumber: 3, BlockHash: blocks[2].Hash()},
+		{Address: common.Address{0xbb}, BlockNumber: 3, BlockHash: blocks[2].Hash(), Index: 1},
+	}}}
+	core.WriteBlockReceipts(db, blocks[2].Hash(), 3, receipts)
+
+	// Stream from the first block, the sink refusing the first delivery
+	sink := newTestSink(1)
+	service := newService(Config{Sink: "test", Addresses: []common.Address{addr}, From: 1}, chain, db, sink)
+	service.Start(nil)
+
+	for i, block := range blocks {
+		ev := sink.expect(t, EventBlock, uint64(i+1), block.Hash())
+		if want := map[bool]int{true: 1, false: 0}[i == 2]; len(ev.Logs) != want {
+			t.Errorf("block #%d: log count mismatch: have %d, want %d", i+1, len(ev.Logs), want)
+		}
+	}
+	// Reorg the chain, rolling the stream back to the fork point
+	if _, err := chain.InsertChain(fork[:4]); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	ev := sink.expect(t, EventReorg, 3, blocks[2].Hash())
+	if want := []common.Hash{blocks[4].Hash(), blocks[3].Hash()}; len(ev.Dropped) != 2 || ev.Dropped[0] != want[0] || ev.Dropped[1] != want[1] {
+		t.Errorf("dropped blocks mismatch: have %x, want %x", ev.Dropped, want)
+	}
+	for i, block := range fork[:4] {
+		sink.expect(t, EventBlock, uint64(i+4), block.Hash())
+	}
+	service.Stop()
+
+	// Restart the stream, resuming after the last delivered block
+	if _, err := chain.InsertChain(fork[4:]); err != nil {
+		t.Fatalf("failed to extend fork: %v", err)
+	}
+	sink = newTestSink(0)
+	service = newService(Config{Sink: "test", From: 1}, chain, db, sink)
+	service.Start(nil)
+	defer service.Stop()
+
+	sink.expect(t, EventBlock, 8, fork[4].Hash())
+	select {
+	case ev := <-sink.events:
+		t.Fatalf("unexpected %s event #%d", ev.Type, ev.Number)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// Tests that logs are filtered by address and by position of the topics.
+func TestMatchLog(t *testing.T) {
+	var (
+		addr  = common.Address{0x01}
+		topic = common.Hash{0x02}
+		other = common.Hash{0x03}
+		log   = &types.Log{Address: addr, Topics: []common.Hash{topic, other}}
+	)
+	tests := []struct {
+		addresses []common.Address
+		topics    [][]common.Hash
+		match     bool
+	}{
+		{nil, nil, true},
+		{[]common.Address{addr}, nil, true},
+		{[]common.Address{{0x09}}, nil, false},
+		{nil, [][]common.Hash{{topic}}, true},
+		{nil, [][]common.Hash{{other}}, false},
+		{nil, [][]common.Hash{nil, {topic, other}}, true},
+		{nil, [][]common.Hash{nil, nil, {topic}}, false},
+		{[]common.Address{{0x09}, addr}, [][]common.Hash{{other, topic}}, true},
+	}
+	for i, tt := range tests {
+		if match := matchLog(log, tt.addresses, tt.topics); match != tt.match {
+			t.Errorf("test %d: match mismatch: have %v, want %v", i, match, tt.match)
+		}
+	}
+}
+
+// Tests that the sinks are created from their URLs, and that webhooks receive
+// the events as JSON arrays.
+func TestWebhookSink(t *testing.T) {
+	for _, url := range []string{"ftp://example.com", "kafka://localhost:8082", "kafka:///topic", "kafka://localhost:8082/a/b"} {
+		if _, err := NewSink(url); err == nil {
+			t.Errorf("invalid sink %q accepted", url)
+		}
+	}
+	sink, err := NewSink("kafka://localhost:8082/chain")
+	if err != nil {
+		t.Fatalf("failed to create kafka sink: %v", err)
+	}
+	if url := sink.(*kafkaSink).url; url != "http://localhost:8082/topics/chain" {
+		t.Errorf("kafka proxy url mismatch: have %s, want http://localhost:8082/topics/chain", url)
+	}
+	received := make(chan []*Event, 1)
+	refuse := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if refuse {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var events []*Event
+		if err := json.Unmarshal(body, &events); err != nil {
+			t.Errorf("invalid webhook body: %v", err)
+		}
+		received <- events
+	}))
+	defer server.Close()
+
+	if sink, err = NewSink(server.URL); err != nil {
+		t.Fatalf("failed to create webhook sink: %v", err)
+	}
+	events := []*Event{{Type: EventBlock, Number: 1, Hash: common.Hash{0x01}, Time: (*hexutil.Big)(big.NewInt(10))}}
+	if err := sink.Send(events); err == nil {
+		t.Fatalf("refused events reported delivered")
+	}
+	refuse = false
+	if err := sink.Send(events); err != nil {
+		t.Fatalf("failed to deliver events: %v", err)
+	}
+	if got := <-received; len(got) != 1 || got[0].Hash != events[0].Hash {
+		t.Errorf("received events mismatch: have %v, want %v", got, events)
+	}
+}
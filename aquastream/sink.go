@@ -0,0 +1,118 @@
+// Copyright 2016 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquastream
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sinkTimeout is the time allowed to a sink to accept a batch of events.
+const sinkTimeout = 30 * time.Second
+
+// Sink is an external system the chain events are pushed to.
+type Sink interface {
+	// Send delivers a batch of events, in order, returning an error unless all
+	// of them were accepted.
+	Send(events []*Event) error
+}
+
+// NewSink creates the sink of a URL: http and https URLs are webhooks receiving
+// the events POSTed as JSON arrays, and kafka://host:port/topic URLs publish the
+// events to a Kafka topic through the REST proxy at host:port.
+func NewSink(rawurl string) (Sink, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: sinkTimeout}
+	switch u.Scheme {
+	case "http", "https":
+		return &webhookSink{url: rawurl, client: client}, nil
+	case "kafka":
+		topic := strings.Trim(u.Path, "/")
+		if u.Host == "" || topic == "" || strings.Contains(topic, "/") {
+			return nil, fmt.Errorf("invalid kafka sink %q, want kafka://host:port/topic", rawurl)
+		}
+		proxy := url.URL{Scheme: "http", Host: u.Host, Path: "/topics/" + topic}
+		return &kafkaSink{url: proxy.String(), client: client}, nil
+	}
+	return nil, fmt.Errorf("unsupported sink %q, want an http(s) or kafka URL", rawurl)
+}
+
+// webhookSink posts the events to a webhook.
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// Send implements Sink, posting the events as a JSON array.
+func (s *webhookSink) Send(events []*Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	return post(s.client, s.url, "application/json", body)
+}
+
+// kafkaSink publishes the events to a Kafka topic through a REST proxy, keyed by
+// the hash of their block to keep the events of a block in one partition.
+type kafkaSink struct {
+	url    string
+	client *http.Client
+}
+
+// kafkaRecord is a message produced through the Kafka REST proxy.
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value *Event `json:"value"`
+}
+
+// Send implements Sink, producing the events as the records of one request.
+func (s *kafkaSink) Send(events []*Event) error {
+	records := make([]kafkaRecord, len(events))
+	for i, ev := range events {
+		records[i] = kafkaRecord{Key: ev.Hash.Hex(), Value: ev}
+	}
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return err
+	}
+	return post(s.client, s.url, "application/vnd.kafka.json.v2+json", body)
+}
+
+// post sends a request body, failing unless it's accepted with a 2xx status.
+func post(client *http.Client, url, contentType string, body []byte) error {
+	resp, err := client.Post(url, contentType, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("sink refused events: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -29,7 +29,7 @@ import (
 )
 
 var (
-	abiFlag = flag.String("abi", "", "Path to the AquaChain contract ABI json to bind")
+	abiFlag = flag.String("abi", "", "Path to the AquaChain contract ABI json to bind, - for STDIN")
 	binFlag = flag.String("bin", "", "Path to the AquaChain contract bytecode (generate deploy method)")
 	typFlag = flag.String("type", "", "Struct name for the binding (default = package name)")
 
@@ -37,6 +37,8 @@ var (
 	solcFlag = flag.String("solc", "solc", "Solidity compiler to use if source builds are requested")
 	excFlag  = flag.String("exc", "", "Comma separated types to exclude from binding")
 
+	jsonFlag = flag.String("combined-json", "", "Path to the combined-json file generated by compiler, - for STDIN")
+
 	pkgFlag  = flag.String("pkg", "", "Package name to generate the binding into")
 	outFlag  = flag.String("out", "", "Output file for the generated binding (default = stdout)")
 	langFlag = flag.String("lang", "go", "Destination language for the bindings (go, java, objc)")
@@ -46,11 +48,14 @@ func main() {
 	// Parse and ensure all needed inputs are specified
 	flag.Parse()
 
-	if *abiFlag == "" && *solFlag == "" {
-		fmt.Printf("No contract ABI (--abi) or Solidity source (--sol) specified\n")
+	if *abiFlag == "" && *solFlag == "" && *jsonFlag == "" {
+		fmt.Printf("No contract ABI (--abi), Solidity source (--sol), or combined-json (--combined-json) specified\n")
+		os.Exit(-1)
+	} else if (*abiFlag != "" || *binFlag != "" || *typFlag != "") && (*solFlag != "" || *jsonFlag != "") {
+		fmt.Printf("Contract ABI (--abi), bytecode (--bin) and type (--type) flags are mutually exclusive with the Solidity source (--sol) and combined-json (--combined-json) flags\n")
 		os.Exit(-1)
-	} else if (*abiFlag != "" || *binFlag != "" || *typFlag != "") && *solFlag != "" {
-		fmt.Printf("Contract ABI (--abi), bytecode (--bin) and type (--type) flags are mutually exclusive with the Solidity source (--sol) flag\n")
+	} else if *solFlag != "" && *jsonFlag != "" {
+		fmt.Printf("Solidity source (--sol) and combined-json (--combined-json) flags are mutually exclusive\n")
 		os.Exit(-1)
 	}
 	if *pkgFlag == "" {
@@ -75,16 +80,33 @@ func main() {
 		bins  []string
 		types []string
 	)
-	if *solFlag != "" {
+	if *solFlag != "" || *jsonFlag != "" {
 		// Generate the list of types to exclude from binding
 		exclude := make(map[string]bool)
 		for _, kind := range strings.Split(*excFlag, ",") {
 			exclude[strings.ToLower(kind)] = true
 		}
-		contracts, err := compiler.CompileSolidity(*solcFlag, *solFlag)
-		if err != nil {
-			fmt.Printf("Failed to build Solidity contract: %v\n", err)
-			os.Exit(-1)
+		var (
+			contracts map[string]*compiler.Contract
+			err       error
+		)
+		if *solFlag != "" {
+			contracts, err = compiler.CompileSolidity(*solcFlag, *solFlag)
+			if err != nil {
+				fmt.Printf("Failed to build Solidity contract: %v\n", err)
+				os.Exit(-1)
+			}
+		} else {
+			output, err := readInput(*jsonFlag)
+			if err != nil {
+				fmt.Printf("Failed to read combined-json: %v\n", err)
+				os.Exit(-1)
+			}
+			contracts, err = compiler.ParseCombinedJSON(output, "", "", "", "")
+			if err != nil {
+				fmt.Printf("Failed to parse combined-json: %v\n", err)
+				os.Exit(-1)
+			}
 		}
 		// Gather all non-excluded contract for binding
 		for name, contract := range contracts {
@@ -93,14 +115,18 @@ func main() {
 			}
 			abi, _ := json.Marshal(contract.Info.AbiDefinition) // Flatten the compiler parse
 			abis = append(abis, string(abi))
-			bins = append(bins, contract.Code)
+			if contract.Code == "0x" {
+				bins = append(bins, "") // Bytecode not requested from the compiler
+			} else {
+				bins = append(bins, contract.Code)
+			}
 
 			nameParts := strings.Split(name, ":")
 			types = append(types, nameParts[len(nameParts)-1])
 		}
 	} else {
 		// Otherwise load up the ABI, optional bytecode and type name from the parameters
-		abi, err := ioutil.ReadFile(*abiFlag)
+		abi, err := readInput(*abiFlag)
 		if err != nil {
 			fmt.Printf("Failed to read input ABI: %v\n", err)
 			os.Exit(-1)
@@ -138,3 +164,12 @@ func main() {
 		os.Exit(-1)
 	}
 }
+
+// readInput reads the contents of the given file, or of the standard input if
+// the path is "-".
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(path)
+}
@@ -22,27 +22,38 @@ import (
 	"flag"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/aquanetwork/aquachain/cmd/utils"
 	"github.com/aquanetwork/aquachain/crypto"
 	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/metrics"
+	"github.com/aquanetwork/aquachain/metrics/exp"
 	"github.com/aquanetwork/aquachain/p2p/discover"
 	"github.com/aquanetwork/aquachain/p2p/discv5"
 	"github.com/aquanetwork/aquachain/p2p/nat"
 	"github.com/aquanetwork/aquachain/p2p/netutil"
 )
 
+// metricsInterval is how often the size of the node table is measured.
+const metricsInterval = 10 * time.Second
+
+// maxTableNodes is more than the node tables of both discovery protocols hold.
+const maxTableNodes = 4096
+
 func main() {
 	var (
 		listenAddr  = flag.String("addr", ":21000", "listen address")
 		genKey      = flag.String("genkey", "", "generate a node key")
 		writeAddr   = flag.Bool("writeaddress", false, "write out the node's pubkey hash and quit")
-		nodeKeyFile = flag.String("nodekey", "", "private key filename")
+		nodeKeyFile = flag.String("nodekey", "", "private key filename, generated if it doesn't exist")
 		nodeKeyHex  = flag.String("nodekeyhex", "", "private key as hex (for testing)")
 		natdesc     = flag.String("nat", "none", "port mapping mechanism (any|none|upnp|pmp|extip:<IP>)")
 		netrestrict = flag.String("netrestrict", "", "restrict network communication to the given IP networks (CIDR masks)")
 		runv5       = flag.Bool("v5", false, "run a v5 topic discovery bootnode")
+		metricsAddr = flag.String("metrics", "", "serve the table size metrics over HTTP on the given address")
 		verbosity   = flag.Int("verbosity", int(log.LvlInfo), "log verbosity (0-9)")
 		vmodule     = flag.String("vmodule", "", "log verbosity pattern")
 
@@ -75,7 +86,7 @@ func main() {
 	case *nodeKeyFile != "" && *nodeKeyHex != "":
 		utils.Fatalf("Options -nodekey and -nodekeyhex are mutually exclusive")
 	case *nodeKeyFile != "":
-		if nodeKey, err = crypto.LoadECDSA(*nodeKeyFile); err != nil {
+		if nodeKey, err = loadOrGenerateKey(*nodeKeyFile); err != nil {
 			utils.Fatalf("-nodekey: %v", err)
 		}
 	case *nodeKeyHex != "":
@@ -117,20 +128,92 @@ func main() {
 		}
 	}
 
+	if *metricsAddr != "" {
+		metrics.Enabled = true
+	}
+	var readNodes func([]*discover.Node) int
 	if *runv5 {
-		if _, err := discv5.ListenUDP(nodeKey, conn, realaddr, "", restrictList); err != nil {
+		network, err := discv5.ListenUDP(nodeKey, conn, realaddr, "", restrictList)
+		if err != nil {
 			utils.Fatalf("%v", err)
 		}
+		buf := make([]*discv5.Node, maxTableNodes)
+		readNodes = func([]*discover.Node) int { return network.ReadRandomNodes(buf) }
 	} else {
 		cfg := discover.Config{
 			PrivateKey:   nodeKey,
 			AnnounceAddr: realaddr,
 			NetRestrict:  restrictList,
 		}
-		if _, err := discover.ListenUDP(conn, cfg); err != nil {
+		table, err := discover.ListenUDP(conn, cfg)
+		if err != nil {
 			utils.Fatalf("%v", err)
 		}
+		readNodes = table.ReadRandomNodes
 	}
+	printSelf(nodeKey, realaddr)
 
-	select {}
+	if *metricsAddr != "" {
+		go func() {
+			log.Info("Starting metrics server", "addr", *metricsAddr)
+			if err := http.ListenAndServe(*metricsAddr, exp.ExpHandler(metrics.DefaultRegistry)); err != nil {
+				log.Error("Metrics server failed", "err", err)
+			}
+		}()
+	}
+	meterTable(readNodes)
+}
+
+// loadOrGenerateKey loads the node key from file, or generates and stores a
+// new one there if the file doesn't exist yet.
+func loadOrGenerateKey(file string) (*ecdsa.PrivateKey, error) {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		if err := crypto.SaveECDSA(file, key); err != nil {
+			return nil, err
+		}
+		log.Info("Generated new node key", "file", file)
+		return key, nil
+	}
+	return crypto.LoadECDSA(file)
+}
+
+// printSelf prints the enode URL and the node record of the bootnode, for the
+// nodes bootstrapping from it.
+func printSelf(key *ecdsa.PrivateKey, addr *net.UDPAddr) {
+	ip := addr.IP
+	if ip.IsUnspecified() {
+		log.Warn("Listening on all interfaces, use -nat extip:<IP> to advertise the public address")
+		ip = net.IP{127, 0, 0, 1}
+	}
+	self := discover.NewNode(discover.PubkeyID(&key.PublicKey), ip, uint16(addr.Port), uint16(addr.Port))
+	fmt.Println(self.String())
+
+	// Sign the record with the current time as sequence number, so restarts
+	// with a changed address supersede the old record
+	record, err := self.Record(key, uint64(time.Now().Unix()))
+	if err != nil {
+		utils.Fatalf("Failed to sign node record: %v", err)
+	}
+	text, err := record.Text()
+	if err != nil {
+		utils.Fatalf("Failed to encode node record: %v", err)
+	}
+	fmt.Println(text)
+}
+
+// meterTable periodically measures and logs the number of nodes in the table.
+func meterTable(readNodes func([]*discover.Node) int) {
+	var (
+		gauge = metrics.NewRegisteredGauge("bootnode/table/nodes", nil)
+		buf   = make([]*discover.Node, maxTableNodes)
+	)
+	for range time.Tick(metricsInterval) {
+		nodes := readNodes(buf)
+		gauge.Update(int64(nodes))
+		log.Debug("Node table measured", "nodes", nodes)
+	}
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/aquanetwork/aquachain/console"
 	"github.com/aquanetwork/aquachain/crypto"
 	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/node"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -75,6 +76,7 @@ Print a short summary of all accounts`,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KDFFlag,
 				},
 				Description: `
     aquachain account new
@@ -100,6 +102,8 @@ password to file or expose in any other way.
 					utils.DataDirFlag,
 					utils.KeyStoreDirFlag,
 					utils.LightKDFFlag,
+					utils.KDFFlag,
+					utils.PasswordFileFlag,
 				},
 				Description: `
     aquachain account update <address>
@@ -112,12 +116,19 @@ for a passphrase to unlock the account and another to save the updated file.
 This same command can therefore be used to migrate an account of a deprecated
 format to the newest format or change the password for an account.
 
+The keyfile is re-encrypted with the scrypt parameters selected by --kdf, so
+accounts taking long to unlock on low-memory devices can be moved to the light
+parameters, along with a passphrase change if wanted:
+
+    aquachain account update --kdf light <address>
+
 For non-interactive use the passphrase can be specified with the --password flag:
 
     aquachain account update [options] <address>
 
-Since only one password can be given, only format update can be performed,
-changing your password is only possible interactively.
+The first line of the password file unlocks the account and the second, if any,
+is the new passphrase. With a single line the passphrase is kept, only updating
+the format and scrypt parameters.
 `,
 			},
 			{
@@ -129,6 +140,7 @@ changing your password is only possible interactively.
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KDFFlag,
 				},
 				ArgsUsage: "<keyFile>",
 				Description: `
@@ -281,6 +293,16 @@ func accountCreate(ctx *cli.Context) error {
 	return nil
 }
 
+// fetchKeystore retrieves the keystore of the node, failing if accounts are
+// managed by an external signer or a remote key vault.
+func fetchKeystore(stack *node.Node) *keystore.KeyStore {
+	keystores := stack.AccountManager().Backends(keystore.KeyStoreType)
+	if len(keystores) == 0 {
+		utils.Fatalf("No local keystore, accounts are managed by the external signer or the remote key vault")
+	}
+	return keystores[0].(*keystore.KeyStore)
+}
+
 // accountUpdate transitions an account from a previous format to the current
 // one, also providing the possibility to change the pass-phrase.
 func accountUpdate(ctx *cli.Context) error {
@@ -288,14 +310,19 @@ func accountUpdate(ctx *cli.Context) error {
 		utils.Fatalf("No accounts specified to update")
 	}
 	stack, _ := makeConfigNode(ctx)
-	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
-
+	ks := fetchKeystore(stack)
+	passwords := utils.MakePasswordList(ctx)
+	if n := len(passwords); n > 1 && passwords[n-1] == "" {
+		// Don't take the end of the file for an empty new passphrase
+		passwords = passwords[:n-1]
+	}
 	for _, addr := range ctx.Args() {
-		account, oldPassword := unlockAccount(ctx, ks, addr, 0, nil)
-		newPassword := getPassPhrase("Please give a new password. Do not forget this password.", true, 0, nil)
+		account, oldPassword := unlockAccount(ctx, ks, addr, 0, passwords)
+		newPassword := getPassPhrase("Please give a new password. Do not forget this password.", true, 1, passwords)
 		if err := ks.Update(account, oldPassword, newPassword); err != nil {
 			utils.Fatalf("Could not update the account: %v", err)
 		}
+		log.Info("Updated account", "address", account.Address.Hex())
 	}
 	return nil
 }
@@ -313,7 +340,7 @@ func importWallet(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 	passphrase := getPassPhrase("", false, 0, utils.MakePasswordList(ctx))
 
-	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	ks := fetchKeystore(stack)
 	acct, err := ks.ImportPreSaleKey(keyJson, passphrase)
 	if err != nil {
 		utils.Fatalf("%v", err)
@@ -334,7 +361,7 @@ func accountImport(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 	passphrase := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
 
-	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	ks := fetchKeystore(stack)
 	acct, err := ks.ImportECDSA(key, passphrase)
 	if err != nil {
 		utils.Fatalf("Could not create the account: %v", err)
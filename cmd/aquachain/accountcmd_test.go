@@ -17,12 +17,14 @@
 package main
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/aquanetwork/aquachain/accounts/keystore"
 	"github.com/cespare/cp"
 )
 
@@ -106,6 +108,39 @@ Repeat passphrase: {{.InputLine "foobar2"}}
 `)
 }
 
+func TestAccountUpdateKDF(t *testing.T) {
+	datadir := tmpDatadirWithKeystore(t)
+	passfile := filepath.Join(datadir, "password")
+	if err := ioutil.WriteFile(passfile, []byte("foobar\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	aquachain := runAquaChain(t, "account", "update",
+		"--datadir", datadir, "--kdf", "light", "--password", passfile,
+		"f466859ead1932d743d622cb74fc058882e8648a")
+	aquachain.ExpectExit()
+
+	keyjson, err := ioutil.ReadFile(filepath.Join(datadir, "keystore", "aaa"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keystore.DecryptKey(keyjson, "foobar"); err != nil {
+		t.Fatalf("updated key failed to decrypt: %v", err)
+	}
+	var key struct {
+		Crypto struct {
+			KDFParams struct {
+				N int `json:"n"`
+			} `json:"kdfparams"`
+		} `json:"crypto"`
+	}
+	if err := json.Unmarshal(keyjson, &key); err != nil {
+		t.Fatal(err)
+	}
+	if key.Crypto.KDFParams.N != keystore.LightScryptN {
+		t.Errorf("scrypt N mismatch: have %d, want %d", key.Crypto.KDFParams.N, keystore.LightScryptN)
+	}
+}
+
 func TestWalletImport(t *testing.T) {
 	t.Skip()
 	aquachain := runAquaChain(t, "wallet", "import", "--lightkdf", "testdata/guswallet.json")
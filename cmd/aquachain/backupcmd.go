@@ -0,0 +1,208 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aqua/backup"
+	"github.com/aquanetwork/aquachain/cmd/utils"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/p2p"
+	"github.com/aquanetwork/aquachain/p2p/discover"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	backupToFlag = cli.StringFlag{
+		Name:  "to",
+		Usage: "Enode URL of the node to stream the chain database to",
+	}
+	backupFromFlag = cli.StringFlag{
+		Name:  "from",
+		Usage: "Enode URL or ID of the only node allowed to stream its chain database",
+	}
+
+	backupCommand = cli.Command{
+		Name:     "backup",
+		Usage:    "Copy the chain database between nodes over the network",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+
+Stream the chain database of a node directly to a fresh node over an encrypted
+and authenticated connection, without copying database files around. Both nodes
+must be stopped while running these commands.`,
+		Subcommands: []cli.Command{
+			{
+				Name:   "stream",
+				Usage:  "Send the chain database to a receiving node",
+				Action: utils.MigrateFlags(streamBackup),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+					utils.RinkebyFlag,
+					utils.LightModeFlag,
+					utils.NodeKeyFileFlag,
+					utils.NodeKeyHexFlag,
+					backupToFlag,
+				},
+				Description: `
+    aquachain backup stream --to <enode>
+
+Connect to the node running 'aquachain backup receive' and send it every entry
+of the local chain database, in checksummed chunks the receiver acknowledges
+once written. The receiver only accepts the node ID this node is started with.
+
+An interrupted transfer is resumed by running the command again.`,
+			},
+			{
+				Name:   "receive",
+				Usage:  "Receive the chain database from a sending node",
+				Action: utils.MigrateFlags(receiveBackup),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+					utils.RinkebyFlag,
+					utils.LightModeFlag,
+					utils.NodeKeyFileFlag,
+					utils.NodeKeyHexFlag,
+					utils.ListenPortFlag,
+					utils.NATFlag,
+					backupFromFlag,
+				},
+				Description: `
+    aquachain backup receive --from <enode>
+
+Wait for the given node to connect with 'aquachain backup stream' and write the
+chain database it sends into the local one, which must be empty or hold an
+unfinished transfer from the same node. The enode URL to pass to the sender is
+printed on startup.`,
+			},
+		},
+	}
+)
+
+// streamBackup sends the local chain database to a receiving node.
+func streamBackup(ctx *cli.Context) error {
+	if !ctx.IsSet(backupToFlag.Name) {
+		utils.Fatalf("This command requires the --%s flag", backupToFlag.Name)
+	}
+	target, err := discover.ParseNode(ctx.String(backupToFlag.Name))
+	if err != nil {
+		utils.Fatalf("Invalid receiver enode: %v", err)
+	}
+	if target.Incomplete() {
+		utils.Fatalf("Receiver enode %s lacks an address", target)
+	}
+	stack, cfg := makeConfigNode(ctx)
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	var (
+		start  = time.Now()
+		stats  *backup.Stats
+		result = make(chan error, 1)
+	)
+	srv := &p2p.Server{Config: p2p.Config{
+		PrivateKey:  cfg.Node.NodeKey(),
+		Name:        cfg.Node.NodeName(),
+		MaxPeers:    1,
+		NoDiscovery: true,
+		Protocols: []p2p.Protocol{backup.Protocol(func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			if p.ID() != target.ID {
+				return errors.New("unexpected peer")
+			}
+			var err error
+			stats, err = backup.Send(db, rw)
+			select {
+			case result <- err:
+			default:
+			}
+			return err
+		})},
+	}}
+	if err := srv.Start(); err != nil {
+		utils.Fatalf("Failed to start networking: %v", err)
+	}
+	defer srv.Stop()
+
+	fmt.Printf("Streaming as %s\n", srv.Self())
+	srv.AddPeer(target)
+	if err := <-result; err != nil {
+		utils.Fatalf("Backup stream failed: %v", err)
+	}
+	fmt.Printf("Streamed %d entries (%v) in %v, %d entries sent earlier\n", stats.Entries, stats.Size, common.PrettyDuration(time.Since(start)), stats.Resumed)
+	return nil
+}
+
+// receiveBackup writes the chain database streamed by a sending node into the
+// local one.
+func receiveBackup(ctx *cli.Context) error {
+	if !ctx.IsSet(backupFromFlag.Name) {
+		utils.Fatalf("This command requires the --%s flag", backupFromFlag.Name)
+	}
+	sender, err := discover.ParseNode(ctx.String(backupFromFlag.Name))
+	if err != nil {
+		utils.Fatalf("Invalid sender enode: %v", err)
+	}
+	stack, cfg := makeConfigNode(ctx)
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	var (
+		start  = time.Now()
+		stats  *backup.Stats
+		result = make(chan error, 1)
+	)
+	srv := &p2p.Server{Config: p2p.Config{
+		PrivateKey:  cfg.Node.NodeKey(),
+		Name:        cfg.Node.NodeName(),
+		MaxPeers:    1,
+		NoDiscovery: true,
+		NoDial:      true,
+		ListenAddr:  cfg.Node.P2P.ListenAddr,
+		NAT:         cfg.Node.P2P.NAT,
+		Protocols: []p2p.Protocol{backup.Protocol(func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			if p.ID() != sender.ID {
+				p.Log().Warn("Rejected backup stream from unknown node")
+				return errors.New("unauthorized sender")
+			}
+			var err error
+			stats, err = backup.Receive(db, rw)
+			select {
+			case result <- err:
+			default:
+			}
+			return err
+		})},
+	}}
+	if err := srv.Start(); err != nil {
+		utils.Fatalf("Failed to start networking: %v", err)
+	}
+	defer srv.Stop()
+
+	fmt.Printf("Waiting for backup stream to %s\n", srv.Self())
+	if err := <-result; err != nil {
+		utils.Fatalf("Backup stream failed: %v", err)
+	}
+	fmt.Printf("Received %d entries (%v) in %v, %d entries received earlier\n", stats.Entries, stats.Size, common.PrettyDuration(time.Since(start)), stats.Resumed)
+	return nil
+}
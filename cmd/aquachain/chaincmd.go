@@ -22,6 +22,7 @@ import (
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -36,7 +37,6 @@ import (
 	"github.com/aquanetwork/aquachain/event"
 	"github.com/aquanetwork/aquachain/log"
 	"github.com/aquanetwork/aquachain/trie"
-	"github.com/syndtr/goleveldb/leveldb/util"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -68,13 +68,22 @@ It expects the genesis file as argument.`,
 			utils.CacheFlag,
 			utils.LightModeFlag,
 			utils.GCModeFlag,
+			utils.TxLookupLimitFlag,
+			utils.SnapshotFlag,
+			utils.AccountHistoryFlag,
 			utils.CacheDatabaseFlag,
+			utils.DBCompressFlag,
 			utils.CacheGCFlag,
+			utils.CacheNoPrefetchFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 The import command imports blocks from an RLP-encoded form. The form can be one file
-with several RLP-encoded blocks, or several files can be used.
+with several RLP-encoded blocks, or several files can be used. Files ending in .gz
+are decompressed on the fly.
+
+Blocks already present in the database are skipped, so an interrupted import is
+resumed by running the same command again.
 
 If only one file is used, import error will result in failure. If several files are used,
 processing will proceed even if an individual RLP-file import failure occurs.`,
@@ -94,7 +103,88 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 Requires a first argument of the file to write to.
 Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
-if already existing.`,
+if already existing. Files ending in .gz are compressed.`,
+	}
+	exportStateCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportState),
+		Name:      "export-state",
+		Usage:     "Export the account state at a block into file",
+		ArgsUsage: "<filename> [<blockHash> | <blockNum>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.TestnetFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Write the full account state (balances, nonces, code and storage) at the given
+block, or the head block if omitted, to the file as JSON lines: the state root
+first, followed by one account per line. Files ending in .gz are compressed.
+
+Nodes running with --gcmode=full only retain the state of recent blocks.`,
+	}
+	exportVectorsCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportVectors),
+		Name:      "export-vectors",
+		Usage:     "Export consensus test vectors of blocks into file",
+		ArgsUsage: "<filename> <blockNumFirst> <blockNumLast> [<step>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.TestnetFlag,
+			utils.RinkebyFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Write the consensus test vectors of the canonical blocks in the given range, or
+of one block every step blocks of it, to the file in JSON: the hard fork and
+header version of each block, the difficulty the rules require of it, whether
+its header and seal are valid, and the ancestors the rules look at.
+
+Placed in tests/testdata/ConsensusTests, the vectors are checked by the tests
+of the tests package, validating changes of the difficulty and seal rules
+against the history of the network before release.`,
+	}
+	reindexIndexesFlag = cli.StringFlag{
+		Name:  "indexes",
+		Usage: "Comma separated list of indexes to rebuild (" + strings.Join(utils.ReindexIndexes, ",") + ")",
+		Value: strings.Join(utils.ReindexIndexes, ","),
+	}
+	reindexCommand = cli.Command{
+		Action:    utils.MigrateFlags(reindexChain),
+		Name:      "reindex",
+		Usage:     "Rebuild derived chain indexes from stored blocks",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.TestnetFlag,
+			reindexIndexesFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The reindex command rebuilds the selected indexes of the canonical chain from
+the stored headers and block bodies, without re-executing any transactions.
+Supported indexes are txlookup (transaction hash lookups) and bloombits (log
+filtering). An interrupted reindex is resumed the next time it is run.`,
+	}
+	rollbackCommand = cli.Command{
+		Action:    utils.MigrateFlags(rollbackChain),
+		Name:      "rollback",
+		Usage:     "Rewind the canonical chain to an older block",
+		ArgsUsage: "<number>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.TestnetFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The rollback command rewinds the canonical chain to the given block, or further
+to the newest one whose state is available, deleting the blocks above it along
+with their transaction lookups and receipts. The transactions of the deleted
+blocks are saved to the transaction pool snapshot, to be reinjected on the next
+start, when the derived indexes follow the rewound chain too.`,
 	}
 	copydbCommand = cli.Command{
 		Action:    utils.MigrateFlags(copyDb),
@@ -218,13 +308,7 @@ func importChain(ctx *cli.Context) error {
 	fmt.Printf("Import done in %v.\n\n", time.Since(start))
 
 	// Output pre-compaction stats mostly to see the import trashing
-	db := chainDb.(*aquadb.LDBDatabase)
-
-	stats, err := db.LDB().GetProperty("leveldb.stats")
-	if err != nil {
-		utils.Fatalf("Failed to read database stats: %v", err)
-	}
-	fmt.Println(stats)
+	printLevelDBStats(chainDb)
 	fmt.Printf("Trie cache misses:  %d\n", trie.CacheMisses())
 	fmt.Printf("Trie cache unloads: %d\n\n", trie.CacheUnloads())
 
@@ -244,18 +328,26 @@ func importChain(ctx *cli.Context) error {
 	// Compact the entire database to more accurately measure disk io and print the stats
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = db.LDB().CompactRange(util.Range{}); err != nil {
+	if err := chainDb.(aquadb.Compacter).Compact(); err != nil {
 		utils.Fatalf("Compaction failed: %v", err)
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
 
-	stats, err = db.LDB().GetProperty("leveldb.stats")
+	printLevelDBStats(chainDb)
+	return nil
+}
+
+// printLevelDBStats prints the internal statistics of leveldb databases.
+func printLevelDBStats(db aquadb.Database) {
+	ldb, ok := db.(*aquadb.LDBDatabase)
+	if !ok {
+		return
+	}
+	stats, err := ldb.LDB().GetProperty("leveldb.stats")
 	if err != nil {
 		utils.Fatalf("Failed to read database stats: %v", err)
 	}
 	fmt.Println(stats)
-
-	return nil
 }
 
 func exportChain(ctx *cli.Context) error {
@@ -290,6 +382,115 @@ func exportChain(ctx *cli.Context) error {
 	return nil
 }
 
+func exportState(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 || len(ctx.Args()) > 2 {
+		utils.Fatalf("This command requires a file name and an optional block.")
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	block := chain.CurrentBlock()
+	if arg := ctx.Args().Get(1); arg != "" {
+		if hashish(arg) {
+			block = chain.GetBlockByHash(common.HexToHash(arg))
+		} else {
+			num, _ := strconv.ParseUint(arg, 10, 64)
+			block = chain.GetBlockByNumber(num)
+		}
+		if block == nil {
+			utils.Fatalf("Block %s not found", arg)
+		}
+	}
+	start := time.Now()
+	if err := utils.ExportState(chain, block, ctx.Args().First()); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export of state at block #%d done in %v\n", block.NumberU64(), time.Since(start))
+	return nil
+}
+
+func exportVectors(ctx *cli.Context) error {
+	if len(ctx.Args()) < 3 || len(ctx.Args()) > 4 {
+		utils.Fatalf("This command requires a file name, the first and last blocks and an optional step.")
+	}
+	var numbers [3]uint64
+	for i := range numbers {
+		arg := ctx.Args().Get(i + 1)
+		if arg == "" {
+			numbers[i] = 1
+			continue
+		}
+		number, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			utils.Fatalf("Invalid block number or step %q: %v", arg, err)
+		}
+		numbers[i] = number
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	start := time.Now()
+	if err := utils.ExportConsensusTests(chain, ctx.Args().First(), numbers[0], numbers[1], numbers[2]); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
+func reindexChain(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	var indexes []string
+	for _, index := range strings.Split(ctx.String(reindexIndexesFlag.Name), ",") {
+		if index = strings.TrimSpace(index); index != "" {
+			indexes = append(indexes, index)
+		}
+	}
+	start := time.Now()
+	if err := utils.ReindexChain(chain, chainDb, indexes); err != nil {
+		utils.Fatalf("Reindex error: %v", err)
+	}
+	chain.Stop()
+	fmt.Printf("Reindex done in %v\n", time.Since(start))
+	return nil
+}
+
+func rollbackChain(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	number, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+	if err != nil {
+		utils.Fatalf("Invalid block number: %v", err)
+	}
+	stack, cfg := makeConfigNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	head := chain.CurrentBlock().NumberU64()
+	if number >= head {
+		utils.Fatalf("Block %d is not below the head block %d", number, head)
+	}
+	txs, err := chain.Rewind(number)
+	if err != nil {
+		utils.Fatalf("Rollback error: %v", err)
+	}
+	number = chain.CurrentBlock().NumberU64()
+	chain.Stop()
+
+	if len(txs) > 0 && cfg.Aqua.TxPool.Snapshot != "" {
+		if err := core.AppendTxSnapshot(stack.ResolvePath(cfg.Aqua.TxPool.Snapshot), txs); err != nil {
+			utils.Fatalf("Failed to save rewound transactions: %v", err)
+		}
+	}
+	fmt.Printf("Rolled back from block %d to %d, %d transactions to reinject\n", head, number, len(txs))
+	return nil
+}
+
 func copyDb(ctx *cli.Context) error {
 	// Ensure we have a source chain directory to copy
 	if len(ctx.Args()) != 1 {
@@ -330,7 +531,7 @@ func copyDb(ctx *cli.Context) error {
 	// Compact the entire database to remove any sync overhead
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = chainDb.(*aquadb.LDBDatabase).LDB().CompactRange(util.Range{}); err != nil {
+	if err = chainDb.(aquadb.Compacter).Compact(); err != nil {
 		utils.Fatalf("Compaction failed: %v", err)
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
@@ -0,0 +1,175 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/aquanetwork/aquachain/cmd/utils"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/p2p/discover"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	checkpointIntervalFlag = cli.Uint64Flag{
+		Name:  "interval",
+		Value: 10000,
+		Usage: "Number of blocks between exported checkpoints",
+	}
+	checkpointSignerFlag = cli.StringFlag{
+		Name:  "checkpoint.signer",
+		Usage: "Node ID or enode URL of the node expected to have signed the bundle (default: any trusted node)",
+	}
+
+	checkpointCommand = cli.Command{
+		Name:     "checkpoint",
+		Usage:    "Exchange trusted checkpoints between nodes",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+
+Export the canonical chain of this node as a signed checkpoint bundle, or import
+a bundle exported by another node. Imported checkpoints anchor the sync of the
+node: chains conflicting with them are rejected and headers below the latest one
+don't need their seals verified.
+
+Bundles are signed with the node key of the exporting node, and are only
+imported if signed by one of the trusted nodes of the importing node.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "export",
+				Usage:     "Export a signed checkpoint bundle",
+				ArgsUsage: "<filename>",
+				Action:    utils.MigrateFlags(checkpointExport),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+					checkpointIntervalFlag,
+				},
+				Description: `
+Write a checkpoint every --interval blocks of the canonical chain, plus one for
+the current head, to the given file and sign it with the node key.`,
+			},
+			{
+				Name:      "import",
+				Usage:     "Import a signed checkpoint bundle",
+				ArgsUsage: "<filename>",
+				Action:    utils.MigrateFlags(checkpointImport),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+					checkpointSignerFlag,
+				},
+				Description: `
+Verify the signature of the given bundle and that it doesn't conflict with the
+local chain, then store its checkpoints in the chain database.`,
+			},
+		},
+	}
+)
+
+func checkpointExport(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires the output file as argument.")
+	}
+	stack, cfg := makeConfigNode(ctx)
+	chain, _ := utils.MakeChain(ctx, stack)
+	defer chain.Stop()
+
+	key := cfg.Node.NodeKey()
+
+	bundle, err := core.NewCheckpointBundle(chain, ctx.Uint64(checkpointIntervalFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to collect checkpoints: %v", err)
+	}
+	if err := bundle.Sign(key); err != nil {
+		utils.Fatalf("Failed to sign checkpoints: %v", err)
+	}
+	blob, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode checkpoints: %v", err)
+	}
+	if err := ioutil.WriteFile(ctx.Args().First(), blob, 0644); err != nil {
+		utils.Fatalf("Failed to write checkpoints: %v", err)
+	}
+	latest := bundle.Checkpoints.Latest()
+	fmt.Printf("Exported %d checkpoints, latest #%d [%x]\n", len(bundle.Checkpoints), latest.Number, latest.Hash)
+	fmt.Printf("Signed by %v\n", discover.PubkeyID(&key.PublicKey))
+	return nil
+}
+
+func checkpointImport(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires the bundle file as argument.")
+	}
+	blob, err := ioutil.ReadFile(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to read checkpoints: %v", err)
+	}
+	var bundle core.CheckpointBundle
+	if err := json.Unmarshal(blob, &bundle); err != nil {
+		utils.Fatalf("Invalid checkpoint bundle: %v", err)
+	}
+	pubkey, err := bundle.Signer()
+	if err != nil {
+		utils.Fatalf("Invalid checkpoint signature: %v", err)
+	}
+	signer := discover.PubkeyID(pubkey)
+
+	stack, cfg := makeConfigNode(ctx)
+	if !checkpointSignerTrusted(ctx, cfg.Node.TrustedNodes(), signer) {
+		utils.Fatalf("Checkpoints signed by untrusted node %v", signer)
+	}
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chain.Stop()
+
+	if err := bundle.Verify(chain); err != nil {
+		utils.Fatalf("Checkpoint verification failed: %v", err)
+	}
+	if err := core.WriteTrustedCheckpoints(chainDb, bundle.Checkpoints); err != nil {
+		utils.Fatalf("Failed to store checkpoints: %v", err)
+	}
+	latest := bundle.Checkpoints.Latest()
+	fmt.Printf("Imported %d checkpoints from %v, latest #%d [%x]\n", len(bundle.Checkpoints), signer, latest.Number, latest.Hash)
+	return nil
+}
+
+// checkpointSignerTrusted returns whether the given node may sign imported
+// checkpoints: the node requested with --checkpoint.signer if set, any trusted node otherwise.
+func checkpointSignerTrusted(ctx *cli.Context, trusted []*discover.Node, signer discover.NodeID) bool {
+	if ctx.IsSet(checkpointSignerFlag.Name) {
+		want := ctx.String(checkpointSignerFlag.Name)
+		if node, err := discover.ParseNode(want); err == nil {
+			return node.ID == signer
+		}
+		id, err := discover.HexID(want)
+		if err != nil {
+			utils.Fatalf("Invalid --%s: %v", checkpointSignerFlag.Name, err)
+		}
+		return id == signer
+	}
+	for _, node := range trusted {
+		if node.ID == signer {
+			return true
+		}
+	}
+	return false
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/aquanetwork/aquachain/aqua"
 	"github.com/aquanetwork/aquachain/cmd/utils"
 	"github.com/aquanetwork/aquachain/dashboard"
+	"github.com/aquanetwork/aquachain/log"
 	"github.com/aquanetwork/aquachain/node"
 	"github.com/aquanetwork/aquachain/params"
 	whisper "github.com/aquanetwork/aquachain/whisper/whisperv5"
@@ -38,13 +39,17 @@ import (
 
 var (
 	dumpConfigCommand = cli.Command{
-		Action:      utils.MigrateFlags(dumpConfig),
-		Name:        "dumpconfig",
-		Usage:       "Show configuration values",
-		ArgsUsage:   "",
-		Flags:       append(append(nodeFlags, rpcFlags...), whisperFlags...),
-		Category:    "MISCELLANEOUS COMMANDS",
-		Description: `The dumpconfig command shows configuration values.`,
+		Action:    utils.MigrateFlags(dumpConfig),
+		Name:      "dumpconfig",
+		Usage:     "Show configuration values",
+		ArgsUsage: "[<file>]",
+		Flags:     append(append(nodeFlags, rpcFlags...), whisperFlags...),
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `
+The dumpconfig command shows the effective configuration values, those of the
+config file overridden by the command line flags, in the TOML format accepted
+by --config. If a file is given, the configuration is written to it instead of
+the standard output.`,
 	}
 
 	configFileFlag = cli.StringFlag{
@@ -116,6 +121,9 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 		Dashboard: dashboard.DefaultConfig,
 	}
 
+	// Preset the flags of the selected node role.
+	utils.ApplyRole(ctx)
+
 	// Load config file.
 	if file := ctx.GlobalString(configFileFlag.Name); file != "" {
 		if err := loadConfig(file, &cfg); err != nil {
@@ -130,8 +138,12 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 		utils.Fatalf("Failed to create the protocol stack: %v", err)
 	}
 	utils.SetAquaConfig(ctx, stack, &cfg.Aqua)
-	if ctx.GlobalIsSet(utils.AquaStatsURLFlag.Name) {
-		cfg.Aquastats.URL = ctx.GlobalString(utils.AquaStatsURLFlag.Name)
+	if ctx.GlobalIsSet(utils.LegacyAquaStatsURLFlag.Name) {
+		cfg.Aquastats.URL = ctx.GlobalString(utils.LegacyAquaStatsURLFlag.Name)
+		log.Warn("The flag --aquastats is deprecated and will be removed in the future, please use --stats")
+	}
+	if ctx.GlobalIsSet(utils.StatsURLFlag.Name) {
+		cfg.Aquastats.URL = ctx.GlobalString(utils.StatsURLFlag.Name)
 	}
 
 	utils.SetShhConfig(ctx, stack, &cfg.Shh)
@@ -171,6 +183,12 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		utils.RegisterShhService(stack, &cfg.Shh)
 	}
 
+	// Add the GraphQL server if requested.
+	utils.RegisterGraphQLService(ctx, stack)
+
+	// Add the chain event stream if a sink was given.
+	utils.RegisterStreamService(ctx, stack)
+
 	// Add the AquaChain Stats daemon if requested.
 	if cfg.Aquastats.URL != "" {
 		utils.RegisterAquaStatsService(stack, cfg.Aquastats.URL)
@@ -180,6 +198,9 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 
 // dumpConfig is the dumpconfig command.
 func dumpConfig(ctx *cli.Context) error {
+	if len(ctx.Args()) > 1 {
+		utils.Fatalf("This command takes at most one argument.")
+	}
 	_, cfg := makeConfigNode(ctx)
 	comment := ""
 
@@ -192,7 +213,14 @@ func dumpConfig(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	io.WriteString(os.Stdout, comment)
-	os.Stdout.Write(out)
+	dump := os.Stdout
+	if file := ctx.Args().First(); file != "" {
+		if dump, err = os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644); err != nil {
+			return err
+		}
+		defer dump.Close()
+	}
+	io.WriteString(dump, comment)
+	dump.Write(out)
 	return nil
 }
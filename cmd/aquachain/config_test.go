@@ -0,0 +1,99 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aqua"
+	"github.com/aquanetwork/aquachain/dashboard"
+	whisper "github.com/aquanetwork/aquachain/whisper/whisperv5"
+)
+
+// Tests that the dumped configuration loads back as is, covering the node, p2p,
+// txpool, miner and aquahash settings.
+func TestConfigRoundTrip(t *testing.T) {
+	want := gethConfig{
+		Aqua:      aqua.DefaultConfig,
+		Shh:       whisper.DefaultConfig,
+		Node:      defaultNodeConfig(),
+		Dashboard: dashboard.DefaultConfig,
+	}
+	want.Node.DataDir = "/var/lib/aquachain"
+	want.Node.P2P.MaxPeers = 12
+	want.Node.P2P.ListenAddr = ":21304"
+	want.Aqua.NoPruning = true
+	want.Aqua.TrieCache = 512
+	want.Aqua.TrieTimeout = 10 * time.Minute
+	want.Aqua.Mining = true
+	want.Aqua.MinerThreads = 2
+	want.Aqua.ExtraData = []byte("declarative")
+	want.Aqua.Aquahash.CachesInMem = 4
+	want.Aqua.Aquahash.Throttle = 0.5
+	want.Aqua.TxPool.GlobalSlots = 8192
+	want.Aqua.TxPool.Defense = true
+
+	out, err := tomlSettings.Marshal(&want)
+	if err != nil {
+		t.Fatalf("failed to dump config: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "aquachain-config-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.toml")
+	if err := ioutil.WriteFile(file, out, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var have gethConfig
+	if err := loadConfig(file, &have); err != nil {
+		t.Fatalf("failed to load dumped config: %v\n%s", err, out)
+	}
+	if again, err := tomlSettings.Marshal(&have); err != nil || string(again) != string(out) {
+		t.Errorf("reloaded config mismatch (err %v):\nhave\n%s\nwant\n%s", err, again, out)
+	}
+	if !have.Aqua.NoPruning || have.Aqua.TrieTimeout != want.Aqua.TrieTimeout || !have.Aqua.Mining || have.Aqua.Aquahash.Throttle != 0.5 {
+		t.Errorf("aqua config mismatch: have %+v, want %+v", have.Aqua, want.Aqua)
+	}
+	if have.Node.DataDir != want.Node.DataDir || have.Node.P2P.MaxPeers != want.Node.P2P.MaxPeers {
+		t.Errorf("node config mismatch: have %+v, want %+v", have.Node, want.Node)
+	}
+}
+
+// Tests that unknown fields are refused rather than silently ignored.
+func TestConfigUnknownField(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aquachain-config-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.toml")
+	if err := ioutil.WriteFile(file, []byte("[Aqua]\nMinerThreadz = 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var cfg gethConfig
+	if err := loadConfig(file, &cfg); err == nil {
+		t.Fatalf("unknown field accepted")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"syscall"
 
@@ -59,13 +60,16 @@ See https://github.com/aquanetwork/aquachain/wiki/JavaScript-Console.`,
 		Name:      "attach",
 		Usage:     "Start an interactive JavaScript environment (connect to node)",
 		ArgsUsage: "[endpoint]",
-		Flags:     append(consoleFlags, utils.DataDirFlag),
+		Flags:     append(consoleFlags, utils.DataDirFlag, utils.IPCPathFlag),
 		Category:  "CONSOLE COMMANDS",
 		Description: `
 The AquaChain console is an interactive shell for the JavaScript runtime environment
 which exposes a node admin interface as well as the Ðapp JavaScript API.
 See https://github.com/aquanetwork/aquachain/wiki/JavaScript-Console.
-This command allows to open a console on a running aquachain node.`,
+This command allows to open a console on a running aquachain node, through an
+IPC path, an http:// or a ws:// URL (default: the IPC endpoint of the node, a
+Unix socket in the data directory or a named pipe on Windows, as set by
+--ipcpath). The APIs bound are those the endpoint serves.`,
 	}
 
 	javascriptCommand = cli.Command{
@@ -106,25 +110,10 @@ func localConsole(ctx *cli.Context) error {
 		utils.Fatalf("Failed to start the JavaScript console: %v", err)
 	}
 	defer console.Stop(false)
-	console.Evaluate(`
-		function balance() {
-			    var totalBal = 0;
-			    for (var acctNum in aqua.accounts) {
-				            var acct = aqua.accounts[acctNum];
-				            var acctBal = web3.fromWei(eth.getBalance(acct), "aqua");
-				            totalBal += parseFloat(acctBal);
-				            console.log("  aqua.accounts[" + acctNum + "]: \t" + acct + " \tbalance: " + acctBal + " AQUA");
-				        }
-			    console.log("  Total balance: " + totalBal + " AQUA");
-		};
-
-
-		`)
 
 	// If only a short execution was requested, evaluate and return
 	if script := ctx.GlobalString(utils.ExecFlag.Name); script != "" {
-		console.Evaluate(script)
-		return nil
+		return execScript(console, script)
 	}
 	// Otherwise print the welcome screen and enter interactive mode
 	console.Welcome()
@@ -139,18 +128,7 @@ func remoteConsole(ctx *cli.Context) error {
 	// Attach to a remotely running aquachain instance and start the JavaScript console
 	endpoint := ctx.Args().First()
 	if endpoint == "" {
-		path := node.DefaultDataDir()
-		if ctx.GlobalIsSet(utils.DataDirFlag.Name) {
-			path = ctx.GlobalString(utils.DataDirFlag.Name)
-		}
-		if path != "" {
-			if ctx.GlobalBool(utils.TestnetFlag.Name) {
-				path = filepath.Join(path, "testnet")
-			} else if ctx.GlobalBool(utils.RinkebyFlag.Name) {
-				path = filepath.Join(path, "rinkeby")
-			}
-		}
-		endpoint = fmt.Sprintf("%s/aquachain.ipc", path)
+		endpoint = defaultIPCEndpoint(ctx)
 	}
 	client, err := dialRPC(endpoint)
 	if err != nil {
@@ -170,8 +148,7 @@ func remoteConsole(ctx *cli.Context) error {
 	defer console.Stop(false)
 
 	if script := ctx.GlobalString(utils.ExecFlag.Name); script != "" {
-		console.Evaluate(script)
-		return nil
+		return execScript(console, script)
 	}
 
 	// Otherwise print the welcome screen and enter interactive mode
@@ -181,7 +158,39 @@ func remoteConsole(ctx *cli.Context) error {
 	return nil
 }
 
-// dialRPC returns a RPC client which connects to the given endpoint.
+// execScript evaluates the --exec statement, failing the command if it throws so
+// that scripts can check the exit status.
+func execScript(console *console.Console, script string) error {
+	if err := console.Evaluate(script); err != nil {
+		return fmt.Errorf("--%s failed", utils.ExecFlag.Name)
+	}
+	return nil
+}
+
+// defaultIPCEndpoint returns the IPC endpoint of a node running with the data
+// directory, network and IPC path selected on the command line: a Unix socket,
+// or a named pipe on Windows.
+func defaultIPCEndpoint(ctx *cli.Context) string {
+	path := node.DefaultDataDir()
+	if ctx.GlobalIsSet(utils.DataDirFlag.Name) {
+		path = ctx.GlobalString(utils.DataDirFlag.Name)
+	}
+	if path != "" {
+		if ctx.GlobalBool(utils.TestnetFlag.Name) {
+			path = filepath.Join(path, "testnet")
+		} else if ctx.GlobalBool(utils.RinkebyFlag.Name) {
+			path = filepath.Join(path, "rinkeby")
+		}
+	}
+	config := node.Config{DataDir: path, IPCPath: clientIdentifier + ".ipc"}
+	if ctx.GlobalIsSet(utils.IPCPathFlag.Name) {
+		config.IPCPath = ctx.GlobalString(utils.IPCPathFlag.Name)
+	}
+	return config.IPCEndpoint()
+}
+
+// dialRPC returns a RPC client which connects to the given endpoint. On Windows,
+// IPC endpoints given by name alone are taken as named pipes.
 // The check for empty endpoint implements the defaulting logic
 // for "aquachain attach" and "aquachain monitor" with no argument.
 func dialRPC(endpoint string) (*rpc.Client, error) {
@@ -192,6 +201,9 @@ func dialRPC(endpoint string) (*rpc.Client, error) {
 		// these prefixes.
 		endpoint = endpoint[4:]
 	}
+	if runtime.GOOS == "windows" && !strings.Contains(endpoint, "://") {
+		endpoint = (&node.Config{IPCPath: endpoint}).IPCEndpoint()
+	}
 	return rpc.Dial(endpoint)
 }
 
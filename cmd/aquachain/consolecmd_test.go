@@ -18,6 +18,7 @@ package main
 
 import (
 	"crypto/rand"
+	"flag"
 	"math/big"
 	"os"
 	"path/filepath"
@@ -27,7 +28,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aquanetwork/aquachain/cmd/utils"
 	"github.com/aquanetwork/aquachain/params"
+	"gopkg.in/urfave/cli.v1"
 )
 
 const (
@@ -159,6 +162,35 @@ at block: 0 ({{niltime}}){{if ipc}}
 	attach.ExpectExit()
 }
 
+// Tests that the default endpoint of attaching commands is the IPC endpoint of a
+// node run with the same data directory, network and IPC path.
+func TestDefaultIPCEndpoint(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("IPC endpoints are named pipes outside of the data directory on Windows")
+	}
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--datadir", "/data"}, filepath.Join("/data", "aquachain.ipc")},
+		{[]string{"--datadir", "/data", "--testnet"}, filepath.Join("/data", "testnet", "aquachain.ipc")},
+		{[]string{"--datadir", "/data", "--ipcpath", "node.ipc"}, filepath.Join("/data", "node.ipc")},
+		{[]string{"--datadir", "/data", "--ipcpath", "/run/node.ipc"}, "/run/node.ipc"},
+	}
+	for i, tt := range tests {
+		set := flag.NewFlagSet("test", flag.ContinueOnError)
+		for _, f := range []cli.Flag{utils.DataDirFlag, utils.TestnetFlag, utils.RinkebyFlag, utils.IPCPathFlag} {
+			f.Apply(set)
+		}
+		if err := set.Parse(tt.args); err != nil {
+			t.Fatalf("test %d: failed to parse flags: %v", i, err)
+		}
+		if have := defaultIPCEndpoint(cli.NewContext(nil, set, nil)); have != tt.want {
+			t.Errorf("test %d: endpoint mismatch: have %s, want %s", i, have, tt.want)
+		}
+	}
+}
+
 // trulyRandInt generates a crypto random integer used by the console tests to
 // not clash network ports with other tests running cocurrently.
 func trulyRandInt(lo, hi int) int {
@@ -0,0 +1,206 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/cmd/utils"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var dbRepairFlag = cli.BoolFlag{
+	Name:  "repair",
+	Usage: "Move the headers stored under the hash of the wrong version to the right one",
+}
+
+var dbCommand = cli.Command{
+	Name:     "db",
+	Usage:    "Manage the storage of the chain databases",
+	Category: "BLOCKCHAIN COMMANDS",
+	Description: `
+
+Offline management of the chain databases. The node must be stopped while
+running these commands.`,
+	Subcommands: []cli.Command{
+		{
+			Name:   "schema",
+			Usage:  "Show the schema version of the chain database and its pending migrations",
+			Action: utils.MigrateFlags(showSchema),
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.CacheFlag,
+				utils.TestnetFlag,
+				utils.RinkebyFlag,
+				utils.LightModeFlag,
+			},
+			Description: `
+    aquachain db schema
+
+Print the version of the layout of the chain database and the migrations that
+will bring it to the version of this release. The node runs them on start, in
+order, resuming an interrupted one where it stopped.`,
+		},
+		{
+			Name:   "compress",
+			Usage:  "Compress the block bodies and receipts stored in the chain database",
+			Action: utils.MigrateFlags(compressDB),
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.CacheFlag,
+				utils.TestnetFlag,
+				utils.RinkebyFlag,
+				utils.LightModeFlag,
+			},
+			Description: `
+    aquachain db compress
+
+Rewrite the block bodies and receipts already stored in the chain database
+compressed with snappy, the way --db.compress stores the new ones. Entries are
+read alike whether compressed or not, so the command can be interrupted and run
+again at any time.`,
+		},
+		{
+			Name:   "decompress",
+			Usage:  "Decompress the block bodies and receipts stored in the chain database",
+			Action: utils.MigrateFlags(decompressDB),
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.CacheFlag,
+				utils.TestnetFlag,
+				utils.RinkebyFlag,
+				utils.LightModeFlag,
+			},
+			Description: `
+    aquachain db decompress
+
+Rewrite the compressed block bodies and receipts of the chain database
+uncompressed, undoing 'aquachain db compress'. Run the node without
+--db.compress afterwards to keep the new ones uncompressed too.`,
+		},
+		{
+			Name:   "headers",
+			Usage:  "Check the headers of the chain database are stored under the hash of their version",
+			Action: utils.MigrateFlags(checkHeaders),
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.CacheFlag,
+				utils.TestnetFlag,
+				utils.RinkebyFlag,
+				dbRepairFlag,
+			},
+			Description: `
+    aquachain db headers [--repair]
+
+Scan the headers stored in the chain database and verify each is stored under
+its hash by the header version scheduled at its number. Nodes that synced with
+releases predating the HF5 version switch may have headers hashed by the wrong
+version, failing the lookups of their blocks. The inconsistencies found are
+reported, and with --repair the misfiled headers are moved along with the rest
+of their block data to the right hash. The canonical chain is checked to link up
+afterwards.`,
+		},
+	},
+}
+
+// showSchema prints the schema version of the chain database and the
+// migrations it still has to go through.
+func showSchema(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	version, pending, err := core.PendingMigrations(db)
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	fmt.Printf("Schema version: %d (release: %d)\n", version, core.SchemaVersion())
+	for i, migration := range pending {
+		fmt.Printf("Pending migration %d: %s\n", version+uint64(i)+1, migration.Name)
+	}
+	return nil
+}
+
+// checkHeaders verifies the versions of the headers of the chain database,
+// repairing the misfiled ones if requested.
+func checkHeaders(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	config, _, err := core.SetupGenesisBlock(db, utils.MakeGenesis(ctx))
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	repair := ctx.Bool(dbRepairFlag.Name)
+
+	log.Info("Checking header versions", "repair", repair)
+	start := time.Now()
+	report, err := core.CheckHeaderVersions(db, config, repair)
+	if err != nil {
+		utils.Fatalf("Failed to check header versions: %v", err)
+	}
+	fmt.Printf("Headers checked:   %d\n", report.Headers)
+	fmt.Printf("Misfiled headers:  %d\n", report.Misfiled)
+	fmt.Printf("Invalid headers:   %d\n", report.Invalid)
+	fmt.Printf("Unlinked headers:  %d\n", report.Unlinked)
+	if repair {
+		fmt.Printf("Repaired headers:  %d\n", report.Repaired)
+	} else if report.Misfiled > 0 {
+		fmt.Println("Run again with --repair to move the misfiled headers")
+	}
+	log.Info("Checked header versions", "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// compressDB compresses the block bodies and receipts of the chain database.
+func compressDB(ctx *cli.Context) error {
+	return recompressDB(ctx, true)
+}
+
+// decompressDB decompresses the block bodies and receipts of the chain database.
+func decompressDB(ctx *cli.Context) error {
+	return recompressDB(ctx, false)
+}
+
+// recompressDB rewrites the block bodies and receipts of the chain database
+// compressed or uncompressed, compacting it afterwards to reclaim the space.
+func recompressDB(ctx *cli.Context, compress bool) error {
+	stack, _ := makeConfigNode(ctx)
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	log.Info("Rewriting bodies and receipts", "compress", compress)
+	start := time.Now()
+	entries, before, after, err := core.RecompressStorage(db, compress)
+	if err != nil {
+		utils.Fatalf("Failed to rewrite bodies and receipts: %v", err)
+	}
+	if compacter, ok := db.(aquadb.Compacter); ok && entries > 0 {
+		log.Info("Compacting database, this may take a while")
+		if err := compacter.Compact(); err != nil {
+			utils.Fatalf("Failed to compact database: %v", err)
+		}
+	}
+	log.Info("Rewrote bodies and receipts", "entries", entries, "before", before, "after", after, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
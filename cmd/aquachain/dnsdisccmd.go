@@ -0,0 +1,201 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aquanetwork/aquachain/cmd/utils"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/p2p/dnsdisc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dnsDomainFlag = cli.StringFlag{
+		Name:  "domain",
+		Usage: "Domain name the tree is published at",
+	}
+	dnsSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "File holding the hex private key signing the tree",
+	}
+	dnsSeqFlag = cli.UintFlag{
+		Name:  "seq",
+		Usage: "Sequence number of the tree (default: current unix time)",
+	}
+	dnsLinksFlag = cli.StringFlag{
+		Name:  "links",
+		Usage: "Comma separated enrtree:// URLs of other trees to link to",
+	}
+	dnsTTLFlag = cli.UintFlag{
+		Name:  "ttl",
+		Usage: "TTL of the TXT records in seconds",
+		Value: 3600,
+	}
+
+	dnsdiscCommand = cli.Command{
+		Name:     "dnsdisc",
+		Usage:    "Create and inspect DNS node lists",
+		Category: "MISCELLANEOUS COMMANDS",
+		Description: `
+Nodes started with --discovery.dns retrieve seed nodes from signed node lists
+published as TXT records in DNS. These commands create such lists and verify
+published ones.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "sign",
+				Usage:     "Create and sign a node list",
+				ArgsUsage: "<nodes-file>",
+				Action:    utils.MigrateFlags(signDNSTree),
+				Flags: []cli.Flag{
+					dnsDomainFlag,
+					dnsSignerFlag,
+					dnsSeqFlag,
+					dnsLinksFlag,
+					dnsTTLFlag,
+				},
+				Description: `
+    aquachain dnsdisc sign --domain <domain> --signer <keyfile> <nodes-file>
+
+Create the node list of the nodes in the given file, one per line, and print the
+TXT records to publish in zone file format along with the URL of the list.
+
+Nodes are given by their signed node record (the "enr" field of admin.nodeInfo)
+or by their enode URL. Records are preferred, as nodes vouch for their own
+address in them. The signer key is created with 'aquabootnode -genkey' and must be
+kept to sign later versions of the list, which need a higher --seq.`,
+			},
+			{
+				Name:      "resolve",
+				Usage:     "Retrieve and verify a published node list",
+				ArgsUsage: "<enrtree-url>",
+				Action:    utils.MigrateFlags(resolveDNSTree),
+				Description: `
+    aquachain dnsdisc resolve enrtree://<key>@<domain>
+
+Retrieve the node list at the given URL, verify it and print its nodes.`,
+			},
+		},
+	}
+)
+
+// signDNSTree creates and signs a DNS node list.
+func signDNSTree(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("This command requires a nodes file argument")
+	}
+	domain := strings.TrimSuffix(ctx.String(dnsDomainFlag.Name), ".")
+	if domain == "" {
+		utils.Fatalf("This command requires the --%s flag", dnsDomainFlag.Name)
+	}
+	if !ctx.IsSet(dnsSignerFlag.Name) {
+		utils.Fatalf("This command requires the --%s flag", dnsSignerFlag.Name)
+	}
+	key, err := crypto.LoadECDSA(ctx.String(dnsSignerFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to load signer key: %v", err)
+	}
+	nodes, err := readNodesFile(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to read nodes: %v", err)
+	}
+	seq := uint(time.Now().Unix())
+	if ctx.IsSet(dnsSeqFlag.Name) {
+		seq = ctx.Uint(dnsSeqFlag.Name)
+	}
+	var links []string
+	if ctx.IsSet(dnsLinksFlag.Name) {
+		links = strings.Split(ctx.String(dnsLinksFlag.Name), ",")
+	}
+	tree, err := dnsdisc.MakeTree(seq, nodes, links)
+	if err != nil {
+		utils.Fatalf("Failed to create tree: %v", err)
+	}
+	url, err := tree.Sign(key, domain)
+	if err != nil {
+		utils.Fatalf("Failed to sign tree: %v", err)
+	}
+	records := tree.ToTXT(domain)
+	names := make([]string, 0, len(records))
+	for name := range records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("; %s (seq %d, %d nodes)\n", url, seq, len(nodes))
+	for _, name := range names {
+		fmt.Printf("%s.\t%d\tIN\tTXT\t%s\n", name, ctx.Uint(dnsTTLFlag.Name), quoteTXT(records[name]))
+	}
+	return nil
+}
+
+// resolveDNSTree retrieves and prints a DNS node list.
+func resolveDNSTree(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("This command requires an enrtree:// URL argument")
+	}
+	tree, err := dnsdisc.NewClient(dnsdisc.Config{}).SyncTree(context.Background(), ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to retrieve node list: %v", err)
+	}
+	fmt.Printf("; seq %d\n", tree.Seq())
+	for _, n := range tree.Nodes() {
+		fmt.Println(n)
+	}
+	for _, link := range tree.Links() {
+		fmt.Println("; link", link)
+	}
+	return nil
+}
+
+// readNodesFile reads the nodes of a list, skipping blank lines and comments.
+func readNodesFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var nodes []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		nodes = append(nodes, line)
+	}
+	return nodes, scanner.Err()
+}
+
+// quoteTXT formats the value of a TXT record, split into character strings of
+// at most 255 bytes.
+func quoteTXT(value string) string {
+	var parts []string
+	for len(value) > 255 {
+		parts = append(parts, `"`+value[:255]+`"`)
+		value = value[255:]
+	}
+	return strings.Join(append(parts, `"`+value+`"`), " ")
+}
@@ -0,0 +1,323 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aquanetwork/aquachain/cmd/utils"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/console"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/p2p/discover"
+	"github.com/aquanetwork/aquachain/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	genesisOutFlag = cli.StringFlag{
+		Name:  "out",
+		Value: ".",
+		Usage: "Directory to write the genesis and network configs to",
+	}
+
+	genesisCommand = cli.Command{
+		Name:     "genesis",
+		Usage:    "Create custom networks",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Build the genesis block of a private network, along with the configs its nodes
+need to find each other.`,
+		Subcommands: []cli.Command{
+			{
+				Name:   "new",
+				Usage:  "Interactively create the genesis block and node configs of a new network",
+				Action: utils.MigrateFlags(genesisNew),
+				Flags: []cli.Flag{
+					genesisOutFlag,
+				},
+				Description: `
+Ask for the chain id, the hard fork schedule (HF5 switching the seal to argon2id),
+the initial difficulty and the prefunded accounts of the network, then write to
+the --out directory:
+
+  genesis.json       to initialize the nodes with 'aquachain init'
+  bootnode.key       the node key of the bootnode, for 'aquabootnode -nodekey'
+  bootnodes.txt      the enode URL of the bootnode, for --bootnodes
+  static-nodes.json  the nodes to always connect to, to copy into the datadirs`,
+			},
+		},
+	}
+)
+
+// genesisHFNotes describes the hard forks a custom network may schedule.
+var genesisHFNotes = []string{
+	"no changes",
+	"minimum difficulty raised",
+	"240 second block difficulty algorithm",
+	"minimum difficulty raised for gpu mining",
+	"HF4 state changes",
+	"seal switched to argon2id",
+}
+
+// defaultBootnodePort is the discovery port aquabootnode listens on by default.
+const defaultBootnodePort = 21000
+
+// genesisPrompter reads the answers to the genesis wizard.
+type genesisPrompter interface {
+	PromptInput(prompt string) (string, error)
+}
+
+// networkSpec is the outcome of the genesis wizard.
+type networkSpec struct {
+	genesis  *core.GenesisSpec
+	bootnode *net.UDPAddr     // Address of the bootnode to generate a key for, nil if none
+	statics  []*discover.Node // Nodes every node of the network connects to
+}
+
+func genesisNew(ctx *cli.Context) error {
+	spec, err := runGenesisWizard(console.Stdin)
+	if err != nil {
+		utils.Fatalf("Genesis wizard aborted: %v", err)
+	}
+	dir := ctx.String(genesisOutFlag.Name)
+	bootnode, err := writeNetwork(dir, spec)
+	if err != nil {
+		utils.Fatalf("Failed to write network configs: %v", err)
+	}
+	fmt.Printf("\nWrote the configs of network %d to %s. To start a node:\n\n", spec.genesis.ChainId, dir)
+	fmt.Printf("  aquachain --datadir <datadir> init %s\n", filepath.Join(dir, "genesis.json"))
+	if bootnode != nil {
+		fmt.Printf("  aquabootnode -nodekey %s -addr :%d\n", filepath.Join(dir, "bootnode.key"), spec.bootnode.Port)
+		fmt.Printf("  aquachain --datadir <datadir> --networkid %d --bootnodes %s\n", spec.genesis.ChainId, bootnode)
+	} else {
+		fmt.Printf("  aquachain --datadir <datadir> --networkid %d\n", spec.genesis.ChainId)
+	}
+	if len(spec.statics) > 0 {
+		fmt.Printf("\nCopy static-nodes.json into the datadir of every node.\n")
+	}
+	return nil
+}
+
+// runGenesisWizard asks for the parameters of a new network.
+func runGenesisWizard(p genesisPrompter) (*networkSpec, error) {
+	chainId := uint64(rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(1<<31-1000) + 1000)
+	chainId, err := promptUint(p, fmt.Sprintf("Chain id, also the network id (default = %d): ", chainId), chainId)
+	if err != nil {
+		return nil, err
+	}
+	genesis := core.DefaultGenesisSpec(chainId)
+
+	for i := 0; i < len(genesisHFNotes); i++ {
+		if i > 0 && genesis.HF[i].Cmp(genesis.HF[i-1]) < 0 {
+			genesis.HF[i] = new(big.Int).Set(genesis.HF[i-1]) // keep the default in order
+		}
+		prompt := fmt.Sprintf("HF%d height, %s (default = %v, 'none' to leave unscheduled): ", i, genesisHFNotes[i], genesis.HF[i])
+		for {
+			answer, err := p.PromptInput(prompt)
+			if err != nil {
+				return nil, err
+			}
+			answer = strings.TrimSpace(answer)
+			if answer == "" {
+				break
+			}
+			if answer == "none" {
+				for j := i; j < len(genesisHFNotes); j++ {
+					delete(genesis.HF, j)
+				}
+				i = len(genesisHFNotes) // later forks can't be scheduled either
+				break
+			}
+			height, err := strconv.ParseUint(answer, 10, 64)
+			if err != nil || (i > 0 && height < genesis.HF[i-1].Uint64()) {
+				fmt.Printf("Invalid height, must follow HF%d at %v\n", i-1, genesis.HF[i-1])
+				continue
+			}
+			genesis.HF[i] = new(big.Int).SetUint64(height)
+			break
+		}
+	}
+	difficulty, err := promptUint(p, fmt.Sprintf("Initial difficulty (default = %v): ", genesis.Difficulty), genesis.Difficulty.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	genesis.Difficulty = new(big.Int).SetUint64(difficulty)
+
+	for {
+		answer, err := p.PromptInput("Account to prefund (empty to finish): ")
+		if err != nil {
+			return nil, err
+		}
+		if answer = strings.TrimSpace(answer); answer == "" {
+			break
+		}
+		if !common.IsHexAddress(answer) {
+			fmt.Println("Invalid address")
+			continue
+		}
+		balance, err := promptAqua(p, "Balance in AQUA (default = 1000): ", "1000")
+		if err != nil {
+			return nil, err
+		}
+		genesis.Alloc[common.HexToAddress(answer)] = balance
+	}
+	if err := genesis.Validate(); err != nil {
+		return nil, err
+	}
+	spec := &networkSpec{genesis: genesis}
+
+	for {
+		answer, err := p.PromptInput("Public IP of the bootnode (empty for none): ")
+		if err != nil {
+			return nil, err
+		}
+		if answer = strings.TrimSpace(answer); answer == "" {
+			break
+		}
+		ip := net.ParseIP(answer)
+		if ip == nil {
+			fmt.Println("Invalid IP address")
+			continue
+		}
+		port, err := promptUint(p, fmt.Sprintf("Bootnode port (default = %d): ", defaultBootnodePort), defaultBootnodePort)
+		if err != nil {
+			return nil, err
+		}
+		spec.bootnode = &net.UDPAddr{IP: ip, Port: int(port)}
+		break
+	}
+	for {
+		answer, err := p.PromptInput("Enode URL of a static node (empty to finish): ")
+		if err != nil {
+			return nil, err
+		}
+		if answer = strings.TrimSpace(answer); answer == "" {
+			break
+		}
+		node, err := discover.ParseNode(answer)
+		if err != nil {
+			fmt.Printf("Invalid enode URL: %v\n", err)
+			continue
+		}
+		spec.statics = append(spec.statics, node)
+	}
+	return spec, nil
+}
+
+// promptUint asks for a number until a valid one or none is entered.
+func promptUint(p genesisPrompter, prompt string, def uint64) (uint64, error) {
+	for {
+		answer, err := p.PromptInput(prompt)
+		if err != nil {
+			return 0, err
+		}
+		if answer = strings.TrimSpace(answer); answer == "" {
+			return def, nil
+		}
+		n, err := strconv.ParseUint(answer, 10, 64)
+		if err == nil {
+			return n, nil
+		}
+		fmt.Println("Invalid number")
+	}
+}
+
+// promptAqua asks for an amount of AQUA until a valid one or none is entered,
+// returning it in wei.
+func promptAqua(p genesisPrompter, prompt string, def string) (*big.Int, error) {
+	for {
+		answer, err := p.PromptInput(prompt)
+		if err != nil {
+			return nil, err
+		}
+		if answer = strings.TrimSpace(answer); answer == "" {
+			answer = def
+		}
+		amount, ok := new(big.Rat).SetString(answer)
+		if ok && amount.Sign() >= 0 {
+			if amount.Mul(amount, new(big.Rat).SetInt(big.NewInt(params.Aqua))); amount.IsInt() {
+				return amount.Num(), nil
+			}
+		}
+		fmt.Println("Invalid amount")
+	}
+}
+
+// writeNetwork writes the genesis block and the node configs of a network to a
+// directory, returning the enode URL of the generated bootnode, if any.
+func writeNetwork(dir string, spec *networkSpec) (*discover.Node, error) {
+	genesis, err := spec.genesis.Genesis()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	blob, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "genesis.json"), blob, 0644); err != nil {
+		return nil, err
+	}
+	var bootnode *discover.Node
+	if spec.bootnode != nil {
+		keyfile := filepath.Join(dir, "bootnode.key")
+		if _, err := os.Stat(keyfile); err == nil {
+			return nil, errors.New("bootnode.key already exists, refusing to overwrite it")
+		}
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		if err := crypto.SaveECDSA(keyfile, key); err != nil {
+			return nil, err
+		}
+		port := uint16(spec.bootnode.Port)
+		bootnode = discover.NewNode(discover.PubkeyID(&key.PublicKey), spec.bootnode.IP, port, port)
+		if err := ioutil.WriteFile(filepath.Join(dir, "bootnodes.txt"), []byte(bootnode.String()+"\n"), 0644); err != nil {
+			return nil, err
+		}
+	}
+	if len(spec.statics) > 0 {
+		urls := make([]string, len(spec.statics))
+		for i, node := range spec.statics {
+			urls[i] = node.String()
+		}
+		blob, err := json.MarshalIndent(urls, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "static-nodes.json"), blob, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return bootnode, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/p2p/discover"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// testPrompter answers the genesis wizard from a script.
+type testPrompter []string
+
+func (p *testPrompter) PromptInput(prompt string) (string, error) {
+	if len(*p) == 0 {
+		return "", errors.New("out of answers")
+	}
+	answer := (*p)[0]
+	*p = (*p)[1:]
+	return answer, nil
+}
+
+// Tests that the genesis wizard writes a network matching the answers, asking
+// again on invalid ones.
+func TestGenesisWizard(t *testing.T) {
+	const static = "enode://a979fb575495b8d6db44f750317d0f4622bf4c2aa3365d6af7c284339968eef29b69ad0dce72a4d8db5ebb4968de0e3bec910127f134779fbcb0cb6d3331163c@52.16.188.185:21303"
+	prompter := &testPrompter{
+		"4242",         // chain id
+		"", "", "", "", // HF0-HF3 at the defaults
+		"2",      // HF4 before HF3, refused
+		"50", "", // HF4, HF5 following it
+		"4096", // difficulty
+		"0x1000000000000000000000000000000000000001", "2.5",
+		"nonsense", // invalid account, refused
+		"0x1000000000000000000000000000000000000002", "",
+		"",                  // accounts done
+		"10.0.0.1", "30000", // bootnode
+		static, "enode://bogus", "", // static nodes
+	}
+	spec, err := runGenesisWizard(prompter)
+	if err != nil {
+		t.Fatalf("wizard failed: %v", err)
+	}
+	if len(*prompter) != 0 {
+		t.Fatalf("unused answers: %v", *prompter)
+	}
+	dir, err := ioutil.TempDir("", "aquachain-genesis-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bootnode, err := writeNetwork(dir, spec)
+	if err != nil {
+		t.Fatalf("failed to write network: %v", err)
+	}
+	blob, err := ioutil.ReadFile(filepath.Join(dir, "genesis.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := new(core.Genesis)
+	if err := json.Unmarshal(blob, genesis); err != nil {
+		t.Fatalf("invalid genesis.json: %v", err)
+	}
+	if genesis.Config.ChainId.Uint64() != 4242 || genesis.Difficulty.Uint64() != 4096 {
+		t.Errorf("genesis mismatch: chain id %v, difficulty %v", genesis.Config.ChainId, genesis.Difficulty)
+	}
+	if genesis.Config.HF[4].Uint64() != 50 || genesis.Config.HF[5].Uint64() != 50 {
+		t.Errorf("hard fork schedule mismatch: %v", genesis.Config.HF)
+	}
+	want := new(big.Int).Mul(big.NewInt(25), big.NewInt(params.Aqua/10))
+	if have := genesis.Alloc[common.HexToAddress("0x1000000000000000000000000000000000000001")].Balance; have.Cmp(want) != 0 {
+		t.Errorf("prefunded balance mismatch: have %v, want %v", have, want)
+	}
+	if len(genesis.Alloc) != 2 {
+		t.Errorf("prefunded account count mismatch: have %d, want 2", len(genesis.Alloc))
+	}
+	// The bootnode URL matches its key, and the static nodes are listed
+	key, err := crypto.LoadECDSA(filepath.Join(dir, "bootnode.key"))
+	if err != nil {
+		t.Fatalf("invalid bootnode key: %v", err)
+	}
+	if bootnode.ID != discover.PubkeyID(&key.PublicKey) || bootnode.UDP != 30000 || !bootnode.IP.Equal([]byte{10, 0, 0, 1}) {
+		t.Errorf("bootnode mismatch: %v", bootnode)
+	}
+	var statics []string
+	blob, _ = ioutil.ReadFile(filepath.Join(dir, "static-nodes.json"))
+	if err := json.Unmarshal(blob, &statics); err != nil || len(statics) != 1 || statics[0] != static {
+		t.Errorf("static nodes mismatch: %v (%v)", statics, err)
+	}
+	// The bootnode key is never overwritten
+	if _, err := writeNetwork(dir, spec); err == nil {
+		t.Errorf("bootnode key overwritten")
+	}
+}
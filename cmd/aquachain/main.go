@@ -55,9 +55,19 @@ var (
 		utils.BootnodesFlag,
 		utils.BootnodesV4Flag,
 		utils.BootnodesV5Flag,
+		utils.DNSDiscoveryFlag,
 		utils.DataDirFlag,
 		utils.KeyStoreDirFlag,
+		utils.DBEngineFlag,
 		utils.NoUSBFlag,
+		utils.HDPathFlag,
+		utils.NoPersonalFlag,
+		utils.ExternalSignerFlag,
+		utils.ExternalSignerAsyncFlag,
+		utils.ExternalSignerTimeoutFlag,
+		utils.KeyStoreBackendFlag,
+		utils.VaultURLFlag,
+		utils.VaultUnlockFlag,
 		utils.DashboardEnabledFlag,
 		utils.DashboardAddrFlag,
 		utils.DashboardPortFlag,
@@ -69,9 +79,15 @@ var (
 		utils.AquahashDatasetDirFlag,
 		utils.AquahashDatasetsInMemoryFlag,
 		utils.AquahashDatasetsOnDiskFlag,
+		utils.AquahashCachesAheadFlag,
+		utils.AquahashDatasetsAheadFlag,
+		utils.AquahashCheckpointsFlag,
+		utils.TxPoolLocalsFlag,
 		utils.TxPoolNoLocalsFlag,
+		utils.TxPoolDefenseFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolNoSnapshotFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -83,23 +99,42 @@ var (
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
 		utils.GCModeFlag,
+		utils.TxLookupLimitFlag,
+		utils.SnapshotFlag,
+		utils.AccountHistoryFlag,
+		utils.RoleFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
 		utils.LightKDFFlag,
+		utils.KDFFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
+		utils.DBCompressFlag,
 		utils.CacheGCFlag,
+		utils.CacheNoPrefetchFlag,
 		utils.TrieCacheGenFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
 		utils.AquabaseFlag,
+		utils.AquabasesFlag,
+		utils.ForceAquabaseFlag,
 		utils.GasPriceFlag,
 		utils.MinerThreadsFlag,
+		utils.MinerThrottleFlag,
 		utils.MiningEnabledFlag,
+		utils.MinerPeersFlag,
+		utils.ForkGuardFlag,
+		utils.MinerStaleGraceFlag,
+		utils.MinerUnclesFlag,
+		utils.MinerUncleVerifyCostFlag,
+		utils.AnnounceDelayFlag,
+		utils.WithholdBlocksFlag,
+		utils.TieBreakFlag,
 		utils.TargetGasLimitFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
+		utils.NoClockCheckFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
 		utils.NodeKeyFileFlag,
@@ -109,15 +144,41 @@ var (
 		utils.TestnetFlag,
 		utils.RinkebyFlag,
 		utils.VMEnableDebugFlag,
+		utils.ForensicsDirFlag,
+		utils.VMRPCCallDepthFlag,
+		utils.VMRPCMemoryFlag,
+		utils.VMTraceLimitFlag,
 		utils.NetworkIdFlag,
 		utils.RPCCORSDomainFlag,
 		utils.RPCVirtualHostsFlag,
-		utils.AquaStatsURLFlag,
+		utils.RPCStrictJSONFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateBurstFlag,
+		utils.RPCBatchLimitFlag,
+		utils.RPCResponseLimitFlag,
+		utils.RPCGasCapFlag,
+		utils.RPCCallGasCapFlag,
+		utils.RPCReexecFlag,
+		utils.RPCAllowUnprotectedTxsFlag,
+		utils.RPCTxWebhookFlag,
+		utils.StatsURLFlag,
+		utils.ContractRegistryFlag,
 		utils.MetricsEnabledFlag,
+		utils.MetricsHTTPFlag,
+		utils.MetricsPortFlag,
+		utils.MetricsEnableInfluxDBFlag,
+		utils.MetricsInfluxDBEndpointFlag,
+		utils.MetricsInfluxDBDatabaseFlag,
+		utils.MetricsInfluxDBUsernameFlag,
+		utils.MetricsInfluxDBPasswordFlag,
+		utils.MetricsInfluxDBHostTagFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
+		utils.LegacyAquaStatsURLFlag,
+		utils.LegacyGpoBlocksFlag,
+		utils.LegacyGpoPercentileFlag,
 		utils.ExtraDataFlag,
 		configFileFlag,
 	}
@@ -127,13 +188,28 @@ var (
 		utils.RPCListenAddrFlag,
 		utils.RPCPortFlag,
 		utils.RPCApiFlag,
+		utils.RPCAdminSecretFlag,
+		utils.RPCAuthApiFlag,
+		utils.RPCDenyFlag,
+		utils.RPCAllowUnsafeAPIsFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.WSVirtualHostsFlag,
+		utils.GraphQLEnabledFlag,
+		utils.GraphQLListenAddrFlag,
+		utils.GraphQLPortFlag,
+		utils.GraphQLCORSDomainFlag,
+		utils.GraphQLVirtualHostsFlag,
+		utils.StreamSinkFlag,
+		utils.StreamAddressesFlag,
+		utils.StreamTopicsFlag,
+		utils.StreamFromFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
+		utils.IPCApiFlag,
 	}
 
 	whisperFlags = []cli.Flag{
@@ -154,13 +230,33 @@ func init() {
 		initCommand,
 		importCommand,
 		exportCommand,
+		exportStateCommand,
+		exportVectorsCommand,
 		copydbCommand,
+		reindexCommand,
+		rollbackCommand,
 		removedbCommand,
 		dumpCommand,
+		// See checkpointcmd.go:
+		checkpointCommand,
+		genesisCommand,
+		// See snapshotcmd.go:
+		snapshotCommand,
+		dbCommand,
+		// See backupcmd.go:
+		backupCommand,
+		// See dnsdisccmd.go:
+		dnsdiscCommand,
 		// See monitorcmd.go:
 		monitorCommand,
 		// See accountcmd.go:
 		accountCommand,
+		// See txcmd.go:
+		txCommand,
+		// See payoutcmd.go:
+		payoutCommand,
+		// See selftestcmd.go:
+		selftestCommand,
 
 		// See walletcmd.go
 		walletCommand,
@@ -176,6 +272,8 @@ func init() {
 		versionCommand,
 		bugCommand,
 		licenseCommand,
+		// See simulatecmd.go:
+		simulateCommand,
 		// See config.go
 		dumpConfigCommand,
 	}
@@ -196,6 +294,7 @@ func init() {
 		go metrics.CollectProcessMetrics(3 * time.Second)
 
 		utils.SetupNetwork(ctx)
+		utils.SetupMetrics(ctx)
 		return nil
 	}
 
@@ -230,17 +329,29 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 	// Start up the node itself
 	utils.StartNode(stack)
 
-	// Unlock any account specifically requested
-	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	// Unlock any account specifically requested. With an external signer there
+	// is no keystore, accounts are unlocked by the signer itself.
+	if keystores := stack.AccountManager().Backends(keystore.KeyStoreType); len(keystores) > 0 {
+		ks := keystores[0].(*keystore.KeyStore)
 
-	passwords := utils.MakePasswordList(ctx)
-	unlocks := strings.Split(ctx.GlobalString(utils.UnlockedAccountFlag.Name), ",")
-	for i, account := range unlocks {
-		if trimmed := strings.TrimSpace(account); trimmed != "" {
-			unlockAccount(ctx, ks, trimmed, i, passwords)
+		passwords := utils.MakePasswordList(ctx)
+		unlocks := strings.Split(ctx.GlobalString(utils.UnlockedAccountFlag.Name), ",")
+		for i, account := range unlocks {
+			if trimmed := strings.TrimSpace(account); trimmed != "" {
+				unlockAccount(ctx, ks, trimmed, i, passwords)
+			}
 		}
+	} else if ctx.GlobalString(utils.UnlockedAccountFlag.Name) != "" {
+		utils.Fatalf("Flag --%s is not supported with an external signer", utils.UnlockedAccountFlag.Name)
 	}
 	// Register wallet event handlers to open and auto-derive wallets
+	var hdpath accounts.DerivationPath
+	if path := ctx.GlobalString(utils.HDPathFlag.Name); path != "" {
+		var err error
+		if hdpath, err = accounts.ParseDerivationPath(path); err != nil {
+			utils.Fatalf("Invalid --%s: %v", utils.HDPathFlag.Name, err)
+		}
+	}
 	events := make(chan accounts.WalletEvent, 16)
 	stack.AccountManager().Subscribe(events)
 
@@ -269,11 +380,17 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 				status, _ := event.Wallet.Status()
 				log.Info("New wallet appeared", "url", event.Wallet.URL(), "status", status)
 
+				// Derive along the AquaChain path, falling back to the Ethereum
+				// one for accounts used before it was the default
+				if hdpath != nil {
+					event.Wallet.SelfDerive([]accounts.DerivationPath{hdpath}, stateReader)
+					break
+				}
+				legacy := accounts.DefaultBaseDerivationPath
 				if event.Wallet.URL().Scheme == "ledger" {
-					event.Wallet.SelfDerive(accounts.DefaultLedgerBaseDerivationPath, stateReader)
-				} else {
-					event.Wallet.SelfDerive(accounts.DefaultBaseDerivationPath, stateReader)
+					legacy = accounts.DefaultLedgerBaseDerivationPath
 				}
+				event.Wallet.SelfDerive([]accounts.DerivationPath{legacy, accounts.AquaBaseDerivationPath}, stateReader)
 
 			case accounts.WalletDropped:
 				log.Info("Old wallet dropped", "url", event.Wallet.URL())
@@ -281,18 +398,22 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 			}
 		}
 	}()
-	// Start auxiliary services if enabled
-	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) || ctx.GlobalBool(utils.DeveloperFlag.Name) {
+	// Start auxiliary services if enabled, mining being requested either on the
+	// command line or in the config file
+	var aquachain *aqua.AquaChain
+	stack.Service(&aquachain) // Left nil on light clients
+	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) || ctx.GlobalBool(utils.DeveloperFlag.Name) || (aquachain != nil && aquachain.Config().Mining) {
 		// Mining only makes sense if a full AquaChain node is running
 		if ctx.GlobalBool(utils.LightModeFlag.Name) || ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
 			utils.Fatalf("Light clients do not support mining")
 		}
-		var aquachain *aqua.AquaChain
-		if err := stack.Service(&aquachain); err != nil {
-			utils.Fatalf("AquaChain service not running: %v", err)
+		if aquachain == nil {
+			utils.Fatalf("AquaChain service not running")
 		}
+		config := aquachain.Config()
+
 		// Use a reduced number of threads if requested
-		if threads := ctx.GlobalInt(utils.MinerThreadsFlag.Name); threads > 0 {
+		if threads := config.MinerThreads; threads > 0 {
 			type threaded interface {
 				SetThreads(threads int)
 			}
@@ -300,8 +421,8 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 				th.SetThreads(threads)
 			}
 		}
-		// Set the gas price to the limits from the CLI and start mining
-		aquachain.TxPool().SetGasPrice(utils.GlobalBig(ctx, utils.GasPriceFlag.Name))
+		// Set the gas price to the configured limit and start mining
+		aquachain.TxPool().SetGasPrice(config.GasPrice)
 		if err := aquachain.StartMining(true); err != nil {
 			utils.Fatalf("Failed to start mining: %v", err)
 		}
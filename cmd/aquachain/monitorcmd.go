@@ -26,7 +26,6 @@ import (
 	"time"
 
 	"github.com/aquanetwork/aquachain/cmd/utils"
-	"github.com/aquanetwork/aquachain/node"
 	"github.com/aquanetwork/aquachain/rpc"
 	"github.com/gizak/termui"
 	"gopkg.in/urfave/cli.v1"
@@ -35,8 +34,7 @@ import (
 var (
 	monitorCommandAttachFlag = cli.StringFlag{
 		Name:  "attach",
-		Value: node.DefaultIPCEndpoint(clientIdentifier),
-		Usage: "API endpoint to attach to",
+		Usage: "API endpoint to attach to (default = the IPC endpoint of the local node)",
 	}
 	monitorCommandRowsFlag = cli.IntFlag{
 		Name:  "rows",
@@ -75,6 +73,9 @@ func monitor(ctx *cli.Context) error {
 	)
 	// Attach to an AquaChain node over IPC or RPC
 	endpoint := ctx.String(monitorCommandAttachFlag.Name)
+	if endpoint == "" {
+		endpoint = defaultIPCEndpoint(ctx)
+	}
 	if client, err = dialRPC(endpoint); err != nil {
 		utils.Fatalf("Unable to attach to aquachain node: %v", err)
 	}
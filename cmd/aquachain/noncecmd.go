@@ -0,0 +1,278 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/aquanetwork/aquachain/aquaclient"
+	"github.com/aquanetwork/aquachain/cmd/utils"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/math"
+	"github.com/aquanetwork/aquachain/console"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/internal/aquaapi"
+	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	fixNoncePriceFlag = cli.StringFlag{
+		Name:  "price",
+		Usage: "Gas price in wei the transactions must reach to be mined (default: suggested by the node)",
+	}
+	fixNonceBumpFlag = cli.Uint64Flag{
+		Name:  "pricebump",
+		Usage: "Gas price increase in percent over the transactions replaced, at least the price bump of the node's pool",
+		Value: 10,
+	}
+	fixNonceCancelFlag = cli.BoolFlag{
+		Name:  "cancel",
+		Usage: "Replace the stuck transactions with self-sends, canceling them, instead of resending them at a higher price",
+	}
+
+	fixNonceCommand = cli.Command{
+		Name:      "fix-nonce",
+		Usage:     "Repair the nonce holes and stuck transactions of an account",
+		ArgsUsage: "<address>",
+		Action:    utils.MigrateFlags(fixNonce),
+		Flags: []cli.Flag{
+			txKeyFileFlag,
+			txMnemonicFlag,
+			utils.HDPathFlag,
+			utils.PasswordFileFlag,
+			fixNoncePriceFlag,
+			fixNonceBumpFlag,
+			fixNonceCancelFlag,
+			txChainIdFlag,
+			utils.DataDirFlag,
+			utils.TestnetFlag,
+			utils.RinkebyFlag,
+			txEndpointFlag,
+		},
+		Description: `
+    aquachain wallet fix-nonce --keyfile <file> [--endpoint <ipc|url>] <address>
+
+Inspect the transactions of an account in the pool of a running node against
+its nonce in the chain, and craft the fewest transactions unblocking them:
+
+  - a nonce missing before queued transactions is filled with a self-send of
+    no value, making the queued transactions executable
+  - a transaction priced below the gas price needed to be mined is replaced by
+    the same transaction at that price (or a self-send with --cancel)
+
+The plan is shown for confirmation before anything is signed and sent.`,
+	}
+)
+
+// Reasons of the nonce fixes.
+const (
+	nonceFixGap   = "gap"   // Nonce missing before queued transactions
+	nonceFixStuck = "stuck" // Transaction priced below the gas price needed to be mined
+)
+
+// nonceFix is a transaction to sign for repairing a nonce of an account.
+type nonceFix struct {
+	Nonce    uint64
+	Reason   string
+	Replaces *aquaapi.RPCTransaction // Transaction in the pool replaced, nil for gaps
+	Price    *big.Int
+	Cancel   bool // Whether the transaction is a self-send rather than a resend
+}
+
+// transaction creates the unsigned transaction of the fix, sent by from.
+func (f *nonceFix) transaction(from common.Address) *types.Transaction {
+	if f.Cancel || f.Replaces == nil {
+		return types.NewTransaction(f.Nonce, from, new(big.Int), params.TxGas, f.Price, nil)
+	}
+	old := f.Replaces
+	if old.To == nil {
+		return types.NewContractCreation(f.Nonce, old.Value.ToInt(), uint64(old.Gas), f.Price, old.Input)
+	}
+	return types.NewTransaction(f.Nonce, *old.To, old.Value.ToInt(), uint64(old.Gas), f.Price, old.Input)
+}
+
+// planNonceFixes crafts the fewest transactions unblocking those of an account
+// in the pool: a self-send filling each nonce missing from the chain's nonce up
+// to the highest one in the pool, and a replacement of each transaction priced
+// below the given price, raised at least by bump percent for the pool to accept
+// it.
+func planNonceFixes(nonce uint64, pool map[uint64]*aquaapi.RPCTransaction, price *big.Int, bump uint64, cancel bool) []*nonceFix {
+	var highest uint64
+	for n := range pool {
+		if n >= nonce && n+1 > highest {
+			highest = n + 1
+		}
+	}
+	var fixes []*nonceFix
+	for n := nonce; n < highest; n++ {
+		tx := pool[n]
+		if tx == nil {
+			fixes = append(fixes, &nonceFix{Nonce: n, Reason: nonceFixGap, Price: price})
+			continue
+		}
+		old := tx.GasPrice.ToInt()
+		if old.Cmp(price) >= 0 {
+			continue
+		}
+		bumped := new(big.Int).Mul(old, new(big.Int).SetUint64(100+bump))
+		bumped.Div(bumped, big.NewInt(100))
+		if bumped.Cmp(price) < 0 {
+			bumped = price
+		}
+		fixes = append(fixes, &nonceFix{Nonce: n, Reason: nonceFixStuck, Replaces: tx, Price: bumped, Cancel: cancel})
+	}
+	return fixes
+}
+
+// poolTransactions flattens the pending and queued transactions of an account
+// returned by txpool_contentFrom, by nonce.
+func poolTransactions(content map[string]map[string]*aquaapi.RPCTransaction) (map[uint64]*aquaapi.RPCTransaction, error) {
+	txs := make(map[uint64]*aquaapi.RPCTransaction)
+	for _, kind := range []string{"pending", "queued"} {
+		for key, tx := range content[kind] {
+			nonce, err := strconv.ParseUint(key, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s nonce %q", kind, key)
+			}
+			txs[nonce] = tx
+		}
+	}
+	return txs, nil
+}
+
+// printNonceFixes prints the state of the account and the planned fixes.
+func printNonceFixes(w io.Writer, account common.Address, nonce uint64, pool map[uint64]*aquaapi.RPCTransaction, price *big.Int, fixes []*nonceFix) {
+	fmt.Fprintf(w, "Account %s: nonce %d in the chain, %d transactions in the pool, gas price needed %v wei\n\n", account.Hex(), nonce, len(pool), price)
+	if len(fixes) == 0 {
+		return
+	}
+	out := tabwriter.NewWriter(w, 1, 2, 2, ' ', 0)
+	fmt.Fprintln(out, "NONCE\tPROBLEM\tFIX\tPRICE\tREPLACES")
+	for _, fix := range fixes {
+		action, replaces := "self-send", ""
+		if fix.Replaces != nil {
+			if !fix.Cancel {
+				action = "resend"
+			}
+			replaces = fmt.Sprintf("%s (%v wei)", fix.Replaces.Hash.Hex(), fix.Replaces.GasPrice.ToInt())
+		}
+		fmt.Fprintf(out, "%d\t%s\t%s\t%v\t%s\n", fix.Nonce, fix.Reason, action, fix.Price, replaces)
+	}
+	out.Flush()
+	fmt.Fprintln(w)
+}
+
+// fixNonce repairs the nonce holes and stuck transactions of an account.
+func fixNonce(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 || !common.IsHexAddress(ctx.Args().First()) {
+		utils.Fatalf("This command requires an account address.")
+	}
+	account := common.HexToAddress(ctx.Args().First())
+
+	var price *big.Int
+	if value := ctx.String(fixNoncePriceFlag.Name); value != "" {
+		var ok bool
+		if price, ok = math.ParseBig256(value); !ok {
+			utils.Fatalf("Invalid --%s: %s", fixNoncePriceFlag.Name, value)
+		}
+	}
+	endpoint := ctx.String(txEndpointFlag.Name)
+	if endpoint == "" {
+		endpoint = defaultIPCEndpoint(ctx)
+	}
+	client, err := dialRPC(endpoint)
+	if err != nil {
+		utils.Fatalf("Unable to attach to aquachain: %v", err)
+	}
+	defer client.Close()
+
+	fixes, err := inspectNonces(context.Background(), client, account, price, ctx.Uint64(fixNonceBumpFlag.Name), ctx.Bool(fixNonceCancelFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to inspect the account: %v", err)
+	}
+	if len(fixes) == 0 {
+		fmt.Println("No nonce holes nor stuck transactions, nothing to fix.")
+		return nil
+	}
+	if ok, err := console.Stdin.PromptConfirm(fmt.Sprintf("Sign and send %d transactions?", len(fixes))); err != nil || !ok {
+		return nil
+	}
+	key := txSigningKey(ctx)
+	if from := crypto.PubkeyToAddress(key.PublicKey); from != account {
+		utils.Fatalf("Signing key of %s, not of %s", from.Hex(), account.Hex())
+	}
+	return sendNonceFixes(context.Background(), aquaclient.NewClient(client), key, txSigner(ctx), fixes)
+}
+
+// inspectNonces fetches the nonce and pool transactions of an account, and
+// prints the fixes planned for them. The gas price needed to be mined is the
+// suggested one if nil.
+func inspectNonces(ctx context.Context, client *rpc.Client, account common.Address, price *big.Int, bump uint64, cancel bool) ([]*nonceFix, error) {
+	aqua := aquaclient.NewClient(client)
+
+	nonce, err := aqua.NonceAt(ctx, account, nil)
+	if err != nil {
+		return nil, err
+	}
+	var content map[string]map[string]*aquaapi.RPCTransaction
+	if err := client.CallContext(ctx, &content, "txpool_contentFrom", account); err != nil {
+		return nil, err
+	}
+	pool, err := poolTransactions(content)
+	if err != nil {
+		return nil, err
+	}
+	if price == nil {
+		if price, err = aqua.SuggestGasPrice(ctx); err != nil {
+			return nil, err
+		}
+	}
+	fixes := planNonceFixes(nonce, pool, price, bump, cancel)
+	printNonceFixes(os.Stdout, account, nonce, pool, price, fixes)
+	return fixes, nil
+}
+
+// nonceFixBackend is the part of the node API the fixes are sent through.
+type nonceFixBackend interface {
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+}
+
+// sendNonceFixes signs and sends the fixes, lowest nonce first.
+func sendNonceFixes(ctx context.Context, backend nonceFixBackend, key *ecdsa.PrivateKey, signer types.Signer, fixes []*nonceFix) error {
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	for _, fix := range fixes {
+		tx, err := types.SignTx(fix.transaction(from), signer, key)
+		if err != nil {
+			return err
+		}
+		if err := backend.SendTransaction(ctx, tx); err != nil {
+			return fmt.Errorf("nonce %d: %v", fix.Nonce, err)
+		}
+		fmt.Printf("Sent %s for nonce %d (%s)\n", tx.Hash().Hex(), fix.Nonce, fix.Reason)
+	}
+	return nil
+}
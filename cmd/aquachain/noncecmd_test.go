@@ -0,0 +1,144 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/internal/aquaapi"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// testPoolTx creates a transaction of the pool transferring to a recipient.
+func testPoolTx(nonce uint64, price int64) *aquaapi.RPCTransaction {
+	to := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	return &aquaapi.RPCTransaction{
+		Hash:     common.BigToHash(big.NewInt(int64(nonce) + 1)),
+		Nonce:    hexutil.Uint64(nonce),
+		To:       &to,
+		Value:    (*hexutil.Big)(big.NewInt(1000)),
+		Gas:      hexutil.Uint64(50000),
+		GasPrice: (*hexutil.Big)(big.NewInt(price)),
+		Input:    hexutil.Bytes{0x01},
+	}
+}
+
+// Tests that the fewest fixes are planned: gaps filled, underpriced
+// transactions replaced, well priced ones left alone.
+func TestPlanNonceFixes(t *testing.T) {
+	pool := map[uint64]*aquaapi.RPCTransaction{
+		4: testPoolTx(4, 50),  // Already mined, ignored
+		5: testPoolTx(5, 100), // Well priced
+		6: testPoolTx(6, 95),  // Stuck, bumped to the needed price
+		9: testPoolTx(9, 10),  // Queued behind the gaps, stuck
+	}
+	fixes := planNonceFixes(5, pool, big.NewInt(100), 10, false)
+
+	want := []struct {
+		nonce  uint64
+		reason string
+		price  int64
+	}{
+		{6, nonceFixStuck, 104}, {7, nonceFixGap, 100}, {8, nonceFixGap, 100}, {9, nonceFixStuck, 100},
+	}
+	if len(fixes) != len(want) {
+		t.Fatalf("fix count mismatch: have %d, want %d", len(fixes), len(want))
+	}
+	for i, fix := range fixes {
+		if fix.Nonce != want[i].nonce || fix.Reason != want[i].reason || fix.Price.Int64() != want[i].price {
+			t.Errorf("fix %d mismatch: have %d/%s/%v, want %d/%s/%d", i, fix.Nonce, fix.Reason, fix.Price, want[i].nonce, want[i].reason, want[i].price)
+		}
+	}
+	if fixes[1].Replaces != nil || fixes[0].Replaces != pool[6] {
+		t.Errorf("replaced transactions mismatch")
+	}
+	// Nothing to fix without gaps nor stuck transactions
+	if fixes := planNonceFixes(5, map[uint64]*aquaapi.RPCTransaction{5: testPoolTx(5, 100)}, big.NewInt(100), 10, false); len(fixes) != 0 {
+		t.Errorf("fixes planned for a healthy account: %d", len(fixes))
+	}
+	if fixes := planNonceFixes(5, nil, big.NewInt(100), 10, false); len(fixes) != 0 {
+		t.Errorf("fixes planned for an empty pool: %d", len(fixes))
+	}
+}
+
+// Tests that the pending and queued transactions are flattened by nonce.
+func TestPoolTransactions(t *testing.T) {
+	content := map[string]map[string]*aquaapi.RPCTransaction{
+		"pending": {"3": testPoolTx(3, 1)},
+		"queued":  {"5": testPoolTx(5, 1)},
+	}
+	txs, err := poolTransactions(content)
+	if err != nil {
+		t.Fatalf("failed to flatten the pool: %v", err)
+	}
+	if len(txs) != 2 || txs[3] != content["pending"]["3"] || txs[5] != content["queued"]["5"] {
+		t.Fatalf("transactions mismatch: %v", txs)
+	}
+	content["queued"]["x"] = testPoolTx(6, 1)
+	if _, err := poolTransactions(content); err == nil {
+		t.Fatalf("invalid nonce accepted")
+	}
+}
+
+// testNonceFixBackend records the transactions sent.
+type testNonceFixBackend struct {
+	sent []*types.Transaction
+}
+
+func (b *testNonceFixBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.sent = append(b.sent, tx)
+	return nil
+}
+
+// Tests that the fixes are signed as resends, or as self-sends for gaps and
+// cancellations.
+func TestSendNonceFixes(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	pool := map[uint64]*aquaapi.RPCTransaction{1: testPoolTx(1, 10), 3: testPoolTx(3, 10)}
+	for _, cancel := range []bool{false, true} {
+		backend := new(testNonceFixBackend)
+		if err := sendNonceFixes(context.Background(), backend, key, types.HomesteadSigner{}, planNonceFixes(0, pool, big.NewInt(100), 10, cancel)); err != nil {
+			t.Fatalf("cancel %v: failed to send fixes: %v", cancel, err)
+		}
+		if len(backend.sent) != 4 {
+			t.Fatalf("cancel %v: sent count mismatch: have %d, want 4", cancel, len(backend.sent))
+		}
+		for i, tx := range backend.sent {
+			if tx.Nonce() != uint64(i) || tx.GasPrice().Int64() != 100 {
+				t.Errorf("cancel %v: tx %d mismatch: nonce %d, price %v", cancel, i, tx.Nonce(), tx.GasPrice())
+			}
+			if sender, _ := types.Sender(types.HomesteadSigner{}, tx); sender != from {
+				t.Errorf("cancel %v: tx %d sender mismatch: have %x, want %x", cancel, i, sender, from)
+			}
+			resend := i%2 == 1 && !cancel
+			switch {
+			case resend && (*tx.To() != *pool[uint64(i)].To || tx.Value().Int64() != 1000 || tx.Gas() != 50000):
+				t.Errorf("cancel %v: tx %d is not a resend", cancel, i)
+			case !resend && (*tx.To() != from || tx.Value().Sign() != 0 || tx.Gas() != params.TxGas):
+				t.Errorf("cancel %v: tx %d is not a self-send", cancel, i)
+			}
+		}
+	}
+}
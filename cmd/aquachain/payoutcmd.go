@@ -0,0 +1,603 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aquanetwork/aquachain"
+	"github.com/aquanetwork/aquachain/aquaclient"
+	"github.com/aquanetwork/aquachain/cmd/utils"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/math"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	payoutCSVFlag = cli.StringFlag{
+		Name:  "csv",
+		Usage: "CSV file of the payouts, as id,address,amount in wei lines (required)",
+	}
+	payoutJournalFlag = cli.StringFlag{
+		Name:  "journal",
+		Usage: "Journal file tracking the payouts across runs (default: the CSV file with a .journal suffix)",
+	}
+	payoutPriceFlag = cli.StringFlag{
+		Name:  "price",
+		Usage: "Gas price in wei (default: suggested by the node)",
+	}
+	payoutMaxPriceFlag = cli.StringFlag{
+		Name:  "maxprice",
+		Usage: "Maximum gas price in wei the price may be raised to by resends",
+	}
+	payoutBumpFlag = cli.Uint64Flag{
+		Name:  "pricebump",
+		Usage: "Gas price increase in percent of each resend, at least 10 to replace pending transactions",
+		Value: 10,
+	}
+	payoutResendFlag = cli.DurationFlag{
+		Name:  "resend",
+		Usage: "Time to wait for a payout to be mined before resending it",
+		Value: 5 * time.Minute,
+	}
+	payoutConfirmationsFlag = cli.Uint64Flag{
+		Name:  "confirmations",
+		Usage: "Number of blocks a payout has to be buried under to be final",
+		Value: 12,
+	}
+	payoutIntervalFlag = cli.DurationFlag{
+		Name:  "interval",
+		Usage: "Interval between checks of the payouts",
+		Value: 15 * time.Second,
+	}
+
+	payoutCommand = cli.Command{
+		Name:     "payout",
+		Usage:    "Send a batch of payouts and track them until final",
+		Action:   utils.MigrateFlags(payout),
+		Category: "ACCOUNT COMMANDS",
+		Flags: []cli.Flag{
+			payoutCSVFlag,
+			payoutJournalFlag,
+			txKeyFileFlag,
+			txMnemonicFlag,
+			utils.HDPathFlag,
+			utils.PasswordFileFlag,
+			txGasFlag,
+			payoutPriceFlag,
+			payoutMaxPriceFlag,
+			payoutBumpFlag,
+			payoutResendFlag,
+			payoutConfirmationsFlag,
+			payoutIntervalFlag,
+			txChainIdFlag,
+			utils.DataDirFlag,
+			utils.TestnetFlag,
+			utils.RinkebyFlag,
+			txEndpointFlag,
+		},
+		Description: `
+    aquachain payout --csv payouts.csv --keyfile <file> [--endpoint <ipc|url>]
+
+Send a batch of payouts, such as the rewards of a mining pool, through a running
+node. Every line of the CSV file holds a unique payout id, the recipient address
+and the amount in wei. A header line is skipped.
+
+Each payout is bound to a nonce of the paying account when first signed, and the
+binding is recorded in a journal before the transaction is broadcast. Payouts
+not mined in time, dropped by the node or reverted by a reorg are resent with the
+same nonce and a raised gas price, so at most one of their transactions can ever
+be included. The command waits until all the payouts are buried under enough
+blocks and prints a reconciliation report.
+
+The command may be interrupted and rerun with the same CSV file at any time:
+payouts already in the journal are tracked, never sent again.`,
+	}
+)
+
+// Statuses of the payouts in the journal.
+const (
+	payoutPending   = "pending"   // Broadcast, not yet mined
+	payoutMined     = "mined"     // Mined, not yet final
+	payoutConfirmed = "confirmed" // Mined and final
+	payoutFailed    = "failed"    // Mined and final, but the transfer failed
+	payoutConflict  = "conflict"  // Nonce taken by a transaction not in the journal
+)
+
+// payoutEntry is a payout and the transactions sent to pay it.
+type payoutEntry struct {
+	ID     string         `json:"id"`
+	To     common.Address `json:"to"`
+	Amount *big.Int       `json:"amount"`
+
+	Nonce  uint64               `json:"nonce"`
+	Txs    []*types.Transaction `json:"txs"`            // Signed transactions, all with the same nonce
+	Sent   time.Time            `json:"sent"`           // Time of the last broadcast
+	Status string               `json:"status"`         // Empty until first signed
+	Hash   common.Hash          `json:"hash,omitempty"` // Transaction included in the chain
+	Fee    *big.Int             `json:"fee,omitempty"`  // Fee paid by the included transaction
+}
+
+// final reports whether the payout needs no further tracking.
+func (e *payoutEntry) final() bool {
+	return e.Status == payoutConfirmed || e.Status == payoutFailed || e.Status == payoutConflict
+}
+
+// payoutJournal is the persistent record of the payouts of an account.
+type payoutJournal struct {
+	Account common.Address `json:"account"`
+	Payouts []*payoutEntry `json:"payouts"`
+}
+
+// loadPayoutJournal reads the journal at path, or creates an empty one for the
+// account if there is none.
+func loadPayoutJournal(path string, account common.Address) (*payoutJournal, error) {
+	blob, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &payoutJournal{Account: account}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	journal := new(payoutJournal)
+	if err := json.Unmarshal(blob, journal); err != nil {
+		return nil, fmt.Errorf("invalid journal %s: %v", path, err)
+	}
+	if journal.Account != account {
+		return nil, fmt.Errorf("journal %s belongs to account %s", path, journal.Account.Hex())
+	}
+	return journal, nil
+}
+
+// save atomically replaces the journal at path.
+func (j *payoutJournal) save(path string) error {
+	blob, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path+".tmp", blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// add appends the payouts not journaled yet. Journaled payouts must not have
+// changed, the payout id is what prevents them from being paid twice.
+func (j *payoutJournal) add(payouts []*payoutEntry) error {
+	known := make(map[string]*payoutEntry)
+	for _, entry := range j.Payouts {
+		known[entry.ID] = entry
+	}
+	for _, payout := range payouts {
+		if entry := known[payout.ID]; entry != nil {
+			if entry.To != payout.To || entry.Amount.Cmp(payout.Amount) != 0 {
+				return fmt.Errorf("payout %q changed since it was journaled: %v wei to %s", payout.ID, entry.Amount, entry.To.Hex())
+			}
+			continue
+		}
+		j.Payouts = append(j.Payouts, payout)
+	}
+	return nil
+}
+
+// readPayouts parses the payouts of a CSV file.
+func readPayouts(r io.Reader) ([]*payoutEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	var (
+		payouts []*payoutEntry
+		ids     = make(map[string]bool)
+	)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return payouts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		id, to, value := record[0], record[1], record[2]
+		amount, ok := math.ParseBig256(value)
+		if line == 1 && !ok && !common.IsHexAddress(to) {
+			continue // header
+		}
+		switch {
+		case id == "":
+			return nil, fmt.Errorf("line %d: missing payout id", line)
+		case ids[id]:
+			return nil, fmt.Errorf("line %d: duplicate payout id %q", line, id)
+		case !common.IsHexAddress(to):
+			return nil, fmt.Errorf("line %d: invalid address %q", line, to)
+		case !ok || amount.Sign() <= 0:
+			return nil, fmt.Errorf("line %d: invalid amount %q", line, value)
+		}
+		ids[id] = true
+		payouts = append(payouts, &payoutEntry{ID: id, To: common.HexToAddress(to), Amount: amount})
+	}
+}
+
+// payoutBackend is the part of the node API the payouts are sent through.
+type payoutBackend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// payer sends the payouts of a journal and tracks them until final.
+type payer struct {
+	backend payoutBackend
+	key     *ecdsa.PrivateKey
+	signer  types.Signer
+	journal *payoutJournal
+	path    string // Journal file, saved on every change
+
+	gas           uint64
+	price         *big.Int // Fixed gas price, nil for the suggested one
+	maxPrice      *big.Int // Limit of the raised gas prices, nil for none
+	bump          uint64
+	resend        time.Duration
+	confirmations uint64
+}
+
+// sign signs the payout transaction with the given gas price and records it in
+// the journal, before it is ever broadcast.
+func (p *payer) sign(entry *payoutEntry, price *big.Int) (*types.Transaction, error) {
+	tx, err := types.SignTx(types.NewTransaction(entry.Nonce, entry.To, entry.Amount, p.gas, price, nil), p.signer, p.key)
+	if err != nil {
+		return nil, err
+	}
+	entry.Txs = append(entry.Txs, tx)
+	entry.Sent = time.Now()
+	if entry.Status == "" {
+		entry.Status = payoutPending
+	}
+	return tx, p.journal.save(p.path)
+}
+
+// broadcast sends a payout transaction. Failures are only logged, the payout is
+// resent later on.
+func (p *payer) broadcast(ctx context.Context, entry *payoutEntry, tx *types.Transaction) {
+	if err := p.backend.SendTransaction(ctx, tx); err != nil && !strings.HasPrefix(err.Error(), "known transaction") {
+		log.Warn("Failed to send payout", "id", entry.ID, "nonce", entry.Nonce, "hash", tx.Hash(), "err", err)
+		return
+	}
+	log.Info("Sent payout", "id", entry.ID, "to", entry.To, "amount", entry.Amount, "nonce", entry.Nonce, "price", tx.GasPrice(), "hash", tx.Hash())
+}
+
+// send signs and broadcasts the payouts never sent before, once the balance of
+// the account is known to cover all the payouts in flight.
+func (p *payer) send(ctx context.Context) error {
+	price := p.price
+	if price == nil {
+		suggested, err := p.backend.SuggestGasPrice(ctx)
+		if err != nil {
+			return err
+		}
+		price = suggested
+	}
+	if p.maxPrice != nil && price.Cmp(p.maxPrice) > 0 {
+		price = p.maxPrice
+	}
+	var (
+		from    = crypto.PubkeyToAddress(p.key.PublicKey)
+		unsent  []*payoutEntry
+		needed  = new(big.Int)
+		nonce   uint64
+		journal bool
+	)
+	for _, entry := range p.journal.Payouts {
+		switch {
+		case entry.Status == "":
+			unsent = append(unsent, entry)
+			needed.Add(needed, entry.Amount)
+			needed.Add(needed, new(big.Int).Mul(price, new(big.Int).SetUint64(p.gas)))
+		case entry.Status == payoutPending:
+			needed.Add(needed, entry.Txs[len(entry.Txs)-1].Cost())
+		}
+		if entry.Status != "" && entry.Nonce >= nonce {
+			nonce, journal = entry.Nonce+1, true
+		}
+	}
+	if len(unsent) == 0 {
+		return nil
+	}
+	balance, err := p.backend.BalanceAt(ctx, from, nil)
+	if err != nil {
+		return err
+	}
+	if balance.Cmp(needed) < 0 {
+		return fmt.Errorf("insufficient funds for the payouts: have %s AQUA, need %s AQUA", common.FormatAqua(balance), common.FormatAqua(needed))
+	}
+	// Continue after both the journaled payouts and any other transaction of the account
+	pending, err := p.backend.PendingNonceAt(ctx, from)
+	if err != nil {
+		return err
+	}
+	if !journal || pending > nonce {
+		nonce = pending
+	}
+	for _, entry := range unsent {
+		entry.Nonce = nonce
+		nonce++
+
+		tx, err := p.sign(entry, price)
+		if err != nil {
+			return err
+		}
+		p.broadcast(ctx, entry, tx)
+	}
+	return nil
+}
+
+// resubmit broadcasts the payout again, replacing its transaction with one with
+// a raised gas price unless the maximum price is reached.
+func (p *payer) resubmit(ctx context.Context, entry *payoutEntry) error {
+	last := entry.Txs[len(entry.Txs)-1]
+
+	price := new(big.Int).Mul(last.GasPrice(), new(big.Int).SetUint64(100+p.bump))
+	price.Div(price, big.NewInt(100))
+	if p.maxPrice != nil && price.Cmp(p.maxPrice) > 0 {
+		price = p.maxPrice
+	}
+	if price.Cmp(last.GasPrice()) <= 0 {
+		entry.Sent = time.Now()
+		p.broadcast(ctx, entry, last)
+		return p.journal.save(p.path)
+	}
+	tx, err := p.sign(entry, price)
+	if err != nil {
+		return err
+	}
+	p.broadcast(ctx, entry, tx)
+	return nil
+}
+
+// receipt returns the receipt of whichever transaction of the payout is
+// included in the chain, if any.
+func (p *payer) receipt(ctx context.Context, entry *payoutEntry) (*types.Transaction, *types.Receipt, error) {
+	for i := len(entry.Txs) - 1; i >= 0; i-- {
+		receipt, err := p.backend.TransactionReceipt(ctx, entry.Txs[i].Hash())
+		if err == aquachain.NotFound || (err == nil && receipt == nil) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return entry.Txs[i], receipt, nil
+	}
+	return nil, nil, nil
+}
+
+// update checks the payouts not final yet against the chain, resending those
+// not mined in time, and reports whether all the payouts are final.
+func (p *payer) update(ctx context.Context) (bool, error) {
+	head, err := p.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	var (
+		from = crypto.PubkeyToAddress(p.key.PublicKey)
+		done = true
+	)
+	for _, entry := range p.journal.Payouts {
+		if entry.Status == payoutConfirmed || entry.Status == payoutFailed {
+			continue
+		}
+		tx, receipt, err := p.receipt(ctx, entry)
+		if err != nil {
+			return false, err
+		}
+		if receipt != nil {
+			entry.Hash, entry.Fee = tx.Hash(), new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(receipt.GasUsed))
+			entry.Status = payoutMined
+
+			// The nonce is used deep enough in the chain for the payout to be final
+			if number := head.Number.Uint64() + 1; number >= p.confirmations && p.confirmations > 0 {
+				nonce, err := p.backend.NonceAt(ctx, from, new(big.Int).SetUint64(number-p.confirmations))
+				if err != nil {
+					return false, err
+				}
+				if nonce > entry.Nonce {
+					entry.Status = payoutConfirmed
+					if receipt.Status == types.ReceiptStatusFailed {
+						entry.Status = payoutFailed
+					}
+				}
+			}
+			done = done && entry.final()
+			continue
+		}
+		if entry.Status == payoutMined {
+			log.Warn("Payout reverted by a reorg", "id", entry.ID, "nonce", entry.Nonce, "hash", entry.Hash)
+			entry.Hash, entry.Fee, entry.Sent = common.Hash{}, nil, time.Time{}
+		}
+		// Without a receipt, a used nonce means another transaction took it
+		nonce, err := p.backend.NonceAt(ctx, from, nil)
+		if err != nil {
+			return false, err
+		}
+		if nonce > entry.Nonce {
+			if entry.Status != payoutConflict {
+				log.Error("Payout nonce used by another transaction", "id", entry.ID, "nonce", entry.Nonce)
+			}
+			entry.Status = payoutConflict
+			continue
+		}
+		entry.Status, done = payoutPending, false
+		if time.Since(entry.Sent) >= p.resend {
+			if err := p.resubmit(ctx, entry); err != nil {
+				return false, err
+			}
+		}
+	}
+	return done, p.journal.save(p.path)
+}
+
+// report prints the reconciliation report of the journaled payouts.
+func (p *payer) report(w io.Writer) {
+	out := tabwriter.NewWriter(w, 1, 2, 2, ' ', 0)
+	fmt.Fprintln(out, "ID\tRECIPIENT\tAMOUNT\tNONCE\tSTATUS\tTRANSACTION")
+
+	var (
+		counts = make(map[string]int)
+		paid   = new(big.Int)
+		fees   = new(big.Int)
+	)
+	for _, entry := range p.journal.Payouts {
+		status, hash := entry.Status, ""
+		if status == "" {
+			status = "unsent"
+		}
+		if entry.Hash != (common.Hash{}) {
+			hash = entry.Hash.Hex()
+		} else if len(entry.Txs) > 0 {
+			hash = entry.Txs[len(entry.Txs)-1].Hash().Hex()
+		}
+		fmt.Fprintf(out, "%s\t%s\t%v\t%d\t%s\t%s\n", entry.ID, entry.To.Hex(), entry.Amount, entry.Nonce, status, hash)
+
+		counts[status]++
+		if entry.Status == payoutConfirmed {
+			paid.Add(paid, entry.Amount)
+		}
+		if entry.Fee != nil && (entry.Status == payoutConfirmed || entry.Status == payoutFailed) {
+			fees.Add(fees, entry.Fee)
+		}
+	}
+	out.Flush()
+
+	fmt.Fprintf(w, "\nConfirmed %d of %d payouts: %s AQUA paid, %s AQUA in fees\n", counts[payoutConfirmed], len(p.journal.Payouts), common.FormatAqua(paid), common.FormatAqua(fees))
+	if n := counts["unsent"] + counts[payoutPending] + counts[payoutMined]; n > 0 {
+		fmt.Fprintf(w, "Not final: %d payouts, rerun to keep tracking them\n", n)
+	}
+	if n := counts[payoutFailed]; n > 0 {
+		fmt.Fprintf(w, "Failed: %d payouts were mined but their transfer failed\n", n)
+	}
+	if n := counts[payoutConflict]; n > 0 {
+		fmt.Fprintf(w, "Conflicts: %d payout nonces were used by other transactions, check them before paying again\n", n)
+	}
+}
+
+// payout sends a batch of payouts and tracks them until final.
+func payout(ctx *cli.Context) error {
+	path := ctx.String(payoutCSVFlag.Name)
+	if path == "" {
+		utils.Fatalf("The payouts are required with --%s", payoutCSVFlag.Name)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		utils.Fatalf("Failed to open the payouts: %v", err)
+	}
+	payouts, err := readPayouts(file)
+	file.Close()
+	if err != nil {
+		utils.Fatalf("Invalid payouts %s: %v", path, err)
+	}
+	var prices [2]*big.Int
+	for i, flag := range []cli.StringFlag{payoutPriceFlag, payoutMaxPriceFlag} {
+		if value := ctx.String(flag.Name); value != "" {
+			price, ok := math.ParseBig256(value)
+			if !ok {
+				utils.Fatalf("Invalid --%s: %s", flag.Name, value)
+			}
+			prices[i] = price
+		}
+	}
+	key := txSigningKey(ctx)
+
+	journalPath := ctx.String(payoutJournalFlag.Name)
+	if journalPath == "" {
+		journalPath = path + ".journal"
+	}
+	journal, err := loadPayoutJournal(journalPath, crypto.PubkeyToAddress(key.PublicKey))
+	if err != nil {
+		utils.Fatalf("Failed to load the journal: %v", err)
+	}
+	if err := journal.add(payouts); err != nil {
+		utils.Fatalf("Failed to journal the payouts: %v", err)
+	}
+	endpoint := ctx.String(txEndpointFlag.Name)
+	if endpoint == "" {
+		endpoint = defaultIPCEndpoint(ctx)
+	}
+	client, err := dialRPC(endpoint)
+	if err != nil {
+		utils.Fatalf("Unable to attach to aquachain: %v", err)
+	}
+	defer client.Close()
+
+	p := &payer{
+		backend:       aquaclient.NewClient(client),
+		key:           key,
+		signer:        txSigner(ctx),
+		journal:       journal,
+		path:          journalPath,
+		gas:           ctx.Uint64(txGasFlag.Name),
+		price:         prices[0],
+		maxPrice:      prices[1],
+		bump:          ctx.Uint64(payoutBumpFlag.Name),
+		resend:        ctx.Duration(payoutResendFlag.Name),
+		confirmations: ctx.Uint64(payoutConfirmationsFlag.Name),
+	}
+	if err := p.send(context.Background()); err != nil {
+		utils.Fatalf("Failed to send the payouts: %v", err)
+	}
+	// Track the payouts until final, or until interrupted
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt)
+	defer signal.Stop(sigc)
+
+	ticker := time.NewTicker(ctx.Duration(payoutIntervalFlag.Name))
+	defer ticker.Stop()
+loop:
+	for {
+		done, err := p.update(context.Background())
+		if err != nil {
+			log.Warn("Failed to check the payouts", "err", err)
+		}
+		if done {
+			break
+		}
+		select {
+		case <-ticker.C:
+		case <-sigc:
+			break loop
+		}
+	}
+	p.report(os.Stdout)
+	return nil
+}
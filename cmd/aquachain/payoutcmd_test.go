@@ -0,0 +1,302 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// testPayoutChain is a payout backend simulating the chain and transaction pool
+// of a single account.
+type testPayoutChain struct {
+	blocks [][]*types.Transaction // Transactions of blocks #1 onwards
+	pool   map[uint64]*types.Transaction
+	sent   []*types.Transaction
+}
+
+func newTestPayoutChain() *testPayoutChain {
+	return &testPayoutChain{pool: make(map[uint64]*types.Transaction)}
+}
+
+func (c *testPayoutChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(int64(len(c.blocks)))}, nil
+}
+
+func (c *testPayoutChain) BalanceAt(ctx context.Context, account common.Address, number *big.Int) (*big.Int, error) {
+	return big.NewInt(params.Aqua), nil
+}
+
+func (c *testPayoutChain) NonceAt(ctx context.Context, account common.Address, number *big.Int) (uint64, error) {
+	blocks := c.blocks
+	if number != nil {
+		blocks = blocks[:number.Uint64()]
+	}
+	var nonce uint64
+	for _, txs := range blocks {
+		nonce += uint64(len(txs))
+	}
+	return nonce, nil
+}
+
+func (c *testPayoutChain) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	nonce, _ := c.NonceAt(ctx, account, nil)
+	for c.pool[nonce] != nil {
+		nonce++
+	}
+	return nonce, nil
+}
+
+func (c *testPayoutChain) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(100), nil
+}
+
+func (c *testPayoutChain) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if nonce, _ := c.NonceAt(ctx, common.Address{}, nil); tx.Nonce() < nonce {
+		return errors.New("nonce too low")
+	}
+	if old := c.pool[tx.Nonce()]; old != nil {
+		if old.Hash() == tx.Hash() {
+			return errors.New("known transaction: " + tx.Hash().Hex())
+		}
+		if tx.GasPrice().Cmp(new(big.Int).Div(new(big.Int).Mul(old.GasPrice(), big.NewInt(110)), big.NewInt(100))) < 0 {
+			return errors.New("replacement transaction underpriced")
+		}
+	}
+	c.pool[tx.Nonce()] = tx
+	c.sent = append(c.sent, tx)
+	return nil
+}
+
+func (c *testPayoutChain) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	for _, txs := range c.blocks {
+		for _, tx := range txs {
+			if tx.Hash() == hash {
+				return &types.Receipt{Status: types.ReceiptStatusSuccessful, GasUsed: params.TxGas}, nil
+			}
+		}
+	}
+	return nil, aquachain.NotFound
+}
+
+// mine includes the executable pool transactions in a new block.
+func (c *testPayoutChain) mine() {
+	var txs []*types.Transaction
+	nonce, _ := c.NonceAt(context.Background(), common.Address{}, nil)
+	for ; c.pool[nonce] != nil; nonce++ {
+		txs = append(txs, c.pool[nonce])
+		delete(c.pool, nonce)
+	}
+	c.blocks = append(c.blocks, txs)
+}
+
+// reorg replaces the last blocks with a longer chain of empty blocks, dropping
+// their transactions.
+func (c *testPayoutChain) reorg(depth int) {
+	c.blocks = c.blocks[:len(c.blocks)-depth]
+	for i := 0; i <= depth; i++ {
+		c.blocks = append(c.blocks, nil)
+	}
+}
+
+func newTestPayer(t *testing.T, chain *testPayoutChain, path string) *payer {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	journal, err := loadPayoutJournal(path, crypto.PubkeyToAddress(key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payouts, err := readPayouts(strings.NewReader(`id,address,amount
+round-1,0x289d485D9771714CCe91D3393D764E1311907ACc,1000
+round-2,0x7EF5A6135f1FD6a02593eEdC869c6D41D934aef8,2000
+round-3,0x0000000000000000000000000000000000000003,3000
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.add(payouts); err != nil {
+		t.Fatal(err)
+	}
+	return &payer{
+		backend:       chain,
+		key:           key,
+		signer:        types.HomesteadSigner{},
+		journal:       journal,
+		path:          path,
+		gas:           params.TxGas,
+		bump:          10,
+		resend:        time.Hour,
+		confirmations: 3,
+	}
+}
+
+func TestPayouts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "payout-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		ctx   = context.Background()
+		chain = newTestPayoutChain()
+		path  = filepath.Join(dir, "payouts.journal")
+		p     = newTestPayer(t, chain, path)
+	)
+	if err := p.send(ctx); err != nil {
+		t.Fatalf("failed to send payouts: %v", err)
+	}
+	if len(chain.sent) != 3 {
+		t.Fatalf("sent transactions mismatch: have %d, want 3", len(chain.sent))
+	}
+	// Drop the last payout from the pool, it is resent with a raised price
+	delete(chain.pool, 2)
+	chain.mine()
+	if done, err := p.update(ctx); err != nil || done {
+		t.Fatalf("update mismatch: done %v, err %v", done, err)
+	}
+	if len(chain.sent) != 3 {
+		t.Fatalf("payout resent before the resend timeout")
+	}
+	p.resend = 0
+	if _, err := p.update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(chain.sent) != 4 || chain.sent[3].Nonce() != 2 || chain.sent[3].GasPrice().Cmp(big.NewInt(110)) != 0 {
+		t.Fatalf("dropped payout not resent with the same nonce and a raised price")
+	}
+	// Revert the block including the resent payout, it is resent again
+	chain.mine()
+	if _, err := p.update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status := p.journal.Payouts[2].Status; status != payoutMined {
+		t.Fatalf("payout status mismatch: have %s, want %s", status, payoutMined)
+	}
+	chain.reorg(1)
+	if _, err := p.update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status := p.journal.Payouts[2].Status; status != payoutPending {
+		t.Fatalf("reverted payout status mismatch: have %s, want %s", status, payoutPending)
+	}
+	for i := 0; i < 3; i++ {
+		chain.mine()
+	}
+	if done, err := p.update(ctx); err != nil || !done {
+		t.Fatalf("payouts not final: done %v, err %v", done, err)
+	}
+	for _, tx := range chain.sent {
+		if tx.Nonce() > 2 {
+			t.Fatalf("payout sent with an extra nonce %d", tx.Nonce())
+		}
+	}
+	for _, entry := range p.journal.Payouts {
+		if entry.Status != payoutConfirmed {
+			t.Errorf("payout %s status mismatch: have %s, want %s", entry.ID, entry.Status, payoutConfirmed)
+		}
+	}
+	// Rerunning the batch doesn't pay anything again
+	sent := len(chain.sent)
+	p = newTestPayer(t, chain, path)
+	if err := p.send(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if done, err := p.update(ctx); err != nil || !done {
+		t.Fatalf("journaled payouts not final: done %v, err %v", done, err)
+	}
+	if len(chain.sent) != sent {
+		t.Fatalf("journaled payouts sent again")
+	}
+	out := new(bytes.Buffer)
+	p.report(out)
+	if !strings.Contains(out.String(), "Confirmed 3 of 3 payouts: 0.000000000000006 AQUA paid, 0.000000000006741 AQUA in fees") {
+		t.Errorf("report mismatch:\n%s", out)
+	}
+	// Changed payouts are refused
+	err = p.journal.add([]*payoutEntry{{ID: "round-1", To: common.Address{1}, Amount: big.NewInt(1000)}})
+	if err == nil {
+		t.Errorf("changed payout accepted")
+	}
+}
+
+func TestPayoutConflict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "payout-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		ctx   = context.Background()
+		chain = newTestPayoutChain()
+		p     = newTestPayer(t, chain, filepath.Join(dir, "payouts.journal"))
+	)
+	if err := p.send(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Another transaction of the account takes the nonce of the first payout
+	foreign, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(1), params.TxGas, big.NewInt(1000), nil), p.signer, p.key)
+	chain.pool[0] = foreign
+	chain.mine()
+	if _, err := p.update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status := p.journal.Payouts[0].Status; status != payoutConflict {
+		t.Fatalf("payout status mismatch: have %s, want %s", status, payoutConflict)
+	}
+	out := new(bytes.Buffer)
+	p.report(out)
+	if !strings.Contains(out.String(), "Conflicts: 1 payout nonces") {
+		t.Errorf("report mismatch:\n%s", out)
+	}
+}
+
+func TestReadPayouts(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{input: "a,0x0000000000000000000000000000000000000001,1\nb,0x0000000000000000000000000000000000000002,2\n"},
+		{input: "a,0x0000000000000000000000000000000000000001,1\na,0x0000000000000000000000000000000000000002,2\n", err: `line 2: duplicate payout id "a"`},
+		{input: "a,0x0000000000000000000000000000000000000001,1\nb,0x01,2\n", err: `line 2: invalid address "0x01"`},
+		{input: "a,0x0000000000000000000000000000000000000001,0\n", err: `line 1: invalid amount "0"`},
+		{input: ",0x0000000000000000000000000000000000000001,1\n", err: "line 1: missing payout id"},
+	}
+	for i, test := range tests {
+		_, err := readPayouts(strings.NewReader(test.input))
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Errorf("test %d: error mismatch: have %v, want %s", i, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquaclient"
+	"github.com/aquanetwork/aquachain/cmd/utils"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	selftestNetworkFlag = cli.StringFlag{
+		Name:  "network",
+		Usage: "Network to test against, only testnet is allowed",
+		Value: "testnet",
+	}
+	selftestBlocksFlag = cli.Uint64Flag{
+		Name:  "blocks",
+		Usage: "Number of blocks to sync before running the checks",
+		Value: 1000,
+	}
+	selftestTimeoutFlag = cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "Maximum time to spend syncing and waiting for the canary transaction",
+		Value: 30 * time.Minute,
+	}
+
+	selftestCommand = cli.Command{
+		Name:     "selftest",
+		Usage:    "Run an integration test of the node against the live testnet",
+		Action:   utils.MigrateFlags(selftest),
+		Category: "MISCELLANEOUS COMMANDS",
+		Flags: []cli.Flag{
+			selftestNetworkFlag,
+			selftestBlocksFlag,
+			selftestTimeoutFlag,
+			txKeyFileFlag,
+			txMnemonicFlag,
+			utils.HDPathFlag,
+			utils.PasswordFileFlag,
+			utils.DataDirFlag,
+			utils.BootnodesFlag,
+		},
+		Description: `
+    aquachain selftest --network testnet [--blocks 1000] [--keyfile <faucet key>]
+
+Start a node on the test network, sync a bounded range of blocks and run a suite
+of checks against it, for validating releases:
+
+ - the synced blocks match the checkpoints shipped for the network,
+ - the core RPC methods answer consistently with the synced chain,
+ - if a faucet key is given, a canary transaction sending nothing to the faucet
+   itself is mined.
+
+A pass/fail report is printed, and the command exits with an error if any of the
+checks failed.
+
+The command guards against harming a real deployment: it refuses any network
+but testnet, syncs into a temporary data directory removed afterwards unless one
+is given, listens on a random port, and the canary never moves funds.`,
+	}
+)
+
+// errSelftestSkipped is returned by the checks which don't apply to the setup.
+var errSelftestSkipped = errors.New("skipped")
+
+// selftestResult is the outcome of one check of the self-test.
+type selftestResult struct {
+	name    string
+	detail  string
+	err     error
+	elapsed time.Duration
+}
+
+// selftestSuite runs the checks of the self-test, collecting their results.
+type selftestSuite struct {
+	results []selftestResult
+}
+
+// run runs a check, recording its result. It returns whether the check passed.
+func (s *selftestSuite) run(name string, check func() (string, error)) bool {
+	start := time.Now()
+	detail, err := check()
+	s.results = append(s.results, selftestResult{name: name, detail: detail, err: err, elapsed: time.Since(start)})
+	return err == nil
+}
+
+// failed returns the number of failed checks.
+func (s *selftestSuite) failed() int {
+	var failed int
+	for _, res := range s.results {
+		if res.err != nil && res.err != errSelftestSkipped {
+			failed++
+		}
+	}
+	return failed
+}
+
+// report prints the results of the checks.
+func (s *selftestSuite) report(out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tRESULT\tTIME\tDETAIL")
+	for _, res := range s.results {
+		status, detail := "PASS", res.detail
+		switch {
+		case res.err == errSelftestSkipped:
+			status = "SKIP"
+		case res.err != nil:
+			status, detail = "FAIL", res.err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%v\t%s\n", res.name, status, common.PrettyDuration(res.elapsed), detail)
+	}
+	w.Flush()
+
+	if failed := s.failed(); failed > 0 {
+		fmt.Fprintf(out, "\n%d of %d checks failed\n", failed, len(s.results))
+	} else {
+		fmt.Fprintf(out, "\nAll %d checks passed\n", len(s.results))
+	}
+}
+
+// selftest is the selftest command.
+func selftest(ctx *cli.Context) error {
+	if network := ctx.String(selftestNetworkFlag.Name); network != "testnet" {
+		utils.Fatalf("Refusing to run the self-test on network %q, only testnet is allowed", network)
+	}
+	// Run a throwaway testnet node, out of the way of any running one
+	ctx.GlobalSet(utils.TestnetFlag.Name, "true")
+	if !ctx.GlobalIsSet(utils.DataDirFlag.Name) {
+		datadir, err := ioutil.TempDir("", "aquachain-selftest-")
+		if err != nil {
+			utils.Fatalf("Failed to create the data directory: %v", err)
+		}
+		defer os.RemoveAll(datadir)
+		ctx.GlobalSet(utils.DataDirFlag.Name, datadir)
+	}
+	if !ctx.GlobalIsSet(utils.ListenPortFlag.Name) {
+		ctx.GlobalSet(utils.ListenPortFlag.Name, "0")
+	}
+	var faucet *selftestFaucet
+	if ctx.IsSet(txKeyFileFlag.Name) || ctx.Bool(txMnemonicFlag.Name) {
+		faucet = &selftestFaucet{txSigningKey(ctx), txSigner(ctx)}
+	}
+	stack := makeFullNode(ctx)
+	utils.StartNode(stack)
+	defer stack.Stop()
+
+	rpcClient, err := stack.Attach()
+	if err != nil {
+		utils.Fatalf("Failed to attach to the node: %v", err)
+	}
+	var (
+		client   = aquaclient.NewClient(rpcClient)
+		deadline = time.Now().Add(ctx.Duration(selftestTimeoutFlag.Name))
+		target   = ctx.Uint64(selftestBlocksFlag.Name)
+		suite    = new(selftestSuite)
+		head     uint64
+	)
+	synced := suite.run("sync", func() (string, error) {
+		start := time.Now()
+		for {
+			header, err := client.HeaderByNumber(context.Background(), nil)
+			if err != nil {
+				return "", err
+			}
+			if head = header.Number.Uint64(); head >= target {
+				return fmt.Sprintf("synced to #%d in %v", head, common.PrettyDuration(time.Since(start))), nil
+			}
+			if time.Now().After(deadline) {
+				return "", fmt.Errorf("synced only %d of %d blocks in time", head, target)
+			}
+			time.Sleep(time.Second)
+		}
+	})
+	suite.run("checkpoints", func() (string, error) {
+		if !synced {
+			return "", errSelftestSkipped
+		}
+		checked, err := verifyCheckpoints(params.TestnetCheckpoints, head, func(number uint64) (common.Hash, error) {
+			return selftestBlockHash(rpcClient, number)
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d checkpoints up to #%d matched", checked, head), nil
+	})
+	suite.run("rpc", func() (string, error) {
+		return selftestRPC(rpcClient, client, head)
+	})
+	suite.run("canary", func() (string, error) {
+		if faucet == nil {
+			return "no faucet key configured", errSelftestSkipped
+		}
+		if !synced {
+			return "", errSelftestSkipped
+		}
+		return selftestCanary(client, faucet, deadline)
+	})
+	suite.report(os.Stdout)
+
+	if suite.failed() > 0 {
+		return errors.New("self-test failed")
+	}
+	return nil
+}
+
+// selftestFaucet is the key of the account funding the canary transaction, and
+// the signer to sign it with.
+type selftestFaucet struct {
+	key    *ecdsa.PrivateKey
+	signer types.Signer
+}
+
+// verifyCheckpoints checks the canonical hashes of the checkpoints at or below
+// the head, returning the number of checkpoints checked.
+func verifyCheckpoints(checkpoints params.Checkpoints, head uint64, hashAt func(uint64) (common.Hash, error)) (int, error) {
+	var checked int
+	for _, cp := range checkpoints {
+		if cp.Number > head {
+			continue
+		}
+		hash, err := hashAt(cp.Number)
+		if err != nil {
+			return checked, fmt.Errorf("checkpoint #%d: %v", cp.Number, err)
+		}
+		if hash != cp.Hash {
+			return checked, fmt.Errorf("checkpoint #%d mismatch: have %x, want %x", cp.Number, hash, cp.Hash)
+		}
+		checked++
+	}
+	if checked == 0 {
+		return 0, errors.New("no checkpoints below the head")
+	}
+	return checked, nil
+}
+
+// selftestBlockHash retrieves the hash of a canonical block as reported by the
+// node, rather than recomputing it from the header.
+func selftestBlockHash(client *rpc.Client, number uint64) (common.Hash, error) {
+	var block *struct {
+		Hash common.Hash `json:"hash"`
+	}
+	if err := client.CallContext(context.Background(), &block, "aqua_getBlockByNumber", hexutil.EncodeUint64(number), false); err != nil {
+		return common.Hash{}, err
+	}
+	if block == nil {
+		return common.Hash{}, fmt.Errorf("block #%d not found", number)
+	}
+	return block.Hash, nil
+}
+
+// selftestRPC exercises the core RPC methods, checking their answers against
+// the synced chain.
+func selftestRPC(rpcClient *rpc.Client, client *aquaclient.Client, head uint64) (string, error) {
+	background := context.Background()
+
+	var version string
+	if err := rpcClient.CallContext(background, &version, "net_version"); err != nil {
+		return "", fmt.Errorf("net_version: %v", err)
+	}
+	if want := params.TestnetChainConfig.ChainId.String(); version != want {
+		return "", fmt.Errorf("net_version mismatch: have %s, want %s", version, want)
+	}
+	var number hexutil.Big
+	if err := rpcClient.CallContext(background, &number, "aqua_blockNumber"); err != nil {
+		return "", fmt.Errorf("aqua_blockNumber: %v", err)
+	}
+	if number.ToInt().Uint64() < head {
+		return "", fmt.Errorf("aqua_blockNumber went back: have %d, want at least %d", number.ToInt(), head)
+	}
+	block, err := client.BlockByNumber(background, new(big.Int).SetUint64(head))
+	if err != nil {
+		return "", fmt.Errorf("aqua_getBlockByNumber: %v", err)
+	}
+	if block.NumberU64() != head {
+		return "", fmt.Errorf("aqua_getBlockByNumber mismatch: have #%d, want #%d", block.NumberU64(), head)
+	}
+	if _, err := client.BalanceAt(background, block.Coinbase(), block.Number()); err != nil {
+		return "", fmt.Errorf("aqua_getBalance: %v", err)
+	}
+	if _, err := client.SuggestGasPrice(background); err != nil {
+		return "", fmt.Errorf("aqua_gasPrice: %v", err)
+	}
+	if _, err := client.SyncProgress(background); err != nil {
+		return "", fmt.Errorf("aqua_syncing: %v", err)
+	}
+	return "net_version, aqua_blockNumber, aqua_getBlockByNumber, aqua_getBalance, aqua_gasPrice, aqua_syncing", nil
+}
+
+// selftestCanary sends a transaction of no value from the faucet to itself and
+// waits for it to be mined.
+func selftestCanary(client *aquaclient.Client, faucet *selftestFaucet, deadline time.Time) (string, error) {
+	background := context.Background()
+
+	from := crypto.PubkeyToAddress(faucet.key.PublicKey)
+	nonce, err := client.PendingNonceAt(background, from)
+	if err != nil {
+		return "", fmt.Errorf("aqua_getTransactionCount: %v", err)
+	}
+	price, err := client.SuggestGasPrice(background)
+	if err != nil {
+		return "", fmt.Errorf("aqua_gasPrice: %v", err)
+	}
+	tx, err := types.SignTx(types.NewTransaction(nonce, from, new(big.Int), params.TxGas, price, nil), faucet.signer, faucet.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign: %v", err)
+	}
+	if err := client.SendTransaction(background, tx); err != nil {
+		return "", fmt.Errorf("aqua_sendRawTransaction: %v", err)
+	}
+	for {
+		receipt, err := client.TransactionReceipt(background, tx.Hash())
+		if err == nil && receipt != nil {
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				return "", fmt.Errorf("transaction %x failed", tx.Hash())
+			}
+			return fmt.Sprintf("transaction %x mined", tx.Hash()), nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("transaction %x not mined in time", tx.Hash())
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that the checkpoints at or below the head are checked against the chain.
+func TestSelftestCheckpoints(t *testing.T) {
+	checkpoints := params.Checkpoints{
+		{Number: 0, Hash: common.Hash{0x00}},
+		{Number: 10, Hash: common.Hash{0x10}},
+		{Number: 20, Hash: common.Hash{0x20}},
+	}
+	chain := map[uint64]common.Hash{0: {0x00}, 10: {0x10}, 20: {0x21}}
+	hashAt := func(number uint64) (common.Hash, error) {
+		hash, ok := chain[number]
+		if !ok {
+			return common.Hash{}, errors.New("not found")
+		}
+		return hash, nil
+	}
+	if checked, err := verifyCheckpoints(checkpoints, 15, hashAt); err != nil || checked != 2 {
+		t.Errorf("below the mismatch: have %d checked, err %v, want 2 checked", checked, err)
+	}
+	if _, err := verifyCheckpoints(checkpoints, 20, hashAt); err == nil {
+		t.Errorf("mismatching checkpoint passed")
+	}
+	if _, err := verifyCheckpoints(checkpoints[1:], 5, hashAt); err == nil {
+		t.Errorf("no checkpoint checked but passed")
+	}
+}
+
+// Tests that skipped checks don't fail the self-test.
+func TestSelftestReport(t *testing.T) {
+	suite := new(selftestSuite)
+	if !suite.run("pass", func() (string, error) { return "fine", nil }) {
+		t.Errorf("passing check reported failed")
+	}
+	suite.run("skip", func() (string, error) { return "", errSelftestSkipped })
+	if failed := suite.failed(); failed != 0 {
+		t.Fatalf("failed checks mismatch: have %d, want 0", failed)
+	}
+	if suite.run("fail", func() (string, error) { return "", errors.New("broken") }) {
+		t.Errorf("failing check reported passed")
+	}
+	if failed := suite.failed(); failed != 1 {
+		t.Fatalf("failed checks mismatch: have %d, want 1", failed)
+	}
+	out := new(bytes.Buffer)
+	suite.report(out)
+	for _, want := range []string{"PASS", "SKIP", "FAIL", "broken", "1 of 3 checks failed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report misses %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/aquanetwork/aquachain/cmd/utils"
+	"github.com/aquanetwork/aquachain/consensus/aquahash/diffsim"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	simulateAlgoFlag = cli.StringFlag{
+		Name:  "algo",
+		Usage: "Comma separated retarget algorithms to compare (" + strings.Join(diffsim.AlgorithmNames(), ",") + ",step)",
+		Value: "hf5",
+	}
+	simulateScenarioFlag = cli.StringFlag{
+		Name:  "scenario",
+		Usage: "Hashrate scenario (constant, step, oscillate, replay)",
+		Value: "step",
+	}
+	simulateBlocksFlag = cli.IntFlag{
+		Name:  "blocks",
+		Usage: "Number of blocks to simulate",
+		Value: 5000,
+	}
+	simulateHashrateFlag = cli.Float64Flag{
+		Name:  "hashrate",
+		Usage: "Hashes per second of the network (default: on target at the start difficulty)",
+	}
+	simulateFactorFlag = cli.Float64Flag{
+		Name:  "factor",
+		Usage: "Hashrate multiplier of the step and oscillate scenarios",
+		Value: 4,
+	}
+	simulateAtFlag = cli.Uint64Flag{
+		Name:  "at",
+		Usage: "Block the hashrate changes at in the step scenario",
+		Value: 1000,
+	}
+	simulatePeriodFlag = cli.Uint64Flag{
+		Name:  "period",
+		Usage: "Blocks between hashrate changes in the oscillate scenario",
+		Value: 100,
+	}
+	simulateDifficultyFlag = cli.StringFlag{
+		Name:  "difficulty",
+		Usage: "Difficulty of the parent of the first block",
+		Value: params.MinimumDifficultyHF5.String(),
+	}
+	simulateSeedFlag = cli.Int64Flag{
+		Name:  "seed",
+		Usage: "Seed of the random block intervals",
+		Value: 1,
+	}
+	simulateInputFlag = cli.StringFlag{
+		Name:  "input",
+		Usage: "CSV file of recorded block timestamps to replay",
+	}
+	simulateCSVFlag = cli.StringFlag{
+		Name:  "csv",
+		Usage: "File to write the simulated blocks to as CSV",
+	}
+	simulateSVGFlag = cli.StringFlag{
+		Name:  "svg",
+		Usage: "File to plot the difficulty and block intervals to as SVG",
+	}
+	simulateDivisorFlag = cli.Uint64Flag{
+		Name:  "step.divisor",
+		Usage: "Difficulty bound divisor of the step algorithm",
+		Value: 16,
+	}
+	simulateTargetFlag = cli.Uint64Flag{
+		Name:  "step.target",
+		Usage: "Target block interval in seconds of the step algorithm",
+		Value: params.DurationLimit.Uint64(),
+	}
+	simulateMinimumFlag = cli.StringFlag{
+		Name:  "step.min",
+		Usage: "Minimum difficulty of the step algorithm",
+		Value: params.MinimumDifficultyHF5.String(),
+	}
+
+	simulateCommand = cli.Command{
+		Action:    utils.MigrateFlags(simulateDifficulty),
+		Name:      "simulate-difficulty",
+		Usage:     "Simulate the difficulty adjustment over a hashrate scenario",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			simulateAlgoFlag,
+			simulateScenarioFlag,
+			simulateBlocksFlag,
+			simulateHashrateFlag,
+			simulateFactorFlag,
+			simulateAtFlag,
+			simulatePeriodFlag,
+			simulateDifficultyFlag,
+			simulateSeedFlag,
+			simulateInputFlag,
+			simulateCSVFlag,
+			simulateSVGFlag,
+			simulateDivisorFlag,
+			simulateTargetFlag,
+			simulateMinimumFlag,
+		},
+		Category: "MISCELLANEOUS COMMANDS",
+		Description: `
+The simulate-difficulty command mines simulated blocks with the difficulty
+adjustment of each given algorithm, and reports how well the block interval
+holds the target. The hard fork algorithms are named after their fork, and the
+"step" algorithm has its parameters set by the --step.* flags to evaluate
+retarget changes of a future hard fork.
+
+The block intervals are drawn at random for the hashrate of the scenario:
+constant, stepping by --factor at block --at, or oscillating by --factor every
+--period blocks. The replay scenario instead runs the algorithms over the
+recorded block timestamps of the --input file.`,
+	}
+)
+
+// simulateDifficulty is the simulate-difficulty command.
+func simulateDifficulty(ctx *cli.Context) error {
+	parent := &types.Header{Number: new(big.Int), Time: new(big.Int), Difficulty: new(big.Int), UncleHash: types.EmptyUncleHash}
+	if _, ok := parent.Difficulty.SetString(ctx.String(simulateDifficultyFlag.Name), 10); !ok {
+		utils.Fatalf("Invalid difficulty: %s", ctx.String(simulateDifficultyFlag.Name))
+	}
+	var (
+		names     = strings.Split(ctx.String(simulateAlgoFlag.Name), ",")
+		retargets = make([]diffsim.Retarget, len(names))
+	)
+	for i, name := range names {
+		if name == "step" {
+			minimum, ok := new(big.Int).SetString(ctx.String(simulateMinimumFlag.Name), 10)
+			if !ok {
+				utils.Fatalf("Invalid minimum difficulty: %s", ctx.String(simulateMinimumFlag.Name))
+			}
+			retargets[i] = diffsim.StepRetarget(ctx.Uint64(simulateDivisorFlag.Name), ctx.Uint64(simulateTargetFlag.Name), minimum)
+			continue
+		}
+		if retargets[i] = diffsim.Algorithms[name]; retargets[i] == nil {
+			utils.Fatalf("Unknown retarget algorithm: %s", name)
+		}
+	}
+	// Default to a hashrate mining the start difficulty on target
+	rate := ctx.Float64(simulateHashrateFlag.Name)
+	if rate == 0 {
+		rate, _ = new(big.Float).SetInt(parent.Difficulty).Float64()
+		rate /= float64(params.DurationLimit.Uint64())
+	}
+	var hashrate diffsim.Hashrate
+	switch scenario := ctx.String(simulateScenarioFlag.Name); scenario {
+	case "constant":
+		hashrate = diffsim.ConstantHashrate(rate)
+	case "step":
+		hashrate = diffsim.StepHashrate(rate, ctx.Float64(simulateFactorFlag.Name), ctx.Uint64(simulateAtFlag.Name))
+	case "oscillate":
+		hashrate = diffsim.OscillatingHashrate(rate, ctx.Float64(simulateFactorFlag.Name), ctx.Uint64(simulatePeriodFlag.Name))
+	case "replay":
+	default:
+		utils.Fatalf("Unknown hashrate scenario: %s", scenario)
+	}
+	var times []uint64
+	if hashrate == nil {
+		file := ctx.String(simulateInputFlag.Name)
+		if file == "" {
+			utils.Fatalf("The replay scenario requires an --input file")
+		}
+		in, err := os.Open(file)
+		if err != nil {
+			utils.Fatalf("Failed to open timestamps: %v", err)
+		}
+		times, err = diffsim.ReadTimestamps(in)
+		in.Close()
+		if err != nil {
+			utils.Fatalf("Failed to read timestamps: %v", err)
+		}
+		if len(times) == 0 {
+			utils.Fatalf("No timestamps to replay in %s", file)
+		}
+		// Start a target interval before the first recorded block
+		if times[0] > params.DurationLimit.Uint64() {
+			parent.Time.SetUint64(times[0] - params.DurationLimit.Uint64())
+		}
+	}
+	// Run every algorithm over the same scenario and report the intervals
+	runs := make([][]diffsim.Block, len(names))
+	for i, retarget := range retargets {
+		if hashrate == nil {
+			blocks, err := diffsim.Replay(retarget, parent, times)
+			if err != nil {
+				utils.Fatalf("Failed to replay timestamps: %v", err)
+			}
+			runs[i] = blocks
+		} else {
+			runs[i] = diffsim.Simulate(retarget, hashrate, parent, ctx.Int(simulateBlocksFlag.Name), rand.New(rand.NewSource(ctx.Int64(simulateSeedFlag.Name))))
+		}
+		stats := diffsim.Summarize(runs[i], params.DurationLimit.Uint64())
+		last := runs[i][len(runs[i])-1]
+
+		fmt.Printf("%-10s blocks: %d, mean interval: %.1fs, median: %ds, stddev: %.1fs, max: %ds, settled: %d, difficulty: %v\n",
+			names[i], stats.Blocks, stats.MeanInterval, stats.Median, stats.StdDev, stats.MaxInterval, stats.Settled, last.Difficulty)
+	}
+	if file := ctx.String(simulateCSVFlag.Name); file != "" {
+		if err := writeSimulation(file, names, runs, diffsim.WriteCSV); err != nil {
+			utils.Fatalf("Failed to write CSV: %v", err)
+		}
+	}
+	if file := ctx.String(simulateSVGFlag.Name); file != "" {
+		if err := writeSimulation(file, names, runs, diffsim.WriteSVG); err != nil {
+			utils.Fatalf("Failed to write plot: %v", err)
+		}
+	}
+	return nil
+}
+
+// writeSimulation writes the simulated blocks to file with the given writer.
+func writeSimulation(file string, names []string, runs [][]diffsim.Block, write func(w io.Writer, names []string, runs [][]diffsim.Block) error) error {
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := write(out, names, runs); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
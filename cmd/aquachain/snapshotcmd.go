@@ -0,0 +1,99 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/aquanetwork/aquachain/cmd/utils"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/state/pruner"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	pruneBloomSizeFlag = cli.Uint64Flag{
+		Name:  "bloomsize",
+		Value: 1024,
+		Usage: "Megabytes of memory allocated to the bloom filter of retained state (larger prunes more thoroughly)",
+	}
+
+	snapshotCommand = cli.Command{
+		Name:     "snapshot",
+		Usage:    "Maintain the state of the chain database",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+
+Offline maintenance of the state stored in the chain database. The node must be
+stopped while running these commands.`,
+		Subcommands: []cli.Command{
+			{
+				Name:   "prune-state",
+				Usage:  "Delete all historical state not referenced by the head block",
+				Action: utils.MigrateFlags(pruneState),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+					utils.RinkebyFlag,
+					pruneBloomSizeFlag,
+				},
+				Description: `
+    aquachain snapshot prune-state [--bloomsize <megabytes>]
+
+Walk the state trie of the head block, recording every reachable node in a bloom
+filter, then delete all other trie nodes and contract code from the database and
+compact it. Only the state of the head block and the genesis block is kept, so
+historical state queries and chain rewinds are no longer possible afterwards.
+
+Stop the node cleanly before pruning, so its head state is written to disk.`,
+			},
+		},
+	}
+)
+
+// pruneState deletes all state not reachable from the head or genesis block.
+func pruneState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	head := core.GetHeadBlockHash(db)
+	if head == (common.Hash{}) {
+		utils.Fatalf("No head block found in the database")
+	}
+	header := core.GetHeaderNoVersion(db, head, core.GetBlockNumber(db, head))
+	if header == nil {
+		utils.Fatalf("Head block %x missing from the database", head)
+	}
+	if _, err := state.New(header.Root, state.NewDatabase(db)); err != nil {
+		utils.Fatalf("State of head block #%d missing, restart and cleanly stop the node before pruning: %v", header.Number, err)
+	}
+	roots := []common.Hash{header.Root}
+	if genesis := core.GetHeaderNoVersion(db, core.GetCanonicalHash(db, 0), 0); genesis != nil && genesis.Root != header.Root {
+		roots = append(roots, genesis.Root)
+	}
+	stats, err := pruner.Prune(db, roots, ctx.Uint64(pruneBloomSizeFlag.Name)*1024*1024)
+	if err != nil {
+		utils.Fatalf("Failed to prune state: %v", err)
+	}
+	fmt.Printf("Pruned state of block #%d [%x]\n", header.Number, head)
+	fmt.Printf("Retained %d entries, deleted %d entries (%v)\n", stats.Retained, stats.Deleted, stats.DeletedSize)
+	return nil
+}
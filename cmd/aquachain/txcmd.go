@@ -0,0 +1,309 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/accounts/hdwallet"
+	"github.com/aquanetwork/aquachain/accounts/keystore"
+	"github.com/aquanetwork/aquachain/aquaclient"
+	"github.com/aquanetwork/aquachain/cmd/utils"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/common/math"
+	"github.com/aquanetwork/aquachain/console"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/rlp"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	txKeyFileFlag = cli.StringFlag{
+		Name:  "keyfile",
+		Usage: "Keystore file of the account to sign with",
+	}
+	txMnemonicFlag = cli.BoolFlag{
+		Name:  "mnemonic",
+		Usage: "Sign with the account derived from a mnemonic phrase (prompted) at --hdpath (default m/44'/61717561'/0'/0/0)",
+	}
+	txToFlag = cli.StringFlag{
+		Name:  "to",
+		Usage: "Recipient address (omit to create a contract)",
+	}
+	txValueFlag = cli.StringFlag{
+		Name:  "value",
+		Value: "0",
+		Usage: "Amount to transfer in wei",
+	}
+	txNonceFlag = cli.Uint64Flag{
+		Name:  "nonce",
+		Usage: "Nonce of the transaction, the number of transactions sent by the account so far (required)",
+	}
+	txGasFlag = cli.Uint64Flag{
+		Name:  "gas",
+		Value: params.TxGas,
+		Usage: "Gas limit of the transaction",
+	}
+	txPriceFlag = cli.StringFlag{
+		Name:  "price",
+		Usage: "Gas price in wei (required)",
+	}
+	txDataFlag = cli.StringFlag{
+		Name:  "data",
+		Usage: "Hex encoded transaction payload",
+	}
+	txChainIdFlag = cli.Uint64Flag{
+		Name:  "chainid",
+		Usage: "Chain ID for replay protected (EIP-155) signing (default: as required by the selected network)",
+	}
+	txOutFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "File to write the signed transaction to (default: stdout)",
+	}
+	txEndpointFlag = cli.StringFlag{
+		Name:  "endpoint",
+		Usage: "IPC path or RPC URL of the node to broadcast to (default: the IPC endpoint in the data directory)",
+	}
+
+	txCommand = cli.Command{
+		Name:     "tx",
+		Usage:    "Sign transactions offline and broadcast signed transactions",
+		Category: "ACCOUNT COMMANDS",
+		Description: `
+
+Sign transactions on an offline machine, keeping the keys away from any online
+node, and broadcast the signed transactions from an online one.`,
+		Subcommands: []cli.Command{
+			{
+				Name:   "sign",
+				Usage:  "Sign a transaction offline",
+				Action: utils.MigrateFlags(txSign),
+				Flags: []cli.Flag{
+					txKeyFileFlag,
+					txMnemonicFlag,
+					utils.HDPathFlag,
+					utils.PasswordFileFlag,
+					txToFlag,
+					txValueFlag,
+					txNonceFlag,
+					txGasFlag,
+					txPriceFlag,
+					txDataFlag,
+					txChainIdFlag,
+					utils.TestnetFlag,
+					utils.RinkebyFlag,
+					txOutFlag,
+				},
+				Description: `
+    aquachain tx sign --keyfile <file> --to <address> --value <wei> --nonce <n> --price <wei>
+
+Sign a transaction with the key of a keystore file (--keyfile) or derived from a
+mnemonic phrase (--mnemonic), without connecting to any node. The signed
+transaction is printed hex encoded, ready to be broadcast with 'tx send'.
+
+The networks of AquaChain sign without EIP-155 replay protection, which can be
+requested with --chainid for networks supporting it.`,
+			},
+			{
+				Name:      "send",
+				Usage:     "Broadcast a signed transaction",
+				ArgsUsage: "<hex|filename>",
+				Action:    utils.MigrateFlags(txSend),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.TestnetFlag,
+					utils.RinkebyFlag,
+					txEndpointFlag,
+				},
+				Description: `
+    aquachain tx send [--endpoint <ipc|url>] <hex|filename>
+
+Broadcast a hex encoded signed transaction, given directly or in a file, through
+a running node.`,
+			},
+		},
+	}
+)
+
+// txSign signs a transaction offline with a key from a keyfile or mnemonic.
+func txSign(ctx *cli.Context) error {
+	if !ctx.IsSet(txNonceFlag.Name) {
+		utils.Fatalf("Offline signing requires --%s", txNonceFlag.Name)
+	}
+	if !ctx.IsSet(txPriceFlag.Name) {
+		utils.Fatalf("Offline signing requires --%s", txPriceFlag.Name)
+	}
+	value, ok := math.ParseBig256(ctx.String(txValueFlag.Name))
+	if !ok {
+		utils.Fatalf("Invalid --%s: %s", txValueFlag.Name, ctx.String(txValueFlag.Name))
+	}
+	price, ok := math.ParseBig256(ctx.String(txPriceFlag.Name))
+	if !ok {
+		utils.Fatalf("Invalid --%s: %s", txPriceFlag.Name, ctx.String(txPriceFlag.Name))
+	}
+	data, err := hexutil.Decode(ctx.String(txDataFlag.Name))
+	if ctx.String(txDataFlag.Name) == "" {
+		data, err = nil, nil
+	}
+	if err != nil {
+		utils.Fatalf("Invalid --%s: %v", txDataFlag.Name, err)
+	}
+	var (
+		nonce = ctx.Uint64(txNonceFlag.Name)
+		gas   = ctx.Uint64(txGasFlag.Name)
+		tx    *types.Transaction
+	)
+	if to := ctx.String(txToFlag.Name); to != "" {
+		if !common.IsHexAddress(to) {
+			utils.Fatalf("Invalid --%s: %s", txToFlag.Name, to)
+		}
+		tx = types.NewTransaction(nonce, common.HexToAddress(to), value, gas, price, data)
+	} else {
+		if len(data) == 0 {
+			utils.Fatalf("Contract creation without --%s", txDataFlag.Name)
+		}
+		tx = types.NewContractCreation(nonce, value, gas, price, data)
+	}
+	key := txSigningKey(ctx)
+	signed, err := types.SignTx(tx, txSigner(ctx), key)
+	if err != nil {
+		utils.Fatalf("Failed to sign transaction: %v", err)
+	}
+	raw, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		utils.Fatalf("Failed to encode transaction: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Signed transaction %s from %s\n", signed.Hash().Hex(), crypto.PubkeyToAddress(key.PublicKey).Hex())
+
+	if out := ctx.String(txOutFlag.Name); out != "" {
+		return ioutil.WriteFile(out, []byte(hexutil.Encode(raw)+"\n"), 0600)
+	}
+	fmt.Println(hexutil.Encode(raw))
+	return nil
+}
+
+// txSigner returns the signer for the requested chain ID, or the one required by
+// the selected network.
+func txSigner(ctx *cli.Context) types.Signer {
+	if ctx.IsSet(txChainIdFlag.Name) {
+		return types.NewEIP155Signer(new(big.Int).SetUint64(ctx.Uint64(txChainIdFlag.Name)))
+	}
+	config := params.MainnetChainConfig
+	switch {
+	case ctx.GlobalBool(utils.TestnetFlag.Name):
+		config = params.TestnetChainConfig
+	case ctx.GlobalBool(utils.RinkebyFlag.Name):
+		config = params.RinkebyChainConfig
+	}
+	if config.EIP155Block != nil {
+		return types.NewEIP155Signer(config.ChainId)
+	}
+	return types.HomesteadSigner{}
+}
+
+// txSigningKey loads the key to sign with from the keyfile or the mnemonic.
+func txSigningKey(ctx *cli.Context) *ecdsa.PrivateKey {
+	keyfile, mnemonic := ctx.String(txKeyFileFlag.Name), ctx.Bool(txMnemonicFlag.Name)
+	switch {
+	case keyfile != "" && mnemonic:
+		utils.Fatalf("Flags --%s and --%s are mutually exclusive", txKeyFileFlag.Name, txMnemonicFlag.Name)
+
+	case keyfile != "":
+		keyjson, err := ioutil.ReadFile(keyfile)
+		if err != nil {
+			utils.Fatalf("Failed to read the keyfile: %v", err)
+		}
+		passphrase := getPassPhrase("", false, 0, utils.MakePasswordList(ctx))
+		key, err := keystore.DecryptKey(keyjson, passphrase)
+		if err != nil {
+			utils.Fatalf("Failed to decrypt the keyfile: %v", err)
+		}
+		return key.PrivateKey
+
+	case mnemonic:
+		path := accounts.AquaBaseDerivationPath
+		if hdpath := ctx.GlobalString(utils.HDPathFlag.Name); hdpath != "" {
+			var err error
+			if path, err = accounts.ParseDerivationPath(hdpath); err != nil {
+				utils.Fatalf("Invalid --%s: %v", utils.HDPathFlag.Name, err)
+			}
+		}
+		phrase, err := console.Stdin.PromptPassword("Mnemonic: ")
+		if err != nil {
+			utils.Fatalf("Failed to read the mnemonic: %v", err)
+		}
+		seed, err := hdwallet.NewSeed(phrase, "")
+		if err != nil {
+			utils.Fatalf("Failed to decode the mnemonic: %v", err)
+		}
+		key, err := hdwallet.DeriveKey(seed, path)
+		if err != nil {
+			utils.Fatalf("Failed to derive key at %v: %v", path, err)
+		}
+		return key
+	}
+	utils.Fatalf("Either --%s or --%s is required to sign", txKeyFileFlag.Name, txMnemonicFlag.Name)
+	return nil
+}
+
+// txSend broadcasts a signed transaction through a running node.
+func txSend(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	input := strings.TrimSpace(ctx.Args().First())
+	if !strings.HasPrefix(input, "0x") {
+		blob, err := ioutil.ReadFile(input)
+		if err != nil {
+			utils.Fatalf("Failed to read the transaction: %v", err)
+		}
+		input = strings.TrimSpace(string(blob))
+	}
+	raw, err := hexutil.Decode(input)
+	if err != nil {
+		utils.Fatalf("Invalid transaction encoding: %v", err)
+	}
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(raw, tx); err != nil {
+		utils.Fatalf("Invalid transaction: %v", err)
+	}
+	endpoint := ctx.String(txEndpointFlag.Name)
+	if endpoint == "" {
+		endpoint = defaultIPCEndpoint(ctx)
+	}
+	client, err := dialRPC(endpoint)
+	if err != nil {
+		utils.Fatalf("Unable to attach to aquachain: %v", err)
+	}
+	defer client.Close()
+
+	if err := aquaclient.NewClient(client).SendTransaction(context.Background(), tx); err != nil {
+		utils.Fatalf("Failed to send transaction: %v", err)
+	}
+	fmt.Println(tx.Hash().Hex())
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/rlp"
+)
+
+func TestTxSign(t *testing.T) {
+	keyfile := filepath.Join("..", "..", "accounts", "keystore", "testdata", "keystore",
+		"UTC--2016-03-22T12-57-55.920751759Z--7ef5a6135f1fd6a02593eedc869c6d41d934aef8")
+	aquachain := runAquaChain(t, "tx", "sign",
+		"--keyfile", keyfile, "--password", "testdata/passwords.txt",
+		"--to", "0x289d485D9771714CCe91D3393D764E1311907ACc", "--value", "1000",
+		"--nonce", "7", "--price", "1000000000", "--chainid", "1")
+	_, matches := aquachain.ExpectRegexp(`(0x[0-9a-f]+)\n`)
+	aquachain.ExpectExit()
+
+	raw, err := hexutil.Decode(matches[1])
+	if err != nil {
+		t.Fatalf("invalid hex output: %v", err)
+	}
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(raw, tx); err != nil {
+		t.Fatalf("invalid transaction: %v", err)
+	}
+	from, err := types.Sender(types.NewEIP155Signer(big.NewInt(1)), tx)
+	if err != nil {
+		t.Fatalf("failed to recover sender: %v", err)
+	}
+	if from != common.HexToAddress("0x7EF5A6135f1FD6a02593eEdC869c6D41D934aef8") {
+		t.Errorf("sender mismatch: have %x", from)
+	}
+	if tx.Nonce() != 7 || tx.Value().Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("transaction mismatch: nonce %d, value %v", tx.Nonce(), tx.Value())
+	}
+	if !strings.Contains(aquachain.StderrText(), "from 0x7EF5A6135f1FD6a02593eEdC869c6D41D934aef8") {
+		t.Errorf("stderr text does not contain the sender")
+	}
+}
+
+func TestTxSignMissingNonce(t *testing.T) {
+	aquachain := runAquaChain(t, "tx", "sign", "--to", "0x289d485D9771714CCe91D3393D764E1311907ACc", "--price", "1")
+	defer aquachain.ExpectExit()
+	aquachain.Expect(`
+Fatal: Offline signing requires --nonce
+`)
+}
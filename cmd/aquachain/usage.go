@@ -77,17 +77,32 @@ var AppHelpFlagGroups = []flagGroup{
 			configFileFlag,
 			utils.DataDirFlag,
 			utils.KeyStoreDirFlag,
+			utils.DBEngineFlag,
 			utils.NoUSBFlag,
+			utils.HDPathFlag,
+			utils.NoPersonalFlag,
+			utils.ExternalSignerFlag,
+			utils.ExternalSignerAsyncFlag,
+			utils.ExternalSignerTimeoutFlag,
+			utils.KeyStoreBackendFlag,
+			utils.VaultURLFlag,
+			utils.VaultUnlockFlag,
 			utils.NetworkIdFlag,
 			utils.TestnetFlag,
 			utils.RinkebyFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
-			utils.AquaStatsURLFlag,
+			utils.TxLookupLimitFlag,
+			utils.SnapshotFlag,
+			utils.AccountHistoryFlag,
+			utils.RoleFlag,
+			utils.StatsURLFlag,
+			utils.ContractRegistryFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
 			utils.LightKDFFlag,
+			utils.KDFFlag,
 		},
 	},
 	{Name: "DEVELOPER CHAIN",
@@ -105,6 +120,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.AquahashDatasetDirFlag,
 			utils.AquahashDatasetsInMemoryFlag,
 			utils.AquahashDatasetsOnDiskFlag,
+			utils.AquahashCachesAheadFlag,
+			utils.AquahashDatasetsAheadFlag,
+			utils.AquahashCheckpointsFlag,
 		},
 	},
 	//{
@@ -120,9 +138,12 @@ var AppHelpFlagGroups = []flagGroup{
 	{
 		Name: "TRANSACTION POOL",
 		Flags: []cli.Flag{
+			utils.TxPoolLocalsFlag,
 			utils.TxPoolNoLocalsFlag,
+			utils.TxPoolDefenseFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
+			utils.TxPoolNoSnapshotFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
@@ -137,7 +158,9 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.CacheFlag,
 			utils.CacheDatabaseFlag,
+			utils.DBCompressFlag,
 			utils.CacheGCFlag,
+			utils.CacheNoPrefetchFlag,
 			utils.TrieCacheGenFlag,
 		},
 	},
@@ -155,15 +178,40 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCListenAddrFlag,
 			utils.RPCPortFlag,
 			utils.RPCApiFlag,
+			utils.RPCAdminSecretFlag,
+			utils.RPCAuthApiFlag,
+			utils.RPCDenyFlag,
+			utils.RPCAllowUnsafeAPIsFlag,
 			utils.WSEnabledFlag,
 			utils.WSListenAddrFlag,
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.WSVirtualHostsFlag,
+			utils.GraphQLEnabledFlag,
+			utils.GraphQLListenAddrFlag,
+			utils.GraphQLPortFlag,
+			utils.GraphQLCORSDomainFlag,
+			utils.GraphQLVirtualHostsFlag,
+			utils.StreamSinkFlag,
+			utils.StreamAddressesFlag,
+			utils.StreamTopicsFlag,
+			utils.StreamFromFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
+			utils.IPCApiFlag,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
+			utils.RPCStrictJSONFlag,
+			utils.RPCRateLimitFlag,
+			utils.RPCRateBurstFlag,
+			utils.RPCBatchLimitFlag,
+			utils.RPCResponseLimitFlag,
+			utils.RPCGasCapFlag,
+			utils.RPCCallGasCapFlag,
+			utils.RPCReexecFlag,
+			utils.RPCAllowUnprotectedTxsFlag,
+			utils.RPCTxWebhookFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -175,11 +223,13 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.BootnodesFlag,
 			utils.BootnodesV4Flag,
 			utils.BootnodesV5Flag,
+			utils.DNSDiscoveryFlag,
 			utils.ListenPortFlag,
 			utils.MaxPeersFlag,
 			utils.MaxPendingPeersFlag,
 			utils.NATFlag,
 			utils.NoDiscoverFlag,
+			utils.NoClockCheckFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
 			utils.NodeKeyFileFlag,
@@ -191,10 +241,26 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.MiningEnabledFlag,
 			utils.MinerThreadsFlag,
+			utils.MinerThrottleFlag,
 			utils.AquabaseFlag,
+			utils.AquabasesFlag,
+			utils.ForceAquabaseFlag,
 			utils.TargetGasLimitFlag,
 			utils.GasPriceFlag,
 			utils.ExtraDataFlag,
+			utils.MinerPeersFlag,
+			utils.ForkGuardFlag,
+			utils.MinerStaleGraceFlag,
+			utils.MinerUnclesFlag,
+			utils.MinerUncleVerifyCostFlag,
+			utils.TieBreakFlag,
+		},
+	},
+	{
+		Name: "RESEARCH",
+		Flags: []cli.Flag{
+			utils.AnnounceDelayFlag,
+			utils.WithholdBlocksFlag,
 		},
 	},
 	{
@@ -208,12 +274,24 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "VIRTUAL MACHINE",
 		Flags: []cli.Flag{
 			utils.VMEnableDebugFlag,
+			utils.ForensicsDirFlag,
+			utils.VMRPCCallDepthFlag,
+			utils.VMRPCMemoryFlag,
+			utils.VMTraceLimitFlag,
 		},
 	},
 	{
 		Name: "LOGGING AND DEBUGGING",
 		Flags: append([]cli.Flag{
 			utils.MetricsEnabledFlag,
+			utils.MetricsHTTPFlag,
+			utils.MetricsPortFlag,
+			utils.MetricsEnableInfluxDBFlag,
+			utils.MetricsInfluxDBEndpointFlag,
+			utils.MetricsInfluxDBDatabaseFlag,
+			utils.MetricsInfluxDBUsernameFlag,
+			utils.MetricsInfluxDBPasswordFlag,
+			utils.MetricsInfluxDBHostTagFlag,
 			utils.FakePoWFlag,
 			utils.NoCompactionFlag,
 		}, debug.Flags...),
@@ -227,6 +305,9 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.FastSyncFlag,
 			utils.LightModeFlag,
+			utils.LegacyAquaStatsURLFlag,
+			utils.LegacyGpoBlocksFlag,
+			utils.LegacyGpoPercentileFlag,
 		},
 	},
 	{
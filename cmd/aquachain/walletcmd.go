@@ -32,7 +32,14 @@ will launch browser MAW`,
 		Description: `
   aquachain wallet
 
-will launch browser MAW`,
+will launch browser MAW
+
+  aquachain wallet fix-nonce <address>
+
+will repair the nonce holes and stuck transactions of an account`,
+		Subcommands: []cli.Command{
+			fixNonceCommand,
+		},
 	}
 	paperCommand = cli.Command{
 		Name:      "paper",
@@ -19,24 +19,36 @@ package utils
 
 import (
 	"compress/gzip"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/signal"
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/aquanetwork/aquachain/aqua"
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
 	"github.com/aquanetwork/aquachain/core"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/internal/debug"
 	"github.com/aquanetwork/aquachain/log"
 	"github.com/aquanetwork/aquachain/node"
+	"github.com/aquanetwork/aquachain/params"
 	"github.com/aquanetwork/aquachain/rlp"
+	"github.com/aquanetwork/aquachain/tests"
 )
 
 const (
-	importBatchSize = 2500
+	importBatchSize      = 2500
+	importValueLimit     = 64 * 1024 * 1024 // Maximum size of an imported block, rejecting corrupt sizes up front
+	reindexBatchSize     = 10000            // Number of blocks to reindex between progress checkpoints
+	importReportInterval = 8 * time.Second  // Time between import progress reports
 )
 
 // Fatalf formats a message to standard error and exits the program.
@@ -117,11 +129,16 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 			return err
 		}
 	}
-	stream := rlp.NewStream(reader, 0)
+	stream := rlp.NewValueStream(reader, importValueLimit)
 
 	// Run actual the import.
-	blocks := make(types.Blocks, importBatchSize)
-	n := 0
+	var (
+		blocks   = make(types.Blocks, importBatchSize)
+		n        = 0
+		imported = 0
+		start    = time.Now()
+		reported = time.Now()
+	)
 	for batch := 0; ; batch++ {
 		// Load a batch of RLP blocks.
 		if checkInterrupt() {
@@ -152,13 +169,19 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 		}
 		missing := missingBlocks(chain, blocks[:i])
 		if len(missing) == 0 {
-			log.Info("Skipping batch as all blocks present", "batch", batch, "first", blocks[0].Hash(), "last", blocks[i-1].Hash())
-			continue
+			log.Debug("Skipping batch as all blocks present", "batch", batch, "first", blocks[0].Hash(), "last", blocks[i-1].Hash())
+		} else {
+			if _, err := chain.InsertChain(missing); err != nil {
+				return fmt.Errorf("invalid block %d: %v", n, err)
+			}
+			imported += len(missing)
 		}
-		if _, err := chain.InsertChain(missing); err != nil {
-			return fmt.Errorf("invalid block %d: %v", n, err)
+		if time.Since(reported) >= importReportInterval {
+			log.Info("Importing blockchain", "read", n, "imported", imported, "skipped", n-imported, "number", blocks[i-1].NumberU64(), "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
 		}
 	}
+	log.Info("Imported blockchain", "file", fn, "read", n, "imported", imported, "skipped", n-imported, "elapsed", common.PrettyDuration(time.Since(start)))
 	return nil
 }
 
@@ -224,3 +247,199 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 	log.Info("Exported blockchain to", "file", fn)
 	return nil
 }
+
+// ExportState streams the full account state at the given block into a file as
+// JSON lines, gzip compressed if the file name ends with .gz.
+func ExportState(chain *core.BlockChain, block *types.Block, fn string) error {
+	statedb, err := chain.StateAt(block.Root())
+	if err != nil {
+		return fmt.Errorf("state of block #%d unavailable: %v", block.NumberU64(), err)
+	}
+	log.Info("Exporting state", "file", fn, "number", block.NumberU64(), "root", block.Root())
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	var (
+		start    = time.Now()
+		reported = time.Now()
+		exported int
+	)
+	err = statedb.IterativeDump(writer, func(accounts int) {
+		exported = accounts
+		if time.Since(reported) >= importReportInterval {
+			log.Info("Exporting state", "accounts", accounts, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	log.Info("Exported state", "file", fn, "accounts", exported, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// ReindexIndexes are the derived chain indexes ReindexChain can rebuild.
+var ReindexIndexes = []string{"txlookup", "bloombits"}
+
+// ReindexChain rebuilds the given derived indexes of the canonical chain from
+// the stored headers and bodies, without re-executing any state. An interrupted
+// rebuild is resumed where it stopped the next time it is run.
+func ReindexChain(chain *core.BlockChain, db aquadb.Database, indexes []string) error {
+	for _, index := range indexes {
+		switch index {
+		case "txlookup", "bloombits":
+		default:
+			return fmt.Errorf("unsupported index %q (supported: %s)", index, strings.Join(ReindexIndexes, ", "))
+		}
+	}
+	// Watch for Ctrl-C while the reindex is running.
+	// If a signal is received, the reindex will stop at the next batch.
+	interrupt := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	defer close(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info("Interrupted during reindex, stopping at next batch")
+		}
+		close(stop)
+	}()
+	checkInterrupt := func() bool {
+		select {
+		case <-stop:
+			return true
+		default:
+			return false
+		}
+	}
+
+	head := chain.CurrentBlock().NumberU64()
+	for _, index := range indexes {
+		var err error
+		switch index {
+		case "txlookup":
+			err = reindexTxLookups(db, head, checkInterrupt)
+		case "bloombits":
+			err = reindexBloomBits(chain.Config(), db, head, stop)
+		}
+		if err != nil {
+			return fmt.Errorf("%s reindex failed: %v", index, err)
+		}
+		if checkInterrupt() {
+			return fmt.Errorf("interrupted, rerun to resume")
+		}
+		core.DeleteReindexProgress(db, index)
+		log.Info("Reindexed chain", "index", index, "head", head)
+	}
+	return nil
+}
+
+// reindexTxLookups rebuilds the transaction lookup entries in batches, storing
+// the progress after every batch.
+func reindexTxLookups(db aquadb.Database, head uint64, checkInterrupt func() bool) error {
+	from, resumed := core.GetReindexProgress(db, "txlookup")
+	if resumed {
+		log.Info("Resuming interrupted reindex", "index", "txlookup", "from", from)
+	}
+	for from <= head {
+		if checkInterrupt() {
+			return nil
+		}
+		to := from + reindexBatchSize - 1
+		if to > head {
+			to = head
+		}
+		if err := core.ReindexTxLookups(db, from, to, runtime.NumCPU()); err != nil {
+			return err
+		}
+		from = to + 1
+		if err := core.WriteReindexProgress(db, "txlookup", from); err != nil {
+			return err
+		}
+		log.Info("Reindexed transaction lookups", "number", to, "head", head)
+	}
+	return nil
+}
+
+// reindexBloomBits rebuilds the bloom bits sections through the chain indexer,
+// which stores its progress after every section.
+func reindexBloomBits(config *params.ChainConfig, db aquadb.Database, head uint64, stop <-chan struct{}) error {
+	indexer := aqua.NewBloomIndexer(config, db, params.BloomBitsBlocks)
+	defer indexer.Close()
+
+	from := uint64(0)
+	if _, resumed := core.GetReindexProgress(db, "bloombits"); resumed {
+		from, _, _ = indexer.Sections()
+		log.Info("Resuming interrupted reindex", "index", "bloombits", "section", from)
+	} else if err := core.WriteReindexProgress(db, "bloombits", 0); err != nil {
+		return err
+	}
+	return indexer.Reindex(from, head, stop)
+}
+
+// ExportConsensusTests writes consensus test vectors of the canonical blocks
+// from first to last, every step blocks, into a file in the JSON format of the
+// tests package. The vectors record the difficulty and validity the current
+// rules give to each block along with the ancestors they look at, so that a
+// change of the rules can be checked against history.
+func ExportConsensusTests(chain *core.BlockChain, fn string, first, last, step uint64) error {
+	var network string
+	switch chain.Genesis().Hash() {
+	case params.MainnetGenesisHash:
+		network = "mainnet"
+	case params.TestnetGenesisHash:
+		network = "testnet"
+	case core.DefaultRinkebyGenesisBlock().ToBlock(nil).Hash():
+		network = "rinkeby"
+	default:
+		return fmt.Errorf("unsupported network with genesis %x", chain.Genesis().Hash())
+	}
+	if first == 0 {
+		first = 1 // The genesis block has no parent to verify against
+	}
+	if step == 0 {
+		step = 1
+	}
+	log.Info("Exporting consensus tests", "file", fn, "network", network, "first", first, "last", last, "step", step)
+
+	var (
+		engine   = aquahash.New(aquahash.Config{CachesInMem: 2})
+		vectors  = make(map[string]*tests.ConsensusTest)
+		start    = time.Now()
+		reported = time.Now()
+	)
+	for number := first; number <= last; number += step {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return fmt.Errorf("export failed on #%d: not found", number)
+		}
+		vector, err := tests.MakeConsensusTest(engine, network, chain, header)
+		if err != nil {
+			return fmt.Errorf("export failed on #%d: %v", number, err)
+		}
+		vectors[fmt.Sprintf("block%d", number)] = vector
+
+		if time.Since(reported) >= importReportInterval {
+			log.Info("Exporting consensus tests", "number", number, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+	}
+	blob, err := json.MarshalIndent(vectors, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(fn, append(blob, '\n'), 0644); err != nil {
+		return err
+	}
+	log.Info("Exported consensus tests", "file", fn, "vectors", len(vectors), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
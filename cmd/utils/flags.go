@@ -22,21 +22,28 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/big"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/accounts/external"
 	"github.com/aquanetwork/aquachain/accounts/keystore"
+	"github.com/aquanetwork/aquachain/accounts/vault"
 	"github.com/aquanetwork/aquachain/aqua"
 	"github.com/aquanetwork/aquachain/aqua/downloader"
 	"github.com/aquanetwork/aquachain/aqua/gasprice"
 	"github.com/aquanetwork/aquachain/aquadb"
 	"github.com/aquanetwork/aquachain/aquastats"
+	"github.com/aquanetwork/aquachain/aquastream"
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/fdlimit"
+	"github.com/aquanetwork/aquachain/common/hexutil"
 	"github.com/aquanetwork/aquachain/consensus"
 	"github.com/aquanetwork/aquachain/consensus/aquahash"
 	"github.com/aquanetwork/aquachain/consensus/clique"
@@ -45,13 +52,19 @@ import (
 	"github.com/aquanetwork/aquachain/core/vm"
 	"github.com/aquanetwork/aquachain/crypto"
 	"github.com/aquanetwork/aquachain/dashboard"
+	"github.com/aquanetwork/aquachain/graphql"
 	"github.com/aquanetwork/aquachain/les"
 	"github.com/aquanetwork/aquachain/log"
 	"github.com/aquanetwork/aquachain/metrics"
+	"github.com/aquanetwork/aquachain/metrics/exp"
+	"github.com/aquanetwork/aquachain/metrics/influxdb"
+	"github.com/aquanetwork/aquachain/metrics/prometheus"
+	"github.com/aquanetwork/aquachain/miner"
 	"github.com/aquanetwork/aquachain/node"
 	"github.com/aquanetwork/aquachain/p2p"
 	"github.com/aquanetwork/aquachain/p2p/discover"
 	"github.com/aquanetwork/aquachain/p2p/discv5"
+	"github.com/aquanetwork/aquachain/p2p/dnsdisc"
 	"github.com/aquanetwork/aquachain/p2p/nat"
 	"github.com/aquanetwork/aquachain/p2p/netutil"
 	"github.com/aquanetwork/aquachain/params"
@@ -127,10 +140,49 @@ var (
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
 	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Storage engine of new databases (leveldb)",
+	}
 	NoUSBFlag = cli.BoolFlag{
 		Name:  "nousb",
 		Usage: "Disables monitoring for and managing USB hardware wallets",
 	}
+	HDPathFlag = cli.StringFlag{
+		Name:  "hdpath",
+		Usage: "Base derivation path for discovering USB hardware wallet accounts (default = m/44'/61717561'/0'/0/0, also searching the Ethereum path for used accounts)",
+	}
+	NoPersonalFlag = cli.BoolFlag{
+		Name:  "nopersonal",
+		Usage: "Disables the personal API, leaving session tokens as the only way to sign with the node's accounts over RPC",
+	}
+	ExternalSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "External signer (IPC path, HTTP url or grpc://host:port) to delegate all signing to, instead of the keystore",
+	}
+	ExternalSignerAsyncFlag = cli.BoolFlag{
+		Name:  "signer.async",
+		Usage: "Submit signing requests to the external signer and poll for their completion (threshold/MPC signers)",
+	}
+	ExternalSignerTimeoutFlag = cli.DurationFlag{
+		Name:  "signer.timeout",
+		Usage: "Time allowed for the external signer to answer a signing request",
+		Value: external.DefaultRequestTimeout,
+	}
+	KeyStoreBackendFlag = cli.StringFlag{
+		Name:  "keystore.backend",
+		Usage: `Where the account keys are kept ("file" or "vault")`,
+		Value: "file",
+	}
+	VaultURLFlag = cli.StringFlag{
+		Name:  "vault.url",
+		Usage: "Vault KV v2 secrets engine holding the keys with the vault keystore backend (http(s)://host:port/<mount>/<path>, token from VAULT_TOKEN)",
+	}
+	VaultUnlockFlag = cli.DurationFlag{
+		Name:  "vault.unlock",
+		Usage: "Time the vault keystore backend signs for once its wallet is opened",
+		Value: vault.DefaultUnlockTimeout,
+	}
 	NetworkIdFlag = cli.Uint64Flag{
 		Name:  "networkid",
 		Usage: "Network identifier (integer, 1=Frontier, 2=Morden (disused), 3=Ropsten, 4=Rinkeby)",
@@ -172,14 +224,30 @@ var (
 	defaultSyncMode = aqua.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "light" or "headers")`,
 		Value: &defaultSyncMode,
 	}
+	RoleFlag = cli.StringFlag{
+		Name:  "role",
+		Usage: `Preset configuration of a node role ("miner", "rpc", "archive", "light" or "monitor"), explicit flags take precedence`,
+	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	TxLookupLimitFlag = cli.Uint64Flag{
+		Name:  "txlookuplimit",
+		Usage: "Number of recent blocks to maintain transaction lookup entries for (default = all blocks)",
+	}
+	SnapshotFlag = cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Maintain a flat snapshot of the state for faster account and storage reads",
+	}
+	AccountHistoryFlag = cli.BoolFlag{
+		Name:  "accounthistory",
+		Usage: "Index the balance and nonce changes of the accounts, serving their history on pruned nodes",
+	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (0-90)",
@@ -194,6 +262,10 @@ var (
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
 	}
+	KDFFlag = cli.StringFlag{
+		Name:  "kdf",
+		Usage: "Scrypt parameters of new and updated keyfiles (" + strings.Join(keystore.KDFPresets(), ", ") + ")",
+	}
 	// Dashboard settings
 	DashboardEnabledFlag = cli.BoolFlag{
 		Name:  "dashboard",
@@ -249,11 +321,33 @@ var (
 		Usage: "Number of recent aquahash mining DAGs to keep on disk (1+GB each)",
 		Value: aqua.DefaultConfig.Aquahash.DatasetsOnDisk,
 	}
+	AquahashCachesAheadFlag = cli.IntFlag{
+		Name:  "aquahash.cachesahead",
+		Usage: "Number of epochs ahead of the chain whose aquahash caches are generated in the background",
+		Value: 1,
+	}
+	AquahashDatasetsAheadFlag = cli.IntFlag{
+		Name:  "aquahash.dagsahead",
+		Usage: "Number of epochs ahead of the chain whose aquahash mining DAGs are generated in the background",
+		Value: 1,
+	}
+	AquahashCheckpointsFlag = cli.StringFlag{
+		Name:  "aquahash.checkpoints",
+		Usage: "Comma separated trusted checkpoints (number:hash) to anchor header verification",
+	}
 	// Transaction pool settings
 	TxPoolNoLocalsFlag = cli.BoolFlag{
 		Name:  "txpool.nolocals",
 		Usage: "Disables price exemptions for locally submitted transactions",
 	}
+	TxPoolLocalsFlag = cli.StringFlag{
+		Name:  "txpool.locals",
+		Usage: "Comma separated accounts to treat as locals (no flush, priority inclusion), in addition to the keystore accounts",
+	}
+	TxPoolDefenseFlag = cli.BoolFlag{
+		Name:  "txpool.defense",
+		Usage: "Throttle, escalate the minimum price for and ban peers flooding the pool with malformed transactions while under pressure",
+	}
 	TxPoolJournalFlag = cli.StringFlag{
 		Name:  "txpool.journal",
 		Usage: "Disk journal for local transaction to survive node restarts",
@@ -264,6 +358,10 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolNoSnapshotFlag = cli.BoolFlag{
+		Name:  "txpool.nosnapshot",
+		Usage: "Disables saving the transaction pool on shutdown and restoring it on start",
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -310,6 +408,14 @@ var (
 		Usage: "Percentage of cache memory allowance to use for database io",
 		Value: 75,
 	}
+	DBCompressFlag = cli.BoolFlag{
+		Name:  "db.compress",
+		Usage: "Compress the block bodies and receipts written to the database with snappy",
+	}
+	CacheNoPrefetchFlag = cli.BoolFlag{
+		Name:  "cache.noprefetch",
+		Usage: "Disable pre-caching the state of the next block while importing a chain segment",
+	}
 	CacheGCFlag = cli.IntFlag{
 		Name:  "cache.gc",
 		Usage: "Percentage of cache memory allowance to use for trie pruning",
@@ -330,6 +436,11 @@ var (
 		Usage: "Number of CPU threads to use for mining",
 		Value: runtime.NumCPU(),
 	}
+	MinerThrottleFlag = cli.Float64Flag{
+		Name:  "miner.throttle",
+		Usage: "Fraction of the time the mining threads hash, resting for the remainder and yielding to block verification (low-power mode)",
+		Value: 1,
+	}
 	TargetGasLimitFlag = cli.Uint64Flag{
 		Name:  "targetgaslimit",
 		Usage: "Target gas limit sets the artificial target gas floor for the blocks to mine",
@@ -340,6 +451,14 @@ var (
 		Usage: "Public address for block mining rewards (default = first account created)",
 		Value: "0",
 	}
+	AquabasesFlag = cli.StringFlag{
+		Name:  "aquabases",
+		Usage: "Comma separated addresses the block rewards rotate through by block number, overriding --aquabase",
+	}
+	ForceAquabaseFlag = cli.BoolFlag{
+		Name:  "miner.force-aquabase",
+		Usage: "Allow mining to the zero address or a known burn address",
+	}
 	GasPriceFlag = BigFlag{
 		Name:  "gasprice",
 		Usage: "Minimal gas price to accept for mining a transactions",
@@ -349,6 +468,41 @@ var (
 		Name:  "extradata",
 		Usage: "Block extra data set by the miner (default = client version)",
 	}
+	MinerPeersFlag = cli.BoolFlag{
+		Name:  "minerpeers",
+		Usage: "Announce this miner (signed by the unlocked aquabase) and keep direct connections to other recently active miners",
+	}
+	ForkGuardFlag = cli.BoolFlag{
+		Name:  "forkguard",
+		Usage: "Pause mining while the local chain lags behind a quorum of peers (suspected minority fork)",
+	}
+	MinerStaleGraceFlag = cli.BoolFlag{
+		Name:  "miner.stalegrace",
+		Usage: "Accept remote solutions for the work handed out right before the chain head changed, instead of rejecting them as stale",
+	}
+	MinerUnclesFlag = cli.StringFlag{
+		Name:  "miner.uncles",
+		Usage: `Uncle inclusion mode ("always", "never" or "profitable", weighing the uncle reward against its verification delay)`,
+		Value: miner.DefaultUnclePolicy.Mode,
+	}
+	MinerUncleVerifyCostFlag = cli.DurationFlag{
+		Name:  "miner.uncleverifycost",
+		Usage: "Estimated time peers spend verifying an uncle, for the profitable uncle inclusion mode",
+		Value: miner.DefaultUnclePolicy.VerifyCost,
+	}
+	AnnounceDelayFlag = cli.DurationFlag{
+		Name:  "research.announcedelay",
+		Usage: "Delay the broadcast of mined blocks, for propagation research (refused on mainnet)",
+	}
+	WithholdBlocksFlag = cli.BoolFlag{
+		Name:  "research.withholdblocks",
+		Usage: "Withhold mined blocks until the network catches up, for selfish mining research (refused on mainnet)",
+	}
+	TieBreakFlag = cli.StringFlag{
+		Name:  "tiebreak",
+		Usage: `Rule choosing between competing blocks of equal total difficulty ("random", "first-seen", "lowest-hash" or "prefer-local")`,
+		Value: string(aqua.DefaultConfig.TieBreak),
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -365,15 +519,98 @@ var (
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	ForensicsDirFlag = cli.StringFlag{
+		Name:  "forensics.dir",
+		Usage: "Directory of the forensic bundles dumped on state root mismatches, within the data directory (empty disables)",
+		Value: aqua.DefaultConfig.ForensicsDir,
+	}
+	VMRPCCallDepthFlag = cli.IntFlag{
+		Name:  "vm.rpcdepth",
+		Usage: "Maximum call depth of RPC calls and traces (0 = protocol limit)",
+		Value: aqua.DefaultConfig.RPCCallDepth,
+	}
+	VMRPCMemoryFlag = cli.Uint64Flag{
+		Name:  "vm.rpcmemory",
+		Usage: "Maximum memory in bytes a call frame may use in RPC calls and traces (0 = unlimited)",
+		Value: aqua.DefaultConfig.RPCMemoryLimit,
+	}
+	VMTraceLimitFlag = cli.IntFlag{
+		Name:  "vm.tracelimit",
+		Usage: "Maximum number of logs returned by a single trace (0 = unlimited)",
+		Value: aqua.DefaultConfig.RPCTraceLimit,
+	}
+	RPCReexecFlag = cli.Uint64Flag{
+		Name:  "rpc.reexec",
+		Usage: "Maximum number of blocks re-executed to regenerate pruned historical state for RPC calls (0 = disabled)",
+		Value: aqua.DefaultConfig.RPCReexec,
+	}
+	RPCGasCapFlag = cli.Uint64Flag{
+		Name:  "rpc.gascap",
+		Usage: "Maximum gas of aqua_estimateGas (0 = bounded by the block gas limit only)",
+		Value: aqua.DefaultConfig.RPCGasCap,
+	}
+	RPCCallGasCapFlag = cli.Uint64Flag{
+		Name:  "rpc.callgascap",
+		Usage: "Maximum gas of aqua_call, metering the calls (0 = unmetered calls, bounded by a timeout)",
+		Value: aqua.DefaultConfig.RPCCallGasCap,
+	}
+	RPCAllowUnprotectedTxsFlag = cli.BoolFlag{
+		Name:  "rpc.allow-unprotected-txs",
+		Usage: "Allow transactions without EIP-155 replay protection to be submitted over RPC once the chain enforces it",
+	}
+	RPCTxWebhookFlag = cli.StringFlag{
+		Name:  "rpc.txwebhook",
+		Usage: "HTTP(S) URL notified of the status changes (mined, confirmed, dropped, replaced) of transactions submitted with watch set",
+	}
 	// Logging and debug settings
-	AquaStatsURLFlag = cli.StringFlag{
-		Name:  "aquastats",
-		Usage: "Reporting URL of a aquastats service (nodename:secret@host:port)",
+	StatsURLFlag = cli.StringFlag{
+		Name:  "stats",
+		Usage: "Reporting URL of an aquastats service, pushing node, block, txpool and peer stats (nodename:secret@host[:port])",
+	}
+	ContractRegistryFlag = cli.StringFlag{
+		Name:  "contractregistry",
+		Usage: "URL of a community registry to import verified contract metadata from",
 	}
 	MetricsEnabledFlag = cli.BoolFlag{
 		Name:  metrics.MetricsEnabledFlag,
 		Usage: "Enable metrics collection and reporting",
 	}
+	MetricsHTTPFlag = cli.StringFlag{
+		Name:  "metrics.addr",
+		Usage: "Metrics HTTP server listening interface, serving /metrics in the Prometheus format (disabled if empty)",
+	}
+	MetricsPortFlag = cli.IntFlag{
+		Name:  "metrics.port",
+		Usage: "Metrics HTTP server listening port",
+		Value: 6061,
+	}
+	MetricsEnableInfluxDBFlag = cli.BoolFlag{
+		Name:  "metrics.influxdb",
+		Usage: "Enable pushing metrics to an InfluxDB database",
+	}
+	MetricsInfluxDBEndpointFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.endpoint",
+		Usage: "InfluxDB API endpoint to push metrics to",
+		Value: "http://localhost:8086",
+	}
+	MetricsInfluxDBDatabaseFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.database",
+		Usage: "InfluxDB database name to push metrics to",
+		Value: "aquachain",
+	}
+	MetricsInfluxDBUsernameFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.username",
+		Usage: "Username to authorize access to the InfluxDB database",
+	}
+	MetricsInfluxDBPasswordFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.password",
+		Usage: "Password to authorize access to the InfluxDB database",
+	}
+	MetricsInfluxDBHostTagFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.host.tag",
+		Usage: "InfluxDB host tag attached to all pushed measurements",
+		Value: "localhost",
+	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -409,13 +646,55 @@ var (
 	}
 	RPCApiFlag = cli.StringFlag{
 		Name:  "rpcapi",
-		Usage: "API's offered over the HTTP-RPC interface",
+		Usage: `API's offered over the HTTP-RPC interface, as namespaces or methods, "-" disabling them (e.g. "aqua,net,web3,debug_traceTransaction,-aqua_sign")`,
 		Value: "",
 	}
+	RPCAdminSecretFlag = cli.StringFlag{
+		Name:  "rpc.adminsecret",
+		Usage: "File holding the bearer token, or the key of HS256 JWTs, that authorizes admin API requests over HTTP-RPC and WS-RPC",
+	}
+	RPCAuthApiFlag = cli.StringFlag{
+		Name:  "rpc.authapi",
+		Usage: `API's offered over HTTP-RPC and WS-RPC to requests authorized by --rpc.adminsecret, as namespaces or methods, "-" disabling them (default = the endpoint's and admin)`,
+	}
+	RPCDenyFlag = cli.StringFlag{
+		Name:  "rpc.deny",
+		Usage: `API's never offered over HTTP-RPC and WS-RPC, even to authorized requests, as namespaces or methods (e.g. "personal,debug_setHead")`,
+	}
+	RPCAllowUnsafeAPIsFlag = cli.BoolFlag{
+		Name:  "rpc.allow-unsafe-apis",
+		Usage: "Allow serving the admin, debug and personal API's without authorization over HTTP-RPC and WS-RPC reachable from other hosts or any origin (unsafe)",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpc.ratelimit",
+		Usage: "Requests per second each remote IP may make over HTTP-RPC and WS-RPC (0 = unlimited)",
+	}
+	RPCRateBurstFlag = cli.IntFlag{
+		Name:  "rpc.rateburst",
+		Usage: "Requests each remote IP may make at once over HTTP-RPC and WS-RPC (default = a second worth)",
+	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Maximum number of requests in a batch over HTTP-RPC and WS-RPC (0 = unlimited)",
+		Value: node.DefaultConfig.RPCBatchLimit,
+	}
+	RPCResponseLimitFlag = cli.IntFlag{
+		Name:  "rpc.responselimit",
+		Usage: "Maximum size in bytes of the result of a call over HTTP-RPC and WS-RPC (0 = unlimited)",
+		Value: node.DefaultConfig.RPCResponseLimit,
+	}
+	RPCStrictJSONFlag = cli.BoolFlag{
+		Name:  "rpc.strictjson",
+		Usage: "Encode RPC results as canonical JSON (sorted keys, hex quantities) on all endpoints",
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
 	}
+	IPCApiFlag = cli.StringFlag{
+		Name:  "ipcapi",
+		Usage: "API's offered over the IPC-RPC interface, as namespaces or methods, \"-\" disabling them (default = all)",
+	}
 	IPCPathFlag = DirectoryFlag{
 		Name:  "ipcpath",
 		Usage: "Filename for IPC socket/pipe within the datadir (explicit paths escape it)",
@@ -436,7 +715,7 @@ var (
 	}
 	WSApiFlag = cli.StringFlag{
 		Name:  "wsapi",
-		Usage: "API's offered over the WS-RPC interface",
+		Usage: "API's offered over the WS-RPC interface, as namespaces or methods, \"-\" disabling them",
 		Value: "",
 	}
 	WSAllowedOriginsFlag = cli.StringFlag{
@@ -444,6 +723,50 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	WSVirtualHostsFlag = cli.StringFlag{
+		Name:  "wsvhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept websockets requests (server enforced). Accepts '*' wildcard. (default = any)",
+	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL server",
+	}
+	GraphQLListenAddrFlag = cli.StringFlag{
+		Name:  "graphql.addr",
+		Usage: "GraphQL server listening interface",
+		Value: node.DefaultGraphQLHost,
+	}
+	GraphQLPortFlag = cli.IntFlag{
+		Name:  "graphql.port",
+		Usage: "GraphQL server listening port",
+		Value: node.DefaultGraphQLPort,
+	}
+	GraphQLCORSDomainFlag = cli.StringFlag{
+		Name:  "graphql.corsdomain",
+		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
+		Value: "",
+	}
+	GraphQLVirtualHostsFlag = cli.StringFlag{
+		Name:  "graphql.vhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: "localhost",
+	}
+	StreamSinkFlag = cli.StringFlag{
+		Name:  "stream.sink",
+		Usage: "Stream new blocks, reorgs and logs to a webhook (http(s)://...) or a Kafka REST proxy (kafka://host:port/topic)",
+	}
+	StreamAddressesFlag = cli.StringFlag{
+		Name:  "stream.addresses",
+		Usage: "Comma separated list of contract addresses whose logs are streamed (default = all)",
+	}
+	StreamTopicsFlag = cli.StringFlag{
+		Name:  "stream.topics",
+		Usage: "Comma separated topic positions of the streamed logs, each a '|' separated list of alternatives, empty for any (default = all)",
+	}
+	StreamFromFlag = cli.Uint64Flag{
+		Name:  "stream.from",
+		Usage: "First block streamed to a new sink (default = head of the chain)",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -484,6 +807,11 @@ var (
 		Usage: "Comma separated enode URLs for P2P v5 discovery bootstrap (light server, light nodes)",
 		Value: "",
 	}
+	DNSDiscoveryFlag = cli.StringFlag{
+		Name:  "discovery.dns",
+		Usage: "Comma separated enrtree:// URLs of DNS node lists used as discovery seeds",
+		Value: "",
+	}
 	NodeKeyFileFlag = cli.StringFlag{
 		Name:  "nodekey",
 		Usage: "P2P node key file",
@@ -494,13 +822,17 @@ var (
 	}
 	NATFlag = cli.StringFlag{
 		Name:  "nat",
-		Usage: "NAT port mapping mechanism (any|none|upnp|pmp|extip:<IP>)",
+		Usage: "NAT port mapping mechanism (any|auto|none|upnp|pmp|pmp:<gateway IP>|extip:<IP>)",
 		Value: "any",
 	}
 	NoDiscoverFlag = cli.BoolFlag{
 		Name:  "nodiscover",
 		Usage: "Disables the peer discovery mechanism (manual peer addition)",
 	}
+	NoClockCheckFlag = cli.BoolFlag{
+		Name:  "noclockcheck",
+		Usage: "Disables the hourly check of the system clock against NTP",
+	}
 	DiscoveryV5Flag = cli.BoolFlag{
 		Name:  "v5disc",
 		Usage: "Enables the experimental RLPx V5 (Topic Discovery) mechanism",
@@ -519,15 +851,29 @@ var (
 
 	// Gas price oracle settings
 	GpoBlocksFlag = cli.IntFlag{
-		Name:  "gpoblocks",
+		Name:  "gpo.blocks",
 		Usage: "Number of recent blocks to check for gas prices",
 		Value: aqua.DefaultConfig.GPO.Blocks,
 	}
 	GpoPercentileFlag = cli.IntFlag{
-		Name:  "gpopercentile",
+		Name:  "gpo.percentile",
 		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices",
 		Value: aqua.DefaultConfig.GPO.Percentile,
 	}
+	LegacyAquaStatsURLFlag = cli.StringFlag{
+		Name:  "aquastats",
+		Usage: "Reporting URL of an aquastats service (deprecated, use --stats)",
+	}
+	LegacyGpoBlocksFlag = cli.IntFlag{
+		Name:  "gpoblocks",
+		Usage: "Number of recent blocks to check for gas prices (deprecated, use --gpo.blocks)",
+		Value: aqua.DefaultConfig.GPO.Blocks,
+	}
+	LegacyGpoPercentileFlag = cli.IntFlag{
+		Name:  "gpopercentile",
+		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices (deprecated, use --gpo.percentile)",
+		Value: aqua.DefaultConfig.GPO.Percentile,
+	}
 	WhisperEnabledFlag = cli.BoolFlag{
 		Name:  "shh",
 		Usage: "Enable Whisper",
@@ -652,6 +998,23 @@ func setBootstrapNodesV5(ctx *cli.Context, cfg *p2p.Config) {
 	}
 }
 
+// setDNSDiscovery sets the DNS node lists to seed discovery with.
+func setDNSDiscovery(ctx *cli.Context, cfg *p2p.Config) {
+	if !ctx.GlobalIsSet(DNSDiscoveryFlag.Name) {
+		return
+	}
+	cfg.DNSDiscovery = nil
+	for _, url := range strings.Split(ctx.GlobalString(DNSDiscoveryFlag.Name), ",") {
+		if url = strings.TrimSpace(url); url == "" {
+			continue
+		}
+		if _, _, err := dnsdisc.ParseURL(url); err != nil {
+			Fatalf("Option %q: invalid URL %q: %v", DNSDiscoveryFlag.Name, url, err)
+		}
+		cfg.DNSDiscovery = append(cfg.DNSDiscovery, url)
+	}
+}
+
 // setListenAddress creates a TCP listening address string from set command
 // line flags.
 func setListenAddress(ctx *cli.Context, cfg *p2p.Config) {
@@ -702,6 +1065,10 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	}
 
 	cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
+
+	if ctx.GlobalIsSet(RPCStrictJSONFlag.Name) {
+		cfg.RPCStrictJSON = ctx.GlobalBool(RPCStrictJSONFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -723,6 +1090,54 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = splitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(WSVirtualHostsFlag.Name) {
+		cfg.WSVirtualHosts = splitAndTrim(ctx.GlobalString(WSVirtualHostsFlag.Name))
+	}
+}
+
+// setRPCAuth applies the access control flags of the HTTP and websocket
+// endpoints, loading the token authorizing admin API requests from the file
+// given on the command line.
+func setRPCAuth(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCDenyFlag.Name) {
+		cfg.RPCDeny = splitAndTrim(ctx.GlobalString(RPCDenyFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCAuthApiFlag.Name) {
+		cfg.RPCAuthModules = splitAndTrim(ctx.GlobalString(RPCAuthApiFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCAllowUnsafeAPIsFlag.Name) {
+		cfg.RPCAllowUnsafeAPIs = ctx.GlobalBool(RPCAllowUnsafeAPIsFlag.Name)
+	}
+	path := ctx.GlobalString(RPCAdminSecretFlag.Name)
+	if path == "" {
+		return
+	}
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		Fatalf("Failed to read admin secret file: %v", err)
+	}
+	token := strings.TrimSpace(string(text))
+	if len(token) < 16 {
+		Fatalf("Admin secret in %s is too short, use at least 16 characters", path)
+	}
+	cfg.RPCAdminToken = token
+}
+
+// setRPCLimits applies the limits on the requests of remote clients to the HTTP
+// and websocket endpoints.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCRateLimit = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateBurstFlag.Name) {
+		cfg.RPCRateBurst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		cfg.RPCBatchLimit = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseLimitFlag.Name) {
+		cfg.RPCResponseLimit = ctx.GlobalInt(RPCResponseLimitFlag.Name)
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -735,6 +1150,9 @@ func setIPC(ctx *cli.Context, cfg *node.Config) {
 	case ctx.GlobalIsSet(IPCPathFlag.Name):
 		cfg.IPCPath = ctx.GlobalString(IPCPathFlag.Name)
 	}
+	if ctx.GlobalIsSet(IPCApiFlag.Name) {
+		cfg.IPCModules = splitAndTrim(ctx.GlobalString(IPCApiFlag.Name))
+	}
 }
 
 // makeDatabaseHandles raises out the number of allowed file handles per process
@@ -773,6 +1191,9 @@ func MakeAddress(ks *keystore.KeyStore, account string) (accounts.Account, error
 	log.Warn("Please use explicit addresses! (can search via `aquachain account list`)")
 	log.Warn("-------------------------------------------------------------------")
 
+	if ks == nil {
+		return accounts.Account{}, fmt.Errorf("account index %d requires a keystore, use an explicit address", index)
+	}
 	accs := ks.Accounts()
 	if len(accs) <= index {
 		return accounts.Account{}, fmt.Errorf("index %d higher than number of accounts %d", index, len(accs))
@@ -783,13 +1204,43 @@ func MakeAddress(ks *keystore.KeyStore, account string) (accounts.Account, error
 // setAquabase retrieves the aquabase either from the directly specified
 // command line flags or from the keystore if CLI indexed.
 func setAquabase(ctx *cli.Context, ks *keystore.KeyStore, cfg *aqua.Config) {
+	cfg.ForceAquabase = ctx.GlobalBool(ForceAquabaseFlag.Name)
 	if ctx.GlobalIsSet(AquabaseFlag.Name) {
-		account, err := MakeAddress(ks, ctx.GlobalString(AquabaseFlag.Name))
+		value := ctx.GlobalString(AquabaseFlag.Name)
+		if common.IsHexAddress(value) {
+			if _, err := aqua.ParseAquabase(value); err != nil {
+				Fatalf("Option %q: %v", AquabaseFlag.Name, err)
+			}
+		}
+		account, err := MakeAddress(ks, value)
 		if err != nil {
 			Fatalf("Option %q: %v", AquabaseFlag.Name, err)
 		}
+		if err := aqua.CheckAquabase(account.Address, cfg.ForceAquabase); err != nil {
+			Fatalf("Option %q: %v, use --%s to override", AquabaseFlag.Name, err, ForceAquabaseFlag.Name)
+		}
+		switch {
+		case account.Address == (common.Address{}):
+			log.Warn("Zero aquabase configured, the first local account will receive the rewards")
+		case aqua.IsBurnAddress(account.Address):
+			log.Warn("Mining to a known burn address, rewards will be lost", "aquabase", account.Address)
+		}
 		cfg.Aquabase = account.Address
 	}
+	if ctx.GlobalIsSet(AquabasesFlag.Name) {
+		var aquabases []common.Address
+		for _, value := range splitAndTrim(ctx.GlobalString(AquabasesFlag.Name)) {
+			addr, err := aqua.ParseAquabase(value)
+			if err != nil {
+				Fatalf("Option %q: %v", AquabasesFlag.Name, err)
+			}
+			if err := aqua.CheckAquabase(addr, cfg.ForceAquabase); err != nil {
+				Fatalf("Option %q: %s: %v, use --%s to override", AquabasesFlag.Name, value, err, ForceAquabaseFlag.Name)
+			}
+			aquabases = append(aquabases, addr)
+		}
+		cfg.Aquabases = aquabases
+	}
 }
 
 // MakePasswordList reads password lines from the file specified by the global --password flag.
@@ -816,6 +1267,7 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	setListenAddress(ctx, cfg)
 	setBootstrapNodes(ctx, cfg)
 	setBootstrapNodesV5(ctx, cfg)
+	setDNSDiscovery(ctx, cfg)
 
 	lightClient := ctx.GlobalBool(LightModeFlag.Name) || ctx.GlobalString(SyncModeFlag.Name) == "light"
 	lightServer := ctx.GlobalInt(LightServFlag.Name) != 0
@@ -846,6 +1298,9 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) || lightClient {
 		cfg.NoDiscovery = true
 	}
+	if ctx.GlobalIsSet(NoClockCheckFlag.Name) {
+		cfg.NoClockCheck = ctx.GlobalBool(NoClockCheckFlag.Name)
+	}
 
 	// if we're running a light client or server, force enable the v5 peer discovery
 	// unless it is explicitly disabled with --nodiscover note that explicitly specifying
@@ -880,6 +1335,8 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCAuth(ctx, cfg)
+	setRPCLimits(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	switch {
@@ -899,12 +1356,42 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(LightKDFFlag.Name) {
 		cfg.UseLightweightKDF = ctx.GlobalBool(LightKDFFlag.Name)
 	}
+	if ctx.GlobalIsSet(KDFFlag.Name) {
+		cfg.KDF = ctx.GlobalString(KDFFlag.Name)
+	}
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		cfg.DBEngine = ctx.GlobalString(DBEngineFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
+	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
+	}
+	if ctx.GlobalIsSet(ExternalSignerAsyncFlag.Name) {
+		cfg.ExternalSignerAsync = ctx.GlobalBool(ExternalSignerAsyncFlag.Name)
+	}
+	if ctx.GlobalIsSet(ExternalSignerTimeoutFlag.Name) {
+		cfg.ExternalSignerTimeout = ctx.GlobalDuration(ExternalSignerTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(KeyStoreBackendFlag.Name) {
+		cfg.KeyStoreBackend = ctx.GlobalString(KeyStoreBackendFlag.Name)
+	}
+	if ctx.GlobalIsSet(VaultURLFlag.Name) {
+		cfg.VaultURL = ctx.GlobalString(VaultURLFlag.Name)
+	}
+	if ctx.GlobalIsSet(VaultUnlockFlag.Name) {
+		cfg.VaultUnlock = ctx.GlobalDuration(VaultUnlockFlag.Name)
+	}
 }
 
 func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
+	if ctx.GlobalIsSet(LegacyGpoBlocksFlag.Name) {
+		cfg.Blocks = ctx.GlobalInt(LegacyGpoBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(LegacyGpoPercentileFlag.Name) {
+		cfg.Percentile = ctx.GlobalInt(LegacyGpoPercentileFlag.Name)
+	}
 	if ctx.GlobalIsSet(GpoBlocksFlag.Name) {
 		cfg.Blocks = ctx.GlobalInt(GpoBlocksFlag.Name)
 	}
@@ -914,15 +1401,30 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
+	if ctx.GlobalIsSet(TxPoolLocalsFlag.Name) {
+		locals := splitAndTrim(ctx.GlobalString(TxPoolLocalsFlag.Name))
+		for _, account := range locals {
+			if !common.IsHexAddress(account) {
+				Fatalf("Invalid account in --%s: %s", TxPoolLocalsFlag.Name, account)
+			}
+			cfg.Locals = append(cfg.Locals, common.HexToAddress(account))
+		}
+	}
 	if ctx.GlobalIsSet(TxPoolNoLocalsFlag.Name) {
 		cfg.NoLocals = ctx.GlobalBool(TxPoolNoLocalsFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolDefenseFlag.Name) {
+		cfg.Defense = ctx.GlobalBool(TxPoolDefenseFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolJournalFlag.Name) {
 		cfg.Journal = ctx.GlobalString(TxPoolJournalFlag.Name)
 	}
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalBool(TxPoolNoSnapshotFlag.Name) {
+		cfg.Snapshot = ""
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -965,6 +1467,23 @@ func setAquahash(ctx *cli.Context, cfg *aqua.Config) {
 	if ctx.GlobalIsSet(AquahashDatasetsOnDiskFlag.Name) {
 		cfg.Aquahash.DatasetsOnDisk = ctx.GlobalInt(AquahashDatasetsOnDiskFlag.Name)
 	}
+	if ctx.GlobalIsSet(AquahashCachesAheadFlag.Name) {
+		cfg.Aquahash.CachesAhead = ctx.GlobalInt(AquahashCachesAheadFlag.Name)
+	}
+	if ctx.GlobalIsSet(AquahashDatasetsAheadFlag.Name) {
+		cfg.Aquahash.DatasetsAhead = ctx.GlobalInt(AquahashDatasetsAheadFlag.Name)
+	}
+	if ctx.GlobalIsSet(AquahashCheckpointsFlag.Name) {
+		var checkpoints params.Checkpoints
+		for _, entry := range splitAndTrim(ctx.GlobalString(AquahashCheckpointsFlag.Name)) {
+			cp, err := params.ParseCheckpoint(entry)
+			if err != nil {
+				Fatalf("Option %q: %v", AquahashCheckpointsFlag.Name, err)
+			}
+			checkpoints = append(checkpoints, cp)
+		}
+		cfg.Aquahash.Checkpoints = cfg.Aquahash.Checkpoints.Merge(checkpoints)
+	}
 }
 
 // checkExclusive verifies that only a single isntance of the provided flags was
@@ -1023,7 +1542,10 @@ func SetAquaConfig(ctx *cli.Context, stack *node.Node, cfg *aqua.Config) {
 	checkExclusive(ctx, LightServFlag, LightModeFlag)
 	checkExclusive(ctx, LightServFlag, SyncModeFlag, "light")
 
-	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	var ks *keystore.KeyStore
+	if keystores := stack.AccountManager().Backends(keystore.KeyStoreType); len(keystores) > 0 {
+		ks = keystores[0].(*keystore.KeyStore)
+	}
 	setAquabase(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
@@ -1040,6 +1562,10 @@ func SetAquaConfig(ctx *cli.Context, stack *node.Node, cfg *aqua.Config) {
 	if ctx.GlobalIsSet(LightServFlag.Name) {
 		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
 	}
+	if cfg.SyncMode == downloader.HeaderSync && cfg.LightServ > 0 {
+		log.Warn("Light clients can't be served in header sync mode")
+		cfg.LightServ = 0
+	}
 	if ctx.GlobalIsSet(LightPeersFlag.Name) {
 		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
 	}
@@ -1051,31 +1577,126 @@ func SetAquaConfig(ctx *cli.Context, stack *node.Node, cfg *aqua.Config) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
+	if ctx.GlobalIsSet(DBCompressFlag.Name) {
+		cfg.StorageCompression = ctx.GlobalBool(DBCompressFlag.Name)
+	}
 
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
-	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+	if ctx.GlobalIsSet(GCModeFlag.Name) {
+		cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+	}
+	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
+		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(SnapshotFlag.Name) {
+		cfg.Snapshot = ctx.GlobalBool(SnapshotFlag.Name)
+	}
+	if ctx.GlobalIsSet(AccountHistoryFlag.Name) {
+		cfg.AccountHistory = ctx.GlobalBool(AccountHistoryFlag.Name)
+	}
+	if ctx.GlobalIsSet(CacheNoPrefetchFlag.Name) {
+		cfg.NoPrefetch = ctx.GlobalBool(CacheNoPrefetchFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalIsSet(MiningEnabledFlag.Name) {
+		cfg.Mining = ctx.GlobalBool(MiningEnabledFlag.Name)
+	}
 	if ctx.GlobalIsSet(MinerThreadsFlag.Name) {
 		cfg.MinerThreads = ctx.GlobalInt(MinerThreadsFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerThrottleFlag.Name) {
+		throttle := ctx.GlobalFloat64(MinerThrottleFlag.Name)
+		if throttle <= 0 || throttle > 1 {
+			Fatalf("Option %q: must be in (0, 1], have %v", MinerThrottleFlag.Name, throttle)
+		}
+		cfg.Aquahash.Throttle = throttle
+	}
 	if ctx.GlobalIsSet(DocRootFlag.Name) {
 		cfg.DocRoot = ctx.GlobalString(DocRootFlag.Name)
 	}
 	if ctx.GlobalIsSet(ExtraDataFlag.Name) {
 		cfg.ExtraData = []byte(ctx.GlobalString(ExtraDataFlag.Name))
 	}
+	if ctx.GlobalIsSet(MinerPeersFlag.Name) {
+		cfg.MinerPeers = ctx.GlobalBool(MinerPeersFlag.Name)
+	}
+	if ctx.GlobalIsSet(ForkGuardFlag.Name) {
+		cfg.ForkGuard = ctx.GlobalBool(ForkGuardFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerStaleGraceFlag.Name) {
+		cfg.StaleGrace = ctx.GlobalBool(MinerStaleGraceFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerUnclesFlag.Name) {
+		cfg.Uncles.Mode = ctx.GlobalString(MinerUnclesFlag.Name)
+		if err := cfg.Uncles.Validate(); err != nil {
+			Fatalf("Option %q: %v", MinerUnclesFlag.Name, err)
+		}
+	}
+	if ctx.GlobalIsSet(MinerUncleVerifyCostFlag.Name) {
+		cfg.Uncles.VerifyCost = ctx.GlobalDuration(MinerUncleVerifyCostFlag.Name)
+	}
+	if ctx.GlobalIsSet(AnnounceDelayFlag.Name) {
+		cfg.AnnounceDelay = ctx.GlobalDuration(AnnounceDelayFlag.Name)
+	}
+	if ctx.GlobalIsSet(WithholdBlocksFlag.Name) {
+		cfg.WithholdBlocks = ctx.GlobalBool(WithholdBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(TieBreakFlag.Name) {
+		rule, err := core.ParseTieBreak(ctx.GlobalString(TieBreakFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", TieBreakFlag.Name, err)
+		}
+		cfg.TieBreak = rule
+	}
 	if ctx.GlobalIsSet(GasPriceFlag.Name) {
 		cfg.GasPrice = GlobalBig(ctx, GasPriceFlag.Name)
 	}
+	if ctx.GlobalIsSet(NoPersonalFlag.Name) {
+		cfg.NoPersonal = ctx.GlobalBool(NoPersonalFlag.Name)
+	}
+	if ctx.GlobalIsSet(ContractRegistryFlag.Name) {
+		cfg.ContractRegistry = ctx.GlobalString(ContractRegistryFlag.Name)
+	}
 	if ctx.GlobalIsSet(VMEnableDebugFlag.Name) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(ForensicsDirFlag.Name) {
+		cfg.ForensicsDir = ctx.GlobalString(ForensicsDirFlag.Name)
+	}
+	if ctx.GlobalIsSet(VMRPCCallDepthFlag.Name) {
+		cfg.RPCCallDepth = ctx.GlobalInt(VMRPCCallDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(VMRPCMemoryFlag.Name) {
+		cfg.RPCMemoryLimit = ctx.GlobalUint64(VMRPCMemoryFlag.Name)
+	}
+	if ctx.GlobalIsSet(VMTraceLimitFlag.Name) {
+		cfg.RPCTraceLimit = ctx.GlobalInt(VMTraceLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCGasCapFlag.Name) {
+		cfg.RPCGasCap = ctx.GlobalUint64(RPCGasCapFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCCallGasCapFlag.Name) {
+		cfg.RPCCallGasCap = ctx.GlobalUint64(RPCCallGasCapFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCReexecFlag.Name) {
+		cfg.RPCReexec = ctx.GlobalUint64(RPCReexecFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAllowUnprotectedTxsFlag.Name) {
+		cfg.RPCAllowUnprotectedTxs = ctx.GlobalBool(RPCAllowUnprotectedTxsFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTxWebhookFlag.Name) {
+		hook := ctx.GlobalString(RPCTxWebhookFlag.Name)
+		if u, err := url.Parse(hook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			Fatalf("Invalid transaction webhook %q, want an http(s) URL", hook)
+		}
+		cfg.RPCTxWebhook = hook
+	}
 
 	// Override any default configs for hard coded networks.
 	switch {
@@ -1182,12 +1803,121 @@ func RegisterAquaStatsService(stack *node.Node, url string) {
 	}
 }
 
+// RegisterGraphQLService adds a GraphQL server serving the chain data of the
+// full or light node, if GraphQL was enabled on the command line.
+func RegisterGraphQLService(ctx *cli.Context, stack *node.Node) {
+	if !ctx.GlobalBool(GraphQLEnabledFlag.Name) {
+		return
+	}
+	endpoint := fmt.Sprintf("%s:%d", ctx.GlobalString(GraphQLListenAddrFlag.Name), ctx.GlobalInt(GraphQLPortFlag.Name))
+	cors := splitAndTrim(ctx.GlobalString(GraphQLCORSDomainFlag.Name))
+	vhosts := splitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
+
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		// Serve from whichever of the aqua and les services is running
+		var aquaServ *aqua.AquaChain
+		if err := ctx.Service(&aquaServ); err == nil {
+			return graphql.New(aquaServ.ApiBackend, endpoint, cors, vhosts)
+		}
+		var lesServ *les.LightAquaChain
+		if err := ctx.Service(&lesServ); err != nil {
+			return nil, err
+		}
+		return graphql.New(lesServ.ApiBackend, endpoint, cors, vhosts)
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
+// RegisterStreamService adds the chain event streaming service to a full node,
+// if a sink was given on the command line.
+func RegisterStreamService(ctx *cli.Context, stack *node.Node) {
+	if !ctx.GlobalIsSet(StreamSinkFlag.Name) {
+		return
+	}
+	config := aquastream.Config{
+		Sink: ctx.GlobalString(StreamSinkFlag.Name),
+		From: ctx.GlobalUint64(StreamFromFlag.Name),
+	}
+	if _, err := aquastream.NewSink(config.Sink); err != nil {
+		Fatalf("Invalid --%s: %v", StreamSinkFlag.Name, err)
+	}
+	if addrs := ctx.GlobalString(StreamAddressesFlag.Name); addrs != "" {
+		for _, addr := range splitAndTrim(addrs) {
+			if !common.IsHexAddress(addr) {
+				Fatalf("Invalid --%s address %q", StreamAddressesFlag.Name, addr)
+			}
+			config.Addresses = append(config.Addresses, common.HexToAddress(addr))
+		}
+	}
+	if topics := ctx.GlobalString(StreamTopicsFlag.Name); topics != "" {
+		for _, position := range strings.Split(topics, ",") {
+			var alternatives []common.Hash
+			for _, topic := range strings.Split(position, "|") {
+				if topic = strings.TrimSpace(topic); topic == "" {
+					continue
+				}
+				enc, err := hexutil.Decode(topic)
+				if err != nil || len(enc) != common.HashLength {
+					Fatalf("Invalid --%s topic %q", StreamTopicsFlag.Name, topic)
+				}
+				alternatives = append(alternatives, common.BytesToHash(enc))
+			}
+			config.Topics = append(config.Topics, alternatives)
+		}
+	}
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var aquaServ *aqua.AquaChain
+		if err := ctx.Service(&aquaServ); err != nil {
+			return nil, fmt.Errorf("chain event streaming requires a full node: %v", err)
+		}
+		return aquastream.New(config, aquaServ.BlockChain(), aquaServ.ChainDb())
+	}); err != nil {
+		Fatalf("Failed to register the chain event streaming service: %v", err)
+	}
+}
+
 // SetupNetwork configures the system for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) {
 	// TODO(fjl): move target gas limit into config
 	params.TargetGasLimit = ctx.GlobalUint64(TargetGasLimitFlag.Name)
 }
 
+// SetupMetrics starts the exporters of the collected metrics requested on the
+// command line.
+func SetupMetrics(ctx *cli.Context) {
+	if !metrics.Enabled {
+		if ctx.GlobalString(MetricsHTTPFlag.Name) != "" || ctx.GlobalBool(MetricsEnableInfluxDBFlag.Name) {
+			log.Warn("Metrics exporters need metrics collection, enable it with --" + MetricsEnabledFlag.Name)
+		}
+		return
+	}
+	if host := ctx.GlobalString(MetricsHTTPFlag.Name); host != "" {
+		address := fmt.Sprintf("%s:%d", host, ctx.GlobalInt(MetricsPortFlag.Name))
+		log.Info("Starting metrics server", "url", fmt.Sprintf("http://%s/metrics", address))
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", prometheus.Handler(metrics.DefaultRegistry, "aquachain"))
+		mux.Handle("/debug/metrics", exp.ExpHandler(metrics.DefaultRegistry))
+		go func() {
+			if err := http.ListenAndServe(address, mux); err != nil {
+				log.Error("Failure in running metrics server", "err", err)
+			}
+		}()
+	}
+	if ctx.GlobalBool(MetricsEnableInfluxDBFlag.Name) {
+		var (
+			endpoint = ctx.GlobalString(MetricsInfluxDBEndpointFlag.Name)
+			database = ctx.GlobalString(MetricsInfluxDBDatabaseFlag.Name)
+			username = ctx.GlobalString(MetricsInfluxDBUsernameFlag.Name)
+			password = ctx.GlobalString(MetricsInfluxDBPasswordFlag.Name)
+			hosttag  = ctx.GlobalString(MetricsInfluxDBHostTagFlag.Name)
+		)
+		log.Info("Pushing metrics to InfluxDB", "endpoint", endpoint, "database", database)
+		go influxdb.InfluxDBWithTags(metrics.DefaultRegistry, 10*time.Second, endpoint, database, username, password, "aquachain.", map[string]string{"host": hosttag})
+	}
+}
+
 // MakeChainDatabase open an LevelDB using the flags passed to the client and will hard crash if it fails.
 func MakeChainDatabase(ctx *cli.Context, stack *node.Node) aquadb.Database {
 	var (
@@ -1202,6 +1932,7 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) aquadb.Database {
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
+	core.SetStorageCompression(ctx.GlobalBool(DBCompressFlag.Name))
 	return chainDb
 }
 
@@ -1222,7 +1953,9 @@ func MakeGenesis(ctx *cli.Context) *core.Genesis {
 func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chainDb aquadb.Database) {
 	var err error
 	chainDb = MakeChainDatabase(ctx, stack)
-
+	if err := core.MigrateDatabase(chainDb); err != nil {
+		Fatalf("%v", err)
+	}
 	config, _, err := core.SetupGenesisBlock(chainDb, MakeGenesis(ctx))
 	if err != nil {
 		Fatalf("%v", err)
@@ -1250,6 +1983,11 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		Disabled:      ctx.GlobalString(GCModeFlag.Name) == "archive",
 		TrieNodeLimit: aqua.DefaultConfig.TrieCache,
 		TrieTimeLimit: aqua.DefaultConfig.TrieTimeout,
+		TxLookupLimit: ctx.GlobalUint64(TxLookupLimitFlag.Name),
+		Snapshot:      ctx.GlobalBool(SnapshotFlag.Name),
+		NoPrefetch:    ctx.GlobalBool(CacheNoPrefetchFlag.Name),
+
+		AccountHistory: ctx.GlobalBool(AccountHistoryFlag.Name),
 	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieNodeLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
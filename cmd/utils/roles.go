@@ -0,0 +1,129 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aquanetwork/aquachain/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// rolePreset is a single flag value set by a node role.
+type rolePreset struct {
+	flag      string   // Name of the flag to preset
+	value     string   // Value of the flag in the role
+	overrides []string // Other flags which, if given, take precedence over the preset
+}
+
+// syncModeOverrides are the shorthand flags that select a sync mode themselves.
+var syncModeOverrides = []string{FastSyncFlag.Name, LightModeFlag.Name}
+
+// nodeRoles are the flag presets of the supported node roles.
+var nodeRoles = map[string][]rolePreset{
+	// Full syncing node sealing blocks, guarded against mining on minority forks
+	"miner": {
+		{flag: SyncModeFlag.Name, value: "full", overrides: syncModeOverrides},
+		{flag: GCModeFlag.Name, value: "full"},
+		{flag: CacheFlag.Name, value: "1024"},
+		{flag: ForkGuardFlag.Name, value: "true"},
+	},
+	// Full node serving the public APIs over HTTP and websockets
+	"rpc": {
+		{flag: SyncModeFlag.Name, value: "full", overrides: syncModeOverrides},
+		{flag: GCModeFlag.Name, value: "full"},
+		{flag: CacheFlag.Name, value: "2048"},
+		{flag: RPCEnabledFlag.Name, value: "true"},
+		{flag: RPCApiFlag.Name, value: "aqua,net,web3,txpool,-txpool_unban,-txpool_resetDefense"},
+		{flag: WSEnabledFlag.Name, value: "true"},
+		{flag: WSApiFlag.Name, value: "aqua,net,web3"},
+	},
+	// Full node keeping the state of every block for historical queries
+	"archive": {
+		{flag: SyncModeFlag.Name, value: "full", overrides: syncModeOverrides},
+		{flag: GCModeFlag.Name, value: "archive"},
+		{flag: CacheFlag.Name, value: "4096"},
+	},
+	// Light client retrieving data on demand from full nodes
+	"light": {
+		{flag: SyncModeFlag.Name, value: "light", overrides: syncModeOverrides},
+		{flag: CacheFlag.Name, value: "128"},
+	},
+	// Light client watching the network, well connected and queryable over HTTP
+	"monitor": {
+		{flag: SyncModeFlag.Name, value: "light", overrides: syncModeOverrides},
+		{flag: CacheFlag.Name, value: "128"},
+		{flag: MaxPeersFlag.Name, value: "100"},
+		{flag: RPCEnabledFlag.Name, value: "true"},
+		{flag: RPCApiFlag.Name, value: "aqua,net,web3"},
+	},
+}
+
+// NodeRoles returns the names of the supported node roles.
+func NodeRoles() []string {
+	roles := make([]string, 0, len(nodeRoles))
+	for role := range nodeRoles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// ApplyRole presets the flags of the node role selected with --role. Flags given
+// explicitly on the command line are left untouched, so they always take
+// precedence over the role, which in turn takes precedence over the config file.
+func ApplyRole(ctx *cli.Context) {
+	role := ctx.GlobalString(RoleFlag.Name)
+	if role == "" {
+		return
+	}
+	presets, ok := nodeRoles[role]
+	if !ok {
+		Fatalf("Unknown --%s %q, must be one of: %s", RoleFlag.Name, role, strings.Join(NodeRoles(), ", "))
+	}
+	// Collect the explicit flags before presetting any, as setting marks them
+	var (
+		explicit = make(map[string]bool)
+		skipped  []string
+	)
+	for _, preset := range presets {
+		if ctx.GlobalIsSet(preset.flag) || isSetAny(ctx, preset.overrides...) {
+			explicit[preset.flag] = true
+			skipped = append(skipped, preset.flag)
+		}
+	}
+	for _, preset := range presets {
+		if explicit[preset.flag] {
+			continue
+		}
+		if err := ctx.GlobalSet(preset.flag, preset.value); err != nil {
+			Fatalf("Failed to apply --%s %s: --%s=%s: %v", RoleFlag.Name, role, preset.flag, preset.value, err)
+		}
+	}
+	log.Info("Applied node role presets", "role", role, "overridden", strings.Join(skipped, ","))
+}
+
+// isSetAny reports whether any of the flags was given on the command line.
+func isSetAny(ctx *cli.Context, flags ...string) bool {
+	for _, name := range flags {
+		if ctx.GlobalIsSet(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aqua/downloader"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// newRoleContext creates a command line context with the role relevant flags,
+// parsed from the given arguments.
+func newRoleContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range []cli.Flag{RoleFlag, SyncModeFlag, FastSyncFlag, LightModeFlag, GCModeFlag, CacheFlag, ForkGuardFlag, RPCEnabledFlag, RPCApiFlag} {
+		f.Apply(set)
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestApplyRole(t *testing.T) {
+	// Presets are applied to flags not given explicitly
+	ctx := newRoleContext(t, "--role", "archive", "--cache", "512")
+	ApplyRole(ctx)
+
+	if mode := ctx.GlobalString(GCModeFlag.Name); mode != "archive" {
+		t.Errorf("gcmode mismatch: have %s, want archive", mode)
+	}
+	if mode := *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode); mode != downloader.FullSync {
+		t.Errorf("syncmode mismatch: have %v, want %v", mode, downloader.FullSync)
+	}
+	if cache := ctx.GlobalInt(CacheFlag.Name); cache != 512 {
+		t.Errorf("explicit cache overridden: have %d, want 512", cache)
+	}
+	// Shorthand sync mode flags take precedence over the preset sync mode
+	ctx = newRoleContext(t, "--role", "miner", "--fast")
+	ApplyRole(ctx)
+
+	if ctx.IsSet(SyncModeFlag.Name) {
+		t.Errorf("syncmode preset despite --fast")
+	}
+	if !ctx.GlobalBool(ForkGuardFlag.Name) {
+		t.Errorf("forkguard not preset for miner")
+	}
+}
@@ -32,6 +32,10 @@ contract test {
 `
 )
 
+// testCombinedJSON is the output of solc --combined-json abi,bin,userdoc for the
+// test source, the dev docs not requested.
+const testCombinedJSON = `{"contracts":{"<stdin>:test":{"abi":"[{\"constant\":false,\"inputs\":[{\"name\":\"a\",\"type\":\"uint256\"}],\"name\":\"multiply\",\"outputs\":[{\"name\":\"d\",\"type\":\"uint256\"}],\"payable\":false,\"type\":\"function\"}]","bin":"6060604052341561000f57600080fd5b","userdoc":"{\"methods\":{}}"}},"version":"0.4.18+commit.9cf6e910.Linux.g++"}`
+
 func skipWithoutSolc(t *testing.T) {
 	if _, err := exec.LookPath("solc"); err != nil {
 		t.Skip(err)
@@ -75,3 +79,31 @@ func TestCompileError(t *testing.T) {
 	}
 	t.Logf("error: %v", err)
 }
+
+// Tests that the output of a compiler run elsewhere is parsed, even without the
+// optional docs.
+func TestParseCombinedJSON(t *testing.T) {
+	contracts, err := ParseCombinedJSON([]byte(testCombinedJSON), testSource, "0.4.18", "0.4.18", "")
+	if err != nil {
+		t.Fatalf("failed to parse combined-json: %v", err)
+	}
+	c, ok := contracts["<stdin>:test"]
+	if !ok || len(contracts) != 1 {
+		t.Fatalf("contracts mismatch: have %v, want <stdin>:test", contracts)
+	}
+	if c.Code != "0x6060604052341561000f57600080fd5b" {
+		t.Errorf("code mismatch: have %s, want %s", c.Code, "0x6060604052341561000f57600080fd5b")
+	}
+	if abi, ok := c.Info.AbiDefinition.([]interface{}); !ok || len(abi) != 1 {
+		t.Errorf("abi definition mismatch: have %v, want 1 method", c.Info.AbiDefinition)
+	}
+	if c.Info.UserDoc == nil || c.Info.DeveloperDoc != nil {
+		t.Errorf("docs mismatch: have user %v, dev %v", c.Info.UserDoc, c.Info.DeveloperDoc)
+	}
+	if c.Info.Source != testSource || c.Info.CompilerVersion != "0.4.18" {
+		t.Errorf("passed through info mismatch: have %q, %q", c.Info.Source, c.Info.CompilerVersion)
+	}
+	if _, err := ParseCombinedJSON([]byte(`{"contracts":{"x":{"abi":"[{"}}}`), "", "", "", ""); err == nil {
+		t.Errorf("malformed embedded abi accepted")
+	}
+}
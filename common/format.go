@@ -18,6 +18,7 @@ package common
 
 import (
 	"fmt"
+	"math/big"
 	"regexp"
 	"strings"
 	"time"
@@ -38,3 +39,25 @@ func (d PrettyDuration) String() string {
 	}
 	return label
 }
+
+// weiPerAqua is the number of wei in one aqua.
+var weiPerAqua = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// FormatAqua formats an amount of wei as the exact decimal amount of aqua, with
+// no trailing zeros, e.g. 1500000000000000000 as "1.5".
+func FormatAqua(wei *big.Int) string {
+	if wei == nil {
+		return "0"
+	}
+	quo, rem := new(big.Int).QuoRem(new(big.Int).Abs(wei), weiPerAqua, new(big.Int))
+	label := quo.String()
+	if rem.Sign() != 0 {
+		frac := rem.String()
+		frac = strings.Repeat("0", 18-len(frac)) + frac
+		label += "." + strings.TrimRight(frac, "0")
+	}
+	if wei.Sign() < 0 {
+		label = "-" + label
+	}
+	return label
+}
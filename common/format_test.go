@@ -0,0 +1,46 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package common
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFormatAqua(t *testing.T) {
+	tests := []struct {
+		wei  string
+		want string
+	}{
+		{"0", "0"},
+		{"1", "0.000000000000000001"},
+		{"1000000000", "0.000000001"},
+		{"1000000000000000000", "1"},
+		{"1500000000000000000", "1.5"},
+		{"123456789012345678901234", "123456.789012345678901234"},
+		{"-2500000000000000000", "-2.5"},
+	}
+	for _, tt := range tests {
+		wei, _ := new(big.Int).SetString(tt.wei, 10)
+		if have := FormatAqua(wei); have != tt.want {
+			t.Errorf("FormatAqua(%s) = %q, want %q", tt.wei, have, tt.want)
+		}
+	}
+	if have := FormatAqua(nil); have != "0" {
+		t.Errorf("FormatAqua(nil) = %q, want %q", have, "0")
+	}
+}
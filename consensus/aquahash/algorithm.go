@@ -22,7 +22,6 @@ import (
 	"reflect"
 	"runtime"
 	"sync"
-	"sync/atomic"
 	"time"
 	"unsafe"
 
@@ -81,8 +80,9 @@ func seedHash(block uint64) []byte {
 // memory, then performing two passes of Sergio Demian Lerner's RandMemoHash
 // algorithm from Strict Memory Hard Hashing Functions (2014). The output is a
 // set of 524288 64-byte values.
-// This method places the result into dest in machine byte order.
-func generateCache(dest []uint32, epoch uint64, seed []byte) {
+// This method places the result into dest in machine byte order, reporting its
+// progress to prog if not nil.
+func generateCache(dest []uint32, epoch uint64, seed []byte, prog *progress) {
 	// Print some debug logs to allow analysis on low end devices
 	logger := log.New("epoch", epoch)
 
@@ -107,7 +107,10 @@ func generateCache(dest []uint32, epoch uint64, seed []byte) {
 	rows := int(size) / hashBytes
 
 	// Start a monitoring goroutine to report progress on low end devices
-	var progress uint32
+	if prog == nil {
+		prog = new(progress)
+	}
+	prog.start(uint32(rows) * (cacheRounds + 1))
 
 	done := make(chan struct{})
 	defer close(done)
@@ -118,7 +121,7 @@ func generateCache(dest []uint32, epoch uint64, seed []byte) {
 			case <-done:
 				return
 			case <-time.After(3 * time.Second):
-				logger.Info("Generating aquahash verification cache", "percentage", atomic.LoadUint32(&progress)*100/uint32(rows)/4, "elapsed", common.PrettyDuration(time.Since(start)))
+				logger.Info("Generating aquahash verification cache", "percentage", uint64(prog.percentage()), "elapsed", common.PrettyDuration(time.Since(start)))
 			}
 		}
 	}()
@@ -129,7 +132,7 @@ func generateCache(dest []uint32, epoch uint64, seed []byte) {
 	keccak512(cache, seed)
 	for offset := uint64(hashBytes); offset < size; offset += hashBytes {
 		keccak512(cache[offset:], cache[offset-hashBytes:offset])
-		atomic.AddUint32(&progress, 1)
+		prog.step()
 	}
 	// Use a low-round version of randmemohash
 	temp := make([]byte, hashBytes)
@@ -144,7 +147,7 @@ func generateCache(dest []uint32, epoch uint64, seed []byte) {
 			bitutil.XORBytes(temp, cache[srcOff:srcOff+hashBytes], cache[xorOff:xorOff+hashBytes])
 			keccak512(cache[dstOff:], temp)
 
-			atomic.AddUint32(&progress, 1)
+			prog.step()
 		}
 	}
 	// Swap the byte order on big endian systems and return
@@ -218,8 +221,9 @@ func generateDatasetItem(cache []uint32, index uint32, keccak512 hasher) []byte
 }
 
 // generateDataset generates the entire aquahash dataset for mining.
-// This method places the result into dest in machine byte order.
-func generateDataset(dest []uint32, epoch uint64, cache []uint32) {
+// This method places the result into dest in machine byte order, reporting its
+// progress to prog if not nil.
+func generateDataset(dest []uint32, epoch uint64, cache []uint32, prog *progress) {
 	// Print some debug logs to allow analysis on low end devices
 	logger := log.New("epoch", epoch)
 
@@ -250,7 +254,10 @@ func generateDataset(dest []uint32, epoch uint64, cache []uint32) {
 	var pend sync.WaitGroup
 	pend.Add(threads)
 
-	var progress uint32
+	if prog == nil {
+		prog = new(progress)
+	}
+	prog.start(uint32(size / hashBytes))
 	for i := 0; i < threads; i++ {
 		go func(id int) {
 			defer pend.Done()
@@ -274,7 +281,7 @@ func generateDataset(dest []uint32, epoch uint64, cache []uint32) {
 				}
 				copy(dataset[index*hashBytes:], item)
 
-				if status := atomic.AddUint32(&progress, 1); status%percent == 0 {
+				if status := prog.step(); status%percent == 0 {
 					logger.Info("Generating DAG in progress", "percentage", uint64(status*100)/(size/hashBytes), "elapsed", common.PrettyDuration(time.Since(start)))
 				}
 			}
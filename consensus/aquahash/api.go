@@ -0,0 +1,125 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquahash
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aquanetwork/aquachain/consensus"
+)
+
+// maxPregenEpochs is the most epochs the API generates ahead at once.
+const maxPregenEpochs = 16
+
+var errNoCaches = errors.New("aquahash running without proof-of-work caches")
+
+// API is a user facing RPC API to manage the verification caches and mining
+// datasets of the aquahash engine, generating them ahead of the chain so the
+// node doesn't stall at epoch transitions.
+type API struct {
+	chain    consensus.ChainReader
+	aquahash *Aquahash
+}
+
+// GenerationStatus is the generation progress of a verification cache or a
+// mining dataset.
+type GenerationStatus struct {
+	Kind     string  `json:"kind"`     // "cache" or "dataset"
+	Epoch    uint64  `json:"epoch"`    // Epoch of the item
+	Block    uint64  `json:"block"`    // First block of the epoch
+	Ahead    bool    `json:"ahead"`    // Whether generated ahead of the chain
+	Ready    bool    `json:"ready"`    // Whether generated or loaded from disk
+	Progress float64 `json:"progress"` // Percentage generated
+}
+
+// engine returns the engine holding the caches, nil if there are none.
+func (api *API) engine() *Aquahash {
+	engine := api.aquahash
+	if engine.shared != nil {
+		engine = engine.shared
+	}
+	if engine.pregen == nil {
+		return nil
+	}
+	return engine
+}
+
+// Generation returns the status of the caches and datasets in memory and of
+// those generated ahead of the chain, ordered by epoch.
+func (api *API) Generation() ([]GenerationStatus, error) {
+	engine := api.engine()
+	if engine == nil {
+		return nil, errNoCaches
+	}
+	var status []GenerationStatus
+	add := func(kind string, epoch uint64, ahead bool, p *progress) {
+		percentage := p.percentage()
+		status = append(status, GenerationStatus{
+			Kind:     kind,
+			Epoch:    epoch,
+			Block:    epoch * epochLength,
+			Ahead:    ahead,
+			Ready:    percentage == 100,
+			Progress: percentage,
+		})
+	}
+	for _, item := range engine.caches.items() {
+		c := item.(*cache)
+		add("cache", c.epoch, false, &c.progress)
+	}
+	for _, item := range engine.datasets.items() {
+		d := item.(*dataset)
+		add("dataset", d.epoch, false, &d.progress)
+	}
+	engine.pregen.lock.Lock()
+	for _, c := range engine.pregen.caches {
+		add("cache", c.epoch, true, &c.progress)
+	}
+	for _, d := range engine.pregen.datasets {
+		add("dataset", d.epoch, true, &d.progress)
+	}
+	engine.pregen.lock.Unlock()
+
+	sort.Slice(status, func(i, j int) bool {
+		if status[i].Epoch != status[j].Epoch {
+			return status[i].Epoch < status[j].Epoch
+		}
+		return status[i].Kind < status[j].Kind
+	})
+	return status, nil
+}
+
+// Pregenerate starts generating in the background the verification caches of
+// the given number of epochs following the one of the chain head, and their
+// mining datasets if requested. It returns the epochs newly scheduled.
+func (api *API) Pregenerate(epochs uint64, datasets *bool) ([]uint64, error) {
+	engine := api.engine()
+	if engine == nil {
+		return nil, errNoCaches
+	}
+	if epochs == 0 || epochs > maxPregenEpochs {
+		return nil, fmt.Errorf("epochs out of range: have %d, want 1-%d", epochs, maxPregenEpochs)
+	}
+	head := api.chain.CurrentHeader().Number.Uint64() / epochLength
+	scheduled := engine.pregenerate(head, epochs, true, datasets != nil && *datasets)
+	if scheduled == nil {
+		scheduled = []uint64{}
+	}
+	return scheduled, nil
+}
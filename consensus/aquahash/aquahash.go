@@ -35,6 +35,7 @@ import (
 	"github.com/aquanetwork/aquachain/consensus"
 	"github.com/aquanetwork/aquachain/log"
 	"github.com/aquanetwork/aquachain/metrics"
+	"github.com/aquanetwork/aquachain/params"
 	"github.com/aquanetwork/aquachain/rpc"
 	mmap "github.com/edsrzf/mmap-go"
 	"github.com/hashicorp/golang-lru/simplelru"
@@ -47,7 +48,7 @@ var (
 	maxUint256 = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
 
 	// sharedAquahash is a full instance that can be shared between multiple users.
-	sharedAquahash = New(Config{"", 3, 0, "", 1, 0, ModeNormal})
+	sharedAquahash = New(Config{CachesInMem: 3, DatasetsInMem: 1, PowMode: ModeNormal})
 
 	// algorithmRevision is the data structure version used for file naming.
 	algorithmRevision = 2
@@ -154,6 +155,9 @@ type lru struct {
 	cache      *simplelru.LRU
 	future     uint64
 	futureItem interface{}
+
+	hitMeter  metrics.Meter // Meter counting requests served from the cache
+	missMeter metrics.Meter // Meter counting requests needing a new item
 }
 
 // newlru create a new least-recently-used cache for ither the verification caches
@@ -165,7 +169,13 @@ func newlru(what string, maxItems int, new func(epoch uint64) interface{}) *lru
 	cache, _ := simplelru.NewLRU(maxItems, func(key, value interface{}) {
 		log.Trace("Evicted aquahash "+what, "epoch", key)
 	})
-	return &lru{what: what, new: new, cache: cache}
+	return &lru{
+		what:      what,
+		new:       new,
+		cache:     cache,
+		hitMeter:  metrics.GetOrRegisterMeter("aquahash/"+what+"/hit", nil),
+		missMeter: metrics.GetOrRegisterMeter("aquahash/"+what+"/miss", nil),
+	}
 }
 
 // get retrieves or creates an item for the given epoch. The first return value is always
@@ -177,7 +187,10 @@ func (lru *lru) get(epoch uint64) (item, future interface{}) {
 
 	// Get or create the item for the requested epoch.
 	item, ok := lru.cache.Get(epoch)
-	if !ok {
+	if ok {
+		lru.hitMeter.Mark(1)
+	} else {
+		lru.missMeter.Mark(1)
 		if lru.future > 0 && lru.future == epoch {
 			item = lru.futureItem
 		} else {
@@ -196,6 +209,32 @@ func (lru *lru) get(epoch uint64) (item, future interface{}) {
 	return item, future
 }
 
+// has returns whether the lru holds an item for the given epoch, including the
+// future item.
+func (lru *lru) has(epoch uint64) bool {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	return lru.cache.Contains(epoch) || (lru.futureItem != nil && lru.future == epoch)
+}
+
+// items returns the items held by the lru, including the future item.
+func (lru *lru) items() []interface{} {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	var items []interface{}
+	for _, key := range lru.cache.Keys() {
+		if item, ok := lru.cache.Peek(key); ok {
+			items = append(items, item)
+		}
+	}
+	if lru.futureItem != nil && !lru.cache.Contains(lru.future) {
+		items = append(items, lru.futureItem)
+	}
+	return items
+}
+
 // cache wraps an aquahash cache with some metadata to allow easier concurrent use.
 type cache struct {
 	epoch uint64    // Epoch for which this cache is relevant
@@ -203,6 +242,8 @@ type cache struct {
 	mmap  mmap.MMap // Memory map itself to unmap before releasing
 	cache []uint32  // The actual cache data content (may be memory mapped)
 	once  sync.Once // Ensures the cache is generated only once
+
+	progress progress // Generation progress, for the management API
 }
 
 // newCache creates a new aquahash verification cache and returns it as a plain Go
@@ -214,6 +255,8 @@ func newCache(epoch uint64) interface{} {
 // generate ensures that the cache content is generated before use.
 func (c *cache) generate(dir string, limit int, test bool) {
 	c.once.Do(func() {
+		defer c.progress.finish()
+
 		size := cacheSize(c.epoch*epochLength + 1)
 		seed := seedHash(c.epoch*epochLength + 1)
 		if test {
@@ -222,7 +265,7 @@ func (c *cache) generate(dir string, limit int, test bool) {
 		// If we don't store anything on disk, generate and return.
 		if dir == "" {
 			c.cache = make([]uint32, size/4)
-			generateCache(c.cache, c.epoch, seed)
+			generateCache(c.cache, c.epoch, seed, &c.progress)
 			return
 		}
 		// Disk storage is needed, this will get fancy
@@ -247,12 +290,12 @@ func (c *cache) generate(dir string, limit int, test bool) {
 		logger.Debug("Failed to load old aquahash cache", "err", err)
 
 		// No previous cache available, create a new cache file to fill
-		c.dump, c.mmap, c.cache, err = memoryMapAndGenerate(path, size, func(buffer []uint32) { generateCache(buffer, c.epoch, seed) })
+		c.dump, c.mmap, c.cache, err = memoryMapAndGenerate(path, size, func(buffer []uint32) { generateCache(buffer, c.epoch, seed, &c.progress) })
 		if err != nil {
 			logger.Error("Failed to generate mapped aquahash cache", "err", err)
 
 			c.cache = make([]uint32, size/4)
-			generateCache(c.cache, c.epoch, seed)
+			generateCache(c.cache, c.epoch, seed, &c.progress)
 		}
 		// Iterate over all previous instances and delete old ones
 		for ep := int(c.epoch) - limit; ep >= 0; ep-- {
@@ -279,6 +322,8 @@ type dataset struct {
 	mmap    mmap.MMap // Memory map itself to unmap before releasing
 	dataset []uint32  // The actual cache data content
 	once    sync.Once // Ensures the cache is generated only once
+
+	progress progress // Generation progress, for the management API
 }
 
 // newDataset creates a new aquahash mining dataset and returns it as a plain Go
@@ -290,6 +335,8 @@ func newDataset(epoch uint64) interface{} {
 // generate ensures that the dataset content is generated before use.
 func (d *dataset) generate(dir string, limit int, test bool) {
 	d.once.Do(func() {
+		defer d.progress.finish()
+
 		csize := cacheSize(d.epoch*epochLength + 1)
 		dsize := datasetSize(d.epoch*epochLength + 1)
 		seed := seedHash(d.epoch*epochLength + 1)
@@ -300,10 +347,11 @@ func (d *dataset) generate(dir string, limit int, test bool) {
 		// If we don't store anything on disk, generate and return
 		if dir == "" {
 			cache := make([]uint32, csize/4)
-			generateCache(cache, d.epoch, seed)
+			generateCache(cache, d.epoch, seed, nil)
 
 			d.dataset = make([]uint32, dsize/4)
-			generateDataset(d.dataset, d.epoch, cache)
+			generateDataset(d.dataset, d.epoch, cache, &d.progress)
+			return
 		}
 		// Disk storage is needed, this will get fancy
 		var endian string
@@ -328,14 +376,14 @@ func (d *dataset) generate(dir string, limit int, test bool) {
 
 		// No previous dataset available, create a new dataset file to fill
 		cache := make([]uint32, csize/4)
-		generateCache(cache, d.epoch, seed)
+		generateCache(cache, d.epoch, seed, nil)
 
-		d.dump, d.mmap, d.dataset, err = memoryMapAndGenerate(path, dsize, func(buffer []uint32) { generateDataset(buffer, d.epoch, cache) })
+		d.dump, d.mmap, d.dataset, err = memoryMapAndGenerate(path, dsize, func(buffer []uint32) { generateDataset(buffer, d.epoch, cache, &d.progress) })
 		if err != nil {
 			logger.Error("Failed to generate mapped aquahash dataset", "err", err)
 
 			d.dataset = make([]uint32, dsize/2)
-			generateDataset(d.dataset, d.epoch, cache)
+			generateDataset(d.dataset, d.epoch, cache, &d.progress)
 		}
 		// Iterate over all previous instances and delete old ones
 		for ep := int(d.epoch) - limit; ep >= 0; ep-- {
@@ -387,6 +435,18 @@ type Config struct {
 	DatasetsInMem  int
 	DatasetsOnDisk int
 	PowMode        Mode
+
+	// Trusted checkpoints anchoring header verification
+	Checkpoints params.Checkpoints `toml:",omitempty"`
+
+	// Fraction of the time the sealing threads hash, resting for the remainder
+	// and yielding to block verification (full speed if 0 or 1)
+	Throttle float64 `toml:",omitempty"`
+
+	// Number of epochs ahead of the chain whose verification caches and mining
+	// datasets are generated in the background (only the next one if 0 or 1)
+	CachesAhead   int `toml:",omitempty"`
+	DatasetsAhead int `toml:",omitempty"`
 }
 
 // Aquahash is a consensus engine based on proot-of-work implementing the aquahash
@@ -394,8 +454,11 @@ type Config struct {
 type Aquahash struct {
 	config Config
 
-	caches   *lru // In memory caches to avoid regenerating too often
-	datasets *lru // In memory datasets to avoid regenerating too often
+	caches   *lru       // In memory caches to avoid regenerating too often
+	datasets *lru       // In memory datasets to avoid regenerating too often
+	seals    *sealCache // Recently verified seals to avoid verifying them again
+
+	pregen *pregenerator // Caches and datasets generated ahead of the chain
 
 	// Mining related fields
 	rand     *rand.Rand    // Properly seeded random source for nonces
@@ -403,6 +466,9 @@ type Aquahash struct {
 	update   chan struct{} // Notification channel to update mining parameters
 	hashrate metrics.Meter // Meter tracking the average hashrate
 
+	threadRates []metrics.Meter // Meters tracking the average hashrate of each search thread
+	verifying   int32           // Number of seals being verified, yielded to by throttled threads. Call atomically
+
 	// The fields below are hooks for testing
 	shared    *Aquahash     // Shared PoW verifier to avoid cache regeneration
 	fakeFail  uint64        // Block number which fails PoW check even in fake mode
@@ -423,10 +489,23 @@ func New(config Config) *Aquahash {
 	if config.DatasetDir != "" && config.DatasetsOnDisk > 0 {
 		log.Info("Disk storage enabled for aquahash DAGs", "dir", config.DatasetDir, "count", config.DatasetsOnDisk)
 	}
+	// Keep the items generated ahead on disk along with the current one, older
+	// ones being deleted as newer ones are generated
+	if config.CacheDir != "" && config.CachesOnDisk > 0 && config.CachesOnDisk <= config.CachesAhead {
+		log.Warn("Keeping more aquahash caches on disk to cover those generated ahead", "requested", config.CachesOnDisk, "ahead", config.CachesAhead)
+		config.CachesOnDisk = config.CachesAhead + 1
+	}
+	if config.DatasetDir != "" && config.DatasetsOnDisk > 0 && config.DatasetsOnDisk <= config.DatasetsAhead {
+		log.Warn("Keeping more aquahash DAGs on disk to cover those generated ahead", "requested", config.DatasetsOnDisk, "ahead", config.DatasetsAhead)
+		config.DatasetsOnDisk = config.DatasetsAhead + 1
+	}
+	pregen := newPregenerator()
 	return &Aquahash{
 		config:   config,
-		caches:   newlru("cache", config.CachesInMem, newCache),
-		datasets: newlru("dataset", config.DatasetsInMem, newDataset),
+		caches:   newlru("cache", config.CachesInMem, pregen.newCache),
+		datasets: newlru("dataset", config.DatasetsInMem, pregen.newDataset),
+		seals:    newSealCache(verifiedSeals),
+		pregen:   pregen,
 		update:   make(chan struct{}),
 		hashrate: metrics.NewMeter(),
 	}
@@ -505,6 +584,10 @@ func (aquahash *Aquahash) cache(block uint64) *cache {
 		future := futureI.(*cache)
 		go future.generate(aquahash.config.CacheDir, aquahash.config.CachesOnDisk, aquahash.config.PowMode == ModeTest)
 	}
+	// Generate the caches further ahead if configured
+	if aquahash.config.CachesAhead > 1 {
+		aquahash.pregenerate(epoch, uint64(aquahash.config.CachesAhead), true, false)
+	}
 	return current
 }
 
@@ -524,7 +607,10 @@ func (aquahash *Aquahash) dataset(block uint64) *dataset {
 		future := futureI.(*dataset)
 		go future.generate(aquahash.config.DatasetDir, aquahash.config.DatasetsOnDisk, aquahash.config.PowMode == ModeTest)
 	}
-
+	// Generate the datasets further ahead if configured
+	if aquahash.config.DatasetsAhead > 1 {
+		aquahash.pregenerate(epoch, uint64(aquahash.config.DatasetsAhead), false, true)
+	}
 	return current
 }
 
@@ -565,10 +651,49 @@ func (aquahash *Aquahash) Hashrate() float64 {
 	return aquahash.hashrate.Rate1()
 }
 
-// APIs implements consensus.Engine, returning the user facing RPC APIs. Currently
-// that is empty.
+// ThreadHashrates returns the measured rate of the search invocations per second
+// over the last minute of each mining thread.
+func (aquahash *Aquahash) ThreadHashrates() []float64 {
+	aquahash.lock.Lock()
+	defer aquahash.lock.Unlock()
+
+	if aquahash.shared != nil {
+		return aquahash.shared.ThreadHashrates()
+	}
+	threads := aquahash.threads
+	if threads == 0 {
+		threads = runtime.NumCPU()
+	}
+	if threads < 0 {
+		threads = 0
+	}
+	rates := make([]float64, threads)
+	for i := 0; i < threads && i < len(aquahash.threadRates); i++ {
+		rates[i] = aquahash.threadRates[i].Rate1()
+	}
+	return rates
+}
+
+// threadMeter returns the hashrate meter of a mining thread.
+func (aquahash *Aquahash) threadMeter(id int) metrics.Meter {
+	aquahash.lock.Lock()
+	defer aquahash.lock.Unlock()
+
+	for len(aquahash.threadRates) <= id {
+		aquahash.threadRates = append(aquahash.threadRates, metrics.NewMeter())
+	}
+	return aquahash.threadRates[id]
+}
+
+// APIs implements consensus.Engine, returning the user facing RPC APIs managing
+// the verification caches and mining datasets.
 func (aquahash *Aquahash) APIs(chain consensus.ChainReader) []rpc.API {
-	return nil
+	return []rpc.API{{
+		Namespace: "aquahash",
+		Version:   "1.0",
+		Service:   &API{chain: chain, aquahash: aquahash},
+		Public:    false,
+	}}
 }
 
 // SeedHash is the seed to use for generating a verification cache and the mining
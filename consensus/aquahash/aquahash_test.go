@@ -44,6 +44,40 @@ func TestTestMode(t *testing.T) {
 	}
 }
 
+// Tests that valid seals are remembered and not verified again, while invalid
+// ones are always rejected.
+func TestSealCache(t *testing.T) {
+	head := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(100)}
+	head.Version = types.H_KECCAK256
+	aquahash := NewTester()
+	block, err := aquahash.Seal(nil, types.NewBlockWithHeader(head), nil)
+	if err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	head.Nonce = types.EncodeNonce(block.Nonce())
+	head.MixDigest = block.MixDigest()
+
+	bad := types.CopyHeader(head)
+	bad.Nonce = types.EncodeNonce(block.Nonce() + 1)
+	for i := 0; i < 2; i++ {
+		if err := aquahash.VerifySeal(nil, head); err != nil {
+			t.Fatalf("attempt %d: unexpected verification error: %v", i, err)
+		}
+		if err := aquahash.VerifySeal(nil, bad); err == nil {
+			t.Fatalf("attempt %d: invalid seal accepted", i)
+		}
+	}
+	if n := aquahash.seals.cache.Len(); n != 1 {
+		t.Fatalf("cached seals mismatch: have %d, want 1", n)
+	}
+	// A seal is remembered for its header only
+	other := types.CopyHeader(head)
+	other.Difficulty = big.NewInt(101)
+	if aquahash.seals.verified(sealKey{hash: other.HashNoNonce(), nonce: other.Nonce, mix: other.MixDigest, version: other.Version}) {
+		t.Fatalf("seal remembered for another header")
+	}
+}
+
 // This test checks that cache lru logic doesn't crash under load.
 // It reproduces https://github.com/aquanetwork/aquachain/issues/14943
 func TestCacheFileEvict(t *testing.T) {
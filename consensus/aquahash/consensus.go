@@ -23,14 +23,17 @@ import (
 	"fmt"
 	"math/big"
 	"runtime"
+	"sync/atomic"
 	"time"
 
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/math"
 	"github.com/aquanetwork/aquachain/consensus"
+	"github.com/aquanetwork/aquachain/consensus/misc"
 	"github.com/aquanetwork/aquachain/core/state"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/log"
 	"github.com/aquanetwork/aquachain/params"
 
 	set "gopkg.in/fatih/set.v0"
@@ -38,11 +41,11 @@ import (
 
 // Aquahash proof-of-work protocol constants.
 var (
-	BlockReward            *big.Int = big.NewInt(1e+18) // Block reward in wei for successfully mining a block
-	ByzantiumBlockReward   *big.Int = big.NewInt(1e+18) // Block reward in wei for successfully mining a block upward from Byzantium
-	maxUncles                       = 2                 // Maximum number of uncles allowed in a single block
-	maxUnclesHF5                    = 1                 // Maximum number of uncles allowed in a single block after HF5 is activated
-	allowedFutureBlockTime          = 15 * time.Second  // Max time from current time allowed for blocks, before they're considered future blocks
+	BlockReward          *big.Int = big.NewInt(1e+18) // Block reward in wei of the default schedule, see params.DefaultRewards
+	ByzantiumBlockReward *big.Int = big.NewInt(1e+18) // Block reward in wei for successfully mining a block upward from Byzantium
+	maxUncles                     = 2                 // Maximum number of uncles allowed in a single block
+	maxUnclesHF5                  = 1                 // Maximum number of uncles allowed in a single block after HF5 is activated
+	multiAlgoLookback             = 256               // Maximum number of ancestors searched for the previous block of an algorithm
 )
 
 // Various error messages to mark blocks invalid. These should be private to
@@ -60,6 +63,10 @@ var (
 	errInvalidDifficulty = errors.New("non-positive difficulty")
 	errInvalidMixDigest  = errors.New("invalid mix digest")
 	errInvalidPoW        = errors.New("invalid proof-of-work")
+
+	errInvalidHeaderVersion = types.ErrInvalidHeaderVersion
+	errInvalidUncleVersion  = errors.New("invalid uncle version")
+	errCheckpointMismatch   = errors.New("header hash conflicts with trusted checkpoint")
 )
 
 // Author implements consensus.Engine, returning the header's coinbase as the
@@ -75,6 +82,10 @@ func (aquahash *Aquahash) VerifyHeader(chain consensus.ChainReader, header *type
 	if aquahash.config.PowMode == ModeFullFake {
 		return nil
 	}
+	// Ensure the header version is sane before hashing it
+	if err := verifyHeaderVersion(chain.Config(), header); err != nil {
+		return err
+	}
 	// Short circuit if the header is known, or it's parent not
 	number := header.Number.Uint64()
 	if chain.GetHeader(header.Hash(), number) != nil {
@@ -109,15 +120,16 @@ func (aquahash *Aquahash) VerifyHeaders(chain consensus.ChainReader, headers []*
 
 	// Create a task channel and spawn the verifiers
 	var (
-		inputs = make(chan int)
-		done   = make(chan int, workers)
-		errors = make([]error, len(headers))
-		abort  = make(chan struct{})
+		anchored = aquahash.anchored(chain, headers)
+		inputs   = make(chan int)
+		done     = make(chan int, workers)
+		errors   = make([]error, len(headers))
+		abort    = make(chan struct{})
 	)
 	for i := 0; i < workers; i++ {
 		go func() {
 			for index := range inputs {
-				errors[index] = aquahash.verifyHeaderWorker(chain, headers, seals, index)
+				errors[index] = aquahash.verifyHeaderWorker(chain, headers, seals, anchored, index)
 				done <- index
 			}
 		}()
@@ -153,7 +165,7 @@ func (aquahash *Aquahash) VerifyHeaders(chain consensus.ChainReader, headers []*
 	return abort, errorsOut
 }
 
-func (aquahash *Aquahash) verifyHeaderWorker(chain consensus.ChainReader, headers []*types.Header, seals []bool, index int) error {
+func (aquahash *Aquahash) verifyHeaderWorker(chain consensus.ChainReader, headers []*types.Header, seals, anchored []bool, index int) error {
 	var parent *types.Header
 	if index == 0 {
 		parent = chain.GetHeader(headers[0].ParentHash, headers[0].Number.Uint64()-1)
@@ -163,10 +175,48 @@ func (aquahash *Aquahash) verifyHeaderWorker(chain consensus.ChainReader, header
 	if parent == nil {
 		return consensus.ErrUnknownAncestor
 	}
-	if chain.GetHeader(headers[index].SetVersion(byte(chain.Config().GetBlockVersion(headers[index].Number))), headers[index].Number.Uint64()) != nil {
+	if err := verifyHeaderVersion(chain.Config(), headers[index]); err != nil {
+		return err
+	}
+	if chain.GetHeader(headers[index].Hash(), headers[index].Number.Uint64()) != nil {
 		return nil // known block
 	}
-	return aquahash.verifyHeader(chain, headers[index], parent, false, seals[index])
+	// Headers anchored by a checkpoint hash are trusted, so their expensive seal
+	// checks can be skipped during batch verification.
+	seal := seals[index] && !anchored[index]
+	return aquahash.verifyHeader(chain, headers[index], parent, false, seal)
+}
+
+// anchored marks the headers of a batch proven to be ancestors of a trusted
+// checkpoint: the checkpoint header itself, matching its hash, and the headers
+// linked to it by their hashes. Headers merely numbered below a checkpoint are
+// not trusted, as a fork off it would otherwise need no work at all.
+func (aquahash *Aquahash) anchored(chain consensus.ChainReader, headers []*types.Header) []bool {
+	var (
+		anchored = make([]bool, len(headers))
+		first    = headers[0].Number.Uint64()
+	)
+	checkpoints := aquahash.config.Checkpoints
+	for i := len(checkpoints) - 1; i >= 0; i-- {
+		cp := checkpoints[i]
+		if cp.Number < first || cp.Number-first >= uint64(len(headers)) {
+			continue
+		}
+		index := int(cp.Number - first)
+		header := headers[index]
+		if header.Number.Uint64() != cp.Number || verifyHeaderVersion(chain.Config(), header) != nil || header.Hash() != cp.Hash {
+			continue
+		}
+		anchored[index] = true
+		for j := index - 1; j >= 0; j-- {
+			if verifyHeaderVersion(chain.Config(), headers[j]) != nil || headers[j].Hash() != headers[j+1].ParentHash {
+				break
+			}
+			anchored[j] = true
+		}
+		break
+	}
+	return anchored
 }
 
 // VerifyUncles verifies that the given block's uncles conform to the consensus
@@ -195,11 +245,11 @@ func (aquahash *Aquahash) VerifyUncles(chain consensus.ChainReader, block *types
 		}
 		ancestors[ancestor.Hash()] = ancestor.Header()
 		for _, uncle := range ancestor.Uncles() {
-			uncles.Add(uncle.SetVersion(byte(chain.Config().GetBlockVersion(uncle.Number))))
+			uncles.Add(uncle.SetVersion(byte(types.BlockVersion(chain.Config(), uncle))))
 		}
 		parent, number = ancestor.ParentHash(), number-1
 	}
-	ancestorhash := block.SetVersion(chain.Config().GetBlockVersion(block.Number()))
+	ancestorhash := block.SetVersion(types.BlockVersion(chain.Config(), block.Header()))
 	ancestors[ancestorhash] = block.Header()
 	uncles.Add(ancestorhash)
 
@@ -224,7 +274,7 @@ func (aquahash *Aquahash) VerifyUncles(chain consensus.ChainReader, block *types
 			switch hash.Hex() {
 			case "0x13cb01d5d3566d076b5e128e5733f17968f95329fb1777ff38db53abdcca3e4c":
 			default:
-				println("uncle: " + hash.Hex())
+				log.Warn("Uncle is an ancestor of the block", "number", block.Number(), "uncle", hash)
 				common.Report(block)
 				return errUncleIsAncestor
 			}
@@ -253,13 +303,21 @@ func (aquahash *Aquahash) verifyHeader(chain consensus.ChainReader, header, pare
 	if uint64(len(header.Extra)) > params.MaximumExtraDataSize {
 		return fmt.Errorf("extra-data too long: %d > %d", len(header.Extra), params.MaximumExtraDataSize)
 	}
+	// Verify the header version is the one scheduled for its height
+	if err := verifyHeaderVersion(chain.Config(), header); err != nil {
+		if uncle {
+			return errInvalidUncleVersion
+		}
+		return err
+	}
 	// Verify the header's timestamp
 	if uncle {
 		if header.Time.Cmp(math.MaxBig256) > 0 {
 			return errLargeBlockTime
 		}
 	} else {
-		if header.Time.Cmp(big.NewInt(time.Now().Add(allowedFutureBlockTime).Unix())) > 0 {
+		allowed := chain.Config().AllowedFutureBlockTime()
+		if header.Time.Cmp(big.NewInt(time.Now().Add(allowed).Unix())) > 0 {
 			return consensus.ErrFutureBlock
 		}
 	}
@@ -267,7 +325,7 @@ func (aquahash *Aquahash) verifyHeader(chain consensus.ChainReader, header, pare
 		return errZeroBlockTime
 	}
 	// Verify the block's difficulty based in it's timestamp and parent's difficulty
-	expected := aquahash.CalcDifficulty(chain, header.Time.Uint64(), parent)
+	expected := aquahash.calcDifficulty(chain, header, parent)
 
 	if expected.Cmp(header.Difficulty) != 0 {
 		return fmt.Errorf("invalid difficulty: have %v, want %v", header.Difficulty, expected)
@@ -281,6 +339,10 @@ func (aquahash *Aquahash) verifyHeader(chain consensus.ChainReader, header, pare
 	if header.GasUsed > header.GasLimit {
 		return fmt.Errorf("invalid gasUsed: have %d, gasLimit %d", header.GasUsed, header.GasLimit)
 	}
+	// Verify the base fee of the fee market
+	if err := misc.VerifyBaseFee(chain.Config(), parent, header); err != nil {
+		return err
+	}
 
 	// Verify that the gas limit remains within allowed bounds
 	diff := int64(parent.GasLimit) - int64(header.GasLimit)
@@ -296,6 +358,12 @@ func (aquahash *Aquahash) verifyHeader(chain consensus.ChainReader, header, pare
 	if diff := new(big.Int).Sub(header.Number, parent.Number); diff.Cmp(big.NewInt(1)) != 0 {
 		return consensus.ErrInvalidNumber
 	}
+	// Reject any canonical candidate that disagrees with a trusted checkpoint
+	if !uncle {
+		if cp := aquahash.config.Checkpoints.Get(header.Number.Uint64()); cp != nil && cp.Hash != header.Hash() {
+			return errCheckpointMismatch
+		}
+	}
 	// Verify the engine specific seal securing the block
 	if seal {
 		if err := aquahash.VerifySeal(chain, header); err != nil {
@@ -305,6 +373,23 @@ func (aquahash *Aquahash) verifyHeader(chain consensus.ChainReader, header, pare
 	return nil
 }
 
+// verifyHeaderVersion checks that the version of a header matches the one the
+// chain configuration mandates for its block number. The version is not part
+// of the RLP encoding, so headers arriving from the network may still have it
+// unset, in which case the expected version is filled in.
+func verifyHeaderVersion(config *params.ChainConfig, header *types.Header) error {
+	return types.VerifyHeaderVersion(config, header)
+}
+
+// calcDifficulty returns the difficulty a header has to have, tracked per
+// algorithm in the multi-algorithm era.
+func (aquahash *Aquahash) calcDifficulty(chain consensus.ChainReader, header, parent *types.Header) *big.Int {
+	if chain.Config().IsMultiAlgo(header.Number) {
+		return calcDifficultyMultiAlgo(chain, header.Time.Uint64(), parent, types.BlockVersion(chain.Config(), header))
+	}
+	return aquahash.CalcDifficulty(chain, header.Time.Uint64(), parent)
+}
+
 // CalcDifficulty is the difficulty adjustment algorithm. It returns
 // the difficulty that a new block should have when created at time
 // given the parent block's time and difficulty.
@@ -367,6 +452,12 @@ func (aquahash *Aquahash) VerifySeal(chain consensus.ChainReader, header *types.
 		return errInvalidDifficulty
 	}
 
+	// Skip the seals verified recently, such as on reorgs and downloader retries
+	hash := header.HashNoNonce()
+	key := sealKey{hash: hash, nonce: header.Nonce, mix: header.MixDigest, version: header.Version}
+	if aquahash.seals.verified(key) {
+		return nil
+	}
 	// Recompute the digest and PoW value and verify against the header
 	cache := aquahash.cache(number)
 	size := datasetSize(number)
@@ -377,30 +468,39 @@ func (aquahash *Aquahash) VerifySeal(chain consensus.ChainReader, header *types.
 		digest []byte
 		result []byte
 	)
+	atomic.AddInt32(&aquahash.verifying, 1)
+	defer atomic.AddInt32(&aquahash.verifying, -1)
+
+	start := time.Now()
 	switch header.Version {
-	default: // types.H_UNSET: // 0
-		panic("header version not set")
+	default: // types.H_UNSET or unknown, never panic on remote input
+		return errInvalidHeaderVersion
 	case types.H_KECCAK256: // 1
-		digest, result = hashimotoLight(size, cache.cache, header.HashNoNonce().Bytes(), header.Nonce.Uint64())
+		digest, result = hashimotoLight(size, cache.cache, hash.Bytes(), header.Nonce.Uint64())
+		verifyHashimotoTimer.UpdateSince(start)
 	case types.H_ARGON2ID: // 2
 		seed := make([]byte, 40)
-		copy(seed, header.HashNoNonce().Bytes())
+		copy(seed, hash.Bytes())
 		binary.LittleEndian.PutUint64(seed[32:], header.Nonce.Uint64())
 		result = crypto.Argon2id(seed)
 		digest = make([]byte, common.HashLength)
+		verifyArgon2idTimer.UpdateSince(start)
 	}
 	// Caches are unmapped in a finalizer. Ensure that the cache stays live
 	// until after the call to hashimotoLight so it's not unmapped while being used.
 	runtime.KeepAlive(cache)
 
 	if !bytes.Equal(header.MixDigest[:], digest) {
+		verifyFailMeter.Mark(1)
 		//fmt.Printf("Invalid Digest (%v):\n%x (!=) %x\n", header.Number.Uint64(), header.MixDigest[:], digest)
 		return errInvalidMixDigest
 	}
 	target := new(big.Int).Div(maxUint256, header.Difficulty)
 	if new(big.Int).SetBytes(result).Cmp(target) > 0 {
+		verifyFailMeter.Mark(1)
 		return errInvalidPoW
 	}
+	aquahash.seals.add(key)
 	return nil
 }
 
@@ -411,7 +511,7 @@ func (aquahash *Aquahash) Prepare(chain consensus.ChainReader, header *types.Hea
 	if parent == nil {
 		return consensus.ErrUnknownAncestor
 	}
-	header.Difficulty = aquahash.CalcDifficulty(chain, header.Time.Uint64(), parent)
+	header.Difficulty = aquahash.calcDifficulty(chain, header, parent)
 	return nil
 }
 
@@ -419,7 +519,7 @@ func (aquahash *Aquahash) Prepare(chain consensus.ChainReader, header *types.Hea
 // setting the final state and assembling the block.
 func (aquahash *Aquahash) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
 	// Accumulate any block and uncle rewards and commit the final state root
-	header.SetVersion(byte(chain.Config().GetBlockVersion(header.Number)))
+	header.SetVersion(byte(types.BlockVersion(chain.Config(), header)))
 	for i := range uncles {
 		uncles[i].Version = header.Version // uncles must have same version
 	}
@@ -429,38 +529,34 @@ func (aquahash *Aquahash) Finalize(chain consensus.ChainReader, header *types.He
 	return types.NewBlock(header, txs, uncles, receipts), nil
 }
 
-// Some weird constants to avoid constant memory allocs for them.
-var (
-	big8  = big.NewInt(8)
-	big32 = big.NewInt(32)
-)
-
 // AccumulateRewards credits the coinbase of the given block with the mining
 // reward. The total reward consists of the static block reward and rewards for
 // included uncles. The coinbase of each uncle block is also rewarded.
 func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header) {
-	// Select the correct block reward based on chain progression
-	blockReward := BlockReward
-
-	// fees-only after 42,000,000
-	// since uncles have a reward too, we will have to adjust this number
-	// luckily we have time before we hit anywhere near there
-	rewarding := header.Number.Cmp(params.MaxMoney) == -1
-	if !rewarding {
+	reward, uncleRewards := Rewards(config, header, uncles)
+	if reward == nil {
 		return
 	}
-	// Accumulate the rewards for the miner and any included uncles
-	reward := new(big.Int).Set(blockReward)
-	r := new(big.Int)
-	for _, uncle := range uncles {
-		r.Add(uncle.Number, big8)
-		r.Sub(r, header.Number)
-		r.Mul(r, blockReward)
-		r.Div(r, big8)
-		state.AddBalance(uncle.Coinbase, r)
-
-		r.Div(blockReward, big32)
-		reward.Add(reward, r)
+	for i, uncle := range uncles {
+		state.AddBalance(uncle.Coinbase, uncleRewards[i])
 	}
 	state.AddBalance(header.Coinbase, reward)
 }
+
+// Rewards returns the reward of the miner of the given block, including those
+// for the uncles it includes, and the rewards of the miners of the uncles, as
+// scheduled by the chain config. The rewards are nil once issuance stopped.
+func Rewards(config *params.ChainConfig, header *types.Header, uncles []*types.Header) (*big.Int, []*big.Int) {
+	era := config.RewardEra(header.Number)
+	if era == nil {
+		return nil, nil
+	}
+	// Accumulate the rewards for the miner and any included uncles
+	reward := new(big.Int).Set(era.BlockReward)
+	uncleRewards := make([]*big.Int, len(uncles))
+	for i, uncle := range uncles {
+		uncleRewards[i] = era.UncleReward(new(big.Int).Sub(header.Number, uncle.Number).Uint64())
+		reward.Add(reward, era.InclusionReward())
+	}
+	return reward, uncleRewards
+}
@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/math"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/params"
@@ -84,3 +85,224 @@ func TestCalcDifficulty(t *testing.T) {
 		}
 	}
 }
+
+func TestVerifyHeaderVersion(t *testing.T) {
+	config := params.TestChainConfig // HF5 activates at block 5
+
+	tests := []struct {
+		number  int64
+		version types.HeaderVersion
+		want    types.HeaderVersion
+		err     error
+	}{
+		{1, types.H_UNSET, types.H_KECCAK256, nil},
+		{1, types.H_KECCAK256, types.H_KECCAK256, nil},
+		{1, types.H_ARGON2ID, types.H_ARGON2ID, errInvalidHeaderVersion},
+		{5, types.H_UNSET, types.H_ARGON2ID, nil},
+		{5, types.H_KECCAK256, types.H_KECCAK256, errInvalidHeaderVersion},
+		{6, types.H_ARGON2ID, types.H_ARGON2ID, nil},
+		{6, 7, 7, types.ErrUnknownHeaderVersion},
+	}
+	for i, tt := range tests {
+		header := &types.Header{Number: big.NewInt(tt.number), Version: tt.version}
+		if err := verifyHeaderVersion(config, header); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if header.Version != tt.want {
+			t.Errorf("test %d: version mismatch: have %d, want %d", i, header.Version, tt.want)
+		}
+	}
+}
+
+// Tests that seal verification of a header without a known version returns an
+// error instead of crashing the node.
+func TestVerifySealUnknownVersion(t *testing.T) {
+	aquahash := NewTester()
+	for _, version := range []types.HeaderVersion{types.H_UNSET, 7} {
+		head := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(100), Version: version}
+		if err := aquahash.VerifySeal(nil, head); err != errInvalidHeaderVersion {
+			t.Errorf("version %d: error mismatch: have %v, want %v", version, err, errInvalidHeaderVersion)
+		}
+	}
+}
+
+// Tests that only headers linked by their hashes to a checkpoint are exempt
+// from seal verification, not a fork merely numbered below it.
+func TestAnchoredHeaders(t *testing.T) {
+	chain := &testChainReader{config: params.TestChainConfig, headers: make(map[common.Hash]*types.Header)}
+	genesis := &types.Header{Number: big.NewInt(0), Time: big.NewInt(1000), Difficulty: big.NewInt(131072)}
+	chain.headers[genesis.Hash()] = genesis
+
+	canon := []*types.Header{chain.add(genesis, 0, 1010)}
+	for i := 1; i < 4; i++ {
+		canon = append(canon, chain.add(canon[i-1], 0, int64(1010+10*i)))
+	}
+	fork := []*types.Header{chain.add(genesis, 0, 1011)}
+	for i := 1; i < 4; i++ {
+		fork = append(fork, chain.add(fork[i-1], 0, int64(1011+10*i)))
+	}
+	aquahash := NewTester()
+	if anchored := aquahash.anchored(chain, canon); anchored[0] || anchored[3] {
+		t.Errorf("headers anchored without checkpoints: %v", anchored)
+	}
+	aquahash.config.Checkpoints = params.Checkpoints{{Number: 3, Hash: canon[2].Hash()}}
+
+	for i, want := range []bool{true, true, true, false} {
+		if have := aquahash.anchored(chain, canon)[i]; have != want {
+			t.Errorf("canonical header %d: anchored mismatch: have %v, want %v", i, have, want)
+		}
+	}
+	for i, anchored := range aquahash.anchored(chain, fork) {
+		if anchored {
+			t.Errorf("forked header %d anchored below checkpoint", i)
+		}
+	}
+	// A fork reattached below the checkpoint header loses the anchor
+	batch := []*types.Header{fork[0], canon[1], canon[2]}
+	for i, want := range []bool{false, true, true} {
+		if have := aquahash.anchored(chain, batch)[i]; have != want {
+			t.Errorf("mixed header %d: anchored mismatch: have %v, want %v", i, have, want)
+		}
+	}
+}
+
+// testChainReader is a chain of headers serving the multi-algorithm tests.
+type testChainReader struct {
+	config  *params.ChainConfig
+	headers map[common.Hash]*types.Header
+}
+
+func (c *testChainReader) Config() *params.ChainConfig                    { return c.config }
+func (c *testChainReader) CurrentHeader() *types.Header                   { return nil }
+func (c *testChainReader) GetHeaderByNumber(number uint64) *types.Header  { return nil }
+func (c *testChainReader) GetHeaderByHash(hash common.Hash) *types.Header { return c.headers[hash] }
+func (c *testChainReader) GetBlock(common.Hash, uint64) *types.Block      { return nil }
+func (c *testChainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.headers[hash]
+}
+
+// add appends a header sealed by the given algorithm to the chain.
+func (c *testChainReader) add(parent *types.Header, algo types.HeaderVersion, time int64) *types.Header {
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, big1),
+		Time:       big.NewInt(time),
+		Extra:      []byte{byte(algo)},
+	}
+	header.Difficulty = new(Aquahash).calcDifficulty(c, header, parent)
+	header.Version = types.BlockVersion(c.config, header)
+	c.headers[header.Hash()] = header
+	return header
+}
+
+// Tests that the multi-algorithm era tracks the difficulty of every algorithm
+// separately, and that blocks may only be sealed by approved algorithms.
+func TestMultiAlgo(t *testing.T) {
+	config := *params.TestChainConfig
+	config.MultiAlgo = &params.MultiAlgoConfig{Block: big.NewInt(11), Algorithms: []uint64{uint64(types.H_KECCAK256), uint64(types.H_ARGON2ID)}}
+
+	chain := &testChainReader{config: &config, headers: make(map[common.Hash]*types.Header)}
+	head := &types.Header{Number: big.NewInt(10), Time: big.NewInt(1000), Difficulty: big.NewInt(1000000), Version: types.H_ARGON2ID}
+	chain.headers[head.Hash()] = head
+
+	adjusted := func(diff *big.Int, up bool) *big.Int {
+		adjust := new(big.Int).Div(diff, params.DifficultyBoundDivisorHF5)
+		if !up {
+			adjust.Neg(adjust)
+		}
+		if adjusted := new(big.Int).Add(diff, adjust); adjusted.Cmp(params.MinimumDifficultyHF5) > 0 {
+			return adjusted
+		}
+		return params.MinimumDifficultyHF5
+	}
+	// The first block of every algorithm starts from the minimum difficulty
+	a1 := chain.add(head, types.H_ARGON2ID, 1100)
+	k1 := chain.add(a1, types.H_KECCAK256, 1200)
+	if a1.Difficulty.Cmp(params.MinimumDifficultyHF5) != 0 || k1.Difficulty.Cmp(params.MinimumDifficultyHF5) != 0 {
+		t.Fatalf("initial difficulties mismatch: have %v and %v, want %v", a1.Difficulty, k1.Difficulty, params.MinimumDifficultyHF5)
+	}
+	// Difficulties are adjusted relative to the previous block of the same
+	// algorithm, expected every other block
+	a2 := chain.add(k1, types.H_ARGON2ID, 1100+2*params.DurationLimit.Int64()-1)
+	if want := adjusted(a1.Difficulty, true); a2.Difficulty.Cmp(want) != 0 {
+		t.Fatalf("raised difficulty mismatch: have %v, want %v", a2.Difficulty, want)
+	}
+	a3 := chain.add(a2, types.H_ARGON2ID, a2.Time.Int64()+2*params.DurationLimit.Int64())
+	if want := adjusted(a2.Difficulty, false); a3.Difficulty.Cmp(want) != 0 {
+		t.Fatalf("lowered difficulty mismatch: have %v, want %v", a3.Difficulty, want)
+	}
+	k2 := chain.add(a3, types.H_KECCAK256, a3.Time.Int64()+1)
+	if want := adjusted(k1.Difficulty, false); k2.Difficulty.Cmp(want) != 0 {
+		t.Fatalf("independent difficulty mismatch: have %v, want %v", k2.Difficulty, want)
+	}
+	// Only approved algorithms are accepted
+	for i, tt := range []struct {
+		extra []byte
+		err   error
+	}{
+		{[]byte{byte(types.H_ARGON2ID)}, nil},
+		{[]byte{byte(types.H_KECCAK256)}, nil},
+		{[]byte{7}, types.ErrUnapprovedHeaderVersion},
+		{nil, types.ErrUnapprovedHeaderVersion},
+	} {
+		header := &types.Header{Number: big.NewInt(20), Extra: tt.extra}
+		if err := verifyHeaderVersion(&config, header); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that the rewards follow the configured schedule at the era boundaries.
+func TestRewardSchedule(t *testing.T) {
+	aqua := big.NewInt(1e18)
+	config := &params.ChainConfig{
+		Rewards: &params.RewardConfig{
+			Eras: []params.RewardEra{
+				{Block: big.NewInt(0), BlockReward: new(big.Int).Mul(aqua, big.NewInt(2)), UncleDivisor: 8, InclusionDivisor: 32},
+				{Block: big.NewInt(100), BlockReward: aqua, UncleDivisor: 4, InclusionDivisor: 10},
+			},
+			End:          big.NewInt(200),
+			TailEmission: big.NewInt(1e17),
+		},
+	}
+	tests := []struct {
+		config *params.ChainConfig
+		number int64
+		depth  int64  // Depth of the single uncle, 0 for none
+		reward string // Miner reward, empty if none
+		uncle  string // Uncle reward
+	}{
+		// The default schedule stops issuance at MaxMoney
+		{params.TestChainConfig, 1, 1, "1031250000000000000", "875000000000000000"},
+		{params.TestChainConfig, params.MaxMoney.Int64() - 1, 7, "1031250000000000000", "125000000000000000"},
+		{params.TestChainConfig, params.MaxMoney.Int64(), 1, "", ""},
+		// Eras switch at their first block, with their own uncle fractions
+		{config, 99, 2, "2062500000000000000", "1500000000000000000"},
+		{config, 100, 2, "1100000000000000000", "500000000000000000"},
+		{config, 100, 5, "1100000000000000000", "0"},
+		{config, 199, 0, "1000000000000000000", ""},
+		// The tail emission keeps the fractions of the last era
+		{config, 200, 1, "110000000000000000", "75000000000000000"},
+		{config, 1000000, 0, "100000000000000000", ""},
+	}
+	for i, tt := range tests {
+		header := &types.Header{Number: big.NewInt(tt.number)}
+		var uncles []*types.Header
+		if tt.depth > 0 {
+			uncles = append(uncles, &types.Header{Number: big.NewInt(tt.number - tt.depth)})
+		}
+		reward, uncleRewards := Rewards(tt.config, header, uncles)
+		if tt.reward == "" {
+			if reward != nil {
+				t.Errorf("test %d: reward mismatch: have %v, want none", i, reward)
+			}
+			continue
+		}
+		if reward == nil || reward.String() != tt.reward {
+			t.Errorf("test %d: reward mismatch: have %v, want %s", i, reward, tt.reward)
+		}
+		if tt.depth > 0 && uncleRewards[0].String() != tt.uncle {
+			t.Errorf("test %d: uncle reward mismatch: have %v, want %s", i, uncleRewards[0], tt.uncle)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"math/big"
 
 	"github.com/aquanetwork/aquachain/common/math"
+	"github.com/aquanetwork/aquachain/consensus"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/params"
 )
@@ -236,6 +237,47 @@ func calcDifficultyHF5(time uint64, parent *types.Header) *big.Int {
 	return diff
 }
 
+// calcDifficultyMultiAlgo is the difficulty adjustment algorithm of the
+// multi-algorithm era. Every algorithm has its own difficulty, adjusted like
+// calcDifficultyHF5 relative to the previous block sealed by the same algorithm,
+// which is expected once every as many blocks as there are approved algorithms.
+// An algorithm without a recent block starts over from the minimum difficulty.
+func calcDifficultyMultiAlgo(chain consensus.ChainReader, time uint64, parent *types.Header, algo types.HeaderVersion) *big.Int {
+	config := chain.Config()
+	prev := lastAlgoHeader(chain, parent, algo)
+	if prev == nil {
+		return new(big.Int).Set(params.MinimumDifficultyHF5)
+	}
+	diff := new(big.Int)
+	adjust := new(big.Int).Div(prev.Difficulty, params.DifficultyBoundDivisorHF5)
+	spacing := new(big.Int).Mul(params.DurationLimit, big.NewInt(int64(len(config.MultiAlgo.Algorithms))))
+
+	if new(big.Int).Sub(new(big.Int).SetUint64(time), prev.Time).Cmp(spacing) < 0 {
+		diff.Add(prev.Difficulty, adjust)
+	} else {
+		diff.Sub(prev.Difficulty, adjust)
+	}
+	if diff.Cmp(params.MinimumDifficultyHF5) < 0 {
+		diff.Set(params.MinimumDifficultyHF5)
+	}
+	return diff
+}
+
+// lastAlgoHeader returns the most recent ancestor sealed by the given algorithm
+// in the multi-algorithm era, searching at most multiAlgoLookback headers back
+// from parent.
+func lastAlgoHeader(chain consensus.ChainReader, parent *types.Header, algo types.HeaderVersion) *types.Header {
+	config := chain.Config()
+	header := parent
+	for i := 0; i < multiAlgoLookback && header != nil && config.IsMultiAlgo(header.Number); i++ {
+		if types.BlockVersion(config, header) == algo {
+			return header
+		}
+		header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return nil
+}
+
 // calcDifficultyFrontier is the difficulty adjustment algorithm. It returns the
 // difficulty that a new block should have when created at time given the parent
 // block's time and difficulty. The calculation uses the Frontier rules.
@@ -0,0 +1,236 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package diffsim simulates the difficulty adjustment of aquahash over recorded
+// or synthetic block timestamps, to evaluate retarget algorithms and parameters
+// before scheduling them in a hard fork.
+package diffsim
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"math/rand"
+	"sort"
+
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Retarget computes the difficulty of a block mined at time on top of parent.
+type Retarget func(time uint64, parent *types.Header) *big.Int
+
+// Algorithms are the difficulty adjustment algorithms of the hard forks, by
+// name. HF4 didn't change the difficulty, so it has no algorithm of its own.
+var Algorithms = map[string]Retarget{
+	"homestead": forkRetarget(-1),
+	"hf1":       forkRetarget(1),
+	"hf2":       forkRetarget(2),
+	"hf3":       forkRetarget(3),
+	"hf5":       forkRetarget(5),
+}
+
+// AlgorithmNames returns the names of the Algorithms, sorted.
+func AlgorithmNames() []string {
+	names := make([]string, 0, len(Algorithms))
+	for name := range Algorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// forkRetarget returns the algorithm of aquahash.CalcDifficulty with the hard
+// forks up to hf active from the genesis block on.
+func forkRetarget(hf int) Retarget {
+	config := &params.ChainConfig{HomesteadBlock: big.NewInt(0), HF: make(params.ForkMap)}
+	for i := 0; i <= hf; i++ {
+		config.HF[i] = big.NewInt(0)
+	}
+	return func(time uint64, parent *types.Header) *big.Int {
+		return aquahash.CalcDifficulty(config, time, parent)
+	}
+}
+
+// StepRetarget returns an algorithm shaped like the one of HF2 onwards with the
+// given parameters: the difficulty moves by a 1/divisor step, up if the block
+// came faster than target seconds and down otherwise, never below minimum.
+func StepRetarget(divisor, target uint64, minimum *big.Int) Retarget {
+	return func(time uint64, parent *types.Header) *big.Int {
+		adjust := new(big.Int).Div(parent.Difficulty, new(big.Int).SetUint64(divisor))
+		diff := new(big.Int)
+		if time-parent.Time.Uint64() < target {
+			diff.Add(parent.Difficulty, adjust)
+		} else {
+			diff.Sub(parent.Difficulty, adjust)
+		}
+		if diff.Cmp(minimum) < 0 {
+			diff.Set(minimum)
+		}
+		return diff
+	}
+}
+
+// Hashrate returns the hashes per second working on a block, given its number
+// and difficulty, letting miners react to the difficulty.
+type Hashrate func(number uint64, difficulty *big.Int) float64
+
+// ConstantHashrate returns a hashrate of rate hashes per second.
+func ConstantHashrate(rate float64) Hashrate {
+	return func(uint64, *big.Int) float64 { return rate }
+}
+
+// StepHashrate returns a hashrate of rate hashes per second, multiplied by
+// factor from block at on, such as when a large miner joins or leaves.
+func StepHashrate(rate, factor float64, at uint64) Hashrate {
+	return func(number uint64, _ *big.Int) float64 {
+		if number >= at {
+			return rate * factor
+		}
+		return rate
+	}
+}
+
+// OscillatingHashrate returns a hashrate of rate hashes per second, multiplied
+// by factor every other period blocks, such as miners hopping between chains.
+func OscillatingHashrate(rate, factor float64, period uint64) Hashrate {
+	return func(number uint64, _ *big.Int) float64 {
+		if period > 0 && (number/period)%2 == 1 {
+			return rate * factor
+		}
+		return rate
+	}
+}
+
+// Block is a simulated block.
+type Block struct {
+	Number     uint64
+	Time       uint64   // Timestamp in seconds
+	Interval   uint64   // Seconds since the parent
+	Difficulty *big.Int // Difficulty required by the retarget algorithm
+	Hashrate   float64  // Hashes per second, estimated from the interval on replays
+}
+
+// Simulate mines count blocks on top of parent, each at the difficulty the
+// retarget algorithm requires, with intervals drawn at random for the hashrate.
+// The rng seeds the intervals so runs can be reproduced.
+func Simulate(retarget Retarget, hashrate Hashrate, parent *types.Header, count int, rng *rand.Rand) []Block {
+	blocks := make([]Block, 0, count)
+	header := types.CopyHeader(parent)
+	for i := 0; i < count; i++ {
+		number := header.Number.Uint64() + 1
+
+		// Find the difficulty at the earliest time, then how long it takes to mine it
+		difficulty := retarget(header.Time.Uint64()+1, header)
+		rate := hashrate(number, difficulty)
+		expected, _ := new(big.Float).SetInt(difficulty).Float64()
+
+		interval := uint64(math.Ceil(rng.ExpFloat64() * expected / rate))
+		if interval == 0 {
+			interval = 1
+		}
+		// The difficulty may depend on the time, settle with the one the block is mined at
+		time := header.Time.Uint64() + interval
+		difficulty = retarget(time, header)
+
+		blocks = append(blocks, Block{Number: number, Time: time, Interval: interval, Difficulty: difficulty, Hashrate: rate})
+		header = &types.Header{Number: new(big.Int).SetUint64(number), Time: new(big.Int).SetUint64(time), Difficulty: difficulty, UncleHash: types.EmptyUncleHash}
+	}
+	return blocks
+}
+
+// errUnorderedTimestamps is returned if recorded timestamps don't increase.
+var errUnorderedTimestamps = errors.New("timestamps must be increasing")
+
+// Replay runs the retarget algorithm over recorded block timestamps, the first
+// of them being the one of parent's child. The hashrate of the blocks is
+// estimated from their difficulty and interval.
+func Replay(retarget Retarget, parent *types.Header, times []uint64) ([]Block, error) {
+	blocks := make([]Block, 0, len(times))
+	header := types.CopyHeader(parent)
+	for _, time := range times {
+		if time <= header.Time.Uint64() {
+			return nil, errUnorderedTimestamps
+		}
+		var (
+			number     = header.Number.Uint64() + 1
+			interval   = time - header.Time.Uint64()
+			difficulty = retarget(time, header)
+		)
+		expected, _ := new(big.Float).SetInt(difficulty).Float64()
+		blocks = append(blocks, Block{Number: number, Time: time, Interval: interval, Difficulty: difficulty, Hashrate: expected / float64(interval)})
+		header = &types.Header{Number: new(big.Int).SetUint64(number), Time: new(big.Int).SetUint64(time), Difficulty: difficulty, UncleHash: types.EmptyUncleHash}
+	}
+	return blocks, nil
+}
+
+// Stats summarizes the intervals of simulated blocks.
+type Stats struct {
+	Blocks       int
+	MeanInterval float64 // Mean block interval in seconds
+	Median       uint64  // Median block interval, what the step algorithms actually target
+	StdDev       float64 // Standard deviation of the block intervals
+	MaxInterval  uint64  // Longest block interval
+	Settled      int     // Blocks after which the trailing median interval stays within 25% of the target, -1 if never
+}
+
+// settleWindow is the number of blocks of the trailing median interval Summarize
+// checks against the target.
+const settleWindow = 64
+
+// Summarize computes the statistics of the block intervals, against the target
+// interval in seconds.
+func Summarize(blocks []Block, target uint64) Stats {
+	stats := Stats{Blocks: len(blocks), Settled: -1}
+	if len(blocks) == 0 {
+		return stats
+	}
+	var sum float64
+	for _, block := range blocks {
+		sum += float64(block.Interval)
+		if block.Interval > stats.MaxInterval {
+			stats.MaxInterval = block.Interval
+		}
+	}
+	stats.MeanInterval = sum / float64(len(blocks))
+	for _, block := range blocks {
+		stats.StdDev += math.Pow(float64(block.Interval)-stats.MeanInterval, 2)
+	}
+	stats.StdDev = math.Sqrt(stats.StdDev / float64(len(blocks)))
+
+	stats.Median = median(blocks)
+
+	// Find the last block the trailing median strays from the target
+	for i := settleWindow; i <= len(blocks); i++ {
+		if mid := float64(median(blocks[i-settleWindow : i])); math.Abs(mid-float64(target)) > float64(target)/4 {
+			stats.Settled = -1
+		} else if stats.Settled == -1 {
+			stats.Settled = i
+		}
+	}
+	return stats
+}
+
+// median returns the median interval of the blocks.
+func median(blocks []Block) uint64 {
+	intervals := make([]uint64, len(blocks))
+	for i, block := range blocks {
+		intervals[i] = block.Interval
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+	return intervals[len(intervals)/2]
+}
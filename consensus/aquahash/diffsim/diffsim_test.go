@@ -0,0 +1,137 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package diffsim
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// genesis returns a parent header at the HF5 minimum difficulty.
+func genesis() *types.Header {
+	return &types.Header{Number: big.NewInt(0), Time: big.NewInt(0), Difficulty: new(big.Int).Set(params.MinimumDifficultyHF5), UncleHash: types.EmptyUncleHash}
+}
+
+// Tests that simulations with the same seed reproduce, and that a replay of the
+// simulated timestamps gives back the simulated difficulties.
+func TestSimulateReplay(t *testing.T) {
+	rate := ConstantHashrate(200000)
+	first := Simulate(Algorithms["hf5"], rate, genesis(), 256, rand.New(rand.NewSource(1)))
+	second := Simulate(Algorithms["hf5"], rate, genesis(), 256, rand.New(rand.NewSource(1)))
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("simulation not reproducible with the same seed")
+	}
+	times := make([]uint64, len(first))
+	for i, block := range first {
+		times[i] = block.Time
+	}
+	replay, err := Replay(Algorithms["hf5"], genesis(), times)
+	if err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	for i := range replay {
+		if replay[i].Difficulty.Cmp(first[i].Difficulty) != 0 {
+			t.Fatalf("block %d: difficulty mismatch: have %v, want %v", replay[i].Number, replay[i].Difficulty, first[i].Difficulty)
+		}
+	}
+	if _, err := Replay(Algorithms["hf5"], genesis(), []uint64{10, 10}); err != errUnorderedTimestamps {
+		t.Errorf("unordered replay error mismatch: have %v, want %v", err, errUnorderedTimestamps)
+	}
+}
+
+// Tests that the retarget algorithms settle the block interval near the target
+// and follow a hashrate increase.
+func TestSimulateSettles(t *testing.T) {
+	for _, name := range []string{"hf2", "hf5"} {
+		var (
+			retarget = Algorithms[name]
+			parent   = genesis()
+			minimum  = retarget(parent.Time.Uint64()+1, parent)
+			start    = new(big.Int).Mul(minimum, big.NewInt(4))
+			rate, _  = new(big.Float).SetInt(start).Float64()
+		)
+		// Start in equilibrium, with the hashrate mining the parent difficulty on target
+		parent.Difficulty = start
+		rate /= float64(params.DurationLimit.Uint64())
+		blocks := Simulate(retarget, StepHashrate(rate, 4, 2000), parent, 4000, rand.New(rand.NewSource(2)))
+
+		before := Summarize(blocks[1000:2000], params.DurationLimit.Uint64())
+		if before.MeanInterval < 120 || before.MeanInterval > 480 {
+			t.Errorf("%s: mean interval %.1f far off the target", name, before.MeanInterval)
+		}
+		after := blocks[len(blocks)-1].Difficulty
+		if after.Cmp(new(big.Int).Mul(blocks[1999].Difficulty, big.NewInt(2))) < 0 {
+			t.Errorf("%s: difficulty didn't follow the hashrate: have %v, before the step %v", name, after, blocks[1999].Difficulty)
+		}
+	}
+}
+
+func TestReadTimestamps(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []uint64
+		fail  bool
+	}{
+		{input: "10\n20\n30\n", want: []uint64{10, 20, 30}},
+		{input: "number,timestamp\n1,10\n2,20\n", want: []uint64{10, 20}},
+		{input: "# comment\n5,x\n", want: []uint64{5}},
+		{input: "number,difficulty\n1,10\n", fail: true},
+		{input: "10\nx\n", fail: true},
+	}
+	for i, tt := range tests {
+		have, err := ReadTimestamps(strings.NewReader(tt.input))
+		if tt.fail {
+			if err == nil {
+				t.Errorf("test %d: expected error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to read: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: timestamps mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+func TestWriteOutputs(t *testing.T) {
+	blocks := Simulate(StepRetarget(16, 240, params.MinimumDifficultyHF5), ConstantHashrate(200000), genesis(), 16, rand.New(rand.NewSource(3)))
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, []string{"step"}, [][]Block{blocks}); err != nil {
+		t.Fatalf("failed to write csv: %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != len(blocks)+1 {
+		t.Errorf("csv lines mismatch: have %d, want %d", lines, len(blocks)+1)
+	}
+	buf.Reset()
+	if err := WriteSVG(&buf, []string{"step"}, [][]Block{blocks}); err != nil {
+		t.Fatalf("failed to write svg: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "<svg") || !strings.HasSuffix(buf.String(), "</svg>\n") {
+		t.Errorf("malformed svg: %s", buf.String())
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package diffsim
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ReadTimestamps reads recorded block timestamps from a CSV stream. The first
+// column holds the timestamps, unless a header row names a "timestamp" column.
+func ReadTimestamps(r io.Reader) ([]uint64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	var (
+		times  []uint64
+		column = 0
+	)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return times, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 {
+			if _, err := strconv.ParseUint(strings.TrimSpace(record[0]), 10, 64); err != nil {
+				// Not a number, find the timestamp column in the header row
+				column = -1
+				for i, name := range record {
+					if strings.EqualFold(strings.TrimSpace(name), "timestamp") {
+						column = i
+					}
+				}
+				if column < 0 {
+					return nil, fmt.Errorf("no timestamp column in header %v", record)
+				}
+				continue
+			}
+		}
+		if column >= len(record) {
+			return nil, fmt.Errorf("line %d: missing timestamp column", line)
+		}
+		time, err := strconv.ParseUint(strings.TrimSpace(record[column]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid timestamp: %v", line, err)
+		}
+		times = append(times, time)
+	}
+}
+
+// WriteCSV writes the simulated blocks of each algorithm as CSV rows of block
+// number, algorithm, timestamp, interval, difficulty and hashrate.
+func WriteCSV(w io.Writer, names []string, runs [][]Block) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"number", "algorithm", "timestamp", "interval", "difficulty", "hashrate"})
+	for i, blocks := range runs {
+		for _, block := range blocks {
+			out.Write([]string{
+				strconv.FormatUint(block.Number, 10),
+				names[i],
+				strconv.FormatUint(block.Time, 10),
+				strconv.FormatUint(block.Interval, 10),
+				block.Difficulty.String(),
+				strconv.FormatFloat(block.Hashrate, 'f', 2, 64),
+			})
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// Plot dimensions and series colors of WriteSVG.
+const (
+	plotWidth  = 960
+	plotHeight = 360
+	plotMargin = 48
+)
+
+var plotColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b"}
+
+// WriteSVG plots the difficulty and the block interval of the simulated blocks
+// of each algorithm as two SVG charts, one above the other.
+func WriteSVG(w io.Writer, names []string, runs [][]Block) error {
+	var (
+		difficulty = make([][]float64, len(runs))
+		interval   = make([][]float64, len(runs))
+	)
+	for i, blocks := range runs {
+		for _, block := range blocks {
+			diff, _ := new(big.Float).SetInt(block.Difficulty).Float64()
+			difficulty[i] = append(difficulty[i], diff)
+			interval[i] = append(interval[i], float64(block.Interval))
+		}
+	}
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"sans-serif\" font-size=\"12\">\n", plotWidth, 2*plotHeight)
+	fmt.Fprintf(w, "<rect width=\"100%%\" height=\"100%%\" fill=\"white\"/>\n")
+	plotSeries(w, "difficulty", 0, difficulty)
+	plotSeries(w, "block interval (s)", plotHeight, interval)
+	for i, name := range names {
+		fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\" fill=\"%s\">%s</text>\n", plotWidth-plotMargin-80, plotMargin+16*(i+1), plotColors[i%len(plotColors)], name)
+	}
+	_, err := fmt.Fprintln(w, "</svg>")
+	return err
+}
+
+// plotSeries draws a chart of the series at the vertical offset, scaled to the
+// largest value of all of them.
+func plotSeries(w io.Writer, title string, offset int, series [][]float64) {
+	var (
+		count int
+		max   float64
+	)
+	for _, values := range series {
+		if len(values) > count {
+			count = len(values)
+		}
+		for _, value := range values {
+			if value > max {
+				max = value
+			}
+		}
+	}
+	if max == 0 {
+		max = 1
+	}
+	var (
+		left   = float64(plotMargin)
+		bottom = float64(offset + plotHeight - plotMargin)
+		width  = float64(plotWidth - 2*plotMargin)
+		height = float64(plotHeight - 2*plotMargin)
+	)
+	fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\">%s (max %.4g)</text>\n", plotMargin, offset+plotMargin-8, title, max)
+	fmt.Fprintf(w, "<polyline points=\"%.0f,%.0f %.0f,%.0f %.0f,%.0f\" fill=\"none\" stroke=\"black\"/>\n", left, bottom-height, left, bottom, left+width, bottom)
+	for i, values := range series {
+		points := make([]string, len(values))
+		for j, value := range values {
+			x := left + width*float64(j)/float64(count)
+			y := bottom - height*value/max
+			points[j] = fmt.Sprintf("%.1f,%.1f", x, y)
+		}
+		fmt.Fprintf(w, "<polyline points=\"%s\" fill=\"none\" stroke=\"%s\" stroke-width=\"1\"/>\n", strings.Join(points, " "), plotColors[i%len(plotColors)])
+	}
+}
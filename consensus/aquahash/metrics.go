@@ -0,0 +1,31 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the metrics collected by the seal verifier.
+
+package aquahash
+
+import (
+	"github.com/aquanetwork/aquachain/metrics"
+)
+
+var (
+	verifyHashimotoTimer = metrics.NewRegisteredTimer("aquahash/verify/hashimoto", nil)
+	verifyArgon2idTimer  = metrics.NewRegisteredTimer("aquahash/verify/argon2id", nil)
+	verifyFailMeter      = metrics.NewRegisteredMeter("aquahash/verify/fail", nil)
+	verifyCacheHitMeter  = metrics.NewRegisteredMeter("aquahash/verify/cache/hit", nil)
+	verifyCacheMissMeter = metrics.NewRegisteredMeter("aquahash/verify/cache/miss", nil)
+)
@@ -0,0 +1,172 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquahash
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/aquanetwork/aquachain/log"
+)
+
+// progress tracks the generation of a verification cache or mining dataset.
+type progress struct {
+	done  uint32 // Generation steps completed, accessed atomically
+	total uint32 // Generation steps needed, 0 until generation starts
+	ready uint32 // Set once the content is usable
+}
+
+// start resets the progress for a generation of total steps.
+func (p *progress) start(total uint32) {
+	atomic.StoreUint32(&p.done, 0)
+	atomic.StoreUint32(&p.total, total)
+}
+
+// step records a generation step, returning the steps completed.
+func (p *progress) step() uint32 {
+	return atomic.AddUint32(&p.done, 1)
+}
+
+// finish marks the content usable, generated or loaded from disk.
+func (p *progress) finish() {
+	atomic.StoreUint32(&p.ready, 1)
+}
+
+// percentage returns the share of the generation completed.
+func (p *progress) percentage() float64 {
+	if atomic.LoadUint32(&p.ready) == 1 {
+		return 100
+	}
+	total := atomic.LoadUint32(&p.total)
+	if total == 0 {
+		return 0
+	}
+	done := atomic.LoadUint32(&p.done)
+	if done > total {
+		done = total
+	}
+	return float64(done) * 100 / float64(total)
+}
+
+// pregenerator generates the verification caches and mining datasets of the
+// epochs ahead of the chain in the background, handing them over to the engine
+// once the chain reaches them instead of stalling at the epoch transition.
+type pregenerator struct {
+	lock     sync.Mutex
+	caches   map[uint64]*cache   // Caches generated ahead, by epoch
+	datasets map[uint64]*dataset // Datasets generated ahead, by epoch
+}
+
+func newPregenerator() *pregenerator {
+	return &pregenerator{
+		caches:   make(map[uint64]*cache),
+		datasets: make(map[uint64]*dataset),
+	}
+}
+
+// pregenerate schedules the generation of the caches, the datasets or both of
+// the given number of epochs following head, skipping those already generated
+// or scheduled. It returns the epochs newly scheduled. The items generated ahead
+// but left unused by the time the chain reached head are dropped.
+func (aquahash *Aquahash) pregenerate(head, ahead uint64, caches, datasets bool) []uint64 {
+	// Find the items the engine already holds, before locking the pregenerator
+	// that the engine caches call into
+	held := make(map[uint64][2]bool)
+	for epoch := head + 1; epoch <= head+ahead && epoch < maxEpoch; epoch++ {
+		held[epoch] = [2]bool{aquahash.caches.has(epoch), aquahash.datasets.has(epoch)}
+	}
+	p := aquahash.pregen
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for epoch := range p.caches {
+		if epoch <= head {
+			delete(p.caches, epoch)
+		}
+	}
+	for epoch := range p.datasets {
+		if epoch <= head {
+			delete(p.datasets, epoch)
+		}
+	}
+	var (
+		scheduled []uint64
+		newCaches []*cache
+		newSets   []*dataset
+	)
+	for epoch := head + 1; epoch <= head+ahead && epoch < maxEpoch; epoch++ {
+		_, cached := p.caches[epoch]
+		_, set := p.datasets[epoch]
+
+		genCache := caches && !cached && !held[epoch][0]
+		genSet := datasets && !set && !held[epoch][1]
+		if genCache {
+			p.caches[epoch] = newCache(epoch).(*cache)
+			newCaches = append(newCaches, p.caches[epoch])
+		}
+		if genSet {
+			p.datasets[epoch] = newDataset(epoch).(*dataset)
+			newSets = append(newSets, p.datasets[epoch])
+		}
+		if genCache || genSet {
+			scheduled = append(scheduled, epoch)
+		}
+	}
+	if len(scheduled) == 0 {
+		return nil
+	}
+	log.Debug("Generating aquahash caches ahead", "epochs", scheduled, "caches", len(newCaches), "datasets", len(newSets))
+
+	// Generate one item at a time, the nearest epochs first
+	go func() {
+		test := aquahash.config.PowMode == ModeTest
+		for _, c := range newCaches {
+			c.generate(aquahash.config.CacheDir, aquahash.config.CachesOnDisk, test)
+		}
+		for _, d := range newSets {
+			d.generate(aquahash.config.DatasetDir, aquahash.config.DatasetsOnDisk, test)
+		}
+	}()
+	return scheduled
+}
+
+// newCache returns the cache generated ahead for the epoch if any, or a new one
+// to generate.
+func (p *pregenerator) newCache(epoch uint64) interface{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if c, ok := p.caches[epoch]; ok {
+		delete(p.caches, epoch)
+		return c
+	}
+	return newCache(epoch)
+}
+
+// newDataset returns the dataset generated ahead for the epoch if any, or a new
+// one to generate.
+func (p *pregenerator) newDataset(epoch uint64) interface{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if d, ok := p.datasets[epoch]; ok {
+		delete(p.datasets, epoch)
+		return d
+	}
+	return newDataset(epoch)
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquahash
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that the caches and datasets generated ahead are handed over to the
+// engine once the chain reaches their epochs, and reported by the API meanwhile.
+func TestPregenerate(t *testing.T) {
+	aquahash := NewTester()
+
+	scheduled := aquahash.pregenerate(0, 2, true, true)
+	if len(scheduled) != 2 || scheduled[0] != 1 || scheduled[1] != 2 {
+		t.Fatalf("scheduled epochs mismatch: have %v, want [1 2]", scheduled)
+	}
+	if again := aquahash.pregenerate(0, 2, true, true); len(again) != 0 {
+		t.Fatalf("epochs scheduled twice: %v", again)
+	}
+	ahead := aquahash.pregen.caches[2]
+
+	// Wait for the generation to finish, as reported by the API
+	api := &API{aquahash: aquahash}
+	for start := time.Now(); ; {
+		status, err := api.Generation()
+		if err != nil {
+			t.Fatalf("failed to retrieve generation status: %v", err)
+		}
+		if len(status) != 4 {
+			t.Fatalf("status count mismatch: have %d, want 4", len(status))
+		}
+		ready := true
+		for _, item := range status {
+			ready = ready && item.Ready && item.Ahead
+		}
+		if ready {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatalf("generation not finished: %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Reaching epoch 2 hands its cache over, dropping the unused ones before it
+	if c := aquahash.cache(2 * epochLength); c != ahead {
+		t.Fatalf("cache generated ahead not used")
+	}
+	if _, ok := aquahash.pregen.caches[2]; ok {
+		t.Fatalf("cache generated ahead still pending once used")
+	}
+	aquahash.pregenerate(2, 1, true, false)
+	if _, ok := aquahash.pregen.caches[1]; ok {
+		t.Fatalf("cache of a past epoch kept")
+	}
+	if _, ok := aquahash.pregen.caches[3]; ok {
+		t.Fatalf("cache held by the engine generated again")
+	}
+	if _, err := (&API{aquahash: NewFaker()}).Generation(); err != errNoCaches {
+		t.Fatalf("error mismatch: have %v, want %v", err, errNoCaches)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"math/rand"
 	"runtime"
 	"sync"
+	"time"
 
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/consensus"
@@ -56,7 +57,7 @@ func (aquahash *Aquahash) Seal(chain consensus.ChainReader, block *types.Block,
 	found := make(chan *types.Block)
 
 	aquahash.lock.Lock()
-	threads := aquahash.threads
+	threads, throttle := aquahash.threads, aquahash.config.Throttle
 	if aquahash.rand == nil {
 		seed, err := crand.Int(crand.Reader, big.NewInt(math.MaxInt64))
 		if err != nil {
@@ -78,7 +79,7 @@ func (aquahash *Aquahash) Seal(chain consensus.ChainReader, block *types.Block,
 		pend.Add(1)
 		go func(id int, nonce uint64) {
 			defer pend.Done()
-			aquahash.mine(version, block, id, nonce, abort, found)
+			aquahash.mine(version, block, id, nonce, throttle, abort, found)
 		}(i, uint64(aquahash.rand.Int63()))
 	}
 	// Wait until sealing is terminated or a nonce is found
@@ -102,8 +103,9 @@ func (aquahash *Aquahash) Seal(chain consensus.ChainReader, block *types.Block,
 }
 
 // mine is the actual proof-of-work miner that searches for a nonce starting from
-// seed that results in correct final block difficulty.
-func (aquahash *Aquahash) mine(version params.HeaderVersion, block *types.Block, id int, seed uint64, abort chan struct{}, found chan *types.Block) {
+// seed that results in correct final block difficulty. If throttled, it only
+// hashes the given fraction of the time and yields to block verification.
+func (aquahash *Aquahash) mine(version params.HeaderVersion, block *types.Block, id int, seed uint64, throttle float64, abort chan struct{}, found chan *types.Block) {
 	// Extract some data from the header
 	var (
 		header  = block.Header()
@@ -117,6 +119,9 @@ func (aquahash *Aquahash) mine(version params.HeaderVersion, block *types.Block,
 	var (
 		attempts = int64(0)
 		nonce    = seed
+		meter    = aquahash.threadMeter(id)
+		marked   = time.Now()
+		cycle    = newDutyCycle(throttle)
 	)
 	logger := log.New("miner", id)
 	logger.Trace("Started aquahash search for new nonces", "seed", seed)
@@ -128,14 +133,23 @@ search:
 			// Mining terminated, update stats and abort
 			logger.Trace("Aquahash nonce search aborted", "attempts", nonce-seed)
 			aquahash.hashrate.Mark(attempts)
+			meter.Mark(attempts)
 			break search
 
 		default:
 			// We don't have to update hash rate on every nonce, so update after after 2^X nonces
+			// or a while, and before resting so the idle time is accounted for
 			attempts++
-			if (attempts % (1 << 15)) == 0 {
+			if (attempts%(1<<15)) == 0 || time.Since(marked) >= hashrateMarkInterval || (cycle != nil && cycle.due()) {
 				aquahash.hashrate.Mark(attempts)
-				attempts = 0
+				meter.Mark(attempts)
+				attempts, marked = 0, time.Now()
+			}
+			if cycle != nil {
+				if cycle.due() {
+					cycle.rest(abort)
+				}
+				aquahash.yield(abort)
 			}
 
 			// Compute the PoW value of this nonce
@@ -0,0 +1,81 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquahash
+
+import (
+	"sync"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// verifiedSeals is the number of recently verified seals remembered, enough to
+// cover a few downloader batches.
+const verifiedSeals = 4096
+
+// sealKey identifies a seal by the hash of the sealed header and the seal
+// itself. The full header hash isn't used as it costs an argon2id computation
+// in its own right.
+type sealKey struct {
+	hash    common.Hash // Hash of the header without the seal
+	nonce   types.BlockNonce
+	mix     common.Hash
+	version types.HeaderVersion
+}
+
+// sealCache remembers the recently verified seals, sparing the proof-of-work
+// computation when the same headers are verified again, such as on reorgs and
+// downloader retries. Only valid seals are remembered.
+type sealCache struct {
+	mu    sync.Mutex
+	cache *simplelru.LRU
+}
+
+// newSealCache creates a cache of the given number of seals.
+func newSealCache(size int) *sealCache {
+	cache, _ := simplelru.NewLRU(size, nil)
+	return &sealCache{cache: cache}
+}
+
+// verified returns whether the seal was verified recently. It's safe to call on
+// a nil cache, which doesn't remember any seal.
+func (c *sealCache) verified(key sealKey) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.cache.Get(key); ok {
+		verifyCacheHitMeter.Mark(1)
+		return true
+	}
+	verifyCacheMissMeter.Mark(1)
+	return false
+}
+
+// add remembers a valid seal.
+func (c *sealCache) add(key sealKey) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache.Add(key, struct{}{})
+}
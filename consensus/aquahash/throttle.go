@@ -0,0 +1,108 @@
+// Copyright 2017 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquahash
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// dutyCyclePeriod is the length of one hashing and resting cycle of the
+	// throttled sealing threads.
+	dutyCyclePeriod = 200 * time.Millisecond
+
+	// yieldInterval is how often throttled sealing threads check whether the
+	// block verification they yielded to is over.
+	yieldInterval = 10 * time.Millisecond
+
+	// hashrateMarkInterval is the maximum time between two hashrate updates of a
+	// sealing thread, argon2id being too slow to update every 2^15 nonces.
+	hashrateMarkInterval = time.Second
+)
+
+// dutyCycle paces a throttled sealing thread, hashing only a fraction of every
+// cycle and resting for the remainder.
+type dutyCycle struct {
+	busy  time.Duration // Hashing time per cycle
+	idle  time.Duration // Resting time per cycle
+	start time.Time     // Start of the current hashing period
+}
+
+// newDutyCycle creates the pacing of a sealing thread hashing the given fraction
+// of the time, nil if at full speed.
+func newDutyCycle(throttle float64) *dutyCycle {
+	if throttle <= 0 || throttle >= 1 {
+		return nil
+	}
+	busy := time.Duration(float64(dutyCyclePeriod) * throttle)
+	return &dutyCycle{
+		busy:  busy,
+		idle:  dutyCyclePeriod - busy,
+		start: time.Now(),
+	}
+}
+
+// due returns whether the hashing period of the cycle is over.
+func (d *dutyCycle) due() bool {
+	return time.Since(d.start) >= d.busy
+}
+
+// rest sleeps through the resting period of the cycle, returning early if the
+// sealing is aborted.
+func (d *dutyCycle) rest(abort <-chan struct{}) {
+	timer := time.NewTimer(d.idle)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-abort:
+	}
+	d.start = time.Now()
+}
+
+// yield pauses a throttled sealing thread while blocks are being verified, so
+// the node mining on shared hardware doesn't lag behind the chain.
+func (aquahash *Aquahash) yield(abort <-chan struct{}) {
+	for atomic.LoadInt32(&aquahash.verifying) > 0 {
+		select {
+		case <-time.After(yieldInterval):
+		case <-abort:
+			return
+		}
+	}
+}
+
+// SetThrottle sets the fraction of the time the sealing threads hash, resting
+// for the remainder and yielding to block verification. Full speed if zero or
+// one.
+func (aquahash *Aquahash) SetThrottle(throttle float64) {
+	aquahash.lock.Lock()
+	defer aquahash.lock.Unlock()
+
+	// If we're running a shared PoW, set the throttle on that instead
+	if aquahash.shared != nil {
+		aquahash.shared.SetThrottle(throttle)
+		return
+	}
+	// Update the throttle and ping any running seal to pull in any changes
+	aquahash.config.Throttle = throttle
+	select {
+	case aquahash.update <- struct{}{}:
+	default:
+	}
+}
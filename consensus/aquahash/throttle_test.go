@@ -0,0 +1,90 @@
+// Copyright 2017 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquahash
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Tests that the sealing threads only rest when throttled, for the remainder of
+// each cycle.
+func TestDutyCycle(t *testing.T) {
+	for _, throttle := range []float64{0, 1, 1.5} {
+		if newDutyCycle(throttle) != nil {
+			t.Errorf("throttle %v: duty cycle created at full speed", throttle)
+		}
+	}
+	cycle := newDutyCycle(0.25)
+	if cycle.busy != dutyCyclePeriod/4 || cycle.idle != dutyCyclePeriod*3/4 {
+		t.Fatalf("cycle mismatch: have %v/%v, want %v/%v", cycle.busy, cycle.idle, dutyCyclePeriod/4, dutyCyclePeriod*3/4)
+	}
+	if cycle.due() {
+		t.Fatalf("cycle due right away")
+	}
+	cycle.start = time.Now().Add(-cycle.busy)
+	if !cycle.due() {
+		t.Fatalf("cycle not due after its hashing period")
+	}
+	start := time.Now()
+	cycle.rest(nil)
+	if elapsed := time.Since(start); elapsed < cycle.idle {
+		t.Fatalf("rested too little: have %v, want %v", elapsed, cycle.idle)
+	}
+	if cycle.due() {
+		t.Fatalf("cycle due right after resting")
+	}
+	// Aborting the sealing cuts the rest short
+	abort := make(chan struct{})
+	close(abort)
+
+	cycle = newDutyCycle(0.01)
+	start = time.Now()
+	cycle.rest(abort)
+	if elapsed := time.Since(start); elapsed >= cycle.idle {
+		t.Fatalf("aborted rest too long: %v", elapsed)
+	}
+}
+
+// Tests that throttled sealing threads wait for the blocks being verified.
+func TestYieldToVerification(t *testing.T) {
+	aquahash := NewTester()
+
+	atomic.AddInt32(&aquahash.verifying, 1)
+	done := make(chan struct{})
+	go func() {
+		aquahash.yield(nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatalf("yielded thread resumed during verification")
+	case <-time.After(5 * yieldInterval):
+	}
+	atomic.AddInt32(&aquahash.verifying, -1)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("yielded thread not resumed after verification")
+	}
+	// Aborting the sealing stops yielding
+	atomic.AddInt32(&aquahash.verifying, 1)
+	abort := make(chan struct{})
+	close(abort)
+	aquahash.yield(abort)
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package misc
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+var (
+	errMissingBaseFee    = errors.New("missing base fee")
+	errUnexpectedBaseFee = errors.New("base fee before the fee market")
+)
+
+// VerifyBaseFee verifies that the header has no base fee before the fee market,
+// and the one CalcBaseFee derives from its parent after.
+func VerifyBaseFee(config *params.ChainConfig, parent, header *types.Header) error {
+	if !config.IsFeeMarket(header.Number) {
+		if header.BaseFee != nil {
+			return errUnexpectedBaseFee
+		}
+		return nil
+	}
+	if header.BaseFee == nil {
+		return errMissingBaseFee
+	}
+	if want := CalcBaseFee(config, parent); header.BaseFee.Cmp(want) != 0 {
+		return fmt.Errorf("invalid base fee: have %v, want %v", header.BaseFee, want)
+	}
+	return nil
+}
+
+// CalcBaseFee returns the base fee of the child of parent in the fee market. It
+// starts at params.InitialBaseFee and moves by up to an eighth from block to
+// block, up if the parent used more than half its gas limit, down if less.
+func CalcBaseFee(config *params.ChainConfig, parent *types.Header) *big.Int {
+	if !config.IsFeeMarket(parent.Number) || parent.BaseFee == nil {
+		return new(big.Int).SetUint64(params.InitialBaseFee)
+	}
+	target := parent.GasLimit / params.ElasticityMultiplier
+	if parent.GasUsed == target || target == 0 {
+		return new(big.Int).Set(parent.BaseFee)
+	}
+	var (
+		used  = new(big.Int).SetUint64(parent.GasUsed)
+		limit = new(big.Int).SetUint64(target)
+		denom = new(big.Int).SetUint64(params.BaseFeeChangeDenominator)
+		delta = new(big.Int)
+	)
+	if parent.GasUsed > target {
+		// Busier than targeted, raise the base fee by at least one
+		delta.Mul(parent.BaseFee, used.Sub(used, limit))
+		delta.Div(delta.Div(delta, limit), denom)
+		if delta.Sign() == 0 {
+			delta.SetUint64(1)
+		}
+		return delta.Add(parent.BaseFee, delta)
+	}
+	// Quieter than targeted, lower the base fee
+	delta.Mul(parent.BaseFee, limit.Sub(limit, used))
+	delta.Div(delta.Div(delta, new(big.Int).SetUint64(target)), denom)
+	if fee := delta.Sub(parent.BaseFee, delta); fee.Sign() > 0 {
+		return fee
+	}
+	return new(big.Int)
+}
@@ -37,7 +37,7 @@ import (
 )
 
 var (
-	passwordRegexp = regexp.MustCompile(`personal.[nus]`)
+	passwordRegexp = regexp.MustCompile(`personal.[nus]|session.open`)
 	onlyWhitespace = regexp.MustCompile(`^\s*$`)
 	exit           = regexp.MustCompile(`^\s*exit\s*;*\s*$`)
 	help           = regexp.MustCompile(`^\s*help\s*;*\s*$`)
@@ -67,6 +67,20 @@ Web links:
 
 `
 
+// helpers are the JavaScript functions the console offers on top of the APIs.
+const helpers = `
+function balance() {
+	var total = 0;
+	for (var i in aqua.accounts) {
+		var account = aqua.accounts[i];
+		var amount = web3.fromWei(aqua.getBalance(account), "aqua");
+		total += parseFloat(amount);
+		console.log("  aqua.accounts[" + i + "]: \t" + account + " \tbalance: " + amount + " AQUA");
+	}
+	console.log("  Total balance: " + total + " AQUA");
+};
+`
+
 const logo = `                              _           _
   __ _  __ _ _   _  __ _  ___| |__   __ _(_)_ __
  / _ '|/ _' | | | |/ _' |/ __| '_ \ / _' | | '_ \
@@ -161,9 +175,10 @@ func (c *Console) init(preload []string) error {
 	if err != nil {
 		return fmt.Errorf("api modules: %v", err)
 	}
-	flatten := "var aqua = web3.aqua; var personal = web3.personal; "
+	// The eth namespace is an alias of aqua, for scripts written against web3
+	flatten := "var aqua = web3.aqua; var eth = web3.eth = web3.aqua; var personal = web3.personal; "
 	for api := range apis {
-		if api == "web3" {
+		if api == "web3" || api == "eth" {
 			continue // manually mapped or ignore
 		}
 		if file, ok := web3ext.Modules[api]; ok {
@@ -180,6 +195,9 @@ func (c *Console) init(preload []string) error {
 	if _, err = c.jsre.Run(flatten); err != nil {
 		return fmt.Errorf("namespace flattening: %v", err)
 	}
+	if _, err = c.jsre.Run(helpers); err != nil {
+		return fmt.Errorf("helpers: %v", err)
+	}
 	// Initialize the global name register (disabled for now)
 	//c.jsre.Run(`var GlobalRegistrar = aqua.contract(` + registrar.GlobalRegistrarAbi + `);   registrar = GlobalRegistrar.at("` + registrar.GlobalRegistrarAddr + `");`)
 
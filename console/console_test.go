@@ -193,6 +193,60 @@ func TestEvaluate(t *testing.T) {
 	}
 }
 
+// Tests that failing statements are reported to the caller.
+func TestEvaluateError(t *testing.T) {
+	tester := newTester(t, nil)
+	defer tester.Close(t)
+
+	if err := tester.console.Evaluate("2 + 2"); err != nil {
+		t.Fatalf("statement evaluation failed: %v", err)
+	}
+	if err := tester.console.Evaluate("nosuch.thing()"); err == nil {
+		t.Fatalf("failing statement evaluated without error")
+	}
+}
+
+// Tests that the web3 compatible bindings and the helpers are available.
+func TestBindings(t *testing.T) {
+	tester := newTester(t, nil)
+	defer tester.Close(t)
+
+	tests := map[string]string{
+		"eth === aqua":                  "true",
+		"web3.eth === web3.aqua":        "true",
+		"typeof balance":                `"function"`,
+		"typeof personal.unlockAccount": `"function"`,
+		"typeof admin.nodeInfo":         `"object"`,
+	}
+	for statement, want := range tests {
+		tester.output.Reset()
+		tester.console.Evaluate(statement)
+		if output := tester.output.String(); !strings.Contains(output, want) {
+			t.Errorf("%s: have %s, want %s", statement, output, want)
+		}
+	}
+}
+
+// Tests that the unit helpers convert between wei, gwei and aqua.
+func TestUnitHelpers(t *testing.T) {
+	tester := newTester(t, nil)
+	defer tester.Close(t)
+
+	tests := map[string]string{
+		"aqua.toAqua('1500000000000000000')": `"1.5"`,
+		"aqua.toAqua(3, 'gwei')":             `"0.000000003"`,
+		"aqua.toWei('0.25')":                 `"250000000000000000"`,
+		"aqua.toWei(7, 'gwei')":              `"7000000000"`,
+	}
+	for statement, want := range tests {
+		tester.output.Reset()
+		tester.console.Evaluate(statement)
+		if output := tester.output.String(); !strings.Contains(output, want) {
+			t.Errorf("%s: have %s, want %s", statement, output, want)
+		}
+	}
+}
+
 // Tests that the console can be used in interactive mode.
 func TestInteractive(t *testing.T) {
 	// Create a tester and run an interactive console in the background
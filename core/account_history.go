@@ -0,0 +1,285 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/rlp"
+)
+
+var (
+	accountChangesPrefix  = []byte("AccountChanges-")    // accountChangesPrefix + num (uint64 big endian) + hash -> account changes of the block
+	accountHistoryPrefix  = []byte("AccountHistory-")    // accountHistoryPrefix + address + num (uint64 big endian) -> canonical account change
+	accountHistoryTailKey = []byte("AccountHistoryTail") // accountHistoryTailKey -> oldest block with indexed account changes (uint64 big endian)
+
+	// ErrAccountNotIndexed is returned if the state of an account at a block is
+	// out of the range covered by the account history index.
+	ErrAccountNotIndexed = errors.New("account history not indexed")
+
+	errStopIteration = errors.New("stop iteration")
+)
+
+// AccountState is the balance and nonce of an account.
+type AccountState struct {
+	Balance *big.Int
+	Nonce   uint64
+}
+
+// AccountChange is the change of the balance or nonce of an account in a block.
+type AccountChange struct {
+	Address common.Address
+	Before  AccountState // State after the parent block
+	After   AccountState // State after the block
+}
+
+// accountHistoryEntry is the change of an account in a canonical block.
+type accountHistoryEntry struct {
+	Hash   common.Hash // Block of the change, for detecting stale entries
+	Before AccountState
+	After  AccountState
+}
+
+// GetAccountHistoryTail retrieves the number of the oldest block whose account
+// changes are indexed, returning false if the index isn't maintained.
+func GetAccountHistoryTail(db DatabaseReader) (uint64, bool) {
+	enc, _ := db.Get(accountHistoryTailKey)
+	if len(enc) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(enc), true
+}
+
+// WriteAccountHistoryTail stores the number of the oldest block whose account
+// changes are indexed.
+func WriteAccountHistoryTail(db aquadb.Putter, number uint64) error {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], number)
+	return db.Put(accountHistoryTailKey, enc[:])
+}
+
+// DeleteAccountHistoryTail marks the account history index as not maintained,
+// discarding it once indexing resumes since it would miss the changes between.
+func DeleteAccountHistoryTail(db aquadb.Database) error {
+	return db.Delete(accountHistoryTailKey)
+}
+
+func accountChangesKey(number uint64, hash common.Hash) []byte {
+	return append(append(append([]byte{}, accountChangesPrefix...), encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+func accountHistoryKey(addr common.Address, number uint64) []byte {
+	return append(append(append([]byte{}, accountHistoryPrefix...), addr.Bytes()...), encodeBlockNumber(number)...)
+}
+
+// GetAccountChanges retrieves the account changes of a block, returning false if
+// they weren't recorded when it was imported.
+func GetAccountChanges(db DatabaseReader, hash common.Hash, number uint64) ([]AccountChange, bool) {
+	data, _ := db.Get(accountChangesKey(number, hash))
+	if len(data) == 0 {
+		return nil, false
+	}
+	var changes []AccountChange
+	if err := rlp.DecodeBytes(data, &changes); err != nil {
+		log.Error("Invalid account changes RLP", "hash", hash, "err", err)
+		return nil, false
+	}
+	return changes, true
+}
+
+// WriteAccountChanges stores the account changes of a block, an empty list
+// recording that none changed.
+func WriteAccountChanges(db aquadb.Putter, hash common.Hash, number uint64, changes []AccountChange) error {
+	if changes == nil {
+		changes = []AccountChange{}
+	}
+	data, err := rlp.EncodeToBytes(changes)
+	if err != nil {
+		return err
+	}
+	return db.Put(accountChangesKey(number, hash), data)
+}
+
+// writeAccountHistory indexes the account changes of a block becoming canonical.
+func writeAccountHistory(db aquadb.Putter, hash common.Hash, number uint64, changes []AccountChange) error {
+	for _, change := range changes {
+		data, err := rlp.EncodeToBytes(accountHistoryEntry{Hash: hash, Before: change.Before, After: change.After})
+		if err != nil {
+			return err
+		}
+		if err := db.Put(accountHistoryKey(change.Address, number), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteAccountHistory unindexes the account changes of a block leaving the
+// canonical chain.
+func deleteAccountHistory(db aquadb.Database, number uint64, changes []AccountChange) error {
+	for _, change := range changes {
+		if err := db.Delete(accountHistoryKey(change.Address, number)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accountChanges collects the balance and nonce changes of the accounts modified
+// by a block, whose resulting state is not committed yet. Accounts only touched
+// are left out.
+func (bc *BlockChain) accountChanges(block *types.Block, statedb *state.StateDB) ([]AccountChange, error) {
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	before, err := state.New(parent.Root, bc.stateCache)
+	if err != nil {
+		return nil, err
+	}
+	changes := []AccountChange{}
+	for _, addr := range statedb.DirtyAccounts() {
+		change := AccountChange{
+			Address: addr,
+			Before:  AccountState{Balance: before.GetBalance(addr), Nonce: before.GetNonce(addr)},
+			After:   AccountState{Balance: statedb.GetBalance(addr), Nonce: statedb.GetNonce(addr)},
+		}
+		if change.Before.Nonce != change.After.Nonce || change.Before.Balance.Cmp(change.After.Balance) != 0 {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// indexAccountHistory indexes the account changes of a block becoming the head
+// of the chain, starting the index with it if not maintained yet.
+func (bc *BlockChain) indexAccountHistory(db aquadb.Putter, block *types.Block, changes []AccountChange) error {
+	if _, ok := GetAccountHistoryTail(bc.db); !ok {
+		log.Info("Starting account history index", "number", block.NumberU64())
+		if err := WriteAccountHistoryTail(db, block.NumberU64()); err != nil {
+			return err
+		}
+	}
+	return writeAccountHistory(db, block.Hash(), block.NumberU64(), changes)
+}
+
+// reindexAccountHistory moves the account history index from the dropped to the
+// added blocks of a reorg, both ordered from the newest. Added blocks imported
+// while the index wasn't maintained move its tail past them.
+func (bc *BlockChain) reindexAccountHistory(dropped, added types.Blocks) error {
+	tail, ok := GetAccountHistoryTail(bc.db)
+	if !ok {
+		return nil
+	}
+	for _, block := range dropped {
+		if changes, ok := GetAccountChanges(bc.db, block.Hash(), block.NumberU64()); ok {
+			if err := deleteAccountHistory(bc.db, block.NumberU64(), changes); err != nil {
+				return err
+			}
+		}
+	}
+	for i, block := range added {
+		if i == 0 {
+			continue // The new head, indexed along with its import
+		}
+		changes, ok := GetAccountChanges(bc.db, block.Hash(), block.NumberU64())
+		if !ok {
+			if block.NumberU64() >= tail {
+				log.Warn("Account history missing reorged block, moving tail", "number", block.NumberU64(), "hash", block.Hash())
+				tail = block.NumberU64() + 1
+				if err := WriteAccountHistoryTail(bc.db, tail); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := writeAccountHistory(bc.db, block.Hash(), block.NumberU64(), changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AccountAt retrieves the balance and nonce of an account after the canonical
+// block of the given number from the account history index, without needing its
+// state. ErrAccountNotIndexed is returned if the block is out of the indexed
+// range, or the index entries involved got stale.
+func (bc *BlockChain) AccountAt(addr common.Address, number uint64) (*AccountState, error) {
+	tail, ok := GetAccountHistoryTail(bc.db)
+	if !ok || number+1 < tail {
+		return nil, ErrAccountNotIndexed
+	}
+	head := bc.CurrentBlock()
+	if number > head.NumberU64() {
+		return nil, ErrAccountNotIndexed
+	}
+	iteratee, ok := bc.db.(aquadb.Iteratee)
+	if !ok {
+		return nil, ErrAccountNotIndexed
+	}
+	// Find the changes closest to the block on either side. Without any, the
+	// account is the same at the head of the chain.
+	var (
+		last, next       *accountHistoryEntry
+		lastNum, nextNum uint64
+		prefix           = accountHistoryKey(addr, 0)[:len(accountHistoryPrefix)+common.AddressLength]
+	)
+	err := iteratee.IteratePrefix(prefix, func(key, value []byte) error {
+		num := binary.BigEndian.Uint64(key[len(prefix):])
+		if num < tail {
+			return nil
+		}
+		entry := new(accountHistoryEntry)
+		if err := rlp.DecodeBytes(value, entry); err != nil {
+			return err
+		}
+		if num <= number {
+			last, lastNum = entry, num
+			return nil
+		}
+		next, nextNum = entry, num
+		return errStopIteration
+	})
+	if err != nil && err != errStopIteration {
+		return nil, err
+	}
+	switch {
+	case last != nil:
+		if GetCanonicalHash(bc.db, lastNum) != last.Hash {
+			return nil, ErrAccountNotIndexed
+		}
+		return &last.After, nil
+	case next != nil:
+		if GetCanonicalHash(bc.db, nextNum) != next.Hash {
+			return nil, ErrAccountNotIndexed
+		}
+		return &next.Before, nil
+	}
+	statedb, err := bc.StateAt(head.Root())
+	if err != nil {
+		return nil, ErrAccountNotIndexed
+	}
+	return &AccountState{Balance: statedb.GetBalance(addr), Nonce: statedb.GetNonce(addr)}, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that the account history index serves the balances and nonces of the
+// accounts at every canonical block, following reorgs, and that it's dropped
+// once not maintained.
+func TestAccountHistory(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		db, _   = aquadb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+
+		accounts = []common.Address{addr, {1}, {2}, {3}, {0xc0}}
+	)
+	transfer := func(gen *BlockGen, to common.Address) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), to, big.NewInt(1), params.TxGas, nil, nil), signer, key)
+		gen.AddTx(tx)
+	}
+	blocks, _ := GenerateChain(gspec.Config, genesis, aquahash.NewFaker(), db, 32, func(i int, gen *BlockGen) {
+		if i%2 == 0 {
+			transfer(gen, common.Address{1})
+		}
+	})
+	fork, _ := GenerateChain(gspec.Config, blocks[9], aquahash.NewFaker(), db, 25, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(common.Address{0xc0})
+		if i%3 == 0 {
+			transfer(gen, common.Address{3})
+		}
+	})
+	cacheConfig := &CacheConfig{Disabled: true, TrieNodeLimit: 256, TrieTimeLimit: 5 * time.Minute, AccountHistory: true}
+	chain, err := NewBlockChain(db, cacheConfig, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	// check compares the index with the archived state of every canonical block
+	check := func(stage string) {
+		t.Helper()
+		for number := uint64(0); number <= chain.CurrentBlock().NumberU64(); number++ {
+			statedb, err := chain.StateAt(chain.GetHeaderByNumber(number).Root)
+			if err != nil {
+				t.Fatalf("%s: block #%d: missing state: %v", stage, number, err)
+			}
+			for _, account := range accounts {
+				have, err := chain.AccountAt(account, number)
+				if err != nil {
+					t.Fatalf("%s: block #%d: account %x: failed to look up: %v", stage, number, account, err)
+				}
+				if want := statedb.GetBalance(account); have.Balance.Cmp(want) != 0 {
+					t.Errorf("%s: block #%d: account %x: balance mismatch: have %v, want %v", stage, number, account, have.Balance, want)
+				}
+				if want := statedb.GetNonce(account); have.Nonce != want {
+					t.Errorf("%s: block #%d: account %x: nonce mismatch: have %d, want %d", stage, number, account, have.Nonce, want)
+				}
+			}
+		}
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if tail, ok := GetAccountHistoryTail(db); !ok || tail != 1 {
+		t.Fatalf("tail mismatch: have %d (%v), want 1", tail, ok)
+	}
+	check("import")
+
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	if head := chain.CurrentBlock().Hash(); head != fork[len(fork)-1].Hash() {
+		t.Fatalf("head mismatch: have %x, want %x", head, fork[len(fork)-1].Hash())
+	}
+	check("reorg")
+	chain.Stop()
+
+	// Restarting without the index drops it, the changes missed meanwhile
+	// making it unusable
+	chain, _ = NewBlockChain(db, &CacheConfig{Disabled: true, TrieNodeLimit: 256, TrieTimeLimit: 5 * time.Minute}, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	defer chain.Stop()
+
+	if _, ok := GetAccountHistoryTail(db); ok {
+		t.Errorf("account history tail kept without the index")
+	}
+	if _, err := chain.AccountAt(addr, 5); err != ErrAccountNotIndexed {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrAccountNotIndexed)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/consensus/aquahash"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/core/vm"
@@ -80,6 +81,38 @@ func TestHeaderVerification(t *testing.T) {
 	}
 }
 
+// Tests that batch header verification rejects chains conflicting with a trusted
+// checkpoint and accepts the ones matching it.
+func TestHeaderCheckpointVerification(t *testing.T) {
+	var (
+		testdb, _ = aquadb.NewMemDatabase()
+		gspec     = &Genesis{Config: params.TestChainConfig}
+		genesis   = gspec.MustCommit(testdb)
+		blocks, _ = GenerateChain(params.TestChainConfig, genesis, aquahash.NewFaker(), testdb, 8, nil)
+	)
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	chain, _ := NewBlockChain(testdb, nil, params.TestChainConfig, aquahash.NewFaker(), vm.Config{})
+	defer chain.Stop()
+
+	for i, checkpoint := range []common.Hash{blocks[3].Hash(), {0x01}} {
+		engine := aquahash.New(aquahash.Config{
+			PowMode:     aquahash.ModeFake,
+			Checkpoints: params.Checkpoints{{Number: 4, Hash: checkpoint}},
+		})
+		_, results := engine.VerifyHeaders(chain, headers, make([]bool, len(headers)))
+
+		for j := range headers {
+			result := <-results
+			if fail := i == 1 && j == 3; (result != nil) != fail {
+				t.Errorf("test %d, header %d: validity mismatch: have %v, want failure %v", i, j, result, fail)
+			}
+		}
+	}
+}
+
 // Tests that concurrent header verification works, for both good and bad blocks.
 func TestHeaderConcurrentVerification2(t *testing.T)  { testHeaderConcurrentVerification(t, 2) }
 func TestHeaderConcurrentVerification8(t *testing.T)  { testHeaderConcurrentVerification(t, 8) }
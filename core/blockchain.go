@@ -22,16 +22,17 @@ import (
 	"fmt"
 	"io"
 	"math/big"
-	mrand "math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/aquanetwork/aquachain/aquadb"
 	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
 	"github.com/aquanetwork/aquachain/common/mclock"
 	"github.com/aquanetwork/aquachain/consensus"
 	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/state/snapshot"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/core/vm"
 	"github.com/aquanetwork/aquachain/crypto"
@@ -47,6 +48,8 @@ import (
 
 var (
 	blockInsertTimer = metrics.NewRegisteredTimer("chain/inserts", nil)
+	tieCounter       = metrics.NewRegisteredCounter("chain/ties", nil)          // Blocks with the same total difficulty as the head
+	tieSwitchCounter = metrics.NewRegisteredCounter("chain/ties/switched", nil) // Ties won by the competing block
 
 	ErrNoGenesis = errors.New("Genesis not found in chain")
 )
@@ -69,6 +72,11 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+	TxLookupLimit uint64        // Number of recent blocks to keep transaction lookup entries of, zero for all
+	Snapshot      bool          // Whether to maintain a flat snapshot of the state for fast reads
+	NoPrefetch    bool          // Whether to skip pre-caching the state of the next block during imports
+
+	AccountHistory bool // Whether to index the balance and nonce changes of the accounts
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -95,6 +103,7 @@ type BlockChain struct {
 
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
+	reorgFeed     event.Feed
 	chainFeed     event.Feed
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
@@ -111,6 +120,7 @@ type BlockChain struct {
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache   state.Database // State database to reuse between imports (contains state cache)
+	snaps        *snapshot.Tree // Snapshot tree for fast state reads, nil if disabled
 	bodyCache    *lru.Cache     // Cache for the most recent block bodies
 	bodyRLPCache *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
 	blockCache   *lru.Cache     // Cache for the most recent entire blocks
@@ -122,14 +132,27 @@ type BlockChain struct {
 	procInterrupt int32          // interrupt signaler for block processing
 	wg            sync.WaitGroup // chain processing wait group for shutting down
 
-	engine    consensus.Engine
-	processor Processor // block processor interface
-	validator Validator // block and state validator interface
-	vmConfig  vm.Config
+	engine     consensus.Engine
+	processor  Processor  // block processor interface
+	prefetcher Prefetcher // block state prefetcher interface
+	validator  Validator  // block and state validator interface
+	vmConfig   vm.Config
 
 	badBlocks *lru.Cache // Bad block cache
+
+	tieBreak TieBreak                // Rule choosing between head candidates of equal total difficulty
+	isLocal  func(*types.Block) bool // Reports whether a block was mined locally, for TieBreakPreferLocal
+
+	forensicsDir string // Directory of the forensic bundles of diverging blocks, empty if disabled
+
+	senderFilter SenderFilter // Permits the senders of block transactions, nil if unrestricted
 }
 
+// SenderFilter decides whether an account may send a transaction included in
+// the block of the given header. Its decision must only depend on the chain, as
+// blocks it refuses are invalid.
+type SenderFilter func(from common.Address, header *types.Header) bool
+
 // NewBlockChain returns a fully initialised block chain using information
 // available in the database. It initialises the default AquaChain Validator and
 // Processor.
@@ -159,10 +182,12 @@ func NewBlockChain(db aquadb.Database, cacheConfig *CacheConfig, chainConfig *pa
 		futureBlocks: futureBlocks,
 		engine:       engine,
 		vmConfig:     vmConfig,
+		tieBreak:     TieBreakRandom,
 		badBlocks:    badBlocks,
 	}
 	bc.SetValidator(NewBlockValidator(chainConfig, bc, engine))
 	bc.SetProcessor(NewStateProcessor(chainConfig, bc, engine))
+	bc.prefetcher = newStatePrefetcher(chainConfig, bc)
 
 	var err error
 	bc.hc, err = NewHeaderChain(db, chainConfig, engine, bc.getProcInterrupt)
@@ -176,6 +201,10 @@ func NewBlockChain(db aquadb.Database, cacheConfig *CacheConfig, chainConfig *pa
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	// Heal the gaps crashes may have left in the recent canonical chain
+	if err := bc.checkChain(chainCheckDepth); err != nil {
+		return nil, err
+	}
 	// Check the current state of the block hashes and make sure that we do not have any of the bad blocks in our chain
 	for hash := range BadHashes {
 		if header := bc.GetHeaderByHash(hash); header != nil {
@@ -189,8 +218,27 @@ func NewBlockChain(db aquadb.Database, cacheConfig *CacheConfig, chainConfig *pa
 			}
 		}
 	}
+	if cacheConfig.AccountHistory {
+		if _, ok := db.(aquadb.Iteratee); !ok {
+			return nil, errors.New("account history database not iterable")
+		}
+	} else if _, ok := GetAccountHistoryTail(db); ok {
+		log.Warn("Account history index discontinued, rebuilt from the next block if reenabled")
+		if err := DeleteAccountHistoryTail(db); err != nil {
+			return nil, err
+		}
+	}
+	if cacheConfig.Snapshot {
+		if bc.snaps, err = snapshot.New(db, bc.stateCache.TrieDB(), bc.CurrentBlock().Root()); err != nil {
+			return nil, err
+		}
+	}
 	// Take ownership of this particular state
 	go bc.update()
+
+	heads := make(chan ChainHeadEvent, chainHeadChanSize)
+	bc.wg.Add(1)
+	go bc.maintainTxIndex(heads, bc.SubscribeChainHeadEvent(heads))
 	return bc, nil
 }
 
@@ -261,14 +309,64 @@ func (bc *BlockChain) loadLastState() error {
 // though, the head may be further rewound if block bodies are missing (non-archive
 // nodes after a fast sync).
 func (bc *BlockChain) SetHead(head uint64) error {
+	_, err := bc.Rewind(head)
+	return err
+}
+
+// Rewind rewinds the local chain to a new head like SetHead, returning the
+// transactions of the rewound canonical blocks, oldest first, to be reinjected
+// into the transaction pool. The head is rewound further to the newest block
+// whose state is available, as pruning nodes don't keep it for every block. The
+// lookup entries and receipts of the rewound blocks are deleted along with them,
+// and the new head is announced for the derived indexes to follow.
+func (bc *BlockChain) Rewind(head uint64) (types.Transactions, error) {
+	oldHead := bc.CurrentBlock().Hash()
+	rewound, err := bc.rewind(head)
+
+	var txs types.Transactions
+	for i := len(rewound) - 1; i >= 0; i-- {
+		txs = append(txs, rewound[i]...)
+	}
+	if err == nil {
+		if block := bc.CurrentBlock(); block.Hash() != oldHead {
+			bc.chainHeadFeed.Send(ChainHeadEvent{Block: block})
+		}
+	}
+	return txs, err
+}
+
+// rewind implements Rewind, returning the transactions of the rewound blocks,
+// newest block first.
+func (bc *BlockChain) rewind(head uint64) ([]types.Transactions, error) {
 	log.Warn("Rewinding blockchain", "target", head)
 
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
 
+	// Look for the newest block with state not above the target, unless the full
+	// chain doesn't reach it (fast or header sync)
+	if currentBlock := bc.CurrentBlock(); currentBlock != nil && currentBlock.NumberU64() > head {
+		for ; head > 0; head-- {
+			block := bc.GetBlockByNumber(head)
+			if block == nil {
+				break
+			}
+			if _, err := state.New(block.Root(), bc.stateCache); err == nil {
+				break
+			}
+		}
+	}
 	// Rewind the header chain, deleting all block bodies until then
+	var rewound []types.Transactions
 	delFn := func(hash common.Hash, num uint64) {
+		if body := GetBodyNoVersion(bc.db, hash, num); body != nil {
+			for _, tx := range body.Transactions {
+				DeleteTxLookupEntry(bc.db, tx.Hash())
+			}
+			rewound = append(rewound, body.Transactions)
+		}
 		DeleteBody(bc.db, hash, num)
+		DeleteBlockReceipts(bc.db, hash, num)
 	}
 	bc.hc.SetHead(head, delFn)
 	currentHeader := bc.hc.CurrentHeader()
@@ -308,7 +406,7 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	if err := WriteHeadFastBlockHash(bc.db, currentFastBlock.Hash()); err != nil {
 		log.Crit("Failed to reset head fast block", "err", err)
 	}
-	return bc.loadLastState()
+	return rewound, bc.loadLastState()
 }
 
 // FastSyncCommitHead sets the current head block to the one defined by the hash
@@ -350,6 +448,13 @@ func (bc *BlockChain) CurrentFastBlock() *types.Block {
 	return bc.currentFastBlock.Load().(*types.Block)
 }
 
+// SetSenderFilter restricts the transactions of imported and mined blocks to
+// the ones sent by accounts permitted by the filter. It must be set before any
+// block is processed.
+func (bc *BlockChain) SetSenderFilter(filter SenderFilter) {
+	bc.senderFilter = filter
+}
+
 // SetProcessor sets the processor required for making state modifications.
 func (bc *BlockChain) SetProcessor(processor Processor) {
 	bc.procmu.Lock()
@@ -357,6 +462,17 @@ func (bc *BlockChain) SetProcessor(processor Processor) {
 	bc.processor = processor
 }
 
+// SetTieBreak sets the rule choosing whether a block with the same total
+// difficulty as the current head replaces it. The isLocal callback reports
+// whether a block was mined by the local node and may be nil.
+func (bc *BlockChain) SetTieBreak(rule TieBreak, isLocal func(*types.Block) bool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	bc.tieBreak = rule
+	bc.isLocal = isLocal
+}
+
 // SetValidator sets the validator which is used to validate incoming blocks.
 func (bc *BlockChain) SetValidator(validator Validator) {
 	bc.procmu.Lock()
@@ -385,7 +501,12 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
-	return state.New(root, bc.stateCache)
+	return state.NewWithSnapshots(root, bc.stateCache, bc.snaps)
+}
+
+// StateCache returns the caching database underpinning the blockchain instance.
+func (bc *BlockChain) StateCache() state.Database {
+	return bc.stateCache
 }
 
 // Reset purges the entire blockchain, restoring it to its genesis state.
@@ -453,18 +574,26 @@ func (bc *BlockChain) ExportN(w io.Writer, first uint64, last uint64) error {
 	}
 	log.Info("Exporting batch of blocks", "count", last-first+1)
 
+	var (
+		enc             = rlp.NewStreamEncoder(w)
+		start, reported = time.Now(), time.Now()
+	)
 	for nr := first; nr <= last; nr++ {
 		block := bc.GetBlockByNumber(nr)
 		if block == nil {
 			return fmt.Errorf("export failed on #%d: not found", nr)
 		}
 
-		if err := block.EncodeRLP(w); err != nil {
+		if err := enc.Encode(block); err != nil {
 			return err
 		}
+		if time.Since(reported) >= statsReportLimit {
+			log.Info("Exporting blocks", "exported", nr-first+1, "number", nr, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
 	}
 
-	return nil
+	return enc.Flush()
 }
 
 // insert injects a new head block into the current block chain. This method
@@ -656,6 +785,14 @@ func (bc *BlockChain) Stop() {
 
 	bc.wg.Wait()
 
+	// Flatten the snapshot into its disk layer, so it's loaded for the head on
+	// restart instead of regenerated
+	if bc.snaps != nil {
+		if err := bc.snaps.Cap(bc.CurrentBlock().Root(), 0); err != nil {
+			log.Error("Failed to flatten state snapshot", "err", err)
+		}
+		bc.snaps.Release()
+	}
 	// Ensure the state of a recent block is also stored to disk before exiting.
 	// We're writing three different states to catch different restart scenarios:
 	//  - HEAD:     So we don't need to reprocess any blocks in the general case
@@ -908,6 +1045,16 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	if err := WriteBlock(batch, block); err != nil {
 		return NonStatTy, err
 	}
+	// Record the account changes before committing the state flushes them
+	var changes []AccountChange
+	if bc.cacheConfig.AccountHistory {
+		if changes, err = bc.accountChanges(block, state); err != nil {
+			return NonStatTy, err
+		}
+		if err := WriteAccountChanges(batch, block.Hash(), block.NumberU64(), changes); err != nil {
+			return NonStatTy, err
+		}
+	}
 	root, err := state.Commit(bc.chainConfig.IsEIP158(block.Number()))
 	if err != nil {
 		return NonStatTy, err
@@ -973,8 +1120,13 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	reorg := externTd.Cmp(localTd) > 0
 	currentBlock = bc.CurrentBlock()
 	if !reorg && externTd.Cmp(localTd) == 0 {
-		// Split same-difficulty blocks by number, then at random
-		reorg = block.NumberU64() < currentBlock.NumberU64() || (block.NumberU64() == currentBlock.NumberU64() && mrand.Float64() < 0.5)
+		// Split same-difficulty blocks by number, then by the configured rule
+		reorg = bc.tieBreak.replaces(currentBlock, block, bc.isLocal)
+		tieCounter.Inc(1)
+		if reorg {
+			tieSwitchCounter.Inc(1)
+		}
+		log.Debug("Competing block with equal total difficulty", "number", block.NumberU64(), "hash", block.Hash(), "head", currentBlock.Hash(), "rule", bc.tieBreak, "switch", reorg)
 	}
 	if reorg {
 		// Reorganise the chain if the parent is not the head block
@@ -991,6 +1143,11 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 		if err := WritePreimages(bc.db, block.NumberU64(), state.Preimages()); err != nil {
 			return NonStatTy, err
 		}
+		if bc.cacheConfig.AccountHistory {
+			if err := bc.indexAccountHistory(batch, block, changes); err != nil {
+				return NonStatTy, err
+			}
+		}
 		status = CanonStatTy
 	} else {
 		status = SideStatTy
@@ -1023,6 +1180,10 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 // only reason this method exists as a separate one is to make locking cleaner
 // with deferred statements.
 func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*types.Log, error) {
+	// Sanity check that we have something meaningful to import
+	if len(chain) == 0 {
+		return 0, nil, nil, nil
+	}
 	// Do a sanity check that the provided chain is actually ordered and linked
 	for i := 1; i < len(chain); i++ {
 		if chain[i].NumberU64() != chain[i-1].NumberU64()+1 || chain[i].ParentHash() != chain[i-1].SetVersion(bc.chainConfig.GetBlockVersion(chain[i-1].Number())) {
@@ -1061,6 +1222,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 	abort, results := bc.engine.VerifyHeaders(bc, headers, seals)
 	defer close(abort)
 
+	// Start a parallel signature recovery (signer will fluke on fork transition, minimal perf loss)
+	senderCacher.recoverFromBlocks(types.MakeSigner(bc.chainConfig, chain[0].Number()), chain)
+
 	// Iterate over the blocks and insert when the verifier permits
 	for i, block := range chain {
 		block.SetVersion(bc.Config().GetBlockVersion(block.Number()))
@@ -1092,9 +1256,14 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 			}
 
 		case err == consensus.ErrFutureBlock:
-			// Allow up to MaxFuture second in the future blocks. If this limit is exceeded
-			// the chain is discarded and processed at a later time if given.
-			max := big.NewInt(time.Now().Unix() + maxTimeFutureBlocks)
+			// Allow up to MaxFuture second in the future blocks, or twice the allowance
+			// of the chain if larger. If this limit is exceeded the chain is discarded
+			// and processed at a later time if given.
+			future := int64(maxTimeFutureBlocks)
+			if allowed := int64(2 * bc.chainConfig.AllowedFutureBlockTime() / time.Second); allowed > future {
+				future = allowed
+			}
+			max := big.NewInt(time.Now().Unix() + future)
 			if block.Time().Cmp(max) > 0 {
 				return i, events, coalescedLogs, fmt.Errorf("future block: %v > %v", block.Time(), max)
 			}
@@ -1152,12 +1321,25 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		} else {
 			parent = chain[i-1]
 		}
-		state, err := state.New(parent.Root(), bc.stateCache)
+		// If we have a followup block, run it against the parent state meanwhile to
+		// load the state it touches, overlapping the disk reads with execution.
+		var interrupt uint32
+		if !bc.cacheConfig.NoPrefetch && i+1 < len(chain) {
+			if throwaway, err := state.NewWithSnapshots(parent.Root(), bc.stateCache, bc.snaps); err == nil {
+				// Work on a copy, the followup's version is set concurrently
+				followup := chain[i+1].WithBody(chain[i+1].Transactions(), nil)
+				followup.SetVersion(bc.Config().GetBlockVersion(followup.Number()))
+				go bc.prefetcher.Prefetch(followup, throwaway, bc.vmConfig, &interrupt)
+			}
+		}
+		state, err := state.NewWithSnapshots(parent.Root(), bc.stateCache, bc.snaps)
 		if err != nil {
+			atomic.StoreUint32(&interrupt, 1)
 			return i, events, coalescedLogs, err
 		}
 		// Process block using the parent state as reference point.
 		receipts, logs, usedGas, err := bc.processor.Process(block, state, bc.vmConfig)
+		atomic.StoreUint32(&interrupt, 1)
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
 			return i, events, coalescedLogs, err
@@ -1165,6 +1347,13 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		// Validate the state using the default validator
 		err = bc.Validator().ValidateState(block, parent, state, receipts, usedGas)
 		if err != nil {
+			if mismatch, ok := err.(*StateRootError); ok {
+				if dir, derr := bc.dumpForensics(block, parent, state, receipts, mismatch); derr != nil {
+					log.Error("Failed to dump forensic bundle", "number", block.Number(), "hash", block.Hash(), "err", derr)
+				} else {
+					mismatch.Dump = dir
+				}
+			}
 			bc.reportBlock(block, receipts, err)
 			return i, events, coalescedLogs, err
 		}
@@ -1334,6 +1523,11 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	} else {
 		log.Error("Impossible reorg, please file an issue", "oldnum", oldBlock.Number(), "oldhash", oldBlock.Hash(), "newnum", newBlock.Number(), "newhash", newBlock.Hash())
 	}
+	if bc.cacheConfig.AccountHistory {
+		if err := bc.reindexAccountHistory(oldChain, newChain); err != nil {
+			return err
+		}
+	}
 	// Insert the new chain, taking care of the proper incremental order
 	var addedTxs types.Transactions
 	for i := len(newChain) - 1; i >= 0; i-- {
@@ -1356,7 +1550,9 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		go bc.rmLogsFeed.Send(RemovedLogsEvent{deletedLogs})
 	}
 	if len(oldChain) > 0 {
+		ev := ReorgEvent{Common: commonBlock, Dropped: oldChain, Added: newChain, DroppedTxs: diff}
 		go func() {
+			bc.reorgFeed.Send(ev)
 			for _, block := range oldChain {
 				bc.chainSideFeed.Send(ChainSideEvent{Block: block})
 			}
@@ -1405,28 +1601,50 @@ func (bc *BlockChain) update() {
 type BadBlockArgs struct {
 	Hash   common.Hash   `json:"hash"`
 	Header *types.Header `json:"header"`
+	RLP    hexutil.Bytes `json:"rlp"`    // Full block, to replay its import
+	Reason string        `json:"reason"` // Why the import failed
+	Time   time.Time     `json:"time"`   // When the import failed
+}
+
+// badBlock is a block whose import failed, kept for diagnosis.
+type badBlock struct {
+	block  *types.Block
+	reason error
+	time   time.Time
 }
 
-// BadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
+// BadBlocks returns a list of the last 'bad blocks' that the client has seen on the network,
+// the oldest first
 func (bc *BlockChain) BadBlocks() ([]BadBlockArgs, error) {
-	headers := make([]BadBlockArgs, 0, bc.badBlocks.Len())
+	blocks := make([]BadBlockArgs, 0, bc.badBlocks.Len())
 	for _, hash := range bc.badBlocks.Keys() {
-		if hdr, exist := bc.badBlocks.Peek(hash); exist {
-			header := hdr.(*types.Header)
-			headers = append(headers, BadBlockArgs{header.Hash(), header})
+		if item, exist := bc.badBlocks.Peek(hash); exist {
+			bad := item.(*badBlock)
+			blob, err := rlp.EncodeToBytes(bad.block)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, BadBlockArgs{
+				Hash:   hash.(common.Hash),
+				Header: bad.block.Header(),
+				RLP:    blob,
+				Reason: bad.reason.Error(),
+				Time:   bad.time,
+			})
 		}
 	}
-	return headers, nil
+	return blocks, nil
 }
 
-// addBadBlock adds a bad block to the bad-block LRU cache
-func (bc *BlockChain) addBadBlock(block *types.Block) {
-	bc.badBlocks.Add(block.Header().Hash(), block.Header())
+// addBadBlock adds a bad block to the bad-block LRU cache, evicting the oldest
+// one once full
+func (bc *BlockChain) addBadBlock(block *types.Block, reason error) {
+	bc.badBlocks.Add(block.Hash(), &badBlock{block: block, reason: reason, time: time.Now()})
 }
 
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
-	bc.addBadBlock(block)
+	bc.addBadBlock(block, err)
 
 	var receiptString string
 	for _, receipt := range receipts {
@@ -1556,6 +1774,11 @@ func (bc *BlockChain) SubscribeRemovedLogsEvent(ch chan<- RemovedLogsEvent) even
 	return bc.scope.Track(bc.rmLogsFeed.Subscribe(ch))
 }
 
+// SubscribeReorgEvent registers a subscription of ReorgEvent.
+func (bc *BlockChain) SubscribeReorgEvent(ch chan<- ReorgEvent) event.Subscription {
+	return bc.scope.Track(bc.reorgFeed.Subscribe(ch))
+}
+
 // SubscribeChainEvent registers a subscription of ChainEvent.
 func (bc *BlockChain) SubscribeChainEvent(ch chan<- ChainEvent) event.Subscription {
 	return bc.scope.Track(bc.chainFeed.Subscribe(ch))
@@ -32,6 +32,7 @@ import (
 	"github.com/aquanetwork/aquachain/core/vm"
 	"github.com/aquanetwork/aquachain/crypto"
 	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/rlp"
 )
 
 // Test fork of length N starting from block i
@@ -457,6 +458,26 @@ func testBadHashes(t *testing.T, full bool) {
 	if err != ErrBlacklistedHash {
 		t.Errorf("error mismatch: have: %v, want: %v", err, ErrBlacklistedHash)
 	}
+	// Bad blocks are recorded in full, with the reason of the failure
+	if full {
+		bad, err := blockchain.BadBlocks()
+		if err != nil {
+			t.Fatalf("failed to retrieve bad blocks: %v", err)
+		}
+		if len(bad) != 1 {
+			t.Fatalf("bad block count mismatch: have %d, want 1", len(bad))
+		}
+		block := new(types.Block)
+		if err := rlp.DecodeBytes(bad[0].RLP, block); err != nil {
+			t.Fatalf("failed to decode bad block: %v", err)
+		}
+		if block.NumberU64() != 3 || bad[0].Header.Number.Uint64() != 3 {
+			t.Errorf("bad block number mismatch: have %d, want 3", block.NumberU64())
+		}
+		if bad[0].Reason != ErrBlacklistedHash.Error() {
+			t.Errorf("bad block reason mismatch: have %q, want %q", bad[0].Reason, ErrBlacklistedHash)
+		}
+	}
 }
 
 // Tests that bad hashes are detected on boot, and the chain rolled back to a
@@ -991,6 +1012,74 @@ done:
 
 }
 
+// Tests that a reorg posts the dropped and added blocks, and the transactions no
+// longer in the canonical chain.
+func TestReorgEvent(t *testing.T) {
+	var (
+		db, _   = aquadb.NewMemDatabase()
+		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{addr1: {Balance: big.NewInt(10000000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+
+	// The original chain holds a transfer, the replacement one a contract creation
+	// at the same nonce
+	chain, _ := GenerateChain(gspec.Config, genesis, aquahash.NewFaker(), db, 3, func(i int, gen *BlockGen) {
+		if i == 1 {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr1), common.Address{0xaa}, big.NewInt(1), params.TxGas, nil, nil), signer, key1)
+			gen.AddTx(tx)
+		}
+	})
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	replacement, _ := GenerateChain(gspec.Config, genesis, aquahash.NewFaker(), db, 4, func(i int, gen *BlockGen) {
+		if i == 0 {
+			tx, _ := types.SignTx(types.NewContractCreation(gen.TxNonce(addr1), new(big.Int), 1000000, new(big.Int), nil), signer, key1)
+			gen.AddTx(tx)
+		}
+		gen.SetExtra([]byte("replacement"))
+	})
+	reorgCh := make(chan ReorgEvent, 1)
+	sub := blockchain.SubscribeReorgEvent(reorgCh)
+	defer sub.Unsubscribe()
+
+	if _, err := blockchain.InsertChain(replacement); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	select {
+	case ev := <-reorgCh:
+		if ev.Common.Hash() != genesis.Hash() {
+			t.Errorf("common ancestor mismatch: have #%d, want genesis", ev.Common.NumberU64())
+		}
+		if len(ev.Dropped) != len(chain) {
+			t.Fatalf("dropped block count mismatch: have %d, want %d", len(ev.Dropped), len(chain))
+		}
+		for i, block := range ev.Dropped {
+			if want := chain[len(chain)-1-i]; block.Hash() != want.Hash() {
+				t.Errorf("dropped block %d mismatch: have #%d, want #%d", i, block.NumberU64(), want.NumberU64())
+			}
+		}
+		for i, block := range ev.Added {
+			if want := replacement[len(ev.Added)-1-i]; block.Hash() != want.Hash() {
+				t.Errorf("added block %d mismatch: have #%d, want #%d", i, block.NumberU64(), want.NumberU64())
+			}
+		}
+		if len(ev.DroppedTxs) != 1 || ev.DroppedTxs[0].Hash() != chain[1].Transactions()[0].Hash() {
+			t.Errorf("dropped transactions mismatch: have %d", len(ev.DroppedTxs))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("reorg event not posted")
+	}
+}
+
 // Tests if the canonical block can be fetched from the database during chain insertion.
 func TestCanonicalBlockRetrieval(t *testing.T) {
 	_, blockchain, err := newCanonical(aquahash.NewFaker(), 0, true)
@@ -1352,3 +1441,114 @@ func TestLargeReorgTrieGC(t *testing.T) {
 		}
 	}
 }
+
+// Tests that a chain maintaining a state snapshot serves the same state as the
+// tries, across more blocks than the snapshot keeps diff layers for, and across
+// a restart.
+func TestSnapshotChain(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		db, _   = aquadb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+		cache   = &CacheConfig{TrieNodeLimit: 256, TrieTimeLimit: 5 * time.Minute, Snapshot: true}
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, aquahash.NewFaker(), db, 80, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{byte(i % 8)}, big.NewInt(1), params.TxGas, nil, nil), signer, key)
+		gen.AddTx(tx)
+	})
+	check := func(chain *BlockChain) {
+		t.Helper()
+
+		root := chain.CurrentBlock().Root()
+		have, _ := chain.StateAt(root)
+		want, _ := state.New(root, chain.stateCache)
+		for _, a := range []common.Address{addr, {0}, {1}, {7}, {8}} {
+			if have.GetBalance(a).Cmp(want.GetBalance(a)) != 0 || have.GetNonce(a) != want.GetNonce(a) {
+				t.Errorf("account %x: have balance %v nonce %d, want balance %v nonce %d", a, have.GetBalance(a), have.GetNonce(a), want.GetBalance(a), want.GetNonce(a))
+			}
+		}
+	}
+	chain, err := NewBlockChain(db, cache, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks[:40]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	check(chain)
+	chain.Stop()
+
+	chain, _ = NewBlockChain(db, cache, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	defer chain.Stop()
+
+	if chain.snaps.Snapshot(chain.CurrentBlock().Root()) == nil {
+		t.Fatalf("snapshot not reloaded for the head state")
+	}
+	if _, err := chain.InsertChain(blocks[40:]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	check(chain)
+}
+
+// Tests that rewinding the chain unindexes the rewound blocks, returns their
+// transactions in chain order and announces the new head.
+func TestRewindTransactions(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		db, _   = aquadb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(1000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, aquahash.NewFaker(), db, 5, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{1}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		gen.AddTx(tx)
+	})
+	chain, _ := NewBlockChain(db, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	heads := make(chan ChainHeadEvent, 1)
+	sub := chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	txs, err := chain.Rewind(2)
+	if err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if head := chain.CurrentBlock().NumberU64(); head != 2 {
+		t.Fatalf("head mismatch: have %d, want 2", head)
+	}
+	if len(txs) != 3 {
+		t.Fatalf("rewound transaction count mismatch: have %d, want 3", len(txs))
+	}
+	for i, block := range blocks[2:] {
+		tx := block.Transactions()[0]
+		if txs[i].Hash() != tx.Hash() {
+			t.Errorf("rewound transaction %d mismatch: have %x, want %x", i, txs[i].Hash(), tx.Hash())
+		}
+		if hash, _, _ := GetTxLookupEntry(db, tx.Hash()); hash != (common.Hash{}) {
+			t.Errorf("transaction %d still indexed in block %x", i, hash)
+		}
+		if receipts := GetBlockReceipts(db, block.Hash(), block.NumberU64()); receipts != nil {
+			t.Errorf("receipts of block %d not deleted", block.NumberU64())
+		}
+	}
+	if hash, _, _ := GetTxLookupEntry(db, blocks[1].Transactions()[0].Hash()); hash != blocks[1].Hash() {
+		t.Errorf("remaining transaction lookup mismatch: have %x, want %x", hash, blocks[1].Hash())
+	}
+	select {
+	case ev := <-heads:
+		if ev.Block.NumberU64() != 2 {
+			t.Errorf("announced head mismatch: have %d, want 2", ev.Block.NumberU64())
+		}
+	default:
+		t.Errorf("new head not announced")
+	}
+}
@@ -180,7 +180,9 @@ func (c *ChainIndexer) eventLoop(currentHeader *types.Header, events chan ChainE
 	defer sub.Unsubscribe()
 	currentHeader.Version = c.config.GetBlockVersion(currentHeader.Number)
 
-	// Fire the initial new head event to start any outstanding processing
+	// Drop the sections rewound meanwhile and fire the initial new head event to
+	// start any outstanding processing
+	c.verifyLastHead(currentHeader.Number.Uint64())
 	c.newHead(currentHeader.Number.Uint64(), false)
 
 	var (
@@ -211,6 +213,9 @@ func (c *ChainIndexer) eventLoop(currentHeader *types.Header, events chan ChainE
 				// potentially also lock up. We need to do with on a different thread somehow.
 				if h := FindCommonAncestor(c.chainDb, prevHeader, header, c.config.GetBlockVersion); h != nil {
 					c.newHead(h.Number.Uint64(), true)
+				} else if GetHeaderNoVersion(c.chainDb, prevHash, prevHeader.Number.Uint64()) == nil {
+					// The previous head was rewound out of the chain, the new one extends the rewound head
+					c.newHead(header.Number.Uint64()-1, true)
 				}
 			}
 			c.newHead(header.Number.Uint64(), false)
@@ -219,6 +224,29 @@ func (c *ChainIndexer) eventLoop(currentHeader *types.Header, events chan ChainE
 	}
 }
 
+// verifyLastHead rolls back the stored sections beyond head or no longer in the
+// canonical chain, rewound or reorged while the indexer wasn't running.
+func (c *ChainIndexer) verifyLastHead(head uint64) {
+	c.lock.Lock()
+	stored, sections := c.storedSections, c.storedSections
+	for ; sections > 0; sections-- {
+		last := sections*c.sectionSize - 1
+		if last > head {
+			continue
+		}
+		// Light clients may lack the canonical hashes of the checkpointed sections
+		if hash := GetCanonicalHash(c.chainDb, last); hash == (common.Hash{}) || hash == c.SectionHead(sections-1) {
+			break
+		}
+	}
+	c.lock.Unlock()
+
+	if sections < stored {
+		c.log.Warn("Rolling back sections no longer in the chain", "stored", stored, "valid", sections)
+		c.newHead(sections*c.sectionSize, true)
+	}
+}
+
 // newHead notifies the indexer about new chain heads and/or reorgs.
 func (c *ChainIndexer) newHead(head uint64, reorg bool) {
 	c.lock.Lock()
@@ -372,6 +400,47 @@ func (c *ChainIndexer) processSection(section uint64, lastHead common.Hash) (com
 	return lastHead, nil
 }
 
+// Reindex synchronously regenerates the index sections of the canonical chain
+// from the given section up to the last one having the required confirmations
+// below head. Progress is stored after every section, so an interrupted reindex
+// can be resumed from the number of sections returned by Sections. Closing stop
+// returns early without error. The indexer must not be started.
+func (c *ChainIndexer) Reindex(from, head uint64, stop <-chan struct{}) error {
+	c.lock.Lock()
+	if from < c.storedSections {
+		c.setValidSections(from)
+	}
+	section := c.storedSections
+	c.lock.Unlock()
+
+	var sections uint64
+	if head+1 >= c.confirmsReq {
+		sections = (head + 1 - c.confirmsReq) / c.sectionSize
+	}
+	for ; section < sections; section++ {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		var lastHead common.Hash
+		if section > 0 {
+			lastHead = c.SectionHead(section - 1)
+		}
+		newHead, err := c.processSection(section, lastHead)
+		if err != nil {
+			return err
+		}
+		c.lock.Lock()
+		c.setSectionHead(section, newHead)
+		c.setValidSections(section + 1)
+		c.lock.Unlock()
+
+		c.log.Info("Reindexed chain section", "section", section, "head", newHead)
+	}
+	return nil
+}
+
 // Sections returns the number of processed sections maintained by the indexer
 // and also the information about the last header indexed for potential canonical
 // verifications.
@@ -227,7 +227,7 @@ func makeHeader(chain consensus.ChainReader, parent *types.Block, state *state.S
 		time = new(big.Int).Add(parent.Time(), big.NewInt(240)) // block time is fixed at 10 seconds
 	}
 	num := new(big.Int).Add(parent.Number(), common.Big1)
-	return &types.Header{
+	header := &types.Header{
 		Root:       state.IntermediateRoot(chain.Config().IsEIP158(num)),
 		ParentHash: parent.Hash(),
 		Coinbase:   parent.Coinbase(),
@@ -242,6 +242,10 @@ func makeHeader(chain consensus.ChainReader, parent *types.Block, state *state.S
 		Time:     time,
 		Version:  chain.Config().GetBlockVersion(num),
 	}
+	if chain.Config().IsFeeMarket(num) {
+		header.BaseFee = misc.CalcBaseFee(chain.Config(), parent.Header())
+	}
+	return header
 }
 
 // newCanonical creates a chain database, and injects a deterministic canonical
@@ -0,0 +1,173 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/log"
+)
+
+// chainCheckDepth is the number of canonical blocks below the head header whose
+// data is checked on startup, the older ones assumed sound.
+const chainCheckDepth = 4096
+
+// checkChain scans the most recent canonical blocks for the inconsistencies left
+// by crashes and interrupted writes: number to hash index entries disagreeing
+// with the header chain, and headers, bodies or receipts missing below the head
+// blocks. The index is rebuilt from the parent links of the headers and missing
+// receipts are regenerated where the parent state is available, while the chain
+// is rewound below any other missing data so the downloader fetches it again.
+//
+// This method assumes that the chain manager mutex is held.
+func (bc *BlockChain) checkChain(depth uint64) error {
+	var (
+		header = bc.hc.CurrentHeader()
+		height = header.Number.Uint64()
+		bodies = bc.CurrentBlock().NumberU64() // Blocks up to which bodies and receipts are expected
+		stop   uint64
+
+		gap      bool          // Whether data is missing in the checked range
+		keep     *types.Header // Highest header below all the missing data
+		indexed  int           // Index entries rebuilt
+		receipts int           // Receipts regenerated
+	)
+	if fast := bc.CurrentFastBlock().NumberU64(); fast > bodies {
+		bodies = fast
+	}
+	if height > depth {
+		stop = height - depth
+	}
+	for {
+		number, hash := header.Number.Uint64(), header.Hash()
+		if GetCanonicalHash(bc.db, number) != hash {
+			if err := WriteCanonicalHash(bc.db, hash, number); err != nil {
+				return err
+			}
+			indexed++
+		}
+		missing := false
+		if number > 0 && number <= bodies {
+			if block := bc.GetBlock(hash, number); block == nil {
+				log.Warn("Canonical block body missing", "number", number, "hash", hash)
+				missing = true
+			} else if block.Transactions().Len() > 0 && GetBlockReceipts(bc.db, hash, number) == nil {
+				if err := bc.rebuildReceipts(block); err != nil {
+					log.Warn("Canonical block receipts missing", "number", number, "hash", hash, "err", err)
+					missing = true
+				} else {
+					receipts++
+				}
+			}
+		}
+		if missing {
+			gap, keep = true, nil
+		} else if gap && keep == nil {
+			keep = header
+		}
+		// Past the checked depth, only go on looking for a block to rewind to
+		if number == 0 || (number <= stop && (!gap || keep != nil)) {
+			break
+		}
+		parent := bc.GetHeader(header.ParentHash, number-1)
+		if parent == nil {
+			// Parent header missing, resume from the highest one still indexed
+			log.Warn("Canonical header missing", "number", number-1, "hash", header.ParentHash)
+			gap, keep = true, nil
+			for n := number - 1; parent == nil; n-- {
+				if hash := GetCanonicalHash(bc.db, n); hash != (common.Hash{}) {
+					parent = bc.GetHeader(hash, n)
+				}
+				if n == 0 {
+					break
+				}
+			}
+			if parent == nil {
+				return fmt.Errorf("canonical chain broken below #%d, no header left to rewind to", number)
+			}
+		}
+		header = parent
+	}
+	if indexed > 0 {
+		log.Warn("Rebuilt canonical index entries", "count", indexed)
+	}
+	if receipts > 0 {
+		log.Warn("Regenerated missing receipts", "count", receipts)
+	}
+	if keep != nil {
+		log.Warn("Rewinding below missing chain data to download it again", "number", keep.Number, "hash", keep.Hash(), "head", height)
+		return bc.rewindChain(keep)
+	}
+	return nil
+}
+
+// rebuildReceipts regenerates the missing receipts of a block by executing it
+// on the state of its parent.
+func (bc *BlockChain) rebuildReceipts(block *types.Block) error {
+	parent := bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return fmt.Errorf("parent block missing")
+	}
+	statedb, err := state.New(parent.Root(), bc.stateCache)
+	if err != nil {
+		return err
+	}
+	receipts, _, _, err := bc.processor.Process(block, statedb, bc.vmConfig)
+	if err != nil {
+		return err
+	}
+	if hash := types.DeriveSha(receipts); hash != block.ReceiptHash() {
+		return fmt.Errorf("regenerated receipt root mismatch: have %x, want %x", hash, block.ReceiptHash())
+	}
+	return WriteBlockReceipts(bc.db, block.Hash(), block.NumberU64(), receipts)
+}
+
+// rewindChain drops the canonical chain above the given header, rewinding the
+// head blocks to it or, lacking its state, further down to the first block with
+// state available.
+func (bc *BlockChain) rewindChain(keep *types.Header) error {
+	number := keep.Number.Uint64()
+	for i := bc.hc.CurrentHeader().Number.Uint64(); i > number; i-- {
+		DeleteCanonicalHash(bc.db, i)
+	}
+	bc.hc.SetCurrentHeader(keep)
+
+	if bc.CurrentFastBlock().NumberU64() > number {
+		if err := WriteHeadFastBlockHash(bc.db, keep.Hash()); err != nil {
+			return err
+		}
+	}
+	if bc.CurrentBlock().NumberU64() > number {
+		block := bc.GetBlock(keep.Hash(), number)
+		if _, err := state.New(block.Root(), bc.stateCache); err != nil {
+			if err := bc.repair(&block); err != nil {
+				return err
+			}
+		}
+		if err := WriteHeadBlockHash(bc.db, block.Hash()); err != nil {
+			return err
+		}
+	}
+	bc.bodyCache.Purge()
+	bc.bodyRLPCache.Purge()
+	bc.blockCache.Purge()
+
+	return bc.loadLastState()
+}
@@ -0,0 +1,100 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that the gaps left in the recent canonical chain are healed on startup,
+// rebuilding what can be and rewinding below the rest.
+func TestCheckChain(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}}}
+		signer = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	// newChain imports a chain of 32 blocks into a fresh archive database
+	newChain := func() (aquadb.Database, []*types.Block) {
+		db, _ := aquadb.NewMemDatabase()
+		genesis := gspec.MustCommit(db)
+		blocks, _ := GenerateChain(gspec.Config, genesis, aquahash.NewFaker(), db, 32, func(i int, gen *BlockGen) {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{1}, big.NewInt(1), params.TxGas, nil, nil), signer, key)
+			gen.AddTx(tx)
+		})
+		chain, _ := NewBlockChain(db, &CacheConfig{Disabled: true}, gspec.Config, aquahash.NewFaker(), vm.Config{})
+		if _, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("failed to insert chain: %v", err)
+		}
+		chain.Stop()
+		return db, blocks
+	}
+	// reopen starts a chain on the damaged database, checking its heads
+	reopen := func(db aquadb.Database, head uint64) *BlockChain {
+		t.Helper()
+
+		chain, err := NewBlockChain(db, &CacheConfig{Disabled: true}, gspec.Config, aquahash.NewFaker(), vm.Config{})
+		if err != nil {
+			t.Fatalf("failed to open chain: %v", err)
+		}
+		if have := chain.CurrentBlock().NumberU64(); have != head {
+			t.Errorf("head block mismatch: have #%d, want #%d", have, head)
+		}
+		if have := chain.CurrentHeader().Number.Uint64(); have != head {
+			t.Errorf("head header mismatch: have #%d, want #%d", have, head)
+		}
+		return chain
+	}
+	// Broken index entries are rebuilt from the headers
+	db, blocks := newChain()
+	WriteCanonicalHash(db, common.HexToHash("0xdead"), 10)
+	DeleteCanonicalHash(db, 11)
+	reopen(db, 32).Stop()
+	for _, number := range []uint64{10, 11} {
+		if have, want := GetCanonicalHash(db, number), blocks[number-1].Hash(); have != want {
+			t.Errorf("index entry #%d mismatch: have %x, want %x", number, have, want)
+		}
+	}
+	// Missing receipts are regenerated from the parent state
+	db, blocks = newChain()
+	DeleteBlockReceipts(db, blocks[19].Hash(), 20)
+	reopen(db, 32).Stop()
+	if receipts := GetBlockReceipts(db, blocks[19].Hash(), 20); len(receipts) != 1 {
+		t.Errorf("receipts not regenerated: have %d, want 1", len(receipts))
+	}
+	// Missing bodies and headers can't be, the chain is rewound below them
+	db, blocks = newChain()
+	DeleteBody(db, blocks[24].Hash(), 25)
+	reopen(db, 24).Stop()
+	if hash := GetCanonicalHash(db, 26); hash != (common.Hash{}) {
+		t.Errorf("index entry above the rewound head left: %x", hash)
+	}
+	db, blocks = newChain()
+	DeleteHeader(db, blocks[14].Hash(), 15)
+	DeleteBody(db, blocks[27].Hash(), 28)
+	reopen(db, 14).Stop()
+}
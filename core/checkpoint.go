@@ -0,0 +1,113 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/rlp"
+)
+
+var (
+	errCheckpointUnsigned = errors.New("checkpoint bundle not signed")
+	errCheckpointEmpty    = errors.New("checkpoint bundle contains no checkpoints")
+)
+
+// CheckpointBundle is a signed list of canonical block hashes, exported by one
+// node so that another node can anchor its sync to them. The last checkpoint is
+// the verified head of the exporting node.
+type CheckpointBundle struct {
+	Genesis     common.Hash        `json:"genesis"`
+	Checkpoints params.Checkpoints `json:"checkpoints"`
+	Signature   hexutil.Bytes      `json:"signature,omitempty"`
+}
+
+// NewCheckpointBundle collects a checkpoint every interval blocks of the
+// canonical chain, plus one for the current head.
+func NewCheckpointBundle(bc *BlockChain, interval uint64) (*CheckpointBundle, error) {
+	if interval == 0 {
+		return nil, fmt.Errorf("invalid checkpoint interval %d", interval)
+	}
+	head := bc.CurrentBlock().NumberU64()
+
+	bundle := &CheckpointBundle{Genesis: bc.Genesis().Hash()}
+	for number := interval; ; number += interval {
+		if number > head {
+			number = head
+		}
+		header := bc.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("missing canonical header #%d", number)
+		}
+		hash := header.Hash()
+		bundle.Checkpoints = append(bundle.Checkpoints, params.Checkpoint{
+			Number: number,
+			Hash:   hash,
+		})
+		if number == head {
+			break
+		}
+	}
+	return bundle, nil
+}
+
+// SigHash returns the hash signed by the exporter of the bundle.
+func (b *CheckpointBundle) SigHash() common.Hash {
+	enc, _ := rlp.EncodeToBytes([]interface{}{b.Genesis, b.Checkpoints})
+	return crypto.Keccak256Hash(enc)
+}
+
+// Sign signs the bundle with the given key.
+func (b *CheckpointBundle) Sign(key *ecdsa.PrivateKey) error {
+	sig, err := crypto.Sign(b.SigHash().Bytes(), key)
+	if err != nil {
+		return err
+	}
+	b.Signature = sig
+	return nil
+}
+
+// Signer recovers the public key that signed the bundle.
+func (b *CheckpointBundle) Signer() (*ecdsa.PublicKey, error) {
+	if len(b.Signature) == 0 {
+		return nil, errCheckpointUnsigned
+	}
+	return crypto.SigToPub(b.SigHash().Bytes(), b.Signature)
+}
+
+// Verify checks that the bundle belongs to the given chain and doesn't conflict
+// with any of its canonical blocks.
+func (b *CheckpointBundle) Verify(bc *BlockChain) error {
+	if len(b.Checkpoints) == 0 {
+		return errCheckpointEmpty
+	}
+	if genesis := bc.Genesis().Hash(); b.Genesis != genesis {
+		return fmt.Errorf("genesis mismatch: have %x, want %x", b.Genesis, genesis)
+	}
+	for _, cp := range b.Checkpoints {
+		if header := bc.GetHeaderByNumber(cp.Number); header != nil && header.Hash() != cp.Hash {
+			return fmt.Errorf("checkpoint #%d conflicts with local chain: have %x, want %x", cp.Number, header.Hash(), cp.Hash)
+		}
+	}
+	return nil
+}
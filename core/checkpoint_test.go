@@ -0,0 +1,90 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that checkpoint bundles are collected at the requested interval, survive
+// signing and are rejected on conflicting chains.
+func TestCheckpointBundle(t *testing.T) {
+	db, chain, err := newCanonical(aquahash.NewFaker(), 25, true)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	bundle, err := NewCheckpointBundle(chain, 10)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+	if len(bundle.Checkpoints) != 3 {
+		t.Fatalf("checkpoint count mismatch: have %d, want 3", len(bundle.Checkpoints))
+	}
+	for i, want := range []uint64{10, 20, 25} {
+		cp := bundle.Checkpoints[i]
+		if cp.Number != want {
+			t.Errorf("checkpoint %d: number mismatch: have %d, want %d", i, cp.Number, want)
+		}
+		if hash := chain.GetHeaderByNumber(cp.Number).Hash(); cp.Hash != hash {
+			t.Errorf("checkpoint %d: hash mismatch: have %x, want %x", i, cp.Hash, hash)
+		}
+	}
+	// Sign the bundle and check the signer can be recovered
+	if _, err := bundle.Signer(); err != errCheckpointUnsigned {
+		t.Errorf("unsigned bundle error mismatch: have %v, want %v", err, errCheckpointUnsigned)
+	}
+	key, _ := crypto.GenerateKey()
+	if err := bundle.Sign(key); err != nil {
+		t.Fatalf("failed to sign bundle: %v", err)
+	}
+	signer, err := bundle.Signer()
+	if err != nil {
+		t.Fatalf("failed to recover signer: %v", err)
+	}
+	if crypto.PubkeyToAddress(*signer) != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("signer mismatch")
+	}
+	// Verify the bundle against the chain, then tamper with it
+	if err := bundle.Verify(chain); err != nil {
+		t.Errorf("valid bundle rejected: %v", err)
+	}
+	bundle.Checkpoints[1].Hash = common.Hash{1}
+	if err := bundle.Verify(chain); err == nil {
+		t.Errorf("conflicting bundle accepted")
+	}
+	if signer, _ := bundle.Signer(); signer != nil && crypto.PubkeyToAddress(*signer) == crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("tampered bundle recovered to original signer")
+	}
+	// Check that stored checkpoints are merged
+	if err := WriteTrustedCheckpoints(db, bundle.Checkpoints[:2]); err != nil {
+		t.Fatalf("failed to store checkpoints: %v", err)
+	}
+	if err := WriteTrustedCheckpoints(db, params.Checkpoints{{Number: 20, Hash: common.Hash{2}}}); err != nil {
+		t.Fatalf("failed to store checkpoints: %v", err)
+	}
+	stored := GetTrustedCheckpoints(db)
+	if len(stored) != 2 || stored[0].Number != 10 || stored[1].Hash != (common.Hash{2}) {
+		t.Errorf("stored checkpoints mismatch: %v", stored)
+	}
+}
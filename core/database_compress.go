@@ -0,0 +1,141 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/golang/snappy"
+)
+
+// storageSnappy marks the block bodies and receipts stored compressed with
+// snappy. Their RLP encoding, a list, never starts with it.
+const storageSnappy = 0x01
+
+// compressStorage is set if the block bodies and receipts written to the
+// database are compressed (see SetStorageCompression).
+var compressStorage int32
+
+// errStorageCompression is returned for corrupt compressed entries.
+var errStorageCompression = errors.New("invalid compressed database entry")
+
+// SetStorageCompression enables or disables compressing the block bodies and
+// receipts written to the database. Entries are read alike whether compressed
+// or not, so it can be changed at any time, only affecting the new ones.
+func SetStorageCompression(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&compressStorage, 1)
+	} else {
+		atomic.StoreInt32(&compressStorage, 0)
+	}
+}
+
+// StorageCompression returns whether the block bodies and receipts written to
+// the database are compressed.
+func StorageCompression() bool {
+	return atomic.LoadInt32(&compressStorage) == 1
+}
+
+// encodeStorage returns the stored form of the RLP encoding of a body or of the
+// receipts of a block, compressed if enabled and worth it.
+func encodeStorage(data []byte, compress bool) []byte {
+	if !compress || len(data) == 0 {
+		return data
+	}
+	enc := make([]byte, 1+snappy.MaxEncodedLen(len(data)))
+	enc[0] = storageSnappy
+	enc = enc[:1+len(snappy.Encode(enc[1:], data))]
+	if len(enc) >= len(data) {
+		return data
+	}
+	return enc
+}
+
+// decodeStorage returns the RLP encoding of a stored body or of the receipts of
+// a block, compressed or not.
+func decodeStorage(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != storageSnappy {
+		return data, nil
+	}
+	dec, err := snappy.Decode(nil, data[1:])
+	if err != nil {
+		return nil, errStorageCompression
+	}
+	return dec, nil
+}
+
+// RecompressStorage rewrites the block bodies and receipts of the database
+// compressed or uncompressed, returning the number of entries rewritten and their
+// size before and after. Entries already stored as requested are left alone, so
+// an interrupted run can simply be restarted.
+func RecompressStorage(db aquadb.Database, compress bool) (uint64, common.StorageSize, common.StorageSize, error) {
+	iteratee, ok := db.(aquadb.Iteratee)
+	if !ok {
+		return 0, 0, 0, errors.New("database can't be iterated")
+	}
+	var (
+		batch     = db.NewBatch()
+		start     = time.Now()
+		reported  = time.Now()
+		rewritten uint64
+		before    common.StorageSize
+		after     common.StorageSize
+	)
+	for _, prefix := range [][]byte{bodyPrefix, blockReceiptsPrefix} {
+		err := iteratee.IteratePrefix(prefix, func(key, value []byte) error {
+			// Skip the entries of other kinds sharing the prefix
+			if len(key) != len(prefix)+8+common.HashLength || len(value) == 0 {
+				return nil
+			}
+			if compressed := value[0] == storageSnappy; compressed == compress {
+				return nil
+			}
+			data, err := decodeStorage(value)
+			if err != nil {
+				return err
+			}
+			enc := encodeStorage(data, compress)
+			if err := batch.Put(common.CopyBytes(key), common.CopyBytes(enc)); err != nil {
+				return err
+			}
+			rewritten++
+			before += common.StorageSize(len(value))
+			after += common.StorageSize(len(enc))
+
+			if batch.ValueSize() >= aquadb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					return err
+				}
+				batch.Reset()
+			}
+			if time.Since(reported) >= 8*time.Second {
+				log.Info("Rewriting bodies and receipts", "compress", compress, "entries", rewritten, "before", before, "after", after, "elapsed", common.PrettyDuration(time.Since(start)))
+				reported = time.Now()
+			}
+			return nil
+		})
+		if err != nil {
+			return rewritten, before, after, err
+		}
+	}
+	return rewritten, before, after, batch.Write()
+}
@@ -0,0 +1,90 @@
+// Copyright 2015 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/rlp"
+)
+
+// Tests that bodies and receipts are read alike whether stored compressed or
+// not, and that existing entries can be converted either way.
+func TestStorageCompression(t *testing.T) {
+	defer SetStorageCompression(false)
+	db, _ := aquadb.NewMemDatabase()
+
+	// Store a body uncompressed and receipts compressed
+	body := &types.Body{Uncles: []*types.Header{{Extra: bytes.Repeat([]byte("uncle"), 64)}}}
+	receipts := types.Receipts{{
+		CumulativeGasUsed: 1,
+		Logs:              []*types.Log{{Address: common.Address{0x11}, Data: make([]byte, 1024)}},
+		TxHash:            common.Hash{0x11},
+		GasUsed:           1,
+	}}
+	hash, number := common.Hash{0x01}, uint64(1)
+
+	if err := WriteBody(db, hash, number, body); err != nil {
+		t.Fatalf("failed to write body: %v", err)
+	}
+	SetStorageCompression(true)
+	if err := WriteBlockReceipts(db, hash, number, receipts); err != nil {
+		t.Fatalf("failed to write receipts: %v", err)
+	}
+	SetStorageCompression(false)
+
+	bodyKey := blockBodyKey(hash, number)
+	receiptsKey := append(append(append([]byte{}, blockReceiptsPrefix...), encodeBlockNumber(number)...), hash[:]...)
+	want, _ := rlp.EncodeToBytes(body)
+
+	check := func(stage string, bodyCompressed, receiptsCompressed bool) {
+		if raw, _ := db.Get(bodyKey); (raw[0] == storageSnappy) != bodyCompressed {
+			t.Errorf("%s: body compression mismatch: have %v, want %v", stage, !bodyCompressed, bodyCompressed)
+		}
+		if raw, _ := db.Get(receiptsKey); (raw[0] == storageSnappy) != receiptsCompressed {
+			t.Errorf("%s: receipts compression mismatch: have %v, want %v", stage, !receiptsCompressed, receiptsCompressed)
+		}
+		if have := GetBodyRLP(db, hash, number); !bytes.Equal(have, want) {
+			t.Errorf("%s: body mismatch: have %x, want %x", stage, have, want)
+		}
+		have := GetBlockReceipts(db, hash, number)
+		if len(have) != 1 || have[0].TxHash != receipts[0].TxHash || len(have[0].Logs) != 1 || !bytes.Equal(have[0].Logs[0].Data, receipts[0].Logs[0].Data) {
+			t.Errorf("%s: receipts mismatch: have %v, want %v", stage, have, receipts)
+		}
+	}
+	check("written", false, true)
+
+	if n, _, _, err := RecompressStorage(db, true); err != nil || n != 1 {
+		t.Fatalf("compression failed: have %d entries, %v, want 1", n, err)
+	}
+	check("compressed", true, true)
+
+	if n, before, after, err := RecompressStorage(db, false); err != nil || n != 2 || after <= before {
+		t.Fatalf("decompression failed: have %d entries (%v -> %v), %v, want 2", n, before, after, err)
+	}
+	check("decompressed", false, false)
+
+	// Corrupt compressed entries must not be returned
+	db.Put(bodyKey, []byte{storageSnappy, 0xff, 0xff})
+	if have := GetBodyRLP(db, hash, number); have != nil {
+		t.Errorf("corrupt body returned: %x", have)
+	}
+}
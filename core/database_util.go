@@ -62,6 +62,8 @@ var (
 	preimagePrefix = "secure-key-"               // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("aquachain-config-") // config prefix for the db
 
+	trustedCheckpointsKey = []byte("TrustedCheckpoints") // imported checkpoints, json encoded
+
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
 
@@ -182,6 +184,11 @@ func GetHeaderNoVersion(db DatabaseReader, hash common.Hash, number uint64) *typ
 // GetBodyRLP retrieves the block body (transactions and uncles) in RLP encoding.
 func GetBodyRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(blockBodyKey(hash, number))
+	data, err := decodeStorage(data)
+	if err != nil {
+		log.Error("Invalid block body", "hash", hash, "err", err)
+		return nil
+	}
 	return data
 }
 
@@ -250,6 +257,11 @@ func GetBlockReceipts(db DatabaseReader, hash common.Hash, number uint64) types.
 	if len(data) == 0 {
 		return nil
 	}
+	data, err := decodeStorage(data)
+	if err != nil {
+		log.Error("Invalid receipt array", "hash", hash, "err", err)
+		return nil
+	}
 	storageReceipts := []*types.ReceiptForStorage{}
 	if err := rlp.DecodeBytes(data, &storageReceipts); err != nil {
 		log.Error("Invalid receipt array RLP", "hash", hash, "err", err)
@@ -426,7 +438,7 @@ func WriteBody(db aquadb.Putter, hash common.Hash, number uint64, body *types.Bo
 // WriteBodyRLP writes a serialized body of a block into the database.
 func WriteBodyRLP(db aquadb.Putter, hash common.Hash, number uint64, rlp rlp.RawValue) error {
 	key := append(append(bodyPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
-	if err := db.Put(key, rlp); err != nil {
+	if err := db.Put(key, encodeStorage(rlp, StorageCompression())); err != nil {
 		log.Crit("Failed to store block body", "err", err)
 	}
 	return nil
@@ -473,7 +485,7 @@ func WriteBlockReceipts(db aquadb.Putter, hash common.Hash, number uint64, recei
 	}
 	// Store the flattened receipt slice
 	key := append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
-	if err := db.Put(key, bytes); err != nil {
+	if err := db.Put(key, encodeStorage(bytes, StorageCompression())); err != nil {
 		log.Crit("Failed to store block receipts", "err", err)
 	}
 	return nil
@@ -624,6 +636,32 @@ func GetChainConfig(db DatabaseReader, hash common.Hash) (*params.ChainConfig, e
 	return &config, nil
 }
 
+// WriteTrustedCheckpoints merges the given checkpoints into the list of
+// imported trusted checkpoints stored in the database.
+func WriteTrustedCheckpoints(db aquadb.Database, checkpoints params.Checkpoints) error {
+	list := GetTrustedCheckpoints(db).Merge(checkpoints)
+	enc, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return db.Put(trustedCheckpointsKey, enc)
+}
+
+// GetTrustedCheckpoints retrieves the imported trusted checkpoints, or nil if
+// none were imported.
+func GetTrustedCheckpoints(db DatabaseReader) params.Checkpoints {
+	enc, _ := db.Get(trustedCheckpointsKey)
+	if len(enc) == 0 {
+		return nil
+	}
+	var list params.Checkpoints
+	if err := json.Unmarshal(enc, &list); err != nil {
+		log.Error("Invalid trusted checkpoints in database", "err", err)
+		return nil
+	}
+	return list
+}
+
 // FindCommonAncestor returns the last common ancestor of two block headers
 func FindCommonAncestor(db DatabaseReader, a, b *types.Header, versionFunc func(*big.Int) params.HeaderVersion) *types.Header {
 	for bn := b.Number.Uint64(); a.Number.Uint64() > bn; {
@@ -16,7 +16,12 @@
 
 package core
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aquanetwork/aquachain/common"
+)
 
 var (
 	// ErrKnownBlock is returned when a block to import is already known locally.
@@ -32,4 +37,28 @@ var (
 	// ErrNonceTooHigh is returned if the nonce of a transaction is higher than the
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
+
+	// ErrFeeCapTooLow is returned if the fee cap of a transaction is below the
+	// base fee of its block.
+	ErrFeeCapTooLow = errors.New("fee cap less than block base fee")
+
+	// ErrTipAboveFeeCap is returned if the tip of a dynamic fee transaction is
+	// above its fee cap.
+	ErrTipAboveFeeCap = errors.New("tip higher than fee cap")
 )
+
+// StateRootError is returned when the state root computed by processing a block
+// differs from the one in its header.
+type StateRootError struct {
+	Remote common.Hash // State root in the block header
+	Local  common.Hash // State root computed locally
+	Dump   string      // Directory of the forensic bundle, if one was written
+}
+
+func (e *StateRootError) Error() string {
+	msg := fmt.Sprintf("invalid merkle root (remote: %x local: %x)", e.Remote, e.Local)
+	if e.Dump != "" {
+		msg += fmt.Sprintf(", forensic bundle in %s", e.Dump)
+	}
+	return msg
+}
@@ -41,6 +41,16 @@ type RemovedTransactionEvent struct{ Txs types.Transactions }
 // RemovedLogsEvent is posted when a reorg happens
 type RemovedLogsEvent struct{ Logs []*types.Log }
 
+// ReorgEvent is posted when a reorg happens, with the blocks dropped from the
+// canonical chain and the ones replacing them, both from the head down, and the
+// transactions no longer in the canonical chain.
+type ReorgEvent struct {
+	Common     *types.Block
+	Dropped    types.Blocks
+	Added      types.Blocks
+	DroppedTxs types.Transactions
+}
+
 type ChainEvent struct {
 	Block *types.Block
 	Hash  common.Hash
@@ -0,0 +1,92 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/consensus/misc"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that dynamic fee transactions pay the base fee of their block, which is
+// burnt, and only their tip to the miner.
+func TestFeeMarket(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		miners  = []common.Address{{0x01}, {0x02}}
+		db, _   = aquadb.NewMemDatabase()
+		config  = *params.TestChainConfig
+		funds   = big.NewInt(params.Aqua)
+		tip     = big.NewInt(10)
+		feeCap  = new(big.Int).SetUint64(2*params.InitialBaseFee + 10)
+		signer  = types.NewFeeMarketSigner(config.ChainId)
+		gspec   = &Genesis{Config: &config, Alloc: GenesisAlloc{addr: {Balance: funds}}}
+		txCost  = new(big.Int)
+		recvr   = common.Address{0xff}
+		payment = big.NewInt(1000)
+	)
+	config.FeeMarket = &params.FeeMarketConfig{Block: big.NewInt(1)}
+	genesis := gspec.MustCommit(db)
+
+	blocks, _ := GenerateChain(&config, genesis, aquahash.NewFaker(), db, 2, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(miners[i])
+		if i == 0 {
+			tx, err := types.SignTx(types.NewDynamicFeeTransaction(config.ChainId, 0, &recvr, payment, params.TxGas, tip, feeCap, nil), signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign transaction: %v", err)
+			}
+			gen.AddTx(tx)
+			txCost.Mul(new(big.Int).Add(gen.header.BaseFee, tip), new(big.Int).SetUint64(params.TxGas))
+		}
+	})
+	if have := blocks[0].BaseFee(); have == nil || have.Uint64() != params.InitialBaseFee {
+		t.Fatalf("first base fee mismatch: have %v, want %v", have, params.InitialBaseFee)
+	}
+	if have, want := blocks[1].BaseFee(), misc.CalcBaseFee(&config, blocks[0].Header()); have.Cmp(want) != 0 || have.Cmp(blocks[0].BaseFee()) >= 0 {
+		t.Fatalf("second base fee mismatch: have %v, want %v below %v", have, want, blocks[0].BaseFee())
+	}
+	chain, _ := NewBlockChain(db, nil, &config, aquahash.NewFaker(), vm.Config{})
+	defer chain.Stop()
+
+	if i, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", i, err)
+	}
+	statedb, _ := chain.State()
+	if have, want := statedb.GetBalance(addr), new(big.Int).Sub(funds, new(big.Int).Add(txCost, payment)); have.Cmp(want) != 0 {
+		t.Errorf("sender balance mismatch: have %v, want %v", have, want)
+	}
+	// Both miners earned the same block reward, the first one the tip on top
+	reward := new(big.Int).Sub(statedb.GetBalance(miners[0]), statedb.GetBalance(miners[1]))
+	if want := new(big.Int).Mul(tip, new(big.Int).SetUint64(params.TxGas)); reward.Cmp(want) != 0 {
+		t.Errorf("miner tip mismatch: have %v, want %v", reward, want)
+	}
+	// Headers without base fee are refused once the fee market is active
+	header := blocks[1].Header()
+	header.BaseFee = nil
+	if err := misc.VerifyBaseFee(&config, blocks[0].Header(), header); err == nil {
+		t.Errorf("header without base fee accepted")
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/log"
+)
+
+// forensicTraceLimit caps the number of opcodes traced per transaction in a
+// forensic bundle.
+const forensicTraceLimit = 100000
+
+// ForensicReport is the summary of a forensic bundle, dumped when the state root
+// computed by processing a block differs from the one in its header.
+type ForensicReport struct {
+	Number       uint64               `json:"number"`
+	Hash         common.Hash          `json:"hash"`
+	Error        string               `json:"error"`
+	RemoteRoot   common.Hash          `json:"remoteRoot"`
+	LocalRoot    common.Hash          `json:"localRoot"`
+	Header       *types.Header        `json:"header"`
+	Transactions []*types.Transaction `json:"transactions"`
+	Receipts     []*types.Receipt     `json:"receipts"`
+	Accounts     []ForensicAccount    `json:"accounts"` // Accounts modified by the block
+}
+
+// ForensicAccount is an account modified by a diverging block, with the proof
+// of its parent state and its local post state.
+type ForensicAccount struct {
+	Address     common.Address  `json:"address"`
+	ParentProof []hexutil.Bytes `json:"parentProof"` // Merkle proof of the account in the parent state
+	Exists      bool            `json:"exists"`
+	Balance     *hexutil.Big    `json:"balance"`
+	Nonce       hexutil.Uint64  `json:"nonce"`
+	CodeHash    common.Hash     `json:"codeHash"`
+}
+
+// SetForensicsDir sets the directory the forensic bundles of the blocks whose
+// state root mismatches are written to. Empty disables them.
+func (bc *BlockChain) SetForensicsDir(dir string) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	bc.forensicsDir = dir
+}
+
+// dumpForensics writes a forensic bundle of a block whose state root mismatches,
+// from the parent block and the local post state: the report with the proofs
+// of the modified accounts, and the opcode traces of the transactions. It
+// returns the directory of the bundle.
+func (bc *BlockChain) dumpForensics(block, parent *types.Block, statedb *state.StateDB, receipts types.Receipts, mismatch *StateRootError) (string, error) {
+	bc.mu.RLock()
+	root := bc.forensicsDir
+	bc.mu.RUnlock()
+
+	if root == "" {
+		return "", nil
+	}
+	dir := filepath.Join(root, fmt.Sprintf("%d-%x", block.NumberU64(), block.Hash().Bytes()[:4]))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	parentState, err := state.New(parent.Root(), bc.stateCache)
+	if err != nil {
+		return "", err
+	}
+	report := &ForensicReport{
+		Number:       block.NumberU64(),
+		Hash:         block.Hash(),
+		Error:        mismatch.Error(),
+		RemoteRoot:   mismatch.Remote,
+		LocalRoot:    mismatch.Local,
+		Header:       block.Header(),
+		Transactions: block.Transactions(),
+		Receipts:     receipts,
+		Accounts:     []ForensicAccount{},
+	}
+	for _, addr := range statedb.DirtyAccounts() {
+		account := ForensicAccount{
+			Address:  addr,
+			Exists:   statedb.Exist(addr),
+			Balance:  (*hexutil.Big)(new(big.Int).Set(statedb.GetBalance(addr))),
+			Nonce:    hexutil.Uint64(statedb.GetNonce(addr)),
+			CodeHash: statedb.GetCodeHash(addr),
+		}
+		proof, err := parentState.GetProof(addr)
+		if err != nil {
+			return "", err
+		}
+		for _, node := range proof {
+			account.ParentProof = append(account.ParentProof, node)
+		}
+		report.Accounts = append(report.Accounts, account)
+	}
+	blob, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "report.json"), blob, 0600); err != nil {
+		return "", err
+	}
+	// Process the block again, tracing the transactions
+	tracer := &forensicTracer{statedb: parentState, loggers: make(map[int]*vm.StructLogger)}
+	if _, _, _, err := bc.processor.Process(block, parentState, vm.Config{Debug: true, Tracer: tracer}); err != nil {
+		log.Warn("Failed to trace diverging block", "number", block.Number(), "hash", block.Hash(), "err", err)
+	}
+	for i, tx := range block.Transactions() {
+		logger, ok := tracer.loggers[i]
+		if !ok {
+			continue // Plain transfer, nothing executed
+		}
+		file, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("trace-%d-%x.txt", i, tx.Hash().Bytes()[:4])), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			return "", err
+		}
+		vm.WriteTrace(file, logger.StructLogs())
+		if err := logger.Error(); err != nil {
+			fmt.Fprintf(file, "Error: %v\n", err)
+		}
+		fmt.Fprintf(file, "Output: %x\n", logger.Output())
+		file.Close()
+	}
+	return dir, nil
+}
+
+// forensicTracer is a vm.Tracer logging the opcodes of each transaction of a
+// block apart.
+type forensicTracer struct {
+	statedb *state.StateDB
+	loggers map[int]*vm.StructLogger // Struct loggers by transaction index
+}
+
+// logger returns the struct logger of the transaction being executed.
+func (t *forensicTracer) logger() *vm.StructLogger {
+	index := t.statedb.TxIndex()
+	logger, ok := t.loggers[index]
+	if !ok {
+		logger = vm.NewStructLogger(&vm.LogConfig{DisableMemory: true, Limit: forensicTraceLimit})
+		t.loggers[index] = logger
+	}
+	return logger
+}
+
+func (t *forensicTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return t.logger().CaptureStart(from, to, create, input, gas, value)
+}
+
+func (t *forensicTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return t.logger().CaptureState(env, pc, op, gas, cost, memory, stack, contract, depth, err)
+}
+
+func (t *forensicTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return t.logger().CaptureFault(env, pc, op, gas, cost, memory, stack, contract, depth, err)
+}
+
+func (t *forensicTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return t.logger().CaptureEnd(output, gasUsed, d, err)
+}
@@ -0,0 +1,113 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that importing a block with a mismatching state root dumps a forensic
+// bundle, referenced by the error.
+func TestForensicDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "forensics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		db, _  = aquadb.NewMemDatabase()
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(10000000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+	blockchain.SetForensicsDir(dir)
+
+	// Create a contract storing a value, then tamper with the state root
+	chain, _ := GenerateChain(gspec.Config, genesis, aquahash.NewFaker(), db, 1, func(i int, gen *BlockGen) {
+		code := hexutil.MustDecode("0x600160005500") // SSTORE(0, 1)
+		tx, _ := types.SignTx(types.NewContractCreation(gen.TxNonce(addr), new(big.Int), 1000000, new(big.Int), code), signer, key)
+		gen.AddTx(tx)
+	})
+	header := chain[0].Header()
+	header.Root = common.Hash{0x01}
+	bad := chain[0].WithSeal(header)
+
+	_, err = blockchain.InsertChain(types.Blocks{bad})
+	mismatch, ok := err.(*StateRootError)
+	if !ok {
+		t.Fatalf("error mismatch: have %v, want state root mismatch", err)
+	}
+	if mismatch.Dump == "" || !strings.Contains(err.Error(), mismatch.Dump) {
+		t.Fatalf("forensic bundle not referenced by the error: %v", err)
+	}
+	blob, err := ioutil.ReadFile(filepath.Join(mismatch.Dump, "report.json"))
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	var report struct {
+		ForensicReport
+		Receipts []json.RawMessage `json:"receipts"` // Logs are null, undecodable
+	}
+	if err := json.Unmarshal(blob, &report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	if report.Hash != bad.Hash() || report.RemoteRoot != header.Root || report.LocalRoot != chain[0].Root() {
+		t.Errorf("report mismatch: have %x/%x/%x", report.Hash, report.RemoteRoot, report.LocalRoot)
+	}
+	if len(report.Transactions) != 1 || len(report.Receipts) != 1 {
+		t.Errorf("transactions/receipts mismatch: have %d/%d, want 1/1", len(report.Transactions), len(report.Receipts))
+	}
+	var sender bool
+	for _, account := range report.Accounts {
+		if account.Address == addr {
+			sender = len(account.ParentProof) > 0 && account.Nonce == 1
+		}
+	}
+	if !sender {
+		t.Errorf("sender account missing or without proof: %+v", report.Accounts)
+	}
+	traces, _ := filepath.Glob(filepath.Join(mismatch.Dump, "trace-0-*.txt"))
+	if len(traces) != 1 {
+		t.Fatalf("transaction trace missing")
+	}
+	if trace, _ := ioutil.ReadFile(traces[0]); !strings.Contains(string(trace), "SSTORE") {
+		t.Errorf("trace lacks the executed opcodes:\n%s", trace)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package forkid implements the fork identifiers exchanged by peers in the
+// handshake, a checksum of the genesis block and the forks passed so far along
+// with the next scheduled fork, after EIP-2124.
+package forkid
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+var (
+	// ErrRemoteStale is returned by the filter if the remote node is known to
+	// be on an older fork, having missed the next fork it should have passed.
+	ErrRemoteStale = errors.New("remote needs update")
+
+	// ErrLocalIncompatibleOrStale is returned by the filter if the remote node
+	// is on a different chain, or has passed a fork this node doesn't know of.
+	ErrLocalIncompatibleOrStale = errors.New("local incompatible or needs update")
+)
+
+// ID is a fork identifier.
+type ID struct {
+	Hash [4]byte // CRC32 checksum of the genesis block and passed fork blocks
+	Next uint64  // Block of the next upcoming fork, or 0 if none is scheduled
+}
+
+// Filter checks the fork identifier of a remote node against the local chain.
+type Filter func(id ID) error
+
+// Readiness is the state of a remote node with regard to the next local fork.
+type Readiness string
+
+const (
+	Ready        Readiness = "ready"        // Remote schedules or has passed the next fork
+	Unupgraded   Readiness = "unupgraded"   // Remote schedules no fork at all
+	Mismatch     Readiness = "mismatch"     // Remote schedules a different fork next
+	Behind       Readiness = "behind"       // Remote was syncing older forks, can't tell
+	Incompatible Readiness = "incompatible" // Remote is on a different chain
+	Unknown      Readiness = "unknown"      // Remote announced no fork identifier
+)
+
+// checksums returns the fork blocks of a chain, terminated by a fork never
+// passed, and the checksums of the forks passed before each of them.
+func checksums(config *params.ChainConfig, genesis common.Hash) ([]uint64, [][4]byte) {
+	forks := config.ForkBlocks()
+	sums := make([][4]byte, len(forks)+1)
+
+	hash := crc32.ChecksumIEEE(genesis[:])
+	binary.BigEndian.PutUint32(sums[0][:], hash)
+	for i, fork := range forks {
+		var blob [8]byte
+		binary.BigEndian.PutUint64(blob[:], fork)
+		hash = crc32.Update(hash, crc32.IEEETable, blob[:])
+		binary.BigEndian.PutUint32(sums[i+1][:], hash)
+	}
+	return append(forks, math.MaxUint64), sums
+}
+
+// upcoming returns the index of the first fork not passed at head, and its
+// block, 0 if no fork is scheduled.
+func upcoming(forks []uint64, head uint64) (int, uint64) {
+	i := 0
+	for head >= forks[i] && forks[i] != math.MaxUint64 {
+		i++
+	}
+	if forks[i] == math.MaxUint64 {
+		return i, 0
+	}
+	return i, forks[i]
+}
+
+// NewID returns the fork identifier of a chain at the given head.
+func NewID(config *params.ChainConfig, genesis common.Hash, head uint64) ID {
+	forks, sums := checksums(config, genesis)
+
+	i, next := upcoming(forks, head)
+	return ID{Hash: sums[i], Next: next}
+}
+
+// NewFilter returns a filter accepting the fork identifiers of the nodes that
+// are compatible with the local chain, at the head returned by headfn.
+func NewFilter(config *params.ChainConfig, genesis common.Hash, headfn func() uint64) Filter {
+	forks, sums := checksums(config, genesis)
+
+	return func(id ID) error {
+		head := headfn()
+		i, _ := upcoming(forks, head)
+
+		// Both on the same fork, the remote must not announce a passed fork
+		if sums[i] == id.Hash {
+			if id.Next > 0 && head >= id.Next {
+				return ErrLocalIncompatibleOrStale
+			}
+			return nil
+		}
+		// Remote on an older fork, it must announce the one following it
+		for j := 0; j < i; j++ {
+			if sums[j] == id.Hash {
+				if forks[j] != id.Next {
+					return ErrRemoteStale
+				}
+				return nil
+			}
+		}
+		// Remote on a newer fork, it's ahead and the local node may catch up
+		for j := i + 1; j < len(sums); j++ {
+			if sums[j] == id.Hash {
+				return nil
+			}
+		}
+		return ErrLocalIncompatibleOrStale
+	}
+}
+
+// CheckReadiness returns whether the remote node announcing id is ready for the
+// next fork of the local chain at head.
+func CheckReadiness(config *params.ChainConfig, genesis common.Hash, head uint64, id ID) Readiness {
+	forks, sums := checksums(config, genesis)
+
+	i, next := upcoming(forks, head)
+	for j := range sums {
+		if sums[j] != id.Hash {
+			continue
+		}
+		switch {
+		case j > i:
+			return Ready
+		case j < i:
+			return Behind
+		case id.Next == next:
+			return Ready
+		case id.Next == 0:
+			return Unupgraded
+		default:
+			return Mismatch
+		}
+	}
+	return Incompatible
+}
@@ -0,0 +1,115 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkid
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+var (
+	testGenesis = common.HexToHash("0x817df985008bb2178975203c512c19f97d8e7a04389b5fea4968a4359534d6b2")
+	testConfig  = &params.ChainConfig{HF: params.ForkMap{0: big.NewInt(10), 1: big.NewInt(20)}}
+)
+
+// Tests that fork identifiers checksum the passed forks and announce the next.
+func TestNewID(t *testing.T) {
+	var genesis [4]byte
+	binary.BigEndian.PutUint32(genesis[:], crc32.ChecksumIEEE(testGenesis[:]))
+
+	tests := []struct {
+		head uint64
+		next uint64
+	}{
+		{0, 10}, {9, 10}, {10, 20}, {19, 20}, {20, 0}, {1000, 0},
+	}
+	for i, tt := range tests {
+		id := NewID(testConfig, testGenesis, tt.head)
+		if id.Next != tt.next {
+			t.Errorf("test %d: next fork mismatch: have %d, want %d", i, id.Next, tt.next)
+		}
+		if (id.Hash == genesis) != (tt.head < 10) {
+			t.Errorf("test %d: checksum mismatch: have %x, genesis %x", i, id.Hash, genesis)
+		}
+	}
+	if NewID(testConfig, testGenesis, 10) == NewID(testConfig, testGenesis, 20) {
+		t.Errorf("checksum unchanged by passing a fork")
+	}
+}
+
+// Tests that the filter accepts the nodes on the local chain, behind or ahead,
+// and rejects the stale and incompatible ones.
+func TestFilter(t *testing.T) {
+	unupgraded := &params.ChainConfig{HF: params.ForkMap{0: big.NewInt(10)}}
+	tests := []struct {
+		head uint64
+		id   ID
+		err  error
+	}{
+		// Same fork, same schedule
+		{15, NewID(testConfig, testGenesis, 15), nil},
+		// Same fork, remote unaware of the next one
+		{15, NewID(unupgraded, testGenesis, 15), nil},
+		// Same fork, remote announces a fork already passed locally
+		{25, ID{Hash: NewID(testConfig, testGenesis, 25).Hash, Next: 22}, ErrLocalIncompatibleOrStale},
+		// Remote syncing an older fork
+		{25, NewID(testConfig, testGenesis, 5), nil},
+		// Remote stuck on an older fork, missing the one passed locally
+		{25, NewID(unupgraded, testGenesis, 25), ErrRemoteStale},
+		// Remote ahead, local node syncing
+		{5, NewID(testConfig, testGenesis, 25), nil},
+		// Remote on another chain
+		{5, NewID(testConfig, common.Hash{1}, 5), ErrLocalIncompatibleOrStale},
+	}
+	for i, tt := range tests {
+		head := tt.head
+		filter := NewFilter(testConfig, testGenesis, func() uint64 { return head })
+		if err := filter(tt.id); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that remote nodes are classified by their readiness for the next fork.
+func TestCheckReadiness(t *testing.T) {
+	unupgraded := &params.ChainConfig{HF: params.ForkMap{0: big.NewInt(10)}}
+	rescheduled := &params.ChainConfig{HF: params.ForkMap{0: big.NewInt(10), 1: big.NewInt(30)}}
+
+	tests := []struct {
+		head uint64
+		id   ID
+		want Readiness
+	}{
+		{15, NewID(testConfig, testGenesis, 15), Ready},
+		{15, NewID(testConfig, testGenesis, 25), Ready},
+		{15, NewID(unupgraded, testGenesis, 15), Unupgraded},
+		{15, NewID(rescheduled, testGenesis, 15), Mismatch},
+		{15, NewID(testConfig, testGenesis, 5), Behind},
+		{15, NewID(testConfig, common.Hash{1}, 15), Incompatible},
+		{25, NewID(testConfig, testGenesis, 25), Ready},
+	}
+	for i, tt := range tests {
+		if have := CheckReadiness(testConfig, testGenesis, tt.head, tt.id); have != tt.want {
+			t.Errorf("test %d: readiness mismatch: have %s, want %s", i, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// maxGenesisHF is the last hard fork a custom network may schedule.
+const maxGenesisHF = 5
+
+// GenesisSpec describes a custom network, from which Genesis assembles the
+// genesis block.
+type GenesisSpec struct {
+	ChainId    uint64                      // Replay protection chain id, also used as network id
+	HF         params.ForkMap              // Hard fork heights, HF5 switching the seal to argon2id
+	Alloc      map[common.Address]*big.Int // Prefunded accounts
+	Difficulty *big.Int                    // Difficulty of the genesis block
+	GasLimit   uint64                      // Gas limit of the genesis block
+	Timestamp  uint64                      // Timestamp of the genesis block, making its hash unique
+	ExtraData  []byte
+}
+
+// DefaultGenesisSpec returns the spec of a private network with the given chain
+// id, activating all hard forks over the first blocks like the testnet does.
+func DefaultGenesisSpec(chainId uint64) *GenesisSpec {
+	hf := make(params.ForkMap)
+	for i, height := range params.TestnetHF {
+		hf[i] = new(big.Int).Set(height)
+	}
+	return &GenesisSpec{
+		ChainId:    chainId,
+		HF:         hf,
+		Alloc:      make(map[common.Address]*big.Int),
+		Difficulty: big.NewInt(1048576),
+		GasLimit:   params.GenesisGasLimit,
+		Timestamp:  uint64(time.Now().Unix()),
+	}
+}
+
+// Validate checks that the spec describes a usable network apart from the
+// public ones: hard forks are scheduled in order without gaps, and the genesis
+// block can be mined.
+func (s *GenesisSpec) Validate() error {
+	switch s.ChainId {
+	case 0:
+		return errors.New("chain id must be positive")
+	case params.MainnetChainConfig.ChainId.Uint64(), params.TestnetChainConfig.ChainId.Uint64(), params.RinkebyChainConfig.ChainId.Uint64():
+		return fmt.Errorf("chain id %d is used by a public network", s.ChainId)
+	}
+	var last *big.Int
+	for i := 0; i <= maxGenesisHF; i++ {
+		height := s.HF[i]
+		if height == nil {
+			last = nil
+			continue
+		}
+		if height.Sign() < 0 {
+			return fmt.Errorf("HF%d scheduled at negative height %v", i, height)
+		}
+		if i > 0 && s.HF[i-1] == nil {
+			return fmt.Errorf("HF%d scheduled without HF%d", i, i-1)
+		}
+		if last != nil && height.Cmp(last) < 0 {
+			return fmt.Errorf("HF%d at %v scheduled before HF%d at %v", i, height, i-1, last)
+		}
+		last = height
+	}
+	for i := range s.HF {
+		if i < 0 || i > maxGenesisHF {
+			return fmt.Errorf("unknown hard fork HF%d", i)
+		}
+	}
+	if s.Difficulty == nil || s.Difficulty.Sign() <= 0 {
+		return errors.New("genesis difficulty must be positive")
+	}
+	if s.GasLimit < params.MinGasLimit {
+		return fmt.Errorf("genesis gas limit %d below the minimum %d", s.GasLimit, params.MinGasLimit)
+	}
+	return nil
+}
+
+// Genesis validates the spec and assembles the genesis block of the network.
+func (s *GenesisSpec) Genesis() (*Genesis, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	hf := make(params.ForkMap)
+	for i, height := range s.HF {
+		if height != nil {
+			hf[i] = new(big.Int).Set(height)
+		}
+	}
+	alloc := make(GenesisAlloc)
+	for addr, balance := range s.Alloc {
+		alloc[addr] = GenesisAccount{Balance: new(big.Int).Set(balance)}
+	}
+	return &Genesis{
+		Config: &params.ChainConfig{
+			ChainId:        new(big.Int).SetUint64(s.ChainId),
+			HomesteadBlock: big.NewInt(0),
+			EIP150Block:    big.NewInt(0),
+			EIP155Block:    big.NewInt(0),
+			Aquahash:       new(params.AquahashConfig),
+			HF:             hf,
+		},
+		Timestamp:  s.Timestamp,
+		ExtraData:  common.CopyBytes(s.ExtraData),
+		GasLimit:   s.GasLimit,
+		Difficulty: new(big.Int).Set(s.Difficulty),
+		Alloc:      alloc,
+	}, nil
+}
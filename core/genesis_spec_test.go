@@ -0,0 +1,82 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that invalid network specs are refused.
+func TestGenesisSpecValidation(t *testing.T) {
+	tests := []struct {
+		modify func(*GenesisSpec)
+		valid  bool
+	}{
+		{func(s *GenesisSpec) {}, true},
+		{func(s *GenesisSpec) { s.ChainId = 0 }, false},
+		{func(s *GenesisSpec) { s.ChainId = params.MainnetChainConfig.ChainId.Uint64() }, false},
+		{func(s *GenesisSpec) { delete(s.HF, 4); delete(s.HF, 5) }, true},
+		{func(s *GenesisSpec) { delete(s.HF, 4) }, false},
+		{func(s *GenesisSpec) { s.HF[5] = big.NewInt(3) }, false},
+		{func(s *GenesisSpec) { s.HF[6] = big.NewInt(10) }, false},
+		{func(s *GenesisSpec) { s.Difficulty = new(big.Int) }, false},
+		{func(s *GenesisSpec) { s.GasLimit = 100 }, false},
+	}
+	for i, tt := range tests {
+		spec := DefaultGenesisSpec(1234)
+		tt.modify(spec)
+		if err := spec.Validate(); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+}
+
+// Tests that the genesis block of a spec commits with its schedule and funds.
+func TestGenesisSpecCommit(t *testing.T) {
+	spec := DefaultGenesisSpec(1234)
+	spec.HF[5] = big.NewInt(100)
+	spec.Alloc[common.Address{1}] = big.NewInt(params.Aqua)
+
+	genesis, err := spec.Genesis()
+	if err != nil {
+		t.Fatalf("failed to build genesis: %v", err)
+	}
+	db, _ := aquadb.NewMemDatabase()
+	block := genesis.MustCommit(db)
+
+	config := genesis.Config
+	if config.ChainId.Uint64() != 1234 || !config.IsEIP155(big.NewInt(0)) {
+		t.Fatalf("replay protection mismatch: chain id %v, eip155 %v", config.ChainId, config.EIP155Block)
+	}
+	if have := config.GetBlockVersion(big.NewInt(99)); have != 1 {
+		t.Errorf("version before HF5 mismatch: have %d, want 1", have)
+	}
+	if have := config.GetBlockVersion(big.NewInt(100)); have != 2 {
+		t.Errorf("version at HF5 mismatch: have %d, want 2", have)
+	}
+	if block.Difficulty().Cmp(spec.Difficulty) != 0 {
+		t.Errorf("difficulty mismatch: have %v, want %v", block.Difficulty(), spec.Difficulty)
+	}
+	if GetCanonicalHash(db, 0) != block.Hash() {
+		t.Fatalf("genesis block not stored")
+	}
+}
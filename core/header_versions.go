@@ -0,0 +1,218 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/rlp"
+)
+
+// HeaderVersionReport is the outcome of checking the versions of the headers
+// stored in a database.
+type HeaderVersionReport struct {
+	Headers  uint64 // Headers checked
+	Misfiled uint64 // Headers stored under the hash of a version not mandated at their number
+	Invalid  uint64 // Headers not decoding or not matching the hash of any version
+	Unlinked uint64 // Canonical headers whose parent isn't the previous canonical one
+	Repaired uint64 // Misfiled headers moved to the hash of their mandated version
+}
+
+// misfiledHeader is a header stored under the hash of the wrong version.
+type misfiledHeader struct {
+	header *types.Header // Header with its mandated version set
+	stored common.Hash   // Hash the header is stored under
+}
+
+// CheckHeaderVersions verifies that every header stored in the database is
+// filed under its hash by the version the chain configuration mandates at its
+// number, as headers stored by releases predating the version scheduling may be
+// hashed by the wrong one. With repair set, misfiled headers are moved along with
+// their total difficulty, body, receipts and canonical mappings to the right hash.
+// The canonical chain is checked to link up afterwards.
+func CheckHeaderVersions(db aquadb.Database, config *params.ChainConfig, repair bool) (*HeaderVersionReport, error) {
+	iteratee, ok := db.(aquadb.Iteratee)
+	if !ok {
+		return nil, errors.New("database can't be iterated")
+	}
+	var (
+		report   = new(HeaderVersionReport)
+		misfiled []misfiledHeader
+		start    = time.Now()
+		reported = time.Now()
+	)
+	err := iteratee.IteratePrefix(headerPrefix, func(key, value []byte) error {
+		// Skip the total difficulties and canonical hashes sharing the prefix
+		if len(key) != len(headerPrefix)+8+common.HashLength {
+			return nil
+		}
+		report.Headers++
+		if time.Since(reported) >= 8*time.Second {
+			log.Info("Checking header versions", "headers", report.Headers, "misfiled", report.Misfiled, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+
+		stored := common.BytesToHash(key[len(headerPrefix)+8:])
+
+		header := new(types.Header)
+		if err := rlp.Decode(bytes.NewReader(value), header); err != nil {
+			log.Warn("Undecodable header", "hash", stored, "err", err)
+			report.Invalid++
+			return nil
+		}
+		want := types.BlockVersion(config, header)
+		if _, err := types.LookupHeaderCodec(want); err != nil {
+			log.Warn("Header without a known version", "number", header.Number, "hash", stored, "version", want)
+			report.Invalid++
+			return nil
+		}
+		if header.SetVersion(byte(want)) == stored {
+			return nil
+		}
+		// Find the version the header was hashed by instead
+		var found bool
+		for _, version := range types.HeaderVersions() {
+			if version != want && header.SetVersion(byte(version)) == stored {
+				found = true
+				break
+			}
+		}
+		if !found {
+			log.Warn("Header hash mismatch", "number", header.Number, "hash", stored)
+			report.Invalid++
+			return nil
+		}
+		wrong := header.Version
+		hash := header.SetVersion(byte(want))
+		log.Warn("Header filed under the wrong version", "number", header.Number, "hash", stored, "version", wrong, "want", want, "rehash", hash)
+		report.Misfiled++
+		misfiled = append(misfiled, misfiledHeader{header: header, stored: stored})
+
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	// Move the misfiled headers once done iterating, the database can't be
+	// written to meanwhile
+	if repair {
+		for _, entry := range misfiled {
+			if err := refileHeader(db, entry.header, entry.stored); err != nil {
+				return report, err
+			}
+			report.Repaired++
+		}
+	}
+	report.Unlinked = checkCanonicalLinks(db)
+	return report, nil
+}
+
+// refileHeader moves the data of a block stored under the wrong hash to the hash
+// of its header, updating the canonical mappings pointing to it.
+func refileHeader(db aquadb.Database, header *types.Header, stored common.Hash) error {
+	var (
+		number = header.Number.Uint64()
+		hash   = header.Hash()
+		batch  = db.NewBatch()
+	)
+	if err := WriteHeader(batch, header); err != nil {
+		return err
+	}
+	if td := GetTd(db, stored, number); td != nil {
+		if err := WriteTd(batch, hash, number, td); err != nil {
+			return err
+		}
+	}
+	// Bodies and receipts are moved in their stored form, compressed or not
+	if data, _ := db.Get(blockBodyKey(stored, number)); len(data) > 0 {
+		if err := batch.Put(blockBodyKey(hash, number), data); err != nil {
+			return err
+		}
+	}
+	receiptsKey := func(hash common.Hash) []byte {
+		return append(append(append([]byte{}, blockReceiptsPrefix...), encodeBlockNumber(number)...), hash.Bytes()...)
+	}
+	if data, _ := db.Get(receiptsKey(stored)); len(data) > 0 {
+		if err := batch.Put(receiptsKey(hash), data); err != nil {
+			return err
+		}
+	}
+	if GetCanonicalHash(db, number) == stored {
+		if err := WriteCanonicalHash(batch, hash, number); err != nil {
+			return err
+		}
+		if body := GetBodyNoVersion(db, stored, number); body != nil {
+			block := types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles)
+			if err := WriteTxLookupEntries(batch, block); err != nil {
+				return err
+			}
+		}
+	}
+	for _, head := range []struct {
+		get   func(DatabaseReader) common.Hash
+		write func(aquadb.Putter, common.Hash) error
+	}{
+		{GetHeadHeaderHash, WriteHeadHeaderHash},
+		{GetHeadBlockHash, WriteHeadBlockHash},
+		{GetHeadFastBlockHash, WriteHeadFastBlockHash},
+	} {
+		if head.get(db) == stored {
+			if err := head.write(batch, hash); err != nil {
+				return err
+			}
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	DeleteBlock(db, stored, number)
+
+	log.Info("Refiled header", "number", number, "hash", hash, "stored", stored)
+	return nil
+}
+
+// checkCanonicalLinks counts the canonical headers that are missing, or whose
+// parent hash isn't the previous canonical hash.
+func checkCanonicalLinks(db aquadb.Database) uint64 {
+	var (
+		unlinked uint64
+		parent   = GetCanonicalHash(db, 0)
+	)
+	for number := uint64(1); ; number++ {
+		hash := GetCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return unlinked
+		}
+		header := GetHeaderNoVersion(db, hash, number)
+		switch {
+		case header == nil:
+			log.Warn("Canonical header missing", "number", number, "hash", hash)
+			unlinked++
+		case header.ParentHash != parent:
+			log.Warn("Canonical header not linked to its parent", "number", number, "hash", hash, "parent", header.ParentHash, "canonical", parent)
+			unlinked++
+		}
+		parent = hash
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that headers stored under the hash of the wrong version are reported,
+// and moved to the right hash along with their block data when repairing.
+func TestCheckHeaderVersions(t *testing.T) {
+	db, _ := aquadb.NewMemDatabase()
+	config := params.TestChainConfig
+
+	// Store a canonical chain crossing HF5, filing its 6th header by keccak256
+	// as releases predating the version scheduling did
+	var (
+		parent common.Hash
+		hashes []common.Hash
+		wrong  common.Hash
+	)
+	for i := 0; i <= 7; i++ {
+		header := &types.Header{ParentHash: parent, Number: big.NewInt(int64(i)), Difficulty: big.NewInt(1), Extra: []byte{}}
+		hash := header.SetVersion(byte(config.GetBlockVersion(header.Number)))
+		if i == 6 {
+			wrong = header.SetVersion(byte(types.H_KECCAK256))
+			if wrong == hash {
+				t.Fatalf("header versions hash alike")
+			}
+		}
+		WriteHeader(db, header)
+		WriteTd(db, header.Hash(), uint64(i), big.NewInt(int64(i+1)))
+		WriteBody(db, header.Hash(), uint64(i), &types.Body{})
+		WriteCanonicalHash(db, header.Hash(), uint64(i))
+		WriteHeadHeaderHash(db, header.Hash())
+
+		parent = hash
+		hashes = append(hashes, hash)
+	}
+	WriteHeadHeaderHash(db, wrong)
+
+	report, err := CheckHeaderVersions(db, config, false)
+	if err != nil {
+		t.Fatalf("failed to check header versions: %v", err)
+	}
+	if want := (HeaderVersionReport{Headers: 8, Misfiled: 1, Unlinked: 1}); *report != want {
+		t.Fatalf("report mismatch: have %+v, want %+v", *report, want)
+	}
+	if report, err = CheckHeaderVersions(db, config, true); err != nil {
+		t.Fatalf("failed to repair header versions: %v", err)
+	}
+	if want := (HeaderVersionReport{Headers: 8, Misfiled: 1, Repaired: 1}); *report != want {
+		t.Fatalf("repair report mismatch: have %+v, want %+v", *report, want)
+	}
+	if report, _ = CheckHeaderVersions(db, config, false); *report != (HeaderVersionReport{Headers: 8}) {
+		t.Fatalf("report after repair mismatch: have %+v", *report)
+	}
+	// Check the block data moved over to the right hash
+	if hash := GetCanonicalHash(db, 6); hash != hashes[6] {
+		t.Errorf("canonical hash mismatch: have %x, want %x", hash, hashes[6])
+	}
+	if hash := GetHeadHeaderHash(db); hash != hashes[6] {
+		t.Errorf("head header hash mismatch: have %x, want %x", hash, hashes[6])
+	}
+	if td := GetTd(db, hashes[6], 6); td == nil || td.Uint64() != 7 {
+		t.Errorf("total difficulty mismatch: have %v, want 7", td)
+	}
+	if GetBodyNoVersion(db, hashes[6], 6) == nil {
+		t.Errorf("body not moved")
+	}
+	if GetHeaderNoVersion(db, wrong, 6) != nil || GetTd(db, wrong, 6) != nil {
+		t.Errorf("misfiled entries not removed")
+	}
+	if number := GetBlockNumber(db, hashes[6]); number != 6 {
+		t.Errorf("block number mismatch: have %d, want 6", number)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/rlp"
+)
+
+var reindexProgressPrefix = []byte("ReindexProgress-") // reindexProgressPrefix + index name -> next item to index (uint64 big endian)
+
+// WriteReindexProgress stores the next item to be processed by an interrupted
+// rebuild of the given index.
+func WriteReindexProgress(db aquadb.Putter, index string, next uint64) error {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], next)
+	return db.Put(append(reindexProgressPrefix, index...), enc[:])
+}
+
+// GetReindexProgress retrieves the progress of an interrupted rebuild of the
+// given index, returning false if no rebuild is in progress.
+func GetReindexProgress(db DatabaseReader, index string) (uint64, bool) {
+	enc, _ := db.Get(append(reindexProgressPrefix, index...))
+	if len(enc) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(enc), true
+}
+
+// DeleteReindexProgress marks the rebuild of the given index as finished.
+func DeleteReindexProgress(db DatabaseDeleter, index string) {
+	db.Delete(append(reindexProgressPrefix, index...))
+}
+
+// ReindexTxLookups regenerates the transaction lookup entries of the canonical
+// blocks in the range [from, to] from the stored block bodies, spreading the
+// work over the given number of threads.
+func ReindexTxLookups(db aquadb.Database, from, to uint64, threads int) error {
+	if threads < 1 {
+		threads = 1
+	}
+	var (
+		numbers = make(chan uint64, threads)
+		errc    = make(chan error, threads)
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Keep draining the queue after a failure so the feeder doesn't block
+			var (
+				batch = db.NewBatch()
+				err   error
+			)
+			for number := range numbers {
+				if err != nil {
+					continue
+				}
+				if err = writeCanonicalTxLookups(db, batch, number); err == nil && batch.ValueSize() >= aquadb.IdealBatchSize {
+					err = batch.Write()
+					batch.Reset()
+				}
+			}
+			if err == nil {
+				err = batch.Write()
+			}
+			if err != nil {
+				errc <- err
+			}
+		}()
+	}
+	for number := from; number <= to; number++ {
+		numbers <- number
+	}
+	close(numbers)
+	wg.Wait()
+
+	select {
+	case err := <-errc:
+		return err
+	default:
+		return nil
+	}
+}
+
+// writeCanonicalTxLookups writes the lookup entries of the transactions in the
+// canonical block with the given number into the batch.
+func writeCanonicalTxLookups(db DatabaseReader, batch aquadb.Putter, number uint64) error {
+	hash := GetCanonicalHash(db, number)
+	if hash == (common.Hash{}) {
+		return fmt.Errorf("canonical block #%d unknown", number)
+	}
+	body := GetBodyNoVersion(db, hash, number)
+	if body == nil {
+		return fmt.Errorf("block #%d [%x…] body missing", number, hash[:4])
+	}
+	for i, tx := range body.Transactions {
+		data, err := rlp.EncodeToBytes(TxLookupEntry{BlockHash: hash, BlockIndex: number, Index: uint64(i)})
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(append(lookupPrefix, tx.Hash().Bytes()...), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that transaction lookup entries are regenerated from the stored bodies.
+func TestReindexTxLookups(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		db, _   = aquadb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, aquahash.NewFaker(), db, 32, func(i int, gen *BlockGen) {
+		for j := 0; j < 2; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{1}, big.NewInt(1), params.TxGas, nil, nil), signer, key)
+			gen.AddTx(tx)
+		}
+	})
+	chain, _ := NewBlockChain(db, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Drop all the lookup entries and regenerate them
+	for _, block := range blocks {
+		for _, tx := range block.Transactions() {
+			DeleteTxLookupEntry(db, tx.Hash())
+		}
+	}
+	if err := ReindexTxLookups(db, 1, 32, 4); err != nil {
+		t.Fatalf("failed to reindex: %v", err)
+	}
+	for _, block := range blocks {
+		for i, tx := range block.Transactions() {
+			hash, number, index := GetTxLookupEntry(db, tx.Hash())
+			if hash != block.Hash() || number != block.NumberU64() || index != uint64(i) {
+				t.Fatalf("tx %x: lookup mismatch: have %x/%d/%d, want %x/%d/%d", tx.Hash(), hash, number, index, block.Hash(), block.NumberU64(), i)
+			}
+		}
+	}
+	// Reindexing unknown blocks should fail
+	if err := ReindexTxLookups(db, 30, 40, 4); err == nil {
+		t.Fatalf("reindex of missing blocks succeeded")
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/rlp"
+)
+
+var (
+	schemaVersionKey  = []byte("SchemaVersion")           // schemaVersionKey -> version of the database layout (uint64 big endian)
+	schemaProgressKey = []byte("SchemaMigrationProgress") // schemaProgressKey -> version being migrated to + position reached (2x uint64 big endian)
+
+	deduplicateDataKey = []byte("dbUpgrade_20170714deduplicateData") // marker of the deduplication predating the schema versions
+)
+
+// Migration converts the chain database from the schema version preceding it
+// to its own. Migrate is passed the position saved by an interrupted earlier
+// run, zero on the first one, and saves its progress with save so a rerun
+// resumes from there.
+type Migration struct {
+	Name    string
+	Migrate func(db aquadb.Database, from uint64, save func(next uint64) error) error
+}
+
+// migrations lists the changes of the database layout, the schema version of a
+// database being the number of them it went through. New ones are appended.
+var migrations = []Migration{
+	{Name: "deduplicate transaction data", Migrate: migrateDeduplicateData},
+	{Name: "snappy compressed bodies and receipts", Migrate: markLayout},
+	{Name: "coin supply index", Migrate: markLayout},
+	{Name: "account history index", Migrate: markLayout},
+	{Name: "transaction lookup tail", Migrate: markLayout},
+}
+
+// SchemaVersion returns the version of the database layout of this release.
+func SchemaVersion() uint64 {
+	return uint64(len(migrations))
+}
+
+// GetSchemaVersion retrieves the version of the database layout, returning
+// false if the database predates the schema versions.
+func GetSchemaVersion(db DatabaseReader) (uint64, bool) {
+	enc, _ := db.Get(schemaVersionKey)
+	if len(enc) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(enc), true
+}
+
+// WriteSchemaVersion stores the version of the database layout.
+func WriteSchemaVersion(db aquadb.Putter, version uint64) error {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], version)
+	return db.Put(schemaVersionKey, enc[:])
+}
+
+// getMigrationProgress retrieves the position reached by an interrupted
+// migration to the given version, zero if there's none.
+func getMigrationProgress(db DatabaseReader, version uint64) uint64 {
+	enc, _ := db.Get(schemaProgressKey)
+	if len(enc) != 16 || binary.BigEndian.Uint64(enc[:8]) != version {
+		return 0
+	}
+	return binary.BigEndian.Uint64(enc[8:])
+}
+
+// writeMigrationProgress stores the position reached by the migration to the
+// given version.
+func writeMigrationProgress(db aquadb.Putter, version, next uint64) error {
+	var enc [16]byte
+	binary.BigEndian.PutUint64(enc[:8], version)
+	binary.BigEndian.PutUint64(enc[8:], next)
+	return db.Put(schemaProgressKey, enc[:])
+}
+
+// PendingMigrations returns the schema version of the database and the
+// migrations it still has to go through. It fails if the database was written
+// by a newer release, with a layout this one doesn't know.
+func PendingMigrations(db DatabaseReader) (uint64, []Migration, error) {
+	version, ok := GetSchemaVersion(db)
+	if !ok {
+		version = legacySchemaVersion(db)
+	}
+	if version > SchemaVersion() {
+		return version, nil, fmt.Errorf("database schema version %d is newer than %d, the latest known by this release", version, SchemaVersion())
+	}
+	return version, migrations[version:], nil
+}
+
+// legacySchemaVersion derives the schema version of a database predating them
+// from the upgrade markers in use back then. Empty databases are up to date.
+func legacySchemaVersion(db DatabaseReader) uint64 {
+	if data, _ := db.Get(headHeaderKey); len(data) == 0 {
+		return SchemaVersion()
+	}
+	if data, _ := db.Get(deduplicateDataKey); len(data) > 0 && data[0] == 42 {
+		return 1
+	}
+	return 0
+}
+
+// MigrateDatabase brings the chain database to the schema version of this
+// release, running the pending migrations in order. An interrupted migration is
+// resumed on the next call.
+func MigrateDatabase(db aquadb.Database) error {
+	version, pending, err := PendingMigrations(db)
+	if err != nil {
+		return err
+	}
+	for _, migration := range pending {
+		version++
+		var (
+			from  = getMigrationProgress(db, version)
+			start = time.Now()
+		)
+		if from > 0 {
+			log.Warn("Resuming database migration", "version", version, "name", migration.Name, "from", from)
+		} else {
+			log.Warn("Migrating database", "version", version, "name", migration.Name)
+		}
+		save := func(next uint64) error { return writeMigrationProgress(db, version, next) }
+		if err := migration.Migrate(db, from, save); err != nil {
+			return fmt.Errorf("database migration %d (%s) failed: %v", version, migration.Name, err)
+		}
+		if err := WriteSchemaVersion(db, version); err != nil {
+			return err
+		}
+		db.Delete(schemaProgressKey)
+		log.Info("Migrated database", "version", version, "name", migration.Name, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	// Record the version of fresh and legacy databases too
+	if _, ok := GetSchemaVersion(db); !ok {
+		return WriteSchemaVersion(db, version)
+	}
+	return nil
+}
+
+// migrateDeduplicateData converts the transaction metadata entries (hash+0x01)
+// of the original layout to lookup entries, deleting the transaction and
+// receipt copies they came with. Converted entries are gone, so a rerun picks
+// up where an interrupted one stopped, from only counts them.
+func migrateDeduplicateData(db aquadb.Database, from uint64, save func(next uint64) error) error {
+	iteratee, ok := db.(aquadb.Iteratee)
+	if !ok {
+		return fmt.Errorf("%T can't be iterated", db)
+	}
+	converted := from
+	err := iteratee.Iterate(func(key, value []byte) error {
+		// Skip any entries that don't look like old transaction meta entries (<hash>0x01)
+		if len(key) != common.HashLength+1 || key[common.HashLength] != oldTxMetaSuffix[0] {
+			return nil
+		}
+		// Skip any entries that don't contain metadata (name clash between <hash>0x01 and <some-prefix><hash>)
+		var meta TxLookupEntry
+		if err := rlp.DecodeBytes(value, &meta); err != nil {
+			return nil
+		}
+		hash := common.CopyBytes(key[:common.HashLength])
+		if hash[0] == lookupPrefix[0] {
+			// Potential clash with a lookup entry, the old hash must point to a live transaction
+			if tx, _, _, _ := GetTransaction(db, common.BytesToHash(hash)); tx == nil || !bytes.Equal(tx.Hash().Bytes(), hash) {
+				return nil
+			}
+		}
+		// Convert the old metadata to a new lookup entry, delete the duplicate data
+		if err := db.Put(append(lookupPrefix, hash...), common.CopyBytes(value)); err != nil {
+			return err
+		}
+		for _, dup := range [][]byte{hash, append(oldReceiptsPrefix, hash...), common.CopyBytes(key)} {
+			if err := db.Delete(dup); err != nil {
+				return err
+			}
+		}
+		if converted++; converted%100000 == 0 {
+			log.Info("Deduplicating database entries", "deduped", converted)
+			return save(converted)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Info("Database deduplication successful", "deduped", converted)
+	return db.Put(deduplicateDataKey, []byte{42}) // Keep older releases from redoing it
+}
+
+// markLayout is the migration of layout changes only adding entries, written
+// once the feature using them is enabled, so there's nothing to convert. The
+// schema version still moves, refusing the database to older releases unable
+// to read them.
+func markLayout(db aquadb.Database, from uint64, save func(next uint64) error) error {
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/rlp"
+)
+
+// Tests that empty databases start at the latest schema version, and that those
+// of newer releases are refused.
+func TestSchemaVersion(t *testing.T) {
+	db, _ := aquadb.NewMemDatabase()
+	if err := MigrateDatabase(db); err != nil {
+		t.Fatalf("failed to migrate empty database: %v", err)
+	}
+	if version, ok := GetSchemaVersion(db); !ok || version != SchemaVersion() {
+		t.Fatalf("schema version mismatch: have %d (%v), want %d", version, ok, SchemaVersion())
+	}
+	WriteSchemaVersion(db, SchemaVersion()+1)
+	if err := MigrateDatabase(db); err == nil {
+		t.Fatalf("database of a newer release migrated")
+	}
+}
+
+// Tests that databases predating the schema versions get the transaction data
+// deduplicated.
+func TestSchemaMigrateDeduplicateData(t *testing.T) {
+	db, _ := aquadb.NewMemDatabase()
+	db.Put(headHeaderKey, common.Hash{0xff}.Bytes())
+
+	hash := common.Hash{0x01}
+	meta, _ := rlp.EncodeToBytes(TxLookupEntry{BlockHash: common.Hash{0x02}, BlockIndex: 1})
+	db.Put(append(hash.Bytes(), oldTxMetaSuffix...), meta)
+	db.Put(hash.Bytes(), []byte{0x03})
+	db.Put(append(oldReceiptsPrefix, hash.Bytes()...), []byte{0x04})
+
+	if version, pending, err := PendingMigrations(db); err != nil || version != 0 || len(pending) != len(migrations) {
+		t.Fatalf("pending migrations mismatch: have version %d, %d pending, err %v", version, len(pending), err)
+	}
+	if err := MigrateDatabase(db); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	if have, _ := db.Get(append(lookupPrefix, hash.Bytes()...)); string(have) != string(meta) {
+		t.Errorf("lookup entry mismatch: have %x, want %x", have, meta)
+	}
+	for _, key := range [][]byte{hash.Bytes(), append(oldReceiptsPrefix, hash.Bytes()...), append(hash.Bytes(), oldTxMetaSuffix...)} {
+		if ok, _ := db.Has(key); ok {
+			t.Errorf("duplicate entry %x not deleted", key)
+		}
+	}
+	if version, _ := GetSchemaVersion(db); version != SchemaVersion() {
+		t.Errorf("schema version mismatch: have %d, want %d", version, SchemaVersion())
+	}
+}
+
+// Tests that databases of releases which deduplicated the transaction data, but
+// predate the layout markers, go through the markers only.
+func TestSchemaMigrateLayoutMarkers(t *testing.T) {
+	db, _ := aquadb.NewMemDatabase()
+	WriteSchemaVersion(db, 1)
+
+	version, pending, err := PendingMigrations(db)
+	if err != nil || version != 1 || len(pending) != 4 {
+		t.Fatalf("pending migrations mismatch: have version %d, %d pending, err %v", version, len(pending), err)
+	}
+	if err := MigrateDatabase(db); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	if version, _ := GetSchemaVersion(db); version != 5 {
+		t.Errorf("schema version mismatch: have %d, want 5", version)
+	}
+}
+
+// Tests that an interrupted migration resumes from its saved progress, without
+// rerunning the completed ones.
+func TestSchemaMigrateResume(t *testing.T) {
+	defer func(saved []Migration) { migrations = saved }(migrations)
+
+	var (
+		runs    [2]int
+		resumed uint64
+		fail    = errors.New("interrupted")
+	)
+	migrations = []Migration{
+		{Name: "first", Migrate: func(db aquadb.Database, from uint64, save func(uint64) error) error {
+			runs[0]++
+			return nil
+		}},
+		{Name: "second", Migrate: func(db aquadb.Database, from uint64, save func(uint64) error) error {
+			runs[1]++
+			if resumed = from; from == 0 {
+				save(5)
+				return fail
+			}
+			return nil
+		}},
+	}
+	db, _ := aquadb.NewMemDatabase()
+	WriteSchemaVersion(db, 0)
+
+	if err := MigrateDatabase(db); err == nil {
+		t.Fatalf("interrupted migration succeeded")
+	}
+	if version, _ := GetSchemaVersion(db); version != 1 {
+		t.Fatalf("interrupted schema version mismatch: have %d, want 1", version)
+	}
+	if err := MigrateDatabase(db); err != nil {
+		t.Fatalf("failed to resume migration: %v", err)
+	}
+	if runs != [2]int{1, 2} || resumed != 5 {
+		t.Errorf("migration runs mismatch: have %v from %d, want [1 2] from 5", runs, resumed)
+	}
+	if version, _ := GetSchemaVersion(db); version != 2 {
+		t.Errorf("schema version mismatch: have %d, want 2", version)
+	}
+	if ok, _ := db.Has(schemaProgressKey); ok {
+		t.Errorf("migration progress not deleted")
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that the senders recovered in the background match the signers of the
+// transactions, however the transactions are split across the threads.
+func TestSenderCacher(t *testing.T) {
+	signer := types.NewEIP155Signer(big.NewInt(1))
+
+	keys := make([]*ecdsa.PrivateKey, 8)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	for _, count := range []int{0, 1, 3, 17, 100} {
+		txs := make([]*types.Transaction, count)
+		for i := range txs {
+			tx := types.NewTransaction(uint64(i), common.Address{}, big.NewInt(1), params.TxGas, big.NewInt(1), nil)
+			txs[i], _ = types.SignTx(tx, signer, keys[i%len(keys)])
+		}
+		blocks := []*types.Block{
+			types.NewBlock(&types.Header{Number: big.NewInt(1)}, txs[:count/2], nil, nil),
+			types.NewBlock(&types.Header{Number: big.NewInt(2)}, txs[count/2:], nil, nil),
+		}
+		cacher := newTxSenderCacher(4)
+		cacher.recoverFromBlocks(signer, blocks)
+
+		for i, tx := range txs {
+			from, err := types.Sender(signer, tx)
+			if err != nil {
+				t.Fatalf("%d txs: tx %d: failed to recover sender: %v", count, i, err)
+			}
+			if want := crypto.PubkeyToAddress(keys[i%len(keys)].PublicKey); from != want {
+				t.Errorf("%d txs: tx %d: sender mismatch: have %x, want %x", count, i, from, want)
+			}
+		}
+	}
+}
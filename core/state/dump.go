@@ -19,6 +19,7 @@ package state
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/rlp"
@@ -47,28 +48,62 @@ func (self *StateDB) RawDump() Dump {
 
 	it := trie.NewIterator(self.trie.NodeIterator(nil))
 	for it.Next() {
-		addr := self.trie.GetKey(it.Key)
-		var data Account
-		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
-			panic(err)
-		}
+		addr, account := self.dumpAccount(it)
+		dump.Accounts[common.Bytes2Hex(addr)] = account
+	}
+	return dump
+}
+
+// dumpAccount assembles the dump of the account the state iterator points to.
+func (self *StateDB) dumpAccount(it *trie.Iterator) ([]byte, DumpAccount) {
+	addr := self.trie.GetKey(it.Key)
+	var data Account
+	if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+		panic(err)
+	}
+
+	obj := newObject(nil, common.BytesToAddress(addr), data, nil)
+	account := DumpAccount{
+		Balance:  data.Balance.String(),
+		Nonce:    data.Nonce,
+		Root:     common.Bytes2Hex(data.Root[:]),
+		CodeHash: common.Bytes2Hex(data.CodeHash),
+		Code:     common.Bytes2Hex(obj.Code(self.db)),
+		Storage:  make(map[string]string),
+	}
+	storageIt := trie.NewIterator(obj.getTrie(self.db).NodeIterator(nil))
+	for storageIt.Next() {
+		account.Storage[common.Bytes2Hex(self.trie.GetKey(storageIt.Key))] = common.Bytes2Hex(storageIt.Value)
+	}
+	return addr, account
+}
+
+// IterativeDump streams the state as JSON lines, the first holding the state
+// root and each following one an account, without loading the entire state into
+// memory. The progress callback, if set, is invoked with every dumped account.
+func (self *StateDB) IterativeDump(w io.Writer, progress func(accounts int)) error {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(struct {
+		Root string `json:"root"`
+	}{fmt.Sprintf("%x", self.trie.Hash())}); err != nil {
+		return err
+	}
+	it := trie.NewIterator(self.trie.NodeIterator(nil))
+	for accounts := 1; it.Next(); accounts++ {
+		addr, account := self.dumpAccount(it)
+		line := struct {
+			Address string `json:"address"`
+			DumpAccount
+		}{common.Bytes2Hex(addr), account}
 
-		obj := newObject(nil, common.BytesToAddress(addr), data, nil)
-		account := DumpAccount{
-			Balance:  data.Balance.String(),
-			Nonce:    data.Nonce,
-			Root:     common.Bytes2Hex(data.Root[:]),
-			CodeHash: common.Bytes2Hex(data.CodeHash),
-			Code:     common.Bytes2Hex(obj.Code(self.db)),
-			Storage:  make(map[string]string),
+		if err := enc.Encode(line); err != nil {
+			return err
 		}
-		storageIt := trie.NewIterator(obj.getTrie(self.db).NodeIterator(nil))
-		for storageIt.Next() {
-			account.Storage[common.Bytes2Hex(self.trie.GetKey(storageIt.Key))] = common.Bytes2Hex(storageIt.Value)
+		if progress != nil {
+			progress(accounts)
 		}
-		dump.Accounts[common.Bytes2Hex(addr)] = account
 	}
-	return dump
+	return it.Err
 }
 
 func (self *StateDB) Dump() []byte {
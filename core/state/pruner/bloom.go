@@ -0,0 +1,66 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"encoding/binary"
+
+	"github.com/aquanetwork/aquachain/common"
+)
+
+// bloomHashes is the number of bits set in the filter per inserted entry.
+const bloomHashes = 4
+
+// stateBloom is a bloom filter of state entry hashes. As the entries are keyed
+// by their Keccak256 hash, the bit positions are taken straight from the key
+// instead of hashing it again.
+type stateBloom struct {
+	bits []uint64
+}
+
+// newStateBloom creates a bloom filter of the given size in bytes.
+func newStateBloom(size uint64) *stateBloom {
+	if size < 8 {
+		size = 8
+	}
+	return &stateBloom{bits: make([]uint64, size/8)}
+}
+
+// add inserts a hash into the filter.
+func (b *stateBloom) add(hash common.Hash) {
+	for i := 0; i < bloomHashes; i++ {
+		bit := b.position(hash, i)
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// contains reports whether the hash may have been inserted into the filter. It
+// never reports false for an inserted hash.
+func (b *stateBloom) contains(hash common.Hash) bool {
+	for i := 0; i < bloomHashes; i++ {
+		bit := b.position(hash, i)
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// position returns the i-th bit position of the hash in the filter.
+func (b *stateBloom) position(hash common.Hash, i int) uint64 {
+	return binary.BigEndian.Uint64(hash[i*8:]) % uint64(len(b.bits)*64)
+}
@@ -0,0 +1,127 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner implements offline pruning of historical state from the chain
+// database, reclaiming the disk space of state tries no longer referenced by the
+// retained ones.
+package pruner
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/log"
+)
+
+// errUnsupportedDatabase is returned if the keys of the database to prune can't
+// be iterated.
+var errUnsupportedDatabase = errors.New("database does not support iteration")
+
+// Stats are the results of a pruning run.
+type Stats struct {
+	Retained    uint64             // State entries reachable from the retained roots
+	Deleted     uint64             // State entries deleted from the database
+	DeletedSize common.StorageSize // Storage size of the deleted entries
+	MarkTime    time.Duration      // Time spent marking the retained state
+	SweepTime   time.Duration      // Time spent deleting the unreferenced state
+	CompactTime time.Duration      // Time spent compacting the database
+}
+
+// Prune deletes every trie node and contract code from the database which isn't
+// reachable from one of the given state roots. The retained state is recorded in
+// a bloom filter of bloomSize bytes, so a small fraction of stale entries may
+// survive, but no referenced one is ever deleted.
+//
+// The database must not be in use while pruning, all state besides that of the
+// retained roots is lost.
+func Prune(db aquadb.Database, roots []common.Hash, bloomSize uint64) (*Stats, error) {
+	iteratee, ok := db.(aquadb.Iteratee)
+	if !ok {
+		return nil, errUnsupportedDatabase
+	}
+	stats := new(Stats)
+
+	// Mark all state entries reachable from the retained roots
+	start := time.Now()
+	bloom := newStateBloom(bloomSize)
+	sdb := state.NewDatabase(db)
+	for _, root := range roots {
+		statedb, err := state.New(root, sdb)
+		if err != nil {
+			return nil, err
+		}
+		it := state.NewNodeIterator(statedb)
+		for it.Next() {
+			if it.Hash == (common.Hash{}) {
+				continue // embedded node, stored within its parent
+			}
+			bloom.add(it.Hash)
+			if stats.Retained++; stats.Retained%1000000 == 0 {
+				log.Info("Marking retained state", "root", root, "entries", stats.Retained, "elapsed", common.PrettyDuration(time.Since(start)))
+			}
+		}
+		if it.Error != nil {
+			return nil, it.Error
+		}
+	}
+	stats.MarkTime = time.Since(start)
+	log.Info("Marked retained state", "entries", stats.Retained, "elapsed", common.PrettyDuration(stats.MarkTime))
+
+	// Sweep all unmarked state entries from the database
+	start = time.Now()
+	err := iteratee.Iterate(func(key, value []byte) error {
+		// State entries are keyed by the hash of their content, skip anything else
+		if len(key) != common.HashLength {
+			return nil
+		}
+		hash := common.BytesToHash(key)
+		if bloom.contains(hash) || !bytes.Equal(crypto.Keccak256(value), key) {
+			return nil
+		}
+		if err := db.Delete(common.CopyBytes(key)); err != nil {
+			return err
+		}
+		stats.Deleted++
+		stats.DeletedSize += common.StorageSize(len(key) + len(value))
+
+		if stats.Deleted%1000000 == 0 {
+			log.Info("Deleting stale state", "entries", stats.Deleted, "size", stats.DeletedSize, "elapsed", common.PrettyDuration(time.Since(start)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats.SweepTime = time.Since(start)
+	log.Info("Deleted stale state", "entries", stats.Deleted, "size", stats.DeletedSize, "elapsed", common.PrettyDuration(stats.SweepTime))
+
+	// Compact the database to actually release the freed disk space
+	if compacter, ok := db.(aquadb.Compacter); ok {
+		start = time.Now()
+		log.Info("Compacting database, this may take a while")
+		if err := compacter.Compact(); err != nil {
+			return nil, err
+		}
+		stats.CompactTime = time.Since(start)
+		log.Info("Compacted database", "elapsed", common.PrettyDuration(stats.CompactTime))
+	}
+	return stats, nil
+}
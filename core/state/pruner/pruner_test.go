@@ -0,0 +1,120 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/state"
+)
+
+// commitState applies the modifications to the state at root and flushes the
+// resulting state to disk.
+func commitState(t *testing.T, sdb state.Database, root common.Hash, modify func(*state.StateDB)) common.Hash {
+	statedb, err := state.New(root, sdb)
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", root, err)
+	}
+	modify(statedb)
+	root, err = statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	return root
+}
+
+// Tests that pruning deletes the state only reachable from dropped roots, while
+// keeping the retained state and unrelated database entries intact.
+func TestPrune(t *testing.T) {
+	db, _ := aquadb.NewMemDatabase()
+	sdb := state.NewDatabase(db)
+
+	old := commitState(t, sdb, common.Hash{}, func(s *state.StateDB) {
+		for i := byte(1); i <= 50; i++ {
+			addr := common.BytesToAddress([]byte{i})
+			s.SetBalance(addr, big.NewInt(int64(i)))
+			s.SetState(addr, common.Hash{i}, common.Hash{i})
+		}
+		s.SetCode(common.BytesToAddress([]byte{1}), []byte{0x01, 0x02})
+	})
+	head := commitState(t, sdb, old, func(s *state.StateDB) {
+		for i := byte(1); i <= 50; i += 2 {
+			addr := common.BytesToAddress([]byte{i})
+			s.SetBalance(addr, big.NewInt(1000))
+			s.SetState(addr, common.Hash{i}, common.Hash{0xff})
+		}
+	})
+	// Insert some entries that aren't state at all
+	unrelated := map[string][]byte{
+		"LastBlock":                       common.Hash{0x01}.Bytes(),
+		string(common.Hash{0xaa}.Bytes()): []byte("not a trie node"),
+	}
+	for key, value := range unrelated {
+		db.Put([]byte(key), value)
+	}
+	stats, err := Prune(db, []common.Hash{head}, 1024*1024)
+	if err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	if stats.Retained == 0 || stats.Deleted == 0 {
+		t.Fatalf("nothing pruned: %+v", stats)
+	}
+	// The retained state must be complete, the dropped one gone
+	statedb, err := state.New(head, state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("retained state missing: %v", err)
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	if it.Error != nil {
+		t.Fatalf("retained state incomplete: %v", it.Error)
+	}
+	if balance := statedb.GetBalance(common.BytesToAddress([]byte{1})); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("retained balance mismatch: have %v, want 1000", balance)
+	}
+	if code := statedb.GetCode(common.BytesToAddress([]byte{1})); len(code) != 2 {
+		t.Errorf("retained code missing")
+	}
+	if ok, _ := db.Has(old.Bytes()); ok {
+		t.Errorf("dropped state root still present")
+	}
+	for key, value := range unrelated {
+		if have, err := db.Get([]byte(key)); err != nil || string(have) != string(value) {
+			t.Errorf("unrelated entry %x deleted", key)
+		}
+	}
+}
+
+// Tests that the bloom filter never misses an inserted hash.
+func TestStateBloom(t *testing.T) {
+	bloom := newStateBloom(64)
+	for i := 0; i < 100; i++ {
+		bloom.add(common.BigToHash(big.NewInt(int64(i * 7919))))
+	}
+	for i := 0; i < 100; i++ {
+		if !bloom.contains(common.BigToHash(big.NewInt(int64(i * 7919)))) {
+			t.Fatalf("inserted hash %d missing", i)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/trie"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// diskCacheLimit is the number of snapshot entries cached in memory in front of
+// the database.
+const diskCacheLimit = 256 * 1024
+
+// diskLayer is a low level persistent snapshot built on top of a key-value store.
+type diskLayer struct {
+	diskdb aquadb.Database // Key-value store containing the base snapshot
+	triedb *trie.Database  // Trie node cache for reconstructing the snapshot
+	cache  *lru.Cache      // Cache to avoid hitting the disk for direct access
+
+	root  common.Hash // Root hash of the base snapshot
+	stale bool        // Signals that the layer became stale (state progressed)
+
+	genMarker []byte             // Last account covered by the snapshot, nil once fully generated
+	genWipe   bool               // Whether the entries past the marker have to be wiped before generating
+	genAbort  chan chan struct{} // Notification channel to abort generating the snapshot in this layer
+
+	lock sync.RWMutex
+}
+
+// loadDiskLayer opens the snapshot persisted in the database if it's of the
+// given root, resuming its generation if it was interrupted, or starts
+// generating a new one otherwise.
+func loadDiskLayer(diskdb aquadb.Database, triedb *trie.Database, root common.Hash) *diskLayer {
+	cache, _ := lru.New(diskCacheLimit)
+	dl := &diskLayer{
+		diskdb: diskdb,
+		triedb: triedb,
+		cache:  cache,
+		root:   root,
+	}
+	stored, _ := diskdb.Get(snapshotRootKey)
+	generating, _ := diskdb.Has(snapshotGeneratorKey)
+
+	switch {
+	case len(stored) == common.HashLength && common.BytesToHash(stored) == root && !generating:
+		log.Info("Loaded state snapshot", "root", root)
+		return dl
+
+	case len(stored) == common.HashLength && common.BytesToHash(stored) == root:
+		marker, _ := diskdb.Get(snapshotGeneratorKey)
+		dl.genMarker = append([]byte{}, marker...)
+		log.Info("Resuming state snapshot generation", "root", root, "at", common.BytesToHash(marker))
+
+	default:
+		dl.genMarker = []byte{}
+		if err := diskdb.Put(snapshotGeneratorKey, dl.genMarker); err != nil {
+			log.Crit("Failed to store snapshot generator marker", "err", err)
+		}
+		if err := diskdb.Put(snapshotRootKey, root.Bytes()); err != nil {
+			log.Crit("Failed to store snapshot root", "err", err)
+		}
+		log.Info("Generating state snapshot", "root", root)
+	}
+	// Anything past the marker may be left over from an interrupted generation
+	// or a snapshot of another root, so wipe it before generating
+	dl.genWipe = true
+	dl.genAbort = make(chan chan struct{})
+	go dl.generate(dl.genAbort)
+
+	return dl
+}
+
+// Root returns  root hash for which this snapshot was made.
+func (dl *diskLayer) Root() common.Hash {
+	return dl.root
+}
+
+// Stale return whether this layer has become stale (was flattened across) or if
+// it's still live.
+func (dl *diskLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+// covered returns whether the entries of an account are in the range already
+// generated. The layer lock must be held.
+func (dl *diskLayer) covered(hash common.Hash) bool {
+	return dl.genMarker == nil || bytes.Compare(hash[:], dl.genMarker) <= 0
+}
+
+// Account directly retrieves the account associated with a particular hash in
+// the snapshot slim data format.
+func (dl *diskLayer) Account(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !dl.covered(hash) {
+		return nil, ErrNotCoveredYet
+	}
+	return dl.get(accountKey(hash)), nil
+}
+
+// Storage directly retrieves the storage data associated with a particular hash,
+// within a particular account.
+func (dl *diskLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !dl.covered(accountHash) {
+		return nil, ErrNotCoveredYet
+	}
+	return dl.get(storageKey(accountHash, storageHash)), nil
+}
+
+// get retrieves a snapshot entry through the cache, nil if missing.
+func (dl *diskLayer) get(key []byte) []byte {
+	if blob, ok := dl.cache.Get(string(key)); ok {
+		return blob.([]byte)
+	}
+	// Database errors are indistinguishable from missing entries
+	blob, _ := dl.diskdb.Get(key)
+	if len(blob) == 0 {
+		blob = nil
+	}
+	dl.cache.Add(string(key), blob)
+	return blob
+}
+
+// merge flattens a diff layer on top of this one into the database, returning
+// the new disk layer and marking this one stale. Only the entries in the range
+// already generated are written, the generation of the rest being resumed from
+// the new state.
+func (dl *diskLayer) merge(diff *diffLayer) *diskLayer {
+	dl.stopGeneration()
+
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.stale = true
+
+	// Drop the root first, so a crash midway is detected as a mismatching snapshot
+	if err := dl.diskdb.Delete(snapshotRootKey); err != nil {
+		log.Crit("Failed to remove snapshot root", "err", err)
+	}
+	for hash := range diff.destructs {
+		if dl.covered(hash) {
+			dl.deleteAccount(hash)
+		}
+	}
+	batch := dl.diskdb.NewBatch()
+	for hash, data := range diff.accounts {
+		if !dl.covered(hash) {
+			continue
+		}
+		key := accountKey(hash)
+		if data == nil {
+			dl.delete(key)
+			continue
+		}
+		if err := batch.Put(key, data); err != nil {
+			log.Crit("Failed to store snapshot account", "err", err)
+		}
+		dl.cache.Add(string(key), data)
+	}
+	for accountHash, slots := range diff.storage {
+		if !dl.covered(accountHash) {
+			continue
+		}
+		for storageHash, data := range slots {
+			key := storageKey(accountHash, storageHash)
+			if data == nil {
+				dl.delete(key)
+				continue
+			}
+			if err := batch.Put(key, data); err != nil {
+				log.Crit("Failed to store snapshot storage slot", "err", err)
+			}
+			dl.cache.Add(string(key), data)
+		}
+	}
+	if dl.genMarker != nil {
+		if err := batch.Put(snapshotGeneratorKey, dl.genMarker); err != nil {
+			log.Crit("Failed to store snapshot generator marker", "err", err)
+		}
+	}
+	if err := batch.Put(snapshotRootKey, diff.root.Bytes()); err != nil {
+		log.Crit("Failed to store snapshot root", "err", err)
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write snapshot", "err", err)
+	}
+	res := &diskLayer{
+		diskdb:    dl.diskdb,
+		triedb:    dl.triedb,
+		cache:     dl.cache,
+		root:      diff.root,
+		genMarker: dl.genMarker,
+		genWipe:   dl.genWipe,
+	}
+	if res.genMarker != nil {
+		res.genAbort = make(chan chan struct{})
+		go res.generate(res.genAbort)
+	}
+	return res
+}
+
+// delete removes a snapshot entry from the database and the cache.
+func (dl *diskLayer) delete(key []byte) {
+	if err := dl.diskdb.Delete(key); err != nil {
+		log.Crit("Failed to remove snapshot entry", "err", err)
+	}
+	dl.cache.Remove(string(key))
+}
+
+// deleteAccount removes an account and all its storage from the snapshot.
+func (dl *diskLayer) deleteAccount(hash common.Hash) {
+	dl.delete(accountKey(hash))
+	dl.deleteStorage(hash)
+}
+
+// stopGeneration aborts the generation of the snapshot in this layer, if
+// running, waiting for its progress to be persisted.
+func (dl *diskLayer) stopGeneration() {
+	if dl.genAbort == nil {
+		return
+	}
+	abort := make(chan struct{})
+	dl.genAbort <- abort
+	<-abort
+	dl.genAbort = nil
+}
@@ -0,0 +1,243 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/rlp"
+	"github.com/aquanetwork/aquachain/trie"
+)
+
+// wipeCheckInterval is the number of entries wiped between two checks for an
+// abort request.
+const wipeCheckInterval = 1024
+
+// errAborted is returned internally when the generation is aborted midway.
+var errAborted = errors.New("aborted")
+
+// account is the consensus representation of an account, as stored in the
+// account trie. It mirrors state.Account, which can't be imported here.
+type account struct {
+	Nonce    uint64
+	Balance  *big.Int
+	Root     common.Hash
+	CodeHash []byte
+}
+
+// generate fills the snapshot of the layer from the state trie, starting after
+// the marker, until done or aborted. The progress is persisted along the way,
+// and on abort, before acknowledging it.
+func (dl *diskLayer) generate(abort chan chan struct{}) {
+	dl.lock.RLock()
+	marker, wipe := dl.genMarker, dl.genWipe
+	dl.lock.RUnlock()
+
+	if wipe {
+		if err := dl.wipe(marker, abort); err != nil {
+			if err == errAborted {
+				return // Acknowledged, redone on resumption
+			}
+			log.Error("Failed to wipe state snapshot", "err", err)
+			dl.waitAbort(abort)
+			return
+		}
+		dl.lock.Lock()
+		dl.genWipe = false
+		dl.lock.Unlock()
+	}
+	var (
+		batch    = dl.diskdb.NewBatch()
+		accounts int
+		start    = time.Now()
+		logged   = start
+	)
+	// flush writes the batch out, moving the marker to the last account fully
+	// written, or removing it if the generation is done
+	flush := func(done bool) {
+		if !done {
+			if err := batch.Put(snapshotGeneratorKey, marker); err != nil {
+				log.Crit("Failed to store snapshot generator marker", "err", err)
+			}
+		}
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to write snapshot", "err", err)
+		}
+		batch.Reset()
+		if done {
+			if err := dl.diskdb.Delete(snapshotGeneratorKey); err != nil {
+				log.Crit("Failed to remove snapshot generator marker", "err", err)
+			}
+			marker = nil
+		}
+		dl.lock.Lock()
+		dl.genMarker = marker
+		dl.lock.Unlock()
+	}
+	accTrie, err := trie.NewSecure(dl.root, dl.triedb, 0)
+	if err != nil {
+		log.Warn("State snapshot generation stalled", "root", dl.root, "err", err)
+		dl.waitAbort(abort)
+		return
+	}
+	it := trie.NewIterator(accTrie.NodeIterator(marker))
+	for it.Next() {
+		if bytes.Equal(it.Key, marker) {
+			continue // Already covered
+		}
+		accountHash := common.BytesToHash(it.Key)
+
+		var acc account
+		if err := rlp.DecodeBytes(it.Value, &acc); err != nil {
+			log.Crit("Invalid account encountered during snapshot generation", "err", err)
+		}
+		storeTrie, err := trie.NewSecure(acc.Root, dl.triedb, 0)
+		if err != nil {
+			flush(false)
+			dl.deleteStorage(accountHash)
+			log.Warn("State snapshot generation stalled", "root", dl.root, "account", accountHash, "err", err)
+			dl.waitAbort(abort)
+			return
+		}
+		storeIt := trie.NewIterator(storeTrie.NodeIterator(nil))
+		for storeIt.Next() {
+			if err := batch.Put(storageKey(accountHash, common.BytesToHash(storeIt.Key)), common.CopyBytes(storeIt.Value)); err != nil {
+				log.Crit("Failed to store snapshot storage slot", "err", err)
+			}
+			if batch.ValueSize() > aquadb.IdealBatchSize {
+				flush(false)
+			}
+			// Roll the account back if aborted midway, it's redone on resumption
+			select {
+			case done := <-abort:
+				flush(false)
+				dl.deleteStorage(accountHash)
+				close(done)
+				return
+			default:
+			}
+		}
+		if storeIt.Err != nil {
+			flush(false)
+			dl.deleteStorage(accountHash)
+			log.Warn("State snapshot generation stalled", "root", dl.root, "account", accountHash, "err", storeIt.Err)
+			dl.waitAbort(abort)
+			return
+		}
+		// The account goes last, so a partially written one is never served
+		if err := batch.Put(accountKey(accountHash), common.CopyBytes(it.Value)); err != nil {
+			log.Crit("Failed to store snapshot account", "err", err)
+		}
+		marker, accounts = accountHash.Bytes(), accounts+1
+
+		if batch.ValueSize() > aquadb.IdealBatchSize {
+			flush(false)
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Generating state snapshot", "root", dl.root, "at", accountHash, "accounts", accounts, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		select {
+		case done := <-abort:
+			flush(false)
+			close(done)
+			return
+		default:
+		}
+	}
+	if it.Err != nil {
+		flush(false)
+		log.Warn("State snapshot generation stalled", "root", dl.root, "err", it.Err)
+		dl.waitAbort(abort)
+		return
+	}
+	flush(true)
+	log.Info("Generated state snapshot", "root", dl.root, "accounts", accounts, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	dl.waitAbort(abort)
+}
+
+// waitAbort blocks until the generation is aborted, acknowledging it.
+func (dl *diskLayer) waitAbort(abort chan chan struct{}) {
+	close(<-abort)
+}
+
+// wipe deletes the snapshot entries past the marker, left over from a snapshot
+// of another root or an interrupted generation.
+func (dl *diskLayer) wipe(marker []byte, abort chan chan struct{}) error {
+	var (
+		db      = dl.diskdb.(aquadb.Iteratee)
+		deleted int
+		aborted chan struct{}
+	)
+	// del removes an entry if past the marker, checking for an abort request
+	// every now and then
+	del := func(key, hash []byte) error {
+		if bytes.Compare(hash, marker) <= 0 {
+			return nil
+		}
+		if err := dl.diskdb.Delete(common.CopyBytes(key)); err != nil {
+			return err
+		}
+		if deleted++; deleted%wipeCheckInterval == 0 {
+			select {
+			case aborted = <-abort:
+				return errAborted
+			default:
+			}
+		}
+		return nil
+	}
+	err := db.IteratePrefix(accountPrefix, func(key, _ []byte) error {
+		if len(key) != len(accountPrefix)+common.HashLength {
+			return nil
+		}
+		return del(key, key[len(accountPrefix):])
+	})
+	if err == nil {
+		err = db.IteratePrefix(storagePrefix, func(key, _ []byte) error {
+			if len(key) != len(storagePrefix)+2*common.HashLength {
+				return nil
+			}
+			return del(key, key[len(storagePrefix):len(storagePrefix)+common.HashLength])
+		})
+	}
+	if aborted != nil {
+		close(aborted)
+	}
+	return err
+}
+
+// deleteStorage removes the storage of an account from the database.
+func (dl *diskLayer) deleteStorage(hash common.Hash) {
+	prefix := append(append([]byte{}, storagePrefix...), hash.Bytes()...)
+	err := dl.diskdb.(aquadb.Iteratee).IteratePrefix(prefix, func(key, _ []byte) error {
+		if len(key) == len(storagePrefix)+2*common.HashLength {
+			dl.delete(common.CopyBytes(key))
+		}
+		return nil
+	})
+	if err != nil {
+		log.Crit("Failed to remove snapshot storage", "err", err)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a flat snapshot of the state, serving account and
+// storage reads with a single database lookup instead of a trie walk.
+//
+// The snapshot is made of a persistent disk layer, holding the state at some
+// root, and in-memory diff layers on top of it, one per block, holding the
+// changes of the block. When too many diff layers pile up, the bottom ones are
+// flattened into the disk layer. A missing disk layer is generated from the
+// state trie in the background, reads of the accounts not generated yet being
+// left to the trie.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/trie"
+)
+
+var (
+	// ErrSnapshotStale is returned from data accessors if the underlying snapshot
+	// layer had been invalidated due to the chain progressing forward far enough
+	// to not maintain the layer's original state.
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+	// ErrNotCoveredYet is returned from data accessors if the underlying snapshot
+	// is being generated currently and the requested data item is not yet in the
+	// range of accounts covered.
+	ErrNotCoveredYet = errors.New("not covered yet")
+
+	// errSnapshotCycle is returned if a snapshot is attempted to be inserted
+	// that forms a cycle in the snapshot tree.
+	errSnapshotCycle = errors.New("snapshot cycle")
+)
+
+var (
+	snapshotRootKey      = []byte("SnapshotRoot")      // snapshotRootKey -> state root of the persisted snapshot
+	snapshotGeneratorKey = []byte("SnapshotGenerator") // snapshotGeneratorKey -> last generated account hash, missing once complete
+
+	accountPrefix = []byte("sa") // accountPrefix + account hash -> account trie value
+	storagePrefix = []byte("so") // storagePrefix + account hash + slot hash -> storage trie value
+)
+
+// accountKey = accountPrefix + hash
+func accountKey(hash common.Hash) []byte {
+	return append(append([]byte{}, accountPrefix...), hash.Bytes()...)
+}
+
+// storageKey = storagePrefix + account hash + slot hash
+func storageKey(accountHash, storageHash common.Hash) []byte {
+	return append(append(append([]byte{}, storagePrefix...), accountHash.Bytes()...), storageHash.Bytes()...)
+}
+
+// Snapshot represents the functionality supported by a snapshot storage layer.
+// The data is returned as stored in the state tries, nil for missing entries.
+type Snapshot interface {
+	// Root returns the state root for which this snapshot was made.
+	Root() common.Hash
+
+	// Account directly retrieves the RLP encoded account associated with a
+	// particular hash in the snapshot slim data format.
+	Account(hash common.Hash) ([]byte, error)
+
+	// Storage directly retrieves the RLP encoded storage slot associated with
+	// a particular hash within a particular account.
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+// snapshot is the internal version of the snapshot data layer that supports
+// some additional methods compared to the public API.
+type snapshot interface {
+	Snapshot
+
+	// Stale returns whether this layer has become stale (was flattened across)
+	// or if it's still live.
+	Stale() bool
+}
+
+// Tree is an AquaChain state snapshot tree. It consists of one persistent base
+// layer backed by a key-value store, on top of which arbitrarily many in-memory
+// diff layers are topped. The memory diffs can form a tree with branching, but
+// the disk layer is singleton and common to all. If a reorg goes deeper than the
+// disk layer, the snapshot stops following the chain until it's regenerated.
+type Tree struct {
+	diskdb aquadb.Database          // Persistent database to store the snapshot
+	triedb *trie.Database           // In-memory cache to access the trie through
+	layers map[common.Hash]snapshot // Collection of all known layers
+	lock   sync.RWMutex
+}
+
+// New attempts to load an already existing snapshot from a persistent key-value
+// store, and if it's not of the given root, discards it and starts generating a
+// new one in the background.
+func New(diskdb aquadb.Database, triedb *trie.Database, root common.Hash) (*Tree, error) {
+	if _, ok := diskdb.(aquadb.Iteratee); !ok {
+		return nil, errors.New("snapshot database not iterable")
+	}
+	base := loadDiskLayer(diskdb, triedb, root)
+	return &Tree{
+		diskdb: diskdb,
+		triedb: triedb,
+		layers: map[common.Hash]snapshot{base.root: base},
+	}, nil
+}
+
+// Snapshot retrieves a snapshot belonging to the given state root, or nil if no
+// snapshot is maintained for that state root.
+func (t *Tree) Snapshot(root common.Hash) Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if layer, ok := t.layers[root]; ok {
+		return layer
+	}
+	return nil
+}
+
+// Update adds a new snapshot into the tree, if that can be linked to an existing
+// old parent. It is disallowed to insert a disk layer (the origin of all).
+func (t *Tree) Update(root, parent common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
+	if root == parent {
+		return errSnapshotCycle
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.layers[root]; ok {
+		return nil // Same state reached on another chain, or committed twice
+	}
+	base, ok := t.layers[parent]
+	if !ok {
+		return fmt.Errorf("parent [%#x] snapshot missing", parent)
+	}
+	t.layers[root] = newDiffLayer(base, root, destructs, accounts, storage)
+	return nil
+}
+
+// Cap traverses downwards the snapshot tree from a head block hash until the
+// number of allowed diff layers are crossed. All layers beyond the permitted
+// number are flattened into the disk layer, and the layers not descending from
+// the new disk layer are dropped.
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	snap, ok := t.layers[root]
+	if !ok {
+		return fmt.Errorf("snapshot [%#x] missing", root)
+	}
+	var diffs []*diffLayer // Diff layers from root, topmost first
+	for layer := snap; ; {
+		diff, ok := layer.(*diffLayer)
+		if !ok {
+			break
+		}
+		diffs = append(diffs, diff)
+		layer = diff.parent
+	}
+	if len(diffs) <= layers {
+		return nil
+	}
+	for len(diffs) > layers {
+		bottom := diffs[len(diffs)-1]
+		diffs = diffs[:len(diffs)-1]
+
+		base := bottom.parent.(*diskLayer)
+		disk := base.merge(bottom)
+
+		bottom.lock.Lock()
+		bottom.stale = true
+		bottom.lock.Unlock()
+
+		delete(t.layers, base.root)
+		delete(t.layers, bottom.root)
+		t.layers[disk.root] = disk
+
+		for _, layer := range t.layers {
+			if diff, ok := layer.(*diffLayer); ok && diff.parent == snapshot(bottom) {
+				diff.lock.Lock()
+				diff.parent = disk
+				diff.lock.Unlock()
+			}
+		}
+	}
+	// Drop the layers branching off below the new disk layer
+	disk := t.disk()
+	for hash, layer := range t.layers {
+		if !t.descends(layer, disk) {
+			if diff, ok := layer.(*diffLayer); ok {
+				diff.lock.Lock()
+				diff.stale = true
+				diff.lock.Unlock()
+			}
+			delete(t.layers, hash)
+		}
+	}
+	return nil
+}
+
+// Release stops the background generation of the disk layer, if running. The
+// generation progress is persisted and resumed when the snapshot is loaded.
+func (t *Tree) Release() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if disk := t.disk(); disk != nil {
+		disk.stopGeneration()
+	}
+}
+
+// disk returns the current disk layer. The tree lock must be held.
+func (t *Tree) disk() *diskLayer {
+	var disk *diskLayer
+	for _, layer := range t.layers {
+		if layer, ok := layer.(*diskLayer); ok && !layer.Stale() {
+			disk = layer
+		}
+	}
+	return disk
+}
+
+// descends returns whether the given layer is the disk layer, or stacked on top
+// of it. The tree lock must be held.
+func (t *Tree) descends(layer snapshot, disk *diskLayer) bool {
+	for {
+		switch l := layer.(type) {
+		case *diskLayer:
+			return l == disk
+		case *diffLayer:
+			layer = l.parent
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/rlp"
+	"github.com/aquanetwork/aquachain/trie"
+)
+
+// testState creates a state trie of accounts with the given storage, returning
+// its root.
+func testState(t *testing.T, triedb *trie.Database, storage map[common.Hash]map[common.Hash][]byte) common.Hash {
+	accTrie, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+	for i := byte(1); i <= 4; i++ {
+		addr := common.Address{i}
+		storeTrie, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+		for j := byte(1); j <= i; j++ {
+			key := common.Hash{j}
+			value, _ := rlp.EncodeToBytes([]byte{i, j})
+			storeTrie.Update(key[:], value)
+
+			hash := crypto.Keccak256Hash(addr[:])
+			if storage[hash] == nil {
+				storage[hash] = make(map[common.Hash][]byte)
+			}
+			storage[hash][crypto.Keccak256Hash(key[:])] = value
+		}
+		root, err := storeTrie.Commit(nil)
+		if err != nil {
+			t.Fatalf("failed to commit storage trie: %v", err)
+		}
+		data, _ := rlp.EncodeToBytes(&account{Nonce: uint64(i), Balance: big.NewInt(int64(i)), Root: root, CodeHash: crypto.Keccak256(nil)})
+		accTrie.Update(addr[:], data)
+	}
+	root, err := accTrie.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit account trie: %v", err)
+	}
+	return root
+}
+
+// waitGeneration waits until the disk layer of the tree is fully generated.
+func waitGeneration(t *testing.T, snaps *Tree) {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		snaps.lock.RLock()
+		disk := snaps.disk()
+		disk.lock.RLock()
+		done := disk.genMarker == nil
+		disk.lock.RUnlock()
+		snaps.lock.RUnlock()
+
+		if done {
+			return
+		}
+	}
+	t.Fatalf("snapshot generation timed out")
+}
+
+// Tests that a generated snapshot holds the same data as the state trie.
+func TestGenerate(t *testing.T) {
+	var (
+		diskdb, _ = aquadb.NewMemDatabase()
+		triedb    = trie.NewDatabase(diskdb)
+		storage   = make(map[common.Hash]map[common.Hash][]byte)
+	)
+	root := testState(t, triedb, storage)
+
+	snaps, err := New(diskdb, triedb, root)
+	if err != nil {
+		t.Fatalf("failed to create snapshot tree: %v", err)
+	}
+	waitGeneration(t, snaps)
+	defer snaps.Release()
+
+	snap := snaps.Snapshot(root)
+	accTrie, _ := trie.NewSecure(root, triedb, 0)
+	for i := byte(1); i <= 5; i++ {
+		addr := common.Address{i}
+		hash := crypto.Keccak256Hash(addr[:])
+		blob, err := snap.Account(hash)
+		if err != nil {
+			t.Fatalf("account %x: failed to read: %v", addr, err)
+		}
+		if want := accTrie.Get(addr[:]); !bytes.Equal(blob, want) {
+			t.Errorf("account %x: data mismatch: have %x, want %x", addr, blob, want)
+		}
+		for j := byte(1); j <= 5; j++ {
+			key := crypto.Keccak256Hash(common.Hash{j}.Bytes())
+			blob, err := snap.Storage(hash, key)
+			if err != nil {
+				t.Fatalf("account %x slot %d: failed to read: %v", addr, j, err)
+			}
+			if want := storage[hash][key]; !bytes.Equal(blob, want) {
+				t.Errorf("account %x slot %d: data mismatch: have %x, want %x", addr, j, blob, want)
+			}
+		}
+	}
+	// Reopening the snapshot at the same root must not regenerate it
+	snaps.Release()
+	if snaps, _ = New(diskdb, triedb, root); snaps.disk().genMarker != nil {
+		t.Fatalf("complete snapshot regenerated on reopen")
+	}
+}
+
+// Tests that diff layers shadow their parents, and that flattening them into
+// the disk layer yields the same reads.
+func TestDiffLayers(t *testing.T) {
+	var (
+		diskdb, _ = aquadb.NewMemDatabase()
+		triedb    = trie.NewDatabase(diskdb)
+		storage   = make(map[common.Hash]map[common.Hash][]byte)
+	)
+	root := testState(t, triedb, storage)
+
+	snaps, err := New(diskdb, triedb, root)
+	if err != nil {
+		t.Fatalf("failed to create snapshot tree: %v", err)
+	}
+	waitGeneration(t, snaps)
+	defer snaps.Release()
+
+	var (
+		acc1  = crypto.Keccak256Hash(common.Address{1}.Bytes())
+		acc2  = crypto.Keccak256Hash(common.Address{2}.Bytes())
+		acc3  = crypto.Keccak256Hash(common.Address{3}.Bytes())
+		slot1 = crypto.Keccak256Hash(common.Hash{1}.Bytes())
+		slot2 = crypto.Keccak256Hash(common.Hash{2}.Bytes())
+		root1 = common.Hash{0x01}
+		root2 = common.Hash{0x02}
+	)
+	// Layer 1 rewrites an account and a slot, and destructs another account
+	err = snaps.Update(root1, root, map[common.Hash]struct{}{acc2: {}},
+		map[common.Hash][]byte{acc1: {0x01}},
+		map[common.Hash]map[common.Hash][]byte{acc3: {slot1: {0x03}, slot2: nil}})
+	if err != nil {
+		t.Fatalf("failed to add layer 1: %v", err)
+	}
+	// Layer 2 recreates the destructed account with a single slot
+	err = snaps.Update(root2, root1, nil,
+		map[common.Hash][]byte{acc2: {0x02}},
+		map[common.Hash]map[common.Hash][]byte{acc2: {slot2: {0x22}}})
+	if err != nil {
+		t.Fatalf("failed to add layer 2: %v", err)
+	}
+	if err := snaps.Update(root2, root1, nil, nil, nil); err != nil {
+		t.Fatalf("failed to re-add layer 2: %v", err)
+	}
+	if err := snaps.Update(common.Hash{0x03}, common.Hash{0xff}, nil, nil, nil); err == nil {
+		t.Fatalf("layer added with missing parent")
+	}
+	check := func(snap Snapshot) {
+		t.Helper()
+
+		tests := []struct {
+			account, slot common.Hash
+			want          []byte
+		}{
+			{acc1, common.Hash{}, []byte{0x01}},
+			{acc2, common.Hash{}, []byte{0x02}},
+			{acc2, slot1, nil},
+			{acc2, slot2, []byte{0x22}},
+			{acc3, slot1, []byte{0x03}},
+			{acc3, slot2, nil},
+			{acc3, crypto.Keccak256Hash(common.Hash{3}.Bytes()), storage[acc3][crypto.Keccak256Hash(common.Hash{3}.Bytes())]},
+		}
+		for i, tt := range tests {
+			var (
+				blob []byte
+				err  error
+			)
+			if tt.slot == (common.Hash{}) {
+				blob, err = snap.Account(tt.account)
+			} else {
+				blob, err = snap.Storage(tt.account, tt.slot)
+			}
+			if err != nil {
+				t.Fatalf("test %d: failed to read: %v", i, err)
+			}
+			if !bytes.Equal(blob, tt.want) {
+				t.Errorf("test %d: data mismatch: have %x, want %x", i, blob, tt.want)
+			}
+		}
+	}
+	check(snaps.Snapshot(root2))
+
+	// Flattening all layers yields the same data from the disk layer
+	diff := snaps.Snapshot(root2)
+	if err := snaps.Cap(root2, 0); err != nil {
+		t.Fatalf("failed to flatten layers: %v", err)
+	}
+	if _, err := diff.Account(acc1); err != ErrSnapshotStale {
+		t.Fatalf("flattened layer read error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if snaps.Snapshot(root) != nil || snaps.Snapshot(root1) != nil {
+		t.Fatalf("flattened layers still in the tree")
+	}
+	disk := snaps.Snapshot(root2)
+	if _, ok := disk.(*diskLayer); !ok {
+		t.Fatalf("head not flattened into the disk layer: %T", disk)
+	}
+	check(disk)
+
+	// Reopening the snapshot at the flattened root loads it as is
+	snaps.Release()
+	if snaps, _ = New(diskdb, triedb, root2); snaps.disk().genMarker != nil {
+		t.Fatalf("flattened snapshot regenerated on reopen")
+	}
+	check(snaps.Snapshot(root2))
+}
+
+// Tests that the layers branching off below the disk layer are dropped when
+// flattening.
+func TestCapDropsSideBranches(t *testing.T) {
+	var (
+		diskdb, _ = aquadb.NewMemDatabase()
+		triedb    = trie.NewDatabase(diskdb)
+		root      = testState(t, triedb, make(map[common.Hash]map[common.Hash][]byte))
+	)
+	snaps, err := New(diskdb, triedb, root)
+	if err != nil {
+		t.Fatalf("failed to create snapshot tree: %v", err)
+	}
+	waitGeneration(t, snaps)
+	defer snaps.Release()
+
+	// root -> a1 -> a2, and root -> b1
+	snaps.Update(common.Hash{0xa1}, root, nil, nil, nil)
+	snaps.Update(common.Hash{0xa2}, common.Hash{0xa1}, nil, nil, nil)
+	snaps.Update(common.Hash{0xb1}, root, nil, nil, nil)
+
+	side := snaps.Snapshot(common.Hash{0xb1})
+	if err := snaps.Cap(common.Hash{0xa2}, 1); err != nil {
+		t.Fatalf("failed to cap tree: %v", err)
+	}
+	if snaps.Snapshot(common.Hash{0xb1}) != nil {
+		t.Fatalf("side branch kept below the disk layer")
+	}
+	if _, err := side.Account(common.Hash{}); err != ErrSnapshotStale {
+		t.Fatalf("side branch read error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if snaps.Snapshot(common.Hash{0xa1}) == nil || snaps.Snapshot(common.Hash{0xa2}) == nil {
+		t.Fatalf("canonical layers dropped")
+	}
+}
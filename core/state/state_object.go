@@ -79,6 +79,7 @@ type stateObject struct {
 
 	cachedStorage Storage // Storage entry cache to avoid duplicate reads
 	dirtyStorage  Storage // Storage entries that need to be flushed to disk
+	fakeStorage   Storage // Storage replacing the real one for simulations, never flushed
 
 	// Cache flags.
 	// When an object is marked suicided it will be delete from the trie
@@ -87,6 +88,7 @@ type stateObject struct {
 	suicided  bool
 	touched   bool
 	deleted   bool
+	created   bool                      // true if the object was created in this state, its storage not read from the snapshot
 	onDirty   func(addr common.Address) // Callback method to mark a state object newly dirty
 }
 
@@ -170,12 +172,25 @@ func (c *stateObject) getTrie(db Database) Trie {
 
 // GetState returns a value in account storage.
 func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
+	if self.fakeStorage != nil {
+		return self.fakeStorage[key]
+	}
 	value, exists := self.cachedStorage[key]
 	if exists {
 		return value
 	}
-	// Load from DB in case it is missing.
-	enc, err := self.getTrie(db).TryGet(key[:])
+	// Load from the snapshot if it covers it, the DB otherwise.
+	var (
+		enc []byte
+		err error
+	)
+	snap := self.db.snap
+	if snap != nil && !self.created {
+		enc, err = snap.Storage(self.addrHash, crypto.Keccak256Hash(key[:]))
+	}
+	if snap == nil || self.created || err != nil {
+		enc, err = self.getTrie(db).TryGet(key[:])
+	}
 	if err != nil {
 		self.setError(err)
 		return common.Hash{}
@@ -203,7 +218,25 @@ func (self *stateObject) SetState(db Database, key, value common.Hash) {
 	self.setState(key, value)
 }
 
+// SetStorage replaces the whole storage of the account with the given one. The
+// replacement is meant for simulating calls against modified state, it's never
+// written to the storage trie.
+func (self *stateObject) SetStorage(storage map[common.Hash]common.Hash) {
+	self.fakeStorage = make(Storage, len(storage))
+	for key, value := range storage {
+		self.fakeStorage[key] = value
+	}
+	if self.onDirty != nil {
+		self.onDirty(self.Address())
+		self.onDirty = nil
+	}
+}
+
 func (self *stateObject) setState(key, value common.Hash) {
+	if self.fakeStorage != nil {
+		self.fakeStorage[key] = value
+		return
+	}
 	self.cachedStorage[key] = value
 	self.dirtyStorage[key] = value
 
@@ -216,15 +249,29 @@ func (self *stateObject) setState(key, value common.Hash) {
 // updateTrie writes cached storage modifications into the object's storage trie.
 func (self *stateObject) updateTrie(db Database) Trie {
 	tr := self.getTrie(db)
+	var storage map[common.Hash][]byte
+	if self.db.snap != nil && len(self.dirtyStorage) > 0 {
+		self.db.snapCreate(self)
+		if storage = self.db.snapStorage[self.addrHash]; storage == nil {
+			storage = make(map[common.Hash][]byte)
+			self.db.snapStorage[self.addrHash] = storage
+		}
+	}
 	for key, value := range self.dirtyStorage {
 		delete(self.dirtyStorage, key)
 		if (value == common.Hash{}) {
 			self.setError(tr.TryDelete(key[:]))
+			if storage != nil {
+				storage[crypto.Keccak256Hash(key[:])] = nil
+			}
 			continue
 		}
 		// Encoding []byte cannot fail, ok to ignore the error.
 		v, _ := rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
 		self.setError(tr.TryUpdate(key[:], v))
+		if storage != nil {
+			storage[crypto.Keccak256Hash(key[:])] = v
+		}
 	}
 	return tr
 }
@@ -300,9 +347,13 @@ func (self *stateObject) deepCopy(db *StateDB, onDirty func(addr common.Address)
 	stateObject.code = self.code
 	stateObject.dirtyStorage = self.dirtyStorage.Copy()
 	stateObject.cachedStorage = self.dirtyStorage.Copy()
+	if self.fakeStorage != nil {
+		stateObject.fakeStorage = self.fakeStorage.Copy()
+	}
 	stateObject.suicided = self.suicided
 	stateObject.dirtyCode = self.dirtyCode
 	stateObject.deleted = self.deleted
+	stateObject.created = self.created
 	return stateObject
 }
 
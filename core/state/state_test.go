@@ -86,6 +86,35 @@ func (s *StateSuite) TestDump(c *checker.C) {
 	}
 }
 
+func (s *StateSuite) TestIterativeDump(c *checker.C) {
+	obj1 := s.state.GetOrNewStateObject(toAddr([]byte{0x01}))
+	obj1.AddBalance(big.NewInt(22))
+	obj2 := s.state.GetOrNewStateObject(toAddr([]byte{0x02}))
+	obj2.SetBalance(big.NewInt(44))
+	s.state.updateStateObject(obj1)
+	s.state.updateStateObject(obj2)
+	s.state.Commit(false)
+
+	var (
+		buf      = new(bytes.Buffer)
+		accounts int
+	)
+	if err := s.state.IterativeDump(buf, func(n int) { accounts = n }); err != nil {
+		c.Fatalf("dump failed: %v", err)
+	}
+	got := buf.String()
+	want := `{"root":"` + s.state.RawDump().Root + `"}
+{"address":"0000000000000000000000000000000000000001","balance":"22","nonce":0,"root":"56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421","codeHash":"c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470","code":"","storage":{}}
+{"address":"0000000000000000000000000000000000000002","balance":"44","nonce":0,"root":"56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421","codeHash":"c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470","code":"","storage":{}}
+`
+	if got != want {
+		c.Errorf("dump mismatch:\ngot: %s\nwant: %s\n", got, want)
+	}
+	if accounts != 2 {
+		c.Errorf("progress mismatch: have %d accounts, want 2", accounts)
+	}
+}
+
 func (s *StateSuite) SetUpTest(c *checker.C) {
 	s.db, _ = aquadb.NewMemDatabase()
 	s.state, _ = New(common.Hash{}, NewDatabase(s.db))
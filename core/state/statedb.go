@@ -18,6 +18,7 @@
 package state
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -27,6 +28,7 @@ import (
 
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core/state/snapshot"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/crypto"
 	"github.com/aquanetwork/aquachain/log"
@@ -47,6 +49,11 @@ var (
 	emptyCode = crypto.Keccak256Hash(nil)
 )
 
+// snapshotLayers is the number of diff layers kept in the snapshot tree above
+// the disk layer. It's below the number of tries the chain keeps in memory, so
+// the trie of the disk layer is always available to generate it from.
+const snapshotLayers = 64
+
 // StateDBs within the aquachain protocol are used to store anything
 // within the merkle trie. StateDBs take care of caching and storing
 // nested states. It's the general query interface to retrieve:
@@ -56,6 +63,14 @@ type StateDB struct {
 	db   Database
 	trie Trie
 
+	// The snapshot of the state, if maintained, serving the reads before the
+	// trie, and the changes to stack on it when committing.
+	snaps         *snapshot.Tree
+	snap          snapshot.Snapshot
+	snapDestructs map[common.Hash]struct{}
+	snapAccounts  map[common.Hash][]byte
+	snapStorage   map[common.Hash]map[common.Hash][]byte
+
 	// This map holds 'live' objects, which will get modified while processing a state transition.
 	stateObjects      map[common.Address]*stateObject
 	stateObjectsDirty map[common.Address]struct{}
@@ -102,6 +117,32 @@ func New(root common.Hash, db Database) (*StateDB, error) {
 	}, nil
 }
 
+// NewWithSnapshots creates a new state from a given trie, reading through the
+// snapshot of the root if the tree has it, and stacking the changes onto the
+// tree when committing.
+func NewWithSnapshots(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error) {
+	sdb, err := New(root, db)
+	if err != nil {
+		return nil, err
+	}
+	if snaps != nil {
+		sdb.snaps = snaps
+		sdb.resetSnapshot(root)
+	}
+	return sdb, nil
+}
+
+// resetSnapshot points the state at the snapshot of the given root, dropping
+// the changes captured so far.
+func (self *StateDB) resetSnapshot(root common.Hash) {
+	self.snap = self.snaps.Snapshot(root)
+	if self.snap != nil {
+		self.snapDestructs = make(map[common.Hash]struct{})
+		self.snapAccounts = make(map[common.Hash][]byte)
+		self.snapStorage = make(map[common.Hash]map[common.Hash][]byte)
+	}
+}
+
 // setError remembers the first non-nil error it is called with.
 func (self *StateDB) setError(err error) {
 	if self.dbErr == nil {
@@ -121,6 +162,9 @@ func (self *StateDB) Reset(root common.Hash) error {
 		return err
 	}
 	self.trie = tr
+	if self.snaps != nil {
+		self.resetSnapshot(root)
+	}
 	self.stateObjects = make(map[common.Address]*stateObject)
 	self.stateObjectsDirty = make(map[common.Address]struct{})
 	self.thash = common.Hash{}
@@ -302,6 +346,34 @@ func (self *StateDB) StorageTrie(a common.Address) Trie {
 	return cpy.updateTrie(self.db)
 }
 
+// proofList collects the encoded trie nodes of a merkle proof, from the root.
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+// GetProof returns the merkle proof of an account in the account trie, or of
+// its absence.
+func (self *StateDB) GetProof(a common.Address) ([][]byte, error) {
+	var proof proofList
+	err := self.trie.Prove(crypto.Keccak256(a.Bytes()), 0, &proof)
+	return proof, err
+}
+
+// GetStorageProof returns the merkle proof of a storage slot in the storage
+// trie of an account, or of its absence.
+func (self *StateDB) GetStorageProof(a common.Address, key common.Hash) ([][]byte, error) {
+	trie := self.StorageTrie(a)
+	if trie == nil {
+		return nil, fmt.Errorf("account %x does not exist", a)
+	}
+	var proof proofList
+	err := trie.Prove(crypto.Keccak256(key.Bytes()), 0, &proof)
+	return proof, err
+}
+
 func (self *StateDB) HasSuicided(addr common.Address) bool {
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
@@ -358,6 +430,16 @@ func (self *StateDB) SetState(addr common.Address, key common.Hash, value common
 	}
 }
 
+// SetStorage replaces the whole storage of an account with the given one, for
+// simulating calls against modified state. The replacement is not journaled and
+// never committed.
+func (self *StateDB) SetStorage(addr common.Address, storage map[common.Hash]common.Hash) {
+	stateObject := self.GetOrNewStateObject(addr)
+	if stateObject != nil {
+		stateObject.SetStorage(storage)
+	}
+}
+
 // Suicide marks the given account as suicided.
 // This clears the account balance.
 //
@@ -391,6 +473,11 @@ func (self *StateDB) updateStateObject(stateObject *stateObject) {
 		panic(fmt.Errorf("can't encode object at %x: %v", addr[:], err))
 	}
 	self.setError(self.trie.TryUpdate(addr[:], data))
+
+	if self.snap != nil {
+		self.snapCreate(stateObject)
+		self.snapAccounts[stateObject.addrHash] = data
+	}
 }
 
 // deleteStateObject removes the given object from the state trie.
@@ -398,6 +485,25 @@ func (self *StateDB) deleteStateObject(stateObject *stateObject) {
 	stateObject.deleted = true
 	addr := stateObject.Address()
 	self.setError(self.trie.TryDelete(addr[:]))
+
+	if self.snap != nil {
+		self.snapDestructs[stateObject.addrHash] = struct{}{}
+		delete(self.snapAccounts, stateObject.addrHash)
+		delete(self.snapStorage, stateObject.addrHash)
+	}
+}
+
+// snapCreate records an object created in this state as destructing any
+// previous account at its address, before any of its own changes, since its
+// storage starts out empty.
+func (self *StateDB) snapCreate(stateObject *stateObject) {
+	if !stateObject.created {
+		return
+	}
+	if _, ok := self.snapDestructs[stateObject.addrHash]; !ok {
+		self.snapDestructs[stateObject.addrHash] = struct{}{}
+		delete(self.snapStorage, stateObject.addrHash)
+	}
 }
 
 // Retrieve a state object given my the address. Returns nil if not found.
@@ -410,8 +516,17 @@ func (self *StateDB) getStateObject(addr common.Address) (stateObject *stateObje
 		return obj
 	}
 
-	// Load the object from the database.
-	enc, err := self.trie.TryGet(addr[:])
+	// Load the object from the snapshot if it covers it, the trie otherwise.
+	var (
+		enc []byte
+		err error
+	)
+	if self.snap != nil {
+		enc, err = self.snap.Account(crypto.Keccak256Hash(addr[:]))
+	}
+	if self.snap == nil || err != nil {
+		enc, err = self.trie.TryGet(addr[:])
+	}
 	if len(enc) == 0 {
 		self.setError(err)
 		return nil
@@ -451,6 +566,7 @@ func (self *StateDB) MarkStateObjectDirty(addr common.Address) {
 func (self *StateDB) createObject(addr common.Address) (newobj, prev *stateObject) {
 	prev = self.getStateObject(addr)
 	newobj = newObject(self, addr, Account{}, self.MarkStateObjectDirty)
+	newobj.created = true
 	newobj.setNonce(0) // sets the object to dirty
 	if prev == nil {
 		self.journal = append(self.journal, createObjectChange{account: &addr})
@@ -467,8 +583,8 @@ func (self *StateDB) createObject(addr common.Address) (newobj, prev *stateObjec
 // CreateAccount is called during the EVM CREATE operation. The situation might arise that
 // a contract does the following:
 //
-//   1. sends funds to sha(account ++ (nonce + 1))
-//   2. tx_create(sha(account ++ nonce)) (note that this gets the address of 1)
+//  1. sends funds to sha(account ++ (nonce + 1))
+//  2. tx_create(sha(account ++ nonce)) (note that this gets the address of 1)
 //
 // Carrying over the balance ensures that Aquaer doesn't disappear.
 func (self *StateDB) CreateAccount(addr common.Address) {
@@ -509,6 +625,8 @@ func (self *StateDB) Copy() *StateDB {
 	state := &StateDB{
 		db:                self.db,
 		trie:              self.db.CopyTrie(self.trie),
+		snaps:             self.snaps,
+		snap:              self.snap,
 		stateObjects:      make(map[common.Address]*stateObject, len(self.stateObjectsDirty)),
 		stateObjectsDirty: make(map[common.Address]struct{}, len(self.stateObjectsDirty)),
 		refund:            self.refund,
@@ -528,6 +646,23 @@ func (self *StateDB) Copy() *StateDB {
 	for hash, preimage := range self.preimages {
 		state.preimages[hash] = preimage
 	}
+	if self.snap != nil {
+		state.snapDestructs = make(map[common.Hash]struct{}, len(self.snapDestructs))
+		for hash := range self.snapDestructs {
+			state.snapDestructs[hash] = struct{}{}
+		}
+		state.snapAccounts = make(map[common.Hash][]byte, len(self.snapAccounts))
+		for hash, data := range self.snapAccounts {
+			state.snapAccounts[hash] = data
+		}
+		state.snapStorage = make(map[common.Hash]map[common.Hash][]byte, len(self.snapStorage))
+		for hash, slots := range self.snapStorage {
+			state.snapStorage[hash] = make(map[common.Hash][]byte, len(slots))
+			for key, data := range slots {
+				state.snapStorage[hash][key] = data
+			}
+		}
+	}
 	return state
 }
 
@@ -597,6 +732,22 @@ func (self *StateDB) Prepare(thash, bhash common.Hash, ti int) {
 	self.txIndex = ti
 }
 
+// TxIndex returns the index of the transaction set by Prepare.
+func (self *StateDB) TxIndex() int {
+	return self.txIndex
+}
+
+// DirtyAccounts returns the addresses of the accounts modified since the last
+// commit, sorted.
+func (self *StateDB) DirtyAccounts() []common.Address {
+	addrs := make([]common.Address, 0, len(self.stateObjectsDirty))
+	for addr := range self.stateObjectsDirty {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+	return addrs
+}
+
 // DeleteSuicides flags the suicided objects for deletion so that it
 // won't be referenced again when called / queried up on.
 //
@@ -667,5 +818,18 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error)
 		return nil
 	})
 	log.Debug("Trie cache stats after commit", "misses", trie.CacheMisses(), "unloads", trie.CacheUnloads())
+
+	// Stack the changes onto the snapshot, flattening the oldest layers
+	if err == nil && s.snap != nil {
+		if parent := s.snap.Root(); parent != root {
+			if err := s.snaps.Update(root, parent, s.snapDestructs, s.snapAccounts, s.snapStorage); err != nil {
+				log.Warn("Failed to update snapshot", "root", root, "parent", parent, "err", err)
+			}
+			if err := s.snaps.Cap(root, snapshotLayers); err != nil {
+				log.Warn("Failed to cap snapshot tree", "root", root, "layers", snapshotLayers, "err", err)
+			}
+		}
+		s.resetSnapshot(root)
+	}
 	return root, err
 }
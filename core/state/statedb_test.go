@@ -27,12 +27,15 @@ import (
 	"strings"
 	"testing"
 	"testing/quick"
+	"time"
 
 	check "gopkg.in/check.v1"
 
 	"github.com/aquanetwork/aquachain/aquadb"
 	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/state/snapshot"
 	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -421,3 +424,116 @@ func (s *StateSuite) TestTouchDelete(c *check.C) {
 		c.Fatal("expected no dirty state object")
 	}
 }
+
+// Tests that a state reading through the snapshot sees the same data as one
+// reading the trie, both before and after the snapshot is flattened.
+func TestSnapshotReads(t *testing.T) {
+	db, _ := aquadb.NewMemDatabase()
+	sdb := NewDatabase(db)
+	state, _ := New(common.Hash{}, sdb)
+
+	addrs := []common.Address{{1}, {2}, {3}, {4}}
+	for i, addr := range addrs[:3] {
+		state.AddBalance(addr, big.NewInt(int64(i+1)))
+		state.SetNonce(addr, uint64(i))
+		for j := byte(1); j <= 3; j++ {
+			state.SetState(addr, common.Hash{j}, common.Hash{byte(i + 1), j})
+		}
+	}
+	root, _ := state.Commit(false)
+	sdb.TrieDB().Commit(root, false)
+
+	snaps, err := snapshot.New(db, sdb.TrieDB(), root)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	defer snaps.Release()
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := snaps.Snapshot(root).Account(crypto.Keccak256Hash(addrs[2][:])); err == nil {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("snapshot generation timed out")
+		}
+	}
+	check := func(root common.Hash) {
+		t.Helper()
+
+		have, _ := NewWithSnapshots(root, sdb, snaps)
+		want, _ := New(root, sdb)
+		if have.snap == nil {
+			t.Fatalf("state %x: not read through the snapshot", root)
+		}
+		for _, addr := range addrs {
+			if have.Exist(addr) != want.Exist(addr) {
+				t.Errorf("state %x account %x: existence mismatch: have %v", root, addr, have.Exist(addr))
+			}
+			if have.GetBalance(addr).Cmp(want.GetBalance(addr)) != 0 || have.GetNonce(addr) != want.GetNonce(addr) {
+				t.Errorf("state %x account %x: balance or nonce mismatch", root, addr)
+			}
+			for j := byte(1); j <= 4; j++ {
+				if h, w := have.GetState(addr, common.Hash{j}), want.GetState(addr, common.Hash{j}); h != w {
+					t.Errorf("state %x account %x slot %d: value mismatch: have %x, want %x", root, addr, j, h, w)
+				}
+			}
+		}
+	}
+	check(root)
+
+	// Modify, delete, recreate and create accounts on top of the snapshot
+	state, _ = NewWithSnapshots(root, sdb, snaps)
+	state.SetState(addrs[0], common.Hash{1}, common.Hash{0xff})
+	state.SetState(addrs[0], common.Hash{2}, common.Hash{})
+	state.SetState(addrs[0], common.Hash{4}, common.Hash{0xee})
+	state.Suicide(addrs[1])
+	state.CreateAccount(addrs[2])
+	state.SetState(addrs[2], common.Hash{3}, common.Hash{0xdd})
+	state.AddBalance(addrs[3], big.NewInt(10))
+
+	root, _ = state.Commit(true)
+	if snaps.Snapshot(root) == nil {
+		t.Fatalf("committed state missing from the snapshot")
+	}
+	check(root)
+
+	if err := snaps.Cap(root, 0); err != nil {
+		t.Fatalf("failed to flatten snapshot: %v", err)
+	}
+	check(root)
+}
+
+// Tests that a replaced storage hides the real one, is reverted like the real
+// one, and is never committed.
+func TestSetStorage(t *testing.T) {
+	var (
+		db, _    = aquadb.NewMemDatabase()
+		sdb      = NewDatabase(db)
+		state, _ = New(common.Hash{}, sdb)
+		addr     = common.Address{1}
+		slot1    = common.Hash{1}
+		slot2    = common.Hash{2}
+	)
+	state.SetState(addr, slot1, common.Hash{0x11})
+	root, _ := state.Commit(false)
+
+	state, _ = New(root, sdb)
+	state.SetStorage(addr, map[common.Hash]common.Hash{slot2: {0x22}})
+	if value := state.GetState(addr, slot1); value != (common.Hash{}) {
+		t.Fatalf("real storage not hidden: %x", value)
+	}
+	snap := state.Snapshot()
+	state.SetState(addr, slot2, common.Hash{0x33})
+	if value := state.GetState(addr, slot2); value != (common.Hash{0x33}) {
+		t.Fatalf("replaced storage not written: %x", value)
+	}
+	state.RevertToSnapshot(snap)
+	if value := state.GetState(addr, slot2); value != (common.Hash{0x22}) {
+		t.Fatalf("replaced storage not reverted: %x", value)
+	}
+	if value := state.Copy().GetState(addr, slot2); value != (common.Hash{0x22}) {
+		t.Fatalf("replaced storage not copied: %x", value)
+	}
+	if have, _ := state.Commit(false); have != root {
+		t.Fatalf("replaced storage committed: root %x, want %x", have, root)
+	}
+}
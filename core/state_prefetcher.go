@@ -0,0 +1,88 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync/atomic"
+
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// statePrefetcher is a basic Prefetcher, which blindly executes a block on top
+// of an arbitrary state with the goal of loading the trie nodes of the accounts
+// and storage slots it touches into the database caches ahead of the actual
+// processing.
+//
+// statePrefetcher implements Prefetcher.
+type statePrefetcher struct {
+	config *params.ChainConfig // Chain configuration options
+	bc     *BlockChain         // Canonical block chain
+}
+
+// newStatePrefetcher initialises a new statePrefetcher.
+func newStatePrefetcher(config *params.ChainConfig, bc *BlockChain) *statePrefetcher {
+	return &statePrefetcher{
+		config: config,
+		bc:     bc,
+	}
+}
+
+// Prefetch processes the state changes according to the AquaChain rules by
+// running the transaction messages using the statedb, but any changes are
+// discarded. The only goal is to pre-cache the state the transactions access,
+// so execution errors are ignored and it stops as soon as interrupt is set.
+func (p *statePrefetcher) Prefetch(block *types.Block, statedb *state.StateDB, cfg vm.Config, interrupt *uint32) {
+	var (
+		header = block.Header()
+		gp     = new(GasPool).AddGas(block.GasLimit())
+		signer = types.MakeSigner(p.config, header.Number)
+	)
+	header.Version = p.config.GetBlockVersion(header.Number)
+
+	// Tracing the throwaway execution would only confuse the tracer
+	cfg.Debug, cfg.Tracer = false, nil
+
+	for i, tx := range block.Transactions() {
+		if interrupt != nil && atomic.LoadUint32(interrupt) == 1 {
+			return
+		}
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		if err := precacheTransaction(p.config, p.bc, gp, statedb, header, tx, signer, cfg); err != nil {
+			return // Ugh, something went horribly wrong, bail out
+		}
+	}
+}
+
+// precacheTransaction attempts to apply a transaction to the given state
+// database and uses the input parameters for its environment. The goal is not
+// to execute the transaction successfully, rather to warm up the touched state.
+func precacheTransaction(config *params.ChainConfig, bc ChainContext, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, signer types.Signer, cfg vm.Config) error {
+	// Convert the transaction into an executable message and pre-cache its sender
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		return err
+	}
+	// Create the EVM and execute the transaction
+	context := NewEVMContext(msg, header, bc, nil)
+	vmenv := vm.NewEVM(context, statedb, config, cfg)
+
+	_, _, _, err = ApplyMessage(vmenv, msg, gp)
+	return err
+}
@@ -0,0 +1,69 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that the prefetcher executes a block on the throwaway state only, and
+// stops once interrupted.
+func TestStatePrefetch(t *testing.T) {
+	var (
+		db, _   = aquadb.NewMemDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{address: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, aquahash.NewFaker(), db, 1, func(i int, block *BlockGen) {
+		for j := 0; j < 3; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+			block.AddTx(tx)
+		}
+	})
+	chain, _ := NewBlockChain(db, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	defer chain.Stop()
+
+	interrupt := uint32(1)
+	throwaway, _ := state.New(genesis.Root(), chain.stateCache)
+	chain.prefetcher.Prefetch(blocks[0], throwaway, vm.Config{}, &interrupt)
+	if nonce := throwaway.GetNonce(address); nonce != 0 {
+		t.Errorf("interrupted prefetch nonce mismatch: have %d, want 0", nonce)
+	}
+	throwaway, _ = state.New(genesis.Root(), chain.stateCache)
+	chain.prefetcher.Prefetch(blocks[0], throwaway, vm.Config{}, nil)
+	if nonce := throwaway.GetNonce(address); nonce != 3 {
+		t.Errorf("prefetch nonce mismatch: have %d, want 3", nonce)
+	}
+	// The chain state must be left untouched
+	statedb, _ := chain.State()
+	if nonce := statedb.GetNonce(address); nonce != 0 {
+		t.Errorf("chain nonce mismatch: have %d, want 0", nonce)
+	}
+}
@@ -97,6 +97,10 @@ func ApplyTransaction(config *params.ChainConfig, bc *BlockChain, author *common
 	if err != nil {
 		return nil, 0, err
 	}
+	// Refuse transactions of senders not permitted on the network
+	if bc != nil && bc.senderFilter != nil && !bc.senderFilter(msg.From(), header) {
+		return nil, 0, ErrSenderNotPermitted
+	}
 	// Create a new context to be used in the EVM environment
 	context := NewEVMContext(msg, header, bc, author)
 	// Create a new environment which holds all relevant information
@@ -67,6 +67,8 @@ type Message interface {
 	To() *common.Address
 
 	GasPrice() *big.Int
+	GasFeeCap() *big.Int // Maximum price per gas, base fee included, the gas price of legacy messages
+	GasTipCap() *big.Int // Maximum tip per gas to the miner, the gas price of legacy messages
 	Gas() uint64
 	Value() *big.Int
 
@@ -114,7 +116,7 @@ func NewStateTransition(evm *vm.EVM, msg Message, gp *GasPool) *StateTransition
 		gp:       gp,
 		evm:      evm,
 		msg:      msg,
-		gasPrice: msg.GasPrice(),
+		gasPrice: effectiveGasPrice(msg, evm.BaseFee),
 		value:    msg.Value(),
 		data:     msg.Data(),
 		state:    evm.StateDB,
@@ -132,6 +134,20 @@ func ApplyMessage(evm *vm.EVM, msg Message, gp *GasPool) ([]byte, uint64, bool,
 	return NewStateTransition(evm, msg, gp).TransitionDb()
 }
 
+// effectiveGasPrice returns the price per gas msg pays in a block with the given
+// base fee: its gas price before the fee market, the base fee and its tip,
+// capped by its fee cap, after.
+func effectiveGasPrice(msg Message, baseFee *big.Int) *big.Int {
+	if baseFee == nil {
+		return msg.GasPrice()
+	}
+	price := new(big.Int).Add(baseFee, msg.GasTipCap())
+	if price.Cmp(msg.GasFeeCap()) > 0 {
+		price.Set(msg.GasFeeCap())
+	}
+	return price
+}
+
 func (st *StateTransition) from() vm.AccountRef {
 	f := st.msg.From()
 	if !st.state.Exist(f) {
@@ -197,6 +213,15 @@ func (st *StateTransition) preCheck() error {
 			return ErrNonceTooLow
 		}
 	}
+	// Make sure the transaction pays the base fee, calls may pay nothing
+	if baseFee := st.evm.BaseFee; baseFee != nil && msg.CheckNonce() {
+		if msg.GasFeeCap().Cmp(msg.GasTipCap()) < 0 {
+			return ErrTipAboveFeeCap
+		}
+		if msg.GasFeeCap().Cmp(baseFee) < 0 {
+			return ErrFeeCapTooLow
+		}
+	}
 	return st.buyGas()
 }
 
@@ -246,7 +271,15 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 		}
 	}
 	st.refundGas()
-	st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice))
+
+	// The miner earns the price paid above the base fee, the base fee is burnt
+	tip := st.gasPrice
+	if baseFee := st.evm.BaseFee; baseFee != nil {
+		if tip = new(big.Int).Sub(st.gasPrice, baseFee); tip.Sign() < 0 {
+			tip.SetUint64(0)
+		}
+	}
+	st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), tip))
 
 	return ret, st.gasUsed(), vmerr != nil, err
 }
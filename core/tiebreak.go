@@ -0,0 +1,93 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	mrand "math/rand"
+	"strings"
+
+	"github.com/aquanetwork/aquachain/core/types"
+)
+
+// TieBreak is the rule choosing whether a block with the same total difficulty
+// as the current head replaces it. Blocks at a lower height always win a tie,
+// as extending a shorter chain reduces the reward of withholding blocks; the
+// rule only decides between blocks at the same height.
+//
+// The tie-breaking rule isn't part of consensus: nodes with different rules
+// agree on the chain as soon as either side of the tie is extended. While a tie
+// lasts however, it decides which block the node mines on. Miners following a
+// deterministic rule other than lowest-hash may keep mining on different sides
+// of a tie, and a miner preferring its own blocks never helps resolve a tie it
+// is part of.
+type TieBreak string
+
+const (
+	// TieBreakRandom switches to the competing block with a probability of one
+	// half, which spreads the hash power of honest miners evenly across a tie.
+	TieBreakRandom TieBreak = "random"
+
+	// TieBreakFirstSeen keeps the block which was imported first.
+	TieBreakFirstSeen TieBreak = "first-seen"
+
+	// TieBreakLowestHash picks the block with the lowest hash, so all nodes
+	// following the rule converge on the same block regardless of the order
+	// they saw the blocks in.
+	TieBreakLowestHash TieBreak = "lowest-hash"
+
+	// TieBreakPreferLocal picks the block mined by the local node, keeping the
+	// first seen block if neither or both are.
+	TieBreakPreferLocal TieBreak = "prefer-local"
+)
+
+// TieBreaks are the supported tie-breaking rules.
+var TieBreaks = []TieBreak{TieBreakRandom, TieBreakFirstSeen, TieBreakLowestHash, TieBreakPreferLocal}
+
+// ParseTieBreak parses the name of a tie-breaking rule.
+func ParseTieBreak(name string) (TieBreak, error) {
+	for _, rule := range TieBreaks {
+		if TieBreak(name) == rule {
+			return rule, nil
+		}
+	}
+	names := make([]string, len(TieBreaks))
+	for i, rule := range TieBreaks {
+		names[i] = string(rule)
+	}
+	return "", fmt.Errorf("unknown tie-breaking rule %q, want one of %s", name, strings.Join(names, ", "))
+}
+
+// replaces reports whether block should replace the current head, both having
+// the same total difficulty. The isLocal callback reports whether a block was
+// mined by the local node and may be nil.
+func (t TieBreak) replaces(current, block *types.Block, isLocal func(*types.Block) bool) bool {
+	if block.NumberU64() != current.NumberU64() {
+		return block.NumberU64() < current.NumberU64()
+	}
+	switch t {
+	case TieBreakFirstSeen:
+		return false
+	case TieBreakLowestHash:
+		return bytes.Compare(block.Hash().Bytes(), current.Hash().Bytes()) < 0
+	case TieBreakPreferLocal:
+		return isLocal != nil && isLocal(block) && !isLocal(current)
+	default:
+		return mrand.Float64() < 0.5
+	}
+}
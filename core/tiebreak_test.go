@@ -0,0 +1,88 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+)
+
+var tieLocal = common.HexToAddress("0x0000000000000000000000000000000000000001")
+
+func tieBlock(number int64, coinbase common.Address, nonce uint64) *types.Block {
+	return types.NewBlockWithHeader(&types.Header{
+		Number:   big.NewInt(number),
+		Coinbase: coinbase,
+		Nonce:    types.EncodeNonce(nonce),
+		Version:  types.H_KECCAK256,
+	})
+}
+
+func TestTieBreak(t *testing.T) {
+	isLocal := func(block *types.Block) bool { return block.Coinbase() == tieLocal }
+
+	a, b := tieBlock(10, common.Address{}, 1), tieBlock(10, tieLocal, 2)
+	low, high := a, b
+	if bytes.Compare(a.Hash().Bytes(), b.Hash().Bytes()) > 0 {
+		low, high = b, a
+	}
+	lower := tieBlock(9, common.Address{}, 3)
+
+	tests := []struct {
+		rule           TieBreak
+		current, block *types.Block
+		replaces       bool
+	}{
+		// Lower blocks win under every rule
+		{TieBreakFirstSeen, a, lower, true},
+		{TieBreakLowestHash, a, lower, true},
+		{TieBreakPreferLocal, b, lower, true},
+		{TieBreakFirstSeen, lower, a, false},
+
+		{TieBreakFirstSeen, a, b, false},
+		{TieBreakFirstSeen, b, a, false},
+		{TieBreakLowestHash, high, low, true},
+		{TieBreakLowestHash, low, high, false},
+		{TieBreakPreferLocal, a, b, true},
+		{TieBreakPreferLocal, b, a, false},
+		{TieBreakPreferLocal, a, tieBlock(10, common.Address{}, 4), false},
+	}
+	for i, test := range tests {
+		if replaces := test.rule.replaces(test.current, test.block, isLocal); replaces != test.replaces {
+			t.Errorf("test %d (%s): replaces mismatch: have %v, want %v", i, test.rule, replaces, test.replaces)
+		}
+	}
+	// Without knowledge of local blocks, prefer-local keeps the first seen
+	if TieBreakPreferLocal.replaces(a, b, nil) {
+		t.Error("prefer-local replaced head without local block callback")
+	}
+}
+
+func TestParseTieBreak(t *testing.T) {
+	for _, rule := range TieBreaks {
+		if parsed, err := ParseTieBreak(string(rule)); err != nil || parsed != rule {
+			t.Errorf("failed to parse %q: have %q, %v", rule, parsed, err)
+		}
+	}
+	if _, err := ParseTieBreak("highest-hash"); err == nil {
+		t.Error("parsed unknown rule")
+	}
+}
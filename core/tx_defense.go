@@ -0,0 +1,269 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/metrics"
+)
+
+const (
+	defenseActivate   = 80 // Pool fill percentage activating the spam defense
+	defenseDeactivate = 50 // Pool fill percentage deactivating it
+
+	defenseWindow        = time.Minute      // Period of the per sender rate and per peer invalid transaction limits
+	defenseRateLimit     = 16               // Remote transactions accepted per sender and window while active
+	defenseInvalidLimit  = 8                // Malformed transactions per peer and window triggering a ban
+	defenseBanTime       = 30 * time.Minute // Duration of the bans of flooding peers
+	defenseMaxEscalation = 16               // Maximum multiplier of the minimum gas price while active
+)
+
+var (
+	// ErrPeerBanned is returned if the peer relaying a transaction is temporarily
+	// banned for flooding the pool with malformed transactions.
+	ErrPeerBanned = errors.New("peer temporarily banned")
+
+	// ErrSenderRateLimited is returned if the sender of a transaction exceeded
+	// its rate while the spam defense is active.
+	ErrSenderRateLimited = errors.New("sender rate limited")
+
+	defenseActiveGauge = metrics.NewRegisteredGauge("txpool/defense/active", nil)
+	defenseRejectMeter = metrics.NewRegisteredMeter("txpool/defense/reject", nil)
+	defenseBannedGauge = metrics.NewRegisteredGauge("txpool/defense/banned", nil)
+)
+
+// DefenseStatus reports the state of the transaction pool spam defense.
+type DefenseStatus struct {
+	Enabled    bool         `json:"enabled"`
+	Active     bool         `json:"active"`
+	Since      *time.Time   `json:"since,omitempty"` // Activation time, if active
+	Escalation uint64       `json:"escalation"`      // Multiplier of the minimum gas price
+	Banned     []BannedPeer `json:"banned"`
+}
+
+// BannedPeer is a peer banned for flooding the pool with malformed transactions.
+type BannedPeer struct {
+	Peer  string    `json:"peer"`
+	Until time.Time `json:"until"`
+}
+
+// spamDefense mitigates transaction floods while the pool is under pressure:
+// remote transactions are rate limited per sender, the minimum gas price is
+// escalated while the pressure lasts, and peers flooding the pool with malformed
+// transactions are banned for a while. Local transactions are never affected,
+// and every action is logged and undone once the pressure is gone or the ban
+// expires.
+//
+// Only accepted transactions count against the rate of their sender, and only
+// transactions no honest peer relays count against the peer: anyone may replay
+// the stale or known transactions of others.
+//
+// It's not thread safe, the pool lock guards it.
+type spamDefense struct {
+	active     bool
+	since      time.Time // Activation time
+	escalated  time.Time // Last escalation of the minimum gas price
+	escalation uint64    // Multiplier of the minimum gas price
+
+	window  time.Time              // Start of the current rate window
+	rates   map[common.Address]int // Accepted transactions per sender in the window
+	invalid map[string]int         // Malformed transactions per peer in the window
+	banned  map[string]time.Time   // Banned peers and the end of their bans
+
+	now func() time.Time
+}
+
+func newSpamDefense() *spamDefense {
+	return &spamDefense{
+		escalation: 1,
+		rates:      make(map[common.Address]int),
+		invalid:    make(map[string]int),
+		banned:     make(map[string]time.Time),
+		now:        time.Now,
+	}
+}
+
+// update activates or deactivates the defense according to the pool fill, and
+// escalates the minimum gas price while the pressure lasts.
+func (d *spamDefense) update(size, capacity uint64) {
+	now := d.now()
+	if now.Sub(d.window) >= defenseWindow {
+		d.window = now
+		d.rates = make(map[common.Address]int)
+		d.invalid = make(map[string]int)
+	}
+	for peer, until := range d.banned {
+		if !now.Before(until) {
+			delete(d.banned, peer)
+			log.Info("Transaction pool ban expired", "peer", peer)
+		}
+	}
+	defenseBannedGauge.Update(int64(len(d.banned)))
+
+	fill := size * 100 / capacity
+	switch {
+	case !d.active && fill >= defenseActivate:
+		d.active, d.since, d.escalated = true, now, now
+		defenseActiveGauge.Update(1)
+		log.Warn("Transaction pool under pressure, spam defense activated", "pooled", size, "capacity", capacity)
+
+	case d.active && fill < defenseDeactivate:
+		log.Warn("Transaction pool pressure relieved, spam defense deactivated", "pooled", size, "duration", common.PrettyDuration(now.Sub(d.since)), "escalation", d.escalation)
+		d.deactivate()
+
+	case d.active && now.Sub(d.escalated) >= defenseWindow && d.escalation < defenseMaxEscalation:
+		d.escalated = now
+		d.escalation *= 2
+		log.Warn("Escalating transaction pool minimum gas price", "multiplier", d.escalation)
+	}
+}
+
+// deactivate lifts the rate limits and the escalated minimum gas price.
+func (d *spamDefense) deactivate() {
+	d.active, d.escalation = false, 1
+	defenseActiveGauge.Update(0)
+}
+
+// admit checks a remote transaction against the rate limit and the escalated
+// minimum gas price while active.
+func (d *spamDefense) admit(from common.Address, tx *types.Transaction, minPrice *big.Int) error {
+	if !d.active {
+		return nil
+	}
+	if d.rates[from] >= defenseRateLimit {
+		defenseRejectMeter.Mark(1)
+		return ErrSenderRateLimited
+	}
+	if d.escalation > 1 && tx.GasPrice().Cmp(new(big.Int).Mul(minPrice, new(big.Int).SetUint64(d.escalation))) < 0 {
+		defenseRejectMeter.Mark(1)
+		return ErrUnderpriced
+	}
+	return nil
+}
+
+// accepted counts a valid remote transaction against the rate of its sender.
+func (d *spamDefense) accepted(from common.Address) {
+	if d.active {
+		d.rates[from]++
+	}
+}
+
+// bannedPeer returns whether the peer is banned from relaying transactions.
+func (d *spamDefense) bannedPeer(peer string) bool {
+	until, ok := d.banned[peer]
+	return ok && d.now().Before(until)
+}
+
+// malformed returns whether a transaction rejected with the given error proves
+// the peer relaying it misbehaved, no valid transaction ever failing so.
+func malformed(err error) bool {
+	switch err {
+	case ErrInvalidSender, ErrIntrinsicGas, ErrOversizedData, ErrNegativeValue, ErrTipAboveFeeCap:
+		return true
+	}
+	return false
+}
+
+// invalidTx notes a transaction a peer relayed that the pool rejected, banning
+// the peer if it floods the pool with malformed ones while the defense is active.
+func (d *spamDefense) invalidTx(peer string, err error) {
+	if !d.active || !malformed(err) {
+		return
+	}
+	d.invalid[peer]++
+	if d.invalid[peer] == defenseInvalidLimit {
+		until := d.now().Add(defenseBanTime)
+		d.banned[peer] = until
+		defenseBannedGauge.Update(int64(len(d.banned)))
+		log.Warn("Banning peer flooding the transaction pool", "peer", peer, "invalid", d.invalid[peer], "last", err, "until", until)
+	}
+}
+
+// unban lifts the ban of a peer, returning whether it was banned.
+func (d *spamDefense) unban(peer string) bool {
+	if _, ok := d.banned[peer]; !ok {
+		return false
+	}
+	delete(d.banned, peer)
+	defenseBannedGauge.Update(int64(len(d.banned)))
+	log.Info("Transaction pool ban lifted", "peer", peer)
+	return true
+}
+
+// status reports the state of the defense.
+func (d *spamDefense) status() DefenseStatus {
+	status := DefenseStatus{
+		Enabled:    true,
+		Active:     d.active,
+		Escalation: d.escalation,
+		Banned:     []BannedPeer{},
+	}
+	if d.active {
+		since := d.since
+		status.Since = &since
+	}
+	for peer, until := range d.banned {
+		status.Banned = append(status.Banned, BannedPeer{Peer: peer, Until: until})
+	}
+	sort.Slice(status.Banned, func(i, j int) bool { return status.Banned[i].Until.Before(status.Banned[j].Until) })
+	return status
+}
+
+// DefenseStatus reports the state of the spam defense of the pool.
+func (pool *TxPool) DefenseStatus() DefenseStatus {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	if pool.defense == nil {
+		return DefenseStatus{Banned: []BannedPeer{}, Escalation: 1}
+	}
+	return pool.defense.status()
+}
+
+// Unban lifts the ban of a peer flooding the pool, returning whether it was
+// banned.
+func (pool *TxPool) Unban(peer string) bool {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	return pool.defense != nil && pool.defense.unban(peer)
+}
+
+// ResetDefense deactivates the spam defense and lifts all bans. The defense is
+// activated again if the pool is still under pressure when transactions arrive.
+func (pool *TxPool) ResetDefense() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.defense == nil {
+		return
+	}
+	for peer := range pool.defense.banned {
+		pool.defense.unban(peer)
+	}
+	if pool.defense.active {
+		log.Warn("Transaction pool spam defense reset")
+		pool.defense.deactivate()
+	}
+}
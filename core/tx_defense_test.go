@@ -0,0 +1,187 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// newTestDefense creates a spam defense on a fake clock, returning a function
+// to advance it.
+func newTestDefense() (*spamDefense, func(time.Duration)) {
+	d := newSpamDefense()
+	now := time.Unix(1500000000, 0)
+	d.now = func() time.Time { return now }
+	return d, func(dt time.Duration) { now = now.Add(dt) }
+}
+
+// Tests that the defense activates under pressure, rate limits the senders and
+// escalates the minimum gas price until the pressure is relieved.
+func TestSpamDefenseThrottling(t *testing.T) {
+	d, advance := newTestDefense()
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	minPrice := big.NewInt(1)
+
+	// Nothing is throttled without pressure
+	d.update(79, 100)
+	for i := 0; i < 2*defenseRateLimit; i++ {
+		if err := d.admit(from, transaction(uint64(i), 100000, key), minPrice); err != nil {
+			t.Fatalf("transaction %d throttled without pressure: %v", i, err)
+		}
+		d.accepted(from)
+	}
+	// Under pressure, senders are rate limited per window
+	d.update(80, 100)
+	if !d.status().Active {
+		t.Fatalf("defense not activated under pressure")
+	}
+	for i := 0; i < defenseRateLimit; i++ {
+		if err := d.admit(from, transaction(uint64(i), 100000, key), minPrice); err != nil {
+			t.Fatalf("transaction %d throttled within the rate: %v", i, err)
+		}
+		d.accepted(from)
+	}
+	if err := d.admit(from, transaction(defenseRateLimit, 100000, key), minPrice); err != ErrSenderRateLimited {
+		t.Fatalf("rate limit error mismatch: have %v, want %v", err, ErrSenderRateLimited)
+	}
+	// The minimum gas price escalates each window, up to the cap
+	for _, want := range []uint64{2, 4, 8, 16, defenseMaxEscalation} {
+		advance(defenseWindow)
+		d.update(90, 100)
+		if have := d.status().Escalation; have != want {
+			t.Fatalf("escalation mismatch: have %d, want %d", have, want)
+		}
+	}
+	cheap := pricedTransaction(0, 100000, big.NewInt(defenseMaxEscalation-1), key)
+	if err := d.admit(from, cheap, minPrice); err != ErrUnderpriced {
+		t.Fatalf("escalated price error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if err := d.admit(from, pricedTransaction(0, 100000, big.NewInt(defenseMaxEscalation), key), minPrice); err != nil {
+		t.Fatalf("transaction at the escalated price throttled: %v", err)
+	}
+	// Hysteresis keeps the defense active until the pool drains enough
+	d.update(60, 100)
+	if !d.status().Active {
+		t.Fatalf("defense deactivated above the low watermark")
+	}
+	d.update(49, 100)
+	if status := d.status(); status.Active || status.Escalation != 1 {
+		t.Fatalf("defense not reverted: active %v, escalation %d", status.Active, status.Escalation)
+	}
+	if err := d.admit(from, cheap, minPrice); err != nil {
+		t.Fatalf("transaction throttled after deactivation: %v", err)
+	}
+}
+
+// Tests that peers flooding the pool with malformed transactions are banned
+// while under pressure, until the ban expires or is lifted, and that replaying
+// stale transactions, which anyone can do, bans neither the peer nor the sender.
+func TestSpamDefenseBans(t *testing.T) {
+	t.Parallel()
+
+	db, _ := aquadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Defense = true
+	config.GlobalSlots = 8
+	config.GlobalQueue = 2
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	filler, _ := crypto.GenerateKey()
+	victim, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(victim.PublicKey)
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(filler.PublicKey), big.NewInt(100000000))
+	pool.currentState.AddBalance(from, big.NewInt(100000000))
+	pool.currentState.SetNonce(from, defenseInvalidLimit)
+
+	relay := func(peer string, tx *types.Transaction) error {
+		return pool.AddRemotesFrom(peer, []*types.Transaction{tx})[0]
+	}
+	// Malformed transactions don't lead to bans without pressure
+	if err := relay("spammer", transaction(defenseInvalidLimit, 1000, victim)); err != ErrIntrinsicGas {
+		t.Fatalf("malformed transaction error mismatch: have %v, want %v", err, ErrIntrinsicGas)
+	}
+	for i := uint64(0); i < config.GlobalSlots; i++ {
+		if err := pool.AddRemote(transaction(i, 100000, filler)); err != nil {
+			t.Fatalf("failed to fill the pool with transaction %d: %v", i, err)
+		}
+	}
+	// Under pressure, replaying the stale transactions of a sender bans nobody
+	for i := uint64(0); i < 2*defenseInvalidLimit; i++ {
+		if err := relay("replayer", transaction(i%defenseInvalidLimit, 100000, victim)); err != ErrNonceTooLow {
+			t.Fatalf("stale transaction %d error mismatch: have %v, want %v", i, err, ErrNonceTooLow)
+		}
+	}
+	if banned := pool.DefenseStatus().Banned; len(banned) != 0 {
+		t.Fatalf("peer banned for replaying stale transactions: %v", banned)
+	}
+	// Flooding malformed transactions bans the peer, not the sender
+	for i := 0; i < defenseInvalidLimit; i++ {
+		if err := relay("spammer", transaction(defenseInvalidLimit, 1000, victim)); err != ErrIntrinsicGas {
+			t.Fatalf("malformed transaction %d error mismatch: have %v, want %v", i, err, ErrIntrinsicGas)
+		}
+	}
+	status := pool.DefenseStatus()
+	if !status.Active || len(status.Banned) != 1 || status.Banned[0].Peer != "spammer" {
+		t.Fatalf("defense status mismatch: %+v", status)
+	}
+	valid := transaction(defenseInvalidLimit, 100000, victim)
+	if err := relay("spammer", valid); err != ErrPeerBanned {
+		t.Fatalf("banned peer error mismatch: have %v, want %v", err, ErrPeerBanned)
+	}
+	if err := relay("replayer", valid); err != nil {
+		t.Fatalf("transaction of sender relayed by banned peer rejected: %v", err)
+	}
+	// Bans may be lifted manually, and expire on their own
+	if !pool.Unban("spammer") || pool.Unban("spammer") {
+		t.Fatalf("unban result mismatch")
+	}
+	now := time.Now()
+	pool.mu.Lock()
+	pool.defense.banned["spammer"] = now.Add(defenseBanTime)
+	pool.defense.now = func() time.Time { return now.Add(defenseBanTime) }
+	pool.mu.Unlock()
+
+	// Expiry moves the defense to the next window, escalating the minimum price
+	if err := relay("spammer", pricedTransaction(defenseInvalidLimit+1, 100000, big.NewInt(defenseMaxEscalation), victim)); err != nil {
+		t.Fatalf("transaction rejected after the ban expired: %v", err)
+	}
+	if banned := pool.DefenseStatus().Banned; len(banned) != 0 {
+		t.Fatalf("expired ban still reported: %v", banned)
+	}
+	// Resetting deactivates the defense
+	pool.ResetDefense()
+	if pool.DefenseStatus().Active {
+		t.Fatalf("defense active after reset")
+	}
+}
@@ -161,3 +161,74 @@ func (journal *txJournal) close() error {
 	}
 	return err
 }
+
+// saveTxSnapshot writes the given transactions into a new snapshot file at path,
+// replacing any previous one.
+func saveTxSnapshot(path string, all map[common.Address]types.Transactions) error {
+	output, err := os.OpenFile(path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	saved := 0
+	for _, txs := range all {
+		for _, tx := range txs {
+			if err = rlp.Encode(output, tx); err != nil {
+				output.Close()
+				return err
+			}
+		}
+		saved += len(txs)
+	}
+	if err := output.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".new", path); err != nil {
+		return err
+	}
+	log.Info("Saved transaction pool snapshot", "transactions", saved, "accounts", len(all))
+	return nil
+}
+
+// AppendTxSnapshot adds the given transactions to the snapshot file at path,
+// for the transaction pool to reinject them on its next start.
+func AppendTxSnapshot(path string, txs types.Transactions) error {
+	output, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if err = rlp.Encode(output, tx); err != nil {
+			output.Close()
+			return err
+		}
+	}
+	return output.Close()
+}
+
+// loadTxSnapshot parses a transaction pool snapshot from disk. The transactions
+// decoded before any failure are returned too.
+func loadTxSnapshot(path string) (types.Transactions, error) {
+	input, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	var (
+		stream = rlp.NewStream(input, 0)
+		txs    types.Transactions
+	)
+	for {
+		tx := new(types.Transaction)
+		if err := stream.Decode(tx); err != nil {
+			if err == io.EOF {
+				return txs, nil
+			}
+			return txs, err
+		}
+		txs = append(txs, tx)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"os"
 	"sort"
 	"sync"
 	"time"
@@ -78,6 +79,10 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrSenderNotPermitted is returned if the sender of a transaction is not
+	// allowed to transact on a permissioned network.
+	ErrSenderNotPermitted = errors.New("sender not permitted")
 )
 
 var (
@@ -125,9 +130,12 @@ type blockChain interface {
 
 // TxPoolConfig are the configuration parameters of the transaction pool.
 type TxPoolConfig struct {
-	NoLocals  bool          // Whether local transaction handling should be disabled
-	Journal   string        // Journal of local transactions to survive node restarts
-	Rejournal time.Duration // Time interval to regenerate the local transaction journal
+	Locals    []common.Address // Addresses whose transactions are treated as local wherever they arrive from
+	NoLocals  bool             // Whether local transaction handling should be disabled
+	Journal   string           // Journal of local transactions to survive node restarts
+	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
+	Snapshot  string           // Snapshot of the whole pool saved on shutdown and restored on start
+	Defense   bool             // Whether to mitigate remote transaction floods while the pool is under pressure
 
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
@@ -145,6 +153,7 @@ type TxPoolConfig struct {
 var DefaultTxPoolConfig = TxPoolConfig{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
+	Snapshot:  "transactions.snapshot.rlp",
 
 	PriceLimit: 1,
 	PriceBump:  10,
@@ -200,8 +209,13 @@ type TxPool struct {
 	currentMaxGas uint64              // Current gas limit for transaction caps
 
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
+	system  *accountSet // Set of system accounts exempt from the price floor
 	journal *txJournal  // Journal of local transaction to back up to disk
 
+	senderFilter func(common.Address) bool // Permits transaction senders, nil if unrestricted
+	filterLock   sync.RWMutex              // Lock protecting the sender filter, separate from mu as filters may be slow
+	defense      *spamDefense              // Mitigates transaction floods, nil if disabled
+
 	pending map[common.Address]*txList         // All currently processable transactions
 	queue   map[common.Address]*txList         // Queued but non-processable transactions
 	beats   map[common.Address]time.Time       // Last heartbeat from each known account
@@ -211,6 +225,7 @@ type TxPool struct {
 	wg sync.WaitGroup // for shutdown sync
 
 	homestead bool
+	feeMarket bool // Whether the next block accepts dynamic fee transactions
 }
 
 // NewTxPool creates a new transaction pool to gather, sort and filter inbound
@@ -224,7 +239,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		config:      config,
 		chainconfig: chainconfig,
 		chain:       chain,
-		signer:      types.NewEIP155Signer(chainconfig.ChainId),
+		signer:      types.NewFeeMarketSigner(chainconfig.ChainId),
 		pending:     make(map[common.Address]*txList),
 		queue:       make(map[common.Address]*txList),
 		beats:       make(map[common.Address]time.Time),
@@ -233,6 +248,20 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
 	}
 	pool.locals = newAccountSet(pool.signer)
+	for _, addr := range config.Locals {
+		log.Info("Setting new local account", "address", addr)
+		pool.locals.add(addr)
+	}
+	pool.system = newAccountSet(pool.signer)
+	if chainconfig.Permission != nil {
+		// System accounts transact for free, but are not treated as locals
+		for _, addr := range chainconfig.Permission.SystemAccounts {
+			pool.system.add(addr)
+		}
+	}
+	if config.Defense {
+		pool.defense = newSpamDefense()
+	}
 	pool.priced = newTxPricedList(&pool.all)
 	pool.reset(nil, chain.CurrentBlock().Header())
 
@@ -247,6 +276,10 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// Restore the transactions the pool held when the node was last stopped
+	if config.Snapshot != "" {
+		pool.restoreSnapshot()
+	}
 	// Subscribe events from blockchain
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
 
@@ -361,6 +394,9 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 
 		if depth := uint64(math.Abs(float64(oldNum) - float64(newNum))); depth > 64 {
 			log.Debug("Skipping deep transaction reorg", "depth", depth)
+		} else if pool.chain.GetBlock(oldHead.Hash(), oldNum) == nil {
+			// The chain was rewound below the old head, see Reinject
+			log.Debug("Skipping transaction reorg from a rewound head", "number", oldNum, "hash", oldHead.Hash())
 		} else {
 			// Reorg seems shallow enough to pull in all transactions into memory
 			var discarded, included types.Transactions
@@ -410,10 +446,11 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.currentState = statedb
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
+	pool.feeMarket = pool.chainconfig.IsFeeMarket(new(big.Int).Add(newHead.Number, big.NewInt(1)))
 
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
-	pool.addTxsLocked(reinject, false)
+	pool.addTxsLocked(reinject, false, "")
 
 	// validate the pool of pending transactions, this will remove
 	// any transactions that have been included in the block or
@@ -431,6 +468,16 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.promoteExecutables(nil)
 }
 
+// Reinject resets the pool to the given head after the chain was rewound below
+// the previous one, and adds back the transactions of the rewound blocks.
+func (pool *TxPool) Reinject(head *types.Header, txs types.Transactions) []error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.reset(nil, head)
+	return pool.addTxsLocked(txs, false, "")
+}
+
 // Stop terminates the transaction pool.
 func (pool *TxPool) Stop() {
 	// Unsubscribe all subscriptions registered from txpool
@@ -440,6 +487,9 @@ func (pool *TxPool) Stop() {
 	pool.chainHeadSub.Unsubscribe()
 	pool.wg.Wait()
 
+	if pool.config.Snapshot != "" {
+		pool.saveSnapshot()
+	}
 	if pool.journal != nil {
 		pool.journal.close()
 	}
@@ -467,12 +517,41 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	defer pool.mu.Unlock()
 
 	pool.gasPrice = price
-	for _, tx := range pool.priced.Cap(price, pool.locals) {
+	for _, tx := range pool.priced.Cap(price, pool.protected()) {
 		pool.removeTx(tx.Hash())
 	}
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
+// SetSenderFilter restricts the transactions accepted by the pool to the ones
+// sent by accounts permitted by the filter. A nil filter lifts the restriction.
+func (pool *TxPool) SetSenderFilter(filter func(common.Address) bool) {
+	pool.filterLock.Lock()
+	defer pool.filterLock.Unlock()
+
+	pool.senderFilter = filter
+}
+
+// checkSenders runs the sender filter over a batch of transactions, returning
+// ErrSenderNotPermitted for the ones it refuses. Filters may execute contract
+// calls, so this is done before acquiring the pool lock. Transactions with an
+// invalid signature are left to validateTx.
+func (pool *TxPool) checkSenders(txs []*types.Transaction) []error {
+	pool.filterLock.RLock()
+	defer pool.filterLock.RUnlock()
+
+	errs := make([]error, len(txs))
+	if pool.senderFilter == nil {
+		return errs
+	}
+	for i, tx := range txs {
+		if from, err := types.Sender(pool.signer, tx); err == nil && !pool.senderFilter(from) {
+			errs[i] = ErrSenderNotPermitted
+		}
+	}
+	return errs
+}
+
 // State returns the virtual managed state of the transaction pool.
 func (pool *TxPool) State() *state.ManagedState {
 	pool.mu.RLock()
@@ -551,13 +630,87 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// protected returns the accounts whose transactions are never evicted for being
+// underpriced: the local accounts and the system accounts of the network.
+func (pool *TxPool) protected() *accountSet {
+	if len(pool.system.accounts) == 0 {
+		return pool.locals
+	}
+	set := newAccountSet(pool.signer)
+	set.merge(pool.locals)
+	set.merge(pool.system)
+	return set
+}
+
+// snapshotted retrieves all transactions not covered by the local journal,
+// grouped by origin account and sorted by nonce.
+func (pool *TxPool) snapshotted() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for addr, list := range pool.pending {
+		if pool.journal == nil || !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], list.Flatten()...)
+		}
+	}
+	for addr, list := range pool.queue {
+		if pool.journal == nil || !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], list.Flatten()...)
+		}
+	}
+	return txs
+}
+
+// saveSnapshot writes the transactions of the pool which wouldn't survive the
+// restart otherwise to the snapshot file.
+func (pool *TxPool) saveSnapshot() {
+	pool.mu.RLock()
+	txs := pool.snapshotted()
+	pool.mu.RUnlock()
+
+	if err := saveTxSnapshot(pool.config.Snapshot, txs); err != nil {
+		log.Warn("Failed to save transaction pool snapshot", "err", err)
+	}
+}
+
+// restoreSnapshot reinjects the transactions saved on the last shutdown as
+// remote ones, dropping any which became invalid since, and removes the snapshot
+// so they aren't restored again after a crash.
+func (pool *TxPool) restoreSnapshot() {
+	txs, err := loadTxSnapshot(pool.config.Snapshot)
+	if err != nil {
+		log.Warn("Failed to load transaction pool snapshot", "err", err)
+	}
+	if len(txs) == 0 {
+		return
+	}
+	dropped := 0
+	for _, err := range pool.AddRemotes(txs) {
+		if err != nil {
+			log.Debug("Failed to restore snapshotted transaction", "err", err)
+			dropped++
+		}
+	}
+	log.Info("Restored transaction pool snapshot", "transactions", len(txs), "dropped", dropped)
+
+	if err := os.Remove(pool.config.Snapshot); err != nil {
+		log.Warn("Failed to remove transaction pool snapshot", "err", err)
+	}
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
+	// Accept dynamic fee transactions only once the fee market is active
+	if tx.Type() != types.LegacyTxType && !pool.feeMarket {
+		return types.ErrTxTypeNotSupported
+	}
 	// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
 	if tx.Size() > 32*1024 {
 		return ErrOversizedData
 	}
+	// The tip can't exceed the total fee the sender is willing to pay
+	if tx.GasTipCap().Cmp(tx.GasFeeCap()) > 0 {
+		return ErrTipAboveFeeCap
+	}
 	// Transactions can't be negative. This may never happen using RLP decoded
 	// transactions but may occur if you create a transaction using the RPC.
 	if tx.Value().Sign() < 0 {
@@ -572,9 +725,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if err != nil {
 		return ErrInvalidSender
 	}
-	// Drop non-local transactions under our own minimal accepted gas price
+	// Drop non-local transactions under our own minimal accepted gas price,
+	// unless sent by a system account transacting for free
 	local = local || pool.locals.contains(from) // account may be local even if the transaction arrived from the network
-	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
+	if !local && !pool.system.contains(from) && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
 		return ErrUnderpriced
 	}
 	// Ensure the transaction adheres to nonce ordering
@@ -611,22 +765,38 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		log.Trace("Discarding already known transaction", "hash", hash)
 		return false, fmt.Errorf("known transaction: %x", hash)
 	}
+	// Under pressure, throttle remote senders flooding the pool
+	defended := false
+	if pool.defense != nil {
+		pool.defense.update(uint64(len(pool.all)), pool.config.GlobalSlots+pool.config.GlobalQueue)
+		if from, err := types.Sender(pool.signer, tx); err == nil && !local && !pool.locals.contains(from) {
+			if err := pool.defense.admit(from, tx, pool.gasPrice); err != nil {
+				log.Trace("Discarding transaction of throttled sender", "hash", hash, "from", from, "err", err)
+				return false, err
+			}
+			defended = true
+		}
+	}
 	// If the transaction fails basic validation, discard it
 	if err := pool.validateTx(tx, local); err != nil {
 		log.Trace("Discarding invalid transaction", "hash", hash, "err", err)
 		invalidTxCounter.Inc(1)
 		return false, err
 	}
+	if defended {
+		from, _ := types.Sender(pool.signer, tx) // already validated
+		pool.defense.accepted(from)
+	}
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
-		if pool.priced.Underpriced(tx, pool.locals) {
+		if pool.priced.Underpriced(tx, pool.protected()) {
 			log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
 			underpricedTxCounter.Inc(1)
 			return false, ErrUnderpriced
 		}
 		// New transaction is better than our worse ones, make room for it
-		drop := pool.priced.Discard(len(pool.all)-int(pool.config.GlobalSlots+pool.config.GlobalQueue-1), pool.locals)
+		drop := pool.priced.Discard(len(pool.all)-int(pool.config.GlobalSlots+pool.config.GlobalQueue-1), pool.protected())
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
 			underpricedTxCounter.Inc(1)
@@ -768,18 +938,29 @@ func (pool *TxPool) AddRemote(tx *types.Transaction) error {
 // marking the senders as a local ones in the mean time, ensuring they go around
 // the local pricing constraints.
 func (pool *TxPool) AddLocals(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, !pool.config.NoLocals)
+	return pool.addTxs(txs, !pool.config.NoLocals, "")
 }
 
 // AddRemotes enqueues a batch of transactions into the pool if they are valid.
 // If the senders are not among the locally tracked ones, full pricing constraints
 // will apply.
 func (pool *TxPool) AddRemotes(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, false)
+	return pool.addTxs(txs, false, "")
+}
+
+// AddRemotesFrom enqueues a batch of transactions relayed by the given peer, as
+// AddRemotes does. Under pressure, peers flooding the pool with malformed
+// transactions are banned for a while.
+func (pool *TxPool) AddRemotesFrom(peer string, txs []*types.Transaction) []error {
+	return pool.addTxs(txs, false, peer)
 }
 
 // addTx enqueues a single transaction into the pool if it is valid.
 func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
+	// Drop transactions of senders not permitted on the network
+	if err := pool.checkSenders([]*types.Transaction{tx})[0]; err != nil {
+		return err
+	}
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
@@ -796,28 +977,57 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 	return nil
 }
 
-// addTxs attempts to queue a batch of transactions if they are valid.
-func (pool *TxPool) addTxs(txs []*types.Transaction, local bool) []error {
+// addTxs attempts to queue a batch of transactions if they are valid, relayed by
+// the given peer if not empty.
+func (pool *TxPool) addTxs(txs []*types.Transaction, local bool, peer string) []error {
+	// Drop transactions of senders not permitted on the network
+	errs := pool.checkSenders(txs)
+
+	var (
+		permitted []*types.Transaction
+		indices   []int
+	)
+	for i, err := range errs {
+		if err == nil {
+			permitted = append(permitted, txs[i])
+			indices = append(indices, i)
+		}
+	}
+	if len(permitted) == 0 {
+		return errs
+	}
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
-	return pool.addTxsLocked(txs, local)
+	for i, err := range pool.addTxsLocked(permitted, local, peer) {
+		errs[indices[i]] = err
+	}
+	return errs
 }
 
 // addTxsLocked attempts to queue a batch of transactions if they are valid,
-// whilst assuming the transaction pool lock is already held.
-func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool) []error {
+// relayed by the given peer if not empty, whilst assuming the transaction pool
+// lock is already held.
+func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool, peer string) []error {
 	// Add the batch of transaction, tracking the accepted ones
 	dirty := make(map[common.Address]struct{})
 	errs := make([]error, len(txs))
 
 	for i, tx := range txs {
-		var replace bool
-		if replace, errs[i] = pool.add(tx, local); errs[i] == nil {
-			if !replace {
-				from, _ := types.Sender(pool.signer, tx) // already validated
-				dirty[from] = struct{}{}
+		if peer != "" && pool.defense != nil && pool.defense.bannedPeer(peer) {
+			errs[i] = ErrPeerBanned
+			continue
+		}
+		replace, err := pool.add(tx, local)
+		if errs[i] = err; err != nil {
+			if peer != "" && pool.defense != nil {
+				pool.defense.invalidTx(peer, err)
 			}
+			continue
+		}
+		if !replace {
+			from, _ := types.Sender(pool.signer, tx) // already validated
+			dirty[from] = struct{}{}
 		}
 	}
 	// Only reprocess the internal state if something was actually added
@@ -1162,3 +1372,10 @@ func (as *accountSet) containsTx(tx *types.Transaction) bool {
 func (as *accountSet) add(addr common.Address) {
 	as.accounts[addr] = struct{}{}
 }
+
+// merge adds all addresses from the 'other' set into 'as'.
+func (as *accountSet) merge(other *accountSet) {
+	for addr := range other.accounts {
+		as.accounts[addr] = struct{}{}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -42,6 +43,7 @@ var testTxPoolConfig TxPoolConfig
 func init() {
 	testTxPoolConfig = DefaultTxPoolConfig
 	testTxPoolConfig.Journal = ""
+	testTxPoolConfig.Snapshot = ""
 }
 
 type testBlockChain struct {
@@ -261,6 +263,158 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that dynamic fee transactions are only accepted once the fee market is
+// active, and with a tip within their fee cap.
+func TestDynamicFeeTransactions(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	signer := types.NewFeeMarketSigner(params.TestChainConfig.ChainId)
+	dynamicTx := func(nonce uint64, tip, feeCap int64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewDynamicFeeTransaction(params.TestChainConfig.ChainId, nonce, &common.Address{}, big.NewInt(100), 100000, big.NewInt(tip), big.NewInt(feeCap), nil), signer, key)
+		return tx
+	}
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	if err := pool.AddRemote(dynamicTx(0, 1, 2)); err != types.ErrTxTypeNotSupported {
+		t.Error("expected", types.ErrTxTypeNotSupported, "got", err)
+	}
+	pool.mu.Lock()
+	pool.feeMarket = true
+	pool.mu.Unlock()
+
+	if err := pool.AddRemote(dynamicTx(0, 3, 2)); err != ErrTipAboveFeeCap {
+		t.Error("expected", ErrTipAboveFeeCap, "got", err)
+	}
+	if err := pool.AddRemote(dynamicTx(0, 1, 2)); err != nil {
+		t.Error("expected", nil, "got", err)
+	}
+}
+
+// Tests that transactions of senders rejected by the sender filter are dropped,
+// even if they're local.
+func TestSenderFilter(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	tx := transaction(0, 100000, key)
+	from, _ := deriveSender(tx)
+	pool.currentState.AddBalance(from, big.NewInt(0xffffffffffffff))
+
+	permitted := false
+	pool.SetSenderFilter(func(addr common.Address) bool { return addr != from || permitted })
+	if err := pool.AddLocal(tx); err != ErrSenderNotPermitted {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrSenderNotPermitted)
+	}
+	permitted = true
+	if err := pool.AddRemote(tx); err != nil {
+		t.Fatalf("failed to add permitted transaction: %v", err)
+	}
+}
+
+// Tests that zero priced transactions of system accounts bypass the pool pricing
+// on permissioned networks.
+func TestSystemTransactions(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	system := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *params.TestChainConfig
+	config.Permission = &params.PermissionConfig{SystemAccounts: []common.Address{system}}
+
+	diskdb, _ := aquadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer pool.Stop()
+
+	pool.SetGasPrice(big.NewInt(1000))
+	pool.currentState.AddBalance(system, big.NewInt(1000))
+
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(0), key)); err != nil {
+		t.Fatalf("failed to add zero priced system transaction: %v", err)
+	}
+	other, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(other.PublicKey), big.NewInt(1000))
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(0), other)); err != ErrUnderpriced {
+		t.Fatalf("error mismatch for regular account: have %v, want %v", err, ErrUnderpriced)
+	}
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+	// System accounts are only exempt from the price floor, not local
+	if pool.locals.contains(system) {
+		t.Fatalf("system account treated as local")
+	}
+}
+
+// Tests that cheap transactions of system accounts are neither rejected nor
+// evicted as underpriced when the pool is full.
+func TestSystemTransactionsFullPool(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	system := crypto.PubkeyToAddress(key.PublicKey)
+
+	chainconfig := *params.TestChainConfig
+	chainconfig.Permission = &params.PermissionConfig{SystemAccounts: []common.Address{system}}
+
+	diskdb, _ := aquadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 2
+	config.GlobalQueue = 2
+
+	pool := NewTxPool(config, &chainconfig, blockchain)
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(system, big.NewInt(1000000))
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(0), key)); err != nil {
+		t.Fatalf("failed to add cheap system transaction: %v", err)
+	}
+	// Fill up the pool with better paying remote transactions
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+		if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(int64(i+2)), keys[i])); err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", i, err)
+		}
+	}
+	// Overflowing the pool must evict a remote transaction, not the system one
+	extra, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(extra.PublicKey), big.NewInt(1000000))
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(5), extra)); err != nil {
+		t.Fatalf("failed to add well priced transaction: %v", err)
+	}
+	if pool.pending[system] == nil || pool.pending[system].Len() != 1 {
+		t.Fatalf("cheap system transaction evicted")
+	}
+	// A cheap system transaction must not be rejected from the full pool either
+	if err := pool.AddRemote(pricedTransaction(1, 100000, big.NewInt(0), key)); err != nil {
+		t.Fatalf("failed to add cheap system transaction to full pool: %v", err)
+	}
+	if pool.pending[system].Len() != 2 {
+		t.Fatalf("system pending transactions mismatched: have %d, want %d", pool.pending[system].Len(), 2)
+	}
+	// Raising the price threshold must not drop the system transactions
+	pool.SetGasPrice(big.NewInt(1000))
+	if pool.pending[system] == nil || pool.pending[system].Len() != 2 {
+		t.Fatalf("system transactions dropped by price threshold")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
@@ -1264,6 +1418,57 @@ func TestTransactionPoolRepricingKeepsLocals(t *testing.T) {
 	validate()
 }
 
+// Tests that the transactions of the configured local accounts are exempt from
+// the price limit and from eviction, even when arriving from the network.
+func TestTransactionPoolConfiguredLocals(t *testing.T) {
+	t.Parallel()
+
+	db, _ := aquadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+
+	config := testTxPoolConfig
+	config.Locals = []common.Address{crypto.PubkeyToAddress(local.PublicKey)}
+	config.PriceLimit = 2
+	config.GlobalSlots = 1
+	config.GlobalQueue = 1
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(10000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(10000000))
+
+	// Transactions under the price limit are only accepted from the locals
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), remote)); err != ErrUnderpriced {
+		t.Fatalf("remote underpriced transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	locals := []*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(1), local),
+		pricedTransaction(1, 100000, big.NewInt(1), local),
+	}
+	for i, tx := range locals {
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add underpriced transaction %d of local account: %v", i, err)
+		}
+	}
+	// Better priced transactions don't evict the ones of the locals
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(10), remote)); err != nil {
+		t.Fatalf("failed to add well priced transaction: %v", err)
+	}
+	for i, tx := range locals {
+		if pool.Get(tx.Hash()) == nil {
+			t.Errorf("transaction %d of local account evicted", i)
+		}
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Tests that when the pool reaches its global transaction limit, underpriced
 // transactions are gradually shifted out for more expensive ones and any gapped
 // pending transactions are moved into the queue.
@@ -1563,6 +1768,67 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that remote transactions are snapshotted on shutdown and restored on the
+// next start, dropping the ones invalidated in between.
+func TestTransactionSnapshot(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "txsnapshot-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, _ := aquadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Snapshot = filepath.Join(dir, "snapshot.rlp")
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	// Add two pending and a queued transaction of two remote accounts
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key1.PublicKey), big.NewInt(1000000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key2.PublicKey), big.NewInt(1000000000))
+
+	for _, err := range pool.AddRemotes([]*types.Transaction{
+		transaction(0, 100000, key1),
+		transaction(1, 100000, key1),
+		transaction(2, 100000, key2),
+	}) {
+		if err != nil {
+			t.Fatalf("failed to add remote transaction: %v", err)
+		}
+	}
+	pool.Stop()
+
+	// Include the first transaction of the first account and restart the pool
+	statedb.SetNonce(crypto.PubkeyToAddress(key1.PublicKey), 1)
+	statedb.AddBalance(crypto.PubkeyToAddress(key1.PublicKey), big.NewInt(1000000000))
+	statedb.AddBalance(crypto.PubkeyToAddress(key2.PublicKey), big.NewInt(1000000000))
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	pending, queued := pool.Stats()
+	if pending != 1 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+	if queued != 1 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	if _, err := os.Stat(config.Snapshot); !os.IsNotExist(err) {
+		t.Fatalf("snapshot not removed after restore: %v", err)
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
@@ -1716,3 +1982,28 @@ func benchmarkPoolBatchInsert(b *testing.B, size int) {
 		pool.AddRemotes(batch)
 	}
 }
+
+// Tests that the transactions of the blocks rewound out of the chain are added
+// back to the pool on the new head.
+func TestTransactionReinject(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	account := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(account, big.NewInt(1000000))
+
+	txs := types.Transactions{transaction(0, 100000, key), transaction(1, 100000, key), transaction(2, 100000, key)}
+	for i, err := range pool.Reinject(pool.chain.CurrentBlock().Header(), txs) {
+		if err != nil {
+			t.Errorf("transaction %d: reinjection failed: %v", i, err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 3 || queued != 0 {
+		t.Errorf("pool stats mismatch: have %d pending %d queued, want 3 pending 0 queued", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
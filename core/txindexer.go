@@ -0,0 +1,198 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"runtime"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/log"
+)
+
+// txIndexBatch is the number of blocks indexed or unindexed between two updates
+// of the index tail, bounding the work lost to an interruption.
+const txIndexBatch = 10000
+
+var txIndexTailKey = []byte("TransactionIndexTail") // txIndexTailKey -> oldest block with indexed transactions (uint64 big endian)
+
+// GetTxIndexTail retrieves the number of the oldest block whose transactions
+// are indexed, returning false if the index was never limited, covering the
+// whole chain.
+func GetTxIndexTail(db DatabaseReader) (uint64, bool) {
+	enc, _ := db.Get(txIndexTailKey)
+	if len(enc) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(enc), true
+}
+
+// WriteTxIndexTail stores the number of the oldest block whose transactions are
+// indexed.
+func WriteTxIndexTail(db aquadb.Putter, number uint64) error {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], number)
+	return db.Put(txIndexTailKey, enc[:])
+}
+
+// UnindexTxLookups removes the lookup entries of the transactions in the
+// canonical blocks in the range [from, to).
+func UnindexTxLookups(db aquadb.Database, from, to uint64) error {
+	for number := from; number < to; number++ {
+		hash := GetCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			continue
+		}
+		body := GetBodyNoVersion(db, hash, number)
+		if body == nil {
+			continue
+		}
+		for _, tx := range body.Transactions {
+			if err := db.Delete(append(lookupPrefix, tx.Hash().Bytes()...)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// txIndexTail returns the oldest block whose transactions have to be indexed
+// to keep the lookup entries of the given number of most recent blocks, all of
+// them if the limit is zero.
+func txIndexTail(head, limit uint64) uint64 {
+	if limit == 0 || head < limit {
+		return 0
+	}
+	return head - limit + 1
+}
+
+// maintainTxIndex keeps the transaction lookup entries limited to the most
+// recent blocks, unindexing the blocks falling out of the limit as the chain
+// grows, and backfilling the missing ones if the limit was raised. The index
+// is moved in the background, only ever catching up with the latest head, so
+// the block imports are never held up.
+func (bc *BlockChain) maintainTxIndex(heads chan ChainHeadEvent, sub event.Subscription) {
+	defer bc.wg.Done()
+	defer sub.Unsubscribe()
+
+	var (
+		limit   = bc.cacheConfig.TxLookupLimit
+		done    = make(chan struct{})
+		running bool
+		next    *types.Block // Latest head not indexed for yet
+	)
+	index := func(head uint64) {
+		running = true
+		go func() {
+			bc.indexTxs(head, limit)
+			done <- struct{}{}
+		}()
+	}
+	index(bc.currentBlock.Load().(*types.Block).NumberU64()) // CurrentBlock sets the version, racing the imports
+	for {
+		select {
+		case head := <-heads:
+			if running {
+				next = head.Block
+			} else {
+				index(head.Block.NumberU64())
+			}
+		case <-done:
+			running = false
+			if limit == 0 {
+				return // Fully indexed, kept so by the block imports
+			}
+			if next != nil {
+				index(next.NumberU64())
+				next = nil
+			}
+		case <-sub.Err():
+			if running {
+				<-done
+			}
+			return
+		case <-bc.quit:
+			if running {
+				<-done
+			}
+			return
+		}
+	}
+}
+
+// indexTxs moves the index tail to where the limit requires it for the given
+// head, in batches, until done or interrupted by the chain stopping.
+func (bc *BlockChain) indexTxs(head, limit uint64) {
+	tail, limited := GetTxIndexTail(bc.db)
+	if !limited && limit == 0 {
+		return // Unlimited index, maintained by the block imports
+	}
+	if tail > head+1 {
+		tail = head + 1 // Chain rewound below the tail, nothing above the head to index
+	}
+	var (
+		target = txIndexTail(head, limit)
+		start  = time.Now()
+		logged = start
+		moved  = tail != target
+	)
+	for tail != target {
+		select {
+		case <-bc.quit:
+			return
+		default:
+		}
+		var err error
+		if tail < target {
+			// Unindex the oldest blocks first, they're the least useful
+			next := tail + txIndexBatch
+			if next > target {
+				next = target
+			}
+			if err = UnindexTxLookups(bc.db, tail, next); err == nil {
+				tail = next
+			}
+		} else {
+			// Backfill the newest blocks first, they're the most queried
+			next := target
+			if tail-target > txIndexBatch {
+				next = tail - txIndexBatch
+			}
+			if err = ReindexTxLookups(bc.db, next, tail-1, runtime.NumCPU()); err == nil {
+				tail = next
+			}
+		}
+		if err == nil {
+			err = WriteTxIndexTail(bc.db, tail)
+		}
+		if err != nil {
+			log.Error("Failed to maintain transaction index", "tail", tail, "target", target, "err", err)
+			return
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Maintaining transaction index", "tail", tail, "target", target, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if moved {
+		log.Debug("Moved transaction index tail", "tail", tail, "head", head, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that the transaction lookup entries are limited to the most recent
+// blocks, and backfilled when the limit is raised or lifted.
+func TestTxLookupLimit(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		db, _   = aquadb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, aquahash.NewFaker(), db, 32, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{1}, big.NewInt(1), params.TxGas, nil, nil), signer, key)
+		gen.AddTx(tx)
+	})
+	// check runs a chain with the given limit, waiting for the index to move
+	check := func(limit uint64, tail uint64, limited bool) {
+		t.Helper()
+
+		chain, _ := NewBlockChain(db, &CacheConfig{TrieNodeLimit: 256, TrieTimeLimit: 5 * time.Minute, TxLookupLimit: limit}, gspec.Config, aquahash.NewFaker(), vm.Config{})
+		defer chain.Stop()
+
+		if chain.CurrentBlock().NumberU64() == 0 {
+			if _, err := chain.InsertChain(blocks); err != nil {
+				t.Fatalf("failed to insert chain: %v", err)
+			}
+		}
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			if have, ok := GetTxIndexTail(db); have == tail && ok == limited {
+				break
+			}
+			if time.Now().After(deadline) {
+				have, ok := GetTxIndexTail(db)
+				t.Fatalf("limit %d: tail mismatch: have %d (%v), want %d (%v)", limit, have, ok, tail, limited)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		for _, block := range blocks {
+			for _, tx := range block.Transactions() {
+				hash, _, _ := GetTxLookupEntry(db, tx.Hash())
+				if indexed := hash != (common.Hash{}); indexed != (block.NumberU64() >= tail) {
+					t.Fatalf("limit %d: block #%d: indexed %v, tail %d", limit, block.NumberU64(), indexed, tail)
+				}
+			}
+		}
+	}
+	check(8, 25, true)  // Old entries unindexed after the import
+	check(16, 17, true) // Raised limit backfilled
+	check(4, 29, true)  // Lowered limit unindexed
+	check(0, 0, true)   // Lifted limit backfilled fully
+}
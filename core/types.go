@@ -44,3 +44,12 @@ type Validator interface {
 type Processor interface {
 	Process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, error)
 }
+
+// Prefetcher is an interface for pre-caching the state of a block ahead of its
+// processing.
+//
+// Prefetch executes the block on a throwaway statedb, only to load the state it
+// touches, stopping as soon as interrupt is set.
+type Prefetcher interface {
+	Prefetch(block *types.Block, statedb *state.StateDB, cfg vm.Config, interrupt *uint32)
+}
@@ -87,7 +87,8 @@ type Header struct {
 	Extra       []byte         `json:"extraData"        gencodec:"required"`
 	MixDigest   common.Hash    `json:"mixHash"          gencodec:"required"`
 	Nonce       BlockNonce     `json:"nonce"            gencodec:"required"`
-	Version     HeaderVersion  `json:"version" gencodec:"required" rlp:"-"` // ignored by rlp
+	BaseFee     *big.Int       `json:"baseFeePerGas,omitempty" rlp:"optional"` // nil before the fee market
+	Version     HeaderVersion  `json:"version"          rlp:"-"`               // ignored by rlp
 }
 
 // field type overrides for gencodec
@@ -98,8 +99,9 @@ type headerMarshaling struct {
 	GasUsed    hexutil.Uint64
 	Time       *hexutil.Big
 	Extra      hexutil.Bytes
+	BaseFee    *hexutil.Big
 	Hash       common.Hash `json:"hash"` // adds call to Hash() in MarshalJSON
-	Version    hexutil.Bytes
+	Version    headerVersion
 }
 
 type HeaderVersion = params.HeaderVersion // byte
@@ -110,33 +112,31 @@ const (
 	H_ARGON2ID
 )
 
+// SetVersion sets the header's Version, returning the hash by its codec.
 func (h *Header) SetVersion(version byte) common.Hash {
 	h.Version = HeaderVersion(version)
 	return h.Hash()
 }
 
-// Hash returns the block hash of the header, which is simply the keccak256 hash of its
-// RLP encoding.
+// Hash returns the block hash of the header, which is the hash of its RLP encoding
+// by the algorithm of its version's codec.
 func (h *Header) Hash() common.Hash {
 	if h.Version == H_UNSET { // special cases
 		if h.Number.Uint64() == 0 {
 			h.Version = H_KECCAK256
 		}
 	}
-	switch h.Version {
-	case H_KECCAK256:
-		return rlpHash(h)
-	case H_ARGON2ID:
-		return rlpHashArgon2id(h)
-	default:
+	codec, err := LookupHeaderCodec(h.Version)
+	if err != nil {
 		common.Report(fmt.Sprintf("Number: %v, Version: %v", h.Number, h.Version))
 		return rlpHash(h)
 	}
+	return codec.Hash(h)
 }
 
 // HashNoNonce returns the hash which is used as input for the proof-of-work search.
 func (h *Header) HashNoNonce() common.Hash {
-	return rlpHash([]interface{}{
+	fields := []interface{}{
 		h.ParentHash,
 		h.UncleHash,
 		h.Coinbase,
@@ -150,7 +150,11 @@ func (h *Header) HashNoNonce() common.Hash {
 		h.GasUsed,
 		h.Time,
 		h.Extra,
-	})
+	}
+	if h.BaseFee != nil {
+		fields = append(fields, h.BaseFee)
+	}
+	return rlpHash(fields)
 }
 
 // Size returns the approximate memory used by all internal contents. It is used
@@ -165,6 +169,17 @@ func rlpHash(x interface{}) (h common.Hash) {
 	hw.Sum(h[:0])
 	return h
 }
+
+// prefixedRlpHash hashes the RLP encoding of x prefixed by the given byte, the
+// type of typed transactions.
+func prefixedRlpHash(prefix byte, x interface{}) (h common.Hash) {
+	hw := sha3.NewKeccak256()
+	hw.Write([]byte{prefix})
+	rlp.Encode(hw, x)
+	hw.Sum(h[:0])
+	return h
+}
+
 func rlpHashArgon2id(x interface{}) (h common.Hash) {
 	buf := &bytes.Buffer{}
 	rlp.Encode(buf, x)
@@ -286,6 +301,9 @@ func CopyHeader(h *Header) *Header {
 	if cpy.Number = new(big.Int); h.Number != nil {
 		cpy.Number.Set(h.Number)
 	}
+	if h.BaseFee != nil {
+		cpy.BaseFee = new(big.Int).Set(h.BaseFee)
+	}
 	if len(h.Extra) > 0 {
 		cpy.Extra = make([]byte, len(h.Extra))
 		copy(cpy.Extra, h.Extra)
@@ -340,7 +358,7 @@ func (b *Block) Transaction(hash common.Hash) *Transaction {
 	return nil
 }
 
-//func (b *Block) Version() params.HeaderVersion { return b.header.Version }
+// func (b *Block) Version() params.HeaderVersion { return b.header.Version }
 func (b *Block) Number() *big.Int     { return new(big.Int).Set(b.header.Number) }
 func (b *Block) GasLimit() uint64     { return b.header.GasLimit }
 func (b *Block) GasUsed() uint64      { return b.header.GasUsed }
@@ -359,6 +377,14 @@ func (b *Block) ReceiptHash() common.Hash { return b.header.ReceiptHash }
 func (b *Block) UncleHash() common.Hash   { return b.header.UncleHash }
 func (b *Block) Extra() []byte            { return common.CopyBytes(b.header.Extra) }
 
+// BaseFee returns the base fee of the block, nil before the fee market.
+func (b *Block) BaseFee() *big.Int {
+	if b.header.BaseFee == nil {
+		return nil
+	}
+	return new(big.Int).Set(b.header.BaseFee)
+}
+
 func (b *Block) Header() *Header { return CopyHeader(b.header) }
 
 // Body returns the non-header content of the block.
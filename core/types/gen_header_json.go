@@ -30,6 +30,8 @@ func (h Header) MarshalJSON() ([]byte, error) {
 		Extra       hexutil.Bytes  `json:"extraData"        gencodec:"required"`
 		MixDigest   common.Hash    `json:"mixHash"          gencodec:"required"`
 		Nonce       BlockNonce     `json:"nonce"            gencodec:"required"`
+		BaseFee     *hexutil.Big   `json:"baseFeePerGas,omitempty" rlp:"optional"`
+		Version     headerVersion  `json:"version"          rlp:"-"`
 		Hash        common.Hash    `json:"hash"`
 	}
 	var enc Header
@@ -48,6 +50,8 @@ func (h Header) MarshalJSON() ([]byte, error) {
 	enc.Extra = h.Extra
 	enc.MixDigest = h.MixDigest
 	enc.Nonce = h.Nonce
+	enc.BaseFee = (*hexutil.Big)(h.BaseFee)
+	enc.Version = headerVersion(h.Version)
 	enc.Hash = h.Hash()
 	return json.Marshal(&enc)
 }
@@ -69,6 +73,8 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 		Extra       *hexutil.Bytes  `json:"extraData"        gencodec:"required"`
 		MixDigest   *common.Hash    `json:"mixHash"          gencodec:"required"`
 		Nonce       *BlockNonce     `json:"nonce"            gencodec:"required"`
+		BaseFee     *hexutil.Big    `json:"baseFeePerGas,omitempty" rlp:"optional"`
+		Version     *headerVersion  `json:"version"          rlp:"-"`
 	}
 	var dec Header
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -134,5 +140,11 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'nonce' for Header")
 	}
 	h.Nonce = *dec.Nonce
+	if dec.BaseFee != nil {
+		h.BaseFee = (*big.Int)(dec.BaseFee)
+	}
+	if dec.Version != nil {
+		h.Version = HeaderVersion(*dec.Version)
+	}
 	return nil
 }
@@ -24,6 +24,10 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 		V            *hexutil.Big    `json:"v" gencodec:"required"`
 		R            *hexutil.Big    `json:"r" gencodec:"required"`
 		S            *hexutil.Big    `json:"s" gencodec:"required"`
+		Type         hexutil.Uint64  `json:"type,omitempty"                 rlp:"-"`
+		ChainID      *hexutil.Big    `json:"chainId,omitempty"              rlp:"-"`
+		GasTipCap    *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty" rlp:"-"`
+		GasFeeCap    *hexutil.Big    `json:"maxFeePerGas,omitempty"`
 		Hash         *common.Hash    `json:"hash" rlp:"-"`
 	}
 	var enc txdata
@@ -36,6 +40,12 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 	enc.V = (*hexutil.Big)(t.V)
 	enc.R = (*hexutil.Big)(t.R)
 	enc.S = (*hexutil.Big)(t.S)
+	enc.Type = hexutil.Uint64(t.Type)
+	enc.ChainID = (*hexutil.Big)(t.ChainID)
+	enc.GasTipCap = (*hexutil.Big)(t.GasTipCap)
+	if t.Type != LegacyTxType {
+		enc.GasFeeCap = (*hexutil.Big)(t.Price)
+	}
 	enc.Hash = t.Hash
 	return json.Marshal(&enc)
 }
@@ -51,6 +61,10 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		V            *hexutil.Big    `json:"v" gencodec:"required"`
 		R            *hexutil.Big    `json:"r" gencodec:"required"`
 		S            *hexutil.Big    `json:"s" gencodec:"required"`
+		Type         *hexutil.Uint64 `json:"type,omitempty"                 rlp:"-"`
+		ChainID      *hexutil.Big    `json:"chainId,omitempty"              rlp:"-"`
+		GasTipCap    *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty" rlp:"-"`
+		GasFeeCap    *hexutil.Big    `json:"maxFeePerGas,omitempty"`
 		Hash         *common.Hash    `json:"hash" rlp:"-"`
 	}
 	var dec txdata
@@ -92,6 +106,19 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 's' for txdata")
 	}
 	t.S = (*big.Int)(dec.S)
+	if dec.Type != nil {
+		t.Type = uint8(*dec.Type)
+	}
+	if dec.ChainID != nil {
+		t.ChainID = (*big.Int)(dec.ChainID)
+	}
+	if dec.GasTipCap != nil {
+		t.GasTipCap = (*big.Int)(dec.GasTipCap)
+	}
+	if dec.GasFeeCap != nil && t.Type != LegacyTxType {
+		// The gas price of included typed transactions is the effective one
+		t.Price = (*big.Int)(dec.GasFeeCap)
+	}
 	if dec.Hash != nil {
 		t.Hash = dec.Hash
 	}
@@ -0,0 +1,175 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+var (
+	// ErrUnknownHeaderVersion is returned for a header version without a
+	// registered codec.
+	ErrUnknownHeaderVersion = errors.New("unknown header version")
+
+	// ErrInvalidHeaderVersion is returned for a known header version that the
+	// chain configuration doesn't mandate at the header's block number, such as
+	// a version used before its activation height.
+	ErrInvalidHeaderVersion = errors.New("invalid header version")
+
+	// ErrUnapprovedHeaderVersion is returned for a block of the multi-algorithm
+	// era naming a header version not approved for sealing.
+	ErrUnapprovedHeaderVersion = errors.New("header version not approved")
+)
+
+// HeaderCodec defines how the headers of a version are hashed. The version is
+// not part of the RLP encoding, it is implied by the block number through the
+// chain configuration, so introducing a version only takes registering its
+// codec and scheduling it in params.ChainConfig.GetBlockVersion.
+type HeaderCodec struct {
+	Name string                    // Name of the hash algorithm, for logging
+	Hash func(*Header) common.Hash // Hashes a header of the version
+}
+
+// headerCodecs is the registry of the known header versions. It is only ever
+// written to by init functions, so it's read without locking.
+var headerCodecs = map[HeaderVersion]*HeaderCodec{
+	H_KECCAK256: {Name: "keccak256", Hash: func(h *Header) common.Hash { return rlpHash(h) }},
+	H_ARGON2ID:  {Name: "argon2id", Hash: func(h *Header) common.Hash { return rlpHashArgon2id(h) }},
+}
+
+// RegisterHeaderCodec registers the codec of a new header version. It panics if
+// the version is unset or already registered, and must only be called from init
+// functions.
+func RegisterHeaderCodec(version HeaderVersion, codec *HeaderCodec) {
+	if version == H_UNSET {
+		panic("header codec registered for the unset version")
+	}
+	if codec == nil || codec.Hash == nil {
+		panic(fmt.Sprintf("header codec of version %d without hash function", version))
+	}
+	if _, ok := headerCodecs[version]; ok {
+		panic(fmt.Sprintf("header codec of version %d registered twice", version))
+	}
+	headerCodecs[version] = codec
+}
+
+// LookupHeaderCodec returns the codec of a header version.
+func LookupHeaderCodec(version HeaderVersion) (*HeaderCodec, error) {
+	if codec, ok := headerCodecs[version]; ok {
+		return codec, nil
+	}
+	return nil, ErrUnknownHeaderVersion
+}
+
+// HeaderVersions returns the registered header versions in ascending order.
+func HeaderVersions() []HeaderVersion {
+	versions := make([]HeaderVersion, 0, len(headerCodecs))
+	for version := range headerCodecs {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+// BlockVersion returns the header version a block has to use. In the
+// multi-algorithm era it's chosen by the block, named by the first byte of its
+// extra data, otherwise it's scheduled by the chain configuration.
+func BlockVersion(config *params.ChainConfig, header *Header) HeaderVersion {
+	if !config.IsMultiAlgo(header.Number) {
+		return config.GetBlockVersion(header.Number)
+	}
+	if len(header.Extra) == 0 {
+		return H_UNSET
+	}
+	return HeaderVersion(header.Extra[0])
+}
+
+// VerifyHeaderVersion checks that the version of a header is the one the chain
+// configuration mandates at its block number, rejecting unknown versions and
+// versions used outside of their activation range. Headers arriving from the
+// network have no version, in which case the mandated one is filled in.
+func VerifyHeaderVersion(config *params.ChainConfig, header *Header) error {
+	want := BlockVersion(config, header)
+	if config.IsMultiAlgo(header.Number) && !config.IsApprovedAlgo(want, header.Number) {
+		return ErrUnapprovedHeaderVersion
+	}
+	if _, err := LookupHeaderCodec(want); err != nil {
+		return fmt.Errorf("header version %d scheduled at block %v: %v", want, header.Number, err)
+	}
+	switch header.Version {
+	case want:
+		return nil
+	case H_UNSET:
+		header.Version = want
+		return nil
+	}
+	if _, err := LookupHeaderCodec(header.Version); err != nil {
+		return err
+	}
+	return ErrInvalidHeaderVersion
+}
+
+// headerVersion is the JSON encoding of a header version, a hex quantity which
+// has to be unset or a registered version.
+type headerVersion HeaderVersion
+
+// MarshalText implements encoding.TextMarshaler.
+func (v headerVersion) MarshalText() ([]byte, error) {
+	return hexutil.Uint64(v).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (v *headerVersion) UnmarshalText(input []byte) error {
+	var dec hexutil.Uint64
+	if err := dec.UnmarshalText(input); err != nil {
+		return err
+	}
+	if dec > 0xff {
+		return ErrUnknownHeaderVersion
+	}
+	if version := HeaderVersion(dec); version != H_UNSET {
+		if _, err := LookupHeaderCodec(version); err != nil {
+			return err
+		}
+	}
+	*v = headerVersion(dec)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Plain numbers are accepted besides
+// hex quantities, as the RPC API serves the version of blocks as a number.
+func (v *headerVersion) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] != '"' {
+		var dec uint64
+		if err := json.Unmarshal(input, &dec); err != nil {
+			return err
+		}
+		return v.UnmarshalText([]byte(hexutil.EncodeUint64(dec)))
+	}
+	var text string
+	if err := json.Unmarshal(input, &text); err != nil {
+		return err
+	}
+	return v.UnmarshalText([]byte(text))
+}
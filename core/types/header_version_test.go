@@ -0,0 +1,95 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+)
+
+// Tests that registered header codecs are used for hashing, and that invalid
+// registrations are refused.
+func TestRegisterHeaderCodec(t *testing.T) {
+	const version = HeaderVersion(200)
+	defer delete(headerCodecs, version)
+
+	if _, err := LookupHeaderCodec(version); err != ErrUnknownHeaderVersion {
+		t.Fatalf("unregistered version lookup error mismatch: have %v, want %v", err, ErrUnknownHeaderVersion)
+	}
+	RegisterHeaderCodec(version, &HeaderCodec{Name: "test", Hash: func(*Header) common.Hash { return common.Hash{0xaa} }})
+
+	header := &Header{Number: big.NewInt(1)}
+	if hash := header.SetVersion(byte(version)); hash != (common.Hash{0xaa}) {
+		t.Fatalf("hash mismatch: have %x, want %x", hash, common.Hash{0xaa})
+	}
+	if versions := HeaderVersions(); !reflect.DeepEqual(versions, []HeaderVersion{H_KECCAK256, H_ARGON2ID, version}) {
+		t.Fatalf("registered versions mismatch: have %v", versions)
+	}
+	for _, version := range []HeaderVersion{H_UNSET, H_KECCAK256, version} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("version %d: invalid registration accepted", version)
+				}
+			}()
+			RegisterHeaderCodec(version, &HeaderCodec{Name: "dup", Hash: func(*Header) common.Hash { return common.Hash{} }})
+		}()
+	}
+}
+
+// Tests that header versions survive a JSON round trip, and that unknown ones
+// are rejected.
+func TestHeaderVersionJSON(t *testing.T) {
+	header := &Header{Difficulty: big.NewInt(1), Number: big.NewInt(1), Time: big.NewInt(1), Extra: []byte{}, Version: H_ARGON2ID}
+	enc, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("failed to encode header: %v", err)
+	}
+	var dec Header
+	if err := json.Unmarshal(enc, &dec); err != nil {
+		t.Fatalf("failed to decode header: %v", err)
+	}
+	if dec.Version != H_ARGON2ID || dec.Hash() != header.Hash() {
+		t.Fatalf("decoded header mismatch: version %d, hash %x, want %x", dec.Version, dec.Hash(), header.Hash())
+	}
+	var fields map[string]interface{}
+	json.Unmarshal(enc, &fields)
+	for _, version := range []interface{}{"0x7", "0x100", 7, 256} {
+		fields["version"] = version
+		enc, _ := json.Marshal(fields)
+		if err := json.Unmarshal(enc, new(Header)); err == nil {
+			t.Errorf("version %v: unknown version accepted", version)
+		}
+	}
+	// Plain numbers, as served by the RPC API, are accepted too
+	fields["version"] = int(H_ARGON2ID)
+	enc, _ = json.Marshal(fields)
+	var numeric Header
+	if err := json.Unmarshal(enc, &numeric); err != nil || numeric.Version != H_ARGON2ID {
+		t.Fatalf("numeric version mismatch: have %d, err %v, want %d", numeric.Version, err, H_ARGON2ID)
+	}
+	// Headers without version are still accepted
+	delete(fields, "version")
+	enc, _ = json.Marshal(fields)
+	if err := json.Unmarshal(enc, &dec); err != nil {
+		t.Fatalf("failed to decode header without version: %v", err)
+	}
+}
@@ -35,6 +35,20 @@ import (
 var (
 	ErrInvalidSig = errors.New("invalid transaction v, r, s values")
 	errNoSigner   = errors.New("missing signing methods")
+
+	// ErrTxTypeNotSupported is returned if a transaction isn't of a known type
+	// or its type isn't supported yet, e.g. by the signer of the chain.
+	ErrTxTypeNotSupported = errors.New("transaction type not supported")
+
+	errEmptyTypedTx          = errors.New("empty typed transaction bytes")
+	errAccessListUnsupported = errors.New("transaction access lists not supported")
+)
+
+// Transaction types, the first byte of the typed transaction envelope. Legacy
+// transactions are encoded as an RLP list, without type byte.
+const (
+	LegacyTxType     = 0x00
+	DynamicFeeTxType = 0x02 // Paying the base fee and a tip to the miner, see params.FeeMarketConfig
 )
 
 // deriveSigner makes a *best* guess about which signer to use.
@@ -67,10 +81,32 @@ type txdata struct {
 	R *big.Int `json:"r" gencodec:"required"`
 	S *big.Int `json:"s" gencodec:"required"`
 
+	// Typed transactions only, encoded in their envelope. The price of dynamic
+	// fee transactions is their fee cap and their V the parity of the signature.
+	Type      uint8    `json:"type,omitempty"                 rlp:"-"`
+	ChainID   *big.Int `json:"chainId,omitempty"              rlp:"-"`
+	GasTipCap *big.Int `json:"maxPriorityFeePerGas,omitempty" rlp:"-"`
+
 	// This is only used when marshaling to JSON.
 	Hash *common.Hash `json:"hash" rlp:"-"`
 }
 
+// dynamicFeeTx is the payload of the envelope of dynamic fee transactions. It
+// has room for an access list, to keep the encoding of other implementations,
+// but access lists aren't supported and must be empty.
+type dynamicFeeTx struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         *common.Address `rlp:"nil"`
+	Value      *big.Int
+	Data       []byte
+	AccessList []rlp.RawValue
+	V, R, S    *big.Int
+}
+
 type txdataMarshaling struct {
 	AccountNonce hexutil.Uint64
 	Price        *hexutil.Big
@@ -80,6 +116,9 @@ type txdataMarshaling struct {
 	V            *hexutil.Big
 	R            *hexutil.Big
 	S            *hexutil.Big
+	Type         hexutil.Uint64
+	ChainID      *hexutil.Big
+	GasTipCap    *hexutil.Big
 }
 
 func NewTransaction(nonce uint64, to common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
@@ -115,16 +154,44 @@ func newTransaction(nonce uint64, to *common.Address, amount *big.Int, gasLimit
 	return &Transaction{data: d}
 }
 
+// NewDynamicFeeTransaction creates an unsigned dynamic fee transaction for the
+// given chain, paying the base fee of its block and a tip of up to tipCap per
+// gas to the miner, as long as the two stay under feeCap. A nil recipient means
+// contract creation.
+func NewDynamicFeeTransaction(chainID *big.Int, nonce uint64, to *common.Address, amount *big.Int, gasLimit uint64, tipCap, feeCap *big.Int, data []byte) *Transaction {
+	tx := newTransaction(nonce, to, amount, gasLimit, feeCap, data)
+	tx.data.Type = DynamicFeeTxType
+	tx.data.ChainID = new(big.Int)
+	if chainID != nil {
+		tx.data.ChainID.Set(chainID)
+	}
+	tx.data.GasTipCap = new(big.Int)
+	if tipCap != nil {
+		tx.data.GasTipCap.Set(tipCap)
+	}
+	return tx
+}
+
 // ChainId returns which chain id this transaction was signed for (if at all)
 func (tx *Transaction) ChainId() *big.Int {
+	if tx.data.Type != LegacyTxType {
+		return new(big.Int).Set(tx.data.ChainID)
+	}
 	return deriveChainId(tx.data.V)
 }
 
 // Protected returns whether the transaction is protected from replay protection.
+// Typed transactions always are, naming their chain.
 func (tx *Transaction) Protected() bool {
+	if tx.data.Type != LegacyTxType {
+		return true
+	}
 	return isProtectedV(tx.data.V)
 }
 
+// Type returns the type of the transaction, LegacyTxType for untyped ones.
+func (tx *Transaction) Type() uint8 { return tx.data.Type }
+
 func isProtectedV(V *big.Int) bool {
 	if V.BitLen() <= 8 {
 		v := V.Uint64()
@@ -134,20 +201,121 @@ func isProtectedV(V *big.Int) bool {
 	return true
 }
 
-// EncodeRLP implements rlp.Encoder
+// EncodeRLP implements rlp.Encoder. Legacy transactions are encoded as an RLP
+// list, typed ones as an RLP string holding their envelope.
 func (tx *Transaction) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &tx.data)
+	if tx.data.Type == LegacyTxType {
+		return rlp.Encode(w, &tx.data)
+	}
+	enc, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, enc)
 }
 
 // DecodeRLP implements rlp.Decoder
 func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
-	_, size, _ := s.Kind()
-	err := s.Decode(&tx.data)
-	if err == nil {
+	kind, size, err := s.Kind()
+	switch {
+	case err != nil:
+		return err
+	case kind == rlp.List:
+		var data txdata
+		if err := s.Decode(&data); err != nil {
+			return err
+		}
+		tx.data = data
 		tx.size.Store(common.StorageSize(rlp.ListSize(size)))
+		return nil
+	default:
+		b, err := s.Bytes()
+		if err != nil {
+			return err
+		}
+		return tx.UnmarshalBinary(b)
 	}
+}
 
-	return err
+// MarshalBinary returns the canonical encoding of the transaction: the RLP list
+// of legacy transactions and the envelope, the type byte followed by the RLP
+// payload, of typed ones. It's the encoding hashed, signed over RPC and
+// included in the transaction trie.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	switch tx.data.Type {
+	case LegacyTxType:
+		return rlp.EncodeToBytes(&tx.data)
+	case DynamicFeeTxType:
+		payload, err := rlp.EncodeToBytes(tx.dynamicFeeTx())
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{tx.data.Type}, payload...), nil
+	default:
+		return nil, ErrTxTypeNotSupported
+	}
+}
+
+// UnmarshalBinary decodes the canonical encoding of a transaction, see
+// MarshalBinary.
+func (tx *Transaction) UnmarshalBinary(b []byte) error {
+	if len(b) > 0 && b[0] > 0x7f {
+		// An RLP list, a legacy transaction
+		var data txdata
+		if err := rlp.DecodeBytes(b, &data); err != nil {
+			return err
+		}
+		tx.data = data
+		tx.size.Store(common.StorageSize(len(b)))
+		return nil
+	}
+	if len(b) == 0 {
+		return errEmptyTypedTx
+	}
+	if b[0] != DynamicFeeTxType {
+		return ErrTxTypeNotSupported
+	}
+	var inner dynamicFeeTx
+	if err := rlp.DecodeBytes(b[1:], &inner); err != nil {
+		return err
+	}
+	if len(inner.AccessList) > 0 {
+		return errAccessListUnsupported
+	}
+	tx.data = txdata{
+		AccountNonce: inner.Nonce,
+		Price:        inner.GasFeeCap,
+		GasLimit:     inner.Gas,
+		Recipient:    inner.To,
+		Amount:       inner.Value,
+		Payload:      inner.Data,
+		V:            inner.V,
+		R:            inner.R,
+		S:            inner.S,
+		Type:         DynamicFeeTxType,
+		ChainID:      inner.ChainID,
+		GasTipCap:    inner.GasTipCap,
+	}
+	tx.size.Store(common.StorageSize(len(b)))
+	return nil
+}
+
+// dynamicFeeTx returns the envelope payload of a dynamic fee transaction.
+func (tx *Transaction) dynamicFeeTx() *dynamicFeeTx {
+	return &dynamicFeeTx{
+		ChainID:    tx.data.ChainID,
+		Nonce:      tx.data.AccountNonce,
+		GasTipCap:  tx.data.GasTipCap,
+		GasFeeCap:  tx.data.Price,
+		Gas:        tx.data.GasLimit,
+		To:         tx.data.Recipient,
+		Value:      tx.data.Amount,
+		Data:       tx.data.Payload,
+		AccessList: []rlp.RawValue{},
+		V:          tx.data.V,
+		R:          tx.data.R,
+		S:          tx.data.S,
+	}
 }
 
 // MarshalJSON encodes the web3 RPC transaction format.
@@ -165,10 +333,18 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 		return err
 	}
 	var V byte
-	if isProtectedV(dec.V) {
+	switch {
+	case dec.Type == DynamicFeeTxType:
+		if dec.ChainID == nil || dec.GasTipCap == nil {
+			return errors.New("missing chainId or maxPriorityFeePerGas for dynamic fee transaction")
+		}
+		V = byte(dec.V.Uint64())
+	case dec.Type != LegacyTxType:
+		return ErrTxTypeNotSupported
+	case isProtectedV(dec.V):
 		chainID := deriveChainId(dec.V).Uint64()
 		V = byte(dec.V.Uint64() - 35 - 2*chainID)
-	} else {
+	default:
 		V = byte(dec.V.Uint64() - 27)
 	}
 	if !crypto.ValidateSignatureValues(V, dec.R, dec.S, false) {
@@ -185,6 +361,33 @@ func (tx *Transaction) Value() *big.Int    { return new(big.Int).Set(tx.data.Amo
 func (tx *Transaction) Nonce() uint64      { return tx.data.AccountNonce }
 func (tx *Transaction) CheckNonce() bool   { return true }
 
+// GasFeeCap returns the maximum price per gas the transaction pays, base fee
+// and tip included. It's the gas price of legacy transactions.
+func (tx *Transaction) GasFeeCap() *big.Int { return new(big.Int).Set(tx.data.Price) }
+
+// GasTipCap returns the maximum tip per gas the transaction pays to the miner
+// on top of the base fee. It's the gas price of legacy transactions.
+func (tx *Transaction) GasTipCap() *big.Int {
+	if tx.data.Type == LegacyTxType {
+		return new(big.Int).Set(tx.data.Price)
+	}
+	return new(big.Int).Set(tx.data.GasTipCap)
+}
+
+// EffectiveGasPrice returns the price per gas the transaction pays in a block
+// with the given base fee: the base fee and its tip, capped by its fee cap. It's
+// the gas price of the transaction if baseFee is nil, before the fee market.
+func (tx *Transaction) EffectiveGasPrice(baseFee *big.Int) *big.Int {
+	if baseFee == nil {
+		return tx.GasPrice()
+	}
+	price := new(big.Int).Add(baseFee, tx.GasTipCap())
+	if price.Cmp(tx.data.Price) > 0 {
+		price.Set(tx.data.Price)
+	}
+	return price
+}
+
 // To returns the recipient address of the transaction.
 // It returns nil if the transaction is a contract creation.
 func (tx *Transaction) To() *common.Address {
@@ -195,25 +398,35 @@ func (tx *Transaction) To() *common.Address {
 	return &to
 }
 
-// Hash hashes the RLP encoding of tx.
+// Hash hashes the canonical encoding of tx, see MarshalBinary.
 // It uniquely identifies the transaction.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
 		return hash.(common.Hash)
 	}
-	v := rlpHash(tx)
+	var v common.Hash
+	if tx.data.Type == LegacyTxType {
+		v = rlpHash(tx)
+	} else {
+		v = prefixedRlpHash(tx.data.Type, tx.dynamicFeeTx())
+	}
 	tx.hash.Store(v)
 	return v
 }
 
-// Size returns the true RLP encoded storage size of the transaction, either by
+// Size returns the true encoded storage size of the transaction, either by
 // encoding and returning it, or returning a previsouly cached value.
 func (tx *Transaction) Size() common.StorageSize {
 	if size := tx.size.Load(); size != nil {
 		return size.(common.StorageSize)
 	}
 	c := writeCounter(0)
-	rlp.Encode(&c, &tx.data)
+	if tx.data.Type == LegacyTxType {
+		rlp.Encode(&c, &tx.data)
+	} else {
+		c.Write([]byte{tx.data.Type})
+		rlp.Encode(&c, tx.dynamicFeeTx())
+	}
 	tx.size.Store(common.StorageSize(c))
 	return common.StorageSize(c)
 }
@@ -228,6 +441,8 @@ func (tx *Transaction) AsMessage(s Signer) (Message, error) {
 		nonce:      tx.data.AccountNonce,
 		gasLimit:   tx.data.GasLimit,
 		gasPrice:   new(big.Int).Set(tx.data.Price),
+		gasFeeCap:  new(big.Int).Set(tx.data.Price),
+		gasTipCap:  tx.GasTipCap(),
 		to:         tx.data.Recipient,
 		amount:     tx.data.Amount,
 		data:       tx.data.Payload,
@@ -251,7 +466,8 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	return cpy, nil
 }
 
-// Cost returns amount + gasprice * gaslimit.
+// Cost returns amount + gasprice * gaslimit, the fee cap standing for the gas
+// price of dynamic fee transactions.
 func (tx *Transaction) Cost() *big.Int {
 	total := new(big.Int).Mul(tx.data.Price, new(big.Int).SetUint64(tx.data.GasLimit))
 	total.Add(total, tx.data.Amount)
@@ -268,6 +484,9 @@ func (tx *Transaction) String() string {
 		// make a best guess about the signer and use that to derive
 		// the sender.
 		signer := deriveSigner(tx.data.V)
+		if tx.data.Type != LegacyTxType {
+			signer = NewFeeMarketSigner(tx.data.ChainID)
+		}
 		if f, err := Sender(signer, tx); err != nil { // derive but don't cache
 			from = "[invalid sender: invalid sig]"
 		} else {
@@ -282,7 +501,7 @@ func (tx *Transaction) String() string {
 	} else {
 		to = fmt.Sprintf("%x", tx.data.Recipient[:])
 	}
-	enc, _ := rlp.EncodeToBytes(&tx.data)
+	enc, _ := tx.MarshalBinary()
 	return fmt.Sprintf(`
 	TX(%x)
 	Contract: %v
@@ -323,9 +542,10 @@ func (s Transactions) Len() int { return len(s) }
 // Swap swaps the i'th and the j'th element in s.
 func (s Transactions) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
-// GetRlp implements Rlpable and returns the i'th element of s in rlp.
+// GetRlp implements Rlpable and returns the canonical encoding of the i'th
+// element of s, the envelope of typed transactions.
 func (s Transactions) GetRlp(i int) []byte {
-	enc, _ := rlp.EncodeToBytes(s[i])
+	enc, _ := s[i].MarshalBinary()
 	return enc
 }
 
@@ -445,6 +665,8 @@ type Message struct {
 	amount     *big.Int
 	gasLimit   uint64
 	gasPrice   *big.Int
+	gasFeeCap  *big.Int
+	gasTipCap  *big.Int
 	data       []byte
 	checkNonce bool
 }
@@ -457,6 +679,8 @@ func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *b
 		amount:     amount,
 		gasLimit:   gasLimit,
 		gasPrice:   gasPrice,
+		gasFeeCap:  gasPrice,
+		gasTipCap:  gasPrice,
 		data:       data,
 		checkNonce: checkNonce,
 	}
@@ -465,6 +689,8 @@ func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *b
 func (m Message) From() common.Address { return m.from }
 func (m Message) To() *common.Address  { return m.to }
 func (m Message) GasPrice() *big.Int   { return m.gasPrice }
+func (m Message) GasFeeCap() *big.Int  { return m.gasFeeCap }
+func (m Message) GasTipCap() *big.Int  { return m.gasTipCap }
 func (m Message) Value() *big.Int      { return m.amount }
 func (m Message) Gas() uint64          { return m.gasLimit }
 func (m Message) Nonce() uint64        { return m.nonce }
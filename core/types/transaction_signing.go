@@ -25,6 +25,7 @@ import (
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/crypto"
 	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/rlp"
 )
 
 var (
@@ -42,6 +43,8 @@ type sigCache struct {
 func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 	var signer Signer
 	switch {
+	case config.IsFeeMarket(blockNumber):
+		signer = NewFeeMarketSigner(config.ChainId)
 	case config.IsEIP155(blockNumber):
 		signer = NewEIP155Signer(config.ChainId)
 	case config.IsHomestead(blockNumber):
@@ -102,6 +105,74 @@ type Signer interface {
 	Equal(Signer) bool
 }
 
+// FeeMarketSigner implements Signer for the fee market: it signs legacy
+// transactions by the EIP155 rules and dynamic fee transactions over their
+// typed envelope, with V the parity of the signature.
+type FeeMarketSigner struct{ EIP155Signer }
+
+func NewFeeMarketSigner(chainId *big.Int) FeeMarketSigner {
+	return FeeMarketSigner{NewEIP155Signer(chainId)}
+}
+
+func (s FeeMarketSigner) Equal(s2 Signer) bool {
+	fm, ok := s2.(FeeMarketSigner)
+	return ok && fm.chainId.Cmp(s.chainId) == 0
+}
+
+func (s FeeMarketSigner) Sender(tx *Transaction) (common.Address, error) {
+	switch tx.Type() {
+	case LegacyTxType:
+		return s.EIP155Signer.Sender(tx)
+	case DynamicFeeTxType:
+		if tx.data.ChainID.Cmp(s.chainId) != 0 {
+			return common.Address{}, ErrInvalidChainId
+		}
+		V := new(big.Int).Add(tx.data.V, big.NewInt(27))
+		return recoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
+	default:
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+}
+
+// SignatureValues returns signature values. This signature
+// needs to be in the [R || S || V] format where V is 0 or 1.
+func (s FeeMarketSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	switch tx.Type() {
+	case LegacyTxType:
+		return s.EIP155Signer.SignatureValues(tx, sig)
+	case DynamicFeeTxType:
+		if tx.data.ChainID.Sign() != 0 && tx.data.ChainID.Cmp(s.chainId) != 0 {
+			return nil, nil, nil, ErrInvalidChainId
+		}
+		R, S, _, err = HomesteadSigner{}.SignatureValues(tx, sig)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return R, S, big.NewInt(int64(sig[64])), nil
+	default:
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
+}
+
+// Hash returns the hash to be signed by the sender.
+// It does not uniquely identify the transaction.
+func (s FeeMarketSigner) Hash(tx *Transaction) common.Hash {
+	if tx.Type() == LegacyTxType {
+		return s.EIP155Signer.Hash(tx)
+	}
+	return prefixedRlpHash(tx.Type(), []interface{}{
+		s.chainId,
+		tx.data.AccountNonce,
+		tx.data.GasTipCap,
+		tx.data.Price,
+		tx.data.GasLimit,
+		tx.data.Recipient,
+		tx.data.Amount,
+		tx.data.Payload,
+		[]rlp.RawValue{}, // empty access list
+	})
+}
+
 // EIP155Transaction implements Signer using the EIP155 rules.
 type EIP155Signer struct {
 	chainId, chainIdMul *big.Int
@@ -125,6 +196,9 @@ func (s EIP155Signer) Equal(s2 Signer) bool {
 var big8 = big.NewInt(8)
 
 func (s EIP155Signer) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	if !tx.Protected() {
 		return HomesteadSigner{}.Sender(tx)
 	}
@@ -180,6 +254,9 @@ func (hs HomesteadSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v
 }
 
 func (hs HomesteadSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	return recoverPlain(hs.Hash(tx), tx.data.R, tx.data.S, tx.data.V, true)
 }
 
@@ -216,6 +293,9 @@ func (fs FrontierSigner) Hash(tx *Transaction) common.Hash {
 }
 
 func (fs FrontierSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	return recoverPlain(fs.Hash(tx), tx.data.R, tx.data.S, tx.data.V, false)
 }
 
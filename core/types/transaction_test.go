@@ -233,3 +233,92 @@ func TestTransactionJSON(t *testing.T) {
 		}
 	}
 }
+
+// Tests that dynamic fee transactions are signed over their envelope and survive
+// the binary, RLP and JSON encodings, while legacy signers refuse them.
+func TestDynamicFeeTransaction(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	signer := NewFeeMarketSigner(big.NewInt(61717561))
+
+	tx := NewDynamicFeeTransaction(big.NewInt(61717561), 3, &common.Address{1}, big.NewInt(10), 21000, big.NewInt(2), big.NewInt(100), []byte("abcdef"))
+	tx, err := SignTx(tx, signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	if from, err := Sender(signer, tx); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	if _, err := Sender(NewEIP155Signer(big.NewInt(61717561)), tx); err != ErrTxTypeNotSupported {
+		t.Errorf("legacy signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	if _, err := Sender(NewFeeMarketSigner(big.NewInt(1)), tx); err != ErrInvalidChainId {
+		t.Errorf("foreign chain error mismatch: have %v, want %v", err, ErrInvalidChainId)
+	}
+	// The envelope is the type byte followed by the payload, and is what's hashed
+	enc, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("could not encode transaction: %v", err)
+	}
+	if enc[0] != DynamicFeeTxType {
+		t.Errorf("envelope type mismatch: have %#x, want %#x", enc[0], DynamicFeeTxType)
+	}
+	if hash := crypto.Keccak256Hash(enc); tx.Hash() != hash {
+		t.Errorf("hash mismatch: have %x, want %x", tx.Hash(), hash)
+	}
+	if int(tx.Size()) != len(enc) {
+		t.Errorf("size mismatch: have %d, want %d", int(tx.Size()), len(enc))
+	}
+	decoded := new(Transaction)
+	if err := decoded.UnmarshalBinary(enc); err != nil {
+		t.Fatalf("could not decode envelope: %v", err)
+	}
+	if decoded.Hash() != tx.Hash() || decoded.GasTipCap().Cmp(big.NewInt(2)) != 0 || decoded.GasFeeCap().Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("decoded envelope mismatch: have %v, want %v", decoded, tx)
+	}
+	// Within lists, e.g. block bodies, alongside legacy transactions
+	legacy, _ := SignTx(NewTransaction(0, common.Address{1}, common.Big0, 21000, common.Big1, nil), signer, key)
+	blob, err := rlp.EncodeToBytes(Transactions{legacy, tx})
+	if err != nil {
+		t.Fatalf("could not encode transactions: %v", err)
+	}
+	var txs Transactions
+	if err := rlp.DecodeBytes(blob, &txs); err != nil {
+		t.Fatalf("could not decode transactions: %v", err)
+	}
+	if len(txs) != 2 || txs[0].Hash() != legacy.Hash() || txs[1].Hash() != tx.Hash() {
+		t.Errorf("decoded transactions mismatch: have %v", txs)
+	}
+	// And over JSON
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	var parsed *Transaction
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if parsed.Hash() != tx.Hash() {
+		t.Errorf("parsed tx differs from original tx, want %v, got %v", tx, parsed)
+	}
+	// The effective price is capped by the fee cap
+	for _, tt := range []struct{ baseFee, want int64 }{{50, 52}, {99, 100}} {
+		if have := tx.EffectiveGasPrice(big.NewInt(tt.baseFee)); have.Int64() != tt.want {
+			t.Errorf("effective price at base fee %d mismatch: have %v, want %d", tt.baseFee, have, tt.want)
+		}
+	}
+}
+
+// Tests that typed transactions with an access list or of an unknown type are refused.
+func TestTypedTransactionUnsupported(t *testing.T) {
+	if err := new(Transaction).UnmarshalBinary([]byte{0x01, 0xc0}); err != ErrTxTypeNotSupported {
+		t.Errorf("unknown type error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	tx := NewDynamicFeeTransaction(common.Big1, 0, nil, common.Big0, 21000, common.Big1, common.Big2, nil)
+	inner := tx.dynamicFeeTx()
+	inner.AccessList = []rlp.RawValue{{0xc0}}
+	payload, _ := rlp.EncodeToBytes(inner)
+	if err := new(Transaction).UnmarshalBinary(append([]byte{DynamicFeeTxType}, payload...)); err != errAccessListUnsupported {
+		t.Errorf("access list error mismatch: have %v, want %v", err, errAccessListUnsupported)
+	}
+}
@@ -20,8 +20,18 @@ import (
 	"math/big"
 
 	"github.com/aquanetwork/aquachain/common"
+	lru "github.com/hashicorp/golang-lru"
 )
 
+// analysisCacheSize is the number of contracts whose JUMPDEST analysis is kept
+// across calls and transactions.
+const analysisCacheSize = 4096
+
+// analysisCache holds the JUMPDEST analysis of the recently run contracts, keyed
+// by code hash and shared by all interpreters. An analysis is never modified
+// once done, so it's safe to share.
+var analysisCache, _ = lru.New(analysisCacheSize)
+
 // destinations stores one map per contract (keyed by hash of code).
 // The maps contain an entry for each location of a JUMPDEST
 // instruction.
@@ -38,12 +48,26 @@ func (d destinations) has(codehash common.Hash, code []byte, dest *big.Int) bool
 
 	m, analysed := d[codehash]
 	if !analysed {
-		m = codeBitmap(code)
+		m = analyse(codehash, code)
 		d[codehash] = m
 	}
 	return OpCode(code[udest]) == JUMPDEST && m.codeSegment(udest)
 }
 
+// analyse returns the JUMPDEST analysis of code, from the shared cache if done
+// already. Code without a hash isn't cached, as it can't be told apart.
+func analyse(codehash common.Hash, code []byte) bitvec {
+	if codehash == (common.Hash{}) {
+		return codeBitmap(code)
+	}
+	if m, ok := analysisCache.Get(codehash); ok {
+		return m.(bitvec)
+	}
+	m := codeBitmap(code)
+	analysisCache.Add(codehash, m)
+	return m
+}
+
 // bitvec is a bit vector which maps bytes in a program.
 // An unset bit means the byte is an opcode, a set bit means
 // it's data (i.e. argument of PUSHxx).
@@ -16,7 +16,13 @@
 
 package vm
 
-import "testing"
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/crypto"
+)
 
 func TestJumpDestAnalysis(t *testing.T) {
 	tests := []struct {
@@ -51,3 +57,31 @@ func TestJumpDestAnalysis(t *testing.T) {
 	}
 
 }
+
+// Tests that the JUMPDEST analysis is shared across contracts of the same code,
+// unless the code hash is unknown.
+func TestJumpDestCache(t *testing.T) {
+	code := []byte{byte(PUSH1), byte(JUMPDEST), byte(JUMPDEST)}
+	hash := crypto.Keccak256Hash(code)
+
+	if !make(destinations).has(hash, code, big.NewInt(2)) {
+		t.Fatalf("valid jump destination rejected")
+	}
+	if !analysisCache.Contains(hash) {
+		t.Fatalf("analysis not cached")
+	}
+	cached, _ := analysisCache.Get(hash)
+	if have := analyse(hash, code); &have[0] != &cached.(bitvec)[0] {
+		t.Errorf("analysis redone for cached code")
+	}
+	if make(destinations).has(hash, code, big.NewInt(1)) {
+		t.Errorf("push data accepted as jump destination")
+	}
+	before := analysisCache.Len()
+	if !make(destinations).has(common.Hash{}, code, big.NewInt(2)) {
+		t.Fatalf("valid jump destination rejected without code hash")
+	}
+	if analysisCache.Len() != before || analysisCache.Contains(common.Hash{}) {
+		t.Errorf("analysis of code without hash cached")
+	}
+}
@@ -22,6 +22,7 @@ var (
 	ErrOutOfGas                 = errors.New("out of fuel")
 	ErrCodeStoreOutOfGas        = errors.New("contract creation code storage out of fuel")
 	ErrDepth                    = errors.New("max call depth exceeded")
+	ErrMemoryLimit              = errors.New("max execution memory exceeded")
 	ErrTraceLimitReached        = errors.New("the number of logs reached the specified limit")
 	ErrInsufficientBalance      = errors.New("insufficient balance for transfer")
 	ErrContractAddressCollision = errors.New("contract address collision")
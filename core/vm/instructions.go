@@ -772,7 +772,7 @@ func opStaticCall(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stac
 
 func opReturn(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	offset, size := stack.pop(), stack.pop()
-	ret := memory.GetPtr(offset.Int64(), size.Int64())
+	ret := memory.Get(offset.Int64(), size.Int64()) // Copied, the memory is recycled
 
 	evm.interpreter.intPool.put(offset, size)
 	return ret, nil
@@ -780,7 +780,7 @@ func opReturn(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *S
 
 func opRevert(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	offset, size := stack.pop(), stack.pop()
-	ret := memory.GetPtr(offset.Int64(), size.Int64())
+	ret := memory.Get(offset.Int64(), size.Int64()) // Copied, the memory is recycled
 
 	evm.interpreter.intPool.put(offset, size)
 	return ret, nil
@@ -37,6 +37,12 @@ type Config struct {
 	DisableGasMetering bool
 	// Enable recording of SHA3/keccak preimages
 	EnablePreimageRecording bool
+	// MaxCallDepth lowers the call depth limit below the protocol one.
+	// Only meant for off-chain executions, zero means the protocol limit.
+	MaxCallDepth int
+	// MaxMemory limits the memory in bytes a single call frame may use.
+	// Only meant for off-chain executions, zero means unlimited.
+	MaxMemory uint64
 	// JumpTable contains the EVM instruction table. This
 	// may be left uninitialised and will be set to the default
 	// table.
@@ -123,8 +129,8 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 
 	var (
 		op    OpCode        // current opcode
-		mem   = NewMemory() // bound memory
-		stack = newstack()  // local stack
+		mem   = getMemory() // bound memory
+		stack = getStack()  // local stack
 		// For optimisation reason we're using uint64 as the program counter.
 		// It's theoretically possible to go above 2^64. The YP defines the PC
 		// to be uint256. Practically much less so feasible.
@@ -135,6 +141,12 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 		gasCopy uint64 // for Tracer to log gas remaining before execution
 		logged  bool   // deferred Tracer should ignore already logged steps
 	)
+	// Recycle the memory and stack once the call returned, after the tracer is
+	// done with them. Nothing may reference them past this call.
+	defer func() {
+		returnStack(stack)
+		returnMemory(mem)
+	}()
 	contract.Input = input
 
 	if in.cfg.Debug {
@@ -196,6 +208,9 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 				return nil, ErrOutOfGas
 			}
 		}
+		if in.cfg.MaxMemory > 0 && memorySize > in.cfg.MaxMemory {
+			return nil, ErrMemoryLimit
+		}
 		if memorySize > 0 {
 			mem.Resize(memorySize)
 		}
@@ -16,7 +16,20 @@
 
 package vm
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
+
+// maxPooledMemory is the size above which memories aren't recycled, so that a
+// single memory hungry call doesn't pin its buffer for good.
+const maxPooledMemory = 1024 * 1024
+
+// memoryPool recycles the memories of returned calls, sparing the growth of
+// their store on every call.
+var memoryPool = sync.Pool{
+	New: func() interface{} { return NewMemory() },
+}
 
 // Memory implements a simple memory model for the aquachain virtual machine.
 type Memory struct {
@@ -28,6 +41,21 @@ func NewMemory() *Memory {
 	return &Memory{}
 }
 
+// getMemory returns an empty memory from the pool.
+func getMemory() *Memory {
+	return memoryPool.Get().(*Memory)
+}
+
+// returnMemory empties a memory no longer referenced and puts it back in the
+// pool. The store is zeroed on resize, so the old contents are never exposed.
+func returnMemory(m *Memory) {
+	if cap(m.store) > maxPooledMemory {
+		return
+	}
+	m.store, m.lastGasCost = m.store[:0], 0
+	memoryPool.Put(m)
+}
+
 // Set sets offset + size to value
 func (m *Memory) Set(offset, size uint64, value []byte) {
 	// length of store may never be less than offset + size.
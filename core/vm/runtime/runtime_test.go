@@ -117,6 +117,84 @@ func TestCall(t *testing.T) {
 	}
 }
 
+func TestMaxMemory(t *testing.T) {
+	// Store a word one megabyte into memory
+	code := []byte{
+		byte(vm.PUSH1), 1,
+		byte(vm.PUSH3), 0x10, 0x00, 0x00,
+		byte(vm.MSTORE),
+	}
+	if _, _, err := Execute(code, nil, &Config{EVMConfig: vm.Config{MaxMemory: 64 * 1024}}); err != vm.ErrMemoryLimit {
+		t.Fatalf("error mismatch: have %v, want %v", err, vm.ErrMemoryLimit)
+	}
+	if _, _, err := Execute(code, nil, &Config{EVMConfig: vm.Config{MaxMemory: 2 * 1024 * 1024}}); err != nil {
+		t.Fatalf("failed to execute within memory limit: %v", err)
+	}
+}
+
+// Tests that the memory recycled across calls starts zeroed, and doesn't alter
+// the data returned by earlier calls.
+func TestMemoryRecycling(t *testing.T) {
+	store := []byte{
+		byte(vm.PUSH1), 0x2a,
+		byte(vm.PUSH1), 0,
+		byte(vm.MSTORE),
+		byte(vm.PUSH1), 32,
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURN),
+	}
+	load := []byte{
+		byte(vm.PUSH1), 32,
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURN),
+	}
+	stored, _, err := Execute(store, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to store: %v", err)
+	}
+	for i := 0; i < 16; i++ {
+		loaded, _, err := Execute(load, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if num := new(big.Int).SetBytes(loaded); num.Sign() != 0 {
+			t.Fatalf("recycled memory not zeroed: have %v", num)
+		}
+	}
+	if num := new(big.Int).SetBytes(stored); num.Cmp(big.NewInt(0x2a)) != 0 {
+		t.Fatalf("returned data altered: have %v, want 42", num)
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	// Increment a counter in slot 0, then call ourselves with all gas
+	code := []byte{
+		byte(vm.PUSH1), 0,
+		byte(vm.SLOAD),
+		byte(vm.PUSH1), 1,
+		byte(vm.ADD),
+		byte(vm.PUSH1), 0,
+		byte(vm.SSTORE),
+		byte(vm.PUSH1), 0,
+		byte(vm.DUP1),
+		byte(vm.DUP1),
+		byte(vm.DUP1),
+		byte(vm.DUP1),
+		byte(vm.ADDRESS),
+		byte(vm.GAS),
+		byte(vm.CALL),
+	}
+	_, state, err := Execute(code, nil, &Config{EVMConfig: vm.Config{MaxCallDepth: 10}})
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+	// The outermost frame plus one nested frame per permitted depth
+	frames := state.GetState(common.StringToAddress("contract"), common.Hash{}).Big()
+	if frames.Cmp(big.NewInt(11)) != 0 {
+		t.Fatalf("frame count mismatch: have %v, want %v", frames, 11)
+	}
+}
+
 func BenchmarkCall(b *testing.B) {
 	var definition = `[{"constant":true,"inputs":[],"name":"seller","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"abort","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"value","outputs":[{"name":"","type":"uint256"}],"type":"function"},{"constant":false,"inputs":[],"name":"refund","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"buyer","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmReceived","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"state","outputs":[{"name":"","type":"uint8"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmPurchase","outputs":[],"type":"function"},{"inputs":[],"type":"constructor"},{"anonymous":false,"inputs":[],"name":"Aborted","type":"event"},{"anonymous":false,"inputs":[],"name":"PurchaseConfirmed","type":"event"},{"anonymous":false,"inputs":[],"name":"ItemReceived","type":"event"},{"anonymous":false,"inputs":[],"name":"Refunded","type":"event"}]`
 
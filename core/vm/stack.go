@@ -19,8 +19,15 @@ package vm
 import (
 	"fmt"
 	"math/big"
+	"sync"
 )
 
+// stackPool recycles the stacks of returned calls, sparing the allocation of
+// their backing array on every call.
+var stackPool = sync.Pool{
+	New: func() interface{} { return newstack() },
+}
+
 // stack is an object for basic stack operations. Items popped to the stack are
 // expected to be changed and modified. stack does not take care of adding newly
 // initialised objects.
@@ -32,6 +39,17 @@ func newstack() *Stack {
 	return &Stack{data: make([]*big.Int, 0, 1024)}
 }
 
+// getStack returns an empty stack from the pool.
+func getStack() *Stack {
+	return stackPool.Get().(*Stack)
+}
+
+// returnStack empties a stack no longer referenced and puts it back in the pool.
+func returnStack(st *Stack) {
+	st.data = st.data[:0]
+	stackPool.Put(st)
+}
+
 func (st *Stack) Data() []*big.Int {
 	return st.data
 }
@@ -0,0 +1,946 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql provides a GraphQL interface to AquaChain chain data.
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/aquanetwork/aquachain/aqua/filters"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+const (
+	maxBlocks        = 1000  // Maximum number of blocks returned by a blocks query
+	maxLogs          = 1000  // Maximum number of logs returned by a logs query
+	maxLogBlockRange = 10000 // Maximum number of blocks a logs query may span
+)
+
+var (
+	errBlockNotFound   = errors.New("block not found")
+	errStateNotFound   = errors.New("state not found")
+	errInvalidRange    = errors.New("invalid block range")
+	errRangeTooLarge   = fmt.Errorf("block range exceeds %d blocks", maxLogBlockRange)
+	errInvalidPaginate = errors.New("invalid pagination arguments")
+)
+
+// Backend is the chain access needed to resolve GraphQL queries.
+type Backend interface {
+	filters.Backend
+
+	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
+	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+	StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error)
+	GetTd(blockHash common.Hash) *big.Int
+	GetPoolTransaction(txHash common.Hash) *types.Transaction
+	SuggestPrice(ctx context.Context) (*big.Int, error)
+	ProtocolVersion() int
+	ChainConfig() *params.ChainConfig
+}
+
+// Long is a 64 bit unsigned integer, accepted as a number or as a decimal or
+// 0x-prefixed hexadecimal string.
+type Long uint64
+
+// ImplementsGraphQLType returns true if Long implements the provided GraphQL type.
+func (l Long) ImplementsGraphQLType(name string) bool { return name == "Long" }
+
+// UnmarshalGraphQL unmarshals the provided GraphQL query data.
+func (l *Long) UnmarshalGraphQL(input interface{}) error {
+	switch input := input.(type) {
+	case string:
+		var (
+			value uint64
+			err   error
+		)
+		if strings.HasPrefix(input, "0x") {
+			value, err = hexutil.DecodeUint64(input)
+		} else {
+			value, err = strconv.ParseUint(input, 10, 64)
+		}
+		*l = Long(value)
+		return err
+	case int32:
+		if input < 0 {
+			return fmt.Errorf("negative value %d for Long", input)
+		}
+		*l = Long(input)
+		return nil
+	case float64:
+		if input < 0 {
+			return fmt.Errorf("negative value %v for Long", input)
+		}
+		*l = Long(input)
+		return nil
+	default:
+		return fmt.Errorf("unexpected type %T for Long", input)
+	}
+}
+
+// blockNumberOrLatest converts an optional block number argument into the
+// number to query state at.
+func blockNumberOrLatest(number *Long) rpc.BlockNumber {
+	if number == nil {
+		return rpc.LatestBlockNumber
+	}
+	return rpc.BlockNumber(*number)
+}
+
+// BlockNumberArgs is the optional block number argument of state accessors.
+type BlockNumberArgs struct {
+	Block *Long
+}
+
+// Account represents an AquaChain account at a particular block.
+type Account struct {
+	backend     Backend
+	address     common.Address
+	blockNumber rpc.BlockNumber
+}
+
+// getState fetches the state the account is resolved at.
+func (a *Account) getState(ctx context.Context) (*state.StateDB, error) {
+	state, _, err := a.backend.StateAndHeaderByNumber(ctx, a.blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, errStateNotFound
+	}
+	return state, nil
+}
+
+func (a *Account) Address(ctx context.Context) (common.Address, error) {
+	return a.address, nil
+}
+
+func (a *Account) Balance(ctx context.Context) (hexutil.Big, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*state.GetBalance(a.address)), nil
+}
+
+func (a *Account) TransactionCount(ctx context.Context) (Long, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Long(state.GetNonce(a.address)), nil
+}
+
+func (a *Account) Code(ctx context.Context) (hexutil.Bytes, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(state.GetCode(a.address)), nil
+}
+
+func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash }) (common.Hash, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return state.GetState(a.address, args.Slot), nil
+}
+
+// Log represents an individual log message. All arguments are mandatory.
+type Log struct {
+	backend     Backend
+	transaction *Transaction
+	log         *types.Log
+}
+
+func (l *Log) Transaction(ctx context.Context) *Transaction {
+	return l.transaction
+}
+
+func (l *Log) Account(ctx context.Context, args BlockNumberArgs) *Account {
+	return &Account{
+		backend:     l.backend,
+		address:     l.log.Address,
+		blockNumber: blockNumberOrLatest(args.Block),
+	}
+}
+
+func (l *Log) Index(ctx context.Context) int32 {
+	return int32(l.log.Index)
+}
+
+func (l *Log) Topics(ctx context.Context) []common.Hash {
+	return l.log.Topics
+}
+
+func (l *Log) Data(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(l.log.Data)
+}
+
+// Transaction represents an AquaChain transaction.
+// backend and hash are mandatory; all others will be fetched when required.
+type Transaction struct {
+	backend Backend
+	hash    common.Hash
+	tx      *types.Transaction
+	block   *Block
+	index   uint64
+}
+
+// resolve returns the internal transaction object, fetching it if needed.
+func (t *Transaction) resolve(ctx context.Context) (*types.Transaction, error) {
+	if t.tx == nil {
+		tx, blockHash, _, index := core.GetTransaction(t.backend.ChainDb(), t.hash)
+		if tx != nil {
+			t.tx = tx
+			t.block = &Block{backend: t.backend, hash: blockHash}
+			t.index = index
+		} else {
+			t.tx = t.backend.GetPoolTransaction(t.hash)
+		}
+	}
+	return t.tx, nil
+}
+
+func (t *Transaction) Hash(ctx context.Context) common.Hash {
+	return t.hash
+}
+
+func (t *Transaction) InputData(ctx context.Context) (hexutil.Bytes, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(tx.Data()), nil
+}
+
+func (t *Transaction) Gas(ctx context.Context) (Long, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return 0, err
+	}
+	return Long(tx.Gas()), nil
+}
+
+func (t *Transaction) GasPrice(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*tx.GasPrice()), nil
+}
+
+func (t *Transaction) Value(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*tx.Value()), nil
+}
+
+func (t *Transaction) Nonce(ctx context.Context) (Long, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return 0, err
+	}
+	return Long(tx.Nonce()), nil
+}
+
+func (t *Transaction) To(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	to := tx.To()
+	if to == nil {
+		return nil, nil
+	}
+	return &Account{
+		backend:     t.backend,
+		address:     *to,
+		blockNumber: blockNumberOrLatest(args.Block),
+	}, nil
+}
+
+func (t *Transaction) From(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	var signer types.Signer = types.FrontierSigner{}
+	if tx.Protected() {
+		signer = types.NewFeeMarketSigner(tx.ChainId())
+	}
+	from, _ := types.Sender(signer, tx)
+
+	return &Account{
+		backend:     t.backend,
+		address:     from,
+		blockNumber: blockNumberOrLatest(args.Block),
+	}, nil
+}
+
+func (t *Transaction) Block(ctx context.Context) (*Block, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	return t.block, nil
+}
+
+func (t *Transaction) Index(ctx context.Context) (*int32, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if t.block == nil {
+		return nil, nil
+	}
+	index := int32(t.index)
+	return &index, nil
+}
+
+// getReceipt returns the receipt of a mined transaction, or nil if it's pending.
+func (t *Transaction) getReceipt(ctx context.Context) (*types.Receipt, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if t.block == nil {
+		return nil, nil
+	}
+	receipts, err := t.block.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(receipts)) <= t.index {
+		return nil, nil
+	}
+	return receipts[t.index], nil
+}
+
+func (t *Transaction) Status(ctx context.Context) (*Long, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	status := Long(receipt.Status)
+	return &status, nil
+}
+
+func (t *Transaction) GasUsed(ctx context.Context) (*Long, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	used := Long(receipt.GasUsed)
+	return &used, nil
+}
+
+func (t *Transaction) CumulativeGasUsed(ctx context.Context) (*Long, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	used := Long(receipt.CumulativeGasUsed)
+	return &used, nil
+}
+
+func (t *Transaction) CreatedContract(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil || receipt.ContractAddress == (common.Address{}) {
+		return nil, err
+	}
+	return &Account{
+		backend:     t.backend,
+		address:     receipt.ContractAddress,
+		blockNumber: blockNumberOrLatest(args.Block),
+	}, nil
+}
+
+func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	logs := make([]*Log, 0, len(receipt.Logs))
+	for _, log := range receipt.Logs {
+		logs = append(logs, &Log{
+			backend:     t.backend,
+			transaction: t,
+			log:         log,
+		})
+	}
+	return &logs, nil
+}
+
+// Block represents an AquaChain block.
+// backend, and either num or hash are mandatory. All other fields are lazily
+// fetched when required.
+type Block struct {
+	backend  Backend
+	num      *rpc.BlockNumber
+	hash     common.Hash
+	header   *types.Header
+	block    *types.Block
+	receipts []*types.Receipt
+}
+
+// resolve returns the internal Block object representing this block, fetching
+// it if necessary.
+func (b *Block) resolve(ctx context.Context) (*types.Block, error) {
+	if b.block != nil {
+		return b.block, nil
+	}
+	var err error
+	if b.hash != (common.Hash{}) {
+		b.block, err = b.backend.GetBlock(ctx, b.hash)
+	} else {
+		b.block, err = b.backend.BlockByNumber(ctx, *b.num)
+	}
+	if b.block != nil {
+		b.header = b.block.Header()
+		b.hash = b.block.Hash()
+	}
+	return b.block, err
+}
+
+// resolveHeader returns the internal Header object for this block, fetching it
+// if necessary. Call this function instead of resolve unless you need the
+// additional data (transactions and uncles).
+func (b *Block) resolveHeader(ctx context.Context) (*types.Header, error) {
+	if b.header != nil {
+		return b.header, nil
+	}
+	var err error
+	if b.hash != (common.Hash{}) {
+		b.header, err = b.backend.HeaderByHash(ctx, b.hash)
+	} else {
+		b.header, err = b.backend.HeaderByNumber(ctx, *b.num)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b.header == nil {
+		return nil, errBlockNotFound
+	}
+	b.hash = b.header.Hash()
+	return b.header, nil
+}
+
+// resolveReceipts returns the list of receipts for this block, fetching them
+// if necessary.
+func (b *Block) resolveReceipts(ctx context.Context) ([]*types.Receipt, error) {
+	if b.receipts == nil {
+		if _, err := b.resolveHeader(ctx); err != nil {
+			return nil, err
+		}
+		receipts, err := b.backend.GetReceipts(ctx, b.hash)
+		if err != nil {
+			return nil, err
+		}
+		b.receipts = []*types.Receipt(receipts)
+	}
+	return b.receipts, nil
+}
+
+func (b *Block) Number(ctx context.Context) (Long, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Long(header.Number.Uint64()), nil
+}
+
+func (b *Block) Hash(ctx context.Context) (common.Hash, error) {
+	if _, err := b.resolveHeader(ctx); err != nil {
+		return common.Hash{}, err
+	}
+	return b.hash, nil
+}
+
+func (b *Block) GasLimit(ctx context.Context) (Long, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Long(header.GasLimit), nil
+}
+
+func (b *Block) GasUsed(ctx context.Context) (Long, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Long(header.GasUsed), nil
+}
+
+func (b *Block) Parent(ctx context.Context) (*Block, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if header.Number.Sign() == 0 {
+		return nil, nil
+	}
+	num := rpc.BlockNumber(header.Number.Uint64() - 1)
+	return &Block{
+		backend: b.backend,
+		num:     &num,
+		hash:    header.ParentHash,
+	}, nil
+}
+
+func (b *Block) Difficulty(ctx context.Context) (hexutil.Big, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*header.Difficulty), nil
+}
+
+func (b *Block) Timestamp(ctx context.Context) (hexutil.Big, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*header.Time), nil
+}
+
+func (b *Block) Nonce(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(header.Nonce[:]), nil
+}
+
+func (b *Block) MixHash(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.MixDigest, nil
+}
+
+func (b *Block) TransactionsRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.TxHash, nil
+}
+
+func (b *Block) StateRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.Root, nil
+}
+
+func (b *Block) ReceiptsRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.ReceiptHash, nil
+}
+
+func (b *Block) OmmerHash(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.UncleHash, nil
+}
+
+func (b *Block) OmmerCount(ctx context.Context) (*int32, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	count := int32(len(block.Uncles()))
+	return &count, nil
+}
+
+func (b *Block) Ommers(ctx context.Context) (*[]*Block, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	ret := make([]*Block, 0, len(block.Uncles()))
+	for _, uncle := range block.Uncles() {
+		uncle.Version = b.backend.ChainConfig().GetBlockVersion(uncle.Number)
+		blockNumber := rpc.BlockNumber(uncle.Number.Uint64())
+		ret = append(ret, &Block{
+			backend: b.backend,
+			num:     &blockNumber,
+			hash:    uncle.Hash(),
+			header:  uncle,
+		})
+	}
+	return &ret, nil
+}
+
+func (b *Block) ExtraData(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(header.Extra), nil
+}
+
+func (b *Block) LogsBloom(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(header.Bloom.Bytes()), nil
+}
+
+func (b *Block) TotalDifficulty(ctx context.Context) (hexutil.Big, error) {
+	if _, err := b.resolveHeader(ctx); err != nil {
+		return hexutil.Big{}, err
+	}
+	td := b.backend.GetTd(b.hash)
+	if td == nil {
+		return hexutil.Big{}, fmt.Errorf("total difficulty not found %x", b.hash)
+	}
+	return hexutil.Big(*td), nil
+}
+
+func (b *Block) Miner(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		backend:     b.backend,
+		address:     header.Coinbase,
+		blockNumber: blockNumberOrLatest(args.Block),
+	}, nil
+}
+
+func (b *Block) TransactionCount(ctx context.Context) (*int32, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	count := int32(len(block.Transactions()))
+	return &count, nil
+}
+
+func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	ret := make([]*Transaction, 0, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		ret = append(ret, &Transaction{
+			backend: b.backend,
+			hash:    tx.Hash(),
+			tx:      tx,
+			block:   b,
+			index:   uint64(i),
+		})
+	}
+	return &ret, nil
+}
+
+func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if args.Index < 0 || int(args.Index) >= len(txs) {
+		return nil, nil
+	}
+	tx := txs[args.Index]
+	return &Transaction{
+		backend: b.backend,
+		hash:    tx.Hash(),
+		tx:      tx,
+		block:   b,
+		index:   uint64(args.Index),
+	}, nil
+}
+
+// BlockFilterCriteria encapsulates criteria passed to a `logs` accessor inside
+// a block.
+type BlockFilterCriteria struct {
+	Addresses *[]common.Address // restricts matches to events created by specific contracts
+
+	// The Topic list restricts matches to particular event topics. Each event has a list
+	// of topics. Topics matches a prefix of that list. An empty element slice matches any
+	// topic. Non-empty elements represent an alternative that matches any of the
+	// contained topics.
+	//
+	// Examples:
+	// {} or nil          matches any topic list
+	// {{A}}              matches topic A in first position
+	// {{}, {B}}          matches any topic in first position, B in second position
+	// {{A}, {B}}         matches topic A in first position, B in second position
+	// {{A, B}}, {C, D}}  matches topic (A OR B) in first position, (C OR D) in second position
+	Topics *[][]common.Hash
+}
+
+// criteria returns the address and topic filters of the criteria.
+func (c BlockFilterCriteria) criteria() ([]common.Address, [][]common.Hash) {
+	var (
+		addresses []common.Address
+		topics    [][]common.Hash
+	)
+	if c.Addresses != nil {
+		addresses = *c.Addresses
+	}
+	if c.Topics != nil {
+		topics = *c.Topics
+	}
+	return addresses, topics
+}
+
+// runFilter runs a log filter and wraps the matched logs.
+func runFilter(ctx context.Context, backend Backend, filter *filters.Filter) ([]*Log, error) {
+	logs, err := filter.Logs(ctx)
+	if err != nil || logs == nil {
+		return []*Log{}, err
+	}
+	return wrapLogs(backend, logs), nil
+}
+
+// wrapLogs wraps raw logs into their GraphQL representation.
+func wrapLogs(backend Backend, logs []*types.Log) []*Log {
+	ret := make([]*Log, 0, len(logs))
+	for _, log := range logs {
+		ret = append(ret, &Log{
+			backend:     backend,
+			transaction: &Transaction{backend: backend, hash: log.TxHash},
+			log:         log,
+		})
+	}
+	return ret
+}
+
+func (b *Block) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) ([]*Log, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	addresses, topics := args.Filter.criteria()
+	number := header.Number.Int64()
+	return runFilter(ctx, b.backend, filters.New(b.backend, number, number, addresses, topics))
+}
+
+func (b *Block) Account(ctx context.Context, args struct{ Address common.Address }) (*Account, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		backend:     b.backend,
+		address:     args.Address,
+		blockNumber: rpc.BlockNumber(header.Number.Uint64()),
+	}, nil
+}
+
+// Resolver is the top-level object in the GraphQL hierarchy.
+type Resolver struct {
+	backend Backend
+}
+
+func (r *Resolver) Block(ctx context.Context, args struct {
+	Number *Long
+	Hash   *common.Hash
+}) (*Block, error) {
+	var block *Block
+	if args.Number != nil {
+		num := rpc.BlockNumber(uint64(*args.Number))
+		block = &Block{
+			backend: r.backend,
+			num:     &num,
+		}
+	} else if args.Hash != nil {
+		block = &Block{
+			backend: r.backend,
+			hash:    *args.Hash,
+		}
+	} else {
+		num := rpc.LatestBlockNumber
+		block = &Block{
+			backend: r.backend,
+			num:     &num,
+		}
+	}
+	// Resolve the header, return nil if it doesn't exist.
+	if _, err := block.resolveHeader(ctx); err == errBlockNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// latestNumber returns the number of the most recent known block.
+func (r *Resolver) latestNumber(ctx context.Context) (uint64, error) {
+	header, err := r.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, errBlockNotFound
+	}
+	return header.Number.Uint64(), nil
+}
+
+func (r *Resolver) Blocks(ctx context.Context, args struct {
+	From Long
+	To   *Long
+}) ([]*Block, error) {
+	from := uint64(args.From)
+
+	var to uint64
+	if args.To != nil {
+		to = uint64(*args.To)
+	} else {
+		latest, err := r.latestNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+		to = latest
+	}
+	if to < from {
+		return []*Block{}, nil
+	}
+	if to-from >= maxBlocks {
+		to = from + maxBlocks - 1
+	}
+	ret := make([]*Block, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		num := rpc.BlockNumber(i)
+		block := &Block{
+			backend: r.backend,
+			num:     &num,
+		}
+		// Stop at the first unknown block, it's past the head
+		if _, err := block.resolveHeader(ctx); err == errBlockNotFound {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		ret = append(ret, block)
+	}
+	return ret, nil
+}
+
+func (r *Resolver) Transaction(ctx context.Context, args struct{ Hash common.Hash }) (*Transaction, error) {
+	tx := &Transaction{
+		backend: r.backend,
+		hash:    args.Hash,
+	}
+	// Resolve the transaction; if it doesn't exist, return nil.
+	t, err := tx.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, nil
+	}
+	return tx, nil
+}
+
+// FilterCriteria encapsulates the arguments to `logs` on the root resolver object.
+type FilterCriteria struct {
+	FromBlock *Long // beginning of the queried range, nil means latest block
+	ToBlock   *Long // end of the range, nil means latest block
+
+	BlockFilterCriteria
+}
+
+func (r *Resolver) Logs(ctx context.Context, args struct {
+	Filter FilterCriteria
+	Skip   *int32
+	First  *int32
+}) ([]*Log, error) {
+	skip, first := 0, maxLogs
+	if args.Skip != nil {
+		skip = int(*args.Skip)
+	}
+	if args.First != nil {
+		first = int(*args.First)
+	}
+	if skip < 0 || first < 0 || first > maxLogs {
+		return nil, errInvalidPaginate
+	}
+	// Resolve the block range, defaulting both ends to the latest block
+	var begin, end uint64
+	if args.Filter.FromBlock == nil || args.Filter.ToBlock == nil {
+		latest, err := r.latestNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+		begin, end = latest, latest
+	}
+	if args.Filter.FromBlock != nil {
+		begin = uint64(*args.Filter.FromBlock)
+	}
+	if args.Filter.ToBlock != nil {
+		end = uint64(*args.Filter.ToBlock)
+	}
+	if end < begin {
+		return nil, errInvalidRange
+	}
+	if end-begin >= maxLogBlockRange {
+		return nil, errRangeTooLarge
+	}
+	addresses, topics := args.Filter.criteria()
+	logs, err := runFilter(ctx, r.backend, filters.New(r.backend, int64(begin), int64(end), addresses, topics))
+	if err != nil {
+		return nil, err
+	}
+	if skip >= len(logs) {
+		return []*Log{}, nil
+	}
+	logs = logs[skip:]
+	if len(logs) > first {
+		logs = logs[:first]
+	}
+	return logs, nil
+}
+
+func (r *Resolver) GasPrice(ctx context.Context) (hexutil.Big, error) {
+	price, err := r.backend.SuggestPrice(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*price), nil
+}
+
+func (r *Resolver) ProtocolVersion(ctx context.Context) (int32, error) {
+	return int32(r.backend.ProtocolVersion()), nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+// testBackend serves a chain of empty headers, leaving the rest of the
+// backend unimplemented.
+type testBackend struct {
+	Backend
+	head uint64
+}
+
+func (b *testBackend) ProtocolVersion() int { return 63 }
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.LatestBlockNumber {
+		number = rpc.BlockNumber(b.head)
+	}
+	if uint64(number) > b.head {
+		return nil, nil
+	}
+	return &types.Header{
+		Number:  big.NewInt(int64(number)),
+		Version: types.H_KECCAK256,
+	}, nil
+}
+
+// query runs a GraphQL query against the test backend and decodes the result.
+func query(t *testing.T, q string, result interface{}) {
+	handler, err := newHandler(&testBackend{head: 2500})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	body, _ := json.Marshal(map[string]string{"query": q})
+	req := httptest.NewRequest("POST", "/", strings.NewReader(string(body)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var res struct {
+		Data   json.RawMessage
+		Errors []interface{}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(res.Errors) > 0 {
+		t.Fatalf("query failed: %v", res.Errors)
+	}
+	if err := json.Unmarshal(res.Data, result); err != nil {
+		t.Fatalf("failed to decode data: %v", err)
+	}
+}
+
+func TestProtocolVersion(t *testing.T) {
+	var res struct{ ProtocolVersion int }
+	query(t, `{ protocolVersion }`, &res)
+	if res.ProtocolVersion != 63 {
+		t.Fatalf("protocol version mismatch: have %d, want 63", res.ProtocolVersion)
+	}
+}
+
+func TestBlocksPagination(t *testing.T) {
+	tests := []struct {
+		query       string
+		first, last uint64
+		count       int
+	}{
+		{`{ blocks(from: 10, to: 20) { number } }`, 10, 20, 11},
+		{`{ blocks(from: 0) { number } }`, 0, maxBlocks - 1, maxBlocks},
+		{`{ blocks(from: 2000) { number } }`, 2000, 2500, 501},
+		{`{ blocks(from: "0x9c4", to: 3000) { number } }`, 2500, 2500, 1},
+	}
+	for i, tt := range tests {
+		var res struct{ Blocks []struct{ Number uint64 } }
+		query(t, tt.query, &res)
+
+		if len(res.Blocks) != tt.count {
+			t.Errorf("test %d: block count mismatch: have %d, want %d", i, len(res.Blocks), tt.count)
+			continue
+		}
+		if first := res.Blocks[0].Number; first != tt.first {
+			t.Errorf("test %d: first block mismatch: have %d, want %d", i, first, tt.first)
+		}
+		if last := res.Blocks[len(res.Blocks)-1].Number; last != tt.last {
+			t.Errorf("test %d: last block mismatch: have %d, want %d", i, last, tt.last)
+		}
+	}
+}
+
+func TestLongUnmarshal(t *testing.T) {
+	tests := []struct {
+		input interface{}
+		want  Long
+		fail  bool
+	}{
+		{int32(12), 12, false},
+		{float64(3000000000), 3000000000, false},
+		{"42", 42, false},
+		{"0x2a", 42, false},
+		{int32(-1), 0, true},
+		{"0xzz", 0, true},
+		{true, 0, true},
+	}
+	for i, tt := range tests {
+		var l Long
+		err := l.UnmarshalGraphQL(tt.input)
+		if (err != nil) != tt.fail {
+			t.Errorf("test %d: error mismatch: have %v, want failure %v", i, err, tt.fail)
+			continue
+		}
+		if !tt.fail && l != tt.want {
+			t.Errorf("test %d: value mismatch: have %d, want %d", i, l, tt.want)
+		}
+	}
+}
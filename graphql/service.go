@@ -0,0 +1,93 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/p2p"
+	"github.com/aquanetwork/aquachain/rpc"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+// Service encapsulates a GraphQL service.
+type Service struct {
+	endpoint string       // The host:port endpoint for this service.
+	cors     []string     // Allowed CORS domains
+	vhosts   []string     // Recognised vhosts
+	backend  Backend      // The backend that queries will operate on.
+	handler  http.Handler // The `http.Handler` used to answer queries.
+	listener net.Listener // The listening socket.
+}
+
+// New constructs a new GraphQL service instance.
+func New(backend Backend, endpoint string, cors, vhosts []string) (*Service, error) {
+	return &Service{
+		endpoint: endpoint,
+		cors:     cors,
+		vhosts:   vhosts,
+		backend:  backend,
+	}, nil
+}
+
+// Protocols returns the list of protocols exported by this service.
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs returns the list of APIs exported by this service.
+func (s *Service) APIs() []rpc.API { return nil }
+
+// Start is called after all services have been constructed and the networking
+// layer was also initialized to spawn any goroutines required by the service.
+func (s *Service) Start(server *p2p.Server) error {
+	var err error
+	s.handler, err = newHandler(s.backend)
+	if err != nil {
+		return err
+	}
+	if s.listener, err = net.Listen("tcp", s.endpoint); err != nil {
+		return err
+	}
+	go http.Serve(s.listener, rpc.NewHTTPHandlerStack(s.handler, s.cors, s.vhosts))
+	log.Info("GraphQL endpoint opened", "url", fmt.Sprintf("http://%s", s.endpoint), "cors", strings.Join(s.cors, ","), "vhosts", strings.Join(s.vhosts, ","))
+	return nil
+}
+
+// Stop terminates all goroutines belonging to the service, blocking until they
+// are all terminated.
+func (s *Service) Stop() error {
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+		log.Info("GraphQL endpoint closed", "url", fmt.Sprintf("http://%s", s.endpoint))
+	}
+	return nil
+}
+
+// newHandler parses the schema against the resolvers of the backend and
+// returns the HTTP handler answering queries.
+func newHandler(backend Backend) (http.Handler, error) {
+	s, err := graphqlgo.ParseSchema(schema, &Resolver{backend})
+	if err != nil {
+		return nil, err
+	}
+	return &relay.Handler{Schema: s}, nil
+}
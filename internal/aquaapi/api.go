@@ -26,12 +26,16 @@ import (
 	"time"
 
 	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/accounts/hdwallet"
 	"github.com/aquanetwork/aquachain/accounts/keystore"
+	"github.com/aquanetwork/aquachain/aqua/downloader"
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/hexutil"
 	"github.com/aquanetwork/aquachain/common/math"
 	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/consensus/misc"
 	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/state"
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/core/vm"
 	"github.com/aquanetwork/aquachain/crypto"
@@ -48,6 +52,14 @@ const (
 	defaultGasPrice = 50 * params.Shannon
 )
 
+// errNoKeystore is returned by keystore operations if signing is delegated to
+// an external signer.
+var errNoKeystore = errors.New("no keystore, accounts are managed by an external signer")
+
+// errUnprotectedTx is returned when submitting a transaction without EIP-155
+// replay protection over RPC, once the chain enforces it.
+var errUnprotectedTx = errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
+
 // PublicAquaChainAPI provides an API to access AquaChain related information.
 // It offers only methods that operate on public data that is freely available to anyone.
 type PublicAquaChainAPI struct {
@@ -64,6 +76,44 @@ func (s *PublicAquaChainAPI) GasPrice(ctx context.Context) (*big.Int, error) {
 	return s.b.SuggestPrice(ctx)
 }
 
+// MaxPriorityFeePerGas returns a suggestion for the tip of a dynamic fee
+// transaction, paid to the miner on top of the base fee.
+func (s *PublicAquaChainAPI) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, error) {
+	tip, err := s.b.SuggestPrice(ctx)
+	return (*hexutil.Big)(tip), err
+}
+
+// feeHistoryResult is the gas price history of a range of blocks.
+type feeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// FeeHistory returns the gas used ratio of up to blockCount blocks ending with
+// lastBlock, and the gas prices paid at the given percentiles of the gas used
+// in each of them.
+func (s *PublicAquaChainAPI) FeeHistory(ctx context.Context, blockCount hexutil.Uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
+	oldest, reward, ratios, err := s.b.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	result := &feeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(oldest),
+		GasUsedRatio: ratios,
+	}
+	if reward != nil {
+		result.Reward = make([][]*hexutil.Big, len(reward))
+		for i, prices := range reward {
+			result.Reward[i] = make([]*hexutil.Big, len(prices))
+			for j, price := range prices {
+				result.Reward[i][j] = (*hexutil.Big)(price)
+			}
+		}
+	}
+	return result, nil
+}
+
 // ProtocolVersion returns the current AquaChain protocol version this node supports
 func (s *PublicAquaChainAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -76,6 +126,9 @@ func (s *PublicAquaChainAPI) ProtocolVersion() hexutil.Uint {
 // - highestBlock:  block number of the highest block header this node has received from peers
 // - pulledStates:  number of state entries processed until now
 // - knownStates:   number of known state entries that still need to be pulled
+// - stages:        progress of the headers, bodies, receipts (fast sync only) and
+//                  blocks imported, as the current, highest and pending blocks
+// - remainingSeconds: estimated time left to import the blocks, if known
 func (s *PublicAquaChainAPI) Syncing() (interface{}, error) {
 	progress := s.b.Downloader().Progress()
 
@@ -84,13 +137,37 @@ func (s *PublicAquaChainAPI) Syncing() (interface{}, error) {
 		return false, nil
 	}
 	// Otherwise gather the block sync stats
-	return map[string]interface{}{
+	stages := s.b.Downloader().Stages()
+	status := map[string]interface{}{
 		"startingBlock": hexutil.Uint64(progress.StartingBlock),
 		"currentBlock":  hexutil.Uint64(progress.CurrentBlock),
 		"highestBlock":  hexutil.Uint64(progress.HighestBlock),
 		"pulledStates":  hexutil.Uint64(progress.PulledStates),
 		"knownStates":   hexutil.Uint64(progress.KnownStates),
-	}, nil
+	}
+	rpcStages := map[string]interface{}{
+		"headers": rpcStageProgress(stages.Headers),
+		"bodies":  rpcStageProgress(stages.Bodies),
+		"blocks":  rpcStageProgress(stages.Blocks),
+	}
+	if stages.Receipts != nil {
+		rpcStages["receipts"] = rpcStageProgress(*stages.Receipts)
+	}
+	status["stages"] = rpcStages
+	if stages.Remaining > 0 {
+		status["remainingSeconds"] = hexutil.Uint64(stages.Remaining / time.Second)
+	}
+	return status, nil
+}
+
+// rpcStageProgress converts the progress of a synchronisation stage to its RPC
+// representation.
+func rpcStageProgress(stage downloader.StageProgress) map[string]interface{} {
+	return map[string]interface{}{
+		"current": hexutil.Uint64(stage.Current),
+		"highest": hexutil.Uint64(stage.Highest),
+		"pending": hexutil.Uint64(stage.Pending),
+	}
 }
 
 // PublicTxPoolAPI offers and API for the transaction pool. It only operates on data that is non confidential.
@@ -130,6 +207,29 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	return content
 }
 
+// ContentFrom returns the transactions of a single account contained within the
+// transaction pool.
+func (s *PublicTxPoolAPI) ContentFrom(addr common.Address) map[string]map[string]*RPCTransaction {
+	content := make(map[string]map[string]*RPCTransaction, 2)
+	pending, queue := s.b.TxPoolContent()
+
+	// Build the pending transactions
+	dump := make(map[string]*RPCTransaction, len(pending[addr]))
+	for _, tx := range pending[addr] {
+		dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+	}
+	content["pending"] = dump
+
+	// Build the queued transactions
+	dump = make(map[string]*RPCTransaction, len(queue[addr]))
+	for _, tx := range queue[addr] {
+		dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+	}
+	content["queued"] = dump
+
+	return content
+}
+
 // Status returns the number of pending and queued transaction in the pool.
 func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	pending, queue := s.b.Stats()
@@ -196,6 +296,55 @@ func (s *PublicAccountAPI) Accounts() []common.Address {
 	return addresses
 }
 
+// walletEventNames are the names of the wallet events sent to subscribers.
+var walletEventNames = map[accounts.WalletEventType]string{
+	accounts.WalletArrived: "arrived",
+	accounts.WalletOpened:  "opened",
+	accounts.WalletDropped: "dropped",
+}
+
+// WalletNotification is sent to the subscribers of wallet events when a wallet
+// is detected, such as a keystore file added or a hardware wallet plugged in,
+// opened or dropped. It holds the accounts of the node after the event.
+type WalletNotification struct {
+	Event    string           `json:"event"`
+	URL      string           `json:"url"`
+	Accounts []common.Address `json:"accounts"`
+}
+
+// Wallets creates a subscription notified of the wallets detected, opened and
+// dropped by the node, along with its accounts, so that clients can follow the
+// accounts without polling them.
+func (s *PublicAccountAPI) Wallets(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan accounts.WalletEvent, 16)
+		eventSub := s.am.Subscribe(events)
+		defer eventSub.Unsubscribe()
+
+		for {
+			select {
+			case event := <-events:
+				notifier.Notify(rpcSub.ID, &WalletNotification{
+					Event:    walletEventNames[event.Kind],
+					URL:      event.Wallet.URL().String(),
+					Accounts: s.Accounts(),
+				})
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // PrivateAccountAPI provides an API to access accounts managed by this node.
 // It offers methods to create, (un)lock en list accounts. Some methods accept
 // passwords and are therefore considered private by default.
@@ -288,16 +437,24 @@ func (s *PrivateAccountAPI) DeriveAccount(url string, path string, pin *bool) (a
 
 // NewAccount will create a new account and returns the address for the new account.
 func (s *PrivateAccountAPI) NewAccount(password string) (common.Address, error) {
-	acc, err := fetchKeystore(s.am).NewAccount(password)
+	ks, err := fetchKeystore(s.am)
+	if err != nil {
+		return common.Address{}, err
+	}
+	acc, err := ks.NewAccount(password)
 	if err == nil {
 		return acc.Address, nil
 	}
 	return common.Address{}, err
 }
 
-// fetchKeystore retrives the encrypted keystore from the account manager.
-func fetchKeystore(am *accounts.Manager) *keystore.KeyStore {
-	return am.Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+// fetchKeystore retrives the encrypted keystore from the account manager. No
+// keystore is available if signing is delegated to an external signer.
+func fetchKeystore(am *accounts.Manager) (*keystore.KeyStore, error) {
+	if ks := am.Backends(keystore.KeyStoreType); len(ks) > 0 {
+		return ks[0].(*keystore.KeyStore), nil
+	}
+	return nil, errNoKeystore
 }
 
 // ImportRawKey stores the given hex encoded ECDSA key into the key directory,
@@ -307,10 +464,101 @@ func (s *PrivateAccountAPI) ImportRawKey(privkey string, password string) (commo
 	if err != nil {
 		return common.Address{}, err
 	}
-	acc, err := fetchKeystore(s.am).ImportECDSA(key, password)
+	ks, err := fetchKeystore(s.am)
+	if err != nil {
+		return common.Address{}, err
+	}
+	acc, err := ks.ImportECDSA(key, password)
+	return acc.Address, err
+}
+
+// maxHDAccounts is the maximum number of accounts listed from a mnemonic at once.
+const maxHDAccounts = 256
+
+// HDAccount is an account derived from a mnemonic phrase.
+type HDAccount struct {
+	Address  common.Address `json:"address"`
+	Path     string         `json:"path"`
+	Mnemonic string         `json:"mnemonic,omitempty"`
+}
+
+// NewHDAccount creates a new mnemonic phrase and stores the account derived at
+// the aquachain base path into the key directory, encrypted with the password.
+// The returned phrase restores the account with ImportMnemonic and should be
+// written down, it is not stored by the node.
+func (s *PrivateAccountAPI) NewHDAccount(password string) (*HDAccount, error) {
+	ks, err := fetchKeystore(s.am)
+	if err != nil {
+		return nil, err
+	}
+	mnemonic, err := hdwallet.NewMnemonic(hdwallet.DefaultEntropyBits)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := ks.ImportMnemonic(mnemonic, "", accounts.AquaBaseDerivationPath, password)
+	if err != nil {
+		return nil, err
+	}
+	return &HDAccount{Address: acc.Address, Path: accounts.AquaBaseDerivationPath.String(), Mnemonic: mnemonic}, nil
+}
+
+// ImportMnemonic restores the account derived from the mnemonic phrase at the
+// given path, or the aquachain base path if omitted, into the key directory,
+// encrypting it with the password.
+func (s *PrivateAccountAPI) ImportMnemonic(mnemonic string, password string, path *string) (common.Address, error) {
+	derivPath := accounts.AquaBaseDerivationPath
+	if path != nil {
+		var err error
+		if derivPath, err = accounts.ParseDerivationPath(*path); err != nil {
+			return common.Address{}, err
+		}
+	}
+	ks, err := fetchKeystore(s.am)
+	if err != nil {
+		return common.Address{}, err
+	}
+	acc, err := ks.ImportMnemonic(mnemonic, "", derivPath, password)
 	return acc.Address, err
 }
 
+// ListHDAccounts lists the addresses derived from the mnemonic phrase along the
+// base path, or the aquachain base path if omitted, incrementing its last
+// component. It doesn't store any keys and helps finding the path of the
+// accounts to restore.
+func (s *PrivateAccountAPI) ListHDAccounts(mnemonic string, base *string, count *uint64) ([]HDAccount, error) {
+	basePath := accounts.AquaBaseDerivationPath
+	if base != nil {
+		var err error
+		if basePath, err = accounts.ParseDerivationPath(*base); err != nil {
+			return nil, err
+		}
+	}
+	n := uint64(10)
+	if count != nil {
+		n = *count
+	}
+	if n > maxHDAccounts {
+		return nil, fmt.Errorf("too many accounts requested, max %d", maxHDAccounts)
+	}
+	seed, err := hdwallet.NewSeed(mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+	list := make([]HDAccount, 0, n)
+	for i := uint64(0); i < n; i++ {
+		path := make(accounts.DerivationPath, len(basePath))
+		copy(path, basePath)
+		path[len(path)-1] += uint32(i)
+
+		key, err := hdwallet.DeriveKey(seed, path)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, HDAccount{Address: crypto.PubkeyToAddress(key.PublicKey), Path: path.String()})
+	}
+	return list, nil
+}
+
 // UnlockAccount will unlock the account associated with the given address with
 // the given password for duration seconds. If duration is nil it will use a
 // default of 300 seconds. It returns an indication if the account was unlocked.
@@ -324,13 +572,21 @@ func (s *PrivateAccountAPI) UnlockAccount(addr common.Address, password string,
 	} else {
 		d = time.Duration(*duration) * time.Second
 	}
-	err := fetchKeystore(s.am).TimedUnlock(accounts.Account{Address: addr}, password, d)
+	ks, err := fetchKeystore(s.am)
+	if err != nil {
+		return false, err
+	}
+	err = ks.TimedUnlock(accounts.Account{Address: addr}, password, d)
 	return err == nil, err
 }
 
 // LockAccount will lock the account associated with the given address when it's unlocked.
 func (s *PrivateAccountAPI) LockAccount(addr common.Address) bool {
-	return fetchKeystore(s.am).Lock(addr) == nil
+	ks, err := fetchKeystore(s.am)
+	if err != nil {
+		return false
+	}
+	return ks.Lock(addr) == nil
 }
 
 // signTransactions sets defaults and signs the given transaction
@@ -384,8 +640,8 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 	if args.Gas == nil {
 		return nil, fmt.Errorf("gas not specified")
 	}
-	if args.GasPrice == nil {
-		return nil, fmt.Errorf("gasPrice not specified")
+	if args.GasPrice == nil && args.MaxFeePerGas == nil {
+		return nil, fmt.Errorf("gasPrice or maxFeePerGas not specified")
 	}
 	if args.Nonce == nil {
 		return nil, fmt.Errorf("nonce not specified")
@@ -394,7 +650,7 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 	if err != nil {
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(signed)
+	data, err := signed.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -484,6 +740,11 @@ func NewPublicBlockChainAPI(b Backend) *PublicBlockChainAPI {
 	return &PublicBlockChainAPI{b}
 }
 
+// ChainId returns the chain ID used for transaction replay protection.
+func (s *PublicBlockChainAPI) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(s.b.ChainConfig().ChainId)
+}
+
 // BlockNumber returns the block number of the chain head.
 func (s *PublicBlockChainAPI) BlockNumber() *big.Int {
 	header, _ := s.b.HeaderByNumber(context.Background(), rpc.LatestBlockNumber) // latest header should always be available
@@ -491,10 +752,17 @@ func (s *PublicBlockChainAPI) BlockNumber() *big.Int {
 }
 
 // GetBalance returns the amount of wei for the given address in the state of the
-// given block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta
-// block numbers are also allowed.
-func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*big.Int, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+// given block, selected by number or hash. The rpc.LatestBlockNumber,
+// rpc.PendingBlockNumber and rpc.EarliestBlockNumber meta block numbers are
+// also allowed. Past blocks covered by the account history index are answered
+// from it, even if their state was pruned.
+func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*big.Int, error) {
+	if account, err := s.b.AccountAt(ctx, address, blockNrOrHash); err != nil {
+		return nil, err
+	} else if account != nil {
+		return account.Balance, nil
+	}
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -503,14 +771,14 @@ func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Add
 }
 
 // Balance returns the amount of aqua for the given address in the state of the
-// given block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta
-// block numbers are also allowed.
-func (s *PublicBlockChainAPI) Balance(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*big.Float, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
-	if state == nil || err != nil {
+// given block, selected by number or hash. The rpc.LatestBlockNumber,
+// rpc.PendingBlockNumber and rpc.EarliestBlockNumber meta block numbers are
+// also allowed.
+func (s *PublicBlockChainAPI) Balance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*big.Float, error) {
+	b, err := s.GetBalance(ctx, address, blockNrOrHash)
+	if b == nil || err != nil {
 		return nil, err
 	}
-	b := state.GetBalance(address)
 	return new(big.Float).Quo(new(big.Float).SetInt(b), big.NewFloat(params.Aqua)), nil
 }
 
@@ -541,6 +809,33 @@ func (s *PublicBlockChainAPI) GetBlockByHash(ctx context.Context, blockHash comm
 	return nil, err
 }
 
+// GetHeaderByNumber returns the requested canonical block header. When blockNr
+// is -1 the chain head is returned. Unlike GetBlockByNumber it doesn't need the
+// block body, so it is also served by header only nodes.
+func (s *PublicBlockChainAPI) GetHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (map[string]interface{}, error) {
+	header, err := s.b.HeaderByNumber(ctx, blockNr)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	response := s.rpcOutputHeader(types.CopyHeader(header))
+	if blockNr == rpc.PendingBlockNumber {
+		// Pending headers need to nil out a few fields
+		for _, field := range []string{"hash", "nonce", "miner"} {
+			response[field] = nil
+		}
+	}
+	return response, nil
+}
+
+// GetHeaderByHash returns the requested block header.
+func (s *PublicBlockChainAPI) GetHeaderByHash(ctx context.Context, blockHash common.Hash) (map[string]interface{}, error) {
+	header, err := s.b.HeaderByHash(ctx, blockHash)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	return s.rpcOutputHeader(types.CopyHeader(header)), nil
+}
+
 // GetUncleByBlockNumberAndIndex returns the uncle block for the given block hash and index. When fullTx is true
 // all transactions in the block are returned in full detail, otherwise only the transaction hash is returned.
 func (s *PublicBlockChainAPI) GetUncleByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (map[string]interface{}, error) {
@@ -591,9 +886,10 @@ func (s *PublicBlockChainAPI) GetUncleCountByBlockHash(ctx context.Context, bloc
 	return nil
 }
 
-// GetCode returns the code stored at the given address in the state for the given block number.
-func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+// GetCode returns the code stored at the given address in the state for the given
+// block, selected by number or hash.
+func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -602,10 +898,11 @@ func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Addres
 }
 
 // GetStorageAt returns the storage from the state at the given address, key and
-// block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block
-// numbers are also allowed.
-func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+// block, selected by number or hash. The rpc.LatestBlockNumber,
+// rpc.PendingBlockNumber and rpc.EarliestBlockNumber meta block numbers are
+// also allowed.
+func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -613,6 +910,81 @@ func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.A
 	return res[:], state.Error()
 }
 
+// AccountResult is the merkle proof of an account and of some of its storage
+// slots, in the EIP-1186 format.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []string        `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the merkle proof of a storage slot.
+type StorageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+	Proof []string     `json:"proof"`
+}
+
+// GetProof returns the merkle proof of an account and of the given storage
+// slots against the state root of the given block, selected by number or hash.
+// Proofs of absent accounts and slots prove their absence.
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*AccountResult, error) {
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	var (
+		storageTrie  = state.StorageTrie(address)
+		storageHash  = types.EmptyRootHash
+		codeHash     = state.GetCodeHash(address)
+		storageProof = make([]StorageResult, len(storageKeys))
+	)
+	if storageTrie != nil {
+		storageHash = storageTrie.Hash()
+	} else {
+		// No storage trie means no account, whose code hash is the one of no code
+		codeHash = crypto.Keccak256Hash(nil)
+	}
+	for i, key := range storageKeys {
+		if storageTrie == nil {
+			storageProof[i] = StorageResult{key, &hexutil.Big{}, []string{}}
+			continue
+		}
+		proof, err := state.GetStorageProof(address, common.HexToHash(key))
+		if err != nil {
+			return nil, err
+		}
+		value := state.GetState(address, common.HexToHash(key)).Big()
+		storageProof[i] = StorageResult{key, (*hexutil.Big)(value), toHexSlice(proof)}
+	}
+	accountProof, err := state.GetProof(address)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(accountProof),
+		Balance:      (*hexutil.Big)(state.GetBalance(address)),
+		CodeHash:     codeHash,
+		Nonce:        hexutil.Uint64(state.GetNonce(address)),
+		StorageHash:  storageHash,
+		StorageProof: storageProof,
+	}, state.Error()
+}
+
+// toHexSlice encodes a list of byte slices as hex strings.
+func toHexSlice(b [][]byte) []string {
+	r := make([]string, len(b))
+	for i := range b {
+		r[i] = hexutil.Encode(b[i])
+	}
+	return r
+}
+
 // CallArgs represents the arguments for a call.
 type CallArgs struct {
 	From     common.Address  `json:"from"`
@@ -623,13 +995,105 @@ type CallArgs struct {
 	Data     hexutil.Bytes   `json:"data"`
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config) ([]byte, uint64, bool, error) {
+// ToMessage converts the call arguments into a message, setting the default
+// gas and gas price if none were set.
+func (args *CallArgs) ToMessage() types.Message {
+	gas, gasPrice := uint64(args.Gas), args.GasPrice.ToInt()
+	if gas == 0 {
+		gas = 50000000
+	}
+	if gasPrice.Sign() == 0 {
+		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
+	}
+	return types.NewMessage(args.From, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+}
+
+// OverrideAccount holds the fields of an account to override for a call. State
+// replaces the whole storage of the account, StateDiff only the given slots, so
+// they can't be both set.
+type OverrideAccount struct {
+	Nonce     *hexutil.Uint64              `json:"nonce"`
+	Code      *hexutil.Bytes               `json:"code"`
+	Balance   *hexutil.Big                 `json:"balance"`
+	State     *map[common.Hash]common.Hash `json:"state"`
+	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
+}
+
+// StateOverride is the set of accounts to override for a call.
+type StateOverride map[common.Address]OverrideAccount
+
+// Apply overrides the accounts in the given state.
+func (diff *StateOverride) Apply(state *state.StateDB) error {
+	if diff == nil {
+		return nil
+	}
+	for addr, account := range *diff {
+		if account.State != nil && account.StateDiff != nil {
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+		}
+		if account.Nonce != nil {
+			state.SetNonce(addr, uint64(*account.Nonce))
+		}
+		if account.Code != nil {
+			state.SetCode(addr, *account.Code)
+		}
+		if account.Balance != nil {
+			state.SetBalance(addr, account.Balance.ToInt())
+		}
+		if account.State != nil {
+			state.SetStorage(addr, *account.State)
+		}
+		if account.StateDiff != nil {
+			for key, value := range *account.StateDiff {
+				state.SetState(addr, key, value)
+			}
+		}
+	}
+	return nil
+}
+
+// BlockOverrides holds the fields of the block to override for a call, for
+// testing contracts reading them.
+type BlockOverrides struct {
+	Number     *hexutil.Big    `json:"number"`
+	Difficulty *hexutil.Big    `json:"difficulty"`
+	Time       *hexutil.Big    `json:"time"`
+	GasLimit   *hexutil.Uint64 `json:"gasLimit"`
+	Coinbase   *common.Address `json:"coinbase"`
+}
+
+// Apply returns a copy of the header with the fields overridden.
+func (diff *BlockOverrides) Apply(header *types.Header) *types.Header {
+	if diff == nil {
+		return header
+	}
+	header = types.CopyHeader(header)
+	if diff.Number != nil {
+		header.Number = new(big.Int).Set(diff.Number.ToInt())
+	}
+	if diff.Difficulty != nil {
+		header.Difficulty = new(big.Int).Set(diff.Difficulty.ToInt())
+	}
+	if diff.Time != nil {
+		header.Time = new(big.Int).Set(diff.Time.ToInt())
+	}
+	if diff.GasLimit != nil {
+		header.GasLimit = uint64(*diff.GasLimit)
+	}
+	if diff.Coinbase != nil {
+		header.Coinbase = *diff.Coinbase
+	}
+	return header
+}
+
+func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides, vmCfg vm.Config) ([]byte, uint64, bool, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
-	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	state, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, 0, false, err
 	}
+	header = blockOverrides.Apply(header)
 	// Set sender address or use a default if none specified
 	addr := args.From
 	if addr == (common.Address{}) {
@@ -639,17 +1103,10 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 			}
 		}
 	}
-	// Set default gas & gas price if none were set
-	gas, gasPrice := uint64(args.Gas), args.GasPrice.ToInt()
-	if gas == 0 {
-		gas = 50000000
-	}
-	if gasPrice.Sign() == 0 {
-		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
-	}
+	args.From = addr
 
 	// Create new call message
-	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+	msg := args.ToMessage()
 
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -668,6 +1125,11 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if err != nil {
 		return nil, 0, false, err
 	}
+	// Override the state after the EVM is set up, so that the balance of the
+	// sender isn't raised over an override
+	if err := overrides.Apply(state); err != nil {
+		return nil, 0, false, err
+	}
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
 	go func() {
@@ -685,16 +1147,50 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	return res, gas, failed, err
 }
 
-// Call executes the given transaction on the state for the given block number.
-// It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	result, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{DisableGasMetering: true})
+// gasCapError is returned by the calls asking for more gas than the RPC gas cap.
+type gasCapError struct{ gas, cap uint64 }
+
+func (e *gasCapError) ErrorCode() int { return -32008 }
+
+func (e *gasCapError) Error() string {
+	return fmt.Sprintf("gas %d exceeds the RPC gas cap of %d", e.gas, e.cap)
+}
+
+// Call executes the given transaction on the state for the given block, selected
+// by number or hash. It doesn't make and changes in the state/blockchain and is
+// useful to execute and retrieve values. The state and the block fields may be
+// overridden for the call.
+//
+// Calls are unmetered unless a call gas cap is configured, in which case they
+// are metered and may use up to the cap, by default.
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides) (hexutil.Bytes, error) {
+	vmCfg := vm.Config{DisableGasMetering: true}
+	if cap := s.b.RPCCallGasCap(); cap > 0 {
+		if uint64(args.Gas) > cap {
+			return nil, &gasCapError{uint64(args.Gas), cap}
+		}
+		if args.Gas == 0 {
+			args.Gas = hexutil.Uint64(cap)
+		}
+		vmCfg = vm.Config{}
+	}
+	result, _, _, err := s.doCall(ctx, args, blockNrOrHash, overrides, blockOverrides, vmCfg)
 	return (hexutil.Bytes)(result), err
 }
 
 // EstimateGas returns an estimate of the amount of gas needed to execute the
-// given transaction against the current pending block.
-func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (hexutil.Uint64, error) {
+// given transaction against the given block, or the current pending block if
+// omitted. The state and the block fields may be overridden for the estimate.
+// Under an RPC gas cap, estimates are searched up to the cap.
+func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides) (hexutil.Uint64, error) {
+	bnh := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	if blockNrOrHash != nil {
+		bnh = *blockNrOrHash
+	}
+	gasCap := s.b.RPCGasCap()
+	if gasCap > 0 && uint64(args.Gas) > gasCap {
+		return 0, &gasCapError{uint64(args.Gas), gasCap}
+	}
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
 		lo  uint64 = params.TxGas - 1
@@ -704,12 +1200,15 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	if uint64(args.Gas) >= params.TxGas {
 		hi = uint64(args.Gas)
 	} else {
-		// Retrieve the current pending block to act as the gas ceiling
-		block, err := s.b.BlockByNumber(ctx, rpc.PendingBlockNumber)
+		// Retrieve the selected block to act as the gas ceiling
+		_, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, bnh)
 		if err != nil {
 			return 0, err
 		}
-		hi = block.GasLimit()
+		hi = blockOverrides.Apply(header).GasLimit
+		if gasCap > 0 && hi > gasCap {
+			hi = gasCap
+		}
 	}
 	cap = hi
 
@@ -717,7 +1216,7 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	executable := func(gas uint64) bool {
 		args.Gas = hexutil.Uint64(gas)
 
-		_, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{})
+		_, _, failed, err := s.doCall(ctx, args, bnh, overrides, blockOverrides, vm.Config{})
 		if err != nil || failed {
 			return false
 		}
@@ -759,7 +1258,7 @@ type StructLogRes struct {
 	Gas     uint64             `json:"gas"`
 	GasCost uint64             `json:"gasCost"`
 	Depth   int                `json:"depth"`
-	Error   error              `json:"error,omitempty"`
+	Error   string             `json:"error,omitempty"`
 	Stack   *[]string          `json:"stack,omitempty"`
 	Memory  *[]string          `json:"memory,omitempty"`
 	Storage *map[string]string `json:"storage,omitempty"`
@@ -775,7 +1274,9 @@ func FormatLogs(logs []vm.StructLog) []StructLogRes {
 			Gas:     trace.Gas,
 			GasCost: trace.GasCost,
 			Depth:   trace.Depth,
-			Error:   trace.Err,
+		}
+		if trace.Err != nil {
+			formatted[index].Error = trace.Err.Error()
 		}
 		if trace.Stack != nil {
 			stack := make([]string, len(trace.Stack))
@@ -802,17 +1303,63 @@ func FormatLogs(logs []vm.StructLog) []StructLogRes {
 	return formatted
 }
 
-// rpcOutputBlock converts the given block to the RPC output which depends on fullTx. If inclTx is true transactions are
-// returned. When fullTx is true the returned block contains full transaction details, otherwise it will only contain
-// transaction hashes.
-func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
-	head := b.Header() // copies the header once
+// StandardLogRes is a single execution step in the EIP-3155 trace format, which
+// is understood by most third party EVM tooling.
+type StandardLogRes struct {
+	Pc      uint64         `json:"pc"`
+	Op      byte           `json:"op"`
+	Gas     hexutil.Uint64 `json:"gas"`
+	GasCost hexutil.Uint64 `json:"gasCost"`
+	MemSize int            `json:"memSize"`
+	Stack   []*hexutil.Big `json:"stack"`
+	Depth   int            `json:"depth"`
+	OpName  string         `json:"opName"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// StandardTraceResult is a transaction trace in the EIP-3155 format, the steps
+// followed by the summary of the execution.
+type StandardTraceResult struct {
+	Steps   []StandardLogRes `json:"steps"`
+	Output  hexutil.Bytes    `json:"output"`
+	GasUsed hexutil.Uint64   `json:"gasUsed"`
+	Pass    bool             `json:"pass"`
+}
+
+// FormatStandardLogs converts EVM returned structured logs into the EIP-3155
+// trace format. Stack values are emitted without padding, bottom first.
+func FormatStandardLogs(logs []vm.StructLog) []StandardLogRes {
+	formatted := make([]StandardLogRes, len(logs))
+	for index, trace := range logs {
+		formatted[index] = StandardLogRes{
+			Pc:      trace.Pc,
+			Op:      byte(trace.Op),
+			Gas:     hexutil.Uint64(trace.Gas),
+			GasCost: hexutil.Uint64(trace.GasCost),
+			MemSize: trace.MemorySize,
+			Stack:   make([]*hexutil.Big, len(trace.Stack)),
+			Depth:   trace.Depth,
+			OpName:  trace.Op.String(),
+		}
+		for i, stackValue := range trace.Stack {
+			formatted[index].Stack[i] = (*hexutil.Big)(stackValue)
+		}
+		if trace.Err != nil {
+			formatted[index].Error = trace.Err.Error()
+		}
+	}
+	return formatted
+}
+
+// rpcOutputHeader converts the given header to the RPC output.
+func (s *PublicBlockChainAPI) rpcOutputHeader(head *types.Header) map[string]interface{} {
 	if head.Version == 0 {
 		head.Version = s.b.ChainConfig().GetBlockVersion(head.Number)
 	}
+	hash := head.Hash()
 	fields := map[string]interface{}{
 		"number":           (*hexutil.Big)(head.Number),
-		"hash":             b.Hash(),
+		"hash":             hash,
 		"parentHash":       head.ParentHash,
 		"nonce":            head.Nonce,
 		"mixHash":          head.MixDigest,
@@ -821,9 +1368,8 @@ func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx
 		"stateRoot":        head.Root,
 		"miner":            head.Coinbase,
 		"difficulty":       (*hexutil.Big)(head.Difficulty),
-		"totalDifficulty":  (*hexutil.Big)(s.b.GetTd(b.Hash())),
+		"totalDifficulty":  (*hexutil.Big)(s.b.GetTd(hash)),
 		"extraData":        hexutil.Bytes(head.Extra),
-		"size":             hexutil.Uint64(b.Size()),
 		"gasLimit":         hexutil.Uint64(head.GasLimit),
 		"gasUsed":          hexutil.Uint64(head.GasUsed),
 		"timestamp":        (*hexutil.Big)(head.Time),
@@ -831,6 +1377,19 @@ func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx
 		"receiptsRoot":     head.ReceiptHash,
 		"version":          head.Version,
 	}
+	if head.BaseFee != nil {
+		fields["baseFeePerGas"] = (*hexutil.Big)(head.BaseFee)
+	}
+	return fields
+}
+
+// rpcOutputBlock converts the given block to the RPC output which depends on fullTx. If inclTx is true transactions are
+// returned. When fullTx is true the returned block contains full transaction details, otherwise it will only contain
+// transaction hashes.
+func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
+	fields := s.rpcOutputHeader(b.Header()) // copies the header once
+	fields["size"] = hexutil.Uint64(b.Size())
+
 	if inclTx {
 		formatTx := func(tx *types.Transaction) (interface{}, error) {
 			return tx.Hash(), nil
@@ -880,14 +1439,21 @@ type RPCTransaction struct {
 	V                *hexutil.Big    `json:"v"`
 	R                *hexutil.Big    `json:"r"`
 	S                *hexutil.Big    `json:"s"`
+
+	// Dynamic fee transactions only
+	Type      *hexutil.Uint64 `json:"type,omitempty"`
+	ChainID   *hexutil.Big    `json:"chainId,omitempty"`
+	GasFeeCap *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	GasTipCap *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
-// representation, with the given location metadata set (if available).
-func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64) *RPCTransaction {
+// representation, with the given location metadata set (if available). The gas
+// price of a mined dynamic fee transaction is the one it paid in its block.
+func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64, baseFee *big.Int) *RPCTransaction {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.NewFeeMarketSigner(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 	v, r, s := tx.RawSignatureValues()
@@ -910,12 +1476,22 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		result.BlockNumber = (*hexutil.Big)(new(big.Int).SetUint64(blockNumber))
 		result.TransactionIndex = hexutil.Uint(index)
 	}
+	if tx.Type() != types.LegacyTxType {
+		txType := hexutil.Uint64(tx.Type())
+		result.Type = &txType
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+		result.GasFeeCap = (*hexutil.Big)(tx.GasFeeCap())
+		result.GasTipCap = (*hexutil.Big)(tx.GasTipCap())
+		if baseFee != nil && blockHash != (common.Hash{}) {
+			result.GasPrice = (*hexutil.Big)(tx.EffectiveGasPrice(baseFee))
+		}
+	}
 	return result
 }
 
 // newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
 func newRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
-	return newRPCTransaction(tx, common.Hash{}, 0, 0)
+	return newRPCTransaction(tx, common.Hash{}, 0, 0, nil)
 }
 
 // newRPCTransactionFromBlockIndex returns a transaction that will serialize to the RPC representation.
@@ -924,7 +1500,7 @@ func newRPCTransactionFromBlockIndex(b *types.Block, index uint64) *RPCTransacti
 	if index >= uint64(len(txs)) {
 		return nil
 	}
-	return newRPCTransaction(txs[index], b.Hash(), b.NumberU64(), index)
+	return newRPCTransaction(txs[index], b.Hash(), b.NumberU64(), index, b.BaseFee())
 }
 
 // newRPCRawTransactionFromBlockIndex returns the bytes of a transaction given a block and a transaction index.
@@ -933,7 +1509,7 @@ func newRPCRawTransactionFromBlockIndex(b *types.Block, index uint64) hexutil.By
 	if index >= uint64(len(txs)) {
 		return nil
 	}
-	blob, _ := rlp.EncodeToBytes(txs[index])
+	blob, _ := txs[index].MarshalBinary()
 	return blob
 }
 
@@ -951,11 +1527,12 @@ func newRPCTransactionFromBlockHash(b *types.Block, hash common.Hash) *RPCTransa
 type PublicTransactionPoolAPI struct {
 	b         Backend
 	nonceLock *AddrLocker
+	watcher   *TxWatcher
 }
 
 // NewPublicTransactionPoolAPI creates a new RPC service with methods specific for the transaction pool.
-func NewPublicTransactionPoolAPI(b Backend, nonceLock *AddrLocker) *PublicTransactionPoolAPI {
-	return &PublicTransactionPoolAPI{b, nonceLock}
+func NewPublicTransactionPoolAPI(b Backend, nonceLock *AddrLocker, watcher *TxWatcher) *PublicTransactionPoolAPI {
+	return &PublicTransactionPoolAPI{b, nonceLock, watcher}
 }
 
 // GetBlockTransactionCountByNumber returns the number of transactions in the block with the given block number.
@@ -1008,9 +1585,17 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByBlockHashAndIndex(ctx cont
 	return nil
 }
 
-// GetTransactionCount returns the number of transactions the given address has sent for the given block number
-func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*hexutil.Uint64, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+// GetTransactionCount returns the number of transactions the given address has
+// sent for the given block, selected by number or hash. Past blocks covered by
+// the account history index are answered from it, even if their state was pruned.
+func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
+	if account, err := s.b.AccountAt(ctx, address, blockNrOrHash); err != nil {
+		return nil, err
+	} else if account != nil {
+		nonce := account.Nonce
+		return (*hexutil.Uint64)(&nonce), nil
+	}
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -1022,7 +1607,11 @@ func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, addr
 func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) *RPCTransaction {
 	// Try to return an already finalized transaction
 	if tx, blockHash, blockNumber, index := core.GetTransaction(s.b.ChainDb(), hash); tx != nil {
-		return newRPCTransaction(tx, blockHash, blockNumber, index)
+		var baseFee *big.Int
+		if header, err := s.b.HeaderByHash(ctx, blockHash); err == nil && header != nil {
+			baseFee = header.BaseFee
+		}
+		return newRPCTransaction(tx, blockHash, blockNumber, index, baseFee)
 	}
 	// No finalized transaction, try to retrieve it from the pool
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
@@ -1043,8 +1632,8 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 			return nil, nil
 		}
 	}
-	// Serialize to RLP and return
-	return rlp.EncodeToBytes(tx)
+	// Serialize to the canonical encoding and return
+	return tx.MarshalBinary()
 }
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
@@ -1060,18 +1649,57 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if len(receipts) <= int(index) {
 		return nil, errors.New("unknown receipt")
 	}
-	receipt := receipts[index]
+	return marshalReceipt(receipts[index], blockHash, blockNumber, tx, index), nil
+}
 
+// GetBlockReceipts returns the receipts of all the transactions included in the
+// block selected by number or hash, in the order of the transactions.
+func (s *PublicTransactionPoolAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	var (
+		block *types.Block
+		err   error
+	)
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		block, err = s.b.BlockByNumber(ctx, blockNr)
+	} else if hash, ok := blockNrOrHash.Hash(); ok {
+		block, err = s.b.GetBlock(ctx, hash)
+		if block != nil && blockNrOrHash.RequireCanonical && core.GetCanonicalHash(s.b.ChainDb(), block.NumberU64()) != hash {
+			return nil, ErrNonCanonicalHash
+		}
+	} else {
+		return nil, errors.New("invalid arguments; neither block nor hash specified")
+	}
+	if block == nil || err != nil {
+		return nil, err
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipts of block #%d unavailable: have %d, want %d", block.NumberU64(), len(receipts), len(txs))
+	}
+	fields := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		fields[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), txs[i], uint64(i))
+	}
+	return fields, nil
+}
+
+// marshalReceipt converts the receipt of the index'th transaction of a block
+// into the JSON fields returned by the receipt RPC methods.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, tx *types.Transaction, index uint64) map[string]interface{} {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.NewFeeMarketSigner(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
+		"transactionHash":   tx.Hash(),
 		"transactionIndex":  hexutil.Uint64(index),
 		"from":              from,
 		"to":                tx.To(),
@@ -1095,7 +1723,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -1127,20 +1755,22 @@ type SendTxArgs struct {
 	// newer name and should be preferred by clients.
 	Data  *hexutil.Bytes `json:"data"`
 	Input *hexutil.Bytes `json:"input"`
+
+	// Setting either fee creates a dynamic fee transaction, see params.FeeMarketConfig
+	MaxFeePerGas         *hexutil.Big `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big `json:"maxPriorityFeePerGas"`
+	ChainID              *hexutil.Big `json:"chainId"`
+}
+
+// dynamicFee reports whether the arguments describe a dynamic fee transaction.
+func (args *SendTxArgs) dynamicFee() bool {
+	return args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
 func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
-	if args.Gas == nil {
-		args.Gas = new(hexutil.Uint64)
-		*(*uint64)(args.Gas) = 90000
-	}
-	if args.GasPrice == nil {
-		price, err := b.SuggestPrice(ctx)
-		if err != nil {
-			return err
-		}
-		args.GasPrice = (*hexutil.Big)(price)
+	if err := args.setGasDefaults(ctx, b); err != nil {
+		return err
 	}
 	if args.Value == nil {
 		args.Value = new(hexutil.Big)
@@ -1170,6 +1800,56 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 	return nil
 }
 
+// setGasDefaults fills in the gas allowance and the gas price or fees, if
+// unspecified.
+func (args *SendTxArgs) setGasDefaults(ctx context.Context, b Backend) error {
+	if args.Gas == nil {
+		args.Gas = new(hexutil.Uint64)
+		*(*uint64)(args.Gas) = 90000
+	}
+	if args.dynamicFee() {
+		return args.setFeeDefaults(ctx, b)
+	}
+	if args.GasPrice == nil {
+		price, err := b.SuggestPrice(ctx)
+		if err != nil {
+			return err
+		}
+		args.GasPrice = (*hexutil.Big)(price)
+	}
+	return nil
+}
+
+// setFeeDefaults fills in the fees of a dynamic fee transaction: the suggested
+// gas price as tip, and room for the base fee to double on top of it.
+func (args *SendTxArgs) setFeeDefaults(ctx context.Context, b Backend) error {
+	if args.GasPrice != nil {
+		return errors.New("both gasPrice and maxFeePerGas or maxPriorityFeePerGas specified")
+	}
+	config, head := b.ChainConfig(), b.CurrentBlock().Header()
+	if !config.IsFeeMarket(new(big.Int).Add(head.Number, big.NewInt(1))) {
+		return types.ErrTxTypeNotSupported
+	}
+	if args.MaxPriorityFeePerGas == nil {
+		tip, err := b.SuggestPrice(ctx)
+		if err != nil {
+			return err
+		}
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tip)
+	}
+	if args.MaxFeePerGas == nil {
+		feeCap := new(big.Int).Mul(misc.CalcBaseFee(config, head), big.NewInt(2))
+		args.MaxFeePerGas = (*hexutil.Big)(feeCap.Add(feeCap, args.MaxPriorityFeePerGas.ToInt()))
+	}
+	if args.MaxPriorityFeePerGas.ToInt().Cmp(args.MaxFeePerGas.ToInt()) > 0 {
+		return core.ErrTipAboveFeeCap
+	}
+	if args.ChainID == nil {
+		args.ChainID = (*hexutil.Big)(config.ChainId)
+	}
+	return nil
+}
+
 func (args *SendTxArgs) toTransaction() *types.Transaction {
 	var input []byte
 	if args.Data != nil {
@@ -1177,6 +1857,9 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 	} else if args.Input != nil {
 		input = *args.Input
 	}
+	if args.dynamicFee() {
+		return types.NewDynamicFeeTransaction((*big.Int)(args.ChainID), uint64(*args.Nonce), args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.MaxPriorityFeePerGas), (*big.Int)(args.MaxFeePerGas), input)
+	}
 	if args.To == nil {
 		return types.NewContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
 	}
@@ -1185,6 +1868,10 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 
 // submitTransaction is a helper function that submits tx to txPool and logs a message.
 func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
+	// Refuse transactions replayable on other chains once this one protects against it
+	if !tx.Protected() && !b.UnprotectedAllowed() && b.ChainConfig().IsEIP155(b.CurrentBlock().Number()) {
+		return common.Hash{}, errUnprotectedTx
+	}
 	if err := b.SendTx(ctx, tx); err != nil {
 		return common.Hash{}, err
 	}
@@ -1241,12 +1928,66 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 
 // SendRawTransaction will add the signed transaction to the transaction pool.
 // The sender is responsible for signing the transaction and using the correct nonce.
-func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
+//
+// With watch set in the options, the status changes of the transaction, up to
+// the requested confirmation depth, are reported to the watchedTransactions
+// subscribers and to the webhook of the node.
+func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes, opts *SendTxOptions) (common.Hash, error) {
 	tx := new(types.Transaction)
-	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
 		return common.Hash{}, err
 	}
-	return submitTransaction(ctx, s.b, tx)
+	if opts == nil || !opts.Watch {
+		return submitTransaction(ctx, s.b, tx)
+	}
+	var confirmations uint64
+	if opts.Confirmations != nil {
+		confirmations = uint64(*opts.Confirmations)
+	}
+	if confirmations > maxWatchConfirmations {
+		return common.Hash{}, fmt.Errorf("confirmations above %d", maxWatchConfirmations)
+	}
+	if s.watcher.full() {
+		return common.Hash{}, ErrTooManyWatched
+	}
+	signer := types.MakeSigner(s.b.ChainConfig(), s.b.CurrentBlock().Number())
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	hash, err := submitTransaction(ctx, s.b, tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return hash, s.watcher.watch(tx, from, confirmations)
+}
+
+// WatchedTransactions creates a subscription reporting the status changes of
+// the transactions submitted with watch set.
+func (s *PublicTransactionPoolAPI) WatchedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan TxStatusEvent, 64)
+		sub := s.watcher.SubscribeTxStatus(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				notifier.Notify(rpcSub.ID, ev)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
 }
 
 // Sign calculates an ECDSA signature for:
@@ -1287,8 +2028,8 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if args.Gas == nil {
 		return nil, fmt.Errorf("gas not specified")
 	}
-	if args.GasPrice == nil {
-		return nil, fmt.Errorf("gasPrice not specified")
+	if args.GasPrice == nil && args.MaxFeePerGas == nil {
+		return nil, fmt.Errorf("gasPrice or maxFeePerGas not specified")
 	}
 	if args.Nonce == nil {
 		return nil, fmt.Errorf("nonce not specified")
@@ -1300,7 +2041,7 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -1319,7 +2060,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 	for _, tx := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if tx.Protected() {
-			signer = types.NewEIP155Signer(tx.ChainId())
+			signer = types.NewFeeMarketSigner(tx.ChainId())
 		}
 		from, _ := types.Sender(signer, tx)
 		if _, err := s.b.AccountManager().Find(accounts.Account{Address: from}); err == nil {
@@ -1347,13 +2088,15 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 	for _, p := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if p.Protected() {
-			signer = types.NewEIP155Signer(p.ChainId())
+			signer = types.NewFeeMarketSigner(p.ChainId())
 		}
 		wantSigHash := signer.Hash(matchTx)
 
 		if pFrom, err := types.Sender(signer, p); err == nil && pFrom == sendArgs.From && signer.Hash(p) == wantSigHash {
 			// Match. Re-sign and send the transaction.
-			if gasPrice != nil {
+			if gasPrice != nil && sendArgs.dynamicFee() {
+				sendArgs.MaxFeePerGas = gasPrice
+			} else if gasPrice != nil {
 				sendArgs.GasPrice = gasPrice
 			}
 			if gasLimit != nil {
@@ -1373,6 +2116,50 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 	return common.Hash{}, fmt.Errorf("Transaction %#x not found", matchTx.Hash())
 }
 
+// CancelTransaction replaces a pending transaction of a local account with a zero
+// value transfer to itself, using the same nonce and a higher gas price. If no
+// gas price is given, the price of the original transaction is bumped by the
+// default replacement price bump of the transaction pool, or raised to the
+// suggested gas price if that's higher.
+func (s *PublicTransactionPoolAPI) CancelTransaction(ctx context.Context, hash common.Hash, gasPrice *hexutil.Big) (common.Hash, error) {
+	tx := s.b.GetPoolTransaction(hash)
+	if tx == nil {
+		return common.Hash{}, fmt.Errorf("transaction %#x not found in pool", hash)
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.NewFeeMarketSigner(tx.ChainId())
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	price := (*big.Int)(gasPrice)
+	if price == nil {
+		price = new(big.Int).Mul(tx.GasPrice(), big.NewInt(100+int64(core.DefaultTxPoolConfig.PriceBump)))
+		price.Div(price, big.NewInt(100))
+		price.Add(price, common.Big1)
+
+		suggested, err := s.b.SuggestPrice(ctx)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if suggested.Cmp(price) > 0 {
+			price = suggested
+		}
+	}
+	cancel := types.NewTransaction(tx.Nonce(), from, new(big.Int), params.TxGas, price, nil)
+	signed, err := s.sign(from, cancel)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := s.b.SendTx(ctx, signed); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted transaction cancellation", "cancelled", hash, "fullhash", signed.Hash().Hex(), "nonce", tx.Nonce())
+	return signed.Hash(), nil
+}
+
 // PublicDebugAPI is the collection of AquaChain APIs exposed over the public
 // debugging endpoint.
 type PublicDebugAPI struct {
@@ -1462,9 +2249,10 @@ func (api *PrivateDebugAPI) ChaindbCompact() error {
 	return nil
 }
 
-// SetHead rewinds the head of the blockchain to a previous block.
-func (api *PrivateDebugAPI) SetHead(number hexutil.Uint64) {
-	api.b.SetHead(uint64(number))
+// SetHead rewinds the head of the blockchain to a previous block, reinjecting
+// the transactions of the rewound blocks into the transaction pool.
+func (api *PrivateDebugAPI) SetHead(number hexutil.Uint64) error {
+	return api.b.SetHead(uint64(number))
 }
 
 // PublicNetAPI offers network related RPC methods
@@ -0,0 +1,591 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquaapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/accounts/keystore"
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/params"
+	"github.com/aquanetwork/aquachain/rlp"
+	"github.com/aquanetwork/aquachain/rpc"
+	"github.com/aquanetwork/aquachain/trie"
+)
+
+// The golden files in testdata/golden hold the RPC encoding of well known values,
+// both in the default and in the canonical (--rpc.strictjson) format. Any change
+// to them is a change of the RPC format integrators rely on. Regenerate them
+// with:
+//
+//	go test ./internal/aquaapi -run TestGolden -update
+var update = flag.Bool("update", false, "update the golden files")
+
+// testBackend is the backend shared by the API tests. It serves what its fields
+// are set to, leaving the rest of the backend unimplemented.
+type testBackend struct {
+	Backend
+	config      *params.ChainConfig // Chain config, params.TestChainConfig if nil
+	db          aquadb.Database
+	head        *types.Block
+	unprotected bool
+	sent        []*types.Transaction // Transactions submitted to the pool
+	pool        map[common.Hash]*types.Transaction
+	hook        string
+
+	gasCap     uint64
+	callGasCap uint64
+
+	// The state, either fixed or created afresh by newState for every request
+	state    *state.StateDB
+	header   *types.Header
+	newState func() (*state.StateDB, *types.Header)
+
+	block    *types.Block // Single block served by number or hash, with its receipts
+	receipts types.Receipts
+
+	heads event.Feed
+	txs   event.Feed
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	if b.config == nil {
+		return params.TestChainConfig
+	}
+	return b.config
+}
+
+func (b *testBackend) ChainDb() aquadb.Database { return b.db }
+
+func (b *testBackend) CurrentBlock() *types.Block { return b.head }
+
+func (b *testBackend) GetTd(hash common.Hash) *big.Int { return big.NewInt(131072) }
+
+func (b *testBackend) UnprotectedAllowed() bool { return b.unprotected }
+
+func (b *testBackend) TxWebhook() string { return b.hook }
+
+func (b *testBackend) RPCGasCap() uint64     { return b.gasCap }
+func (b *testBackend) RPCCallGasCap() uint64 { return b.callGasCap }
+
+func (b *testBackend) SendTx(ctx context.Context, tx *types.Transaction) error {
+	b.sent = append(b.sent, tx)
+	return nil
+}
+
+func (b *testBackend) GetPoolTransaction(hash common.Hash) *types.Transaction { return b.pool[hash] }
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	if b.newState != nil {
+		statedb, header := b.newState()
+		return statedb, header, nil
+	}
+	return b.state, b.header, nil
+}
+
+func (b *testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	return b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+}
+
+func (b *testBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	state.SetBalance(msg.From(), new(big.Int).Lsh(big.NewInt(1), 200))
+	context := core.NewEVMContext(msg, header, nil, &header.Coinbase)
+	return vm.NewEVM(context, state, b.ChainConfig(), vmCfg), state.Error, nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber || uint64(number) == b.block.NumberU64() {
+		return b.block, nil
+	}
+	return nil, nil
+}
+
+func (b *testBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	if hash == b.block.Hash() {
+		return b.block, nil
+	}
+	return nil, nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	if hash == b.block.Hash() {
+		return b.receipts, nil
+	}
+	return nil, nil
+}
+
+func (b *testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.heads.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Subscription {
+	return b.txs.Subscribe(ch)
+}
+
+// checkGolden compares the default and the canonical JSON encodings of the value
+// to the golden files of the given name.
+func checkGolden(t *testing.T, name string, v interface{}) {
+	plain, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("%s: failed to encode: %v", name, err)
+	}
+	canonical, err := rpc.CanonicalJSON(v)
+	if err != nil {
+		t.Fatalf("%s: failed to encode canonical: %v", name, err)
+	}
+	var strict bytes.Buffer
+	if err := json.Indent(&strict, canonical, "", "  "); err != nil {
+		t.Fatalf("%s: failed to indent canonical: %v", name, err)
+	}
+	for file, have := range map[string][]byte{
+		name + ".json":        plain,
+		name + ".strict.json": strict.Bytes(),
+	} {
+		path := filepath.Join("testdata", "golden", file)
+		have = append(have, '\n')
+		if *update {
+			if err := ioutil.WriteFile(path, have, 0644); err != nil {
+				t.Fatalf("%s: failed to update golden file: %v", file, err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: failed to read golden file: %v", file, err)
+		}
+		if !bytes.Equal(have, want) {
+			t.Errorf("%s: encoding mismatch\nhave: %s\nwant: %s", file, have, want)
+		}
+	}
+}
+
+// goldenBlock creates a deterministic block with a contract creation and a
+// value transfer.
+func goldenBlock() *types.Block {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	signer := types.NewEIP155Signer(params.TestChainConfig.ChainId)
+
+	create, _ := types.SignTx(types.NewContractCreation(0, new(big.Int), 100000, big.NewInt(params.Shannon), common.FromHex("0x6001600055")), signer, key)
+	transfer, _ := types.SignTx(types.NewTransaction(1, common.HexToAddress("0x0000000000000000000000000000000000000aaa"), big.NewInt(params.Aqua), params.TxGas, big.NewInt(2*params.Shannon), nil), signer, key)
+
+	header := &types.Header{
+		ParentHash: common.HexToHash("0x01"),
+		Coinbase:   common.HexToAddress("0x0000000000000000000000000000000000000bbb"),
+		Root:       common.HexToHash("0x02"),
+		Difficulty: big.NewInt(131072),
+		Number:     big.NewInt(100),
+		GasLimit:   4712388,
+		GasUsed:    74000,
+		Time:       big.NewInt(1525000000),
+		Extra:      []byte("golden"),
+		Version:    types.H_KECCAK256,
+	}
+	return types.NewBlock(header, types.Transactions{create, transfer}, nil, nil)
+}
+
+func TestGolden(t *testing.T) {
+	var (
+		api   = NewPublicBlockChainAPI(&testBackend{})
+		block = goldenBlock()
+	)
+	header, err := api.rpcOutputBlock(block, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "header", header)
+
+	full, err := api.rpcOutputBlock(block, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "block_fulltx", full)
+
+	checkGolden(t, "transaction_pending", newRPCPendingTransaction(block.Transactions()[1]))
+	checkGolden(t, "gas_price", big.NewInt(params.Shannon))
+}
+
+// Tests that struct logs are converted into the EIP-3155 step format.
+func TestFormatStandardLogs(t *testing.T) {
+	logs := []vm.StructLog{
+		{Pc: 0, Op: vm.PUSH1, Gas: 100, GasCost: 3, Depth: 1},
+		{Pc: 2, Op: vm.SLOAD, Gas: 97, GasCost: 200, MemorySize: 32, Stack: []*big.Int{big.NewInt(0), big.NewInt(255)}, Depth: 1, Err: vm.ErrOutOfGas},
+	}
+	blob, err := json.Marshal(FormatStandardLogs(logs))
+	if err != nil {
+		t.Fatalf("failed to encode steps: %v", err)
+	}
+	want := `[{"pc":0,"op":96,"gas":"0x64","gasCost":"0x3","memSize":0,"stack":[],"depth":1,"opName":"PUSH1"},` +
+		`{"pc":2,"op":84,"gas":"0x61","gasCost":"0xc8","memSize":32,"stack":["0x0","0xff"],"depth":1,"opName":"SLOAD","error":"out of fuel"}]`
+	if string(blob) != want {
+		t.Errorf("encoding mismatch:\nhave %s\nwant %s", blob, want)
+	}
+}
+
+// newCallBackend creates a backend serving a fresh state holding a single
+// contract for every call.
+func newCallBackend(contract common.Address) *testBackend {
+	return &testBackend{newState: func() (*state.StateDB, *types.Header) {
+		db, _ := aquadb.NewMemDatabase()
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+		// The contract returns the sum of its first storage slot and the timestamp
+		statedb.SetCode(contract, []byte{
+			byte(vm.PUSH1), 0, byte(vm.SLOAD),
+			byte(vm.TIMESTAMP), byte(vm.ADD),
+			byte(vm.PUSH1), 0, byte(vm.MSTORE),
+			byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+		})
+		statedb.SetState(contract, common.Hash{}, common.BigToHash(big.NewInt(5)))
+
+		return statedb, &types.Header{Number: big.NewInt(1), Time: big.NewInt(100), Difficulty: big.NewInt(1), GasLimit: 10000000}
+	}}
+}
+
+// Tests that calls run against the overridden state and block fields.
+func TestCallOverrides(t *testing.T) {
+	var (
+		contract = common.HexToAddress("0x0000000000000000000000000000000000000c0d")
+		other    = common.HexToAddress("0x0000000000000000000000000000000000000c0e")
+		api      = NewPublicBlockChainAPI(newCallBackend(contract))
+		latest   = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		slots    = func(value int64) *map[common.Hash]common.Hash {
+			return &map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(value))}
+		}
+		code = hexutil.Bytes{byte(vm.PUSH1), 42, byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN)}
+	)
+	tests := []struct {
+		to        common.Address
+		overrides *StateOverride
+		block     *BlockOverrides
+		want      int64
+	}{
+		{contract, nil, nil, 105},
+		{contract, &StateOverride{contract: {StateDiff: slots(7)}}, nil, 107},
+		{contract, &StateOverride{contract: {State: &map[common.Hash]common.Hash{}}}, nil, 100},
+		{contract, nil, &BlockOverrides{Time: (*hexutil.Big)(big.NewInt(1000))}, 1005},
+		{contract, &StateOverride{contract: {StateDiff: slots(1)}}, &BlockOverrides{Time: (*hexutil.Big)(big.NewInt(10))}, 11},
+		{other, &StateOverride{other: {Code: &code}}, nil, 42},
+	}
+	for i, tt := range tests {
+		to := tt.to
+		res, err := api.Call(context.Background(), CallArgs{From: common.Address{1}, To: &to}, latest, tt.overrides, tt.block)
+		if err != nil {
+			t.Fatalf("test %d: call failed: %v", i, err)
+		}
+		if have := new(big.Int).SetBytes(res).Int64(); have != tt.want {
+			t.Errorf("test %d: result mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+	conflicting := &StateOverride{contract: {State: slots(1), StateDiff: slots(2)}}
+	if _, err := api.Call(context.Background(), CallArgs{From: common.Address{1}, To: &contract}, latest, conflicting, nil); err == nil {
+		t.Errorf("conflicting storage overrides accepted")
+	}
+	gas, err := api.EstimateGas(context.Background(), CallArgs{From: common.Address{1}, To: &contract}, &latest, &StateOverride{contract: {StateDiff: slots(7)}}, nil)
+	if err != nil || gas <= hexutil.Uint64(params.TxGas) {
+		t.Errorf("gas estimate with overrides: have %d, err %v", gas, err)
+	}
+}
+
+// Tests that calls and estimates asking for more gas than their RPC gas caps are
+// rejected, that the others run under them, and that calls are only capped if
+// opted in.
+func TestCallGasCap(t *testing.T) {
+	var (
+		contract = common.HexToAddress("0x0000000000000000000000000000000000000c0d")
+		capped   = newCallBackend(contract)
+		free     = newCallBackend(contract)
+		api      = NewPublicBlockChainAPI(capped)
+		uncapped = NewPublicBlockChainAPI(free)
+		latest   = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	)
+	capped.gasCap, capped.callGasCap = 100000, 100000
+	free.gasCap = 100000
+	if _, err := uncapped.Call(context.Background(), CallArgs{From: common.Address{1}, To: &contract, Gas: 200000}, latest, nil, nil); err != nil {
+		t.Errorf("call without a call gas cap failed: %v", err)
+	}
+	res, err := api.Call(context.Background(), CallArgs{From: common.Address{1}, To: &contract}, latest, nil, nil)
+	if err != nil {
+		t.Fatalf("call under the cap failed: %v", err)
+	}
+	if have := new(big.Int).SetBytes(res).Int64(); have != 105 {
+		t.Errorf("result mismatch: have %d, want %d", have, 105)
+	}
+	_, err = api.Call(context.Background(), CallArgs{From: common.Address{1}, To: &contract, Gas: 100001}, latest, nil, nil)
+	if err, ok := err.(*gasCapError); !ok || err.ErrorCode() != -32008 {
+		t.Errorf("call over the cap: error mismatch: have %v, want gas cap error", err)
+	}
+	gas, err := api.EstimateGas(context.Background(), CallArgs{From: common.Address{1}, To: &contract}, &latest, nil, nil)
+	if err != nil || gas <= hexutil.Uint64(params.TxGas) || gas > 100000 {
+		t.Errorf("gas estimate under the cap: have %d, err %v", gas, err)
+	}
+	if _, err := api.EstimateGas(context.Background(), CallArgs{From: common.Address{1}, To: &contract, Gas: 200000}, &latest, nil, nil); err == nil {
+		t.Errorf("gas estimate over the cap accepted")
+	}
+}
+
+// Tests that the proofs of accounts and storage slots, present or not, verify
+// against the state root.
+func TestGetProof(t *testing.T) {
+	var (
+		db, _      = aquadb.NewMemDatabase()
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(db))
+		account    = common.Address{0xaa}
+		missing    = common.Address{0xbb}
+	)
+	statedb.SetBalance(account, big.NewInt(1000))
+	statedb.SetNonce(account, 3)
+	statedb.SetCode(account, []byte{byte(vm.STOP)})
+	statedb.SetState(account, common.Hash{1}, common.BigToHash(big.NewInt(7)))
+	statedb.SetState(common.Address{0xcc}, common.Hash{1}, common.BigToHash(big.NewInt(8)))
+
+	root, err := statedb.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	statedb.Database().TrieDB().Commit(root, false)
+	statedb, _ = state.New(root, statedb.Database())
+
+	api := NewPublicBlockChainAPI(&testBackend{state: statedb, header: &types.Header{Number: big.NewInt(1), Root: root}})
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+
+	// verify checks a proof, returning the proven value
+	verify := func(root common.Hash, key []byte, proof []string) []byte {
+		t.Helper()
+		proofDb, _ := aquadb.NewMemDatabase()
+		for _, node := range proof {
+			blob := hexutil.MustDecode(node)
+			proofDb.Put(crypto.Keccak256(blob), blob)
+		}
+		value, err, _ := trie.VerifyProof(root, crypto.Keccak256(key), proofDb)
+		if err != nil {
+			t.Fatalf("invalid proof: %v", err)
+		}
+		return value
+	}
+	res, err := api.GetProof(context.Background(), account, []string{common.Hash{1}.Hex(), common.Hash{2}.Hex()}, latest)
+	if err != nil {
+		t.Fatalf("failed to prove account: %v", err)
+	}
+	if res.Balance.ToInt().Int64() != 1000 || res.Nonce != 3 || res.CodeHash != crypto.Keccak256Hash([]byte{byte(vm.STOP)}) {
+		t.Errorf("account mismatch: balance %v, nonce %d, code hash %x", res.Balance, res.Nonce, res.CodeHash)
+	}
+	var acc state.Account
+	if err := rlp.DecodeBytes(verify(root, account[:], res.AccountProof), &acc); err != nil {
+		t.Fatalf("failed to decode proven account: %v", err)
+	}
+	if acc.Root != res.StorageHash || !bytes.Equal(acc.CodeHash, res.CodeHash[:]) {
+		t.Errorf("proven account mismatch: root %x, code hash %x", acc.Root, acc.CodeHash)
+	}
+	if have := res.StorageProof[0].Value.ToInt().Int64(); have != 7 {
+		t.Errorf("slot value mismatch: have %d, want %d", have, 7)
+	}
+	value, _ := rlp.EncodeToBytes(big.NewInt(7).Bytes())
+	if have := verify(res.StorageHash, common.Hash{1}.Bytes(), res.StorageProof[0].Proof); !bytes.Equal(have, value) {
+		t.Errorf("proven slot mismatch: have %x, want %x", have, value)
+	}
+	if have := verify(res.StorageHash, common.Hash{2}.Bytes(), res.StorageProof[1].Proof); have != nil || res.StorageProof[1].Value.ToInt().Sign() != 0 {
+		t.Errorf("absent slot proven with value %x", have)
+	}
+	// Proofs of absent accounts prove their absence
+	res, err = api.GetProof(context.Background(), missing, []string{common.Hash{1}.Hex()}, latest)
+	if err != nil {
+		t.Fatalf("failed to prove absent account: %v", err)
+	}
+	if have := verify(root, missing[:], res.AccountProof); have != nil {
+		t.Errorf("absent account proven with value %x", have)
+	}
+	if res.StorageHash != types.EmptyRootHash || len(res.StorageProof[0].Proof) != 0 {
+		t.Errorf("absent account storage mismatch: root %x, proof %v", res.StorageHash, res.StorageProof[0].Proof)
+	}
+}
+
+// Tests that transactions without replay protection are refused over RPC once
+// the chain enforces EIP-155, unless explicitly allowed.
+func TestSubmitUnprotected(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tx := types.NewTransaction(0, common.Address{1}, big.NewInt(1), params.TxGas, big.NewInt(1), nil)
+	unprotected, _ := types.SignTx(tx, types.HomesteadSigner{}, key)
+	protected, _ := types.SignTx(tx, types.NewEIP155Signer(params.TestChainConfig.ChainId), key)
+
+	before := *params.TestChainConfig
+	before.EIP155Block = big.NewInt(11)
+
+	tests := []struct {
+		config      *params.ChainConfig
+		unprotected bool
+		tx          *types.Transaction
+		err         error
+	}{
+		{params.TestChainConfig, false, protected, nil},
+		{params.TestChainConfig, false, unprotected, errUnprotectedTx},
+		{params.TestChainConfig, true, unprotected, nil},
+		{&before, false, unprotected, nil},
+	}
+	for i, tt := range tests {
+		b := &testBackend{
+			config:      tt.config,
+			head:        types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10)}),
+			unprotected: tt.unprotected,
+		}
+		if _, err := submitTransaction(context.Background(), b, tt.tx); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if sent := len(b.sent) == 1; sent != (tt.err == nil) {
+			t.Errorf("test %d: transaction submitted: %v", i, sent)
+		}
+	}
+}
+
+// Tests that the receipts of a whole block are returned in transaction order,
+// encoded the same as the receipts of single transactions.
+func TestGetBlockReceipts(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := types.NewEIP155Signer(params.TestChainConfig.ChainId)
+	tx0, _ := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, key)
+	tx1, _ := types.SignTx(types.NewContractCreation(1, big.NewInt(0), 100000, big.NewInt(1), []byte{0x00}), signer, key)
+
+	receipts := types.Receipts{
+		{Status: types.ReceiptStatusSuccessful, GasUsed: params.TxGas, CumulativeGasUsed: params.TxGas, TxHash: tx0.Hash()},
+		{Status: types.ReceiptStatusFailed, GasUsed: 60000, CumulativeGasUsed: params.TxGas + 60000, TxHash: tx1.Hash(), ContractAddress: common.Address{2}},
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(5)}, types.Transactions{tx0, tx1}, nil, receipts)
+
+	db, _ := aquadb.NewMemDatabase()
+	core.WriteCanonicalHash(db, common.Hash{0xff}, block.NumberU64())
+	api := &PublicTransactionPoolAPI{b: &testBackend{db: db, block: block, receipts: receipts}}
+
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	for _, sel := range []rpc.BlockNumberOrHash{
+		rpc.BlockNumberOrHashWithNumber(5),
+		rpc.BlockNumberOrHashWithHash(block.Hash(), false),
+	} {
+		fields, err := api.GetBlockReceipts(context.Background(), sel)
+		if err != nil {
+			t.Fatalf("%v: failed to retrieve receipts: %v", sel, err)
+		}
+		if len(fields) != len(receipts) {
+			t.Fatalf("%v: receipt count mismatch: have %d, want %d", sel, len(fields), len(receipts))
+		}
+		for i, tx := range block.Transactions() {
+			if hash := fields[i]["transactionHash"]; hash != tx.Hash() {
+				t.Errorf("%v: receipt %d: transaction hash mismatch: have %v, want %x", sel, i, hash, tx.Hash())
+			}
+			if index := fields[i]["transactionIndex"]; index != hexutil.Uint64(i) {
+				t.Errorf("%v: receipt %d: transaction index mismatch: have %v, want %d", sel, i, index, i)
+			}
+			if from := fields[i]["from"]; from != sender {
+				t.Errorf("%v: receipt %d: sender mismatch: have %v, want %x", sel, i, from, sender)
+			}
+			if hash := fields[i]["blockHash"]; hash != block.Hash() {
+				t.Errorf("%v: receipt %d: block hash mismatch: have %v, want %x", sel, i, hash, block.Hash())
+			}
+			if status := fields[i]["status"]; status != hexutil.Uint(receipts[i].Status) {
+				t.Errorf("%v: receipt %d: status mismatch: have %v, want %d", sel, i, status, receipts[i].Status)
+			}
+		}
+		if addr := fields[1]["contractAddress"]; addr != (common.Address{2}) {
+			t.Errorf("%v: contract address mismatch: have %v, want %x", sel, addr, common.Address{2})
+		}
+	}
+	// Unknown blocks are reported as missing, non-canonical ones as errors when
+	// canonicality is required.
+	if fields, err := api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(6)); fields != nil || err != nil {
+		t.Errorf("unknown block: have %v, %v, want nil, nil", fields, err)
+	}
+	if _, err := api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithHash(block.Hash(), true)); err != ErrNonCanonicalHash {
+		t.Errorf("non-canonical block: error mismatch: have %v, want %v", err, ErrNonCanonicalHash)
+	}
+	// Missing receipts must not be silently returned as a shorter list.
+	api.b.(*testBackend).receipts = receipts[:1]
+	if _, err := api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(5)); err == nil {
+		t.Errorf("partial receipts: expected error")
+	}
+}
+
+// Tests that wallet subscribers are notified of the keystore accounts created
+// and deleted, along with the accounts of the node.
+func TestWalletSubscription(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aquaapi-wallets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	am := accounts.NewManager(ks)
+	defer am.Close()
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("aqua", NewPublicAccountAPI(am)); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	notifications := make(chan *WalletNotification)
+	sub, err := client.Subscribe(context.Background(), "aqua", notifications, "wallets")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	expect := func(event string, want []common.Address) {
+		select {
+		case n := <-notifications:
+			if n.Event != event {
+				t.Errorf("event mismatch: have %s, want %s", n.Event, event)
+			}
+			if !reflect.DeepEqual(n.Accounts, want) {
+				t.Errorf("accounts mismatch: have %v, want %v", n.Accounts, want)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s notification timeout", event)
+		}
+	}
+	account, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	expect("arrived", []common.Address{account.Address})
+
+	if err := ks.Delete(account, ""); err != nil {
+		t.Fatalf("failed to delete account: %v", err)
+	}
+	expect("dropped", []common.Address{})
+}
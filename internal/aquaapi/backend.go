@@ -19,6 +19,7 @@ package aquaapi
 
 import (
 	"context"
+	"errors"
 	"math/big"
 
 	"github.com/aquanetwork/aquachain/accounts"
@@ -34,6 +35,16 @@ import (
 	"github.com/aquanetwork/aquachain/rpc"
 )
 
+var (
+	// ErrHeaderNotFound is returned if the block selected for a state query is
+	// not known (yet).
+	ErrHeaderNotFound = errors.New("header not found")
+
+	// ErrNonCanonicalHash is returned if a block selected by hash for a state
+	// query isn't canonical, while the caller required it to be.
+	ErrNonCanonicalHash = errors.New("hash is not currently canonical")
+)
+
 // Backend interface provides the common API services (that are provided by
 // both full and light clients) with access to necessary functions.
 type Backend interface {
@@ -41,15 +52,19 @@ type Backend interface {
 	Downloader() *downloader.Downloader
 	ProtocolVersion() int
 	SuggestPrice(ctx context.Context) (*big.Int, error)
+	FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error)
 	ChainDb() aquadb.Database
 	EventMux() *event.TypeMux
 	AccountManager() *accounts.Manager
 
 	// BlockChain API
-	SetHead(number uint64)
+	SetHead(number uint64) error
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
 	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
 	StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error)
+	StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error)
+	AccountAt(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*core.AccountState, error) // Nil if the block isn't covered by the account history
 	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
 	GetTd(blockHash common.Hash) *big.Int
@@ -69,11 +84,36 @@ type Backend interface {
 
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
+
+	// UnprotectedAllowed returns whether transactions without EIP-155 replay
+	// protection are accepted over RPC once the chain enforces it.
+	UnprotectedAllowed() bool
+
+	// TxWebhook returns the URL notified of the status changes of watched
+	// transactions, if any.
+	TxWebhook() string
+
+	// RPCGasCap returns the maximum gas of aqua_estimateGas, zero if unlimited.
+	RPCGasCap() uint64
+
+	// RPCCallGasCap returns the maximum gas of aqua_call, zero if calls are
+	// unmetered.
+	RPCCallGasCap() uint64
 }
 
-func GetAPIs(apiBackend Backend) []rpc.API {
-	nonceLock := new(AddrLocker)
-	return []rpc.API{
+// callFeatures are the optional behaviours of aqua_call and aqua_estimateGas,
+// reported by rpc_capabilities.
+var callFeatures = []string{"blockHash", "stateOverrides", "blockOverrides"}
+
+// GetAPIs returns the common AquaChain APIs. The personal namespace, signing with
+// bare passphrases and unlocked accounts, is only included if personal is set.
+func GetAPIs(apiBackend Backend, personal bool) []rpc.API {
+	var (
+		nonceLock = new(AddrLocker)
+		sessions  = NewSessionManager()
+		watcher   = NewTxWatcher(apiBackend)
+	)
+	apis := []rpc.API{
 		{
 			Namespace: "aqua",
 			Version:   "1.0",
@@ -84,11 +124,16 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicBlockChainAPI(apiBackend),
 			Public:    true,
+			Features: map[string][]string{
+				"call":        callFeatures,
+				"estimateGas": callFeatures,
+			},
 		}, {
 			Namespace: "aqua",
 			Version:   "1.0",
-			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock),
+			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock, watcher),
 			Public:    true,
+			Features:  map[string][]string{"sendRawTransaction": {"watch"}},
 		}, {
 			Namespace: "txpool",
 			Version:   "1.0",
@@ -109,10 +154,13 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Service:   NewPublicAccountAPI(apiBackend.AccountManager()),
 			Public:    true,
 		}, {
-			Namespace: "personal",
+			Namespace: "session",
 			Version:   "1.0",
-			Service:   NewPrivateAccountAPI(apiBackend, nonceLock),
-			Public:    false,
+			Service:   NewPrivateSessionAPI(apiBackend, nonceLock, sessions),
+		}, {
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   NewPrivateSessionAdminAPI(sessions),
 		}, { // eth alias
 			Namespace: "eth",
 			Version:   "1.0",
@@ -123,11 +171,16 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicBlockChainAPI(apiBackend),
 			Public:    true,
+			Features: map[string][]string{
+				"call":        callFeatures,
+				"estimateGas": callFeatures,
+			},
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock),
+			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock, watcher),
 			Public:    true,
+			Features:  map[string][]string{"sendRawTransaction": {"watch"}},
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
@@ -135,4 +188,13 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Public:    true,
 		},
 	}
+	if personal {
+		apis = append(apis, rpc.API{
+			Namespace: "personal",
+			Version:   "1.0",
+			Service:   NewPrivateAccountAPI(apiBackend, nonceLock),
+			Public:    false,
+		})
+	}
+	return apis
 }
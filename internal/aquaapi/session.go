@@ -0,0 +1,383 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquaapi
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/crypto"
+)
+
+const (
+	defaultSessionDuration = time.Hour          // Lifetime of sessions opened without a duration
+	maxSessionDuration     = 7 * 24 * time.Hour // Maximum lifetime of a session
+)
+
+var (
+	// ErrUnknownSession is returned if a session token is invalid, expired or
+	// was revoked.
+	ErrUnknownSession = errors.New("unknown or expired session")
+
+	// ErrSessionAccount is returned if signing is requested with an account the
+	// session doesn't grant access to.
+	ErrSessionAccount = errors.New("account not permitted by session")
+
+	// ErrSessionAllowance is returned if a transaction would exceed the total
+	// the session may spend, in value and gas.
+	ErrSessionAllowance = errors.New("session allowance exceeded")
+)
+
+// session is a scoped grant to sign with a set of accounts.
+type session struct {
+	id        string
+	accounts  map[common.Address]bool
+	passwd    string   // Passphrase of the accounts, never leaves the node
+	allowance *big.Int // Total the session may still spend in value and gas, nil if unlimited
+	spent     *big.Int // Total spent through the session, at the highest gas prices signed
+	created   time.Time
+	expiry    time.Time
+}
+
+// SessionInfo describes an open session without revealing its token.
+type SessionInfo struct {
+	ID        string           `json:"id"`
+	Accounts  []common.Address `json:"accounts"`
+	Allowance *hexutil.Big     `json:"allowance"` // Remaining allowance, nil if unlimited
+	Spent     *hexutil.Big     `json:"spent"`
+	Created   time.Time        `json:"created"`
+	Expiry    time.Time        `json:"expiry"`
+}
+
+// SessionManager tracks the signing sessions opened on the node. Sessions are
+// keyed by the hash of their token, so the tokens themselves are never stored.
+type SessionManager struct {
+	sessions map[common.Hash]*session
+	lock     sync.Mutex
+}
+
+// NewSessionManager creates an empty session manager.
+func NewSessionManager() *SessionManager {
+	return &SessionManager{sessions: make(map[common.Hash]*session)}
+}
+
+// open registers a new session and returns its token.
+func (m *SessionManager) open(addrs []common.Address, passwd string, allowance *big.Int, lifetime time.Duration) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	token := hexutil.Encode(secret)
+	hash := crypto.Keccak256Hash([]byte(token))
+
+	s := &session{
+		id:       hexutil.Encode(hash[:8]),
+		accounts: make(map[common.Address]bool),
+		passwd:   passwd,
+		spent:    new(big.Int),
+		created:  time.Now(),
+	}
+	s.expiry = s.created.Add(lifetime)
+	if allowance != nil {
+		s.allowance = new(big.Int).Set(allowance)
+	}
+	for _, addr := range addrs {
+		s.accounts[addr] = true
+	}
+	m.lock.Lock()
+	m.sessions[hash] = s
+	m.lock.Unlock()
+
+	return token, nil
+}
+
+// get returns the session of the token if it exists and hasn't expired.
+func (m *SessionManager) get(token string) (*session, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.expire()
+	s, ok := m.sessions[crypto.Keccak256Hash([]byte(token))]
+	if !ok {
+		return nil, ErrUnknownSession
+	}
+	return s, nil
+}
+
+// authorize checks that the session grants signing with the account, returning
+// its passphrase.
+func (m *SessionManager) authorize(token string, addr common.Address) (string, error) {
+	s, err := m.get(token)
+	if err != nil {
+		return "", err
+	}
+	if !s.accounts[addr] {
+		return "", fmt.Errorf("%v: %s", ErrSessionAccount, addr.Hex())
+	}
+	return s.passwd, nil
+}
+
+// spend deducts the cost of a transaction from the allowance of the session,
+// failing if it would be exceeded.
+func (m *SessionManager) spend(token string, value *big.Int) error {
+	s, err := m.get(token)
+	if err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if s.allowance != nil {
+		if s.allowance.Cmp(value) < 0 {
+			return fmt.Errorf("%v: have %v, want %v", ErrSessionAllowance, s.allowance, value)
+		}
+		s.allowance.Sub(s.allowance, value)
+	}
+	s.spent.Add(s.spent, value)
+	return nil
+}
+
+// refund returns a cost to the allowance of the session, undoing a spend whose
+// transaction failed to be submitted.
+func (m *SessionManager) refund(token string, value *big.Int) {
+	s, err := m.get(token)
+	if err != nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if s.allowance != nil {
+		s.allowance.Add(s.allowance, value)
+	}
+	s.spent.Sub(s.spent, value)
+}
+
+// close terminates the session of the token.
+func (m *SessionManager) close(token string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	hash := crypto.Keccak256Hash([]byte(token))
+	if _, ok := m.sessions[hash]; !ok {
+		return false
+	}
+	delete(m.sessions, hash)
+	return true
+}
+
+// Revoke terminates the session with the given id.
+func (m *SessionManager) Revoke(id string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for hash, s := range m.sessions {
+		if s.id == id {
+			delete(m.sessions, hash)
+			return true
+		}
+	}
+	return false
+}
+
+// Sessions returns the open sessions, ordered by creation time.
+func (m *SessionManager) Sessions() []SessionInfo {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.expire()
+	infos := make([]SessionInfo, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		info := SessionInfo{
+			ID:      s.id,
+			Spent:   (*hexutil.Big)(new(big.Int).Set(s.spent)),
+			Created: s.created,
+			Expiry:  s.expiry,
+		}
+		if s.allowance != nil {
+			info.Allowance = (*hexutil.Big)(new(big.Int).Set(s.allowance))
+		}
+		for addr := range s.accounts {
+			info.Accounts = append(info.Accounts, addr)
+		}
+		sort.Slice(info.Accounts, func(i, j int) bool { return info.Accounts[i].Hex() < info.Accounts[j].Hex() })
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Created.Before(infos[j].Created) })
+	return infos
+}
+
+// expire drops all sessions past their expiry. The lock must be held.
+func (m *SessionManager) expire() {
+	now := time.Now()
+	for hash, s := range m.sessions {
+		if now.After(s.expiry) {
+			delete(m.sessions, hash)
+		}
+	}
+}
+
+// PrivateSessionAPI signs on behalf of sessions, scoped grants to use a set of
+// accounts for a limited time and spending. Unlike unlocking an account, which lets
+// anyone with access to the node sign with it, only holders of the session token
+// can sign, and only within its limits.
+type PrivateSessionAPI struct {
+	sessions *SessionManager
+	personal *PrivateAccountAPI
+}
+
+// NewPrivateSessionAPI creates a new session signing API.
+func NewPrivateSessionAPI(b Backend, nonceLock *AddrLocker, sessions *SessionManager) *PrivateSessionAPI {
+	return &PrivateSessionAPI{
+		sessions: sessions,
+		personal: NewPrivateAccountAPI(b, nonceLock),
+	}
+}
+
+// Open verifies the passphrase of the accounts and opens a session permitting to
+// sign with them. The session expires after duration seconds, an hour if nil. If
+// maxValue is set, the session may spend at most that much in total, counting
+// both the value and the gas of its transactions at the highest price they may
+// pay.
+// The returned token authenticates the session and is not shown again.
+func (s *PrivateSessionAPI) Open(addrs []common.Address, passwd string, maxValue *hexutil.Big, duration *uint64) (string, error) {
+	if len(addrs) == 0 {
+		return "", errors.New("no accounts given")
+	}
+	lifetime := defaultSessionDuration
+	if duration != nil {
+		if *duration == 0 || *duration > uint64(maxSessionDuration/time.Second) {
+			return "", fmt.Errorf("session duration must be between 1 and %d seconds", uint64(maxSessionDuration/time.Second))
+		}
+		lifetime = time.Duration(*duration) * time.Second
+	}
+	// Verify the passphrase up front, so a session can't be used to guess it
+	for _, addr := range addrs {
+		account := accounts.Account{Address: addr}
+		wallet, err := s.personal.am.Find(account)
+		if err != nil {
+			return "", err
+		}
+		if _, err := wallet.SignHashWithPassphrase(account, passwd, make([]byte, 32)); err != nil {
+			return "", err
+		}
+	}
+	return s.sessions.open(addrs, passwd, (*big.Int)(maxValue), lifetime)
+}
+
+// Close terminates the session of the token.
+func (s *PrivateSessionAPI) Close(token string) bool {
+	return s.sessions.close(token)
+}
+
+// SendTransaction signs a transaction with an account of the session and submits
+// it to the transaction pool, deducting its cost from the session allowance.
+func (s *PrivateSessionAPI) SendTransaction(ctx context.Context, token string, args SendTxArgs) (common.Hash, error) {
+	passwd, err := s.sessions.authorize(token, args.From)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// Default the gas and its price up front, as they count against the allowance
+	if err := args.setGasDefaults(ctx, s.personal.b); err != nil {
+		return common.Hash{}, err
+	}
+	cost := txCost(args)
+	if err := s.sessions.spend(token, cost); err != nil {
+		return common.Hash{}, err
+	}
+	hash, err := s.personal.SendTransaction(ctx, args, passwd)
+	if err != nil {
+		s.sessions.refund(token, cost)
+	}
+	return hash, err
+}
+
+// SignTransaction signs a transaction with an account of the session without
+// submitting it. As the transaction may be broadcast by the caller, its cost is
+// deducted from the session allowance.
+func (s *PrivateSessionAPI) SignTransaction(ctx context.Context, token string, args SendTxArgs) (*SignTransactionResult, error) {
+	passwd, err := s.sessions.authorize(token, args.From)
+	if err != nil {
+		return nil, err
+	}
+	cost := txCost(args)
+	if err := s.sessions.spend(token, cost); err != nil {
+		return nil, err
+	}
+	result, err := s.personal.SignTransaction(ctx, args, passwd)
+	if err != nil {
+		s.sessions.refund(token, cost)
+	}
+	return result, err
+}
+
+// Sign calculates an AquaChain ECDSA signature of the message with an account
+// of the session, as personal_sign does.
+func (s *PrivateSessionAPI) Sign(ctx context.Context, token string, data hexutil.Bytes, addr common.Address) (hexutil.Bytes, error) {
+	passwd, err := s.sessions.authorize(token, addr)
+	if err != nil {
+		return nil, err
+	}
+	return s.personal.Sign(ctx, data, addr, passwd)
+}
+
+// txCost returns the most the transaction arguments may cost the sender: the
+// value plus the gas at the gas price, or at the fee cap of dynamic fee
+// transactions. Unspecified fields count as zero.
+func txCost(args SendTxArgs) *big.Int {
+	price := args.GasPrice
+	if args.dynamicFee() {
+		price = args.MaxFeePerGas
+	}
+	cost := new(big.Int)
+	if args.Gas != nil && price != nil {
+		cost.Mul((*big.Int)(price), new(big.Int).SetUint64(uint64(*args.Gas)))
+	}
+	if args.Value != nil {
+		cost.Add(cost, (*big.Int)(args.Value))
+	}
+	return cost
+}
+
+// PrivateSessionAdminAPI lets the node operator oversee the signing sessions.
+type PrivateSessionAdminAPI struct {
+	sessions *SessionManager
+}
+
+// NewPrivateSessionAdminAPI creates a new session administration API.
+func NewPrivateSessionAdminAPI(sessions *SessionManager) *PrivateSessionAdminAPI {
+	return &PrivateSessionAdminAPI{sessions: sessions}
+}
+
+// Sessions lists the open signing sessions.
+func (s *PrivateSessionAdminAPI) Sessions() []SessionInfo {
+	return s.sessions.Sessions()
+}
+
+// RevokeSession terminates the signing session with the given id.
+func (s *PrivateSessionAdminAPI) RevokeSession(id string) bool {
+	return s.sessions.Revoke(id)
+}
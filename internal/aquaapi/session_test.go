@@ -0,0 +1,129 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquaapi
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+)
+
+// Tests that sessions only grant their accounts within their value allowance,
+// and can be closed and revoked.
+func TestSessionManager(t *testing.T) {
+	var (
+		m       = NewSessionManager()
+		granted = common.HexToAddress("0x0000000000000000000000000000000000000aaa")
+		other   = common.HexToAddress("0x0000000000000000000000000000000000000bbb")
+	)
+	token, err := m.open([]common.Address{granted}, "secret", big.NewInt(100), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	if passwd, err := m.authorize(token, granted); err != nil || passwd != "secret" {
+		t.Fatalf("granted account: have %q, %v, want %q, nil", passwd, err, "secret")
+	}
+	if _, err := m.authorize(token, other); err == nil || !strings.HasPrefix(err.Error(), ErrSessionAccount.Error()) {
+		t.Fatalf("other account error mismatch: have %v, want %v", err, ErrSessionAccount)
+	}
+	if _, err := m.authorize("0xbad", granted); err != ErrUnknownSession {
+		t.Fatalf("bad token error mismatch: have %v, want %v", err, ErrUnknownSession)
+	}
+	// Spend the allowance, refunding a failed transfer
+	if err := m.spend(token, big.NewInt(60)); err != nil {
+		t.Fatalf("failed to spend within allowance: %v", err)
+	}
+	if err := m.spend(token, big.NewInt(50)); err == nil || !strings.HasPrefix(err.Error(), ErrSessionAllowance.Error()) {
+		t.Fatalf("overspend error mismatch: have %v, want %v", err, ErrSessionAllowance)
+	}
+	m.refund(token, big.NewInt(60))
+
+	sessions := m.Sessions()
+	if len(sessions) != 1 {
+		t.Fatalf("session count mismatch: have %d, want 1", len(sessions))
+	}
+	if info := sessions[0]; info.Allowance.ToInt().Int64() != 100 || info.Spent.ToInt().Sign() != 0 {
+		t.Errorf("allowance mismatch: have %v spent %v, want 100 spent 0", info.Allowance, info.Spent)
+	}
+	if strings.Contains(sessions[0].ID, token[2:]) {
+		t.Errorf("session id %s reveals token", sessions[0].ID)
+	}
+	// Revoke the session by id and ensure it's gone
+	if !m.Revoke(sessions[0].ID) {
+		t.Fatalf("failed to revoke session")
+	}
+	if _, err := m.authorize(token, granted); err != ErrUnknownSession {
+		t.Fatalf("revoked session error mismatch: have %v, want %v", err, ErrUnknownSession)
+	}
+	// Closed and expired sessions are also gone
+	token, _ = m.open([]common.Address{granted}, "secret", nil, time.Hour)
+	if !m.close(token) || m.close(token) {
+		t.Fatalf("failed to close session exactly once")
+	}
+	token, _ = m.open([]common.Address{granted}, "secret", nil, -time.Second)
+	if _, err := m.authorize(token, granted); err != ErrUnknownSession {
+		t.Fatalf("expired session error mismatch: have %v, want %v", err, ErrUnknownSession)
+	}
+	if len(m.Sessions()) != 0 {
+		t.Errorf("sessions left: %v", m.Sessions())
+	}
+}
+
+// Tests that transactions are charged to the session allowance for their gas
+// too, at the highest price they may pay, so that no session can burn funds in
+// fees.
+func TestSessionGasAllowance(t *testing.T) {
+	var (
+		m       = NewSessionManager()
+		api     = &PrivateSessionAPI{sessions: m, personal: &PrivateAccountAPI{b: &testBackend{}}}
+		granted = common.HexToAddress("0x0000000000000000000000000000000000000aaa")
+		gas     = hexutil.Uint64(21000)
+	)
+	token, err := m.open([]common.Address{granted}, "secret", big.NewInt(1000000), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	args := SendTxArgs{From: granted, To: &common.Address{1}, Gas: &gas, GasPrice: (*hexutil.Big)(big.NewInt(1000))}
+	if _, err := api.SendTransaction(context.Background(), token, args); err == nil || !strings.HasPrefix(err.Error(), ErrSessionAllowance.Error()) {
+		t.Fatalf("zero value transaction error mismatch: have %v, want %v", err, ErrSessionAllowance)
+	}
+	if _, err := api.SignTransaction(context.Background(), token, args); err == nil || !strings.HasPrefix(err.Error(), ErrSessionAllowance.Error()) {
+		t.Fatalf("zero value signing error mismatch: have %v, want %v", err, ErrSessionAllowance)
+	}
+	if info := m.Sessions()[0]; info.Allowance.ToInt().Int64() != 1000000 || info.Spent.ToInt().Sign() != 0 {
+		t.Errorf("allowance mismatch: have %v spent %v, want 1000000 spent 0", info.Allowance, info.Spent)
+	}
+	// Dynamic fee transactions are charged at their fee cap
+	tests := []struct {
+		args SendTxArgs
+		want int64
+	}{
+		{SendTxArgs{Gas: &gas, GasPrice: (*hexutil.Big)(big.NewInt(2)), Value: (*hexutil.Big)(big.NewInt(5))}, 2*21000 + 5},
+		{SendTxArgs{Gas: &gas, MaxFeePerGas: (*hexutil.Big)(big.NewInt(3)), MaxPriorityFeePerGas: (*hexutil.Big)(big.NewInt(1))}, 3 * 21000},
+		{SendTxArgs{Value: (*hexutil.Big)(big.NewInt(5))}, 5},
+	}
+	for i, tt := range tests {
+		if have := txCost(tt.args); have.Int64() != tt.want {
+			t.Errorf("test %d: cost mismatch: have %v, want %d", i, have, tt.want)
+		}
+	}
+}
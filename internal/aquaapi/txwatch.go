@@ -0,0 +1,302 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquaapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+const (
+	maxWatchedTxs         = 4096             // Maximum number of transactions watched at once
+	maxWatchConfirmations = 1024             // Maximum confirmation depth a transaction can be watched to
+	txWebhookQueue        = 256              // Number of status changes waiting to be posted to the webhook
+	txWebhookTimeout      = 10 * time.Second // Time allowed to the webhook to accept a status change
+)
+
+// The statuses of the watched transactions. Mined and reorged are reported as
+// they happen, while confirmed, dropped and replaced end the watch.
+const (
+	TxStatusMined     = "mined"     // Included in a canonical block
+	TxStatusConfirmed = "confirmed" // Included deep enough in the canonical chain
+	TxStatusReorged   = "reorged"   // The block including it left the canonical chain
+	TxStatusDropped   = "dropped"   // Neither pending nor included, its nonce still unused
+	TxStatusReplaced  = "replaced"  // Another transaction with the same nonce superseded it
+)
+
+// ErrTooManyWatched is returned if a transaction is submitted with watch set
+// while the maximum number of transactions are watched.
+var ErrTooManyWatched = errors.New("too many watched transactions")
+
+// SendTxOptions are the options of aqua_sendRawTransaction.
+type SendTxOptions struct {
+	// Watch reports the status changes of the transaction to the
+	// watchedTransactions subscribers and to the webhook of the node, if any.
+	Watch bool `json:"watch"`
+
+	// Confirmations is the depth at which the transaction is confirmed, one
+	// (the including block) if nil.
+	Confirmations *hexutil.Uint64 `json:"confirmations"`
+}
+
+// TxStatusEvent is a status change of a watched transaction.
+type TxStatusEvent struct {
+	Hash          common.Hash    `json:"hash"`
+	Status        string         `json:"status"`
+	BlockHash     *common.Hash   `json:"blockHash,omitempty"`
+	BlockNumber   *hexutil.Big   `json:"blockNumber,omitempty"`
+	Confirmations hexutil.Uint64 `json:"confirmations"`
+	ReplacedBy    *common.Hash   `json:"replacedBy,omitempty"`
+}
+
+// watchedTx is a transaction watched until it is confirmed, dropped or replaced.
+type watchedTx struct {
+	tx            *types.Transaction
+	from          common.Address
+	confirmations uint64
+	blockHash     common.Hash // Block including the transaction, zero if pending
+}
+
+// TxWatcher follows the transactions submitted with watch set, reporting their
+// status changes as the chain and the pool progress. It only runs while
+// transactions are watched.
+type TxWatcher struct {
+	b       Backend
+	feed    event.Feed
+	scope   event.SubscriptionScope
+	txs     map[common.Hash]*watchedTx
+	running bool
+	lock    sync.Mutex
+}
+
+// NewTxWatcher creates a watcher following the chain and the pool of b.
+func NewTxWatcher(b Backend) *TxWatcher {
+	return &TxWatcher{b: b, txs: make(map[common.Hash]*watchedTx)}
+}
+
+// SubscribeTxStatus subscribes to the status changes of watched transactions.
+func (w *TxWatcher) SubscribeTxStatus(ch chan<- TxStatusEvent) event.Subscription {
+	return w.scope.Track(w.feed.Subscribe(ch))
+}
+
+// full returns whether no more transactions may be watched.
+func (w *TxWatcher) full() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return len(w.txs) >= maxWatchedTxs
+}
+
+// watch starts following the given transaction until it is included the given
+// number of blocks deep, or dropped or replaced.
+func (w *TxWatcher) watch(tx *types.Transaction, from common.Address, confirmations uint64) error {
+	if confirmations == 0 {
+		confirmations = 1
+	}
+	if confirmations > maxWatchConfirmations {
+		return fmt.Errorf("confirmations above %d", maxWatchConfirmations)
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if _, ok := w.txs[tx.Hash()]; ok {
+		return nil
+	}
+	if len(w.txs) >= maxWatchedTxs {
+		return ErrTooManyWatched
+	}
+	w.txs[tx.Hash()] = &watchedTx{tx: tx, from: from, confirmations: confirmations}
+	if !w.running {
+		w.running = true
+		go w.loop()
+	}
+	return nil
+}
+
+// loop checks the watched transactions on every new head and replaces them on
+// new pool transactions, until none is watched anymore.
+func (w *TxWatcher) loop() {
+	heads := make(chan core.ChainHeadEvent, 16)
+	headSub := w.b.SubscribeChainHeadEvent(heads)
+	defer headSub.Unsubscribe()
+
+	pending := make(chan core.TxPreEvent, 256)
+	pendingSub := w.b.SubscribeTxPreEvent(pending)
+	defer pendingSub.Unsubscribe()
+
+	var hooks chan TxStatusEvent
+	if url := w.b.TxWebhook(); url != "" {
+		hooks = make(chan TxStatusEvent, txWebhookQueue)
+		defer close(hooks)
+		go postTxStatus(url, hooks)
+	}
+	for {
+		var events []TxStatusEvent
+		select {
+		case ev := <-heads:
+			events = w.check(ev.Block)
+		case ev := <-pending:
+			events = w.replace(ev.Tx)
+		case <-headSub.Err():
+			return
+		case <-pendingSub.Err():
+			return
+		}
+		for _, ev := range events {
+			w.feed.Send(ev)
+			if hooks == nil {
+				continue
+			}
+			select {
+			case hooks <- ev:
+			default:
+				log.Warn("Transaction webhook lagging, status dropped", "hash", ev.Hash, "status", ev.Status)
+			}
+		}
+		w.lock.Lock()
+		if len(w.txs) == 0 {
+			w.running = false
+			w.lock.Unlock()
+			return
+		}
+		w.lock.Unlock()
+	}
+}
+
+// check updates the status of the watched transactions at the given head.
+func (w *TxWatcher) check(head *types.Block) []TxStatusEvent {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var (
+		events []TxStatusEvent
+		nonces = make(map[common.Address]uint64)
+	)
+	for hash, wtx := range w.txs {
+		if tx, blockHash, number, _ := core.GetTransaction(w.b.ChainDb(), hash); tx != nil && number <= head.NumberU64() {
+			depth := head.NumberU64() - number + 1
+			ev := TxStatusEvent{
+				Hash:          hash,
+				BlockHash:     &blockHash,
+				BlockNumber:   (*hexutil.Big)(new(big.Int).SetUint64(number)),
+				Confirmations: hexutil.Uint64(depth),
+			}
+			if wtx.blockHash != blockHash {
+				wtx.blockHash = blockHash
+				ev.Status = TxStatusMined
+				events = append(events, ev)
+			}
+			if depth >= wtx.confirmations {
+				ev.Status = TxStatusConfirmed
+				events = append(events, ev)
+				delete(w.txs, hash)
+			}
+			continue
+		}
+		if wtx.blockHash != (common.Hash{}) {
+			wtx.blockHash = common.Hash{}
+			events = append(events, TxStatusEvent{Hash: hash, Status: TxStatusReorged})
+		}
+		if w.b.GetPoolTransaction(hash) != nil {
+			continue
+		}
+		// Neither included nor pending, replaced if its nonce was used
+		nonce, ok := nonces[wtx.from]
+		if !ok {
+			state, _, err := w.b.StateAndHeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+			if state == nil || err != nil {
+				continue
+			}
+			nonce = state.GetNonce(wtx.from)
+			nonces[wtx.from] = nonce
+		}
+		status := TxStatusDropped
+		if nonce > wtx.tx.Nonce() {
+			status = TxStatusReplaced
+		}
+		events = append(events, TxStatusEvent{Hash: hash, Status: status})
+		delete(w.txs, hash)
+	}
+	return events
+}
+
+// replace ends the watch of the pending transactions the given one supersedes.
+func (w *TxWatcher) replace(tx *types.Transaction) []TxStatusEvent {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var (
+		events []TxStatusEvent
+		from   *common.Address
+		hash   = tx.Hash()
+	)
+	for watched, wtx := range w.txs {
+		if watched == hash || wtx.blockHash != (common.Hash{}) || wtx.tx.Nonce() != tx.Nonce() {
+			continue
+		}
+		if from == nil {
+			signer := types.MakeSigner(w.b.ChainConfig(), w.b.CurrentBlock().Number())
+			sender, err := types.Sender(signer, tx)
+			if err != nil {
+				return events
+			}
+			from = &sender
+		}
+		if wtx.from != *from {
+			continue
+		}
+		events = append(events, TxStatusEvent{Hash: watched, Status: TxStatusReplaced, ReplacedBy: &hash})
+		delete(w.txs, watched)
+	}
+	return events
+}
+
+// postTxStatus posts the status changes to the webhook at url, in order, until
+// events is closed.
+func postTxStatus(url string, events <-chan TxStatusEvent) {
+	client := &http.Client{Timeout: txWebhookTimeout}
+	for ev := range events {
+		body, err := json.Marshal(ev)
+		if err != nil {
+			continue
+		}
+		resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Warn("Failed to post transaction status", "hash", ev.Hash, "status", ev.Status, "err", err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			log.Warn("Transaction webhook refused status", "hash", ev.Hash, "status", ev.Status, "code", resp.StatusCode)
+		}
+	}
+}
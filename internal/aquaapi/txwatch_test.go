@@ -0,0 +1,172 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquaapi
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that the watcher reports watched transactions as they are mined,
+// confirmed, reorged, dropped and replaced.
+func TestTxWatcher(t *testing.T) {
+	db, _ := aquadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	b := &testBackend{db: db, head: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)}), pool: make(map[common.Hash]*types.Transaction), state: statedb}
+	w := NewTxWatcher(b)
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	signer := types.MakeSigner(params.TestChainConfig, big.NewInt(1))
+	sign := func(nonce uint64, price int64) *types.Transaction {
+		tx := types.NewTransaction(nonce, common.Address{1}, big.NewInt(1), params.TxGas, big.NewInt(price), nil)
+		signed, _ := types.SignTx(tx, signer, key)
+		return signed
+	}
+	var (
+		mined    = sign(0, 1) // Included in block 1, confirmed at depth 2
+		dropped  = sign(1, 1) // Leaving the pool with its nonce unused
+		replaced = sign(2, 1) // Superseded in the pool by a higher priced one
+		reorged  = sign(3, 1) // Included in block 1, then reorged out
+	)
+	for _, tx := range []*types.Transaction{mined, dropped, replaced, reorged} {
+		w.txs[tx.Hash()] = &watchedTx{tx: tx, from: from, confirmations: 2}
+	}
+	b.pool[dropped.Hash()], b.pool[replaced.Hash()] = dropped, replaced
+
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, []*types.Transaction{mined, reorged}, nil, nil)
+	core.WriteBlock(db, block)
+	core.WriteTxLookupEntries(db, block)
+
+	statuses := func(events []TxStatusEvent) map[common.Hash]string {
+		have := make(map[common.Hash]string)
+		for _, ev := range events {
+			have[ev.Hash] += ev.Status
+		}
+		return have
+	}
+	check := func(step string, have, want map[common.Hash]string) {
+		if len(have) != len(want) {
+			t.Errorf("%s: statuses mismatch: have %v, want %v", step, have, want)
+		}
+		for hash, status := range want {
+			if have[hash] != status {
+				t.Errorf("%s: status of %x mismatch: have %q, want %q", step, hash[:4], have[hash], status)
+			}
+		}
+	}
+	check("block 1", statuses(w.check(block)), map[common.Hash]string{
+		mined.Hash():   TxStatusMined,
+		reorged.Hash(): TxStatusMined,
+	})
+
+	// Replace a pending transaction, drop another and reorg one out
+	bump := sign(2, 2)
+	events := w.replace(bump)
+	if len(events) != 1 || events[0].Hash != replaced.Hash() || events[0].Status != TxStatusReplaced || *events[0].ReplacedBy != bump.Hash() {
+		t.Errorf("replacement mismatch: have %+v", events)
+	}
+	delete(b.pool, dropped.Hash())
+	core.DeleteTxLookupEntry(db, reorged.Hash())
+
+	head := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2)})
+	check("block 2", statuses(w.check(head)), map[common.Hash]string{
+		mined.Hash():   TxStatusConfirmed,
+		dropped.Hash(): TxStatusDropped,
+		reorged.Hash(): TxStatusReorged + TxStatusDropped,
+	})
+	if len(w.txs) != 0 {
+		t.Errorf("transactions still watched: %d", len(w.txs))
+	}
+}
+
+// Tests that the status changes are delivered to the subscribers and to the
+// webhook, and that the watcher stops once nothing is watched.
+func TestTxWatcherDelivery(t *testing.T) {
+	posted := make(chan TxStatusEvent, 2)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev TxStatusEvent
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("failed to decode webhook status: %v", err)
+		}
+		posted <- ev
+	}))
+	defer hook.Close()
+
+	db, _ := aquadb.NewMemDatabase()
+	b := &testBackend{db: db, head: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)}), hook: hook.URL}
+	w := NewTxWatcher(b)
+
+	events := make(chan TxStatusEvent, 2)
+	sub := w.SubscribeTxStatus(events)
+	defer sub.Unsubscribe()
+
+	key, _ := crypto.GenerateKey()
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), params.TxGas, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+	if err := w.watch(tx, crypto.PubkeyToAddress(key.PublicKey), 1); err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, []*types.Transaction{tx}, nil, nil)
+	core.WriteBlock(db, block)
+	core.WriteTxLookupEntries(db, block)
+
+	// Wait for the watcher to subscribe before announcing the head
+	for b.heads.Send(core.ChainHeadEvent{Block: block}) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for _, want := range []string{TxStatusMined, TxStatusConfirmed} {
+		select {
+		case ev := <-events:
+			if ev.Hash != tx.Hash() || ev.Status != want {
+				t.Errorf("subscription status mismatch: have %s %s, want %s", ev.Hash.Hex(), ev.Status, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s status not delivered to subscribers", want)
+		}
+		select {
+		case ev := <-posted:
+			if ev.Hash != tx.Hash() || ev.Status != want {
+				t.Errorf("webhook status mismatch: have %s %s, want %s", ev.Hash.Hex(), ev.Status, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s status not posted to the webhook", want)
+		}
+	}
+	for start := time.Now(); ; time.Sleep(time.Millisecond) {
+		w.lock.Lock()
+		running := w.running
+		w.lock.Unlock()
+		if !running {
+			break
+		}
+		if time.Since(start) > time.Second {
+			t.Fatalf("watcher still running with nothing watched")
+		}
+	}
+}
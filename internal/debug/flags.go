@@ -52,6 +52,24 @@ var (
 		Name:  "debug",
 		Usage: "Prepends log messages with call-site location (file and line number)",
 	}
+	logJSONFlag = cli.BoolFlag{
+		Name:  "log.json",
+		Usage: "Format log messages as JSON objects, one per line",
+	}
+	logFileFlag = cli.StringFlag{
+		Name:  "log.file",
+		Usage: "Write log messages to the given file instead of the terminal",
+	}
+	logMaxSizeFlag = cli.IntFlag{
+		Name:  "log.maxsize",
+		Usage: "Size in megabytes at which the log file is rotated (0 = never)",
+		Value: 100,
+	}
+	logMaxBackupsFlag = cli.IntFlag{
+		Name:  "log.maxbackups",
+		Usage: "Number of rotated log files to keep",
+		Value: 10,
+	}
 	pprofFlag = cli.BoolFlag{
 		Name:  "pprof",
 		Usage: "Enable the pprof HTTP server",
@@ -88,6 +106,7 @@ var (
 // Flags holds all command-line flags required for debugging.
 var Flags = []cli.Flag{
 	verbosityFlag, vmoduleFlag, backtraceAtFlag, debugFlag,
+	logJSONFlag, logFileFlag, logMaxSizeFlag, logMaxBackupsFlag,
 	pprofFlag, pprofAddrFlag, pprofPortFlag,
 	memprofilerateFlag, blockprofilerateFlag, cpuprofileFlag, traceFlag,
 }
@@ -103,13 +122,51 @@ func init() {
 	glogger = log.NewGlogHandler(log.StreamHandler(output, log.TerminalFormat(usecolor)))
 }
 
+// logHandler assembles the log output requested by the CLI flags, or returns nil
+// to keep logging to the terminal.
+func logHandler(ctx *cli.Context) (log.Handler, error) {
+	var (
+		asJSON = ctx.GlobalBool(logJSONFlag.Name)
+		file   = ctx.GlobalString(logFileFlag.Name)
+	)
+	if !asJSON && file == "" {
+		return nil, nil
+	}
+	format := log.TerminalFormat(false)
+	if asJSON {
+		format = log.JsonFormat()
+	}
+	handler := log.StreamHandler(os.Stderr, format)
+	if file != "" {
+		var err error
+		maxSize := int64(ctx.GlobalInt(logMaxSizeFlag.Name)) * 1024 * 1024
+		if handler, err = log.RotatingFileHandler(file, maxSize, ctx.GlobalInt(logMaxBackupsFlag.Name), format); err != nil {
+			return nil, err
+		}
+	}
+	// JSON records carry no location, attach it as a field instead
+	if asJSON && ctx.GlobalBool(debugFlag.Name) {
+		handler = log.CallerFileHandler(handler)
+	}
+	return handler, nil
+}
+
 // Setup initializes profiling and logging based on the CLI flags.
 // It should be called as early as possible in the program.
 func Setup(ctx *cli.Context) error {
 	// logging
 	log.PrintOrigins(ctx.GlobalBool(debugFlag.Name))
+	handler, err := logHandler(ctx)
+	if err != nil {
+		return err
+	}
+	if handler != nil {
+		glogger = log.NewGlogHandler(handler)
+	}
 	glogger.Verbosity(log.Lvl(ctx.GlobalInt(verbosityFlag.Name)))
-	glogger.Vmodule(ctx.GlobalString(vmoduleFlag.Name))
+	if err := glogger.Vmodule(ctx.GlobalString(vmoduleFlag.Name)); err != nil {
+		return fmt.Errorf("invalid --%s: %v", vmoduleFlag.Name, err)
+	}
 	glogger.BacktraceAt(ctx.GlobalString(backtraceAtFlag.Name))
 	log.Root().SetHandler(glogger)
 
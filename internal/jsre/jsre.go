@@ -312,6 +312,7 @@ func (self *JSRE) Evaluate(code string, w io.Writer) error {
 		val, err := vm.Run(code)
 		if err != nil {
 			prettyError(vm, err, w)
+			fail = err
 		} else {
 			prettyPrint(vm, val, w)
 		}
@@ -19,6 +19,7 @@ package web3ext
 
 var Modules = map[string]string{
 	"admin":      Admin_JS,
+	"aquahash":   Aquahash_JS,
 	"chequebook": Chequebook_JS,
 	"clique":     Clique_JS,
 	"debug":      Debug_JS,
@@ -27,6 +28,7 @@ var Modules = map[string]string{
 	"net":        Net_JS,
 	"personal":   Personal_JS,
 	"rpc":        RPC_JS,
+	"session":    Session_JS,
 	"shh":        Shh_JS,
 	"swarmfs":    SWARMFS_JS,
 	"txpool":     TxPool_JS,
@@ -63,6 +65,26 @@ web3._extend({
 });
 `
 
+const Aquahash_JS = `
+web3._extend({
+	property: 'aquahash',
+	methods: [
+		new web3._extend.Method({
+			name: 'pregenerate',
+			call: 'aquahash_pregenerate',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'generation',
+			getter: 'aquahash_generation'
+		}),
+	]
+});
+`
+
 const Clique_JS = `
 web3._extend({
 	property: 'clique',
@@ -123,6 +145,16 @@ web3._extend({
 			call: 'admin_removePeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'addTrustedPeer',
+			call: 'admin_addTrustedPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'removeTrustedPeer',
+			call: 'admin_removeTrustedPeer',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -171,6 +203,38 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'registerContractMetadata',
+			call: 'admin_registerContractMetadata',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'removeContractMetadata',
+			call: 'admin_removeContractMetadata',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'syncContractMetadata',
+			call: 'admin_syncContractMetadata',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'auditCoinbases',
+			call: 'admin_auditCoinbases',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'runTask',
+			call: 'admin_runTask',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'revokeSession',
+			call: 'admin_revokeSession',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -185,6 +249,26 @@ web3._extend({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new web3._extend.Property({
+			name: 'pruningStats',
+			getter: 'admin_pruningStats'
+		}),
+		new web3._extend.Property({
+			name: 'scheduledTasks',
+			getter: 'admin_scheduledTasks'
+		}),
+		new web3._extend.Property({
+			name: 'forkStatus',
+			getter: 'admin_forkStatus'
+		}),
+		new web3._extend.Property({
+			name: 'sessions',
+			getter: 'admin_sessions'
+		}),
+		new web3._extend.Property({
+			name: 'networkTime',
+			getter: 'admin_networkTime'
+		}),
 	]
 });
 `
@@ -354,6 +438,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'traceCall',
+			call: 'debug_traceCall',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -403,6 +493,18 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
 		}),
+		new web3._extend.Method({
+			name: 'feeHistory',
+			call: 'aqua_feeHistory',
+			params: 3,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'cancelTransaction',
+			call: 'aqua_cancelTransaction',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.fromDecimal]
+		}),
 		new web3._extend.Method({
 			name: 'signTransaction',
 			call: 'aqua_signTransaction',
@@ -415,6 +517,15 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'getHeader',
+			call: function(args) {
+				return (web3._extend.utils.isString(args[0]) && args[0].indexOf('0x') === 0) ? 'aqua_getHeaderByHash' : 'aqua_getHeaderByNumber';
+			},
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter],
+			outputFormatter: web3._extend.formatters.outputBlockFormatter
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransaction',
 			call: 'aqua_getRawTransactionByHash',
@@ -428,8 +539,69 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
 		}),
+		new web3._extend.Method({
+			name: 'getContractMetadata',
+			call: 'aqua_getContractMetadata',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'recentConflicts',
+			call: 'aqua_recentConflicts',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'totalSupply',
+			call: 'aqua_totalSupply',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'supplyDelta',
+			call: 'aqua_supplyDelta',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getChainWork',
+			call: 'aqua_getChainWork',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getProof',
+			call: 'aqua_getProof',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getBlockReceipts',
+			call: 'aqua_getBlockReceipts',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter],
+			outputFormatter: function(receipts) {
+				if (receipts === null) {
+					return null;
+				}
+				var formatted = [];
+				for (var i = 0; i < receipts.length; i++) {
+					formatted.push(web3._extend.formatters.outputTransactionReceiptFormatter(receipts[i]));
+				}
+				return formatted;
+			}
+		}),
 	],
 	properties: [
+		new web3._extend.Property({
+			name: 'chainId',
+			getter: 'aqua_chainId',
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Property({
+			name: 'maxPriorityFeePerGas',
+			getter: 'aqua_maxPriorityFeePerGas',
+			outputFormatter: web3._extend.utils.toBigNumber
+		}),
 		new web3._extend.Property({
 			name: 'pendingTransactions',
 			getter: 'aqua_pendingTransactions',
@@ -444,6 +616,14 @@ web3._extend({
 		}),
 	]
 });
+
+web3.aqua.toAqua = function(amount, unit) {
+	return web3.fromWei(web3.toWei(amount, unit || 'wei'), 'aqua');
+};
+
+web3.aqua.toWei = function(amount, unit) {
+	return web3.toWei(amount, unit || 'aqua');
+};
 `
 
 const Miner_JS = `
@@ -466,11 +646,21 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'setAquabases',
+			call: 'miner_setAquabases',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'setExtra',
 			call: 'miner_setExtra',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'setThrottle',
+			call: 'miner_setThrottle',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'setGasPrice',
 			call: 'miner_setGasPrice',
@@ -481,8 +671,26 @@ web3._extend({
 			name: 'getHashrate',
 			call: 'miner_getHashrate'
 		}),
+		new web3._extend.Method({
+			name: 'setUncles',
+			call: 'miner_setUncles',
+			params: 1
+		}),
 	],
-	properties: []
+	properties: [
+		new web3._extend.Property({
+			name: 'uncleStats',
+			getter: 'miner_uncleStats'
+		}),
+		new web3._extend.Property({
+			name: 'stats',
+			getter: 'miner_stats'
+		}),
+		new web3._extend.Property({
+			name: 'shareStats',
+			getter: 'miner_shareStats'
+		}),
+	]
 });
 `
 
@@ -529,6 +737,21 @@ web3._extend({
 			call: 'personal_deriveAccount',
 			params: 3
 		}),
+		new web3._extend.Method({
+			name: 'newHDAccount',
+			call: 'personal_newHDAccount',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'importMnemonic',
+			call: 'personal_importMnemonic',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'listHDAccounts',
+			call: 'personal_listHDAccounts',
+			params: 3
+		}),
 		new web3._extend.Method({
 			name: 'signTransaction',
 			call: 'personal_signTransaction',
@@ -554,6 +777,47 @@ web3._extend({
 			name: 'modules',
 			getter: 'rpc_modules'
 		}),
+		new web3._extend.Property({
+			name: 'capabilities',
+			getter: 'rpc_capabilities'
+		}),
+	]
+});
+`
+
+const Session_JS = `
+web3._extend({
+	property: 'session',
+	methods: [
+		new web3._extend.Method({
+			name: 'open',
+			call: 'session_open',
+			params: 4,
+			inputFormatter: [null, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'close',
+			call: 'session_close',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'sendTransaction',
+			call: 'session_sendTransaction',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputTransactionFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'signTransaction',
+			call: 'session_signTransaction',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputTransactionFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'sign',
+			call: 'session_sign',
+			params: 3,
+			inputFormatter: [null, null, web3._extend.formatters.inputAddressFormatter]
+		}),
 	]
 });
 `
@@ -605,7 +869,23 @@ web3._extend({
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
-	methods: [],
+	methods: [
+		new web3._extend.Method({
+			name: 'contentFrom',
+			call: 'txpool_contentFrom',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'unban',
+			call: 'txpool_unban',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'resetDefense',
+			call: 'txpool_resetDefense'
+		}),
+	],
 	properties:
 	[
 		new web3._extend.Property({
@@ -625,6 +905,10 @@ web3._extend({
 				return status;
 			}
 		}),
+		new web3._extend.Property({
+			name: 'defense',
+			getter: 'txpool_defense'
+		}),
 	]
 });
 `
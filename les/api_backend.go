@@ -32,6 +32,7 @@ import (
 	"github.com/aquanetwork/aquachain/core/types"
 	"github.com/aquanetwork/aquachain/core/vm"
 	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/internal/aquaapi"
 	"github.com/aquanetwork/aquachain/light"
 	"github.com/aquanetwork/aquachain/params"
 	"github.com/aquanetwork/aquachain/rpc"
@@ -54,9 +55,26 @@ func (b *LesApiBackend) CurrentBlock() *types.Block {
 	return types.NewBlockWithHeader(b.aqua.BlockChain().CurrentHeader())
 }
 
-func (b *LesApiBackend) SetHead(number uint64) {
+func (b *LesApiBackend) UnprotectedAllowed() bool {
+	return b.aqua.config.RPCAllowUnprotectedTxs
+}
+
+func (b *LesApiBackend) TxWebhook() string {
+	return b.aqua.config.RPCTxWebhook
+}
+
+func (b *LesApiBackend) RPCGasCap() uint64 {
+	return b.aqua.config.RPCGasCap
+}
+
+func (b *LesApiBackend) RPCCallGasCap() uint64 {
+	return b.aqua.config.RPCCallGasCap
+}
+
+func (b *LesApiBackend) SetHead(number uint64) error {
 	b.aqua.protocolManager.downloader.Cancel()
 	b.aqua.blockchain.SetHead(number)
+	return nil
 }
 
 func (b *LesApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
@@ -67,6 +85,10 @@ func (b *LesApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	return b.aqua.blockchain.GetHeaderByNumberOdr(ctx, uint64(blockNr))
 }
 
+func (b *LesApiBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.aqua.blockchain.GetHeaderByHash(hash), nil
+}
+
 func (b *LesApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
 	header, err := b.HeaderByNumber(ctx, blockNr)
 	if header == nil || err != nil {
@@ -77,12 +99,34 @@ func (b *LesApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 
 func (b *LesApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
 	header, err := b.HeaderByNumber(ctx, blockNr)
-	if header == nil || err != nil {
+	if err != nil {
 		return nil, nil, err
 	}
+	if header == nil {
+		return nil, nil, aquaapi.ErrHeaderNotFound
+	}
 	return light.NewState(ctx, header, b.ChainConfig().GetBlockVersion(header.Number), b.aqua.odr), header, nil
 }
 
+func (b *LesApiBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		return b.StateAndHeaderByNumber(ctx, blockNr)
+	}
+	hash, _ := blockNrOrHash.Hash()
+	header := b.aqua.blockchain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, nil, aquaapi.ErrHeaderNotFound
+	}
+	if blockNrOrHash.RequireCanonical && core.GetCanonicalHash(b.aqua.chainDb, header.Number.Uint64()) != hash {
+		return nil, nil, aquaapi.ErrNonCanonicalHash
+	}
+	return light.NewState(ctx, header, b.ChainConfig().GetBlockVersion(header.Number), b.aqua.odr), header, nil
+}
+
+func (b *LesApiBackend) AccountAt(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*core.AccountState, error) {
+	return nil, nil
+}
+
 func (b *LesApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
 	return b.aqua.blockchain.GetBlockByHash(ctx, blockHash)
 }
@@ -102,7 +146,7 @@ func (b *LesApiBackend) GetTd(blockHash common.Hash) *big.Int {
 func (b *LesApiBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
 	state.SetBalance(msg.From(), math.MaxBig256)
 	context := core.NewEVMContext(msg, header, b.aqua.blockchain, nil)
-	return vm.NewEVM(context, state, b.aqua.chainConfig, vmCfg), state.Error, nil
+	return vm.NewEVM(context, state, b.aqua.chainConfig, b.aqua.config.RPCVMConfig(vmCfg)), state.Error, nil
 }
 
 func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
@@ -157,6 +201,10 @@ func (b *LesApiBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEven
 	return b.aqua.blockchain.SubscribeRemovedLogsEvent(ch)
 }
 
+func (b *LesApiBackend) SubscribeReorgEvent(ch chan<- core.ReorgEvent) event.Subscription {
+	return b.aqua.blockchain.SubscribeReorgEvent(ch)
+}
+
 func (b *LesApiBackend) Downloader() *downloader.Downloader {
 	return b.aqua.Downloader()
 }
@@ -169,6 +217,10 @@ func (b *LesApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *LesApiBackend) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	return b.gpo.FeeHistory(ctx, blocks, lastBlock, percentiles)
+}
+
 func (b *LesApiBackend) ChainDb() aquadb.Database {
 	return b.aqua.chainDb
 }
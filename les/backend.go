@@ -90,6 +90,8 @@ func New(ctx *node.ServiceContext, config *aqua.Config) (*LightAquaChain, error)
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
+	config.Aquahash.Checkpoints = params.TrustedCheckpoints(genesisHash).Merge(config.Aquahash.Checkpoints)
+
 	peers := newPeerSet()
 	quitSync := make(chan struct{})
 
@@ -176,7 +178,7 @@ func (s *LightDummyAPI) Mining() bool {
 // APIs returns the collection of RPC services the aquachain package offers.
 // NOTE, some of these services probably need to be moved to somewhere else.
 func (s *LightAquaChain) APIs() []rpc.API {
-	return append(aquaapi.GetAPIs(s.ApiBackend), []rpc.API{
+	return append(aquaapi.GetAPIs(s.ApiBackend, !s.config.NoPersonal), []rpc.API{
 		{
 			Namespace: "aqua",
 			Version:   "1.0",
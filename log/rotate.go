@@ -0,0 +1,87 @@
+package log
+
+import (
+	"fmt"
+	"os"
+)
+
+// RotatingFileHandler returns a handler which writes log records to the file at
+// path using the given format, rotating it once it grows beyond maxSize bytes.
+// Rotated files are kept as path.1 (newest) to path.<maxBackups> (oldest), any
+// older ones are deleted.
+func RotatingFileHandler(path string, maxSize int64, maxBackups int, fmtr Format) (Handler, error) {
+	w, err := newRotatingWriter(path, maxSize, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+	return closingHandler{w, StreamHandler(w, fmtr)}, nil
+}
+
+// rotatingWriter is an io.WriteCloser appending to a file which is rotated when
+// reaching a size limit. It isn't safe for concurrent use, StreamHandler
+// serializes the writes.
+type rotatingWriter struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+}
+
+func newRotatingWriter(path string, maxSize int64, maxBackups int) (*rotatingWriter, error) {
+	w := &rotatingWriter{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// open opens the log file for appending, continuing at its current size.
+func (w *rotatingWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size = f, info.Size()
+	return nil
+}
+
+// rotate shifts the backups by one, moves the current file into the first
+// backup and reopens an empty one.
+func (w *rotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	if w.maxBackups > 0 {
+		for i := w.maxBackups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+		}
+		if err := os.Rename(w.path, w.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(w.path); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotatingWriter) Close() error {
+	return w.file.Close()
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log-rotate-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "node.log")
+	w, err := newRotatingWriter(path, 10, 2)
+	if err != nil {
+		t.Fatalf("failed to open log: %v", err)
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("failed to write %q: %v", line, err)
+		}
+	}
+	w.Close()
+
+	// The oldest line was rotated out, each file holds a single line
+	for file, want := range map[string]string{path: "fourth\n", path + ".1": "third\n", path + ".2": "second\n"} {
+		have, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		if string(have) != want {
+			t.Errorf("%s content mismatch: have %q, want %q", file, have, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("too many backups kept: %v", err)
+	}
+}
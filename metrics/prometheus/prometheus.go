@@ -0,0 +1,96 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package prometheus exposes a go-metrics registry in the Prometheus text format.
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aquanetwork/aquachain/metrics"
+)
+
+// quantiles are the percentiles reported for histograms and timers.
+var quantiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999}
+
+// Handler returns an HTTP handler serving the metrics of the registry in the
+// Prometheus text exposition format, prefixing every metric with namespace.
+func Handler(r metrics.Registry, namespace string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(Format(r, namespace))
+	})
+}
+
+// Format renders the metrics of the registry in the Prometheus text exposition
+// format, sorted by name.
+func Format(r metrics.Registry, namespace string) []byte {
+	names := make([]string, 0)
+	all := make(map[string]interface{})
+	r.Each(func(name string, i interface{}) {
+		names = append(names, name)
+		all[name] = i
+	})
+	sort.Strings(names)
+
+	buf := new(bytes.Buffer)
+	for _, name := range names {
+		key := mangle(namespace + "_" + name)
+		switch m := all[name].(type) {
+		case metrics.Counter:
+			writeValue(buf, key, "counter", float64(m.Count()))
+		case metrics.Gauge:
+			writeValue(buf, key, "gauge", float64(m.Value()))
+		case metrics.GaugeFloat64:
+			writeValue(buf, key, "gauge", m.Value())
+		case metrics.Meter:
+			writeValue(buf, key, "counter", float64(m.Snapshot().Count()))
+		case metrics.Histogram:
+			h := m.Snapshot()
+			writeSummary(buf, key, h.Percentiles(quantiles), h.Sum(), h.Count())
+		case metrics.Timer:
+			t := m.Snapshot()
+			writeSummary(buf, key, t.Percentiles(quantiles), t.Sum(), t.Count())
+		}
+	}
+	return buf.Bytes()
+}
+
+// mangle converts a metric name into one accepted by Prometheus.
+func mangle(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func writeValue(buf *bytes.Buffer, key, typ string, value float64) {
+	fmt.Fprintf(buf, "# TYPE %s %s\n%s %v\n", key, typ, key, value)
+}
+
+func writeSummary(buf *bytes.Buffer, key string, values []float64, sum, count int64) {
+	fmt.Fprintf(buf, "# TYPE %s summary\n", key)
+	for i, q := range quantiles {
+		fmt.Fprintf(buf, "%s{quantile=\"%v\"} %v\n", key, q, values[i])
+	}
+	fmt.Fprintf(buf, "%s_sum %d\n%s_count %d\n", key, sum, key, count)
+}
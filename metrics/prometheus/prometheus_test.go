@@ -0,0 +1,54 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/aquanetwork/aquachain/metrics"
+)
+
+func init() {
+	metrics.Enabled = true
+}
+
+func TestFormat(t *testing.T) {
+	r := metrics.NewRegistry()
+	metrics.NewRegisteredCounter("p2p/peers", r).Inc(3)
+	metrics.NewRegisteredGaugeFloat64("txpool/load-factor", r).Update(0.5)
+
+	h := metrics.NewRegisteredHistogram("aquahash/verify", r, metrics.NewUniformSample(16))
+	for i := int64(1); i <= 4; i++ {
+		h.Update(i)
+	}
+	want := `# TYPE aquachain_aquahash_verify summary
+aquachain_aquahash_verify{quantile="0.5"} 2.5
+aquachain_aquahash_verify{quantile="0.75"} 3.75
+aquachain_aquahash_verify{quantile="0.95"} 4
+aquachain_aquahash_verify{quantile="0.99"} 4
+aquachain_aquahash_verify{quantile="0.999"} 4
+aquachain_aquahash_verify_sum 10
+aquachain_aquahash_verify_count 4
+# TYPE aquachain_p2p_peers counter
+aquachain_p2p_peers 3
+# TYPE aquachain_txpool_load_factor gauge
+aquachain_txpool_load_factor 0.5
+`
+	if have := string(Format(r, "aquachain")); have != want {
+		t.Errorf("format mismatch:\nhave:\n%s\nwant:\n%s", have, want)
+	}
+}
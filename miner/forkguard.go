@@ -0,0 +1,187 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain/consensus"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/metrics"
+)
+
+const (
+	forkGuardInterval = 30 * time.Second // Time between two minority fork checks
+	forkGuardQuorum   = 3                // Number of peers that must advertise a heavier chain
+	forkGuardDepth    = 6                // Blocks worth of difficulty the local chain may lag behind
+)
+
+var (
+	forkPausedGauge = metrics.NewRegisteredGauge("miner/forkguard/paused", nil)
+	forkPauseMeter  = metrics.NewRegisteredMeter("miner/forkguard/pauses", nil)
+)
+
+// ForkPauseEvent is posted when mining is paused because the local chain seems
+// to be a minority fork.
+type ForkPauseEvent struct {
+	LocalTd  *big.Int // Total difficulty of the local chain
+	QuorumTd *big.Int // Total difficulty advertised by a quorum of peers
+}
+
+// PeerHead is the head of a connected peer, with the header fetched from it.
+type PeerHead struct {
+	Header *types.Header // Header of the head block
+	Td     *big.Int      // Total difficulty advertised for the head
+}
+
+// ForkResumeEvent is posted when mining is resumed after the local chain caught
+// up with the quorum of peers.
+type ForkResumeEvent struct {
+	LocalTd *big.Int // Total difficulty of the local chain
+}
+
+// ForkGuard periodically compares the local chain against the heads advertised
+// by the connected peers, pausing the miner while the local chain lags behind a
+// quorum of them by more than a few blocks, in which case any mined block is all
+// but certain to be orphaned.
+//
+// Only the peers whose head header carries a valid seal of a difficulty close to
+// the local one are counted, so a quorum can't be faked by merely advertising
+// heavy chains.
+type ForkGuard struct {
+	miner  *Miner
+	chain  *core.BlockChain
+	engine consensus.Engine
+	peers  func() []PeerHead // Heads of the connected peers whose headers were fetched
+	mux    *event.TypeMux
+
+	paused bool
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewForkGuard creates a minority fork guard for the miner. The peers callback
+// returns the heads of the connected peers whose headers were fetched.
+func NewForkGuard(miner *Miner, chain *core.BlockChain, peers func() []PeerHead, mux *event.TypeMux) *ForkGuard {
+	return &ForkGuard{
+		miner:  miner,
+		chain:  chain,
+		engine: chain.Engine(),
+		peers:  peers,
+		mux:    mux,
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start launches the periodic minority fork checks.
+func (g *ForkGuard) Start() {
+	g.wg.Add(1)
+	go g.loop()
+}
+
+// Stop terminates the checks. A paused miner is left paused, the guard being
+// stopped along with the node.
+func (g *ForkGuard) Stop() {
+	close(g.quit)
+	g.wg.Wait()
+}
+
+func (g *ForkGuard) loop() {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(forkGuardInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			g.check()
+		case <-g.quit:
+			return
+		}
+	}
+}
+
+// check pauses or resumes the miner according to the current peer heads.
+func (g *ForkGuard) check() {
+	head := g.chain.CurrentBlock()
+	localTd := g.chain.GetTd(head.Hash(), head.NumberU64())
+	if localTd == nil {
+		return
+	}
+	quorumTd, minority := minorityFork(localTd, head.Difficulty(), g.verifiedTds(head.Header(), g.peers()))
+
+	switch {
+	case minority && !g.paused:
+		log.Warn("Pausing mining on suspected minority fork", "number", head.Number(), "td", localTd, "quorumtd", quorumTd)
+		g.paused = true
+		g.miner.pause()
+		forkPausedGauge.Update(1)
+		forkPauseMeter.Mark(1)
+		g.mux.Post(ForkPauseEvent{LocalTd: localTd, QuorumTd: quorumTd})
+
+	case !minority && g.paused:
+		log.Info("Resuming mining, local chain rejoined the network", "number", head.Number(), "td", localTd)
+		g.paused = false
+		g.miner.resume()
+		forkPausedGauge.Update(0)
+		g.mux.Post(ForkResumeEvent{LocalTd: localTd})
+	}
+}
+
+// verifiedTds returns the total difficulties advertised by the peers whose head
+// header carries a valid seal, of at least half the difficulty of the local head.
+func (g *ForkGuard) verifiedTds(head *types.Header, peers []PeerHead) []*big.Int {
+	minDifficulty := new(big.Int).Rsh(head.Difficulty, 1)
+
+	tds := make([]*big.Int, 0, len(peers))
+	for _, peer := range peers {
+		if peer.Header.Difficulty.Cmp(minDifficulty) < 0 {
+			continue
+		}
+		if err := g.engine.VerifySeal(g.chain, peer.Header); err != nil {
+			log.Debug("Ignoring peer head with invalid seal", "number", peer.Header.Number, "hash", peer.Header.Hash(), "err", err)
+			continue
+		}
+		tds = append(tds, peer.Td)
+	}
+	return tds
+}
+
+// minorityFork reports whether a quorum of peers advertises a chain heavier than
+// the local one by more than forkGuardDepth blocks of the current difficulty,
+// along with the highest total difficulty advertised by such a quorum.
+func minorityFork(localTd, difficulty *big.Int, peerTds []*big.Int) (*big.Int, bool) {
+	if len(peerTds) < forkGuardQuorum {
+		return nil, false
+	}
+	tds := make([]*big.Int, len(peerTds))
+	copy(tds, peerTds)
+	sort.Slice(tds, func(i, j int) bool { return tds[i].Cmp(tds[j]) > 0 })
+
+	quorumTd := tds[forkGuardQuorum-1]
+	limit := new(big.Int).Mul(difficulty, big.NewInt(forkGuardDepth))
+	limit.Add(limit, localTd)
+
+	return quorumTd, quorumTd.Cmp(limit) > 0
+}
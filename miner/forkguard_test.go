@@ -0,0 +1,83 @@
+// Copyright 2016 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core/types"
+)
+
+// Tests that a minority fork is only reported if a quorum of peers advertises a
+// chain heavier by more than the allowed depth.
+func TestMinorityFork(t *testing.T) {
+	tds := func(values ...int64) []*big.Int {
+		list := make([]*big.Int, len(values))
+		for i, v := range values {
+			list[i] = big.NewInt(v)
+		}
+		return list
+	}
+	var (
+		local      = big.NewInt(1000)
+		difficulty = big.NewInt(10) // allowed lag of forkGuardDepth*10 = 60
+	)
+	tests := []struct {
+		peers    []*big.Int
+		minority bool
+	}{
+		{nil, false},                               // no peers at all
+		{tds(5000, 5000), false},                   // heavier, but below quorum
+		{tds(1060, 1060, 1060), false},             // lagging within the allowed depth
+		{tds(1061, 1061, 1061), true},              // lagging beyond the allowed depth
+		{tds(9000, 9000, 1000, 1000), false},       // only two heavier peers
+		{tds(900, 9000, 1000, 9000, 2000), true},   // quorum at 2000
+		{tds(1000, 1000, 1000, 1000, 1000), false}, // in sync with everyone
+		{tds(100, 100, 100, 100, 100, 100), false}, // peers lagging behind us
+	}
+	for i, tt := range tests {
+		if _, minority := minorityFork(local, difficulty, tt.peers); minority != tt.minority {
+			t.Errorf("test %d: minority mismatch: have %v, want %v", i, minority, tt.minority)
+		}
+	}
+}
+
+// Tests that only the peer heads with a valid seal of a difficulty close to the
+// local one are counted.
+func TestVerifiedPeerHeads(t *testing.T) {
+	guard := &ForkGuard{engine: aquahash.NewFakeFailer(7)}
+	head := &types.Header{Number: big.NewInt(5), Difficulty: big.NewInt(100)}
+
+	peer := func(number, difficulty, td int64) PeerHead {
+		return PeerHead{
+			Header: &types.Header{Number: big.NewInt(number), Difficulty: big.NewInt(difficulty)},
+			Td:     big.NewInt(td),
+		}
+	}
+	peers := []PeerHead{
+		peer(6, 100, 1000), // valid seal and difficulty
+		peer(6, 50, 2000),  // half the local difficulty
+		peer(6, 49, 3000),  // cheap fake header
+		peer(7, 100, 4000), // invalid seal
+	}
+	tds := guard.verifiedTds(head, peers)
+	if len(tds) != 2 || tds[0].Int64() != 1000 || tds[1].Int64() != 2000 {
+		t.Fatalf("verified total difficulties mismatch: have %v, want [1000 2000]", tds)
+	}
+}
@@ -55,6 +55,7 @@ type Miner struct {
 
 	canStart    int32 // can start indicates whether we can start the mining operation
 	shouldStart int32 // should start indicates whether we should start after sync
+	paused      int32 // paused indicates whether mining is held back on a suspected minority fork
 }
 
 func New(aqua Backend, config *params.ChainConfig, mux *event.TypeMux, engine consensus.Engine) *Miner {
@@ -112,6 +113,10 @@ func (self *Miner) Start(coinbase common.Address) {
 		log.Info("Network syncing, will start miner afterwards")
 		return
 	}
+	if atomic.LoadInt32(&self.paused) == 1 {
+		log.Info("Mining paused on suspected minority fork, will start miner afterwards")
+		return
+	}
 	atomic.StoreInt32(&self.mining, 1)
 
 	log.Info("Starting mining operation")
@@ -125,6 +130,24 @@ func (self *Miner) Stop() {
 	atomic.StoreInt32(&self.shouldStart, 0)
 }
 
+// pause holds back mining without forgetting whether it was requested, so that
+// resume can restart it.
+func (self *Miner) pause() {
+	atomic.StoreInt32(&self.paused, 1)
+	if self.Mining() {
+		self.worker.stop()
+		atomic.StoreInt32(&self.mining, 0)
+	}
+}
+
+// resume lifts a pause, restarting mining if it was requested in the meantime.
+func (self *Miner) resume() {
+	atomic.StoreInt32(&self.paused, 0)
+	if atomic.LoadInt32(&self.shouldStart) == 1 && !self.Mining() {
+		self.Start(self.coinbase)
+	}
+}
+
 func (self *Miner) Register(agent Agent) {
 	if self.Mining() {
 		agent.Start()
@@ -163,6 +186,28 @@ func (self *Miner) SetExtra(extra []byte) error {
 	return nil
 }
 
+// SetUnclePolicy sets the policy deciding which uncles are included in the
+// mined blocks.
+func (self *Miner) SetUnclePolicy(policy UnclePolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	self.worker.setUnclePolicy(policy)
+	return nil
+}
+
+// UnclePolicy returns the policy deciding which uncles are included in the
+// mined blocks.
+func (self *Miner) UnclePolicy() UnclePolicy {
+	return self.worker.getUnclePolicy()
+}
+
+// UncleStats returns the counts of the uncles included in and left out of the
+// mined blocks.
+func (self *Miner) UncleStats() UncleStats {
+	return self.worker.uncleStats.stats()
+}
+
 // Pending returns the currently pending block and associated state.
 func (self *Miner) Pending() (*types.Block, *state.StateDB) {
 	return self.worker.pending()
@@ -181,3 +226,9 @@ func (self *Miner) SetAquabase(addr common.Address) {
 	self.coinbase = addr
 	self.worker.setAquabase(addr)
 }
+
+// SetAquabases sets the addresses the block rewards rotate through by block
+// number, overriding the aquabase. An empty list stops the rotation.
+func (self *Miner) SetAquabases(addrs []common.Address) {
+	self.worker.setAquabases(addrs)
+}
@@ -42,15 +42,28 @@ type RemoteAgent struct {
 	workCh   chan *Work
 	returnCh chan<- *Result
 
-	chain       consensus.ChainReader
-	engine      consensus.Engine
-	currentWork *Work
-	work        map[common.Hash]*Work
+	chain          consensus.ChainReader
+	engine         consensus.Engine
+	currentWork    *Work
+	currentPackage [3]string             // getWork reply for currentWork, assembled on arrival
+	currentHanded  bool                  // whether currentWork was handed out to a miner
+	work           map[common.Hash]*Work // tasks handed out, by hash without nonce
+
+	lastHanded   *Work                     // task most recently handed out to a miner
+	previousWork *Work                     // task last handed out before the chain head changed
+	solved       map[common.Hash]time.Time // tasks already solved, by hash without nonce
+
+	staleGrace bool          // whether solutions for previousWork are still accepted
+	shares     shareCounters // outcomes of the submitted solutions
+
+	seedNumber uint64      // block number the seed hash was derived for
+	seedHash   common.Hash // seed hash of the current tasks, shared by those of the same parent
 
 	hashrateMu sync.RWMutex
 	hashrate   map[common.Hash]hashrate
 
-	running int32 // running indicates whether the agent is active. Call atomically
+	running int32  // running indicates whether the agent is active. Call atomically
+	stale   uint64 // number of solutions submitted for outdated work. Call atomically
 }
 
 func NewRemoteAgent(chain consensus.ChainReader, engine consensus.Engine) *RemoteAgent {
@@ -58,10 +71,21 @@ func NewRemoteAgent(chain consensus.ChainReader, engine consensus.Engine) *Remot
 		chain:    chain,
 		engine:   engine,
 		work:     make(map[common.Hash]*Work),
+		solved:   make(map[common.Hash]time.Time),
 		hashrate: make(map[common.Hash]hashrate),
 	}
 }
 
+// SetStaleGrace sets whether solutions for the work package handed out right
+// before the chain head changed are still accepted, competing with the new head
+// instead of being rejected as stale.
+func (a *RemoteAgent) SetStaleGrace(grace bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.staleGrace = grace
+}
+
 func (a *RemoteAgent) SubmitHashrate(id common.Hash, rate uint64) {
 	a.hashrateMu.Lock()
 	defer a.hashrateMu.Unlock()
@@ -106,43 +130,77 @@ func (a *RemoteAgent) GetHashRate() (tot int64) {
 	return
 }
 
+// GetWork returns the work package of the current sealing task, assembled when
+// the task arrived so polling miners don't rehash anything.
 func (a *RemoteAgent) GetWork() ([3]string, error) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	var res [3]string
+	if a.currentWork == nil {
+		return [3]string{}, errors.New("No work available yet, don't panic.")
+	}
+	if !a.currentHanded {
+		a.work[a.currentWork.Block.HashNoNonce()] = a.currentWork
+		a.currentHanded = true
+		a.lastHanded = a.currentWork
+	}
+	return a.currentPackage, nil
+}
 
-	if a.currentWork != nil {
-		block := a.currentWork.Block
+// setWork makes a sealing task the current one, assembling its work package.
+// The seed hash is only derived again once the block number changes.
+func (a *RemoteAgent) setWork(work *Work) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-		res[0] = block.HashNoNonce().Hex()
-		seedHash := aquahash.SeedHash(block.NumberU64())
-		res[1] = common.BytesToHash(seedHash).Hex()
-		// Calculate the "target" to be returned to the external miner
-		n := big.NewInt(1)
-		n.Lsh(n, 255)
-		n.Div(n, block.Difficulty())
-		n.Lsh(n, 1)
-		res[2] = common.BytesToHash(n.Bytes()).Hex()
+	block := work.Block
+	if number := block.NumberU64(); a.currentWork == nil || a.seedNumber != number {
+		a.seedNumber = number
+		a.seedHash = common.BytesToHash(aquahash.SeedHash(number))
+	}
+	// Calculate the "target" to be returned to the external miner
+	n := big.NewInt(1)
+	n.Lsh(n, 255)
+	n.Div(n, block.Difficulty())
+	n.Lsh(n, 1)
 
-		a.work[block.HashNoNonce()] = a.currentWork
-		return res, nil
+	if a.lastHanded != nil && a.lastHanded.Block.ParentHash() != block.ParentHash() {
+		a.previousWork = a.lastHanded
+	}
+	a.currentWork, a.currentHanded = work, false
+	a.currentPackage = [3]string{
+		block.HashNoNonce().Hex(),
+		a.seedHash.Hex(),
+		common.BytesToHash(n.Bytes()).Hex(),
 	}
-	return res, errors.New("No work available yet, don't panic.")
 }
 
 // SubmitWork tries to inject a pow solution into the remote agent, returning
 // whether the solution was accepted or not (not can be both a bad pow as well as
-// any other error, like no work pending).
-func (a *RemoteAgent) SubmitWork(nonce types.BlockNonce, mixDigest, hash common.Hash) bool {
+// any other error, like no work pending). The outcome is counted for the miner
+// identified by id, the zero hash if anonymous.
+func (a *RemoteAgent) SubmitWork(nonce types.BlockNonce, mixDigest, hash common.Hash, id common.Hash) bool {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	result := a.submitWork(nonce, mixDigest, hash)
+	a.shares.submitted(id, result)
+	return result == shareAccepted
+}
+
+// submitWork verifies a pow solution and hands it to the worker if it solves
+// current work, returning the outcome of the submission.
+func (a *RemoteAgent) submitWork(nonce types.BlockNonce, mixDigest, hash common.Hash) shareResult {
 	// Make sure the work submitted is present
 	work := a.work[hash]
 	if work == nil {
+		if _, ok := a.solved[hash]; ok {
+			log.Info("Work submitted was already solved", "hash", hash)
+			return shareDuplicate
+		}
 		log.Info("Work submitted but wasnt pending", "hash", hash)
-		return false
+		atomic.AddUint64(&a.stale, 1)
+		return shareStale
 	}
 	// Make sure the Engine solutions is indeed valid
 	result := work.Block.Header()
@@ -153,15 +211,22 @@ func (a *RemoteAgent) SubmitWork(nonce types.BlockNonce, mixDigest, hash common.
 	}
 	if err := a.engine.VerifySeal(a.chain, result); err != nil {
 		log.Warn("Invalid proof-of-work submitted", "hash", hash, "err", err)
-		return false
+		return shareInvalid
+	}
+	// Refuse solutions built on a replaced chain head, unless within the grace
+	if parent := a.currentWork.Block.ParentHash(); work.Block.ParentHash() != parent && !(a.staleGrace && work == a.previousWork) {
+		log.Info("Stale work submitted", "number", result.Number, "hash", hash)
+		atomic.AddUint64(&a.stale, 1)
+		return shareStale
 	}
 	block := work.Block.WithSeal(result)
 
 	// Solutions seems to be valid, return to the miner and notify acceptance
 	a.returnCh <- &Result{work, block}
 	delete(a.work, hash)
+	a.solved[hash] = work.createdAt
 
-	return true
+	return shareAccepted
 }
 
 // loop monitors mining events on the work and quit channels, updating the internal
@@ -179,9 +244,7 @@ func (a *RemoteAgent) loop(workCh chan *Work, quitCh chan struct{}) {
 		case <-quitCh:
 			return
 		case work := <-workCh:
-			a.mu.Lock()
-			a.currentWork = work
-			a.mu.Unlock()
+			a.setWork(work)
 		case <-ticker.C:
 			// cleanup
 			a.mu.Lock()
@@ -190,6 +253,11 @@ func (a *RemoteAgent) loop(workCh chan *Work, quitCh chan struct{}) {
 					delete(a.work, hash)
 				}
 			}
+			for hash, created := range a.solved {
+				if time.Since(created) > 7*(12*time.Second) {
+					delete(a.solved, hash)
+				}
+			}
 			a.mu.Unlock()
 
 			a.hashrateMu.Lock()
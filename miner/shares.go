@@ -0,0 +1,152 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"sync"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/metrics"
+)
+
+// maxShareSources caps the number of miner identifiers tracked separately, so
+// remote callers can't grow the counters without bound. Submissions of further
+// identifiers are counted under the zero identifier.
+const maxShareSources = 256
+
+var (
+	shareAcceptedMeter  = metrics.NewRegisteredMeter("miner/remote/accepted", nil)
+	shareStaleMeter     = metrics.NewRegisteredMeter("miner/remote/stale", nil)
+	shareDuplicateMeter = metrics.NewRegisteredMeter("miner/remote/duplicate", nil)
+	shareInvalidMeter   = metrics.NewRegisteredMeter("miner/remote/invalid", nil)
+)
+
+// shareResult is the outcome of a solution submitted by a remote miner.
+type shareResult int
+
+const (
+	shareAccepted  shareResult = iota // Valid solution for current work, handed to the worker
+	shareStale                        // Solution for outdated or unknown work
+	shareDuplicate                    // Solution for work already solved
+	shareInvalid                      // Solution not meeting the work's difficulty
+)
+
+// ShareCounts counts the solutions submitted by remote miners, by outcome.
+type ShareCounts struct {
+	Accepted  uint64 `json:"accepted"`
+	Stale     uint64 `json:"stale"`     // Work expired or built on a replaced chain head
+	Duplicate uint64 `json:"duplicate"` // Work already solved by an earlier submission
+	Invalid   uint64 `json:"invalid"`   // Seal failing verification
+}
+
+// ShareStats is a snapshot of the solutions submitted by remote miners, for
+// pools to tell their own latency issues from node problems.
+type ShareStats struct {
+	Total   ShareCounts                 `json:"total"`
+	Sources map[common.Hash]ShareCounts `json:"sources"` // By the identifier passed along the submissions, zero if none
+}
+
+// add counts a submission outcome.
+func (c *ShareCounts) add(result shareResult) {
+	switch result {
+	case shareAccepted:
+		c.Accepted++
+	case shareStale:
+		c.Stale++
+	case shareDuplicate:
+		c.Duplicate++
+	case shareInvalid:
+		c.Invalid++
+	}
+}
+
+// merge adds the counts of another set to this one.
+func (c *ShareCounts) merge(other ShareCounts) {
+	c.Accepted += other.Accepted
+	c.Stale += other.Stale
+	c.Duplicate += other.Duplicate
+	c.Invalid += other.Invalid
+}
+
+// shareCounters tracks the solutions submitted by remote miners, in total and
+// by miner identifier.
+type shareCounters struct {
+	total   ShareCounts
+	sources map[common.Hash]*ShareCounts
+	lock    sync.Mutex
+}
+
+// submitted records the outcome of a submission by the given miner.
+func (c *shareCounters) submitted(id common.Hash, result shareResult) {
+	switch result {
+	case shareAccepted:
+		shareAcceptedMeter.Mark(1)
+	case shareStale:
+		shareStaleMeter.Mark(1)
+	case shareDuplicate:
+		shareDuplicateMeter.Mark(1)
+	case shareInvalid:
+		shareInvalidMeter.Mark(1)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.sources == nil {
+		c.sources = make(map[common.Hash]*ShareCounts)
+	}
+	counts := c.sources[id]
+	if counts == nil {
+		if len(c.sources) >= maxShareSources {
+			id = common.Hash{}
+		}
+		if counts = c.sources[id]; counts == nil {
+			counts = new(ShareCounts)
+			c.sources[id] = counts
+		}
+	}
+	counts.add(result)
+	c.total.add(result)
+}
+
+// stats returns a snapshot of the counters.
+func (c *shareCounters) stats() ShareStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	stats := ShareStats{Total: c.total, Sources: make(map[common.Hash]ShareCounts, len(c.sources))}
+	for id, counts := range c.sources {
+		stats.Sources[id] = *counts
+	}
+	return stats
+}
+
+// ShareStats returns a snapshot of the solutions submitted by remote miners.
+func (self *Miner) ShareStats() ShareStats {
+	stats := ShareStats{Sources: make(map[common.Hash]ShareCounts)}
+	for agent := range self.worker.agents {
+		if remote, ok := agent.(*RemoteAgent); ok {
+			agentStats := remote.shares.stats()
+			stats.Total.merge(agentStats.Total)
+			for id, counts := range agentStats.Sources {
+				merged := stats.Sources[id]
+				merged.merge(counts)
+				stats.Sources[id] = merged
+			}
+		}
+	}
+	return stats
+}
@@ -0,0 +1,96 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core/types"
+)
+
+// Tests that the solutions submitted by remote miners are told apart as
+// accepted, stale, duplicate or invalid, and counted by miner.
+func TestRemoteShares(t *testing.T) {
+	agent := NewRemoteAgent(nil, aquahash.NewFakeFailer(5))
+	agent.SetReturnCh(make(chan *Result, 10))
+
+	task := func(number int64, parent common.Hash, extra byte) *Work {
+		header := &types.Header{Number: big.NewInt(number), ParentHash: parent, Difficulty: big.NewInt(1000), Extra: []byte{extra}, Version: 2}
+		return &Work{Block: types.NewBlockWithHeader(header), createdAt: time.Now()}
+	}
+	handOut := func(work *Work) common.Hash {
+		agent.setWork(work)
+		if _, err := agent.GetWork(); err != nil {
+			t.Fatalf("failed to get work: %v", err)
+		}
+		return work.Block.HashNoNonce()
+	}
+	var (
+		pool  = common.HexToHash("0x01")
+		other = common.HexToHash("0x02")
+	)
+	submit := func(hash common.Hash, id common.Hash, want bool) {
+		if have := agent.SubmitWork(types.BlockNonce{}, common.Hash{}, hash, id); have != want {
+			t.Fatalf("submission of %x acceptance mismatch: have %v, want %v", hash, have, want)
+		}
+	}
+	first := handOut(task(2, common.HexToHash("0xa"), 1))
+	submit(first, pool, true)
+	submit(first, pool, false) // duplicate
+
+	previous := handOut(task(2, common.HexToHash("0xa"), 2))
+	handOut(task(3, common.HexToHash("0xb"), 3))
+	submit(previous, pool, false) // stale, built on a replaced head
+	submit(common.HexToHash("0xdead"), other, false)
+
+	agent.SetStaleGrace(true)
+	submit(previous, other, true)
+
+	invalid := handOut(task(5, common.HexToHash("0xb"), 4))
+	submit(invalid, pool, false)
+
+	stats := agent.shares.stats()
+	if want := (ShareCounts{Accepted: 2, Stale: 2, Duplicate: 1, Invalid: 1}); stats.Total != want {
+		t.Errorf("total share counts mismatch: have %+v, want %+v", stats.Total, want)
+	}
+	if want := (ShareCounts{Accepted: 1, Stale: 1, Duplicate: 1, Invalid: 1}); stats.Sources[pool] != want {
+		t.Errorf("pool share counts mismatch: have %+v, want %+v", stats.Sources[pool], want)
+	}
+	if want := (ShareCounts{Accepted: 1, Stale: 1}); stats.Sources[other] != want {
+		t.Errorf("other share counts mismatch: have %+v, want %+v", stats.Sources[other], want)
+	}
+}
+
+// Tests that the miners tracked separately are capped, further ones counted
+// under the zero identifier.
+func TestShareSourcesCap(t *testing.T) {
+	var c shareCounters
+	for i := 0; i < maxShareSources+10; i++ {
+		c.submitted(common.BigToHash(big.NewInt(int64(i+1))), shareStale)
+	}
+	stats := c.stats()
+	if len(stats.Sources) != maxShareSources+1 {
+		t.Fatalf("tracked miners mismatch: have %d, want %d", len(stats.Sources), maxShareSources+1)
+	}
+	if have := stats.Sources[common.Hash{}].Stale; have != 10 {
+		t.Fatalf("overflow count mismatch: have %d, want %d", have, 10)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+	"github.com/aquanetwork/aquachain/core/types"
+)
+
+// maxUint256 is used to convert a difficulty into the target a seal must meet.
+var maxUint256 = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
+
+// Stats is a snapshot of the mining progress, for monitoring miners without
+// parsing their logs.
+type Stats struct {
+	Mining          bool           `json:"mining"`
+	Threads         int            `json:"threads"`         // Local search threads, zero if not mining with the CPU
+	Hashrate        hexutil.Uint64 `json:"hashrate"`        // Hashes per second of the local threads and remote miners
+	ThreadHashrates []float64      `json:"threadHashrates"` // Hashes per second of each local thread
+	Number          hexutil.Uint64 `json:"number"`          // Number of the block being mined
+	Difficulty      *hexutil.Big   `json:"difficulty"`      // Difficulty of the block being mined
+	Target          *hexutil.Big   `json:"target"`          // Maximum seal value meeting the difficulty
+	TimeToBlock     float64        `json:"timeToBlock"`     // Expected seconds to seal a block, zero if not hashing
+	Accepted        uint64         `json:"accepted"`        // Sealed blocks that became canonical
+	Stale           uint64         `json:"stale"`           // Sealed blocks that didn't, and outdated remote seals
+	LastSealed      *SealedBlock   `json:"lastSealed"`      // Most recently sealed block, nil if none yet
+}
+
+// SealedBlock describes a block sealed by the miner.
+type SealedBlock struct {
+	Number     hexutil.Uint64 `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	Difficulty *hexutil.Big   `json:"difficulty"`
+	Time       hexutil.Uint64 `json:"time"`      // Unix time of the sealing
+	Canonical  bool           `json:"canonical"` // Whether the block was canonical when written
+}
+
+// sealCounters tracks the blocks sealed by the worker.
+type sealCounters struct {
+	accepted uint64
+	stale    uint64
+
+	last *SealedBlock
+	lock sync.Mutex
+}
+
+// sealed records a sealed block, canonical or not when written.
+func (c *sealCounters) sealed(block *types.Block, canonical bool) {
+	if canonical {
+		atomic.AddUint64(&c.accepted, 1)
+	} else {
+		atomic.AddUint64(&c.stale, 1)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.last = &SealedBlock{
+		Number:     hexutil.Uint64(block.NumberU64()),
+		Hash:       block.Hash(),
+		Difficulty: (*hexutil.Big)(new(big.Int).Set(block.Difficulty())),
+		Time:       hexutil.Uint64(time.Now().Unix()),
+		Canonical:  canonical,
+	}
+}
+
+// lastSealed returns the most recently sealed block, nil if none.
+func (c *sealCounters) lastSealed() *SealedBlock {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.last == nil {
+		return nil
+	}
+	last := *c.last
+	return &last
+}
+
+// Stats returns a snapshot of the mining progress.
+func (self *Miner) Stats() Stats {
+	stats := Stats{
+		Mining:          self.Mining(),
+		Hashrate:        hexutil.Uint64(self.HashRate()),
+		ThreadHashrates: []float64{},
+		Accepted:        atomic.LoadUint64(&self.worker.seals.accepted),
+		Stale:           atomic.LoadUint64(&self.worker.seals.stale),
+		LastSealed:      self.worker.seals.lastSealed(),
+	}
+	if pow, ok := self.engine.(interface{ ThreadHashrates() []float64 }); ok && stats.Mining {
+		stats.ThreadHashrates = pow.ThreadHashrates()
+		stats.Threads = len(stats.ThreadHashrates)
+	}
+	for agent := range self.worker.agents {
+		if remote, ok := agent.(*RemoteAgent); ok {
+			stats.Stale += atomic.LoadUint64(&remote.stale)
+		}
+	}
+	if header := self.worker.pendingHeader(); header != nil {
+		stats.Number = hexutil.Uint64(header.Number.Uint64())
+		if header.Difficulty != nil && header.Difficulty.Sign() > 0 {
+			stats.Difficulty = (*hexutil.Big)(new(big.Int).Set(header.Difficulty))
+			stats.Target = (*hexutil.Big)(new(big.Int).Div(maxUint256, header.Difficulty))
+			if stats.Mining && stats.Hashrate > 0 {
+				expected, _ := new(big.Float).Quo(new(big.Float).SetInt(header.Difficulty), new(big.Float).SetUint64(uint64(stats.Hashrate))).Float64()
+				stats.TimeToBlock = expected
+			}
+		}
+	}
+	return stats
+}
@@ -0,0 +1,43 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/core/types"
+)
+
+// Tests that the sealed blocks are counted as accepted or stale, and the last
+// one recorded.
+func TestSealCounters(t *testing.T) {
+	var c sealCounters
+	if c.lastSealed() != nil {
+		t.Fatalf("last sealed block reported before sealing")
+	}
+	for i, canonical := range []bool{true, false, true} {
+		c.sealed(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(int64(i + 1)), Difficulty: big.NewInt(100)}), canonical)
+	}
+	if c.accepted != 2 || c.stale != 1 {
+		t.Fatalf("seal counts mismatch: have %d/%d, want %d/%d", c.accepted, c.stale, 2, 1)
+	}
+	last := c.lastSealed()
+	if last == nil || last.Number != 3 || !last.Canonical || last.Difficulty.ToInt().Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("last sealed block mismatch: have %+v", last)
+	}
+}
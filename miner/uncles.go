@@ -0,0 +1,117 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Uncle inclusion modes.
+const (
+	UnclesAlways     = "always"     // Include any valid uncle
+	UnclesNever      = "never"      // Never include uncles
+	UnclesProfitable = "profitable" // Include uncles whose reward outweighs their verification delay
+)
+
+// UnclePolicy decides which of the valid uncles the miner includes in its
+// blocks, at most one since HF5.
+//
+// An included uncle earns the miner a fraction of the block reward (and the
+// uncle reward too if the miner mined the uncle), but peers have to verify its
+// seal before relaying the block, delaying its propagation. In the profitable
+// mode, an uncle is only included if the reward outweighs the block reward
+// times the odds of being orphaned because of the delay, estimated as the
+// verification cost over the target block time.
+type UnclePolicy struct {
+	Mode       string        // Inclusion mode, UnclesAlways if empty
+	VerifyCost time.Duration // Estimated time peers spend verifying an uncle
+}
+
+// DefaultUnclePolicy includes any valid uncle.
+var DefaultUnclePolicy = UnclePolicy{
+	Mode:       UnclesAlways,
+	VerifyCost: 200 * time.Millisecond,
+}
+
+// UncleStats counts the valid uncles included in and left out of the mined
+// blocks by the uncle policy.
+type UncleStats struct {
+	Included uint64 `json:"included"`
+	Skipped  uint64 `json:"skipped"`
+}
+
+// Validate checks the mode of the policy.
+func (p UnclePolicy) Validate() error {
+	switch p.Mode {
+	case "", UnclesAlways, UnclesNever, UnclesProfitable:
+		return nil
+	}
+	return fmt.Errorf("unknown uncle inclusion mode %q (want %q, %q or %q)", p.Mode, UnclesAlways, UnclesNever, UnclesProfitable)
+}
+
+// include returns whether the uncle is worth including in the block of the
+// given header, mined to coinbase.
+func (p UnclePolicy) include(config *params.ChainConfig, header, uncle *types.Header, coinbase common.Address) bool {
+	switch p.Mode {
+	case UnclesNever:
+		return false
+	case UnclesProfitable:
+		era := config.RewardEra(header.Number)
+		if era == nil {
+			return false
+		}
+		return uncleGain(era, header, uncle, coinbase).Cmp(uncleDelayCost(era, p.VerifyCost)) > 0
+	}
+	return true
+}
+
+// uncleGain returns the reward the miner earns by including the uncle, mirroring
+// the aquahash reward rules.
+func uncleGain(era *params.RewardEra, header, uncle *types.Header, coinbase common.Address) *big.Int {
+	gain := era.InclusionReward()
+	if uncle.Coinbase == coinbase {
+		gain.Add(gain, era.UncleReward(new(big.Int).Sub(header.Number, uncle.Number).Uint64()))
+	}
+	return gain
+}
+
+// uncleDelayCost returns the expected reward lost to orphaning because of the
+// verification delay of an uncle.
+func uncleDelayCost(era *params.RewardEra, verify time.Duration) *big.Int {
+	cost := new(big.Int).Mul(era.BlockReward, big.NewInt(int64(verify)))
+	return cost.Div(cost, new(big.Int).Mul(params.DurationLimit, big.NewInt(int64(time.Second))))
+}
+
+// uncleCounters tracks the uncle inclusion statistics of the worker.
+type uncleCounters struct {
+	included uint64
+	skipped  uint64
+}
+
+func (c *uncleCounters) stats() UncleStats {
+	return UncleStats{
+		Included: atomic.LoadUint64(&c.included),
+		Skipped:  atomic.LoadUint64(&c.skipped),
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// Tests that the uncle policy modes include the expected uncles.
+func TestUnclePolicy(t *testing.T) {
+	var (
+		coinbase = common.Address{0xaa}
+		header   = &types.Header{Number: big.NewInt(100)}
+		foreign  = &types.Header{Number: big.NewInt(99), Coinbase: common.Address{0xbb}}
+		own      = &types.Header{Number: big.NewInt(99), Coinbase: coinbase}
+	)
+	tests := []struct {
+		policy  UnclePolicy
+		uncle   *types.Header
+		include bool
+	}{
+		{UnclePolicy{}, foreign, true},
+		{UnclePolicy{Mode: UnclesAlways}, foreign, true},
+		{UnclePolicy{Mode: UnclesNever}, own, false},
+		// The nephew reward is 1/32 of the block reward, worth a 7.5s delay at a
+		// 240s block time
+		{UnclePolicy{Mode: UnclesProfitable, VerifyCost: 7 * time.Second}, foreign, true},
+		{UnclePolicy{Mode: UnclesProfitable, VerifyCost: 8 * time.Second}, foreign, false},
+		// Own uncles earn the uncle reward too
+		{UnclePolicy{Mode: UnclesProfitable, VerifyCost: 8 * time.Second}, own, true},
+		{UnclePolicy{Mode: UnclesProfitable, VerifyCost: 4 * time.Minute}, own, false},
+	}
+	for i, tt := range tests {
+		if have := tt.policy.include(params.TestChainConfig, header, tt.uncle, coinbase); have != tt.include {
+			t.Errorf("test %d: inclusion mismatch: have %v, want %v", i, have, tt.include)
+		}
+	}
+	if err := (UnclePolicy{Mode: "sometimes"}).Validate(); err == nil {
+		t.Errorf("unknown mode accepted")
+	}
+}
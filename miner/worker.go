@@ -19,6 +19,7 @@ package miner
 import (
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -48,6 +49,9 @@ const (
 	chainHeadChanSize = 10
 	// chainSideChanSize is the size of channel listening to ChainSideEvent.
 	chainSideChanSize = 10
+	// recommitInterval is the minimum time between two sealing tasks of the same
+	// block, batching the transactions appended in between.
+	recommitInterval = time.Second
 )
 
 // Agent can register themself with the worker
@@ -70,12 +74,15 @@ type Work struct {
 	family    *set.Set       // family set (used for checking uncle invalidity)
 	uncles    *set.Set       // uncle set
 	tcount    int            // tx count in cycle
+	skipped   int            // valid uncles left out by the uncle policy
+	gasPool   *core.GasPool  // gas left for appending transactions
 
 	Block *types.Block // the new block
 
-	header   *types.Header
-	txs      []*types.Transaction
-	receipts []*types.Receipt
+	header      *types.Header
+	txs         []*types.Transaction
+	receipts    []*types.Receipt
+	uncleHeader []*types.Header // uncles committed to the block
 
 	createdAt time.Time
 }
@@ -110,14 +117,21 @@ type worker struct {
 	proc    core.Validator
 	chainDb aquadb.Database
 
-	coinbase common.Address
-	extra    []byte
+	coinbase  common.Address
+	coinbases []common.Address // Coinbases rotated through by block number, overriding coinbase if set
+	extra     []byte
 
 	currentMu sync.Mutex
-	current   *Work
+	current   *Work // environment of the pending block, open for appending transactions
+	task      *Work // finalized copy of current being sealed, nil if not mining
+	dirty     bool  // whether transactions were appended to current since task was made
 
 	uncleMu        sync.Mutex
 	possibleUncles map[common.Hash]*types.Block
+	unclePolicy    UnclePolicy
+	uncleStats     uncleCounters // uncle inclusion counts of the mined blocks
+
+	seals sealCounters // accepted and stale counts of the sealed blocks
 
 	unconfirmed *unconfirmedBlocks // set of locally mined blocks pending canonicalness confirmations
 
@@ -140,6 +154,7 @@ func newWorker(config *params.ChainConfig, engine consensus.Engine, coinbase com
 		chain:          aqua.BlockChain(),
 		proc:           aqua.BlockChain().Validator(),
 		possibleUncles: make(map[common.Hash]*types.Block),
+		unclePolicy:    DefaultUnclePolicy,
 		coinbase:       coinbase,
 		agents:         make(map[Agent]struct{}),
 		unconfirmed:    newUnconfirmedBlocks(aqua.BlockChain(), miningLogAtDepth),
@@ -163,6 +178,33 @@ func (self *worker) setAquabase(addr common.Address) {
 	self.coinbase = addr
 }
 
+func (self *worker) setAquabases(addrs []common.Address) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.coinbases = append([]common.Address{}, addrs...)
+}
+
+// coinbaseAt returns the coinbase of the block of the given number, rotating
+// through the coinbases if set.
+func (self *worker) coinbaseAt(number *big.Int) common.Address {
+	if len(self.coinbases) == 0 {
+		return self.coinbase
+	}
+	return self.coinbases[new(big.Int).Mod(number, big.NewInt(int64(len(self.coinbases)))).Uint64()]
+}
+
+func (self *worker) setUnclePolicy(policy UnclePolicy) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.unclePolicy = policy
+}
+
+func (self *worker) getUnclePolicy() UnclePolicy {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.unclePolicy
+}
+
 func (self *worker) setExtra(extra []byte) {
 	self.mu.Lock()
 	defer self.mu.Unlock()
@@ -173,7 +215,7 @@ func (self *worker) pending() (*types.Block, *state.StateDB) {
 	self.currentMu.Lock()
 	defer self.currentMu.Unlock()
 
-	if atomic.LoadInt32(&self.mining) == 0 {
+	if atomic.LoadInt32(&self.mining) == 0 || self.task == nil {
 		return types.NewBlock(
 			self.current.header,
 			self.current.txs,
@@ -181,14 +223,26 @@ func (self *worker) pending() (*types.Block, *state.StateDB) {
 			self.current.receipts,
 		), self.current.state.Copy()
 	}
-	return self.current.Block, self.current.state.Copy()
+	return self.task.Block, self.task.state.Copy()
+}
+
+// pendingHeader returns a copy of the header of the block being mined, nil if
+// there's none.
+func (self *worker) pendingHeader() *types.Header {
+	self.currentMu.Lock()
+	defer self.currentMu.Unlock()
+
+	if self.current == nil || self.current.header == nil {
+		return nil
+	}
+	return types.CopyHeader(self.current.header)
 }
 
 func (self *worker) pendingBlock() *types.Block {
 	self.currentMu.Lock()
 	defer self.currentMu.Unlock()
 
-	if atomic.LoadInt32(&self.mining) == 0 {
+	if atomic.LoadInt32(&self.mining) == 0 || self.task == nil {
 		return types.NewBlock(
 			self.current.header,
 			self.current.txs,
@@ -196,7 +250,7 @@ func (self *worker) pendingBlock() *types.Block {
 			self.current.receipts,
 		)
 	}
-	return self.current.Block
+	return self.task.Block
 }
 
 func (self *worker) start() {
@@ -244,6 +298,9 @@ func (self *worker) update() {
 	defer self.chainHeadSub.Unsubscribe()
 	defer self.chainSideSub.Unsubscribe()
 
+	recommit := time.NewTicker(recommitInterval)
+	defer recommit.Stop()
+
 	for {
 		// A real event arrived, process interesting content
 		select {
@@ -257,23 +314,23 @@ func (self *worker) update() {
 			self.possibleUncles[ev.Block.Hash()] = ev.Block
 			self.uncleMu.Unlock()
 
-		// Handle TxPreEvent
+		// Handle TxPreEvent, appending the whole burst to the pending block
 		case ev := <-self.txCh:
-			// Apply transaction to the pending state if we're not mining
-			if atomic.LoadInt32(&self.mining) == 0 {
-				self.currentMu.Lock()
-				acc, _ := types.Sender(self.current.signer, ev.Tx)
-				txs := map[common.Address]types.Transactions{acc: {ev.Tx}}
-				txset := types.NewTransactionsByPriceAndNonce(self.current.signer, txs)
-
-				self.current.commitTransactions(self.mux, txset, self.chain, self.coinbase)
-				self.currentMu.Unlock()
-			} else {
-				// If we're mining, but nothing is being processed, wake on new transactions
-				if self.config.Clique != nil && self.config.Clique.Period == 0 {
-					self.commitNewWork()
+			txs := []*types.Transaction{ev.Tx}
+		drain:
+			for {
+				select {
+				case ev := <-self.txCh:
+					txs = append(txs, ev.Tx)
+				default:
+					break drain
 				}
 			}
+			self.appendTransactions(txs)
+
+		// Seal the transactions appended since the last task
+		case <-recommit.C:
+			self.recommit()
 
 		// System stopped
 		case <-self.txSub.Err():
@@ -311,8 +368,10 @@ func (self *worker) wait() {
 			stat, err := self.chain.WriteBlockWithState(block, work.receipts, work.state)
 			if err != nil {
 				log.Error("Failed writing block to chain", "err", err)
+				self.seals.sealed(block, false)
 				continue
 			}
+			self.seals.sealed(block, stat == core.CanonStatTy)
 			// check if canon block and write transactions
 			if stat == core.CanonStatTy {
 				// implicit by posting ChainHeadEvent
@@ -332,6 +391,8 @@ func (self *worker) wait() {
 
 			// Insert the block into the set of pending ones to wait for confirmations
 			self.unconfirmed.Insert(block.NumberU64(), hash)
+			atomic.AddUint64(&self.uncleStats.included, uint64(len(block.Uncles())))
+			atomic.AddUint64(&self.uncleStats.skipped, uint64(work.skipped))
 
 			if mustCommitNewWork {
 				self.commitNewWork()
@@ -362,7 +423,7 @@ func (self *worker) makeCurrent(parent *types.Block, header *types.Header) error
 
 	work := &Work{
 		config:    self.config,
-		signer:    types.NewEIP155Signer(self.config.ChainId),
+		signer:    types.NewFeeMarketSigner(self.config.ChainId),
 		state:     state,
 		ancestors: set.New(),
 		family:    set.New(),
@@ -382,6 +443,7 @@ func (self *worker) makeCurrent(parent *types.Block, header *types.Header) error
 
 	// Keep track of transactions which return errors so they can be removed
 	work.tcount = 0
+	work.gasPool = new(core.GasPool).AddGas(header.GasLimit)
 	self.current = work
 	return nil
 }
@@ -418,9 +480,14 @@ func (self *worker) commitNewWork() {
 		Time:       big.NewInt(tstamp),
 		Version:    self.chain.Config().GetBlockVersion(numnew),
 	}
+	if self.chain.Config().IsFeeMarket(numnew) {
+		header.BaseFee = misc.CalcBaseFee(self.chain.Config(), parent.Header())
+	}
+	coinbase := self.coinbaseAt(numnew)
+
 	// Only set the coinbase if we are mining (avoid spurious block rewards)
 	if atomic.LoadInt32(&self.mining) == 1 {
-		header.Coinbase = self.coinbase
+		header.Coinbase = coinbase
 	}
 	if err := self.engine.Prepare(self.chain, header); err != nil {
 		log.Error("Failed to prepare header for mining", "err", err)
@@ -462,7 +529,7 @@ func (self *worker) commitNewWork() {
 		return
 	}
 	txs := types.NewTransactionsByPriceAndNonce(self.current.signer, pending)
-	work.commitTransactions(self.mux, txs, self.chain, self.coinbase)
+	work.commitTransactions(self.mux, txs, self.chain, coinbase)
 
 	// compute uncles for the new block.
 	var (
@@ -488,6 +555,12 @@ func (self *worker) commitNewWork() {
 		// 	badUncles = append(badUncles, hash)
 		// }
 
+		if !self.unclePolicy.include(self.config, header, unclehead, coinbase) {
+			log.Trace("Uncle left out by the inclusion policy", "hash", hash, "mode", self.unclePolicy.Mode)
+			work.uncles.Remove(unclehead.Hash())
+			work.skipped++
+			continue
+		}
 		log.Debug("Committing new uncle to block", "hash", hash)
 		uncles = append(uncles, uncle.Header())
 
@@ -495,8 +568,10 @@ func (self *worker) commitNewWork() {
 	for _, hash := range badUncles {
 		delete(self.possibleUncles, hash)
 	}
+	work.uncleHeader = uncles
+
 	// Create the new block to seal with the consensus engine
-	if work.Block, err = self.engine.Finalize(self.chain, header, work.state, work.txs, uncles, work.receipts); err != nil {
+	if err := self.seal(work); err != nil {
 		log.Error("Failed to finalize block for sealing", "err", err)
 		return
 	}
@@ -508,7 +583,88 @@ func (self *worker) commitNewWork() {
 			"algo", header.Version)
 		self.unconfirmed.Shift(work.Block.NumberU64() - 1)
 	}
-	self.push(work)
+	self.push(self.task)
+}
+
+// seal finalizes a copy of the pending block environment into a sealing task,
+// leaving the environment itself open for appending transactions.
+//
+// The caller must hold currentMu.
+func (self *worker) seal(env *Work) error {
+	receipts := make([]*types.Receipt, len(env.receipts))
+	for i, receipt := range env.receipts {
+		receipts[i] = new(types.Receipt)
+		*receipts[i] = *receipt
+	}
+	task := &Work{
+		config:      env.config,
+		signer:      env.signer,
+		state:       env.state.Copy(),
+		ancestors:   env.ancestors,
+		family:      env.family,
+		uncles:      env.uncles,
+		tcount:      env.tcount,
+		skipped:     env.skipped,
+		header:      types.CopyHeader(env.header),
+		txs:         append([]*types.Transaction(nil), env.txs...),
+		receipts:    receipts,
+		uncleHeader: env.uncleHeader,
+		createdAt:   time.Now(),
+	}
+	block, err := self.engine.Finalize(self.chain, task.header, task.state, task.txs, task.uncleHeader, task.receipts)
+	if err != nil {
+		return err
+	}
+	task.Block, env.Block = block, block
+	self.task, self.dirty = task, false
+	return nil
+}
+
+// appendTransactions applies a burst of new transactions on top of the pending
+// block, instead of rebuilding it from the whole pool. While mining, the block
+// is resealed with them at the next recommit.
+func (self *worker) appendTransactions(txs []*types.Transaction) {
+	self.currentMu.Lock()
+	defer self.currentMu.Unlock()
+
+	env := self.current
+	if env == nil {
+		return
+	}
+	grouped := make(map[common.Address]types.Transactions)
+	for _, tx := range txs {
+		from, _ := types.Sender(env.signer, tx) // already validated by the pool
+		grouped[from] = append(grouped[from], tx)
+	}
+	for _, list := range grouped {
+		sort.Sort(types.TxByNonce(list))
+	}
+	tcount := env.tcount
+	env.commitTransactions(self.mux, types.NewTransactionsByPriceAndNonce(env.signer, grouped), self.chain, self.coinbaseAt(env.header.Number))
+
+	if env.tcount > tcount && atomic.LoadInt32(&self.mining) == 1 {
+		log.Debug("Appended transactions to mining work", "number", env.header.Number, "txs", env.tcount-tcount)
+		self.dirty = true
+	}
+}
+
+// recommit reseals the pending block if transactions were appended to it since
+// the sealing task was made.
+func (self *worker) recommit() {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.currentMu.Lock()
+	defer self.currentMu.Unlock()
+
+	if !self.dirty || atomic.LoadInt32(&self.mining) == 0 {
+		return
+	}
+	if err := self.seal(self.current); err != nil {
+		log.Error("Failed to finalize block for sealing", "err", err)
+		return
+	}
+	log.Debug("Resealing mining work", "number", self.task.Block.Number(), "txs", self.task.tcount)
+	self.push(self.task)
 }
 
 func (self *worker) commitUncle(work *Work, uncle *types.Header) error {
@@ -530,7 +686,7 @@ func (self *worker) commitUncle(work *Work, uncle *types.Header) error {
 }
 
 func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsByPriceAndNonce, bc *core.BlockChain, coinbase common.Address) {
-	gp := new(core.GasPool).AddGas(env.header.GasLimit)
+	gp := env.gasPool
 
 	var coalescedLogs []*types.Log
 
@@ -578,6 +734,16 @@ func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsB
 			log.Trace("Skipping account with hight nonce", "sender", from, "nonce", tx.Nonce())
 			txs.Pop()
 
+		case core.ErrSenderNotPermitted:
+			// The sender is not permitted on the network (any more), skip account
+			log.Trace("Skipping account not permitted to transact", "sender", from)
+			txs.Pop()
+
+		case core.ErrFeeCapTooLow:
+			// The base fee outgrew what the account is willing to pay, skip account
+			log.Trace("Skipping account with low fee cap", "sender", from, "feecap", tx.GasFeeCap())
+			txs.Pop()
+
 		case nil:
 			// Everything ok, collect the logs and shift in the next transaction from the same account
 			coalescedLogs = append(coalescedLogs, logs...)
@@ -0,0 +1,187 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/event"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+var (
+	testBankKey, _  = crypto.GenerateKey()
+	testBankAddress = crypto.PubkeyToAddress(testBankKey.PublicKey)
+)
+
+// testBackend implements Backend over an in-memory chain with a funded account.
+type testBackend struct {
+	db     aquadb.Database
+	chain  *core.BlockChain
+	txPool *core.TxPool
+}
+
+func newTestBackend(t *testing.T) *testBackend {
+	db, _ := aquadb.NewMemDatabase()
+	gspec := core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{testBankAddress: {Balance: big.NewInt(1000000000000000000)}},
+	}
+	gspec.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	poolConfig := core.DefaultTxPoolConfig
+	poolConfig.Journal, poolConfig.Snapshot = "", ""
+
+	return &testBackend{db: db, chain: chain, txPool: core.NewTxPool(poolConfig, gspec.Config, chain)}
+}
+
+func (b *testBackend) AccountManager() *accounts.Manager { return nil }
+func (b *testBackend) BlockChain() *core.BlockChain      { return b.chain }
+func (b *testBackend) TxPool() *core.TxPool              { return b.txPool }
+func (b *testBackend) ChainDb() aquadb.Database          { return b.db }
+
+// newTestWorker creates a worker without its event loops, so tests drive it.
+func newTestWorker(backend *testBackend) *worker {
+	return &worker{
+		config:         backend.chain.Config(),
+		engine:         backend.chain.Engine(),
+		aqua:           backend,
+		mux:            new(event.TypeMux),
+		chain:          backend.chain,
+		possibleUncles: make(map[common.Hash]*types.Block),
+		unclePolicy:    DefaultUnclePolicy,
+		coinbase:       common.Address{0xaa},
+		agents:         make(map[Agent]struct{}),
+		unconfirmed:    newUnconfirmedBlocks(backend.chain, miningLogAtDepth),
+		mining:         1,
+	}
+}
+
+func newTestTransaction(nonce uint64) *types.Transaction {
+	tx := types.NewTransaction(nonce, common.Address{0xbb}, big.NewInt(1), params.TxGas, big.NewInt(1), nil)
+	tx, _ = types.SignTx(tx, types.NewEIP155Signer(params.TestChainConfig.ChainId), testBankKey)
+	return tx
+}
+
+// Tests that the coinbase rotates through the configured list by block number,
+// falling back to the single coinbase.
+func TestCoinbaseRotation(t *testing.T) {
+	w := &worker{coinbase: common.Address{0xaa}}
+	if have := w.coinbaseAt(big.NewInt(7)); have != w.coinbase {
+		t.Fatalf("coinbase mismatch without rotation: have %x, want %x", have, w.coinbase)
+	}
+	w.setAquabases([]common.Address{{1}, {2}, {3}})
+	for number, want := range []common.Address{{1}, {2}, {3}, {1}, {2}} {
+		if have := w.coinbaseAt(big.NewInt(int64(number))); have != want {
+			t.Errorf("block %d: coinbase mismatch: have %x, want %x", number, have, want)
+		}
+	}
+	w.setAquabases(nil)
+	if have := w.coinbaseAt(big.NewInt(7)); have != w.coinbase {
+		t.Fatalf("coinbase mismatch after clearing rotation: have %x, want %x", have, w.coinbase)
+	}
+}
+
+// Tests that new transactions are appended to the pending block without
+// rebuilding it, and sealed at the next recommit.
+func TestAppendTransactions(t *testing.T) {
+	backend := newTestBackend(t)
+	defer backend.txPool.Stop()
+
+	w := newTestWorker(backend)
+	w.commitNewWork()
+	if w.task == nil || len(w.task.Block.Transactions()) != 0 {
+		t.Fatalf("initial sealing task mismatch: %v", w.task)
+	}
+	initial := w.task
+
+	// Appended transactions aren't sealed before the recommit, a burst at once
+	w.appendTransactions([]*types.Transaction{newTestTransaction(1), newTestTransaction(0)})
+	if !w.dirty || w.task != initial {
+		t.Fatalf("appended transactions sealed before the recommit")
+	}
+	w.recommit()
+	if w.dirty || w.task == initial || len(w.task.Block.Transactions()) != 2 {
+		t.Fatalf("sealing task mismatch after recommit: dirty %v, txs %d", w.dirty, len(w.task.Block.Transactions()))
+	}
+	// Further transactions use up the remaining gas of the same block, while the
+	// stale or gapped ones are left out
+	w.appendTransactions([]*types.Transaction{newTestTransaction(2), newTestTransaction(0), newTestTransaction(5)})
+	w.recommit()
+	if have := len(w.task.Block.Transactions()); have != 3 {
+		t.Fatalf("transaction count mismatch: have %d, want 3", have)
+	}
+	if have := w.current.header.GasUsed; have != 3*params.TxGas {
+		t.Fatalf("gas used mismatch: have %d, want %d", have, 3*params.TxGas)
+	}
+	// The sealed block is final, the pending environment isn't finalized
+	if w.task.Block.Root() == initial.Block.Root() || w.current.header.Root != (common.Hash{}) {
+		t.Fatalf("finalization mismatch: sealed root %x, pending root %x", w.task.Block.Root(), w.current.header.Root)
+	}
+	// Without new transactions, nothing is resealed
+	task := w.task
+	w.recommit()
+	if w.task != task {
+		t.Fatalf("work resealed without new transactions")
+	}
+}
+
+// Tests that the remote agent hands out the work package assembled on arrival,
+// only tracking the tasks actually handed out.
+func TestRemoteWorkPackages(t *testing.T) {
+	agent := NewRemoteAgent(nil, nil)
+	if _, err := agent.GetWork(); err == nil {
+		t.Fatalf("work handed out before any arrived")
+	}
+	task := func(number int64, extra byte) *Work {
+		header := &types.Header{Number: big.NewInt(number), Difficulty: big.NewInt(1000), Extra: []byte{extra}, Version: 2}
+		return &Work{Block: types.NewBlockWithHeader(header)}
+	}
+	first, second := task(1, 1), task(1, 2)
+
+	agent.setWork(first)
+	agent.setWork(second) // replaces the first before any miner polled it
+	pkg, err := agent.GetWork()
+	if err != nil {
+		t.Fatalf("failed to get work: %v", err)
+	}
+	if pkg[0] != second.Block.HashNoNonce().Hex() {
+		t.Fatalf("work hash mismatch: have %s, want %s", pkg[0], second.Block.HashNoNonce().Hex())
+	}
+	if pkg[1] != common.BytesToHash(aquahash.SeedHash(1)).Hex() {
+		t.Fatalf("seed hash mismatch: have %s", pkg[1])
+	}
+	if again, _ := agent.GetWork(); again != pkg {
+		t.Fatalf("work package changed between polls")
+	}
+	if len(agent.work) != 1 || agent.work[second.Block.HashNoNonce()] != second {
+		t.Fatalf("tracked tasks mismatch: have %d", len(agent.work))
+	}
+}
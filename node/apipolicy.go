@@ -0,0 +1,219 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+// sensitiveNamespaces are the RPC namespaces controlling the node, its accounts
+// or its miner, which must not be served to untrusted clients.
+var sensitiveNamespaces = map[string]bool{
+	"admin":    true,
+	"debug":    true,
+	"miner":    true,
+	"personal": true,
+}
+
+// unsafeNamespaces are the sensitive namespaces refused on HTTP and websocket
+// endpoints reachable by untrusted clients, unless explicitly allowed.
+var unsafeNamespaces = map[string]bool{
+	"admin":    true,
+	"debug":    true,
+	"personal": true,
+}
+
+// apiRuleRegexp matches the rules of an API policy: an optionally negated
+// namespace or method name.
+var apiRuleRegexp = regexp.MustCompile(`^-?[a-z0-9]+(_[a-zA-Z0-9]+)?$`)
+
+// APIPolicy selects the RPC APIs an endpoint serves from a list of rules, each
+// enabling a namespace ("debug") or a single method ("debug_traceTransaction"),
+// or disabling it if prefixed with "-". Method rules take precedence over the
+// namespace rules, and a namespace only enabled by method rules serves those
+// methods alone. Subscriptions are matched by name (e.g. "aqua_newHeads").
+//
+// Without any enabling rule, an endpoint serves the APIs designated public, less
+// the disabled ones. Namespaces and methods denied on top of the rules are never
+// served.
+type APIPolicy struct {
+	namespaces map[string]bool // Namespaces enabled or disabled as a whole
+	methods    map[string]bool // Methods enabled or disabled, by full name
+	partial    map[string]bool // Namespaces with methods enabled by method rules
+	denied     map[string]bool // Namespaces and methods never served
+}
+
+// ParseAPIPolicy creates the API policy of a list of rules.
+func ParseAPIPolicy(rules []string) (*APIPolicy, error) {
+	p := &APIPolicy{
+		namespaces: make(map[string]bool),
+		methods:    make(map[string]bool),
+		partial:    make(map[string]bool),
+		denied:     make(map[string]bool),
+	}
+	for _, rule := range rules {
+		if rule == "" {
+			continue
+		}
+		if !apiRuleRegexp.MatchString(rule) {
+			return nil, fmt.Errorf("invalid API rule %q", rule)
+		}
+		enable := !strings.HasPrefix(rule, "-")
+		name := strings.TrimPrefix(rule, "-")
+
+		if i := strings.Index(name, "_"); i >= 0 {
+			p.methods[name] = enable
+			if enable {
+				p.partial[name[:i]] = true
+			}
+			continue
+		}
+		p.namespaces[name] = enable
+	}
+	return p, nil
+}
+
+// Deny disables namespaces or methods regardless of the rules of the policy,
+// and of whether an endpoint exposes all APIs.
+func (p *APIPolicy) Deny(names []string) error {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if strings.HasPrefix(name, "-") || !apiRuleRegexp.MatchString(name) {
+			return fmt.Errorf("invalid denied API %q", name)
+		}
+		p.denied[name] = true
+	}
+	return nil
+}
+
+// Empty returns whether the policy has no rules.
+func (p *APIPolicy) Empty() bool {
+	return len(p.namespaces) == 0 && len(p.methods) == 0
+}
+
+// Defaults returns whether the policy has no enabling rules, only disabling some
+// of the APIs an endpoint serves by default.
+func (p *APIPolicy) Defaults() bool {
+	if len(p.partial) > 0 {
+		return false
+	}
+	for _, enable := range p.namespaces {
+		if enable {
+			return false
+		}
+	}
+	return true
+}
+
+// Disables returns whether a namespace is disabled as a whole, by a rule or a
+// denial.
+func (p *APIPolicy) Disables(namespace string) bool {
+	enable, ok := p.namespaces[namespace]
+	return p.denied[namespace] || (ok && !enable)
+}
+
+// Exposes returns whether any method of the API is served under the policy.
+func (p *APIPolicy) Exposes(api rpc.API) bool {
+	if p.denied[api.Namespace] {
+		return false
+	}
+	if enable, ok := p.namespaces[api.Namespace]; ok {
+		return enable
+	}
+	if p.Defaults() {
+		return api.Public
+	}
+	return p.partial[api.Namespace]
+}
+
+// Method returns whether a method of a served namespace is enabled.
+func (p *APIPolicy) Method(namespace, method string) bool {
+	if p.denied[namespace] || p.denied[namespace+"_"+method] {
+		return false
+	}
+	if enable, ok := p.methods[namespace+"_"+method]; ok {
+		return enable
+	}
+	if enable, ok := p.namespaces[namespace]; ok {
+		return enable
+	}
+	return !p.partial[namespace]
+}
+
+// methodFilter returns the filter of the enabled methods of a namespace, for
+// registering it in an RPC server.
+func (p *APIPolicy) methodFilter(namespace string) func(method string) bool {
+	return func(method string) bool {
+		return p.Method(namespace, method)
+	}
+}
+
+// sensitive returns the sensitive namespaces served under the policy, or the
+// enabled methods of those only partially served, sorted.
+func (p *APIPolicy) sensitive(namespaces map[string]bool) []string {
+	var names []string
+	for namespace := range namespaces {
+		if !sensitiveNamespaces[namespace] {
+			continue
+		}
+		if p.namespaces[namespace] || !p.partial[namespace] {
+			names = append(names, namespace)
+			continue
+		}
+		for method, enable := range p.methods {
+			if enable && strings.HasPrefix(method, namespace+"_") {
+				names = append(names, method)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// unsafeAPIs returns the names of the sensitive APIs belonging to the unsafe
+// namespaces.
+func unsafeAPIs(sensitive []string) []string {
+	var unsafe []string
+	for _, name := range sensitive {
+		if unsafeNamespaces[strings.SplitN(name, "_", 2)[0]] {
+			unsafe = append(unsafe, name)
+		}
+	}
+	return unsafe
+}
+
+// isLoopbackEndpoint returns whether a listening endpoint is only reachable
+// from the local host.
+func isLoopbackEndpoint(endpoint string) bool {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,170 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/rpc"
+)
+
+// Tests that the API rules select the namespaces and methods served.
+func TestAPIPolicy(t *testing.T) {
+	policy, err := ParseAPIPolicy([]string{"aqua", "net", "-aqua_sign", "debug_traceTransaction", "-web3", ""})
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+	exposes := map[string]bool{"aqua": true, "net": true, "debug": true, "web3": false, "personal": false}
+	for namespace, want := range exposes {
+		if have := policy.Exposes(rpc.API{Namespace: namespace, Public: true}); have != want {
+			t.Errorf("namespace %s: exposed mismatch: have %v, want %v", namespace, have, want)
+		}
+	}
+	methods := []struct {
+		namespace, method string
+		want              bool
+	}{
+		{"aqua", "getBalance", true},
+		{"aqua", "sign", false},
+		{"debug", "traceTransaction", true},
+		{"debug", "setHead", false},
+		{"admin", "peers", true}, // Not mentioned, served only if exposed otherwise
+	}
+	for _, tt := range methods {
+		if have := policy.Method(tt.namespace, tt.method); have != tt.want {
+			t.Errorf("method %s_%s: enabled mismatch: have %v, want %v", tt.namespace, tt.method, have, tt.want)
+		}
+	}
+	if have, want := policy.sensitive(map[string]bool{"aqua": true, "debug": true, "admin": true}), []string{"admin", "debug_traceTransaction"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("sensitive APIs mismatch: have %v, want %v", have, want)
+	}
+	// Without rules, the public APIs are served
+	policy, _ = ParseAPIPolicy(nil)
+	if !policy.Empty() || !policy.Exposes(rpc.API{Namespace: "aqua", Public: true}) || policy.Exposes(rpc.API{Namespace: "personal"}) {
+		t.Errorf("empty policy doesn't serve the public APIs alone")
+	}
+	for _, rule := range []string{"aqua.sign", "-", "AQUA", "aqua_"} {
+		if _, err := ParseAPIPolicy([]string{rule}); err == nil {
+			t.Errorf("invalid rule %q accepted", rule)
+		}
+	}
+}
+
+// Tests that policies with only disabling rules serve the public APIs less the
+// disabled ones.
+func TestAPIPolicyNegativeOnly(t *testing.T) {
+	policy, err := ParseAPIPolicy([]string{"-net", "-aqua_sign"})
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+	if policy.Empty() || !policy.Defaults() {
+		t.Fatalf("policy defaults mismatch: empty %v, defaults %v", policy.Empty(), policy.Defaults())
+	}
+	apis := []struct {
+		api  rpc.API
+		want bool
+	}{
+		{rpc.API{Namespace: "aqua", Public: true}, true},
+		{rpc.API{Namespace: "web3", Public: true}, true},
+		{rpc.API{Namespace: "net", Public: true}, false},
+		{rpc.API{Namespace: "personal"}, false},
+	}
+	for _, tt := range apis {
+		if have := policy.Exposes(tt.api); have != tt.want {
+			t.Errorf("namespace %s: exposed mismatch: have %v, want %v", tt.api.Namespace, have, tt.want)
+		}
+	}
+	if policy.Method("aqua", "sign") || !policy.Method("aqua", "getBalance") {
+		t.Errorf("disabled method rules not applied")
+	}
+	if !policy.Disables("net") || policy.Disables("personal") {
+		t.Errorf("namespace disabling mismatch")
+	}
+	// An enabling rule drops the defaults
+	policy, _ = ParseAPIPolicy([]string{"-net", "debug_traceTransaction"})
+	if policy.Defaults() || policy.Exposes(rpc.API{Namespace: "aqua", Public: true}) {
+		t.Errorf("public APIs served along with enabling rules")
+	}
+}
+
+// Tests that the unsafe APIs are picked from the sensitive ones.
+func TestUnsafeAPIs(t *testing.T) {
+	have := unsafeAPIs([]string{"admin", "debug_traceTransaction", "miner", "personal_unlockAccount"})
+	if want := []string{"admin", "debug_traceTransaction", "personal_unlockAccount"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("unsafe APIs mismatch: have %v, want %v", have, want)
+	}
+}
+
+// Tests that denied namespaces and methods are never served, whatever the rules.
+func TestAPIPolicyDeny(t *testing.T) {
+	policy, _ := ParseAPIPolicy([]string{"aqua", "personal", "debug_traceTransaction"})
+	if err := policy.Deny([]string{"personal", "aqua_sign", "debug_traceTransaction", ""}); err != nil {
+		t.Fatalf("failed to deny APIs: %v", err)
+	}
+	exposes := map[string]bool{"aqua": true, "personal": false}
+	for namespace, want := range exposes {
+		if have := policy.Exposes(rpc.API{Namespace: namespace, Public: true}); have != want {
+			t.Errorf("namespace %s: exposed mismatch: have %v, want %v", namespace, have, want)
+		}
+	}
+	if !policy.Disables("personal") || policy.Disables("aqua") {
+		t.Errorf("namespace denial mismatch")
+	}
+	methods := []struct {
+		namespace, method string
+		want              bool
+	}{
+		{"aqua", "getBalance", true},
+		{"aqua", "sign", false},
+		{"personal", "listAccounts", false},
+		{"debug", "traceTransaction", false},
+	}
+	for _, tt := range methods {
+		if have := policy.Method(tt.namespace, tt.method); have != tt.want {
+			t.Errorf("method %s_%s: enabled mismatch: have %v, want %v", tt.namespace, tt.method, have, tt.want)
+		}
+	}
+	// Denials apply to policies without rules too
+	policy, _ = ParseAPIPolicy(nil)
+	policy.Deny([]string{"aqua"})
+	if !policy.Empty() || policy.Exposes(rpc.API{Namespace: "aqua", Public: true}) {
+		t.Errorf("denied namespace exposed by empty policy")
+	}
+	for _, name := range []string{"-aqua", "aqua.sign", "AQUA"} {
+		if err := policy.Deny([]string{name}); err == nil {
+			t.Errorf("invalid denied API %q accepted", name)
+		}
+	}
+}
+
+// Tests that endpoints only reachable from the local host are told apart.
+func TestLoopbackEndpoint(t *testing.T) {
+	tests := map[string]bool{
+		"localhost:8543": true,
+		"127.0.0.1:8543": true,
+		"[::1]:8543":     true,
+		":8543":          false,
+		"0.0.0.0:8543":   false,
+		"10.0.0.1:8543":  false,
+	}
+	for endpoint, want := range tests {
+		if have := isLoopbackEndpoint(endpoint); have != want {
+			t.Errorf("%s: loopback mismatch: have %v, want %v", endpoint, have, want)
+		}
+	}
+}
@@ -24,10 +24,13 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/aquanetwork/aquachain/accounts"
+	"github.com/aquanetwork/aquachain/accounts/external"
 	"github.com/aquanetwork/aquachain/accounts/keystore"
 	"github.com/aquanetwork/aquachain/accounts/usbwallet"
+	"github.com/aquanetwork/aquachain/accounts/vault"
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/crypto"
 	"github.com/aquanetwork/aquachain/log"
@@ -82,15 +85,56 @@ type Config struct {
 	// scrypt KDF at the expense of security.
 	UseLightweightKDF bool `toml:",omitempty"`
 
+	// KDF names the scrypt parameter preset of new and updated keyfiles, light or
+	// standard. It takes precedence over UseLightweightKDF if set.
+	KDF string `toml:",omitempty"`
+
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
+	// ExternalSigner is the IPC path or HTTP URL of an external signer. If set,
+	// all signing is delegated to it and neither the keystore nor hardware
+	// wallets are opened by the node.
+	ExternalSigner string `toml:",omitempty"`
+
+	// ExternalSignerAsync selects the asynchronous signer API, submitting the
+	// signing requests and polling for their completion, for threshold signers.
+	ExternalSignerAsync bool `toml:",omitempty"`
+
+	// ExternalSignerTimeout is the time allowed for the external signer to answer
+	// a signing request, including any manual approval. Zero selects the default
+	// of five minutes.
+	ExternalSignerTimeout time.Duration `toml:",omitempty"`
+
+	// KeyStoreBackend selects where the keys of the node are kept: "file" (the
+	// default) for the encrypted key files of the keystore directory, or "vault"
+	// for the remote secret store at VaultURL, keeping them off the local disk.
+	KeyStoreBackend string `toml:",omitempty"`
+
+	// VaultURL is the HashiCorp Vault KV version 2 secrets engine holding the keys
+	// with the "vault" keystore backend, as http(s)://host:port/<mount>/<path>.
+	// The access token is read from the VAULT_TOKEN environment variable.
+	VaultURL string `toml:",omitempty"`
+
+	// VaultUnlock is the time the "vault" keystore backend signs for once its
+	// wallet is opened. Zero selects vault.DefaultUnlockTimeout.
+	VaultUnlock time.Duration `toml:",omitempty"`
+
+	// DBEngine is the storage engine of newly created databases. Existing ones
+	// are always opened with the engine they were created with, and refused if
+	// a different one is requested explicitly. Empty selects the default.
+	DBEngine string `toml:",omitempty"`
+
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
 	// relative), then that specific path is enforced. An empty path disables IPC.
 	IPCPath string `toml:",omitempty"`
 
+	// IPCModules is a list of API rules selecting the modules and methods to expose
+	// via the IPC interface (see APIPolicy). If empty, all APIs are exposed.
+	IPCModules []string `toml:",omitempty"`
+
 	// HTTPHost is the host interface on which to start the HTTP RPC server. If this
 	// field is empty, no HTTP API endpoint will be started.
 	HTTPHost string `toml:",omitempty"`
@@ -114,9 +158,9 @@ type Config struct {
 	// Requests using ip address directly are not affected
 	HTTPVirtualHosts []string `toml:",omitempty"`
 
-	// HTTPModules is a list of API modules to expose via the HTTP RPC interface.
-	// If the module list is empty, all RPC API endpoints designated public will be
-	// exposed.
+	// HTTPModules is a list of API rules selecting the modules and methods to
+	// expose via the HTTP RPC interface (see APIPolicy). If the list is empty, all
+	// RPC API endpoints designated public will be exposed.
 	HTTPModules []string `toml:",omitempty"`
 
 	// WSHost is the host interface on which to start the websocket RPC server. If
@@ -133,9 +177,14 @@ type Config struct {
 	// cannot verify the validity of the request header.
 	WSOrigins []string `toml:",omitempty"`
 
-	// WSModules is a list of API modules to expose via the websocket RPC interface.
-	// If the module list is empty, all RPC API endpoints designated public will be
-	// exposed.
+	// WSVirtualHosts is the list of virtual hostnames which are allowed on incoming
+	// websocket requests, as HTTPVirtualHosts for HTTP. If the list is empty, any
+	// host name is accepted.
+	WSVirtualHosts []string `toml:",omitempty"`
+
+	// WSModules is a list of API rules selecting the modules and methods to expose
+	// via the websocket RPC interface (see APIPolicy). If the list is empty, all
+	// RPC API endpoints designated public will be exposed.
 	WSModules []string `toml:",omitempty"`
 
 	// WSExposeAll exposes all API modules via the WebSocket RPC interface rather
@@ -145,6 +194,47 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// RPCAdminToken is the bearer token authorizing HTTP and websocket requests to
+	// the admin API. If empty, the admin API is only available over IPC, unless
+	// WSExposeAll is set.
+	RPCAdminToken string `toml:"-"`
+
+	// RPCAuthModules is a list of API rules selecting the modules and methods served
+	// over HTTP and websocket to requests authenticated with the admin token (see
+	// APIPolicy). If the list is empty, those requests are served the APIs of the
+	// endpoint and the admin API.
+	RPCAuthModules []string `toml:",omitempty"`
+
+	// RPCDeny is a list of modules and methods never served over HTTP and
+	// websocket, whether requests are authenticated or not.
+	RPCDeny []string `toml:",omitempty"`
+
+	// RPCAllowUnsafeAPIs allows serving the admin, debug and personal APIs without
+	// authentication over HTTP and websocket endpoints reachable from other hosts
+	// or any web page. Otherwise those endpoints refuse to start.
+	//
+	// *WARNING* Anyone reaching such an endpoint can control the node and drain
+	// its unlocked accounts.
+	RPCAllowUnsafeAPIs bool `toml:",omitempty"`
+
+	// RPCRateLimit is the number of requests per second each remote IP may make to
+	// the HTTP and websocket endpoints, and RPCRateBurst the number it may make at
+	// once, defaulting to a second worth. Zero disables rate limiting.
+	RPCRateLimit float64 `toml:",omitempty"`
+	RPCRateBurst int     `toml:",omitempty"`
+
+	// RPCBatchLimit is the maximum number of requests in a batch, and
+	// RPCResponseLimit the maximum size in bytes of the result of a call, sent
+	// over HTTP and websocket. Zero disables the respective limit. None of the
+	// limits apply to requests authenticated with the admin token.
+	RPCBatchLimit    int `toml:",omitempty"`
+	RPCResponseLimit int `toml:",omitempty"`
+
+	// RPCStrictJSON encodes the results of all RPC endpoints as canonical JSON,
+	// with sorted object keys and integers as hex quantities, so that responses
+	// keep the same format across releases.
+	RPCStrictJSON bool `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 }
@@ -380,6 +470,13 @@ func (c *Config) AccountConfig() (int, int, string, error) {
 		scryptN = keystore.LightScryptN
 		scryptP = keystore.LightScryptP
 	}
+	if c.KDF != "" {
+		n, p, err := keystore.ScryptParams(c.KDF)
+		if err != nil {
+			return 0, 0, "", err
+		}
+		scryptN, scryptP = n, p
+	}
 
 	var (
 		keydir string
@@ -401,23 +498,51 @@ func (c *Config) AccountConfig() (int, int, string, error) {
 }
 
 func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
-	scryptN, scryptP, keydir, err := conf.AccountConfig()
-	var ephemeral string
-	if keydir == "" {
-		// There is no datadir.
-		keydir, err = ioutil.TempDir("", "aquachain-keystore")
-		ephemeral = keydir
-	}
-
-	if err != nil {
-		return nil, "", err
-	}
-	if err := os.MkdirAll(keydir, 0700); err != nil {
-		return nil, "", err
+	if conf.ExternalSigner != "" {
+		// Keys are held by the external signer, keep them out of the node
+		log.Info("Using external signer", "url", conf.ExternalSigner)
+		newBackend := external.NewBackend
+		if conf.ExternalSignerAsync {
+			newBackend = external.NewAsyncBackend
+		}
+		extapi, err := newBackend(conf.ExternalSigner, conf.ExternalSignerTimeout)
+		if err != nil {
+			return nil, "", fmt.Errorf("error connecting to external signer: %v", err)
+		}
+		return accounts.NewManager(extapi), "", nil
 	}
 	// Assemble the account manager and supported backends
-	backends := []accounts.Backend{
-		keystore.NewKeyStore(keydir, scryptN, scryptP),
+	var (
+		backends  []accounts.Backend
+		ephemeral string
+	)
+	switch conf.KeyStoreBackend {
+	case "", "file":
+		scryptN, scryptP, keydir, err := conf.AccountConfig()
+		if keydir == "" {
+			// There is no datadir.
+			keydir, err = ioutil.TempDir("", "aquachain-keystore")
+			ephemeral = keydir
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if err := os.MkdirAll(keydir, 0700); err != nil {
+			return nil, "", err
+		}
+		backends = append(backends, keystore.NewKeyStore(keydir, scryptN, scryptP))
+
+	case "vault":
+		// Keys are held by the remote vault, keep them off the disk
+		log.Info("Using remote key vault", "url", conf.VaultURL)
+		vaultapi, err := vault.NewBackend(conf.VaultURL, "", conf.VaultUnlock)
+		if err != nil {
+			return nil, "", fmt.Errorf("error opening remote key vault: %v", err)
+		}
+		backends = append(backends, vaultapi)
+
+	default:
+		return nil, "", fmt.Errorf("unknown keystore backend %q", conf.KeyStoreBackend)
 	}
 	if !conf.NoUSB {
 		// Start a USB hub for Ledger hardware wallets
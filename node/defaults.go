@@ -27,10 +27,12 @@ import (
 )
 
 const (
-	DefaultHTTPHost = "localhost" // Default host interface for the HTTP RPC server
-	DefaultHTTPPort = 8543        // Default TCP port for the HTTP RPC server
-	DefaultWSHost   = "localhost" // Default host interface for the websocket RPC server
-	DefaultWSPort   = 8544        // Default TCP port for the websocket RPC server
+	DefaultHTTPHost    = "localhost" // Default host interface for the HTTP RPC server
+	DefaultHTTPPort    = 8543        // Default TCP port for the HTTP RPC server
+	DefaultWSHost      = "localhost" // Default host interface for the websocket RPC server
+	DefaultWSPort      = 8544        // Default TCP port for the websocket RPC server
+	DefaultGraphQLHost = "localhost" // Default host interface for the GraphQL server
+	DefaultGraphQLPort = 8547        // Default TCP port for the GraphQL server
 )
 
 // DefaultConfig contains reasonable default settings.
@@ -40,6 +42,10 @@ var DefaultConfig = Config{
 	HTTPModules: []string{"net", "web3"},
 	WSPort:      DefaultWSPort,
 	WSModules:   []string{"net", "web3"},
+
+	RPCBatchLimit:    1000,
+	RPCResponseLimit: 25 * 1024 * 1024,
+
 	P2P: p2p.Config{
 		ListenAddr: ":21303",
 		MaxPeers:   50,
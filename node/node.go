@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -62,10 +63,12 @@ type Node struct {
 	httpWhitelist []string     // HTTP RPC modules to allow through this endpoint
 	httpListener  net.Listener // HTTP RPC listener socket to server API requests
 	httpHandler   *rpc.Server  // HTTP RPC request handler to process the API requests
+	httpAdmin     *rpc.Server  // HTTP RPC request handler to process authenticated admin requests
 
 	wsEndpoint string       // Websocket endpoint (interface + port) to listen at (empty = websocket disabled)
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
+	wsAdmin    *rpc.Server  // Websocket RPC request handler to process authenticated admin requests
 
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
@@ -268,7 +271,7 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 		n.stopInProc()
 		return err
 	}
-	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll); err != nil {
+	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSVirtualHosts, n.config.WSExposeAll); err != nil {
 		n.stopHTTP()
 		n.stopIPC()
 		n.stopInProc()
@@ -283,8 +286,9 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 func (n *Node) startInProc(apis []rpc.API) error {
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	handler.SetStrictJSON(n.config.RPCStrictJSON)
 	for _, api := range apis {
-		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+		if err := handler.RegisterAPI(api, nil); err != nil {
 			return err
 		}
 		n.log.Debug("InProc registered", "service", api.Service, "namespace", api.Namespace)
@@ -307,20 +311,30 @@ func (n *Node) startIPC(apis []rpc.API) error {
 	if n.ipcEndpoint == "" {
 		return nil
 	}
-	// Register all the APIs exposed by the services
+	// Register the APIs exposed by the services, all of them but the disabled ones
+	// without any enabling rule
+	policy, err := ParseAPIPolicy(n.config.IPCModules)
+	if err != nil {
+		return fmt.Errorf("IPC: %v", err)
+	}
 	handler := rpc.NewServer()
+	handler.SetStrictJSON(n.config.RPCStrictJSON)
 	for _, api := range apis {
-		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+		if policy.Defaults() {
+			if policy.Disables(api.Namespace) {
+				continue
+			}
+		} else if !policy.Exposes(api) {
+			continue
+		}
+		if err := handler.RegisterAPI(api, policy.methodFilter(api.Namespace)); err != nil {
 			return err
 		}
 		n.log.Debug("IPC registered", "service", api.Service, "namespace", api.Namespace)
 	}
 	// All APIs registered, start the IPC listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = rpc.CreateIPCListener(n.ipcEndpoint); err != nil {
+	listener, err := rpc.CreateIPCListener(n.ipcEndpoint)
+	if err != nil {
 		return err
 	}
 	go func() {
@@ -370,39 +384,153 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	if endpoint == "" {
 		return nil
 	}
-	// Generate the whitelist based on the allowed modules
-	whitelist := make(map[string]bool)
-	for _, module := range modules {
-		whitelist[module] = true
+	// Register the APIs exposed by the services under the configured policy
+	policy, err := n.rpcPolicy(modules)
+	if err != nil {
+		return fmt.Errorf("HTTP: %v", err)
 	}
-	// Register all the APIs exposed by the services
-	handler := rpc.NewServer()
-	for _, api := range apis {
-		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
-			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
-				return err
-			}
-			n.log.Debug("HTTP registered", "service", api.Service, "namespace", api.Namespace)
-		}
+	handler, admin, sensitive, err := n.newRPCHandlers("HTTP", apis, policy, false)
+	if err != nil {
+		return err
+	}
+	if err := n.checkSensitiveAPIs("HTTP", endpoint, sensitive, cors); err != nil {
+		return err
 	}
+
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
 		return err
 	}
-	go rpc.NewHTTPServer(cors, vhosts, handler).Serve(listener)
+	var served http.Handler = handler
+	if admin != nil {
+		served = rpc.NewAuthHandler(n.config.RPCAdminToken, handler, admin)
+	}
+	go (&http.Server{Handler: rpc.NewHTTPHandlerStack(served, cors, vhosts)}).Serve(listener)
 	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
 	n.httpListener = listener
 	n.httpHandler = handler
+	n.httpAdmin = admin
 
 	return nil
 }
 
+// rpcPolicy creates the API policy of an HTTP or websocket endpoint from its
+// rules, denying the APIs configured never to be served over those.
+func (n *Node) rpcPolicy(rules []string) (*APIPolicy, error) {
+	policy, err := ParseAPIPolicy(rules)
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.Deny(n.config.RPCDeny); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// newRPCHandlers creates the request handler of an HTTP or websocket endpoint,
+// registering the APIs selected by the policy, or all of them but the denied
+// ones if exposeAll is set. The admin namespace is left out unless exposeAll is
+// set. Instead, if an admin token is configured, a second handler is returned
+// for requests authenticated with it, which serves the APIs selected by the
+// authenticated rules, or else the endpoint's and the admin namespace. Only the
+// first handler is subject to the request limits. The sensitive APIs served
+// without authentication are returned too.
+func (n *Node) newRPCHandlers(kind string, apis []rpc.API, policy *APIPolicy, exposeAll bool) (*rpc.Server, *rpc.Server, []string, error) {
+	handler := rpc.NewServer()
+	handler.SetStrictJSON(n.config.RPCStrictJSON)
+	handler.SetLimits(rpc.Limits{
+		RequestRate:   n.config.RPCRateLimit,
+		RequestBurst:  n.config.RPCRateBurst,
+		BatchItems:    n.config.RPCBatchLimit,
+		ResponseBytes: n.config.RPCResponseLimit,
+	})
+
+	var (
+		admin      *rpc.Server
+		authPolicy *APIPolicy
+		warned     bool
+		served     = make(map[string]bool)
+	)
+	if n.config.RPCAdminToken != "" && !exposeAll {
+		admin = rpc.NewServer()
+		admin.SetStrictJSON(n.config.RPCStrictJSON)
+
+		if len(n.config.RPCAuthModules) > 0 {
+			var err error
+			if authPolicy, err = n.rpcPolicy(n.config.RPCAuthModules); err != nil {
+				return nil, nil, nil, fmt.Errorf("%s authenticated: %v", kind, err)
+			}
+		}
+	}
+	for _, api := range apis {
+		expose := policy.Exposes(api) || (exposeAll && !policy.Disables(api.Namespace))
+		filter := policy.methodFilter(api.Namespace)
+
+		if authPolicy != nil && authPolicy.Exposes(api) {
+			if err := admin.RegisterAPI(api, authPolicy.methodFilter(api.Namespace)); err != nil {
+				return nil, nil, nil, err
+			}
+			n.log.Debug(kind+" registered", "service", api.Service, "namespace", api.Namespace, "auth", true)
+		}
+		if api.Namespace == adminNamespace && !exposeAll {
+			if admin == nil {
+				if expose && !api.Public && !warned {
+					n.log.Warn("Admin API requires an admin token, not exposed", "endpoint", kind)
+					warned = true
+				}
+				continue
+			}
+			if authPolicy == nil {
+				if err := admin.RegisterAPI(api, filter); err != nil {
+					return nil, nil, nil, err
+				}
+				n.log.Debug(kind+" registered", "service", api.Service, "namespace", api.Namespace, "auth", true)
+			}
+			continue
+		}
+		if !expose {
+			continue
+		}
+		if err := handler.RegisterAPI(api, filter); err != nil {
+			return nil, nil, nil, err
+		}
+		if admin != nil && authPolicy == nil {
+			if err := admin.RegisterAPI(api, filter); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		served[api.Namespace] = true
+		n.log.Debug(kind+" registered", "service", api.Service, "namespace", api.Namespace)
+	}
+	return handler, admin, policy.sensitive(served), nil
+}
+
+// checkSensitiveAPIs warns about the sensitive APIs an HTTP or websocket endpoint
+// serves without authentication. If other hosts or any web page can reach them,
+// the admin, debug and personal APIs are refused unless explicitly allowed.
+func (n *Node) checkSensitiveAPIs(kind string, endpoint string, sensitive []string, origins []string) error {
+	if len(sensitive) == 0 {
+		return nil
+	}
+	var anyOrigin bool
+	for _, origin := range origins {
+		anyOrigin = anyOrigin || origin == "*"
+	}
+	public := !isLoopbackEndpoint(endpoint)
+	if public || anyOrigin {
+		if unsafe := unsafeAPIs(sensitive); len(unsafe) > 0 && !n.config.RPCAllowUnsafeAPIs {
+			return fmt.Errorf("%s: refusing to expose %s to untrusted clients without authentication, unsafe APIs not allowed", kind, strings.Join(unsafe, ","))
+		}
+		n.log.Error("Sensitive APIs exposed to untrusted clients, they can control the node and drain its unlocked accounts", "endpoint", kind, "addr", endpoint, "apis", strings.Join(sensitive, ","), "public", public, "anyorigin", anyOrigin)
+		return nil
+	}
+	n.log.Warn("Sensitive APIs exposed without authentication", "endpoint", kind, "addr", endpoint, "apis", strings.Join(sensitive, ","))
+	return nil
+}
+
 // stopHTTP terminates the HTTP RPC endpoint.
 func (n *Node) stopHTTP() {
 	if n.httpListener != nil {
@@ -415,44 +543,51 @@ func (n *Node) stopHTTP() {
 		n.httpHandler.Stop()
 		n.httpHandler = nil
 	}
+	if n.httpAdmin != nil {
+		n.httpAdmin.Stop()
+		n.httpAdmin = nil
+	}
 }
 
 // startWS initializes and starts the websocket RPC endpoint.
-func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool) error {
+func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, vhosts []string, exposeAll bool) error {
 	// Short circuit if the WS endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
 	}
-	// Generate the whitelist based on the allowed modules
-	whitelist := make(map[string]bool)
-	for _, module := range modules {
-		whitelist[module] = true
+	// Register the APIs exposed by the services under the configured policy
+	policy, err := n.rpcPolicy(modules)
+	if err != nil {
+		return fmt.Errorf("WebSocket: %v", err)
 	}
-	// Register all the APIs exposed by the services
-	handler := rpc.NewServer()
-	for _, api := range apis {
-		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
-			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
-				return err
-			}
-			n.log.Debug("WebSocket registered", "service", api.Service, "namespace", api.Namespace)
-		}
+	handler, admin, sensitive, err := n.newRPCHandlers("WebSocket", apis, policy, exposeAll)
+	if err != nil {
+		return err
 	}
+	if err := n.checkSensitiveAPIs("WebSocket", endpoint, sensitive, wsOrigins); err != nil {
+		return err
+	}
+
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
 		return err
 	}
-	go rpc.NewWSServer(wsOrigins, handler).Serve(listener)
-	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()))
+	served := handler.WebsocketHandler(wsOrigins)
+	if admin != nil {
+		served = rpc.NewAuthHandler(n.config.RPCAdminToken, served, admin.WebsocketHandler(wsOrigins))
+	}
+	if len(vhosts) > 0 {
+		served = rpc.NewVHostHandler(vhosts, served)
+	}
+	go (&http.Server{Handler: served}).Serve(listener)
+	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()), "vhosts", strings.Join(vhosts, ","))
 
 	// All listeners booted successfully
 	n.wsEndpoint = endpoint
 	n.wsListener = listener
 	n.wsHandler = handler
+	n.wsAdmin = admin
 
 	return nil
 }
@@ -469,6 +604,10 @@ func (n *Node) stopWS() {
 		n.wsHandler.Stop()
 		n.wsHandler = nil
 	}
+	if n.wsAdmin != nil {
+		n.wsAdmin.Stop()
+		n.wsAdmin = nil
+	}
 }
 
 // Stop terminates a running node along with all it's services. In the node was
@@ -645,7 +784,7 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (aquadb.Database, e
 	if n.config.DataDir == "" {
 		return aquadb.NewMemDatabase()
 	}
-	return aquadb.NewLDBDatabase(n.config.resolvePath(name), cache, handles)
+	return aquadb.Open(n.config.DBEngine, n.config.resolvePath(name), cache, handles)
 }
 
 // ResolvePath returns the absolute path of a resource in the instance directory.
@@ -653,15 +792,19 @@ func (n *Node) ResolvePath(x string) string {
 	return n.config.resolvePath(x)
 }
 
+// adminNamespace is the RPC namespace of the node management APIs, served over
+// HTTP and websocket only to requests authenticated with the admin token.
+const adminNamespace = "admin"
+
 // apis returns the collection of RPC descriptors this node offers.
 func (n *Node) apis() []rpc.API {
 	return []rpc.API{
 		{
-			Namespace: "admin",
+			Namespace: adminNamespace,
 			Version:   "1.0",
 			Service:   NewPrivateAdminAPI(n),
 		}, {
-			Namespace: "admin",
+			Namespace: adminNamespace,
 			Version:   "1.0",
 			Service:   NewPublicAdminAPI(n),
 			Public:    true,
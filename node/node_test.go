@@ -19,8 +19,10 @@ package node
 import (
 	"errors"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -573,3 +575,130 @@ func TestAPIGather(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the admin API is only served over HTTP to requests authenticated
+// with the admin token.
+func TestHTTPAdminAuth(t *testing.T) {
+	config := testNodeConfig()
+	config.HTTPHost = "127.0.0.1"
+	config.HTTPModules = []string{"admin", "web3"}
+	config.HTTPVirtualHosts = []string{"*"}
+	config.RPCAdminToken = "0123456789abcdef"
+
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	url := "http://" + stack.httpListener.Addr().String()
+	tests := []struct {
+		method, token string
+		status        int
+		result        bool
+	}{
+		{"web3_clientVersion", "", http.StatusOK, true},
+		{"admin_nodeInfo", "", http.StatusOK, false},
+		{"admin_nodeInfo", "wrong", http.StatusUnauthorized, false},
+		{"admin_nodeInfo", config.RPCAdminToken, http.StatusOK, true},
+		{"web3_clientVersion", config.RPCAdminToken, http.StatusOK, true},
+	}
+	for i, test := range tests {
+		status, body := callHTTP(t, url, test.method, test.token)
+		if status != test.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, status, test.status)
+		}
+		if result := strings.Contains(body, `"result"`); status == http.StatusOK && result != test.result {
+			t.Errorf("test %d: result mismatch: have %v, want %v: %s", i, result, test.result, body)
+		}
+	}
+}
+
+// Tests that authenticated HTTP requests are served the APIs of their own rules,
+// and that denied methods are served to no request at all.
+func TestHTTPAuthModules(t *testing.T) {
+	config := testNodeConfig()
+	config.HTTPHost = "127.0.0.1"
+	config.HTTPModules = []string{"debug", "web3"}
+	config.HTTPVirtualHosts = []string{"*"}
+	config.RPCAdminToken = "0123456789abcdef"
+	config.RPCAuthModules = []string{"admin"}
+	config.RPCDeny = []string{"admin_peers", "debug_memStats"}
+
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	url := "http://" + stack.httpListener.Addr().String()
+	tests := []struct {
+		method, token string
+		result        bool
+	}{
+		{"web3_clientVersion", "", true},
+		{"debug_gcStats", "", true},
+		{"debug_memStats", "", false},
+		{"admin_nodeInfo", "", false},
+		{"web3_clientVersion", config.RPCAdminToken, false},
+		{"admin_nodeInfo", config.RPCAdminToken, true},
+		{"admin_peers", config.RPCAdminToken, false},
+	}
+	for i, test := range tests {
+		status, body := callHTTP(t, url, test.method, test.token)
+		if status != http.StatusOK {
+			t.Fatalf("test %d: status mismatch: have %d, want %d", i, status, http.StatusOK)
+		}
+		if result := strings.Contains(body, `"result"`); result != test.result {
+			t.Errorf("test %d: result mismatch: have %v, want %v: %s", i, result, test.result, body)
+		}
+	}
+}
+
+// Tests that HTTP endpoints reachable from other hosts refuse to serve the unsafe
+// APIs without authentication, unless explicitly allowed.
+func TestHTTPUnsafeAPIs(t *testing.T) {
+	config := testNodeConfig()
+	config.HTTPHost = "0.0.0.0"
+	config.HTTPModules = []string{"web3", "debug"}
+
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Start(); err == nil {
+		stack.Stop()
+		t.Fatalf("unsafe APIs exposed to untrusted clients")
+	}
+	config.RPCAllowUnsafeAPIs = true
+	if stack, err = New(config); err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack with unsafe APIs allowed: %v", err)
+	}
+	stack.Stop()
+}
+
+// callHTTP invokes a parameterless RPC method over HTTP, with a bearer token if
+// one is given, returning the status and body of the response.
+func callHTTP(t *testing.T, url, method, token string) (int, string) {
+	req, _ := http.NewRequest("POST", url, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"`+method+`","params":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer res.Body.Close()
+	body, _ := ioutil.ReadAll(res.Body)
+	return res.StatusCode, string(body)
+}
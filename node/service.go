@@ -43,7 +43,7 @@ func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int) (aq
 	if ctx.config.DataDir == "" {
 		return aquadb.NewMemDatabase()
 	}
-	db, err := aquadb.NewLDBDatabase(ctx.config.resolvePath(name), cache, handles)
+	db, err := aquadb.Open(ctx.config.DBEngine, ctx.config.resolvePath(name), cache, handles)
 	if err != nil {
 		return nil, err
 	}
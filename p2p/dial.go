@@ -34,6 +34,10 @@ const (
 	// redialing a certain node.
 	dialHistoryExpiration = 30 * time.Second
 
+	// Static nodes which can't be reached are redialed with exponential
+	// backoff, up to this delay between attempts.
+	maxStaticDialDelay = 10 * time.Minute
+
 	// Discovery lookups are throttled and can only run
 	// once every few seconds.
 	lookupInterval = 4 * time.Second
@@ -112,6 +116,7 @@ type dialTask struct {
 	dest         *discover.Node
 	lastResolved time.Time
 	resolveDelay time.Duration
+	failures     int // Number of consecutive failed dials of a static node
 }
 
 // discoverTask runs discovery table operations.
@@ -280,7 +285,7 @@ func (s *dialstate) checkDial(n *discover.Node, peers map[discover.NodeID]*Peer)
 func (s *dialstate) taskDone(t task, now time.Time) {
 	switch t := t.(type) {
 	case *dialTask:
-		s.hist.add(t.dest.ID, now.Add(dialHistoryExpiration))
+		s.hist.add(t.dest.ID, now.Add(t.redialDelay()))
 		delete(s.dialing, t.dest.ID)
 	case *discoverTask:
 		s.lookupRunning = false
@@ -291,6 +296,7 @@ func (s *dialstate) taskDone(t task, now time.Time) {
 func (t *dialTask) Do(srv *Server) {
 	if t.dest.Incomplete() {
 		if !t.resolve(srv) {
+			t.failures++
 			return
 		}
 	}
@@ -300,10 +306,31 @@ func (t *dialTask) Do(srv *Server) {
 		// Try resolving the ID of static nodes if dialing failed.
 		if _, ok := err.(*dialError); ok && t.flags&staticDialedConn != 0 {
 			if t.resolve(srv) {
-				t.dial(srv, t.dest)
+				err = t.dial(srv, t.dest)
 			}
 		}
 	}
+	if err != nil {
+		t.failures++
+	} else {
+		t.failures = 0
+	}
+}
+
+// redialDelay returns the time to wait before dialing the node again. Static
+// nodes which failed to connect are backed off exponentially.
+func (t *dialTask) redialDelay() time.Duration {
+	delay := dialHistoryExpiration
+	if t.flags&staticDialedConn == 0 {
+		return delay
+	}
+	for i := 1; i < t.failures && delay < maxStaticDialDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxStaticDialDelay {
+		delay = maxStaticDialDelay
+	}
+	return delay
 }
 
 // resolve attempts to find the current endpoint for the destination
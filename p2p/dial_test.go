@@ -694,3 +694,25 @@ func (t *resolveMock) Close()                                   {}
 func (t *resolveMock) Bootstrap([]*discover.Node)               {}
 func (t *resolveMock) Lookup(discover.NodeID) []*discover.Node  { return nil }
 func (t *resolveMock) ReadRandomNodes(buf []*discover.Node) int { return 0 }
+
+// This test checks that unreachable static nodes are redialed with backoff.
+func TestDialTaskRedialDelay(t *testing.T) {
+	tests := []struct {
+		flags    connFlag
+		failures int
+		delay    time.Duration
+	}{
+		{dynDialedConn, 5, dialHistoryExpiration},
+		{staticDialedConn, 0, dialHistoryExpiration},
+		{staticDialedConn, 1, dialHistoryExpiration},
+		{staticDialedConn, 2, 2 * dialHistoryExpiration},
+		{staticDialedConn, 4, 8 * dialHistoryExpiration},
+		{staticDialedConn, 100, maxStaticDialDelay},
+	}
+	for i, test := range tests {
+		task := &dialTask{flags: test.flags, dest: &discover.Node{ID: uintID(1)}, failures: test.failures}
+		if delay := task.redialDelay(); delay != test.delay {
+			t.Errorf("test %d: delay mismatch: have %v, want %v", i, delay, test.delay)
+		}
+	}
+}
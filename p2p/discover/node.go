@@ -34,6 +34,7 @@ import (
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/crypto"
 	"github.com/aquanetwork/aquachain/crypto/secp256k1"
+	"github.com/aquanetwork/aquachain/p2p/enr"
 )
 
 const NodeIDBits = 512
@@ -207,6 +208,70 @@ func MustParseNode(rawurl string) *Node {
 	return n
 }
 
+// NodeFromRecord creates a node from a signed node record. The record must hold
+// the public key, IP address and TCP port of the node. The UDP port defaults to
+// the TCP port if the record doesn't hold one.
+func NodeFromRecord(r *enr.Record) (*Node, error) {
+	if !r.Signed() {
+		return nil, errors.New("unsigned node record")
+	}
+	var (
+		pubkey enr.Secp256k1
+		ip4    enr.IP4
+		ip6    enr.IP6
+		ip     net.IP
+		tcp    enr.TCP
+		udp    enr.UDP
+	)
+	if err := r.Load(&pubkey); err != nil {
+		return nil, err
+	}
+	if err := r.Load(&ip4); err == nil {
+		ip = net.IP(ip4)
+	} else if err := r.Load(&ip6); err == nil {
+		ip = net.IP(ip6)
+	} else {
+		return nil, errors.New("node record lacks an IP address")
+	}
+	if err := r.Load(&tcp); err != nil {
+		return nil, err
+	}
+	if err := r.Load(&udp); enr.IsNotFound(err) {
+		udp = enr.UDP(tcp)
+	} else if err != nil {
+		return nil, err
+	}
+	key := ecdsa.PublicKey(pubkey)
+	return NewNode(PubkeyID(&key), ip, uint16(udp), uint16(tcp)), nil
+}
+
+// Record creates a node record of a complete node, signed with the private key
+// of the node. Signing increments the given sequence number, which must grow
+// with every change of the node's address for the newest record to be used.
+func (n *Node) Record(key *ecdsa.PrivateKey, seq uint64) (*enr.Record, error) {
+	if n.Incomplete() {
+		return nil, errors.New("incomplete node")
+	}
+	if PubkeyID(&key.PublicKey) != n.ID {
+		return nil, errors.New("key doesn't match node ID")
+	}
+	r := new(enr.Record)
+	r.SetSeq(seq)
+	if ip4 := n.IP.To4(); ip4 != nil {
+		r.Set(enr.IP4(ip4))
+	} else {
+		r.Set(enr.IP6(n.IP))
+	}
+	r.Set(enr.TCP(n.TCP))
+	if n.UDP != n.TCP {
+		r.Set(enr.UDP(n.UDP))
+	}
+	if err := r.Sign(key); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
 // MarshalText implements encoding.TextMarshaler.
 func (n *Node) MarshalText() ([]byte, error) {
 	return []byte(n.String()), nil
@@ -180,6 +180,37 @@ func TestNodeString(t *testing.T) {
 	}
 }
 
+func TestNodeRecord(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	id := PubkeyID(&key.PublicKey)
+	for _, n := range []*Node{
+		NewNode(id, net.IP{10, 3, 58, 6}, 30303, 30303),
+		NewNode(id, net.ParseIP("2001:db8:3c4d:15::abcd:ef12"), 52150, 30303),
+	} {
+		r, err := n.Record(key, 7)
+		if err != nil {
+			t.Fatalf("%v: can't create record: %v", n, err)
+		}
+		if r.Seq() != 8 {
+			t.Errorf("%v: record seq mismatch: have %d, want 8", n, r.Seq())
+		}
+		n2, err := NodeFromRecord(r)
+		if err != nil {
+			t.Fatalf("%v: can't create node from record: %v", n, err)
+		}
+		if !reflect.DeepEqual(n2, n) {
+			t.Errorf("node mismatch:\nhave %v\nwant %v", n2, n)
+		}
+	}
+	other, _ := crypto.GenerateKey()
+	if _, err := NewNode(id, net.IP{10, 3, 58, 6}, 30303, 30303).Record(other, 0); err == nil {
+		t.Error("record signed with foreign key")
+	}
+	if _, err := NewNode(id, nil, 0, 0).Record(key, 0); err == nil {
+		t.Error("record of incomplete node")
+	}
+}
+
 func TestHexID(t *testing.T) {
 	ref := NodeID{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 128, 106, 217, 182, 31, 165, 174, 1, 67, 7, 235, 220, 150, 66, 83, 173, 205, 159, 44, 10, 57, 42, 161, 26, 188}
 	id1 := MustHexID("0x000000000000000000000000000000000000000000000000000000000000000000000000000000806ad9b61fa5ae014307ebdc964253adcd9f2c0a392aa11abc")
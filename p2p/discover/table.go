@@ -238,6 +238,21 @@ func (tab *Table) setFallbackNodes(nodes []*Node) error {
 	return nil
 }
 
+// AddSeeds bonds with the given nodes and adds those responding to the table.
+// It is meant for seed nodes learned after the table was created, such as the
+// nodes of a DNS node list. Incomplete nodes are ignored.
+func (tab *Table) AddSeeds(nodes []*Node) {
+	seeds := make([]*Node, 0, len(nodes))
+	for _, n := range nodes {
+		if n.validateComplete() == nil {
+			seeds = append(seeds, n)
+		}
+	}
+	for _, n := range tab.bondall(seeds) {
+		tab.add(n)
+	}
+}
+
 // isInitDone returns whether the table's initial seeding procedure has completed.
 func (tab *Table) isInitDone() bool {
 	select {
@@ -22,6 +22,7 @@ import (
 	"crypto/ecdsa"
 	"errors"
 	"fmt"
+	"math"
 	"net"
 	"time"
 
@@ -29,6 +30,7 @@ import (
 	"github.com/aquanetwork/aquachain/log"
 	"github.com/aquanetwork/aquachain/p2p/nat"
 	"github.com/aquanetwork/aquachain/p2p/netutil"
+	"github.com/aquanetwork/aquachain/p2p/timesync"
 	"github.com/aquanetwork/aquachain/rlp"
 )
 
@@ -174,6 +176,7 @@ type udp struct {
 
 	closing chan struct{}
 	nat     nat.Interface
+	clock   *timesync.Sampler
 
 	*Table
 }
@@ -232,6 +235,7 @@ type Config struct {
 	NetRestrict  *netutil.Netlist  // network whitelist
 	Bootnodes    []*Node           // list of bootstrap nodes
 	Unhandled    chan<- ReadPacket // unhandled packets are sent on this channel
+	Clock        *timesync.Sampler // if set, sampled with the clocks of the pinging nodes
 }
 
 // ListenUDP returns a new table that listens for UDP packets on laddr.
@@ -252,6 +256,7 @@ func newUDP(c conn, cfg Config) (*Table, *udp, error) {
 		closing:     make(chan struct{}),
 		gotreply:    make(chan reply),
 		addpending:  make(chan *pending),
+		clock:       cfg.Clock,
 	}
 	realaddr := c.LocalAddr().(*net.UDPAddr)
 	if cfg.AnnounceAddr != nil {
@@ -270,6 +275,26 @@ func newUDP(c conn, cfg Config) (*Table, *udp, error) {
 	return udp.Table, udp, nil
 }
 
+// sampleClock records the clock of a node from the expiration of a valid packet
+// it just sent, which is set a fixed time ahead of its clock. Only bonded nodes
+// are sampled, and only once per network, so that no node can sway the estimate
+// with a multitude of identities.
+func (t *udp) sampleClock(id NodeID, from *net.UDPAddr, ts uint64) {
+	if t.clock == nil || ts > math.MaxInt64 || !t.db.hasBond(id) {
+		return
+	}
+	t.clock.Add(clockNetwork(from.IP), time.Unix(int64(ts), 0).Add(-expiration))
+}
+
+// clockNetwork returns the network clock samples of an address are keyed by,
+// its /24 for IPv4 and its /64 for IPv6.
+func clockNetwork(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(64, 128)).String()
+}
+
 func (t *udp) close() {
 	close(t.closing)
 	t.conn.Close()
@@ -592,6 +617,7 @@ func (req *ping) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) er
 	if expired(req.Expiration) {
 		return errExpired
 	}
+	t.sampleClock(fromID, from, req.Expiration)
 	t.send(from, pongPacket, &pong{
 		To:         makeEndpoint(from, req.From.TCP),
 		ReplyTok:   mac,
@@ -613,6 +639,7 @@ func (req *pong) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) er
 	if !t.handleReply(fromID, pongPacket, req) {
 		return errUnsolicitedReply
 	}
+	t.sampleClock(fromID, from, req.Expiration)
 	return nil
 }
 
@@ -35,6 +35,7 @@ import (
 
 	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/p2p/timesync"
 	"github.com/aquanetwork/aquachain/rlp"
 	"github.com/davecgh/go-spew/spew"
 )
@@ -130,6 +131,37 @@ func TestUDP_packetErrors(t *testing.T) {
 	test.packetIn(errUnsolicitedReply, neighborsPacket, &neighbors{Expiration: futureExp})
 }
 
+// Tests that only the valid packets of bonded nodes sample the clock, and only
+// once per network.
+func TestUDP_clockSamples(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+	test.udp.clock = timesync.NewSampler()
+
+	samples := func() int {
+		_, n, _ := test.udp.clock.Offset()
+		return n
+	}
+	newPing := func() *ping {
+		return &ping{From: testRemote, To: testLocalAnnounced, Version: Version, Expiration: uint64(time.Now().Add(expiration).Unix())}
+	}
+	// Unbonded nodes and expired packets aren't sampled
+	test.packetIn(nil, pingPacket, newPing())
+	test.packetIn(errExpired, pingPacket, &ping{From: testRemote, To: testLocalAnnounced, Version: Version})
+	if n := samples(); n != 0 {
+		t.Fatalf("samples mismatch: have %d, want %d", n, 0)
+	}
+	// Bonded nodes are, but a single one per network
+	for i, ip := range []net.IP{{10, 0, 1, 99}, {10, 0, 1, 100}, {10, 0, 2, 99}} {
+		test.remotekey, test.remoteaddr = newkey(), &net.UDPAddr{IP: ip, Port: 30303}
+		test.table.db.updateBondTime(PubkeyID(&test.remotekey.PublicKey), time.Now())
+		test.packetIn(nil, pingPacket, newPing())
+		if n, want := samples(), []int{1, 1, 2}[i]; n != want {
+			t.Fatalf("node %d: samples mismatch: have %d, want %d", i, n, want)
+		}
+	}
+}
+
 func TestUDP_pingTimeout(t *testing.T) {
 	t.Parallel()
 	test := newUDPTest(t)
@@ -0,0 +1,74 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"time"
+
+	"github.com/aquanetwork/aquachain/p2p/discover"
+	"github.com/aquanetwork/aquachain/p2p/discv5"
+	"github.com/aquanetwork/aquachain/p2p/dnsdisc"
+)
+
+// dnsRefreshInterval is the time between two retrievals of the DNS node lists.
+const dnsRefreshInterval = time.Hour
+
+// dnsDiscoveryLoop retrieves the nodes of the configured DNS node lists on
+// startup and then periodically, and seeds the discovery tables with them.
+func (srv *Server) dnsDiscoveryLoop(tab *discover.Table) {
+	defer srv.loopWG.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-srv.quit
+		cancel()
+	}()
+
+	var (
+		client = dnsdisc.NewClient(dnsdisc.Config{})
+		timer  = time.NewTimer(0)
+	)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-srv.quit:
+			return
+		}
+		nodes, err := client.Nodes(ctx, srv.DNSDiscovery...)
+		if err != nil {
+			srv.log.Warn("Failed to retrieve DNS node lists", "err", err)
+		} else {
+			srv.log.Debug("Retrieved DNS node lists", "nodes", len(nodes))
+			if tab != nil {
+				tab.AddSeeds(nodes)
+			}
+			if srv.DiscV5 != nil {
+				seeds := append([]*discv5.Node{}, srv.BootstrapNodesV5...)
+				for _, n := range nodes {
+					seeds = append(seeds, discv5.NewNode(discv5.NodeID(n.ID), n.IP, n.UDP, n.TCP))
+				}
+				if err := srv.DiscV5.SetFallbackNodes(seeds); err != nil {
+					srv.log.Warn("Invalid DNS discovery v5 seed", "err", err)
+				}
+			}
+		}
+		timer.Reset(dnsRefreshInterval)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/p2p/discover"
+)
+
+const (
+	defaultTimeout = 10 * time.Second // Timeout of a whole tree sync
+	maxEntries     = 10000            // Maximum number of records fetched per tree
+	maxLinkDepth   = 5                // Maximum number of links followed in a row
+)
+
+// Resolver is a DNS resolver that can query TXT records.
+type Resolver interface {
+	LookupTXT(ctx context.Context, domain string) ([]string, error)
+}
+
+// Config holds the settings of a client.
+type Config struct {
+	Timeout  time.Duration // Timeout of a tree sync, default 10s
+	Resolver Resolver      // DNS resolver, default the system resolver
+}
+
+// Client retrieves node lists from DNS.
+type Client struct {
+	cfg Config
+}
+
+// NewClient creates a client.
+func NewClient(cfg Config) *Client {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = new(net.Resolver)
+	}
+	return &Client{cfg}
+}
+
+// SyncTree downloads and verifies the tree at the given URL.
+func (c *Client) SyncTree(ctx context.Context, url string) (*Tree, error) {
+	link, err := parseLink(url)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	root, err := c.resolveRoot(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tree{root: root, entries: make(map[string]entry)}
+	if err := c.syncSubtree(ctx, t, link.domain, root.eroot, false); err != nil {
+		return nil, err
+	}
+	if err := c.syncSubtree(ctx, t, link.domain, root.lroot, true); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Nodes retrieves the nodes of the trees at the given URLs and of the trees they
+// link to. Trees failing to sync are skipped, an error is only returned if no
+// node could be retrieved at all.
+func (c *Client) Nodes(ctx context.Context, urls ...string) ([]*discover.Node, error) {
+	var (
+		nodes   []*discover.Node
+		seen    = make(map[discover.NodeID]bool)
+		visited = make(map[string]bool)
+		lastErr error
+	)
+	var visit func(url string, depth int)
+	visit = func(url string, depth int) {
+		if visited[url] || depth > maxLinkDepth || ctx.Err() != nil {
+			return
+		}
+		visited[url] = true
+
+		t, err := c.SyncTree(ctx, url)
+		if err != nil {
+			log.Debug("Failed to sync DNS node list", "url", url, "err", err)
+			lastErr = err
+			return
+		}
+		for _, n := range t.Nodes() {
+			if !seen[n.ID] {
+				seen[n.ID] = true
+				nodes = append(nodes, n)
+			}
+		}
+		for _, link := range t.Links() {
+			visit(link, depth+1)
+		}
+	}
+	for _, url := range urls {
+		visit(url, 0)
+	}
+	if len(nodes) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return nodes, nil
+}
+
+// resolveRoot retrieves the root record of a tree and verifies its signature.
+func (c *Client) resolveRoot(ctx context.Context, link *linkEntry) (*rootEntry, error) {
+	txts, err := c.cfg.Resolver.LookupTXT(ctx, link.domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, txt := range txts {
+		if !strings.HasPrefix(txt, rootPrefix) {
+			continue
+		}
+		root, err := parseRoot(txt)
+		if err != nil {
+			return nil, err
+		}
+		if !root.verifySignature(link.pubkey) {
+			return nil, errInvalidSig
+		}
+		return root, nil
+	}
+	return nil, fmt.Errorf("no root record at %s", link.domain)
+}
+
+// syncSubtree retrieves the subtree with the given root hash into the tree. Link
+// subtrees may only hold links, node subtrees only nodes.
+func (c *Client) syncSubtree(ctx context.Context, t *Tree, domain, hash string, links bool) error {
+	if _, ok := t.entries[hash]; ok {
+		return nil
+	}
+	if len(t.entries) >= maxEntries {
+		return fmt.Errorf("tree at %s exceeds %d records", domain, maxEntries)
+	}
+	e, err := c.resolveEntry(ctx, domain, hash)
+	if err != nil {
+		return err
+	}
+	t.entries[hash] = e
+
+	switch e := e.(type) {
+	case *branchEntry:
+		for _, child := range e.children {
+			if err := c.syncSubtree(ctx, t, domain, child, links); err != nil {
+				return err
+			}
+		}
+	case *linkEntry:
+		if !links {
+			return fmt.Errorf("link %s in node subtree", hash)
+		}
+	case *nodeEntry:
+		if links {
+			return fmt.Errorf("node %s in link subtree", hash)
+		}
+	}
+	return nil
+}
+
+// resolveEntry retrieves the record with the given hash, checking its content
+// matches the hash.
+func (c *Client) resolveEntry(ctx context.Context, domain, hash string) (entry, error) {
+	name := hash + "." + domain
+	txts, err := c.cfg.Resolver.LookupTXT(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, txt := range txts {
+		e, err := parseEntry(txt)
+		if err == errUnknownEntry {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid record at %s: %v", name, err)
+		}
+		if subdomain(e) != hash {
+			return nil, fmt.Errorf("hash mismatch at %s", name)
+		}
+		return e, nil
+	}
+	return nil, fmt.Errorf("no tree record at %s", name)
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/crypto"
+)
+
+// mapResolver serves TXT records from a map.
+type mapResolver map[string]string
+
+func (r mapResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if txt, ok := r[name]; ok {
+		return []string{"v=spf1 -all", txt}, nil
+	}
+	return nil, fmt.Errorf("no such host: %s", name)
+}
+
+// publish signs a tree and adds its records to the resolver.
+func (r mapResolver) publish(t *testing.T, tree *Tree, key *ecdsa.PrivateKey, domain string) string {
+	url, err := tree.Sign(key, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, txt := range tree.ToTXT(domain) {
+		r[name] = txt
+	}
+	return url
+}
+
+func TestClientSyncTree(t *testing.T) {
+	texts, nodes := testNodes(t, 40)
+	tree, err := MakeTree(1, texts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := make(mapResolver)
+	url := resolver.publish(t, tree, testKey(), "nodes.example.org")
+
+	synced, err := NewClient(Config{Resolver: resolver}).SyncTree(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if synced.Seq() != 1 {
+		t.Errorf("seq mismatch: have %d, want 1", synced.Seq())
+	}
+	if !reflect.DeepEqual(synced.Nodes(), tree.Nodes()) {
+		t.Errorf("nodes mismatch:\nhave %v\nwant %v", synced.Nodes(), tree.Nodes())
+	}
+	if len(synced.Nodes()) != len(nodes) {
+		t.Errorf("node count mismatch: have %d, want %d", len(synced.Nodes()), len(nodes))
+	}
+}
+
+func TestClientSyncTreeInvalid(t *testing.T) {
+	texts, _ := testNodes(t, 3)
+	tree, _ := MakeTree(1, texts, nil)
+	resolver := make(mapResolver)
+	url := resolver.publish(t, tree, testKey(), "nodes.example.org")
+	client := NewClient(Config{Resolver: resolver})
+
+	// A tree signed by another key is rejected
+	other, _ := crypto.GenerateKey()
+	if _, err := client.SyncTree(context.Background(), linkURL(&other.PublicKey, "nodes.example.org")); err != errInvalidSig {
+		t.Errorf("foreign signature: have %v, want %v", err, errInvalidSig)
+	}
+	// A record not matching its hash is rejected
+	for name, txt := range resolver {
+		if txt == texts[0] {
+			resolver[name] = texts[1]
+		}
+	}
+	if _, err := client.SyncTree(context.Background(), url); err == nil || !strings.Contains(err.Error(), "hash mismatch") {
+		t.Errorf("tampered record: have %v, want hash mismatch", err)
+	}
+}
+
+func TestClientNodesLinks(t *testing.T) {
+	var (
+		resolver = make(mapResolver)
+		key      = testKey()
+	)
+	textsA, nodesA := testNodes(t, 5)
+	textsB, nodesB := testNodes(t, 5)
+
+	// Tree B links back to A, which must not loop
+	urlA := linkURL(&key.PublicKey, "a.example.org")
+	treeB, _ := MakeTree(1, textsB, []string{urlA})
+	urlB := resolver.publish(t, treeB, key, "b.example.org")
+	treeA, _ := MakeTree(1, append(textsA, textsB[0]), []string{urlB})
+	resolver.publish(t, treeA, key, "a.example.org")
+
+	nodes, err := NewClient(Config{Resolver: resolver}).Nodes(context.Background(), urlA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(nodesA) + len(nodesB); len(nodes) != want {
+		t.Errorf("node count mismatch: have %d, want %d", len(nodes), want)
+	}
+	// Unreachable trees are skipped
+	missing := linkURL(&key.PublicKey, "missing.example.org")
+	if nodes, err := NewClient(Config{Resolver: resolver}).Nodes(context.Background(), missing, urlB); err != nil || len(nodes) != len(nodesA)+len(nodesB) {
+		t.Errorf("nodes with missing tree: have %d nodes, err %v", len(nodes), err)
+	}
+	if _, err := NewClient(Config{Resolver: resolver}).Nodes(context.Background(), missing); err == nil {
+		t.Error("no error for missing tree")
+	}
+}
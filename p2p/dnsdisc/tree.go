@@ -0,0 +1,377 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package dnsdisc implements node discovery via DNS, as specified in EIP-1459.
+//
+// A node list is published as a tree of TXT records below a domain name. The
+// root record at the domain holds the hashes of the node and link subtrees and
+// is signed by the key of the list operator; every other record is named by the
+// hash of its content, so a client knowing the public key of the operator can
+// verify the whole tree. Lists are referred to by URLs of the form
+//
+//    enrtree://<base32 compressed public key>@<domain>
+//
+// The leaves of the node subtree are signed node records ("enr:..."); enode URLs
+// are accepted as well for nodes which don't publish a record, relying on the
+// signature of the tree alone. The link subtree holds the URLs of other lists
+// the client should also follow.
+package dnsdisc
+
+import (
+	"crypto/ecdsa"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/p2p/discover"
+	"github.com/aquanetwork/aquachain/p2p/enr"
+)
+
+const (
+	rootPrefix   = "enrtree-root:v1"
+	linkPrefix   = "enrtree://"
+	branchPrefix = "enrtree-branch:"
+	enrPrefix    = "enr:"
+	enodePrefix  = "enode://"
+)
+
+// maxChildren is the number of hashes fitting a branch record, keeping it below
+// the size of a DNS response over UDP.
+const maxChildren = 13
+
+var (
+	b32format = base32.StdEncoding.WithPadding(base32.NoPadding)
+	b64format = base64.RawURLEncoding
+
+	errUnknownEntry = errors.New("unknown entry type")
+	errNoPubkey     = errors.New("missing public key")
+	errBadPubkey    = errors.New("invalid public key")
+	errInvalidSig   = errors.New("invalid root signature")
+	errInvalidChild = errors.New("invalid child hash")
+	errDuplicate    = errors.New("duplicate entry")
+)
+
+// entry is a record of a tree, in its TXT form when formatted.
+type entry interface {
+	fmt.Stringer
+}
+
+type (
+	rootEntry struct {
+		eroot string // Hash of the node subtree root
+		lroot string // Hash of the link subtree root
+		seq   uint
+		sig   []byte
+	}
+	branchEntry struct {
+		children []string
+	}
+	nodeEntry struct {
+		text string // Record or enode URL as published
+		node *discover.Node
+	}
+	linkEntry struct {
+		str    string
+		domain string
+		pubkey *ecdsa.PublicKey
+	}
+)
+
+func (e *rootEntry) String() string {
+	return e.signedText() + " sig=" + b64format.EncodeToString(e.sig)
+}
+
+// signedText is the part of the root record covered by the signature.
+func (e *rootEntry) signedText() string {
+	return fmt.Sprintf("%s e=%s l=%s seq=%d", rootPrefix, e.eroot, e.lroot, e.seq)
+}
+
+func (e *rootEntry) sigHash() []byte {
+	return crypto.Keccak256([]byte(e.signedText()))
+}
+
+func (e *rootEntry) verifySignature(pubkey *ecdsa.PublicKey) bool {
+	if len(e.sig) != 65 {
+		return false
+	}
+	return crypto.VerifySignature(crypto.CompressPubkey(pubkey), e.sigHash(), e.sig[:64])
+}
+
+func (e *branchEntry) String() string {
+	return branchPrefix + strings.Join(e.children, ",")
+}
+
+func (e *nodeEntry) String() string {
+	return e.text
+}
+
+func (e *linkEntry) String() string {
+	return e.str
+}
+
+// subdomain returns the name of the record holding an entry relative to the
+// domain of the tree, the hash of its content.
+func subdomain(e entry) string {
+	return b32format.EncodeToString(crypto.Keccak256([]byte(e.String()))[:16])
+}
+
+// parseEntry parses the content of a tree record. The root of a tree is parsed
+// with parseRoot instead.
+func parseEntry(text string) (entry, error) {
+	switch {
+	case strings.HasPrefix(text, branchPrefix):
+		return parseBranch(text[len(branchPrefix):])
+	case strings.HasPrefix(text, linkPrefix):
+		return parseLink(text)
+	case strings.HasPrefix(text, enrPrefix), strings.HasPrefix(text, enodePrefix):
+		return parseNode(text)
+	default:
+		return nil, errUnknownEntry
+	}
+}
+
+func parseRoot(text string) (*rootEntry, error) {
+	var (
+		e   rootEntry
+		sig string
+	)
+	if _, err := fmt.Sscanf(text, rootPrefix+" e=%s l=%s seq=%d sig=%s", &e.eroot, &e.lroot, &e.seq, &sig); err != nil {
+		return nil, fmt.Errorf("invalid root record: %v", err)
+	}
+	if !isHash(e.eroot) || !isHash(e.lroot) {
+		return nil, errInvalidChild
+	}
+	var err error
+	if e.sig, err = b64format.DecodeString(sig); err != nil || len(e.sig) != 65 {
+		return nil, errInvalidSig
+	}
+	return &e, nil
+}
+
+func parseBranch(list string) (entry, error) {
+	var children []string
+	if list != "" {
+		children = strings.Split(list, ",")
+	}
+	for _, c := range children {
+		if !isHash(c) {
+			return nil, errInvalidChild
+		}
+	}
+	return &branchEntry{children}, nil
+}
+
+func parseNode(text string) (entry, error) {
+	var (
+		node *discover.Node
+		err  error
+	)
+	if strings.HasPrefix(text, enrPrefix) {
+		var r *enr.Record
+		if r, err = enr.ParseText(text); err != nil {
+			return nil, fmt.Errorf("invalid node record: %v", err)
+		}
+		node, err = discover.NodeFromRecord(r)
+	} else {
+		node, err = discover.ParseNode(text)
+		if err == nil && node.Incomplete() {
+			err = errors.New("incomplete node")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid node %q: %v", text, err)
+	}
+	return &nodeEntry{text: text, node: node}, nil
+}
+
+// ParseURL parses the URL of a tree, returning the public key of the tree
+// signer and the domain of the tree.
+func ParseURL(url string) (*ecdsa.PublicKey, string, error) {
+	link, err := parseLink(url)
+	if err != nil {
+		return nil, "", err
+	}
+	return link.pubkey, link.domain, nil
+}
+
+// parseLink parses the URL of a tree.
+func parseLink(url string) (*linkEntry, error) {
+	if !strings.HasPrefix(url, linkPrefix) {
+		return nil, fmt.Errorf("tree URL must start with %q", linkPrefix)
+	}
+	pos := strings.IndexByte(url, '@')
+	if pos == -1 {
+		return nil, errNoPubkey
+	}
+	keystring, domain := url[len(linkPrefix):pos], url[pos+1:]
+	if domain == "" {
+		return nil, errors.New("missing domain")
+	}
+	keybytes, err := b32format.DecodeString(keystring)
+	if err != nil {
+		return nil, errBadPubkey
+	}
+	key, err := crypto.DecompressPubkey(keybytes)
+	if err != nil {
+		return nil, errBadPubkey
+	}
+	return &linkEntry{url, domain, key}, nil
+}
+
+// isHash reports whether s is a valid subdomain hash.
+func isHash(s string) bool {
+	b, err := b32format.DecodeString(s)
+	return err == nil && len(b) == 16
+}
+
+// Tree is a signed node list in its DNS form.
+type Tree struct {
+	root    *rootEntry
+	entries map[string]entry
+}
+
+// MakeTree creates an unsigned tree holding the given nodes, each a node record
+// in text form or an enode URL, and links to other trees.
+func MakeTree(seq uint, nodes []string, links []string) (*Tree, error) {
+	var (
+		nodeEntries []entry
+		linkEntries []entry
+	)
+	for _, text := range nodes {
+		e, err := parseNode(text)
+		if err != nil {
+			return nil, err
+		}
+		nodeEntries = append(nodeEntries, e)
+	}
+	for _, url := range links {
+		e, err := parseLink(url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid link %q: %v", url, err)
+		}
+		linkEntries = append(linkEntries, e)
+	}
+	t := &Tree{entries: make(map[string]entry)}
+	eroot, err := t.build(nodeEntries)
+	if err != nil {
+		return nil, err
+	}
+	lroot, err := t.build(linkEntries)
+	if err != nil {
+		return nil, err
+	}
+	t.root = &rootEntry{eroot: eroot, lroot: lroot, seq: seq}
+	return t, nil
+}
+
+// build adds a subtree holding the given leaves to the tree, returning the hash
+// of its root. The leaves are sorted to produce the same tree for the same list.
+func (t *Tree) build(leaves []entry) (string, error) {
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].String() < leaves[j].String() })
+	hashes := make([]string, 0, len(leaves))
+	for _, e := range leaves {
+		hash := subdomain(e)
+		if _, ok := t.entries[hash]; ok {
+			return "", fmt.Errorf("%v: %s", errDuplicate, e)
+		}
+		t.entries[hash] = e
+		hashes = append(hashes, hash)
+	}
+	// Group the hashes into branches until a single one holds them all
+	for {
+		var next []string
+		for len(hashes) > 0 {
+			n := len(hashes)
+			if n > maxChildren {
+				n = maxChildren
+			}
+			branch := &branchEntry{children: hashes[:n]}
+			hash := subdomain(branch)
+			t.entries[hash] = branch
+			next = append(next, hash)
+			hashes = hashes[n:]
+		}
+		if len(next) == 0 {
+			// Empty subtree, represented by a branch without children
+			branch := new(branchEntry)
+			hash := subdomain(branch)
+			t.entries[hash] = branch
+			return hash, nil
+		}
+		if len(next) == 1 {
+			return next[0], nil
+		}
+		hashes = next
+	}
+}
+
+// Sign signs the tree with the given key and returns its URL.
+func (t *Tree) Sign(key *ecdsa.PrivateKey, domain string) (string, error) {
+	sig, err := crypto.Sign(t.root.sigHash(), key)
+	if err != nil {
+		return "", err
+	}
+	t.root.sig = sig
+	return linkURL(&key.PublicKey, domain), nil
+}
+
+// linkURL formats the URL of the tree signed by the key at the domain.
+func linkURL(pubkey *ecdsa.PublicKey, domain string) string {
+	return linkPrefix + b32format.EncodeToString(crypto.CompressPubkey(pubkey)) + "@" + domain
+}
+
+// Seq returns the sequence number of the tree.
+func (t *Tree) Seq() uint {
+	return t.root.seq
+}
+
+// Nodes returns all nodes of the tree.
+func (t *Tree) Nodes() []*discover.Node {
+	var nodes []*discover.Node
+	for _, e := range t.entries {
+		if ne, ok := e.(*nodeEntry); ok {
+			nodes = append(nodes, ne.node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].String() < nodes[j].String() })
+	return nodes
+}
+
+// Links returns the URLs of all trees the tree links to.
+func (t *Tree) Links() []string {
+	var links []string
+	for _, e := range t.entries {
+		if le, ok := e.(*linkEntry); ok {
+			links = append(links, le.str)
+		}
+	}
+	sort.Strings(links)
+	return links
+}
+
+// ToTXT returns all records of a signed tree published at the given domain,
+// keyed by their full name.
+func (t *Tree) ToTXT(domain string) map[string]string {
+	records := map[string]string{domain: t.root.String()}
+	for hash, e := range t.entries {
+		records[hash+"."+domain] = e.String()
+	}
+	return records
+}
@@ -0,0 +1,169 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/p2p/discover"
+)
+
+// testNodes creates n nodes, every other one given as a node record.
+func testNodes(t *testing.T, n int) ([]string, []*discover.Node) {
+	var (
+		texts []string
+		nodes []*discover.Node
+	)
+	for i := 0; i < n; i++ {
+		key, _ := crypto.GenerateKey()
+		node := discover.NewNode(discover.PubkeyID(&key.PublicKey), net.IP{10, 0, byte(i >> 8), byte(i)}, 30303, 30303)
+		text := node.String()
+		if i%2 == 0 {
+			r, err := node.Record(key, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text, err = r.Text(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		texts = append(texts, text)
+		nodes = append(nodes, node)
+	}
+	return texts, nodes
+}
+
+func testKey() *ecdsa.PrivateKey {
+	key, _ := crypto.HexToECDSA("45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8")
+	return key
+}
+
+func TestParseLink(t *testing.T) {
+	key := testKey()
+	url := linkURL(&key.PublicKey, "nodes.example.org")
+	link, err := parseLink(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.domain != "nodes.example.org" || !reflect.DeepEqual(link.pubkey, &key.PublicKey) {
+		t.Errorf("link mismatch: have %s %x", link.domain, crypto.FromECDSAPub(link.pubkey))
+	}
+	for _, bad := range []string{
+		"enode://nodes.example.org",
+		"enrtree://nodes.example.org",
+		"enrtree://AAAA@nodes.example.org",
+		url[:len(url)-len("nodes.example.org")],
+	} {
+		if _, err := parseLink(bad); err == nil {
+			t.Errorf("invalid link %q accepted", bad)
+		}
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	texts, _ := testNodes(t, 2)
+	for _, text := range append(texts, branchPrefix, branchPrefix+"AAAAAAAAAAAAAAAAAAAAAAAAAA,BBBBBBBBBBBBBBBBBBBBBBBBBB", linkURL(&testKey().PublicKey, "example.org")) {
+		e, err := parseEntry(text)
+		if err != nil {
+			t.Errorf("can't parse %q: %v", text, err)
+			continue
+		}
+		if e.String() != text {
+			t.Errorf("entry text mismatch: have %q, want %q", e.String(), text)
+		}
+	}
+	for _, bad := range []string{
+		"v=spf1 -all",
+		branchPrefix + "AAAA",
+		"enode://1dd9d65c4552b5eb43d5ad55a2ee3f56c6cbc1c64a5c8d659f51fcd51bace24351232b8d7821617d2b29b54b81cdefb9b3e9c37d7fd5f63270bcc9e1a6f6a439",
+		"enr:AAAA",
+	} {
+		if _, err := parseEntry(bad); err == nil {
+			t.Errorf("invalid entry %q accepted", bad)
+		}
+	}
+}
+
+func TestMakeTree(t *testing.T) {
+	for _, n := range []int{0, 1, maxChildren, maxChildren + 1, 3 * maxChildren * maxChildren} {
+		texts, nodes := testNodes(t, n)
+		tree, err := MakeTree(3, texts, nil)
+		if err != nil {
+			t.Fatalf("%d nodes: %v", n, err)
+		}
+		if have := tree.Nodes(); len(have) != len(nodes) {
+			t.Errorf("%d nodes: tree holds %d nodes", n, len(have))
+		}
+		for hash, e := range tree.entries {
+			if len(e.String()) > 370 {
+				t.Errorf("%d nodes: record %s too large: %d bytes", n, hash, len(e.String()))
+			}
+		}
+		// The same list in any order makes the same tree
+		reversed := make([]string, len(texts))
+		for i, text := range texts {
+			reversed[len(texts)-1-i] = text
+		}
+		tree2, _ := MakeTree(3, reversed, nil)
+		if tree2.root.eroot != tree.root.eroot {
+			t.Errorf("%d nodes: tree depends on node order", n)
+		}
+	}
+	texts, _ := testNodes(t, 1)
+	if _, err := MakeTree(1, append(texts, texts[0]), nil); err == nil {
+		t.Error("duplicate node accepted")
+	}
+}
+
+func TestSignTree(t *testing.T) {
+	texts, _ := testNodes(t, 3)
+	link := linkURL(&testKey().PublicKey, "other.example.org")
+	tree, err := MakeTree(5, texts, []string{link})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := crypto.GenerateKey()
+	url, err := tree.Sign(key, "nodes.example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("enrtree://%s@nodes.example.org", b32format.EncodeToString(crypto.CompressPubkey(&key.PublicKey))); url != want {
+		t.Errorf("URL mismatch: have %s, want %s", url, want)
+	}
+	records := tree.ToTXT("nodes.example.org")
+	root, err := parseRoot(records["nodes.example.org"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(root, tree.root) {
+		t.Errorf("root mismatch: have %v, want %v", root, tree.root)
+	}
+	if !root.verifySignature(&key.PublicKey) {
+		t.Error("signature invalid")
+	}
+	if root.verifySignature(&testKey().PublicKey) {
+		t.Error("signature valid for another key")
+	}
+	if links := tree.Links(); !reflect.DeepEqual(links, []string{link}) {
+		t.Errorf("links mismatch: have %v, want %v", links, []string{link})
+	}
+}
@@ -30,10 +30,12 @@ package enr
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 
 	"github.com/aquanetwork/aquachain/crypto"
 	"github.com/aquanetwork/aquachain/crypto/sha3"
@@ -44,6 +46,8 @@ const SizeLimit = 300 // maximum encoded size of a node record in bytes
 
 const ID_SECP256k1_KECCAK = ID("secp256k1-keccak") // the default identity scheme
 
+const textPrefix = "enr:" // prefix of the text form of a record
+
 var (
 	errNoID           = errors.New("unknown or unspecified identity scheme")
 	errInvalidSigsize = errors.New("invalid signature size")
@@ -54,6 +58,7 @@ var (
 	errTooBig         = fmt.Errorf("record bigger than %d bytes", SizeLimit)
 	errEncodeUnsigned = errors.New("can't encode unsigned record")
 	errNotFound       = errors.New("no such key in record")
+	errNoTextPrefix   = fmt.Errorf("record text lacks %q prefix", textPrefix)
 )
 
 // Record represents a node record. The zero value is an empty record.
@@ -205,6 +210,31 @@ func (r *Record) DecodeRLP(s *rlp.Stream) error {
 	return nil
 }
 
+// Text returns the text form of a signed record, "enr:" followed by the URL-safe
+// base64 encoding of the record without padding.
+func (r *Record) Text() (string, error) {
+	if !r.Signed() {
+		return "", errEncodeUnsigned
+	}
+	return textPrefix + base64.RawURLEncoding.EncodeToString(r.raw), nil
+}
+
+// ParseText decodes the text form of a record and verifies its signature.
+func ParseText(text string) (*Record, error) {
+	if !strings.HasPrefix(text, textPrefix) {
+		return nil, errNoTextPrefix
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(text[len(textPrefix):])
+	if err != nil {
+		return nil, err
+	}
+	r := new(Record)
+	if err := rlp.DecodeBytes(raw, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
 type s256raw []byte
 
 func (s256raw) ENRKey() string { return "secp256k1" }
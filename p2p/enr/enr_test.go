@@ -224,6 +224,28 @@ func TestSignEncodeAndDecode(t *testing.T) {
 	assert.Equal(t, blob, blob2)
 }
 
+// TestTextEncoding tests the "enr:" text form of a record.
+func TestTextEncoding(t *testing.T) {
+	var r Record
+	_, err := r.Text()
+	assert.Equal(t, errEncodeUnsigned, err)
+
+	r.Set(IP4{127, 0, 0, 1})
+	r.Set(TCP(30303))
+	require.NoError(t, r.Sign(privkey))
+
+	text, err := r.Text()
+	require.NoError(t, err)
+	r2, err := ParseText(text)
+	require.NoError(t, err)
+	assert.Equal(t, r, *r2)
+
+	_, err = ParseText(text[len(textPrefix):])
+	assert.Equal(t, errNoTextPrefix, err)
+	_, err = ParseText(text[:len(text)-2])
+	assert.Error(t, err)
+}
+
 func TestNodeAddr(t *testing.T) {
 	var r Record
 	if addr := r.NodeAddr(); addr != nil {
@@ -98,6 +98,12 @@ const (
 // Map adds a port mapping on m and keeps it alive until c is closed.
 // This function is typically invoked in its own goroutine.
 func Map(m Interface, c chan struct{}, protocol string, extport, intport int, name string) {
+	MapNotify(m, c, protocol, extport, intport, name, nil)
+}
+
+// MapNotify is like Map, calling notify with the result of every attempt to add
+// or refresh the mapping if notify is non-nil.
+func MapNotify(m Interface, c chan struct{}, protocol string, extport, intport int, name string, notify func(error)) {
 	log := log.New("proto", protocol, "extport", extport, "intport", intport, "interface", m)
 	refresh := time.NewTimer(mapUpdateInterval)
 	defer func() {
@@ -105,11 +111,15 @@ func Map(m Interface, c chan struct{}, protocol string, extport, intport int, na
 		log.Debug("Deleting port mapping")
 		m.DeleteMapping(protocol, extport, intport)
 	}()
-	if err := m.AddMapping(protocol, extport, intport, name, mapTimeout); err != nil {
+	err := m.AddMapping(protocol, extport, intport, name, mapTimeout)
+	if err != nil {
 		log.Debug("Couldn't add port mapping", "err", err)
 	} else {
 		log.Info("Mapped network port")
 	}
+	if notify != nil {
+		notify(err)
+	}
 	for {
 		select {
 		case _, ok := <-c:
@@ -118,9 +128,13 @@ func Map(m Interface, c chan struct{}, protocol string, extport, intport int, na
 			}
 		case <-refresh.C:
 			log.Trace("Refreshing port mapping")
-			if err := m.AddMapping(protocol, extport, intport, name, mapTimeout); err != nil {
+			err := m.AddMapping(protocol, extport, intport, name, mapTimeout)
+			if err != nil {
 				log.Debug("Couldn't add port mapping", "err", err)
 			}
+			if notify != nil {
+				notify(err)
+			}
 			refresh.Reset(mapUpdateInterval)
 		}
 	}
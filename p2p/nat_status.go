@@ -0,0 +1,97 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"strconv"
+	"sync"
+)
+
+// NATInfo reports the outcome of the NAT traversal of the server.
+type NATInfo struct {
+	Mechanism  string `json:"mechanism"`            // NAT mechanism in use
+	ExternalIP string `json:"externalIP,omitempty"` // External IP address, once detected
+	Endpoint   string `json:"endpoint,omitempty"`   // External TCP endpoint, once mapped
+	MappedTCP  bool   `json:"mappedTCP"`            // Whether the listening port is mapped
+	MappedUDP  bool   `json:"mappedUDP"`            // Whether the discovery port is mapped
+	Error      string `json:"error,omitempty"`      // Last detection or mapping failure
+}
+
+// natStatus tracks the external IP address and the port mappings obtained from
+// the NAT mechanism. The zero value is ready to use.
+type natStatus struct {
+	lock     sync.Mutex
+	extIP    net.IP
+	tcp, udp int // Mapped ports, zero if not mapped
+	err      error
+}
+
+// setExternalIP records the result of the external IP detection.
+func (s *natStatus) setExternalIP(ip net.IP, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.extIP = ip
+}
+
+// externalIP returns the detected external IP address, or nil.
+func (s *natStatus) externalIP() net.IP {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.extIP
+}
+
+// mapped returns the callback recording the results of mapping a port.
+func (s *natStatus) mapped(protocol string, port int) func(error) {
+	return func(err error) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		if err != nil {
+			s.err, port = err, 0
+		}
+		if protocol == "tcp" {
+			s.tcp = port
+		} else {
+			s.udp = port
+		}
+	}
+}
+
+// info assembles the NAT report of the given mechanism.
+func (s *natStatus) info(mechanism string) *NATInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	info := &NATInfo{Mechanism: mechanism, MappedTCP: s.tcp != 0, MappedUDP: s.udp != 0}
+	if s.extIP != nil {
+		info.ExternalIP = s.extIP.String()
+		if s.tcp != 0 {
+			info.Endpoint = net.JoinHostPort(info.ExternalIP, strconv.Itoa(s.tcp))
+		}
+	}
+	if s.err != nil {
+		info.Error = s.err.Error()
+	}
+	return info
+}
@@ -165,6 +165,11 @@ func (p *Peer) Inbound() bool {
 	return p.rw.flags&inboundConn != 0
 }
 
+// Trusted returns true if the peer is a trusted one
+func (p *Peer) Trusted() bool {
+	return p.rw.is(trustedConn)
+}
+
 func newPeer(conn *conn, protocols []Protocol) *Peer {
 	protomap := matchProtocols(protocols, conn.caps, conn)
 	p := &Peer{
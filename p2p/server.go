@@ -33,6 +33,7 @@ import (
 	"github.com/aquanetwork/aquachain/p2p/discv5"
 	"github.com/aquanetwork/aquachain/p2p/nat"
 	"github.com/aquanetwork/aquachain/p2p/netutil"
+	"github.com/aquanetwork/aquachain/p2p/timesync"
 )
 
 const (
@@ -76,6 +77,10 @@ type Config struct {
 	// Disabling is useful for protocol debugging (manual topology).
 	NoDiscovery bool
 
+	// NoClockCheck disables checking the local clock against NTP on start and
+	// hourly, which warns if it's off enough to reject valid blocks.
+	NoClockCheck bool `toml:",omitempty"`
+
 	// DiscoveryV5 specifies whether the the new topic-discovery based V5 discovery
 	// protocol should be started or not.
 	DiscoveryV5 bool `toml:",omitempty"`
@@ -93,6 +98,10 @@ type Config struct {
 	// protocol.
 	BootstrapNodesV5 []*discv5.Node `toml:",omitempty"`
 
+	// DNSDiscovery holds the enrtree:// URLs of DNS node lists, whose nodes
+	// are used as seed nodes by the discovery protocols.
+	DNSDiscovery []string `toml:",omitempty"`
+
 	// Static nodes are used as pre-configured connections which are always
 	// maintained and re-connected on disconnects.
 	StaticNodes []*discover.Node
@@ -169,12 +178,16 @@ type Server struct {
 	quit          chan struct{}
 	addstatic     chan *discover.Node
 	removestatic  chan *discover.Node
+	addtrusted    chan *discover.Node
+	removetrusted chan *discover.Node
 	posthandshake chan *conn
 	addpeer       chan *conn
 	delpeer       chan peerDrop
 	loopWG        sync.WaitGroup // loop, listenLoop
 	peerFeed      event.Feed
 	log           log.Logger
+
+	nat natStatus // External address and port mappings obtained via NAT
 }
 
 type peerOpFunc func(map[discover.NodeID]*Peer)
@@ -300,6 +313,25 @@ func (srv *Server) RemovePeer(node *discover.Node) {
 	}
 }
 
+// AddTrustedPeer adds the given node to the trusted set, allowing it to connect
+// even if the peer limit is reached. Connections already established aren't
+// affected.
+func (srv *Server) AddTrustedPeer(node *discover.Node) {
+	select {
+	case srv.addtrusted <- node:
+	case <-srv.quit:
+	}
+}
+
+// RemoveTrustedPeer removes the given node from the trusted set. It doesn't
+// disconnect the node.
+func (srv *Server) RemoveTrustedPeer(node *discover.Node) {
+	select {
+	case srv.removetrusted <- node:
+	case <-srv.quit:
+	}
+}
+
 // SubscribePeers subscribes the given channel to peer events
 func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return srv.peerFeed.Subscribe(ch)
@@ -324,11 +356,16 @@ func (srv *Server) makeSelf(listener net.Listener, ntab discoverTable) *discover
 		if listener == nil {
 			return &discover.Node{IP: net.ParseIP("0.0.0.0"), ID: discover.PubkeyID(&srv.PrivateKey.PublicKey)}
 		}
-		// Otherwise inject the listener address too
+		// Otherwise inject the listener address too, preferring the external
+		// address detected via NAT
 		addr := listener.Addr().(*net.TCPAddr)
+		ip := addr.IP
+		if ext := srv.nat.externalIP(); ext != nil {
+			ip = ext
+		}
 		return &discover.Node{
 			ID:  discover.PubkeyID(&srv.PrivateKey.PublicKey),
-			IP:  addr.IP,
+			IP:  ip,
 			TCP: uint16(addr.Port),
 		}
 	}
@@ -410,6 +447,8 @@ func (srv *Server) Start() (err error) {
 	srv.posthandshake = make(chan *conn)
 	srv.addstatic = make(chan *discover.Node)
 	srv.removestatic = make(chan *discover.Node)
+	srv.addtrusted = make(chan *discover.Node)
+	srv.removetrusted = make(chan *discover.Node)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
 
@@ -432,13 +471,19 @@ func (srv *Server) Start() (err error) {
 		realaddr = conn.LocalAddr().(*net.UDPAddr)
 		if srv.NAT != nil {
 			if !realaddr.IP.IsLoopback() {
-				go nat.Map(srv.NAT, srv.quit, "udp", realaddr.Port, realaddr.Port, "aquachain discovery")
+				go nat.MapNotify(srv.NAT, srv.quit, "udp", realaddr.Port, realaddr.Port, "aquachain discovery", srv.nat.mapped("udp", realaddr.Port))
 			}
 			// TODO: react to external IP changes over time.
-			if ext, err := srv.NAT.ExternalIP(); err == nil {
+			ext, err := srv.NAT.ExternalIP()
+			if err == nil {
 				realaddr = &net.UDPAddr{IP: ext, Port: realaddr.Port}
 			}
+			srv.nat.setExternalIP(ext, err)
 		}
+	} else if srv.NAT != nil {
+		// Without discovery, the external IP is only needed to report the
+		// node's address, detect it in the background.
+		go func() { srv.nat.setExternalIP(srv.NAT.ExternalIP()) }()
 	}
 
 	if !srv.NoDiscovery && srv.DiscoveryV5 {
@@ -447,6 +492,7 @@ func (srv *Server) Start() (err error) {
 	}
 
 	// node table
+	var tab *discover.Table
 	if !srv.NoDiscovery {
 		cfg := discover.Config{
 			PrivateKey:   srv.PrivateKey,
@@ -455,12 +501,13 @@ func (srv *Server) Start() (err error) {
 			NetRestrict:  srv.NetRestrict,
 			Bootnodes:    srv.BootstrapNodes,
 			Unhandled:    unhandled,
+			Clock:        timesync.Default,
 		}
 		ntab, err := discover.ListenUDP(conn, cfg)
 		if err != nil {
 			return err
 		}
-		srv.ntab = ntab
+		srv.ntab, tab = ntab, ntab
 	}
 
 	if srv.DiscoveryV5 {
@@ -481,6 +528,10 @@ func (srv *Server) Start() (err error) {
 		}
 		srv.DiscV5 = ntab
 	}
+	if len(srv.DNSDiscovery) > 0 && (tab != nil || srv.DiscV5 != nil) {
+		srv.loopWG.Add(1)
+		go srv.dnsDiscoveryLoop(tab)
+	}
 
 	dynPeers := srv.maxDialedConns()
 	dialer := newDialState(srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
@@ -500,6 +551,9 @@ func (srv *Server) Start() (err error) {
 		srv.log.Warn("P2P server will be useless, neither dialing nor listening")
 	}
 
+	if !srv.NoClockCheck {
+		go timesync.Default.MonitorNTP(srv.quit)
+	}
 	srv.loopWG.Add(1)
 	go srv.run(dialer)
 	srv.running = true
@@ -521,7 +575,7 @@ func (srv *Server) startListening() error {
 	if !laddr.IP.IsLoopback() && srv.NAT != nil {
 		srv.loopWG.Add(1)
 		go func() {
-			nat.Map(srv.NAT, srv.quit, "tcp", laddr.Port, laddr.Port, "aquachain p2p")
+			nat.MapNotify(srv.NAT, srv.quit, "tcp", laddr.Port, laddr.Port, "aquachain p2p", srv.nat.mapped("tcp", laddr.Port))
 			srv.loopWG.Done()
 		}()
 	}
@@ -546,8 +600,8 @@ func (srv *Server) run(dialstate dialer) {
 		queuedTasks  []task // tasks that can't run yet
 	)
 	// Put trusted nodes into a map to speed up checks.
-	// Trusted peers are loaded on startup and cannot be
-	// modified while the server is running.
+	// Trusted peers are loaded on startup and can be
+	// modified by AddTrustedPeer and RemoveTrustedPeer.
 	for _, n := range srv.TrustedNodes {
 		trusted[n.ID] = true
 	}
@@ -605,6 +659,16 @@ running:
 			if p, ok := peers[n.ID]; ok {
 				p.Disconnect(DiscRequested)
 			}
+		case n := <-srv.addtrusted:
+			// This channel is used by AddTrustedPeer to add a node
+			// to the trusted set, letting it bypass the peer limit.
+			srv.log.Debug("Adding trusted node", "node", n)
+			trusted[n.ID] = true
+		case n := <-srv.removetrusted:
+			// This channel is used by RemoveTrustedPeer to remove a
+			// node from the trusted set.
+			srv.log.Debug("Removing trusted node", "node", n)
+			delete(trusted, n.ID)
 		case op := <-srv.peerOp:
 			// This channel is used by Peers and PeerCount.
 			op(peers)
@@ -907,16 +971,19 @@ func (srv *Server) runPeer(p *Peer) {
 
 // NodeInfo represents a short summary of the information known about the host.
 type NodeInfo struct {
-	ID    string `json:"id"`    // Unique node identifier (also the encryption key)
-	Name  string `json:"name"`  // Name of the node, including client type, version, OS, custom data
-	Enode string `json:"enode"` // Enode URL for adding this peer from remote peers
-	IP    string `json:"ip"`    // IP address of the node
+	ID    string   `json:"id"`            // Unique node identifier (also the encryption key)
+	Name  string   `json:"name"`          // Name of the node, including client type, version, OS, custom data
+	Enode string   `json:"enode"`         // Enode URL for adding this peer from remote peers
+	ENR   string   `json:"enr"`           // Signed node record, for publishing in DNS node lists
+	NAT   *NATInfo `json:"nat,omitempty"` // NAT traversal outcome, if a NAT mechanism is configured
+	IP    string   `json:"ip"`            // IP address of the node
 	Ports struct {
 		Discovery int `json:"discovery"` // UDP listening port for discovery protocol
 		Listener  int `json:"listener"`  // TCP listening port for RLPx
 	} `json:"ports"`
 	ListenAddr string                 `json:"listenAddr"`
 	Protocols  map[string]interface{} `json:"protocols"`
+	Clock      *timesync.Info         `json:"clock"` // Offsets of the network and NTP time from the local clock
 }
 
 // NodeInfo gathers and returns a collection of metadata known about the host.
@@ -931,9 +998,21 @@ func (srv *Server) NodeInfo() *NodeInfo {
 		IP:         node.IP.String(),
 		ListenAddr: srv.ListenAddr,
 		Protocols:  make(map[string]interface{}),
+		Clock:      timesync.Default.Info(),
 	}
 	info.Ports.Discovery = int(node.UDP)
 	info.Ports.Listener = int(node.TCP)
+	if srv.NAT != nil {
+		info.NAT = srv.nat.info(srv.NAT.String())
+	}
+
+	// The sequence number of the record is its signing time, so records signed
+	// after an address change supersede earlier ones
+	if !node.Incomplete() {
+		if r, err := node.Record(srv.PrivateKey, uint64(time.Now().Unix())); err == nil {
+			info.ENR, _ = r.Text()
+		}
+	}
 
 	// Gather all the running protocol infos (only once per protocol type)
 	for _, proto := range srv.Protocols {
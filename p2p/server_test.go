@@ -22,6 +22,7 @@ import (
 	"math/rand"
 	"net"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
@@ -319,6 +320,58 @@ func (t *testTask) Do(srv *Server) {
 	t.called = true
 }
 
+// testNAT is a NAT mechanism refusing to map UDP ports.
+type testNAT struct{ ip net.IP }
+
+func (n testNAT) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) error {
+	if protocol == "udp" {
+		return errors.New("udp mapping refused")
+	}
+	return nil
+}
+func (n testNAT) DeleteMapping(protocol string, extport, intport int) error { return nil }
+func (n testNAT) ExternalIP() (net.IP, error)                               { return n.ip, nil }
+func (n testNAT) String() string                                            { return "test" }
+
+// This test checks that the outcome of the NAT traversal is reported, and the
+// external address used when discovery is off.
+func TestServerNATInfo(t *testing.T) {
+	srv := &Server{
+		Config: Config{
+			Name:        "test",
+			MaxPeers:    10,
+			ListenAddr:  ":0",
+			NoDiscovery: true,
+			NAT:         testNAT{net.IP{1, 2, 3, 4}},
+			PrivateKey:  newkey(),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	var info *NodeInfo
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if info = srv.NodeInfo(); info.NAT.MappedTCP && info.NAT.ExternalIP != "" {
+			break
+		}
+	}
+	port := srv.listener.Addr().(*net.TCPAddr).Port
+	want := &NATInfo{
+		Mechanism:  "test",
+		ExternalIP: "1.2.3.4",
+		Endpoint:   net.JoinHostPort("1.2.3.4", strconv.Itoa(port)),
+		MappedTCP:  true,
+	}
+	if !reflect.DeepEqual(info.NAT, want) {
+		t.Errorf("NAT info mismatch:\nhave %+v\nwant %+v", info.NAT, want)
+	}
+	if info.IP != "1.2.3.4" {
+		t.Errorf("node IP mismatch: have %s, want 1.2.3.4", info.IP)
+	}
+}
+
 // This test checks that connections are disconnected
 // just after the encryption handshake when the server is
 // at capacity. Trusted connections should still be accepted.
@@ -364,6 +417,22 @@ func TestServerAtCap(t *testing.T) {
 		t.Error("Server did not set trusted flag")
 	}
 
+	// Trust a node at runtime, it should bypass the limit as well.
+	addedID := randomID()
+	srv.AddTrustedPeer(&discover.Node{ID: addedID})
+	c = newconn(addedID)
+	if err := srv.checkpoint(c, srv.posthandshake); err != nil {
+		t.Error("unexpected error for added trusted conn @posthandshake:", err)
+	}
+	if !c.is(trustedConn) {
+		t.Error("Server did not set trusted flag for added node")
+	}
+	// Untrusting it makes it subject to the limit again.
+	srv.RemoveTrustedPeer(&discover.Node{ID: addedID})
+	c = newconn(addedID)
+	if err := srv.checkpoint(c, srv.posthandshake); err != DiscTooManyPeers {
+		t.Error("wrong error for removed trusted conn:", err)
+	}
 }
 
 func TestServerSetupConn(t *testing.T) {
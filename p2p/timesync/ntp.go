@@ -0,0 +1,149 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the NTP clock drift check via the SNTP protocol:
+//   https://tools.ietf.org/html/rfc4330
+
+package timesync
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/log"
+)
+
+const (
+	ntpPool     = "pool.ntp.org" // NTP server to query for the current time
+	ntpChecks   = 3              // Number of measurements to do against the NTP server
+	ntpInterval = time.Hour      // Time between two checks of the local clock
+)
+
+// ntpCheck is a measurement of the local clock against NTP.
+type ntpCheck struct {
+	offset time.Duration // Offset of the NTP time from the local clock
+	taken  time.Time
+}
+
+// CheckNTP measures the offset of the NTP time from the local clock, warning if
+// the local clock is off by more than the threshold.
+func (s *Sampler) CheckNTP() (time.Duration, error) {
+	offset, err := s.ntpQuery(ntpChecks)
+	if err != nil {
+		log.Debug("NTP clock check failed", "err", err)
+		return 0, err
+	}
+	s.lock.Lock()
+	s.ntp = &ntpCheck{offset: offset, taken: s.now()}
+	s.lock.Unlock()
+
+	if offset > warnThreshold || offset < -warnThreshold {
+		log.Warn("System clock seems off from NTP time", "offset", common.PrettyDuration(offset))
+		log.Warn("Blocks may be wrongly rejected as future ones, and mined ones by the network. Please enable network time synchronisation.")
+	} else {
+		log.Debug("NTP clock check done", "offset", offset)
+	}
+	return offset, nil
+}
+
+// MonitorNTP checks the local clock against NTP right away and then
+// periodically, until quit is closed.
+func (s *Sampler) MonitorNTP(quit <-chan struct{}) {
+	ticker := time.NewTicker(ntpInterval)
+	defer ticker.Stop()
+
+	for {
+		s.CheckNTP()
+		select {
+		case <-ticker.C:
+		case <-quit:
+			return
+		}
+	}
+}
+
+// sntpOffset does a naive time resolution against an NTP server and returns the
+// measured offset of its time from the local clock. This method uses the simple
+// version of NTP. It's not precise but should be fine for these purposes.
+//
+// Note, it executes two extra measurements compared to the number of requested
+// ones to be able to discard the two extremes as outliers.
+func sntpOffset(measurements int) (time.Duration, error) {
+	// Resolve the address of the NTP server
+	addr, err := net.ResolveUDPAddr("udp", ntpPool+":123")
+	if err != nil {
+		return 0, err
+	}
+	// Construct the time request (empty package with only 2 fields set):
+	//   Bits 3-5: Protocol version, 3
+	//   Bits 6-8: Mode of operation, client, 3
+	request := make([]byte, 48)
+	request[0] = 3<<3 | 3
+
+	// Execute each of the measurements
+	offsets := []time.Duration{}
+	for i := 0; i < measurements+2; i++ {
+		offset, err := sntpMeasure(addr, request)
+		if err != nil {
+			return 0, err
+		}
+		offsets = append(offsets, offset)
+	}
+	// Calculate the average offset (drop two extremities to avoid outliers)
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	offset := time.Duration(0)
+	for i := 1; i < len(offsets)-1; i++ {
+		offset += offsets[i]
+	}
+	return offset / time.Duration(measurements), nil
+}
+
+// sntpMeasure sends one time request to the NTP server, returning the offset of
+// its time from the local clock.
+func sntpMeasure(addr *net.UDPAddr, request []byte) (time.Duration, error) {
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	sent := time.Now()
+	if _, err = conn.Write(request); err != nil {
+		return 0, err
+	}
+	// Retrieve the reply and calculate the elapsed time
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	reply := make([]byte, 48)
+	if _, err = conn.Read(reply); err != nil {
+		return 0, err
+	}
+	elapsed := time.Since(sent)
+
+	// Reconstruct the time from the reply data
+	sec := uint64(reply[43]) | uint64(reply[42])<<8 | uint64(reply[41])<<16 | uint64(reply[40])<<24
+	frac := uint64(reply[47]) | uint64(reply[46])<<8 | uint64(reply[45])<<16 | uint64(reply[44])<<24
+
+	nanosec := sec*1e9 + (frac*1e9)>>32
+
+	t := time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(nanosec)).Local()
+
+	// Calculate the offset based on an assumed answer time of RRT/2
+	return t.Sub(sent) - elapsed/2, nil
+}
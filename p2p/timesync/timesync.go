@@ -0,0 +1,182 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package timesync estimates the network time from the timestamps of peers.
+//
+// The offset of the local clock from the network time is estimated as the median
+// of the offsets sampled from the peers, which no minority of them can move. It
+// complements NTP without requiring access to it: a skewed clock makes the node
+// reject valid blocks as future ones, and its miner seal blocks the network
+// rejects or penalizes. Where NTP is reachable, the local clock is checked
+// against it as well.
+//
+// The estimate is advisory only: it's logged and reported, but never used to
+// validate blocks, which must not depend on what peers claim.
+package timesync
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/log"
+)
+
+const (
+	maxSamples     = 256              // Maximum number of peers sampled at once
+	sampleLifetime = time.Hour        // Time after which a sample is discarded
+	minSamples     = 5                // Minimum number of samples to estimate the network time
+	warnThreshold  = 10 * time.Second // Offset above which the local clock is reported off
+	warnInterval   = 10 * time.Minute // Minimum time between two reports of an off clock
+	maxOffset      = 24 * time.Hour   // Offset above which a sample is deemed bogus
+)
+
+// Default is the sampler fed by the peer-to-peer networking of the process.
+var Default = NewSampler()
+
+// sample is the offset of the clock of a peer from the local one.
+type sample struct {
+	offset time.Duration
+	taken  time.Time
+}
+
+// Sampler collects the clock offsets of peers, keeping the latest of each.
+type Sampler struct {
+	samples map[string]sample
+	warned  time.Time        // Last time the local clock was reported off
+	ntp     *ntpCheck        // Last check of the local clock against NTP, if any
+	now     func() time.Time // Local clock, replaceable in tests
+
+	ntpQuery func(int) (time.Duration, error) // NTP offset measurement, replaceable in tests
+
+	lock sync.Mutex
+}
+
+// NewSampler creates a sampler without samples.
+func NewSampler() *Sampler {
+	return &Sampler{
+		samples:  make(map[string]sample),
+		now:      time.Now,
+		ntpQuery: sntpOffset,
+	}
+}
+
+// Add records the current time of a peer, as read from a message it just sent,
+// replacing the previous sample of the same source.
+func (s *Sampler) Add(peer string, remote time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	if offset := remote.Sub(now); offset > maxOffset || offset < -maxOffset {
+		return
+	}
+	if _, ok := s.samples[peer]; !ok {
+		s.expire(now)
+		if len(s.samples) >= maxSamples {
+			s.dropOldest()
+		}
+	}
+	s.samples[peer] = sample{offset: remote.Sub(now), taken: now}
+
+	offset, samples := s.median(now)
+	if samples >= minSamples && (offset > warnThreshold || offset < -warnThreshold) && now.Sub(s.warned) > warnInterval {
+		s.warned = now
+		log.Warn("System clock seems off from the network time", "offset", common.PrettyDuration(offset), "peers", samples)
+		log.Warn("Blocks may be wrongly rejected as future ones, and mined ones by the network. Please synchronise the clock.")
+	}
+}
+
+// Offset returns the median offset of the network time from the local clock,
+// and the number of peers it's estimated from. There's no estimate below a
+// minimum number of samples.
+func (s *Sampler) Offset() (offset time.Duration, samples int, ok bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	offset, samples = s.median(s.now())
+	return offset, samples, samples >= minSamples
+}
+
+// median returns the median offset of the live samples and their number. The
+// lock must be held.
+func (s *Sampler) median(now time.Time) (time.Duration, int) {
+	offsets := make([]time.Duration, 0, len(s.samples))
+	for _, smp := range s.samples {
+		if now.Sub(smp.taken) < sampleLifetime {
+			offsets = append(offsets, smp.offset)
+		}
+	}
+	if len(offsets) == 0 {
+		return 0, 0
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	if n := len(offsets); n%2 == 0 {
+		return (offsets[n/2-1] + offsets[n/2]) / 2, n
+	}
+	return offsets[len(offsets)/2], len(offsets)
+}
+
+// expire drops the samples past their lifetime. The lock must be held.
+func (s *Sampler) expire(now time.Time) {
+	for peer, smp := range s.samples {
+		if now.Sub(smp.taken) >= sampleLifetime {
+			delete(s.samples, peer)
+		}
+	}
+}
+
+// dropOldest drops the oldest sample. The lock must be held.
+func (s *Sampler) dropOldest() {
+	var (
+		oldest string
+		taken  time.Time
+	)
+	for peer, smp := range s.samples {
+		if oldest == "" || smp.taken.Before(taken) {
+			oldest, taken = peer, smp.taken
+		}
+	}
+	delete(s.samples, oldest)
+}
+
+// Info is the report of the network time estimate.
+type Info struct {
+	Offset    float64 `json:"offset"`    // Median offset of the network time from the local clock, in seconds
+	Samples   int     `json:"samples"`   // Number of peers sampled
+	Estimated bool    `json:"estimated"` // Whether there are enough samples for an estimate
+
+	NTPOffset  *float64 `json:"ntpOffset,omitempty"`  // Offset of the NTP time from the local clock, in seconds, if checked
+	NTPChecked *int64   `json:"ntpChecked,omitempty"` // Unix time of the last NTP check
+}
+
+// Info reports the network time estimate.
+func (s *Sampler) Info() *Info {
+	offset, samples, ok := s.Offset()
+	info := &Info{
+		Offset:    offset.Seconds(),
+		Samples:   samples,
+		Estimated: ok,
+	}
+	s.lock.Lock()
+	if s.ntp != nil {
+		offset, taken := s.ntp.offset.Seconds(), s.ntp.taken.Unix()
+		info.NTPOffset, info.NTPChecked = &offset, &taken
+	}
+	s.lock.Unlock()
+	return info
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package timesync
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newTestSampler creates a sampler on a fake clock, returning a function to
+// advance it.
+func newTestSampler() (*Sampler, func(time.Duration)) {
+	s := NewSampler()
+	now := time.Unix(1500000000, 0)
+	s.now = func() time.Time { return now }
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+// Tests that the network time is the median of the samples, and only estimated
+// from enough of them.
+func TestMedian(t *testing.T) {
+	s, _ := newTestSampler()
+
+	offsets := []time.Duration{5 * time.Second, -time.Hour, 3 * time.Second, 2 * time.Hour, 4 * time.Second}
+	for i, offset := range offsets {
+		if _, _, ok := s.Offset(); ok {
+			t.Fatalf("estimated from %d samples", i)
+		}
+		s.Add(fmt.Sprint(i), s.now().Add(offset))
+	}
+	if offset, samples, ok := s.Offset(); !ok || samples != 5 || offset != 4*time.Second {
+		t.Fatalf("estimate mismatch: have %v/%d/%v, want %v/%d/%v", offset, samples, ok, 4*time.Second, 5, true)
+	}
+	// A sample replaces the previous one of the peer, and an even number of
+	// them averages the middle two
+	s.Add("1", s.now().Add(time.Hour))
+	s.Add("5", s.now().Add(6*time.Second))
+	if offset, samples, _ := s.Offset(); samples != 6 || offset != 5500*time.Millisecond {
+		t.Fatalf("estimate mismatch: have %v/%d, want %v/%d", offset, samples, 5500*time.Millisecond, 6)
+	}
+	// Bogus samples are ignored
+	s.Add("6", s.now().Add(48*time.Hour))
+	if _, samples, _ := s.Offset(); samples != 6 {
+		t.Fatalf("bogus sample recorded")
+	}
+}
+
+// Tests that samples expire, and that the oldest are dropped when full.
+func TestExpiry(t *testing.T) {
+	s, advance := newTestSampler()
+
+	for i := 0; i < maxSamples; i++ {
+		s.Add(fmt.Sprint(i), s.now())
+		advance(time.Second)
+	}
+	s.Add("new", s.now())
+	if len(s.samples) != maxSamples {
+		t.Fatalf("sample count mismatch: have %d, want %d", len(s.samples), maxSamples)
+	}
+	if _, ok := s.samples["0"]; ok {
+		t.Fatalf("oldest sample not dropped")
+	}
+	// Past their lifetime, samples are no longer counted
+	advance(sampleLifetime - time.Second)
+	if _, samples, _ := s.Offset(); samples != 1 {
+		t.Fatalf("live sample count mismatch: have %d, want %d", samples, 1)
+	}
+	s.Add("newer", s.now())
+	if len(s.samples) != 2 {
+		t.Fatalf("expired samples not dropped: have %d, want %d", len(s.samples), 2)
+	}
+}
+
+// Tests that the NTP checks are reported, failed ones keeping the last result.
+func TestCheckNTP(t *testing.T) {
+	s, advance := newTestSampler()
+	if info := s.Info(); info.NTPOffset != nil || info.NTPChecked != nil {
+		t.Fatalf("unchecked clock reported checked")
+	}
+	s.ntpQuery = func(int) (time.Duration, error) { return 20 * time.Second, nil }
+	if offset, err := s.CheckNTP(); err != nil || offset != 20*time.Second {
+		t.Fatalf("check mismatch: have %v (%v), want %v", offset, err, 20*time.Second)
+	}
+	checked := s.now().Unix()
+	advance(time.Minute)
+
+	s.ntpQuery = func(int) (time.Duration, error) { return 0, errors.New("unreachable") }
+	if _, err := s.CheckNTP(); err == nil {
+		t.Fatalf("failed check succeeded")
+	}
+	info := s.Info()
+	if info.NTPOffset == nil || *info.NTPOffset != 20 || info.NTPChecked == nil || *info.NTPChecked != checked {
+		t.Fatalf("reported check mismatch: have %v at %v, want %v at %v", info.NTPOffset, info.NTPChecked, 20, checked)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aquanetwork/aquachain/common"
+)
+
+// Checkpoint is a trusted block hash at a given height. Headers below the latest
+// checkpoint don't need their seals verified, and chains that disagree with a
+// checkpoint are rejected outright.
+type Checkpoint struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+}
+
+// String implements fmt.Stringer, using the same format ParseCheckpoint accepts.
+func (c Checkpoint) String() string {
+	return fmt.Sprintf("%d:%s", c.Number, c.Hash.Hex())
+}
+
+// ParseCheckpoint parses a checkpoint in the "number:hash" format.
+func ParseCheckpoint(s string) (Checkpoint, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 {
+		return Checkpoint{}, fmt.Errorf("invalid checkpoint %q, want number:hash", s)
+	}
+	number, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("invalid checkpoint number %q: %v", parts[0], err)
+	}
+	hash := parts[1]
+	if !strings.HasPrefix(hash, "0x") || len(hash) != 2+2*common.HashLength {
+		return Checkpoint{}, fmt.Errorf("invalid checkpoint hash %q", hash)
+	}
+	return Checkpoint{Number: number, Hash: common.HexToHash(hash)}, nil
+}
+
+// Checkpoints is a list of trusted checkpoints sorted by block number.
+type Checkpoints []Checkpoint
+
+// Get returns the checkpoint at the given height, or nil if there is none.
+func (cs Checkpoints) Get(number uint64) *Checkpoint {
+	i := sort.Search(len(cs), func(i int) bool { return cs[i].Number >= number })
+	if i < len(cs) && cs[i].Number == number {
+		return &cs[i]
+	}
+	return nil
+}
+
+// Latest returns the highest checkpoint, or nil if the list is empty.
+func (cs Checkpoints) Latest() *Checkpoint {
+	if len(cs) == 0 {
+		return nil
+	}
+	return &cs[len(cs)-1]
+}
+
+// Merge combines two checkpoint lists into a new sorted one. Entries in other
+// override entries at the same height in cs.
+func (cs Checkpoints) Merge(other Checkpoints) Checkpoints {
+	merged := make(map[uint64]Checkpoint, len(cs)+len(other))
+	for _, cp := range cs {
+		merged[cp.Number] = cp
+	}
+	for _, cp := range other {
+		merged[cp.Number] = cp
+	}
+	list := make(Checkpoints, 0, len(merged))
+	for _, cp := range merged {
+		list = append(list, cp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Number < list[j].Number })
+	return list
+}
+
+var (
+	// MainnetCheckpoints are the checkpoints shipped for the main network. New
+	// entries are appended at release time from blocks well past reorg depth.
+	MainnetCheckpoints = Checkpoints{
+		{Number: 0, Hash: MainnetGenesisHash},
+	}
+
+	// TestnetCheckpoints are the checkpoints shipped for the test network.
+	TestnetCheckpoints = Checkpoints{
+		{Number: 0, Hash: TestnetGenesisHash},
+	}
+)
+
+// TrustedCheckpoints returns the hardcoded checkpoints of the network with the
+// given genesis hash, nil for unknown networks.
+func TrustedCheckpoints(genesis common.Hash) Checkpoints {
+	switch genesis {
+	case MainnetGenesisHash:
+		return MainnetCheckpoints
+	case TestnetGenesisHash:
+		return TestnetCheckpoints
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+)
+
+func TestParseCheckpoint(t *testing.T) {
+	hash := "0x381c8d2c3e3bc702533ee504d7621d510339cafd830028337a4b532ff27cd505"
+
+	tests := []struct {
+		input string
+		want  *Checkpoint
+	}{
+		{"100:" + hash, &Checkpoint{Number: 100, Hash: common.HexToHash(hash)}},
+		{"100", nil},
+		{"x:" + hash, nil},
+		{"100:0x1234", nil},
+		{"100:" + hash + ":12345", nil},
+	}
+	for i, tt := range tests {
+		cp, err := ParseCheckpoint(tt.input)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("test %d: expected error for %q", i, tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			continue
+		}
+		if cp.String() != tt.want.String() {
+			t.Errorf("test %d: checkpoint mismatch: have %v, want %v", i, cp, tt.want)
+		}
+	}
+}
+
+func TestCheckpointsMerge(t *testing.T) {
+	base := Checkpoints{{Number: 0, Hash: common.Hash{0}}, {Number: 20, Hash: common.Hash{20}}}
+	user := Checkpoints{{Number: 30, Hash: common.Hash{30}}, {Number: 10, Hash: common.Hash{10}}, {Number: 20, Hash: common.Hash{21}}}
+
+	merged := base.Merge(user)
+	if len(merged) != 4 {
+		t.Fatalf("merged length mismatch: have %d, want 4", len(merged))
+	}
+	for i, number := range []uint64{0, 10, 20, 30} {
+		if merged[i].Number != number {
+			t.Errorf("entry %d: number mismatch: have %d, want %d", i, merged[i].Number, number)
+		}
+	}
+	if cp := merged.Get(20); cp == nil || cp.Hash != (common.Hash{21}) {
+		t.Errorf("override not applied: have %v", cp)
+	}
+	if cp := merged.Get(15); cp != nil {
+		t.Errorf("unexpected checkpoint at 15: %v", cp)
+	}
+	if latest := merged.Latest(); latest == nil || latest.Number != 30 {
+		t.Errorf("latest checkpoint mismatch: have %v", latest)
+	}
+}
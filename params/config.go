@@ -19,6 +19,7 @@ package params
 import (
 	"fmt"
 	"math/big"
+	"time"
 
 	"github.com/aquanetwork/aquachain/common"
 )
@@ -79,9 +80,9 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllAquahashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(AquahashConfig), nil, TestnetHF}
+	AllAquahashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(AquahashConfig), nil, TestnetHF, nil, nil, nil, nil}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(AquahashConfig), nil, TestnetHF}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(AquahashConfig), nil, TestnetHF, nil, nil, nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -116,10 +117,27 @@ type ChainConfig struct {
 	Aquahash *AquahashConfig `json:"aquahash,omitempty"`
 	Clique   *CliqueConfig   `json:"clique,omitempty"`
 	HF       ForkMap         `json:"hf,omitempty"`
+
+	// Permissioning of private networks
+	Permission *PermissionConfig `json:"permission,omitempty"`
+
+	// Multi-algorithm proof-of-work era (nil = not scheduled)
+	MultiAlgo *MultiAlgoConfig `json:"multiAlgo,omitempty"`
+
+	// Fee market, with a burnt base fee and dynamic fee transactions (nil = not scheduled)
+	FeeMarket *FeeMarketConfig `json:"feeMarket,omitempty"`
+
+	// Issuance schedule (nil = DefaultRewards)
+	Rewards *RewardConfig `json:"rewards,omitempty"`
 }
 
 // AquahashConfig is the consensus engine configs for proof-of-work based sealing.
-type AquahashConfig struct{}
+type AquahashConfig struct {
+	// FutureBlockTime is the number of seconds the timestamp of a block may be
+	// ahead of the local clock before it's considered a future block,
+	// DefaultFutureBlockTime if zero.
+	FutureBlockTime uint64 `json:"futureBlockTime,omitempty"`
+}
 
 // String implements the stringer interface, returning the consensus engine details.
 func (c *AquahashConfig) String() string {
@@ -137,6 +155,35 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// PermissionConfig restricts a private network to allowlisted nodes and
+// transaction senders. Entries are allowed if listed in the local allowlist
+// file of a node or approved by the allowlist contract, if one is configured.
+// Only the contract restricts the senders of block transactions.
+type PermissionConfig struct {
+	Nodes    bool            `json:"nodes,omitempty"`    // Whether to restrict p2p connections to allowed nodes
+	Accounts bool            `json:"accounts,omitempty"` // Whether to restrict transaction senders to allowed accounts
+	Contract *common.Address `json:"contract,omitempty"` // Allowlist contract, nil to use the local allowlist only
+
+	// SystemAccounts may send transactions at any gas price, including zero,
+	// bypassing the pricing rules of the transaction pool. They're always
+	// allowed to transact, even if accounts are restricted.
+	SystemAccounts []common.Address `json:"systemAccounts,omitempty"`
+}
+
+// IsSystemAccount returns whether addr is a system account of a permissioned
+// network.
+func (c *ChainConfig) IsSystemAccount(addr common.Address) bool {
+	if c.Permission == nil {
+		return false
+	}
+	for _, system := range c.Permission.SystemAccounts {
+		if system == addr {
+			return true
+		}
+	}
+	return false
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -167,6 +214,15 @@ func (c *ChainConfig) String() string {
 	// )
 }
 
+// AllowedFutureBlockTime returns the time the timestamp of a block may be ahead of
+// the local clock before it's considered a future block.
+func (c *ChainConfig) AllowedFutureBlockTime() time.Duration {
+	if c.Aquahash != nil && c.Aquahash.FutureBlockTime != 0 {
+		return time.Duration(c.Aquahash.FutureBlockTime) * time.Second
+	}
+	return time.Duration(DefaultFutureBlockTime) * time.Second
+}
+
 // IsHF returns whether num is either equal to the hf block or greater.
 func (c *ChainConfig) IsHF(hf int, num *big.Int) bool {
 	if c.HF[hf] == nil {
@@ -287,6 +343,15 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
 		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
 	}
+	if isForkIncompatible(c.multiAlgoBlock(), newcfg.multiAlgoBlock(), head) {
+		return newCompatError("Multi-algorithm fork block", c.multiAlgoBlock(), newcfg.multiAlgoBlock())
+	}
+	if isForkIncompatible(c.feeMarketBlock(), newcfg.feeMarketBlock(), head) {
+		return newCompatError("Fee market fork block", c.feeMarketBlock(), newcfg.feeMarketBlock())
+	}
+	if change := c.rewardsChange(newcfg); isForked(change, head) {
+		return newCompatError("reward schedule", change, change)
+	}
 	return nil
 }
 
@@ -20,6 +20,7 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -70,6 +71,37 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     9,
 			},
 		},
+		{
+			stored: &ChainConfig{MultiAlgo: &MultiAlgoConfig{Block: big.NewInt(10), Algorithms: []uint64{1, 2}}},
+			new:    &ChainConfig{},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "Multi-algorithm fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    nil,
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{},
+			new:     &ChainConfig{Rewards: &RewardConfig{Eras: DefaultRewards.Eras, End: MaxMoney, TailEmission: big.NewInt(1)}},
+			head:    100,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{},
+			new: &ChainConfig{Rewards: &RewardConfig{Eras: []RewardEra{
+				DefaultRewards.Eras[0],
+				{Block: big.NewInt(50), BlockReward: big.NewInt(1), UncleDivisor: 8, InclusionDivisor: 32},
+			}}},
+			head: 100,
+			wantErr: &ConfigCompatError{
+				What:         "reward schedule",
+				StoredConfig: big.NewInt(50),
+				NewConfig:    big.NewInt(50),
+				RewindTo:     49,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -79,3 +111,58 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestRewardConfigValidate(t *testing.T) {
+	era := func(block, reward int64) RewardEra {
+		return RewardEra{Block: big.NewInt(block), BlockReward: big.NewInt(reward), UncleDivisor: 8, InclusionDivisor: 32}
+	}
+	tests := []struct {
+		rewards *RewardConfig
+		valid   bool
+	}{
+		{nil, true},
+		{DefaultRewards, true},
+		{&RewardConfig{}, false},
+		{&RewardConfig{Eras: []RewardEra{era(1, 1)}}, false},
+		{&RewardConfig{Eras: []RewardEra{era(0, 2), era(10, 1)}, End: big.NewInt(20), TailEmission: big.NewInt(1)}, true},
+		{&RewardConfig{Eras: []RewardEra{era(0, 2), era(0, 1)}}, false},
+		{&RewardConfig{Eras: []RewardEra{era(0, 2), era(10, 1)}, End: big.NewInt(10)}, false},
+		{&RewardConfig{Eras: []RewardEra{era(0, -1)}}, false},
+		{&RewardConfig{Eras: []RewardEra{{Block: big.NewInt(0), BlockReward: big.NewInt(1)}}}, false},
+	}
+	for i, tt := range tests {
+		if err := tt.rewards.Validate(); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want %v", i, err, tt.valid)
+		}
+	}
+}
+
+func TestForkBlocks(t *testing.T) {
+	config := &ChainConfig{
+		HomesteadBlock: big.NewInt(0),
+		EIP150Block:    big.NewInt(100),
+		EIP155Block:    big.NewInt(100),
+		HF:             ForkMap{0: big.NewInt(50), 1: big.NewInt(200)},
+		FeeMarket:      &FeeMarketConfig{Block: big.NewInt(150)},
+	}
+	want := []uint64{50, 100, 150, 200}
+	if have := config.ForkBlocks(); !reflect.DeepEqual(have, want) {
+		t.Errorf("fork blocks mismatch: have %v, want %v", have, want)
+	}
+}
+
+func TestAllowedFutureBlockTime(t *testing.T) {
+	tests := []struct {
+		config *ChainConfig
+		want   time.Duration
+	}{
+		{&ChainConfig{}, 15 * time.Second},
+		{&ChainConfig{Aquahash: new(AquahashConfig)}, 15 * time.Second},
+		{&ChainConfig{Aquahash: &AquahashConfig{FutureBlockTime: 60}}, time.Minute},
+	}
+	for i, tt := range tests {
+		if have := tt.config.AllowedFutureBlockTime(); have != tt.want {
+			t.Errorf("test %d: allowance mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
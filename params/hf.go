@@ -19,6 +19,7 @@ package params
 import (
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 )
 
@@ -33,6 +34,8 @@ func (f ForkMap) String() (s string) {
 
 type HeaderVersion byte
 
+// GetBlockVersion returns the header version scheduled at height. In the
+// multi-algorithm era blocks choose their own, see types.BlockVersion.
 func (c ChainConfig) GetBlockVersion(height *big.Int) HeaderVersion {
 	if height == nil {
 		return 2
@@ -42,3 +45,93 @@ func (c ChainConfig) GetBlockVersion(height *big.Int) HeaderVersion {
 	}
 	return 1
 }
+
+// MultiAlgoConfig schedules the multi-algorithm proof-of-work era. From its
+// activation block on, every block may be sealed by any of the approved
+// algorithms, naming the header version it uses in the first byte of its extra
+// data, and the difficulty is tracked separately for every algorithm.
+type MultiAlgoConfig struct {
+	Block      *big.Int `json:"block"`      // Activation block
+	Algorithms []uint64 `json:"algorithms"` // Header versions approved for sealing
+}
+
+// IsMultiAlgo returns whether num is in the multi-algorithm era.
+func (c *ChainConfig) IsMultiAlgo(num *big.Int) bool {
+	return c.MultiAlgo != nil && isForked(c.MultiAlgo.Block, num)
+}
+
+// IsApprovedAlgo returns whether blocks at num may be sealed by the algorithm
+// of the given header version.
+func (c *ChainConfig) IsApprovedAlgo(version HeaderVersion, num *big.Int) bool {
+	if !c.IsMultiAlgo(num) {
+		return false
+	}
+	for _, approved := range c.MultiAlgo.Algorithms {
+		if approved == uint64(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// multiAlgoBlock returns the activation block of the multi-algorithm era, nil
+// if it isn't scheduled.
+func (c *ChainConfig) multiAlgoBlock() *big.Int {
+	if c.MultiAlgo == nil {
+		return nil
+	}
+	return c.MultiAlgo.Block
+}
+
+// FeeMarketConfig schedules the fee market. From its activation block on, every
+// header carries a base fee, adjusted from block to block towards half full
+// blocks and burnt by every transaction, and dynamic fee transactions, paying
+// the base fee and a tip to the miner, are valid alongside legacy ones.
+type FeeMarketConfig struct {
+	Block *big.Int `json:"block"` // Activation block
+}
+
+// IsFeeMarket returns whether num is in the fee market era.
+func (c *ChainConfig) IsFeeMarket(num *big.Int) bool {
+	return c.FeeMarket != nil && isForked(c.FeeMarket.Block, num)
+}
+
+// feeMarketBlock returns the activation block of the fee market, nil if it
+// isn't scheduled.
+func (c *ChainConfig) feeMarketBlock() *big.Int {
+	if c.FeeMarket == nil {
+		return nil
+	}
+	return c.FeeMarket.Block
+}
+
+// ForkBlocks returns the blocks at which the scheduled forks change the
+// consensus rules, in ascending order and without duplicates. Forks active from
+// genesis are left out, as they make no difference between nodes.
+func (c *ChainConfig) ForkBlocks() []uint64 {
+	blocks := []*big.Int{
+		c.HomesteadBlock, c.DAOForkBlock, c.EIP150Block, c.EIP155Block, c.EIP158Block,
+		c.ByzantiumBlock, c.ConstantinopleBlock, c.multiAlgoBlock(), c.feeMarketBlock(),
+	}
+	for _, block := range c.HF {
+		blocks = append(blocks, block)
+	}
+	if c.Rewards != nil {
+		blocks = append(blocks, c.Rewards.bounds()...)
+	}
+	var forks []uint64
+	for _, block := range blocks {
+		if block != nil && block.Sign() > 0 && block.IsUint64() {
+			forks = append(forks, block.Uint64())
+		}
+	}
+	sort.Slice(forks, func(i, j int) bool { return forks[i] < forks[j] })
+
+	unique := forks[:0]
+	for _, fork := range forks {
+		if len(unique) == 0 || fork != unique[len(unique)-1] {
+			unique = append(unique, fork)
+		}
+	}
+	return unique
+}
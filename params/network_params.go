@@ -23,4 +23,9 @@ const (
 	// BloomBitsBlocks is the number of blocks a single bloom bit section vector
 	// contains.
 	BloomBitsBlocks uint64 = 4096
+
+	// DefaultFutureBlockTime is the number of seconds the timestamp of a block
+	// may be ahead of the local clock by default, before it's considered a future
+	// block (see AquahashConfig.FutureBlockTime).
+	DefaultFutureBlockTime uint64 = 15
 )
@@ -27,6 +27,10 @@ const (
 	MinGasLimit          uint64 = 5000    // Minimum the gas limit may ever be.
 	GenesisGasLimit      uint64 = 4712388 // Gas limit of the Genesis block.
 
+	BaseFeeChangeDenominator uint64 = 8         // Bounds the amount the base fee can change between blocks.
+	ElasticityMultiplier     uint64 = 2         // Bounds the maximum gas limit a block may have relative to its gas target.
+	InitialBaseFee           uint64 = 100000000 // Base fee of the first block of the fee market, the default gas price of miners.
+
 	MaximumExtraDataSize  uint64 = 32    // Maximum size extra data may be after Genesis.
 	ExpByteGas            uint64 = 10    // Times ceil(log256(exponent)) for the EXP instruction.
 	SloadGas              uint64 = 50    // Multiplied by the number of 32-byte words that are copied (round up) for any *COPY operation and added.
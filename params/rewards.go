@@ -0,0 +1,170 @@
+// Copyright 2016 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// RewardConfig schedules the issuance of a chain. Every era sets the rewards of
+// the blocks from its first one on, until the next era begins. From End on,
+// miners earn the tail emission, if any, besides the fees.
+type RewardConfig struct {
+	Eras         []RewardEra `json:"eras"`                   // Reward eras, in block order, the first one starting at genesis
+	End          *big.Int    `json:"end,omitempty"`          // First block past the eras (nil = never)
+	TailEmission *big.Int    `json:"tailEmission,omitempty"` // Block reward from End on, in wei (nil = fees only)
+}
+
+// RewardEra is the reward schedule of the blocks from Block on. An uncle n
+// blocks older than its nephew earns (UncleDivisor-n)/UncleDivisor of the
+// block reward, and the nephew miner earns 1/InclusionDivisor of it for every
+// uncle included.
+type RewardEra struct {
+	Block            *big.Int `json:"block"`            // First block of the era
+	BlockReward      *big.Int `json:"blockReward"`      // Reward of the block miner, in wei
+	UncleDivisor     uint64   `json:"uncleDivisor"`     // Divisor of the uncle rewards
+	InclusionDivisor uint64   `json:"inclusionDivisor"` // Divisor of the uncle inclusion rewards
+}
+
+// DefaultRewards is the reward schedule of the chains not configuring one: 1
+// aqua per block with the usual uncle fractions, fees only from block MaxMoney
+// on.
+var DefaultRewards = &RewardConfig{
+	Eras: []RewardEra{
+		{Block: big.NewInt(0), BlockReward: big.NewInt(1e+18), UncleDivisor: 8, InclusionDivisor: 32},
+	},
+	End: MaxMoney,
+}
+
+// Validate checks that the eras are ordered from genesis on and that the
+// rewards are well defined.
+func (r *RewardConfig) Validate() error {
+	if r == nil {
+		return nil
+	}
+	if len(r.Eras) == 0 {
+		return errors.New("reward schedule without eras")
+	}
+	for i, era := range r.Eras {
+		switch {
+		case era.Block == nil:
+			return fmt.Errorf("reward era %d without first block", i)
+		case i == 0 && era.Block.Sign() != 0:
+			return fmt.Errorf("first reward era starting at block %v, want 0", era.Block)
+		case i > 0 && era.Block.Cmp(r.Eras[i-1].Block) <= 0:
+			return fmt.Errorf("reward era %d at block %v not after era %d at block %v", i, era.Block, i-1, r.Eras[i-1].Block)
+		case era.BlockReward == nil || era.BlockReward.Sign() < 0:
+			return fmt.Errorf("reward era %d without valid block reward", i)
+		case era.UncleDivisor == 0 || era.InclusionDivisor == 0:
+			return fmt.Errorf("reward era %d with zero divisor", i)
+		}
+	}
+	if r.End != nil && r.End.Cmp(r.Eras[len(r.Eras)-1].Block) <= 0 {
+		return fmt.Errorf("reward schedule ending at block %v before its last era", r.End)
+	}
+	if r.TailEmission != nil && r.TailEmission.Sign() < 0 {
+		return errors.New("negative tail emission")
+	}
+	return nil
+}
+
+// at returns the reward era of block num, nil if its miner only earns fees.
+// Past End, the tail emission keeps the uncle fractions of the last era.
+func (r *RewardConfig) at(num *big.Int) *RewardEra {
+	if r.End != nil && r.End.Cmp(num) <= 0 {
+		if r.TailEmission == nil {
+			return nil
+		}
+		tail := r.Eras[len(r.Eras)-1]
+		tail.Block, tail.BlockReward = r.End, r.TailEmission
+		return &tail
+	}
+	for i := len(r.Eras) - 1; i >= 0; i-- {
+		if isForked(r.Eras[i].Block, num) {
+			return &r.Eras[i]
+		}
+	}
+	return nil
+}
+
+// bounds returns the blocks at which the schedule may change the rewards.
+func (r *RewardConfig) bounds() []*big.Int {
+	bounds := make([]*big.Int, 0, len(r.Eras)+1)
+	for _, era := range r.Eras {
+		bounds = append(bounds, era.Block)
+	}
+	if r.End != nil {
+		bounds = append(bounds, r.End)
+	}
+	return bounds
+}
+
+// UncleReward returns the reward of an uncle depth blocks older than its
+// nephew.
+func (e *RewardEra) UncleReward(depth uint64) *big.Int {
+	if depth >= e.UncleDivisor {
+		return new(big.Int)
+	}
+	r := new(big.Int).SetUint64(e.UncleDivisor - depth)
+	r.Mul(r, e.BlockReward)
+	return r.Div(r, new(big.Int).SetUint64(e.UncleDivisor))
+}
+
+// InclusionReward returns the reward of the nephew miner for every uncle
+// included.
+func (e *RewardEra) InclusionReward() *big.Int {
+	return new(big.Int).Div(e.BlockReward, new(big.Int).SetUint64(e.InclusionDivisor))
+}
+
+// equal returns whether both eras reward blocks alike.
+func (e *RewardEra) equal(other *RewardEra) bool {
+	if e == nil || other == nil {
+		return e == other
+	}
+	return e.BlockReward.Cmp(other.BlockReward) == 0 && e.UncleDivisor == other.UncleDivisor && e.InclusionDivisor == other.InclusionDivisor
+}
+
+// rewards returns the reward schedule of the chain.
+func (c *ChainConfig) rewards() *RewardConfig {
+	if c.Rewards == nil {
+		return DefaultRewards
+	}
+	return c.Rewards
+}
+
+// RewardEra returns the reward era of block num, nil if its miner only earns
+// the fees.
+func (c *ChainConfig) RewardEra(num *big.Int) *RewardEra {
+	return c.rewards().at(num)
+}
+
+// rewardsChange returns the first block the reward schedules of both configs
+// reward differently, nil if they never do.
+func (c *ChainConfig) rewardsChange(newcfg *ChainConfig) *big.Int {
+	var first *big.Int
+	for _, bound := range append(c.rewards().bounds(), newcfg.rewards().bounds()...) {
+		if first != nil && first.Cmp(bound) <= 0 {
+			continue
+		}
+		if !c.RewardEra(bound).equal(newcfg.RewardEra(bound)) {
+			first = bound
+		}
+	}
+	return first
+}
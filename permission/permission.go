@@ -0,0 +1,336 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package permission implements the node and account allowlists of permissioned
+// private networks, as enabled by the permission section of the chain config.
+//
+// Nodes and accounts are allowed if they are listed in the local allowlist file
+// of the node, or if the allowlist contract configured in the chain config
+// approves them. An allowlist contract implements:
+//
+//	function isNodeAllowed(bytes32 nodeHash) view returns (bool)
+//	function isAccountAllowed(address account) view returns (bool)
+//
+// where nodeHash is the keccak256 hash of the 64 byte node ID.
+//
+// The local allowlist only governs what the node itself admits: peers and the
+// transactions of its pool. Block transactions are checked against the allowlist
+// contract alone, on top of the state of the parent block, so that all nodes
+// agree on the validity of blocks whatever their local allowlists.
+package permission
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aquanetwork/aquachain/accounts/abi"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/state"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/log"
+	"github.com/aquanetwork/aquachain/p2p/discover"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+const (
+	// AllowlistFile is the name of the local allowlist within the data directory.
+	AllowlistFile = "permissioned.json"
+
+	reloadInterval = 5 * time.Second // Minimum time between checks for allowlist file changes
+	callGas        = 100000          // Gas allowance of allowlist contract calls
+)
+
+// allowlistABI is the interface allowlist contracts have to implement.
+const allowlistABI = `[
+	{"constant":true,"inputs":[{"name":"nodeHash","type":"bytes32"}],"name":"isNodeAllowed","outputs":[{"name":"","type":"bool"}],"payable":false,"stateMutability":"view","type":"function"},
+	{"constant":true,"inputs":[{"name":"account","type":"address"}],"name":"isAccountAllowed","outputs":[{"name":"","type":"bool"}],"payable":false,"stateMutability":"view","type":"function"}
+]`
+
+// Allowlist is the format of the local allowlist file.
+type Allowlist struct {
+	Nodes    []string         `json:"nodes"`    // Enode URLs or hex node IDs
+	Accounts []common.Address `json:"accounts"` // Allowed transaction senders
+}
+
+// Chain is the part of the blockchain needed to query the allowlist contract.
+type Chain interface {
+	core.ChainContext
+	CurrentBlock() *types.Block
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// Checker decides which nodes and accounts are allowed on a permissioned network.
+type Checker struct {
+	config      *params.PermissionConfig
+	chainConfig *params.ChainConfig
+	chain       Chain
+	path        string // Path of the local allowlist file
+	abi         abi.ABI
+
+	nodes    map[discover.NodeID]bool // Nodes in the local allowlist
+	accounts map[common.Address]bool  // Accounts in the local allowlist
+	modTime  time.Time                // Modification time of the loaded allowlist file
+	checked  time.Time                // Last time the allowlist file was checked for changes
+
+	cacheHead     common.Hash              // Chain head the contract results were cached at
+	nodeCache     map[discover.NodeID]bool // Contract results for nodes at cacheHead
+	accountsCache map[common.Address]bool  // Contract results for accounts at cacheHead
+
+	blockParent common.Hash             // Parent of the block the sender results were cached for
+	blockCache  map[common.Address]bool // Contract results for the senders of the block
+
+	lock sync.Mutex
+}
+
+// New creates a permission checker for a chain with permissioning enabled,
+// loading the local allowlist from the given path.
+func New(chainConfig *params.ChainConfig, chain Chain, path string) (*Checker, error) {
+	if chainConfig.Permission == nil {
+		return nil, fmt.Errorf("permissioning not enabled in chain config")
+	}
+	parsed, err := abi.JSON(strings.NewReader(allowlistABI))
+	if err != nil {
+		return nil, err
+	}
+	c := &Checker{
+		config:      chainConfig.Permission,
+		chainConfig: chainConfig,
+		chain:       chain,
+		path:        path,
+		abi:         parsed,
+		nodes:       make(map[discover.NodeID]bool),
+		accounts:    make(map[common.Address]bool),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// RestrictsNodes returns whether p2p connections are restricted to allowed nodes.
+func (c *Checker) RestrictsNodes() bool {
+	return c.config.Nodes
+}
+
+// RestrictsAccounts returns whether transaction senders are restricted to
+// allowed accounts.
+func (c *Checker) RestrictsAccounts() bool {
+	return c.config.Accounts
+}
+
+// NodeAllowed returns whether the node may connect to us.
+func (c *Checker) NodeAllowed(id discover.NodeID) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.config.Nodes {
+		return true
+	}
+	c.reload()
+	if c.nodes[id] {
+		return true
+	}
+	if c.config.Contract == nil {
+		return false
+	}
+	c.resetCache()
+	allowed, ok := c.nodeCache[id]
+	if !ok {
+		block := c.chain.CurrentBlock()
+		statedb, err := c.chain.StateAt(block.Root())
+		if err != nil {
+			log.Warn("Failed to retrieve state for allowlist call", "err", err)
+			return false
+		}
+		allowed = c.call(block.Header(), statedb, "isNodeAllowed", crypto.Keccak256Hash(id[:]))
+		c.nodeCache[id] = allowed
+	}
+	return allowed
+}
+
+// AccountAllowed returns whether the account may send transactions.
+func (c *Checker) AccountAllowed(addr common.Address) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.config.Accounts || c.chainConfig.IsSystemAccount(addr) {
+		return true
+	}
+	c.reload()
+	if c.accounts[addr] {
+		return true
+	}
+	if c.config.Contract == nil {
+		return false
+	}
+	c.resetCache()
+	allowed, ok := c.accountsCache[addr]
+	if !ok {
+		block := c.chain.CurrentBlock()
+		statedb, err := c.chain.StateAt(block.Root())
+		if err != nil {
+			log.Warn("Failed to retrieve state for allowlist call", "err", err)
+			return false
+		}
+		allowed = c.call(block.Header(), statedb, "isAccountAllowed", addr)
+		c.accountsCache[addr] = allowed
+	}
+	return allowed
+}
+
+// AccountPermitted returns whether the account may send a transaction included
+// in the block of the given header. Only the allowlist contract is queried, on
+// top of the state of the parent block, never the local allowlist: the validity
+// of a block must not depend on the node importing it. Without a contract, block
+// transactions aren't restricted. It implements core.SenderFilter.
+func (c *Checker) AccountPermitted(addr common.Address, header *types.Header) bool {
+	if !c.config.Accounts || c.config.Contract == nil || c.chainConfig.IsSystemAccount(addr) {
+		return true
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if header.ParentHash != c.blockParent || c.blockCache == nil {
+		c.blockParent = header.ParentHash
+		c.blockCache = make(map[common.Address]bool)
+	}
+	if allowed, ok := c.blockCache[addr]; ok {
+		return allowed
+	}
+	parent := c.chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return false
+	}
+	statedb, err := c.chain.StateAt(parent.Root)
+	if err != nil {
+		log.Warn("Failed to retrieve parent state for allowlist call", "number", parent.Number, "err", err)
+		return false
+	}
+	allowed := c.call(header, statedb, "isAccountAllowed", addr)
+	c.blockCache[addr] = allowed
+	return allowed
+}
+
+// reload reloads the local allowlist if the file changed since it was loaded.
+func (c *Checker) reload() {
+	if time.Since(c.checked) < reloadInterval {
+		return
+	}
+	if err := c.load(); err != nil {
+		log.Warn("Failed to reload permission allowlist", "path", c.path, "err", err)
+	}
+}
+
+// load reads the local allowlist file, unless it's unchanged since last loaded.
+// A missing file is treated as an empty allowlist.
+func (c *Checker) load() error {
+	c.checked = time.Now()
+
+	info, err := os.Stat(c.path)
+	if os.IsNotExist(err) {
+		c.nodes = make(map[discover.NodeID]bool)
+		c.accounts = make(map[common.Address]bool)
+		c.modTime = time.Time{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(c.modTime) {
+		return nil
+	}
+	blob, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		return err
+	}
+	var list Allowlist
+	if err := json.Unmarshal(blob, &list); err != nil {
+		return fmt.Errorf("invalid allowlist %s: %v", c.path, err)
+	}
+	nodes := make(map[discover.NodeID]bool, len(list.Nodes))
+	for _, entry := range list.Nodes {
+		id, err := parseNodeID(entry)
+		if err != nil {
+			return fmt.Errorf("invalid allowlist node %q: %v", entry, err)
+		}
+		nodes[id] = true
+	}
+	accounts := make(map[common.Address]bool, len(list.Accounts))
+	for _, addr := range list.Accounts {
+		accounts[addr] = true
+	}
+	c.nodes, c.accounts, c.modTime = nodes, accounts, info.ModTime()
+
+	log.Info("Loaded permission allowlist", "path", c.path, "nodes", len(nodes), "accounts", len(accounts))
+	return nil
+}
+
+// parseNodeID parses an allowlisted node given as an enode URL or a hex node ID.
+func parseNodeID(entry string) (discover.NodeID, error) {
+	if strings.HasPrefix(entry, "enode://") {
+		node, err := discover.ParseNode(entry)
+		if err != nil {
+			return discover.NodeID{}, err
+		}
+		return node.ID, nil
+	}
+	return discover.HexID(entry)
+}
+
+// resetCache drops the cached contract results if the chain head moved.
+func (c *Checker) resetCache() {
+	if head := c.chain.CurrentBlock().Hash(); head != c.cacheHead || c.nodeCache == nil {
+		c.cacheHead = head
+		c.nodeCache = make(map[discover.NodeID]bool)
+		c.accountsCache = make(map[common.Address]bool)
+	}
+}
+
+// call invokes a method of the allowlist contract on top of the given state,
+// treating any failure as a denial.
+func (c *Checker) call(header *types.Header, statedb *state.StateDB, method string, args ...interface{}) bool {
+	input, err := c.abi.Pack(method, args...)
+	if err != nil {
+		log.Error("Failed to pack allowlist call", "method", method, "err", err)
+		return false
+	}
+	var (
+		contract = *c.config.Contract
+		msg      = types.NewMessage(common.Address{}, &contract, 0, new(big.Int), callGas, new(big.Int), input, false)
+		context  = core.NewEVMContext(msg, header, c.chain, nil)
+		evm      = vm.NewEVM(context, statedb, c.chainConfig, vm.Config{})
+	)
+	ret, _, err := evm.StaticCall(vm.AccountRef(common.Address{}), contract, input, callGas)
+	if err != nil {
+		log.Debug("Allowlist contract call failed", "method", method, "err", err)
+		return false
+	}
+	var allowed bool
+	if err := c.abi.Unpack(&allowed, method, ret); err != nil {
+		log.Debug("Invalid allowlist contract result", "method", method, "err", err)
+		return false
+	}
+	return allowed
+}
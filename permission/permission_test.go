@@ -0,0 +1,239 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package permission
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/core/vm"
+	"github.com/aquanetwork/aquachain/crypto"
+	"github.com/aquanetwork/aquachain/p2p/discover"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+var (
+	allowlistAddr = common.HexToAddress("0x0000000000000000000000000000000000000a11")
+
+	allowAllCode = common.FromHex("0x600160005260206000f3") // return uint256(1)
+	denyAllCode  = common.FromHex("0x600060005260206000f3") // return uint256(0)
+
+	testNode    = discover.NodeID{1}
+	testAccount = common.HexToAddress("0x0000000000000000000000000000000000000001")
+)
+
+// newTestChecker creates a checker on top of a chain with the given allowlist
+// contract code deployed, or no contract if code is nil.
+func newTestChecker(t *testing.T, code []byte, path string) *Checker {
+	config := *params.TestChainConfig
+	config.Permission = &params.PermissionConfig{Nodes: true, Accounts: true}
+
+	gspec := &core.Genesis{Config: &config}
+	if code != nil {
+		config.Permission.Contract = &allowlistAddr
+		gspec.Alloc = core.GenesisAlloc{allowlistAddr: {Code: code, Balance: new(big.Int)}}
+	}
+	db, _ := aquadb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, &config, aquahash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	checker, err := New(&config, chain, path)
+	if err != nil {
+		t.Fatalf("failed to create checker: %v", err)
+	}
+	return checker
+}
+
+func writeAllowlist(t *testing.T, path string, list *Allowlist) {
+	blob, _ := json.Marshal(list)
+	if err := ioutil.WriteFile(path, blob, 0600); err != nil {
+		t.Fatalf("failed to write allowlist: %v", err)
+	}
+}
+
+func TestLocalAllowlist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "permission-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, AllowlistFile)
+
+	checker := newTestChecker(t, nil, path)
+	if checker.NodeAllowed(testNode) || checker.AccountAllowed(testAccount) {
+		t.Fatalf("entries allowed without allowlist")
+	}
+	writeAllowlist(t, path, &Allowlist{
+		Nodes:    []string{testNode.String()},
+		Accounts: []common.Address{testAccount},
+	})
+	// Force the reload instead of waiting for the reload interval
+	checker.checked = time.Time{}
+
+	if !checker.NodeAllowed(testNode) {
+		t.Errorf("allowlisted node rejected")
+	}
+	if !checker.AccountAllowed(testAccount) {
+		t.Errorf("allowlisted account rejected")
+	}
+	if checker.NodeAllowed(discover.NodeID{2}) || checker.AccountAllowed(common.Address{2}) {
+		t.Errorf("unlisted entries allowed")
+	}
+}
+
+func TestContractAllowlist(t *testing.T) {
+	allow := newTestChecker(t, allowAllCode, "")
+	if !allow.NodeAllowed(testNode) || !allow.AccountAllowed(testAccount) {
+		t.Errorf("entries approved by contract rejected")
+	}
+	deny := newTestChecker(t, denyAllCode, "")
+	if deny.NodeAllowed(testNode) || deny.AccountAllowed(testAccount) {
+		t.Errorf("entries denied by contract allowed")
+	}
+}
+
+// Tests that blocks with transactions of senders denied by the allowlist are
+// refused on import, not just by the transaction pool.
+func TestBlockSenders(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *params.TestChainConfig
+	config.Permission = &params.PermissionConfig{Accounts: true, Contract: &allowlistAddr}
+
+	gspec := &core.Genesis{
+		Config: &config,
+		Alloc: core.GenesisAlloc{
+			allowlistAddr: {Code: denyAllCode, Balance: new(big.Int)},
+			sender:        {Balance: big.NewInt(params.Aqua)},
+		},
+	}
+	db, _ := aquadb.NewMemDatabase()
+	genesis := gspec.MustCommit(db)
+
+	blocks, _ := core.GenerateChain(&config, genesis, aquahash.NewFaker(), db, 1, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), params.TxGas, nil, nil), types.HomesteadSigner{}, key)
+		b.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(db, nil, &config, aquahash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	checker, err := New(&config, chain, "")
+	if err != nil {
+		t.Fatalf("failed to create checker: %v", err)
+	}
+	chain.SetSenderFilter(checker.AccountPermitted)
+	if _, err := chain.InsertChain(blocks); err != core.ErrSenderNotPermitted {
+		t.Fatalf("import error mismatch: have %v, want %v", err, core.ErrSenderNotPermitted)
+	}
+}
+
+// Tests that nodes with different local allowlists agree on the validity of the
+// same block, block senders being checked against the allowlist contract only.
+func TestBlockSendersLocalAllowlist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "permission-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	listed := filepath.Join(dir, "listed.json")
+	writeAllowlist(t, listed, &Allowlist{Accounts: []common.Address{sender}})
+	unlisted := filepath.Join(dir, "unlisted.json")
+	writeAllowlist(t, unlisted, &Allowlist{})
+
+	for _, tt := range []struct {
+		code []byte
+		err  error
+	}{
+		{nil, nil},
+		{allowAllCode, nil},
+		{denyAllCode, core.ErrSenderNotPermitted},
+	} {
+		config := *params.TestChainConfig
+		config.Permission = &params.PermissionConfig{Accounts: true}
+
+		gspec := &core.Genesis{
+			Config: &config,
+			Alloc:  core.GenesisAlloc{sender: {Balance: big.NewInt(params.Aqua)}},
+		}
+		if tt.code != nil {
+			config.Permission.Contract = &allowlistAddr
+			gspec.Alloc[allowlistAddr] = core.GenesisAccount{Code: tt.code, Balance: new(big.Int)}
+		}
+		genDb, _ := aquadb.NewMemDatabase()
+		genesis := gspec.MustCommit(genDb)
+		blocks, _ := core.GenerateChain(&config, genesis, aquahash.NewFaker(), genDb, 1, func(i int, b *core.BlockGen) {
+			tx, _ := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), params.TxGas, nil, nil), types.HomesteadSigner{}, key)
+			b.AddTx(tx)
+		})
+		for _, path := range []string{listed, unlisted} {
+			db, _ := aquadb.NewMemDatabase()
+			gspec.MustCommit(db)
+
+			chain, err := core.NewBlockChain(db, nil, &config, aquahash.NewFaker(), vm.Config{})
+			if err != nil {
+				t.Fatalf("failed to create chain: %v", err)
+			}
+			checker, err := New(&config, chain, path)
+			if err != nil {
+				t.Fatalf("failed to create checker: %v", err)
+			}
+			chain.SetSenderFilter(checker.AccountPermitted)
+			if _, err := chain.InsertChain(blocks); err != tt.err {
+				t.Errorf("contract %x, allowlist %s: import error mismatch: have %v, want %v", tt.code, filepath.Base(path), err, tt.err)
+			}
+			chain.Stop()
+		}
+	}
+}
+
+func TestInvalidAllowlist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "permission-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, AllowlistFile)
+
+	writeAllowlist(t, path, &Allowlist{Nodes: []string{"not a node"}})
+
+	config := *params.TestChainConfig
+	config.Permission = &params.PermissionConfig{Nodes: true}
+	if _, err := New(&config, nil, path); err == nil {
+		t.Fatalf("invalid allowlist accepted")
+	}
+}
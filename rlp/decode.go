@@ -64,12 +64,17 @@ type Decoder interface {
 // error if there are too few or too many elements.
 //
 // The decoding of struct fields honours certain struct tags, "tail",
-// "nil" and "-".
+// "nil", "optional" and "-".
 //
 // The "-" tag ignores fields.
 //
 // For an explanation of "tail", see the example.
 //
+// The "optional" tag lets the input list end before the field, leaving it and
+// the following fields, which must all be optional, zero. When encoding, the
+// trailing optional fields holding zero values are omitted. This allows adding
+// fields to a struct without changing the encoding of existing values.
+//
 // The "nil" tag applies to pointer-typed fields and changes the decoding
 // rules for the field such that input values of size zero decode as a nil
 // pointer. This tag can be useful when decoding recursive types.
@@ -422,8 +427,15 @@ func makeStructDecoder(typ reflect.Type) (decoder, error) {
 		if _, err := s.List(); err != nil {
 			return wrapStreamError(err, typ)
 		}
-		for _, f := range fields {
+		for i, f := range fields {
 			err := f.info.decoder(s, val.Field(f.index))
+			if err == EOL && f.optional {
+				// The missing optional fields, and the ones following them, are zero
+				for _, f := range fields[i:] {
+					val.Field(f.index).Set(reflect.Zero(val.Field(f.index).Type()))
+				}
+				break
+			}
 			if err == EOL {
 				return &decodeError{msg: "too few elements", typ: typ}
 			} else if err != nil {
@@ -567,6 +579,7 @@ var (
 	ErrCanonSize      = errors.New("rlp: non-canonical size information")
 	ErrElemTooLarge   = errors.New("rlp: element is larger than containing list")
 	ErrValueTooLarge  = errors.New("rlp: value size exceeds available input length")
+	ErrValueOverLimit = errors.New("rlp: value size exceeds the value limit")
 
 	// This error is reported by DecodeBytes if the slice contains
 	// additional data after the first RLP value.
@@ -604,6 +617,9 @@ type Stream struct {
 	remaining uint64
 	limited   bool
 
+	// maximum size of toplevel values, zero if unlimited.
+	valueLimit uint64
+
 	// auxiliary buffer for integer decoding
 	uintbuf []byte
 
@@ -853,6 +869,7 @@ func (s *Stream) Reset(r io.Reader, inputLimit uint64) {
 	}
 	s.r = bufr
 	// Reset the decoding context.
+	s.valueLimit = 0
 	s.stack = s.stack[:0]
 	s.size = 0
 	s.kind = -1
@@ -892,6 +909,8 @@ func (s *Stream) Kind() (kind Kind, size uint64, err error) {
 				// than the remaining input length.
 				if s.limited && s.size > s.remaining {
 					s.kinderr = ErrValueTooLarge
+				} else if s.valueLimit > 0 && s.size > s.valueLimit {
+					s.kinderr = ErrValueOverLimit
 				}
 			} else {
 				// Inside a list, check that the value doesn't overflow the list.
@@ -354,6 +354,17 @@ var (
 	)
 )
 
+type optionalFields struct {
+	A uint
+	B uint  `rlp:"optional"`
+	C *uint `rlp:"optional"`
+}
+
+type invalidOptional struct {
+	A uint `rlp:"optional"`
+	B uint
+}
+
 type hasIgnoredField struct {
 	A uint
 	B uint `rlp:"-"`
@@ -494,6 +505,17 @@ var decodeTests = []decodeTest{
 		error: "rlp: expected input string or byte for uint, decoding into (rlp.tailUint).Tail[1]",
 	},
 
+	// struct tag "optional"
+	{input: "C101", ptr: new(optionalFields), value: optionalFields{A: 1}},
+	{input: "C20102", ptr: new(optionalFields), value: optionalFields{A: 1, B: 2}},
+	{input: "C3010203", ptr: new(optionalFields), value: optionalFields{A: 1, B: 2, C: uintp(3)}},
+	{input: "C0", ptr: new(optionalFields), error: "rlp: too few elements for rlp.optionalFields"},
+	{
+		input: "C101",
+		ptr:   new(invalidOptional),
+		error: `rlp: struct field rlp.invalidOptional.B needs "optional" tag (previous field A is optional)`,
+	},
+
 	// struct tag "tail"
 	{
 		input: "C3010203",
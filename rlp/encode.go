@@ -533,7 +533,7 @@ func makeStructWriter(typ reflect.Type) (writer, error) {
 	}
 	writer := func(val reflect.Value, w *encbuf) error {
 		lh := w.list()
-		for _, f := range fields {
+		for _, f := range fields[:lastPresentField(val, fields)+1] {
 			if err := f.info.writer(val.Field(f.index), w); err != nil {
 				return err
 			}
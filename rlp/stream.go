@@ -0,0 +1,89 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlp
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// streamBufferSize is the size of the write buffer of a StreamEncoder.
+const streamBufferSize = 64 * 1024
+
+// StreamEncoder writes a sequence of RLP values to an output stream. Unlike
+// Encode, it reuses its encoding buffer across values and batches the writes,
+// so encoding a sequence of any length only needs as much memory as the largest
+// value of it.
+type StreamEncoder struct {
+	w   *bufio.Writer
+	buf *encbuf
+}
+
+// NewStreamEncoder creates a stream encoder writing to w. Flush has to be
+// called once done to write out the buffered data.
+func NewStreamEncoder(w io.Writer) *StreamEncoder {
+	return &StreamEncoder{
+		w:   bufio.NewWriterSize(w, streamBufferSize),
+		buf: &encbuf{sizebuf: make([]byte, 9)},
+	}
+}
+
+// Encode writes the RLP encoding of val to the stream. Please see the
+// documentation of Encode for the encoding rules.
+func (e *StreamEncoder) Encode(val interface{}) error {
+	e.buf.reset()
+	if err := e.buf.encode(val); err != nil {
+		return err
+	}
+	return e.buf.toWriter(e.w)
+}
+
+// Flush writes any buffered data out to the underlying writer.
+func (e *StreamEncoder) Flush() error {
+	return e.w.Flush()
+}
+
+// NewValueStream creates a stream decoding a sequence of values from r, none of
+// which may be larger than valueLimit bytes. Unlike an input limit, the value
+// limit bounds the memory needed to decode any single value of an input of any
+// length, and rejects corrupt size prefixes before anything is allocated for
+// them. Larger values fail with ErrValueOverLimit.
+func NewValueStream(r io.Reader, valueLimit uint64) *Stream {
+	s := NewStream(r, 0)
+	s.valueLimit = valueLimit
+	return s
+}
+
+// RawListReader returns a reader streaming the RLP encoding of a list of already
+// encoded items, along with the total size of the encoding. The items are read
+// in place rather than copied into a single buffer.
+func RawListReader(items []RawValue) (size int, r io.Reader) {
+	var content uint64
+	for _, item := range items {
+		content += uint64(len(item))
+	}
+	head := make([]byte, 9)
+	head = head[:puthead(head, 0xC0, 0xF7, content)]
+
+	readers := make([]io.Reader, 0, len(items)+1)
+	readers = append(readers, bytes.NewReader(head))
+	for _, item := range items {
+		readers = append(readers, bytes.NewReader(item))
+	}
+	return len(head) + int(content), io.MultiReader(readers...)
+}
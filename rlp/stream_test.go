@@ -0,0 +1,105 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"testing"
+)
+
+func TestStreamEncoder(t *testing.T) {
+	values := []interface{}{
+		uint(0), "dog", []uint{1, 2, 3}, big.NewInt(1 << 40),
+		bytes.Repeat([]byte{0xaa}, 70000), // larger than the write buffer
+		[]interface{}{"cat", []string{"a", "b"}},
+	}
+	var (
+		out  = new(bytes.Buffer)
+		enc  = NewStreamEncoder(out)
+		want []byte
+	)
+	for _, val := range values {
+		if err := enc.Encode(val); err != nil {
+			t.Fatalf("failed to encode %v: %v", val, err)
+		}
+		blob, _ := EncodeToBytes(val)
+		want = append(want, blob...)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("encoding mismatch:\nhave %x\nwant %x", out.Bytes(), want)
+	}
+	if err := enc.Encode(int(1)); err == nil {
+		t.Fatalf("unsupported value encoded")
+	}
+}
+
+func TestValueStream(t *testing.T) {
+	small, _ := EncodeToBytes([]uint{1, 2, 3})
+	large, _ := EncodeToBytes(bytes.Repeat([]byte{1}, 100))
+
+	// Values within the limit decode, larger ones don't
+	s := NewValueStream(ioutil.NopCloser(bytes.NewReader(append(small, large...))), 64)
+	var list []uint
+	if err := s.Decode(&list); err != nil {
+		t.Fatalf("failed to decode value within limit: %v", err)
+	}
+	var blob []byte
+	if err := s.Decode(&blob); err != ErrValueOverLimit {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrValueOverLimit)
+	}
+	// A corrupt size is rejected before reading the value
+	s = NewValueStream(ioutil.NopCloser(bytes.NewReader(unhex("BBFFFFFFFF"))), 64)
+	if err := s.Decode(&blob); err != ErrValueOverLimit {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrValueOverLimit)
+	}
+	// The limit doesn't survive a reset
+	s.Reset(bytes.NewReader(large), 0)
+	if err := s.Decode(&blob); err != nil {
+		t.Fatalf("failed to decode after reset: %v", err)
+	}
+}
+
+func TestRawListReader(t *testing.T) {
+	tests := [][]RawValue{
+		nil,
+		{unhex("01"), unhex("820102")},
+		{bytes.Repeat([]byte{0x01}, 30), bytes.Repeat([]byte{0x02}, 30)},
+	}
+	for i, items := range tests {
+		size, r := RawListReader(items)
+		have, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("test %d: failed to read: %v", i, err)
+		}
+		want, _ := EncodeToBytes(items)
+		if !bytes.Equal(have, want) {
+			t.Errorf("test %d: encoding mismatch: have %x, want %x", i, have, want)
+		}
+		if size != len(want) {
+			t.Errorf("test %d: size mismatch: have %d, want %d", i, size, len(want))
+		}
+		if _, err := r.Read(make([]byte, 1)); err != io.EOF {
+			t.Errorf("test %d: read past the end: %v", i, err)
+		}
+	}
+}
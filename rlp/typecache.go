@@ -41,6 +41,11 @@ type tags struct {
 	// elements. It can only be set for the last field, which must be
 	// of slice type.
 	tail bool
+	// rlp:"optional" allows the field to be missing from the input
+	// list, leaving it zero, and omits it from the output if it's zero
+	// along with all the following ones. Every field following an
+	// optional one must be optional too.
+	optional bool
 	// rlp:"-" ignores fields.
 	ignored bool
 }
@@ -91,11 +96,13 @@ func cachedTypeInfo1(typ reflect.Type, tags tags) (*typeinfo, error) {
 }
 
 type field struct {
-	index int
-	info  *typeinfo
+	index    int
+	info     *typeinfo
+	optional bool
 }
 
 func structFields(typ reflect.Type) (fields []field, err error) {
+	var optional string // name of the first optional field
 	for i := 0; i < typ.NumField(); i++ {
 		if f := typ.Field(i); f.PkgPath == "" { // exported
 			tags, err := parseStructTag(typ, i)
@@ -105,16 +112,54 @@ func structFields(typ reflect.Type) (fields []field, err error) {
 			if tags.ignored {
 				continue
 			}
+			if tags.optional && optional == "" {
+				optional = f.Name
+			} else if !tags.optional && !tags.tail && optional != "" {
+				return nil, fmt.Errorf(`rlp: struct field %v.%s needs "optional" tag (previous field %s is optional)`, typ, f.Name, optional)
+			}
 			info, err := cachedTypeInfo1(f.Type, tags)
 			if err != nil {
 				return nil, err
 			}
-			fields = append(fields, field{i, info})
+			fields = append(fields, field{i, info, tags.optional})
 		}
 	}
 	return fields, nil
 }
 
+// lastPresentField returns the index, in fields, of the last field of val to
+// encode: trailing optional fields holding zero values are omitted.
+func lastPresentField(val reflect.Value, fields []field) int {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if !fields[i].optional || !isZero(val.Field(fields[i].index)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isZero returns whether v holds the zero value of its type. Arrays and structs
+// are only zero if they have no elements or fields.
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.String, reflect.Slice, reflect.Array:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface, reflect.Map:
+		return v.IsNil()
+	case reflect.Struct:
+		return v.NumField() == 0
+	}
+	return false
+}
+
 func parseStructTag(typ reflect.Type, fi int) (tags, error) {
 	f := typ.Field(fi)
 	var ts tags
@@ -125,8 +170,16 @@ func parseStructTag(typ reflect.Type, fi int) (tags, error) {
 			ts.ignored = true
 		case "nil":
 			ts.nilOK = true
+		case "optional":
+			ts.optional = true
+			if ts.tail {
+				return ts, fmt.Errorf(`rlp: invalid struct tag "optional" for %v.%s (also has "tail" tag)`, typ, f.Name)
+			}
 		case "tail":
 			ts.tail = true
+			if ts.optional {
+				return ts, fmt.Errorf(`rlp: invalid struct tag "tail" for %v.%s (also has "optional" tag)`, typ, f.Name)
+			}
 			if fi != typ.NumField()-1 {
 				return ts, fmt.Errorf(`rlp: invalid struct tag "tail" for %v.%s (must be on last field)`, typ, f.Name)
 			}
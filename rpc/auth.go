@@ -0,0 +1,85 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// jwtIssuedAtTolerance is the maximum difference between the issuance time of
+// a JWT and the local clock, bounding the replay of intercepted tokens.
+const jwtIssuedAtTolerance = 60 * time.Second
+
+// authHandler dispatches requests carrying the expected bearer token, or a JWT
+// signed with it, to a privileged handler, and all others to the public one.
+type authHandler struct {
+	token  []byte
+	public http.Handler
+	authed http.Handler
+}
+
+// NewAuthHandler creates an HTTP handler passing requests with an Authorization
+// header of "Bearer <token>" to authed, and requests without a bearer token to
+// public. Instead of the token itself, clients may present a JWT signed with it
+// using HS256, with an "iat" claim within a minute of the local time. Requests
+// with a wrong token are rejected. Both HTTP-RPC and websocket upgrade requests
+// can be dispatched this way.
+func NewAuthHandler(token string, public, authed http.Handler) http.Handler {
+	return &authHandler{token: []byte(token), public: public, authed: authed}
+}
+
+func (h *authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		h.public.ServeHTTP(w, r)
+		return
+	}
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if len(h.token) == 0 || (subtle.ConstantTimeCompare([]byte(token), h.token) != 1 && !h.validJWT(token)) {
+		http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+		return
+	}
+	h.authed.ServeHTTP(w, r)
+}
+
+// validJWT returns whether token is a JWT signed with the handler's token using
+// HS256 and issued around the current time.
+func (h *authHandler) validJWT(token string) bool {
+	if strings.Count(token, ".") != 2 {
+		return false
+	}
+	parser := &jwt.Parser{ValidMethods: []string{"HS256"}, SkipClaimsValidation: true}
+	parsed, err := parser.Parse(token, func(*jwt.Token) (interface{}, error) { return h.token, nil })
+	if err != nil || !parsed.Valid {
+		return false
+	}
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return false
+	}
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return false
+	}
+	drift := time.Since(time.Unix(int64(iat), 0))
+	return drift <= jwtIssuedAtTolerance && drift >= -jwtIssuedAtTolerance
+}
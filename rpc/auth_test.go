@@ -0,0 +1,75 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func TestAuthHandler(t *testing.T) {
+	named := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(name)) })
+	}
+	handler := NewAuthHandler("secret", named("public"), named("authed"))
+
+	sign := func(method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("failed to sign JWT: %v", err)
+		}
+		return token
+	}
+	now := time.Now().Unix()
+
+	tests := []struct {
+		auth   string
+		status int
+		body   string
+	}{
+		{"", http.StatusOK, "public"},
+		{"Basic c2VjcmV0", http.StatusOK, "public"},
+		{"Bearer secret", http.StatusOK, "authed"},
+		{"Bearer secre", http.StatusUnauthorized, ""},
+		{"Bearer ", http.StatusUnauthorized, ""},
+		{"Bearer " + sign(jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"iat": now}), http.StatusOK, "authed"},
+		{"Bearer " + sign(jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"iat": now + 30}), http.StatusOK, "authed"},
+		{"Bearer " + sign(jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"iat": now - 120}), http.StatusUnauthorized, ""},
+		{"Bearer " + sign(jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{}), http.StatusUnauthorized, ""},
+		{"Bearer " + sign(jwt.SigningMethodHS256, []byte("other"), jwt.MapClaims{"iat": now}), http.StatusUnauthorized, ""},
+		{"Bearer " + sign(jwt.SigningMethodHS512, []byte("secret"), jwt.MapClaims{"iat": now}), http.StatusUnauthorized, ""},
+	}
+	for i, test := range tests {
+		req := httptest.NewRequest("POST", "http://localhost", nil)
+		if test.auth != "" {
+			req.Header.Set("Authorization", test.auth)
+		}
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+
+		if resp.Code != test.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, resp.Code, test.status)
+		}
+		if test.body != "" && resp.Body.String() != test.body {
+			t.Errorf("test %d: served by %q, want %q", i, resp.Body.String(), test.body)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/aquanetwork/aquachain/common/hexutil"
+)
+
+// CanonicalJSON encodes a value into its canonical JSON form, which stays stable
+// regardless of how the value's type chooses to marshal itself: object keys are
+// sorted and non-negative integers are encoded as 0x-prefixed hex quantities.
+// Strings, booleans, nulls, fractional and negative numbers are left as is.
+func CanonicalJSON(v interface{}) (json.RawMessage, error) {
+	blob, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(blob))
+	dec.UseNumber()
+
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(canonicalize(generic))
+}
+
+// canonicalize converts the integers within a decoded JSON value into hex
+// quantities. Objects are decoded into maps, which encoding/json marshals with
+// sorted keys.
+func canonicalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			v[key] = canonicalize(field)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = canonicalize(item)
+		}
+		return v
+	case json.Number:
+		if strings.ContainsAny(string(v), ".eE-") {
+			return v
+		}
+		if n, ok := new(big.Int).SetString(string(v), 10); ok {
+			return (*hexutil.Big)(n)
+		}
+		return v
+	default:
+		return v
+	}
+}
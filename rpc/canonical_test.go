@@ -0,0 +1,53 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		input interface{}
+		want  string
+	}{
+		{nil, `null`},
+		{uint64(0), `"0x0"`},
+		{big.NewInt(1000000), `"0xf4240"`},
+		{new(big.Int).Lsh(big.NewInt(1), 100), `"0x10000000000000000000000000"`},
+		{-5, `-5`},
+		{1.5, `1.5`},
+		{"123", `"123"`},
+		{[]interface{}{true, 16, "a"}, `[true,"0x10","a"]`},
+		{map[string]interface{}{"z": 1, "a": map[string]int{"y": 2, "b": 3}}, `{"a":{"b":"0x3","y":"0x2"},"z":"0x1"}`},
+		{struct {
+			B int
+			A []uint
+		}{10, []uint{1}}, `{"A":["0x1"],"B":"0xa"}`},
+	}
+	for i, tt := range tests {
+		have, err := CanonicalJSON(tt.input)
+		if err != nil {
+			t.Errorf("test %d: error: %v", i, err)
+			continue
+		}
+		if string(have) != tt.want {
+			t.Errorf("test %d: output mismatch: have %s, want %s", i, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2015 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"reflect"
+	"sort"
+)
+
+// defaultVersion is the version of the namespaces registered without one.
+const defaultVersion = "1.0"
+
+// MethodCapabilities describes a method of a namespace to the clients of a
+// server, so they can feature-detect instead of probing with failing calls.
+type MethodCapabilities struct {
+	Params    int      `json:"params"`             // number of required parameters
+	MaxParams int      `json:"maxParams"`          // number of accepted parameters, the trailing ones being optional
+	Features  []string `json:"features,omitempty"` // optional behaviour supported by the method
+}
+
+// ModuleCapabilities describes a namespace served by a server.
+type ModuleCapabilities struct {
+	Version       string                        `json:"version"`
+	Methods       map[string]MethodCapabilities `json:"methods"`
+	Subscriptions []string                      `json:"subscriptions,omitempty"`
+}
+
+// RegisterAPI registers the service of api under its namespace like
+// RegisterNameFiltered, recording its version and method features for
+// rpc_modules and rpc_capabilities. The first version registered for a
+// namespace is the one reported.
+func (s *Server) RegisterAPI(api API, allow func(method string) bool) error {
+	if err := s.RegisterNameFiltered(api.Namespace, api.Service, allow); err != nil {
+		return err
+	}
+	if _, served := s.services[api.Namespace]; !served {
+		return nil
+	}
+	if s.versions == nil {
+		s.versions = make(map[string]string)
+		s.features = make(map[string]map[string][]string)
+	}
+	if _, ok := s.versions[api.Namespace]; !ok && api.Version != "" {
+		s.versions[api.Namespace] = api.Version
+	}
+	for method, features := range api.Features {
+		if s.features[api.Namespace] == nil {
+			s.features[api.Namespace] = make(map[string][]string)
+		}
+		s.features[api.Namespace][method] = append(s.features[api.Namespace][method], features...)
+	}
+	return nil
+}
+
+// version returns the version of the given namespace.
+func (s *Server) version(name string) string {
+	if version, ok := s.versions[name]; ok {
+		return version
+	}
+	return defaultVersion
+}
+
+// Capabilities returns, for every namespace, its version along with the
+// methods and subscriptions available on this server. Only the methods
+// exposed by the endpoint answering are listed, with the optional features
+// they support (e.g. state overrides on aqua_call).
+func (s *RPCService) Capabilities() map[string]ModuleCapabilities {
+	modules := make(map[string]ModuleCapabilities)
+	for name, svc := range s.server.services {
+		module := ModuleCapabilities{
+			Version: s.server.version(name),
+			Methods: make(map[string]MethodCapabilities),
+		}
+		for method, cb := range svc.callbacks {
+			module.Methods[method] = MethodCapabilities{
+				Params:    requiredArgs(cb.argTypes),
+				MaxParams: len(cb.argTypes),
+				Features:  s.server.features[name][method],
+			}
+		}
+		for subscription := range svc.subscriptions {
+			module.Subscriptions = append(module.Subscriptions, subscription)
+		}
+		sort.Strings(module.Subscriptions)
+		modules[name] = module
+	}
+	return modules
+}
+
+// requiredArgs returns the number of arguments a call must provide, the
+// trailing pointer arguments being optional.
+func requiredArgs(types []reflect.Type) int {
+	n := len(types)
+	for n > 0 && types[n-1].Kind() == reflect.Ptr {
+		n--
+	}
+	return n
+}
@@ -64,3 +64,28 @@ type shutdownError struct{}
 func (e *shutdownError) ErrorCode() int { return -32000 }
 
 func (e *shutdownError) Error() string { return "server is shutting down" }
+
+// issued when a client sends requests faster than the server allows.
+type rateLimitError struct{}
+
+func (e *rateLimitError) ErrorCode() int { return -32005 }
+
+func (e *rateLimitError) Error() string { return "request rate limit exceeded" }
+
+// issued when a batch holds more requests than the server allows.
+type batchLimitError struct{ size, limit int }
+
+func (e *batchLimitError) ErrorCode() int { return -32006 }
+
+func (e *batchLimitError) Error() string {
+	return fmt.Sprintf("batch of %d requests exceeds the limit of %d", e.size, e.limit)
+}
+
+// issued when the result of a call is larger than the server allows.
+type responseLimitError struct{ size, limit int }
+
+func (e *responseLimitError) ErrorCode() int { return -32007 }
+
+func (e *responseLimitError) Error() string {
+	return fmt.Sprintf("response of %d bytes exceeds the limit of %d", e.size, e.limit)
+}
@@ -151,10 +151,21 @@ func (t *httpReadWriteNopCloser) Close() error {
 //
 // Deprecated: Server implements http.Handler
 func NewHTTPServer(cors []string, vhosts []string, srv *Server) *http.Server {
+	return &http.Server{Handler: NewHTTPHandlerStack(srv, cors, vhosts)}
+}
+
+// NewHTTPHandlerStack wraps an HTTP handler within the CORS and virtual host
+// validating handlers used by the HTTP-RPC server.
+func NewHTTPHandlerStack(srv http.Handler, cors []string, vhosts []string) http.Handler {
 	// Wrap the CORS-handler within a host-handler
 	handler := newCorsHandler(srv, cors)
-	handler = newVHostHandler(vhosts, handler)
-	return &http.Server{Handler: handler}
+	return newVHostHandler(vhosts, handler)
+}
+
+// NewVHostHandler wraps a handler, such as a websocket one, rejecting requests
+// naming a host other than the given virtual hosts in their Host header.
+func NewVHostHandler(vhosts []string, next http.Handler) http.Handler {
+	return newVHostHandler(vhosts, next)
 }
 
 // ServeHTTP serves JSON-RPC requests over HTTP.
@@ -175,7 +186,7 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer codec.Close()
 
 	w.Header().Set("content-type", contentType)
-	srv.ServeSingleRequest(codec, OptionMethodInvocation)
+	srv.serveRequest(codec, true, OptionMethodInvocation|humanUnitsOption(r), remoteIP(r))
 }
 
 // validateRequest returns a non-zero response code and error message if the
@@ -196,7 +207,7 @@ func validateRequest(r *http.Request) (int, error) {
 	return 0, nil
 }
 
-func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
+func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
 		return srv
@@ -0,0 +1,107 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/http"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/hexutil"
+)
+
+// humanUnitFields are the object fields holding amounts of wei.
+var humanUnitFields = map[string]bool{
+	"value":   true,
+	"balance": true,
+}
+
+// humanUnitsKey is the context key marking the calls of a connection for which
+// the results are encoded with amounts in aqua.
+type humanUnitsKey struct{}
+
+// humanUnitsOption returns OptionHumanUnits if a request asks for amounts in
+// aqua with the human=true query parameter.
+func humanUnitsOption(r *http.Request) CodecOption {
+	if r.URL.Query().Get("human") == "true" {
+		return OptionHumanUnits
+	}
+	return 0
+}
+
+// HumanJSON encodes a value into JSON, adding next to every object field holding
+// an amount of wei the amount in aqua, as a decimal string in a field suffixed
+// with "Aqua", e.g. "valueAqua": "1.5" next to "value": "0x14d1120d7b160000".
+func HumanJSON(v interface{}) (json.RawMessage, error) {
+	blob, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(blob))
+	dec.UseNumber()
+
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(humanize(generic))
+}
+
+// humanize adds the amounts in aqua to the objects within a decoded JSON value.
+func humanize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		amounts := make(map[string]string)
+		for key, field := range v {
+			v[key] = humanize(field)
+			if !humanUnitFields[key] {
+				continue
+			}
+			if wei, ok := parseWei(field); ok {
+				amounts[key+"Aqua"] = common.FormatAqua(wei)
+			}
+		}
+		for key, amount := range amounts {
+			if _, ok := v[key]; !ok {
+				v[key] = amount
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = humanize(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// parseWei parses an amount of wei encoded as a hex quantity or a number.
+func parseWei(v interface{}) (*big.Int, bool) {
+	switch v := v.(type) {
+	case string:
+		wei, err := hexutil.DecodeBig(v)
+		return wei, err == nil
+	case json.Number:
+		return new(big.Int).SetString(string(v), 10)
+	default:
+		return nil, false
+	}
+}
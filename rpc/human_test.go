@@ -0,0 +1,86 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common/hexutil"
+)
+
+func TestHumanJSON(t *testing.T) {
+	aqua := new(big.Int).Mul(big.NewInt(15), big.NewInt(1e17))
+	tests := []struct {
+		input interface{}
+		want  string
+	}{
+		{(*hexutil.Big)(aqua), `"0x14d1120d7b160000"`},
+		{map[string]interface{}{"value": (*hexutil.Big)(aqua)}, `{"value":"0x14d1120d7b160000","valueAqua":"1.5"}`},
+		{map[string]interface{}{"balance": aqua, "nonce": 1}, `{"balance":1500000000000000000,"balanceAqua":"1.5","nonce":1}`},
+		{[]interface{}{map[string]interface{}{"value": "0x1"}}, `[{"value":"0x1","valueAqua":"0.000000000000000001"}]`},
+		{map[string]interface{}{"value": "abc"}, `{"value":"abc"}`},
+		{map[string]interface{}{"value": "0x0", "valueAqua": "kept"}, `{"value":"0x0","valueAqua":"kept"}`},
+	}
+	for i, tt := range tests {
+		have, err := HumanJSON(tt.input)
+		if err != nil {
+			t.Errorf("test %d: error: %v", i, err)
+			continue
+		}
+		if string(have) != tt.want {
+			t.Errorf("test %d: output mismatch: have %s, want %s", i, have, tt.want)
+		}
+	}
+}
+
+type HumanService struct{}
+
+func (s *HumanService) Transfer() map[string]interface{} {
+	return map[string]interface{}{"value": (*hexutil.Big)(big.NewInt(2e18))}
+}
+
+// Tests that the amounts in aqua are only added for HTTP requests asking so.
+func TestHTTPHumanUnits(t *testing.T) {
+	server := NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("test", new(HumanService)); err != nil {
+		t.Fatal(err)
+	}
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	call := func(url string) string {
+		resp, err := http.Post(url, contentType, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"test_transfer","params":[]}`))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return string(body)
+	}
+	if body := call(httpsrv.URL); strings.Contains(body, "valueAqua") {
+		t.Errorf("amount in aqua added without asking: %s", body)
+	}
+	if body := call(httpsrv.URL + "/?human=true"); !strings.Contains(body, `"valueAqua":"2"`) {
+		t.Errorf("amount in aqua missing: %s", body)
+	}
+}
@@ -20,11 +20,17 @@ package rpc
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
 )
 
+// maxUnixSocketPath is the length of the longest Unix socket path all platforms
+// accept, the size of the sun_path field of a socket address less its
+// terminating zero on the BSDs. Linux accepts a few more characters.
+const maxUnixSocketPath = 103
+
 // ipcListen will create a Unix socket on the given endpoint.
 func ipcListen(endpoint string) (net.Listener, error) {
 	// Ensure the IPC path exists and remove any previous leftover
@@ -34,6 +40,9 @@ func ipcListen(endpoint string) (net.Listener, error) {
 	os.Remove(endpoint)
 	l, err := net.Listen("unix", endpoint)
 	if err != nil {
+		if len(endpoint) > maxUnixSocketPath {
+			return nil, fmt.Errorf("IPC path too long (%d > %d characters), use a shorter one: %v", len(endpoint), maxUnixSocketPath, err)
+		}
 		return nil, err
 	}
 	os.Chmod(endpoint, 0600)
@@ -0,0 +1,69 @@
+// Copyright 2015 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build darwin dragonfly freebsd linux nacl netbsd openbsd solaris
+
+package rpc
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Tests that clients are served over a Unix socket, and that a socket path
+// too long to listen on is reported as such.
+func TestIPCListen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpc-ipc-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	endpoint := filepath.Join(dir, "test.ipc")
+	listener, err := CreateIPCListener(endpoint)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", endpoint, err)
+	}
+	defer listener.Close()
+	go server.ServeListener(listener)
+
+	client, err := DialIPC(context.Background(), endpoint)
+	if err != nil {
+		t.Fatalf("failed to dial %s: %v", endpoint, err)
+	}
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "hello", 1, &Args{"x"}); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if result.String != "hello" {
+		t.Errorf("result mismatch: have %q, want %q", result.String, "hello")
+	}
+	long := filepath.Join(dir, strings.Repeat("x", 200)+".ipc")
+	if _, err := CreateIPCListener(long); err == nil || !strings.Contains(err.Error(), "too long") {
+		t.Errorf("long path error mismatch: have %v, want path too long", err)
+	}
+}
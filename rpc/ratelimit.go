@@ -0,0 +1,121 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxRateBuckets is the number of clients tracked by a rate limiter before the
+// idle ones are forgotten.
+const maxRateBuckets = 4096
+
+// Limits bounds the requests clients may make to a server. Zero values leave the
+// respective resource unbounded.
+type Limits struct {
+	RequestRate   float64 // Requests per second allowed from each remote IP
+	RequestBurst  int     // Requests allowed at once from each remote IP
+	BatchItems    int     // Maximum number of requests in a batch
+	ResponseBytes int     // Maximum size of the result of a call, in bytes
+}
+
+// SetLimits bounds the requests clients may make over HTTP and websocket. The
+// request rate is limited per remote IP, each request of a batch counting as
+// one. It must be called before the server starts serving requests.
+func (s *Server) SetLimits(limits Limits) {
+	s.limits = limits
+	s.limiter = nil
+	if limits.RequestRate > 0 {
+		s.limiter = newRateLimiter(limits.RequestRate, limits.RequestBurst)
+	}
+}
+
+// rateBucket holds the requests a client may still make, refilled over time.
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits the rate of requests of each client with a token bucket.
+type rateLimiter struct {
+	rate  float64 // Tokens added to each bucket per second
+	burst float64 // Capacity of each bucket
+
+	buckets map[string]*rateBucket
+	lock    sync.Mutex
+}
+
+// newRateLimiter creates a rate limiter allowing rate requests per second, and
+// up to burst at once, defaulting to a second worth of requests.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*rateBucket),
+	}
+}
+
+// allow returns whether a client may make n requests now, consuming them if so.
+func (l *rateLimiter) allow(client string, n int) bool {
+	now := time.Now()
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	bucket := l.buckets[client]
+	if bucket == nil {
+		if len(l.buckets) >= maxRateBuckets {
+			l.prune(now)
+		}
+		bucket = &rateBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+
+	if bucket.tokens < float64(n) {
+		return false
+	}
+	bucket.tokens -= float64(n)
+	return true
+}
+
+// prune forgets the clients whose buckets would be full again by now.
+func (l *rateLimiter) prune(now time.Time) {
+	for client, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// remoteIP returns the IP address a request was received from, the client the
+// request rate is limited for.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,98 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests that the rate limiter allows bursts, refills over time and tracks
+// clients separately.
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(1, 2)
+	if !limiter.allow("a", 2) {
+		t.Fatalf("burst denied")
+	}
+	if limiter.allow("a", 1) {
+		t.Fatalf("request over the burst allowed")
+	}
+	if !limiter.allow("b", 1) {
+		t.Fatalf("other client denied")
+	}
+	limiter.buckets["a"].last = time.Now().Add(-time.Second)
+	if !limiter.allow("a", 1) {
+		t.Fatalf("request denied after refill")
+	}
+	if limiter.allow("a", 1) {
+		t.Fatalf("request allowed over the refill")
+	}
+	limiter.buckets["b"].last = time.Now().Add(-time.Minute)
+	limiter.prune(time.Now())
+	if _, ok := limiter.buckets["b"]; ok {
+		t.Errorf("idle client not forgotten")
+	}
+	if _, ok := limiter.buckets["a"]; !ok {
+		t.Errorf("active client forgotten")
+	}
+}
+
+// Tests that the requests over HTTP exceeding the limits of the server are
+// rejected with their own error codes.
+func TestServerLimits(t *testing.T) {
+	server := NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetLimits(Limits{RequestRate: 0.001, RequestBurst: 3, BatchItems: 2, ResponseBytes: 64})
+
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	call := func(body string) string {
+		resp, err := http.Post(httpsrv.URL, contentType, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		res, _ := ioutil.ReadAll(resp.Body)
+		return string(res)
+	}
+	echo := func(text string) string {
+		return `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["` + text + `",1,{"S":"x"}]}`
+	}
+	tests := []struct {
+		body, want string
+	}{
+		{echo("short"), `"result"`},
+		{echo(strings.Repeat("long", 20)), `"code":-32007`},
+		{"[" + echo("a") + "," + echo("b") + "," + echo("c") + "]", `"code":-32006`},
+		{"[" + echo("a") + "," + echo("b") + "]", `"code":-32005`},
+		{echo("short"), `"result"`},
+		{echo("short"), `"code":-32005`},
+	}
+	for i, tt := range tests {
+		if body := call(tt.body); !strings.Contains(body, tt.want) {
+			t.Errorf("test %d: response mismatch: have %s, want %s", i, body, tt.want)
+		}
+	}
+}
@@ -18,6 +18,7 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"runtime"
@@ -40,6 +41,10 @@ const (
 
 	// OptionSubscriptions is an indication that the codec suports RPC notifications
 	OptionSubscriptions = 1 << iota // support pub sub
+
+	// OptionHumanUnits is an indication that the results of method calls are to
+	// be encoded with amounts in aqua (see HumanJSON)
+	OptionHumanUnits = 1 << iota
 )
 
 // NewServer will create a new server instance with no registered handlers.
@@ -58,6 +63,13 @@ func NewServer() *Server {
 	return server
 }
 
+// SetStrictJSON enables or disables encoding the results of method calls as
+// canonical JSON (see CanonicalJSON), keeping the format of responses stable
+// across releases. It must be called before the server starts serving requests.
+func (s *Server) SetStrictJSON(strict bool) {
+	s.strict = strict
+}
+
 // RPCService gives meta information about the server.
 // e.g. gives information about the loaded modules.
 type RPCService struct {
@@ -68,7 +80,7 @@ type RPCService struct {
 func (s *RPCService) Modules() map[string]string {
 	modules := make(map[string]string)
 	for name := range s.server.services {
-		modules[name] = "1.0"
+		modules[name] = s.server.version(name)
 	}
 	return modules
 }
@@ -77,6 +89,14 @@ func (s *RPCService) Modules() map[string]string {
 // match the criteria to be either a RPC method or a subscription an error is returned. Otherwise a new service is
 // created and added to the service collection this server instance serves.
 func (s *Server) RegisterName(name string, rcvr interface{}) error {
+	return s.RegisterNameFiltered(name, rcvr, nil)
+}
+
+// RegisterNameFiltered is like RegisterName, but only registers the methods and
+// subscriptions of rcvr accepted by allow, called with their RPC names (e.g.
+// "getBalance"). Those filtered out don't exist for the clients of the server.
+// Nothing is registered if allow rejects them all.
+func (s *Server) RegisterNameFiltered(name string, rcvr interface{}, allow func(method string) bool) error {
 	if s.services == nil {
 		s.services = make(serviceRegistry)
 	}
@@ -93,6 +113,24 @@ func (s *Server) RegisterName(name string, rcvr interface{}) error {
 	}
 
 	methods, subscriptions := suitableCallbacks(rcvrVal, svc.typ)
+	if allow != nil {
+		if len(methods) == 0 && len(subscriptions) == 0 {
+			return fmt.Errorf("Service %T doesn't have any suitable methods/subscriptions to expose", rcvr)
+		}
+		for method := range methods {
+			if !allow(method) {
+				delete(methods, method)
+			}
+		}
+		for method := range subscriptions {
+			if !allow(method) {
+				delete(subscriptions, method)
+			}
+		}
+		if len(methods) == 0 && len(subscriptions) == 0 {
+			return nil
+		}
+	}
 
 	// already a previous service register under given sname, merge methods/subscriptions
 	if regsvc, present := s.services[name]; present {
@@ -125,7 +163,10 @@ func (s *Server) RegisterName(name string, rcvr interface{}) error {
 // If singleShot is true it will process a single request, otherwise it will handle
 // requests until the codec returns an error when reading a request (in most cases
 // an EOF). It executes requests in parallel when singleShot is false.
-func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecOption) error {
+//
+// Requests from a remote client, identified by its IP address, are subject to
+// the limits of the server, while local ones (remote empty) are not.
+func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecOption, remote string) error {
 	var pend sync.WaitGroup
 
 	defer func() {
@@ -149,6 +190,9 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 	if options&OptionSubscriptions == OptionSubscriptions {
 		ctx = context.WithValue(ctx, notifierKey{}, newNotifier(codec))
 	}
+	if options&OptionHumanUnits == OptionHumanUnits {
+		ctx = context.WithValue(ctx, humanUnitsKey{}, true)
+	}
 	s.codecsMu.Lock()
 	if atomic.LoadInt32(&s.run) != 1 { // server stopped
 		s.codecsMu.Unlock()
@@ -174,17 +218,25 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 		// check if server is ordered to shutdown and return an error
 		// telling the client that his request failed.
 		if atomic.LoadInt32(&s.run) != 1 {
-			err = &shutdownError{}
-			if batch {
-				resps := make([]interface{}, len(reqs))
-				for i, r := range reqs {
-					resps[i] = codec.CreateErrorResponse(&r.id, err)
+			s.rejectRequests(codec, reqs, batch, &shutdownError{})
+			return nil
+		}
+		// Reject the requests of remote clients exceeding the limits
+		if remote != "" {
+			if limit := s.limits.BatchItems; batch && limit > 0 && len(reqs) > limit {
+				codec.Write(codec.CreateErrorResponse(nil, &batchLimitError{len(reqs), limit}))
+				if singleShot {
+					return nil
 				}
-				codec.Write(resps)
-			} else {
-				codec.Write(codec.CreateErrorResponse(&reqs[0].id, err))
+				continue
+			}
+			if s.limiter != nil && !s.limiter.allow(remote, len(reqs)) {
+				s.rejectRequests(codec, reqs, batch, &rateLimitError{})
+				if singleShot {
+					return nil
+				}
+				continue
 			}
-			return nil
 		}
 		// If a single shot request is executing, run and return immediately
 		if singleShot {
@@ -210,19 +262,32 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 	return nil
 }
 
+// rejectRequests answers all the requests read at once with the same error.
+func (s *Server) rejectRequests(codec ServerCodec, reqs []*serverRequest, batch bool, err Error) {
+	if batch {
+		resps := make([]interface{}, len(reqs))
+		for i, r := range reqs {
+			resps[i] = codec.CreateErrorResponse(&r.id, err)
+		}
+		codec.Write(resps)
+	} else {
+		codec.Write(codec.CreateErrorResponse(&reqs[0].id, err))
+	}
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes the
 // response back using the given codec. It will block until the codec is closed or the server is
 // stopped. In either case the codec is closed.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	defer codec.Close()
-	s.serveRequest(codec, false, options)
+	s.serveRequest(codec, false, options, "")
 }
 
 // ServeSingleRequest reads and processes a single RPC request from the given codec. It will not
 // close the codec unless a non-recoverable error has occurred. Note, this method will return after
 // a single request has been processed!
 func (s *Server) ServeSingleRequest(codec ServerCodec, options CodecOption) {
-	s.serveRequest(codec, true, options)
+	s.serveRequest(codec, true, options, "")
 }
 
 // Stop will stop reading new requests, wait for stopPendingRequestTimeout to allow pending requests to finish,
@@ -316,11 +381,40 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
+			if rpcErr, ok := e.(Error); ok {
+				// Keep the codes of errors reporting a specific condition
+				return codec.CreateErrorResponse(&req.id, rpcErr), nil
+			}
 			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
 			return res, nil
 		}
 	}
-	return codec.CreateResponse(req.id, reply[0].Interface()), nil
+	result := reply[0].Interface()
+	if human, _ := ctx.Value(humanUnitsKey{}).(bool); human {
+		humanized, err := HumanJSON(result)
+		if err != nil {
+			return codec.CreateErrorResponse(&req.id, &callbackError{err.Error()}), nil
+		}
+		result = humanized
+	}
+	if s.strict {
+		canonical, err := CanonicalJSON(result)
+		if err != nil {
+			return codec.CreateErrorResponse(&req.id, &callbackError{err.Error()}), nil
+		}
+		result = canonical
+	}
+	if limit := s.limits.ResponseBytes; limit > 0 {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return codec.CreateErrorResponse(&req.id, &callbackError{err.Error()}), nil
+		}
+		if len(encoded) > limit {
+			return codec.CreateErrorResponse(&req.id, &responseLimitError{len(encoded), limit}), nil
+		}
+		result = json.RawMessage(encoded)
+	}
+	return codec.CreateResponse(req.id, result), nil
 }
 
 // exec executes the given request and writes the result back using the codec.
@@ -153,6 +153,72 @@ func testServerMethodExecution(t *testing.T, method string) {
 	}
 }
 
+func TestServerRegisterNameFiltered(t *testing.T) {
+	server := NewServer()
+	service := new(Service)
+
+	allow := func(method string) bool { return method == "echo" || method == "subscription" }
+	if err := server.RegisterNameFiltered("calc", service, allow); err != nil {
+		t.Fatalf("%v", err)
+	}
+	svc := server.services["calc"]
+	if len(svc.callbacks) != 1 || svc.callbacks["echo"] == nil {
+		t.Errorf("Expected only the echo callback for service 'calc', got %d", len(svc.callbacks))
+	}
+	if len(svc.subscriptions) != 1 {
+		t.Errorf("Expected 1 subscription for service 'calc', got %d", len(svc.subscriptions))
+	}
+	// A service with every method filtered out isn't registered
+	if err := server.RegisterNameFiltered("none", service, func(string) bool { return false }); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, ok := server.services["none"]; ok {
+		t.Errorf("Expected service none not to be registered")
+	}
+}
+
+func TestServerCapabilities(t *testing.T) {
+	server := NewServer()
+	api := API{
+		Namespace: "calc",
+		Version:   "1.2",
+		Service:   new(Service),
+		Features:  map[string][]string{"echo": {"args"}},
+	}
+	allow := func(method string) bool { return method != "sleep" }
+	if err := server.RegisterAPI(api, allow); err != nil {
+		t.Fatalf("%v", err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	var modules map[string]string
+	if err := client.Call(&modules, "rpc_modules"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if modules["calc"] != "1.2" || modules[MetadataApi] != defaultVersion {
+		t.Errorf("modules mismatch: have %v", modules)
+	}
+	var caps map[string]ModuleCapabilities
+	if err := client.Call(&caps, "rpc_capabilities"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	calc := caps["calc"]
+	if calc.Version != "1.2" {
+		t.Errorf("version mismatch: have %s, want 1.2", calc.Version)
+	}
+	echo := calc.Methods["echo"]
+	if echo.Params != 2 || echo.MaxParams != 3 || !reflect.DeepEqual(echo.Features, []string{"args"}) {
+		t.Errorf("echo capabilities mismatch: have %+v", echo)
+	}
+	if _, ok := calc.Methods["sleep"]; ok {
+		t.Errorf("filtered out method listed")
+	}
+	if !reflect.DeepEqual(calc.Subscriptions, []string{"subscription"}) {
+		t.Errorf("subscriptions mismatch: have %v, want [subscription]", calc.Subscriptions)
+	}
+}
+
 func TestServerMethodExecution(t *testing.T) {
 	testServerMethodExecution(t, "echo")
 }
@@ -160,3 +226,33 @@ func TestServerMethodExecution(t *testing.T) {
 func TestServerMethodWithCtx(t *testing.T) {
 	testServerMethodExecution(t, "echoWithCtx")
 }
+
+func TestServerStrictJSON(t *testing.T) {
+	server := NewServer()
+	server.SetStrictJSON(true)
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatalf("%v", err)
+	}
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	go server.ServeCodec(NewJSONCodec(serverConn), OptionMethodInvocation)
+
+	request := map[string]interface{}{
+		"id":      1,
+		"method":  "test_echo",
+		"version": "2.0",
+		"params":  []interface{}{"str", 255, &Args{"abcde"}},
+	}
+	if err := json.NewEncoder(clientConn).Encode(request); err != nil {
+		t.Fatal(err)
+	}
+	var response struct{ Result json.RawMessage }
+	if err := json.NewDecoder(clientConn).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Args":{"S":"abcde"},"Int":"0xff","String":"str"}`
+	if string(response.Result) != want {
+		t.Fatalf("result mismatch: have %s, want %s", response.Result, want)
+	}
+}
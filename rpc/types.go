@@ -17,12 +17,14 @@
 package rpc
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"reflect"
 	"strings"
 	"sync"
 
+	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/hexutil"
 	"gopkg.in/fatih/set.v0"
 )
@@ -33,6 +35,10 @@ type API struct {
 	Version   string      // api version for DApp's
 	Service   interface{} // receiver instance which holds the methods
 	Public    bool        // indication if the methods must be considered safe for public use
+
+	// Features lists, per method (e.g. "call"), the optional behaviour the
+	// method supports, reported to clients by rpc_capabilities.
+	Features map[string][]string
 }
 
 // callback is a method callback which was registered in the server
@@ -70,8 +76,13 @@ type subscriptions map[string]*callback  // collection of subscription callbacks
 // Server represents a RPC server
 type Server struct {
 	services serviceRegistry
+	versions map[string]string              // version of each namespace, see RegisterAPI
+	features map[string]map[string][]string // optional features of the methods of each namespace
 
 	run      int32
+	strict   bool // Whether results are encoded as canonical JSON
+	limits   Limits
+	limiter  *rateLimiter // Request rate limiter of the remote clients, if any
 	codecsMu sync.Mutex
 	codecs   *set.Set
 }
@@ -163,3 +174,99 @@ func (bn *BlockNumber) UnmarshalJSON(data []byte) error {
 func (bn BlockNumber) Int64() int64 {
 	return (int64)(bn)
 }
+
+// BlockNumberOrHash selects a block either by number (or meta block number) or
+// by hash. It decodes from a plain block number, a plain block hash, or an
+// object of the form:
+//
+//	{"blockNumber": "0x1"}
+//	{"blockHash": "0x...", "requireCanonical": true}
+//
+// If RequireCanonical is set, blocks selected by hash must be on the canonical
+// chain, otherwise uncle and side chain blocks are accepted as well.
+type BlockNumberOrHash struct {
+	BlockNumber      *BlockNumber `json:"blockNumber,omitempty"`
+	BlockHash        *common.Hash `json:"blockHash,omitempty"`
+	RequireCanonical bool         `json:"requireCanonical,omitempty"`
+}
+
+func (bnh *BlockNumberOrHash) UnmarshalJSON(data []byte) error {
+	type object BlockNumberOrHash
+	var obj object
+	if err := json.Unmarshal(data, &obj); err == nil {
+		if obj.BlockNumber != nil && obj.BlockHash != nil {
+			return fmt.Errorf("cannot specify both blockHash and blockNumber, choose one or the other")
+		}
+		if obj.BlockNumber == nil && obj.BlockHash == nil {
+			return fmt.Errorf("either blockHash or blockNumber must be specified")
+		}
+		if obj.BlockNumber != nil && obj.RequireCanonical {
+			return fmt.Errorf("requireCanonical is only supported with blockHash")
+		}
+		*bnh = BlockNumberOrHash(obj)
+		return nil
+	}
+	var input string
+	if err := json.Unmarshal(data, &input); err != nil {
+		return err
+	}
+	// Plain strings are block hashes if 32 bytes long, block numbers otherwise
+	if len(input) == 66 {
+		var hash common.Hash
+		if err := hash.UnmarshalText([]byte(input)); err != nil {
+			return err
+		}
+		*bnh = BlockNumberOrHash{BlockHash: &hash}
+		return nil
+	}
+	var number BlockNumber
+	if err := number.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*bnh = BlockNumberOrHash{BlockNumber: &number}
+	return nil
+}
+
+// Number returns the selected block number, if the block is selected by number.
+func (bnh *BlockNumberOrHash) Number() (BlockNumber, bool) {
+	if bnh.BlockNumber != nil {
+		return *bnh.BlockNumber, true
+	}
+	return BlockNumber(0), false
+}
+
+// Hash returns the selected block hash, if the block is selected by hash.
+func (bnh *BlockNumberOrHash) Hash() (common.Hash, bool) {
+	if bnh.BlockHash != nil {
+		return *bnh.BlockHash, true
+	}
+	return common.Hash{}, false
+}
+
+// String implements fmt.Stringer.
+func (bnh BlockNumberOrHash) String() string {
+	if bnh.BlockHash != nil {
+		return bnh.BlockHash.Hex()
+	}
+	if bnh.BlockNumber != nil {
+		switch *bnh.BlockNumber {
+		case PendingBlockNumber:
+			return "pending"
+		case LatestBlockNumber:
+			return "latest"
+		}
+		return fmt.Sprintf("#%d", *bnh.BlockNumber)
+	}
+	return "nil"
+}
+
+// BlockNumberOrHashWithNumber selects a block by number.
+func BlockNumberOrHashWithNumber(number BlockNumber) BlockNumberOrHash {
+	return BlockNumberOrHash{BlockNumber: &number}
+}
+
+// BlockNumberOrHashWithHash selects a block by hash, optionally requiring it to
+// be canonical.
+func BlockNumberOrHashWithHash(hash common.Hash, canonical bool) BlockNumberOrHash {
+	return BlockNumberOrHash{BlockHash: &hash, RequireCanonical: canonical}
+}
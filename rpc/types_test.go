@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/aquanetwork/aquachain/common"
 	"github.com/aquanetwork/aquachain/common/math"
 )
 
@@ -64,3 +65,46 @@ func TestBlockNumberJSONUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestBlockNumberOrHashUnmarshal(t *testing.T) {
+	hash := common.HexToHash("0x6c8ef2efd2a2d1bb7f3b4c2c32b5e8d3bc8c37e1fbbd4e8b0d4e46bb2a1f9c3d")
+	tests := []struct {
+		input    string
+		mustFail bool
+		expected BlockNumberOrHash
+	}{
+		0:  {`"0x"`, true, BlockNumberOrHash{}},
+		1:  {`"0x0"`, false, BlockNumberOrHashWithNumber(0)},
+		2:  {`"0x12"`, false, BlockNumberOrHashWithNumber(18)},
+		3:  {`"earliest"`, false, BlockNumberOrHashWithNumber(EarliestBlockNumber)},
+		4:  {`"latest"`, false, BlockNumberOrHashWithNumber(LatestBlockNumber)},
+		5:  {`"pending"`, false, BlockNumberOrHashWithNumber(PendingBlockNumber)},
+		6:  {`"` + hash.Hex() + `"`, false, BlockNumberOrHashWithHash(hash, false)},
+		7:  {`{"blockNumber":"0x12"}`, false, BlockNumberOrHashWithNumber(18)},
+		8:  {`{"blockNumber":"latest"}`, false, BlockNumberOrHashWithNumber(LatestBlockNumber)},
+		9:  {`{"blockHash":"` + hash.Hex() + `"}`, false, BlockNumberOrHashWithHash(hash, false)},
+		10: {`{"blockHash":"` + hash.Hex() + `","requireCanonical":true}`, false, BlockNumberOrHashWithHash(hash, true)},
+		11: {`{"blockHash":"` + hash.Hex() + `","blockNumber":"0x1"}`, true, BlockNumberOrHash{}},
+		12: {`{"blockNumber":"0x1","requireCanonical":true}`, true, BlockNumberOrHash{}},
+		13: {`{}`, true, BlockNumberOrHash{}},
+		14: {`"0x6c8ef2efd2a2d1bb7f3b4c2c32b5e8d3bc8c37e1fbbd4e8b0d4e46bb2a1f9cxx"`, true, BlockNumberOrHash{}},
+		15: {`1`, true, BlockNumberOrHash{}},
+	}
+	for i, test := range tests {
+		var bnh BlockNumberOrHash
+		err := json.Unmarshal([]byte(test.input), &bnh)
+		if test.mustFail {
+			if err == nil {
+				t.Errorf("test %d: should fail", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: should pass but got err: %v", i, err)
+			continue
+		}
+		if bnh.String() != test.expected.String() || bnh.RequireCanonical != test.expected.RequireCanonical {
+			t.Errorf("test %d: got unexpected value, want %v, got %v", i, test.expected, bnh)
+		}
+	}
+}
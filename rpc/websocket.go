@@ -68,7 +68,9 @@ func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			decoder := func(v interface{}) error {
 				return websocketJSONCodec.Receive(conn, v)
 			}
-			srv.ServeCodec(NewCodec(conn, encoder, decoder), OptionMethodInvocation|OptionSubscriptions)
+			codec := NewCodec(conn, encoder, decoder)
+			defer codec.Close()
+			srv.serveRequest(codec, false, OptionMethodInvocation|OptionSubscriptions|humanUnitsOption(conn.Request()), remoteIP(conn.Request()))
 		},
 	}
 }
@@ -0,0 +1,93 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tests
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+func TestConsensus(t *testing.T) {
+	t.Parallel()
+
+	// Share the engine to generate the verification cache of an epoch once
+	engine := aquahash.New(aquahash.Config{CachesInMem: 2})
+
+	ct := new(testMatcher)
+	ct.walk(t, consensusTestDir, func(t *testing.T, name string, test *ConsensusTest) {
+		if err := ct.checkFailure(t, name, test.Run(engine)); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// Tests that generated vectors record the ancestors the rules look at and pass
+// once reloaded.
+func TestMakeConsensusTest(t *testing.T) {
+	t.Parallel()
+
+	engine := aquahash.New(aquahash.Config{CachesInMem: 1})
+	parent := &types.Header{
+		ParentHash: common.Hash{0x01},
+		Number:     big.NewInt(29999),
+		Difficulty: big.NewInt(60000000),
+		GasLimit:   4200000,
+		Time:       big.NewInt(1527199760),
+		Version:    types.H_ARGON2ID,
+	}
+	chain := newConsensusChain(params.MainnetChainConfig, []*types.Header{parent})
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     big.NewInt(30000),
+		Difficulty: big.NewInt(63750000),
+		GasLimit:   4200000,
+		Time:       big.NewInt(1527199900),
+	}
+	test, err := MakeConsensusTest(engine, "mainnet", chain, header)
+	if err != nil {
+		t.Fatalf("failed to make test: %v", err)
+	}
+	if len(test.json.Ancestors) != 1 || test.json.Ancestors[0].Hash() != parent.Hash() {
+		t.Fatalf("ancestors mismatch: have %v, want [%v]", test.json.Ancestors, parent)
+	}
+	if test.json.Fork != 5 || header.Version != types.H_ARGON2ID || !test.json.Valid || test.json.SealValid {
+		t.Fatalf("outcome mismatch: have fork %d, version %d, valid %v, seal valid %v; want 5, %d, true, false",
+			test.json.Fork, header.Version, test.json.Valid, test.json.SealValid, types.H_ARGON2ID)
+	}
+	blob, err := json.Marshal(test)
+	if err != nil {
+		t.Fatalf("failed to encode test: %v", err)
+	}
+	reloaded := new(ConsensusTest)
+	if err := json.Unmarshal(blob, reloaded); err != nil {
+		t.Fatalf("failed to decode test: %v", err)
+	}
+	if err := reloaded.Run(engine); err != nil {
+		t.Fatalf("reloaded test failed: %v", err)
+	}
+	// Tampering with the recorded outcome must be caught
+	reloaded.json.Valid = false
+	if err := reloaded.Run(engine); err == nil {
+		t.Fatalf("tampered test passed")
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/aquanetwork/aquachain/common"
+	"github.com/aquanetwork/aquachain/common/math"
+	"github.com/aquanetwork/aquachain/consensus"
+	"github.com/aquanetwork/aquachain/consensus/aquahash"
+	"github.com/aquanetwork/aquachain/core/types"
+	"github.com/aquanetwork/aquachain/params"
+)
+
+// ConsensusNetworks are the chain configurations consensus tests can be run
+// against, by the name of their network.
+var ConsensusNetworks = map[string]*params.ChainConfig{
+	"mainnet": params.MainnetChainConfig,
+	"testnet": params.TestnetChainConfig,
+	"rinkeby": params.RinkebyChainConfig,
+}
+
+// A ConsensusTest checks the consensus rules of a header against the outcome
+// recorded for it: the hard fork active at its height, its header version, the
+// difficulty it has to have and whether the header and its seal are valid.
+type ConsensusTest struct {
+	json ctJSON
+}
+
+func (t *ConsensusTest) UnmarshalJSON(in []byte) error {
+	return json.Unmarshal(in, &t.json)
+}
+
+func (t *ConsensusTest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&t.json)
+}
+
+type ctJSON struct {
+	Network    string                `json:"network"`
+	Fork       int                   `json:"fork"`      // Latest hard fork active at the header, -1 if none
+	Ancestors  []*types.Header       `json:"ancestors"` // Parent first, as far back as the difficulty rules look
+	Header     *types.Header         `json:"header"`
+	Difficulty *math.HexOrDecimal256 `json:"difficulty"` // Difficulty required by the rules
+	Valid      bool                  `json:"valid"`      // Whether the header passes verification, seal aside
+	SealValid  bool                  `json:"sealValid"`  // Whether the seal is valid for the header's difficulty
+}
+
+// MakeConsensusTest records the outcome of the consensus rules of a network for
+// a header on top of chain, along with the ancestors they look at. The version
+// of the header is filled in if unset.
+func MakeConsensusTest(engine *aquahash.Aquahash, network string, chain consensus.ChainReader, header *types.Header) (*ConsensusTest, error) {
+	config, ok := ConsensusNetworks[network]
+	if !ok {
+		return nil, UnsupportedForkError{network}
+	}
+	if header.Version == types.H_UNSET {
+		header.Version = types.BlockVersion(config, header)
+	}
+	// Find the ancestors the rules look at, hiding the header itself which
+	// would otherwise be reported as known without verification
+	recorder := &recordingChain{ChainReader: chain, hidden: header.Hash(), seen: make(map[common.Hash]*types.Header)}
+	if _, err := checkConsensus(engine, recorder, header); err != nil {
+		return nil, err
+	}
+	ancestors := recorder.ancestors()
+
+	outcome, err := checkConsensus(engine, newConsensusChain(config, ancestors), header)
+	if err != nil {
+		return nil, err
+	}
+	return &ConsensusTest{ctJSON{
+		Network:    network,
+		Fork:       activeFork(config, header.Number),
+		Ancestors:  ancestors,
+		Header:     header,
+		Difficulty: (*math.HexOrDecimal256)(outcome.difficulty),
+		Valid:      outcome.header == nil,
+		SealValid:  outcome.seal == nil,
+	}}, nil
+}
+
+// Run checks the header of the test against the consensus rules.
+func (t *ConsensusTest) Run(engine *aquahash.Aquahash) error {
+	config, ok := ConsensusNetworks[t.json.Network]
+	if !ok {
+		return UnsupportedForkError{t.json.Network}
+	}
+	header := t.json.Header
+	if fork := activeFork(config, header.Number); fork != t.json.Fork {
+		return fmt.Errorf("fork mismatch: have %d, want %d", fork, t.json.Fork)
+	}
+	if version := types.BlockVersion(config, header); version != header.Version {
+		return fmt.Errorf("header version mismatch: have %d, want %d", version, header.Version)
+	}
+	outcome, err := checkConsensus(engine, newConsensusChain(config, t.json.Ancestors), header)
+	if err != nil {
+		return err
+	}
+	if want := (*big.Int)(t.json.Difficulty); outcome.difficulty.Cmp(want) != 0 {
+		return fmt.Errorf("difficulty mismatch: have %v, want %v", outcome.difficulty, want)
+	}
+	if valid := outcome.header == nil; valid != t.json.Valid {
+		return fmt.Errorf("header validity mismatch: have %v (%v), want %v", valid, outcome.header, t.json.Valid)
+	}
+	if valid := outcome.seal == nil; valid != t.json.SealValid {
+		return fmt.Errorf("seal validity mismatch: have %v (%v), want %v", valid, outcome.seal, t.json.SealValid)
+	}
+	return nil
+}
+
+// consensusOutcome is the result of the consensus rules for a header.
+type consensusOutcome struct {
+	difficulty *big.Int // Difficulty required by the rules
+	header     error    // Verification error of the header, seal aside
+	seal       error    // Verification error of the seal
+}
+
+// checkConsensus runs the consensus rules for a header on top of chain.
+func checkConsensus(engine *aquahash.Aquahash, chain consensus.ChainReader, header *types.Header) (*consensusOutcome, error) {
+	required := types.CopyHeader(header)
+	if err := engine.Prepare(chain, required); err != nil {
+		return nil, err
+	}
+	return &consensusOutcome{
+		difficulty: required.Difficulty,
+		header:     engine.VerifyHeader(chain, header, false),
+		seal:       engine.VerifySeal(chain, header),
+	}, nil
+}
+
+// activeFork returns the latest hard fork of config active at number, -1 if none.
+func activeFork(config *params.ChainConfig, number *big.Int) int {
+	forks := make([]int, 0, len(config.HF))
+	for hf := range config.HF {
+		forks = append(forks, hf)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(forks)))
+	for _, hf := range forks {
+		if config.IsHF(hf, number) {
+			return hf
+		}
+	}
+	return -1
+}
+
+// consensusChain is the chain of the ancestors of a tested header, implementing
+// consensus.ChainReader.
+type consensusChain struct {
+	config  *params.ChainConfig
+	headers map[common.Hash]*types.Header
+	current *types.Header
+}
+
+func newConsensusChain(config *params.ChainConfig, ancestors []*types.Header) *consensusChain {
+	chain := &consensusChain{config: config, headers: make(map[common.Hash]*types.Header)}
+	for _, header := range ancestors {
+		if header.Version == types.H_UNSET {
+			header.Version = types.BlockVersion(config, header)
+		}
+		chain.headers[header.Hash()] = header
+	}
+	if len(ancestors) > 0 {
+		chain.current = ancestors[0]
+	}
+	return chain
+}
+
+func (c *consensusChain) Config() *params.ChainConfig  { return c.config }
+func (c *consensusChain) CurrentHeader() *types.Header { return c.current }
+
+func (c *consensusChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.headers[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+func (c *consensusChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return c.headers[hash]
+}
+
+func (c *consensusChain) GetHeaderByNumber(number uint64) *types.Header {
+	for _, header := range c.headers {
+		if header.Number.Uint64() == number {
+			return header
+		}
+	}
+	return nil
+}
+
+func (c *consensusChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return nil
+}
+
+// recordingChain is a consensus.ChainReader keeping track of the headers read,
+// the hidden one aside.
+type recordingChain struct {
+	consensus.ChainReader
+	hidden common.Hash
+	seen   map[common.Hash]*types.Header
+}
+
+func (c *recordingChain) record(header *types.Header) *types.Header {
+	if header == nil {
+		return nil
+	}
+	if header.Version == types.H_UNSET {
+		header = types.CopyHeader(header)
+		header.Version = types.BlockVersion(c.Config(), header)
+	}
+	if hash := header.Hash(); hash != c.hidden {
+		c.seen[hash] = header
+		return header
+	}
+	return nil
+}
+
+func (c *recordingChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.record(c.ChainReader.GetHeader(hash, number))
+}
+
+func (c *recordingChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return c.record(c.ChainReader.GetHeaderByHash(hash))
+}
+
+func (c *recordingChain) GetHeaderByNumber(number uint64) *types.Header {
+	return c.record(c.ChainReader.GetHeaderByNumber(number))
+}
+
+func (c *recordingChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return nil
+}
+
+// ancestors returns the headers read, the most recent first.
+func (c *recordingChain) ancestors() []*types.Header {
+	headers := make([]*types.Header, 0, len(c.seen))
+	for _, header := range c.seen {
+		headers = append(headers, header)
+	}
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].Number.Cmp(headers[j].Number) > 0
+	})
+	return headers
+}
@@ -57,9 +57,24 @@ type Database struct {
 	nodesSize     common.StorageSize // Storage size of the nodes cache
 	preimagesSize common.StorageSize // Storage size of the preimages cache
 
+	stats DatabaseStats // Cumulative flush and garbage collection statistics
+
 	lock sync.RWMutex
 }
 
+// DatabaseStats are the cumulative persistence and garbage collection statistics
+// of a trie database since its creation.
+type DatabaseStats struct {
+	Commits        uint64             // Number of tries flushed to disk
+	FlushedNodes   uint64             // Nodes flushed to disk
+	FlushedSize    common.StorageSize // Data storage flushed to disk
+	CollectedNodes uint64             // Nodes garbage collected from memory
+	CollectedSize  common.StorageSize // Data storage garbage collected from memory
+	CollectionTime time.Duration      // Time spent on garbage collection
+	LiveNodes      int                // Nodes currently held in memory
+	LiveSize       common.StorageSize // Storage size of the memory cache
+}
+
 // cachedNode is all the information we know about a single cached node in the
 // memory database write layer.
 type cachedNode struct {
@@ -208,6 +223,10 @@ func (db *Database) Dereference(child common.Hash, parent common.Hash) {
 	db.gcsize += storage - db.nodesSize
 	db.gctime += time.Since(start)
 
+	db.stats.CollectedNodes += uint64(nodes - len(db.nodes))
+	db.stats.CollectedSize += storage - db.nodesSize
+	db.stats.CollectionTime += time.Since(start)
+
 	log.Debug("Dereferenced trie from memory database", "nodes", nodes-len(db.nodes), "size", storage-db.nodesSize, "time", time.Since(start),
 		"gcnodes", db.gcnodes, "gcsize", db.gcsize, "gctime", db.gctime, "livenodes", len(db.nodes), "livesize", db.nodesSize)
 }
@@ -289,6 +308,10 @@ func (db *Database) Commit(node common.Hash, report bool) error {
 
 	db.uncache(node)
 
+	db.stats.Commits++
+	db.stats.FlushedNodes += uint64(nodes - len(db.nodes))
+	db.stats.FlushedSize += storage - db.nodesSize
+
 	logger := log.Info
 	if !report {
 		logger = log.Debug
@@ -353,3 +376,15 @@ func (db *Database) Size() common.StorageSize {
 
 	return db.nodesSize + db.preimagesSize
 }
+
+// Stats returns the cumulative flush and garbage collection statistics of the
+// database, along with the current size of the memory cache.
+func (db *Database) Stats() DatabaseStats {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	stats := db.stats
+	stats.LiveNodes = len(db.nodes) - 1 // meta root
+	stats.LiveSize = db.nodesSize + db.preimagesSize
+	return stats
+}
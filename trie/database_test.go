@@ -0,0 +1,60 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"testing"
+
+	"github.com/aquanetwork/aquachain/aquadb"
+	"github.com/aquanetwork/aquachain/common"
+)
+
+// Tests that the database statistics track flushed and garbage collected tries.
+func TestDatabaseStats(t *testing.T) {
+	diskdb, _ := aquadb.NewMemDatabase()
+	triedb := NewDatabase(diskdb)
+
+	// Create two tries, keeping the first one and dropping the second
+	trie, _ := New(common.Hash{}, triedb)
+	updateString(trie, "120000", "qwerqwerqwerqwerqwerqwerqwerqwer")
+	updateString(trie, "123456", "asdfasdfasdfasdfasdfasdfasdfasdf")
+	kept, _ := trie.Commit(nil)
+
+	trie, _ = New(common.Hash{}, triedb)
+	updateString(trie, "abcdef", "zxcvzxcvzxcvzxcvzxcvzxcvzxcvzxcv")
+	dropped, _ := trie.Commit(nil)
+
+	stats := triedb.Stats()
+	if stats.LiveNodes == 0 || stats.LiveSize == 0 || stats.Commits != 0 {
+		t.Fatalf("unexpected stats before gc: %+v", stats)
+	}
+	triedb.Reference(dropped, common.Hash{})
+	triedb.Dereference(dropped, common.Hash{})
+	if stats = triedb.Stats(); stats.CollectedNodes == 0 || stats.CollectedSize == 0 {
+		t.Fatalf("garbage collection not tracked: %+v", stats)
+	}
+	if err := triedb.Commit(kept, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	stats = triedb.Stats()
+	if stats.Commits != 1 || stats.FlushedNodes == 0 || stats.FlushedSize == 0 {
+		t.Fatalf("flush not tracked: %+v", stats)
+	}
+	if stats.LiveNodes != 0 || stats.LiveSize != 0 {
+		t.Fatalf("memory cache not empty after flush: %+v", stats)
+	}
+}